@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/gcperror"
+)
+
+// client calls the admin API and the emulated GCP APIs of a running mock.
+type client struct {
+	baseURL string
+	project string
+	http    *http.Client
+}
+
+// newClient creates a client for the mock at baseURL.
+// A base URL without a scheme, like STORAGE_EMULATOR_HOST values, uses http.
+func newClient(baseURL, project string) *client {
+	if !strings.Contains(baseURL, "://") {
+		baseURL = "http://" + baseURL
+	}
+	return &client{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		project: project,
+		http:    &http.Client{Timeout: 5 * time.Minute},
+	}
+}
+
+// apiError is an error response of the mock.
+type apiError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// isStatus reports whether err is an error response with the given status code.
+func isStatus(err error, statusCode int) bool {
+	apiErr, ok := err.(*apiError)
+	return ok && apiErr.StatusCode == statusCode
+}
+
+// do sends a request to the mock and decodes the JSON response into out, if out isn't nil.
+func (c *client) do(method, path, contentType string, body io.Reader, out any) error {
+	resp, err := c.send(method, path, contentType, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response of %s %s: %w", method, path, err)
+	}
+	return nil
+}
+
+// doJSON sends in as JSON to the mock and decodes the JSON response into out, if out isn't nil.
+func (c *client) doJSON(method, path string, in, out any) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return c.do(method, path, "application/json", bytes.NewReader(body), out)
+}
+
+// send sends a request to the mock and returns the response if it was successful.
+// The caller must close the response body.
+func (c *client) send(method, path, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(method, c.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		return nil, responseError(resp)
+	}
+	return resp, nil
+}
+
+// responseError returns the error of an unsuccessful response, using the message of
+// the GCP error body if there is one.
+func responseError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+
+	var errResp gcperror.Response
+	if err := json.Unmarshal(body, &errResp); err == nil && errResp.Error.Message != "" {
+		return &apiError{StatusCode: resp.StatusCode, Message: errResp.Error.Message}
+	}
+	return &apiError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(body))}
+}
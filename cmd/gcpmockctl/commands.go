@@ -0,0 +1,361 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/clock"
+	"github.com/katharinasick/gcp-api-mock/internal/gcsimport"
+	"github.com/katharinasick/gcp-api-mock/internal/loadgen"
+	"github.com/katharinasick/gcp-api-mock/internal/sqladmin"
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+	"github.com/katharinasick/gcp-api-mock/internal/store"
+)
+
+// logPollInterval is how often "logs -f" asks the mock for new requests.
+var logPollInterval = time.Second
+
+// runReset deletes all resources.
+func runReset(c *client, args []string, stdout io.Writer) error {
+	if err := c.do(http.MethodPost, "/admin/reset", "", nil, nil); err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, "Deleted all resources")
+	return nil
+}
+
+// runTick evaluates time-dependent state, like soft delete retention, right away.
+func runTick(c *client, args []string, stdout io.Writer) error {
+	return c.do(http.MethodPost, "/admin/tick", "", nil, nil)
+}
+
+// runList lists buckets with their objects and Cloud SQL instances.
+func runList(c *client, args []string, stdout io.Writer) error {
+	tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TYPE\tNAME\tDETAILS")
+
+	buckets, err := c.listBuckets()
+	if err != nil {
+		return err
+	}
+	for _, bucket := range buckets {
+		fmt.Fprintf(tw, "bucket\t%s\t%s %s\n", bucket.Name, bucket.Location, bucket.StorageClass)
+
+		objects, err := c.listObjects(bucket.Name)
+		if err != nil {
+			return err
+		}
+		for _, obj := range objects {
+			fmt.Fprintf(tw, "object\tgs://%s/%s\t%d bytes, %s\n", bucket.Name, obj.Name, obj.Size, obj.ContentType)
+		}
+	}
+
+	var instances sqladmin.InstancesListResponse
+	if err := c.do(http.MethodGet, "/sql/v1beta4/projects/"+c.project+"/instances", "", nil, &instances); err != nil {
+		return err
+	}
+	for _, instance := range instances.Items {
+		fmt.Fprintf(tw, "sql-instance\t%s\t%s %s\n", instance.Name, instance.DatabaseVersion, instance.State)
+	}
+
+	return tw.Flush()
+}
+
+// listBuckets lists all buckets, following the pages of the list.
+func (c *client) listBuckets() ([]*storage.Bucket, error) {
+	var buckets []*storage.Bucket
+	pageToken := ""
+	for {
+		var page storage.BucketList
+		path := "/storage/v1/b?project=" + url.QueryEscape(c.project) + "&pageToken=" + url.QueryEscape(pageToken)
+		if err := c.do(http.MethodGet, path, "", nil, &page); err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, page.Items...)
+		if page.NextPageToken == "" {
+			return buckets, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+// listObjects lists all objects in a bucket, following the pages of the list.
+func (c *client) listObjects(bucketName string) ([]*storage.Object, error) {
+	var objects []*storage.Object
+	pageToken := ""
+	for {
+		var page storage.ObjectList
+		path := "/storage/v1/b/" + url.PathEscape(bucketName) + "/o?pageToken=" + url.QueryEscape(pageToken)
+		if err := c.do(http.MethodGet, path, "", nil, &page); err != nil {
+			return nil, err
+		}
+		objects = append(objects, page.Items...)
+		if page.NextPageToken == "" {
+			return objects, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+// requestLogEntry is an entry of the request log, as listed by GET /admin/requests.
+type requestLogEntry struct {
+	ID     int64     `json:"id"`
+	Time   time.Time `json:"time"`
+	Method string    `json:"method"`
+	Path   string    `json:"path"`
+	Status int       `json:"status"`
+}
+
+// runLogs prints the request log and, with -f, keeps printing new requests until interrupted.
+func runLogs(c *client, args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("logs", flag.ContinueOnError)
+	follow := flags.Bool("f", false, "keep printing new requests")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var lastID int64
+	for {
+		var entries []requestLogEntry
+		if err := c.do(http.MethodGet, fmt.Sprintf("/admin/requests?after=%d", lastID), "", nil, &entries); err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			fmt.Fprintf(stdout, "%s %3d %-6s %s\n", entry.Time.Format(time.TimeOnly), entry.Status, entry.Method, entry.Path)
+			lastID = entry.ID
+		}
+
+		if !*follow {
+			return nil
+		}
+		time.Sleep(logPollInterval)
+	}
+}
+
+// runLatency shows the latency profile or replaces it. The profile uses the format of GCP_MOCK_LATENCY,
+// and "off" removes all injected latency.
+func runLatency(c *client, args []string, stdout io.Writer) error {
+	var profile map[string]string
+	switch {
+	case len(args) == 0:
+		if err := c.do(http.MethodGet, "/admin/latency", "", nil, &profile); err != nil {
+			return err
+		}
+	case len(args) == 1 && args[0] == "off":
+		if err := c.doJSON(http.MethodPut, "/admin/latency", map[string]string{}, &profile); err != nil {
+			return err
+		}
+	case len(args) == 1:
+		parsed, err := parseLatencyProfile(args[0])
+		if err != nil {
+			return err
+		}
+		if err := c.doJSON(http.MethodPut, "/admin/latency", parsed, &profile); err != nil {
+			return err
+		}
+	default:
+		return errors.New("expected at most one profile argument")
+	}
+
+	if len(profile) == 0 {
+		fmt.Fprintln(stdout, "No latency injected")
+		return nil
+	}
+	operations := make([]string, 0, len(profile))
+	for operation := range profile {
+		operations = append(operations, operation)
+	}
+	sort.Strings(operations)
+	for _, operation := range operations {
+		fmt.Fprintf(stdout, "%s=%s\n", operation, profile[operation])
+	}
+	return nil
+}
+
+// parseLatencyProfile parses a comma-separated profile like "storage.get=20ms-80ms,sql.*=2s"
+// into the body of PUT /admin/latency. The ranges are validated by the mock.
+func parseLatencyProfile(s string) (map[string]string, error) {
+	profile := make(map[string]string)
+	for _, entry := range strings.Split(s, ",") {
+		operation, latencyRange, found := strings.Cut(strings.TrimSpace(entry), "=")
+		if !found || operation == "" || latencyRange == "" {
+			return nil, fmt.Errorf("invalid profile entry %q: expected operation=latency", entry)
+		}
+		profile[operation] = latencyRange
+	}
+	return profile, nil
+}
+
+// runClock shows the virtual clock or changes it.
+func runClock(c *client, args []string, stdout io.Writer) error {
+	var status clock.Status
+	var err error
+	switch {
+	case len(args) == 0:
+		err = c.do(http.MethodGet, "/admin/clock", "", nil, &status)
+	case len(args) == 1 && args[0] == "freeze":
+		err = c.doJSON(http.MethodPut, "/admin/clock", map[string]bool{"frozen": true}, &status)
+	case len(args) == 1 && args[0] == "unfreeze":
+		err = c.doJSON(http.MethodPut, "/admin/clock", map[string]bool{"frozen": false}, &status)
+	case len(args) == 1 && args[0] == "reset":
+		err = c.do(http.MethodDelete, "/admin/clock", "", nil, &status)
+	case len(args) == 2 && args[0] == "advance":
+		err = c.doJSON(http.MethodPost, "/admin/clock/advance", map[string]string{"duration": args[1]}, &status)
+	default:
+		return errors.New("expected freeze, unfreeze, reset or advance <duration>")
+	}
+	if err != nil {
+		return err
+	}
+
+	state := "running"
+	if status.Frozen {
+		state = "frozen"
+	}
+	fmt.Fprintf(stdout, "%s (%s, offset %s)\n", status.Now.Format(time.RFC3339), state, status.Offset)
+	return nil
+}
+
+// runSnapshot saves the entire mock state to a file.
+func runSnapshot(c *client, args []string, stdout io.Writer) error {
+	if len(args) != 1 {
+		return errors.New("expected a file argument")
+	}
+
+	resp, err := c.send(http.MethodGet, "/admin/snapshot", "", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	f, err := os.Create(args[0])
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to download snapshot: %w", err)
+	}
+	return f.Close()
+}
+
+// runRestore replaces the entire mock state with a snapshot file.
+func runRestore(c *client, args []string, stdout io.Writer) error {
+	if len(args) != 1 {
+		return errors.New("expected a file argument")
+	}
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var summary store.SnapshotSummary
+	if err := c.do(http.MethodPost, "/admin/restore", "application/x-tar", f, &summary); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Restored %d buckets, %d objects, %d Cloud SQL instances and %d documents\n",
+		summary.Buckets, summary.Objects, summary.SQLInstances, summary.Documents)
+	return nil
+}
+
+// runImport mirrors the objects of a real bucket, optionally only those below a prefix, into the mock.
+// The mock reads the bucket with the access token of -token or GOOGLE_OAUTH_ACCESS_TOKEN,
+// e.g. GOOGLE_OAUTH_ACCESS_TOKEN=$(gcloud auth print-access-token).
+func runImport(c *client, args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	to := flags.String("to", "", "bucket of the mock to import into; defaults to the name of the source bucket")
+	metadataOnly := flags.Bool("metadata-only", false, "import empty objects with the metadata of the source objects")
+	token := flags.String("token", os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"), "OAuth access token to read the bucket with (env GOOGLE_OAUTH_ACCESS_TOKEN)")
+	endpoint := flags.String("endpoint", gcsimport.DefaultEndpoint, "Cloud Storage endpoint to read the bucket from")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("expected a gs://<bucket>[/<prefix>] argument")
+	}
+
+	path, found := strings.CutPrefix(flags.Arg(0), "gs://")
+	sourceBucket, prefix, _ := strings.Cut(path, "/")
+	if !found || sourceBucket == "" {
+		return fmt.Errorf("invalid source %q: expected gs://<bucket>[/<prefix>]", flags.Arg(0))
+	}
+
+	opts := gcsimport.Options{
+		SourceBucket: sourceBucket,
+		Bucket:       *to,
+		Prefix:       prefix,
+		MetadataOnly: *metadataOnly,
+		AccessToken:  *token,
+		Endpoint:     *endpoint,
+	}
+	var result gcsimport.Result
+	if err := c.doJSON(http.MethodPost, "/admin/storage/import", opts, &result); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Imported %d objects (%d bytes) into gs://%s\n", result.Objects, result.Bytes, result.Bucket)
+	return nil
+}
+
+// runRemove deletes the objects of a bucket under a prefix, or all of them, in one request.
+func runRemove(c *client, args []string, stdout io.Writer) error {
+	if len(args) != 1 {
+		return errors.New("expected a gs://<bucket>[/<prefix>] argument")
+	}
+
+	path, found := strings.CutPrefix(args[0], "gs://")
+	bucket, prefix, _ := strings.Cut(path, "/")
+	if !found || bucket == "" {
+		return fmt.Errorf("invalid target %q: expected gs://<bucket>[/<prefix>]", args[0])
+	}
+
+	var result struct {
+		Deleted int `json:"deleted"`
+	}
+	endpoint := "/admin/storage/buckets/" + url.PathEscape(bucket) + "/objects?prefix=" + url.QueryEscape(prefix)
+	if err := c.do(http.MethodDelete, endpoint, "", nil, &result); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Deleted %d objects from gs://%s/%s\n", result.Deleted, bucket, prefix)
+	return nil
+}
+
+// runGenerate fills the mock with generated buckets, objects and Cloud SQL instances for performance tests.
+func runGenerate(c *client, args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("generate", flag.ContinueOnError)
+	var opts loadgen.Options
+	flags.IntVar(&opts.Buckets, "buckets", 1, "number of buckets")
+	flags.IntVar(&opts.Objects, "objects", 100, "number of objects per bucket")
+	flags.IntVar(&opts.Folders, "folders", 0, "number of folders to spread the objects of each bucket over")
+	flags.StringVar(&opts.Sizes, "sizes", "1KiB", `object size distribution, e.g. "1KiB-64KiB" or "90%=1KiB-16KiB,10%=1MiB-8MiB"`)
+	flags.StringVar(&opts.Content, "content", loadgen.ContentRandom, "object content: random or compressible")
+	flags.IntVar(&opts.SQLInstances, "sql-instances", 0, "number of Cloud SQL instances")
+	flags.StringVar(&opts.DatabaseVersion, "database-version", "", "database version of the Cloud SQL instances")
+	flags.StringVar(&opts.Prefix, "prefix", loadgen.DefaultPrefix, "prefix of the bucket and instance names")
+	flags.Uint64Var(&opts.Seed, "seed", 0, "seed to repeat a generation with; random if 0")
+	flags.IntVar(&opts.Parallelism, "parallelism", 0, "number of objects created at the same time; the number of CPUs of the mock if 0")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return fmt.Errorf("unexpected argument %q", flags.Arg(0))
+	}
+
+	var result loadgen.Result
+	if err := c.doJSON(http.MethodPost, "/admin/generate", opts, &result); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Generated %d buckets, %d objects (%d bytes) and %d Cloud SQL instances in %s (seed %d)\n",
+		result.Buckets, result.Objects, result.Bytes, result.SQLInstances, result.Duration, result.Seed)
+	return nil
+}
@@ -0,0 +1,103 @@
+// Package main is the entry point for gcpmockctl, a command line client for the admin API of the GCP API Mock.
+// It covers the tasks that CI scripts otherwise do with curl: seeding fixtures, resetting state,
+// listing resources, tailing the request log, controlling latency, the clock and snapshots, importing
+// real buckets, deleting the objects under a prefix and generating data for performance tests.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// command is a gcpmockctl subcommand.
+type command struct {
+	// usage lists the arguments of the command
+	usage string
+	// description is a one-line description for the help output
+	description string
+	run         func(c *client, args []string, stdout io.Writer) error
+}
+
+// commands are the gcpmockctl subcommands by name.
+var commands = map[string]command{
+	"reset":    {"", "Delete all resources", runReset},
+	"seed":     {"<fixtures.json>", "Create the buckets, objects and Cloud SQL instances of a fixtures file", runSeed},
+	"list":     {"", "List buckets, objects and Cloud SQL instances", runList},
+	"logs":     {"[-f]", "Print the request log; -f keeps printing new requests", runLogs},
+	"tick":     {"", "Purge expired soft-deleted objects and finish pending operations now", runTick},
+	"latency":  {"[<profile>|off]", `Show or set the injected latency, e.g. "storage.*=100ms-500ms,sql.insert=2s"`, runLatency},
+	"clock":    {"[freeze|unfreeze|reset|advance <duration>]", "Show or control the virtual clock", runClock},
+	"snapshot": {"<file>", "Save the entire mock state to a file", runSnapshot},
+	"restore":  {"<file>", "Replace the entire mock state with a snapshot file", runRestore},
+	"import":   {"[-to <bucket>] [-metadata-only] gs://<bucket>[/<prefix>]", "Mirror the objects of a real bucket into the mock", runImport},
+	"rm":       {"gs://<bucket>[/<prefix>]", "Delete all objects of a bucket, or only those under a prefix", runRemove},
+	"generate": {"[-buckets N] [-objects M] [-sizes <sizes>] [flags]", "Generate buckets, objects and Cloud SQL instances for performance tests", runGenerate},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run runs gcpmockctl with the given arguments and returns the exit code.
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("gcpmockctl", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	addr := flags.String("addr", getEnv("GCP_MOCK_ADDR", "http://localhost:8080"), "URL of the mock (env GCP_MOCK_ADDR)")
+	project := flags.String("project", getEnv("GCP_MOCK_PROJECT", "mock-project"), "project used for API calls (env GCP_MOCK_PROJECT)")
+	flags.Usage = func() { printUsage(flags, stderr) }
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		printUsage(flags, stderr)
+		return 2
+	}
+
+	cmd, exists := commands[flags.Arg(0)]
+	if !exists {
+		fmt.Fprintf(stderr, "gcpmockctl: unknown command %q\n\n", flags.Arg(0))
+		printUsage(flags, stderr)
+		return 2
+	}
+
+	c := newClient(*addr, *project)
+	if err := cmd.run(c, flags.Args()[1:], stdout); err != nil {
+		fmt.Fprintf(stderr, "gcpmockctl %s: %v\n", flags.Arg(0), err)
+		return 1
+	}
+	return 0
+}
+
+// printUsage prints the help output with all commands.
+func printUsage(flags *flag.FlagSet, w io.Writer) {
+	fmt.Fprintln(w, "Usage: gcpmockctl [flags] <command> [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		cmd := commands[name]
+		fmt.Fprintf(w, "  %-50s %s\n", strings.TrimSpace(name+" "+cmd.usage), cmd.description)
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Flags:")
+	flags.PrintDefaults()
+}
+
+// getEnv retrieves an environment variable or returns a default value.
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/katharinasick/gcp-api-mock/internal/config"
+	"github.com/katharinasick/gcp-api-mock/internal/server"
+)
+
+// startMock starts the mock server for a test and returns its URL.
+func startMock(t *testing.T) string {
+	t.Helper()
+
+	ts := httptest.NewServer(server.New(&config.Config{}).Handler)
+	t.Cleanup(ts.Close)
+	return ts.URL
+}
+
+func TestRun_SeedListAndReset(t *testing.T) {
+	addr := startMock(t)
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "logo.svg"), []byte("<svg/>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fixturesFile := filepath.Join(dir, "fixtures.json")
+	fixtures := `{
+		"buckets": [{"name": "assets", "location": "EU", "objects": [
+			{"name": "config.json", "contentType": "application/json", "content": "{}", "metadata": {"owner": "team-a"}},
+			{"name": "img/logo.svg", "file": "logo.svg"}
+		]}],
+		"sqlInstances": [{"name": "main", "databaseVersion": "POSTGRES_15", "databases": ["app"]}]
+	}`
+	if err := os.WriteFile(fixturesFile, []byte(fixtures), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name             string
+		args             []string
+		expectedCode     int
+		expectedOutput   []string
+		unexpectedOutput []string
+	}{
+		{"seed", []string{"seed", fixturesFile}, 0, []string{"object gs://assets/img/logo.svg", "sql-database main/app"}, nil},
+		{"seed again", []string{"seed", fixturesFile}, 0, []string{"bucket gs://assets"}, nil},
+		{"list", []string{"list"}, 0, []string{"gs://assets/config.json", "6 bytes", "main"}, nil},
+		{"logs", []string{"logs"}, 0, []string{"POST", "/storage/v1/b"}, nil},
+		{"set latency", []string{"latency", "storage.get=1ms"}, 0, []string{"storage.get=1ms"}, nil},
+		{"invalid latency", []string{"latency", "storage.get"}, 1, nil, nil},
+		{"disable latency", []string{"latency", "off"}, 0, []string{"No latency injected"}, nil},
+		{"freeze clock", []string{"clock", "freeze"}, 0, []string{"frozen"}, nil},
+		{"tick", []string{"tick"}, 0, nil, nil},
+		{"rm prefix", []string{"rm", "gs://assets/img/"}, 0, []string{"Deleted 1 objects from gs://assets/img/"}, nil},
+		{"list after rm", []string{"list"}, 0, []string{"gs://assets/config.json"}, []string{"logo.svg"}},
+		{"rm without gs://", []string{"rm", "assets"}, 1, nil, nil},
+		{"reset", []string{"reset"}, 0, []string{"Deleted all resources"}, nil},
+		{"list after reset", []string{"list"}, 0, nil, []string{"assets", "main"}},
+		{"unknown command", []string{"frobnicate"}, 2, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(append([]string{"-addr", addr}, tt.args...), &stdout, &stderr)
+
+			if code != tt.expectedCode {
+				t.Fatalf("expected exit code %d, got %d: %s", tt.expectedCode, code, stderr.String())
+			}
+			for _, expected := range tt.expectedOutput {
+				if !strings.Contains(stdout.String(), expected) {
+					t.Errorf("expected output to contain %q, got:\n%s", expected, stdout.String())
+				}
+			}
+			for _, unexpected := range tt.unexpectedOutput {
+				if strings.Contains(stdout.String(), unexpected) {
+					t.Errorf("expected output not to contain %q, got:\n%s", unexpected, stdout.String())
+				}
+			}
+		})
+	}
+}
+
+func TestRun_SnapshotAndRestore(t *testing.T) {
+	addr := startMock(t)
+	snapshotFile := filepath.Join(t.TempDir(), "snapshot.tar")
+
+	steps := [][]string{
+		{"latency", "off"},
+		{"snapshot", snapshotFile},
+		{"restore", snapshotFile},
+	}
+	for _, args := range steps {
+		var stdout, stderr bytes.Buffer
+		if code := run(append([]string{"-addr", addr}, args...), &stdout, &stderr); code != 0 {
+			t.Fatalf("%s: expected exit code 0, got %d: %s", args[0], code, stderr.String())
+		}
+	}
+}
+
+func TestRun_Import(t *testing.T) {
+	// Another mock stands in for the real Cloud Storage
+	source := startMock(t)
+	addr := startMock(t)
+
+	dir := t.TempDir()
+	fixturesFile := filepath.Join(dir, "fixtures.json")
+	fixtures := `{"buckets": [{"name": "prod-assets", "objects": [
+		{"name": "config.json", "content": "{}"},
+		{"name": "img/logo.svg", "content": "<svg/>"}
+	]}]}`
+	if err := os.WriteFile(fixturesFile, []byte(fixtures), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		args           []string
+		expectedOutput string
+	}{
+		{[]string{"-addr", source, "seed", fixturesFile}, "gs://prod-assets"},
+		{[]string{"-addr", addr, "import", "-endpoint", source, "-to", "local", "gs://prod-assets/img/"}, "Imported 1 objects (6 bytes) into gs://local"},
+		{[]string{"-addr", addr, "list"}, "gs://local/img/logo.svg"},
+	}
+	for _, step := range steps {
+		var stdout, stderr bytes.Buffer
+		if code := run(step.args, &stdout, &stderr); code != 0 {
+			t.Fatalf("%v: expected exit code 0, got %d: %s", step.args, code, stderr.String())
+		}
+		if !strings.Contains(stdout.String(), step.expectedOutput) {
+			t.Errorf("%v: expected output to contain %q, got:\n%s", step.args, step.expectedOutput, stdout.String())
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-addr", addr, "import", "prod-assets"}, &stdout, &stderr); code != 1 {
+		t.Errorf("expected exit code 1 for a source without gs://, got %d", code)
+	}
+}
+
+func TestRun_Generate(t *testing.T) {
+	addr := startMock(t)
+
+	steps := []struct {
+		args           []string
+		expectedOutput string
+	}{
+		{[]string{"generate", "-buckets", "2", "-objects", "5", "-sizes", "100", "-sql-instances", "1", "-seed", "3"}, "Generated 2 buckets, 10 objects (1000 bytes) and 1 Cloud SQL instances"},
+		{[]string{"list"}, "gs://loadtest-0001/object-0004"},
+	}
+	for _, step := range steps {
+		var stdout, stderr bytes.Buffer
+		if code := run(append([]string{"-addr", addr}, step.args...), &stdout, &stderr); code != 0 {
+			t.Fatalf("%v: expected exit code 0, got %d: %s", step.args, code, stderr.String())
+		}
+		if !strings.Contains(stdout.String(), step.expectedOutput) {
+			t.Errorf("%v: expected output to contain %q, got:\n%s", step.args, step.expectedOutput, stdout.String())
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-addr", addr, "generate", "-content", "zeros"}, &stdout, &stderr); code != 1 {
+		t.Errorf("expected exit code 1 for unknown content, got %d", code)
+	}
+}
+
+func TestParseLatencyProfile(t *testing.T) {
+	profile, err := parseLatencyProfile("storage.get=20ms-80ms, sql.*=2s")
+	if err != nil {
+		t.Fatalf("parseLatencyProfile() error = %v", err)
+	}
+	if profile["storage.get"] != "20ms-80ms" || profile["sql.*"] != "2s" {
+		t.Errorf("unexpected profile: %v", profile)
+	}
+
+	if _, err := parseLatencyProfile("storage.get"); err == nil {
+		t.Error("expected error for entry without latency")
+	}
+}
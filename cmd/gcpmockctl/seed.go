@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/katharinasick/gcp-api-mock/internal/sqladmin"
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+)
+
+// fixtures describes the resources created by "gcpmockctl seed".
+//
+//	{
+//	  "buckets": [{"name": "assets", "objects": [{"name": "config.json", "content": "{}"}, {"name": "logo.png", "file": "logo.png"}]}],
+//	  "sqlInstances": [{"name": "main", "databaseVersion": "POSTGRES_15", "databases": ["app"]}]
+//	}
+type fixtures struct {
+	Buckets      []bucketFixture      `json:"buckets"`
+	SQLInstances []sqlInstanceFixture `json:"sqlInstances"`
+}
+
+// bucketFixture is a bucket resource with the objects to upload to it.
+type bucketFixture struct {
+	storage.BucketInsertRequest
+	Objects []objectFixture `json:"objects,omitempty"`
+}
+
+// objectFixture is an object to upload. Its content is either given inline or read from a file,
+// relative to the fixtures file.
+type objectFixture struct {
+	Name        string            `json:"name"`
+	ContentType string            `json:"contentType,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	Content     string            `json:"content,omitempty"`
+	File        string            `json:"file,omitempty"`
+}
+
+// sqlInstanceFixture is a Cloud SQL instance resource with the databases to create in it.
+type sqlInstanceFixture struct {
+	sqladmin.InstanceInsertRequest
+	Databases []string `json:"databases,omitempty"`
+}
+
+// runSeed creates the resources of a fixtures file. Buckets, instances and databases that
+// already exist are kept and objects are overwritten, so seeding twice is safe.
+func runSeed(c *client, args []string, stdout io.Writer) error {
+	if len(args) != 1 {
+		return errors.New("expected a fixtures file argument")
+	}
+
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	var f fixtures
+	if err := json.Unmarshal(data, &f); err != nil {
+		return fmt.Errorf("invalid fixtures file %s: %w", args[0], err)
+	}
+
+	return c.seed(&f, filepath.Dir(args[0]), stdout)
+}
+
+// seed creates the resources of f. Object files are read relative to dir.
+func (c *client) seed(f *fixtures, dir string, stdout io.Writer) error {
+	for _, bucket := range f.Buckets {
+		err := c.doJSON(http.MethodPost, "/storage/v1/b?project="+url.QueryEscape(c.project), bucket.BucketInsertRequest, nil)
+		if err != nil && !isStatus(err, http.StatusConflict) {
+			return fmt.Errorf("failed to create bucket %s: %w", bucket.Name, err)
+		}
+		fmt.Fprintf(stdout, "bucket gs://%s\n", bucket.Name)
+
+		for _, obj := range bucket.Objects {
+			if err := c.uploadObject(bucket.Name, obj, dir); err != nil {
+				return fmt.Errorf("failed to upload gs://%s/%s: %w", bucket.Name, obj.Name, err)
+			}
+			fmt.Fprintf(stdout, "object gs://%s/%s\n", bucket.Name, obj.Name)
+		}
+	}
+
+	instancesPath := "/sql/v1beta4/projects/" + url.PathEscape(c.project) + "/instances"
+	for _, instance := range f.SQLInstances {
+		err := c.doJSON(http.MethodPost, instancesPath, instance.InstanceInsertRequest, nil)
+		if err != nil && !isStatus(err, http.StatusConflict) {
+			return fmt.Errorf("failed to create Cloud SQL instance %s: %w", instance.Name, err)
+		}
+		fmt.Fprintf(stdout, "sql-instance %s\n", instance.Name)
+
+		for _, database := range instance.Databases {
+			path := instancesPath + "/" + url.PathEscape(instance.Name) + "/databases"
+			err := c.doJSON(http.MethodPost, path, sqladmin.DatabaseInsertRequest{Name: database}, nil)
+			if err != nil && !isStatus(err, http.StatusConflict) {
+				return fmt.Errorf("failed to create database %s in %s: %w", database, instance.Name, err)
+			}
+			fmt.Fprintf(stdout, "sql-database %s/%s\n", instance.Name, database)
+		}
+	}
+
+	return nil
+}
+
+// uploadObject uploads an object fixture with a simple media upload.
+func (c *client) uploadObject(bucketName string, obj objectFixture, dir string) error {
+	if obj.Name == "" {
+		return errors.New("object name is required")
+	}
+
+	var content io.Reader = strings.NewReader(obj.Content)
+	if obj.File != "" {
+		path := obj.File
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		content = bytes.NewReader(data)
+	}
+
+	contentType := obj.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	path := "/upload/storage/v1/b/" + url.PathEscape(bucketName) + "/o?uploadType=media&name=" + url.QueryEscape(obj.Name)
+	req, err := http.NewRequest(http.MethodPost, c.baseURL+path, content)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for key, value := range obj.Metadata {
+		req.Header.Set("X-Goog-Meta-"+key, value)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return responseError(resp)
+	}
+	return nil
+}
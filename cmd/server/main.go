@@ -3,28 +3,61 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/katharinasick/gcp-api-mock/internal/capabilities"
 	"github.com/katharinasick/gcp-api-mock/internal/config"
 	"github.com/katharinasick/gcp-api-mock/internal/server"
 )
 
 func main() {
+	validateOnly := flag.Bool("validate-config", false, "check the configuration from the GCP_MOCK_* environment variables and exit")
+	flag.Parse()
+
 	// Load configuration
 	cfg := config.Load()
 
+	// Report every invalid setting at once; the mock still starts, falling back to defaults for them
+	if err := cfg.Validate(); err != nil {
+		if *validateOnly {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		for _, line := range strings.Split(err.Error(), "\n") {
+			log.Print(line)
+		}
+	}
+	if *validateOnly {
+		fmt.Println("Configuration is valid")
+		return
+	}
+
 	// Create and configure server
 	srv := server.New(cfg)
 
+	// Tell what this mock can do, so nobody has to find out by failing requests
+	for _, line := range strings.Split(capabilities.Get(cfg).Summary(), "\n") {
+		log.Print(line)
+	}
+
 	// Start server in a goroutine
 	go func() {
-		log.Printf("Starting GCP API Mock server on %s", cfg.Address())
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Printf("Starting GCP API Mock server on %s (%s)", cfg.Address(), cfg.ExternalURL())
+		var err error
+		if srv.TLSConfig != nil {
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			log.Fatalf("Server error: %v", err)
 		}
 	}()
@@ -36,8 +69,13 @@ func main() {
 
 	log.Println("Shutting down server...")
 
-	// Create shutdown context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	// Create shutdown context with timeout, giving uploads and downloads in flight time to finish
+	timeout, err := time.ParseDuration(cfg.ShutdownTimeout)
+	if err != nil {
+		log.Printf("Invalid shutdown timeout, waiting 30s for transfers in flight: %v", err)
+		timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
 	if err := srv.Shutdown(ctx); err != nil {
@@ -0,0 +1,188 @@
+package auditlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Action is an audited admin action, like creating a bucket or deleting a Cloud SQL user.
+type Action struct {
+	ServiceName  string
+	MethodName   string
+	ResourceName string
+	Project      string
+
+	// ResourceType and Labels describe the monitored resource, e.g. a gcs_bucket with its bucket_name.
+	// The project_id label is added from Project.
+	ResourceType string
+	Labels       map[string]string
+}
+
+// rule maps the requests for an admin action to the action.
+// Segments of pattern in braces match any single path segment and are passed to resource by name.
+type rule struct {
+	method     string
+	pattern    string
+	methodName string
+	resource   func(params map[string]string, r *http.Request, body createBody) Action
+}
+
+// createBody holds the fields of create request bodies that name the created resource.
+type createBody struct {
+	Name     string `json:"name"`
+	Location string `json:"location"`
+	Region   string `json:"region"`
+}
+
+// rules lists the audited admin actions. Only Admin Activity is audited, like in Cloud Audit Logs,
+// so reads and data writes like object uploads or Firestore documents are not.
+var rules = []rule{
+	{"POST", "/storage/v1/b", "storage.buckets.create", bucketResource},
+	{"PUT", "/storage/v1/b/{bucket}", "storage.buckets.update", bucketResource},
+	{"PATCH", "/storage/v1/b/{bucket}", "storage.buckets.update", bucketResource},
+	{"DELETE", "/storage/v1/b/{bucket}", "storage.buckets.delete", bucketResource},
+	{"PUT", "/storage/v1/b/{bucket}/iam", "storage.setIamPermissions", bucketResource},
+	{"POST", "/storage/v1/b/{bucket}/notificationConfigs", "storage.notifications.insert", bucketResource},
+	{"DELETE", "/storage/v1/b/{bucket}/notificationConfigs/{notification}", "storage.notifications.delete", bucketResource},
+
+	{"POST", "/sql/v1beta4/projects/{project}/instances", "cloudsql.instances.create", sqlInstanceResource},
+	{"PATCH", "/sql/v1beta4/projects/{project}/instances/{instance}", "cloudsql.instances.update", sqlInstanceResource},
+	{"DELETE", "/sql/v1beta4/projects/{project}/instances/{instance}", "cloudsql.instances.delete", sqlInstanceResource},
+	{"POST", "/sql/v1beta4/projects/{project}/instances/{instance}/restart", "cloudsql.instances.restart", sqlInstanceResource},
+	{"POST", "/sql/v1beta4/projects/{project}/instances/{instance}/promoteReplica", "cloudsql.instances.promoteReplica", sqlInstanceResource},
+	{"POST", "/sql/v1beta4/projects/{project}/instances/{instance}/databases", "cloudsql.databases.create", sqlInstanceResource},
+	{"PATCH", "/sql/v1beta4/projects/{project}/instances/{instance}/databases/{database}", "cloudsql.databases.update", sqlInstanceResource},
+	{"DELETE", "/sql/v1beta4/projects/{project}/instances/{instance}/databases/{database}", "cloudsql.databases.delete", sqlInstanceResource},
+	{"POST", "/sql/v1beta4/projects/{project}/instances/{instance}/users", "cloudsql.users.create", sqlInstanceResource},
+	{"PUT", "/sql/v1beta4/projects/{project}/instances/{instance}/users", "cloudsql.users.update", sqlInstanceResource},
+	{"DELETE", "/sql/v1beta4/projects/{project}/instances/{instance}/users", "cloudsql.users.delete", sqlInstanceResource},
+
+	{"POST", "/v2/projects/{project}/locations/{location}/services", "google.cloud.run.v2.Services.CreateService", runServiceResource},
+	{"PATCH", "/v2/projects/{project}/locations/{location}/services/{service}", "google.cloud.run.v2.Services.UpdateService", runServiceResource},
+	{"DELETE", "/v2/projects/{project}/locations/{location}/services/{service}", "google.cloud.run.v2.Services.DeleteService", runServiceResource},
+}
+
+// Classify returns the admin action a request performs, if it performs one.
+// The body of create requests is read to name the created resource and then restored for the handler.
+func Classify(r *http.Request) (Action, bool) {
+	path := r.URL.Path
+	// /b/... is the Cloud Storage JSON API without its /storage/v1 prefix
+	if path == "/b" || strings.HasPrefix(path, "/b/") {
+		path = "/storage/v1" + path
+	}
+
+	for _, rule := range rules {
+		if rule.method != r.Method {
+			continue
+		}
+		params, ok := match(rule.pattern, path)
+		if !ok {
+			continue
+		}
+
+		var body createBody
+		if rule.method == http.MethodPost && r.Body != nil {
+			data, err := io.ReadAll(r.Body)
+			if err == nil {
+				r.Body = io.NopCloser(bytes.NewReader(data))
+				_ = json.Unmarshal(data, &body)
+			}
+		}
+
+		action := rule.resource(params, r, body)
+		action.MethodName = rule.methodName
+		return action, true
+	}
+
+	return Action{}, false
+}
+
+// match matches a path against a pattern and returns the values of its {name} segments.
+func match(pattern, path string) (map[string]string, bool) {
+	patternSegments := strings.Split(pattern, "/")
+	pathSegments := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	if len(patternSegments) != len(pathSegments) {
+		return nil, false
+	}
+
+	params := make(map[string]string)
+	for i, segment := range patternSegments {
+		if name, found := strings.CutPrefix(segment, "{"); found {
+			if pathSegments[i] == "" {
+				return nil, false
+			}
+			params[strings.TrimSuffix(name, "}")] = pathSegments[i]
+			continue
+		}
+		if segment != pathSegments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// bucketResource describes a Cloud Storage bucket. Bucket inserts name the bucket in the body
+// and the project in the project query parameter.
+func bucketResource(params map[string]string, r *http.Request, body createBody) Action {
+	bucket := params["bucket"]
+	labels := map[string]string{}
+	if bucket == "" {
+		bucket = body.Name
+		if body.Location != "" {
+			labels["location"] = strings.ToLower(body.Location)
+		}
+	}
+	labels["bucket_name"] = bucket
+
+	return Action{
+		ServiceName:  "storage.googleapis.com",
+		ResourceName: "projects/_/buckets/" + bucket,
+		Project:      r.URL.Query().Get("project"),
+		ResourceType: "gcs_bucket",
+		Labels:       labels,
+	}
+}
+
+// sqlInstanceResource describes a Cloud SQL instance. Databases and users are audited against their instance,
+// like Cloud SQL does. Instance inserts name the instance and its region in the body.
+func sqlInstanceResource(params map[string]string, _ *http.Request, body createBody) Action {
+	instance := params["instance"]
+	labels := map[string]string{}
+	if instance == "" {
+		instance = body.Name
+		if body.Region != "" {
+			labels["region"] = body.Region
+		}
+	}
+	labels["database_id"] = params["project"] + ":" + instance
+
+	return Action{
+		ServiceName:  "cloudsql.googleapis.com",
+		ResourceName: "projects/" + params["project"] + "/instances/" + instance,
+		Project:      params["project"],
+		ResourceType: "cloudsql_database",
+		Labels:       labels,
+	}
+}
+
+// runServiceResource describes a Cloud Run service. Service creates name the service in the serviceId query parameter.
+func runServiceResource(params map[string]string, r *http.Request, _ createBody) Action {
+	service := params["service"]
+	if service == "" {
+		service = r.URL.Query().Get("serviceId")
+	}
+
+	return Action{
+		ServiceName:  "run.googleapis.com",
+		ResourceName: "projects/" + params["project"] + "/locations/" + params["location"] + "/services/" + service,
+		Project:      params["project"],
+		ResourceType: "cloud_run_revision",
+		Labels: map[string]string{
+			"service_name": service,
+			"location":     params["location"],
+		},
+	}
+}
@@ -0,0 +1,234 @@
+// Package auditlog emits Cloud Audit Logs entries for the admin actions of the GCP API Mock,
+// so pipelines that parse audit logs can be tested against realistic entries.
+package auditlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultProject is used in log names when a request doesn't name its project.
+const defaultProject = "mock-project"
+
+// LogEntry is a Cloud Logging entry carrying an audit log in its protoPayload.
+// Reference: https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry
+type LogEntry struct {
+	LogName          string            `json:"logName"`
+	Resource         MonitoredResource `json:"resource"`
+	ProtoPayload     AuditLog          `json:"protoPayload"`
+	InsertID         string            `json:"insertId"`
+	Timestamp        time.Time         `json:"timestamp"`
+	ReceiveTimestamp time.Time         `json:"receiveTimestamp"`
+	Severity         string            `json:"severity"`
+}
+
+// MonitoredResource is the resource a log entry is about, e.g. a gcs_bucket.
+type MonitoredResource struct {
+	Type   string            `json:"type"`
+	Labels map[string]string `json:"labels"`
+}
+
+// AuditLog is the payload of an audit log entry.
+// Reference: https://cloud.google.com/logging/docs/reference/audit/auditlog/rest/Shared.Types/AuditLog
+type AuditLog struct {
+	Type               string             `json:"@type"`
+	Status             Status             `json:"status"`
+	AuthenticationInfo AuthenticationInfo `json:"authenticationInfo"`
+	RequestMetadata    RequestMetadata    `json:"requestMetadata"`
+	ServiceName        string             `json:"serviceName"`
+	MethodName         string             `json:"methodName"`
+	ResourceName       string             `json:"resourceName"`
+}
+
+// Status is the outcome of the audited operation. Successful operations have an empty status.
+type Status struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// AuthenticationInfo identifies the caller.
+type AuthenticationInfo struct {
+	PrincipalEmail string `json:"principalEmail,omitempty"`
+}
+
+// RequestMetadata describes the request of the audited operation.
+type RequestMetadata struct {
+	CallerIP                string `json:"callerIp,omitempty"`
+	CallerSuppliedUserAgent string `json:"callerSuppliedUserAgent,omitempty"`
+}
+
+// Logger writes audit log entries to a file as JSON lines and/or posts them to a URL.
+// It is safe for concurrent access.
+type Logger struct {
+	mu       sync.Mutex
+	file     *os.File
+	enc      *json.Encoder
+	url      string
+	client   *http.Client
+	insertID atomic.Int64
+}
+
+// New creates a Logger that appends entries to the file at path and posts them to url.
+// Either may be empty to skip that destination.
+func New(path, url string) (*Logger, error) {
+	l := &Logger{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+
+	if path != "" {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open audit log file: %w", err)
+		}
+		l.file = file
+		l.enc = json.NewEncoder(file)
+	}
+
+	return l, nil
+}
+
+// Close closes the audit log file.
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	l.enc = nil
+	return err
+}
+
+// Log builds the entry for an audited action and writes it. Posting to the URL happens asynchronously.
+// statusCode is the HTTP status the action was answered with and message its error message, if it failed.
+func (l *Logger) Log(action Action, r *http.Request, principal string, statusCode int, message string) {
+	entry := l.buildEntry(action, r, principal, statusCode, message)
+
+	l.mu.Lock()
+	if l.enc != nil {
+		if err := l.enc.Encode(entry); err != nil {
+			log.Printf("audit log: failed to write entry: %v", err)
+		}
+	}
+	l.mu.Unlock()
+
+	if l.url != "" {
+		go l.post(entry)
+	}
+}
+
+// buildEntry builds the log entry for an audited action.
+func (l *Logger) buildEntry(action Action, r *http.Request, principal string, statusCode int, message string) *LogEntry {
+	now := time.Now().UTC()
+
+	project := action.Project
+	if project == "" {
+		project = defaultProject
+	}
+	labels := map[string]string{"project_id": project}
+	for k, v := range action.Labels {
+		labels[k] = v
+	}
+
+	entry := &LogEntry{
+		LogName:  "projects/" + project + "/logs/cloudaudit.googleapis.com%2Factivity",
+		Resource: MonitoredResource{Type: action.ResourceType, Labels: labels},
+		ProtoPayload: AuditLog{
+			Type:               "type.googleapis.com/google.cloud.audit.AuditLog",
+			AuthenticationInfo: AuthenticationInfo{PrincipalEmail: principal},
+			RequestMetadata: RequestMetadata{
+				CallerIP:                callerIP(r),
+				CallerSuppliedUserAgent: r.UserAgent(),
+			},
+			ServiceName:  action.ServiceName,
+			MethodName:   action.MethodName,
+			ResourceName: action.ResourceName,
+		},
+		InsertID:         fmt.Sprintf("%x%d", now.Unix(), l.insertID.Add(1)),
+		Timestamp:        now,
+		ReceiveTimestamp: now,
+		Severity:         "NOTICE",
+	}
+
+	if statusCode >= 400 {
+		entry.Severity = "ERROR"
+		entry.ProtoPayload.Status = Status{Code: rpcCode(statusCode), Message: message}
+		if entry.ProtoPayload.Status.Message == "" {
+			entry.ProtoPayload.Status.Message = http.StatusText(statusCode)
+		}
+	}
+
+	return entry
+}
+
+// post sends an entry to the URL.
+func (l *Logger) post(entry *LogEntry) {
+	body, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("audit log: failed to encode entry: %v", err)
+		return
+	}
+
+	resp, err := l.client.Post(l.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Printf("audit log: failed to deliver to %s: %v", l.url, err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		log.Printf("audit log: %s returned status %d", l.url, resp.StatusCode)
+	}
+}
+
+// callerIP returns the IP address of the client, preferring X-Forwarded-For when behind a proxy.
+func callerIP(r *http.Request) string {
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		ip, _, _ := strings.Cut(forwarded, ",")
+		return strings.TrimSpace(ip)
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// rpcCode maps an HTTP status code to the google.rpc.Code audit logs report.
+// Reference: https://github.com/googleapis/googleapis/blob/master/google/rpc/code.proto
+func rpcCode(statusCode int) int {
+	switch statusCode {
+	case http.StatusBadRequest:
+		return 3 // INVALID_ARGUMENT
+	case http.StatusUnauthorized:
+		return 16 // UNAUTHENTICATED
+	case http.StatusForbidden:
+		return 7 // PERMISSION_DENIED
+	case http.StatusNotFound:
+		return 5 // NOT_FOUND
+	case http.StatusConflict:
+		return 6 // ALREADY_EXISTS
+	case http.StatusPreconditionFailed:
+		return 9 // FAILED_PRECONDITION
+	case http.StatusTooManyRequests:
+		return 8 // RESOURCE_EXHAUSTED
+	case http.StatusNotImplemented:
+		return 12 // UNIMPLEMENTED
+	case http.StatusServiceUnavailable:
+		return 14 // UNAVAILABLE
+	default:
+		return 13 // INTERNAL
+	}
+}
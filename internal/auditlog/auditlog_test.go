@@ -0,0 +1,153 @@
+package auditlog
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		method       string
+		target       string
+		body         string
+		wantOK       bool
+		wantMethod   string
+		wantResource string
+		wantProject  string
+	}{
+		{http.MethodPost, "/storage/v1/b?project=p", `{"name":"logs","location":"EU"}`, true, "storage.buckets.create", "projects/_/buckets/logs", "p"},
+		{http.MethodPatch, "/b/logs", "", true, "storage.buckets.update", "projects/_/buckets/logs", ""},
+		{http.MethodDelete, "/storage/v1/b/logs", "", true, "storage.buckets.delete", "projects/_/buckets/logs", ""},
+		{http.MethodPost, "/sql/v1beta4/projects/p/instances", `{"name":"main","region":"us-central1"}`, true, "cloudsql.instances.create", "projects/p/instances/main", "p"},
+		{http.MethodPost, "/sql/v1beta4/projects/p/instances/main/users", `{"name":"app"}`, true, "cloudsql.users.create", "projects/p/instances/main", "p"},
+		{http.MethodDelete, "/sql/v1beta4/projects/p/instances/main/databases/app", "", true, "cloudsql.databases.delete", "projects/p/instances/main", "p"},
+		{http.MethodPost, "/v2/projects/p/locations/europe-west1/services?serviceId=api", `{}`, true, "google.cloud.run.v2.Services.CreateService", "projects/p/locations/europe-west1/services/api", "p"},
+		{http.MethodGet, "/storage/v1/b/logs", "", false, "", "", ""},
+		{http.MethodPost, "/upload/storage/v1/b/logs/o", "data", false, "", "", ""},
+		{http.MethodPatch, "/v1/projects/p/databases/(default)/documents/users/alice", "{}", false, "", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.target, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			action, ok := Classify(req)
+			if ok != tt.wantOK {
+				t.Fatalf("Classify() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if action.MethodName != tt.wantMethod {
+				t.Errorf("MethodName = %q, want %q", action.MethodName, tt.wantMethod)
+			}
+			if action.ResourceName != tt.wantResource {
+				t.Errorf("ResourceName = %q, want %q", action.ResourceName, tt.wantResource)
+			}
+			if action.Project != tt.wantProject {
+				t.Errorf("Project = %q, want %q", action.Project, tt.wantProject)
+			}
+
+			// The handler must still be able to read the body
+			body, _ := io.ReadAll(req.Body)
+			if string(body) != tt.body {
+				t.Errorf("body after Classify() = %q, want %q", body, tt.body)
+			}
+		})
+	}
+}
+
+func TestLogger_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	logger, err := New(path, "")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/storage/v1/b?project=p", strings.NewReader(`{"name":"logs"}`))
+	req.Header.Set("User-Agent", "terraform")
+	action, _ := Classify(req)
+	logger.Log(action, req, "ci@p.iam.gserviceaccount.com", http.StatusOK, "")
+	logger.Log(action, req, "", http.StatusConflict, "Your previous request to create the named bucket succeeded and you already own it.")
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read audit log: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 entries, got %d: %s", len(lines), data)
+	}
+
+	var entry LogEntry
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("failed to parse entry: %v", err)
+	}
+	if entry.LogName != "projects/p/logs/cloudaudit.googleapis.com%2Factivity" {
+		t.Errorf("logName = %q", entry.LogName)
+	}
+	if entry.ProtoPayload.Type != "type.googleapis.com/google.cloud.audit.AuditLog" {
+		t.Errorf("@type = %q", entry.ProtoPayload.Type)
+	}
+	if entry.Resource.Type != "gcs_bucket" || entry.Resource.Labels["bucket_name"] != "logs" || entry.Resource.Labels["project_id"] != "p" {
+		t.Errorf("resource = %+v", entry.Resource)
+	}
+	if entry.ProtoPayload.AuthenticationInfo.PrincipalEmail != "ci@p.iam.gserviceaccount.com" {
+		t.Errorf("principalEmail = %q", entry.ProtoPayload.AuthenticationInfo.PrincipalEmail)
+	}
+	if entry.ProtoPayload.RequestMetadata.CallerSuppliedUserAgent != "terraform" || entry.ProtoPayload.RequestMetadata.CallerIP != "192.0.2.1" {
+		t.Errorf("requestMetadata = %+v", entry.ProtoPayload.RequestMetadata)
+	}
+	if entry.Severity != "NOTICE" || entry.ProtoPayload.Status != (Status{}) {
+		t.Errorf("severity = %q, status = %+v, want NOTICE and an empty status", entry.Severity, entry.ProtoPayload.Status)
+	}
+
+	if err := json.Unmarshal([]byte(lines[1]), &entry); err != nil {
+		t.Fatalf("failed to parse entry: %v", err)
+	}
+	if entry.Severity != "ERROR" || entry.ProtoPayload.Status.Code != 6 {
+		t.Errorf("severity = %q, status = %+v, want ERROR and ALREADY_EXISTS", entry.Severity, entry.ProtoPayload.Status)
+	}
+}
+
+func TestLogger_URL(t *testing.T) {
+	received := make(chan LogEntry, 1)
+	sink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var entry LogEntry
+		if err := json.NewDecoder(r.Body).Decode(&entry); err != nil {
+			t.Errorf("failed to decode posted entry: %v", err)
+		}
+		received <- entry
+	}))
+	defer sink.Close()
+
+	logger, err := New("", sink.URL)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodDelete, "/sql/v1beta4/projects/p/instances/main/users?name=app", nil)
+	action, _ := Classify(req)
+	logger.Log(action, req, "", http.StatusOK, "")
+
+	select {
+	case entry := <-received:
+		if entry.ProtoPayload.MethodName != "cloudsql.users.delete" || entry.ProtoPayload.ServiceName != "cloudsql.googleapis.com" {
+			t.Errorf("protoPayload = %+v", entry.ProtoPayload)
+		}
+		if entry.Resource.Labels["database_id"] != "p:main" {
+			t.Errorf("resource labels = %v", entry.Resource.Labels)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no entry was posted")
+	}
+}
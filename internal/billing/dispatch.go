@@ -0,0 +1,78 @@
+package billing
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/notification"
+)
+
+// Dispatcher publishes budget notifications to the Pub/Sub topics of budgets.
+// Topics that are http(s) URLs receive a Pub/Sub push-style POST request,
+// all other topics are logged since there is no Pub/Sub mock to publish to.
+type Dispatcher struct {
+	client    *http.Client
+	messageID atomic.Int64
+}
+
+// NewDispatcher creates a new Dispatcher.
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Publish sends a notification of a budget to its topic and returns the published message.
+// Unlike other notifications, it waits for webhook topics to respond, so tests can check the outcome right away.
+// Reference: https://cloud.google.com/billing/docs/how-to/budgets-programmatic-notifications#notification_format
+func (d *Dispatcher) Publish(budget *Budget, n *Notification) (*notification.PushMessage, error) {
+	data, err := json.Marshal(n)
+	if err != nil {
+		return nil, err
+	}
+
+	// Budget names are billingAccounts/{billingAccount}/budgets/{budget}
+	parts := strings.Split(budget.Name, "/")
+	msg := &notification.PushMessage{
+		Attributes: map[string]string{
+			"billingAccountId": parts[1],
+			"budgetId":         parts[3],
+			"schemaVersion":    SchemaVersion,
+		},
+		Data:        base64.StdEncoding.EncodeToString(data),
+		MessageID:   fmt.Sprintf("%d", d.messageID.Add(1)),
+		PublishTime: time.Now().UTC(),
+	}
+
+	topic := budget.NotificationsRule.PubsubTopic
+	if !strings.HasPrefix(topic, "http://") && !strings.HasPrefix(topic, "https://") {
+		log.Printf("budget %s: %.2f of %.2f %s spent (topic %s)", budget.Name, n.CostAmount, n.BudgetAmount, n.CurrencyCode, topic)
+		return msg, nil
+	}
+
+	body, err := json.Marshal(notification.PushRequest{
+		Message:      *msg,
+		Subscription: "projects/mock-project/subscriptions/gcp-api-mock-budget-" + parts[3],
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := d.client.Post(topic, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to deliver to %s: %w", topic, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("webhook %s returned status %d", topic, resp.StatusCode)
+	}
+	return msg, nil
+}
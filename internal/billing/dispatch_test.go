@@ -0,0 +1,60 @@
+package billing
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/katharinasick/gcp-api-mock/internal/notification"
+)
+
+func TestDispatcher_Publish(t *testing.T) {
+	var received notification.PushRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("failed to decode push request: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	budget := &Budget{
+		Name:              "billingAccounts/012345-6789AB-CDEF01/budgets/budget-1",
+		NotificationsRule: &NotificationsRule{PubsubTopic: server.URL},
+	}
+	if _, err := NewDispatcher().Publish(budget, &Notification{BudgetDisplayName: "monthly", CostAmount: 42}); err != nil {
+		t.Fatalf("Publish() error: %v", err)
+	}
+
+	attributes := received.Message.Attributes
+	if attributes["billingAccountId"] != "012345-6789AB-CDEF01" || attributes["budgetId"] != "budget-1" || attributes["schemaVersion"] != SchemaVersion {
+		t.Errorf("unexpected attributes: %v", attributes)
+	}
+	data, err := base64.StdEncoding.DecodeString(received.Message.Data)
+	if err != nil {
+		t.Fatalf("failed to decode data: %v", err)
+	}
+	var n Notification
+	if err := json.Unmarshal(data, &n); err != nil {
+		t.Fatalf("failed to decode notification: %v", err)
+	}
+	if n.BudgetDisplayName != "monthly" || n.CostAmount != 42 {
+		t.Errorf("unexpected notification: %+v", n)
+	}
+}
+
+func TestDispatcher_Publish_WebhookError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	budget := &Budget{
+		Name:              "billingAccounts/012345-6789AB-CDEF01/budgets/budget-1",
+		NotificationsRule: &NotificationsRule{PubsubTopic: server.URL},
+	}
+	if _, err := NewDispatcher().Publish(budget, &Notification{}); err == nil {
+		t.Error("expected an error for a failing webhook")
+	}
+}
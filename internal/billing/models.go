@@ -0,0 +1,138 @@
+// Package billing provides data models and budget alert delivery for the Cloud Billing Budget API (v1) mock.
+package billing
+
+import (
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/notification"
+)
+
+// Spend bases of threshold rules.
+const (
+	SpendBasisCurrent    = "CURRENT_SPEND"
+	SpendBasisForecasted = "FORECASTED_SPEND"
+)
+
+// Budget amount types of budget notifications.
+const (
+	AmountTypeSpecified  = "SPECIFIED_AMOUNT"
+	AmountTypeLastPeriod = "LAST_PERIODS_AMOUNT"
+)
+
+// SchemaVersion is the only schema version of budget notifications.
+const SchemaVersion = "1.0"
+
+// Budget is a budget of a billing account.
+// Reference: https://cloud.google.com/billing/docs/reference/budget/rest/v1/billingAccounts.budgets
+type Budget struct {
+	// Name is the resource name, e.g. billingAccounts/{billingAccount}/budgets/{budget}.
+	Name string `json:"name,omitempty"`
+	// DisplayName is a user-provided name for the budget.
+	DisplayName string `json:"displayName,omitempty"`
+	// BudgetFilter limits the costs the budget tracks.
+	BudgetFilter *Filter `json:"budgetFilter,omitempty"`
+	// Amount is the amount of the budget.
+	Amount *BudgetAmount `json:"amount,omitempty"`
+	// ThresholdRules are the spend percentages that trigger alerts.
+	ThresholdRules []*ThresholdRule `json:"thresholdRules,omitempty"`
+	// NotificationsRule configures where alerts are sent.
+	NotificationsRule *NotificationsRule `json:"notificationsRule,omitempty"`
+	// Etag changes whenever the budget changes.
+	Etag string `json:"etag,omitempty"`
+}
+
+// Filter limits the costs a budget tracks.
+type Filter struct {
+	// Projects limits the budget to projects, e.g. projects/123456789.
+	Projects []string `json:"projects,omitempty"`
+	// CreditTypesTreatment defines how credits are applied, INCLUDE_ALL_CREDITS by default.
+	CreditTypesTreatment string `json:"creditTypesTreatment,omitempty"`
+	// Services limits the budget to services, e.g. services/24E6-581D-38E5.
+	Services []string `json:"services,omitempty"`
+	// Labels limits the budget to costs with labels.
+	Labels map[string][]string `json:"labels,omitempty"`
+	// CalendarPeriod is the period the budget is tracked over: MONTH (the default), QUARTER or YEAR.
+	CalendarPeriod string `json:"calendarPeriod,omitempty"`
+}
+
+// BudgetAmount is the amount of a budget. Exactly one of its fields is set.
+type BudgetAmount struct {
+	// SpecifiedAmount is a fixed amount.
+	SpecifiedAmount *Money `json:"specifiedAmount,omitempty"`
+	// LastPeriodAmount uses the spend of the last period as the amount.
+	LastPeriodAmount *LastPeriodAmount `json:"lastPeriodAmount,omitempty"`
+}
+
+// LastPeriodAmount uses the spend of the last period as the amount of a budget. It has no fields.
+type LastPeriodAmount struct{}
+
+// Money is an amount of money in a currency.
+// Reference: https://cloud.google.com/billing/docs/reference/budget/rest/v1/billingAccounts.budgets#Money
+type Money struct {
+	// CurrencyCode is the three-letter ISO 4217 currency code, e.g. USD.
+	CurrencyCode string `json:"currencyCode,omitempty"`
+	// Units are the whole units of the amount, an int64 as a string.
+	Units string `json:"units,omitempty"`
+	// Nanos are the nano units of the amount.
+	Nanos int32 `json:"nanos,omitempty"`
+}
+
+// ThresholdRule triggers an alert when the spend exceeds a percentage of the budget.
+type ThresholdRule struct {
+	// ThresholdPercent is the percentage of the budget, e.g. 0.5 for 50%.
+	ThresholdPercent float64 `json:"thresholdPercent"`
+	// SpendBasis is CURRENT_SPEND (the default) or FORECASTED_SPEND.
+	SpendBasis string `json:"spendBasis,omitempty"`
+}
+
+// NotificationsRule configures where the alerts of a budget are sent.
+type NotificationsRule struct {
+	// PubsubTopic is the topic notifications are published to, e.g. projects/{project}/topics/{topic}.
+	// The mock also accepts an http(s) URL, which receives Pub/Sub push-style requests.
+	PubsubTopic string `json:"pubsubTopic,omitempty"`
+	// SchemaVersion is the schema of the notifications, always 1.0.
+	SchemaVersion string `json:"schemaVersion,omitempty"`
+	// MonitoringNotificationChannels are Cloud Monitoring channels that get alerts. The mock doesn't send to them.
+	MonitoringNotificationChannels []string `json:"monitoringNotificationChannels,omitempty"`
+	// DisableDefaultIamRecipients stops emails to the billing account admins and users.
+	DisableDefaultIamRecipients bool `json:"disableDefaultIamRecipients,omitempty"`
+}
+
+// ListBudgetsResponse is the response of budgets.list.
+type ListBudgetsResponse struct {
+	Budgets       []*Budget `json:"budgets,omitempty"`
+	NextPageToken string    `json:"nextPageToken,omitempty"`
+}
+
+// Notification is the data of a budget notification published to the Pub/Sub topic of a budget.
+// Reference: https://cloud.google.com/billing/docs/how-to/budgets-programmatic-notifications#notification_format
+type Notification struct {
+	BudgetDisplayName string `json:"budgetDisplayName"`
+	// AlertThresholdExceeded is the highest current spend threshold that was exceeded, if any.
+	AlertThresholdExceeded float64 `json:"alertThresholdExceeded,omitempty"`
+	// ForecastThresholdExceeded is the highest forecasted spend threshold that was exceeded, if any.
+	ForecastThresholdExceeded float64   `json:"forecastThresholdExceeded,omitempty"`
+	CostAmount                float64   `json:"costAmount"`
+	CostIntervalStart         time.Time `json:"costIntervalStart"`
+	BudgetAmount              float64   `json:"budgetAmount"`
+	BudgetAmountType          string    `json:"budgetAmountType"`
+	CurrencyCode              string    `json:"currencyCode"`
+}
+
+// AlertRequest is the body of the admin request that simulates a budget notification.
+type AlertRequest struct {
+	// CostAmount is the cost so far in the current period.
+	CostAmount float64 `json:"costAmount"`
+	// ForecastedAmount is the forecasted cost of the period, checked against FORECASTED_SPEND thresholds.
+	ForecastedAmount float64 `json:"forecastedAmount,omitempty"`
+	// BudgetAmount is the spend of the last period, required for budgets with lastPeriodAmount.
+	BudgetAmount float64 `json:"budgetAmount,omitempty"`
+}
+
+// AlertResponse is the response of the admin request that simulates a budget notification.
+type AlertResponse struct {
+	// Message is the Pub/Sub message that was published.
+	Message *notification.PushMessage `json:"message"`
+	// Notification is the data of the message.
+	Notification *Notification `json:"notification"`
+}
@@ -0,0 +1,484 @@
+// Package blob provides storage backends for object content of the GCP API Mock.
+// Object content is written and read as streams, so backends can keep it
+// outside of the process memory.
+package blob
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// Blob is the stored content of a single object.
+type Blob interface {
+	// Size returns the size of the content in bytes.
+	Size() int64
+	// Open returns a reader for the content. The caller must close it.
+	Open() (io.ReadSeekCloser, error)
+	// Release frees the resources held by the blob. The blob must not be used afterwards,
+	// but readers that are already open remain valid.
+	Release() error
+}
+
+// Backend stores object content.
+type Backend interface {
+	// Write stores all content read from r as a new blob.
+	Write(r io.Reader) (Blob, error)
+}
+
+// Checker is implemented by backends that depend on something that can fail, like a disk.
+type Checker interface {
+	// Check returns an error if the backend can't store content.
+	Check() error
+}
+
+// Wrapper is implemented by backends storing content in another backend.
+type Wrapper interface {
+	// Unwrap returns the backend the content is stored in.
+	Unwrap() Backend
+}
+
+// Find returns the first backend of type T in a chain of wrapping backends, starting with backend itself.
+func Find[T Backend](backend Backend) (T, bool) {
+	for backend != nil {
+		if found, ok := backend.(T); ok {
+			return found, true
+		}
+		wrapper, ok := backend.(Wrapper)
+		if !ok {
+			break
+		}
+		backend = wrapper.Unwrap()
+	}
+	var zero T
+	return zero, false
+}
+
+// Check verifies that a backend can store content. Backends that can't fail are always fine.
+func Check(backend Backend) error {
+	if checker, ok := backend.(Checker); ok {
+		return checker.Check()
+	}
+	return nil
+}
+
+// MemoryBackend keeps object content in memory.
+type MemoryBackend struct{}
+
+// NewMemoryBackend creates a new MemoryBackend.
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{}
+}
+
+// Write reads all content from r into memory.
+func (b *MemoryBackend) Write(r io.Reader) (Blob, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return memoryBlob(data), nil
+}
+
+// memoryBlob is a blob held in memory.
+type memoryBlob []byte
+
+// Size returns the size of the content in bytes.
+func (b memoryBlob) Size() int64 {
+	return int64(len(b))
+}
+
+// Open returns a reader for the content.
+func (b memoryBlob) Open() (io.ReadSeekCloser, error) {
+	return nopCloser{bytes.NewReader(b)}, nil
+}
+
+// Release is a no-op; the memory is freed by the garbage collector.
+func (b memoryBlob) Release() error {
+	return nil
+}
+
+// nopCloser adds a no-op Close method to an io.ReadSeeker.
+type nopCloser struct {
+	io.ReadSeeker
+}
+
+// Close does nothing.
+func (nopCloser) Close() error {
+	return nil
+}
+
+// DiskBackend keeps object content in files in a directory.
+type DiskBackend struct {
+	dir string
+}
+
+// NewDiskBackend creates a new DiskBackend storing files in dir.
+// The directory is created if it doesn't exist.
+func NewDiskBackend(dir string) (*DiskBackend, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create blob directory %s: %w", dir, err)
+	}
+	return &DiskBackend{dir: dir}, nil
+}
+
+// Write streams all content from r into a new file.
+func (b *DiskBackend) Write(r io.Reader) (Blob, error) {
+	f, err := os.CreateTemp(b.dir, "blob-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create blob file: %w", err)
+	}
+	defer f.Close()
+
+	size, err := io.Copy(f, r)
+	if err != nil {
+		os.Remove(f.Name())
+		return nil, err
+	}
+
+	return &diskBlob{path: f.Name(), size: size}, nil
+}
+
+// Check verifies that the directory is writable by creating and removing a file in it.
+func (b *DiskBackend) Check() error {
+	f, err := os.CreateTemp(b.dir, "check-*")
+	if err != nil {
+		return fmt.Errorf("blob directory %s is not writable: %w", b.dir, err)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// diskBlob is a blob stored in a file.
+type diskBlob struct {
+	path string
+	size int64
+}
+
+// Size returns the size of the content in bytes.
+func (b *diskBlob) Size() int64 {
+	return b.size
+}
+
+// Open opens the blob file for reading.
+func (b *diskBlob) Open() (io.ReadSeekCloser, error) {
+	return os.Open(b.path)
+}
+
+// Release removes the blob file.
+func (b *diskBlob) Release() error {
+	return os.Remove(b.path)
+}
+
+// DedupBackend stores content addressed by its SHA-256 hash in another backend, so equal content,
+// like a fixture uploaded to many buckets, is stored only once. Each Write returns a new reference;
+// the content is released when the last reference to it is.
+type DedupBackend struct {
+	backend Backend
+
+	mu sync.Mutex
+	// contents is a map of content hash to the shared content
+	contents map[[sha256.Size]byte]*dedupContent
+}
+
+// dedupContent is content shared by all references to the same hash.
+type dedupContent struct {
+	blob Blob
+	refs int
+}
+
+// NewDedupBackend creates a new DedupBackend storing content in backend.
+func NewDedupBackend(backend Backend) *DedupBackend {
+	return &DedupBackend{
+		backend:  backend,
+		contents: make(map[[sha256.Size]byte]*dedupContent),
+	}
+}
+
+// Write stores all content read from r, hashing it on the way. If the same content is already stored,
+// the new copy is released again and a reference to the existing content is returned.
+func (b *DedupBackend) Write(r io.Reader) (Blob, error) {
+	hash := sha256.New()
+	content, err := b.backend.Write(io.TeeReader(r, hash))
+	if err != nil {
+		return nil, err
+	}
+
+	var key [sha256.Size]byte
+	hash.Sum(key[:0])
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	shared, exists := b.contents[key]
+	if exists {
+		content.Release()
+	} else {
+		shared = &dedupContent{blob: content}
+		b.contents[key] = shared
+	}
+	shared.refs++
+
+	return &dedupBlob{backend: b, key: key, content: shared}, nil
+}
+
+// DedupStats describes how much storage deduplication saves.
+type DedupStats struct {
+	// Blobs is the number of distinct contents stored.
+	Blobs int `json:"blobs"`
+	// References is the number of blobs referencing them.
+	References int `json:"references"`
+	// StoredBytes is the size of the distinct contents.
+	StoredBytes int64 `json:"storedBytes"`
+	// ReferencedBytes is the size of all referencing blobs, i.e. what would be stored without deduplication.
+	ReferencedBytes int64 `json:"referencedBytes"`
+}
+
+// Check verifies the backend the content is stored in.
+func (b *DedupBackend) Check() error {
+	return Check(b.backend)
+}
+
+// Unwrap returns the backend the content is stored in.
+func (b *DedupBackend) Unwrap() Backend {
+	return b.backend
+}
+
+// Stats returns the current deduplication statistics.
+func (b *DedupBackend) Stats() DedupStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stats := DedupStats{Blobs: len(b.contents)}
+	for _, shared := range b.contents {
+		stats.References += shared.refs
+		stats.StoredBytes += shared.blob.Size()
+		stats.ReferencedBytes += int64(shared.refs) * shared.blob.Size()
+	}
+	return stats
+}
+
+// release drops a reference to content, releasing it when it was the last one.
+func (b *DedupBackend) release(key [sha256.Size]byte, shared *dedupContent) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	shared.refs--
+	if shared.refs > 0 {
+		return nil
+	}
+	delete(b.contents, key)
+	return shared.blob.Release()
+}
+
+// dedupBlob is a reference to content stored by a DedupBackend.
+type dedupBlob struct {
+	backend  *DedupBackend
+	key      [sha256.Size]byte
+	content  *dedupContent
+	released sync.Once
+}
+
+// Size returns the size of the content in bytes.
+func (b *dedupBlob) Size() int64 {
+	return b.content.blob.Size()
+}
+
+// Open returns a reader for the shared content.
+func (b *dedupBlob) Open() (io.ReadSeekCloser, error) {
+	return b.content.blob.Open()
+}
+
+// Release drops the reference; releasing it more than once has no further effect.
+func (b *dedupBlob) Release() error {
+	var err error
+	b.released.Do(func() {
+		err = b.backend.release(b.key, b.content)
+	})
+	return err
+}
+
+// ErrEvicted is returned when opening content an LRUBackend evicted to stay below its size limit.
+var ErrEvicted = errors.New("content was evicted to stay below the content size limit")
+
+// LRUBackend limits the total size of the content stored in another backend. When a write exceeds the limit,
+// the least recently written or opened blobs are evicted: their content is released, but they keep their size,
+// and opening them returns ErrEvicted. The blob just written is never evicted, so a single blob larger than
+// the limit is still stored.
+type LRUBackend struct {
+	backend  Backend
+	maxBytes int64
+
+	mu sync.Mutex
+	// recent holds the stored *lruBlob, least recently used first
+	recent       *list.List
+	storedBytes  int64
+	evictions    int64
+	evictedBytes int64
+}
+
+// NewLRUBackend creates a new LRUBackend storing at most maxBytes of content in backend.
+func NewLRUBackend(backend Backend, maxBytes int64) *LRUBackend {
+	return &LRUBackend{
+		backend:  backend,
+		maxBytes: maxBytes,
+		recent:   list.New(),
+	}
+}
+
+// Write stores all content read from r, then evicts the least recently used blobs until the stored
+// content fits into the limit again.
+func (b *LRUBackend) Write(r io.Reader) (Blob, error) {
+	content, err := b.backend.Write(r)
+	if err != nil {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	written := &lruBlob{backend: b, content: content, size: content.Size()}
+	written.element = b.recent.PushBack(written)
+	b.storedBytes += written.size
+
+	for b.storedBytes > b.maxBytes {
+		oldest := b.recent.Front().Value.(*lruBlob)
+		if oldest == written {
+			break
+		}
+		b.evict(oldest)
+	}
+	return written, nil
+}
+
+// evict releases the content of a blob. The caller must hold b.mu.
+func (b *LRUBackend) evict(evicted *lruBlob) {
+	b.recent.Remove(evicted.element)
+	b.storedBytes -= evicted.size
+	b.evictions++
+	b.evictedBytes += evicted.size
+	evicted.evicted = true
+	evicted.content.Release()
+}
+
+// LRUStats describes the content stored by an LRUBackend and what it evicted.
+type LRUStats struct {
+	// MaxBytes is the size limit of the stored content.
+	MaxBytes int64 `json:"maxBytes"`
+	// StoredBytes is the size of the content currently stored.
+	StoredBytes int64 `json:"storedBytes"`
+	// Blobs is the number of blobs currently stored.
+	Blobs int `json:"blobs"`
+	// Evictions is the number of blobs evicted so far.
+	Evictions int64 `json:"evictions"`
+	// EvictedBytes is the size of the content evicted so far.
+	EvictedBytes int64 `json:"evictedBytes"`
+}
+
+// Check verifies the backend the content is stored in.
+func (b *LRUBackend) Check() error {
+	return Check(b.backend)
+}
+
+// Unwrap returns the backend the content is stored in.
+func (b *LRUBackend) Unwrap() Backend {
+	return b.backend
+}
+
+// Stats returns the current statistics.
+func (b *LRUBackend) Stats() LRUStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return LRUStats{
+		MaxBytes:     b.maxBytes,
+		StoredBytes:  b.storedBytes,
+		Blobs:        b.recent.Len(),
+		Evictions:    b.evictions,
+		EvictedBytes: b.evictedBytes,
+	}
+}
+
+// lruBlob is a blob stored by an LRUBackend.
+type lruBlob struct {
+	backend *LRUBackend
+	content Blob
+	size    int64
+	// element is the blob's element in the backend's recent list, guarded by the backend's mutex
+	element  *list.Element
+	evicted  bool
+	released bool
+}
+
+// Size returns the size of the content in bytes, even if it was evicted.
+func (b *lruBlob) Size() int64 {
+	return b.size
+}
+
+// Open marks the blob as the most recently used one and returns a reader for the content.
+// Returns ErrEvicted if the content was evicted.
+func (b *lruBlob) Open() (io.ReadSeekCloser, error) {
+	b.backend.mu.Lock()
+	defer b.backend.mu.Unlock()
+
+	if b.evicted {
+		return nil, ErrEvicted
+	}
+	b.backend.recent.MoveToBack(b.element)
+	return b.content.Open()
+}
+
+// Release releases the content unless it was already evicted; releasing it more than once has no further effect.
+func (b *lruBlob) Release() error {
+	b.backend.mu.Lock()
+	defer b.backend.mu.Unlock()
+
+	if b.evicted || b.released {
+		return nil
+	}
+	b.released = true
+	b.backend.recent.Remove(b.element)
+	b.backend.storedBytes -= b.size
+	return b.content.Release()
+}
+
+// IsEvicted reports whether the content of a blob was evicted.
+func IsEvicted(b Blob) bool {
+	switch b := b.(type) {
+	case *lruBlob:
+		b.backend.mu.Lock()
+		defer b.backend.mu.Unlock()
+		return b.evicted
+	case evictedBlob:
+		return true
+	}
+	return false
+}
+
+// Evicted returns a blob of the given size whose content was evicted, e.g. to restore an evicted blob
+// from a snapshot.
+func Evicted(size int64) Blob {
+	return evictedBlob(size)
+}
+
+// evictedBlob is a blob whose content was evicted; only its size is known.
+type evictedBlob int64
+
+// Size returns the size the content had.
+func (b evictedBlob) Size() int64 {
+	return int64(b)
+}
+
+// Open returns ErrEvicted.
+func (b evictedBlob) Open() (io.ReadSeekCloser, error) {
+	return nil, ErrEvicted
+}
+
+// Release is a no-op, as there is no content.
+func (b evictedBlob) Release() error {
+	return nil
+}
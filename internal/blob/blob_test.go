@@ -0,0 +1,207 @@
+package blob
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestBackends(t *testing.T) {
+	diskBackend, err := NewDiskBackend(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewDiskBackend() error: %v", err)
+	}
+
+	backends := map[string]Backend{
+		"memory": NewMemoryBackend(),
+		"disk":   diskBackend,
+		"dedup":  NewDedupBackend(NewMemoryBackend()),
+		"lru":    NewLRUBackend(NewMemoryBackend(), 1024),
+	}
+
+	for name, backend := range backends {
+		t.Run(name, func(t *testing.T) {
+			b, err := backend.Write(strings.NewReader("Hello, World!"))
+			if err != nil {
+				t.Fatalf("Write() error: %v", err)
+			}
+
+			if b.Size() != 13 {
+				t.Errorf("Size() = %d, want 13", b.Size())
+			}
+
+			r, err := b.Open()
+			if err != nil {
+				t.Fatalf("Open() error: %v", err)
+			}
+			data, err := io.ReadAll(r)
+			r.Close()
+			if err != nil {
+				t.Fatalf("failed to read blob: %v", err)
+			}
+			if string(data) != "Hello, World!" {
+				t.Errorf("content = %s, want Hello, World!", string(data))
+			}
+
+			if err := b.Release(); err != nil {
+				t.Errorf("Release() error: %v", err)
+			}
+		})
+	}
+}
+
+func TestDiskBackend_ReleaseRemovesFile(t *testing.T) {
+	dir := t.TempDir()
+	backend, _ := NewDiskBackend(dir)
+
+	b, _ := backend.Write(strings.NewReader("data"))
+	_ = b.Release()
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Errorf("expected blob directory to be empty, got %d entries", len(entries))
+	}
+}
+
+func TestDedupBackend(t *testing.T) {
+	dir := t.TempDir()
+	diskBackend, _ := NewDiskBackend(dir)
+	backend := NewDedupBackend(diskBackend)
+
+	first, _ := backend.Write(strings.NewReader("fixture"))
+	second, _ := backend.Write(strings.NewReader("fixture"))
+	other, _ := backend.Write(strings.NewReader("other"))
+
+	stats := backend.Stats()
+	want := DedupStats{Blobs: 2, References: 3, StoredBytes: 12, ReferencedBytes: 19}
+	if stats != want {
+		t.Errorf("Stats() = %+v, want %+v", stats, want)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("expected 2 blob files, got %d", len(entries))
+	}
+
+	// The shared content stays readable until its last reference is released
+	_ = first.Release()
+	_ = first.Release()
+	r, err := second.Open()
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	data, _ := io.ReadAll(r)
+	r.Close()
+	if string(data) != "fixture" {
+		t.Errorf("content = %q, want fixture", data)
+	}
+
+	_ = second.Release()
+	_ = other.Release()
+	if stats := backend.Stats(); stats != (DedupStats{}) {
+		t.Errorf("expected no content after releasing all references, got %+v", stats)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("expected blob directory to be empty, got %d entries", len(entries))
+	}
+}
+
+func TestLRUBackend(t *testing.T) {
+	dir := t.TempDir()
+	diskBackend, _ := NewDiskBackend(dir)
+	backend := NewLRUBackend(diskBackend, 10)
+
+	first, _ := backend.Write(strings.NewReader("aaaa"))
+	second, _ := backend.Write(strings.NewReader("bbbb"))
+
+	// Reading the first blob makes the second one the least recently used
+	r, err := first.Open()
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	r.Close()
+
+	third, _ := backend.Write(strings.NewReader("cccc"))
+
+	if _, err := second.Open(); !errors.Is(err, ErrEvicted) {
+		t.Errorf("expected the least recently used blob to be evicted, got %v", err)
+	}
+	if second.Size() != 4 {
+		t.Errorf("Size() of evicted blob = %d, want 4", second.Size())
+	}
+	for _, b := range []Blob{first, third} {
+		r, err := b.Open()
+		if err != nil {
+			t.Fatalf("Open() error: %v", err)
+		}
+		r.Close()
+	}
+
+	want := LRUStats{MaxBytes: 10, StoredBytes: 8, Blobs: 2, Evictions: 1, EvictedBytes: 4}
+	if stats := backend.Stats(); stats != want {
+		t.Errorf("Stats() = %+v, want %+v", stats, want)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("expected 2 blob files, got %d", len(entries))
+	}
+
+	// A blob larger than the limit evicts everything else, but is kept itself
+	large, _ := backend.Write(strings.NewReader("larger than ten bytes"))
+	if _, err := large.Open(); err != nil {
+		t.Errorf("expected the blob just written to be kept, got %v", err)
+	}
+	if stats := backend.Stats(); stats.Blobs != 1 || stats.Evictions != 3 {
+		t.Errorf("expected only the large blob to be left, got %+v", stats)
+	}
+
+	_ = second.Release()
+	_ = large.Release()
+	_ = large.Release()
+	if stats := backend.Stats(); stats.StoredBytes != 0 || stats.Blobs != 0 {
+		t.Errorf("expected no content after releasing all blobs, got %+v", stats)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("expected blob directory to be empty, got %d entries", len(entries))
+	}
+}
+
+func TestFind(t *testing.T) {
+	dedup := NewDedupBackend(NewMemoryBackend())
+	backend := NewLRUBackend(dedup, 1024)
+
+	if found, ok := Find[*DedupBackend](backend); !ok || found != dedup {
+		t.Errorf("expected to find the wrapped dedup backend, got %v, %v", found, ok)
+	}
+	if _, ok := Find[*DiskBackend](backend); ok {
+		t.Error("expected not to find a disk backend")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	dir := t.TempDir()
+	diskBackend, err := NewDiskBackend(dir)
+	if err != nil {
+		t.Fatalf("NewDiskBackend() error: %v", err)
+	}
+	dedupBackend := NewDedupBackend(diskBackend)
+
+	for name, backend := range map[string]Backend{"memory": NewMemoryBackend(), "disk": diskBackend, "dedup": dedupBackend} {
+		if err := Check(backend); err != nil {
+			t.Errorf("%s: Check() error: %v", name, err)
+		}
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Errorf("expected Check() to clean up, found %d files", len(entries))
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatalf("failed to remove directory: %v", err)
+	}
+	for name, backend := range map[string]Backend{"disk": diskBackend, "dedup": dedupBackend} {
+		if err := Check(backend); err == nil {
+			t.Errorf("%s: expected Check() to fail without the directory", name)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+// Package capabilities reports which APIs, methods and features the running mock supports, so test
+// harnesses can skip scenarios the mock can't serve instead of failing on them.
+package capabilities
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/katharinasick/gcp-api-mock/internal/config"
+	"github.com/katharinasick/gcp-api-mock/internal/discovery"
+	"github.com/katharinasick/gcp-api-mock/internal/version"
+)
+
+// Report lists the capabilities of the running mock.
+type Report struct {
+	// Version is the build information of the mock.
+	Version version.Info `json:"version"`
+	// APIs are the emulated APIs.
+	APIs []API `json:"apis"`
+	// Features are features of the APIs and of the mock itself, including known gaps.
+	Features []Feature `json:"features"`
+}
+
+// API is an emulated API.
+type API struct {
+	// Name is the name of the API, like "storage".
+	Name string `json:"name"`
+	// Version is the emulated version, like "v1".
+	Version string `json:"version"`
+	// Title is the human-readable name of the API.
+	Title string `json:"title"`
+	// Service is the service name, like "storage.googleapis.com".
+	Service string `json:"service"`
+	// Enabled is false if the service is disabled with GCP_MOCK_DISABLED_SERVICES.
+	Enabled bool `json:"enabled"`
+	// Methods are the IDs of the implemented methods, like "storage.objects.insert", as listed by
+	// the API's discovery document.
+	Methods []string `json:"methods,omitempty"`
+}
+
+// Feature is a feature the mock implements or is known not to implement.
+type Feature struct {
+	// Name identifies the feature, like "storage.resumableUploads".
+	Name string `json:"name"`
+	// Supported reports whether the mock implements the feature.
+	Supported bool `json:"supported"`
+	// Enabled reports whether the feature can be used with the current configuration.
+	Enabled bool `json:"enabled"`
+	// Note explains limitations or how to enable the feature.
+	Note string `json:"note,omitempty"`
+}
+
+// Get returns the capabilities of a mock running with the given configuration.
+func Get(cfg *config.Config) *Report {
+	return &Report{Version: version.Get(), APIs: apis(cfg), Features: features(cfg)}
+}
+
+// apis lists the APIs with a discovery document, followed by the Artifact Registry, which speaks the
+// Docker Registry protocol instead.
+func apis(cfg *config.Config) []API {
+	rootURL := cfg.ExternalURL() + "/"
+
+	var result []API
+	for _, item := range discovery.Directory(rootURL).Items {
+		api := API{
+			Name:    item.Name,
+			Version: item.Version,
+			Title:   item.Title,
+			Service: item.Name + ".googleapis.com",
+		}
+		if doc := discovery.Document(item.Name, item.Version, rootURL); doc != nil {
+			api.Methods = methodIDs(doc.Resources)
+		}
+		result = append(result, api)
+	}
+	result = append(result, API{
+		Name:    "artifactregistry",
+		Version: "v2",
+		Title:   "Artifact Registry (Docker Registry HTTP API V2)",
+		Service: "artifactregistry.googleapis.com",
+	})
+
+	for i := range result {
+		result[i].Enabled = !slices.Contains(cfg.DisabledServices, result[i].Service)
+	}
+	return result
+}
+
+// methodIDs returns the IDs of the methods of resources and their nested resources, sorted.
+func methodIDs(resources map[string]*discovery.Resource) []string {
+	var ids []string
+	for _, resource := range resources {
+		for _, method := range resource.Methods {
+			ids = append(ids, method.ID)
+		}
+		ids = append(ids, methodIDs(resource.Resources)...)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// features lists the features of the mock. Features that are always available are supported and enabled;
+// configurable ones are enabled depending on cfg.
+func features(cfg *config.Config) []Feature {
+	supported := func(name string) Feature {
+		return Feature{Name: name, Supported: true, Enabled: true}
+	}
+	configurable := func(name string, enabled bool, note string) Feature {
+		f := Feature{Name: name, Supported: true, Enabled: enabled}
+		if !enabled {
+			f.Note = note
+		}
+		return f
+	}
+	unsupported := func(name, note string) Feature {
+		return Feature{Name: name, Note: note}
+	}
+
+	return []Feature{
+		supported("storage.jsonApi"),
+		supported("storage.xmlApi"),
+		supported("storage.multipartUploads"),
+		supported("storage.resumableUploads"),
+		supported("storage.checksumValidation"),
+		supported("storage.preconditions"),
+		supported("storage.partialResponses"),
+		supported("storage.cors"),
+		supported("storage.requesterPays"),
+		supported("storage.autoclass"),
+		supported("storage.rewrite"),
+		supported("storage.objectAcls"),
+		supported("storage.iamPolicies"),
+		supported("storage.publicAccessPrevention"),
+		supported("storage.softDelete"),
+		supported("storage.retentionPolicies"),
+		supported("storage.notifications"),
+		supported("storage.hmacKeys"),
+		configurable("storage.s3Api", cfg.S3Enabled, "enable with GCP_MOCK_S3_ENABLED=true"),
+		unsupported("storage.versioning", "the versioning configuration is stored, but noncurrent generations aren't kept"),
+		supported("storage.lifecycleRules"),
+		supported("storage.objectHolds"),
+		unsupported("storage.compose", "objects.compose isn't implemented"),
+		unsupported("storage.signedUrls", "signatures of signed URLs aren't verified"),
+		supported("sqladmin.databaseFlags"),
+		supported("sqladmin.replicas"),
+		supported("sqladmin.instanceFilters"),
+		unsupported("sqladmin.authProxy", "the Cloud SQL Auth Proxy handshake isn't emulated; connect to the instance ports of GCP_MOCK_SQL_PROXY_PORTS directly"),
+		configurable("sqladmin.scheduledMaintenance", cfg.SQLMaintenanceDuration != "", "enable with GCP_MOCK_SQL_MAINTENANCE_DURATION"),
+		configurable("cloudscheduler.cron", cfg.SchedulerCron, "enable with GCP_MOCK_SCHEDULER_CRON=true"),
+		unsupported("cloudscheduler.retries", "retryConfig is stored, but failed attempts aren't retried"),
+		supported("eventarc.httpEndpoints"),
+		unsupported("eventarc.cloudRunDestinations", "Cloud Run services don't run in the mock, so their events are only logged"),
+		configurable("redis.localInstance", cfg.MemorystoreEndpoint != "", "enable with GCP_MOCK_MEMORYSTORE_ENDPOINT"),
+		configurable("container.kubeconfig", cfg.GKEKubeconfig != "", "enable with GCP_MOCK_GKE_KUBECONFIG"),
+		unsupported("container.nodePools", "node pools are created with their cluster and can't be changed on their own"),
+		supported("compute.networks"),
+		unsupported("compute.autoModeSubnetworks", "auto mode networks don't get a subnetwork in every region; create subnetworks explicitly"),
+		supported("billingbudgets.alerts"),
+		unsupported("billingbudgets.updateDelete", "budgets can only be created, listed and read"),
+		supported("orgpolicy.booleanConstraints"),
+		unsupported("orgpolicy.listConstraints", "only storage.publicAccessPrevention, storage.uniformBucketLevelAccess, sql.restrictPublicIp and sql.restrictAuthorizedNetworks are enforced; other policies are stored only"),
+		supported("mock.namespaces"),
+		supported("mock.readOnly"),
+		supported("mock.responseOverrides"),
+		supported("mock.responseTemplates"),
+		supported("mock.timeTravel"),
+		supported("mock.latencyInjection"),
+		supported("mock.sloSimulation"),
+		supported("mock.recording"),
+		supported("mock.snapshots"),
+		supported("mock.bucketImport"),
+		supported("mock.loadTestData"),
+		supported("mock.objectSearch"),
+		supported("mock.archiveDownload"),
+		supported("mock.requestLog"),
+		supported("mock.metrics"),
+		supported("mock.metadataServer"),
+		configurable("mock.strictAuth", cfg.IsStrictAuth(), "enable with GCP_MOCK_AUTH_MODE=strict"),
+		configurable("mock.strictValidation", cfg.StrictValidation, "enable with GCP_MOCK_STRICT_VALIDATION=true"),
+		configurable("mock.tls", cfg.IsTLS(), "enable with GCP_MOCK_TLS=true"),
+		configurable("mock.blobDedup", cfg.BlobDedup, "enable with GCP_MOCK_BLOB_DEDUP=true"),
+		configurable("mock.contentLimit", cfg.MaxContentSize != "", "enable with GCP_MOCK_MAX_CONTENT_SIZE"),
+		configurable("mock.sqlData", cfg.SQLDataDir != "", "enable with GCP_MOCK_SQL_DATA_DIR"),
+		configurable("mock.mirror", cfg.MirrorDir != "", "enable with GCP_MOCK_MIRROR_DIR"),
+		configurable("mock.sqlProxy", cfg.SQLProxyPorts != "", "enable with GCP_MOCK_SQL_PROXY_PORTS"),
+		configurable("mock.sharedState", cfg.RedisURL != "", "enable with GCP_MOCK_REDIS_URL"),
+		configurable("mock.schemaValidation", cfg.SchemaValidation != "", "enable with GCP_MOCK_SCHEMA_VALIDATION=log or fail"),
+		configurable("mock.requestRedaction", len(cfg.RedactHeaders) > 0 || len(cfg.RedactJSONPaths) > 0, "enable with GCP_MOCK_REDACT_HEADERS or GCP_MOCK_REDACT_JSON_PATHS"),
+	}
+}
+
+// Summary describes the report in a few lines for the startup log.
+func (r *Report) Summary() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "GCP API Mock %s", r.Version.Version)
+	if r.Version.Commit != "" {
+		fmt.Fprintf(&b, " (%s)", shortCommit(r.Version.Commit))
+	}
+
+	var apis []string
+	for _, api := range r.APIs {
+		switch {
+		case !api.Enabled:
+			apis = append(apis, fmt.Sprintf("%s %s (disabled)", api.Name, api.Version))
+		case len(api.Methods) > 0:
+			apis = append(apis, fmt.Sprintf("%s %s (%d methods)", api.Name, api.Version, len(api.Methods)))
+		default:
+			apis = append(apis, api.Name+" "+api.Version)
+		}
+	}
+	fmt.Fprintf(&b, "\nAPIs: %s", strings.Join(apis, ", "))
+
+	var enabled, disabled, unsupported []string
+	for _, f := range r.Features {
+		switch {
+		case !f.Supported:
+			unsupported = append(unsupported, f.Name)
+		case !f.Enabled:
+			disabled = append(disabled, f.Name)
+		case strings.HasPrefix(f.Name, "mock."):
+			// Only list mock features, the API features would drown the summary
+			enabled = append(enabled, f.Name)
+		}
+	}
+	fmt.Fprintf(&b, "\nMock features: %s", strings.Join(enabled, ", "))
+	if len(disabled) > 0 {
+		fmt.Fprintf(&b, "\nDisabled features: %s", strings.Join(disabled, ", "))
+	}
+	if len(unsupported) > 0 {
+		fmt.Fprintf(&b, "\nUnsupported features: %s", strings.Join(unsupported, ", "))
+	}
+	b.WriteString("\nFull report: GET /capabilities")
+	return b.String()
+}
+
+// shortCommit abbreviates a commit hash like git does.
+func shortCommit(commit string) string {
+	if len(commit) > 7 {
+		return commit[:7]
+	}
+	return commit
+}
@@ -0,0 +1,89 @@
+package capabilities
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/katharinasick/gcp-api-mock/internal/config"
+)
+
+func TestGet_APIs(t *testing.T) {
+	report := Get(&config.Config{Host: "localhost", Port: "8080", DisabledServices: []string{"run.googleapis.com"}})
+
+	apis := make(map[string]API)
+	for _, api := range report.APIs {
+		apis[api.Name] = api
+	}
+
+	storage, ok := apis["storage"]
+	if !ok {
+		t.Fatal("expected the storage API to be listed")
+	}
+	if !storage.Enabled || storage.Version != "v1" || storage.Service != "storage.googleapis.com" {
+		t.Errorf("unexpected storage API %+v", storage)
+	}
+	if !slices.Contains(storage.Methods, "storage.objects.insert") || !slices.IsSorted(storage.Methods) {
+		t.Errorf("expected the sorted storage methods to include storage.objects.insert, got %v", storage.Methods)
+	}
+	if run := apis["run"]; run.Enabled {
+		t.Error("expected the disabled Cloud Run API to be reported as disabled")
+	}
+	if _, ok := apis["artifactregistry"]; !ok {
+		t.Error("expected the Artifact Registry to be listed")
+	}
+}
+
+func TestGet_Features(t *testing.T) {
+	tests := []struct {
+		name          string
+		cfg           *config.Config
+		feature       string
+		wantSupported bool
+		wantEnabled   bool
+	}{
+		{"always available", &config.Config{}, "storage.resumableUploads", true, true},
+		{"not configured", &config.Config{}, "storage.s3Api", true, false},
+		{"configured", &config.Config{S3Enabled: true}, "storage.s3Api", true, true},
+		{"content limit", &config.Config{MaxContentSize: "1GiB"}, "mock.contentLimit", true, true},
+		{"known gap", &config.Config{}, "storage.versioning", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := Get(tt.cfg)
+			i := slices.IndexFunc(report.Features, func(f Feature) bool { return f.Name == tt.feature })
+			if i < 0 {
+				t.Fatalf("expected feature %s to be listed", tt.feature)
+			}
+			f := report.Features[i]
+			if f.Supported != tt.wantSupported || f.Enabled != tt.wantEnabled {
+				t.Errorf("expected supported=%v enabled=%v, got %+v", tt.wantSupported, tt.wantEnabled, f)
+			}
+			if !f.Enabled && f.Note == "" {
+				t.Error("expected a note explaining why the feature can't be used")
+			}
+		})
+	}
+}
+
+func TestReport_Summary(t *testing.T) {
+	report := Get(&config.Config{DisabledServices: []string{"logging.googleapis.com"}})
+	report.Version.Version = "v1.2.0"
+	report.Version.Commit = "0123456789abcdef"
+
+	summary := report.Summary()
+	for _, want := range []string{
+		"GCP API Mock v1.2.0 (0123456)",
+		"storage v1 (",
+		"logging v2 (disabled)",
+		"Mock features: mock.namespaces",
+		"Disabled features: storage.s3Api",
+		"Unsupported features: storage.versioning",
+		"GET /capabilities",
+	} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("expected summary to contain %q, got:\n%s", want, summary)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package certs
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+)
+
+func TestGenerateSelfSigned(t *testing.T) {
+	bundle, err := GenerateSelfSigned([]string{"localhost", "127.0.0.1", "*.storage.googleapis.com"})
+	if err != nil {
+		t.Fatalf("GenerateSelfSigned() error: %v", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(bundle.CACertPEM) {
+		t.Fatal("expected CA certificate PEM to be valid")
+	}
+
+	leaf, err := x509.ParseCertificate(bundle.Certificate.Certificate[0])
+	if err != nil {
+		t.Fatalf("failed to parse server certificate: %v", err)
+	}
+
+	tests := []string{"localhost", "127.0.0.1", "my-bucket.storage.googleapis.com"}
+	for _, host := range tests {
+		t.Run(host, func(t *testing.T) {
+			if _, err := leaf.Verify(x509.VerifyOptions{DNSName: host, Roots: pool}); err != nil {
+				t.Errorf("expected certificate to be valid for %s: %v", host, err)
+			}
+		})
+	}
+
+	if _, err := leaf.Verify(x509.VerifyOptions{DNSName: "example.com", Roots: pool}); err == nil {
+		t.Error("expected certificate to be invalid for example.com")
+	}
+}
+
+func TestGenerateCA(t *testing.T) {
+	caPEM, err := GenerateCA("test-cluster")
+	if err != nil {
+		t.Fatalf("GenerateCA() error: %v", err)
+	}
+
+	block, _ := pem.Decode(caPEM)
+	if block == nil {
+		t.Fatal("expected a PEM block")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	if !cert.IsCA || cert.Subject.CommonName != "test-cluster" {
+		t.Errorf("unexpected certificate: CA %v, common name %q", cert.IsCA, cert.Subject.CommonName)
+	}
+}
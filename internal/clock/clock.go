@@ -0,0 +1,136 @@
+// Package clock provides a virtual clock that can be frozen and moved,
+// so time-dependent behavior can be tested without sleeping.
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is a virtual clock. It follows the real time shifted by an offset,
+// or stands still at a fixed time while frozen.
+// Its skew shifts only the time reported in HTTP headers like Date, not the time of the resources,
+// like a server whose clock is off.
+// It is safe for concurrent access.
+type Clock struct {
+	mu       sync.RWMutex
+	real     func() time.Time
+	offset   time.Duration
+	frozen   bool
+	frozenAt time.Time
+	skew     time.Duration
+}
+
+// Status describes the state of a Clock.
+type Status struct {
+	Now    time.Time `json:"now"`
+	Frozen bool      `json:"frozen"`
+	Offset string    `json:"offset"`
+	Skew   string    `json:"skew"`
+}
+
+// New creates a new Clock that follows the real time.
+func New() *Clock {
+	return &Clock{real: time.Now}
+}
+
+// Now returns the current virtual time.
+func (c *Clock) Now() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.nowLocked()
+}
+
+// nowLocked returns the current virtual time. The caller must hold the lock.
+func (c *Clock) nowLocked() time.Time {
+	if c.frozen {
+		return c.frozenAt
+	}
+	return c.real().Add(c.offset)
+}
+
+// Set moves the clock to t. A frozen clock stays frozen at t, a running clock continues from t.
+func (c *Clock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.frozen {
+		c.frozenAt = t
+		return
+	}
+	c.offset = t.Sub(c.real())
+}
+
+// Advance moves the clock forward by d, or backward if d is negative.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.frozen {
+		c.frozenAt = c.frozenAt.Add(d)
+		return
+	}
+	c.offset += d
+}
+
+// Freeze stops the clock at the current virtual time.
+func (c *Clock) Freeze() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.frozen {
+		c.frozenAt = c.nowLocked()
+		c.frozen = true
+	}
+}
+
+// Unfreeze lets a frozen clock run again from the time it was frozen at.
+func (c *Clock) Unfreeze() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.frozen {
+		c.offset = c.frozenAt.Sub(c.real())
+		c.frozen = false
+	}
+}
+
+// Reset returns the clock to the real time, without skew.
+func (c *Clock) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.offset = 0
+	c.frozen = false
+	c.frozenAt = time.Time{}
+	c.skew = 0
+}
+
+// SetSkew sets the skew of the time reported in HTTP headers; negative skews report a time in the past.
+func (c *Clock) SetSkew(skew time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.skew = skew
+}
+
+// Skew returns the skew of the time reported in HTTP headers.
+func (c *Clock) Skew() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.skew
+}
+
+// Status returns the state of the clock.
+func (c *Clock) Status() Status {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	now := c.nowLocked()
+	return Status{
+		Now:    now.UTC(),
+		Frozen: c.frozen,
+		Offset: now.Sub(c.real()).Round(time.Millisecond).String(),
+		Skew:   c.skew.String(),
+	}
+}
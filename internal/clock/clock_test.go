@@ -0,0 +1,100 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestClock(real *time.Time) *Clock {
+	c := New()
+	c.real = func() time.Time { return *real }
+	return c
+}
+
+func TestClock_Advance(t *testing.T) {
+	real := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := newTestClock(&real)
+
+	c.Advance(36 * time.Hour)
+	if got, want := c.Now(), real.Add(36*time.Hour); !got.Equal(want) {
+		t.Errorf("Now() = %v, want %v", got, want)
+	}
+
+	// A running clock keeps following the real time
+	real = real.Add(time.Minute)
+	if got, want := c.Now(), real.Add(36*time.Hour); !got.Equal(want) {
+		t.Errorf("Now() = %v, want %v", got, want)
+	}
+	if status := c.Status(); status.Frozen || status.Offset != "36h0m0s" {
+		t.Errorf("unexpected status: %+v", status)
+	}
+}
+
+func TestClock_Freeze(t *testing.T) {
+	real := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := newTestClock(&real)
+
+	c.Freeze()
+	frozenAt := real
+
+	real = real.Add(time.Hour)
+	if got := c.Now(); !got.Equal(frozenAt) {
+		t.Errorf("expected frozen clock to stand still at %v, got %v", frozenAt, got)
+	}
+
+	c.Advance(24 * time.Hour)
+	if got, want := c.Now(), frozenAt.Add(24*time.Hour); !got.Equal(want) {
+		t.Errorf("Now() = %v, want %v", got, want)
+	}
+
+	// Unfreezing continues from the frozen time
+	c.Unfreeze()
+	real = real.Add(time.Minute)
+	if got, want := c.Now(), frozenAt.Add(24*time.Hour+time.Minute); !got.Equal(want) {
+		t.Errorf("Now() = %v, want %v", got, want)
+	}
+}
+
+func TestClock_SetAndReset(t *testing.T) {
+	real := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := newTestClock(&real)
+	target := time.Date(2030, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	c.Set(target)
+	if got := c.Now(); !got.Equal(target) {
+		t.Errorf("Now() = %v, want %v", got, target)
+	}
+
+	c.Freeze()
+	c.Set(target.Add(time.Hour))
+	real = real.Add(time.Hour)
+	if got, want := c.Now(), target.Add(time.Hour); !got.Equal(want) {
+		t.Errorf("Now() = %v, want %v", got, want)
+	}
+
+	c.Reset()
+	if got := c.Now(); !got.Equal(real) {
+		t.Errorf("expected real time %v after Reset(), got %v", real, got)
+	}
+	if status := c.Status(); status.Frozen || status.Offset != "0s" {
+		t.Errorf("unexpected status: %+v", status)
+	}
+}
+
+func TestClock_Skew(t *testing.T) {
+	real := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := newTestClock(&real)
+
+	c.SetSkew(-5 * time.Minute)
+	if got := c.Now(); !got.Equal(real) {
+		t.Errorf("expected the skew not to change Now(), got %v", got)
+	}
+	if status := c.Status(); status.Skew != "-5m0s" {
+		t.Errorf("unexpected status: %+v", status)
+	}
+
+	c.Reset()
+	if skew := c.Skew(); skew != 0 {
+		t.Errorf("expected no skew after Reset(), got %v", skew)
+	}
+}
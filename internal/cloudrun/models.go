@@ -0,0 +1,253 @@
+// Package cloudrun provides data models for the Cloud Run Admin API (v2) mock.
+package cloudrun
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/gcperror"
+	"github.com/katharinasick/gcp-api-mock/internal/longrunning"
+)
+
+// Type URLs of the messages embedded in long-running operations.
+const (
+	ServiceTypeURL = "type.googleapis.com/google.cloud.run.v2.Service"
+)
+
+// Service represents a Cloud Run service.
+// Reference: https://cloud.google.com/run/docs/reference/rest/v2/projects.locations.services
+type Service struct {
+	// Name is the resource name, e.g. projects/{project}/locations/{location}/services/{service}.
+	Name string `json:"name"`
+	// Description is a user-provided description of the service.
+	Description string `json:"description,omitempty"`
+	// Uid is a server-assigned unique identifier.
+	Uid string `json:"uid"`
+	// Generation is incremented on every update of the service spec.
+	Generation int64 `json:"generation,string"`
+	// Labels are user-provided key/value labels.
+	Labels map[string]string `json:"labels,omitempty"`
+	// Annotations are user-provided key/value annotations.
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// CreateTime is the creation time.
+	CreateTime time.Time `json:"createTime"`
+	// UpdateTime is the last modification time.
+	UpdateTime time.Time `json:"updateTime"`
+	// Creator is the email of the principal that created the service.
+	Creator string `json:"creator,omitempty"`
+	// LastModifier is the email of the principal that last modified the service.
+	LastModifier string `json:"lastModifier,omitempty"`
+	// Ingress controls which traffic can reach the service.
+	Ingress string `json:"ingress,omitempty"`
+	// LaunchStage is the launch stage of the service.
+	LaunchStage string `json:"launchStage,omitempty"`
+	// Template is the template used to create revisions.
+	Template *RevisionTemplate `json:"template,omitempty"`
+	// Traffic specifies how traffic is split between revisions.
+	Traffic []*TrafficTarget `json:"traffic,omitempty"`
+	// ObservedGeneration is the generation last processed by the server.
+	ObservedGeneration int64 `json:"observedGeneration,string"`
+	// TerminalCondition is the Ready condition of the service.
+	TerminalCondition *Condition `json:"terminalCondition,omitempty"`
+	// Conditions are the conditions of the service.
+	Conditions []*Condition `json:"conditions,omitempty"`
+	// LatestReadyRevision is the name of the latest revision that is ready to serve.
+	LatestReadyRevision string `json:"latestReadyRevision,omitempty"`
+	// LatestCreatedRevision is the name of the last created revision.
+	LatestCreatedRevision string `json:"latestCreatedRevision,omitempty"`
+	// TrafficStatuses is the traffic split as observed by the server.
+	TrafficStatuses []*TrafficTargetStatus `json:"trafficStatuses,omitempty"`
+	// Uri is the main URI at which the service is serving traffic.
+	Uri string `json:"uri,omitempty"`
+	// Reconciling is true while the service is being updated.
+	Reconciling bool `json:"reconciling"`
+	// Etag is the entity tag of the service.
+	Etag string `json:"etag"`
+}
+
+// RevisionTemplate describes the revisions created from a service.
+// Reference: https://cloud.google.com/run/docs/reference/rest/v2/RevisionTemplate
+type RevisionTemplate struct {
+	// Revision is the name of the revision to create; generated if empty.
+	Revision string `json:"revision,omitempty"`
+	// Labels are labels applied to the revision.
+	Labels map[string]string `json:"labels,omitempty"`
+	// Annotations are annotations applied to the revision.
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// Scaling configures the number of instances.
+	Scaling *RevisionScaling `json:"scaling,omitempty"`
+	// Timeout is the maximum request duration, e.g. "300s".
+	Timeout string `json:"timeout,omitempty"`
+	// ServiceAccount is the email of the service account the revision runs as.
+	ServiceAccount string `json:"serviceAccount,omitempty"`
+	// Containers are the containers of the revision.
+	Containers []*Container `json:"containers,omitempty"`
+	// ExecutionEnvironment is the sandbox environment of the revision.
+	ExecutionEnvironment string `json:"executionEnvironment,omitempty"`
+	// MaxInstanceRequestConcurrency is the maximum number of concurrent requests per instance.
+	MaxInstanceRequestConcurrency int `json:"maxInstanceRequestConcurrency,omitempty"`
+}
+
+// RevisionScaling configures the number of instances of a revision.
+type RevisionScaling struct {
+	// MinInstanceCount is the minimum number of instances.
+	MinInstanceCount int `json:"minInstanceCount,omitempty"`
+	// MaxInstanceCount is the maximum number of instances.
+	MaxInstanceCount int `json:"maxInstanceCount,omitempty"`
+}
+
+// Container describes a container of a revision.
+// Reference: https://cloud.google.com/run/docs/reference/rest/v2/Container
+type Container struct {
+	// Name is the name of the container.
+	Name string `json:"name,omitempty"`
+	// Image is the container image URL.
+	Image string `json:"image"`
+	// Command is the entrypoint array.
+	Command []string `json:"command,omitempty"`
+	// Args are the arguments to the entrypoint.
+	Args []string `json:"args,omitempty"`
+	// Env lists the environment variables of the container.
+	Env []*EnvVar `json:"env,omitempty"`
+	// Resources are the compute resource requirements.
+	Resources *ResourceRequirements `json:"resources,omitempty"`
+	// Ports lists the ports the container listens on.
+	Ports []*ContainerPort `json:"ports,omitempty"`
+}
+
+// EnvVar is an environment variable of a container.
+type EnvVar struct {
+	// Name is the name of the variable.
+	Name string `json:"name"`
+	// Value is the value of the variable.
+	Value string `json:"value,omitempty"`
+	// ValueSource is the source of the value, e.g. a Secret Manager secret.
+	ValueSource json.RawMessage `json:"valueSource,omitempty"`
+}
+
+// ResourceRequirements describes the compute resources of a container.
+type ResourceRequirements struct {
+	// Limits are the resource limits, e.g. {"cpu": "1", "memory": "512Mi"}.
+	Limits map[string]string `json:"limits,omitempty"`
+	// CpuIdle determines whether CPU is only allocated during requests.
+	CpuIdle bool `json:"cpuIdle,omitempty"`
+	// StartupCpuBoost determines whether CPU is boosted during startup.
+	StartupCpuBoost bool `json:"startupCpuBoost,omitempty"`
+}
+
+// ContainerPort is a port a container listens on.
+type ContainerPort struct {
+	// Name is the protocol name, "http1" or "h2c".
+	Name string `json:"name,omitempty"`
+	// ContainerPort is the port number.
+	ContainerPort int `json:"containerPort,omitempty"`
+}
+
+// TrafficTarget assigns a share of traffic to a revision.
+// Reference: https://cloud.google.com/run/docs/reference/rest/v2/TrafficTarget
+type TrafficTarget struct {
+	// Type is the allocation type, e.g. TRAFFIC_TARGET_ALLOCATION_TYPE_LATEST.
+	Type string `json:"type,omitempty"`
+	// Revision is the revision receiving traffic, for TRAFFIC_TARGET_ALLOCATION_TYPE_REVISION.
+	Revision string `json:"revision,omitempty"`
+	// Percent is the share of traffic.
+	Percent int `json:"percent,omitempty"`
+	// Tag is an optional tag providing a dedicated URL.
+	Tag string `json:"tag,omitempty"`
+}
+
+// TrafficTargetStatus is the observed state of a traffic target.
+type TrafficTargetStatus struct {
+	// Type is the allocation type.
+	Type string `json:"type,omitempty"`
+	// Revision is the revision receiving traffic.
+	Revision string `json:"revision,omitempty"`
+	// Percent is the share of traffic.
+	Percent int `json:"percent,omitempty"`
+	// Tag is the tag of the target.
+	Tag string `json:"tag,omitempty"`
+	// Uri is the dedicated URL of a tagged target.
+	Uri string `json:"uri,omitempty"`
+}
+
+// Condition describes the state of a resource.
+// Reference: https://cloud.google.com/run/docs/reference/rest/v2/Condition
+type Condition struct {
+	// Type is the condition type, e.g. "Ready".
+	Type string `json:"type"`
+	// State is the condition state, e.g. CONDITION_SUCCEEDED.
+	State string `json:"state"`
+	// LastTransitionTime is the time the condition last changed.
+	LastTransitionTime time.Time `json:"lastTransitionTime"`
+}
+
+// Revision represents an immutable snapshot of a service's template.
+// Reference: https://cloud.google.com/run/docs/reference/rest/v2/projects.locations.services.revisions
+type Revision struct {
+	// Name is the resource name, e.g. projects/{project}/locations/{location}/services/{service}/revisions/{revision}.
+	Name string `json:"name"`
+	// Uid is a server-assigned unique identifier.
+	Uid string `json:"uid"`
+	// Generation is always 1 for revisions.
+	Generation int64 `json:"generation,string"`
+	// Labels are labels from the template.
+	Labels map[string]string `json:"labels,omitempty"`
+	// Annotations are annotations from the template.
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// CreateTime is the creation time.
+	CreateTime time.Time `json:"createTime"`
+	// UpdateTime is the last modification time.
+	UpdateTime time.Time `json:"updateTime"`
+	// LaunchStage is the launch stage of the revision.
+	LaunchStage string `json:"launchStage,omitempty"`
+	// Service is the name of the parent service.
+	Service string `json:"service"`
+	// Scaling configures the number of instances.
+	Scaling *RevisionScaling `json:"scaling,omitempty"`
+	// Timeout is the maximum request duration.
+	Timeout string `json:"timeout,omitempty"`
+	// ServiceAccount is the email of the service account the revision runs as.
+	ServiceAccount string `json:"serviceAccount,omitempty"`
+	// Containers are the containers of the revision.
+	Containers []*Container `json:"containers,omitempty"`
+	// ExecutionEnvironment is the sandbox environment of the revision.
+	ExecutionEnvironment string `json:"executionEnvironment,omitempty"`
+	// MaxInstanceRequestConcurrency is the maximum number of concurrent requests per instance.
+	MaxInstanceRequestConcurrency int `json:"maxInstanceRequestConcurrency,omitempty"`
+	// Conditions are the conditions of the revision.
+	Conditions []*Condition `json:"conditions,omitempty"`
+	// ObservedGeneration is the generation last processed by the server.
+	ObservedGeneration int64 `json:"observedGeneration,string"`
+	// Reconciling is true while the revision is being created.
+	Reconciling bool `json:"reconciling"`
+	// Etag is the entity tag of the revision.
+	Etag string `json:"etag"`
+}
+
+// Operation is a google.longrunning.Operation.
+// Reference: https://cloud.google.com/run/docs/reference/rest/v2/projects.locations.operations
+type Operation = longrunning.Operation
+
+// ListServicesResponse is the response for listing services.
+type ListServicesResponse struct {
+	Services      []*Service `json:"services,omitempty"`
+	NextPageToken string     `json:"nextPageToken,omitempty"`
+}
+
+// ListRevisionsResponse is the response for listing revisions.
+type ListRevisionsResponse struct {
+	Revisions     []*Revision `json:"revisions,omitempty"`
+	NextPageToken string      `json:"nextPageToken,omitempty"`
+}
+
+// ListOperationsResponse is the response for listing operations.
+type ListOperationsResponse struct {
+	Operations    []*Operation `json:"operations,omitempty"`
+	NextPageToken string       `json:"nextPageToken,omitempty"`
+}
+
+// APIError represents an error response from the Cloud Run Admin API.
+type APIError = gcperror.Response
+
+// ErrorDetails contains the details of an API error.
+type ErrorDetails = gcperror.Details
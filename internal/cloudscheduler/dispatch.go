@@ -0,0 +1,120 @@
+package cloudscheduler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/gcperror"
+)
+
+// defaultAttemptDeadline is how long an HTTP attempt may take if the job doesn't set an attempt deadline.
+const defaultAttemptDeadline = 3 * time.Minute
+
+// userAgent is the User-Agent of the requests to HTTP targets.
+const userAgent = "Google-Cloud-Scheduler"
+
+// rpcCodes maps the names of google.rpc.Code values to their numbers.
+var rpcCodes = map[string]int{
+	"CANCELLED":           1,
+	"UNKNOWN":             2,
+	"INVALID_ARGUMENT":    3,
+	"DEADLINE_EXCEEDED":   4,
+	"NOT_FOUND":           5,
+	"ALREADY_EXISTS":      6,
+	"PERMISSION_DENIED":   7,
+	"RESOURCE_EXHAUSTED":  8,
+	"FAILED_PRECONDITION": 9,
+	"OUT_OF_RANGE":        11,
+	"UNIMPLEMENTED":       12,
+	"INTERNAL":            13,
+	"UNAVAILABLE":         14,
+	"UNAUTHENTICATED":     16,
+}
+
+// Dispatcher runs jobs by delivering them to their targets.
+// HTTP targets receive the configured request. There is no Pub/Sub or App Engine mock to deliver to,
+// so Pub/Sub messages are logged and App Engine targets fail as unimplemented.
+type Dispatcher struct {
+	client *http.Client
+}
+
+// NewDispatcher creates a new Dispatcher.
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{client: &http.Client{}}
+}
+
+// Attempt delivers a job to its target and returns the status of the attempt, which is empty if it succeeded.
+// scheduleTime is the time the attempt was scheduled for, sent to HTTP targets like Cloud Scheduler does.
+func (d *Dispatcher) Attempt(job *Job, scheduleTime time.Time) *Status {
+	switch {
+	case job.HttpTarget != nil:
+		return d.attemptHTTP(job, scheduleTime)
+	case job.PubsubTarget != nil:
+		log.Printf("scheduler job %s: publishing %d bytes to %s", job.Name, len(job.PubsubTarget.Data), job.PubsubTarget.TopicName)
+		return &Status{}
+	default:
+		return &Status{Code: rpcCodes["UNIMPLEMENTED"], Message: "App Engine targets aren't emulated"}
+	}
+}
+
+// Deliver delivers a job to its target asynchronously, like Attempt, and passes the status of the attempt to done.
+func (d *Dispatcher) Deliver(job *Job, scheduleTime time.Time, done func(status *Status)) {
+	go func() {
+		done(d.Attempt(job, scheduleTime))
+	}()
+}
+
+// attemptHTTP sends the request of a job with an HTTP target and returns the status of the attempt.
+// Responses with a 2xx status code are successes; the status of other responses is mapped to a google.rpc.Code.
+// Reference: https://cloud.google.com/scheduler/docs/reference/rest/v1/projects.locations.jobs#HttpTarget
+func (d *Dispatcher) attemptHTTP(job *Job, scheduleTime time.Time) *Status {
+	target := job.HttpTarget
+
+	deadline := defaultAttemptDeadline
+	if job.AttemptDeadline != "" {
+		if parsed, err := time.ParseDuration(job.AttemptDeadline); err == nil && parsed > 0 {
+			deadline = parsed
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), deadline)
+	defer cancel()
+
+	method := target.HttpMethod
+	if method == "" {
+		method = http.MethodPost
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target.Uri, bytes.NewReader(target.Body))
+	if err != nil {
+		return &Status{Code: rpcCodes["INVALID_ARGUMENT"], Message: err.Error()}
+	}
+	for name, value := range target.Headers {
+		req.Header.Set(name, value)
+	}
+	if len(target.Body) > 0 && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/octet-stream")
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("X-CloudScheduler", "true")
+	req.Header.Set("X-CloudScheduler-JobName", job.Name[strings.LastIndex(job.Name, "/")+1:])
+	req.Header.Set("X-CloudScheduler-ScheduleTime", scheduleTime.UTC().Format(time.RFC3339))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return &Status{Code: rpcCodes["DEADLINE_EXCEEDED"], Message: fmt.Sprintf("no response from %s within %s", target.Uri, deadline)}
+		}
+		return &Status{Code: rpcCodes["UNAVAILABLE"], Message: err.Error()}
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return &Status{}
+	}
+	return &Status{Code: rpcCodes[gcperror.StatusFromCode(resp.StatusCode)], Message: fmt.Sprintf("%s returned HTTP status %d", target.Uri, resp.StatusCode)}
+}
@@ -0,0 +1,57 @@
+package cloudscheduler
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDispatcher_Attempt_HTTP(t *testing.T) {
+	var got *http.Request
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		got, body = r, string(data)
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	job := &Job{
+		Name: "projects/p/locations/l/jobs/cleanup",
+		HttpTarget: &HttpTarget{
+			Uri:        server.URL + "/tasks/cleanup",
+			HttpMethod: http.MethodPut,
+			Headers:    map[string]string{"X-Custom": "yes"},
+			Body:       []byte("hello"),
+		},
+	}
+	scheduleTime := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	if status := NewDispatcher().Attempt(job, scheduleTime); status.Code != 0 {
+		t.Fatalf("expected a successful attempt, got %+v", status)
+	}
+	if got.Method != http.MethodPut || got.URL.Path != "/tasks/cleanup" || body != "hello" {
+		t.Errorf("unexpected request %s %s with body %q", got.Method, got.URL.Path, body)
+	}
+	for header, expected := range map[string]string{
+		"X-Custom":                      "yes",
+		"Content-Type":                  "application/octet-stream",
+		"User-Agent":                    "Google-Cloud-Scheduler",
+		"X-CloudScheduler-JobName":      "cleanup",
+		"X-CloudScheduler-ScheduleTime": "2025-01-15T10:00:00Z",
+	} {
+		if value := got.Header.Get(header); value != expected {
+			t.Errorf("expected header %s %q, got %q", header, expected, value)
+		}
+	}
+
+	// Non-2xx responses fail the attempt with the matching code
+	job.HttpTarget.Uri = server.URL + "/fail"
+	if status := NewDispatcher().Attempt(job, scheduleTime); status.Code != rpcCodes["UNAVAILABLE"] {
+		t.Errorf("expected UNAVAILABLE, got %+v", status)
+	}
+}
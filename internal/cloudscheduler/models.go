@@ -0,0 +1,126 @@
+// Package cloudscheduler provides data models, schedule parsing and target delivery for the
+// Cloud Scheduler API (v1) mock.
+package cloudscheduler
+
+import (
+	"time"
+)
+
+// Job states.
+const (
+	StateEnabled = "ENABLED"
+	StatePaused  = "PAUSED"
+)
+
+// Job represents a Cloud Scheduler job.
+// Reference: https://cloud.google.com/scheduler/docs/reference/rest/v1/projects.locations.jobs
+type Job struct {
+	// Name is the resource name, e.g. projects/{project}/locations/{location}/jobs/{job}.
+	Name string `json:"name"`
+	// Description is a user-provided description of the job.
+	Description string `json:"description,omitempty"`
+	// Schedule is when the job runs in unix-cron format, e.g. "*/5 * * * *".
+	Schedule string `json:"schedule,omitempty"`
+	// TimeZone is the time zone the schedule is interpreted in, e.g. "Europe/Vienna". Defaults to UTC.
+	TimeZone string `json:"timeZone,omitempty"`
+	// UserUpdateTime is the time the job was last changed by a user.
+	UserUpdateTime time.Time `json:"userUpdateTime,omitzero"`
+	// State is ENABLED or PAUSED.
+	State string `json:"state,omitempty"`
+	// Status is the result of the last attempt; an empty status means it succeeded.
+	Status *Status `json:"status,omitempty"`
+	// ScheduleTime is the time of the next scheduled run.
+	ScheduleTime time.Time `json:"scheduleTime,omitzero"`
+	// LastAttemptTime is the time of the last attempt to run the job.
+	LastAttemptTime time.Time `json:"lastAttemptTime,omitzero"`
+	// RetryConfig controls retries of failed attempts. It is stored, but failed attempts aren't retried.
+	RetryConfig *RetryConfig `json:"retryConfig,omitempty"`
+	// AttemptDeadline is how long an HTTP attempt may take, e.g. "180s".
+	AttemptDeadline string `json:"attemptDeadline,omitempty"`
+
+	// The target of the job; exactly one is set.
+	HttpTarget          *HttpTarget          `json:"httpTarget,omitempty"`
+	PubsubTarget        *PubsubTarget        `json:"pubsubTarget,omitempty"`
+	AppEngineHttpTarget *AppEngineHttpTarget `json:"appEngineHttpTarget,omitempty"`
+}
+
+// HttpTarget is an HTTP endpoint the job sends a request to.
+// Reference: https://cloud.google.com/scheduler/docs/reference/rest/v1/projects.locations.jobs#HttpTarget
+type HttpTarget struct {
+	// Uri is the full URL of the request, e.g. https://example.com/tasks/cleanup.
+	Uri string `json:"uri"`
+	// HttpMethod is the method of the request. Defaults to POST.
+	HttpMethod string `json:"httpMethod,omitempty"`
+	// Headers are sent with the request.
+	Headers map[string]string `json:"headers,omitempty"`
+	// Body is the body of the request, base64-encoded in JSON.
+	Body []byte `json:"body,omitempty"`
+	// OauthToken and OidcToken configure the Authorization header. They are stored, but no token is sent.
+	OauthToken *OAuthToken `json:"oauthToken,omitempty"`
+	OidcToken  *OidcToken  `json:"oidcToken,omitempty"`
+}
+
+// OAuthToken configures an OAuth access token for an HTTP target.
+type OAuthToken struct {
+	ServiceAccountEmail string `json:"serviceAccountEmail,omitempty"`
+	Scope               string `json:"scope,omitempty"`
+}
+
+// OidcToken configures an OpenID Connect token for an HTTP target.
+type OidcToken struct {
+	ServiceAccountEmail string `json:"serviceAccountEmail,omitempty"`
+	Audience            string `json:"audience,omitempty"`
+}
+
+// PubsubTarget is a Pub/Sub topic the job publishes a message to.
+// Reference: https://cloud.google.com/scheduler/docs/reference/rest/v1/projects.locations.jobs#PubsubTarget
+type PubsubTarget struct {
+	// TopicName is the topic, e.g. projects/{project}/topics/{topic}.
+	TopicName string `json:"topicName"`
+	// Data is the message payload, base64-encoded in JSON.
+	Data []byte `json:"data,omitempty"`
+	// Attributes are the attributes of the message.
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// AppEngineHttpTarget is an App Engine endpoint the job sends a request to.
+// Reference: https://cloud.google.com/scheduler/docs/reference/rest/v1/projects.locations.jobs#AppEngineHttpTarget
+type AppEngineHttpTarget struct {
+	HttpMethod       string            `json:"httpMethod,omitempty"`
+	AppEngineRouting *AppEngineRouting `json:"appEngineRouting,omitempty"`
+	RelativeUri      string            `json:"relativeUri,omitempty"`
+	Headers          map[string]string `json:"headers,omitempty"`
+	Body             []byte            `json:"body,omitempty"`
+}
+
+// AppEngineRouting is the App Engine service, version and instance an App Engine target is sent to.
+type AppEngineRouting struct {
+	Service  string `json:"service,omitempty"`
+	Version  string `json:"version,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	Host     string `json:"host,omitempty"`
+}
+
+// RetryConfig controls retries of failed attempts.
+// Reference: https://cloud.google.com/scheduler/docs/reference/rest/v1/projects.locations.jobs#RetryConfig
+type RetryConfig struct {
+	RetryCount         int    `json:"retryCount,omitempty"`
+	MaxRetryDuration   string `json:"maxRetryDuration,omitempty"`
+	MinBackoffDuration string `json:"minBackoffDuration,omitempty"`
+	MaxBackoffDuration string `json:"maxBackoffDuration,omitempty"`
+	MaxDoublings       int    `json:"maxDoublings,omitempty"`
+}
+
+// Status is a google.rpc.Status describing the result of an attempt.
+type Status struct {
+	// Code is the google.rpc.Code, e.g. 5 for NOT_FOUND; 0 means OK.
+	Code int `json:"code,omitempty"`
+	// Message describes the error.
+	Message string `json:"message,omitempty"`
+}
+
+// ListJobsResponse is the response of jobs.list.
+type ListJobsResponse struct {
+	Jobs          []*Job `json:"jobs,omitempty"`
+	NextPageToken string `json:"nextPageToken,omitempty"`
+}
@@ -0,0 +1,168 @@
+package cloudscheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	// Jobs name their time zone, like "Europe/Vienna", which the container image has no database for
+	_ "time/tzdata"
+)
+
+// Schedule is a parsed unix-cron schedule like "*/5 9-17 * * MON-FRI".
+// Reference: https://cloud.google.com/scheduler/docs/configuring/cron-job-schedules
+type Schedule struct {
+	minutes, hours, days, months, weekdays uint64
+	// daysRestricted and weekdaysRestricted are true if the field doesn't start with "*". If both are,
+	// a day matches if either of them does, like in cron.
+	daysRestricted, weekdaysRestricted bool
+}
+
+// scheduleField describes a field of a unix-cron schedule.
+type scheduleField struct {
+	name     string
+	min, max int
+	names    []string
+}
+
+var (
+	minuteField  = scheduleField{name: "minute", min: 0, max: 59}
+	hourField    = scheduleField{name: "hour", min: 0, max: 23}
+	dayField     = scheduleField{name: "day of month", min: 1, max: 31}
+	monthField   = scheduleField{name: "month", min: 1, max: 12, names: []string{"JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}}
+	weekdayField = scheduleField{name: "day of week", min: 0, max: 7, names: []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}}
+)
+
+// maxScheduleSearch is how far ahead Next looks for a matching time, so schedules like "0 0 31 2 *"
+// that never match don't loop forever.
+const maxScheduleSearch = 5 * 366 * 24 * time.Hour
+
+// ParseSchedule parses a schedule in unix-cron format: minute, hour, day of month, month and day of week,
+// each a "*", a value, a range like "1-5" or a list like "1,15", optionally with a step like "*/10".
+// Months and days of week may be given by name, like "JAN" or "MON"; 0 and 7 are Sunday.
+func ParseSchedule(spec string) (*Schedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid schedule %q: expected 5 fields (minute hour day-of-month month day-of-week), got %d", spec, len(fields))
+	}
+
+	s := &Schedule{
+		daysRestricted:     !strings.HasPrefix(fields[2], "*"),
+		weekdaysRestricted: !strings.HasPrefix(fields[4], "*"),
+	}
+	var err error
+	for i, target := range []struct {
+		field scheduleField
+		bits  *uint64
+	}{
+		{minuteField, &s.minutes},
+		{hourField, &s.hours},
+		{dayField, &s.days},
+		{monthField, &s.months},
+		{weekdayField, &s.weekdays},
+	} {
+		if *target.bits, err = target.field.parse(fields[i]); err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %w", spec, err)
+		}
+	}
+	// 7 is Sunday too
+	if s.weekdays&(1<<7) != 0 {
+		s.weekdays |= 1
+	}
+	return s, nil
+}
+
+// parse parses a field into a bit set of the values it matches.
+func (f scheduleField) parse(value string) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(value, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepPart); err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step %q in %s field", stepPart, f.name)
+			}
+		}
+
+		first, last := f.min, f.max
+		if rangePart != "*" {
+			lower, upper, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if first, err = f.value(lower); err != nil {
+				return 0, err
+			}
+			last = first
+			if isRange {
+				if last, err = f.value(upper); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				// "5/15" means every 15 from 5 on
+				last = f.max
+			}
+			if last < first {
+				return 0, fmt.Errorf("invalid range %q in %s field", rangePart, f.name)
+			}
+		}
+
+		for v := first; v <= last; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+// value parses a single value of a field, by number or name.
+func (f scheduleField) value(s string) (int, error) {
+	for i, name := range f.names {
+		if strings.EqualFold(s, name) {
+			return f.min + i, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("invalid value %q in %s field: must be between %d and %d", s, f.name, f.min, f.max)
+	}
+	return v, nil
+}
+
+// Next returns the first time after t the schedule matches, in t's location, or the zero time if it doesn't
+// match within the next five years.
+func (s *Schedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(maxScheduleSearch)
+
+	for t.Before(limit) {
+		if s.months&(1<<int(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !s.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if s.hours&(1<<t.Hour()) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if s.minutes&(1<<t.Minute()) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// matchesDay reports whether the day of t matches the day of month and day of week fields.
+func (s *Schedule) matchesDay(t time.Time) bool {
+	day := s.days&(1<<t.Day()) != 0
+	weekday := s.weekdays&(1<<int(t.Weekday())) != 0
+	if s.daysRestricted && s.weekdaysRestricted {
+		return day || weekday
+	}
+	return day && weekday
+}
@@ -0,0 +1,128 @@
+// Package compute provides data models for the VPC networks and subnetworks of the Compute Engine API (v1) mock.
+package compute
+
+import "time"
+
+// Operation states.
+const (
+	OperationDone = "DONE"
+)
+
+// Network is a VPC network.
+// Reference: https://cloud.google.com/compute/docs/reference/rest/v1/networks
+type Network struct {
+	// Kind is always "compute#network".
+	Kind string `json:"kind"`
+	// Id is the unique numeric ID of the network.
+	Id string `json:"id,omitempty"`
+	// CreationTimestamp is the time the network was created.
+	CreationTimestamp time.Time `json:"creationTimestamp,omitzero"`
+	// Name is the name of the network, unique within its project.
+	Name string `json:"name"`
+	// Description is an optional description.
+	Description string `json:"description,omitempty"`
+	// SelfLink is the URL of the network.
+	SelfLink string `json:"selfLink,omitempty"`
+	// AutoCreateSubnetworks creates an auto mode network if true, a custom mode network if false.
+	// It defaults to true, like with gcloud.
+	AutoCreateSubnetworks *bool `json:"autoCreateSubnetworks,omitempty"`
+	// Subnetworks are the URLs of the subnetworks of the network.
+	Subnetworks []string `json:"subnetworks,omitempty"`
+	// RoutingConfig configures whether routes are learned regionally or globally.
+	RoutingConfig *RoutingConfig `json:"routingConfig,omitempty"`
+	// Mtu is the maximum transmission unit in bytes, between 1300 and 8896.
+	Mtu int `json:"mtu,omitempty"`
+	// NetworkFirewallPolicyEnforcementOrder is AFTER_CLASSIC_FIREWALL or BEFORE_CLASSIC_FIREWALL.
+	NetworkFirewallPolicyEnforcementOrder string `json:"networkFirewallPolicyEnforcementOrder,omitempty"`
+}
+
+// RoutingConfig configures the dynamic routing of a network.
+type RoutingConfig struct {
+	// RoutingMode is REGIONAL or GLOBAL.
+	RoutingMode string `json:"routingMode,omitempty"`
+}
+
+// Subnetwork is a regional IP range of a VPC network.
+// Reference: https://cloud.google.com/compute/docs/reference/rest/v1/subnetworks
+type Subnetwork struct {
+	// Kind is always "compute#subnetwork".
+	Kind string `json:"kind"`
+	// Id is the unique numeric ID of the subnetwork.
+	Id string `json:"id,omitempty"`
+	// CreationTimestamp is the time the subnetwork was created.
+	CreationTimestamp time.Time `json:"creationTimestamp,omitzero"`
+	// Name is the name of the subnetwork, unique within its project and region.
+	Name string `json:"name"`
+	// Description is an optional description.
+	Description string `json:"description,omitempty"`
+	// Network is the URL of the network of the subnetwork.
+	Network string `json:"network,omitempty"`
+	// IpCidrRange is the primary IP range, e.g. 10.0.0.0/24.
+	IpCidrRange string `json:"ipCidrRange,omitempty"`
+	// GatewayAddress is the gateway address for default routes, the first address of IpCidrRange.
+	GatewayAddress string `json:"gatewayAddress,omitempty"`
+	// Region is the URL of the region of the subnetwork.
+	Region string `json:"region,omitempty"`
+	// SelfLink is the URL of the subnetwork.
+	SelfLink string `json:"selfLink,omitempty"`
+	// PrivateIpGoogleAccess lets VMs without external IP addresses reach Google APIs.
+	PrivateIpGoogleAccess bool `json:"privateIpGoogleAccess,omitempty"`
+	// SecondaryIpRanges are additional ranges, e.g. for the pods and services of GKE clusters.
+	SecondaryIpRanges []*SecondaryIpRange `json:"secondaryIpRanges,omitempty"`
+	// Fingerprint changes with every update and must be sent with patch requests.
+	Fingerprint string `json:"fingerprint,omitempty"`
+	// Purpose is the purpose of the subnetwork, e.g. PRIVATE.
+	Purpose string `json:"purpose,omitempty"`
+	// StackType is IPV4_ONLY or IPV4_IPV6.
+	StackType string `json:"stackType,omitempty"`
+}
+
+// SecondaryIpRange is a named secondary IP range of a subnetwork.
+type SecondaryIpRange struct {
+	RangeName   string `json:"rangeName"`
+	IpCidrRange string `json:"ipCidrRange"`
+}
+
+// Operation is a Compute Engine operation, which has its own type instead of google.longrunning.Operation.
+// Reference: https://cloud.google.com/compute/docs/reference/rest/v1/globalOperations
+type Operation struct {
+	// Kind is always "compute#operation".
+	Kind string `json:"kind"`
+	// Id is the unique numeric ID of the operation.
+	Id string `json:"id,omitempty"`
+	// Name is the name of the operation, e.g. operation-1700000000000-1a2b3c4d.
+	Name string `json:"name"`
+	// OperationType is the kind of change: insert, patch or delete.
+	OperationType string `json:"operationType,omitempty"`
+	// TargetLink is the URL of the changed resource and TargetId its ID.
+	TargetLink string `json:"targetLink,omitempty"`
+	TargetId   string `json:"targetId,omitempty"`
+	// Status is the state of the operation, e.g. DONE.
+	Status string `json:"status,omitempty"`
+	// Progress is the progress in percent; always 100.
+	Progress int `json:"progress"`
+	// InsertTime, StartTime and EndTime are when the operation was requested, started and finished.
+	InsertTime time.Time `json:"insertTime,omitzero"`
+	StartTime  time.Time `json:"startTime,omitzero"`
+	EndTime    time.Time `json:"endTime,omitzero"`
+	// SelfLink is the URL of the operation.
+	SelfLink string `json:"selfLink,omitempty"`
+	// Region is the URL of the region of regional operations; global operations have none.
+	Region string `json:"region,omitempty"`
+}
+
+// NetworkList is the response of networks.list.
+type NetworkList struct {
+	Kind          string     `json:"kind"`
+	Items         []*Network `json:"items,omitempty"`
+	NextPageToken string     `json:"nextPageToken,omitempty"`
+	SelfLink      string     `json:"selfLink,omitempty"`
+}
+
+// SubnetworkList is the response of subnetworks.list.
+type SubnetworkList struct {
+	Kind          string        `json:"kind"`
+	Items         []*Subnetwork `json:"items,omitempty"`
+	NextPageToken string        `json:"nextPageToken,omitempty"`
+	SelfLink      string        `json:"selfLink,omitempty"`
+}
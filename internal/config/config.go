@@ -3,7 +3,10 @@ package config
 
 import (
 	"fmt"
+	"math"
 	"os"
+	"strconv"
+	"strings"
 )
 
 // Config holds the application configuration.
@@ -16,14 +19,262 @@ type Config struct {
 
 	// Environment is the runtime environment (development, production).
 	Environment string
+
+	// BlobDir is the directory for storing object content on disk.
+	// If empty, object content is kept in memory.
+	BlobDir string
+
+	// MaxObjectSize is the size limit of uploaded objects, e.g. "100MiB" or "5000000".
+	// If empty, object size is not limited.
+	MaxObjectSize string
+
+	// BlobDedup stores object content addressed by its hash, so equal content is stored only once.
+	BlobDedup bool
+
+	// MaxContentSize limits the total size of the stored content, e.g. "2GiB". When it is exceeded,
+	// the content of the least recently used objects is evicted, keeping their metadata.
+	// If empty, the stored content is not limited.
+	MaxContentSize string
+
+	// RecordFile is the file to record API requests to from startup.
+	// If empty, recording can still be started via the admin API.
+	RecordFile string
+
+	// RedactHeaders lists the headers whose values are redacted before requests are logged or recorded,
+	// e.g. "Authorization,X-Goog-Api-Key".
+	RedactHeaders []string
+
+	// RedactJSONPaths lists the JSON paths whose values are redacted from request and response bodies
+	// before they are logged or recorded, e.g. "$.password,$..token".
+	RedactJSONPaths []string
+
+	// AuditLogFile is the file Cloud Audit Logs entries for admin actions are appended to as JSON lines.
+	AuditLogFile string
+
+	// AuditLogURL is the URL Cloud Audit Logs entries for admin actions are posted to, e.g. a SIEM webhook.
+	AuditLogURL string
+
+	// SnapshotFile is a snapshot downloaded from /admin/snapshot to restore at startup.
+	SnapshotFile string
+
+	// AuthMode controls authentication enforcement (permissive, strict).
+	// In strict mode, API requests must carry a Bearer token for the right project.
+	AuthMode string
+
+	// S3Enabled enables the S3 compatibility layer for SigV4-signed requests.
+	S3Enabled bool
+
+	// S3AccessKey and S3SecretKey are the credentials S3 request signatures are verified against.
+	// If either is empty, signatures are accepted without verification.
+	S3AccessKey string
+	S3SecretKey string
+
+	// Latency is a latency profile injected into API requests, e.g. "storage.get=20ms-80ms,sql.insert=2s-5s".
+	// It can be changed at runtime via the admin API.
+	Latency string
+
+	// LatencyFile is a JSON file with a latency profile; entries in Latency take precedence.
+	LatencyFile string
+
+	// SLO is a service level objective simulated over all API requests, e.g. "success=99.9%,p50=20ms,p99=300ms".
+	// It can be changed at runtime via the admin API.
+	SLO string
+
+	// SchemaValidation checks the responses of the Cloud Storage and Cloud SQL Admin APIs against Google's
+	// discovery documents: "log" logs divergences, "fail" also answers with 500. If empty, responses aren't checked.
+	SchemaValidation string
+
+	// SchemaDir is a directory with the discovery documents to check responses against, named like
+	// storage.v1.json and sqladmin.v1beta4.json. If empty, they are downloaded from Google at startup.
+	SchemaDir string
+
+	// TemplatesFile is a JSON file with response templates to add at startup, e.g.
+	// [{"path": "/storage/v1/b/*", "template": "{{ json (set . \"satisfiesPZS\" false) }}"}].
+	// More templates can be added via the admin API.
+	TemplatesFile string
+
+	// ClockSkew shifts the time reported in HTTP headers like Date, e.g. "-5m", so client-side clock checks can be
+	// tested. It doesn't change the time of resources and can be changed at runtime via the admin API.
+	ClockSkew string
+
+	// SQLCreateDelay is how long new Cloud SQL instances stay in PENDING_CREATE, e.g. "3m".
+	// If empty, instances are RUNNABLE right away.
+	SQLCreateDelay string
+
+	// SQLMaintenanceDuration is how long Cloud SQL instances are in MAINTENANCE once the clock reaches
+	// their maintenance window, e.g. "10m". If empty, maintenance only starts through the admin API.
+	SQLMaintenanceDuration string
+
+	// SchedulerCron runs Cloud Scheduler jobs on their schedule, delivering them to their targets.
+	// Without it, jobs only run via jobs.run or the admin API.
+	SchedulerCron bool
+
+	// MemorystoreEndpoint is the address of a Redis server, like "localhost:6379", that Memorystore instances
+	// report as their host and port, so clients can connect to it. If empty, instances get made-up private addresses.
+	MemorystoreEndpoint string
+
+	// GKEKubeconfig is the path of the kubeconfig file of a real cluster, like one written by `kind get kubeconfig`.
+	// GKE clusters report its API server as their endpoint, and their kubeconfig connects to it.
+	GKEKubeconfig string
+
+	// SQLProxyPorts are the ports Cloud SQL instances get, like "13306-13399", or "0" to let the system pick them.
+	// If empty, instances get no ports.
+	SQLProxyPorts string
+
+	// SQLProxyTargets are the databases connections to the instance ports are forwarded to, like
+	// "my-project:us-central1:main=localhost:5432". Connections to other instances are closed right away.
+	SQLProxyTargets string
+
+	// SQLDataDir is a directory with a SQLite file for each Cloud SQL database, so tests can run queries.
+	// If empty, databases are metadata only.
+	SQLDataDir string
+
+	// MirrorDir is a directory the buckets are mirrored to: each bucket is a directory and each object a file,
+	// kept in sync both ways. If empty, buckets aren't mirrored.
+	MirrorDir string
+
+	// MirrorInterval is how often the mirror directory is checked for local edits, e.g. "1s".
+	MirrorInterval string
+
+	// StrictValidation rejects requests the real APIs reject but the mock otherwise accepts: unknown JSON fields,
+	// missing required parameters, invalid bucket names, unknown bucket locations and unavailable
+	// Cloud SQL tiers and regions.
+	StrictValidation bool
+
+	// StrictObjectPaths rejects object names that aren't clean paths, like "/a.txt" or "a/../b.txt",
+	// which Cloud Storage accepts but which usually point at path handling bugs.
+	StrictObjectPaths bool
+
+	// DisabledServices lists the services that answer 403 SERVICE_DISABLED, e.g. "sqladmin.googleapis.com".
+	// Each service is enabled unless its GCP_MOCK_ENABLE_* variable is "false".
+	DisabledServices []string
+
+	// TLSEnabled serves HTTPS instead of HTTP. Without a certificate, a self-signed one is generated
+	// and its CA certificate can be downloaded from /admin/tls/ca.pem.
+	TLSEnabled bool
+
+	// TLSCertFile and TLSKeyFile are the PEM files of the certificate to serve HTTPS with.
+	// Setting them enables TLS.
+	TLSCertFile string
+	TLSKeyFile  string
+
+	// BaseURL is the external URL of the mock used in selfLinks and mediaLinks, e.g. "https://gcp-mock.internal:8443".
+	// If empty, it is derived from the port and whether TLS is enabled.
+	BaseURL string
+
+	// ShutdownTimeout is how long uploads and downloads in flight may take to finish on shutdown, e.g. "30s".
+	// Transfers still running after it are aborted.
+	ShutdownTimeout string
+
+	// NamespaceTTL is how long a namespace is kept after its last request, e.g. "1h".
+	// If empty or "0", namespaces are kept until they're deleted.
+	NamespaceTTL string
+
+	// RedisURL is the URL of a Redis server replicas share their state through, e.g. "redis://:password@redis:6379/0".
+	// If empty, each replica keeps its own state.
+	RedisURL string
+
+	// RedisKey is the prefix of the Redis keys holding the shared state, so several deployments can share a Redis server.
+	RedisKey string
+
+	// ReadOnly keeps mutating API requests from changing state: "success" answers them with a synthetic
+	// success, "reject" with 403. It can be changed at runtime via the admin API.
+	ReadOnly string
+
+	// VirtualHostDomains are the domains whose subdomains are treated as bucket names,
+	// so my-bucket.storage.googleapis.com/file.txt is served like /my-bucket/file.txt.
+	VirtualHostDomains []string
+}
+
+// serviceToggles maps the environment variables that enable or disable a service to the service name.
+var serviceToggles = []struct {
+	envVar  string
+	service string
+}{
+	{"GCP_MOCK_ENABLE_STORAGE", "storage.googleapis.com"},
+	{"GCP_MOCK_ENABLE_SQLADMIN", "sqladmin.googleapis.com"},
+	{"GCP_MOCK_ENABLE_FIRESTORE", "firestore.googleapis.com"},
+	{"GCP_MOCK_ENABLE_ARTIFACTREGISTRY", "artifactregistry.googleapis.com"},
+	{"GCP_MOCK_ENABLE_RUN", "run.googleapis.com"},
+	{"GCP_MOCK_ENABLE_MONITORING", "monitoring.googleapis.com"},
+	{"GCP_MOCK_ENABLE_LOGGING", "logging.googleapis.com"},
+	{"GCP_MOCK_ENABLE_SCHEDULER", "cloudscheduler.googleapis.com"},
+	{"GCP_MOCK_ENABLE_EVENTARC", "eventarc.googleapis.com"},
+	{"GCP_MOCK_ENABLE_REDIS", "redis.googleapis.com"},
+	{"GCP_MOCK_ENABLE_CONTAINER", "container.googleapis.com"},
+	{"GCP_MOCK_ENABLE_COMPUTE", "compute.googleapis.com"},
+	{"GCP_MOCK_ENABLE_BILLINGBUDGETS", "billingbudgets.googleapis.com"},
+	{"GCP_MOCK_ENABLE_ORGPOLICY", "orgpolicy.googleapis.com"},
 }
 
 // Load reads configuration from environment variables with sensible defaults.
 func Load() *Config {
+	var disabled []string
+	for _, toggle := range serviceToggles {
+		if getEnv(toggle.envVar, "true") == "false" {
+			disabled = append(disabled, toggle.service)
+		}
+	}
+
 	return &Config{
 		Host:        getEnv("GCP_MOCK_HOST", "0.0.0.0"),
 		Port:        getEnv("GCP_MOCK_PORT", "8080"),
 		Environment: getEnv("GCP_MOCK_ENV", "development"),
+		BlobDir:     getEnv("GCP_MOCK_BLOB_DIR", ""),
+		BlobDedup:   getEnv("GCP_MOCK_BLOB_DEDUP", "false") == "true",
+		RecordFile:  getEnv("GCP_MOCK_RECORD_FILE", ""),
+		AuthMode:    getEnv("GCP_MOCK_AUTH_MODE", "permissive"),
+		S3Enabled:   getEnv("GCP_MOCK_S3_ENABLED", "false") == "true",
+		S3AccessKey: getEnv("GCP_MOCK_S3_ACCESS_KEY", ""),
+		S3SecretKey: getEnv("GCP_MOCK_S3_SECRET_KEY", ""),
+
+		MaxObjectSize:  getEnv("GCP_MOCK_MAX_OBJECT_SIZE", ""),
+		MaxContentSize: getEnv("GCP_MOCK_MAX_CONTENT_SIZE", ""),
+
+		Latency:        getEnv("GCP_MOCK_LATENCY", ""),
+		LatencyFile:    getEnv("GCP_MOCK_LATENCY_FILE", ""),
+		SLO:            getEnv("GCP_MOCK_SLO", ""),
+		TemplatesFile:  getEnv("GCP_MOCK_TEMPLATES_FILE", ""),
+		ClockSkew:      getEnv("GCP_MOCK_CLOCK_SKEW", ""),
+		SQLCreateDelay: getEnv("GCP_MOCK_SQL_CREATE_DELAY", ""),
+
+		SchemaValidation: getEnv("GCP_MOCK_SCHEMA_VALIDATION", ""),
+		SchemaDir:        getEnv("GCP_MOCK_SCHEMA_DIR", ""),
+
+		SQLMaintenanceDuration: getEnv("GCP_MOCK_SQL_MAINTENANCE_DURATION", ""),
+		SQLProxyPorts:          getEnv("GCP_MOCK_SQL_PROXY_PORTS", ""),
+		SQLProxyTargets:        getEnv("GCP_MOCK_SQL_PROXY_TARGETS", ""),
+		SQLDataDir:             getEnv("GCP_MOCK_SQL_DATA_DIR", ""),
+
+		MirrorDir:      getEnv("GCP_MOCK_MIRROR_DIR", ""),
+		MirrorInterval: getEnv("GCP_MOCK_MIRROR_INTERVAL", "1s"),
+
+		RedactHeaders:   splitList(getEnv("GCP_MOCK_REDACT_HEADERS", "")),
+		RedactJSONPaths: splitList(getEnv("GCP_MOCK_REDACT_JSON_PATHS", "")),
+
+		AuditLogFile: getEnv("GCP_MOCK_AUDIT_LOG_FILE", ""),
+		AuditLogURL:  getEnv("GCP_MOCK_AUDIT_LOG_URL", ""),
+
+		StrictValidation:  getEnv("GCP_MOCK_STRICT_VALIDATION", "false") == "true",
+		StrictObjectPaths: getEnv("GCP_MOCK_STRICT_OBJECT_PATHS", "false") == "true",
+		SchedulerCron:     getEnv("GCP_MOCK_SCHEDULER_CRON", "false") == "true",
+
+		MemorystoreEndpoint: getEnv("GCP_MOCK_MEMORYSTORE_ENDPOINT", ""),
+		GKEKubeconfig:       getEnv("GCP_MOCK_GKE_KUBECONFIG", ""),
+
+		DisabledServices: disabled,
+		SnapshotFile:     getEnv("GCP_MOCK_SNAPSHOT_FILE", ""),
+		NamespaceTTL:     getEnv("GCP_MOCK_NAMESPACE_TTL", "1h"),
+		ShutdownTimeout:  getEnv("GCP_MOCK_SHUTDOWN_TIMEOUT", "30s"),
+		RedisURL:         getEnv("GCP_MOCK_REDIS_URL", ""),
+		RedisKey:         getEnv("GCP_MOCK_REDIS_KEY", "gcp-api-mock"),
+		ReadOnly:         getEnv("GCP_MOCK_READ_ONLY", ""),
+
+		TLSEnabled:         getEnv("GCP_MOCK_TLS", "false") == "true",
+		TLSCertFile:        getEnv("GCP_MOCK_TLS_CERT_FILE", ""),
+		TLSKeyFile:         getEnv("GCP_MOCK_TLS_KEY_FILE", ""),
+		BaseURL:            getEnv("GCP_MOCK_BASE_URL", ""),
+		VirtualHostDomains: splitList(getEnv("GCP_MOCK_VIRTUAL_HOST_DOMAINS", "storage.googleapis.com")),
 	}
 }
 
@@ -37,6 +288,71 @@ func (c *Config) IsDevelopment() bool {
 	return c.Environment == "development"
 }
 
+// IsStrictAuth returns true if API requests must be authenticated.
+func (c *Config) IsStrictAuth() bool {
+	return c.AuthMode == "strict"
+}
+
+// IsTLS returns true if the server should serve HTTPS.
+func (c *Config) IsTLS() bool {
+	return c.TLSEnabled || (c.TLSCertFile != "" && c.TLSKeyFile != "")
+}
+
+// ExternalURL returns the base URL clients reach the mock at.
+func (c *Config) ExternalURL() string {
+	if c.BaseURL != "" {
+		return strings.TrimSuffix(c.BaseURL, "/")
+	}
+
+	scheme := "http"
+	if c.IsTLS() {
+		scheme = "https"
+	}
+	port := c.Port
+	if port == "" {
+		port = "8080"
+	}
+	return fmt.Sprintf("%s://localhost:%s", scheme, port)
+}
+
+// byteSizeUnits are the units ParseByteSize accepts, longest first so prefixes don't shadow them.
+var byteSizeUnits = []struct {
+	suffix string
+	factor int64
+}{
+	{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30}, {"TiB", 1 << 40},
+	{"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9}, {"TB", 1e12},
+	{"B", 1},
+}
+
+// ParseByteSize parses a size like "512", "100MB" or "5GiB" into bytes.
+func ParseByteSize(s string) (int64, error) {
+	value, factor := strings.TrimSpace(s), int64(1)
+	for _, unit := range byteSizeUnits {
+		if number, found := strings.CutSuffix(value, unit.suffix); found {
+			value, factor = strings.TrimSpace(number), unit.factor
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 || n > math.MaxInt64/factor {
+		return 0, fmt.Errorf("invalid size %q: expected a number of bytes with an optional unit like MiB or GB", s)
+	}
+	return n * factor, nil
+}
+
+// splitList splits a comma-separated list, dropping empty entries.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 // getEnv retrieves an environment variable or returns a default value.
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
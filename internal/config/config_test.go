@@ -1,7 +1,11 @@
 package config
 
 import (
+	"errors"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -10,7 +14,9 @@ func TestLoad(t *testing.T) {
 	originalHost := os.Getenv("GCP_MOCK_HOST")
 	originalPort := os.Getenv("GCP_MOCK_PORT")
 	originalEnv := os.Getenv("GCP_MOCK_ENV")
+	originalS3Enabled := os.Getenv("GCP_MOCK_S3_ENABLED")
 	defer func() {
+		os.Setenv("GCP_MOCK_S3_ENABLED", originalS3Enabled)
 		os.Setenv("GCP_MOCK_HOST", originalHost)
 		os.Setenv("GCP_MOCK_PORT", originalPort)
 		os.Setenv("GCP_MOCK_ENV", originalEnv)
@@ -20,6 +26,7 @@ func TestLoad(t *testing.T) {
 		os.Unsetenv("GCP_MOCK_HOST")
 		os.Unsetenv("GCP_MOCK_PORT")
 		os.Unsetenv("GCP_MOCK_ENV")
+		os.Unsetenv("GCP_MOCK_S3_ENABLED")
 
 		cfg := Load()
 
@@ -32,12 +39,16 @@ func TestLoad(t *testing.T) {
 		if cfg.Environment != "development" {
 			t.Errorf("expected Environment 'development', got '%s'", cfg.Environment)
 		}
+		if cfg.S3Enabled {
+			t.Error("expected S3Enabled to be false")
+		}
 	})
 
 	t.Run("custom values from environment", func(t *testing.T) {
 		os.Setenv("GCP_MOCK_HOST", "127.0.0.1")
 		os.Setenv("GCP_MOCK_PORT", "9090")
 		os.Setenv("GCP_MOCK_ENV", "production")
+		os.Setenv("GCP_MOCK_S3_ENABLED", "true")
 
 		cfg := Load()
 
@@ -50,9 +61,30 @@ func TestLoad(t *testing.T) {
 		if cfg.Environment != "production" {
 			t.Errorf("expected Environment 'production', got '%s'", cfg.Environment)
 		}
+		if !cfg.S3Enabled {
+			t.Error("expected S3Enabled to be true")
+		}
 	})
 }
 
+func TestLoad_DisabledServices(t *testing.T) {
+	t.Setenv("GCP_MOCK_ENABLE_SQLADMIN", "false")
+	t.Setenv("GCP_MOCK_ENABLE_RUN", "false")
+	t.Setenv("GCP_MOCK_ENABLE_STORAGE", "true")
+
+	cfg := Load()
+
+	expected := []string{"sqladmin.googleapis.com", "run.googleapis.com"}
+	if len(cfg.DisabledServices) != len(expected) {
+		t.Fatalf("expected DisabledServices %v, got %v", expected, cfg.DisabledServices)
+	}
+	for i, service := range expected {
+		if cfg.DisabledServices[i] != service {
+			t.Errorf("expected DisabledServices %v, got %v", expected, cfg.DisabledServices)
+		}
+	}
+}
+
 func TestConfig_Address(t *testing.T) {
 	cfg := &Config{Host: "localhost", Port: "3000"}
 	expected := "localhost:3000"
@@ -82,3 +114,179 @@ func TestConfig_IsDevelopment(t *testing.T) {
 		})
 	}
 }
+
+func TestConfig_IsStrictAuth(t *testing.T) {
+	tests := []struct {
+		name     string
+		authMode string
+		want     bool
+	}{
+		{"strict mode", "strict", true},
+		{"permissive mode", "permissive", false},
+		{"empty mode", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{AuthMode: tt.authMode}
+			if got := cfg.IsStrictAuth(); got != tt.want {
+				t.Errorf("IsStrictAuth() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfig_ExternalURL(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		want string
+	}{
+		{"default", Config{}, "http://localhost:8080"},
+		{"custom port", Config{Port: "9090"}, "http://localhost:9090"},
+		{"tls", Config{Port: "8443", TLSEnabled: true}, "https://localhost:8443"},
+		{"tls from cert files", Config{Port: "8443", TLSCertFile: "cert.pem", TLSKeyFile: "key.pem"}, "https://localhost:8443"},
+		{"base url", Config{Port: "8080", BaseURL: "https://gcp-mock.internal/"}, "https://gcp-mock.internal"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cfg.ExternalURL(); got != tt.want {
+				t.Errorf("ExternalURL() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    int64
+		wantErr bool
+	}{
+		{"512", 512, false},
+		{"0", 0, false},
+		{"100B", 100, false},
+		{"1KiB", 1024, false},
+		{"32MiB", 32 << 20, false},
+		{"5 GiB", 5 << 30, false},
+		{"100MB", 100_000_000, false},
+		{"1TB", 1_000_000_000_000, false},
+		{"", 0, true},
+		{"MiB", 0, true},
+		{"-1", 0, true},
+		{"1.5GB", 0, true},
+		{"10XB", 0, true},
+		{"9999999999TiB", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseByteSize(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseByteSize(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseByteSize(%q) = %d, want %d", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	t.Run("defaults are valid", func(t *testing.T) {
+		if err := Load().Validate(); err != nil {
+			t.Errorf("expected the defaults to be valid, got %v", err)
+		}
+	})
+
+	t.Run("all invalid settings are reported", func(t *testing.T) {
+		cfg := &Config{
+			Port:                   "80a",
+			AuthMode:               "lenient",
+			ReadOnly:               "yes",
+			MaxObjectSize:          "1.5GB",
+			SQLCreateDelay:         "3 minutes",
+			SQLMaintenanceDuration: "0s",
+			NamespaceTTL:           "-1h",
+			Latency:                "storage.get=80ms-20ms",
+			SLO:                    "success=99.9%,p50=300ms,p99=20ms",
+			SQLProxyPorts:          "13399-13306",
+			MemorystoreEndpoint:    "localhost",
+			GKEKubeconfig:          filepath.Join(t.TempDir(), "missing"),
+			TLSCertFile:            "cert.pem",
+			BaseURL:                "localhost:8080",
+			RedisURL:               "http://redis:6379",
+			SnapshotFile:           t.TempDir(),
+			RedactJSONPaths:        []string{"$.password", "password"},
+		}
+
+		err := cfg.Validate()
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Fatalf("expected a ValidationError, got %v", err)
+		}
+
+		want := []string{
+			"GCP_MOCK_PORT",
+			"GCP_MOCK_AUTH_MODE",
+			"GCP_MOCK_READ_ONLY",
+			"GCP_MOCK_MAX_OBJECT_SIZE",
+			"GCP_MOCK_SQL_CREATE_DELAY",
+			"GCP_MOCK_SQL_MAINTENANCE_DURATION",
+			"GCP_MOCK_NAMESPACE_TTL",
+			"GCP_MOCK_LATENCY",
+			"GCP_MOCK_SLO",
+			"GCP_MOCK_SQL_PROXY_PORTS",
+			"GCP_MOCK_MEMORYSTORE_ENDPOINT",
+			"GCP_MOCK_GKE_KUBECONFIG",
+			"GCP_MOCK_TLS_KEY_FILE",
+			"GCP_MOCK_BASE_URL",
+			"GCP_MOCK_REDIS_URL",
+			"GCP_MOCK_SNAPSHOT_FILE",
+			"GCP_MOCK_REDACT_JSON_PATHS",
+		}
+		var got []string
+		for _, field := range validationErr.Fields {
+			got = append(got, field.Field)
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("expected errors for\n%v\ngot\n%v", want, got)
+		}
+
+		message := err.Error()
+		for _, line := range []string{
+			`GCP_MOCK_SQL_CREATE_DELAY="3 minutes": must be a duration like 30s, 5m or 1h`,
+			`GCP_MOCK_NAMESPACE_TTL="-1h": must not be negative`,
+			`GCP_MOCK_SQL_MAINTENANCE_DURATION="0s": must be positive`,
+		} {
+			if !strings.Contains(message, "\n  "+line) {
+				t.Errorf("expected the error to contain %q, got:\n%s", line, message)
+			}
+		}
+	})
+
+	t.Run("valid settings", func(t *testing.T) {
+		kubeconfig := filepath.Join(t.TempDir(), "kubeconfig")
+		if err := os.WriteFile(kubeconfig, []byte("clusters:\n- cluster:\n    server: https://127.0.0.1:6443\n  name: kind-kind\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		cfg := &Config{
+			Port:                "0",
+			ReadOnly:            "success",
+			MaxContentSize:      "2GiB",
+			ClockSkew:           "-5m",
+			NamespaceTTL:        "0",
+			Latency:             "storage.get=20ms-80ms,*=5ms",
+			SQLProxyPorts:       "0",
+			SQLProxyTargets:     "my-project:us-central1:main=localhost:5432",
+			MemorystoreEndpoint: "localhost:6379",
+			GKEKubeconfig:       kubeconfig,
+			BaseURL:             "https://gcp-mock.internal:8443",
+			RedisURL:            "redis://:password@redis:6379/0",
+		}
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("expected the configuration to be valid, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,218 @@
+package config
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/gke"
+	"github.com/katharinasick/gcp-api-mock/internal/latency"
+	"github.com/katharinasick/gcp-api-mock/internal/readonly"
+	"github.com/katharinasick/gcp-api-mock/internal/redact"
+	"github.com/katharinasick/gcp-api-mock/internal/slo"
+	"github.com/katharinasick/gcp-api-mock/internal/sqlproxy"
+)
+
+// FieldError is an invalid setting.
+type FieldError struct {
+	// Field is the environment variable of the setting, like "GCP_MOCK_PORT".
+	Field string
+	// Value is the invalid value.
+	Value string
+	// Reason tells what is wrong with the value.
+	Reason string
+}
+
+// Error returns the setting, its value and what is wrong with it, like
+// `GCP_MOCK_SQL_CREATE_DELAY="3 minutes": must be a duration like 30s, 5m or 1h`.
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s=%q: %s", e.Field, e.Value, e.Reason)
+}
+
+// ValidationError lists all invalid settings of a configuration, so they can be fixed at once
+// rather than one startup at a time.
+type ValidationError struct {
+	Fields []*FieldError
+}
+
+// Error returns one line per invalid setting.
+func (e *ValidationError) Error() string {
+	lines := []string{"invalid configuration:"}
+	for _, field := range e.Fields {
+		lines = append(lines, "  "+field.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// validator collects the invalid settings of a configuration.
+type validator struct {
+	fields []*FieldError
+}
+
+// fail records an invalid setting.
+func (v *validator) fail(field, value, reason string, args ...any) {
+	v.fields = append(v.fields, &FieldError{Field: field, Value: value, Reason: fmt.Sprintf(reason, args...)})
+}
+
+// check records an invalid setting if err isn't nil.
+func (v *validator) check(field, value string, err error) {
+	if err != nil {
+		v.fail(field, value, "%v", err)
+	}
+}
+
+// duration checks that a setting is a duration that isn't negative, or is positive if zero isn't allowed,
+// unless it is empty.
+func (v *validator) duration(field, value string, allowZero bool) {
+	if value == "" {
+		return
+	}
+	d, err := time.ParseDuration(value)
+	switch {
+	case err != nil:
+		v.fail(field, value, "must be a duration like 30s, 5m or 1h")
+	case d < 0:
+		v.fail(field, value, "must not be negative")
+	case d == 0 && !allowZero:
+		v.fail(field, value, "must be positive")
+	}
+}
+
+// byteSize checks that a setting is a size like "100MiB", unless it is empty.
+func (v *validator) byteSize(field, value string) {
+	if value == "" {
+		return
+	}
+	if _, err := ParseByteSize(value); err != nil {
+		v.fail(field, value, "must be a number of bytes with an optional unit like MiB or GB")
+	}
+}
+
+// url checks that a setting is an absolute URL with one of the schemes, unless it is empty.
+func (v *validator) url(field, value string, schemes ...string) {
+	if value == "" {
+		return
+	}
+	u, err := url.Parse(value)
+	if err != nil || u.Host == "" || !slices.Contains(schemes, u.Scheme) {
+		v.fail(field, value, "must be a URL like %s://host:port", schemes[0])
+	}
+}
+
+// file checks that a setting names a readable file, unless it is empty.
+func (v *validator) file(field, value string) {
+	if value == "" {
+		return
+	}
+	info, err := os.Stat(value)
+	switch {
+	case err != nil:
+		v.fail(field, value, "file can't be read: %v", err)
+	case info.IsDir():
+		v.fail(field, value, "must be a file, not a directory")
+	}
+}
+
+// Validate checks the configuration and returns a *ValidationError listing every invalid setting,
+// or nil if all settings are valid. The mock still starts with invalid settings, ignoring them or
+// falling back to a safe default, so Validate is how they're caught, e.g. in CI before deploying the mock.
+func (c *Config) Validate() error {
+	v := &validator{}
+
+	if c.Port != "" {
+		if port, err := strconv.Atoi(c.Port); err != nil || port < 0 || port > 65535 {
+			v.fail("GCP_MOCK_PORT", c.Port, "must be a port between 0 and 65535")
+		}
+	}
+	switch c.AuthMode {
+	case "", "permissive", "strict":
+	default:
+		v.fail("GCP_MOCK_AUTH_MODE", c.AuthMode, "must be permissive or strict")
+	}
+	if _, err := readonly.ParseMode(c.ReadOnly); err != nil {
+		v.fail("GCP_MOCK_READ_ONLY", c.ReadOnly, "must be off, success or reject")
+	}
+
+	v.byteSize("GCP_MOCK_MAX_OBJECT_SIZE", c.MaxObjectSize)
+	v.byteSize("GCP_MOCK_MAX_CONTENT_SIZE", c.MaxContentSize)
+
+	v.duration("GCP_MOCK_SQL_CREATE_DELAY", c.SQLCreateDelay, true)
+	v.duration("GCP_MOCK_SQL_MAINTENANCE_DURATION", c.SQLMaintenanceDuration, false)
+	v.duration("GCP_MOCK_MIRROR_INTERVAL", c.MirrorInterval, false)
+	v.duration("GCP_MOCK_SHUTDOWN_TIMEOUT", c.ShutdownTimeout, true)
+	v.duration("GCP_MOCK_NAMESPACE_TTL", c.NamespaceTTL, true)
+	if c.ClockSkew != "" {
+		if _, err := time.ParseDuration(c.ClockSkew); err != nil {
+			v.fail("GCP_MOCK_CLOCK_SKEW", c.ClockSkew, "must be a duration like -5m or 10s")
+		}
+	}
+
+	if c.Latency != "" {
+		_, err := latency.ParseProfile(c.Latency)
+		v.check("GCP_MOCK_LATENCY", c.Latency, err)
+	}
+	if c.LatencyFile != "" {
+		_, err := latency.LoadProfile(c.LatencyFile)
+		v.check("GCP_MOCK_LATENCY_FILE", c.LatencyFile, err)
+	}
+
+	if c.SLO != "" {
+		_, err := slo.ParseObjective(c.SLO)
+		v.check("GCP_MOCK_SLO", c.SLO, err)
+	}
+
+	if c.SQLProxyPorts != "" {
+		_, err := sqlproxy.ParsePortRange(c.SQLProxyPorts)
+		v.check("GCP_MOCK_SQL_PROXY_PORTS", c.SQLProxyPorts, err)
+	}
+	if c.SQLProxyTargets != "" {
+		_, err := sqlproxy.ParseTargets(c.SQLProxyTargets)
+		v.check("GCP_MOCK_SQL_PROXY_TARGETS", c.SQLProxyTargets, err)
+	}
+
+	if c.MemorystoreEndpoint != "" {
+		if _, port, err := net.SplitHostPort(c.MemorystoreEndpoint); err != nil {
+			v.fail("GCP_MOCK_MEMORYSTORE_ENDPOINT", c.MemorystoreEndpoint, "must be a host and port like localhost:6379")
+		} else if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+			v.fail("GCP_MOCK_MEMORYSTORE_ENDPOINT", c.MemorystoreEndpoint, "must have a port between 0 and 65535")
+		}
+	}
+
+	if c.GKEKubeconfig != "" {
+		if _, err := gke.LoadKubeconfig(c.GKEKubeconfig); err != nil {
+			v.fail("GCP_MOCK_GKE_KUBECONFIG", c.GKEKubeconfig, "can't be loaded: %v", err)
+		}
+	}
+
+	switch {
+	case c.TLSCertFile != "" && c.TLSKeyFile == "":
+		v.fail("GCP_MOCK_TLS_KEY_FILE", "", "must be set along with GCP_MOCK_TLS_CERT_FILE")
+	case c.TLSCertFile == "" && c.TLSKeyFile != "":
+		v.fail("GCP_MOCK_TLS_CERT_FILE", "", "must be set along with GCP_MOCK_TLS_KEY_FILE")
+	case c.TLSCertFile != "":
+		if _, err := tls.LoadX509KeyPair(c.TLSCertFile, c.TLSKeyFile); err != nil {
+			v.fail("GCP_MOCK_TLS_CERT_FILE", c.TLSCertFile, "can't be loaded with GCP_MOCK_TLS_KEY_FILE: %v", err)
+		}
+	}
+
+	v.url("GCP_MOCK_BASE_URL", c.BaseURL, "http", "https")
+	v.url("GCP_MOCK_AUDIT_LOG_URL", c.AuditLogURL, "http", "https")
+	v.url("GCP_MOCK_REDIS_URL", c.RedisURL, "redis")
+	v.file("GCP_MOCK_SNAPSHOT_FILE", c.SnapshotFile)
+
+	for _, path := range c.RedactJSONPaths {
+		_, err := redact.ParsePath(path)
+		v.check("GCP_MOCK_REDACT_JSON_PATHS", path, err)
+	}
+
+	if len(v.fields) > 0 {
+		return &ValidationError{Fields: v.fields}
+	}
+	return nil
+}
@@ -0,0 +1,346 @@
+package discovery
+
+import (
+	"net/http"
+
+	"github.com/katharinasick/gcp-api-mock/internal/billing"
+	"github.com/katharinasick/gcp-api-mock/internal/cloudrun"
+	"github.com/katharinasick/gcp-api-mock/internal/cloudscheduler"
+	"github.com/katharinasick/gcp-api-mock/internal/compute"
+	"github.com/katharinasick/gcp-api-mock/internal/eventarc"
+	"github.com/katharinasick/gcp-api-mock/internal/firestore"
+	"github.com/katharinasick/gcp-api-mock/internal/gke"
+	"github.com/katharinasick/gcp-api-mock/internal/logging"
+	"github.com/katharinasick/gcp-api-mock/internal/memorystore"
+	"github.com/katharinasick/gcp-api-mock/internal/monitoring"
+	"github.com/katharinasick/gcp-api-mock/internal/orgpolicy"
+	"github.com/katharinasick/gcp-api-mock/internal/sqladmin"
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+)
+
+// Query parameters shared by several Cloud Storage methods.
+var (
+	storageMetagenerationPreconditions = []string{"ifMetagenerationMatch", "ifMetagenerationNotMatch"}
+	storagePreconditions               = append([]string{"ifGenerationMatch", "ifGenerationNotMatch"}, storageMetagenerationPreconditions...)
+	storageListParams                  = []string{"maxResults", "pageToken"}
+)
+
+// pageSizeParams are the paging query parameters of the Firestore, Cloud Run, Cloud Monitoring, Cloud Logging,
+// Cloud Scheduler and Eventarc list methods.
+var pageSizeParams = []string{"pageSize", "pageToken"}
+
+// apis are the emulated APIs. Keep them in sync with the routes in internal/server.
+var apis = []*api{
+	{
+		name:        "storage",
+		version:     "v1",
+		title:       "Cloud Storage JSON API",
+		description: "Stores and retrieves potentially large, immutable data objects.",
+		docsLink:    "https://cloud.google.com/storage/docs/apis",
+		servicePath: "storage/v1/",
+		resources: map[string]map[string]method{
+			"buckets": {
+				"list":                {httpMethod: http.MethodGet, path: "b", query: append([]string{"project", "prefix"}, storageListParams...), response: storage.BucketList{}},
+				"insert":              {httpMethod: http.MethodPost, path: "b", query: []string{"project"}, request: storage.BucketInsertRequest{}, response: storage.Bucket{}},
+				"get":                 {httpMethod: http.MethodGet, path: "b/{bucket}", query: storageMetagenerationPreconditions, response: storage.Bucket{}},
+				"update":              {httpMethod: http.MethodPut, path: "b/{bucket}", query: storageMetagenerationPreconditions, request: storage.BucketUpdateRequest{}, response: storage.Bucket{}},
+				"patch":               {httpMethod: http.MethodPatch, path: "b/{bucket}", query: storageMetagenerationPreconditions, request: storage.BucketPatchRequest{}, response: storage.Bucket{}},
+				"delete":              {httpMethod: http.MethodDelete, path: "b/{bucket}", query: storageMetagenerationPreconditions},
+				"getIamPolicy":        {httpMethod: http.MethodGet, path: "b/{bucket}/iam", response: storage.Policy{}},
+				"setIamPolicy":        {httpMethod: http.MethodPut, path: "b/{bucket}/iam", request: storage.Policy{}, response: storage.Policy{}},
+				"lockRetentionPolicy": {httpMethod: http.MethodPost, path: "b/{bucket}/lockRetentionPolicy", query: []string{"ifMetagenerationMatch"}, response: storage.Bucket{}},
+			},
+			"objects": {
+				"list": {httpMethod: http.MethodGet, path: "b/{bucket}/o", query: append([]string{
+					"prefix", "delimiter", "startOffset", "endOffset", "matchGlob", "includeTrailingDelimiter",
+					"includeFoldersAsPrefixes", "softDeleted",
+				}, storageListParams...), response: storage.ObjectList{}},
+				"insert":  {httpMethod: http.MethodPost, path: "b/{bucket}/o", query: append([]string{"name", "uploadType", "kmsKeyName", "predefinedAcl"}, storagePreconditions...), request: storage.ObjectInsertRequest{}, response: storage.Object{}, mediaUpload: true},
+				"get":     {httpMethod: http.MethodGet, path: "b/{bucket}/o/{object}", query: append([]string{"generation", "softDeleted"}, storagePreconditions...), response: storage.Object{}, mediaDownload: true},
+				"update":  {httpMethod: http.MethodPut, path: "b/{bucket}/o/{object}", query: append([]string{"generation"}, storagePreconditions...), request: storage.ObjectUpdateRequest{}, response: storage.Object{}},
+				"patch":   {httpMethod: http.MethodPatch, path: "b/{bucket}/o/{object}", query: append([]string{"generation"}, storagePreconditions...), request: storage.ObjectPatchRequest{}, response: storage.Object{}},
+				"delete":  {httpMethod: http.MethodDelete, path: "b/{bucket}/o/{object}", query: append([]string{"generation"}, storagePreconditions...)},
+				"restore": {httpMethod: http.MethodPost, path: "b/{bucket}/o/{object}/restore", query: append([]string{"generation"}, storagePreconditions...), response: storage.Object{}},
+				"rewrite": {httpMethod: http.MethodPost, path: "b/{sourceBucket}/o/{sourceObject}/rewriteTo/b/{destinationBucket}/o/{destinationObject}", query: append([]string{"destinationPredefinedAcl"}, storagePreconditions...), request: storage.ObjectInsertRequest{}, response: storage.RewriteResponse{}},
+			},
+			"objectAccessControls": {
+				"list":   {httpMethod: http.MethodGet, path: "b/{bucket}/o/{object}/acl", response: storage.ObjectAccessControls{}},
+				"insert": {httpMethod: http.MethodPost, path: "b/{bucket}/o/{object}/acl", request: storage.ObjectAccessControl{}, response: storage.ObjectAccessControl{}},
+				"get":    {httpMethod: http.MethodGet, path: "b/{bucket}/o/{object}/acl/{entity}", response: storage.ObjectAccessControl{}},
+				"update": {httpMethod: http.MethodPut, path: "b/{bucket}/o/{object}/acl/{entity}", request: storage.ObjectAccessControl{}, response: storage.ObjectAccessControl{}},
+				"patch":  {httpMethod: http.MethodPatch, path: "b/{bucket}/o/{object}/acl/{entity}", request: storage.ObjectAccessControl{}, response: storage.ObjectAccessControl{}},
+				"delete": {httpMethod: http.MethodDelete, path: "b/{bucket}/o/{object}/acl/{entity}"},
+			},
+			"notifications": {
+				"list":   {httpMethod: http.MethodGet, path: "b/{bucket}/notificationConfigs", response: storage.NotificationList{}},
+				"insert": {httpMethod: http.MethodPost, path: "b/{bucket}/notificationConfigs", request: storage.NotificationInsertRequest{}, response: storage.Notification{}},
+				"get":    {httpMethod: http.MethodGet, path: "b/{bucket}/notificationConfigs/{notification}", response: storage.Notification{}},
+				"delete": {httpMethod: http.MethodDelete, path: "b/{bucket}/notificationConfigs/{notification}"},
+			},
+			"projects.hmacKeys": {
+				"list":   {httpMethod: http.MethodGet, path: "projects/{projectId}/hmacKeys", query: append([]string{"serviceAccountEmail", "showDeletedKeys"}, storageListParams...), response: storage.HmacKeyList{}},
+				"create": {httpMethod: http.MethodPost, path: "projects/{projectId}/hmacKeys", query: []string{"serviceAccountEmail"}, response: storage.HmacKey{}},
+				"get":    {httpMethod: http.MethodGet, path: "projects/{projectId}/hmacKeys/{accessId}", response: storage.HmacKeyMetadata{}},
+				"update": {httpMethod: http.MethodPut, path: "projects/{projectId}/hmacKeys/{accessId}", request: storage.HmacKeyMetadata{}, response: storage.HmacKeyMetadata{}},
+				"delete": {httpMethod: http.MethodDelete, path: "projects/{projectId}/hmacKeys/{accessId}"},
+			},
+		},
+	},
+	{
+		name:        "sqladmin",
+		version:     "v1beta4",
+		title:       "Cloud SQL Admin API",
+		description: "API for Cloud SQL database instance management",
+		docsLink:    "https://cloud.google.com/sql/docs",
+		servicePath: "",
+		resources: map[string]map[string]method{
+			"instances": {
+				"list":           {httpMethod: http.MethodGet, path: "sql/v1beta4/projects/{project}/instances", query: []string{"filter", "maxResults", "pageToken"}, response: sqladmin.InstancesListResponse{}},
+				"insert":         {httpMethod: http.MethodPost, path: "sql/v1beta4/projects/{project}/instances", request: sqladmin.InstanceInsertRequest{}, response: sqladmin.Operation{}},
+				"get":            {httpMethod: http.MethodGet, path: "sql/v1beta4/projects/{project}/instances/{instance}", response: sqladmin.DatabaseInstance{}},
+				"patch":          {httpMethod: http.MethodPatch, path: "sql/v1beta4/projects/{project}/instances/{instance}", request: sqladmin.InstancePatchRequest{}, response: sqladmin.Operation{}},
+				"delete":         {httpMethod: http.MethodDelete, path: "sql/v1beta4/projects/{project}/instances/{instance}", response: sqladmin.Operation{}},
+				"restart":        {httpMethod: http.MethodPost, path: "sql/v1beta4/projects/{project}/instances/{instance}/restart", response: sqladmin.Operation{}},
+				"promoteReplica": {httpMethod: http.MethodPost, path: "sql/v1beta4/projects/{project}/instances/{instance}/promoteReplica", response: sqladmin.Operation{}},
+			},
+			"databases": {
+				"list":   {httpMethod: http.MethodGet, path: "sql/v1beta4/projects/{project}/instances/{instance}/databases", response: sqladmin.DatabasesListResponse{}},
+				"insert": {httpMethod: http.MethodPost, path: "sql/v1beta4/projects/{project}/instances/{instance}/databases", request: sqladmin.DatabaseInsertRequest{}, response: sqladmin.Operation{}},
+				"get":    {httpMethod: http.MethodGet, path: "sql/v1beta4/projects/{project}/instances/{instance}/databases/{database}", response: sqladmin.Database{}},
+				"patch":  {httpMethod: http.MethodPatch, path: "sql/v1beta4/projects/{project}/instances/{instance}/databases/{database}", request: sqladmin.DatabasePatchRequest{}, response: sqladmin.Operation{}},
+				"delete": {httpMethod: http.MethodDelete, path: "sql/v1beta4/projects/{project}/instances/{instance}/databases/{database}", response: sqladmin.Operation{}},
+			},
+			"users": {
+				"list":   {httpMethod: http.MethodGet, path: "sql/v1beta4/projects/{project}/instances/{instance}/users", response: sqladmin.UsersListResponse{}},
+				"insert": {httpMethod: http.MethodPost, path: "sql/v1beta4/projects/{project}/instances/{instance}/users", request: sqladmin.UserInsertRequest{}, response: sqladmin.Operation{}},
+				"update": {httpMethod: http.MethodPut, path: "sql/v1beta4/projects/{project}/instances/{instance}/users", query: []string{"name", "host"}, request: sqladmin.UserUpdateRequest{}, response: sqladmin.Operation{}},
+				"delete": {httpMethod: http.MethodDelete, path: "sql/v1beta4/projects/{project}/instances/{instance}/users", query: []string{"name", "host"}, response: sqladmin.Operation{}},
+			},
+			"operations": {
+				"list": {httpMethod: http.MethodGet, path: "sql/v1beta4/projects/{project}/operations", query: []string{"instance", "maxResults", "pageToken"}, response: sqladmin.OperationsListResponse{}},
+				"get":  {httpMethod: http.MethodGet, path: "sql/v1beta4/projects/{project}/operations/{operation}", response: sqladmin.Operation{}},
+			},
+			"flags": {
+				"list": {httpMethod: http.MethodGet, path: "sql/v1beta4/flags", query: []string{"databaseVersion"}, response: sqladmin.FlagsListResponse{}},
+			},
+		},
+	},
+	{
+		name:        "firestore",
+		version:     "v1",
+		title:       "Cloud Firestore API",
+		description: "Accesses the NoSQL document database built for automatic scaling, high performance, and ease of application development.",
+		docsLink:    "https://cloud.google.com/firestore",
+		servicePath: "",
+		resources: map[string]map[string]method{
+			"projects.databases.documents": {
+				"get":            {httpMethod: http.MethodGet, path: "v1/{+name}", response: firestore.Document{}},
+				"list":           {httpMethod: http.MethodGet, path: "v1/{+parent}/{collectionId}", query: pageSizeParams, response: firestore.ListDocumentsResponse{}},
+				"createDocument": {httpMethod: http.MethodPost, path: "v1/{+parent}/{collectionId}", query: []string{"documentId"}, request: firestore.Document{}, response: firestore.Document{}},
+				"patch":          {httpMethod: http.MethodPatch, path: "v1/{+name}", query: []string{"updateMask.fieldPaths", "currentDocument.exists"}, request: firestore.Document{}, response: firestore.Document{}},
+				"delete":         {httpMethod: http.MethodDelete, path: "v1/{+name}", query: []string{"currentDocument.exists"}},
+				"runQuery":       {httpMethod: http.MethodPost, path: "v1/{+parent}:runQuery", request: firestore.RunQueryRequest{}, response: firestore.RunQueryResponse{}},
+			},
+		},
+	},
+	{
+		name:        "run",
+		version:     "v2",
+		title:       "Cloud Run Admin API",
+		description: "Deploy and manage user provided container images that scale automatically based on incoming requests.",
+		docsLink:    "https://cloud.google.com/run/",
+		servicePath: "",
+		resources: map[string]map[string]method{
+			"projects.locations.services": {
+				"list":   {httpMethod: http.MethodGet, path: "v2/{+parent}/services", query: pageSizeParams, response: cloudrun.ListServicesResponse{}},
+				"create": {httpMethod: http.MethodPost, path: "v2/{+parent}/services", query: []string{"serviceId"}, request: cloudrun.Service{}, response: cloudrun.Operation{}},
+				"get":    {httpMethod: http.MethodGet, path: "v2/{+name}", response: cloudrun.Service{}},
+				"patch":  {httpMethod: http.MethodPatch, path: "v2/{+name}", query: []string{"allowMissing"}, request: cloudrun.Service{}, response: cloudrun.Operation{}},
+				"delete": {httpMethod: http.MethodDelete, path: "v2/{+name}", response: cloudrun.Operation{}},
+			},
+			"projects.locations.services.revisions": {
+				"list": {httpMethod: http.MethodGet, path: "v2/{+parent}/revisions", query: pageSizeParams, response: cloudrun.ListRevisionsResponse{}},
+				"get":  {httpMethod: http.MethodGet, path: "v2/{+name}", response: cloudrun.Revision{}},
+			},
+			"projects.locations.operations": {
+				"list": {httpMethod: http.MethodGet, path: "v2/{+name}/operations", query: pageSizeParams, response: cloudrun.ListOperationsResponse{}},
+				"get":  {httpMethod: http.MethodGet, path: "v2/{+name}", response: cloudrun.Operation{}},
+			},
+		},
+	},
+	{
+		name:        "monitoring",
+		version:     "v3",
+		title:       "Cloud Monitoring API",
+		description: "Manages your Cloud Monitoring data and configurations.",
+		docsLink:    "https://cloud.google.com/monitoring/api/",
+		servicePath: "",
+		resources: map[string]map[string]method{
+			"projects.timeSeries": {
+				"create":        {httpMethod: http.MethodPost, path: "v3/{+name}/timeSeries", request: monitoring.CreateTimeSeriesRequest{}},
+				"createService": {httpMethod: http.MethodPost, path: "v3/{+name}/timeSeries:createService", request: monitoring.CreateTimeSeriesRequest{}},
+				"list": {httpMethod: http.MethodGet, path: "v3/{+name}/timeSeries", query: append([]string{"filter", "interval.startTime", "interval.endTime", "view"}, pageSizeParams...),
+					response: monitoring.ListTimeSeriesResponse{}},
+			},
+			"projects.metricDescriptors": {
+				"create": {httpMethod: http.MethodPost, path: "v3/{+name}/metricDescriptors", request: monitoring.MetricDescriptor{}, response: monitoring.MetricDescriptor{}},
+				"list":   {httpMethod: http.MethodGet, path: "v3/{+name}/metricDescriptors", query: append([]string{"filter"}, pageSizeParams...), response: monitoring.ListMetricDescriptorsResponse{}},
+				"get":    {httpMethod: http.MethodGet, path: "v3/{+name}", response: monitoring.MetricDescriptor{}},
+				"delete": {httpMethod: http.MethodDelete, path: "v3/{+name}"},
+			},
+		},
+	}, {
+		name:        "logging",
+		version:     "v2",
+		title:       "Cloud Logging API",
+		description: "Writes log entries and manages your Cloud Logging configuration.",
+		docsLink:    "https://cloud.google.com/logging/docs/",
+		servicePath: "",
+		resources: map[string]map[string]method{
+			"entries": {
+				"write": {httpMethod: http.MethodPost, path: "v2/entries:write", request: logging.WriteLogEntriesRequest{}},
+				"list":  {httpMethod: http.MethodPost, path: "v2/entries:list", request: logging.ListLogEntriesRequest{}, response: logging.ListLogEntriesResponse{}},
+			},
+			"projects.logs": {
+				"list": {httpMethod: http.MethodGet, path: "v2/{+parent}/logs", query: pageSizeParams, response: logging.ListLogsResponse{}},
+			},
+		},
+	}, {
+		name:        "cloudscheduler",
+		version:     "v1",
+		title:       "Cloud Scheduler API",
+		description: "Creates and manages jobs run on a regular recurring schedule.",
+		docsLink:    "https://cloud.google.com/scheduler/",
+		servicePath: "",
+		resources: map[string]map[string]method{
+			"projects.locations.jobs": {
+				"list":   {httpMethod: http.MethodGet, path: "v1/{+parent}/jobs", query: pageSizeParams, response: cloudscheduler.ListJobsResponse{}},
+				"create": {httpMethod: http.MethodPost, path: "v1/{+parent}/jobs", request: cloudscheduler.Job{}, response: cloudscheduler.Job{}},
+				"get":    {httpMethod: http.MethodGet, path: "v1/{+name}", response: cloudscheduler.Job{}},
+				"patch":  {httpMethod: http.MethodPatch, path: "v1/{+name}", query: []string{"updateMask"}, request: cloudscheduler.Job{}, response: cloudscheduler.Job{}},
+				"delete": {httpMethod: http.MethodDelete, path: "v1/{+name}"},
+				"pause":  {httpMethod: http.MethodPost, path: "v1/{+name}:pause", response: cloudscheduler.Job{}},
+				"resume": {httpMethod: http.MethodPost, path: "v1/{+name}:resume", response: cloudscheduler.Job{}},
+				"run":    {httpMethod: http.MethodPost, path: "v1/{+name}:run", response: cloudscheduler.Job{}},
+			},
+		},
+	}, {
+		name:        "eventarc",
+		version:     "v1",
+		title:       "Eventarc API",
+		description: "Build event-driven applications on Google Cloud Platform.",
+		docsLink:    "https://cloud.google.com/eventarc",
+		servicePath: "",
+		resources: map[string]map[string]method{
+			"projects.locations.triggers": {
+				"list":   {httpMethod: http.MethodGet, path: "v1/{+parent}/triggers", query: pageSizeParams, response: eventarc.ListTriggersResponse{}},
+				"create": {httpMethod: http.MethodPost, path: "v1/{+parent}/triggers", query: []string{"triggerId"}, request: eventarc.Trigger{}, response: eventarc.Operation{}},
+				"get":    {httpMethod: http.MethodGet, path: "v1/{+name}", response: eventarc.Trigger{}},
+				"patch":  {httpMethod: http.MethodPatch, path: "v1/{+name}", query: []string{"updateMask"}, request: eventarc.Trigger{}, response: eventarc.Operation{}},
+				"delete": {httpMethod: http.MethodDelete, path: "v1/{+name}", response: eventarc.Operation{}},
+			},
+			"projects.locations.operations": {
+				"get": {httpMethod: http.MethodGet, path: "v1/{+name}", response: eventarc.Operation{}},
+			},
+		},
+	}, {
+		name:        "redis",
+		version:     "v1",
+		title:       "Google Cloud Memorystore for Redis API",
+		description: "Creates and manages Redis instances on the Google Cloud Platform.",
+		docsLink:    "https://cloud.google.com/memorystore/docs/redis/",
+		servicePath: "",
+		resources: map[string]map[string]method{
+			"projects.locations.instances": {
+				"list":   {httpMethod: http.MethodGet, path: "v1/{+parent}/instances", query: pageSizeParams, response: memorystore.ListInstancesResponse{}},
+				"create": {httpMethod: http.MethodPost, path: "v1/{+parent}/instances", query: []string{"instanceId"}, request: memorystore.Instance{}, response: memorystore.Operation{}},
+				"get":    {httpMethod: http.MethodGet, path: "v1/{+name}", response: memorystore.Instance{}},
+				"patch":  {httpMethod: http.MethodPatch, path: "v1/{+name}", query: []string{"updateMask"}, request: memorystore.Instance{}, response: memorystore.Operation{}},
+				"delete": {httpMethod: http.MethodDelete, path: "v1/{+name}", response: memorystore.Operation{}},
+			},
+			"projects.locations.operations": {
+				"get": {httpMethod: http.MethodGet, path: "v1/{+name}", response: memorystore.Operation{}},
+			},
+		},
+	}, {
+		name:        "container",
+		version:     "v1",
+		title:       "Kubernetes Engine API",
+		description: "Builds and manages container-based applications, powered by the open source Kubernetes technology.",
+		docsLink:    "https://cloud.google.com/kubernetes-engine/docs/",
+		servicePath: "",
+		resources: map[string]map[string]method{
+			"projects.locations.clusters": {
+				"list":   {httpMethod: http.MethodGet, path: "v1/{+parent}/clusters", response: gke.ListClustersResponse{}},
+				"create": {httpMethod: http.MethodPost, path: "v1/{+parent}/clusters", request: gke.CreateClusterRequest{}, response: gke.Operation{}},
+				"get":    {httpMethod: http.MethodGet, path: "v1/{+name}", response: gke.Cluster{}},
+				"delete": {httpMethod: http.MethodDelete, path: "v1/{+name}", response: gke.Operation{}},
+			},
+			"projects.locations.operations": {
+				"get": {httpMethod: http.MethodGet, path: "v1/{+name}", response: gke.Operation{}},
+			},
+		},
+	}, {
+		name:        "compute",
+		version:     "v1",
+		title:       "Compute Engine API",
+		description: "Creates and runs virtual machines on Google Cloud Platform.",
+		docsLink:    "https://cloud.google.com/compute/",
+		servicePath: "compute/v1/",
+		resources: map[string]map[string]method{
+			"networks": {
+				"list":   {httpMethod: http.MethodGet, path: "projects/{project}/global/networks", query: []string{"maxResults", "pageToken"}, response: compute.NetworkList{}},
+				"insert": {httpMethod: http.MethodPost, path: "projects/{project}/global/networks", request: compute.Network{}, response: compute.Operation{}},
+				"get":    {httpMethod: http.MethodGet, path: "projects/{project}/global/networks/{network}", response: compute.Network{}},
+				"patch":  {httpMethod: http.MethodPatch, path: "projects/{project}/global/networks/{network}", request: compute.Network{}, response: compute.Operation{}},
+				"delete": {httpMethod: http.MethodDelete, path: "projects/{project}/global/networks/{network}", response: compute.Operation{}},
+			},
+			"subnetworks": {
+				"list":   {httpMethod: http.MethodGet, path: "projects/{project}/regions/{region}/subnetworks", query: []string{"maxResults", "pageToken"}, response: compute.SubnetworkList{}},
+				"insert": {httpMethod: http.MethodPost, path: "projects/{project}/regions/{region}/subnetworks", request: compute.Subnetwork{}, response: compute.Operation{}},
+				"get":    {httpMethod: http.MethodGet, path: "projects/{project}/regions/{region}/subnetworks/{subnetwork}", response: compute.Subnetwork{}},
+				"patch":  {httpMethod: http.MethodPatch, path: "projects/{project}/regions/{region}/subnetworks/{subnetwork}", request: compute.Subnetwork{}, response: compute.Operation{}},
+				"delete": {httpMethod: http.MethodDelete, path: "projects/{project}/regions/{region}/subnetworks/{subnetwork}", response: compute.Operation{}},
+			},
+			"globalOperations": {
+				"get":  {httpMethod: http.MethodGet, path: "projects/{project}/global/operations/{operation}", response: compute.Operation{}},
+				"wait": {httpMethod: http.MethodPost, path: "projects/{project}/global/operations/{operation}/wait", response: compute.Operation{}},
+			},
+			"regionOperations": {
+				"get":  {httpMethod: http.MethodGet, path: "projects/{project}/regions/{region}/operations/{operation}", response: compute.Operation{}},
+				"wait": {httpMethod: http.MethodPost, path: "projects/{project}/regions/{region}/operations/{operation}/wait", response: compute.Operation{}},
+			},
+		},
+	}, {
+		name:        "billingbudgets",
+		version:     "v1",
+		title:       "Cloud Billing Budget API",
+		description: "The Cloud Billing Budget API stores Cloud Billing budgets, which define a budget plan and the rules to execute as spend is tracked against that plan.",
+		docsLink:    "https://cloud.google.com/billing/docs/how-to/budget-api-overview",
+		servicePath: "",
+		resources: map[string]map[string]method{
+			"billingAccounts.budgets": {
+				"list":   {httpMethod: http.MethodGet, path: "v1/{+parent}/budgets", query: pageSizeParams, response: billing.ListBudgetsResponse{}},
+				"create": {httpMethod: http.MethodPost, path: "v1/{+parent}/budgets", request: billing.Budget{}, response: billing.Budget{}},
+				"get":    {httpMethod: http.MethodGet, path: "v1/{+name}", response: billing.Budget{}},
+			},
+		},
+	},
+	{
+		name:        "orgpolicy",
+		version:     "v2",
+		title:       "Organization Policy API",
+		description: "The Organization Policy API allows users to configure governance rules on their Google Cloud resources across the resource hierarchy.",
+		docsLink:    "https://cloud.google.com/resource-manager/docs/organization-policy/overview",
+		servicePath: "",
+		resources: map[string]map[string]method{
+			"projects.policies": {
+				"list":   {httpMethod: http.MethodGet, path: "v2/{+parent}/policies", query: pageSizeParams, response: orgpolicy.ListPoliciesResponse{}},
+				"create": {httpMethod: http.MethodPost, path: "v2/{+parent}/policies", request: orgpolicy.Policy{}, response: orgpolicy.Policy{}},
+				"get":    {httpMethod: http.MethodGet, path: "v2/{+name}", response: orgpolicy.Policy{}},
+				"patch":  {httpMethod: http.MethodPatch, path: "v2/{+name}", query: []string{"updateMask"}, request: orgpolicy.Policy{}, response: orgpolicy.Policy{}},
+				"delete": {httpMethod: http.MethodDelete, path: "v2/{+name}"},
+			},
+		},
+	},
+}
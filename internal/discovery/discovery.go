@@ -0,0 +1,439 @@
+// Package discovery builds Google API discovery documents for the APIs emulated by the mock.
+// The documents only list the methods the mock implements, so generated clients and discovery-based
+// tools resolve methods against the mock and users can see exactly what is supported.
+// Reference: https://developers.google.com/discovery/v1/reference
+package discovery
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DirectoryList is the list of APIs served at /discovery/v1/apis.
+// Reference: https://developers.google.com/discovery/v1/reference/apis/list
+type DirectoryList struct {
+	Kind             string           `json:"kind"`
+	DiscoveryVersion string           `json:"discoveryVersion"`
+	Items            []*DirectoryItem `json:"items"`
+}
+
+// DirectoryItem is an API in the DirectoryList.
+type DirectoryItem struct {
+	Kind              string `json:"kind"`
+	ID                string `json:"id"`
+	Name              string `json:"name"`
+	Version           string `json:"version"`
+	Title             string `json:"title"`
+	DiscoveryRestURL  string `json:"discoveryRestUrl"`
+	DocumentationLink string `json:"documentationLink,omitempty"`
+	Preferred         bool   `json:"preferred"`
+}
+
+// RestDescription is the discovery document of an API.
+// Reference: https://developers.google.com/discovery/v1/reference/apis
+type RestDescription struct {
+	Kind              string                `json:"kind"`
+	DiscoveryVersion  string                `json:"discoveryVersion"`
+	ID                string                `json:"id"`
+	Name              string                `json:"name"`
+	Version           string                `json:"version"`
+	Revision          string                `json:"revision"`
+	Title             string                `json:"title"`
+	Description       string                `json:"description"`
+	DocumentationLink string                `json:"documentationLink,omitempty"`
+	Protocol          string                `json:"protocol"`
+	RootURL           string                `json:"rootUrl"`
+	ServicePath       string                `json:"servicePath"`
+	BaseURL           string                `json:"baseUrl"`
+	BasePath          string                `json:"basePath"`
+	Parameters        map[string]*Parameter `json:"parameters"`
+	Resources         map[string]*Resource  `json:"resources"`
+	Schemas           map[string]*Schema    `json:"schemas"`
+}
+
+// Resource is a collection of methods and nested resources.
+type Resource struct {
+	Methods   map[string]*Method   `json:"methods,omitempty"`
+	Resources map[string]*Resource `json:"resources,omitempty"`
+}
+
+// Method is an API method.
+type Method struct {
+	ID                      string                `json:"id"`
+	Path                    string                `json:"path"`
+	FlatPath                string                `json:"flatPath,omitempty"`
+	HTTPMethod              string                `json:"httpMethod"`
+	Description             string                `json:"description,omitempty"`
+	Parameters              map[string]*Parameter `json:"parameters,omitempty"`
+	ParameterOrder          []string              `json:"parameterOrder,omitempty"`
+	Request                 *Schema               `json:"request,omitempty"`
+	Response                *Schema               `json:"response,omitempty"`
+	SupportsMediaDownload   bool                  `json:"supportsMediaDownload,omitempty"`
+	UseMediaDownloadService bool                  `json:"useMediaDownloadService,omitempty"`
+	SupportsMediaUpload     bool                  `json:"supportsMediaUpload,omitempty"`
+	MediaUpload             *MediaUpload          `json:"mediaUpload,omitempty"`
+}
+
+// Parameter is a path or query parameter of a method, or a parameter common to all methods.
+type Parameter struct {
+	Type        string   `json:"type"`
+	Format      string   `json:"format,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Location    string   `json:"location"`
+	Required    bool     `json:"required,omitempty"`
+	Repeated    bool     `json:"repeated,omitempty"`
+	Pattern     string   `json:"pattern,omitempty"`
+	Default     string   `json:"default,omitempty"`
+	Enum        []string `json:"enum,omitempty"`
+}
+
+// MediaUpload describes the upload protocols of a method that accepts media.
+type MediaUpload struct {
+	Accept    []string                   `json:"accept"`
+	Protocols map[string]*UploadProtocol `json:"protocols"`
+}
+
+// UploadProtocol is a media upload protocol, either simple or resumable.
+type UploadProtocol struct {
+	Multipart bool   `json:"multipart"`
+	Path      string `json:"path"`
+}
+
+// Schema is a JSON schema of a request or response body, or a reference to one with Ref.
+type Schema struct {
+	ID                   string             `json:"id,omitempty"`
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	// Enum lists the values of a string. The mock's documents don't restrict values, but Google's do.
+	Enum []string `json:"enum,omitempty"`
+}
+
+// api describes an emulated API.
+type api struct {
+	name        string
+	version     string
+	title       string
+	description string
+	docsLink    string
+	servicePath string
+	resources   map[string]map[string]method
+}
+
+// method describes an implemented method of an API.
+type method struct {
+	httpMethod string
+	// path is relative to the service path. Path parameters are written as {name},
+	// or {+name} if they may contain slashes.
+	path        string
+	query       []string
+	request     any
+	response    any
+	mediaUpload bool
+	// mediaDownload marks methods that return the content instead of the resource with alt=media.
+	mediaDownload bool
+}
+
+// pathParamPattern matches the parameters of a method path.
+var pathParamPattern = regexp.MustCompile(`\{\+?([A-Za-z0-9_]+)\}`)
+
+// Directory lists the emulated APIs. rootURL is the URL the mock is reached at, ending with a slash.
+func Directory(rootURL string) *DirectoryList {
+	list := &DirectoryList{Kind: "discovery#directoryList", DiscoveryVersion: "v1", Items: []*DirectoryItem{}}
+	for _, a := range apis {
+		list.Items = append(list.Items, &DirectoryItem{
+			Kind:              "discovery#directoryItem",
+			ID:                a.name + ":" + a.version,
+			Name:              a.name,
+			Version:           a.version,
+			Title:             a.title,
+			DiscoveryRestURL:  rootURL + "discovery/v1/apis/" + a.name + "/" + a.version + "/rest",
+			DocumentationLink: a.docsLink,
+			Preferred:         true,
+		})
+	}
+	return list
+}
+
+// Document returns the discovery document of an emulated API, or nil if the mock doesn't emulate it.
+// rootURL is the URL the mock is reached at, ending with a slash.
+func Document(name, version, rootURL string) *RestDescription {
+	for _, a := range apis {
+		if a.name == name && a.version == version {
+			return a.document(rootURL)
+		}
+	}
+	return nil
+}
+
+// document builds the discovery document of the API.
+func (a *api) document(rootURL string) *RestDescription {
+	doc := &RestDescription{
+		Kind:              "discovery#restDescription",
+		DiscoveryVersion:  "v1",
+		ID:                a.name + ":" + a.version,
+		Name:              a.name,
+		Version:           a.version,
+		Revision:          "mock",
+		Title:             a.title,
+		Description:       a.description,
+		DocumentationLink: a.docsLink,
+		Protocol:          "rest",
+		RootURL:           rootURL,
+		ServicePath:       a.servicePath,
+		BaseURL:           rootURL + a.servicePath,
+		BasePath:          "/" + a.servicePath,
+		Parameters:        commonParameters(),
+		Resources:         make(map[string]*Resource),
+		Schemas:           make(map[string]*Schema),
+	}
+
+	for resourcePath, methods := range a.resources {
+		resource := nestedResource(doc.Resources, strings.Split(resourcePath, "."))
+		for methodName, m := range methods {
+			resource.Methods[methodName] = a.method(doc, a.name+"."+resourcePath+"."+methodName, m)
+		}
+	}
+	return doc
+}
+
+// nestedResource returns the resource at path below resources, creating it and its parents if needed.
+func nestedResource(resources map[string]*Resource, path []string) *Resource {
+	resource, ok := resources[path[0]]
+	if !ok {
+		resource = &Resource{Methods: make(map[string]*Method), Resources: make(map[string]*Resource)}
+		resources[path[0]] = resource
+	}
+	if len(path) == 1 {
+		return resource
+	}
+	return nestedResource(resource.Resources, path[1:])
+}
+
+// method builds the description of an API method, adding the schemas it uses to doc.
+func (a *api) method(doc *RestDescription, id string, m method) *Method {
+	desc := &Method{
+		ID:         id,
+		Path:       m.path,
+		HTTPMethod: m.httpMethod,
+		Parameters: make(map[string]*Parameter),
+	}
+
+	for _, match := range pathParamPattern.FindAllStringSubmatch(m.path, -1) {
+		desc.Parameters[match[1]] = &Parameter{Type: "string", Location: "path", Required: true}
+		desc.ParameterOrder = append(desc.ParameterOrder, match[1])
+	}
+	for _, name := range m.query {
+		desc.Parameters[name] = &Parameter{Type: "string", Location: "query"}
+	}
+	if strings.Contains(m.path, "{+") {
+		desc.FlatPath = pathParamPattern.ReplaceAllString(m.path, "{$1}")
+	}
+
+	if m.request != nil {
+		desc.Request = &Schema{Ref: addSchema(doc.Schemas, reflect.TypeOf(m.request))}
+	}
+	if m.response != nil {
+		desc.Response = &Schema{Ref: addSchema(doc.Schemas, reflect.TypeOf(m.response))}
+	}
+	if m.mediaDownload {
+		desc.SupportsMediaDownload = true
+		desc.UseMediaDownloadService = true
+	}
+	if m.mediaUpload {
+		uploadPath := "/upload/" + a.servicePath + m.path
+		desc.SupportsMediaUpload = true
+		desc.MediaUpload = &MediaUpload{
+			Accept: []string{"*/*"},
+			Protocols: map[string]*UploadProtocol{
+				"simple":    {Multipart: true, Path: uploadPath},
+				"resumable": {Multipart: true, Path: uploadPath},
+			},
+		}
+	}
+	return desc
+}
+
+// commonParameters returns the parameters every method accepts.
+func commonParameters() map[string]*Parameter {
+	return map[string]*Parameter{
+		"alt":         {Type: "string", Location: "query", Default: "json", Enum: []string{"json", "media"}, Description: "Data format for the response."},
+		"fields":      {Type: "string", Location: "query", Description: "Selector specifying which fields to include in a partial response."},
+		"key":         {Type: "string", Location: "query", Description: "API key. Accepted and ignored by the mock."},
+		"prettyPrint": {Type: "boolean", Location: "query", Default: "true", Description: "Returns response with indentations and line breaks."},
+		"quotaUser":   {Type: "string", Location: "query", Description: "Accepted and ignored by the mock."},
+		"userProject": {Type: "string", Location: "query", Description: "The project to be billed for the request."},
+	}
+}
+
+// timeType is the type of timestamps, which are encoded as RFC 3339 strings.
+var timeType = reflect.TypeOf(time.Time{})
+
+// rawMessageType is the type of fields kept as written JSON, which can hold any value.
+var rawMessageType = reflect.TypeOf(json.RawMessage{})
+
+// addSchema adds the schema of a struct type and all structs it references to schemas and returns its ID.
+func addSchema(schemas map[string]*Schema, t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	id := t.Name()
+	if _, ok := schemas[id]; ok {
+		return id
+	}
+
+	schema := &Schema{ID: id, Type: "object", Properties: make(map[string]*Schema)}
+	schemas[id] = schema // Added before its fields, so recursive types like firestore.Value terminate
+	addProperties(schemas, schema, t)
+	return id
+}
+
+// addProperties adds the JSON fields of a struct type to schema, including those of embedded structs.
+func addProperties(schemas map[string]*Schema, schema *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			addProperties(schemas, schema, field.Type)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property := typeSchema(schemas, field.Type)
+		if hasOption(options, "string") {
+			property = &Schema{Type: "string", Format: property.Format}
+		}
+		schema.Properties[name] = property
+	}
+}
+
+// typeSchema returns the schema of a Go type, referencing structs by ID.
+func typeSchema(schemas map[string]*Schema, t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == rawMessageType:
+		return &Schema{Type: "any"}
+	case t.Kind() == reflect.Struct:
+		return &Schema{Ref: addSchema(schemas, t)}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "uint32"}
+	case reflect.Int64:
+		return &Schema{Type: "string", Format: "int64"}
+	case reflect.Uint64:
+		return &Schema{Type: "string", Format: "uint64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: typeSchema(schemas, t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: typeSchema(schemas, t.Elem())}
+	default:
+		return &Schema{Type: "any"}
+	}
+}
+
+// hasOption reports whether a comma-separated list of JSON tag options contains option.
+func hasOption(options, option string) bool {
+	for _, o := range strings.Split(options, ",") {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+// Methods lists the IDs of the methods of an API document, sorted, like "storage.buckets.get".
+func Methods(doc *RestDescription) []string {
+	var ids []string
+	var collect func(resources map[string]*Resource)
+	collect = func(resources map[string]*Resource) {
+		for _, resource := range resources {
+			for _, m := range resource.Methods {
+				ids = append(ids, m.ID)
+			}
+			collect(resource.Resources)
+		}
+	}
+	collect(doc.Resources)
+	sort.Strings(ids)
+	return ids
+}
+
+// routeParamPattern matches the wildcards of a ServeMux pattern, like {bucket} or {object...}.
+var routeParamPattern = regexp.MustCompile(`\{[^}]*\}`)
+
+// methodIDs maps "{HTTP method} {path}" of the emulated methods to their IDs, with the path parameters
+// written as {}. Media uploads and downloads are mapped by their upload and download paths too.
+var methodIDs = sync.OnceValue(func() map[string]string {
+	ids := make(map[string]string)
+	for _, a := range apis {
+		for resourcePath, methods := range a.resources {
+			for methodName, m := range methods {
+				id := a.name + "." + resourcePath + "." + methodName
+				path := routeParamPattern.ReplaceAllString(m.path, "{}")
+				ids[m.httpMethod+" /"+a.servicePath+path] = id
+				if m.mediaUpload {
+					// The chunks of resumable uploads are PUT to the upload path
+					ids[m.httpMethod+" /upload/"+a.servicePath+path] = id
+					ids["PUT /upload/"+a.servicePath+path] = id
+				}
+				if m.mediaDownload {
+					ids[m.httpMethod+" /download/"+a.servicePath+path] = id
+				}
+			}
+		}
+	}
+	return ids
+})
+
+// MethodID returns the ID of the method a ServeMux route pattern like "GET /storage/v1/b/{bucket}" serves,
+// like "storage.buckets.get", or "" if the route doesn't serve a method of an emulated API.
+func MethodID(pattern string) string {
+	method, path, found := strings.Cut(pattern, " ")
+	if !found {
+		return ""
+	}
+	if method == "HEAD" {
+		method = "GET"
+	}
+	path = routeParamPattern.ReplaceAllString(strings.TrimSuffix(path, "{$}"), "{}")
+	return methodIDs()[method+" "+path]
+}
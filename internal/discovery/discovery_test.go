@@ -0,0 +1,253 @@
+package discovery
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestDirectory(t *testing.T) {
+	list := Directory("http://localhost:8080/")
+
+	var ids []string
+	for _, item := range list.Items {
+		ids = append(ids, item.ID)
+	}
+	expected := []string{"storage:v1", "sqladmin:v1beta4", "firestore:v1", "run:v2", "monitoring:v3", "logging:v2", "cloudscheduler:v1", "eventarc:v1", "redis:v1", "container:v1", "compute:v1", "billingbudgets:v1", "orgpolicy:v2"}
+	if !slices.Equal(ids, expected) {
+		t.Errorf("expected APIs %v, got %v", expected, ids)
+	}
+	if url := list.Items[0].DiscoveryRestURL; url != "http://localhost:8080/discovery/v1/apis/storage/v1/rest" {
+		t.Errorf("unexpected discoveryRestUrl %q", url)
+	}
+}
+
+func TestDocument(t *testing.T) {
+	tests := []struct {
+		name            string
+		api             string
+		version         string
+		expectedMethods []string
+		expectedSchemas []string
+	}{
+		{
+			name:            "storage",
+			api:             "storage",
+			version:         "v1",
+			expectedMethods: []string{"storage.buckets.patch", "storage.objects.insert", "storage.objects.restore", "storage.objects.rewrite", "storage.notifications.get", "storage.projects.hmacKeys.create"},
+			expectedSchemas: []string{"Bucket", "Object", "ObjectList", "Lifecycle", "LifecycleRule", "HmacKey"},
+		},
+		{
+			name:            "sqladmin",
+			api:             "sqladmin",
+			version:         "v1beta4",
+			expectedMethods: []string{"sqladmin.instances.insert", "sqladmin.users.update", "sqladmin.flags.list"},
+			expectedSchemas: []string{"DatabaseInstance", "Settings", "Operation"},
+		},
+		{
+			name:            "firestore with nested resources",
+			api:             "firestore",
+			version:         "v1",
+			expectedMethods: []string{"firestore.projects.databases.documents.get", "firestore.projects.databases.documents.runQuery"},
+			expectedSchemas: []string{"Document", "Value", "StructuredQuery"},
+		},
+		{
+			name:            "cloud run",
+			api:             "run",
+			version:         "v2",
+			expectedMethods: []string{"run.projects.locations.services.create", "run.projects.locations.services.revisions.list"},
+			expectedSchemas: []string{"Service", "Revision", "Operation"},
+		},
+		{
+			name:            "monitoring",
+			api:             "monitoring",
+			version:         "v3",
+			expectedMethods: []string{"monitoring.projects.timeSeries.create", "monitoring.projects.metricDescriptors.get"},
+			expectedSchemas: []string{"TimeSeries", "TypedValue", "MetricDescriptor"},
+		},
+		{
+			name:            "logging",
+			api:             "logging",
+			version:         "v2",
+			expectedMethods: []string{"logging.entries.write", "logging.entries.list", "logging.projects.logs.list"},
+			expectedSchemas: []string{"LogEntry", "WriteLogEntriesRequest", "ListLogEntriesResponse"},
+		},
+		{
+			name:            "cloud scheduler",
+			api:             "cloudscheduler",
+			version:         "v1",
+			expectedMethods: []string{"cloudscheduler.projects.locations.jobs.create", "cloudscheduler.projects.locations.jobs.run"},
+			expectedSchemas: []string{"Job", "HttpTarget", "PubsubTarget"},
+		},
+		{
+			name:            "eventarc",
+			api:             "eventarc",
+			version:         "v1",
+			expectedMethods: []string{"eventarc.projects.locations.triggers.create", "eventarc.projects.locations.operations.get"},
+			expectedSchemas: []string{"Trigger", "EventFilter", "Destination"},
+		},
+		{
+			name:            "redis",
+			api:             "redis",
+			version:         "v1",
+			expectedMethods: []string{"redis.projects.locations.instances.patch", "redis.projects.locations.operations.get"},
+			expectedSchemas: []string{"Instance", "Operation"},
+		},
+		{
+			name:            "container",
+			api:             "container",
+			version:         "v1",
+			expectedMethods: []string{"container.projects.locations.clusters.create", "container.projects.locations.operations.get"},
+			expectedSchemas: []string{"Cluster", "NodePool", "CreateClusterRequest"},
+		},
+		{
+			name:            "compute",
+			api:             "compute",
+			version:         "v1",
+			expectedMethods: []string{"compute.networks.insert", "compute.subnetworks.patch", "compute.regionOperations.wait"},
+			expectedSchemas: []string{"Network", "Subnetwork", "SecondaryIpRange"},
+		},
+		{
+			name:            "billingbudgets",
+			api:             "billingbudgets",
+			version:         "v1",
+			expectedMethods: []string{"billingbudgets.billingAccounts.budgets.create", "billingbudgets.billingAccounts.budgets.get"},
+			expectedSchemas: []string{"Budget", "ThresholdRule", "NotificationsRule", "Money"},
+		},
+		{
+			name:            "orgpolicy",
+			api:             "orgpolicy",
+			version:         "v2",
+			expectedMethods: []string{"orgpolicy.projects.policies.patch", "orgpolicy.projects.policies.delete"},
+			expectedSchemas: []string{"Policy", "PolicySpec", "PolicyRule", "StringValues", "Expr"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := Document(tt.api, tt.version, "http://localhost:8080/")
+			if doc == nil {
+				t.Fatal("expected a document")
+			}
+			if doc.ID != tt.api+":"+tt.version || doc.RootURL != "http://localhost:8080/" {
+				t.Errorf("unexpected id %q or rootUrl %q", doc.ID, doc.RootURL)
+			}
+
+			methods := Methods(doc)
+			for _, id := range tt.expectedMethods {
+				if !slices.Contains(methods, id) {
+					t.Errorf("expected method %s, got %v", id, methods)
+				}
+			}
+			for _, id := range tt.expectedSchemas {
+				if doc.Schemas[id] == nil {
+					t.Errorf("expected schema %s", id)
+				}
+			}
+
+			// Every referenced schema must be defined
+			data, err := json.Marshal(doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertRefsDefined(t, data, doc.Schemas)
+		})
+	}
+}
+
+func TestDocument_Unknown(t *testing.T) {
+	if doc := Document("pubsub", "v1", "http://localhost:8080/"); doc != nil {
+		t.Errorf("expected no document for an API the mock doesn't emulate, got %s", doc.ID)
+	}
+	if doc := Document("storage", "v2", "http://localhost:8080/"); doc != nil {
+		t.Errorf("expected no document for an unknown version, got %s", doc.ID)
+	}
+}
+
+func TestDocument_Methods(t *testing.T) {
+	doc := Document("storage", "v1", "http://localhost:8080/")
+
+	insert := doc.Resources["objects"].Methods["insert"]
+	if !insert.SupportsMediaUpload || insert.MediaUpload.Protocols["simple"].Path != "/upload/storage/v1/b/{bucket}/o" {
+		t.Errorf("unexpected media upload of objects.insert: %+v", insert.MediaUpload)
+	}
+	if !slices.Equal(insert.ParameterOrder, []string{"bucket"}) || !insert.Parameters["bucket"].Required {
+		t.Errorf("unexpected path parameters of objects.insert: %v", insert.ParameterOrder)
+	}
+	if insert.Parameters["ifGenerationMatch"] == nil {
+		t.Error("expected objects.insert to accept ifGenerationMatch")
+	}
+	if !doc.Resources["objects"].Methods["get"].SupportsMediaDownload {
+		t.Error("expected objects.get to support media download")
+	}
+
+	bucket := doc.Schemas["Bucket"]
+	if p := bucket.Properties["metageneration"]; p.Type != "string" || p.Format != "int64" {
+		t.Errorf("expected metageneration to be an int64 string, got %+v", p)
+	}
+	if p := bucket.Properties["timeCreated"]; p.Type != "string" || p.Format != "date-time" {
+		t.Errorf("expected timeCreated to be a date-time string, got %+v", p)
+	}
+	if p := bucket.Properties["labels"]; p.Type != "object" || p.AdditionalProperties.Type != "string" {
+		t.Errorf("expected labels to be a string map, got %+v", p)
+	}
+
+	get := Document("firestore", "v1", "http://localhost:8080/").Resources["projects"].Resources["databases"].Resources["documents"].Methods["get"]
+	if get.Path != "v1/{+name}" || get.FlatPath != "v1/{name}" {
+		t.Errorf("unexpected path %q and flatPath %q", get.Path, get.FlatPath)
+	}
+}
+
+// assertRefsDefined checks that every $ref in a JSON document names a schema in schemas.
+func assertRefsDefined(t *testing.T, data []byte, schemas map[string]*Schema) {
+	t.Helper()
+
+	var walk func(v any)
+	walk = func(v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			if ref, ok := v["$ref"].(string); ok && schemas[ref] == nil {
+				t.Errorf("schema %s is referenced but not defined", ref)
+			}
+			for _, child := range v {
+				walk(child)
+			}
+		case []any:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	walk(doc)
+}
+
+func TestMethodID(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    string
+	}{
+		{"GET /storage/v1/b", "storage.buckets.list"},
+		{"GET /storage/v1/b/{bucket}", "storage.buckets.get"},
+		{"GET /storage/v1/b/{bucket}/o/{object...}", "storage.objects.get"},
+		{"HEAD /storage/v1/b/{bucket}/o/{object...}", "storage.objects.get"},
+		{"GET /download/storage/v1/b/{bucket}/o/{object...}", "storage.objects.get"},
+		{"POST /upload/storage/v1/b/{bucket}/o", "storage.objects.insert"},
+		{"PUT /upload/storage/v1/b/{bucket}/o", "storage.objects.insert"},
+		{"POST /sql/v1beta4/projects/{project}/instances", "sqladmin.instances.insert"},
+		{"GET /sql/v1beta4/projects/{project}/instances/{instance}", "sqladmin.instances.get"},
+		{"GET /ui/buckets", ""},
+		{"GET /{$}", ""},
+		{"/static/", ""},
+	}
+
+	for _, tt := range tests {
+		if got := MethodID(tt.pattern); got != tt.want {
+			t.Errorf("MethodID(%q) = %q, want %q", tt.pattern, got, tt.want)
+		}
+	}
+}
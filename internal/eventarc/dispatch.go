@@ -0,0 +1,70 @@
+package eventarc
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Dispatcher delivers the events of triggers to their destinations.
+// HTTP endpoints receive the event in the CloudEvents binary content mode, like Eventarc sends it.
+// Cloud Run services and workflows don't run in the mock, so events for them are logged.
+type Dispatcher struct {
+	client *http.Client
+}
+
+// NewDispatcher creates a new Dispatcher.
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Deliver sends an event to the destination of a trigger asynchronously.
+// It matches the store.TriggerHandler signature.
+func (d *Dispatcher) Deliver(trigger *Trigger, event *CloudEvent) {
+	switch {
+	case trigger.Destination.HttpEndpoint != nil:
+		go d.push(trigger, event)
+	case trigger.Destination.CloudRun != nil:
+		log.Printf("trigger %s: %s %s for Cloud Run service %s", trigger.Name, event.Type, event.Subject, trigger.Destination.CloudRun.Service)
+	default:
+		log.Printf("trigger %s: %s %s for workflow %s", trigger.Name, event.Type, event.Subject, trigger.Destination.Workflow)
+	}
+}
+
+// push sends an event to the HTTP endpoint of a trigger. The data is the body and the other attributes
+// are ce-* headers.
+// Reference: https://github.com/cloudevents/spec/blob/v1.0.2/cloudevents/bindings/http-protocol-binding.md#31-binary-content-mode
+func (d *Dispatcher) push(trigger *Trigger, event *CloudEvent) {
+	uri := trigger.Destination.HttpEndpoint.Uri
+	req, err := http.NewRequest(http.MethodPost, uri, bytes.NewReader(event.Data))
+	if err != nil {
+		log.Printf("trigger %s: failed to build request for %s: %v", trigger.Name, uri, err)
+		return
+	}
+	req.Header.Set("Content-Type", event.DataContentType)
+	req.Header.Set("Ce-Id", event.ID)
+	req.Header.Set("Ce-Source", event.Source)
+	req.Header.Set("Ce-Specversion", event.SpecVersion)
+	req.Header.Set("Ce-Type", event.Type)
+	req.Header.Set("Ce-Time", event.Time.Format(time.RFC3339Nano))
+	if event.Subject != "" {
+		req.Header.Set("Ce-Subject", event.Subject)
+	}
+	for name, value := range event.Extensions {
+		req.Header.Set("Ce-"+name, value)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		log.Printf("trigger %s: failed to deliver to %s: %v", trigger.Name, uri, err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		log.Printf("trigger %s: endpoint %s returned status %d", trigger.Name, uri, resp.StatusCode)
+	}
+}
@@ -0,0 +1,179 @@
+package eventarc
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/logging"
+)
+
+// Event types the mock emits.
+// Reference: https://cloud.google.com/eventarc/docs/reference/supported-events
+const (
+	EventTypeObjectFinalized       = "google.cloud.storage.object.v1.finalized"
+	EventTypeObjectDeleted         = "google.cloud.storage.object.v1.deleted"
+	EventTypeObjectMetadataUpdated = "google.cloud.storage.object.v1.metadataUpdated"
+	EventTypeAuditLogWritten       = "google.cloud.audit.log.v1.written"
+)
+
+// OperatorMatchPathPattern is the event filter operator for path patterns.
+const OperatorMatchPathPattern = "match-path-pattern"
+
+// EventAttributes are the attributes triggers may filter on for each event type, besides "type".
+var EventAttributes = map[string][]string{
+	EventTypeObjectFinalized:       {"bucket"},
+	EventTypeObjectDeleted:         {"bucket"},
+	EventTypeObjectMetadataUpdated: {"bucket"},
+	EventTypeAuditLogWritten:       {"serviceName", "methodName", "resourceName"},
+}
+
+// RequiredAttributes are the attributes triggers must filter on for each event type, besides "type".
+var RequiredAttributes = map[string][]string{
+	EventTypeObjectFinalized:       {"bucket"},
+	EventTypeObjectDeleted:         {"bucket"},
+	EventTypeObjectMetadataUpdated: {"bucket"},
+	EventTypeAuditLogWritten:       {"serviceName", "methodName"},
+}
+
+// CloudEvent is an event in the CloudEvents 1.0 format, as Eventarc delivers it.
+// Reference: https://github.com/cloudevents/spec/blob/v1.0.2/cloudevents/spec.md
+type CloudEvent struct {
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	SpecVersion     string          `json:"specversion"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject,omitempty"`
+	Time            time.Time       `json:"time"`
+	DataContentType string          `json:"datacontenttype,omitempty"`
+	Data            json.RawMessage `json:"data,omitempty"`
+	// Extensions are the event-specific attributes, like "bucket" for Cloud Storage events.
+	Extensions map[string]string `json:"-"`
+}
+
+// Attribute returns the value of a context attribute or extension of the event, or "" if it has none.
+func (e *CloudEvent) Attribute(name string) string {
+	switch name {
+	case "type":
+		return e.Type
+	case "source":
+		return e.Source
+	case "subject":
+		return e.Subject
+	default:
+		return e.Extensions[name]
+	}
+}
+
+// NewStorageEvent creates a Cloud Storage event of an object, whose data is the object resource.
+// Reference: https://cloud.google.com/eventarc/docs/cloudevents#cloud-storage
+func NewStorageEvent(eventType, bucket, object string, data any, t time.Time) (*CloudEvent, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	return &CloudEvent{
+		ID:              rand.Text(),
+		Source:          "//storage.googleapis.com/projects/_/buckets/" + bucket,
+		SpecVersion:     "1.0",
+		Type:            eventType,
+		Subject:         "objects/" + object,
+		Time:            t.UTC(),
+		DataContentType: "application/json",
+		Data:            payload,
+		Extensions:      map[string]string{"bucket": bucket},
+	}, nil
+}
+
+// NewAuditLogEvent creates the event of a Cloud Audit Logs entry, whose data is the log entry.
+// serviceName and methodName are like "cloudsql.googleapis.com" and "cloudsql.instances.create", resourceName
+// is the changed resource, like projects/{project}/instances/{instance}, and resource its monitored resource.
+// Reference: https://cloud.google.com/eventarc/docs/cloudevents#audit-logs
+func NewAuditLogEvent(project, serviceName, methodName, resourceName string, resource *logging.MonitoredResource, t time.Time) (*CloudEvent, error) {
+	protoPayload, err := json.Marshal(map[string]any{
+		"@type":              "type.googleapis.com/google.cloud.audit.AuditLog",
+		"serviceName":        serviceName,
+		"methodName":         methodName,
+		"resourceName":       resourceName,
+		"authenticationInfo": map[string]string{},
+	})
+	if err != nil {
+		return nil, err
+	}
+	id := rand.Text()
+	payload, err := json.Marshal(&logging.LogEntry{
+		LogName:          "projects/" + project + "/logs/cloudaudit.googleapis.com%2Factivity",
+		Resource:         resource,
+		Timestamp:        t.UTC(),
+		ReceiveTimestamp: t.UTC(),
+		Severity:         "NOTICE",
+		InsertID:         id,
+		ProtoPayload:     protoPayload,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &CloudEvent{
+		ID:              id,
+		Source:          "//cloudaudit.googleapis.com/projects/" + project + "/logs/activity",
+		SpecVersion:     "1.0",
+		Type:            EventTypeAuditLogWritten,
+		Subject:         serviceName + "/" + resourceName,
+		Time:            t.UTC(),
+		DataContentType: "application/json",
+		Data:            payload,
+		Extensions: map[string]string{
+			"servicename":  serviceName,
+			"methodname":   methodName,
+			"resourcename": resourceName,
+		},
+	}, nil
+}
+
+// Matches reports whether an event matches all event filters of the trigger.
+// Extension names are lowercase in CloudEvents, so filters on serviceName match the servicename extension.
+func (t *Trigger) Matches(e *CloudEvent) bool {
+	for _, filter := range t.EventFilters {
+		value := e.Attribute(strings.ToLower(filter.Attribute))
+		if filter.Operator == OperatorMatchPathPattern {
+			if !MatchPathPattern(filter.Value, value) {
+				return false
+			}
+		} else if value != filter.Value {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchPathPattern reports whether a path like /projects/_/buckets/logs/objects/a.txt matches a pattern,
+// in which "*" matches within a segment and "**" matches any number of segments.
+// Leading slashes are ignored.
+// Reference: https://cloud.google.com/eventarc/docs/path-patterns
+func MatchPathPattern(pattern, value string) bool {
+	return matchSegments(strings.Split(strings.TrimPrefix(pattern, "/"), "/"), strings.Split(strings.TrimPrefix(value, "/"), "/"))
+}
+
+// matchSegments matches the segments of a path against the segments of a pattern.
+func matchSegments(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	if matched, err := path.Match(pattern[0], segments[0]); err != nil || !matched {
+		return false
+	}
+	return matchSegments(pattern[1:], segments[1:])
+}
@@ -0,0 +1,103 @@
+package eventarc
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTrigger_Matches(t *testing.T) {
+	event, err := NewAuditLogEvent("p", "cloudsql.googleapis.com", "cloudsql.instances.create", "projects/p/instances/db", nil, time.Now())
+	if err != nil {
+		t.Fatalf("NewAuditLogEvent() error: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		filters []*EventFilter
+		want    bool
+	}{
+		{"type only", []*EventFilter{{Attribute: "type", Value: EventTypeAuditLogWritten}}, true},
+		{"other type", []*EventFilter{{Attribute: "type", Value: EventTypeObjectFinalized}}, false},
+		{"service and method", []*EventFilter{
+			{Attribute: "type", Value: EventTypeAuditLogWritten},
+			{Attribute: "serviceName", Value: "cloudsql.googleapis.com"},
+			{Attribute: "methodName", Value: "cloudsql.instances.create"},
+		}, true},
+		{"other method", []*EventFilter{{Attribute: "methodName", Value: "cloudsql.instances.delete"}}, false},
+		{"path pattern", []*EventFilter{{Attribute: "resourceName", Value: "/projects/*/instances/*", Operator: OperatorMatchPathPattern}}, true},
+		{"other path pattern", []*EventFilter{{Attribute: "resourceName", Value: "/projects/*/instances/other", Operator: OperatorMatchPathPattern}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trigger := &Trigger{EventFilters: tt.filters}
+			if got := trigger.Matches(event); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchPathPattern(t *testing.T) {
+	tests := []struct {
+		pattern, value string
+		want           bool
+	}{
+		{"/projects/_/buckets/logs/objects/a.txt", "projects/_/buckets/logs/objects/a.txt", true},
+		{"/projects/_/buckets/logs/objects/*.txt", "/projects/_/buckets/logs/objects/a.txt", true},
+		{"/projects/_/buckets/logs/objects/*.txt", "/projects/_/buckets/logs/objects/dir/a.txt", false},
+		{"/projects/_/buckets/logs/objects/**", "/projects/_/buckets/logs/objects/dir/a.txt", true},
+		{"/projects/**/instances/db", "/projects/p/instances/db", true},
+		{"/projects/*", "/projects/p/instances/db", false},
+	}
+
+	for _, tt := range tests {
+		if got := MatchPathPattern(tt.pattern, tt.value); got != tt.want {
+			t.Errorf("MatchPathPattern(%q, %q) = %v, want %v", tt.pattern, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestDispatcher_Deliver(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		received <- r
+	}))
+	defer server.Close()
+
+	event, err := NewStorageEvent(EventTypeObjectFinalized, "uploads", "a.txt", map[string]string{"name": "a.txt"}, time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("NewStorageEvent() error: %v", err)
+	}
+	trigger := &Trigger{Name: "projects/p/locations/l/triggers/t", Destination: &Destination{HttpEndpoint: &HttpEndpoint{Uri: server.URL + "/events"}}}
+	NewDispatcher().Deliver(trigger, event)
+
+	select {
+	case r := <-received:
+		for header, expected := range map[string]string{
+			"Content-Type":   "application/json",
+			"Ce-Id":          event.ID,
+			"Ce-Specversion": "1.0",
+			"Ce-Type":        EventTypeObjectFinalized,
+			"Ce-Source":      "//storage.googleapis.com/projects/_/buckets/uploads",
+			"Ce-Subject":     "objects/a.txt",
+			"Ce-Bucket":      "uploads",
+			"Ce-Time":        "2025-01-15T10:00:00Z",
+		} {
+			if value := r.Header.Get(header); value != expected {
+				t.Errorf("expected header %s %q, got %q", header, expected, value)
+			}
+		}
+		if r.URL.Path != "/events" || body != `{"name":"a.txt"}` {
+			t.Errorf("unexpected request to %s with body %s", r.URL.Path, body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the event to be delivered")
+	}
+}
@@ -0,0 +1,90 @@
+// Package eventarc provides data models, CloudEvents and trigger delivery for the Eventarc API (v1) mock.
+package eventarc
+
+import (
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/longrunning"
+)
+
+// TriggerTypeURL is the type URL of triggers in operation responses.
+const TriggerTypeURL = "type.googleapis.com/google.cloud.eventarc.v1.Trigger"
+
+// Trigger routes the events matching its filters to a destination.
+// Reference: https://cloud.google.com/eventarc/docs/reference/rest/v1/projects.locations.triggers
+type Trigger struct {
+	// Name is the resource name, e.g. projects/{project}/locations/{location}/triggers/{trigger}.
+	Name string `json:"name"`
+	// Uid is a server-assigned unique identifier.
+	Uid string `json:"uid,omitempty"`
+	// CreateTime and UpdateTime are the times the trigger was created and last updated.
+	CreateTime time.Time `json:"createTime,omitzero"`
+	UpdateTime time.Time `json:"updateTime,omitzero"`
+	// EventFilters select the events the trigger fires for; an event must match all of them.
+	EventFilters []*EventFilter `json:"eventFilters,omitempty"`
+	// ServiceAccount is the service account events are delivered as. It is stored, but no token is sent.
+	ServiceAccount string `json:"serviceAccount,omitempty"`
+	// Destination is where matching events are sent.
+	Destination *Destination `json:"destination,omitempty"`
+	// Transport is the Pub/Sub topic events are delivered through.
+	Transport *Transport `json:"transport,omitempty"`
+	// Labels are user-defined key/value labels.
+	Labels map[string]string `json:"labels,omitempty"`
+	// EventDataContentType is the content type of the event data, application/json by default.
+	EventDataContentType string `json:"eventDataContentType,omitempty"`
+	// Etag changes whenever the trigger is updated.
+	Etag string `json:"etag,omitempty"`
+}
+
+// EventFilter matches an attribute of an event, like its type or bucket.
+// Reference: https://cloud.google.com/eventarc/docs/reference/rest/v1/projects.locations.triggers#EventFilter
+type EventFilter struct {
+	// Attribute is the name of the CloudEvents attribute, e.g. "type" or "bucket".
+	Attribute string `json:"attribute"`
+	// Value is the value the attribute must have.
+	Value string `json:"value"`
+	// Operator is empty for an exact match, or "match-path-pattern" for a path pattern like "/projects/_/buckets/*".
+	Operator string `json:"operator,omitempty"`
+}
+
+// Destination is where the events of a trigger are sent; exactly one field is set.
+// Reference: https://cloud.google.com/eventarc/docs/reference/rest/v1/projects.locations.triggers#Destination
+type Destination struct {
+	CloudRun     *CloudRun     `json:"cloudRun,omitempty"`
+	HttpEndpoint *HttpEndpoint `json:"httpEndpoint,omitempty"`
+	Workflow     string        `json:"workflow,omitempty"`
+}
+
+// CloudRun is a Cloud Run service events are sent to.
+type CloudRun struct {
+	Service string `json:"service"`
+	Path    string `json:"path,omitempty"`
+	Region  string `json:"region,omitempty"`
+}
+
+// HttpEndpoint is an HTTP endpoint events are sent to.
+type HttpEndpoint struct {
+	// Uri is the URL of the endpoint, e.g. http://localhost:9090/events.
+	Uri string `json:"uri"`
+}
+
+// Transport is the intermediary events are delivered through.
+type Transport struct {
+	Pubsub *Pubsub `json:"pubsub,omitempty"`
+}
+
+// Pubsub is the Pub/Sub topic and subscription of a trigger.
+type Pubsub struct {
+	Topic        string `json:"topic,omitempty"`
+	Subscription string `json:"subscription,omitempty"`
+}
+
+// Operation is a google.longrunning.Operation.
+// Reference: https://cloud.google.com/eventarc/docs/reference/rest/v1/projects.locations.operations
+type Operation = longrunning.Operation
+
+// ListTriggersResponse is the response of triggers.list.
+type ListTriggersResponse struct {
+	Triggers      []*Trigger `json:"triggers,omitempty"`
+	NextPageToken string     `json:"nextPageToken,omitempty"`
+}
@@ -0,0 +1,166 @@
+// Package fieldmask implements the fields parameter of Google APIs, which selects the fields of a partial
+// response, like "items(name,settings/tier),nextPageToken".
+// Reference: https://cloud.google.com/storage/docs/json_api#partial-response
+package fieldmask
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Mask is a parsed field selection. A nil Mask selects everything.
+type Mask struct {
+	// fields maps the selected field names to the selection within them; nil selects the whole field.
+	// The name "*" selects all fields.
+	fields map[string]*Mask
+}
+
+// Parse parses a field selection: comma-separated fields, with "/" selecting a field within another
+// and parentheses selecting several fields within one, like "kind,items(name,settings/tier)".
+// An empty selection returns a nil Mask, which selects everything.
+// Returns an "invalid field selection" error for malformed selections.
+func Parse(s string) (*Mask, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	p := &parser{input: s}
+	m, err := p.parseFields()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.input) {
+		return nil, fmt.Errorf("invalid field selection %q: unexpected %q", s, p.input[p.pos])
+	}
+	return m, nil
+}
+
+// parser is a recursive descent parser for field selections.
+type parser struct {
+	input string
+	pos   int
+}
+
+// parseFields parses comma-separated fields up to the end of the input or a closing parenthesis.
+func (p *parser) parseFields() (*Mask, error) {
+	m := &Mask{fields: make(map[string]*Mask)}
+	for {
+		if err := p.parseField(m); err != nil {
+			return nil, err
+		}
+		if p.pos >= len(p.input) || p.input[p.pos] != ',' {
+			return m, nil
+		}
+		p.pos++
+	}
+}
+
+// parseField parses a field path like "settings/tier" or "items(name,size)" and adds it to m.
+func (p *parser) parseField(m *Mask) error {
+	name := p.parseName()
+	if name == "" {
+		return fmt.Errorf("invalid field selection %q: expected a field name at position %d", p.input, p.pos)
+	}
+
+	var sub *Mask
+	switch {
+	case p.pos < len(p.input) && p.input[p.pos] == '/':
+		p.pos++
+		sub = &Mask{fields: make(map[string]*Mask)}
+		if err := p.parseField(sub); err != nil {
+			return err
+		}
+	case p.pos < len(p.input) && p.input[p.pos] == '(':
+		p.pos++
+		parsed, err := p.parseFields()
+		if err != nil {
+			return err
+		}
+		if p.pos >= len(p.input) || p.input[p.pos] != ')' {
+			return fmt.Errorf("invalid field selection %q: missing closing parenthesis", p.input)
+		}
+		p.pos++
+		sub = parsed
+	}
+
+	m.add(name, sub)
+	return nil
+}
+
+// parseName parses a field name, skipping surrounding spaces.
+func (p *parser) parseName() string {
+	for p.pos < len(p.input) && p.input[p.pos] == ' ' {
+		p.pos++
+	}
+	start := p.pos
+	for p.pos < len(p.input) && !strings.ContainsRune("/(),", rune(p.input[p.pos])) {
+		p.pos++
+	}
+	return strings.TrimSpace(p.input[start:p.pos])
+}
+
+// add selects a field, merging the selection with an earlier one of the same field.
+func (m *Mask) add(name string, sub *Mask) {
+	existing, ok := m.fields[name]
+	switch {
+	case !ok:
+		m.fields[name] = sub
+	case existing == nil || sub == nil:
+		// Selecting the whole field wins over selecting parts of it
+		m.fields[name] = nil
+	default:
+		for subName, subMask := range sub.fields {
+			existing.add(subName, subMask)
+		}
+	}
+}
+
+// Apply returns the parts of a decoded JSON value the mask selects. Selections apply to each element of
+// arrays, and fields the value doesn't have are left out.
+func (m *Mask) Apply(value any) any {
+	if m == nil {
+		return value
+	}
+	switch v := value.(type) {
+	case map[string]any:
+		result := make(map[string]any)
+		for name, field := range v {
+			sub, ok := m.fields[name]
+			if !ok {
+				sub, ok = m.fields["*"]
+			}
+			if ok {
+				result[name] = sub.Apply(field)
+			}
+		}
+		return result
+	case []any:
+		result := make([]any, len(v))
+		for i, item := range v {
+			result[i] = m.Apply(item)
+		}
+		return result
+	default:
+		return value
+	}
+}
+
+// Select encodes data as JSON and returns the parts the mask selects, ready to be encoded again.
+func (m *Mask) Select(data any) (any, error) {
+	if m == nil {
+		return data, nil
+	}
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	// Keep numbers as they are, since large integers don't fit into a float64
+	dec := json.NewDecoder(bytes.NewReader(encoded))
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+	return m.Apply(value), nil
+}
@@ -0,0 +1,60 @@
+package fieldmask
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMask_Select(t *testing.T) {
+	response := map[string]any{
+		"kind":          "sql#instancesList",
+		"nextPageToken": "abc",
+		"items": []any{
+			map[string]any{"name": "db-1", "state": "RUNNABLE", "settings": map[string]any{"tier": "db-f1-micro", "dataDiskSizeGb": "10"}},
+			map[string]any{"name": "db-2", "state": "STOPPED", "settings": map[string]any{"tier": "db-g1-small"}},
+		},
+		"generation": json.Number("1700000000000000001"),
+	}
+
+	tests := []struct {
+		name     string
+		fields   string
+		expected string
+	}{
+		{"empty selection", "", `{"generation":1700000000000000001,"items":[{"name":"db-1","settings":{"dataDiskSizeGb":"10","tier":"db-f1-micro"},"state":"RUNNABLE"},{"name":"db-2","settings":{"tier":"db-g1-small"},"state":"STOPPED"}],"kind":"sql#instancesList","nextPageToken":"abc"}`},
+		{"top-level fields", "kind,nextPageToken", `{"kind":"sql#instancesList","nextPageToken":"abc"}`},
+		{"fields of items", "items(name,settings/tier),nextPageToken", `{"items":[{"name":"db-1","settings":{"tier":"db-f1-micro"}},{"name":"db-2","settings":{"tier":"db-g1-small"}}],"nextPageToken":"abc"}`},
+		{"path into items", "items/name", `{"items":[{"name":"db-1"},{"name":"db-2"}]}`},
+		{"wildcard", "items(*)", `{"items":[{"name":"db-1","settings":{"dataDiskSizeGb":"10","tier":"db-f1-micro"},"state":"RUNNABLE"},{"name":"db-2","settings":{"tier":"db-g1-small"},"state":"STOPPED"}]}`},
+		{"merged selections", "items/name,items/state", `{"items":[{"name":"db-1","state":"RUNNABLE"},{"name":"db-2","state":"STOPPED"}]}`},
+		{"unknown field", "selfLink", `{}`},
+		{"large numbers", "generation", `{"generation":1700000000000000001}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mask, err := Parse(tt.fields)
+			if err != nil {
+				t.Fatalf("Parse(%q) failed: %v", tt.fields, err)
+			}
+			selected, err := mask.Select(response)
+			if err != nil {
+				t.Fatalf("Select failed: %v", err)
+			}
+			got, _ := json.Marshal(selected)
+			if string(got) != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, fields := range []string{"items(name", "items()", ",kind", "kind,", "items)name", "settings/"} {
+		t.Run(fields, func(t *testing.T) {
+			if _, err := Parse(fields); err == nil {
+				t.Errorf("expected Parse(%q) to fail", fields)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+// Package firestore provides data models for the Google Cloud Firestore API mock.
+package firestore
+
+import (
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/gcperror"
+)
+
+// Document represents a Firestore document.
+// Reference: https://cloud.google.com/firestore/docs/reference/rest/v1/projects.databases.documents
+type Document struct {
+	// Name is the resource name of the document, e.g.
+	// projects/{project}/databases/{database}/documents/{collection}/{document}.
+	Name string `json:"name"`
+	// Fields are the document's fields.
+	Fields map[string]*Value `json:"fields,omitempty"`
+	// CreateTime is the time at which the document was created.
+	CreateTime time.Time `json:"createTime"`
+	// UpdateTime is the time at which the document was last changed.
+	UpdateTime time.Time `json:"updateTime"`
+}
+
+// Value holds a single Firestore value. Exactly one field is set.
+// Reference: https://cloud.google.com/firestore/docs/reference/rest/v1/Value
+type Value struct {
+	// NullValue is set to "NULL_VALUE" for a null value.
+	NullValue *string `json:"nullValue,omitempty"`
+	// BooleanValue is a boolean value.
+	BooleanValue *bool `json:"booleanValue,omitempty"`
+	// IntegerValue is a 64-bit integer value, encoded as a string.
+	IntegerValue *string `json:"integerValue,omitempty"`
+	// DoubleValue is a double value.
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+	// TimestampValue is a timestamp value in RFC 3339 format.
+	TimestampValue *string `json:"timestampValue,omitempty"`
+	// StringValue is a string value.
+	StringValue *string `json:"stringValue,omitempty"`
+	// BytesValue is a bytes value, encoded using base64.
+	BytesValue *string `json:"bytesValue,omitempty"`
+	// ReferenceValue is a reference to a document.
+	ReferenceValue *string `json:"referenceValue,omitempty"`
+	// GeoPointValue is a geo point value.
+	GeoPointValue *LatLng `json:"geoPointValue,omitempty"`
+	// ArrayValue is an array value.
+	ArrayValue *ArrayValue `json:"arrayValue,omitempty"`
+	// MapValue is a map value.
+	MapValue *MapValue `json:"mapValue,omitempty"`
+}
+
+// LatLng represents a latitude/longitude pair.
+type LatLng struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// ArrayValue represents an array value.
+type ArrayValue struct {
+	Values []*Value `json:"values,omitempty"`
+}
+
+// MapValue represents a map value.
+type MapValue struct {
+	Fields map[string]*Value `json:"fields,omitempty"`
+}
+
+// ListDocumentsResponse represents the response for listing documents.
+// Reference: https://cloud.google.com/firestore/docs/reference/rest/v1/projects.databases.documents/list
+type ListDocumentsResponse struct {
+	Documents     []*Document `json:"documents,omitempty"`
+	NextPageToken string      `json:"nextPageToken,omitempty"`
+}
+
+// RunQueryRequest represents the request body for running a query.
+// Reference: https://cloud.google.com/firestore/docs/reference/rest/v1/projects.databases.documents/runQuery
+type RunQueryRequest struct {
+	StructuredQuery *StructuredQuery `json:"structuredQuery"`
+}
+
+// RunQueryResponse is a single element of the response for running a query.
+// A query without results returns a single element without a document.
+type RunQueryResponse struct {
+	Document *Document `json:"document,omitempty"`
+	ReadTime time.Time `json:"readTime"`
+}
+
+// StructuredQuery represents a Firestore query.
+// Cursors (startAt/endAt) and projections are not supported.
+// Reference: https://cloud.google.com/firestore/docs/reference/rest/v1/StructuredQuery
+type StructuredQuery struct {
+	From    []CollectionSelector `json:"from"`
+	Where   *Filter              `json:"where,omitempty"`
+	OrderBy []Order              `json:"orderBy,omitempty"`
+	Offset  int                  `json:"offset,omitempty"`
+	Limit   *int                 `json:"limit,omitempty"`
+}
+
+// CollectionSelector selects the collections a query runs on.
+type CollectionSelector struct {
+	// CollectionID is the ID of the collection.
+	CollectionID string `json:"collectionId"`
+	// AllDescendants selects all collections with this ID below the parent, not just direct children.
+	AllDescendants bool `json:"allDescendants,omitempty"`
+}
+
+// Filter is a query filter. Exactly one field is set.
+type Filter struct {
+	CompositeFilter *CompositeFilter `json:"compositeFilter,omitempty"`
+	FieldFilter     *FieldFilter     `json:"fieldFilter,omitempty"`
+	UnaryFilter     *UnaryFilter     `json:"unaryFilter,omitempty"`
+}
+
+// CompositeFilter combines filters with AND or OR.
+type CompositeFilter struct {
+	Op      string   `json:"op"`
+	Filters []Filter `json:"filters"`
+}
+
+// FieldFilter compares a field with a value.
+type FieldFilter struct {
+	Field FieldReference `json:"field"`
+	Op    string         `json:"op"`
+	Value *Value         `json:"value"`
+}
+
+// UnaryFilter checks a field for null or NaN.
+type UnaryFilter struct {
+	Op    string         `json:"op"`
+	Field FieldReference `json:"field"`
+}
+
+// FieldReference references a field by its path, e.g. "address.city".
+type FieldReference struct {
+	FieldPath string `json:"fieldPath"`
+}
+
+// Order orders query results by a field.
+type Order struct {
+	Field FieldReference `json:"field"`
+	// Direction is ASCENDING (default) or DESCENDING.
+	Direction string `json:"direction,omitempty"`
+}
+
+// APIError represents an error response from the Firestore API.
+type APIError = gcperror.Response
+
+// ErrorDetails contains the details of an API error.
+type ErrorDetails = gcperror.Details
@@ -0,0 +1,392 @@
+package firestore
+
+import (
+	"bytes"
+	"encoding/base64"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Type order of values, used to compare values of different types.
+// Reference: https://cloud.google.com/firestore/docs/concepts/data-types#value_type_ordering
+const (
+	typeOrderNull = iota
+	typeOrderBoolean
+	typeOrderNumber
+	typeOrderTimestamp
+	typeOrderString
+	typeOrderBytes
+	typeOrderReference
+	typeOrderGeoPoint
+	typeOrderArray
+	typeOrderMap
+)
+
+// typeOrder returns the position of a value's type in the Firestore type order.
+func typeOrder(v *Value) int {
+	switch {
+	case v.BooleanValue != nil:
+		return typeOrderBoolean
+	case v.IntegerValue != nil, v.DoubleValue != nil:
+		return typeOrderNumber
+	case v.TimestampValue != nil:
+		return typeOrderTimestamp
+	case v.StringValue != nil:
+		return typeOrderString
+	case v.BytesValue != nil:
+		return typeOrderBytes
+	case v.ReferenceValue != nil:
+		return typeOrderReference
+	case v.GeoPointValue != nil:
+		return typeOrderGeoPoint
+	case v.ArrayValue != nil:
+		return typeOrderArray
+	case v.MapValue != nil:
+		return typeOrderMap
+	default:
+		return typeOrderNull
+	}
+}
+
+// Compare compares two values using the Firestore value ordering.
+// Returns a negative number if a < b, zero if they are equal and a positive number if a > b.
+func Compare(a, b *Value) int {
+	if orderA, orderB := typeOrder(a), typeOrder(b); orderA != orderB {
+		return orderA - orderB
+	}
+
+	switch typeOrder(a) {
+	case typeOrderBoolean:
+		return compareBools(*a.BooleanValue, *b.BooleanValue)
+	case typeOrderNumber:
+		return compareNumbers(number(a), number(b))
+	case typeOrderTimestamp:
+		return compareTimestamps(*a.TimestampValue, *b.TimestampValue)
+	case typeOrderString:
+		return strings.Compare(*a.StringValue, *b.StringValue)
+	case typeOrderBytes:
+		bytesA, _ := base64.StdEncoding.DecodeString(*a.BytesValue)
+		bytesB, _ := base64.StdEncoding.DecodeString(*b.BytesValue)
+		return bytes.Compare(bytesA, bytesB)
+	case typeOrderReference:
+		return strings.Compare(*a.ReferenceValue, *b.ReferenceValue)
+	case typeOrderGeoPoint:
+		if c := compareNumbers(a.GeoPointValue.Latitude, b.GeoPointValue.Latitude); c != 0 {
+			return c
+		}
+		return compareNumbers(a.GeoPointValue.Longitude, b.GeoPointValue.Longitude)
+	case typeOrderArray:
+		return compareArrays(a.ArrayValue.Values, b.ArrayValue.Values)
+	case typeOrderMap:
+		return compareMaps(a.MapValue.Fields, b.MapValue.Fields)
+	default:
+		return 0
+	}
+}
+
+// number returns the numeric value of an integer or double value.
+func number(v *Value) float64 {
+	if v.DoubleValue != nil {
+		return *v.DoubleValue
+	}
+	n, _ := strconv.ParseInt(*v.IntegerValue, 10, 64)
+	return float64(n)
+}
+
+// compareBools orders false before true.
+func compareBools(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case !a:
+		return -1
+	default:
+		return 1
+	}
+}
+
+// compareNumbers compares two numbers, ordering NaN before all other numbers.
+func compareNumbers(a, b float64) int {
+	switch {
+	case math.IsNaN(a) && math.IsNaN(b):
+		return 0
+	case math.IsNaN(a), a < b:
+		return -1
+	case math.IsNaN(b), a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// compareTimestamps compares two RFC 3339 timestamps.
+func compareTimestamps(a, b string) int {
+	timeA, errA := time.Parse(time.RFC3339Nano, a)
+	timeB, errB := time.Parse(time.RFC3339Nano, b)
+	if errA != nil || errB != nil {
+		return strings.Compare(a, b)
+	}
+	return timeA.Compare(timeB)
+}
+
+// compareArrays compares arrays element by element, then by length.
+func compareArrays(a, b []*Value) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := Compare(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	return len(a) - len(b)
+}
+
+// compareMaps compares maps by their sorted keys and values, then by size.
+func compareMaps(a, b map[string]*Value) int {
+	keysA := sortedKeys(a)
+	keysB := sortedKeys(b)
+	for i := 0; i < len(keysA) && i < len(keysB); i++ {
+		if c := strings.Compare(keysA[i], keysB[i]); c != 0 {
+			return c
+		}
+		if c := Compare(a[keysA[i]], b[keysB[i]]); c != 0 {
+			return c
+		}
+	}
+	return len(keysA) - len(keysB)
+}
+
+// sortedKeys returns the keys of a map in sorted order.
+func sortedKeys(m map[string]*Value) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// splitFieldPath splits a field path like "address.city" into its segments.
+// Segments quoted with backticks may contain dots.
+func splitFieldPath(path string) []string {
+	var segments []string
+	var current strings.Builder
+	quoted := false
+	for _, c := range path {
+		switch {
+		case c == '`':
+			quoted = !quoted
+		case c == '.' && !quoted:
+			segments = append(segments, current.String())
+			current.Reset()
+		default:
+			current.WriteRune(c)
+		}
+	}
+	return append(segments, current.String())
+}
+
+// GetField returns the value at a field path, or nil if it doesn't exist.
+func GetField(fields map[string]*Value, path string) *Value {
+	segments := splitFieldPath(path)
+	for i, segment := range segments {
+		value, exists := fields[segment]
+		if !exists {
+			return nil
+		}
+		if i == len(segments)-1 {
+			return value
+		}
+		if value.MapValue == nil {
+			return nil
+		}
+		fields = value.MapValue.Fields
+	}
+	return nil
+}
+
+// SetField returns a copy of fields with the value at a field path set.
+// Only the maps along the path are copied, values are treated as immutable.
+func SetField(fields map[string]*Value, path string, value *Value) map[string]*Value {
+	return setField(fields, splitFieldPath(path), value)
+}
+
+// DeleteField returns a copy of fields with the value at a field path removed.
+func DeleteField(fields map[string]*Value, path string) map[string]*Value {
+	return setField(fields, splitFieldPath(path), nil)
+}
+
+// setField sets (or with a nil value, removes) the value at a split field path.
+func setField(fields map[string]*Value, segments []string, value *Value) map[string]*Value {
+	result := make(map[string]*Value, len(fields)+1)
+	for key, v := range fields {
+		result[key] = v
+	}
+
+	segment := segments[0]
+	if len(segments) == 1 {
+		if value == nil {
+			delete(result, segment)
+		} else {
+			result[segment] = value
+		}
+		return result
+	}
+
+	var nested map[string]*Value
+	if existing := result[segment]; existing != nil && existing.MapValue != nil {
+		nested = existing.MapValue.Fields
+	} else if value == nil {
+		// Nothing to remove
+		return result
+	}
+
+	result[segment] = &Value{MapValue: &MapValue{Fields: setField(nested, segments[1:], value)}}
+	return result
+}
+
+// Matches returns true if a document's fields match a query filter.
+// Reference: https://cloud.google.com/firestore/docs/query-data/queries
+func Matches(fields map[string]*Value, filter *Filter) bool {
+	switch {
+	case filter == nil:
+		return true
+	case filter.CompositeFilter != nil:
+		return matchesComposite(fields, filter.CompositeFilter)
+	case filter.FieldFilter != nil:
+		return matchesField(fields, filter.FieldFilter)
+	case filter.UnaryFilter != nil:
+		return matchesUnary(fields, filter.UnaryFilter)
+	default:
+		return true
+	}
+}
+
+// matchesComposite evaluates an AND or OR filter.
+func matchesComposite(fields map[string]*Value, filter *CompositeFilter) bool {
+	if filter.Op == "OR" {
+		for i := range filter.Filters {
+			if Matches(fields, &filter.Filters[i]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for i := range filter.Filters {
+		if !Matches(fields, &filter.Filters[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesField evaluates a field filter. Documents without the field never match.
+func matchesField(fields map[string]*Value, filter *FieldFilter) bool {
+	value := GetField(fields, filter.Field.FieldPath)
+	if value == nil || filter.Value == nil {
+		return false
+	}
+
+	switch filter.Op {
+	case "EQUAL":
+		return Compare(value, filter.Value) == 0
+	case "NOT_EQUAL":
+		return typeOrder(value) != typeOrderNull && Compare(value, filter.Value) != 0
+	case "LESS_THAN":
+		return typeOrder(value) == typeOrder(filter.Value) && Compare(value, filter.Value) < 0
+	case "LESS_THAN_OR_EQUAL":
+		return typeOrder(value) == typeOrder(filter.Value) && Compare(value, filter.Value) <= 0
+	case "GREATER_THAN":
+		return typeOrder(value) == typeOrder(filter.Value) && Compare(value, filter.Value) > 0
+	case "GREATER_THAN_OR_EQUAL":
+		return typeOrder(value) == typeOrder(filter.Value) && Compare(value, filter.Value) >= 0
+	case "ARRAY_CONTAINS":
+		return value.ArrayValue != nil && containsValue(value.ArrayValue.Values, filter.Value)
+	case "ARRAY_CONTAINS_ANY":
+		if value.ArrayValue == nil || filter.Value.ArrayValue == nil {
+			return false
+		}
+		for _, candidate := range filter.Value.ArrayValue.Values {
+			if containsValue(value.ArrayValue.Values, candidate) {
+				return true
+			}
+		}
+		return false
+	case "IN":
+		return filter.Value.ArrayValue != nil && containsValue(filter.Value.ArrayValue.Values, value)
+	case "NOT_IN":
+		return filter.Value.ArrayValue != nil && typeOrder(value) != typeOrderNull &&
+			!containsValue(filter.Value.ArrayValue.Values, value)
+	default:
+		return false
+	}
+}
+
+// matchesUnary evaluates a null or NaN check.
+func matchesUnary(fields map[string]*Value, filter *UnaryFilter) bool {
+	value := GetField(fields, filter.Field.FieldPath)
+	if value == nil {
+		return false
+	}
+
+	isNaN := value.DoubleValue != nil && math.IsNaN(*value.DoubleValue)
+	isNull := typeOrder(value) == typeOrderNull
+
+	switch filter.Op {
+	case "IS_NULL":
+		return isNull
+	case "IS_NOT_NULL":
+		return !isNull
+	case "IS_NAN":
+		return isNaN
+	case "IS_NOT_NAN":
+		return !isNaN && !isNull
+	default:
+		return false
+	}
+}
+
+// containsValue returns true if values contains a value equal to v.
+func containsValue(values []*Value, v *Value) bool {
+	for _, candidate := range values {
+		if Compare(candidate, v) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// SortDocuments sorts documents by the given orders, then by name.
+// Documents without a field used for ordering are removed, like in Firestore.
+func SortDocuments(docs []*Document, orders []Order) []*Document {
+	sorted := make([]*Document, 0, len(docs))
+	for _, doc := range docs {
+		hasFields := true
+		for _, order := range orders {
+			if GetField(doc.Fields, order.Field.FieldPath) == nil {
+				hasFields = false
+				break
+			}
+		}
+		if hasFields {
+			sorted = append(sorted, doc)
+		}
+	}
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		for _, order := range orders {
+			c := Compare(GetField(sorted[i].Fields, order.Field.FieldPath), GetField(sorted[j].Fields, order.Field.FieldPath))
+			if order.Direction == "DESCENDING" {
+				c = -c
+			}
+			if c != 0 {
+				return c < 0
+			}
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	return sorted
+}
@@ -0,0 +1,133 @@
+package firestore
+
+import (
+	"math"
+	"testing"
+)
+
+func stringValue(s string) *Value  { return &Value{StringValue: &s} }
+func integerValue(s string) *Value { return &Value{IntegerValue: &s} }
+func doubleValue(f float64) *Value { return &Value{DoubleValue: &f} }
+func arrayValue(values ...*Value) *Value {
+	return &Value{ArrayValue: &ArrayValue{Values: values}}
+}
+
+func TestCompare(t *testing.T) {
+	null := "NULL_VALUE"
+	tests := []struct {
+		name string
+		a, b *Value
+		want int
+	}{
+		{"equal strings", stringValue("a"), stringValue("a"), 0},
+		{"ordered strings", stringValue("a"), stringValue("b"), -1},
+		{"integer and double", integerValue("2"), doubleValue(1.5), 1},
+		{"equal integer and double", integerValue("2"), doubleValue(2), 0},
+		{"NaN before numbers", doubleValue(math.NaN()), integerValue("-100"), -1},
+		{"null before string", &Value{NullValue: &null}, stringValue(""), -1},
+		{"number before string", integerValue("100"), stringValue("1"), -1},
+		{"arrays by element", arrayValue(integerValue("1"), integerValue("2")), arrayValue(integerValue("1"), integerValue("3")), -1},
+		{"arrays by length", arrayValue(integerValue("1")), arrayValue(integerValue("1"), integerValue("2")), -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Compare(tt.a, tt.b)
+			if (got < 0) != (tt.want < 0) || (got > 0) != (tt.want > 0) {
+				t.Errorf("Compare() = %d, want sign of %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatches(t *testing.T) {
+	fields := map[string]*Value{
+		"name": stringValue("alice"),
+		"age":  integerValue("30"),
+		"tags": arrayValue(stringValue("admin"), stringValue("dev")),
+		"address": {MapValue: &MapValue{Fields: map[string]*Value{
+			"city": stringValue("Vienna"),
+		}}},
+	}
+
+	fieldFilter := func(path, op string, value *Value) *Filter {
+		return &Filter{FieldFilter: &FieldFilter{Field: FieldReference{FieldPath: path}, Op: op, Value: value}}
+	}
+
+	tests := []struct {
+		name   string
+		filter *Filter
+		want   bool
+	}{
+		{"nil filter", nil, true},
+		{"equal", fieldFilter("name", "EQUAL", stringValue("alice")), true},
+		{"not equal", fieldFilter("name", "NOT_EQUAL", stringValue("alice")), false},
+		{"nested field", fieldFilter("address.city", "EQUAL", stringValue("Vienna")), true},
+		{"greater than", fieldFilter("age", "GREATER_THAN", integerValue("18")), true},
+		{"range across types", fieldFilter("age", "LESS_THAN", stringValue("z")), false},
+		{"missing field", fieldFilter("missing", "NOT_EQUAL", stringValue("x")), false},
+		{"array contains", fieldFilter("tags", "ARRAY_CONTAINS", stringValue("dev")), true},
+		{"array contains any", fieldFilter("tags", "ARRAY_CONTAINS_ANY", arrayValue(stringValue("x"), stringValue("admin"))), true},
+		{"in", fieldFilter("name", "IN", arrayValue(stringValue("bob"), stringValue("alice"))), true},
+		{"not in", fieldFilter("name", "NOT_IN", arrayValue(stringValue("alice"))), false},
+		{
+			"and",
+			&Filter{CompositeFilter: &CompositeFilter{Op: "AND", Filters: []Filter{
+				*fieldFilter("name", "EQUAL", stringValue("alice")),
+				*fieldFilter("age", "LESS_THAN", integerValue("30")),
+			}}},
+			false,
+		},
+		{
+			"or",
+			&Filter{CompositeFilter: &CompositeFilter{Op: "OR", Filters: []Filter{
+				*fieldFilter("name", "EQUAL", stringValue("bob")),
+				*fieldFilter("age", "LESS_THAN_OR_EQUAL", integerValue("30")),
+			}}},
+			true,
+		},
+		{"is not null", &Filter{UnaryFilter: &UnaryFilter{Op: "IS_NOT_NULL", Field: FieldReference{FieldPath: "name"}}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Matches(fields, tt.filter); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetAndDeleteField(t *testing.T) {
+	original := map[string]*Value{"a": stringValue("1")}
+
+	updated := SetField(original, "b.c", stringValue("2"))
+	if GetField(updated, "b.c") == nil {
+		t.Error("expected nested field to be set")
+	}
+	if GetField(original, "b.c") != nil {
+		t.Error("expected original fields to be unchanged")
+	}
+
+	updated = DeleteField(updated, "a")
+	if GetField(updated, "a") != nil {
+		t.Error("expected field to be removed")
+	}
+}
+
+func TestSortDocuments(t *testing.T) {
+	docs := []*Document{
+		{Name: "c", Fields: map[string]*Value{"n": integerValue("1")}},
+		{Name: "a", Fields: map[string]*Value{"n": integerValue("2")}},
+		{Name: "b", Fields: map[string]*Value{}},
+	}
+
+	sorted := SortDocuments(docs, []Order{{Field: FieldReference{FieldPath: "n"}, Direction: "DESCENDING"}})
+
+	if len(sorted) != 2 {
+		t.Fatalf("expected documents without the order field to be removed, got %d", len(sorted))
+	}
+	if sorted[0].Name != "a" || sorted[1].Name != "c" {
+		t.Errorf("unexpected order: %s, %s", sorted[0].Name, sorted[1].Name)
+	}
+}
@@ -0,0 +1,272 @@
+// Package fixture renders logged API requests as runnable reproductions: curl scripts, Go tests
+// against the in-process mock and VCR cassettes, so a manual reproduction becomes a regression test.
+package fixture
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Export formats.
+const (
+	// FormatCurl renders a shell script sending each request with curl to $BASE_URL.
+	FormatCurl = "curl"
+	// FormatGo renders a Go test sending each request to a mock started with pkg/mock
+	// and checking the response status.
+	FormatGo = "go"
+	// FormatCassette renders a go-vcr (version 2) cassette. It is written as JSON, which YAML parsers read.
+	FormatCassette = "cassette"
+)
+
+// IsValidFormat checks if a format is a known export format.
+func IsValidFormat(format string) bool {
+	return format == FormatCurl || format == FormatGo || format == FormatCassette
+}
+
+// Request is a logged request with the response the mock returned.
+type Request struct {
+	// ID is the ID of the request in the request log.
+	ID int64
+	// Method is the HTTP method.
+	Method string
+	// URL is the path of the request including the query, like /storage/v1/b?project=p.
+	URL string
+	// Header holds the request headers.
+	Header http.Header
+	// Body is the request body.
+	Body []byte
+	// Status is the response status code.
+	Status int
+	// ResponseHeader holds the response headers.
+	ResponseHeader http.Header
+	// ResponseBody is the response body.
+	ResponseBody []byte
+}
+
+// skippedHeaders are the request headers left out of reproductions: headers the HTTP clients set
+// themselves and credentials, which don't belong in test fixtures.
+var skippedHeaders = map[string]bool{
+	"Accept-Encoding": true,
+	"Authorization":   true,
+	"Connection":      true,
+	"Content-Length":  true,
+	"Cookie":          true,
+	"User-Agent":      true,
+	"X-Request-Id":    true,
+}
+
+// headerNames returns the names of the headers of a request that are reproduced, sorted.
+func headerNames(header http.Header) []string {
+	var names []string
+	for name := range header {
+		if !skippedHeaders[http.CanonicalHeaderKey(name)] {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Export writes the requests as a reproduction in the given format, in the order they're passed.
+// baseURL is the URL of the mock the requests were sent to: curl scripts send them there unless
+// $BASE_URL is set, and cassettes record it, since go-vcr matches requests by their full URL.
+func Export(w io.Writer, requests []Request, format, baseURL string) error {
+	if !IsValidFormat(format) {
+		return fmt.Errorf("invalid format %q: must be %q, %q or %q", format, FormatCurl, FormatGo, FormatCassette)
+	}
+
+	bw := bufio.NewWriter(w)
+	switch format {
+	case FormatCurl:
+		exportCurl(bw, requests, baseURL)
+	case FormatGo:
+		if err := exportGo(bw, requests); err != nil {
+			return err
+		}
+	case FormatCassette:
+		if err := exportCassette(bw, requests, baseURL); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// exportCurl writes a shell script sending the requests with curl.
+func exportCurl(w *bufio.Writer, requests []Request, baseURL string) {
+	fmt.Fprintln(w, "#!/bin/sh")
+	fmt.Fprintln(w, "# Generated by the GCP API Mock from its request log.")
+	fmt.Fprintln(w, "set -e")
+	fmt.Fprintf(w, "BASE_URL=\"${BASE_URL:-%s}\"\n", baseURL)
+
+	for _, req := range requests {
+		fmt.Fprintf(w, "\n# Request %d: %s %s, answered with %d\n", req.ID, req.Method, req.URL, req.Status)
+		fmt.Fprintf(w, "curl -sS -X %s \"$BASE_URL\"%s", req.Method, shellQuote(req.URL))
+		for _, name := range headerNames(req.Header) {
+			for _, value := range req.Header[name] {
+				fmt.Fprintf(w, " \\\n  -H %s", shellQuote(name+": "+value))
+			}
+		}
+		if len(req.Body) > 0 {
+			fmt.Fprintf(w, " \\\n  --data-binary %s", shellQuote(string(req.Body)))
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// shellQuote quotes s for a POSIX shell, in single quotes.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// exportGo writes a Go test sending the requests to a mock and checking the response status of each.
+// The test is formatted with gofmt.
+func exportGo(out io.Writer, requests []Request) error {
+	w := &bytes.Buffer{}
+	w.WriteString(`// Code generated by the GCP API Mock from its request log.
+
+package reproduction_test
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/katharinasick/gcp-api-mock/pkg/mock"
+)
+
+func TestReproduction(t *testing.T) {
+	m := mock.New(t)
+
+	steps := []struct {
+		method     string
+		url        string
+		header     http.Header
+		body       string
+		wantStatus int
+	}{
+`)
+	for _, req := range requests {
+		fmt.Fprintf(w, "\t\t// Request %d\n", req.ID)
+		fmt.Fprintf(w, "\t\t{\n\t\t\tmethod: %s,\n\t\t\turl: %s,\n", strconv.Quote(req.Method), strconv.Quote(req.URL))
+		if names := headerNames(req.Header); len(names) > 0 {
+			fmt.Fprint(w, "\t\t\theader: http.Header{\n")
+			for _, name := range names {
+				values := make([]string, len(req.Header[name]))
+				for i, value := range req.Header[name] {
+					values[i] = strconv.Quote(value)
+				}
+				fmt.Fprintf(w, "\t\t\t\t%s: {%s},\n", strconv.Quote(name), strings.Join(values, ", "))
+			}
+			fmt.Fprint(w, "\t\t\t},\n")
+		}
+		if len(req.Body) > 0 {
+			fmt.Fprintf(w, "\t\t\tbody: %s,\n", strconv.Quote(string(req.Body)))
+		}
+		fmt.Fprintf(w, "\t\t\twantStatus: %d,\n\t\t},\n", req.Status)
+	}
+	w.WriteString(`	}
+
+	for _, step := range steps {
+		req, err := http.NewRequest(step.method, m.URL+step.url, strings.NewReader(step.body))
+		if err != nil {
+			t.Fatalf("%s %s: %v", step.method, step.url, err)
+		}
+		for name, values := range step.header {
+			req.Header[name] = values
+		}
+
+		resp, err := m.Client().Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", step.method, step.url, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != step.wantStatus {
+			t.Errorf("%s %s: expected status %d, got %d - %s", step.method, step.url, step.wantStatus, resp.StatusCode, body)
+		}
+	}
+}
+`)
+
+	formatted, err := format.Source(w.Bytes())
+	if err != nil {
+		return fmt.Errorf("failed to format Go test: %w", err)
+	}
+	_, err = out.Write(formatted)
+	return err
+}
+
+// Cassette is a go-vcr cassette of version 2.
+// Reference: https://github.com/dnaeon/go-vcr
+type Cassette struct {
+	Version      int           `json:"version"`
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a request of a cassette together with its response.
+type Interaction struct {
+	Request  CassetteRequest  `json:"request"`
+	Response CassetteResponse `json:"response"`
+}
+
+// CassetteRequest is a recorded request of a cassette.
+type CassetteRequest struct {
+	Body    string              `json:"body"`
+	Form    map[string][]string `json:"form"`
+	Headers http.Header         `json:"headers"`
+	URL     string              `json:"url"`
+	Method  string              `json:"method"`
+}
+
+// CassetteResponse is a recorded response of a cassette.
+type CassetteResponse struct {
+	Body     string      `json:"body"`
+	Headers  http.Header `json:"headers"`
+	Status   string      `json:"status"`
+	Code     int         `json:"code"`
+	Duration string      `json:"duration"`
+}
+
+// exportCassette writes the requests as a go-vcr cassette.
+func exportCassette(w io.Writer, requests []Request, baseURL string) error {
+	cassette := Cassette{Version: 2, Interactions: make([]Interaction, 0, len(requests))}
+	for _, req := range requests {
+		header := make(http.Header)
+		for _, name := range headerNames(req.Header) {
+			header[name] = req.Header[name]
+		}
+		responseHeader := req.ResponseHeader
+		if responseHeader == nil {
+			responseHeader = http.Header{}
+		}
+		cassette.Interactions = append(cassette.Interactions, Interaction{
+			Request: CassetteRequest{
+				Body:    string(req.Body),
+				Form:    map[string][]string{},
+				Headers: header,
+				URL:     baseURL + req.URL,
+				Method:  req.Method,
+			},
+			Response: CassetteResponse{
+				Body:     string(req.ResponseBody),
+				Headers:  responseHeader,
+				Status:   fmt.Sprintf("%d %s", req.Status, http.StatusText(req.Status)),
+				Code:     req.Status,
+				Duration: "0s",
+			},
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(cassette)
+}
@@ -0,0 +1,111 @@
+package fixture
+
+import (
+	"bytes"
+	"encoding/json"
+	"go/format"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+var testRequests = []Request{
+	{
+		ID:     1,
+		Method: http.MethodPost,
+		URL:    "/storage/v1/b?project=test-project",
+		Header: http.Header{
+			"Content-Type":  {"application/json"},
+			"Authorization": {"Bearer secret"},
+			"User-Agent":    {"gcloud"},
+		},
+		Body:           []byte(`{"name": "it's-a-bucket"}`),
+		Status:         http.StatusOK,
+		ResponseHeader: http.Header{"Content-Type": {"application/json"}},
+		ResponseBody:   []byte(`{"kind": "storage#bucket"}`),
+	},
+	{
+		ID:     2,
+		Method: http.MethodDelete,
+		URL:    "/storage/v1/b/missing",
+		Status: http.StatusNotFound,
+	},
+}
+
+func TestExport_Curl(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Export(&buf, testRequests, FormatCurl, "http://localhost:8080"); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	out := buf.String()
+
+	expected := []string{
+		`BASE_URL="${BASE_URL:-http://localhost:8080}"`,
+		`curl -sS -X POST "$BASE_URL"'/storage/v1/b?project=test-project'`,
+		`-H 'Content-Type: application/json'`,
+		`--data-binary '{"name": "it'\''s-a-bucket"}'`,
+		`# Request 2: DELETE /storage/v1/b/missing, answered with 404`,
+	}
+	for _, s := range expected {
+		if !strings.Contains(out, s) {
+			t.Errorf("expected script to contain %q, got:\n%s", s, out)
+		}
+	}
+	if strings.Contains(out, "secret") || strings.Contains(out, "gcloud") {
+		t.Errorf("expected credentials and client headers to be left out, got:\n%s", out)
+	}
+}
+
+func TestExport_Go(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Export(&buf, testRequests, FormatGo, "http://localhost:8080"); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	formatted, err := format.Source(buf.Bytes())
+	if err != nil {
+		t.Fatalf("expected valid Go, got %v:\n%s", err, buf.String())
+	}
+	if !bytes.Equal(formatted, buf.Bytes()) {
+		t.Errorf("expected gofmt-formatted Go, got:\n%s", buf.String())
+	}
+	for _, s := range []string{`"/storage/v1/b?project=test-project"`, `wantStatus: 404`, `"Content-Type": {"application/json"}`} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("expected test to contain %q, got:\n%s", s, buf.String())
+		}
+	}
+}
+
+func TestExport_Cassette(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Export(&buf, testRequests, FormatCassette, "http://localhost:8080"); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	var cassette Cassette
+	if err := json.Unmarshal(buf.Bytes(), &cassette); err != nil {
+		t.Fatalf("failed to decode cassette: %v", err)
+	}
+	if cassette.Version != 2 || len(cassette.Interactions) != 2 {
+		t.Fatalf("expected a version 2 cassette with 2 interactions, got %+v", cassette)
+	}
+	first := cassette.Interactions[0]
+	if first.Request.URL != "http://localhost:8080/storage/v1/b?project=test-project" {
+		t.Errorf("expected absolute request URL, got %q", first.Request.URL)
+	}
+	if first.Request.Headers.Get("Authorization") != "" {
+		t.Error("expected the Authorization header to be left out")
+	}
+	if first.Response.Code != http.StatusOK || first.Response.Status != "200 OK" || first.Response.Body != `{"kind": "storage#bucket"}` {
+		t.Errorf("unexpected response: %+v", first.Response)
+	}
+	if cassette.Interactions[1].Response.Status != "404 Not Found" {
+		t.Errorf("expected status 404 Not Found, got %q", cassette.Interactions[1].Response.Status)
+	}
+}
+
+func TestExport_InvalidFormat(t *testing.T) {
+	if err := Export(&bytes.Buffer{}, testRequests, "har", ""); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
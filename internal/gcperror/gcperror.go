@@ -0,0 +1,150 @@
+// Package gcperror provides the JSON error envelope shared by Google Cloud APIs.
+//
+// Cloud Storage, Cloud SQL, Firestore and Cloud Run all return errors in the same envelope:
+//
+//	{
+//	  "error": {
+//	    "code": 404,
+//	    "message": "No such object: bucket/object",
+//	    "status": "NOT_FOUND",
+//	    "errors": [{"domain": "global", "reason": "notFound", "message": "No such object: bucket/object"}]
+//	  }
+//	}
+//
+// The errors list is only present for APIs with v1-style error reasons (Cloud Storage, Cloud SQL and Compute Engine).
+// Reference: https://cloud.google.com/apis/design/errors#http_mapping
+package gcperror
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Response is the JSON error envelope returned by Google Cloud APIs.
+type Response struct {
+	Error Details `json:"error"`
+}
+
+// Details contains the details of an API error.
+type Details struct {
+	// Code is the HTTP status code.
+	Code int `json:"code"`
+	// Message is a developer-facing error message.
+	Message string `json:"message"`
+	// Status is the canonical google.rpc.Code name, e.g. NOT_FOUND.
+	Status string `json:"status,omitempty"`
+	// Errors lists the reasons for the error.
+	Errors []Reason `json:"errors,omitempty"`
+	// Details carries structured google.rpc error details.
+	Details []ErrorInfo `json:"details,omitempty"`
+}
+
+// ErrorInfo is a google.rpc.ErrorInfo error detail, which client libraries use to tell errors apart,
+// e.g. reason SERVICE_DISABLED for a disabled API.
+type ErrorInfo struct {
+	// Type is the type URL of the detail, always "type.googleapis.com/google.rpc.ErrorInfo".
+	Type string `json:"@type"`
+	// Reason is the UPPER_SNAKE_CASE reason of the error.
+	Reason string `json:"reason"`
+	// Domain is the logical grouping of the reason, e.g. "googleapis.com".
+	Domain string `json:"domain"`
+	// Metadata is additional structured context, e.g. the disabled service.
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// Reason contains a single reason for an error.
+type Reason struct {
+	// Domain is the error domain, usually "global".
+	Domain string `json:"domain"`
+	// Reason is the error reason, e.g. "notFound" or "required".
+	Reason string `json:"reason"`
+	// Message is a developer-facing error message.
+	Message string `json:"message"`
+	// LocationType is the kind of input that caused the error, e.g. "parameter" or "header".
+	LocationType string `json:"locationType,omitempty"`
+	// Location is the name of the input that caused the error, e.g. "Authorization".
+	Location string `json:"location,omitempty"`
+}
+
+// New creates an error with the status derived from code.
+// If reason is not empty, a single reason in the global domain is added.
+func New(code int, message, reason string) *Response {
+	resp := &Response{
+		Error: Details{
+			Code:    code,
+			Message: message,
+			Status:  StatusFromCode(code),
+		},
+	}
+	if reason != "" {
+		resp.Error.Errors = []Reason{{Domain: "global", Reason: reason, Message: message}}
+	}
+	return resp
+}
+
+// WithStatus overrides the status derived from the HTTP status code.
+func (r *Response) WithStatus(status string) *Response {
+	r.Error.Status = status
+	return r
+}
+
+// WithLocation sets the input that caused the error on all reasons.
+func (r *Response) WithLocation(locationType, location string) *Response {
+	for i := range r.Error.Errors {
+		r.Error.Errors[i].LocationType = locationType
+		r.Error.Errors[i].Location = location
+	}
+	return r
+}
+
+// WithErrorInfo adds a google.rpc.ErrorInfo detail.
+func (r *Response) WithErrorInfo(reason, domain string, metadata map[string]string) *Response {
+	r.Error.Details = append(r.Error.Details, ErrorInfo{
+		Type:     "type.googleapis.com/google.rpc.ErrorInfo",
+		Reason:   reason,
+		Domain:   domain,
+		Metadata: metadata,
+	})
+	return r
+}
+
+// Write writes the error as a JSON response.
+func (r *Response) Write(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(r.Error.Code)
+	json.NewEncoder(w).Encode(r)
+}
+
+// StatusFromCode returns the canonical google.rpc.Code name for an HTTP status code.
+func StatusFromCode(code int) string {
+	switch code {
+	case http.StatusBadRequest:
+		return "INVALID_ARGUMENT"
+	case http.StatusUnauthorized:
+		return "UNAUTHENTICATED"
+	case http.StatusForbidden:
+		return "PERMISSION_DENIED"
+	case http.StatusNotFound:
+		return "NOT_FOUND"
+	case http.StatusConflict:
+		return "ALREADY_EXISTS"
+	case http.StatusPreconditionFailed:
+		return "FAILED_PRECONDITION"
+	case http.StatusRequestedRangeNotSatisfiable:
+		return "OUT_OF_RANGE"
+	case http.StatusTooManyRequests:
+		return "RESOURCE_EXHAUSTED"
+	case 499:
+		return "CANCELLED"
+	case http.StatusInternalServerError:
+		return "INTERNAL"
+	case http.StatusNotImplemented:
+		return "UNIMPLEMENTED"
+	case http.StatusServiceUnavailable:
+		return "UNAVAILABLE"
+	case http.StatusGatewayTimeout:
+		return "DEADLINE_EXCEEDED"
+	default:
+		return "UNKNOWN"
+	}
+}
@@ -0,0 +1,97 @@
+package gcperror
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	resp := New(http.StatusNotFound, "No such object: b/o", "notFound")
+
+	if resp.Error.Status != "NOT_FOUND" {
+		t.Errorf("expected status NOT_FOUND, got %s", resp.Error.Status)
+	}
+	if len(resp.Error.Errors) != 1 {
+		t.Fatalf("expected 1 reason, got %d", len(resp.Error.Errors))
+	}
+	reason := resp.Error.Errors[0]
+	if reason.Domain != "global" || reason.Reason != "notFound" || reason.Message != "No such object: b/o" {
+		t.Errorf("unexpected reason: %+v", reason)
+	}
+
+	if resp := New(http.StatusNotFound, "not found", ""); resp.Error.Errors != nil {
+		t.Errorf("expected no reasons, got %+v", resp.Error.Errors)
+	}
+}
+
+func TestResponse_Write(t *testing.T) {
+	rr := httptest.NewRecorder()
+	New(http.StatusUnauthorized, "Login Required.", "required").WithLocation("header", "Authorization").Write(rr)
+
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d, got %d", http.StatusUnauthorized, rr.Code)
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected Content-Type application/json, got %s", ct)
+	}
+
+	var body map[string]map[string]any
+	if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	details := body["error"]
+	if details["code"] != float64(401) || details["status"] != "UNAUTHENTICATED" {
+		t.Errorf("unexpected error details: %v", details)
+	}
+	reason := details["errors"].([]any)[0].(map[string]any)
+	if reason["locationType"] != "header" || reason["location"] != "Authorization" {
+		t.Errorf("unexpected location: %v", reason)
+	}
+}
+
+func TestResponse_WithErrorInfo(t *testing.T) {
+	rr := httptest.NewRecorder()
+	New(http.StatusForbidden, "API disabled", "").
+		WithErrorInfo("SERVICE_DISABLED", "googleapis.com", map[string]string{"service": "run.googleapis.com"}).
+		Write(rr)
+
+	var body struct {
+		Error struct {
+			Details []map[string]any `json:"details"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(body.Error.Details) != 1 {
+		t.Fatalf("expected 1 detail, got %d", len(body.Error.Details))
+	}
+	detail := body.Error.Details[0]
+	if detail["@type"] != "type.googleapis.com/google.rpc.ErrorInfo" || detail["reason"] != "SERVICE_DISABLED" {
+		t.Errorf("unexpected detail: %v", detail)
+	}
+}
+
+func TestStatusFromCode(t *testing.T) {
+	tests := []struct {
+		code     int
+		expected string
+	}{
+		{http.StatusBadRequest, "INVALID_ARGUMENT"},
+		{http.StatusForbidden, "PERMISSION_DENIED"},
+		{http.StatusConflict, "ALREADY_EXISTS"},
+		{http.StatusPreconditionFailed, "FAILED_PRECONDITION"},
+		{http.StatusInternalServerError, "INTERNAL"},
+		{http.StatusTeapot, "UNKNOWN"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			if got := StatusFromCode(tt.code); got != tt.expected {
+				t.Errorf("StatusFromCode(%d) = %s, want %s", tt.code, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,245 @@
+// Package gcsimport mirrors the objects of a real Cloud Storage bucket into the store, so developers can work
+// against realistic data offline. Objects are read through the JSON API with an OAuth access token, like the one
+// printed by `gcloud auth print-access-token`; without a token only public buckets can be read.
+package gcsimport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/katharinasick/gcp-api-mock/internal/gcperror"
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+	"github.com/katharinasick/gcp-api-mock/internal/store"
+)
+
+// DefaultEndpoint is the Cloud Storage endpoint buckets are imported from.
+const DefaultEndpoint = "https://storage.googleapis.com"
+
+// Options describe what to import.
+type Options struct {
+	// SourceBucket is the name of the bucket to import.
+	SourceBucket string `json:"sourceBucket"`
+	// Bucket is the bucket of the mock the objects are imported into. If empty, it has the name of the source bucket.
+	// It is created with the location, storage class and labels of the source bucket if it doesn't exist.
+	Bucket string `json:"bucket,omitempty"`
+	// Prefix limits the import to the objects whose names start with it.
+	Prefix string `json:"prefix,omitempty"`
+	// MetadataOnly imports the objects without their content: they are created empty, with the content type,
+	// custom metadata and other attributes of the source objects.
+	MetadataOnly bool `json:"metadataOnly,omitempty"`
+	// AccessToken is the OAuth access token the source bucket is read with. If empty, requests are anonymous.
+	AccessToken string `json:"accessToken,omitempty"`
+	// Endpoint is the Cloud Storage endpoint to read from. If empty, DefaultEndpoint is used.
+	Endpoint string `json:"endpoint,omitempty"`
+}
+
+// Result summarizes an import.
+type Result struct {
+	// Bucket is the bucket of the mock the objects were imported into.
+	Bucket string `json:"bucket"`
+	// BucketCreated is set if the bucket didn't exist and was created by the import.
+	BucketCreated bool `json:"bucketCreated"`
+	// Objects is the number of imported objects.
+	Objects int `json:"objects"`
+	// Bytes is the size of the imported content.
+	Bytes int64 `json:"bytes"`
+}
+
+// Import mirrors the objects of the source bucket into s. Existing objects with the same names are overwritten,
+// others are kept. The import stops at the first object that fails, keeping the objects imported until then.
+func Import(ctx context.Context, client *http.Client, s *store.Store, opts Options) (*Result, error) {
+	if opts.SourceBucket == "" {
+		return nil, fmt.Errorf("invalid import: source bucket is required")
+	}
+	if opts.Bucket == "" {
+		opts.Bucket = opts.SourceBucket
+	}
+	if opts.Endpoint == "" {
+		opts.Endpoint = DefaultEndpoint
+	}
+	src := &source{client: client, endpoint: strings.TrimSuffix(opts.Endpoint, "/"), accessToken: opts.AccessToken, bucket: opts.SourceBucket}
+
+	result := &Result{Bucket: opts.Bucket}
+	if s.GetBucket(opts.Bucket) == nil {
+		bucket, err := src.getBucket(ctx)
+		if err != nil {
+			return nil, err
+		}
+		_, err = s.CreateBucket(&storage.BucketInsertRequest{
+			Name:         opts.Bucket,
+			Location:     bucket.Location,
+			StorageClass: bucket.StorageClass,
+			Labels:       bucket.Labels,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create bucket %s: %w", opts.Bucket, err)
+		}
+		result.BucketCreated = true
+	}
+
+	pageToken := ""
+	for {
+		page, err := src.listObjects(ctx, opts.Prefix, pageToken)
+		if err != nil {
+			return result, err
+		}
+		for _, obj := range page.Items {
+			size, err := importObject(ctx, src, s, opts, obj)
+			if err != nil {
+				return result, fmt.Errorf("failed to import object %s: %w", obj.Name, err)
+			}
+			result.Objects++
+			result.Bytes += size
+		}
+		if page.NextPageToken == "" {
+			return result, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+// importObject creates a copy of a source object in the destination bucket and returns the size of its content.
+// The checksums of the source object are verified, so corrupted downloads fail.
+func importObject(ctx context.Context, src *source, s *store.Store, opts Options, obj *storage.Object) (int64, error) {
+	objOpts := store.ObjectOptions{
+		CacheControl:       obj.CacheControl,
+		ContentDisposition: obj.ContentDisposition,
+		ContentLanguage:    obj.ContentLanguage,
+		CustomTime:         obj.CustomTime,
+	}
+	if storage.IsValidStorageClass(obj.StorageClass) && !hasAutoclass(s.GetBucket(opts.Bucket)) {
+		objOpts.StorageClass = obj.StorageClass
+	}
+
+	if opts.MetadataOnly {
+		_, err := s.CreateObjectWithOptions(opts.Bucket, obj.Name, obj.ContentType, strings.NewReader(""), obj.Metadata, objOpts)
+		return 0, err
+	}
+
+	content, err := src.download(ctx, obj.Name)
+	if err != nil {
+		return 0, err
+	}
+	defer content.Close()
+
+	// Composite objects have no MD5 hash, but every object has a CRC32C checksum
+	objOpts.Md5Hash = obj.Md5Hash
+	objOpts.Crc32c = obj.Crc32c
+	created, err := s.CreateObjectWithOptions(opts.Bucket, obj.Name, obj.ContentType, content, obj.Metadata, objOpts)
+	if err != nil {
+		return 0, err
+	}
+	return int64(created.Size), nil
+}
+
+// hasAutoclass reports whether Autoclass manages the storage classes of the objects of a bucket.
+func hasAutoclass(bucket *storage.Bucket) bool {
+	return bucket != nil && bucket.Autoclass != nil && bucket.Autoclass.Enabled
+}
+
+// source reads a bucket through the Cloud Storage JSON API.
+type source struct {
+	client      *http.Client
+	endpoint    string
+	accessToken string
+	bucket      string
+}
+
+// getBucket returns the metadata of the bucket.
+func (src *source) getBucket(ctx context.Context) (*storage.Bucket, error) {
+	var bucket storage.Bucket
+	if err := src.getJSON(ctx, "/storage/v1/b/"+url.PathEscape(src.bucket), &bucket); err != nil {
+		return nil, err
+	}
+	return &bucket, nil
+}
+
+// listObjects returns a page of the objects whose names start with prefix.
+func (src *source) listObjects(ctx context.Context, prefix, pageToken string) (*storage.ObjectList, error) {
+	query := url.Values{}
+	if prefix != "" {
+		query.Set("prefix", prefix)
+	}
+	if pageToken != "" {
+		query.Set("pageToken", pageToken)
+	}
+
+	var page storage.ObjectList
+	if err := src.getJSON(ctx, "/storage/v1/b/"+url.PathEscape(src.bucket)+"/o?"+query.Encode(), &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// download returns the content of an object. The caller must close it.
+func (src *source) download(ctx context.Context, objectName string) (io.ReadCloser, error) {
+	resp, err := src.get(ctx, "/storage/v1/b/"+url.PathEscape(src.bucket)+"/o/"+url.PathEscape(objectName)+"?alt=media")
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// getJSON sends a GET request and decodes the JSON response into out.
+func (src *source) getJSON(ctx context.Context, path string, out any) error {
+	resp, err := src.get(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response of %s: %w", path, err)
+	}
+	return nil
+}
+
+// get sends a GET request and returns the response if it was successful. The caller must close the response body.
+func (src *source) get(ctx context.Context, path string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src.endpoint+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if src.accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+src.accessToken)
+	}
+
+	resp, err := src.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read gs://%s: %w", src.bucket, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, responseError(src.bucket, resp)
+	}
+	return resp, nil
+}
+
+// responseError returns the error of an unsuccessful response of the source, using the message of the
+// GCP error body if there is one.
+func responseError(bucket string, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+
+	message := strings.TrimSpace(string(body))
+	var errResp gcperror.Response
+	if err := json.Unmarshal(body, &errResp); err == nil && errResp.Error.Message != "" {
+		message = errResp.Error.Message
+	}
+	return &SourceError{Bucket: bucket, StatusCode: resp.StatusCode, Message: message}
+}
+
+// SourceError is an error response of the Cloud Storage endpoint, like 403 for a missing permission.
+type SourceError struct {
+	Bucket     string
+	StatusCode int
+	Message    string
+}
+
+func (e *SourceError) Error() string {
+	return fmt.Sprintf("failed to read gs://%s: %d %s: %s", e.Bucket, e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
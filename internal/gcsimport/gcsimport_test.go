@@ -0,0 +1,120 @@
+package gcsimport
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+	"github.com/katharinasick/gcp-api-mock/internal/store"
+)
+
+// newSource starts a fake Cloud Storage endpoint serving the objects of src, two per page.
+// Requests without the token "secret" are rejected.
+func newSource(t *testing.T, src *store.Store) string {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /storage/v1/b/{bucket}", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(src.GetBucket(r.PathValue("bucket")))
+	})
+	mux.HandleFunc("GET /storage/v1/b/{bucket}/o", func(w http.ResponseWriter, r *http.Request) {
+		objects, _, _ := src.ListObjectsWithOptions(r.PathValue("bucket"), store.ListOptions{Prefix: r.URL.Query().Get("prefix")})
+		page := storage.ObjectList{Kind: "storage#objects"}
+		start := 0
+		if r.URL.Query().Get("pageToken") == "next" {
+			start = 2
+		}
+		page.Items = objects[start:min(start+2, len(objects))]
+		if start+2 < len(objects) {
+			page.NextPageToken = "next"
+		}
+		json.NewEncoder(w).Encode(page)
+	})
+	mux.HandleFunc("GET /storage/v1/b/{bucket}/o/{object...}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(src.GetObjectContent(r.PathValue("bucket"), r.PathValue("object")))
+	})
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, `{"error": {"code": 403, "message": "Permission denied"}}`, http.StatusForbidden)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(ts.Close)
+	return ts.URL
+}
+
+func TestImport(t *testing.T) {
+	src := store.New()
+	src.CreateBucket(&storage.BucketInsertRequest{Name: "prod-assets", Location: "EU", Labels: map[string]string{"env": "prod"}})
+	src.CreateObject("prod-assets", "config.json", "application/json", []byte("{}"), map[string]string{"owner": "team-a"})
+	for _, name := range []string{"img/a.png", "img/b.png", "img/c.png"} {
+		src.CreateObject("prod-assets", name, "image/png", []byte("png:"+name), nil)
+	}
+	endpoint := newSource(t, src)
+
+	t.Run("all objects", func(t *testing.T) {
+		s := store.New()
+		result, err := Import(context.Background(), http.DefaultClient, s, Options{SourceBucket: "prod-assets", AccessToken: "secret", Endpoint: endpoint})
+		if err != nil {
+			t.Fatalf("Import() error: %v", err)
+		}
+		if result.Bucket != "prod-assets" || !result.BucketCreated || result.Objects != 4 || result.Bytes != int64(2+3*len("png:img/a.png")) {
+			t.Errorf("unexpected result: %+v", result)
+		}
+
+		bucket := s.GetBucket("prod-assets")
+		if bucket == nil || bucket.Location != "EU" || bucket.Labels["env"] != "prod" {
+			t.Errorf("expected the bucket to be created like the source, got %+v", bucket)
+		}
+		obj := s.GetObject("prod-assets", "config.json")
+		if obj == nil || obj.ContentType != "application/json" || obj.Metadata["owner"] != "team-a" {
+			t.Errorf("expected the object attributes to be imported, got %+v", obj)
+		}
+		if content := s.GetObjectContent("prod-assets", "img/c.png"); string(content) != "png:img/c.png" {
+			t.Errorf("expected the content to be imported, got %q", content)
+		}
+	})
+
+	t.Run("prefix into another bucket", func(t *testing.T) {
+		s := store.New()
+		s.CreateBucket(&storage.BucketInsertRequest{Name: "local"})
+		result, err := Import(context.Background(), http.DefaultClient, s, Options{SourceBucket: "prod-assets", Bucket: "local", Prefix: "img/", AccessToken: "secret", Endpoint: endpoint})
+		if err != nil {
+			t.Fatalf("Import() error: %v", err)
+		}
+		if result.BucketCreated || result.Objects != 3 {
+			t.Errorf("unexpected result: %+v", result)
+		}
+		if s.GetObject("local", "config.json") != nil {
+			t.Error("expected objects outside of the prefix not to be imported")
+		}
+	})
+
+	t.Run("metadata only", func(t *testing.T) {
+		s := store.New()
+		result, err := Import(context.Background(), http.DefaultClient, s, Options{SourceBucket: "prod-assets", MetadataOnly: true, AccessToken: "secret", Endpoint: endpoint})
+		if err != nil {
+			t.Fatalf("Import() error: %v", err)
+		}
+		if result.Objects != 4 || result.Bytes != 0 {
+			t.Errorf("unexpected result: %+v", result)
+		}
+		obj := s.GetObject("prod-assets", "config.json")
+		if obj == nil || obj.Size != 0 || obj.Metadata["owner"] != "team-a" {
+			t.Errorf("expected an empty object with the metadata, got %+v", obj)
+		}
+	})
+
+	t.Run("without permission", func(t *testing.T) {
+		_, err := Import(context.Background(), http.DefaultClient, store.New(), Options{SourceBucket: "prod-assets", Endpoint: endpoint})
+		sourceErr, ok := err.(*SourceError)
+		if !ok || sourceErr.StatusCode != http.StatusForbidden || sourceErr.Message != "Permission denied" {
+			t.Errorf("expected a 403 source error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,165 @@
+package gke
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Kubeconfig is the API server and credentials of a cluster, read from a kubeconfig file like the one
+// `kind get kubeconfig` writes.
+type Kubeconfig struct {
+	// Server is the URL of the API server, e.g. https://127.0.0.1:44321.
+	Server string
+	// CertificateAuthorityData is the base64-encoded PEM certificate of the cluster's CA.
+	CertificateAuthorityData string
+	// ClientCertificateData and ClientKeyData are the base64-encoded PEM client certificate and key of the user.
+	ClientCertificateData string
+	ClientKeyData         string
+	// Token is the bearer token of the user, if it authenticates with one.
+	Token string
+}
+
+// LoadKubeconfig reads the cluster and user of the current context of a kubeconfig file, or the first
+// cluster and user if it has no current context.
+//
+// Only the subset of YAML kubeconfig files use is supported: block mappings and sequences with plain or
+// quoted scalars, as written by kind and kubectl. Credentials in files (certificate-authority, client-key)
+// and exec plugins are not.
+func LoadKubeconfig(path string) (*Kubeconfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseKubeconfig(data)
+}
+
+// ParseKubeconfig parses the content of a kubeconfig file. See LoadKubeconfig.
+func ParseKubeconfig(data []byte) (*Kubeconfig, error) {
+	// Flatten each entry of the clusters, contexts and users lists to its leaf values,
+	// e.g. {"name": "kind-kind", "server": "https://127.0.0.1:44321", ...}
+	lists := map[string][]map[string]string{}
+	var currentContext, section string
+	var entry map[string]string
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || trimmed == "---" {
+			continue
+		}
+
+		if !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "-") {
+			key, value, _ := strings.Cut(trimmed, ":")
+			section = key
+			entry = nil
+			if key == "current-context" {
+				currentContext = unquote(strings.TrimSpace(value))
+			}
+			continue
+		}
+
+		if rest, found := strings.CutPrefix(trimmed, "- "); found {
+			entry = map[string]string{}
+			lists[section] = append(lists[section], entry)
+			trimmed = rest
+		}
+		if entry == nil {
+			continue
+		}
+		key, value, found := strings.Cut(trimmed, ":")
+		if value = unquote(strings.TrimSpace(value)); found && value != "" {
+			entry[strings.TrimSpace(key)] = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	clusterName, userName := "", ""
+	for _, context := range lists["contexts"] {
+		if context["name"] == currentContext {
+			clusterName, userName = context["cluster"], context["user"]
+		}
+	}
+	cluster := findKubeconfigEntry(lists["clusters"], clusterName)
+	if cluster == nil || cluster["server"] == "" {
+		return nil, fmt.Errorf("no cluster with a server found")
+	}
+	user := findKubeconfigEntry(lists["users"], userName)
+	if user == nil {
+		user = map[string]string{}
+	}
+
+	return &Kubeconfig{
+		Server:                   cluster["server"],
+		CertificateAuthorityData: cluster["certificate-authority-data"],
+		ClientCertificateData:    user["client-certificate-data"],
+		ClientKeyData:            user["client-key-data"],
+		Token:                    user["token"],
+	}, nil
+}
+
+// findKubeconfigEntry returns the entry with a name, or the first entry if name is empty.
+// Returns nil if there is no such entry.
+func findKubeconfigEntry(entries []map[string]string, name string) map[string]string {
+	for _, entry := range entries {
+		if name == "" || entry["name"] == name {
+			return entry
+		}
+	}
+	return nil
+}
+
+// unquote removes the quotes around a YAML scalar.
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// Render writes a kubeconfig file connecting to the server of k with its credentials, in a context named
+// like the ones `gcloud container clusters get-credentials` creates, e.g. gke_my-project_us-central1_my-cluster.
+// server and certificateAuthorityData override those of k if not empty.
+func (k *Kubeconfig) Render(contextName, server, certificateAuthorityData string) []byte {
+	if server == "" {
+		server = k.Server
+	}
+	if certificateAuthorityData == "" {
+		certificateAuthorityData = k.CertificateAuthorityData
+	}
+
+	var b strings.Builder
+	b.WriteString("apiVersion: v1\nkind: Config\n")
+	b.WriteString("clusters:\n- name: " + contextName + "\n  cluster:\n")
+	b.WriteString("    server: " + server + "\n")
+	if certificateAuthorityData != "" {
+		b.WriteString("    certificate-authority-data: " + certificateAuthorityData + "\n")
+	}
+	b.WriteString("contexts:\n- name: " + contextName + "\n  context:\n")
+	b.WriteString("    cluster: " + contextName + "\n    user: " + contextName + "\n")
+	b.WriteString("current-context: " + contextName + "\n")
+	b.WriteString("users:\n- name: " + contextName + "\n  user:")
+	user := []struct{ key, value string }{
+		{"client-certificate-data", k.ClientCertificateData},
+		{"client-key-data", k.ClientKeyData},
+		{"token", k.Token},
+	}
+	empty := true
+	for _, field := range user {
+		if field.value != "" {
+			b.WriteString("\n    " + field.key + ": " + field.value)
+			empty = false
+		}
+	}
+	if empty {
+		b.WriteString(" {}")
+	}
+	b.WriteString("\n")
+	return []byte(b.String())
+}
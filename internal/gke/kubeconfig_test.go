@@ -0,0 +1,85 @@
+package gke
+
+import (
+	"strings"
+	"testing"
+)
+
+// testKindKubeconfig is a kubeconfig like `kind get kubeconfig` writes, with a second cluster in front.
+const testKindKubeconfig = `apiVersion: v1
+clusters:
+- cluster:
+    server: https://staging.example.com
+  name: staging
+- cluster:
+    certificate-authority-data: Q0E=
+    server: https://127.0.0.1:44321
+  name: kind-kind
+contexts:
+- context:
+    cluster: staging
+    user: staging
+  name: staging
+- context:
+    cluster: kind-kind
+    user: kind-kind
+  name: kind-kind
+current-context: "kind-kind"
+kind: Config
+preferences: {}
+users:
+- name: staging
+  user:
+    token: secret
+- name: kind-kind
+  user:
+    client-certificate-data: Q0VSVA==
+    client-key-data: S0VZ
+`
+
+func TestParseKubeconfig(t *testing.T) {
+	kc, err := ParseKubeconfig([]byte(testKindKubeconfig))
+	if err != nil {
+		t.Fatalf("ParseKubeconfig() error: %v", err)
+	}
+	want := Kubeconfig{
+		Server:                   "https://127.0.0.1:44321",
+		CertificateAuthorityData: "Q0E=",
+		ClientCertificateData:    "Q0VSVA==",
+		ClientKeyData:            "S0VZ",
+	}
+	if *kc != want {
+		t.Errorf("expected %+v, got %+v", want, *kc)
+	}
+
+	// Without a current context, the first cluster and user are used
+	kc, err = ParseKubeconfig([]byte(strings.Replace(testKindKubeconfig, `current-context: "kind-kind"`, "", 1)))
+	if err != nil {
+		t.Fatalf("ParseKubeconfig() error: %v", err)
+	}
+	if kc.Server != "https://staging.example.com" || kc.Token != "secret" {
+		t.Errorf("expected the first cluster and user, got %+v", kc)
+	}
+
+	if _, err := ParseKubeconfig([]byte("apiVersion: v1\nkind: Config\n")); err == nil {
+		t.Error("expected an error for a kubeconfig without clusters")
+	}
+}
+
+func TestKubeconfig_Render(t *testing.T) {
+	kc := &Kubeconfig{Server: "https://127.0.0.1:44321", CertificateAuthorityData: "Q0E=", Token: "secret"}
+
+	rendered := kc.Render("gke_p_us-central1_c", "", "")
+
+	// The rendered kubeconfig can be read back
+	parsed, err := ParseKubeconfig(rendered)
+	if err != nil {
+		t.Fatalf("ParseKubeconfig() error: %v\n%s", err, rendered)
+	}
+	if *parsed != *kc {
+		t.Errorf("expected %+v, got %+v", *kc, *parsed)
+	}
+	if !strings.Contains(string(rendered), "current-context: gke_p_us-central1_c\n") {
+		t.Errorf("expected the context to be named like gcloud names it, got:\n%s", rendered)
+	}
+}
@@ -0,0 +1,153 @@
+// Package gke provides data models for the Google Kubernetes Engine API (v1) mock, and reads and writes
+// the kubeconfig files that connect clients to a cluster.
+package gke
+
+import "time"
+
+// Cluster and node pool states.
+const (
+	StatusRunning = "RUNNING"
+)
+
+// Operation types and states.
+const (
+	OperationCreateCluster = "CREATE_CLUSTER"
+	OperationDeleteCluster = "DELETE_CLUSTER"
+	OperationDone          = "DONE"
+)
+
+// Cluster is a GKE cluster.
+// Reference: https://cloud.google.com/kubernetes-engine/docs/reference/rest/v1/projects.locations.clusters
+type Cluster struct {
+	// Name is the name of the cluster, unique within its project and location.
+	Name string `json:"name"`
+	// Description is an optional description.
+	Description string `json:"description,omitempty"`
+	// InitialNodeCount is the number of nodes of the default node pool created with the cluster.
+	InitialNodeCount int `json:"initialNodeCount,omitempty"`
+	// NodeConfig is the configuration of the nodes of the default node pool.
+	NodeConfig *NodeConfig `json:"nodeConfig,omitempty"`
+	// MasterAuth holds the credentials of the cluster's API server.
+	MasterAuth *MasterAuth `json:"masterAuth,omitempty"`
+	// LoggingService and MonitoringService are where the cluster writes logs and metrics.
+	LoggingService    string `json:"loggingService,omitempty"`
+	MonitoringService string `json:"monitoringService,omitempty"`
+	// Network and Subnetwork are the VPC network and subnetwork of the nodes.
+	Network    string `json:"network,omitempty"`
+	Subnetwork string `json:"subnetwork,omitempty"`
+	// ClusterIpv4Cidr is the IP range of the pods, e.g. 10.0.0.0/14.
+	ClusterIpv4Cidr string `json:"clusterIpv4Cidr,omitempty"`
+	// ServicesIpv4Cidr is the IP range of the services.
+	ServicesIpv4Cidr string `json:"servicesIpv4Cidr,omitempty"`
+	// NodePools are the node pools of the cluster.
+	NodePools []*NodePool `json:"nodePools,omitempty"`
+	// Locations are the zones the nodes are in.
+	Locations []string `json:"locations,omitempty"`
+	// ResourceLabels are user-defined key/value labels.
+	ResourceLabels map[string]string `json:"resourceLabels,omitempty"`
+	// Autopilot configures Autopilot mode, in which GKE manages the nodes.
+	Autopilot *Autopilot `json:"autopilot,omitempty"`
+	// ReleaseChannel is the channel the cluster gets upgrades from.
+	ReleaseChannel *ReleaseChannel `json:"releaseChannel,omitempty"`
+	// SelfLink is the URL of the cluster.
+	SelfLink string `json:"selfLink,omitempty"`
+	// Zone is the location of the cluster. Deprecated in favor of Location.
+	Zone string `json:"zone,omitempty"`
+	// Endpoint is the address of the cluster's API server, without a scheme.
+	Endpoint string `json:"endpoint,omitempty"`
+	// InitialClusterVersion is the Kubernetes version requested at creation, e.g. "1.30" or "latest".
+	InitialClusterVersion string `json:"initialClusterVersion,omitempty"`
+	// CurrentMasterVersion and CurrentNodeVersion are the Kubernetes versions running.
+	CurrentMasterVersion string `json:"currentMasterVersion,omitempty"`
+	CurrentNodeVersion   string `json:"currentNodeVersion,omitempty"`
+	// CreateTime is the time the cluster was created.
+	CreateTime time.Time `json:"createTime,omitzero"`
+	// Status is the state of the cluster, e.g. RUNNING.
+	Status string `json:"status,omitempty"`
+	// Location is the region or zone of the cluster.
+	Location string `json:"location,omitempty"`
+	// Id is the unique ID of the cluster.
+	Id string `json:"id,omitempty"`
+}
+
+// NodeConfig is the configuration of the nodes of a node pool.
+type NodeConfig struct {
+	MachineType    string            `json:"machineType,omitempty"`
+	DiskSizeGb     int               `json:"diskSizeGb,omitempty"`
+	DiskType       string            `json:"diskType,omitempty"`
+	ImageType      string            `json:"imageType,omitempty"`
+	OauthScopes    []string          `json:"oauthScopes,omitempty"`
+	ServiceAccount string            `json:"serviceAccount,omitempty"`
+	Labels         map[string]string `json:"labels,omitempty"`
+}
+
+// NodePool is a group of nodes with the same configuration.
+type NodePool struct {
+	Name             string               `json:"name"`
+	Config           *NodeConfig          `json:"config,omitempty"`
+	InitialNodeCount int                  `json:"initialNodeCount,omitempty"`
+	Locations        []string             `json:"locations,omitempty"`
+	Autoscaling      *NodePoolAutoscaling `json:"autoscaling,omitempty"`
+	SelfLink         string               `json:"selfLink,omitempty"`
+	Version          string               `json:"version,omitempty"`
+	Status           string               `json:"status,omitempty"`
+}
+
+// NodePoolAutoscaling configures the number of nodes of a node pool to follow the load.
+type NodePoolAutoscaling struct {
+	Enabled      bool `json:"enabled,omitempty"`
+	MinNodeCount int  `json:"minNodeCount,omitempty"`
+	MaxNodeCount int  `json:"maxNodeCount,omitempty"`
+}
+
+// MasterAuth holds the credentials of a cluster's API server.
+type MasterAuth struct {
+	// ClusterCaCertificate is the base64-encoded PEM certificate of the cluster's CA.
+	ClusterCaCertificate string `json:"clusterCaCertificate,omitempty"`
+}
+
+// Autopilot configures Autopilot mode.
+type Autopilot struct {
+	Enabled bool `json:"enabled,omitempty"`
+}
+
+// ReleaseChannel is the channel a cluster gets upgrades from: RAPID, REGULAR, STABLE or EXTENDED.
+type ReleaseChannel struct {
+	Channel string `json:"channel,omitempty"`
+}
+
+// CreateClusterRequest is the request body of clusters.create.
+type CreateClusterRequest struct {
+	Cluster *Cluster `json:"cluster"`
+	// Parent is projects/{project}/locations/{location}; the path names it too.
+	Parent string `json:"parent,omitempty"`
+}
+
+// ListClustersResponse is the response of clusters.list. GKE doesn't paginate clusters.
+type ListClustersResponse struct {
+	Clusters     []*Cluster `json:"clusters,omitempty"`
+	MissingZones []string   `json:"missingZones,omitempty"`
+}
+
+// Operation is a GKE operation. Unlike most APIs, GKE has its own operation type instead of
+// google.longrunning.Operation.
+// Reference: https://cloud.google.com/kubernetes-engine/docs/reference/rest/v1/projects.locations.operations
+type Operation struct {
+	// Name is the ID of the operation, e.g. operation-1700000000000-1a2b3c4d.
+	Name string `json:"name"`
+	// Zone is the location of the operation. Deprecated in favor of Location.
+	Zone string `json:"zone,omitempty"`
+	// OperationType is the kind of change, e.g. CREATE_CLUSTER.
+	OperationType string `json:"operationType,omitempty"`
+	// Status is the state of the operation, e.g. DONE.
+	Status string `json:"status,omitempty"`
+	// SelfLink is the URL of the operation.
+	SelfLink string `json:"selfLink,omitempty"`
+	// TargetLink is the URL of the cluster the operation changes.
+	TargetLink string `json:"targetLink,omitempty"`
+	// Location is the region or zone of the operation.
+	Location string `json:"location,omitempty"`
+	// StartTime and EndTime are when the operation started and finished.
+	StartTime time.Time `json:"startTime,omitzero"`
+	EndTime   time.Time `json:"endTime,omitzero"`
+}
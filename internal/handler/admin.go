@@ -0,0 +1,665 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/clock"
+	"github.com/katharinasick/gcp-api-mock/internal/fixture"
+	"github.com/katharinasick/gcp-api-mock/internal/gcsimport"
+	"github.com/katharinasick/gcp-api-mock/internal/latency"
+	"github.com/katharinasick/gcp-api-mock/internal/loadgen"
+	"github.com/katharinasick/gcp-api-mock/internal/recorder"
+	"github.com/katharinasick/gcp-api-mock/internal/sqladmin"
+	"github.com/katharinasick/gcp-api-mock/internal/store"
+	"github.com/katharinasick/gcp-api-mock/internal/terraform"
+)
+
+// Admin handles the mock's own admin API, which controls the mock rather than emulating a GCP service.
+type Admin struct {
+	store    *store.Store
+	recorder *recorder.Recorder
+	replay   http.Handler
+	latency  *latency.Injector
+	clock    *clock.Clock
+	requests *RequestLogger
+}
+
+// NewAdmin creates a new Admin handler.
+// Replayed requests are sent to the replay handler.
+func NewAdmin(s *store.Store, rec *recorder.Recorder, replay http.Handler, injector *latency.Injector, clk *clock.Clock, requests *RequestLogger) *Admin {
+	return &Admin{store: s, recorder: rec, replay: replay, latency: injector, clock: clk, requests: requests}
+}
+
+// RecordingRequest is the request body for starting a recording or replaying one.
+type RecordingRequest struct {
+	Path string `json:"path"`
+}
+
+// ReplayResponse is the response for replaying a recording.
+type ReplayResponse struct {
+	Total      int                     `json:"total"`
+	Matched    int                     `json:"matched"`
+	Mismatched int                     `json:"mismatched"`
+	Results    []recorder.ReplayResult `json:"results"`
+}
+
+// ClockRequest is the request body for setting the virtual clock.
+// Omitted fields are left unchanged.
+type ClockRequest struct {
+	Time   *time.Time `json:"time,omitempty"`
+	Frozen *bool      `json:"frozen,omitempty"`
+	// Skew shifts the time reported in HTTP headers like Date, e.g. "-5m".
+	Skew *string `json:"skew,omitempty"`
+}
+
+// AdvanceClockRequest is the request body for advancing the virtual clock.
+type AdvanceClockRequest struct {
+	Duration string `json:"duration"`
+}
+
+// SQLInstanceStateRequest is the request body for setting the state of a Cloud SQL instance.
+type SQLInstanceStateRequest struct {
+	State            string   `json:"state"`
+	SuspensionReason []string `json:"suspensionReason,omitempty"`
+}
+
+// SQLMaintenanceRequest is the request body for starting maintenance of a Cloud SQL instance.
+type SQLMaintenanceRequest struct {
+	Duration string `json:"duration,omitempty"`
+	Force    bool   `json:"force,omitempty"`
+}
+
+// DeleteObjectsResponse is the response for deleting the objects under a prefix.
+type DeleteObjectsResponse struct {
+	Deleted int `json:"deleted"`
+}
+
+// GetRecording handles GET /admin/recording - Get the recording status.
+func (h *Admin) GetRecording(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, h.recorder.Status())
+}
+
+// StartRecording handles POST /admin/recording/start - Start recording API requests to a file.
+func (h *Admin) StartRecording(w http.ResponseWriter, r *http.Request) {
+	var req RecordingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid JSON body", "invalid")
+		return
+	}
+
+	if req.Path == "" {
+		respondError(w, http.StatusBadRequest, "Recording path is required", "required")
+		return
+	}
+
+	if err := h.recorder.Start(req.Path); err != nil {
+		if strings.Contains(err.Error(), "already recording") {
+			respondError(w, http.StatusConflict, err.Error(), "conflict")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error(), "internalError")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, h.recorder.Status())
+}
+
+// StopRecording handles POST /admin/recording/stop - Stop the current recording.
+func (h *Admin) StopRecording(w http.ResponseWriter, r *http.Request) {
+	status, err := h.recorder.Stop()
+	if err != nil {
+		if strings.Contains(err.Error(), "not recording") {
+			respondError(w, http.StatusConflict, err.Error(), "conflict")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error(), "internalError")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, status)
+}
+
+// ReplayRecording handles POST /admin/recording/replay - Replay a recording against the mock.
+// Requests are replayed in order against the current state, and the response status codes
+// are compared with the recorded ones.
+func (h *Admin) ReplayRecording(w http.ResponseWriter, r *http.Request) {
+	var req RecordingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid JSON body", "invalid")
+		return
+	}
+
+	if req.Path == "" {
+		respondError(w, http.StatusBadRequest, "Recording path is required", "required")
+		return
+	}
+
+	exchanges, err := recorder.Load(req.Path)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+		return
+	}
+
+	results := recorder.Replay(h.replay, exchanges)
+
+	response := ReplayResponse{Total: len(results), Results: results}
+	for _, result := range results {
+		if result.Match {
+			response.Matched++
+		} else {
+			response.Mismatched++
+		}
+	}
+
+	respondJSON(w, http.StatusOK, response)
+}
+
+// GetLatency handles GET /admin/latency - Get the active latency profile.
+func (h *Admin) GetLatency(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, h.latency.Profile())
+}
+
+// SetLatency handles PUT /admin/latency - Replace the latency profile.
+// The body maps operations to ranges, e.g. {"storage.get": "20ms-80ms", "sql.*": "2s-5s"}.
+// An empty object removes all injected latency.
+func (h *Admin) SetLatency(w http.ResponseWriter, r *http.Request) {
+	var profile latency.Profile
+	if err := json.NewDecoder(r.Body).Decode(&profile); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid latency profile: "+err.Error(), "invalid")
+		return
+	}
+
+	h.latency.SetProfile(profile)
+
+	respondJSON(w, http.StatusOK, h.latency.Profile())
+}
+
+// Snapshot handles GET /admin/snapshot - Download the entire mock state as a tar archive.
+// The archive holds the resource metadata in state.json and the object and registry blob content as separate entries.
+func (h *Admin) Snapshot(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/x-tar")
+	w.Header().Set("Content-Disposition", `attachment; filename="gcp-api-mock-snapshot.tar"`)
+
+	// The status is sent with the first write, so a failure can only be reported by aborting the response
+	if err := h.store.Snapshot(w); err != nil {
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// ExportTerraform handles GET /admin/terraform - Download the buckets and Cloud SQL instances, databases and users
+// as Terraform configuration. With ?format=hcl (the default) each resource gets a resource and an import block;
+// with ?format=import only the import blocks are rendered, for terraform plan -generate-config-out.
+func (h *Admin) ExportTerraform(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = terraform.FormatHCL
+	}
+	if !terraform.IsValidFormat(format) {
+		respondError(w, http.StatusBadRequest, "Invalid format "+strconv.Quote(format)+", must be hcl or import", "invalid")
+		return
+	}
+
+	res := &terraform.Resources{
+		Project:      h.store.ProjectID(),
+		Buckets:      h.store.ListBuckets(),
+		SQLInstances: h.store.ListSQLInstances(),
+		SQLDatabases: make(map[string][]*sqladmin.Database),
+		SQLUsers:     make(map[string][]*sqladmin.User),
+	}
+	for _, instance := range res.SQLInstances {
+		// Instances deleted in the meantime are left without databases and users
+		res.SQLDatabases[instance.Name], _ = h.store.ListSQLDatabases(instance.Name)
+		res.SQLUsers[instance.Name], _ = h.store.ListSQLUsers(instance.Name)
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="gcp-api-mock.tf"`)
+	if err := terraform.Export(w, res, format); err != nil {
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// Restore handles POST /admin/restore - Replace the entire mock state with a snapshot.
+// The request body is an archive downloaded from /admin/snapshot.
+func (h *Admin) Restore(w http.ResponseWriter, r *http.Request) {
+	summary, err := h.store.Restore(r.Body)
+	if err != nil {
+		if strings.Contains(err.Error(), "invalid") {
+			respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error(), "internalError")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, summary)
+}
+
+// Reset handles POST /admin/reset - Delete all resources, like a restart of the mock.
+// The clock, latency profile and recording are left unchanged.
+func (h *Admin) Reset(w http.ResponseWriter, r *http.Request) {
+	h.store.Reset()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Tick handles POST /admin/tick - Evaluate time-dependent state now, like purging soft-deleted
+// objects past their retention and finishing pending operations, instead of waiting for the next request.
+func (h *Admin) Tick(w http.ResponseWriter, r *http.Request) {
+	h.store.Tick()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListRequests handles GET /admin/requests - List the logged API requests, oldest first.
+// With ?after={id}, only requests logged after the one with that ID are listed, so clients can tail the log.
+func (h *Admin) ListRequests(w http.ResponseWriter, r *http.Request) {
+	var after int64
+	if value := r.URL.Query().Get("after"); value != "" {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid after: "+value, "invalid")
+			return
+		}
+		after = id
+	}
+
+	respondJSON(w, http.StatusOK, h.requests.Since(after))
+}
+
+// ReplayRequest handles POST /admin/requests/{id}/replay - Send a logged API request to the mock again,
+// e.g. to reproduce what a client sent after changing the state. The replay is logged with replayOf set
+// to the ID of the original request and returned, including the response body.
+func (h *Admin) ReplayRequest(w http.ResponseWriter, r *http.Request) {
+	value := r.PathValue("id")
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request ID: "+value, "invalid")
+		return
+	}
+
+	entry, ok := h.requests.Get(id)
+	if !ok {
+		respondError(w, http.StatusNotFound, "Request "+value+" not found in the request log", "notFound")
+		return
+	}
+	if !entry.Replayable() {
+		respondError(w, http.StatusBadRequest, "Request "+value+" can't be replayed, since its body wasn't logged completely", "invalid")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, h.requests.Replay(entry, h.replay))
+}
+
+// fixtureFiles are the content types and file names of the request exports.
+var fixtureFiles = map[string]struct{ contentType, name string }{
+	fixture.FormatCurl:     {"text/x-shellscript; charset=utf-8", "gcp-api-mock-requests.sh"},
+	fixture.FormatGo:       {"text/x-go; charset=utf-8", "reproduction_test.go"},
+	fixture.FormatCassette: {"application/yaml; charset=utf-8", "gcp-api-mock-cassette.yaml"},
+}
+
+// ExportRequests handles GET /admin/requests/export - Render logged API requests as a reproduction: a curl script
+// (?format=curl, the default), a Go test against pkg/mock (?format=go) or a go-vcr cassette (?format=cassette).
+// ?ids=3,4,7 selects requests by ID and ?after={id} the requests logged after one; otherwise all logged requests
+// are exported, oldest first. Requests whose body wasn't logged completely are left out.
+func (h *Admin) ExportRequests(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = fixture.FormatCurl
+	}
+	if !fixture.IsValidFormat(format) {
+		respondError(w, http.StatusBadRequest, "Invalid format "+strconv.Quote(format)+", must be curl, go or cassette", "invalid")
+		return
+	}
+
+	var after int64
+	if value := query.Get("after"); value != "" {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid after: "+value, "invalid")
+			return
+		}
+		after = id
+	}
+	var ids map[int64]bool
+	if value := query.Get("ids"); value != "" {
+		ids = make(map[int64]bool)
+		for _, part := range strings.Split(value, ",") {
+			id, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+			if err != nil {
+				respondError(w, http.StatusBadRequest, "Invalid ids: "+value, "invalid")
+				return
+			}
+			ids[id] = true
+		}
+	}
+
+	var requests []fixture.Request
+	for _, entry := range h.requests.Since(after) {
+		if !entry.Replayable() || (ids != nil && !ids[entry.ID]) {
+			continue
+		}
+		requests = append(requests, fixture.Request{
+			ID:             entry.ID,
+			Method:         entry.Method,
+			URL:            entry.URL,
+			Header:         entry.RequestHeader,
+			Body:           entry.requestBody,
+			Status:         entry.Status,
+			ResponseHeader: entry.ResponseHeader,
+			ResponseBody:   []byte(entry.ResponseBody),
+		})
+	}
+
+	file := fixtureFiles[format]
+	w.Header().Set("Content-Type", file.contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+file.name+`"`)
+	if err := fixture.Export(w, requests, format, h.store.BaseURL()); err != nil {
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// GetClock handles GET /admin/clock - Get the state of the virtual clock.
+func (h *Admin) GetClock(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, h.clock.Status())
+}
+
+// SetClock handles PUT /admin/clock - Set the virtual time, freeze or unfreeze the clock and set its skew.
+// E.g. {"time": "2030-01-01T00:00:00Z", "frozen": true} stops the clock at the start of 2030,
+// and {"skew": "-5m"} makes Date headers report a time five minutes in the past.
+func (h *Admin) SetClock(w http.ResponseWriter, r *http.Request) {
+	var req ClockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid clock request: "+err.Error(), "invalid")
+		return
+	}
+	var skew time.Duration
+	if req.Skew != nil {
+		var err error
+		if skew, err = time.ParseDuration(*req.Skew); err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid skew: "+err.Error(), "invalid")
+			return
+		}
+	}
+
+	// Freeze first, so a frozen clock stands still exactly at the requested time
+	if req.Frozen != nil && *req.Frozen {
+		h.clock.Freeze()
+	}
+	if req.Time != nil {
+		h.clock.Set(*req.Time)
+	}
+	if req.Frozen != nil && !*req.Frozen {
+		h.clock.Unfreeze()
+	}
+	if req.Skew != nil {
+		h.clock.SetSkew(skew)
+	}
+	h.store.Tick()
+
+	respondJSON(w, http.StatusOK, h.clock.Status())
+}
+
+// AdvanceClock handles POST /admin/clock/advance - Move the virtual clock forward, e.g. {"duration": "36h"}.
+// Time-dependent state like soft delete retention and pending operations is evaluated against the new time.
+func (h *Admin) AdvanceClock(w http.ResponseWriter, r *http.Request) {
+	var req AdvanceClockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid JSON body", "invalid")
+		return
+	}
+
+	d, err := time.ParseDuration(req.Duration)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid duration: "+err.Error(), "invalid")
+		return
+	}
+
+	h.clock.Advance(d)
+	h.store.Tick()
+
+	respondJSON(w, http.StatusOK, h.clock.Status())
+}
+
+// ResetClock handles DELETE /admin/clock - Return the virtual clock to the real time, without skew.
+func (h *Admin) ResetClock(w http.ResponseWriter, r *http.Request) {
+	h.clock.Reset()
+
+	respondJSON(w, http.StatusOK, h.clock.Status())
+}
+
+// SetSQLInstanceState handles PUT /admin/sql/instances/{instance}/state - Put a Cloud SQL instance into a state,
+// e.g. {"state": "SUSPENDED", "suspensionReason": ["BILLING_ISSUE"]} or {"state": "MAINTENANCE"}.
+func (h *Admin) SetSQLInstanceState(w http.ResponseWriter, r *http.Request) {
+	instanceName := r.PathValue("instance")
+
+	var req SQLInstanceStateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid JSON body", "invalid")
+		return
+	}
+
+	instance, err := h.store.SetSQLInstanceState(instanceName, req.State, req.SuspensionReason)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondError(w, http.StatusNotFound, err.Error(), "notFound")
+			return
+		}
+		if strings.Contains(err.Error(), "invalid") {
+			respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error(), "internalError")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, instance)
+}
+
+// StartSQLMaintenance handles POST /admin/sql/instances/{instance}/maintenance - Put an instance into
+// MAINTENANCE right away with a RUNNING MAINTENANCE operation, e.g. {"duration": "5m"}. The body is optional;
+// {"force": true} also starts maintenance in a deny maintenance period.
+func (h *Admin) StartSQLMaintenance(w http.ResponseWriter, r *http.Request) {
+	instanceName := r.PathValue("instance")
+
+	var req SQLMaintenanceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		respondError(w, http.StatusBadRequest, "Invalid JSON body", "invalid")
+		return
+	}
+
+	var duration time.Duration
+	if req.Duration != "" {
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil || d <= 0 {
+			respondError(w, http.StatusBadRequest, "Invalid duration: must be positive, e.g. 5m", "invalid")
+			return
+		}
+		duration = d
+	}
+
+	op, err := h.store.StartSQLMaintenance(instanceName, duration, req.Force)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondError(w, http.StatusNotFound, err.Error(), "notFound")
+			return
+		}
+		if strings.Contains(err.Error(), "not in an appropriate state") {
+			respondError(w, http.StatusConflict, err.Error(), "invalidState")
+			return
+		}
+		if strings.Contains(err.Error(), "invalid") {
+			respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error(), "internalError")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, op)
+}
+
+// GetBucketQuota handles GET /admin/storage/buckets/{bucket}/quota - Get the quota of a bucket and its usage.
+func (h *Admin) GetBucketQuota(w http.ResponseWriter, r *http.Request) {
+	bucketName := r.PathValue("bucket")
+
+	usage, err := h.store.GetBucketUsage(bucketName)
+	if err != nil {
+		respondError(w, http.StatusNotFound, err.Error(), "notFound")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, usage)
+}
+
+// GetDedupStats handles GET /admin/storage/dedup - Get how much content deduplication saves,
+// i.e. how many objects share how many distinct contents. Returns 404 if deduplication is disabled.
+func (h *Admin) GetDedupStats(w http.ResponseWriter, r *http.Request) {
+	stats, enabled := h.store.BlobDedupStats()
+	if !enabled {
+		respondError(w, http.StatusNotFound, "Content deduplication is disabled; enable it with GCP_MOCK_BLOB_DEDUP=true", "notFound")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, stats)
+}
+
+// GetContentLimitStats handles GET /admin/storage/content - Get how much content is stored and how much
+// was evicted to stay below the content size limit. Returns 404 if the stored content isn't limited.
+func (h *Admin) GetContentLimitStats(w http.ResponseWriter, r *http.Request) {
+	stats, enabled := h.store.ContentLimitStats()
+	if !enabled {
+		respondError(w, http.StatusNotFound, "The stored content isn't limited; limit it with GCP_MOCK_MAX_CONTENT_SIZE", "notFound")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, stats)
+}
+
+// DeleteObjects handles DELETE /admin/storage/buckets/{bucket}/objects - Delete all objects of a bucket
+// in one call, or only those under a prefix with ?prefix=tmp/, e.g. to tear down the objects of a test suite.
+// Objects are deleted like by objects.delete, so soft delete policies and notifications apply. If a hold
+// or the retention policy of the bucket keeps any of them, none are deleted.
+func (h *Admin) DeleteObjects(w http.ResponseWriter, r *http.Request) {
+	bucketName := r.PathValue("bucket")
+
+	deleted, err := h.store.DeleteObjects(bucketName, r.URL.Query().Get("prefix"))
+	if err != nil {
+		if isRetained(err) {
+			respondError(w, http.StatusForbidden, err.Error(), "retentionPolicyNotMet")
+			return
+		}
+		respondError(w, http.StatusNotFound, err.Error(), "notFound")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, DeleteObjectsResponse{Deleted: deleted})
+}
+
+// SetBucketQuota handles PUT /admin/storage/buckets/{bucket}/quota - Limit the size of a bucket,
+// e.g. {"maxBytes": 1048576, "maxObjects": 100}. Writes beyond the quota fail with 403 quotaExceeded.
+func (h *Admin) SetBucketQuota(w http.ResponseWriter, r *http.Request) {
+	bucketName := r.PathValue("bucket")
+
+	var quota store.BucketQuota
+	if err := json.NewDecoder(r.Body).Decode(&quota); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid JSON body", "invalid")
+		return
+	}
+
+	usage, err := h.store.SetBucketQuota(bucketName, quota)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondError(w, http.StatusNotFound, err.Error(), "notFound")
+			return
+		}
+		if strings.Contains(err.Error(), "invalid") {
+			respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error(), "internalError")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, usage)
+}
+
+// DeleteBucketQuota handles DELETE /admin/storage/buckets/{bucket}/quota - Remove the quota of a bucket.
+func (h *Admin) DeleteBucketQuota(w http.ResponseWriter, r *http.Request) {
+	bucketName := r.PathValue("bucket")
+
+	if _, err := h.store.SetBucketQuota(bucketName, store.BucketQuota{}); err != nil {
+		respondError(w, http.StatusNotFound, err.Error(), "notFound")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ImportBucket handles POST /admin/storage/import - Mirror the objects of a real Cloud Storage bucket into the mock,
+// e.g. {"sourceBucket": "prod-assets", "prefix": "images/", "accessToken": "ya29..."}.
+// Imports can take longer than the server's write timeout, so it is lifted for this request.
+func (h *Admin) ImportBucket(w http.ResponseWriter, r *http.Request) {
+	var opts gcsimport.Options
+	if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid JSON body", "invalid")
+		return
+	}
+	if opts.SourceBucket == "" {
+		respondError(w, http.StatusBadRequest, "Source bucket is required", "required")
+		return
+	}
+
+	liftWriteDeadline(w, r)
+
+	result, err := gcsimport.Import(r.Context(), http.DefaultClient, h.store, opts)
+	if err != nil {
+		var sourceErr *gcsimport.SourceError
+		if errors.As(err, &sourceErr) {
+			// The source's status tells what went wrong, like a missing permission, but the request to the mock was fine
+			respondError(w, http.StatusBadGateway, err.Error(), "badGateway")
+			return
+		}
+		if strings.Contains(err.Error(), "invalid") {
+			respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error(), "internalError")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, result)
+}
+
+// GenerateData handles POST /admin/generate - Fill the mock with generated data for performance tests, e.g.
+// {"buckets": 10, "objects": 10000, "sizes": "90%=1KiB-16KiB,10%=1MiB", "content": "compressible", "sqlInstances": 5}.
+// Objects are created in parallel; generation can take longer than the server's write timeout, so it is
+// lifted for this request.
+func (h *Admin) GenerateData(w http.ResponseWriter, r *http.Request) {
+	var opts loadgen.Options
+	if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid JSON body", "invalid")
+		return
+	}
+
+	liftWriteDeadline(w, r)
+
+	result, err := loadgen.Generate(r.Context(), h.store, opts)
+	if err != nil {
+		if strings.Contains(err.Error(), "invalid") {
+			respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error(), "internalError")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,541 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/clock"
+	"github.com/katharinasick/gcp-api-mock/internal/latency"
+	"github.com/katharinasick/gcp-api-mock/internal/recorder"
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+	"github.com/katharinasick/gcp-api-mock/internal/store"
+)
+
+func TestAdmin_RecordAndReplay(t *testing.T) {
+	rec := recorder.New()
+	replay := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	h := NewAdmin(store.New(), rec, replay, latency.New(), clock.New(), NewRequestLogger(100))
+	path := filepath.Join(t.TempDir(), "recording.jsonl")
+
+	// Start recording
+	req := httptest.NewRequest(http.MethodPost, "/admin/recording/start", strings.NewReader(`{"path":"`+path+`"}`))
+	rr := httptest.NewRecorder()
+	h.StartRecording(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	// Starting again conflicts
+	req = httptest.NewRequest(http.MethodPost, "/admin/recording/start", strings.NewReader(`{"path":"`+path+`"}`))
+	rr = httptest.NewRecorder()
+	h.StartRecording(rr, req)
+	if rr.Code != http.StatusConflict {
+		t.Errorf("expected status %d, got %d", http.StatusConflict, rr.Code)
+	}
+
+	_ = rec.Record(&recorder.Exchange{
+		Request:  recorder.RecordedRequest{Method: http.MethodGet, URL: "/storage/v1/b"},
+		Response: recorder.RecordedResponse{Status: http.StatusOK},
+	})
+	_ = rec.Record(&recorder.Exchange{
+		Request:  recorder.RecordedRequest{Method: http.MethodGet, URL: "/storage/v1/b/missing"},
+		Response: recorder.RecordedResponse{Status: http.StatusNotFound},
+	})
+
+	// Status reports the recorded count
+	req = httptest.NewRequest(http.MethodGet, "/admin/recording", nil)
+	rr = httptest.NewRecorder()
+	h.GetRecording(rr, req)
+	var status recorder.Status
+	if err := json.NewDecoder(rr.Body).Decode(&status); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !status.Recording || status.Count != 2 {
+		t.Errorf("unexpected status: %+v", status)
+	}
+
+	// Stop recording
+	req = httptest.NewRequest(http.MethodPost, "/admin/recording/stop", nil)
+	rr = httptest.NewRecorder()
+	h.StopRecording(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	// Replay the recording
+	req = httptest.NewRequest(http.MethodPost, "/admin/recording/replay", strings.NewReader(`{"path":"`+path+`"}`))
+	rr = httptest.NewRecorder()
+	h.ReplayRecording(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	var resp ReplayResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Total != 2 || resp.Matched != 1 || resp.Mismatched != 1 {
+		t.Errorf("unexpected replay response: %+v", resp)
+	}
+}
+
+func TestAdmin_StopRecording_NotRecording(t *testing.T) {
+	h := NewAdmin(store.New(), recorder.New(), http.NotFoundHandler(), latency.New(), clock.New(), NewRequestLogger(100))
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/recording/stop", nil)
+	rr := httptest.NewRecorder()
+	h.StopRecording(rr, req)
+
+	if rr.Code != http.StatusConflict {
+		t.Errorf("expected status %d, got %d", http.StatusConflict, rr.Code)
+	}
+}
+
+func TestAdmin_Latency(t *testing.T) {
+	injector := latency.New()
+	h := NewAdmin(store.New(), recorder.New(), http.NotFoundHandler(), injector, clock.New(), NewRequestLogger(100))
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedDelay  time.Duration
+	}{
+		{"set profile", `{"storage.get":"2s","sql.*":"1s-1s"}`, http.StatusOK, 2 * time.Second},
+		{"invalid range", `{"storage.get":"80ms-20ms"}`, http.StatusBadRequest, 2 * time.Second},
+		{"invalid JSON", `{`, http.StatusBadRequest, 2 * time.Second},
+		{"clear profile", `{}`, http.StatusOK, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/admin/latency", strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+			h.SetLatency(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if delay := injector.Delay("storage.get"); delay != tt.expectedDelay {
+				t.Errorf("expected delay %v, got %v", tt.expectedDelay, delay)
+			}
+		})
+	}
+
+	injector.SetProfile(latency.Profile{"sql.insert": {Min: 2 * time.Second, Max: 5 * time.Second}})
+	req := httptest.NewRequest(http.MethodGet, "/admin/latency", nil)
+	rr := httptest.NewRecorder()
+	h.GetLatency(rr, req)
+
+	if body := strings.TrimSpace(rr.Body.String()); body != `{"sql.insert":"2s-5s"}` {
+		t.Errorf("unexpected profile: %s", body)
+	}
+}
+
+func TestAdmin_SnapshotAndRestore(t *testing.T) {
+	s := store.New()
+	h := NewAdmin(s, recorder.New(), http.NotFoundHandler(), latency.New(), clock.New(), NewRequestLogger(100))
+
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "known-good"})
+	_, _ = s.CreateObject("known-good", "seed.json", "application/json", []byte(`{"seed":true}`), nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/snapshot", nil)
+	rr := httptest.NewRecorder()
+	h.Snapshot(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "application/x-tar" {
+		t.Errorf("expected Content-Type application/x-tar, got %s", ct)
+	}
+	snapshot := rr.Body.Bytes()
+
+	// Changes made by a test run are undone by restoring the snapshot
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-run"})
+	_ = s.DeleteObject("known-good", "seed.json")
+
+	req = httptest.NewRequest(http.MethodPost, "/admin/restore", bytes.NewReader(snapshot))
+	rr = httptest.NewRecorder()
+	h.Restore(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	var summary store.SnapshotSummary
+	if err := json.NewDecoder(rr.Body).Decode(&summary); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if summary.Buckets != 1 || summary.Objects != 1 {
+		t.Errorf("unexpected summary: %+v", summary)
+	}
+	if s.GetBucket("test-run") != nil {
+		t.Error("expected bucket created after the snapshot to be gone")
+	}
+	if content := s.GetObjectContent("known-good", "seed.json"); string(content) != `{"seed":true}` {
+		t.Errorf("expected restored content, got %q", string(content))
+	}
+
+	// Invalid archives are rejected
+	req = httptest.NewRequest(http.MethodPost, "/admin/restore", strings.NewReader("not a tar archive"))
+	rr = httptest.NewRecorder()
+	h.Restore(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, rr.Code)
+	}
+}
+
+func TestAdmin_Clock(t *testing.T) {
+	s := store.New()
+	clk := clock.New()
+	s.SetClock(clk.Now)
+	h := NewAdmin(s, recorder.New(), http.NotFoundHandler(), latency.New(), clk, NewRequestLogger(100))
+
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{
+		Name:             "test-bucket",
+		SoftDeletePolicy: &storage.SoftDeletePolicy{RetentionDurationSeconds: 3600},
+	})
+	_, _ = s.CreateObject("test-bucket", "file.txt", "text/plain", []byte("data"), nil)
+	_ = s.DeleteObject("test-bucket", "file.txt")
+
+	tests := []struct {
+		name           string
+		method         string
+		body           string
+		handler        http.HandlerFunc
+		expectedStatus int
+		expectedFrozen bool
+		softDeleted    int
+	}{
+		{"freeze", http.MethodPut, `{"frozen":true}`, h.SetClock, http.StatusOK, true, 1},
+		{"advance within retention", http.MethodPost, `{"duration":"30m"}`, h.AdvanceClock, http.StatusOK, true, 1},
+		{"invalid duration", http.MethodPost, `{"duration":"soon"}`, h.AdvanceClock, http.StatusBadRequest, true, 1},
+		{"advance past retention", http.MethodPost, `{"duration":"31m"}`, h.AdvanceClock, http.StatusOK, true, 0},
+		{"invalid time", http.MethodPut, `{"time":"tomorrow"}`, h.SetClock, http.StatusBadRequest, true, 0},
+		{"reset", http.MethodDelete, "", h.ResetClock, http.StatusOK, false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/admin/clock", strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+			tt.handler(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if frozen := clk.Status().Frozen; frozen != tt.expectedFrozen {
+				t.Errorf("expected frozen %v, got %v", tt.expectedFrozen, frozen)
+			}
+			if got := len(s.ListSoftDeletedObjects("test-bucket", "")); got != tt.softDeleted {
+				t.Errorf("expected %d soft-deleted objects, got %d", tt.softDeleted, got)
+			}
+		})
+	}
+
+	target := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	req := httptest.NewRequest(http.MethodPut, "/admin/clock", strings.NewReader(`{"time":"2030-01-01T00:00:00Z","frozen":true}`))
+	rr := httptest.NewRecorder()
+	h.SetClock(rr, req)
+
+	var status clock.Status
+	if err := json.NewDecoder(rr.Body).Decode(&status); err != nil {
+		t.Fatalf("failed to decode clock status: %v", err)
+	}
+	if !status.Now.Equal(target) || !status.Frozen {
+		t.Errorf("unexpected clock status: %+v", status)
+	}
+
+	bucket, _ := s.CreateBucket(&storage.BucketInsertRequest{Name: "future-bucket"})
+	if !bucket.TimeCreated.Equal(target) {
+		t.Errorf("expected timeCreated %v, got %v", target, bucket.TimeCreated)
+	}
+}
+
+func TestAdmin_ListRequests(t *testing.T) {
+	requests := NewRequestLogger(100)
+	h := NewAdmin(store.New(), recorder.New(), http.NotFoundHandler(), latency.New(), clock.New(), requests)
+
+	requests.Add(http.MethodPost, "/storage/v1/b", http.StatusOK)
+	requests.Add(http.MethodGet, "/storage/v1/b/missing", http.StatusNotFound)
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedPaths  []string
+	}{
+		{"all requests", "", http.StatusOK, []string{"/storage/v1/b", "/storage/v1/b/missing"}},
+		{"after first request", "?after=1", http.StatusOK, []string{"/storage/v1/b/missing"}},
+		{"no new requests", "?after=2", http.StatusOK, []string{}},
+		{"invalid after", "?after=abc", http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin/requests"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			h.ListRequests(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if tt.expectedPaths == nil {
+				return
+			}
+
+			var entries []RequestLogEntry
+			if err := json.NewDecoder(rr.Body).Decode(&entries); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			paths := make([]string, 0, len(entries))
+			for _, entry := range entries {
+				paths = append(paths, entry.Path)
+			}
+			if strings.Join(paths, ",") != strings.Join(tt.expectedPaths, ",") {
+				t.Errorf("expected paths %v, got %v", tt.expectedPaths, paths)
+			}
+		})
+	}
+}
+
+func TestAdmin_ExportRequests(t *testing.T) {
+	requests := NewRequestLogger(100)
+	h := NewAdmin(store.New(), recorder.New(), http.NotFoundHandler(), latency.New(), clock.New(), requests)
+
+	requests.AddExchange(&recorder.Exchange{
+		Request:  recorder.RecordedRequest{Method: http.MethodPost, URL: "/storage/v1/b?project=test", Body: []byte(`{"name":"bucket"}`)},
+		Response: recorder.RecordedResponse{Status: http.StatusOK},
+	}, false, false)
+	requests.AddExchange(&recorder.Exchange{
+		Request:  recorder.RecordedRequest{Method: http.MethodPost, URL: "/upload/storage/v1/b/bucket/o", Body: []byte("partial")},
+		Response: recorder.RecordedResponse{Status: http.StatusOK},
+	}, true, false)
+	requests.AddExchange(&recorder.Exchange{
+		Request:  recorder.RecordedRequest{Method: http.MethodGet, URL: "/storage/v1/b/bucket"},
+		Response: recorder.RecordedResponse{Status: http.StatusOK},
+	}, false, false)
+
+	tests := []struct {
+		name             string
+		query            string
+		expectedStatus   int
+		expectedContains []string
+		expectedMissing  []string
+	}{
+		{"curl by default", "", http.StatusOK, []string{"curl -sS -X POST", "/storage/v1/b/bucket'"}, []string{"partial"}},
+		{"selected requests", "?format=go&ids=3", http.StatusOK, []string{`"/storage/v1/b/bucket"`}, []string{"project=test"}},
+		{"after a request", "?format=cassette&after=1", http.StatusOK, []string{`/storage/v1/b/bucket"`}, []string{"project=test"}},
+		{"invalid format", "?format=har", http.StatusBadRequest, nil, nil},
+		{"invalid ids", "?ids=1,x", http.StatusBadRequest, nil, nil},
+		{"invalid after", "?after=x", http.StatusBadRequest, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin/requests/export"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			h.ExportRequests(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			for _, s := range tt.expectedContains {
+				if !strings.Contains(rr.Body.String(), s) {
+					t.Errorf("expected export to contain %q, got:\n%s", s, rr.Body.String())
+				}
+			}
+			for _, s := range tt.expectedMissing {
+				if strings.Contains(rr.Body.String(), s) {
+					t.Errorf("expected export not to contain %q, got:\n%s", s, rr.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestAdmin_ReplayRequest(t *testing.T) {
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		_, _ = io.Copy(w, r.Body)
+	})
+	requests := NewRequestLogger(100)
+	h := NewAdmin(store.New(), recorder.New(), echo, latency.New(), clock.New(), requests)
+
+	requests.AddExchange(&recorder.Exchange{
+		Request:  recorder.RecordedRequest{Method: http.MethodPost, URL: "/storage/v1/b?project=test", Body: []byte(`{"name":"bucket"}`)},
+		Response: recorder.RecordedResponse{Status: http.StatusOK},
+	}, false, false)
+	requests.AddExchange(&recorder.Exchange{
+		Request:  recorder.RecordedRequest{Method: http.MethodPost, URL: "/upload/storage/v1/b/bucket/o", Body: []byte("partial")},
+		Response: recorder.RecordedResponse{Status: http.StatusOK},
+	}, true, false)
+	requests.Add(http.MethodGet, "/storage/v1/b", http.StatusOK)
+
+	tests := []struct {
+		name           string
+		id             string
+		expectedStatus int
+	}{
+		{"logged request", "1", http.StatusOK},
+		{"truncated request body", "2", http.StatusBadRequest},
+		{"request without exchange", "3", http.StatusBadRequest},
+		{"unknown request", "99", http.StatusNotFound},
+		{"invalid id", "abc", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/admin/requests/"+tt.id+"/replay", nil)
+			rr := httptest.NewRecorder()
+			serveRoute("POST /admin/requests/{id}/replay", h.ReplayRequest, rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var entry RequestLogEntry
+			if err := json.NewDecoder(rr.Body).Decode(&entry); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if entry.ReplayOf != 1 {
+				t.Errorf("expected replayOf 1, got %d", entry.ReplayOf)
+			}
+			if entry.Status != http.StatusCreated {
+				t.Errorf("expected status %d, got %d", http.StatusCreated, entry.Status)
+			}
+			if entry.ResponseBody != `{"name":"bucket"}` {
+				t.Errorf("expected echoed body, got %q", entry.ResponseBody)
+			}
+		})
+	}
+
+	if all := requests.GetAll(); len(all) != 4 {
+		t.Errorf("expected the replay to be logged, got %d entries", len(all))
+	}
+}
+
+func TestAdmin_ExportTerraform(t *testing.T) {
+	s := store.New()
+	h := NewAdmin(s, recorder.New(), http.NotFoundHandler(), latency.New(), clock.New(), NewRequestLogger(100))
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "assets"})
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expected       string
+	}{
+		{"default format", "", http.StatusOK, `resource "google_storage_bucket" "assets" {`},
+		{"import format", "?format=import", http.StatusOK, `to = google_storage_bucket.assets`},
+		{"invalid format", "?format=json", http.StatusBadRequest, "Invalid format"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin/terraform"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			h.ExportTerraform(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if !strings.Contains(rr.Body.String(), tt.expected) {
+				t.Errorf("expected body to contain %q, got:\n%s", tt.expected, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestAdmin_SearchObjects(t *testing.T) {
+	s := store.New()
+	h := NewAdmin(s, recorder.New(), http.NotFoundHandler(), latency.New(), clock.New(), NewRequestLogger(100))
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "assets"})
+	_, _ = s.CreateObject("assets", "images/cat.png", "image/png", make([]byte, 2048), map[string]string{"test": "checkout"})
+	_, _ = s.CreateObject("assets", "images/dog.png", "image/png", make([]byte, 10), map[string]string{"test": "checkout"})
+	_, _ = s.CreateObject("assets", "notes.txt", "text/plain", []byte("notes"), map[string]string{"test": "login"})
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedItems  int
+		expectedTotal  int
+	}{
+		{"all objects", "", http.StatusOK, 3, 3},
+		{"by metadata", "?metadata=test=checkout", http.StatusOK, 2, 2},
+		{"by metadata and size", "?metadata=test=checkout&minSize=1KB", http.StatusOK, 1, 1},
+		{"by content type and prefix", "?contentType=image/*&prefix=images/d", http.StatusOK, 1, 1},
+		{"limited", "?limit=1", http.StatusOK, 1, 3},
+		{"no matches", "?bucket=other", http.StatusOK, 0, 0},
+		{"invalid size", "?maxSize=huge", http.StatusBadRequest, 0, 0},
+		{"invalid limit", "?limit=-1", http.StatusBadRequest, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin/storage/objects"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			h.SearchObjects(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if rr.Code != http.StatusOK {
+				return
+			}
+			var response SearchObjectsResponse
+			if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if len(response.Items) != tt.expectedItems || response.Total != tt.expectedTotal {
+				t.Errorf("expected %d of %d objects, got %d of %d", tt.expectedItems, tt.expectedTotal, len(response.Items), response.Total)
+			}
+		})
+	}
+}
+
+func TestAdmin_GenerateData(t *testing.T) {
+	s := store.New()
+	h := NewAdmin(s, recorder.New(), http.NotFoundHandler(), latency.New(), clock.New(), NewRequestLogger(100))
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{"generate", `{"buckets": 2, "objects": 20, "sizes": "1KiB-4KiB", "content": "compressible", "sqlInstances": 1, "seed": 7}`, http.StatusOK},
+		{"invalid JSON", `{`, http.StatusBadRequest},
+		{"invalid sizes", `{"buckets": 1, "objects": 1, "sizes": "huge"}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/admin/generate", strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+			h.GenerateData(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+
+	objects, _ := s.ListObjects("loadtest-0001", "", "")
+	if len(objects) != 20 || s.GetSQLInstance("loadtest-0000") == nil {
+		t.Errorf("expected 20 objects in loadtest-0001 and instance loadtest-0000, got %d objects", len(objects))
+	}
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/katharinasick/gcp-api-mock/internal/clock"
+	"github.com/katharinasick/gcp-api-mock/internal/latency"
+	"github.com/katharinasick/gcp-api-mock/internal/recorder"
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+	"github.com/katharinasick/gcp-api-mock/internal/store"
+)
+
+func TestAdmin_DownloadArchive(t *testing.T) {
+	s := store.New()
+	h := NewAdmin(s, recorder.New(), http.NotFoundHandler(), latency.New(), clock.New(), NewRequestLogger(100))
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "assets"})
+	_, _ = s.CreateObject("assets", "fixtures/a.json", "application/json", []byte(`{"a":1}`), nil)
+	_, _ = s.CreateObject("assets", "fixtures/nested/b.txt", "text/plain", []byte("b"), nil)
+	_, _ = s.CreateObject("assets", "other.txt", "text/plain", []byte("other"), nil)
+
+	download := func(t *testing.T, query string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/admin/storage/buckets/assets/archive"+query, nil)
+		rr := httptest.NewRecorder()
+		serveRoute("GET /admin/storage/buckets/{bucket}/archive", h.DownloadArchive, rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		return rr
+	}
+	expected := map[string]string{"fixtures/a.json": `{"a":1}`, "fixtures/nested/b.txt": "b"}
+
+	t.Run("zip", func(t *testing.T) {
+		rr := download(t, "?prefix=fixtures/")
+		if got := rr.Header().Get("Content-Disposition"); got != `attachment; filename="assets-fixtures.zip"` {
+			t.Errorf("unexpected Content-Disposition %q", got)
+		}
+
+		archive, err := zip.NewReader(bytes.NewReader(rr.Body.Bytes()), int64(rr.Body.Len()))
+		if err != nil {
+			t.Fatalf("invalid zip archive: %v", err)
+		}
+		entries := make(map[string]string)
+		for _, f := range archive.File {
+			r, _ := f.Open()
+			content, _ := io.ReadAll(r)
+			entries[f.Name] = string(content)
+		}
+		if len(entries) != len(expected) || entries["fixtures/a.json"] != expected["fixtures/a.json"] || entries["fixtures/nested/b.txt"] != "b" {
+			t.Errorf("expected %v, got %v", expected, entries)
+		}
+	})
+
+	t.Run("tar", func(t *testing.T) {
+		rr := download(t, "?format=tar")
+		if got := rr.Header().Get("Content-Type"); got != "application/x-tar" {
+			t.Errorf("expected application/x-tar, got %q", got)
+		}
+
+		archive := tar.NewReader(rr.Body)
+		var names []string
+		for {
+			header, err := archive.Next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				t.Fatalf("invalid tar archive: %v", err)
+			}
+			names = append(names, header.Name)
+		}
+		if !slices.Equal(names, []string{"fixtures/a.json", "fixtures/nested/b.txt", "other.txt"}) {
+			t.Errorf("unexpected entries %v", names)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		for _, tt := range []struct {
+			path           string
+			expectedStatus int
+		}{
+			{"/admin/storage/buckets/assets/archive?format=rar", http.StatusBadRequest},
+			{"/admin/storage/buckets/missing/archive", http.StatusNotFound},
+		} {
+			rr := httptest.NewRecorder()
+			serveRoute("GET /admin/storage/buckets/{bucket}/archive", h.DownloadArchive, rr, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("%s: expected status %d, got %d", tt.path, tt.expectedStatus, rr.Code)
+			}
+		}
+	})
+}
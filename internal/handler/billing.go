@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/katharinasick/gcp-api-mock/internal/billing"
+	"github.com/katharinasick/gcp-api-mock/internal/store"
+)
+
+// Billing handles Cloud Billing Budget API (v1) endpoints.
+// Any billing account ID in the 012345-6789AB-CDEF01 format is accepted. Budgets don't track real costs;
+// their notifications are sent with the admin API.
+type Billing struct {
+	store      *store.Store
+	dispatcher *billing.Dispatcher
+}
+
+// NewBilling creates a new Billing handler.
+func NewBilling(s *store.Store) *Billing {
+	return &Billing{store: s, dispatcher: billing.NewDispatcher()}
+}
+
+// billingDefaultPageSize is the default page size for budgets.list.
+const billingDefaultPageSize = 100
+
+// ListBudgets handles GET /v1/billingAccounts/{billingAccount}/budgets - List budgets.
+// Reference: https://cloud.google.com/billing/docs/reference/budget/rest/v1/billingAccounts.budgets/list
+func (h *Billing) ListBudgets(w http.ResponseWriter, r *http.Request) {
+	pageSize := billingDefaultPageSize
+	if value := r.URL.Query().Get("pageSize"); value != "" {
+		size, err := strconv.Atoi(value)
+		if err != nil || size < 1 {
+			respondStatusError(w, http.StatusBadRequest, "Invalid pageSize: "+value, "INVALID_ARGUMENT")
+			return
+		}
+		pageSize = size
+	}
+
+	budgets, nextPageToken, err := paginate(h.store.ListBillingBudgets(billingParent(r)), r.URL.Query().Get("pageToken"), pageSize, func(b *billing.Budget) string { return b.Name })
+	if err != nil {
+		respondStatusError(w, http.StatusBadRequest, err.Error(), "INVALID_ARGUMENT")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, &billing.ListBudgetsResponse{
+		Budgets:       budgets,
+		NextPageToken: nextPageToken,
+	})
+}
+
+// CreateBudget handles POST /v1/billingAccounts/{billingAccount}/budgets - Create a budget.
+// Reference: https://cloud.google.com/billing/docs/reference/budget/rest/v1/billingAccounts.budgets/create
+func (h *Billing) CreateBudget(w http.ResponseWriter, r *http.Request) {
+	var req billing.Budget
+	if err := decodeJSON(r.Body, &req, h.store.StrictValidation()); err != nil {
+		respondStatusError(w, http.StatusBadRequest, invalidJSONMessage(err), "INVALID_ARGUMENT")
+		return
+	}
+
+	budget, err := h.store.CreateBillingBudget(billingParent(r), &req)
+	if err != nil {
+		respondBillingStoreError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, budget)
+}
+
+// GetBudget handles GET /v1/billingAccounts/{billingAccount}/budgets/{budget} - Get a budget.
+// Reference: https://cloud.google.com/billing/docs/reference/budget/rest/v1/billingAccounts.budgets/get
+func (h *Billing) GetBudget(w http.ResponseWriter, r *http.Request) {
+	name := billingBudgetName(r)
+
+	budget := h.store.GetBillingBudget(name)
+	if budget == nil {
+		respondStatusError(w, http.StatusNotFound, "Budget not found: "+name, "NOT_FOUND")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, budget)
+}
+
+// SendAlert handles POST /admin/billing/billingAccounts/{billingAccount}/budgets/{budget}/alert - Publish the
+// notification a budget sends for a spend to its Pub/Sub topic, and respond once it was delivered.
+// The body is a billing.AlertRequest with the cost so far.
+func (h *Billing) SendAlert(w http.ResponseWriter, r *http.Request) {
+	var req billing.AlertRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondStatusError(w, http.StatusBadRequest, invalidJSONMessage(err), "INVALID_ARGUMENT")
+		return
+	}
+
+	budget, n, err := h.store.BillingBudgetNotification(billingBudgetName(r), &req)
+	if err != nil {
+		respondBillingStoreError(w, err)
+		return
+	}
+
+	msg, err := h.dispatcher.Publish(budget, n)
+	if err != nil {
+		respondStatusError(w, http.StatusBadGateway, err.Error(), "UNAVAILABLE")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, &billing.AlertResponse{Message: msg, Notification: n})
+}
+
+// billingParent returns the parent (billingAccounts/{billingAccount}) named by the path of a request.
+func billingParent(r *http.Request) string {
+	return "billingAccounts/" + r.PathValue("billingAccount")
+}
+
+// billingBudgetName returns the budget name (billingAccounts/{billingAccount}/budgets/{budget})
+// named by the path of a request.
+func billingBudgetName(r *http.Request) string {
+	return billingParent(r) + "/budgets/" + r.PathValue("budget")
+}
+
+// respondBillingStoreError maps a store error to a Cloud Billing Budget API error response.
+func respondBillingStoreError(w http.ResponseWriter, err error) {
+	if strings.Contains(err.Error(), "precondition failed") {
+		respondStatusError(w, http.StatusBadRequest, err.Error(), "FAILED_PRECONDITION")
+		return
+	}
+	respondStatusStoreError(w, err)
+}
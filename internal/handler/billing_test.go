@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/katharinasick/gcp-api-mock/internal/billing"
+	"github.com/katharinasick/gcp-api-mock/internal/store"
+)
+
+const testBillingAccount = "/v1/billingAccounts/012345-6789AB-CDEF01"
+
+func setupTestBilling() (*Billing, *store.Store) {
+	s := store.New()
+	return NewBilling(s), s
+}
+
+func TestBilling_CreateBudget(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{"valid", `{"displayName": "monthly", "amount": {"specifiedAmount": {"units": "100"}}}`, http.StatusOK},
+		{"missing amount", `{"displayName": "monthly"}`, http.StatusBadRequest},
+		{"invalid body", `{`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, _ := setupTestBilling()
+
+			req := httptest.NewRequest(http.MethodPost, testBillingAccount+"/budgets", strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+			serveRoute("POST /v1/billingAccounts/{billingAccount}/budgets", h.CreateBudget, rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestBilling_GetBudget_NotFound(t *testing.T) {
+	h, _ := setupTestBilling()
+
+	req := httptest.NewRequest(http.MethodGet, testBillingAccount+"/budgets/missing", nil)
+	rr := httptest.NewRecorder()
+	serveRoute("GET /v1/billingAccounts/{billingAccount}/budgets/{budget}", h.GetBudget, rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", rr.Code)
+	}
+}
+
+func TestBilling_SendAlert(t *testing.T) {
+	var received int
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received++
+	}))
+	defer webhook.Close()
+
+	h, s := setupTestBilling()
+	budget, err := s.CreateBillingBudget("billingAccounts/012345-6789AB-CDEF01", &billing.Budget{
+		DisplayName:       "monthly",
+		Amount:            &billing.BudgetAmount{SpecifiedAmount: &billing.Money{Units: "100"}},
+		ThresholdRules:    []*billing.ThresholdRule{{ThresholdPercent: 0.5}, {ThresholdPercent: 1.0}},
+		NotificationsRule: &billing.NotificationsRule{PubsubTopic: webhook.URL},
+	})
+	if err != nil {
+		t.Fatalf("CreateBillingBudget() error: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/billing/"+budget.Name+"/alert", strings.NewReader(`{"costAmount": 120}`))
+	rr := httptest.NewRecorder()
+	serveRoute("POST /admin/billing/billingAccounts/{billingAccount}/budgets/{budget}/alert", h.SendAlert, rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp billing.AlertResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Notification.AlertThresholdExceeded != 1.0 || resp.Notification.BudgetAmount != 100 || resp.Message.MessageID == "" {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+	if received != 1 {
+		t.Errorf("expected the webhook to receive 1 request, got %d", received)
+	}
+}
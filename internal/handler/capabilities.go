@@ -0,0 +1,23 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/katharinasick/gcp-api-mock/internal/capabilities"
+)
+
+// Capabilities handles the capability report endpoint.
+type Capabilities struct {
+	report *capabilities.Report
+}
+
+// NewCapabilities creates a new Capabilities handler serving report.
+func NewCapabilities(report *capabilities.Report) *Capabilities {
+	return &Capabilities{report: report}
+}
+
+// Get handles GET /capabilities - The APIs, methods and features the mock supports, so test harnesses
+// can skip scenarios it can't serve.
+func (h *Capabilities) Get(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, h.report)
+}
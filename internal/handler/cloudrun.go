@@ -0,0 +1,263 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/katharinasick/gcp-api-mock/internal/cloudrun"
+	"github.com/katharinasick/gcp-api-mock/internal/store"
+)
+
+// CloudRun handles Cloud Run Admin API (v2) endpoints.
+// Any project and location is accepted. Mutations return long-running operations that are already done.
+type CloudRun struct {
+	store *store.Store
+}
+
+// NewCloudRun creates a new CloudRun handler.
+func NewCloudRun(s *store.Store) *CloudRun {
+	return &CloudRun{store: s}
+}
+
+// runDefaultPageSize is the default page size for Cloud Run list calls.
+const runDefaultPageSize = 100
+
+// =============================================================================
+// Service Handlers
+// =============================================================================
+
+// ListServices handles GET /v2/projects/{project}/locations/{location}/services - List services.
+// Reference: https://cloud.google.com/run/docs/reference/rest/v2/projects.locations.services/list
+func (h *CloudRun) ListServices(w http.ResponseWriter, r *http.Request) {
+	parent := runParent(r)
+
+	pageSize, ok := parseRunPageSize(w, r)
+	if !ok {
+		return
+	}
+
+	services, nextPageToken, err := paginate(h.store.ListRunServices(parent), r.URL.Query().Get("pageToken"), pageSize, func(s *cloudrun.Service) string { return s.Name })
+	if err != nil {
+		respondStatusError(w, http.StatusBadRequest, err.Error(), "INVALID_ARGUMENT")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, &cloudrun.ListServicesResponse{
+		Services:      services,
+		NextPageToken: nextPageToken,
+	})
+}
+
+// CreateService handles POST /v2/projects/{project}/locations/{location}/services?serviceId={id} - Create a service.
+// Reference: https://cloud.google.com/run/docs/reference/rest/v2/projects.locations.services/create
+func (h *CloudRun) CreateService(w http.ResponseWriter, r *http.Request) {
+	parent := runParent(r)
+
+	serviceID := r.URL.Query().Get("serviceId")
+	if !isValidRunServiceID(serviceID) {
+		respondStatusError(w, http.StatusBadRequest, "Invalid serviceId: \""+serviceID+"\". Service IDs must start with a letter, "+
+			"contain only lowercase letters, digits and hyphens and be at most 49 characters long.", "INVALID_ARGUMENT")
+		return
+	}
+
+	var req cloudrun.Service
+	if err := decodeJSON(r.Body, &req, h.store.StrictValidation()); err != nil {
+		respondStatusError(w, http.StatusBadRequest, invalidJSONMessage(err), "INVALID_ARGUMENT")
+		return
+	}
+
+	op, err := h.store.CreateRunService(parent, serviceID, &req)
+	if err != nil {
+		respondStatusStoreError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, op)
+}
+
+// GetService handles GET /v2/projects/{project}/locations/{location}/services/{service} - Get a service.
+// Reference: https://cloud.google.com/run/docs/reference/rest/v2/projects.locations.services/get
+func (h *CloudRun) GetService(w http.ResponseWriter, r *http.Request) {
+	name := runServiceName(r)
+
+	service := h.store.GetRunService(name)
+	if service == nil {
+		respondStatusError(w, http.StatusNotFound, "Resource '"+name+"' was not found", "NOT_FOUND")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, service)
+}
+
+// UpdateService handles PATCH /v2/projects/{project}/locations/{location}/services/{service} - Update a service.
+// The request body replaces the service spec; with allowMissing=true a missing service is created.
+// Reference: https://cloud.google.com/run/docs/reference/rest/v2/projects.locations.services/patch
+func (h *CloudRun) UpdateService(w http.ResponseWriter, r *http.Request) {
+	name := runServiceName(r)
+
+	var req cloudrun.Service
+	if err := decodeJSON(r.Body, &req, h.store.StrictValidation()); err != nil {
+		respondStatusError(w, http.StatusBadRequest, invalidJSONMessage(err), "INVALID_ARGUMENT")
+		return
+	}
+
+	if !isValidRunServiceID(r.PathValue("service")) {
+		respondStatusError(w, http.StatusBadRequest, "Invalid service name: "+name, "INVALID_ARGUMENT")
+		return
+	}
+
+	op, err := h.store.UpdateRunService(name, &req, r.URL.Query().Get("allowMissing") == "true")
+	if err != nil {
+		respondStatusStoreError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, op)
+}
+
+// DeleteService handles DELETE /v2/projects/{project}/locations/{location}/services/{service} - Delete a service.
+// Reference: https://cloud.google.com/run/docs/reference/rest/v2/projects.locations.services/delete
+func (h *CloudRun) DeleteService(w http.ResponseWriter, r *http.Request) {
+	name := runServiceName(r)
+
+	op, err := h.store.DeleteRunService(name)
+	if err != nil {
+		respondStatusStoreError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, op)
+}
+
+// =============================================================================
+// Revision Handlers
+// =============================================================================
+
+// ListRevisions handles GET /v2/projects/{project}/locations/{location}/services/{service}/revisions - List revisions.
+// Reference: https://cloud.google.com/run/docs/reference/rest/v2/projects.locations.services.revisions/list
+func (h *CloudRun) ListRevisions(w http.ResponseWriter, r *http.Request) {
+	serviceName := runServiceName(r)
+
+	pageSize, ok := parseRunPageSize(w, r)
+	if !ok {
+		return
+	}
+
+	revisions, err := h.store.ListRunRevisions(serviceName)
+	if err != nil {
+		respondStatusStoreError(w, err)
+		return
+	}
+
+	// Revisions are listed newest first
+	revisions, nextPageToken, err := paginateDescending(revisions, r.URL.Query().Get("pageToken"), pageSize, func(rev *cloudrun.Revision) string {
+		return sortableTime(rev.CreateTime) + "\x00" + rev.Name
+	})
+	if err != nil {
+		respondStatusError(w, http.StatusBadRequest, err.Error(), "INVALID_ARGUMENT")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, &cloudrun.ListRevisionsResponse{
+		Revisions:     revisions,
+		NextPageToken: nextPageToken,
+	})
+}
+
+// GetRevision handles GET /v2/projects/{project}/locations/{location}/services/{service}/revisions/{revision} - Get a revision.
+// Reference: https://cloud.google.com/run/docs/reference/rest/v2/projects.locations.services.revisions/get
+func (h *CloudRun) GetRevision(w http.ResponseWriter, r *http.Request) {
+	name := runServiceName(r) + "/revisions/" + r.PathValue("revision")
+
+	revision := h.store.GetRunRevision(name)
+	if revision == nil {
+		respondStatusError(w, http.StatusNotFound, "Resource '"+name+"' was not found", "NOT_FOUND")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, revision)
+}
+
+// =============================================================================
+// Operation Handlers
+// =============================================================================
+
+// ListOperations handles GET /v2/projects/{project}/locations/{location}/operations - List operations.
+// Reference: https://cloud.google.com/run/docs/reference/rest/v2/projects.locations.operations/list
+func (h *CloudRun) ListOperations(w http.ResponseWriter, r *http.Request) {
+	parent := runParent(r)
+
+	pageSize, ok := parseRunPageSize(w, r)
+	if !ok {
+		return
+	}
+
+	operations, nextPageToken, err := paginate(h.store.ListRunOperations(parent), r.URL.Query().Get("pageToken"), pageSize, func(op *cloudrun.Operation) string { return op.Name })
+	if err != nil {
+		respondStatusError(w, http.StatusBadRequest, err.Error(), "INVALID_ARGUMENT")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, &cloudrun.ListOperationsResponse{
+		Operations:    operations,
+		NextPageToken: nextPageToken,
+	})
+}
+
+// GetOperation handles GET /v2/projects/{project}/locations/{location}/operations/{operation} - Get an operation.
+// Reference: https://cloud.google.com/run/docs/reference/rest/v2/projects.locations.operations/get
+func (h *CloudRun) GetOperation(w http.ResponseWriter, r *http.Request) {
+	name := runParent(r) + "/operations/" + r.PathValue("operation")
+
+	op := h.store.GetRunOperation(name)
+	if op == nil {
+		respondStatusError(w, http.StatusNotFound, "Operation '"+name+"' was not found", "NOT_FOUND")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, op)
+}
+
+// =============================================================================
+// Helper Functions
+// =============================================================================
+
+// runParent returns the parent (projects/{project}/locations/{location}) named by the path of a request.
+func runParent(r *http.Request) string {
+	return "projects/" + r.PathValue("project") + "/locations/" + r.PathValue("location")
+}
+
+// runServiceName returns the service name (projects/{project}/locations/{location}/services/{service})
+// named by the path of a request.
+func runServiceName(r *http.Request) string {
+	return runParent(r) + "/services/" + r.PathValue("service")
+}
+
+// isValidRunServiceID reports whether id is a valid Cloud Run service ID.
+func isValidRunServiceID(id string) bool {
+	if id == "" || len(id) > 49 || id[0] < 'a' || id[0] > 'z' || strings.HasSuffix(id, "-") {
+		return false
+	}
+	for _, c := range id {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// parseRunPageSize parses the pageSize query parameter, writing an error response if it is invalid.
+func parseRunPageSize(w http.ResponseWriter, r *http.Request) (int, bool) {
+	value := r.URL.Query().Get("pageSize")
+	if value == "" {
+		return runDefaultPageSize, true
+	}
+
+	pageSize, err := strconv.Atoi(value)
+	if err != nil || pageSize < 1 {
+		respondStatusError(w, http.StatusBadRequest, "Invalid pageSize: "+value, "INVALID_ARGUMENT")
+		return 0, false
+	}
+	return pageSize, true
+}
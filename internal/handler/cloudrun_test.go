@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/katharinasick/gcp-api-mock/internal/cloudrun"
+	"github.com/katharinasick/gcp-api-mock/internal/store"
+)
+
+const testRunLocation = "/v2/projects/test-project/locations/us-central1"
+
+func setupTestCloudRun() (*CloudRun, *store.Store) {
+	s := store.New()
+	return NewCloudRun(s), s
+}
+
+func TestCloudRun_CreateService(t *testing.T) {
+	tests := []struct {
+		name           string
+		path           string
+		body           string
+		expectedStatus int
+	}{
+		{"valid", testRunLocation + "/services?serviceId=api", `{"template":{"containers":[{"image":"nginx"}]}}`, http.StatusOK},
+		{"missing serviceId", testRunLocation + "/services", `{}`, http.StatusBadRequest},
+		{"invalid serviceId", testRunLocation + "/services?serviceId=My_Service", `{}`, http.StatusBadRequest},
+		{"invalid body", testRunLocation + "/services?serviceId=api", `{`, http.StatusBadRequest},
+		{"invalid traffic", testRunLocation + "/services?serviceId=api", `{"traffic":[{"type":"TRAFFIC_TARGET_ALLOCATION_TYPE_LATEST","percent":10}]}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, _ := setupTestCloudRun()
+
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+			serveRoute("POST /v2/projects/{project}/locations/{location}/services", h.CreateService, rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestCloudRun_CreateService_Operation(t *testing.T) {
+	h, _ := setupTestCloudRun()
+
+	req := httptest.NewRequest(http.MethodPost, testRunLocation+"/services?serviceId=api", strings.NewReader(`{"template":{"containers":[{"image":"nginx"}]}}`))
+	rr := httptest.NewRecorder()
+	serveRoute("POST /v2/projects/{project}/locations/{location}/services", h.CreateService, rr, req)
+
+	var op struct {
+		Name     string `json:"name"`
+		Done     bool   `json:"done"`
+		Response struct {
+			Type string `json:"@type"`
+			cloudrun.Service
+		} `json:"response"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&op); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if !op.Done || !strings.HasPrefix(op.Name, "projects/test-project/locations/us-central1/operations/") {
+		t.Errorf("unexpected operation: %+v", op)
+	}
+	if op.Response.Type != cloudrun.ServiceTypeURL || op.Response.Name != "projects/test-project/locations/us-central1/services/api" {
+		t.Errorf("unexpected operation response: %s %s", op.Response.Type, op.Response.Name)
+	}
+
+	// The operation can be polled
+	req = httptest.NewRequest(http.MethodGet, "/v2/"+op.Name, nil)
+	rr = httptest.NewRecorder()
+	serveRoute("GET /v2/projects/{project}/locations/{location}/operations/{operation}", h.GetOperation, rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+}
+
+func TestCloudRun_GetService(t *testing.T) {
+	h, s := setupTestCloudRun()
+	s.CreateRunService("projects/test-project/locations/us-central1", "api", &cloudrun.Service{})
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+	}{
+		{"existing", testRunLocation + "/services/api", http.StatusOK},
+		{"missing", testRunLocation + "/services/other", http.StatusNotFound},
+		{"other location", "/v2/projects/test-project/locations/europe-west1/services/api", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			rr := httptest.NewRecorder()
+			serveRoute("GET /v2/projects/{project}/locations/{location}/services/{service}", h.GetService, rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+		})
+	}
+}
+
+func TestCloudRun_UpdateService(t *testing.T) {
+	h, _ := setupTestCloudRun()
+	path := testRunLocation + "/services/api"
+
+	req := httptest.NewRequest(http.MethodPatch, path, strings.NewReader(`{}`))
+	rr := httptest.NewRecorder()
+	serveRoute("PATCH /v2/projects/{project}/locations/{location}/services/{service}", h.UpdateService, rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d", http.StatusNotFound, rr.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPatch, path+"?allowMissing=true", strings.NewReader(`{"description":"created by patch"}`))
+	rr = httptest.NewRecorder()
+	serveRoute("PATCH /v2/projects/{project}/locations/{location}/services/{service}", h.UpdateService, rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, path, nil)
+	rr = httptest.NewRecorder()
+	serveRoute("GET /v2/projects/{project}/locations/{location}/services/{service}", h.GetService, rr, req)
+
+	var service cloudrun.Service
+	if err := json.NewDecoder(rr.Body).Decode(&service); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if service.Description != "created by patch" {
+		t.Errorf("expected description to be set, got %q", service.Description)
+	}
+}
+
+func TestCloudRun_ListRevisions(t *testing.T) {
+	h, s := setupTestCloudRun()
+	name := "projects/test-project/locations/us-central1/services/api"
+	s.CreateRunService("projects/test-project/locations/us-central1", "api", &cloudrun.Service{})
+	s.UpdateRunService(name, &cloudrun.Service{Template: &cloudrun.RevisionTemplate{Revision: "api-v2"}}, false)
+
+	req := httptest.NewRequest(http.MethodGet, "/v2/"+name+"/revisions", nil)
+	rr := httptest.NewRecorder()
+	serveRoute("GET /v2/projects/{project}/locations/{location}/services/{service}/revisions", h.ListRevisions, rr, req)
+
+	var response cloudrun.ListRevisionsResponse
+	if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(response.Revisions) != 2 || response.Revisions[0].Name != name+"/revisions/api-v2" {
+		t.Errorf("expected the named revision first, got %+v", response.Revisions)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/v2/"+name+"/revisions/api-v2", nil)
+	rr = httptest.NewRecorder()
+	serveRoute("GET /v2/projects/{project}/locations/{location}/services/{service}/revisions/{revision}", h.GetRevision, rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, testRunLocation+"/services/missing/revisions", nil)
+	rr = httptest.NewRecorder()
+	serveRoute("GET /v2/projects/{project}/locations/{location}/services/{service}/revisions", h.ListRevisions, rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d", http.StatusNotFound, rr.Code)
+	}
+}
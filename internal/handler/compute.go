@@ -0,0 +1,248 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/katharinasick/gcp-api-mock/internal/compute"
+	"github.com/katharinasick/gcp-api-mock/internal/store"
+)
+
+// Compute handles the VPC network endpoints of the Compute Engine API (v1).
+// Any project and region is accepted. Mutations return operations that are already done.
+type Compute struct {
+	store *store.Store
+}
+
+// NewCompute creates a new Compute handler.
+func NewCompute(s *store.Store) *Compute {
+	return &Compute{store: s}
+}
+
+// Compute Engine returns up to 500 results per page, which is also the default.
+const computeMaxResults = 500
+
+// =============================================================================
+// Network Handlers
+// =============================================================================
+
+// ListNetworks handles GET /compute/v1/projects/{project}/global/networks - List networks.
+// Reference: https://cloud.google.com/compute/docs/reference/rest/v1/networks/list
+func (h *Compute) ListNetworks(w http.ResponseWriter, r *http.Request) {
+	maxResults, err := parseMaxResults(r.URL.Query().Get("maxResults"), computeMaxResults, computeMaxResults)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+		return
+	}
+
+	networks, nextPageToken, err := paginate(h.store.ListComputeNetworks(r.PathValue("project")), r.URL.Query().Get("pageToken"), maxResults, func(n *compute.Network) string { return n.Name })
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, &compute.NetworkList{
+		Kind:          "compute#networkList",
+		Items:         networks,
+		NextPageToken: nextPageToken,
+	})
+}
+
+// InsertNetwork handles POST /compute/v1/projects/{project}/global/networks - Create a network.
+// Reference: https://cloud.google.com/compute/docs/reference/rest/v1/networks/insert
+func (h *Compute) InsertNetwork(w http.ResponseWriter, r *http.Request) {
+	var req compute.Network
+	if err := decodeJSON(r.Body, &req, h.store.StrictValidation()); err != nil {
+		respondError(w, http.StatusBadRequest, invalidJSONMessage(err), "invalid")
+		return
+	}
+
+	op, err := h.store.InsertComputeNetwork(r.PathValue("project"), &req)
+	if err != nil {
+		respondComputeStoreError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, op)
+}
+
+// GetNetwork handles GET /compute/v1/projects/{project}/global/networks/{network} - Get a network.
+// Reference: https://cloud.google.com/compute/docs/reference/rest/v1/networks/get
+func (h *Compute) GetNetwork(w http.ResponseWriter, r *http.Request) {
+	network := h.store.GetComputeNetwork(r.PathValue("project"), r.PathValue("network"))
+	if network == nil {
+		respondComputeNotFound(w, "projects/"+r.PathValue("project")+"/global/networks/"+r.PathValue("network"))
+		return
+	}
+
+	respondJSON(w, http.StatusOK, network)
+}
+
+// PatchNetwork handles PATCH /compute/v1/projects/{project}/global/networks/{network} - Update a network.
+// Reference: https://cloud.google.com/compute/docs/reference/rest/v1/networks/patch
+func (h *Compute) PatchNetwork(w http.ResponseWriter, r *http.Request) {
+	var req compute.Network
+	if err := decodeJSON(r.Body, &req, h.store.StrictValidation()); err != nil {
+		respondError(w, http.StatusBadRequest, invalidJSONMessage(err), "invalid")
+		return
+	}
+
+	op, err := h.store.PatchComputeNetwork(r.PathValue("project"), r.PathValue("network"), &req)
+	if err != nil {
+		respondComputeStoreError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, op)
+}
+
+// DeleteNetwork handles DELETE /compute/v1/projects/{project}/global/networks/{network} - Delete a network.
+// Reference: https://cloud.google.com/compute/docs/reference/rest/v1/networks/delete
+func (h *Compute) DeleteNetwork(w http.ResponseWriter, r *http.Request) {
+	op, err := h.store.DeleteComputeNetwork(r.PathValue("project"), r.PathValue("network"))
+	if err != nil {
+		respondComputeStoreError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, op)
+}
+
+// =============================================================================
+// Subnetwork Handlers
+// =============================================================================
+
+// ListSubnetworks handles GET /compute/v1/projects/{project}/regions/{region}/subnetworks - List subnetworks.
+// Reference: https://cloud.google.com/compute/docs/reference/rest/v1/subnetworks/list
+func (h *Compute) ListSubnetworks(w http.ResponseWriter, r *http.Request) {
+	maxResults, err := parseMaxResults(r.URL.Query().Get("maxResults"), computeMaxResults, computeMaxResults)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+		return
+	}
+
+	subnetworks, nextPageToken, err := paginate(h.store.ListComputeSubnetworks(r.PathValue("project"), r.PathValue("region")), r.URL.Query().Get("pageToken"), maxResults, func(s *compute.Subnetwork) string { return s.Name })
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, &compute.SubnetworkList{
+		Kind:          "compute#subnetworkList",
+		Items:         subnetworks,
+		NextPageToken: nextPageToken,
+	})
+}
+
+// InsertSubnetwork handles POST /compute/v1/projects/{project}/regions/{region}/subnetworks - Create a subnetwork.
+// Reference: https://cloud.google.com/compute/docs/reference/rest/v1/subnetworks/insert
+func (h *Compute) InsertSubnetwork(w http.ResponseWriter, r *http.Request) {
+	var req compute.Subnetwork
+	if err := decodeJSON(r.Body, &req, h.store.StrictValidation()); err != nil {
+		respondError(w, http.StatusBadRequest, invalidJSONMessage(err), "invalid")
+		return
+	}
+
+	op, err := h.store.InsertComputeSubnetwork(r.PathValue("project"), r.PathValue("region"), &req)
+	if err != nil {
+		respondComputeStoreError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, op)
+}
+
+// GetSubnetwork handles GET /compute/v1/projects/{project}/regions/{region}/subnetworks/{subnetwork} - Get a subnetwork.
+// Reference: https://cloud.google.com/compute/docs/reference/rest/v1/subnetworks/get
+func (h *Compute) GetSubnetwork(w http.ResponseWriter, r *http.Request) {
+	subnetwork := h.store.GetComputeSubnetwork(r.PathValue("project"), r.PathValue("region"), r.PathValue("subnetwork"))
+	if subnetwork == nil {
+		respondComputeNotFound(w, "projects/"+r.PathValue("project")+"/regions/"+r.PathValue("region")+"/subnetworks/"+r.PathValue("subnetwork"))
+		return
+	}
+
+	respondJSON(w, http.StatusOK, subnetwork)
+}
+
+// PatchSubnetwork handles PATCH /compute/v1/projects/{project}/regions/{region}/subnetworks/{subnetwork} - Update a subnetwork.
+// The request must carry the current fingerprint of the subnetwork.
+// Reference: https://cloud.google.com/compute/docs/reference/rest/v1/subnetworks/patch
+func (h *Compute) PatchSubnetwork(w http.ResponseWriter, r *http.Request) {
+	var req compute.Subnetwork
+	if err := decodeJSON(r.Body, &req, h.store.StrictValidation()); err != nil {
+		respondError(w, http.StatusBadRequest, invalidJSONMessage(err), "invalid")
+		return
+	}
+
+	op, err := h.store.PatchComputeSubnetwork(r.PathValue("project"), r.PathValue("region"), r.PathValue("subnetwork"), &req)
+	if err != nil {
+		respondComputeStoreError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, op)
+}
+
+// DeleteSubnetwork handles DELETE /compute/v1/projects/{project}/regions/{region}/subnetworks/{subnetwork} - Delete a subnetwork.
+// Reference: https://cloud.google.com/compute/docs/reference/rest/v1/subnetworks/delete
+func (h *Compute) DeleteSubnetwork(w http.ResponseWriter, r *http.Request) {
+	op, err := h.store.DeleteComputeSubnetwork(r.PathValue("project"), r.PathValue("region"), r.PathValue("subnetwork"))
+	if err != nil {
+		respondComputeStoreError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, op)
+}
+
+// =============================================================================
+// Operation Handlers
+// =============================================================================
+
+// GetGlobalOperation handles GET /compute/v1/projects/{project}/global/operations/{operation} - Get an operation.
+// POST .../operations/{operation}/wait is served too; operations are done, so it returns right away.
+// Reference: https://cloud.google.com/compute/docs/reference/rest/v1/globalOperations/get
+func (h *Compute) GetGlobalOperation(w http.ResponseWriter, r *http.Request) {
+	h.respondOperation(w, "projects/"+r.PathValue("project")+"/global/operations/"+r.PathValue("operation"))
+}
+
+// GetRegionOperation handles GET /compute/v1/projects/{project}/regions/{region}/operations/{operation} - Get an operation.
+// POST .../operations/{operation}/wait is served too; operations are done, so it returns right away.
+// Reference: https://cloud.google.com/compute/docs/reference/rest/v1/regionOperations/get
+func (h *Compute) GetRegionOperation(w http.ResponseWriter, r *http.Request) {
+	h.respondOperation(w, "projects/"+r.PathValue("project")+"/regions/"+r.PathValue("region")+"/operations/"+r.PathValue("operation"))
+}
+
+// respondOperation writes the operation with a key, or a not found error.
+func (h *Compute) respondOperation(w http.ResponseWriter, key string) {
+	op := h.store.GetComputeOperation(key)
+	if op == nil {
+		respondComputeNotFound(w, key)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, op)
+}
+
+// respondComputeNotFound writes the not found error of Compute Engine for a resource.
+func respondComputeNotFound(w http.ResponseWriter, name string) {
+	respondError(w, http.StatusNotFound, "The resource '"+name+"' was not found", "notFound")
+}
+
+// respondComputeStoreError maps a store error to a Compute Engine API error response.
+func respondComputeStoreError(w http.ResponseWriter, err error) {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		respondError(w, http.StatusNotFound, err.Error(), "notFound")
+	case strings.Contains(err.Error(), "already exists"):
+		respondError(w, http.StatusConflict, err.Error(), "alreadyExists")
+	case strings.Contains(err.Error(), "in use"):
+		respondError(w, http.StatusBadRequest, err.Error(), "resourceInUseByAnotherResource")
+	case strings.Contains(err.Error(), "precondition failed"):
+		respondError(w, http.StatusPreconditionFailed, err.Error(), "conditionNotMet")
+	case strings.Contains(err.Error(), "invalid"):
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+	default:
+		respondError(w, http.StatusInternalServerError, err.Error(), "internalError")
+	}
+}
@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/katharinasick/gcp-api-mock/internal/compute"
+	"github.com/katharinasick/gcp-api-mock/internal/store"
+)
+
+const testComputeProject = "/compute/v1/projects/test-project"
+
+func setupTestCompute() (*Compute, *store.Store) {
+	s := store.New()
+	return NewCompute(s), s
+}
+
+func TestCompute_InsertNetwork(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{"valid", `{"name": "vpc", "autoCreateSubnetworks": false}`, http.StatusOK},
+		{"invalid name", `{"name": "VPC"}`, http.StatusBadRequest},
+		{"invalid body", `{`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, _ := setupTestCompute()
+
+			req := httptest.NewRequest(http.MethodPost, testComputeProject+"/global/networks", strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+			serveRoute("POST /compute/v1/projects/{project}/global/networks", h.InsertNetwork, rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestCompute_InsertNetwork_Operation(t *testing.T) {
+	h, _ := setupTestCompute()
+
+	req := httptest.NewRequest(http.MethodPost, testComputeProject+"/global/networks", strings.NewReader(`{"name": "vpc", "autoCreateSubnetworks": false}`))
+	rr := httptest.NewRecorder()
+	serveRoute("POST /compute/v1/projects/{project}/global/networks", h.InsertNetwork, rr, req)
+
+	var op compute.Operation
+	if err := json.NewDecoder(rr.Body).Decode(&op); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if op.Status != compute.OperationDone {
+		t.Errorf("unexpected operation: %+v", op)
+	}
+
+	// The operation can be polled and waited for
+	req = httptest.NewRequest(http.MethodPost, testComputeProject+"/global/operations/"+op.Name+"/wait", nil)
+	rr = httptest.NewRecorder()
+	serveRoute("POST /compute/v1/projects/{project}/global/operations/{operation}/wait", h.GetGlobalOperation, rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected status 200 for the operation, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, testComputeProject+"/global/networks/vpc", nil)
+	rr = httptest.NewRecorder()
+	serveRoute("GET /compute/v1/projects/{project}/global/networks/{network}", h.GetNetwork, rr, req)
+	var network compute.Network
+	if err := json.NewDecoder(rr.Body).Decode(&network); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if network.Name != "vpc" || network.Kind != "compute#network" || network.Id != op.TargetId {
+		t.Errorf("unexpected network: %+v", network)
+	}
+}
+
+func TestCompute_GetNetwork_NotFound(t *testing.T) {
+	h, _ := setupTestCompute()
+
+	req := httptest.NewRequest(http.MethodGet, testComputeProject+"/global/networks/missing", nil)
+	rr := httptest.NewRecorder()
+	serveRoute("GET /compute/v1/projects/{project}/global/networks/{network}", h.GetNetwork, rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", rr.Code)
+	}
+	if !strings.Contains(rr.Body.String(), `"reason":"notFound"`) {
+		t.Errorf("expected the notFound reason, got %s", rr.Body.String())
+	}
+}
+
+func TestCompute_Subnetworks(t *testing.T) {
+	h, s := setupTestCompute()
+	autoCreate := false
+	if _, err := s.InsertComputeNetwork("test-project", &compute.Network{Name: "vpc", AutoCreateSubnetworks: &autoCreate}); err != nil {
+		t.Fatalf("InsertComputeNetwork() error: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{"valid", `{"name": "apps", "network": "global/networks/vpc", "ipCidrRange": "10.0.0.0/24"}`, http.StatusOK},
+		{"duplicate", `{"name": "apps", "network": "global/networks/vpc", "ipCidrRange": "10.1.0.0/24"}`, http.StatusConflict},
+		{"overlapping", `{"name": "jobs", "network": "global/networks/vpc", "ipCidrRange": "10.0.0.0/16"}`, http.StatusBadRequest},
+		{"missing network", `{"name": "jobs", "network": "global/networks/missing", "ipCidrRange": "10.1.0.0/24"}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, testComputeProject+"/regions/europe-west1/subnetworks", strings.NewReader(tt.body))
+		rr := httptest.NewRecorder()
+		serveRoute("POST /compute/v1/projects/{project}/regions/{region}/subnetworks", h.InsertSubnetwork, rr, req)
+
+		if rr.Code != tt.expectedStatus {
+			t.Errorf("%s: expected status %d, got %d: %s", tt.name, tt.expectedStatus, rr.Code, rr.Body.String())
+		}
+	}
+
+	// A patch with a stale fingerprint fails
+	req := httptest.NewRequest(http.MethodPatch, testComputeProject+"/regions/europe-west1/subnetworks/apps", strings.NewReader(`{"description": "Apps", "fingerprint": "stale"}`))
+	rr := httptest.NewRecorder()
+	serveRoute("PATCH /compute/v1/projects/{project}/regions/{region}/subnetworks/{subnetwork}", h.PatchSubnetwork, rr, req)
+	if rr.Code != http.StatusPreconditionFailed {
+		t.Errorf("expected status 412 for a stale fingerprint, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	// The network can't be deleted while it has subnetworks
+	req = httptest.NewRequest(http.MethodDelete, testComputeProject+"/global/networks/vpc", nil)
+	rr = httptest.NewRecorder()
+	serveRoute("DELETE /compute/v1/projects/{project}/global/networks/{network}", h.DeleteNetwork, rr, req)
+	if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), "resourceInUseByAnotherResource") {
+		t.Errorf("expected status 400 for a network in use, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, testComputeProject+"/regions/europe-west1/subnetworks", nil)
+	rr = httptest.NewRecorder()
+	serveRoute("GET /compute/v1/projects/{project}/regions/{region}/subnetworks", h.ListSubnetworks, rr, req)
+	var list compute.SubnetworkList
+	if err := json.NewDecoder(rr.Body).Decode(&list); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(list.Items) != 1 || list.Items[0].Name != "apps" || list.Items[0].GatewayAddress != "10.0.0.1" {
+		t.Errorf("unexpected subnetworks: %+v", list.Items)
+	}
+}
@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/katharinasick/gcp-api-mock/internal/discovery"
+)
+
+// Discovery handles the Google API Discovery Service endpoints.
+type Discovery struct{}
+
+// NewDiscovery creates a new Discovery handler.
+func NewDiscovery() *Discovery {
+	return &Discovery{}
+}
+
+// ListAPIs handles GET /discovery/v1/apis - List the emulated APIs.
+// Reference: https://developers.google.com/discovery/v1/reference/apis/list
+func (h *Discovery) ListAPIs(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, discovery.Directory(rootURL(r)))
+}
+
+// GetRest handles GET /discovery/v1/apis/{api}/{version}/rest - Get the discovery document of an API,
+// listing the methods the mock implements.
+// Reference: https://developers.google.com/discovery/v1/reference/apis/getRest
+func (h *Discovery) GetRest(w http.ResponseWriter, r *http.Request) {
+	doc := discovery.Document(r.PathValue("api"), r.PathValue("version"), rootURL(r))
+	if doc == nil {
+		respondError(w, http.StatusNotFound, "Requested entity was not found.", "notFound")
+		return
+	}
+	respondJSON(w, http.StatusOK, doc)
+}
+
+// rootURL returns the URL the client reached the mock at, ending with a slash.
+// Discovery-based clients send requests relative to it, so they keep talking to the mock.
+func rootURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + "/"
+}
@@ -0,0 +1,28 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/katharinasick/gcp-api-mock/internal/gcperror"
+)
+
+// respondStatusError writes a JSON error response in the plain google.rpc.Status format of the newer APIs,
+// like Cloud Run, Firestore or Eventarc, which have a status like NOT_FOUND but no error reasons.
+func respondStatusError(w http.ResponseWriter, statusCode int, message, status string) {
+	gcperror.New(statusCode, message, "").WithStatus(status).Write(w)
+}
+
+// respondStatusStoreError maps a store error to a google.rpc.Status error response.
+func respondStatusStoreError(w http.ResponseWriter, err error) {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		respondStatusError(w, http.StatusNotFound, err.Error(), "NOT_FOUND")
+	case strings.Contains(err.Error(), "already exists"):
+		respondStatusError(w, http.StatusConflict, err.Error(), "ALREADY_EXISTS")
+	case strings.Contains(err.Error(), "invalid"):
+		respondStatusError(w, http.StatusBadRequest, err.Error(), "INVALID_ARGUMENT")
+	default:
+		respondStatusError(w, http.StatusInternalServerError, err.Error(), "INTERNAL")
+	}
+}
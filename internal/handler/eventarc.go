@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/katharinasick/gcp-api-mock/internal/eventarc"
+	"github.com/katharinasick/gcp-api-mock/internal/store"
+)
+
+// Eventarc handles Eventarc API (v1) endpoints.
+// Any project and location is accepted. Mutations return long-running operations that are already done;
+// they are served by LocationOperations.
+type Eventarc struct {
+	store *store.Store
+}
+
+// NewEventarc creates a new Eventarc handler.
+func NewEventarc(s *store.Store) *Eventarc {
+	return &Eventarc{store: s}
+}
+
+// eventarcDefaultPageSize is the default page size for Eventarc list calls.
+const eventarcDefaultPageSize = 100
+
+// ListTriggers handles GET /v1/projects/{project}/locations/{location}/triggers - List triggers.
+// Reference: https://cloud.google.com/eventarc/docs/reference/rest/v1/projects.locations.triggers/list
+func (h *Eventarc) ListTriggers(w http.ResponseWriter, r *http.Request) {
+	pageSize := eventarcDefaultPageSize
+	if value := r.URL.Query().Get("pageSize"); value != "" {
+		size, err := strconv.Atoi(value)
+		if err != nil || size < 1 {
+			respondStatusError(w, http.StatusBadRequest, "Invalid pageSize: "+value, "INVALID_ARGUMENT")
+			return
+		}
+		pageSize = size
+	}
+
+	triggers, nextPageToken, err := paginate(h.store.ListEventarcTriggers(eventarcParent(r)), r.URL.Query().Get("pageToken"), pageSize, func(t *eventarc.Trigger) string { return t.Name })
+	if err != nil {
+		respondStatusError(w, http.StatusBadRequest, err.Error(), "INVALID_ARGUMENT")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, &eventarc.ListTriggersResponse{
+		Triggers:      triggers,
+		NextPageToken: nextPageToken,
+	})
+}
+
+// CreateTrigger handles POST /v1/projects/{project}/locations/{location}/triggers?triggerId={id} - Create a trigger.
+// Reference: https://cloud.google.com/eventarc/docs/reference/rest/v1/projects.locations.triggers/create
+func (h *Eventarc) CreateTrigger(w http.ResponseWriter, r *http.Request) {
+	var req eventarc.Trigger
+	if err := decodeJSON(r.Body, &req, h.store.StrictValidation()); err != nil {
+		respondStatusError(w, http.StatusBadRequest, invalidJSONMessage(err), "INVALID_ARGUMENT")
+		return
+	}
+
+	op, err := h.store.CreateEventarcTrigger(eventarcParent(r), r.URL.Query().Get("triggerId"), &req)
+	if err != nil {
+		respondStatusStoreError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, op)
+}
+
+// GetTrigger handles GET /v1/projects/{project}/locations/{location}/triggers/{trigger} - Get a trigger.
+// Reference: https://cloud.google.com/eventarc/docs/reference/rest/v1/projects.locations.triggers/get
+func (h *Eventarc) GetTrigger(w http.ResponseWriter, r *http.Request) {
+	name := eventarcTriggerName(r)
+
+	trigger := h.store.GetEventarcTrigger(name)
+	if trigger == nil {
+		respondStatusError(w, http.StatusNotFound, "Trigger not found: "+name, "NOT_FOUND")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, trigger)
+}
+
+// UpdateTrigger handles PATCH /v1/projects/{project}/locations/{location}/triggers/{trigger}?updateMask={fields} - Update a trigger.
+// Reference: https://cloud.google.com/eventarc/docs/reference/rest/v1/projects.locations.triggers/patch
+func (h *Eventarc) UpdateTrigger(w http.ResponseWriter, r *http.Request) {
+	var req eventarc.Trigger
+	if err := decodeJSON(r.Body, &req, h.store.StrictValidation()); err != nil {
+		respondStatusError(w, http.StatusBadRequest, invalidJSONMessage(err), "INVALID_ARGUMENT")
+		return
+	}
+
+	var updateMask []string
+	if value := r.URL.Query().Get("updateMask"); value != "" {
+		updateMask = strings.Split(value, ",")
+	}
+
+	op, err := h.store.UpdateEventarcTrigger(eventarcTriggerName(r), &req, updateMask)
+	if err != nil {
+		respondStatusStoreError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, op)
+}
+
+// DeleteTrigger handles DELETE /v1/projects/{project}/locations/{location}/triggers/{trigger} - Delete a trigger.
+// Reference: https://cloud.google.com/eventarc/docs/reference/rest/v1/projects.locations.triggers/delete
+func (h *Eventarc) DeleteTrigger(w http.ResponseWriter, r *http.Request) {
+	op, err := h.store.DeleteEventarcTrigger(eventarcTriggerName(r))
+	if err != nil {
+		respondStatusStoreError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, op)
+}
+
+// eventarcParent returns the parent (projects/{project}/locations/{location}) named by the path of a request.
+func eventarcParent(r *http.Request) string {
+	return "projects/" + r.PathValue("project") + "/locations/" + r.PathValue("location")
+}
+
+// eventarcTriggerName returns the trigger name (projects/{project}/locations/{location}/triggers/{trigger})
+// named by the path of a request.
+func eventarcTriggerName(r *http.Request) string {
+	return eventarcParent(r) + "/triggers/" + r.PathValue("trigger")
+}
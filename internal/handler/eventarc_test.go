@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/katharinasick/gcp-api-mock/internal/eventarc"
+	"github.com/katharinasick/gcp-api-mock/internal/store"
+)
+
+const testEventarcLocation = "/v1/projects/test-project/locations/europe-west1"
+
+const testEventarcTrigger = `{
+	"eventFilters": [
+		{"attribute": "type", "value": "google.cloud.storage.object.v1.finalized"},
+		{"attribute": "bucket", "value": "uploads"}
+	],
+	"destination": {"httpEndpoint": {"uri": "http://localhost:9090/events"}}
+}`
+
+func setupTestEventarc() (*Eventarc, *store.Store) {
+	s := store.New()
+	return NewEventarc(s), s
+}
+
+func TestEventarc_CreateTrigger(t *testing.T) {
+	tests := []struct {
+		name           string
+		path           string
+		body           string
+		expectedStatus int
+	}{
+		{"valid", testEventarcLocation + "/triggers?triggerId=uploads", testEventarcTrigger, http.StatusOK},
+		{"missing triggerId", testEventarcLocation + "/triggers", testEventarcTrigger, http.StatusBadRequest},
+		{"no filters", testEventarcLocation + "/triggers?triggerId=uploads", `{"destination": {"workflow": "w"}}`, http.StatusBadRequest},
+		{"invalid body", testEventarcLocation + "/triggers?triggerId=uploads", `{`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, _ := setupTestEventarc()
+
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+			serveRoute("POST /v1/projects/{project}/locations/{location}/triggers", h.CreateTrigger, rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestEventarc_CreateTrigger_Operation(t *testing.T) {
+	h, _ := setupTestEventarc()
+
+	req := httptest.NewRequest(http.MethodPost, testEventarcLocation+"/triggers?triggerId=uploads", strings.NewReader(testEventarcTrigger))
+	rr := httptest.NewRecorder()
+	serveRoute("POST /v1/projects/{project}/locations/{location}/triggers", h.CreateTrigger, rr, req)
+
+	var op eventarc.Operation
+	if err := json.NewDecoder(rr.Body).Decode(&op); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !op.Done || !strings.HasPrefix(op.Name, "projects/test-project/locations/europe-west1/operations/") {
+		t.Errorf("unexpected operation: %+v", op)
+	}
+
+	// The operation can be polled
+	req = httptest.NewRequest(http.MethodGet, "/v1/"+op.Name, nil)
+	rr = httptest.NewRecorder()
+	serveRoute("GET /v1/projects/{project}/locations/{location}/operations/{operation}", NewLocationOperations(h.store).GetOperation, rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected status 200 for the operation, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	// The trigger exists
+	req = httptest.NewRequest(http.MethodGet, testEventarcLocation+"/triggers/uploads", nil)
+	rr = httptest.NewRecorder()
+	serveRoute("GET /v1/projects/{project}/locations/{location}/triggers/{trigger}", h.GetTrigger, rr, req)
+	var trigger eventarc.Trigger
+	if err := json.NewDecoder(rr.Body).Decode(&trigger); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if trigger.Name != "projects/test-project/locations/europe-west1/triggers/uploads" || len(trigger.EventFilters) != 2 {
+		t.Errorf("unexpected trigger: %+v", trigger)
+	}
+}
@@ -0,0 +1,284 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/katharinasick/gcp-api-mock/internal/firestore"
+	"github.com/katharinasick/gcp-api-mock/internal/store"
+)
+
+// Firestore handles Firestore API document endpoints.
+// Any project and database ID is accepted; each combination is a separate set of documents.
+type Firestore struct {
+	store *store.Store
+}
+
+// NewFirestore creates a new Firestore handler.
+func NewFirestore(s *store.Store) *Firestore {
+	return &Firestore{store: s}
+}
+
+// firestoreDefaultPageSize is the default page size for listing documents.
+const firestoreDefaultPageSize = 300
+
+// GetDocument handles GET /v1/projects/{project}/databases/{database}/documents/{path} - Get a document,
+// or list the documents of a collection if the path names a collection.
+// Reference: https://cloud.google.com/firestore/docs/reference/rest/v1/projects.databases.documents/get
+func (h *Firestore) GetDocument(w http.ResponseWriter, r *http.Request) {
+	root, rest := firestoreDocumentsRoot(r), r.PathValue("path")
+
+	segments, ok := splitDocumentPath(rest)
+	if !ok {
+		respondStatusError(w, http.StatusBadRequest, "Invalid document path: "+rest, "INVALID_ARGUMENT")
+		return
+	}
+
+	// An odd number of segments names a collection
+	if len(segments)%2 == 1 {
+		h.listDocuments(w, r, root, segments)
+		return
+	}
+
+	name := root + "/" + rest
+	doc := h.store.GetDocument(name)
+	if doc == nil {
+		respondStatusError(w, http.StatusNotFound, "Document \""+name+"\" not found.", "NOT_FOUND")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, doc)
+}
+
+// listDocuments lists the documents of a collection.
+// Reference: https://cloud.google.com/firestore/docs/reference/rest/v1/projects.databases.documents/list
+func (h *Firestore) listDocuments(w http.ResponseWriter, r *http.Request, root string, segments []string) {
+	pageSize := firestoreDefaultPageSize
+	if value := r.URL.Query().Get("pageSize"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			respondStatusError(w, http.StatusBadRequest, "Invalid pageSize: "+value, "INVALID_ARGUMENT")
+			return
+		}
+		pageSize = parsed
+	}
+
+	parent := strings.Join(append([]string{root}, segments[:len(segments)-1]...), "/")
+	collectionID := segments[len(segments)-1]
+
+	docs, nextPageToken, err := paginate(h.store.ListDocuments(parent, collectionID), r.URL.Query().Get("pageToken"), pageSize, func(d *firestore.Document) string { return d.Name })
+	if err != nil {
+		respondStatusError(w, http.StatusBadRequest, err.Error(), "INVALID_ARGUMENT")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, &firestore.ListDocumentsResponse{
+		Documents:     docs,
+		NextPageToken: nextPageToken,
+	})
+}
+
+// DocumentAction handles POST /v1/projects/{project}/databases/{database}/documents/{path}.
+// Paths ending in :runQuery run a query below a document, all others create a document in a collection.
+func (h *Firestore) DocumentAction(w http.ResponseWriter, r *http.Request) {
+	if strings.HasSuffix(r.PathValue("path"), ":runQuery") {
+		h.RunQuery(w, r)
+		return
+	}
+	h.CreateDocument(w, r)
+}
+
+// CreateDocument handles POST /v1/projects/{project}/databases/{database}/documents/{parent}/{collectionId} - Create a document.
+// Reference: https://cloud.google.com/firestore/docs/reference/rest/v1/projects.databases.documents/createDocument
+func (h *Firestore) CreateDocument(w http.ResponseWriter, r *http.Request) {
+	root, rest := firestoreDocumentsRoot(r), r.PathValue("path")
+
+	segments, ok := splitDocumentPath(rest)
+	if !ok || len(segments)%2 == 0 {
+		respondStatusError(w, http.StatusBadRequest, "Invalid collection path: "+rest, "INVALID_ARGUMENT")
+		return
+	}
+
+	var req firestore.Document
+	if err := decodeJSON(r.Body, &req, h.store.StrictValidation()); err != nil {
+		respondStatusError(w, http.StatusBadRequest, invalidJSONMessage(err), "INVALID_ARGUMENT")
+		return
+	}
+
+	documentID := r.URL.Query().Get("documentId")
+	if strings.Contains(documentID, "/") {
+		respondStatusError(w, http.StatusBadRequest, "Invalid documentId: "+documentID, "INVALID_ARGUMENT")
+		return
+	}
+
+	parent := strings.Join(append([]string{root}, segments[:len(segments)-1]...), "/")
+	doc, err := h.store.CreateDocument(parent, segments[len(segments)-1], documentID, req.Fields)
+	if err != nil {
+		if strings.Contains(err.Error(), "already exists") {
+			respondStatusError(w, http.StatusConflict, "Document already exists: "+root+"/"+rest+"/"+documentID, "ALREADY_EXISTS")
+			return
+		}
+		respondStatusError(w, http.StatusInternalServerError, err.Error(), "INTERNAL")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, doc)
+}
+
+// UpdateDocument handles PATCH /v1/projects/{project}/databases/{database}/documents/{path} - Update or create a document.
+// Supports the updateMask.fieldPaths and currentDocument.exists query parameters.
+// Reference: https://cloud.google.com/firestore/docs/reference/rest/v1/projects.databases.documents/patch
+func (h *Firestore) UpdateDocument(w http.ResponseWriter, r *http.Request) {
+	root, rest := firestoreDocumentsRoot(r), r.PathValue("path")
+
+	segments, ok := splitDocumentPath(rest)
+	if !ok || len(segments)%2 == 1 {
+		respondStatusError(w, http.StatusBadRequest, "Invalid document path: "+rest, "INVALID_ARGUMENT")
+		return
+	}
+
+	var req firestore.Document
+	if err := decodeJSON(r.Body, &req, h.store.StrictValidation()); err != nil {
+		respondStatusError(w, http.StatusBadRequest, invalidJSONMessage(err), "INVALID_ARGUMENT")
+		return
+	}
+
+	exists, err := parseCurrentDocumentExists(r)
+	if err != nil {
+		respondStatusError(w, http.StatusBadRequest, err.Error(), "INVALID_ARGUMENT")
+		return
+	}
+
+	name := root + "/" + rest
+	doc, err := h.store.UpdateDocument(name, req.Fields, r.URL.Query()["updateMask.fieldPaths"], exists)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondStatusError(w, http.StatusNotFound, "No document to update: "+name, "NOT_FOUND")
+			return
+		}
+		if strings.Contains(err.Error(), "already exists") {
+			respondStatusError(w, http.StatusConflict, "Document already exists: "+name, "ALREADY_EXISTS")
+			return
+		}
+		respondStatusError(w, http.StatusInternalServerError, err.Error(), "INTERNAL")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, doc)
+}
+
+// DeleteDocument handles DELETE /v1/projects/{project}/databases/{database}/documents/{path} - Delete a document.
+// Like in Firestore, deleting a missing document succeeds unless currentDocument.exists=true is set.
+// Reference: https://cloud.google.com/firestore/docs/reference/rest/v1/projects.databases.documents/delete
+func (h *Firestore) DeleteDocument(w http.ResponseWriter, r *http.Request) {
+	root, rest := firestoreDocumentsRoot(r), r.PathValue("path")
+
+	segments, ok := splitDocumentPath(rest)
+	if !ok || len(segments)%2 == 1 {
+		respondStatusError(w, http.StatusBadRequest, "Invalid document path: "+rest, "INVALID_ARGUMENT")
+		return
+	}
+
+	exists, err := parseCurrentDocumentExists(r)
+	if err != nil {
+		respondStatusError(w, http.StatusBadRequest, err.Error(), "INVALID_ARGUMENT")
+		return
+	}
+
+	name := root + "/" + rest
+	if err := h.store.DeleteDocument(name); err != nil {
+		if !strings.Contains(err.Error(), "not found") {
+			respondStatusError(w, http.StatusInternalServerError, err.Error(), "INTERNAL")
+			return
+		}
+		if exists != nil && *exists {
+			respondStatusError(w, http.StatusNotFound, "No document to delete: "+name, "NOT_FOUND")
+			return
+		}
+	}
+
+	respondJSON(w, http.StatusOK, struct{}{})
+}
+
+// RunQuery handles POST /v1/projects/{project}/databases/{database}/documents[/{path}]:runQuery - Run a query.
+// The response is a JSON array with one element per result, like the streamed REST response.
+// Reference: https://cloud.google.com/firestore/docs/reference/rest/v1/projects.databases.documents/runQuery
+func (h *Firestore) RunQuery(w http.ResponseWriter, r *http.Request) {
+	root, rest := firestoreDocumentsRoot(r), strings.TrimSuffix(r.PathValue("path"), ":runQuery")
+
+	parent := root
+	if rest != "" {
+		segments, ok := splitDocumentPath(rest)
+		if !ok || len(segments)%2 == 1 {
+			respondStatusError(w, http.StatusBadRequest, "Invalid parent path: "+rest, "INVALID_ARGUMENT")
+			return
+		}
+		parent = root + "/" + rest
+	}
+
+	var req firestore.RunQueryRequest
+	if err := decodeJSON(r.Body, &req, h.store.StrictValidation()); err != nil {
+		respondStatusError(w, http.StatusBadRequest, invalidJSONMessage(err), "INVALID_ARGUMENT")
+		return
+	}
+
+	if req.StructuredQuery == nil {
+		respondStatusError(w, http.StatusBadRequest, "structuredQuery is required", "INVALID_ARGUMENT")
+		return
+	}
+
+	docs, err := h.store.RunQuery(parent, req.StructuredQuery)
+	if err != nil {
+		respondStatusError(w, http.StatusBadRequest, err.Error(), "INVALID_ARGUMENT")
+		return
+	}
+
+	readTime := h.store.Now()
+	response := []firestore.RunQueryResponse{}
+	for _, doc := range docs {
+		response = append(response, firestore.RunQueryResponse{Document: doc, ReadTime: readTime})
+	}
+	if len(response) == 0 {
+		response = append(response, firestore.RunQueryResponse{ReadTime: readTime})
+	}
+
+	respondJSON(w, http.StatusOK, response)
+}
+
+// firestoreDocumentsRoot returns the documents root (projects/{project}/databases/{database}/documents)
+// named by the path of a request.
+func firestoreDocumentsRoot(r *http.Request) string {
+	return "projects/" + r.PathValue("project") + "/databases/" + r.PathValue("database") + "/documents"
+}
+
+// splitDocumentPath splits a document or collection path into its segments.
+// Returns false if the path is empty or contains empty segments.
+func splitDocumentPath(path string) ([]string, bool) {
+	if path == "" {
+		return nil, false
+	}
+
+	segments := strings.Split(path, "/")
+	for _, segment := range segments {
+		if segment == "" {
+			return nil, false
+		}
+	}
+	return segments, true
+}
+
+// parseCurrentDocumentExists parses the currentDocument.exists precondition.
+func parseCurrentDocumentExists(r *http.Request) (*bool, error) {
+	value := r.URL.Query().Get("currentDocument.exists")
+	if value == "" {
+		return nil, nil
+	}
+
+	exists, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid currentDocument.exists: %s", value)
+	}
+	return &exists, nil
+}
@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/katharinasick/gcp-api-mock/internal/firestore"
+	"github.com/katharinasick/gcp-api-mock/internal/store"
+)
+
+const testDocumentsPath = "/v1/projects/test-project/databases/(default)/documents"
+
+func setupTestFirestore() (*Firestore, *store.Store) {
+	s := store.New()
+	return NewFirestore(s), s
+}
+
+func TestFirestore_DocumentCRUD(t *testing.T) {
+	h, _ := setupTestFirestore()
+
+	// Create
+	body := `{"fields":{"name":{"stringValue":"Alice"},"age":{"integerValue":"30"}}}`
+	req := httptest.NewRequest(http.MethodPost, testDocumentsPath+"/users?documentId=alice", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	serveRoute("POST /v1/projects/{project}/databases/{database}/documents/{path...}", h.DocumentAction, rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	// Create again conflicts
+	req = httptest.NewRequest(http.MethodPost, testDocumentsPath+"/users?documentId=alice", strings.NewReader(body))
+	rr = httptest.NewRecorder()
+	serveRoute("POST /v1/projects/{project}/databases/{database}/documents/{path...}", h.DocumentAction, rr, req)
+	if rr.Code != http.StatusConflict {
+		t.Errorf("expected status %d, got %d", http.StatusConflict, rr.Code)
+	}
+
+	// Get
+	req = httptest.NewRequest(http.MethodGet, testDocumentsPath+"/users/alice", nil)
+	rr = httptest.NewRecorder()
+	serveRoute("GET /v1/projects/{project}/databases/{database}/documents/{path...}", h.GetDocument, rr, req)
+	var doc firestore.Document
+	if err := json.NewDecoder(rr.Body).Decode(&doc); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if doc.Fields["name"] == nil || *doc.Fields["name"].StringValue != "Alice" {
+		t.Errorf("unexpected document: %+v", doc)
+	}
+
+	// Patch with a mask
+	req = httptest.NewRequest(http.MethodPatch, testDocumentsPath+"/users/alice?updateMask.fieldPaths=age",
+		strings.NewReader(`{"fields":{"age":{"integerValue":"31"}}}`))
+	rr = httptest.NewRecorder()
+	serveRoute("PATCH /v1/projects/{project}/databases/{database}/documents/{path...}", h.UpdateDocument, rr, req)
+	if err := json.NewDecoder(rr.Body).Decode(&doc); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if *doc.Fields["age"].IntegerValue != "31" || doc.Fields["name"] == nil {
+		t.Errorf("unexpected document after patch: %+v", doc.Fields)
+	}
+
+	// List the collection
+	req = httptest.NewRequest(http.MethodGet, testDocumentsPath+"/users", nil)
+	rr = httptest.NewRecorder()
+	serveRoute("GET /v1/projects/{project}/databases/{database}/documents/{path...}", h.GetDocument, rr, req)
+	var list firestore.ListDocumentsResponse
+	if err := json.NewDecoder(rr.Body).Decode(&list); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(list.Documents) != 1 {
+		t.Errorf("expected 1 document, got %d", len(list.Documents))
+	}
+
+	// Delete, then get returns 404
+	req = httptest.NewRequest(http.MethodDelete, testDocumentsPath+"/users/alice", nil)
+	rr = httptest.NewRecorder()
+	serveRoute("DELETE /v1/projects/{project}/databases/{database}/documents/{path...}", h.DeleteDocument, rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, testDocumentsPath+"/users/alice", nil)
+	rr = httptest.NewRecorder()
+	serveRoute("GET /v1/projects/{project}/databases/{database}/documents/{path...}", h.GetDocument, rr, req)
+	var errResp firestore.APIError
+	if err := json.NewDecoder(rr.Body).Decode(&errResp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if rr.Code != http.StatusNotFound || errResp.Error.Status != "NOT_FOUND" {
+		t.Errorf("expected 404 NOT_FOUND, got %d %s", rr.Code, errResp.Error.Status)
+	}
+}
+
+func TestFirestore_RunQuery(t *testing.T) {
+	h, s := setupTestFirestore()
+
+	root := strings.TrimPrefix(testDocumentsPath, "/v1/")
+	for _, city := range []string{"Vienna", "Berlin", "Vienna"} {
+		s.CreateDocument(root, "users", "", map[string]*firestore.Value{"city": {StringValue: &city}})
+	}
+
+	tests := []struct {
+		name          string
+		query         string
+		expectedCount int
+		expectedDocs  bool
+	}{
+		{
+			name:          "equality filter",
+			query:         `{"structuredQuery":{"from":[{"collectionId":"users"}],"where":{"fieldFilter":{"field":{"fieldPath":"city"},"op":"EQUAL","value":{"stringValue":"Vienna"}}}}}`,
+			expectedCount: 2,
+			expectedDocs:  true,
+		},
+		{
+			name:          "limit",
+			query:         `{"structuredQuery":{"from":[{"collectionId":"users"}],"orderBy":[{"field":{"fieldPath":"city"}}],"limit":1}}`,
+			expectedCount: 1,
+			expectedDocs:  true,
+		},
+		{
+			name:          "no results",
+			query:         `{"structuredQuery":{"from":[{"collectionId":"orders"}]}}`,
+			expectedCount: 1,
+			expectedDocs:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, testDocumentsPath+":runQuery", strings.NewReader(tt.query))
+			rr := httptest.NewRecorder()
+			serveRoute("POST /v1/projects/{project}/databases/{database}/documents:runQuery", h.RunQuery, rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+			}
+
+			var results []firestore.RunQueryResponse
+			if err := json.NewDecoder(rr.Body).Decode(&results); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if len(results) != tt.expectedCount {
+				t.Fatalf("expected %d results, got %d", tt.expectedCount, len(results))
+			}
+			if (results[0].Document != nil) != tt.expectedDocs {
+				t.Errorf("expected documents=%v, got %+v", tt.expectedDocs, results[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/katharinasick/gcp-api-mock/internal/gke"
+	"github.com/katharinasick/gcp-api-mock/internal/store"
+)
+
+// GKE handles Google Kubernetes Engine API (v1) endpoints.
+// Any project and location is accepted. Mutations return operations that are already done;
+// they are served by LocationOperations.
+type GKE struct {
+	store *store.Store
+}
+
+// NewGKE creates a new GKE handler.
+func NewGKE(s *store.Store) *GKE {
+	return &GKE{store: s}
+}
+
+// ListClusters handles GET /v1/projects/{project}/locations/{location}/clusters - List clusters.
+// The location "-" lists the clusters of all locations.
+// Reference: https://cloud.google.com/kubernetes-engine/docs/reference/rest/v1/projects.locations.clusters/list
+func (h *GKE) ListClusters(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, &gke.ListClustersResponse{
+		Clusters: h.store.ListGKEClusters(gkeParent(r)),
+	})
+}
+
+// CreateCluster handles POST /v1/projects/{project}/locations/{location}/clusters - Create a cluster.
+// Reference: https://cloud.google.com/kubernetes-engine/docs/reference/rest/v1/projects.locations.clusters/create
+func (h *GKE) CreateCluster(w http.ResponseWriter, r *http.Request) {
+	var req gke.CreateClusterRequest
+	if err := decodeJSON(r.Body, &req, h.store.StrictValidation()); err != nil {
+		respondStatusError(w, http.StatusBadRequest, invalidJSONMessage(err), "INVALID_ARGUMENT")
+		return
+	}
+
+	op, err := h.store.CreateGKECluster(gkeParent(r), req.Cluster)
+	if err != nil {
+		respondStatusStoreError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, op)
+}
+
+// GetCluster handles GET /v1/projects/{project}/locations/{location}/clusters/{cluster} - Get a cluster.
+// Reference: https://cloud.google.com/kubernetes-engine/docs/reference/rest/v1/projects.locations.clusters/get
+func (h *GKE) GetCluster(w http.ResponseWriter, r *http.Request) {
+	name := gkeClusterName(r)
+
+	cluster := h.store.GetGKECluster(name)
+	if cluster == nil {
+		respondStatusError(w, http.StatusNotFound, "Not found: "+name+".", "NOT_FOUND")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, cluster)
+}
+
+// DeleteCluster handles DELETE /v1/projects/{project}/locations/{location}/clusters/{cluster} - Delete a cluster.
+// Reference: https://cloud.google.com/kubernetes-engine/docs/reference/rest/v1/projects.locations.clusters/delete
+func (h *GKE) DeleteCluster(w http.ResponseWriter, r *http.Request) {
+	op, err := h.store.DeleteGKECluster(gkeClusterName(r))
+	if err != nil {
+		respondStatusStoreError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, op)
+}
+
+// GetKubeconfig handles GET /admin/gke/projects/{project}/locations/{location}/clusters/{cluster}/kubeconfig.
+// It returns a kubeconfig file connecting to the kind cluster configured with GCP_MOCK_GKE_KUBECONFIG,
+// with a context named like `gcloud container clusters get-credentials` names it.
+func (h *GKE) GetKubeconfig(w http.ResponseWriter, r *http.Request) {
+	kubeconfig, err := h.store.GKEKubeconfig(gkeClusterName(r))
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondStatusError(w, http.StatusNotFound, err.Error(), "NOT_FOUND")
+		} else {
+			respondStatusError(w, http.StatusBadRequest, err.Error(), "FAILED_PRECONDITION")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Write(kubeconfig)
+}
+
+// gkeParent returns the parent (projects/{project}/locations/{location}) named by the path of a request.
+func gkeParent(r *http.Request) string {
+	return "projects/" + r.PathValue("project") + "/locations/" + r.PathValue("location")
+}
+
+// gkeClusterName returns the cluster name (projects/{project}/locations/{location}/clusters/{cluster})
+// named by the path of a request.
+func gkeClusterName(r *http.Request) string {
+	return gkeParent(r) + "/clusters/" + r.PathValue("cluster")
+}
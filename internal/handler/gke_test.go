@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/katharinasick/gcp-api-mock/internal/gke"
+	"github.com/katharinasick/gcp-api-mock/internal/store"
+)
+
+const testGKELocation = "/v1/projects/test-project/locations/europe-west1"
+
+func setupTestGKE() (*GKE, *store.Store) {
+	s := store.New()
+	return NewGKE(s), s
+}
+
+func TestGKE_CreateCluster(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{"valid", `{"cluster": {"name": "platform", "initialNodeCount": 1}}`, http.StatusOK},
+		{"missing cluster", `{}`, http.StatusBadRequest},
+		{"no nodes", `{"cluster": {"name": "platform"}}`, http.StatusBadRequest},
+		{"invalid body", `{`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, _ := setupTestGKE()
+
+			req := httptest.NewRequest(http.MethodPost, testGKELocation+"/clusters", strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+			serveRoute("POST /v1/projects/{project}/locations/{location}/clusters", h.CreateCluster, rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestGKE_CreateCluster_Operation(t *testing.T) {
+	h, s := setupTestGKE()
+
+	req := httptest.NewRequest(http.MethodPost, testGKELocation+"/clusters", strings.NewReader(`{"cluster": {"name": "platform", "initialNodeCount": 1}}`))
+	rr := httptest.NewRecorder()
+	serveRoute("POST /v1/projects/{project}/locations/{location}/clusters", h.CreateCluster, rr, req)
+
+	var op gke.Operation
+	if err := json.NewDecoder(rr.Body).Decode(&op); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if op.Status != gke.OperationDone || !strings.HasPrefix(op.Name, "operation-") {
+		t.Errorf("unexpected operation: %+v", op)
+	}
+
+	// The operation can be polled by its name in the location
+	req = httptest.NewRequest(http.MethodGet, testGKELocation+"/operations/"+op.Name, nil)
+	rr = httptest.NewRecorder()
+	serveRoute("GET /v1/projects/{project}/locations/{location}/operations/{operation}", NewLocationOperations(s).GetOperation, rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected status 200 for the operation, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	// The cluster exists
+	req = httptest.NewRequest(http.MethodGet, testGKELocation+"/clusters/platform", nil)
+	rr = httptest.NewRecorder()
+	serveRoute("GET /v1/projects/{project}/locations/{location}/clusters/{cluster}", h.GetCluster, rr, req)
+	var cluster gke.Cluster
+	if err := json.NewDecoder(rr.Body).Decode(&cluster); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if cluster.Name != "platform" || cluster.Status != gke.StatusRunning {
+		t.Errorf("unexpected cluster: %+v", cluster)
+	}
+}
+
+func TestGKE_GetKubeconfig(t *testing.T) {
+	h, s := setupTestGKE()
+	if _, err := s.CreateGKECluster("projects/test-project/locations/europe-west1", &gke.Cluster{Name: "platform", InitialNodeCount: 1}); err != nil {
+		t.Fatalf("CreateGKECluster() error: %v", err)
+	}
+
+	pattern := "GET /admin/gke/projects/{project}/locations/{location}/clusters/{cluster}/kubeconfig"
+	path := "/admin/gke/projects/test-project/locations/europe-west1/clusters/platform/kubeconfig"
+
+	// Without a kubeconfig there is nothing to connect to
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	rr := httptest.NewRecorder()
+	serveRoute(pattern, h.GetKubeconfig, rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 without a kubeconfig, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	s.SetGKEKubeconfig(&gke.Kubeconfig{Server: "https://127.0.0.1:44321", Token: "secret"})
+	req = httptest.NewRequest(http.MethodGet, path, nil)
+	rr = httptest.NewRecorder()
+	serveRoute(pattern, h.GetKubeconfig, rr, req)
+	if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "application/yaml" {
+		t.Fatalf("expected a YAML kubeconfig, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if kubeconfig, err := gke.ParseKubeconfig(rr.Body.Bytes()); err != nil || kubeconfig.Server != "https://127.0.0.1:44321" || kubeconfig.Token != "secret" {
+		t.Errorf("unexpected kubeconfig %+v (%v):\n%s", kubeconfig, err, rr.Body.String())
+	}
+}
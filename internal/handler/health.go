@@ -1,32 +1,68 @@
-// Package handler provides HTTP handlers for the GCP API Mock.
 package handler
 
 import (
 	"encoding/json"
 	"net/http"
+
+	"github.com/katharinasick/gcp-api-mock/internal/version"
 )
 
+// ReadinessCheck verifies a dependency the mock needs to serve requests, like the blob directory.
+type ReadinessCheck struct {
+	// Name identifies the check in the /ready response.
+	Name string
+	// Check returns an error if the dependency isn't usable.
+	Check func() error
+}
+
 // Health handles health check endpoints.
-type Health struct{}
+type Health struct {
+	checks []ReadinessCheck
+}
 
-// NewHealth creates a new Health handler.
-func NewHealth() *Health {
-	return &Health{}
+// NewHealth creates a new Health handler. The mock is only ready if all checks pass.
+func NewHealth(checks ...ReadinessCheck) *Health {
+	return &Health{checks: checks}
 }
 
 // HealthResponse represents the health check response.
 type HealthResponse struct {
 	Status string `json:"status"`
+	// Checks maps the readiness checks to "ok" or the error of a failed check.
+	Checks map[string]string `json:"checks,omitempty"`
 }
 
 // Check handles the /health endpoint for liveness probes.
+// It only tells that the process serves requests, so a failing dependency doesn't get the mock restarted.
 func (h *Health) Check(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, HealthResponse{Status: "ok"})
 }
 
 // Ready handles the /ready endpoint for readiness probes.
+// Returns 503 with the failed checks if a dependency isn't usable, e.g. the blob directory isn't writable.
 func (h *Health) Ready(w http.ResponseWriter, r *http.Request) {
-	respondJSON(w, http.StatusOK, HealthResponse{Status: "ready"})
+	resp := HealthResponse{Status: "ready"}
+	status := http.StatusOK
+
+	if len(h.checks) > 0 {
+		resp.Checks = make(map[string]string, len(h.checks))
+	}
+	for _, check := range h.checks {
+		if err := check.Check(); err != nil {
+			resp.Checks[check.Name] = err.Error()
+			resp.Status = "unavailable"
+			status = http.StatusServiceUnavailable
+			continue
+		}
+		resp.Checks[check.Name] = "ok"
+	}
+
+	respondJSON(w, status, resp)
+}
+
+// Version handles the /version endpoint - The version, commit and build time of the mock.
+func (h *Health) Version(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, version.Get())
 }
 
 // respondJSON writes a JSON response with the given status code.
@@ -2,9 +2,13 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"maps"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+
+	"github.com/katharinasick/gcp-api-mock/internal/version"
 )
 
 func TestHealth_Check(t *testing.T) {
@@ -50,3 +54,61 @@ func TestHealth_Ready(t *testing.T) {
 		t.Errorf("expected status 'ready', got '%s'", resp.Status)
 	}
 }
+
+func TestHealth_ReadyChecks(t *testing.T) {
+	ok := ReadinessCheck{Name: "memory", Check: func() error { return nil }}
+	failing := ReadinessCheck{Name: "blobStorage", Check: func() error { return errors.New("blob directory is not writable") }}
+
+	tests := []struct {
+		name           string
+		checks         []ReadinessCheck
+		expectedStatus int
+		expectedChecks map[string]string
+	}{
+		{"all checks pass", []ReadinessCheck{ok}, http.StatusOK, map[string]string{"memory": "ok"}},
+		{"a check fails", []ReadinessCheck{ok, failing}, http.StatusServiceUnavailable, map[string]string{"memory": "ok", "blobStorage": "blob directory is not writable"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHealth(tt.checks...)
+
+			req := httptest.NewRequest(http.MethodGet, "/ready", nil)
+			rr := httptest.NewRecorder()
+			h.Ready(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+
+			var resp HealthResponse
+			if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if !maps.Equal(resp.Checks, tt.expectedChecks) {
+				t.Errorf("expected checks %v, got %v", tt.expectedChecks, resp.Checks)
+			}
+		})
+	}
+}
+
+func TestHealth_Version(t *testing.T) {
+	h := NewHealth()
+
+	req := httptest.NewRequest(http.MethodGet, "/version", nil)
+	rr := httptest.NewRecorder()
+
+	h.Version(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	var resp version.Info
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Version == "" || resp.GoVersion == "" {
+		t.Errorf("expected version and Go version, got %+v", resp)
+	}
+}
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+)
+
+// ListHmacKeys handles GET /storage/v1/projects/{project}/hmacKeys - List the HMAC keys of a project.
+// Reference: https://cloud.google.com/storage/docs/json_api/v1/projects/hmacKeys/list
+func (h *Storage) ListHmacKeys(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	maxResults, err := parseMaxResults(query.Get("maxResults"), storageDefaultMaxResults, storageDefaultMaxResults)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+		return
+	}
+
+	keys := h.store.ListHmacKeys(r.PathValue("project"), query.Get("serviceAccountEmail"), query.Get("showDeletedKeys") == "true")
+	keys, nextPageToken, err := paginate(keys, query.Get("pageToken"), maxResults, func(k *storage.HmacKeyMetadata) string {
+		return sortableTime(k.TimeCreated) + "\x00" + k.AccessID
+	})
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+		return
+	}
+
+	response := &storage.HmacKeyList{
+		Kind:          "storage#hmacKeysMetadata",
+		Items:         keys,
+		NextPageToken: nextPageToken,
+	}
+
+	respondStorageJSON(w, r, http.StatusOK, response)
+}
+
+// CreateHmacKey handles POST /storage/v1/projects/{project}/hmacKeys?serviceAccountEmail={email} -
+// Create an HMAC key for a service account.
+// Reference: https://cloud.google.com/storage/docs/json_api/v1/projects/hmacKeys/create
+func (h *Storage) CreateHmacKey(w http.ResponseWriter, r *http.Request) {
+	email := r.URL.Query().Get("serviceAccountEmail")
+	if email == "" {
+		respondError(w, http.StatusBadRequest, "Required parameter: serviceAccountEmail", "required")
+		return
+	}
+
+	key, err := h.store.CreateHmacKey(r.PathValue("project"), email)
+	if err != nil {
+		if strings.Contains(err.Error(), "invalid") {
+			respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error(), "internalError")
+		return
+	}
+
+	respondStorageJSON(w, r, http.StatusOK, key)
+}
+
+// GetHmacKey handles GET /storage/v1/projects/{project}/hmacKeys/{accessId} - Get the metadata of an HMAC key.
+// Reference: https://cloud.google.com/storage/docs/json_api/v1/projects/hmacKeys/get
+func (h *Storage) GetHmacKey(w http.ResponseWriter, r *http.Request) {
+	key := h.store.GetHmacKey(r.PathValue("project"), r.PathValue("accessId"))
+	if key == nil {
+		respondError(w, http.StatusNotFound, "Access ID not found", "notFound")
+		return
+	}
+
+	respondStorageJSON(w, r, http.StatusOK, key)
+}
+
+// UpdateHmacKey handles PUT /storage/v1/projects/{project}/hmacKeys/{accessId} - Activate or deactivate an HMAC key.
+// Reference: https://cloud.google.com/storage/docs/json_api/v1/projects/hmacKeys/update
+func (h *Storage) UpdateHmacKey(w http.ResponseWriter, r *http.Request) {
+	var req storage.HmacKeyMetadata
+	if err := decodeJSON(r.Body, &req, h.store.StrictValidation()); err != nil {
+		respondError(w, http.StatusBadRequest, invalidJSONMessage(err), "invalid")
+		return
+	}
+
+	key, err := h.store.UpdateHmacKey(r.PathValue("project"), r.PathValue("accessId"), &req)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			respondError(w, http.StatusNotFound, "Access ID not found", "notFound")
+		case strings.Contains(err.Error(), "precondition failed"):
+			respondError(w, http.StatusPreconditionFailed, err.Error(), "conditionNotMet")
+		case strings.Contains(err.Error(), "invalid"):
+			respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+		default:
+			respondError(w, http.StatusInternalServerError, err.Error(), "internalError")
+		}
+		return
+	}
+
+	respondStorageJSON(w, r, http.StatusOK, key)
+}
+
+// DeleteHmacKey handles DELETE /storage/v1/projects/{project}/hmacKeys/{accessId} - Delete an inactive HMAC key.
+// Reference: https://cloud.google.com/storage/docs/json_api/v1/projects/hmacKeys/delete
+func (h *Storage) DeleteHmacKey(w http.ResponseWriter, r *http.Request) {
+	if err := h.store.DeleteHmacKey(r.PathValue("project"), r.PathValue("accessId")); err != nil {
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			respondError(w, http.StatusNotFound, "Access ID not found", "notFound")
+		case strings.Contains(err.Error(), "invalid"):
+			respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+		default:
+			respondError(w, http.StatusInternalServerError, err.Error(), "internalError")
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
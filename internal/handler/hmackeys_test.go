@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+)
+
+func TestStorage_HmacKeyCRUD(t *testing.T) {
+	h, _ := setupTestStorage()
+	base := "/storage/v1/projects/test-project/hmacKeys"
+
+	rr := httptest.NewRecorder()
+	serveRoute("POST /storage/v1/projects/{project}/hmacKeys", h.CreateHmacKey, rr,
+		httptest.NewRequest(http.MethodPost, base+"?serviceAccountEmail=sa@test-project.iam.gserviceaccount.com", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	var key storage.HmacKey
+	if err := json.NewDecoder(rr.Body).Decode(&key); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if key.Kind != "storage#hmacKey" || key.Secret == "" || key.Metadata.ProjectID != "test-project" {
+		t.Errorf("unexpected key: %+v", key)
+	}
+	accessID := key.Metadata.AccessID
+
+	rr = httptest.NewRecorder()
+	serveRoute("GET /storage/v1/projects/{project}/hmacKeys/{accessId}", h.GetHmacKey, rr, httptest.NewRequest(http.MethodGet, base+"/"+accessID, nil))
+	if rr.Code != http.StatusOK || strings.Contains(rr.Body.String(), "secret") {
+		t.Errorf("expected the metadata without the secret, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	serveRoute("GET /storage/v1/projects/{project}/hmacKeys", h.ListHmacKeys, rr, httptest.NewRequest(http.MethodGet, base, nil))
+	var list storage.HmacKeyList
+	if err := json.NewDecoder(rr.Body).Decode(&list); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if list.Kind != "storage#hmacKeysMetadata" || len(list.Items) != 1 {
+		t.Errorf("expected 1 key, got %+v", list)
+	}
+
+	rr = httptest.NewRecorder()
+	serveRoute("DELETE /storage/v1/projects/{project}/hmacKeys/{accessId}", h.DeleteHmacKey, rr, httptest.NewRequest(http.MethodDelete, base+"/"+accessID, nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d deleting an active key, got %d", http.StatusBadRequest, rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	serveRoute("PUT /storage/v1/projects/{project}/hmacKeys/{accessId}", h.UpdateHmacKey, rr,
+		httptest.NewRequest(http.MethodPut, base+"/"+accessID, strings.NewReader(`{"state": "INACTIVE", "etag": "stale"}`)))
+	if rr.Code != http.StatusPreconditionFailed {
+		t.Errorf("expected status %d, got %d", http.StatusPreconditionFailed, rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	serveRoute("PUT /storage/v1/projects/{project}/hmacKeys/{accessId}", h.UpdateHmacKey, rr,
+		httptest.NewRequest(http.MethodPut, base+"/"+accessID, strings.NewReader(`{"state": "INACTIVE"}`)))
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"state":"INACTIVE"`) {
+		t.Errorf("expected an inactive key, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	serveRoute("DELETE /storage/v1/projects/{project}/hmacKeys/{accessId}", h.DeleteHmacKey, rr, httptest.NewRequest(http.MethodDelete, base+"/"+accessID, nil))
+	if rr.Code != http.StatusNoContent {
+		t.Errorf("expected status %d, got %d: %s", http.StatusNoContent, rr.Code, rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	serveRoute("GET /storage/v1/projects/{project}/hmacKeys/{accessId}", h.GetHmacKey, rr, httptest.NewRequest(http.MethodGet, "/storage/v1/projects/other/hmacKeys/"+accessID, nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected status %d for another project, got %d", http.StatusNotFound, rr.Code)
+	}
+}
+
+func TestStorage_CreateHmacKey_MissingServiceAccount(t *testing.T) {
+	h, _ := setupTestStorage()
+
+	rr := httptest.NewRecorder()
+	serveRoute("POST /storage/v1/projects/{project}/hmacKeys", h.CreateHmacKey, rr, httptest.NewRequest(http.MethodPost, "/storage/v1/projects/p/hmacKeys", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, rr.Code)
+	}
+}
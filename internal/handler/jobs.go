@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/katharinasick/gcp-api-mock/internal/jobs"
+)
+
+// Jobs handles the admin API for the background jobs of the mock.
+type Jobs struct {
+	scheduler *jobs.Scheduler
+}
+
+// NewJobs creates a new Jobs handler.
+func NewJobs(scheduler *jobs.Scheduler) *Jobs {
+	return &Jobs{scheduler: scheduler}
+}
+
+// JobList is the response body listing the background jobs.
+type JobList struct {
+	Items []jobs.Status `json:"items"`
+}
+
+// List handles GET /admin/jobs - List the background jobs with their last and next run.
+func (h *Jobs) List(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, JobList{Items: h.scheduler.List()})
+}
+
+// Run handles POST /admin/jobs/{name}/run - Run a background job right away and return its status,
+// e.g. to pick up edited mirror files without waiting for the next sync.
+func (h *Jobs) Run(w http.ResponseWriter, r *http.Request) {
+	status, err := h.scheduler.Run(r.PathValue("name"))
+	if err != nil {
+		respondError(w, http.StatusNotFound, err.Error(), "notFound")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, status)
+}
@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/katharinasick/gcp-api-mock/internal/logging"
+	"github.com/katharinasick/gcp-api-mock/internal/store"
+)
+
+// Logging handles Cloud Logging API (v2) log entry endpoints.
+// Written entries are kept in a capped in-memory buffer; sinks, metrics and exclusions are not emulated.
+type Logging struct {
+	store *store.Store
+}
+
+// NewLogging creates a new Logging handler.
+func NewLogging(s *store.Store) *Logging {
+	return &Logging{store: s}
+}
+
+const (
+	// loggingDefaultPageSize is the default page size of entries.list and logs.list.
+	loggingDefaultPageSize = 50
+	// loggingMaxPageSize is the largest page size of entries.list and logs.list.
+	loggingMaxPageSize = 1000
+)
+
+// WriteEntries handles POST /v2/entries:write - Write log entries.
+// Reference: https://cloud.google.com/logging/docs/reference/v2/rest/v2/entries/write
+func (h *Logging) WriteEntries(w http.ResponseWriter, r *http.Request) {
+	var req logging.WriteLogEntriesRequest
+	if err := decodeJSON(r.Body, &req, h.store.StrictValidation()); err != nil {
+		respondStatusError(w, http.StatusBadRequest, invalidJSONMessage(err), "INVALID_ARGUMENT")
+		return
+	}
+
+	if err := h.store.WriteLogEntries(&req); err != nil {
+		respondStatusError(w, http.StatusBadRequest, err.Error(), "INVALID_ARGUMENT")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, struct{}{})
+}
+
+// ListEntries handles POST /v2/entries:list - List the log entries of projects matching a filter.
+// Reference: https://cloud.google.com/logging/docs/reference/v2/rest/v2/entries/list
+func (h *Logging) ListEntries(w http.ResponseWriter, r *http.Request) {
+	var req logging.ListLogEntriesRequest
+	if err := decodeJSON(r.Body, &req, h.store.StrictValidation()); err != nil {
+		respondStatusError(w, http.StatusBadRequest, invalidJSONMessage(err), "INVALID_ARGUMENT")
+		return
+	}
+
+	if len(req.ResourceNames) == 0 {
+		respondStatusError(w, http.StatusBadRequest, "Field resourceNames is required", "INVALID_ARGUMENT")
+		return
+	}
+
+	filter, err := logging.ParseFilter(req.Filter)
+	if err != nil {
+		respondStatusError(w, http.StatusBadRequest, "Field filter had an invalid value: "+err.Error(), "INVALID_ARGUMENT")
+		return
+	}
+
+	var descending bool
+	switch strings.Join(strings.Fields(req.OrderBy), " ") {
+	case "", "timestamp", "timestamp asc":
+	case "timestamp desc":
+		descending = true
+	default:
+		respondStatusError(w, http.StatusBadRequest, "Field orderBy had an invalid value: "+req.OrderBy, "INVALID_ARGUMENT")
+		return
+	}
+
+	pageSize, ok := loggingPageSize(w, req.PageSize)
+	if !ok {
+		return
+	}
+
+	entries, nextPageToken, err := paginateSorted(h.store.ListLogEntries(req.ResourceNames, filter, descending), req.PageToken, pageSize, logEntryKey, descending)
+	if err != nil {
+		respondStatusError(w, http.StatusBadRequest, err.Error(), "INVALID_ARGUMENT")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, &logging.ListLogEntriesResponse{
+		Entries:       entries,
+		NextPageToken: nextPageToken,
+	})
+}
+
+// logEntryKey returns the page key of a log entry, in the order ListLogEntries sorts the entries in.
+func logEntryKey(entry *logging.LogEntry) string {
+	return sortableTime(entry.Timestamp) + "\x00" + entry.InsertID + "\x00" + entry.LogName
+}
+
+// ListLogs handles GET /v2/projects/{project}/logs - List the names of the logs of a project that have entries.
+// Reference: https://cloud.google.com/logging/docs/reference/v2/rest/v2/projects.logs/list
+func (h *Logging) ListLogs(w http.ResponseWriter, r *http.Request) {
+	parent := "projects/" + r.PathValue("project")
+
+	requested := 0
+	if value := r.URL.Query().Get("pageSize"); value != "" {
+		var err error
+		if requested, err = strconv.Atoi(value); err != nil {
+			respondStatusError(w, http.StatusBadRequest, "Invalid pageSize: "+value, "INVALID_ARGUMENT")
+			return
+		}
+	}
+	pageSize, ok := loggingPageSize(w, requested)
+	if !ok {
+		return
+	}
+
+	names, nextPageToken, err := paginate(h.store.ListLogs(parent), r.URL.Query().Get("pageToken"), pageSize, func(name string) string { return name })
+	if err != nil {
+		respondStatusError(w, http.StatusBadRequest, err.Error(), "INVALID_ARGUMENT")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, &logging.ListLogsResponse{
+		LogNames:      names,
+		NextPageToken: nextPageToken,
+	})
+}
+
+// =============================================================================
+// Helper Functions
+// =============================================================================
+
+// loggingPageSize returns the page size for a requested one, writing an error response if it is invalid.
+// 0 selects the default page size.
+func loggingPageSize(w http.ResponseWriter, requested int) (int, bool) {
+	switch {
+	case requested == 0:
+		return loggingDefaultPageSize, true
+	case requested < 0 || requested > loggingMaxPageSize:
+		respondStatusError(w, http.StatusBadRequest, "Invalid pageSize: "+strconv.Itoa(requested), "INVALID_ARGUMENT")
+		return 0, false
+	default:
+		return requested, true
+	}
+}
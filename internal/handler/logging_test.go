@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/katharinasick/gcp-api-mock/internal/logging"
+	"github.com/katharinasick/gcp-api-mock/internal/store"
+)
+
+// testLogEntriesBody is an entries.write body writing two entries to projects/test-project/logs/app.
+const testLogEntriesBody = `{
+	"logName": "projects/test-project/logs/app",
+	"resource": {"type": "cloud_run_revision", "labels": {"service_name": "api"}},
+	"entries": [
+		{"severity": "INFO", "textPayload": "started", "timestamp": "2024-01-01T12:00:00Z"},
+		{"severity": "ERROR", "jsonPayload": {"message": "request failed"}, "timestamp": "2024-01-01T12:00:01Z"}
+	]
+}`
+
+func setupTestLogging() (*Logging, *store.Store) {
+	s := store.New()
+	return NewLogging(s), s
+}
+
+func TestLogging_WriteEntries(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{"valid", testLogEntriesBody, http.StatusOK},
+		{"invalid body", `{`, http.StatusBadRequest},
+		{"no entries", `{"logName":"projects/p/logs/app","resource":{"type":"global"},"entries":[]}`, http.StatusBadRequest},
+		{"no resource", `{"logName":"projects/p/logs/app","entries":[{"textPayload":"x"}]}`, http.StatusBadRequest},
+		{"invalid severity", `{"logName":"projects/p/logs/app","resource":{"type":"global"},"entries":[{"severity":"LOUD"}]}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, _ := setupTestLogging()
+
+			req := httptest.NewRequest(http.MethodPost, "/v2/entries:write", strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+			h.WriteEntries(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestLogging_ListEntries(t *testing.T) {
+	h, _ := setupTestLogging()
+
+	rr := httptest.NewRecorder()
+	h.WriteEntries(rr, httptest.NewRequest(http.MethodPost, "/v2/entries:write", strings.NewReader(testLogEntriesBody)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("entries.write failed: %d %s", rr.Code, rr.Body.String())
+	}
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedFirst  []string
+	}{
+		{"all", `{"resourceNames":["projects/test-project"]}`, http.StatusOK, []string{"INFO", "ERROR"}},
+		{"newest first", `{"resourceNames":["projects/test-project"],"orderBy":"timestamp desc"}`, http.StatusOK, []string{"ERROR", "INFO"}},
+		{"filtered", `{"resourceNames":["projects/test-project"],"filter":"severity>=ERROR AND jsonPayload.message:\"failed\""}`, http.StatusOK, []string{"ERROR"}},
+		{"paged", `{"resourceNames":["projects/test-project"],"pageSize":1}`, http.StatusOK, []string{"INFO"}},
+		{"other project", `{"resourceNames":["projects/other"]}`, http.StatusOK, []string{}},
+		{"no resource names", `{}`, http.StatusBadRequest, nil},
+		{"invalid filter", `{"resourceNames":["projects/test-project"],"filter":"severity >="}`, http.StatusBadRequest, nil},
+		{"invalid order", `{"resourceNames":["projects/test-project"],"orderBy":"severity"}`, http.StatusBadRequest, nil},
+		{"invalid page size", `{"resourceNames":["projects/test-project"],"pageSize":5000}`, http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/v2/entries:list", strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+			h.ListEntries(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var resp logging.ListLogEntriesResponse
+			if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			var severities []string
+			for _, entry := range resp.Entries {
+				severities = append(severities, entry.Severity)
+			}
+			if strings.Join(severities, ",") != strings.Join(tt.expectedFirst, ",") {
+				t.Errorf("expected entries %v, got %v", tt.expectedFirst, severities)
+			}
+		})
+	}
+}
+
+func TestLogging_ListLogs(t *testing.T) {
+	h, _ := setupTestLogging()
+
+	rr := httptest.NewRecorder()
+	h.WriteEntries(rr, httptest.NewRequest(http.MethodPost, "/v2/entries:write", strings.NewReader(testLogEntriesBody)))
+
+	rr = httptest.NewRecorder()
+	serveRoute("GET /v2/projects/{project}/logs", h.ListLogs, rr, httptest.NewRequest(http.MethodGet, "/v2/projects/test-project/logs", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var resp logging.ListLogsResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.LogNames) != 1 || resp.LogNames[0] != "projects/test-project/logs/app" {
+		t.Errorf("expected [projects/test-project/logs/app], got %v", resp.LogNames)
+	}
+
+	rr = httptest.NewRecorder()
+	serveRoute("GET /v2/projects/{project}/logs", h.ListLogs, rr, httptest.NewRequest(http.MethodGet, "/v2/projects/test-project/logs?pageSize=x", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for an invalid page size, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/katharinasick/gcp-api-mock/internal/memorystore"
+	"github.com/katharinasick/gcp-api-mock/internal/store"
+)
+
+// Memorystore handles Memorystore for Redis API (v1) endpoints.
+// Any project and location is accepted. Mutations return long-running operations that are already done;
+// they are served by LocationOperations.
+type Memorystore struct {
+	store *store.Store
+}
+
+// NewMemorystore creates a new Memorystore handler.
+func NewMemorystore(s *store.Store) *Memorystore {
+	return &Memorystore{store: s}
+}
+
+// redisDefaultPageSize is the default page size for Memorystore list calls.
+const redisDefaultPageSize = 100
+
+// ListInstances handles GET /v1/projects/{project}/locations/{location}/instances - List instances.
+// The location "-" lists the instances of all locations.
+// Reference: https://cloud.google.com/memorystore/docs/redis/reference/rest/v1/projects.locations.instances/list
+func (h *Memorystore) ListInstances(w http.ResponseWriter, r *http.Request) {
+	pageSize := redisDefaultPageSize
+	if value := r.URL.Query().Get("pageSize"); value != "" {
+		size, err := strconv.Atoi(value)
+		if err != nil || size < 1 {
+			respondStatusError(w, http.StatusBadRequest, "Invalid pageSize: "+value, "INVALID_ARGUMENT")
+			return
+		}
+		pageSize = size
+	}
+
+	instances, nextPageToken, err := paginate(h.store.ListRedisInstances(redisParent(r)), r.URL.Query().Get("pageToken"), pageSize, func(i *memorystore.Instance) string { return i.Name })
+	if err != nil {
+		respondStatusError(w, http.StatusBadRequest, err.Error(), "INVALID_ARGUMENT")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, &memorystore.ListInstancesResponse{
+		Instances:     instances,
+		NextPageToken: nextPageToken,
+	})
+}
+
+// CreateInstance handles POST /v1/projects/{project}/locations/{location}/instances?instanceId={id} - Create an instance.
+// Reference: https://cloud.google.com/memorystore/docs/redis/reference/rest/v1/projects.locations.instances/create
+func (h *Memorystore) CreateInstance(w http.ResponseWriter, r *http.Request) {
+	var req memorystore.Instance
+	if err := decodeJSON(r.Body, &req, h.store.StrictValidation()); err != nil {
+		respondStatusError(w, http.StatusBadRequest, invalidJSONMessage(err), "INVALID_ARGUMENT")
+		return
+	}
+
+	op, err := h.store.CreateRedisInstance(redisParent(r), r.URL.Query().Get("instanceId"), &req)
+	if err != nil {
+		respondStatusStoreError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, op)
+}
+
+// GetInstance handles GET /v1/projects/{project}/locations/{location}/instances/{instance} - Get an instance.
+// Reference: https://cloud.google.com/memorystore/docs/redis/reference/rest/v1/projects.locations.instances/get
+func (h *Memorystore) GetInstance(w http.ResponseWriter, r *http.Request) {
+	name := redisInstanceName(r)
+
+	instance := h.store.GetRedisInstance(name)
+	if instance == nil {
+		respondStatusError(w, http.StatusNotFound, "Instance not found: "+name, "NOT_FOUND")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, instance)
+}
+
+// UpdateInstance handles PATCH /v1/projects/{project}/locations/{location}/instances/{instance}?updateMask={fields} - Update an instance.
+// Reference: https://cloud.google.com/memorystore/docs/redis/reference/rest/v1/projects.locations.instances/patch
+func (h *Memorystore) UpdateInstance(w http.ResponseWriter, r *http.Request) {
+	var req memorystore.Instance
+	if err := decodeJSON(r.Body, &req, h.store.StrictValidation()); err != nil {
+		respondStatusError(w, http.StatusBadRequest, invalidJSONMessage(err), "INVALID_ARGUMENT")
+		return
+	}
+
+	var updateMask []string
+	if value := r.URL.Query().Get("updateMask"); value != "" {
+		updateMask = strings.Split(value, ",")
+	}
+
+	op, err := h.store.UpdateRedisInstance(redisInstanceName(r), &req, updateMask)
+	if err != nil {
+		respondStatusStoreError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, op)
+}
+
+// DeleteInstance handles DELETE /v1/projects/{project}/locations/{location}/instances/{instance} - Delete an instance.
+// Reference: https://cloud.google.com/memorystore/docs/redis/reference/rest/v1/projects.locations.instances/delete
+func (h *Memorystore) DeleteInstance(w http.ResponseWriter, r *http.Request) {
+	op, err := h.store.DeleteRedisInstance(redisInstanceName(r))
+	if err != nil {
+		respondStatusStoreError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, op)
+}
+
+// redisParent returns the parent (projects/{project}/locations/{location}) named by the path of a request.
+func redisParent(r *http.Request) string {
+	return "projects/" + r.PathValue("project") + "/locations/" + r.PathValue("location")
+}
+
+// redisInstanceName returns the instance name (projects/{project}/locations/{location}/instances/{instance})
+// named by the path of a request.
+func redisInstanceName(r *http.Request) string {
+	return redisParent(r) + "/instances/" + r.PathValue("instance")
+}
@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/katharinasick/gcp-api-mock/internal/memorystore"
+	"github.com/katharinasick/gcp-api-mock/internal/store"
+)
+
+const testRedisLocation = "/v1/projects/test-project/locations/europe-west1"
+
+const testRedisInstance = `{"tier": "BASIC", "memorySizeGb": 1}`
+
+func setupTestMemorystore() (*Memorystore, *store.Store) {
+	s := store.New()
+	return NewMemorystore(s), s
+}
+
+func TestMemorystore_CreateInstance(t *testing.T) {
+	tests := []struct {
+		name           string
+		path           string
+		body           string
+		expectedStatus int
+	}{
+		{"valid", testRedisLocation + "/instances?instanceId=cache", testRedisInstance, http.StatusOK},
+		{"missing instanceId", testRedisLocation + "/instances", testRedisInstance, http.StatusBadRequest},
+		{"no tier", testRedisLocation + "/instances?instanceId=cache", `{"memorySizeGb": 1}`, http.StatusBadRequest},
+		{"invalid body", testRedisLocation + "/instances?instanceId=cache", `{`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, _ := setupTestMemorystore()
+
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+			serveRoute("POST /v1/projects/{project}/locations/{location}/instances", h.CreateInstance, rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestMemorystore_CreateInstance_Operation(t *testing.T) {
+	h, s := setupTestMemorystore()
+	s.SetRedisEndpoint("localhost:6379")
+
+	req := httptest.NewRequest(http.MethodPost, testRedisLocation+"/instances?instanceId=cache", strings.NewReader(testRedisInstance))
+	rr := httptest.NewRecorder()
+	serveRoute("POST /v1/projects/{project}/locations/{location}/instances", h.CreateInstance, rr, req)
+
+	var op memorystore.Operation
+	if err := json.NewDecoder(rr.Body).Decode(&op); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !op.Done || !strings.HasPrefix(op.Name, "projects/test-project/locations/europe-west1/operations/") {
+		t.Errorf("unexpected operation: %+v", op)
+	}
+
+	// The operation can be polled
+	req = httptest.NewRequest(http.MethodGet, "/v1/"+op.Name, nil)
+	rr = httptest.NewRecorder()
+	serveRoute("GET /v1/projects/{project}/locations/{location}/operations/{operation}", NewLocationOperations(s).GetOperation, rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected status 200 for the operation, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	// The instance reports the local Redis server
+	req = httptest.NewRequest(http.MethodGet, testRedisLocation+"/instances/cache", nil)
+	rr = httptest.NewRecorder()
+	serveRoute("GET /v1/projects/{project}/locations/{location}/instances/{instance}", h.GetInstance, rr, req)
+	var instance memorystore.Instance
+	if err := json.NewDecoder(rr.Body).Decode(&instance); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if instance.Host != "localhost" || instance.Port != 6379 || instance.State != memorystore.StateReady {
+		t.Errorf("unexpected instance: %+v", instance)
+	}
+}
+
+func TestMemorystore_UpdateInstance(t *testing.T) {
+	h, s := setupTestMemorystore()
+	if _, err := s.CreateRedisInstance("projects/test-project/locations/europe-west1", "cache", &memorystore.Instance{Tier: memorystore.TierBasic, MemorySizeGb: 1}); err != nil {
+		t.Fatalf("CreateRedisInstance() error: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+	}{
+		{"valid", testRedisLocation + "/instances/cache?updateMask=memorySizeGb,displayName", http.StatusOK},
+		{"missing updateMask", testRedisLocation + "/instances/cache", http.StatusBadRequest},
+		{"missing instance", testRedisLocation + "/instances/missing?updateMask=memorySizeGb", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPatch, tt.path, strings.NewReader(`{"memorySizeGb": 4, "displayName": "Cache"}`))
+			rr := httptest.NewRecorder()
+			serveRoute("PATCH /v1/projects/{project}/locations/{location}/instances/{instance}", h.UpdateInstance, rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+
+	if instance := s.GetRedisInstance("projects/test-project/locations/europe-west1/instances/cache"); instance.MemorySizeGb != 4 || instance.DisplayName != "Cache" {
+		t.Errorf("unexpected instance after update: %+v", instance)
+	}
+}
+
+func TestLocationOperations_GetOperation_NotFound(t *testing.T) {
+	h := NewLocationOperations(store.New())
+
+	req := httptest.NewRequest(http.MethodGet, testRedisLocation+"/operations/operation-missing", nil)
+	rr := httptest.NewRecorder()
+	serveRoute("GET /v1/projects/{project}/locations/{location}/operations/{operation}", h.GetOperation, rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+
+	"github.com/katharinasick/gcp-api-mock/internal/idtoken"
+	"github.com/katharinasick/gcp-api-mock/internal/store"
+)
+
+// Metadata emulates the parts of the GCE metadata server that credentials use: the project, the default
+// service account and its access and ID tokens. Point clients at it with GCE_METADATA_HOST=localhost:8080.
+// Reference: https://cloud.google.com/compute/docs/metadata/predefined-metadata-keys
+type Metadata struct {
+	store  *store.Store
+	signer *idtoken.Signer
+}
+
+// NewMetadata creates a new Metadata handler minting ID tokens with signer.
+func NewMetadata(s *store.Store, signer *idtoken.Signer) *Metadata {
+	return &Metadata{store: s, signer: signer}
+}
+
+// The instance that tokens requested with format=full describe.
+const (
+	metadataZone         = "us-central1-a"
+	metadataInstanceName = "gcp-api-mock"
+	metadataInstanceID   = "4520031799277581759"
+)
+
+// GetProjectID handles GET /computeMetadata/v1/project/project-id - Get the project ID.
+func (h *Metadata) GetProjectID(w http.ResponseWriter, r *http.Request) {
+	if !checkMetadataFlavor(w, r) {
+		return
+	}
+	respondMetadata(w, http.StatusOK, h.store.ProjectID())
+}
+
+// GetNumericProjectID handles GET /computeMetadata/v1/project/numeric-project-id - Get the project number.
+func (h *Metadata) GetNumericProjectID(w http.ResponseWriter, r *http.Request) {
+	if !checkMetadataFlavor(w, r) {
+		return
+	}
+	respondMetadata(w, http.StatusOK, strconv.FormatUint(h.store.ProjectNumber(), 10))
+}
+
+// GetServiceAccountEmail handles GET /computeMetadata/v1/instance/service-accounts/{account}/email -
+// Get the email of the default service account.
+func (h *Metadata) GetServiceAccountEmail(w http.ResponseWriter, r *http.Request) {
+	email, ok := h.serviceAccount(w, r)
+	if !ok {
+		return
+	}
+	respondMetadata(w, http.StatusOK, email)
+}
+
+// metadataToken is the access token response of the metadata server.
+type metadataToken struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+	TokenType   string `json:"token_type"`
+}
+
+// GetServiceAccountToken handles GET /computeMetadata/v1/instance/service-accounts/{account}/token -
+// Get an access token of the default service account. Tokens are opaque; the mock accepts any token.
+func (h *Metadata) GetServiceAccountToken(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.serviceAccount(w, r); !ok {
+		return
+	}
+
+	buf := make([]byte, 24)
+	rand.Read(buf)
+
+	w.Header().Set("Metadata-Flavor", "Google")
+	respondJSON(w, http.StatusOK, metadataToken{
+		AccessToken: "ya29.mock-" + hex.EncodeToString(buf),
+		ExpiresIn:   int(idtoken.Lifetime.Seconds()) - 1,
+		TokenType:   "Bearer",
+	})
+}
+
+// GetServiceAccountIdentity handles GET /computeMetadata/v1/instance/service-accounts/{account}/identity?audience={audience} -
+// Get an ID token of the default service account for an audience, signed with the mock's key.
+// With format=full, the token describes the instance too.
+func (h *Metadata) GetServiceAccountIdentity(w http.ResponseWriter, r *http.Request) {
+	email, ok := h.serviceAccount(w, r)
+	if !ok {
+		return
+	}
+
+	audience := r.URL.Query().Get("audience")
+	if audience == "" {
+		respondMetadata(w, http.StatusBadRequest, "non-empty audience parameter required")
+		return
+	}
+
+	var instance *idtoken.ComputeEngine
+	if r.URL.Query().Get("format") == "full" {
+		instance = &idtoken.ComputeEngine{
+			ProjectID:     h.store.ProjectID(),
+			ProjectNumber: h.store.ProjectNumber(),
+			Zone:          metadataZone,
+			InstanceID:    metadataInstanceID,
+			InstanceName:  metadataInstanceName,
+		}
+	}
+
+	token, err := h.signer.Mint(email, audience, instance)
+	if err != nil {
+		respondMetadata(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondMetadata(w, http.StatusOK, token)
+}
+
+// GetCerts handles GET /oauth2/v3/certs - Get the JWKS with the public key ID tokens are signed with,
+// the mock's counterpart of https://www.googleapis.com/oauth2/v3/certs.
+func (h *Metadata) GetCerts(w http.ResponseWriter, r *http.Request) {
+	jwks, err := h.signer.JWKS()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error(), "internalError")
+		return
+	}
+	respondJSON(w, http.StatusOK, jwks)
+}
+
+// serviceAccount returns the email of the service account named by a request, "default" or its email,
+// and writes an error if the request doesn't come from a metadata client or names another account.
+func (h *Metadata) serviceAccount(w http.ResponseWriter, r *http.Request) (string, bool) {
+	if !checkMetadataFlavor(w, r) {
+		return "", false
+	}
+
+	email := strconv.FormatUint(h.store.ProjectNumber(), 10) + "-compute@developer.gserviceaccount.com"
+	if account := r.PathValue("account"); account != "default" && account != email {
+		respondMetadata(w, http.StatusNotFound, "service account "+account+" is not attached to the instance")
+		return "", false
+	}
+	return email, true
+}
+
+// checkMetadataFlavor writes a 403 error if a request lacks the Metadata-Flavor: Google header,
+// which the metadata server requires to stop requests forwarded from elsewhere.
+func checkMetadataFlavor(w http.ResponseWriter, r *http.Request) bool {
+	if r.Header.Get("Metadata-Flavor") != "Google" {
+		respondMetadata(w, http.StatusForbidden, "Missing Metadata-Flavor:Google header.")
+		return false
+	}
+	return true
+}
+
+// respondMetadata writes a text response of the metadata server.
+func respondMetadata(w http.ResponseWriter, status int, value string) {
+	w.Header().Set("Metadata-Flavor", "Google")
+	w.Header().Set("Content-Type", "application/text")
+	w.WriteHeader(status)
+	w.Write([]byte(value))
+}
@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/idtoken"
+	"github.com/katharinasick/gcp-api-mock/internal/store"
+)
+
+func setupTestMetadata() (*Metadata, *idtoken.Signer) {
+	signer := idtoken.NewSigner(time.Now)
+	return NewMetadata(store.New(), signer), signer
+}
+
+func TestMetadata_GetServiceAccountIdentity(t *testing.T) {
+	pattern := "GET /computeMetadata/v1/instance/service-accounts/{account}/identity"
+	tests := []struct {
+		name           string
+		target         string
+		flavor         string
+		expectedStatus int
+	}{
+		{"default account", "/computeMetadata/v1/instance/service-accounts/default/identity?audience=https://svc.example.com", "Google", http.StatusOK},
+		{"account email", "/computeMetadata/v1/instance/service-accounts/123456789012-compute@developer.gserviceaccount.com/identity?audience=aud&format=full", "Google", http.StatusOK},
+		{"other account", "/computeMetadata/v1/instance/service-accounts/other@example.com/identity?audience=aud", "Google", http.StatusNotFound},
+		{"missing audience", "/computeMetadata/v1/instance/service-accounts/default/identity", "Google", http.StatusBadRequest},
+		{"missing flavor", "/computeMetadata/v1/instance/service-accounts/default/identity?audience=aud", "", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, signer := setupTestMetadata()
+
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.flavor != "" {
+				req.Header.Set("Metadata-Flavor", tt.flavor)
+			}
+			rr := httptest.NewRecorder()
+			serveRoute(pattern, h.GetServiceAccountIdentity, rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if rr.Header().Get("Metadata-Flavor") != "Google" {
+				t.Error("expected the Metadata-Flavor header")
+			}
+			if rr.Code != http.StatusOK {
+				return
+			}
+
+			claims, err := signer.Verify(rr.Body.String())
+			if err != nil {
+				t.Fatalf("expected a token of the signer, got %v", err)
+			}
+			if claims.Email != "123456789012-compute@developer.gserviceaccount.com" || claims.Audience != req.URL.Query().Get("audience") {
+				t.Errorf("unexpected claims: %+v", claims)
+			}
+			if full := req.URL.Query().Get("format") == "full"; full != (claims.Google != nil) {
+				t.Errorf("expected instance claims only for format=full, got %+v", claims.Google)
+			}
+		})
+	}
+}
+
+func TestMetadata_GetServiceAccountToken(t *testing.T) {
+	h, _ := setupTestMetadata()
+
+	req := httptest.NewRequest(http.MethodGet, "/computeMetadata/v1/instance/service-accounts/default/token", nil)
+	req.Header.Set("Metadata-Flavor", "Google")
+	rr := httptest.NewRecorder()
+	serveRoute("GET /computeMetadata/v1/instance/service-accounts/{account}/token", h.GetServiceAccountToken, rr, req)
+
+	var token metadataToken
+	if err := json.Unmarshal(rr.Body.Bytes(), &token); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if rr.Code != http.StatusOK || token.AccessToken == "" || token.TokenType != "Bearer" || token.ExpiresIn <= 0 {
+		t.Errorf("unexpected token: %d %+v", rr.Code, token)
+	}
+}
+
+func TestMetadata_GetCerts(t *testing.T) {
+	h, _ := setupTestMetadata()
+
+	rr := httptest.NewRecorder()
+	h.GetCerts(rr, httptest.NewRequest(http.MethodGet, "/oauth2/v3/certs", nil))
+
+	var jwks idtoken.JWKS
+	if err := json.Unmarshal(rr.Body.Bytes(), &jwks); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if len(jwks.Keys) != 1 || jwks.Keys[0].KeyID == "" {
+		t.Errorf("expected the signing key, got %+v", jwks)
+	}
+}
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/katharinasick/gcp-api-mock/internal/metrics"
+	"github.com/katharinasick/gcp-api-mock/internal/store"
+)
+
+// Metrics handles the Prometheus metrics of the mock and the bucket usage report.
+type Metrics struct {
+	metrics *metrics.Metrics
+	store   *store.Store
+}
+
+// NewMetrics creates a new Metrics handler.
+func NewMetrics(m *metrics.Metrics, s *store.Store) *Metrics {
+	return &Metrics{metrics: m, store: s}
+}
+
+// BucketStatsList is the response body listing the largest buckets.
+type BucketStatsList struct {
+	Items []metrics.BucketStats `json:"items"`
+}
+
+// Serve handles GET /metrics - Export the request counters and the size of the buckets in the Prometheus
+// text format, with a bucket label on the per-bucket series.
+func (h *Metrics) Serve(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", metrics.ContentType)
+	h.metrics.Write(w, h.store)
+}
+
+// TopBuckets handles GET /admin/storage/usage - List the largest buckets with their object count, size
+// and requests, e.g. ?top=10&orderBy=requests. Buckets are ordered by size by default and all are listed without top.
+func (h *Metrics) TopBuckets(w http.ResponseWriter, r *http.Request) {
+	var top int
+	if value := r.URL.Query().Get("top"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			respondError(w, http.StatusBadRequest, "Invalid top: "+value, "invalid")
+			return
+		}
+		top = n
+	}
+
+	stats, err := h.metrics.TopBuckets(h.store, top, r.URL.Query().Get("orderBy"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, BucketStatsList{Items: stats})
+}
@@ -0,0 +1,200 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/monitoring"
+	"github.com/katharinasick/gcp-api-mock/internal/store"
+)
+
+// Monitoring handles Cloud Monitoring API (v3) metric descriptor and time series endpoints.
+// Any project is accepted. Points are stored as written; aggregation and alignment parameters are ignored.
+type Monitoring struct {
+	store *store.Store
+}
+
+// NewMonitoring creates a new Monitoring handler.
+func NewMonitoring(s *store.Store) *Monitoring {
+	return &Monitoring{store: s}
+}
+
+// monitoringDefaultPageSize is the default page size for Cloud Monitoring list calls.
+const monitoringDefaultPageSize = 100000
+
+// =============================================================================
+// Time Series Handlers
+// =============================================================================
+
+// CreateTimeSeries handles POST /v3/projects/{project}/timeSeries - Write one point to each time series.
+// POST /v3/projects/{project}/timeSeries:createService, used for service metrics, is handled the same way.
+// Reference: https://cloud.google.com/monitoring/api/ref_v3/rest/v3/projects.timeSeries/create
+func (h *Monitoring) CreateTimeSeries(w http.ResponseWriter, r *http.Request) {
+	project := r.PathValue("project")
+
+	var req monitoring.CreateTimeSeriesRequest
+	if err := decodeJSON(r.Body, &req, h.store.StrictValidation()); err != nil {
+		respondStatusError(w, http.StatusBadRequest, invalidJSONMessage(err), "INVALID_ARGUMENT")
+		return
+	}
+
+	if err := h.store.CreateTimeSeries(project, req.TimeSeries); err != nil {
+		respondStatusStoreError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, struct{}{})
+}
+
+// ListTimeSeries handles GET /v3/projects/{project}/timeSeries - List the time series matching a filter
+// with their points in interval.startTime to interval.endTime. Without a start time, only points ending
+// at the end time are returned, like for the real API. view=HEADERS leaves out the points.
+// Reference: https://cloud.google.com/monitoring/api/ref_v3/rest/v3/projects.timeSeries/list
+func (h *Monitoring) ListTimeSeries(w http.ResponseWriter, r *http.Request) {
+	project := r.PathValue("project")
+	query := r.URL.Query()
+
+	if query.Get("filter") == "" {
+		respondStatusError(w, http.StatusBadRequest, "Field filter had an invalid value: a filter is required", "INVALID_ARGUMENT")
+		return
+	}
+	filter, err := monitoring.ParseFilter(query.Get("filter"))
+	if err != nil {
+		respondStatusError(w, http.StatusBadRequest, "Field filter had an invalid value: "+err.Error(), "INVALID_ARGUMENT")
+		return
+	}
+
+	end, err := time.Parse(time.RFC3339Nano, query.Get("interval.endTime"))
+	if err != nil {
+		respondStatusError(w, http.StatusBadRequest, "Field interval.endTime had an invalid value: "+query.Get("interval.endTime"), "INVALID_ARGUMENT")
+		return
+	}
+	// The interval excludes its start, so step back to include points ending at the end time
+	start := end.Add(-time.Nanosecond)
+	if value := query.Get("interval.startTime"); value != "" {
+		start, err = time.Parse(time.RFC3339Nano, value)
+		if err != nil || start.After(end) {
+			respondStatusError(w, http.StatusBadRequest, "Field interval.startTime had an invalid value: "+value, "INVALID_ARGUMENT")
+			return
+		}
+	}
+
+	pageSize, ok := parseMonitoringPageSize(w, r)
+	if !ok {
+		return
+	}
+
+	series := h.store.ListTimeSeries(project, filter, start, end, query.Get("view") == "HEADERS")
+	series, nextPageToken, err := paginate(series, query.Get("pageToken"), pageSize, store.TimeSeriesKey)
+	if err != nil {
+		respondStatusError(w, http.StatusBadRequest, err.Error(), "INVALID_ARGUMENT")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, &monitoring.ListTimeSeriesResponse{
+		TimeSeries:    series,
+		NextPageToken: nextPageToken,
+	})
+}
+
+// =============================================================================
+// Metric Descriptor Handlers
+// =============================================================================
+
+// CreateMetricDescriptor handles POST /v3/projects/{project}/metricDescriptors - Create a metric descriptor.
+// Reference: https://cloud.google.com/monitoring/api/ref_v3/rest/v3/projects.metricDescriptors/create
+func (h *Monitoring) CreateMetricDescriptor(w http.ResponseWriter, r *http.Request) {
+	project := r.PathValue("project")
+
+	var req monitoring.MetricDescriptor
+	if err := decodeJSON(r.Body, &req, h.store.StrictValidation()); err != nil {
+		respondStatusError(w, http.StatusBadRequest, invalidJSONMessage(err), "INVALID_ARGUMENT")
+		return
+	}
+
+	descriptor, err := h.store.CreateMetricDescriptor(project, &req)
+	if err != nil {
+		respondStatusStoreError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, descriptor)
+}
+
+// ListMetricDescriptors handles GET /v3/projects/{project}/metricDescriptors - List metric descriptors,
+// optionally filtered by metric.type.
+// Reference: https://cloud.google.com/monitoring/api/ref_v3/rest/v3/projects.metricDescriptors/list
+func (h *Monitoring) ListMetricDescriptors(w http.ResponseWriter, r *http.Request) {
+	project := r.PathValue("project")
+
+	filter, err := monitoring.ParseFilter(r.URL.Query().Get("filter"))
+	if err != nil {
+		respondStatusError(w, http.StatusBadRequest, "Field filter had an invalid value: "+err.Error(), "INVALID_ARGUMENT")
+		return
+	}
+
+	pageSize, ok := parseMonitoringPageSize(w, r)
+	if !ok {
+		return
+	}
+
+	descriptors, nextPageToken, err := paginate(h.store.ListMetricDescriptors(project, filter), r.URL.Query().Get("pageToken"), pageSize, func(d *monitoring.MetricDescriptor) string { return d.Type })
+	if err != nil {
+		respondStatusError(w, http.StatusBadRequest, err.Error(), "INVALID_ARGUMENT")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, &monitoring.ListMetricDescriptorsResponse{
+		MetricDescriptors: descriptors,
+		NextPageToken:     nextPageToken,
+	})
+}
+
+// GetMetricDescriptor handles GET /v3/projects/{project}/metricDescriptors/{type} - Get a metric descriptor.
+// Reference: https://cloud.google.com/monitoring/api/ref_v3/rest/v3/projects.metricDescriptors/get
+func (h *Monitoring) GetMetricDescriptor(w http.ResponseWriter, r *http.Request) {
+	name := "projects/" + r.PathValue("project") + "/metricDescriptors/" + r.PathValue("type")
+
+	descriptor := h.store.GetMetricDescriptor(name)
+	if descriptor == nil {
+		respondStatusError(w, http.StatusNotFound, "Could not find descriptor for metric '"+strings.TrimPrefix(name, "projects/")+"'.", "NOT_FOUND")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, descriptor)
+}
+
+// DeleteMetricDescriptor handles DELETE /v3/projects/{project}/metricDescriptors/{type} - Delete a metric
+// descriptor along with its time series.
+// Reference: https://cloud.google.com/monitoring/api/ref_v3/rest/v3/projects.metricDescriptors/delete
+func (h *Monitoring) DeleteMetricDescriptor(w http.ResponseWriter, r *http.Request) {
+	name := "projects/" + r.PathValue("project") + "/metricDescriptors/" + r.PathValue("type")
+
+	if err := h.store.DeleteMetricDescriptor(name); err != nil {
+		respondStatusStoreError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, struct{}{})
+}
+
+// =============================================================================
+// Helper Functions
+// =============================================================================
+
+// parseMonitoringPageSize parses the pageSize query parameter, writing an error response if it is invalid.
+func parseMonitoringPageSize(w http.ResponseWriter, r *http.Request) (int, bool) {
+	value := r.URL.Query().Get("pageSize")
+	if value == "" {
+		return monitoringDefaultPageSize, true
+	}
+
+	pageSize, err := strconv.Atoi(value)
+	if err != nil || pageSize < 1 {
+		respondStatusError(w, http.StatusBadRequest, "Invalid pageSize: "+value, "INVALID_ARGUMENT")
+		return 0, false
+	}
+	return pageSize, true
+}
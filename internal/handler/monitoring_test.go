@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/katharinasick/gcp-api-mock/internal/monitoring"
+	"github.com/katharinasick/gcp-api-mock/internal/store"
+)
+
+const testMonitoringProject = "/v3/projects/test-project"
+
+// testTimeSeriesBody is a timeSeries.create body writing one INT64 gauge point at endTime.
+func testTimeSeriesBody(value, endTime string) string {
+	return `{"timeSeries":[{
+		"metric":{"type":"custom.googleapis.com/requests","labels":{"status":"200"}},
+		"resource":{"type":"generic_task","labels":{"job":"api"}},
+		"points":[{"interval":{"endTime":"` + endTime + `"},"value":{"int64Value":"` + value + `"}}]
+	}]}`
+}
+
+func setupTestMonitoring() (*Monitoring, *store.Store) {
+	s := store.New()
+	return NewMonitoring(s), s
+}
+
+func TestMonitoring_CreateTimeSeries(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{"valid", testTimeSeriesBody("1", "2024-01-01T12:00:00Z"), http.StatusOK},
+		{"invalid body", `{`, http.StatusBadRequest},
+		{"no time series", `{"timeSeries":[]}`, http.StatusBadRequest},
+		{"missing value", `{"timeSeries":[{"metric":{"type":"custom.googleapis.com/x"},"resource":{"type":"global"},"points":[{"interval":{"endTime":"2024-01-01T12:00:00Z"},"value":{}}]}]}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, _ := setupTestMonitoring()
+
+			req := httptest.NewRequest(http.MethodPost, testMonitoringProject+"/timeSeries", strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+			serveRoute("POST /v3/projects/{project}/timeSeries", h.CreateTimeSeries, rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestMonitoring_ListTimeSeries(t *testing.T) {
+	h, _ := setupTestMonitoring()
+
+	for i, endTime := range []string{"2024-01-01T12:00:00Z", "2024-01-01T12:01:00Z"} {
+		req := httptest.NewRequest(http.MethodPost, testMonitoringProject+"/timeSeries", strings.NewReader(testTimeSeriesBody(string(rune('1'+i)), endTime)))
+		rr := httptest.NewRecorder()
+		serveRoute("POST /v3/projects/{project}/timeSeries", h.CreateTimeSeries, rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("failed to write point: %d %s", rr.Code, rr.Body.String())
+		}
+	}
+
+	tests := []struct {
+		name           string
+		query          url.Values
+		expectedStatus int
+		expectedPoints int
+	}{
+		{"all points", url.Values{
+			"filter":             {`metric.type = "custom.googleapis.com/requests"`},
+			"interval.startTime": {"2024-01-01T00:00:00Z"},
+			"interval.endTime":   {"2024-01-02T00:00:00Z"},
+		}, http.StatusOK, 2},
+		{"point at the end time", url.Values{
+			"filter":           {`metric.type = "custom.googleapis.com/requests"`},
+			"interval.endTime": {"2024-01-01T12:01:00Z"},
+		}, http.StatusOK, 1},
+		{"no matching series", url.Values{
+			"filter":             {`metric.type = "custom.googleapis.com/requests" AND resource.labels.job = "worker"`},
+			"interval.startTime": {"2024-01-01T00:00:00Z"},
+			"interval.endTime":   {"2024-01-02T00:00:00Z"},
+		}, http.StatusOK, 0},
+		{"missing filter", url.Values{"interval.endTime": {"2024-01-02T00:00:00Z"}}, http.StatusBadRequest, 0},
+		{"invalid filter", url.Values{"filter": {`metric.type > "x"`}, "interval.endTime": {"2024-01-02T00:00:00Z"}}, http.StatusBadRequest, 0},
+		{"missing end time", url.Values{"filter": {`metric.type = "custom.googleapis.com/requests"`}}, http.StatusBadRequest, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, testMonitoringProject+"/timeSeries?"+tt.query.Encode(), nil)
+			rr := httptest.NewRecorder()
+			serveRoute("GET /v3/projects/{project}/timeSeries", h.ListTimeSeries, rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if rr.Code != http.StatusOK {
+				return
+			}
+
+			var resp monitoring.ListTimeSeriesResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+			points := 0
+			for _, ts := range resp.TimeSeries {
+				points += len(ts.Points)
+			}
+			if points != tt.expectedPoints {
+				t.Errorf("expected %d points, got %d: %s", tt.expectedPoints, points, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestMonitoring_MetricDescriptors(t *testing.T) {
+	h, _ := setupTestMonitoring()
+	const name = testMonitoringProject + "/metricDescriptors/custom.googleapis.com/queue_depth"
+
+	req := httptest.NewRequest(http.MethodPost, testMonitoringProject+"/metricDescriptors",
+		strings.NewReader(`{"type":"custom.googleapis.com/queue_depth","metricKind":"GAUGE","valueType":"INT64","unit":"1"}`))
+	rr := httptest.NewRecorder()
+	serveRoute("POST /v3/projects/{project}/metricDescriptors", h.CreateMetricDescriptor, rr, req)
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"name":"projects/test-project/metricDescriptors/custom.googleapis.com/queue_depth"`) {
+		t.Fatalf("unexpected create response: %d %s", rr.Code, rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	serveRoute("GET /v3/projects/{project}/metricDescriptors/{type...}", h.GetMetricDescriptor, rr, httptest.NewRequest(http.MethodGet, name, nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected descriptor, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	serveRoute("GET /v3/projects/{project}/metricDescriptors", h.ListMetricDescriptors, rr, httptest.NewRequest(http.MethodGet, testMonitoringProject+"/metricDescriptors?filter="+url.QueryEscape(`metric.type = starts_with("custom.googleapis.com/")`), nil))
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "queue_depth") {
+		t.Errorf("expected descriptor in list, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	serveRoute("DELETE /v3/projects/{project}/metricDescriptors/{type...}", h.DeleteMetricDescriptor, rr, httptest.NewRequest(http.MethodDelete, name, nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected delete to succeed, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	serveRoute("GET /v3/projects/{project}/metricDescriptors/{type...}", h.GetMetricDescriptor, rr, httptest.NewRequest(http.MethodGet, name, nil))
+	if rr.Code != http.StatusNotFound || !strings.Contains(rr.Body.String(), "NOT_FOUND") {
+		t.Errorf("expected 404 NOT_FOUND, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
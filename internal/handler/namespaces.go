@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/katharinasick/gcp-api-mock/internal/namespace"
+)
+
+// Namespaces handles the admin API for namespaces, the isolated copies of the mock's state
+// that parallel test runs select with the X-Mock-Namespace header or the /_ns/{namespace} path prefix.
+type Namespaces struct {
+	namespaces *namespace.Namespaces
+}
+
+// NewNamespaces creates a new Namespaces handler.
+func NewNamespaces(namespaces *namespace.Namespaces) *Namespaces {
+	return &Namespaces{namespaces: namespaces}
+}
+
+// List handles GET /admin/namespaces - List the namespaces and when they expire.
+func (h *Namespaces) List(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, h.namespaces.List())
+}
+
+// Delete handles DELETE /admin/namespaces/{namespace} - Delete a namespace with all its resources,
+// e.g. when a CI job finishes. The next request for the namespace starts with an empty state.
+func (h *Namespaces) Delete(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("namespace")
+
+	if !h.namespaces.Delete(name) {
+		respondError(w, http.StatusNotFound, "Namespace "+name+" not found", "notFound")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/katharinasick/gcp-api-mock/internal/store"
+)
+
+// LocationOperations handles the operations endpoint the v1 APIs with operations named
+// projects/{project}/locations/{location}/operations/{operation} share: Eventarc, Memorystore for Redis and GKE.
+// They serve the same path, so the operation is looked up in each of them; operation IDs are unique.
+type LocationOperations struct {
+	store *store.Store
+}
+
+// NewLocationOperations creates a new LocationOperations handler.
+func NewLocationOperations(s *store.Store) *LocationOperations {
+	return &LocationOperations{store: s}
+}
+
+// GetOperation handles GET /v1/projects/{project}/locations/{location}/operations/{operation} - Get an operation.
+// References:
+//   - https://cloud.google.com/eventarc/docs/reference/rest/v1/projects.locations.operations/get
+//   - https://cloud.google.com/memorystore/docs/redis/reference/rest/v1/projects.locations.operations/get
+//   - https://cloud.google.com/kubernetes-engine/docs/reference/rest/v1/projects.locations.operations/get
+func (h *LocationOperations) GetOperation(w http.ResponseWriter, r *http.Request) {
+	name := "projects/" + r.PathValue("project") + "/locations/" + r.PathValue("location") + "/operations/" + r.PathValue("operation")
+
+	if op := h.store.GetEventarcOperation(name); op != nil {
+		respondJSON(w, http.StatusOK, op)
+		return
+	}
+	if op := h.store.GetRedisOperation(name); op != nil {
+		respondJSON(w, http.StatusOK, op)
+		return
+	}
+	if op := h.store.GetGKEOperation(name); op != nil {
+		respondJSON(w, http.StatusOK, op)
+		return
+	}
+
+	respondStatusError(w, http.StatusNotFound, "Operation not found: "+name, "NOT_FOUND")
+}
@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/katharinasick/gcp-api-mock/internal/orgpolicy"
+	"github.com/katharinasick/gcp-api-mock/internal/store"
+)
+
+// OrgPolicy handles the project policies of the Organization Policy API (v2).
+// Policies of the mock's project enforcing one of orgpolicy.BooleanConstraints make Cloud Storage and Cloud SQL
+// requests violating them fail; policies for other constraints are only stored.
+type OrgPolicy struct {
+	store *store.Store
+}
+
+// NewOrgPolicy creates a new OrgPolicy handler.
+func NewOrgPolicy(s *store.Store) *OrgPolicy {
+	return &OrgPolicy{store: s}
+}
+
+// orgPolicyDefaultPageSize is the default page size for policies.list.
+const orgPolicyDefaultPageSize = 100
+
+// ListPolicies handles GET /v2/projects/{project}/policies - List policies.
+// Reference: https://cloud.google.com/resource-manager/docs/reference/orgpolicy/rest/v2/projects.policies/list
+func (h *OrgPolicy) ListPolicies(w http.ResponseWriter, r *http.Request) {
+	pageSize := orgPolicyDefaultPageSize
+	if value := r.URL.Query().Get("pageSize"); value != "" {
+		size, err := strconv.Atoi(value)
+		if err != nil || size < 1 {
+			respondStatusError(w, http.StatusBadRequest, "Invalid pageSize: "+value, "INVALID_ARGUMENT")
+			return
+		}
+		pageSize = size
+	}
+
+	policies, nextPageToken, err := paginate(h.store.ListOrgPolicies(orgPolicyParent(r)), r.URL.Query().Get("pageToken"), pageSize, func(p *orgpolicy.Policy) string { return p.Name })
+	if err != nil {
+		respondStatusError(w, http.StatusBadRequest, err.Error(), "INVALID_ARGUMENT")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, &orgpolicy.ListPoliciesResponse{
+		Policies:      policies,
+		NextPageToken: nextPageToken,
+	})
+}
+
+// CreatePolicy handles POST /v2/projects/{project}/policies - Create a policy.
+// Reference: https://cloud.google.com/resource-manager/docs/reference/orgpolicy/rest/v2/projects.policies/create
+func (h *OrgPolicy) CreatePolicy(w http.ResponseWriter, r *http.Request) {
+	var req orgpolicy.Policy
+	if err := decodeJSON(r.Body, &req, h.store.StrictValidation()); err != nil {
+		respondStatusError(w, http.StatusBadRequest, invalidJSONMessage(err), "INVALID_ARGUMENT")
+		return
+	}
+
+	policy, err := h.store.CreateOrgPolicy(orgPolicyParent(r), &req)
+	if err != nil {
+		respondOrgPolicyStoreError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, policy)
+}
+
+// GetPolicy handles GET /v2/projects/{project}/policies/{policy} - Get a policy.
+// Reference: https://cloud.google.com/resource-manager/docs/reference/orgpolicy/rest/v2/projects.policies/get
+func (h *OrgPolicy) GetPolicy(w http.ResponseWriter, r *http.Request) {
+	name := orgPolicyName(r)
+
+	policy := h.store.GetOrgPolicy(name)
+	if policy == nil {
+		respondStatusError(w, http.StatusNotFound, "Policy not found: "+name, "NOT_FOUND")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, policy)
+}
+
+// UpdatePolicy handles PATCH /v2/projects/{project}/policies/{policy}?updateMask={fields} - Update a policy.
+// Reference: https://cloud.google.com/resource-manager/docs/reference/orgpolicy/rest/v2/projects.policies/patch
+func (h *OrgPolicy) UpdatePolicy(w http.ResponseWriter, r *http.Request) {
+	var req orgpolicy.Policy
+	if err := decodeJSON(r.Body, &req, h.store.StrictValidation()); err != nil {
+		respondStatusError(w, http.StatusBadRequest, invalidJSONMessage(err), "INVALID_ARGUMENT")
+		return
+	}
+	name := orgPolicyName(r)
+	if req.Name != "" && req.Name != name {
+		respondStatusError(w, http.StatusBadRequest, "Policy name "+req.Name+" doesn't match the name in the path, "+name, "INVALID_ARGUMENT")
+		return
+	}
+	req.Name = name
+
+	var updateMask []string
+	if value := r.URL.Query().Get("updateMask"); value != "" {
+		updateMask = strings.Split(value, ",")
+	}
+
+	policy, err := h.store.UpdateOrgPolicy(name, &req, updateMask)
+	if err != nil {
+		respondOrgPolicyStoreError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, policy)
+}
+
+// DeletePolicy handles DELETE /v2/projects/{project}/policies/{policy} - Delete a policy.
+// Reference: https://cloud.google.com/resource-manager/docs/reference/orgpolicy/rest/v2/projects.policies/delete
+func (h *OrgPolicy) DeletePolicy(w http.ResponseWriter, r *http.Request) {
+	if err := h.store.DeleteOrgPolicy(orgPolicyName(r)); err != nil {
+		respondOrgPolicyStoreError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, struct{}{})
+}
+
+// orgPolicyParent returns the parent (projects/{project}) named by the path of a request.
+func orgPolicyParent(r *http.Request) string {
+	return "projects/" + r.PathValue("project")
+}
+
+// orgPolicyName returns the policy name (projects/{project}/policies/{policy}) named by the path of a request.
+func orgPolicyName(r *http.Request) string {
+	return orgPolicyParent(r) + "/policies/" + r.PathValue("policy")
+}
+
+// respondOrgPolicyStoreError maps a store error to an Organization Policy API error response.
+// Etag mismatches are ABORTED, like in the real API.
+func respondOrgPolicyStoreError(w http.ResponseWriter, err error) {
+	if strings.Contains(err.Error(), "precondition failed") {
+		respondStatusError(w, http.StatusConflict, err.Error(), "ABORTED")
+		return
+	}
+	respondStatusStoreError(w, err)
+}
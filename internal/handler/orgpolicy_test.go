@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/katharinasick/gcp-api-mock/internal/orgpolicy"
+	"github.com/katharinasick/gcp-api-mock/internal/store"
+)
+
+const testOrgPolicyName = "projects/test-project/policies/sql.restrictPublicIp"
+
+func setupTestOrgPolicy() (*OrgPolicy, *store.Store) {
+	s := store.New()
+	return NewOrgPolicy(s), s
+}
+
+func TestOrgPolicy_CreatePolicy(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{"valid", `{"name": "` + testOrgPolicyName + `", "spec": {"rules": [{"enforce": true}]}}`, http.StatusOK},
+		{"values for a boolean constraint", `{"name": "` + testOrgPolicyName + `", "spec": {"rules": [{"allowAll": true}]}}`, http.StatusBadRequest},
+		{"other project", `{"name": "projects/other/policies/sql.restrictPublicIp"}`, http.StatusBadRequest},
+		{"invalid body", `{`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, _ := setupTestOrgPolicy()
+
+			req := httptest.NewRequest(http.MethodPost, "/v2/projects/test-project/policies", strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+			serveRoute("POST /v2/projects/{project}/policies", h.CreatePolicy, rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestOrgPolicy_UpdatePolicy(t *testing.T) {
+	h, s := setupTestOrgPolicy()
+	enforce := true
+	policy, err := s.CreateOrgPolicy("projects/test-project", &orgpolicy.Policy{
+		Name: testOrgPolicyName,
+		Spec: &orgpolicy.PolicySpec{Rules: []*orgpolicy.PolicyRule{{Enforce: &enforce}}},
+	})
+	if err != nil {
+		t.Fatalf("CreateOrgPolicy() error: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		body           string
+		expectedStatus int
+	}{
+		{"stale etag", "", `{"etag": "\"stale\"", "spec": {"reset": true}}`, http.StatusConflict},
+		{"other name", "", `{"name": "projects/test-project/policies/other", "spec": {"reset": true}}`, http.StatusBadRequest},
+		{"invalid updateMask", "?updateMask=name", `{"spec": {"reset": true}}`, http.StatusBadRequest},
+		{"valid", "?updateMask=spec", `{"etag": ` + strconv.Quote(policy.Etag) + `, "spec": {"reset": true}}`, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPatch, "/v2/"+testOrgPolicyName+tt.query, strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+			serveRoute("PATCH /v2/projects/{project}/policies/{policy}", h.UpdatePolicy, rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+
+	if updated := s.GetOrgPolicy(testOrgPolicyName); !updated.Spec.Reset || updated.Enforced() {
+		t.Errorf("expected the policy to be reset, got %+v", updated.Spec)
+	}
+}
+
+func TestOrgPolicy_ListAndDelete(t *testing.T) {
+	h, s := setupTestOrgPolicy()
+	for _, constraint := range orgpolicy.BooleanConstraints {
+		if _, err := s.CreateOrgPolicy("projects/test-project", &orgpolicy.Policy{Name: "projects/test-project/policies/" + constraint}); err != nil {
+			t.Fatalf("CreateOrgPolicy() error: %v", err)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/v2/projects/test-project/policies?pageSize=3", nil)
+	rr := httptest.NewRecorder()
+	serveRoute("GET /v2/projects/{project}/policies", h.ListPolicies, rr, req)
+
+	var list orgpolicy.ListPoliciesResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &list); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if len(list.Policies) != 3 || list.NextPageToken == "" {
+		t.Errorf("expected a page of 3 policies and a next page token, got %+v", list)
+	}
+
+	for _, expectedStatus := range []int{http.StatusOK, http.StatusNotFound} {
+		req := httptest.NewRequest(http.MethodDelete, "/v2/"+testOrgPolicyName, nil)
+		rr := httptest.NewRecorder()
+		serveRoute("DELETE /v2/projects/{project}/policies/{policy}", h.DeletePolicy, rr, req)
+
+		if rr.Code != expectedStatus {
+			t.Errorf("expected status %d, got %d: %s", expectedStatus, rr.Code, rr.Body.String())
+		}
+	}
+}
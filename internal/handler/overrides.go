@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/katharinasick/gcp-api-mock/internal/override"
+)
+
+// Overrides handles the admin API of the response overrides.
+type Overrides struct {
+	overrides *override.Overrides
+}
+
+// NewOverrides creates a new Overrides handler.
+func NewOverrides(overrides *override.Overrides) *Overrides {
+	return &Overrides{overrides: overrides}
+}
+
+// OverrideList is the response body listing the active overrides.
+type OverrideList struct {
+	Items []override.Rule `json:"items"`
+}
+
+// List handles GET /admin/overrides - List the active overrides in the order they match,
+// with how many requests each answered.
+func (h *Overrides) List(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, OverrideList{Items: h.overrides.List()})
+}
+
+// Create handles POST /admin/overrides - Answer matching requests with a fixed response, e.g.
+// {"method": "GET", "path": "/storage/v1/b/foo", "status": 200, "body": {"name": "foo"}, "times": 1}.
+// Without times, the override answers requests until it is deleted.
+func (h *Overrides) Create(w http.ResponseWriter, r *http.Request) {
+	var rule override.Rule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid JSON body", "invalid")
+		return
+	}
+
+	created, err := h.overrides.Add(rule)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, created)
+}
+
+// Delete handles DELETE /admin/overrides/{id} - Remove an override.
+func (h *Overrides) Delete(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if !h.overrides.Delete(id) {
+		respondError(w, http.StatusNotFound, "Override "+id+" not found", "notFound")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Clear handles DELETE /admin/overrides - Remove all overrides.
+func (h *Overrides) Clear(w http.ResponseWriter, r *http.Request) {
+	h.overrides.Clear()
+
+	w.WriteHeader(http.StatusNoContent)
+}
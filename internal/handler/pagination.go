@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Page size limits matching the real APIs.
+const (
+	// storageDefaultMaxResults is the default and maximum page size for Cloud Storage list calls.
+	storageDefaultMaxResults = 1000
+	// sqlDefaultMaxResults is the default page size for Cloud SQL list calls.
+	sqlDefaultMaxResults = 500
+	// sqlMaxMaxResults is the maximum page size for Cloud SQL list calls.
+	sqlMaxMaxResults = 1000
+)
+
+// parseMaxResults parses a maxResults query parameter.
+// An empty value returns defaultValue, values above maxValue are capped like the real APIs do.
+func parseMaxResults(value string, defaultValue, maxValue int) (int, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	maxResults, err := strconv.Atoi(value)
+	if err != nil || maxResults < 1 {
+		return 0, fmt.Errorf("invalid maxResults: %s", value)
+	}
+
+	if maxResults > maxValue {
+		maxResults = maxValue
+	}
+
+	return maxResults, nil
+}
+
+// paginate returns the page of items selected by pageToken and maxResults,
+// along with the token for the next page (empty if this is the last page).
+// Items must be sorted by key in ascending order, and keys must be unique. Page tokens are opaque
+// to clients and encode the key of the last item of the previous page; the next page starts right
+// after it, so items deleted between pages, like by cleanup code that lists and deletes page by page,
+// don't shift later items onto pages the client already saw.
+func paginate[T any](items []T, pageToken string, maxResults int, key func(T) string) ([]T, string, error) {
+	return paginateSorted(items, pageToken, maxResults, key, false)
+}
+
+// paginateDescending is paginate for items sorted by key in descending order, like newest first.
+func paginateDescending[T any](items []T, pageToken string, maxResults int, key func(T) string) ([]T, string, error) {
+	return paginateSorted(items, pageToken, maxResults, key, true)
+}
+
+// paginateSorted returns a page of items sorted by key in ascending or descending order.
+func paginateSorted[T any](items []T, pageToken string, maxResults int, key func(T) string, descending bool) ([]T, string, error) {
+	start := 0
+	if pageToken != "" {
+		last, err := decodePageToken(pageToken)
+		if err != nil {
+			return nil, "", err
+		}
+		start = sort.Search(len(items), func(i int) bool {
+			if descending {
+				return key(items[i]) < last
+			}
+			return key(items[i]) > last
+		})
+	}
+
+	end := start + maxResults
+	if end >= len(items) {
+		return items[start:], "", nil
+	}
+
+	return items[start:end], encodePageToken(key(items[end-1])), nil
+}
+
+// encodePageToken encodes the key of the last item of a page into an opaque page token.
+func encodePageToken(key string) string {
+	return base64.URLEncoding.EncodeToString([]byte(key))
+}
+
+// decodePageToken decodes an opaque page token into the key of the last item of the previous page.
+func decodePageToken(pageToken string) (string, error) {
+	decoded, err := base64.URLEncoding.DecodeString(pageToken)
+	if err != nil || len(decoded) == 0 {
+		return "", fmt.Errorf("invalid pageToken: %s", pageToken)
+	}
+	return string(decoded), nil
+}
+
+// sortableTime formats a time so that the formatted times sort like the times, for page keys.
+func sortableTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000000000")
+}
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseMaxResults(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{name: "empty uses default", value: "", want: 500},
+		{name: "valid value", value: "10", want: 10},
+		{name: "capped at max", value: "5000", want: 1000},
+		{name: "zero", value: "0", wantErr: true},
+		{name: "negative", value: "-1", wantErr: true},
+		{name: "not a number", value: "abc", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseMaxResults(tt.value, 500, 1000)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseMaxResults() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseMaxResults() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	items := []string{"a", "b", "c", "d", "e"}
+
+	var collected []string
+	pageToken := ""
+	pages := 0
+	for {
+		page, next, err := paginate(items, pageToken, 2, identity)
+		if err != nil {
+			t.Fatalf("paginate() error: %v", err)
+		}
+		collected = append(collected, page...)
+		pages++
+		if next == "" {
+			break
+		}
+		pageToken = next
+	}
+
+	if pages != 3 {
+		t.Errorf("expected 3 pages, got %d", pages)
+	}
+	if len(collected) != len(items) {
+		t.Errorf("expected %d items, got %d", len(items), len(collected))
+	}
+	for i := range items {
+		if collected[i] != items[i] {
+			t.Errorf("item %d = %s, want %s", i, collected[i], items[i])
+		}
+	}
+}
+
+func TestPaginate_DeleteBetweenPages(t *testing.T) {
+	// Cleanup code lists a page and deletes its items before it asks for the next page
+	items := []string{"a", "b", "c", "d", "e"}
+
+	var deleted []string
+	pageToken := ""
+	for {
+		page, next, err := paginate(items, pageToken, 2, identity)
+		if err != nil {
+			t.Fatalf("paginate() error: %v", err)
+		}
+		deleted = append(deleted, page...)
+		items = slices.DeleteFunc(items, func(item string) bool { return slices.Contains(page, item) })
+		if next == "" {
+			break
+		}
+		pageToken = next
+	}
+
+	if len(items) != 0 {
+		t.Errorf("expected all items to be deleted, left %v", items)
+	}
+	if !slices.Equal(deleted, []string{"a", "b", "c", "d", "e"}) {
+		t.Errorf("expected to delete a to e in order, deleted %v", deleted)
+	}
+}
+
+func TestPaginateDescending(t *testing.T) {
+	items := []string{"e", "d", "c", "b", "a"}
+
+	page, next, err := paginateDescending(items, "", 2, identity)
+	if err != nil || !slices.Equal(page, []string{"e", "d"}) {
+		t.Fatalf("expected first page [e d], got %v, %v", page, err)
+	}
+
+	// The last item of the page is gone, but the next page still starts after it
+	page, next, err = paginateDescending([]string{"e", "c", "b", "a"}, next, 2, identity)
+	if err != nil || !slices.Equal(page, []string{"c", "b"}) || next == "" {
+		t.Errorf("expected second page [c b] with a next page, got %v, %q, %v", page, next, err)
+	}
+}
+
+func TestPaginate_InvalidToken(t *testing.T) {
+	_, _, err := paginate([]string{"a"}, "not-a-token!", 10, identity)
+	if err == nil {
+		t.Error("expected error for invalid page token")
+	}
+}
+
+// identity is the page key of string items.
+func identity(s string) string {
+	return s
+}
@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/katharinasick/gcp-api-mock/internal/fieldmask"
+)
+
+// writePartialJSON writes a JSON response the way Google APIs do: only with the fields the fields query parameter
+// selects, like "items(name,size),nextPageToken", and indented with prettyPrint=true. Unlike Google APIs,
+// responses are compact by default, as the mock has always answered.
+// Returns an error without writing anything if the field selection is invalid.
+func writePartialJSON(w http.ResponseWriter, r *http.Request, statusCode int, data interface{}) error {
+	query := r.URL.Query()
+	mask, err := fieldmask.Parse(query.Get("fields"))
+	if err != nil {
+		return err
+	}
+	if data, err = mask.Select(data); err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	enc := json.NewEncoder(w)
+	if pretty, _ := strconv.ParseBool(query.Get("prettyPrint")); pretty {
+		enc.SetIndent("", "  ")
+	}
+	enc.Encode(data)
+	return nil
+}
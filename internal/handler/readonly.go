@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/katharinasick/gcp-api-mock/internal/readonly"
+)
+
+// ReadOnly handles the admin API of the read-only mode.
+type ReadOnly struct {
+	sw *readonly.Switch
+}
+
+// NewReadOnly creates a new ReadOnly handler.
+func NewReadOnly(sw *readonly.Switch) *ReadOnly {
+	return &ReadOnly{sw: sw}
+}
+
+// ReadOnlyMode is the request and response body of the read-only mode.
+type ReadOnlyMode struct {
+	Mode readonly.Mode `json:"mode"`
+}
+
+// Get handles GET /admin/readonly - Get the read-only mode.
+func (h *ReadOnly) Get(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, ReadOnlyMode{Mode: h.sw.Mode()})
+}
+
+// Set handles PUT /admin/readonly - Change the read-only mode, e.g. {"mode": "success"}.
+// In success mode mutating API requests get a synthetic success, in reject mode they get 403,
+// and "off" serves them as usual again.
+func (h *ReadOnly) Set(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Mode string `json:"mode"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid JSON body", "invalid")
+		return
+	}
+
+	mode, err := readonly.ParseMode(req.Mode)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+		return
+	}
+	h.sw.SetMode(mode)
+
+	respondJSON(w, http.StatusOK, ReadOnlyMode{Mode: mode})
+}
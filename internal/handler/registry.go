@@ -0,0 +1,322 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/registry"
+	"github.com/katharinasick/gcp-api-mock/internal/store"
+)
+
+// Registry handles the Docker Registry v2 API, which Artifact Registry and Container Registry implement.
+// Repository names may contain slashes (e.g. "my-project/my-repo/my-image"), so requests are routed
+// by parsing the path rather than by route patterns.
+// Reference: https://distribution.github.io/distribution/spec/api/
+type Registry struct {
+	store *store.Store
+}
+
+// NewRegistry creates a new Registry handler.
+func NewRegistry(s *store.Store) *Registry {
+	return &Registry{store: s}
+}
+
+// maxManifestSize is the maximum size of a manifest, matching common registry limits.
+const maxManifestSize = 4 << 20
+
+// Base handles GET /v2/ - API version check. Clients call it first to detect registry support.
+func (h *Registry) Base(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Docker-Distribution-API-Version", "registry/2.0")
+	respondJSON(w, http.StatusOK, struct{}{})
+}
+
+// Catalog handles GET /v2/_catalog - List repositories.
+func (h *Registry) Catalog(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Docker-Distribution-API-Version", "registry/2.0")
+	respondJSON(w, http.StatusOK, &registry.Catalog{Repositories: h.store.ListRepositories()})
+}
+
+// Dispatch handles all other /v2/ requests by parsing the repository name and the resource from the path.
+// Repository names contain slashes, so they can't be matched with a wildcard of the route pattern.
+func (h *Registry) Dispatch(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Docker-Distribution-API-Version", "registry/2.0")
+
+	name, resource, reference := parseRegistryPath(r.PathValue("path"))
+	if name == "" {
+		respondRegistryError(w, http.StatusNotFound, registry.ErrorCodeNameInvalid, "invalid repository name")
+		return
+	}
+
+	// HEAD requests are served like GET requests, without the body
+	isRead := r.Method == http.MethodGet || r.Method == http.MethodHead
+
+	switch {
+	case resource == "uploads" && r.Method == http.MethodPost:
+		h.startUpload(w, r, name)
+	case resource == "uploads" && r.Method == http.MethodPatch:
+		h.appendUpload(w, r, name, reference)
+	case resource == "uploads" && r.Method == http.MethodPut:
+		h.completeUpload(w, r, name, reference)
+	case resource == "uploads" && isRead:
+		h.getUpload(w, r, name, reference)
+	case resource == "uploads" && r.Method == http.MethodDelete:
+		h.cancelUpload(w, r, name, reference)
+	case resource == "blobs" && isRead:
+		h.getBlob(w, r, name, reference)
+	case resource == "manifests" && r.Method == http.MethodPut:
+		h.putManifest(w, r, name, reference)
+	case resource == "manifests" && isRead:
+		h.getManifest(w, r, name, reference)
+	case resource == "manifests" && r.Method == http.MethodDelete:
+		h.deleteManifest(w, r, name, reference)
+	case resource == "tags" && isRead:
+		h.listTags(w, r, name)
+	default:
+		respondRegistryError(w, http.StatusMethodNotAllowed, registry.ErrorCodeUnsupported, "The operation is unsupported.")
+	}
+}
+
+// =============================================================================
+// Blob Upload Handlers
+// =============================================================================
+
+// startUpload handles POST /v2/{name}/blobs/uploads/ - Start a blob upload.
+// Supports cross-repository mounts (?mount=&from=) and monolithic uploads (?digest=).
+func (h *Registry) startUpload(w http.ResponseWriter, r *http.Request, name string) {
+	query := r.URL.Query()
+
+	// Mount an existing blob instead of uploading it; if it doesn't exist, fall back to an upload
+	if mount := query.Get("mount"); mount != "" && h.store.MountRegistryBlob(name, mount) {
+		respondBlobCreated(w, name, mount)
+		return
+	}
+
+	id, err := h.store.StartRegistryUpload(name)
+	if err != nil {
+		respondRegistryError(w, http.StatusInternalServerError, registry.ErrorCodeBlobUploadInvalid, err.Error())
+		return
+	}
+
+	if digest := query.Get("digest"); digest != "" {
+		h.completeUpload(w, r, name, id)
+		return
+	}
+
+	respondUploadAccepted(w, name, id, 0)
+}
+
+// appendUpload handles PATCH /v2/{name}/blobs/uploads/{uuid} - Upload a chunk.
+func (h *Registry) appendUpload(w http.ResponseWriter, r *http.Request, name, id string) {
+	size, err := h.store.AppendRegistryUpload(name, id, r.Body)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondRegistryError(w, http.StatusNotFound, registry.ErrorCodeBlobUploadUnknown, "blob upload unknown to registry")
+			return
+		}
+		respondRegistryError(w, http.StatusInternalServerError, registry.ErrorCodeBlobUploadInvalid, err.Error())
+		return
+	}
+
+	respondUploadAccepted(w, name, id, size)
+}
+
+// completeUpload handles PUT /v2/{name}/blobs/uploads/{uuid}?digest= - Complete a blob upload.
+// The request body is an optional final chunk.
+func (h *Registry) completeUpload(w http.ResponseWriter, r *http.Request, name, id string) {
+	digest := r.URL.Query().Get("digest")
+	if digest == "" {
+		respondRegistryError(w, http.StatusBadRequest, registry.ErrorCodeDigestInvalid, "digest is required")
+		return
+	}
+
+	if err := h.store.CompleteRegistryUpload(name, id, digest, r.Body); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondRegistryError(w, http.StatusNotFound, registry.ErrorCodeBlobUploadUnknown, "blob upload unknown to registry")
+			return
+		}
+		if strings.Contains(err.Error(), "invalid digest") {
+			respondRegistryError(w, http.StatusBadRequest, registry.ErrorCodeDigestInvalid, err.Error())
+			return
+		}
+		respondRegistryError(w, http.StatusInternalServerError, registry.ErrorCodeBlobUploadInvalid, err.Error())
+		return
+	}
+
+	respondBlobCreated(w, name, digest)
+}
+
+// getUpload handles GET /v2/{name}/blobs/uploads/{uuid} - Get the status of a blob upload.
+func (h *Registry) getUpload(w http.ResponseWriter, r *http.Request, name, id string) {
+	size, err := h.store.GetRegistryUploadSize(name, id)
+	if err != nil {
+		respondRegistryError(w, http.StatusNotFound, registry.ErrorCodeBlobUploadUnknown, "blob upload unknown to registry")
+		return
+	}
+
+	w.Header().Set("Docker-Upload-UUID", id)
+	w.Header().Set("Range", uploadRange(size))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// cancelUpload handles DELETE /v2/{name}/blobs/uploads/{uuid} - Cancel a blob upload.
+func (h *Registry) cancelUpload(w http.ResponseWriter, r *http.Request, name, id string) {
+	if err := h.store.CancelRegistryUpload(name, id); err != nil {
+		respondRegistryError(w, http.StatusNotFound, registry.ErrorCodeBlobUploadUnknown, "blob upload unknown to registry")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// =============================================================================
+// Blob and Manifest Handlers
+// =============================================================================
+
+// getBlob handles GET and HEAD /v2/{name}/blobs/{digest} - Download a blob.
+// Range requests are supported so clients can resume downloads.
+func (h *Registry) getBlob(w http.ResponseWriter, r *http.Request, name, digest string) {
+	_, content, err := h.store.OpenRegistryBlob(name, digest)
+	if err != nil {
+		respondRegistryError(w, http.StatusNotFound, registry.ErrorCodeBlobUnknown, "blob unknown to registry")
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Docker-Content-Digest", digest)
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("ETag", `"`+digest+`"`)
+	http.ServeContent(w, r, "", time.Time{}, content)
+}
+
+// putManifest handles PUT /v2/{name}/manifests/{reference} - Push a manifest by tag or digest.
+func (h *Registry) putManifest(w http.ResponseWriter, r *http.Request, name, reference string) {
+	content, err := io.ReadAll(io.LimitReader(r.Body, maxManifestSize+1))
+	if err != nil || len(content) > maxManifestSize || !json.Valid(content) {
+		respondRegistryError(w, http.StatusBadRequest, registry.ErrorCodeManifestInvalid, "manifest invalid")
+		return
+	}
+
+	mediaType := r.Header.Get("Content-Type")
+	if mediaType == "" {
+		// Fall back to the media type declared in the manifest itself
+		var declared struct {
+			MediaType string `json:"mediaType"`
+		}
+		json.Unmarshal(content, &declared)
+		mediaType = declared.MediaType
+	}
+
+	manifest, err := h.store.PutManifest(name, reference, mediaType, content)
+	if err != nil {
+		respondRegistryError(w, http.StatusBadRequest, registry.ErrorCodeDigestInvalid, err.Error())
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/v2/%s/manifests/%s", name, manifest.Digest))
+	w.Header().Set("Docker-Content-Digest", manifest.Digest)
+	w.WriteHeader(http.StatusCreated)
+}
+
+// getManifest handles GET and HEAD /v2/{name}/manifests/{reference} - Pull a manifest by tag or digest.
+func (h *Registry) getManifest(w http.ResponseWriter, r *http.Request, name, reference string) {
+	manifest := h.store.GetManifest(name, reference)
+	if manifest == nil {
+		respondRegistryError(w, http.StatusNotFound, registry.ErrorCodeManifestUnknown, "manifest unknown")
+		return
+	}
+
+	w.Header().Set("Content-Type", manifest.MediaType)
+	w.Header().Set("Docker-Content-Digest", manifest.Digest)
+	w.Header().Set("Content-Length", strconv.Itoa(len(manifest.Content)))
+	w.Header().Set("ETag", `"`+manifest.Digest+`"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write(manifest.Content)
+}
+
+// deleteManifest handles DELETE /v2/{name}/manifests/{digest} - Delete a manifest and its tags.
+func (h *Registry) deleteManifest(w http.ResponseWriter, r *http.Request, name, digest string) {
+	if !strings.HasPrefix(digest, "sha256:") {
+		respondRegistryError(w, http.StatusBadRequest, registry.ErrorCodeDigestInvalid, "manifests can only be deleted by digest")
+		return
+	}
+
+	if err := h.store.DeleteManifest(name, digest); err != nil {
+		respondRegistryError(w, http.StatusNotFound, registry.ErrorCodeManifestUnknown, "manifest unknown")
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// listTags handles GET /v2/{name}/tags/list - List the tags of a repository.
+func (h *Registry) listTags(w http.ResponseWriter, r *http.Request, name string) {
+	tags, err := h.store.ListTags(name)
+	if err != nil {
+		respondRegistryError(w, http.StatusNotFound, registry.ErrorCodeNameUnknown, "repository name not known to registry")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, &registry.TagList{Name: name, Tags: tags})
+}
+
+// =============================================================================
+// Helper Functions
+// =============================================================================
+
+// parseRegistryPath splits the path below /v2/, like {name}/{resource}/{reference}, into the repository name,
+// the resource ("uploads", "blobs", "manifests" or "tags") and the reference (upload ID, digest or tag).
+func parseRegistryPath(path string) (string, string, string) {
+	if name, found := strings.CutSuffix(path, "/tags/list"); found {
+		return name, "tags", ""
+	}
+
+	for _, marker := range []struct{ separator, resource string }{
+		{"/blobs/uploads", "uploads"},
+		{"/blobs/", "blobs"},
+		{"/manifests/", "manifests"},
+	} {
+		if idx := strings.LastIndex(path, marker.separator); idx > 0 {
+			reference := strings.TrimPrefix(path[idx+len(marker.separator):], "/")
+			return path[:idx], marker.resource, reference
+		}
+	}
+
+	return "", "", ""
+}
+
+// uploadRange formats the Range header of an upload with size bytes, e.g. "0-41".
+func uploadRange(size int64) string {
+	if size == 0 {
+		return "0-0"
+	}
+	return fmt.Sprintf("0-%d", size-1)
+}
+
+// respondUploadAccepted writes the response for a started or continued blob upload.
+func respondUploadAccepted(w http.ResponseWriter, name, id string, size int64) {
+	w.Header().Set("Location", fmt.Sprintf("/v2/%s/blobs/uploads/%s", name, id))
+	w.Header().Set("Docker-Upload-UUID", id)
+	w.Header().Set("Range", uploadRange(size))
+	w.Header().Set("Content-Length", "0")
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// respondBlobCreated writes the response for a completed or mounted blob.
+func respondBlobCreated(w http.ResponseWriter, name, digest string) {
+	w.Header().Set("Location", fmt.Sprintf("/v2/%s/blobs/%s", name, digest))
+	w.Header().Set("Docker-Content-Digest", digest)
+	w.Header().Set("Content-Length", "0")
+	w.WriteHeader(http.StatusCreated)
+}
+
+// respondRegistryError writes a JSON error response matching the registry API format.
+func respondRegistryError(w http.ResponseWriter, statusCode int, code, message string) {
+	respondJSON(w, statusCode, registry.ErrorResponse{
+		Errors: []registry.Error{{Code: code, Message: message}},
+	})
+}
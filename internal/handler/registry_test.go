@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/katharinasick/gcp-api-mock/internal/registry"
+	"github.com/katharinasick/gcp-api-mock/internal/store"
+)
+
+func setupTestRegistry() (*Registry, *store.Store) {
+	s := store.New()
+	return NewRegistry(s), s
+}
+
+func sha256DigestOf(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func TestParseRegistryPath(t *testing.T) {
+	tests := []struct {
+		path      string
+		name      string
+		resource  string
+		reference string
+	}{
+		{"project/repo/image/blobs/uploads/", "project/repo/image", "uploads", ""},
+		{"project/repo/image/blobs/uploads/abc-123", "project/repo/image", "uploads", "abc-123"},
+		{"image/blobs/sha256:abc", "image", "blobs", "sha256:abc"},
+		{"project/image/manifests/latest", "project/image", "manifests", "latest"},
+		{"project/image/tags/list", "project/image", "tags", ""},
+		{"unknown", "", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			name, resource, reference := parseRegistryPath(tt.path)
+			if name != tt.name || resource != tt.resource || reference != tt.reference {
+				t.Errorf("parseRegistryPath() = %q, %q, %q", name, resource, reference)
+			}
+		})
+	}
+}
+
+func TestRegistry_PushAndPull(t *testing.T) {
+	h, _ := setupTestRegistry()
+	const repo = "/v2/my-project/my-repo/my-image"
+	layer := "layer content"
+
+	// Start a chunked upload
+	req := httptest.NewRequest(http.MethodPost, repo+"/blobs/uploads/", nil)
+	rr := httptest.NewRecorder()
+	serveRoute("/v2/{path...}", h.Dispatch, rr, req)
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusAccepted, rr.Code, rr.Body.String())
+	}
+	location := rr.Header().Get("Location")
+
+	req = httptest.NewRequest(http.MethodPatch, location, strings.NewReader(layer))
+	rr = httptest.NewRecorder()
+	serveRoute("/v2/{path...}", h.Dispatch, rr, req)
+	if rr.Code != http.StatusAccepted || rr.Header().Get("Range") != "0-12" {
+		t.Fatalf("expected 202 with range 0-12, got %d %s", rr.Code, rr.Header().Get("Range"))
+	}
+
+	req = httptest.NewRequest(http.MethodPut, location+"?digest="+sha256DigestOf(layer), nil)
+	rr = httptest.NewRecorder()
+	serveRoute("/v2/{path...}", h.Dispatch, rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+
+	// Push a manifest by tag
+	manifest := `{"schemaVersion":2,"mediaType":"application/vnd.oci.image.manifest.v1+json","layers":[]}`
+	req = httptest.NewRequest(http.MethodPut, repo+"/manifests/v1", strings.NewReader(manifest))
+	req.Header.Set("Content-Type", "application/vnd.oci.image.manifest.v1+json")
+	rr = httptest.NewRecorder()
+	serveRoute("/v2/{path...}", h.Dispatch, rr, req)
+	if rr.Code != http.StatusCreated || rr.Header().Get("Docker-Content-Digest") != sha256DigestOf(manifest) {
+		t.Fatalf("expected 201 with digest, got %d %s", rr.Code, rr.Header().Get("Docker-Content-Digest"))
+	}
+
+	// Pull it back
+	req = httptest.NewRequest(http.MethodGet, repo+"/manifests/v1", nil)
+	rr = httptest.NewRecorder()
+	serveRoute("/v2/{path...}", h.Dispatch, rr, req)
+	if rr.Body.String() != manifest || rr.Header().Get("Content-Type") != "application/vnd.oci.image.manifest.v1+json" {
+		t.Errorf("unexpected manifest response: %s %s", rr.Header().Get("Content-Type"), rr.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodHead, repo+"/blobs/"+sha256DigestOf(layer), nil)
+	rr = httptest.NewRecorder()
+	serveRoute("/v2/{path...}", h.Dispatch, rr, req)
+	if rr.Code != http.StatusOK || rr.Header().Get("Content-Length") != "13" {
+		t.Errorf("expected 200 with length 13, got %d %s", rr.Code, rr.Header().Get("Content-Length"))
+	}
+
+	req = httptest.NewRequest(http.MethodGet, repo+"/tags/list", nil)
+	rr = httptest.NewRecorder()
+	serveRoute("/v2/{path...}", h.Dispatch, rr, req)
+	var tags registry.TagList
+	if err := json.NewDecoder(rr.Body).Decode(&tags); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if tags.Name != "my-project/my-repo/my-image" || len(tags.Tags) != 1 || tags.Tags[0] != "v1" {
+		t.Errorf("unexpected tag list: %+v", tags)
+	}
+}
+
+func TestRegistry_Errors(t *testing.T) {
+	h, _ := setupTestRegistry()
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{"unknown manifest", http.MethodGet, "/v2/repo/manifests/latest", http.StatusNotFound, registry.ErrorCodeManifestUnknown},
+		{"unknown blob", http.MethodGet, "/v2/repo/blobs/sha256:abc", http.StatusNotFound, registry.ErrorCodeBlobUnknown},
+		{"unknown upload", http.MethodPatch, "/v2/repo/blobs/uploads/missing", http.StatusNotFound, registry.ErrorCodeBlobUploadUnknown},
+		{"unknown repository", http.MethodGet, "/v2/repo/tags/list", http.StatusNotFound, registry.ErrorCodeNameUnknown},
+		{"delete by tag", http.MethodDelete, "/v2/repo/manifests/latest", http.StatusBadRequest, registry.ErrorCodeDigestInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			rr := httptest.NewRecorder()
+			serveRoute("/v2/{path...}", h.Dispatch, rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+
+			var errResp registry.ErrorResponse
+			if err := json.NewDecoder(rr.Body).Decode(&errResp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if len(errResp.Errors) != 1 || errResp.Errors[0].Code != tt.expectedCode {
+				t.Errorf("expected code %s, got %+v", tt.expectedCode, errResp.Errors)
+			}
+		})
+	}
+}
@@ -0,0 +1,520 @@
+package handler
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/s3"
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+	"github.com/katharinasick/gcp-api-mock/internal/store"
+)
+
+// S3 handles the S3 compatibility layer. It maps S3 bucket and object calls to the same
+// store the Cloud Storage endpoints use, so S3 tooling sees the same data.
+// Only path-style requests are supported, and multipart uploads are not implemented.
+type S3 struct {
+	store     *store.Store
+	accessKey string
+	secretKey string
+	strict    bool
+}
+
+// NewS3 creates a new S3 handler.
+// Requests signed with a Cloud Storage HMAC key are verified against the key's secret. If accessKey
+// and secretKey are set, other requests are verified against them, otherwise any signature is accepted
+// unless strict is set, like for strict auth.
+func NewS3(s *store.Store, accessKey, secretKey string, strict bool) *S3 {
+	return &S3{store: s, accessKey: accessKey, secretKey: secretKey, strict: strict}
+}
+
+// s3DefaultMaxKeys is the default and maximum number of entries in an S3 listing.
+const s3DefaultMaxKeys = 1000
+
+// Authenticate creates middleware that verifies request signatures made with an HMAC key of the store
+// or, if credentials are configured, with those. Signatures that can't be verified are rejected in
+// strict mode.
+func (h *S3) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accessKey, secretKey := h.accessKey, h.secretKey
+		if signedWith, err := s3.AccessKey(r); err == nil {
+			if secret, active, ok := h.store.HmacKeySecret(signedWith); ok {
+				if !active {
+					respondS3Error(w, http.StatusForbidden, "InvalidAccessKeyId", "The HMAC key you provided is not active.", r.URL.Path)
+					return
+				}
+				accessKey, secretKey = signedWith, secret
+			}
+		}
+
+		if accessKey == "" || secretKey == "" {
+			if h.strict {
+				respondS3Error(w, http.StatusForbidden, "InvalidAccessKeyId", "The AWS Access Key Id you provided does not exist in our records.", r.URL.Path)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if err := s3.Verify(r, accessKey, secretKey); err != nil {
+			switch {
+			case strings.Contains(err.Error(), "invalid access key"):
+				respondS3Error(w, http.StatusForbidden, "InvalidAccessKeyId", "The AWS Access Key Id you provided does not exist in our records.", r.URL.Path)
+			case strings.Contains(err.Error(), "signature does not match"):
+				respondS3Error(w, http.StatusForbidden, "SignatureDoesNotMatch", "The request signature we calculated does not match the signature you provided.", r.URL.Path)
+			default:
+				respondS3Error(w, http.StatusForbidden, "AccessDenied", err.Error(), r.URL.Path)
+			}
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// =============================================================================
+// Bucket Handlers
+// =============================================================================
+
+// ListBuckets handles GET / - List buckets.
+// Reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_ListBuckets.html
+func (h *S3) ListBuckets(w http.ResponseWriter, r *http.Request) {
+	result := &s3.ListAllMyBucketsResult{
+		Xmlns: s3.Namespace,
+		Owner: s3.Owner{ID: "gcp-api-mock", DisplayName: "gcp-api-mock"},
+	}
+	for _, bucket := range h.store.ListBuckets() {
+		result.Buckets = append(result.Buckets, s3.Bucket{
+			Name:         bucket.Name,
+			CreationDate: bucket.TimeCreated.UTC().Format(time.RFC3339),
+		})
+	}
+
+	respondXML(w, http.StatusOK, result)
+}
+
+// GetBucket handles GET /{bucket} - List objects or, with ?location, get the bucket location.
+// Reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_ListObjectsV2.html
+func (h *S3) GetBucket(w http.ResponseWriter, r *http.Request) {
+	bucketName := r.PathValue("bucket")
+
+	bucket := h.store.GetBucket(bucketName)
+	if bucket == nil {
+		respondS3Error(w, http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist.", r.URL.Path)
+		return
+	}
+
+	if r.URL.Query().Has("location") {
+		respondXML(w, http.StatusOK, &s3.LocationConstraint{Xmlns: s3.Namespace, Location: bucket.Location})
+		return
+	}
+
+	h.listObjects(w, r, bucketName)
+}
+
+// listObjects lists objects for both ListObjects (marker) and ListObjectsV2 (list-type=2).
+func (h *S3) listObjects(w http.ResponseWriter, r *http.Request, bucketName string) {
+	query := r.URL.Query()
+	prefix := query.Get("prefix")
+	delimiter := query.Get("delimiter")
+	isV2 := query.Get("list-type") == "2"
+
+	maxKeys := s3DefaultMaxKeys
+	if value := query.Get("max-keys"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			respondS3Error(w, http.StatusBadRequest, "InvalidArgument", "Invalid max-keys: "+value, r.URL.Path)
+			return
+		}
+		if parsed < maxKeys {
+			maxKeys = parsed
+		}
+	}
+
+	result := &s3.ListBucketResult{
+		Xmlns:     s3.Namespace,
+		Name:      bucketName,
+		Prefix:    prefix,
+		MaxKeys:   maxKeys,
+		Delimiter: delimiter,
+	}
+
+	// The listing resumes after a key, taken from the marker (V1) or the continuation token or start-after (V2)
+	var after string
+	if isV2 {
+		result.StartAfter = query.Get("start-after")
+		result.ContinuationToken = query.Get("continuation-token")
+		after = result.StartAfter
+		if result.ContinuationToken != "" {
+			decoded, err := base64.URLEncoding.DecodeString(result.ContinuationToken)
+			if err != nil {
+				respondS3Error(w, http.StatusBadRequest, "InvalidArgument", "Invalid continuation-token", r.URL.Path)
+				return
+			}
+			after = string(decoded)
+		}
+	} else {
+		marker := query.Get("marker")
+		result.Marker = &marker
+		after = marker
+	}
+
+	objects, prefixes := h.store.ListObjects(bucketName, prefix, delimiter)
+
+	// Objects and prefixes both count towards max-keys
+	count := 0
+	last := ""
+	for _, entry := range mergeObjectListEntries(objects, prefixes) {
+		if entry.name <= after {
+			continue
+		}
+		if count == maxKeys {
+			result.IsTruncated = true
+			break
+		}
+
+		if entry.object != nil {
+			result.Contents = append(result.Contents, s3.Object{
+				Key:          entry.object.Name,
+				LastModified: entry.object.Updated.UTC().Format(time.RFC3339Nano),
+				ETag:         `"` + md5Hex(entry.object.Md5Hash) + `"`,
+				Size:         entry.object.Size,
+				StorageClass: "STANDARD",
+			})
+		} else {
+			result.CommonPrefixes = append(result.CommonPrefixes, s3.CommonPrefix{Prefix: entry.name})
+		}
+		last = entry.name
+		count++
+	}
+
+	if isV2 {
+		result.KeyCount = &count
+		if result.IsTruncated {
+			result.NextContinuationToken = base64.URLEncoding.EncodeToString([]byte(last))
+		}
+	} else if result.IsTruncated {
+		result.NextMarker = last
+	}
+
+	respondXML(w, http.StatusOK, result)
+}
+
+// CreateBucket handles PUT /{bucket} - Create a new bucket.
+// Reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_CreateBucket.html
+func (h *S3) CreateBucket(w http.ResponseWriter, r *http.Request) {
+	bucketName := r.PathValue("bucket")
+
+	req := &storage.BucketInsertRequest{Name: bucketName}
+
+	// The request body is optional
+	body, err := io.ReadAll(h.body(r))
+	if err != nil {
+		respondS3Error(w, http.StatusBadRequest, "IncompleteBody", "Failed to read request body", r.URL.Path)
+		return
+	}
+	if len(body) > 0 {
+		var cfg storage.CreateBucketConfiguration
+		if err := xml.Unmarshal(body, &cfg); err != nil {
+			respondS3Error(w, http.StatusBadRequest, "MalformedXML", "The XML you provided was not well-formed.", r.URL.Path)
+			return
+		}
+		req.Location = cfg.LocationConstraint
+	}
+
+	if _, err := h.store.CreateBucket(req); err != nil {
+		if strings.Contains(err.Error(), "already exists") {
+			respondS3Error(w, http.StatusConflict, "BucketAlreadyOwnedByYou", "Your previous request to create the named bucket succeeded and you already own it.", r.URL.Path)
+			return
+		}
+		if strings.Contains(err.Error(), "invalid bucket name") {
+			respondS3Error(w, http.StatusBadRequest, "InvalidBucketName", "The specified bucket is not valid.", r.URL.Path)
+			return
+		}
+		if strings.Contains(err.Error(), "invalid location") {
+			respondS3Error(w, http.StatusBadRequest, "InvalidLocationConstraint", "The specified location-constraint is not valid.", r.URL.Path)
+			return
+		}
+		respondS3Error(w, http.StatusInternalServerError, "InternalError", err.Error(), r.URL.Path)
+		return
+	}
+
+	w.Header().Set("Location", "/"+bucketName)
+	w.WriteHeader(http.StatusOK)
+}
+
+// DeleteBucket handles DELETE /{bucket} - Delete a bucket.
+// Reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_DeleteBucket.html
+func (h *S3) DeleteBucket(w http.ResponseWriter, r *http.Request) {
+	bucketName := r.PathValue("bucket")
+
+	if err := h.store.DeleteBucket(bucketName); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondS3Error(w, http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist.", r.URL.Path)
+			return
+		}
+		if strings.Contains(err.Error(), "not empty") {
+			respondS3Error(w, http.StatusConflict, "BucketNotEmpty", "The bucket you tried to delete is not empty.", r.URL.Path)
+			return
+		}
+		respondS3Error(w, http.StatusInternalServerError, "InternalError", err.Error(), r.URL.Path)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PostBucket handles POST /{bucket}?delete - Delete multiple objects.
+// Reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_DeleteObjects.html
+func (h *S3) PostBucket(w http.ResponseWriter, r *http.Request) {
+	bucketName := r.PathValue("bucket")
+
+	if !r.URL.Query().Has("delete") {
+		respondS3Error(w, http.StatusNotImplemented, "NotImplemented", "This operation is not supported by the mock.", r.URL.Path)
+		return
+	}
+
+	if h.store.GetBucket(bucketName) == nil {
+		respondS3Error(w, http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist.", r.URL.Path)
+		return
+	}
+
+	var req s3.Delete
+	if err := xml.NewDecoder(h.body(r)).Decode(&req); err != nil {
+		respondS3Error(w, http.StatusBadRequest, "MalformedXML", "The XML you provided was not well-formed.", r.URL.Path)
+		return
+	}
+
+	result := &s3.DeleteResult{Xmlns: s3.Namespace}
+	for _, obj := range req.Objects {
+		// Deleting a missing object succeeds in S3
+		err := h.store.DeleteObject(bucketName, obj.Key)
+		if err != nil && isRetained(err) {
+			result.Errors = append(result.Errors, s3.DeleteError{Key: obj.Key, Code: "AccessDenied", Message: err.Error()})
+			continue
+		}
+		if err != nil && !strings.Contains(err.Error(), "not found") {
+			result.Errors = append(result.Errors, s3.DeleteError{Key: obj.Key, Code: "InternalError", Message: err.Error()})
+			continue
+		}
+		if !req.Quiet {
+			result.Deleted = append(result.Deleted, s3.DeletedObject{Key: obj.Key})
+		}
+	}
+
+	respondXML(w, http.StatusOK, result)
+}
+
+// =============================================================================
+// Object Handlers
+// =============================================================================
+
+// GetObject handles GET and HEAD /{bucket}/{key} - Download an object.
+// Range requests and conditional headers are supported.
+// Reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetObject.html
+func (h *S3) GetObject(w http.ResponseWriter, r *http.Request) {
+	bucketName, key := r.PathValue("bucket"), r.PathValue("key")
+
+	if h.store.GetBucket(bucketName) == nil {
+		respondS3Error(w, http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist.", r.URL.Path)
+		return
+	}
+
+	obj, content, err := h.store.OpenObjectContent(bucketName, key)
+	if err != nil && strings.Contains(err.Error(), "evicted") {
+		respondS3Error(w, http.StatusGone, "NoSuchKey", err.Error(), r.URL.Path)
+		return
+	}
+	if err != nil {
+		respondS3Error(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.", r.URL.Path)
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("ETag", `"`+md5Hex(obj.Md5Hash)+`"`)
+	setObjectContentHeaders(w, obj)
+	for name, value := range obj.Metadata {
+		if isHeaderToken(name) {
+			w.Header().Set("X-Amz-Meta-"+name, value)
+		}
+	}
+
+	http.ServeContent(w, r, key, obj.Updated, content)
+}
+
+// PutObject handles PUT /{bucket}/{key} - Upload an object, or copy one if x-amz-copy-source is set.
+// Reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutObject.html
+func (h *S3) PutObject(w http.ResponseWriter, r *http.Request) {
+	bucketName, key := r.PathValue("bucket"), r.PathValue("key")
+
+	if key == "" {
+		respondS3Error(w, http.StatusBadRequest, "InvalidArgument", "Object key is required", r.URL.Path)
+		return
+	}
+
+	if r.URL.Query().Has("uploadId") {
+		respondS3Error(w, http.StatusNotImplemented, "NotImplemented", "Multipart uploads are not supported by the mock.", r.URL.Path)
+		return
+	}
+
+	if r.Header.Get("X-Amz-Copy-Source") != "" {
+		h.copyObject(w, r, bucketName, key)
+		return
+	}
+
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	// Chunked (aws-chunked) bodies carry signatures besides the content, so only the content is limited
+	if !s3.IsChunked(r) && !limitUploadBody(w, r, h.store.MaxObjectSize(), 0) {
+		respondS3Error(w, http.StatusRequestEntityTooLarge, "EntityTooLarge", "Your proposed upload exceeds the maximum allowed object size.", r.URL.Path)
+		return
+	}
+
+	obj, err := h.store.CreateObjectFromReader(bucketName, key, contentType, h.body(r), amzMetadata(r.Header))
+	if err != nil {
+		if isTooLarge(err) {
+			respondS3Error(w, http.StatusRequestEntityTooLarge, "EntityTooLarge", "Your proposed upload exceeds the maximum allowed object size.", r.URL.Path)
+			return
+		}
+		if strings.Contains(err.Error(), "not found") {
+			respondS3Error(w, http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist.", r.URL.Path)
+			return
+		}
+		if strings.Contains(err.Error(), "quota exceeded") || isRetained(err) {
+			respondS3Error(w, http.StatusForbidden, "AccessDenied", err.Error(), r.URL.Path)
+			return
+		}
+		if strings.Contains(err.Error(), "invalid object name") {
+			respondS3Error(w, http.StatusBadRequest, "InvalidArgument", err.Error(), r.URL.Path)
+			return
+		}
+		respondS3Error(w, http.StatusInternalServerError, "InternalError", err.Error(), r.URL.Path)
+		return
+	}
+
+	w.Header().Set("ETag", `"`+md5Hex(obj.Md5Hash)+`"`)
+	w.WriteHeader(http.StatusOK)
+}
+
+// copyObject copies the object named by the x-amz-copy-source header.
+// Reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_CopyObject.html
+func (h *S3) copyObject(w http.ResponseWriter, r *http.Request, bucketName, key string) {
+	source, _, _ := strings.Cut(r.Header.Get("X-Amz-Copy-Source"), "?")
+	if decoded, err := url.PathUnescape(source); err == nil {
+		source = decoded
+	}
+	sourceBucket, sourceKey, _ := strings.Cut(strings.TrimPrefix(source, "/"), "/")
+
+	sourceObj, content, err := h.store.OpenObjectContent(sourceBucket, sourceKey)
+	if err != nil && strings.Contains(err.Error(), "evicted") {
+		respondS3Error(w, http.StatusGone, "NoSuchKey", err.Error(), source)
+		return
+	}
+	if err != nil {
+		respondS3Error(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.", source)
+		return
+	}
+	defer content.Close()
+
+	// Metadata is copied from the source unless the request replaces it
+	contentType := sourceObj.ContentType
+	metadata := sourceObj.Metadata
+	if r.Header.Get("X-Amz-Metadata-Directive") == "REPLACE" {
+		contentType = r.Header.Get("Content-Type")
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		metadata = amzMetadata(r.Header)
+	}
+
+	obj, err := h.store.CreateObjectFromReader(bucketName, key, contentType, content, metadata)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondS3Error(w, http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist.", r.URL.Path)
+			return
+		}
+		if strings.Contains(err.Error(), "quota exceeded") || isRetained(err) {
+			respondS3Error(w, http.StatusForbidden, "AccessDenied", err.Error(), r.URL.Path)
+			return
+		}
+		if strings.Contains(err.Error(), "invalid object name") {
+			respondS3Error(w, http.StatusBadRequest, "InvalidArgument", err.Error(), r.URL.Path)
+			return
+		}
+		respondS3Error(w, http.StatusInternalServerError, "InternalError", err.Error(), r.URL.Path)
+		return
+	}
+
+	respondXML(w, http.StatusOK, &s3.CopyObjectResult{
+		LastModified: obj.Updated.UTC().Format(time.RFC3339Nano),
+		ETag:         `"` + md5Hex(obj.Md5Hash) + `"`,
+	})
+}
+
+// DeleteObject handles DELETE /{bucket}/{key} - Delete an object.
+// Like in S3, deleting a missing object succeeds.
+// Reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_DeleteObject.html
+func (h *S3) DeleteObject(w http.ResponseWriter, r *http.Request) {
+	bucketName, key := r.PathValue("bucket"), r.PathValue("key")
+
+	if h.store.GetBucket(bucketName) == nil {
+		respondS3Error(w, http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist.", r.URL.Path)
+		return
+	}
+
+	if err := h.store.DeleteObject(bucketName, key); err != nil && !strings.Contains(err.Error(), "not found") {
+		if isRetained(err) {
+			respondS3Error(w, http.StatusForbidden, "AccessDenied", err.Error(), r.URL.Path)
+			return
+		}
+		respondS3Error(w, http.StatusInternalServerError, "InternalError", err.Error(), r.URL.Path)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PostObject handles POST /{bucket}/{key} - Multipart upload calls, which are not supported.
+func (h *S3) PostObject(w http.ResponseWriter, r *http.Request) {
+	respondS3Error(w, http.StatusNotImplemented, "NotImplemented", "Multipart uploads are not supported by the mock.", r.URL.Path)
+}
+
+// =============================================================================
+// Helper Functions
+// =============================================================================
+
+// body returns the request body, decoding the aws-chunked encoding of streaming uploads.
+func (h *S3) body(r *http.Request) io.Reader {
+	if s3.IsChunked(r) {
+		return s3.NewChunkedReader(r.Body)
+	}
+	return r.Body
+}
+
+// amzMetadata collects custom metadata from x-amz-meta-* headers.
+func amzMetadata(header http.Header) map[string]string {
+	var metadata map[string]string
+	for key, values := range header {
+		metaKey, found := strings.CutPrefix(strings.ToLower(key), "x-amz-meta-")
+		if found && len(values) > 0 {
+			if metadata == nil {
+				metadata = make(map[string]string)
+			}
+			metadata[metaKey] = values[0]
+		}
+	}
+	return metadata
+}
+
+// respondS3Error writes an error response matching the S3 format.
+func respondS3Error(w http.ResponseWriter, statusCode int, code, message, resource string) {
+	respondXML(w, statusCode, s3.Error{Code: code, Message: message, Resource: resource})
+}
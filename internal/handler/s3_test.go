@@ -0,0 +1,242 @@
+package handler
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/katharinasick/gcp-api-mock/internal/s3"
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+	"github.com/katharinasick/gcp-api-mock/internal/store"
+)
+
+func setupTestS3() (*S3, *store.Store) {
+	s := store.New()
+	return NewS3(s, "", "", false), s
+}
+
+func TestS3_BucketAndObjectLifecycle(t *testing.T) {
+	h, s := setupTestS3()
+
+	req := httptest.NewRequest(http.MethodPut, "/s3-bucket", nil)
+	req.SetPathValue("bucket", "s3-bucket")
+	rr := httptest.NewRecorder()
+	h.CreateBucket(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	// Upload with an aws-chunked body, as streaming clients do
+	body := "5;chunk-signature=abc\r\nhello\r\n0;chunk-signature=def\r\n\r\n"
+	req = httptest.NewRequest(http.MethodPut, "/s3-bucket/dir/a+b.txt", strings.NewReader(body))
+	req.SetPathValue("bucket", "s3-bucket")
+	req.SetPathValue("key", "dir/a+b.txt")
+	req.Header.Set("X-Amz-Content-Sha256", "STREAMING-AWS4-HMAC-SHA256-PAYLOAD")
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set("X-Amz-Meta-Owner", "team-a")
+	rr = httptest.NewRecorder()
+	h.PutObject(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	// The object is visible to the Cloud Storage side of the store
+	if content := s.GetObjectContent("s3-bucket", "dir/a+b.txt"); string(content) != "hello" {
+		t.Errorf("expected content 'hello', got '%s'", string(content))
+	}
+
+	// Range request
+	req = httptest.NewRequest(http.MethodGet, "/s3-bucket/dir/a+b.txt", nil)
+	req.SetPathValue("bucket", "s3-bucket")
+	req.SetPathValue("key", "dir/a+b.txt")
+	req.Header.Set("Range", "bytes=1-3")
+	rr = httptest.NewRecorder()
+	h.GetObject(rr, req)
+	if rr.Code != http.StatusPartialContent || rr.Body.String() != "ell" {
+		t.Errorf("expected 206 'ell', got %d '%s'", rr.Code, rr.Body.String())
+	}
+	if rr.Header().Get("X-Amz-Meta-Owner") != "team-a" {
+		t.Errorf("expected x-amz-meta-owner 'team-a', got '%s'", rr.Header().Get("X-Amz-Meta-Owner"))
+	}
+
+	// Copy
+	req = httptest.NewRequest(http.MethodPut, "/s3-bucket/copy.txt", nil)
+	req.SetPathValue("bucket", "s3-bucket")
+	req.SetPathValue("key", "copy.txt")
+	req.Header.Set("X-Amz-Copy-Source", "/s3-bucket/dir/a%2Bb.txt")
+	rr = httptest.NewRecorder()
+	h.PutObject(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	if obj := s.GetObject("s3-bucket", "copy.txt"); obj == nil || obj.Metadata["owner"] != "team-a" {
+		t.Errorf("expected copy with metadata, got %+v", obj)
+	}
+
+	// Multi-delete
+	deleteBody := `<Delete><Object><Key>dir/a+b.txt</Key></Object><Object><Key>copy.txt</Key></Object></Delete>`
+	req = httptest.NewRequest(http.MethodPost, "/s3-bucket?delete", strings.NewReader(deleteBody))
+	req.SetPathValue("bucket", "s3-bucket")
+	rr = httptest.NewRecorder()
+	h.PostBucket(rr, req)
+	var result s3.DeleteResult
+	if err := xml.NewDecoder(rr.Body).Decode(&result); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(result.Deleted) != 2 {
+		t.Errorf("expected 2 deleted objects, got %d", len(result.Deleted))
+	}
+
+	// Missing objects return NoSuchKey
+	req = httptest.NewRequest(http.MethodGet, "/s3-bucket/copy.txt", nil)
+	req.SetPathValue("bucket", "s3-bucket")
+	req.SetPathValue("key", "copy.txt")
+	rr = httptest.NewRecorder()
+	h.GetObject(rr, req)
+	var s3Err s3.Error
+	if err := xml.NewDecoder(rr.Body).Decode(&s3Err); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if rr.Code != http.StatusNotFound || s3Err.Code != "NoSuchKey" {
+		t.Errorf("expected 404 NoSuchKey, got %d %s", rr.Code, s3Err.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodDelete, "/s3-bucket", nil)
+	req.SetPathValue("bucket", "s3-bucket")
+	rr = httptest.NewRecorder()
+	h.DeleteBucket(rr, req)
+	if rr.Code != http.StatusNoContent {
+		t.Errorf("expected status %d, got %d", http.StatusNoContent, rr.Code)
+	}
+}
+
+func TestS3_GetObject_ContentHeaders(t *testing.T) {
+	h, s := setupTestS3()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "s3-bucket"})
+	_, _ = s.CreateObjectWithOptions("s3-bucket", "report.csv", "text/csv", strings.NewReader("a,b"), nil,
+		store.ObjectOptions{CacheControl: "no-store", ContentDisposition: `attachment; filename="report.csv"`, ContentLanguage: "en"})
+
+	req := httptest.NewRequest(http.MethodGet, "/s3-bucket/report.csv", nil)
+	req.SetPathValue("bucket", "s3-bucket")
+	req.SetPathValue("key", "report.csv")
+	rr := httptest.NewRecorder()
+	h.GetObject(rr, req)
+
+	expectedHeaders := map[string]string{
+		"Content-Type":        "text/csv",
+		"Cache-Control":       "no-store",
+		"Content-Disposition": `attachment; filename="report.csv"`,
+		"Content-Language":    "en",
+	}
+	for header, expected := range expectedHeaders {
+		if got := rr.Header().Get(header); got != expected {
+			t.Errorf("expected %s %q, got %q", header, expected, got)
+		}
+	}
+}
+
+func TestS3_ListObjectsV2(t *testing.T) {
+	h, s := setupTestS3()
+	s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+	for _, name := range []string{"a.txt", "b.txt", "dir/c.txt", "e.txt"} {
+		s.CreateObject("test-bucket", name, "text/plain", []byte("x"), nil)
+	}
+
+	var keys []string
+	token := ""
+	for page := 0; page < 10; page++ {
+		path := "/test-bucket?list-type=2&delimiter=/&max-keys=2"
+		if token != "" {
+			path += "&continuation-token=" + token
+		}
+
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.SetPathValue("bucket", "test-bucket")
+		rr := httptest.NewRecorder()
+		h.GetBucket(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+		}
+
+		var result s3.ListBucketResult
+		if err := xml.NewDecoder(rr.Body).Decode(&result); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		for _, obj := range result.Contents {
+			keys = append(keys, obj.Key)
+		}
+		for _, p := range result.CommonPrefixes {
+			keys = append(keys, p.Prefix)
+		}
+
+		if !result.IsTruncated {
+			break
+		}
+		token = result.NextContinuationToken
+	}
+
+	sort.Strings(keys)
+	if strings.Join(keys, ",") != "a.txt,b.txt,dir/,e.txt" {
+		t.Errorf("unexpected keys: %v", keys)
+	}
+}
+
+func TestS3_Authenticate(t *testing.T) {
+	h := NewS3(store.New(), "AKID", "secret", false)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential=OTHER/20240101/us-east-1/s3/aws4_request,SignedHeaders=host,Signature=abc")
+	rr := httptest.NewRecorder()
+	h.Authenticate(next).ServeHTTP(rr, req)
+
+	var s3Err s3.Error
+	if err := xml.NewDecoder(rr.Body).Decode(&s3Err); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if rr.Code != http.StatusForbidden || s3Err.Code != "InvalidAccessKeyId" {
+		t.Errorf("expected 403 InvalidAccessKeyId, got %d %s", rr.Code, s3Err.Code)
+	}
+}
+
+func TestS3_Authenticate_HmacKeys(t *testing.T) {
+	h, s := setupTestS3()
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	key, err := s.CreateHmacKey("test-project", "sa@test-project.iam.gserviceaccount.com")
+	if err != nil {
+		t.Fatalf("CreateHmacKey() error: %v", err)
+	}
+
+	authenticate := func(accessKey string) (int, string) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+accessKey+"/20240101/us-east-1/s3/aws4_request,SignedHeaders=host,Signature=abc")
+		rr := httptest.NewRecorder()
+		h.Authenticate(next).ServeHTTP(rr, req)
+
+		var s3Err s3.Error
+		_ = xml.NewDecoder(rr.Body).Decode(&s3Err)
+		return rr.Code, s3Err.Code
+	}
+
+	// Without configured credentials, unknown keys are accepted, but HMAC keys are verified
+	if code, _ := authenticate("OTHER"); code != http.StatusOK {
+		t.Errorf("expected unknown keys to be accepted, got %d", code)
+	}
+	if code, s3Code := authenticate(key.Metadata.AccessID); code != http.StatusForbidden || s3Code != "SignatureDoesNotMatch" {
+		t.Errorf("expected 403 SignatureDoesNotMatch, got %d %s", code, s3Code)
+	}
+
+	if _, err := s.UpdateHmacKey("test-project", key.Metadata.AccessID, &storage.HmacKeyMetadata{State: storage.HmacKeyInactive}); err != nil {
+		t.Fatalf("UpdateHmacKey() error: %v", err)
+	}
+	if code, s3Code := authenticate(key.Metadata.AccessID); code != http.StatusForbidden || s3Code != "InvalidAccessKeyId" {
+		t.Errorf("expected 403 InvalidAccessKeyId for an inactive key, got %d %s", code, s3Code)
+	}
+}
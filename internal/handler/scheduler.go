@@ -0,0 +1,188 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/katharinasick/gcp-api-mock/internal/cloudscheduler"
+	"github.com/katharinasick/gcp-api-mock/internal/store"
+)
+
+// CloudScheduler handles Cloud Scheduler API (v1) endpoints.
+// Any project and location is accepted. Jobs run on their schedule only if the store has a scheduler handler,
+// but can always be run right away with jobs.run or the admin API.
+type CloudScheduler struct {
+	store      *store.Store
+	dispatcher *cloudscheduler.Dispatcher
+}
+
+// NewCloudScheduler creates a new CloudScheduler handler.
+func NewCloudScheduler(s *store.Store) *CloudScheduler {
+	return &CloudScheduler{store: s, dispatcher: cloudscheduler.NewDispatcher()}
+}
+
+// schedulerDefaultPageSize is the default page size for Cloud Scheduler list calls.
+const schedulerDefaultPageSize = 100
+
+// ListJobs handles GET /v1/projects/{project}/locations/{location}/jobs - List jobs.
+// Reference: https://cloud.google.com/scheduler/docs/reference/rest/v1/projects.locations.jobs/list
+func (h *CloudScheduler) ListJobs(w http.ResponseWriter, r *http.Request) {
+	parent := schedulerParent(r)
+
+	pageSize := schedulerDefaultPageSize
+	if value := r.URL.Query().Get("pageSize"); value != "" {
+		size, err := strconv.Atoi(value)
+		if err != nil || size < 1 {
+			respondStatusError(w, http.StatusBadRequest, "Invalid pageSize: "+value, "INVALID_ARGUMENT")
+			return
+		}
+		pageSize = size
+	}
+
+	jobs, nextPageToken, err := paginate(h.store.ListSchedulerJobs(parent), r.URL.Query().Get("pageToken"), pageSize, func(j *cloudscheduler.Job) string { return j.Name })
+	if err != nil {
+		respondStatusError(w, http.StatusBadRequest, err.Error(), "INVALID_ARGUMENT")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, &cloudscheduler.ListJobsResponse{
+		Jobs:          jobs,
+		NextPageToken: nextPageToken,
+	})
+}
+
+// CreateJob handles POST /v1/projects/{project}/locations/{location}/jobs - Create a job.
+// Reference: https://cloud.google.com/scheduler/docs/reference/rest/v1/projects.locations.jobs/create
+func (h *CloudScheduler) CreateJob(w http.ResponseWriter, r *http.Request) {
+	var req cloudscheduler.Job
+	if err := decodeJSON(r.Body, &req, h.store.StrictValidation()); err != nil {
+		respondStatusError(w, http.StatusBadRequest, invalidJSONMessage(err), "INVALID_ARGUMENT")
+		return
+	}
+
+	job, err := h.store.CreateSchedulerJob(schedulerParent(r), &req)
+	if err != nil {
+		respondStatusStoreError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, job)
+}
+
+// GetJob handles GET /v1/projects/{project}/locations/{location}/jobs/{job} - Get a job.
+// Reference: https://cloud.google.com/scheduler/docs/reference/rest/v1/projects.locations.jobs/get
+func (h *CloudScheduler) GetJob(w http.ResponseWriter, r *http.Request) {
+	name := schedulerJobName(r)
+
+	job := h.store.GetSchedulerJob(name)
+	if job == nil {
+		respondStatusError(w, http.StatusNotFound, "Job not found: "+name, "NOT_FOUND")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, job)
+}
+
+// UpdateJob handles PATCH /v1/projects/{project}/locations/{location}/jobs/{job}?updateMask={fields} - Update a job.
+// Reference: https://cloud.google.com/scheduler/docs/reference/rest/v1/projects.locations.jobs/patch
+func (h *CloudScheduler) UpdateJob(w http.ResponseWriter, r *http.Request) {
+	var req cloudscheduler.Job
+	if err := decodeJSON(r.Body, &req, h.store.StrictValidation()); err != nil {
+		respondStatusError(w, http.StatusBadRequest, invalidJSONMessage(err), "INVALID_ARGUMENT")
+		return
+	}
+
+	var updateMask []string
+	if value := r.URL.Query().Get("updateMask"); value != "" {
+		updateMask = strings.Split(value, ",")
+	}
+
+	job, err := h.store.UpdateSchedulerJob(schedulerJobName(r), &req, updateMask)
+	if err != nil {
+		respondStatusStoreError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, job)
+}
+
+// DeleteJob handles DELETE /v1/projects/{project}/locations/{location}/jobs/{job} - Delete a job.
+// Reference: https://cloud.google.com/scheduler/docs/reference/rest/v1/projects.locations.jobs/delete
+func (h *CloudScheduler) DeleteJob(w http.ResponseWriter, r *http.Request) {
+	if err := h.store.DeleteSchedulerJob(schedulerJobName(r)); err != nil {
+		respondStatusStoreError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, struct{}{})
+}
+
+// JobAction handles POST /v1/projects/{project}/locations/{location}/jobs/{job}:{verb} - Pause, resume or run a job.
+// ServeMux wildcards span whole path segments, so the custom verb is cut off the job ID here.
+// References:
+//   - https://cloud.google.com/scheduler/docs/reference/rest/v1/projects.locations.jobs/pause
+//   - https://cloud.google.com/scheduler/docs/reference/rest/v1/projects.locations.jobs/resume
+//   - https://cloud.google.com/scheduler/docs/reference/rest/v1/projects.locations.jobs/run
+func (h *CloudScheduler) JobAction(w http.ResponseWriter, r *http.Request) {
+	jobID, verb, _ := strings.Cut(r.PathValue("job"), ":")
+	name := schedulerParent(r) + "/jobs/" + jobID
+
+	var job *cloudscheduler.Job
+	var err error
+	switch verb {
+	case "pause":
+		job, err = h.store.PauseSchedulerJob(name)
+	case "resume":
+		job, err = h.store.ResumeSchedulerJob(name)
+	case "run":
+		// Like Cloud Scheduler, respond once the attempt started; its status is recorded when it's done
+		job, err = h.store.StartSchedulerAttempt(name)
+		if err == nil {
+			h.dispatcher.Deliver(job, job.LastAttemptTime, func(status *cloudscheduler.Status) {
+				h.store.RecordSchedulerAttempt(name, job.LastAttemptTime, status)
+			})
+		}
+	default:
+		respondStatusError(w, http.StatusNotFound, "Method not found: "+verb, "NOT_FOUND")
+		return
+	}
+	if err != nil {
+		respondStatusStoreError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, job)
+}
+
+// RunJobNow handles POST /admin/scheduler/projects/{project}/locations/{location}/jobs/{job}/run - Run a job
+// right away, whatever its schedule and state, and respond once its target was called.
+// Unlike jobs.run, the response is the job with the status of this attempt, so tests don't have to poll for it.
+func (h *CloudScheduler) RunJobNow(w http.ResponseWriter, r *http.Request) {
+	name := schedulerJobName(r)
+
+	job, err := h.store.StartSchedulerAttempt(name)
+	if err != nil {
+		respondStatusStoreError(w, err)
+		return
+	}
+	h.store.RecordSchedulerAttempt(name, job.LastAttemptTime, h.dispatcher.Attempt(job, job.LastAttemptTime))
+
+	job = h.store.GetSchedulerJob(name)
+	if job == nil {
+		respondStatusError(w, http.StatusNotFound, "Job not found: "+name, "NOT_FOUND")
+		return
+	}
+	respondJSON(w, http.StatusOK, job)
+}
+
+// schedulerParent returns the parent (projects/{project}/locations/{location}) named by the path of a request.
+func schedulerParent(r *http.Request) string {
+	return "projects/" + r.PathValue("project") + "/locations/" + r.PathValue("location")
+}
+
+// schedulerJobName returns the job name (projects/{project}/locations/{location}/jobs/{job})
+// named by the path of a request.
+func schedulerJobName(r *http.Request) string {
+	return schedulerParent(r) + "/jobs/" + r.PathValue("job")
+}
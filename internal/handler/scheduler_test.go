@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/cloudscheduler"
+	"github.com/katharinasick/gcp-api-mock/internal/store"
+)
+
+const testSchedulerLocation = "/v1/projects/test-project/locations/europe-west1"
+
+func setupTestCloudScheduler() (*CloudScheduler, *store.Store) {
+	s := store.New()
+	return NewCloudScheduler(s), s
+}
+
+func TestCloudScheduler_CreateJob(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{"valid", `{"name":"projects/test-project/locations/europe-west1/jobs/cleanup","schedule":"*/5 * * * *","httpTarget":{"uri":"http://localhost:9090/cleanup"}}`, http.StatusOK},
+		{"generated name", `{"schedule":"*/5 * * * *","pubsubTarget":{"topicName":"projects/test-project/topics/t"}}`, http.StatusOK},
+		{"invalid schedule", `{"schedule":"often","httpTarget":{"uri":"http://localhost:9090/cleanup"}}`, http.StatusBadRequest},
+		{"no target", `{"schedule":"*/5 * * * *"}`, http.StatusBadRequest},
+		{"invalid body", `{`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, _ := setupTestCloudScheduler()
+
+			req := httptest.NewRequest(http.MethodPost, testSchedulerLocation+"/jobs", strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+			serveRoute("POST /v1/projects/{project}/locations/{location}/jobs", h.CreateJob, rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestCloudScheduler_JobAction(t *testing.T) {
+	h, s := setupTestCloudScheduler()
+	name := "projects/test-project/locations/europe-west1/jobs/cleanup"
+	if _, err := s.CreateSchedulerJob("projects/test-project/locations/europe-west1", &cloudscheduler.Job{
+		Name:         name,
+		Schedule:     "0 * * * *",
+		PubsubTarget: &cloudscheduler.PubsubTarget{TopicName: "projects/test-project/topics/cleanup"},
+	}); err != nil {
+		t.Fatalf("CreateSchedulerJob() error: %v", err)
+	}
+
+	tests := []struct {
+		verb           string
+		expectedStatus int
+		expectedState  string
+	}{
+		{"pause", http.StatusOK, cloudscheduler.StatePaused},
+		{"resume", http.StatusOK, cloudscheduler.StateEnabled},
+		{"run", http.StatusOK, cloudscheduler.StateEnabled},
+		{"explode", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.verb, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, testSchedulerLocation+"/jobs/cleanup:"+tt.verb, nil)
+			rr := httptest.NewRecorder()
+			serveRoute("POST /v1/projects/{project}/locations/{location}/jobs/{job}", h.JobAction, rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if tt.expectedState == "" {
+				return
+			}
+			var job cloudscheduler.Job
+			if err := json.NewDecoder(rr.Body).Decode(&job); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if job.Name != name || job.State != tt.expectedState {
+				t.Errorf("expected job %s in state %s, got %s in state %s", name, tt.expectedState, job.Name, job.State)
+			}
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodPost, testSchedulerLocation+"/jobs/missing:run", nil)
+	rr := httptest.NewRecorder()
+	serveRoute("POST /v1/projects/{project}/locations/{location}/jobs/{job}", h.JobAction, rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for a missing job, got %d", rr.Code)
+	}
+}
+
+func TestCloudScheduler_RunJobNow(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer target.Close()
+
+	h, s := setupTestCloudScheduler()
+	now := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	s.SetClock(func() time.Time { return now })
+	if _, err := s.CreateSchedulerJob("projects/test-project/locations/europe-west1", &cloudscheduler.Job{
+		Name:       "projects/test-project/locations/europe-west1/jobs/cleanup",
+		Schedule:   "0 * * * *",
+		HttpTarget: &cloudscheduler.HttpTarget{Uri: target.URL + "/cleanup"},
+	}); err != nil {
+		t.Fatalf("CreateSchedulerJob() error: %v", err)
+	}
+
+	// Paused jobs run too, and the response has the status of the attempt
+	if _, err := s.PauseSchedulerJob("projects/test-project/locations/europe-west1/jobs/cleanup"); err != nil {
+		t.Fatalf("PauseSchedulerJob() error: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/admin/scheduler/projects/test-project/locations/europe-west1/jobs/cleanup/run", nil)
+	rr := httptest.NewRecorder()
+	serveRoute("POST /admin/scheduler/projects/{project}/locations/{location}/jobs/{job}/run", h.RunJobNow, rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var job cloudscheduler.Job
+	if err := json.NewDecoder(rr.Body).Decode(&job); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if job.Status == nil || job.Status.Code != 5 || !job.LastAttemptTime.Equal(now) {
+		t.Errorf("expected a NOT_FOUND attempt at %v, got %+v at %v", now, job.Status, job.LastAttemptTime)
+	}
+}
@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/katharinasick/gcp-api-mock/internal/config"
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+	"github.com/katharinasick/gcp-api-mock/internal/store"
+)
+
+// Result limits of object searches.
+const (
+	// searchDefaultLimit is the default number of objects a search returns.
+	searchDefaultLimit = 100
+	// searchMaxLimit is the maximum number of objects a search returns.
+	searchMaxLimit = 1000
+)
+
+// SearchObjectsResponse is the response of an object search.
+type SearchObjectsResponse struct {
+	// Items are the matching objects, ordered by bucket and name, at most the limit of the search.
+	Items []*storage.Object `json:"items"`
+	// Total is the number of matching objects, including those beyond the limit.
+	Total int `json:"total"`
+}
+
+// parseObjectQuery reads an object search from query parameters: bucket, prefix, contentType (like
+// application/json or image/*), minSize and maxSize (like 1GiB) and metadata, given once per entry as
+// key=value or key. Empty parameters are ignored, so dashboard forms can submit every field.
+func parseObjectQuery(params url.Values) (store.ObjectQuery, error) {
+	query := store.ObjectQuery{
+		Bucket:      strings.TrimSpace(params.Get("bucket")),
+		Prefix:      params.Get("prefix"),
+		ContentType: strings.TrimSpace(params.Get("contentType")),
+		MinSize:     -1,
+		MaxSize:     -1,
+	}
+
+	for _, size := range []struct {
+		param string
+		value *int64
+	}{{"minSize", &query.MinSize}, {"maxSize", &query.MaxSize}} {
+		value := strings.TrimSpace(params.Get(size.param))
+		if value == "" {
+			continue
+		}
+		n, err := config.ParseByteSize(value)
+		if err != nil {
+			return store.ObjectQuery{}, fmt.Errorf("invalid %s: %w", size.param, err)
+		}
+		*size.value = n
+	}
+
+	for _, entry := range params["metadata"] {
+		key, value, _ := strings.Cut(strings.TrimSpace(entry), "=")
+		if key == "" {
+			continue
+		}
+		if query.Metadata == nil {
+			query.Metadata = make(map[string]string)
+		}
+		query.Metadata[key] = value
+	}
+
+	return query, nil
+}
+
+// SearchObjects handles GET /admin/storage/objects - Find objects across all buckets by custom metadata,
+// name prefix, content type and size, e.g. ?metadata=test=checkout-e2e&minSize=1GiB to find which test
+// wrote a large object. At most ?limit objects are returned, 100 by default.
+func (h *Admin) SearchObjects(w http.ResponseWriter, r *http.Request) {
+	query, err := parseObjectQuery(r.URL.Query())
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+		return
+	}
+
+	limit, err := parseMaxResults(r.URL.Query().Get("limit"), searchDefaultLimit, searchMaxLimit)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid limit: "+r.URL.Query().Get("limit"), "invalid")
+		return
+	}
+
+	objects, total := h.store.SearchObjects(query, limit)
+	if objects == nil {
+		objects = []*storage.Object{}
+	}
+	respondJSON(w, http.StatusOK, SearchObjectsResponse{Items: objects, Total: total})
+}
+
+// ObjectSearchData holds the data for the object search template.
+type ObjectSearchData struct {
+	// Error is the error of an invalid search
+	Error   string
+	Objects []*storage.Object
+	// Total is the number of matching objects, including those that aren't shown
+	Total int
+	// BasePath is the path prefix of the namespace of the objects, for download links
+	BasePath string
+}
+
+// SearchObjectsUI renders the results of an object search for HTMX, with the parameters of SearchObjects.
+func (u *UI) SearchObjectsUI(w http.ResponseWriter, r *http.Request) {
+	data := ObjectSearchData{BasePath: basePath(r)}
+
+	query, err := parseObjectQuery(r.URL.Query())
+	if err != nil {
+		data.Error = err.Error()
+	} else {
+		data.Objects, data.Total = u.store.SearchObjects(query, searchDefaultLimit)
+	}
+
+	if err := u.templates.ExecuteTemplate(w, "object_search.html", data); err != nil {
+		http.Error(w, "failed to render template", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/katharinasick/gcp-api-mock/internal/slo"
+)
+
+// SLO handles the admin API of the simulated service level objective.
+type SLO struct {
+	simulator *slo.Simulator
+}
+
+// NewSLO creates a new SLO handler.
+func NewSLO(simulator *slo.Simulator) *SLO {
+	return &SLO{simulator: simulator}
+}
+
+// Get handles GET /admin/slo - Show the simulated objective with the success rate and latency
+// percentiles the requests since it was set actually saw.
+func (h *SLO) Get(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, h.simulator.Status())
+}
+
+// Set handles PUT /admin/slo - Simulate an objective, e.g. {"successRate": 0.999, "latency": {"p50": "20ms",
+// "p99": "300ms"}, "errorStatus": 503}. Requests fail and are delayed so that every stretch of requests meets it.
+func (h *SLO) Set(w http.ResponseWriter, r *http.Request) {
+	objective := slo.NewObjective()
+	if err := json.NewDecoder(r.Body).Decode(&objective); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid objective: "+err.Error(), "invalid")
+		return
+	}
+
+	if err := h.simulator.SetObjective(&objective); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, h.simulator.Status())
+}
+
+// Delete handles DELETE /admin/slo - Stop simulating the objective.
+func (h *SLO) Delete(w http.ResponseWriter, r *http.Request) {
+	_ = h.simulator.SetObjective(nil)
+	w.WriteHeader(http.StatusNoContent)
+}
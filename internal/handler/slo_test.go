@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/katharinasick/gcp-api-mock/internal/slo"
+)
+
+func TestSLO(t *testing.T) {
+	simulator := slo.New()
+	h := NewSLO(simulator)
+
+	req := httptest.NewRequest(http.MethodPut, "/admin/slo", strings.NewReader(`{"successRate": 0.99, "latency": {"p50": "1ms", "p99": "5ms"}}`))
+	rr := httptest.NewRecorder()
+	h.Set(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	for range 100 {
+		simulator.Next()
+	}
+
+	rr = httptest.NewRecorder()
+	h.Get(rr, httptest.NewRequest(http.MethodGet, "/admin/slo", nil))
+	var status slo.Status
+	if err := json.NewDecoder(rr.Body).Decode(&status); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if status.Objective == nil || status.Objective.ErrorStatus != http.StatusServiceUnavailable {
+		t.Fatalf("expected the objective with the default error status, got %+v", status.Objective)
+	}
+	if status.Stats.Requests != 100 || status.Stats.Failures != 1 {
+		t.Errorf("expected 1 failure in 100 requests, got %+v", status.Stats)
+	}
+
+	for _, body := range []string{`{"successRate": 2}`, `{"latency": {"p50": "fast"}}`, `{"errorStatus": 404}`} {
+		rr = httptest.NewRecorder()
+		h.Set(rr, httptest.NewRequest(http.MethodPut, "/admin/slo", strings.NewReader(body)))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", body, rr.Code)
+		}
+	}
+
+	rr = httptest.NewRecorder()
+	h.Delete(rr, httptest.NewRequest(http.MethodDelete, "/admin/slo", nil))
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("expected status 204, got %d", rr.Code)
+	}
+	if _, ok := simulator.Next(); ok {
+		t.Error("expected the objective to be removed")
+	}
+}
@@ -1,11 +1,10 @@
-// Package handler provides HTTP handlers for the GCP API Mock.
 package handler
 
 import (
-	"encoding/json"
 	"net/http"
 	"strings"
 
+	"github.com/katharinasick/gcp-api-mock/internal/gcperror"
 	"github.com/katharinasick/gcp-api-mock/internal/sqladmin"
 	"github.com/katharinasick/gcp-api-mock/internal/store"
 )
@@ -25,24 +24,48 @@ func NewSQLAdmin(s *store.Store) *SQLAdmin {
 // =============================================================================
 
 // ListInstances handles GET /sql/v1beta4/projects/{project}/instances - List instances.
+// A filter like "settings.userLabels.env:prod state:RUNNABLE" selects the listed instances.
 // Reference: https://cloud.google.com/sql/docs/mysql/admin-api/rest/v1beta4/instances/list
 func (h *SQLAdmin) ListInstances(w http.ResponseWriter, r *http.Request) {
-	instances := h.store.ListSQLInstances()
+	maxResults, err := parseMaxResults(r.URL.Query().Get("maxResults"), sqlDefaultMaxResults, sqlMaxMaxResults)
+	if err != nil {
+		respondSQLError(w, http.StatusBadRequest, err.Error(), "INVALID_ARGUMENT", "invalid")
+		return
+	}
+
+	filter, err := sqladmin.ParseInstanceFilter(r.URL.Query().Get("filter"))
+	if err != nil {
+		respondSQLError(w, http.StatusBadRequest, err.Error(), "INVALID_ARGUMENT", "invalid")
+		return
+	}
+	matching := make([]*sqladmin.DatabaseInstance, 0)
+	for _, instance := range h.store.ListSQLInstances() {
+		if filter.Matches(instance) {
+			matching = append(matching, instance)
+		}
+	}
+
+	instances, nextPageToken, err := paginate(matching, r.URL.Query().Get("pageToken"), maxResults, func(i *sqladmin.DatabaseInstance) string { return i.Name })
+	if err != nil {
+		respondSQLError(w, http.StatusBadRequest, err.Error(), "INVALID_ARGUMENT", "invalid")
+		return
+	}
 
 	response := &sqladmin.InstancesListResponse{
-		Kind:  "sql#instancesList",
-		Items: instances,
+		Kind:          "sql#instancesList",
+		Items:         instances,
+		NextPageToken: nextPageToken,
 	}
 
-	respondSQLJSON(w, http.StatusOK, response)
+	respondSQLJSON(w, r, http.StatusOK, response)
 }
 
 // CreateInstance handles POST /sql/v1beta4/projects/{project}/instances - Create an instance.
 // Reference: https://cloud.google.com/sql/docs/mysql/admin-api/rest/v1beta4/instances/insert
 func (h *SQLAdmin) CreateInstance(w http.ResponseWriter, r *http.Request) {
 	var req sqladmin.InstanceInsertRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondSQLError(w, http.StatusBadRequest, "Invalid JSON body", "INVALID_ARGUMENT", "invalid")
+	if err := decodeJSON(r.Body, &req, h.store.StrictValidation(), &sqladmin.DatabaseInstance{}); err != nil {
+		respondSQLError(w, http.StatusBadRequest, invalidJSONMessage(err), "INVALID_ARGUMENT", "invalid")
 		return
 	}
 
@@ -57,17 +80,25 @@ func (h *SQLAdmin) CreateInstance(w http.ResponseWriter, r *http.Request) {
 			respondSQLError(w, http.StatusConflict, err.Error(), "ALREADY_EXISTS", "conflict")
 			return
 		}
+		if strings.Contains(err.Error(), "not found") {
+			respondSQLError(w, http.StatusNotFound, err.Error(), "NOT_FOUND", "notFound")
+			return
+		}
+		if strings.Contains(err.Error(), "invalid") {
+			respondSQLError(w, http.StatusBadRequest, err.Error(), "INVALID_ARGUMENT", invalidSQLReason(err))
+			return
+		}
 		respondSQLError(w, http.StatusInternalServerError, err.Error(), "INTERNAL", "internalError")
 		return
 	}
 
-	respondSQLJSON(w, http.StatusOK, op)
+	respondSQLJSON(w, r, http.StatusOK, op)
 }
 
 // GetInstance handles GET /sql/v1beta4/projects/{project}/instances/{instance} - Get instance.
 // Reference: https://cloud.google.com/sql/docs/mysql/admin-api/rest/v1beta4/instances/get
 func (h *SQLAdmin) GetInstance(w http.ResponseWriter, r *http.Request) {
-	instanceName := extractSQLInstanceName(r.URL.Path)
+	instanceName := r.PathValue("instance")
 
 	if instanceName == "" {
 		respondSQLError(w, http.StatusBadRequest, "Instance name is required", "INVALID_ARGUMENT", "required")
@@ -80,13 +111,14 @@ func (h *SQLAdmin) GetInstance(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respondSQLJSON(w, http.StatusOK, instance)
+	respondSQLJSON(w, r, http.StatusOK, instance)
 }
 
 // UpdateInstance handles PATCH /sql/v1beta4/projects/{project}/instances/{instance} - Update instance.
+// An etag in the body or an If-Match header must match the current etag of the instance, else 412 is returned.
 // Reference: https://cloud.google.com/sql/docs/mysql/admin-api/rest/v1beta4/instances/patch
 func (h *SQLAdmin) UpdateInstance(w http.ResponseWriter, r *http.Request) {
-	instanceName := extractSQLInstanceName(r.URL.Path)
+	instanceName := r.PathValue("instance")
 
 	if instanceName == "" {
 		respondSQLError(w, http.StatusBadRequest, "Instance name is required", "INVALID_ARGUMENT", "required")
@@ -94,28 +126,103 @@ func (h *SQLAdmin) UpdateInstance(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req sqladmin.InstancePatchRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondSQLError(w, http.StatusBadRequest, "Invalid JSON body", "INVALID_ARGUMENT", "invalid")
+	var err error
+	if err = decodeJSON(r.Body, &req, h.store.StrictValidation(), &sqladmin.DatabaseInstance{}); err != nil {
+		respondSQLError(w, http.StatusBadRequest, invalidJSONMessage(err), "INVALID_ARGUMENT", "invalid")
+		return
+	}
+	if req.Etag, err = ifMatchEtag(r, req.Etag); err != nil {
+		respondSQLError(w, http.StatusPreconditionFailed, err.Error(), "FAILED_PRECONDITION", "conditionNotMet")
+		return
+	}
+
+	instance, op, err := h.store.UpdateSQLInstance(instanceName, &req)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondSQLError(w, http.StatusNotFound, err.Error(), "NOT_FOUND", "notFound")
+			return
+		}
+		if strings.Contains(err.Error(), "precondition failed") {
+			respondSQLError(w, http.StatusPreconditionFailed, err.Error(), "FAILED_PRECONDITION", "conditionNotMet")
+			return
+		}
+		if strings.Contains(err.Error(), "not in an appropriate state") {
+			respondSQLError(w, http.StatusConflict, err.Error(), "FAILED_PRECONDITION", "invalidState")
+			return
+		}
+		if strings.Contains(err.Error(), "invalid") {
+			respondSQLError(w, http.StatusBadRequest, err.Error(), "INVALID_ARGUMENT", invalidSQLReason(err))
+			return
+		}
+		respondSQLError(w, http.StatusInternalServerError, err.Error(), "INTERNAL", "internalError")
+		return
+	}
+
+	// The operation is returned, so the new etag of the instance is passed in the header
+	w.Header().Set("ETag", instance.Etag)
+	respondSQLJSON(w, r, http.StatusOK, op)
+}
+
+// RestartInstance handles POST /sql/v1beta4/projects/{project}/instances/{instance}/restart - Restart instance.
+// Stopped, suspended and instances under maintenance can't be restarted.
+// Reference: https://cloud.google.com/sql/docs/mysql/admin-api/rest/v1beta4/instances/restart
+func (h *SQLAdmin) RestartInstance(w http.ResponseWriter, r *http.Request) {
+	instanceName := r.PathValue("instance")
+
+	if instanceName == "" {
+		respondSQLError(w, http.StatusBadRequest, "Instance name is required", "INVALID_ARGUMENT", "required")
+		return
+	}
+
+	op, err := h.store.RestartSQLInstance(instanceName)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondSQLError(w, http.StatusNotFound, err.Error(), "NOT_FOUND", "notFound")
+			return
+		}
+		if strings.Contains(err.Error(), "not in an appropriate state") {
+			respondSQLError(w, http.StatusConflict, err.Error(), "FAILED_PRECONDITION", "invalidState")
+			return
+		}
+		respondSQLError(w, http.StatusInternalServerError, err.Error(), "INTERNAL", "internalError")
 		return
 	}
 
-	_, op, err := h.store.UpdateSQLInstance(instanceName, &req)
+	respondSQLJSON(w, r, http.StatusOK, op)
+}
+
+// PromoteReplica handles POST /sql/v1beta4/projects/{project}/instances/{instance}/promoteReplica - Promote read replica.
+// The replica becomes a standalone instance and is removed from its primary's replicaNames.
+// Reference: https://cloud.google.com/sql/docs/mysql/admin-api/rest/v1beta4/instances/promoteReplica
+func (h *SQLAdmin) PromoteReplica(w http.ResponseWriter, r *http.Request) {
+	instanceName := r.PathValue("instance")
+
+	if instanceName == "" {
+		respondSQLError(w, http.StatusBadRequest, "Instance name is required", "INVALID_ARGUMENT", "required")
+		return
+	}
+
+	op, err := h.store.PromoteSQLReplica(instanceName)
 	if err != nil {
 		if strings.Contains(err.Error(), "not found") {
 			respondSQLError(w, http.StatusNotFound, err.Error(), "NOT_FOUND", "notFound")
 			return
 		}
+		if strings.Contains(err.Error(), "invalid") {
+			respondSQLError(w, http.StatusBadRequest, err.Error(), "INVALID_ARGUMENT", "invalid")
+			return
+		}
 		respondSQLError(w, http.StatusInternalServerError, err.Error(), "INTERNAL", "internalError")
 		return
 	}
 
-	respondSQLJSON(w, http.StatusOK, op)
+	respondSQLJSON(w, r, http.StatusOK, op)
 }
 
 // DeleteInstance handles DELETE /sql/v1beta4/projects/{project}/instances/{instance} - Delete instance.
 // Reference: https://cloud.google.com/sql/docs/mysql/admin-api/rest/v1beta4/instances/delete
 func (h *SQLAdmin) DeleteInstance(w http.ResponseWriter, r *http.Request) {
-	instanceName := extractSQLInstanceName(r.URL.Path)
+	instanceName := r.PathValue("instance")
 
 	if instanceName == "" {
 		respondSQLError(w, http.StatusBadRequest, "Instance name is required", "INVALID_ARGUMENT", "required")
@@ -128,7 +235,7 @@ func (h *SQLAdmin) DeleteInstance(w http.ResponseWriter, r *http.Request) {
 			respondSQLError(w, http.StatusNotFound, err.Error(), "NOT_FOUND", "notFound")
 			return
 		}
-		if strings.Contains(err.Error(), "deletion protection") {
+		if strings.Contains(err.Error(), "deletion protection") || strings.Contains(err.Error(), "has replicas") {
 			respondSQLError(w, http.StatusBadRequest, err.Error(), "FAILED_PRECONDITION", "failedPrecondition")
 			return
 		}
@@ -136,7 +243,35 @@ func (h *SQLAdmin) DeleteInstance(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respondSQLJSON(w, http.StatusOK, op)
+	respondSQLJSON(w, r, http.StatusOK, op)
+}
+
+// invalidSQLReason returns the error reason for an invalid instance configuration.
+func invalidSQLReason(err error) string {
+	switch {
+	case strings.Contains(err.Error(), "invalid flag name"):
+		return "invalidFlagName"
+	case strings.Contains(err.Error(), "invalid flag value"):
+		return "invalidFlagValue"
+	default:
+		return "invalid"
+	}
+}
+
+// =============================================================================
+// Flag Handlers
+// =============================================================================
+
+// ListFlags handles GET /sql/v1beta4/flags - List the flags that can be set on instances.
+// The databaseVersion query parameter filters the flags by database version.
+// Reference: https://cloud.google.com/sql/docs/mysql/admin-api/rest/v1beta4/flags/list
+func (h *SQLAdmin) ListFlags(w http.ResponseWriter, r *http.Request) {
+	response := sqladmin.FlagsListResponse{
+		Kind:  "sql#flagsList",
+		Items: sqladmin.ListFlags(r.URL.Query().Get("databaseVersion")),
+	}
+
+	respondSQLJSON(w, r, http.StatusOK, response)
 }
 
 // =============================================================================
@@ -146,7 +281,7 @@ func (h *SQLAdmin) DeleteInstance(w http.ResponseWriter, r *http.Request) {
 // ListDatabases handles GET /sql/v1beta4/projects/{project}/instances/{instance}/databases - List databases.
 // Reference: https://cloud.google.com/sql/docs/mysql/admin-api/rest/v1beta4/databases/list
 func (h *SQLAdmin) ListDatabases(w http.ResponseWriter, r *http.Request) {
-	instanceName := extractSQLInstanceNameFromDatabasePath(r.URL.Path)
+	instanceName := r.PathValue("instance")
 
 	if instanceName == "" {
 		respondSQLError(w, http.StatusBadRequest, "Instance name is required", "INVALID_ARGUMENT", "required")
@@ -168,13 +303,13 @@ func (h *SQLAdmin) ListDatabases(w http.ResponseWriter, r *http.Request) {
 		Items: databases,
 	}
 
-	respondSQLJSON(w, http.StatusOK, response)
+	respondSQLJSON(w, r, http.StatusOK, response)
 }
 
 // CreateDatabase handles POST /sql/v1beta4/projects/{project}/instances/{instance}/databases - Create database.
 // Reference: https://cloud.google.com/sql/docs/mysql/admin-api/rest/v1beta4/databases/insert
 func (h *SQLAdmin) CreateDatabase(w http.ResponseWriter, r *http.Request) {
-	instanceName := extractSQLInstanceNameFromDatabasePath(r.URL.Path)
+	instanceName := r.PathValue("instance")
 
 	if instanceName == "" {
 		respondSQLError(w, http.StatusBadRequest, "Instance name is required", "INVALID_ARGUMENT", "required")
@@ -182,8 +317,8 @@ func (h *SQLAdmin) CreateDatabase(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req sqladmin.DatabaseInsertRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondSQLError(w, http.StatusBadRequest, "Invalid JSON body", "INVALID_ARGUMENT", "invalid")
+	if err := decodeJSON(r.Body, &req, h.store.StrictValidation(), &sqladmin.Database{}); err != nil {
+		respondSQLError(w, http.StatusBadRequest, invalidJSONMessage(err), "INVALID_ARGUMENT", "invalid")
 		return
 	}
 
@@ -206,13 +341,13 @@ func (h *SQLAdmin) CreateDatabase(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respondSQLJSON(w, http.StatusOK, op)
+	respondSQLJSON(w, r, http.StatusOK, op)
 }
 
 // GetDatabase handles GET /sql/v1beta4/projects/{project}/instances/{instance}/databases/{database} - Get database.
 // Reference: https://cloud.google.com/sql/docs/mysql/admin-api/rest/v1beta4/databases/get
 func (h *SQLAdmin) GetDatabase(w http.ResponseWriter, r *http.Request) {
-	instanceName, dbName := extractInstanceAndDatabase(r.URL.Path)
+	instanceName, dbName := r.PathValue("instance"), r.PathValue("database")
 
 	if instanceName == "" || dbName == "" {
 		respondSQLError(w, http.StatusBadRequest, "Instance and database names are required", "INVALID_ARGUMENT", "required")
@@ -225,13 +360,13 @@ func (h *SQLAdmin) GetDatabase(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respondSQLJSON(w, http.StatusOK, db)
+	respondSQLJSON(w, r, http.StatusOK, db)
 }
 
 // UpdateDatabase handles PATCH /sql/v1beta4/projects/{project}/instances/{instance}/databases/{database} - Update database.
 // Reference: https://cloud.google.com/sql/docs/mysql/admin-api/rest/v1beta4/databases/patch
 func (h *SQLAdmin) UpdateDatabase(w http.ResponseWriter, r *http.Request) {
-	instanceName, dbName := extractInstanceAndDatabase(r.URL.Path)
+	instanceName, dbName := r.PathValue("instance"), r.PathValue("database")
 
 	if instanceName == "" || dbName == "" {
 		respondSQLError(w, http.StatusBadRequest, "Instance and database names are required", "INVALID_ARGUMENT", "required")
@@ -239,8 +374,8 @@ func (h *SQLAdmin) UpdateDatabase(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req sqladmin.DatabasePatchRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondSQLError(w, http.StatusBadRequest, "Invalid JSON body", "INVALID_ARGUMENT", "invalid")
+	if err := decodeJSON(r.Body, &req, h.store.StrictValidation(), &sqladmin.Database{}); err != nil {
+		respondSQLError(w, http.StatusBadRequest, invalidJSONMessage(err), "INVALID_ARGUMENT", "invalid")
 		return
 	}
 
@@ -254,13 +389,13 @@ func (h *SQLAdmin) UpdateDatabase(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respondSQLJSON(w, http.StatusOK, op)
+	respondSQLJSON(w, r, http.StatusOK, op)
 }
 
 // DeleteDatabase handles DELETE /sql/v1beta4/projects/{project}/instances/{instance}/databases/{database} - Delete database.
 // Reference: https://cloud.google.com/sql/docs/mysql/admin-api/rest/v1beta4/databases/delete
 func (h *SQLAdmin) DeleteDatabase(w http.ResponseWriter, r *http.Request) {
-	instanceName, dbName := extractInstanceAndDatabase(r.URL.Path)
+	instanceName, dbName := r.PathValue("instance"), r.PathValue("database")
 
 	if instanceName == "" || dbName == "" {
 		respondSQLError(w, http.StatusBadRequest, "Instance and database names are required", "INVALID_ARGUMENT", "required")
@@ -277,7 +412,7 @@ func (h *SQLAdmin) DeleteDatabase(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respondSQLJSON(w, http.StatusOK, op)
+	respondSQLJSON(w, r, http.StatusOK, op)
 }
 
 // =============================================================================
@@ -287,7 +422,7 @@ func (h *SQLAdmin) DeleteDatabase(w http.ResponseWriter, r *http.Request) {
 // ListUsers handles GET /sql/v1beta4/projects/{project}/instances/{instance}/users - List users.
 // Reference: https://cloud.google.com/sql/docs/mysql/admin-api/rest/v1beta4/users/list
 func (h *SQLAdmin) ListUsers(w http.ResponseWriter, r *http.Request) {
-	instanceName := extractSQLInstanceNameFromUsersPath(r.URL.Path)
+	instanceName := r.PathValue("instance")
 
 	if instanceName == "" {
 		respondSQLError(w, http.StatusBadRequest, "Instance name is required", "INVALID_ARGUMENT", "required")
@@ -309,13 +444,13 @@ func (h *SQLAdmin) ListUsers(w http.ResponseWriter, r *http.Request) {
 		Items: users,
 	}
 
-	respondSQLJSON(w, http.StatusOK, response)
+	respondSQLJSON(w, r, http.StatusOK, response)
 }
 
 // CreateUser handles POST /sql/v1beta4/projects/{project}/instances/{instance}/users - Create user.
 // Reference: https://cloud.google.com/sql/docs/mysql/admin-api/rest/v1beta4/users/insert
 func (h *SQLAdmin) CreateUser(w http.ResponseWriter, r *http.Request) {
-	instanceName := extractSQLInstanceNameFromUsersPath(r.URL.Path)
+	instanceName := r.PathValue("instance")
 
 	if instanceName == "" {
 		respondSQLError(w, http.StatusBadRequest, "Instance name is required", "INVALID_ARGUMENT", "required")
@@ -323,8 +458,8 @@ func (h *SQLAdmin) CreateUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req sqladmin.UserInsertRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondSQLError(w, http.StatusBadRequest, "Invalid JSON body", "INVALID_ARGUMENT", "invalid")
+	if err := decodeJSON(r.Body, &req, h.store.StrictValidation(), &sqladmin.User{}); err != nil {
+		respondSQLError(w, http.StatusBadRequest, invalidJSONMessage(err), "INVALID_ARGUMENT", "invalid")
 		return
 	}
 
@@ -343,17 +478,21 @@ func (h *SQLAdmin) CreateUser(w http.ResponseWriter, r *http.Request) {
 			respondSQLError(w, http.StatusConflict, err.Error(), "ALREADY_EXISTS", "conflict")
 			return
 		}
+		if strings.Contains(err.Error(), "invalid") {
+			respondSQLError(w, http.StatusBadRequest, err.Error(), "INVALID_ARGUMENT", "invalid")
+			return
+		}
 		respondSQLError(w, http.StatusInternalServerError, err.Error(), "INTERNAL", "internalError")
 		return
 	}
 
-	respondSQLJSON(w, http.StatusOK, op)
+	respondSQLJSON(w, r, http.StatusOK, op)
 }
 
 // UpdateUser handles PUT /sql/v1beta4/projects/{project}/instances/{instance}/users - Update user.
 // Reference: https://cloud.google.com/sql/docs/mysql/admin-api/rest/v1beta4/users/update
 func (h *SQLAdmin) UpdateUser(w http.ResponseWriter, r *http.Request) {
-	instanceName := extractSQLInstanceNameFromUsersPath(r.URL.Path)
+	instanceName := r.PathValue("instance")
 
 	if instanceName == "" {
 		respondSQLError(w, http.StatusBadRequest, "Instance name is required", "INVALID_ARGUMENT", "required")
@@ -370,8 +509,8 @@ func (h *SQLAdmin) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req sqladmin.UserUpdateRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondSQLError(w, http.StatusBadRequest, "Invalid JSON body", "INVALID_ARGUMENT", "invalid")
+	if err := decodeJSON(r.Body, &req, h.store.StrictValidation(), &sqladmin.User{}); err != nil {
+		respondSQLError(w, http.StatusBadRequest, invalidJSONMessage(err), "INVALID_ARGUMENT", "invalid")
 		return
 	}
 
@@ -381,17 +520,21 @@ func (h *SQLAdmin) UpdateUser(w http.ResponseWriter, r *http.Request) {
 			respondSQLError(w, http.StatusNotFound, err.Error(), "NOT_FOUND", "notFound")
 			return
 		}
+		if strings.Contains(err.Error(), "invalid") {
+			respondSQLError(w, http.StatusBadRequest, err.Error(), "INVALID_ARGUMENT", "invalid")
+			return
+		}
 		respondSQLError(w, http.StatusInternalServerError, err.Error(), "INTERNAL", "internalError")
 		return
 	}
 
-	respondSQLJSON(w, http.StatusOK, op)
+	respondSQLJSON(w, r, http.StatusOK, op)
 }
 
 // DeleteUser handles DELETE /sql/v1beta4/projects/{project}/instances/{instance}/users - Delete user.
 // Reference: https://cloud.google.com/sql/docs/mysql/admin-api/rest/v1beta4/users/delete
 func (h *SQLAdmin) DeleteUser(w http.ResponseWriter, r *http.Request) {
-	instanceName := extractSQLInstanceNameFromUsersPath(r.URL.Path)
+	instanceName := r.PathValue("instance")
 
 	if instanceName == "" {
 		respondSQLError(w, http.StatusBadRequest, "Instance name is required", "INVALID_ARGUMENT", "required")
@@ -417,7 +560,7 @@ func (h *SQLAdmin) DeleteUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respondSQLJSON(w, http.StatusOK, op)
+	respondSQLJSON(w, r, http.StatusOK, op)
 }
 
 // =============================================================================
@@ -429,20 +572,34 @@ func (h *SQLAdmin) DeleteUser(w http.ResponseWriter, r *http.Request) {
 func (h *SQLAdmin) ListOperations(w http.ResponseWriter, r *http.Request) {
 	instanceName := r.URL.Query().Get("instance")
 
-	operations := h.store.ListSQLOperations(instanceName)
+	maxResults, err := parseMaxResults(r.URL.Query().Get("maxResults"), sqlDefaultMaxResults, sqlMaxMaxResults)
+	if err != nil {
+		respondSQLError(w, http.StatusBadRequest, err.Error(), "INVALID_ARGUMENT", "invalid")
+		return
+	}
+
+	// Operations are listed newest first
+	operations, nextPageToken, err := paginateDescending(h.store.ListSQLOperations(instanceName), r.URL.Query().Get("pageToken"), maxResults, func(op *sqladmin.Operation) string {
+		return sortableTime(op.InsertTime) + "\x00" + op.Name
+	})
+	if err != nil {
+		respondSQLError(w, http.StatusBadRequest, err.Error(), "INVALID_ARGUMENT", "invalid")
+		return
+	}
 
 	response := &sqladmin.OperationsListResponse{
-		Kind:  "sql#operationsList",
-		Items: operations,
+		Kind:          "sql#operationsList",
+		Items:         operations,
+		NextPageToken: nextPageToken,
 	}
 
-	respondSQLJSON(w, http.StatusOK, response)
+	respondSQLJSON(w, r, http.StatusOK, response)
 }
 
 // GetOperation handles GET /sql/v1beta4/projects/{project}/operations/{operation} - Get operation.
 // Reference: https://cloud.google.com/sql/docs/mysql/admin-api/rest/v1beta4/operations/get
 func (h *SQLAdmin) GetOperation(w http.ResponseWriter, r *http.Request) {
-	opName := extractOperationName(r.URL.Path)
+	opName := r.PathValue("operation")
 
 	if opName == "" {
 		respondSQLError(w, http.StatusBadRequest, "Operation name is required", "INVALID_ARGUMENT", "required")
@@ -455,106 +612,22 @@ func (h *SQLAdmin) GetOperation(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respondSQLJSON(w, http.StatusOK, op)
+	respondSQLJSON(w, r, http.StatusOK, op)
 }
 
 // =============================================================================
 // Helper Functions
 // =============================================================================
 
-// respondSQLJSON writes a JSON response.
-func respondSQLJSON(w http.ResponseWriter, statusCode int, data interface{}) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(data)
+// respondSQLJSON writes a JSON response. Like every Cloud SQL Admin API method, it only returns the fields
+// the fields query parameter selects, like "items(name,settings/tier),nextPageToken", and honors prettyPrint.
+func respondSQLJSON(w http.ResponseWriter, r *http.Request, statusCode int, data interface{}) {
+	if err := writePartialJSON(w, r, statusCode, data); err != nil {
+		respondSQLError(w, http.StatusBadRequest, err.Error(), "INVALID_ARGUMENT", "invalid")
+	}
 }
 
 // respondSQLError writes a JSON error response matching the Cloud SQL Admin API format.
 func respondSQLError(w http.ResponseWriter, statusCode int, message, status, reason string) {
-	errResp := sqladmin.APIError{
-		Error: sqladmin.ErrorDetails{
-			Code:    statusCode,
-			Message: message,
-			Status:  status,
-			Errors: []sqladmin.ErrorReason{
-				{
-					Domain:  "global",
-					Reason:  reason,
-					Message: message,
-				},
-			},
-		},
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(errResp)
-}
-
-// extractSQLInstanceName extracts the instance name from a path like /sql/v1/projects/{project}/instances/{instance}.
-func extractSQLInstanceName(path string) string {
-	// Find /instances/ in the path
-	const marker = "/instances/"
-	idx := strings.Index(path, marker)
-	if idx < 0 {
-		return ""
-	}
-
-	remaining := path[idx+len(marker):]
-	// Remove any trailing path segments
-	if slashIdx := strings.Index(remaining, "/"); slashIdx >= 0 {
-		remaining = remaining[:slashIdx]
-	}
-	return remaining
-}
-
-// extractSQLInstanceNameFromDatabasePath extracts the instance name from a database path.
-func extractSQLInstanceNameFromDatabasePath(path string) string {
-	return extractSQLInstanceName(path)
-}
-
-// extractSQLInstanceNameFromUsersPath extracts the instance name from a users path.
-func extractSQLInstanceNameFromUsersPath(path string) string {
-	return extractSQLInstanceName(path)
-}
-
-// extractInstanceAndDatabase extracts instance and database names from a path like
-// /sql/v1/projects/{project}/instances/{instance}/databases/{database}.
-func extractInstanceAndDatabase(path string) (string, string) {
-	const instanceMarker = "/instances/"
-	const dbMarker = "/databases/"
-
-	instanceIdx := strings.Index(path, instanceMarker)
-	dbIdx := strings.Index(path, dbMarker)
-
-	if instanceIdx < 0 || dbIdx < 0 {
-		return "", ""
-	}
-
-	instancePart := path[instanceIdx+len(instanceMarker) : dbIdx]
-	dbPart := path[dbIdx+len(dbMarker):]
-
-	// Remove any trailing path segments from db name
-	if slashIdx := strings.Index(dbPart, "/"); slashIdx >= 0 {
-		dbPart = dbPart[:slashIdx]
-	}
-
-	return instancePart, dbPart
-}
-
-// extractOperationName extracts the operation name from a path like
-// /sql/v1/projects/{project}/operations/{operation}.
-func extractOperationName(path string) string {
-	const marker = "/operations/"
-	idx := strings.Index(path, marker)
-	if idx < 0 {
-		return ""
-	}
-
-	remaining := path[idx+len(marker):]
-	// Remove any trailing path segments
-	if slashIdx := strings.Index(remaining, "/"); slashIdx >= 0 {
-		remaining = remaining[:slashIdx]
-	}
-	return remaining
+	gcperror.New(statusCode, message, reason).WithStatus(status).Write(w)
 }
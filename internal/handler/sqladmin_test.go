@@ -46,6 +46,94 @@ func TestSQLAdmin_ListInstances_Empty(t *testing.T) {
 	}
 }
 
+func TestSQLAdmin_ListInstances_Pagination(t *testing.T) {
+	h, s := setupTestSQLAdmin()
+	_, _, _ = s.CreateSQLInstance(&sqladmin.InstanceInsertRequest{Name: "instance-1"})
+	_, _, _ = s.CreateSQLInstance(&sqladmin.InstanceInsertRequest{Name: "instance-2"})
+	_, _, _ = s.CreateSQLInstance(&sqladmin.InstanceInsertRequest{Name: "instance-3"})
+
+	req := httptest.NewRequest(http.MethodGet, "/sql/v1/projects/test-project/instances?maxResults=2", nil)
+	rr := httptest.NewRecorder()
+	h.ListInstances(rr, req)
+
+	var resp sqladmin.InstancesListResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if len(resp.Items) != 2 {
+		t.Errorf("expected 2 items on first page, got %d", len(resp.Items))
+	}
+	if resp.NextPageToken == "" {
+		t.Fatal("expected nextPageToken on first page")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/sql/v1/projects/test-project/instances?maxResults=2&pageToken="+resp.NextPageToken, nil)
+	rr = httptest.NewRecorder()
+	h.ListInstances(rr, req)
+
+	resp = sqladmin.InstancesListResponse{}
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if len(resp.Items) != 1 || resp.Items[0].Name != "instance-3" {
+		t.Errorf("expected instance-3 on second page, got %v", resp.Items)
+	}
+	if resp.NextPageToken != "" {
+		t.Errorf("expected no nextPageToken on last page, got %s", resp.NextPageToken)
+	}
+}
+
+func TestSQLAdmin_ListInstances_Filter(t *testing.T) {
+	h, s := setupTestSQLAdmin()
+	_, _, _ = s.CreateSQLInstance(&sqladmin.InstanceInsertRequest{Name: "prod-db", Settings: &sqladmin.Settings{UserLabels: map[string]string{"env": "prod"}}})
+	_, _, _ = s.CreateSQLInstance(&sqladmin.InstanceInsertRequest{Name: "dev-db", Settings: &sqladmin.Settings{UserLabels: map[string]string{"env": "dev"}}})
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"label filter", "?filter=settings.userLabels.env:prod&fields=items/name", http.StatusOK, `{"items":[{"name":"prod-db"}]}`},
+		{"no match", "?filter=settings.userLabels.env:staging&fields=kind,items/name", http.StatusOK, `{"items":[],"kind":"sql#instancesList"}`},
+		{"invalid filter", "?filter=settings.userLabels.env:", http.StatusBadRequest, ""},
+		{"invalid fields", "?fields=items(name", http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/sql/v1beta4/projects/test-project/instances"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			h.ListInstances(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if tt.expectedBody != "" && strings.TrimSpace(rr.Body.String()) != tt.expectedBody {
+				t.Errorf("expected body %s, got %s", tt.expectedBody, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestSQLAdmin_GetInstance_Fields(t *testing.T) {
+	h, s := setupTestSQLAdmin()
+	_, _, _ = s.CreateSQLInstance(&sqladmin.InstanceInsertRequest{Name: "test-instance", Settings: &sqladmin.Settings{Tier: "db-f1-micro"}})
+
+	req := httptest.NewRequest(http.MethodGet, "/sql/v1beta4/projects/test-project/instances/test-instance?fields=name,settings/tier", nil)
+	req.SetPathValue("project", "test-project")
+	req.SetPathValue("instance", "test-instance")
+	rr := httptest.NewRecorder()
+	h.GetInstance(rr, req)
+
+	expected := `{"name":"test-instance","settings":{"tier":"db-f1-micro"}}`
+	if rr.Code != http.StatusOK || strings.TrimSpace(rr.Body.String()) != expected {
+		t.Errorf("expected %s, got %d %s", expected, rr.Code, rr.Body.String())
+	}
+}
+
 func TestSQLAdmin_CreateInstance(t *testing.T) {
 	h, _ := setupTestSQLAdmin()
 
@@ -74,6 +162,90 @@ func TestSQLAdmin_CreateInstance(t *testing.T) {
 	}
 }
 
+func TestSQLAdmin_CreateInstance_InvalidFlag(t *testing.T) {
+	h, _ := setupTestSQLAdmin()
+
+	body := `{"name": "test-instance", "databaseVersion": "POSTGRES_15", "settings": {"databaseFlags": [{"name": "log_min_duration_statment", "value": "100"}]}}`
+	req := httptest.NewRequest(http.MethodPost, "/sql/v1/projects/test-project/instances", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	h.CreateInstance(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusBadRequest, rr.Code, rr.Body.String())
+	}
+
+	var errResp sqladmin.APIError
+	if err := json.NewDecoder(rr.Body).Decode(&errResp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(errResp.Error.Errors) == 0 || errResp.Error.Errors[0].Reason != "invalidFlagName" {
+		t.Errorf("expected reason invalidFlagName, got %+v", errResp.Error.Errors)
+	}
+}
+
+func TestSQLAdmin_CreateInstance_StrictValidation(t *testing.T) {
+	h, s := setupTestSQLAdmin()
+	s.SetStrictValidation(true)
+
+	tests := []struct {
+		name            string
+		body            string
+		expectedStatus  int
+		expectedMessage string
+	}{
+		{"valid", `{"name": "main", "databaseVersion": "POSTGRES_15", "region": "europe-west1", "settings": {"tier": "db-custom-2-7680"}}`, http.StatusOK, ""},
+		{"invalid tier", `{"name": "main2", "databaseVersion": "POSTGRES_15", "settings": {"tier": "db-n1-standard-1"}}`, http.StatusBadRequest, "invalid tier"},
+		{"invalid region", `{"name": "main2", "databaseVersion": "POSTGRES_15", "region": "europe-west"}`, http.StatusBadRequest, "invalid region"},
+		{"unknown field", `{"name": "main2", "settings": {"tierr": "db-f1-micro"}}`, http.StatusBadRequest, `Unknown name \"tierr\"`},
+		{"output-only field", `{"name": "main2", "state": "RUNNABLE"}`, http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/sql/v1beta4/projects/test-project/instances", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+
+			h.CreateInstance(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if !strings.Contains(rr.Body.String(), tt.expectedMessage) {
+				t.Errorf("expected body to contain %q, got %s", tt.expectedMessage, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestSQLAdmin_ListFlags(t *testing.T) {
+	h, _ := setupTestSQLAdmin()
+
+	req := httptest.NewRequest(http.MethodGet, "/sql/v1beta4/flags?databaseVersion=MYSQL_8_0", nil)
+	rr := httptest.NewRecorder()
+
+	h.ListFlags(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	var resp sqladmin.FlagsListResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Kind != "sql#flagsList" || len(resp.Items) == 0 {
+		t.Fatalf("expected a non-empty sql#flagsList, got %s with %d items", resp.Kind, len(resp.Items))
+	}
+	for _, flag := range resp.Items {
+		if flag.Name == "log_statement" {
+			t.Error("expected PostgreSQL flags to be filtered out")
+		}
+	}
+}
+
 func TestSQLAdmin_CreateInstance_InvalidJSON(t *testing.T) {
 	h, _ := setupTestSQLAdmin()
 
@@ -127,6 +299,7 @@ func TestSQLAdmin_GetInstance(t *testing.T) {
 	_, _, _ = s.CreateSQLInstance(&sqladmin.InstanceInsertRequest{Name: "test-instance"})
 
 	req := httptest.NewRequest(http.MethodGet, "/sql/v1/projects/test-project/instances/test-instance", nil)
+	req.SetPathValue("instance", "test-instance")
 	rr := httptest.NewRecorder()
 
 	h.GetInstance(rr, req)
@@ -149,6 +322,7 @@ func TestSQLAdmin_GetInstance_NotFound(t *testing.T) {
 	h, _ := setupTestSQLAdmin()
 
 	req := httptest.NewRequest(http.MethodGet, "/sql/v1/projects/test-project/instances/non-existent", nil)
+	req.SetPathValue("instance", "non-existent")
 	rr := httptest.NewRecorder()
 
 	h.GetInstance(rr, req)
@@ -164,6 +338,7 @@ func TestSQLAdmin_UpdateInstance(t *testing.T) {
 
 	body := `{"settings": {"tier": "db-n1-standard-2", "userLabels": {"env": "test"}}}`
 	req := httptest.NewRequest(http.MethodPatch, "/sql/v1/projects/test-project/instances/test-instance", strings.NewReader(body))
+	req.SetPathValue("instance", "test-instance")
 	req.Header.Set("Content-Type", "application/json")
 	rr := httptest.NewRecorder()
 
@@ -189,11 +364,49 @@ func TestSQLAdmin_UpdateInstance(t *testing.T) {
 	}
 }
 
+func TestSQLAdmin_UpdateInstance_Etag(t *testing.T) {
+	h, s := setupTestSQLAdmin()
+	original, _, _ := s.CreateSQLInstance(&sqladmin.InstanceInsertRequest{Name: "test-instance"})
+
+	patch := func(body, ifMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, "/sql/v1/projects/test-project/instances/test-instance", strings.NewReader(body))
+		req.SetPathValue("instance", "test-instance")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		rr := httptest.NewRecorder()
+		h.UpdateInstance(rr, req)
+		return rr
+	}
+
+	rr := patch(`{"settings": {"tier": "db-n1-standard-2"}, "etag": "`+original.Etag+`"}`, "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	updated := s.GetSQLInstance("test-instance")
+	if updated.Etag == original.Etag || rr.Header().Get("ETag") != updated.Etag {
+		t.Errorf("expected the fresh etag %q in the header, got %q", updated.Etag, rr.Header().Get("ETag"))
+	}
+
+	for _, rr := range []*httptest.ResponseRecorder{
+		patch(`{"settings": {"tier": "db-n1-standard-4"}, "etag": "`+original.Etag+`"}`, ""),
+		patch(`{"settings": {"tier": "db-n1-standard-4"}}`, original.Etag),
+	} {
+		if rr.Code != http.StatusPreconditionFailed {
+			t.Errorf("expected status %d for a stale etag, got %d", http.StatusPreconditionFailed, rr.Code)
+		}
+	}
+	if instance := s.GetSQLInstance("test-instance"); instance.Settings.Tier != "db-n1-standard-2" {
+		t.Errorf("expected the stale patches to be rejected, got tier %s", instance.Settings.Tier)
+	}
+}
+
 func TestSQLAdmin_UpdateInstance_NotFound(t *testing.T) {
 	h, _ := setupTestSQLAdmin()
 
 	body := `{"settings": {"tier": "db-n1-standard-2"}}`
 	req := httptest.NewRequest(http.MethodPatch, "/sql/v1/projects/test-project/instances/non-existent", strings.NewReader(body))
+	req.SetPathValue("instance", "non-existent")
 	req.Header.Set("Content-Type", "application/json")
 	rr := httptest.NewRecorder()
 
@@ -209,6 +422,7 @@ func TestSQLAdmin_DeleteInstance(t *testing.T) {
 	_, _, _ = s.CreateSQLInstance(&sqladmin.InstanceInsertRequest{Name: "test-instance"})
 
 	req := httptest.NewRequest(http.MethodDelete, "/sql/v1/projects/test-project/instances/test-instance", nil)
+	req.SetPathValue("instance", "test-instance")
 	rr := httptest.NewRecorder()
 
 	h.DeleteInstance(rr, req)
@@ -236,6 +450,7 @@ func TestSQLAdmin_DeleteInstance_NotFound(t *testing.T) {
 	h, _ := setupTestSQLAdmin()
 
 	req := httptest.NewRequest(http.MethodDelete, "/sql/v1/projects/test-project/instances/non-existent", nil)
+	req.SetPathValue("instance", "non-existent")
 	rr := httptest.NewRecorder()
 
 	h.DeleteInstance(rr, req)
@@ -254,6 +469,7 @@ func TestSQLAdmin_ListDatabases(t *testing.T) {
 	_, _, _ = s.CreateSQLInstance(&sqladmin.InstanceInsertRequest{Name: "test-instance"})
 
 	req := httptest.NewRequest(http.MethodGet, "/sql/v1/projects/test-project/instances/test-instance/databases", nil)
+	req.SetPathValue("instance", "test-instance")
 	rr := httptest.NewRecorder()
 
 	h.ListDatabases(rr, req)
@@ -283,6 +499,7 @@ func TestSQLAdmin_CreateDatabase(t *testing.T) {
 
 	body := `{"name": "mydb", "charset": "utf8mb4", "collation": "utf8mb4_general_ci"}`
 	req := httptest.NewRequest(http.MethodPost, "/sql/v1/projects/test-project/instances/test-instance/databases", strings.NewReader(body))
+	req.SetPathValue("instance", "test-instance")
 	req.Header.Set("Content-Type", "application/json")
 	rr := httptest.NewRecorder()
 
@@ -307,6 +524,7 @@ func TestSQLAdmin_CreateDatabase_InstanceNotFound(t *testing.T) {
 
 	body := `{"name": "mydb"}`
 	req := httptest.NewRequest(http.MethodPost, "/sql/v1/projects/test-project/instances/non-existent/databases", strings.NewReader(body))
+	req.SetPathValue("instance", "non-existent")
 	req.Header.Set("Content-Type", "application/json")
 	rr := httptest.NewRecorder()
 
@@ -323,6 +541,8 @@ func TestSQLAdmin_GetDatabase(t *testing.T) {
 	_, _, _ = s.CreateSQLDatabase("test-instance", &sqladmin.DatabaseInsertRequest{Name: "mydb"})
 
 	req := httptest.NewRequest(http.MethodGet, "/sql/v1/projects/test-project/instances/test-instance/databases/mydb", nil)
+	req.SetPathValue("instance", "test-instance")
+	req.SetPathValue("database", "mydb")
 	rr := httptest.NewRecorder()
 
 	h.GetDatabase(rr, req)
@@ -346,6 +566,8 @@ func TestSQLAdmin_GetDatabase_NotFound(t *testing.T) {
 	_, _, _ = s.CreateSQLInstance(&sqladmin.InstanceInsertRequest{Name: "test-instance"})
 
 	req := httptest.NewRequest(http.MethodGet, "/sql/v1/projects/test-project/instances/test-instance/databases/non-existent", nil)
+	req.SetPathValue("instance", "test-instance")
+	req.SetPathValue("database", "non-existent")
 	rr := httptest.NewRecorder()
 
 	h.GetDatabase(rr, req)
@@ -361,6 +583,8 @@ func TestSQLAdmin_DeleteDatabase(t *testing.T) {
 	_, _, _ = s.CreateSQLDatabase("test-instance", &sqladmin.DatabaseInsertRequest{Name: "mydb"})
 
 	req := httptest.NewRequest(http.MethodDelete, "/sql/v1/projects/test-project/instances/test-instance/databases/mydb", nil)
+	req.SetPathValue("instance", "test-instance")
+	req.SetPathValue("database", "mydb")
 	rr := httptest.NewRecorder()
 
 	h.DeleteDatabase(rr, req)
@@ -384,6 +608,7 @@ func TestSQLAdmin_ListUsers(t *testing.T) {
 	_, _, _ = s.CreateSQLInstance(&sqladmin.InstanceInsertRequest{Name: "test-instance"})
 
 	req := httptest.NewRequest(http.MethodGet, "/sql/v1/projects/test-project/instances/test-instance/users", nil)
+	req.SetPathValue("instance", "test-instance")
 	rr := httptest.NewRecorder()
 
 	h.ListUsers(rr, req)
@@ -413,6 +638,7 @@ func TestSQLAdmin_CreateUser(t *testing.T) {
 
 	body := `{"name": "testuser", "password": "secret123", "host": "%"}`
 	req := httptest.NewRequest(http.MethodPost, "/sql/v1/projects/test-project/instances/test-instance/users", strings.NewReader(body))
+	req.SetPathValue("instance", "test-instance")
 	req.Header.Set("Content-Type", "application/json")
 	rr := httptest.NewRecorder()
 
@@ -437,6 +663,7 @@ func TestSQLAdmin_CreateUser_InstanceNotFound(t *testing.T) {
 
 	body := `{"name": "testuser"}`
 	req := httptest.NewRequest(http.MethodPost, "/sql/v1/projects/test-project/instances/non-existent/users", strings.NewReader(body))
+	req.SetPathValue("instance", "non-existent")
 	req.Header.Set("Content-Type", "application/json")
 	rr := httptest.NewRecorder()
 
@@ -447,6 +674,28 @@ func TestSQLAdmin_CreateUser_InstanceNotFound(t *testing.T) {
 	}
 }
 
+func TestSQLAdmin_CreateUser_WeakPassword(t *testing.T) {
+	h, s := setupTestSQLAdmin()
+	_, _, _ = s.CreateSQLInstance(&sqladmin.InstanceInsertRequest{
+		Name: "test-instance",
+		Settings: &sqladmin.Settings{
+			PasswordValidationPolicy: &sqladmin.PasswordValidationPolicy{EnablePasswordPolicy: true, MinLength: 12},
+		},
+	})
+
+	body := `{"name": "testuser", "password": "short"}`
+	req := httptest.NewRequest(http.MethodPost, "/sql/v1/projects/test-project/instances/test-instance/users", strings.NewReader(body))
+	req.SetPathValue("instance", "test-instance")
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	h.CreateUser(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d: %s", http.StatusBadRequest, rr.Code, rr.Body.String())
+	}
+}
+
 func TestSQLAdmin_UpdateUser(t *testing.T) {
 	h, s := setupTestSQLAdmin()
 	_, _, _ = s.CreateSQLInstance(&sqladmin.InstanceInsertRequest{Name: "test-instance"})
@@ -454,6 +703,7 @@ func TestSQLAdmin_UpdateUser(t *testing.T) {
 
 	body := `{"password": "newpassword"}`
 	req := httptest.NewRequest(http.MethodPut, "/sql/v1/projects/test-project/instances/test-instance/users?name=testuser&host=%25", strings.NewReader(body))
+	req.SetPathValue("instance", "test-instance")
 	req.Header.Set("Content-Type", "application/json")
 	rr := httptest.NewRecorder()
 
@@ -479,6 +729,7 @@ func TestSQLAdmin_DeleteUser(t *testing.T) {
 	_, _, _ = s.CreateSQLUser("test-instance", &sqladmin.UserInsertRequest{Name: "testuser", Host: "%"})
 
 	req := httptest.NewRequest(http.MethodDelete, "/sql/v1/projects/test-project/instances/test-instance/users?name=testuser&host=%25", nil)
+	req.SetPathValue("instance", "test-instance")
 	rr := httptest.NewRecorder()
 
 	h.DeleteUser(rr, req)
@@ -557,6 +808,7 @@ func TestSQLAdmin_GetOperation(t *testing.T) {
 	_, op, _ := s.CreateSQLInstance(&sqladmin.InstanceInsertRequest{Name: "test-instance"})
 
 	req := httptest.NewRequest(http.MethodGet, "/sql/v1/projects/test-project/operations/"+op.Name, nil)
+	req.SetPathValue("operation", op.Name)
 	rr := httptest.NewRecorder()
 
 	h.GetOperation(rr, req)
@@ -579,6 +831,7 @@ func TestSQLAdmin_GetOperation_NotFound(t *testing.T) {
 	h, _ := setupTestSQLAdmin()
 
 	req := httptest.NewRequest(http.MethodGet, "/sql/v1/projects/test-project/operations/non-existent", nil)
+	req.SetPathValue("operation", "non-existent")
 	rr := httptest.NewRecorder()
 
 	h.GetOperation(rr, req)
@@ -587,115 +840,3 @@ func TestSQLAdmin_GetOperation_NotFound(t *testing.T) {
 		t.Errorf("expected status %d, got %d", http.StatusNotFound, rr.Code)
 	}
 }
-
-// =============================================================================
-// Helper Function Tests
-// =============================================================================
-
-func TestExtractSQLInstanceName(t *testing.T) {
-	tests := []struct {
-		name     string
-		path     string
-		expected string
-	}{
-		{
-			name:     "standard path",
-			path:     "/sql/v1/projects/test-project/instances/my-instance",
-			expected: "my-instance",
-		},
-		{
-			name:     "path with trailing slash",
-			path:     "/sql/v1/projects/test-project/instances/my-instance/",
-			expected: "my-instance",
-		},
-		{
-			name:     "path with databases",
-			path:     "/sql/v1/projects/test-project/instances/my-instance/databases",
-			expected: "my-instance",
-		},
-		{
-			name:     "no instances in path",
-			path:     "/sql/v1/projects/test-project/operations/my-op",
-			expected: "",
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := extractSQLInstanceName(tt.path)
-			if result != tt.expected {
-				t.Errorf("expected '%s', got '%s'", tt.expected, result)
-			}
-		})
-	}
-}
-
-func TestExtractInstanceAndDatabase(t *testing.T) {
-	tests := []struct {
-		name             string
-		path             string
-		expectedInstance string
-		expectedDB       string
-	}{
-		{
-			name:             "standard path",
-			path:             "/sql/v1/projects/test-project/instances/my-instance/databases/mydb",
-			expectedInstance: "my-instance",
-			expectedDB:       "mydb",
-		},
-		{
-			name:             "no database",
-			path:             "/sql/v1/projects/test-project/instances/my-instance/databases",
-			expectedInstance: "",
-			expectedDB:       "",
-		},
-		{
-			name:             "no instances marker",
-			path:             "/sql/v1/projects/test-project/databases/mydb",
-			expectedInstance: "",
-			expectedDB:       "",
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			instance, db := extractInstanceAndDatabase(tt.path)
-			if instance != tt.expectedInstance || db != tt.expectedDB {
-				t.Errorf("expected ('%s', '%s'), got ('%s', '%s')", tt.expectedInstance, tt.expectedDB, instance, db)
-			}
-		})
-	}
-}
-
-func TestExtractOperationName(t *testing.T) {
-	tests := []struct {
-		name     string
-		path     string
-		expected string
-	}{
-		{
-			name:     "standard path",
-			path:     "/sql/v1/projects/test-project/operations/op-12345",
-			expected: "op-12345",
-		},
-		{
-			name:     "path with trailing content",
-			path:     "/sql/v1/projects/test-project/operations/op-12345/extra",
-			expected: "op-12345",
-		},
-		{
-			name:     "no operations in path",
-			path:     "/sql/v1/projects/test-project/instances/my-instance",
-			expected: "",
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := extractOperationName(tt.path)
-			if result != tt.expected {
-				t.Errorf("expected '%s', got '%s'", tt.expected, result)
-			}
-		})
-	}
-}
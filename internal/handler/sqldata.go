@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/katharinasick/gcp-api-mock/internal/sqldata"
+)
+
+// SQLData handles the admin API of the SQLite files backing the Cloud SQL databases.
+type SQLData struct {
+	files *sqldata.Files
+}
+
+// NewSQLData creates a new SQLData handler.
+func NewSQLData(files *sqldata.Files) *SQLData {
+	return &SQLData{files: files}
+}
+
+// SQLDataList is the response body listing the SQLite files of the Cloud SQL databases.
+type SQLDataList struct {
+	Items []sqldata.Database `json:"items"`
+}
+
+// List handles GET /admin/sql/databases - List the SQLite file of each Cloud SQL database.
+func (h *SQLData) List(w http.ResponseWriter, r *http.Request) {
+	h.files.Sync()
+
+	respondJSON(w, http.StatusOK, SQLDataList{Items: h.files.List()})
+}
+
+// Get handles GET /admin/sql/instances/{instance}/databases/{database}/dsn - Get the SQLite file of a
+// Cloud SQL database with the DSN to open it with, e.g. sql.Open(db.Driver, db.DSN).
+func (h *SQLData) Get(w http.ResponseWriter, r *http.Request) {
+	h.files.Sync()
+
+	instance, database := r.PathValue("instance"), r.PathValue("database")
+	db, ok := h.files.Get(instance, database)
+	if !ok {
+		respondError(w, http.StatusNotFound, "Database "+database+" of instance "+instance+" not found", "notFound")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, db)
+}
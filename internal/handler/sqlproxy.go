@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/katharinasick/gcp-api-mock/internal/sqlproxy"
+)
+
+// SQLProxy handles the admin API of the Cloud SQL instance ports.
+type SQLProxy struct {
+	proxy *sqlproxy.Proxy
+}
+
+// NewSQLProxy creates a new SQLProxy handler.
+func NewSQLProxy(proxy *sqlproxy.Proxy) *SQLProxy {
+	return &SQLProxy{proxy: proxy}
+}
+
+// SQLProxyList is the response body listing the ports of the Cloud SQL instances.
+type SQLProxyList struct {
+	Items []sqlproxy.Listener `json:"items"`
+}
+
+// SQLProxyTargetRequest is the request body for setting the database connections to an instance are forwarded to.
+type SQLProxyTargetRequest struct {
+	Target string `json:"target"`
+}
+
+// List handles GET /admin/sql/proxy - List the port of each Cloud SQL instance, with the database
+// its connections are forwarded to and how many are open.
+func (h *SQLProxy) List(w http.ResponseWriter, r *http.Request) {
+	h.proxy.Sync()
+
+	respondJSON(w, http.StatusOK, SQLProxyList{Items: h.proxy.List()})
+}
+
+// SetTarget handles PUT /admin/sql/proxy/{connectionName} - Forward new connections to the instance with the
+// connection name to a database, e.g. {"target": "localhost:5432"}. The instance doesn't have to exist yet.
+func (h *SQLProxy) SetTarget(w http.ResponseWriter, r *http.Request) {
+	var req SQLProxyTargetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Target == "" {
+		respondError(w, http.StatusBadRequest, "Invalid JSON body: expected a target like localhost:5432", "invalid")
+		return
+	}
+
+	if err := h.proxy.SetTarget(r.PathValue("connectionName"), req.Target); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteTarget handles DELETE /admin/sql/proxy/{connectionName} - Close new connections to the instance with the
+// connection name right away instead of forwarding them.
+func (h *SQLProxy) DeleteTarget(w http.ResponseWriter, r *http.Request) {
+	h.proxy.SetTarget(r.PathValue("connectionName"), "")
+
+	w.WriteHeader(http.StatusNoContent)
+}
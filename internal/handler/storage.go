@@ -2,15 +2,20 @@
 package handler
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"mime"
 	"mime/multipart"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/katharinasick/gcp-api-mock/internal/gcperror"
 	"github.com/katharinasick/gcp-api-mock/internal/storage"
 	"github.com/katharinasick/gcp-api-mock/internal/store"
 )
@@ -28,22 +33,41 @@ func NewStorage(s *store.Store) *Storage {
 // ListBuckets handles GET /storage/v1/b - List buckets in a project.
 // Reference: https://cloud.google.com/storage/docs/json_api/v1/buckets/list
 func (h *Storage) ListBuckets(w http.ResponseWriter, r *http.Request) {
-	buckets := h.store.ListBuckets()
+	if !h.checkProject(w, r) {
+		return
+	}
+
+	maxResults, err := parseMaxResults(r.URL.Query().Get("maxResults"), storageDefaultMaxResults, storageDefaultMaxResults)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+		return
+	}
+
+	buckets, nextPageToken, err := paginate(h.store.ListBuckets(), r.URL.Query().Get("pageToken"), maxResults, func(b *storage.Bucket) string { return b.Name })
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+		return
+	}
 
 	response := &storage.BucketList{
-		Kind:  "storage#buckets",
-		Items: buckets,
+		Kind:          "storage#buckets",
+		Items:         buckets,
+		NextPageToken: nextPageToken,
 	}
 
-	respondJSON(w, http.StatusOK, response)
+	respondStorageJSON(w, r, http.StatusOK, response)
 }
 
 // CreateBucket handles POST /storage/v1/b - Create a new bucket.
 // Reference: https://cloud.google.com/storage/docs/json_api/v1/buckets/insert
 func (h *Storage) CreateBucket(w http.ResponseWriter, r *http.Request) {
+	if !h.checkProject(w, r) {
+		return
+	}
+
 	var req storage.BucketInsertRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondError(w, http.StatusBadRequest, "Invalid JSON body", "invalid")
+	if err := decodeJSON(r.Body, &req, h.store.StrictValidation(), &storage.Bucket{}); err != nil {
+		respondError(w, http.StatusBadRequest, invalidJSONMessage(err), "invalid")
 		return
 	}
 
@@ -52,23 +76,56 @@ func (h *Storage) CreateBucket(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := validateEncryption(req.Encryption); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+		return
+	}
+	if err := validateAutoclass(req.Autoclass); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+		return
+	}
+	if err := storage.ValidateLifecycle(req.Lifecycle); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+		return
+	}
+	if err := storage.ValidateIamConfiguration(req.IamConfiguration); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+		return
+	}
+	if err := storage.ValidateRetentionPolicy(req.RetentionPolicy); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+		return
+	}
+
 	bucket, err := h.store.CreateBucket(&req)
 	if err != nil {
 		if strings.Contains(err.Error(), "already exists") {
 			respondError(w, http.StatusConflict, err.Error(), "conflict")
 			return
 		}
+		if strings.Contains(err.Error(), "invalid bucket name") {
+			respondError(w, http.StatusBadRequest, "Invalid bucket name: '"+req.Name+"'", "invalid")
+			return
+		}
+		if strings.Contains(err.Error(), "invalid location") {
+			respondError(w, http.StatusBadRequest, "The specified location constraint is not valid.", "invalid")
+			return
+		}
+		if strings.Contains(err.Error(), "precondition failed") {
+			respondError(w, http.StatusPreconditionFailed, err.Error(), "conditionNotMet")
+			return
+		}
 		respondError(w, http.StatusInternalServerError, err.Error(), "internalError")
 		return
 	}
 
-	respondJSON(w, http.StatusOK, bucket)
+	respondStorageJSON(w, r, http.StatusOK, bucket)
 }
 
 // GetBucket handles GET /storage/v1/b/{bucket} - Get bucket metadata.
 // Reference: https://cloud.google.com/storage/docs/json_api/v1/buckets/get
 func (h *Storage) GetBucket(w http.ResponseWriter, r *http.Request) {
-	bucketName := extractBucketName(r.URL.Path, "/storage/v1/b/")
+	bucketName := r.PathValue("bucket")
 
 	if bucketName == "" {
 		respondError(w, http.StatusBadRequest, "Bucket name is required", "required")
@@ -81,14 +138,14 @@ func (h *Storage) GetBucket(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respondJSON(w, http.StatusOK, bucket)
+	respondStorageJSON(w, r, http.StatusOK, bucket)
 }
 
-// UpdateBucket handles PUT/PATCH /storage/v1/b/{bucket} - Update bucket metadata.
+// UpdateBucket handles PUT /storage/v1/b/{bucket} - Update bucket metadata.
+// An etag in the body or an If-Match header must match the current etag of the bucket, else 412 is returned.
 // Reference: https://cloud.google.com/storage/docs/json_api/v1/buckets/update
-// Reference: https://cloud.google.com/storage/docs/json_api/v1/buckets/patch
 func (h *Storage) UpdateBucket(w http.ResponseWriter, r *http.Request) {
-	bucketName := extractBucketName(r.URL.Path, "/storage/v1/b/")
+	bucketName := r.PathValue("bucket")
 
 	if bucketName == "" {
 		respondError(w, http.StatusBadRequest, "Bucket name is required", "required")
@@ -96,8 +153,34 @@ func (h *Storage) UpdateBucket(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req storage.BucketUpdateRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondError(w, http.StatusBadRequest, "Invalid JSON body", "invalid")
+	var err error
+	if err = decodeJSON(r.Body, &req, h.store.StrictValidation(), &storage.Bucket{}); err != nil {
+		respondError(w, http.StatusBadRequest, invalidJSONMessage(err), "invalid")
+		return
+	}
+
+	if err := validateEncryption(req.Encryption); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+		return
+	}
+	if err := validateAutoclass(req.Autoclass); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+		return
+	}
+	if err := storage.ValidateLifecycle(req.Lifecycle); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+		return
+	}
+	if err := storage.ValidateIamConfiguration(req.IamConfiguration); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+		return
+	}
+	if err := storage.ValidateRetentionPolicy(req.RetentionPolicy); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+		return
+	}
+	if req.Etag, err = ifMatchEtag(r, req.Etag); err != nil {
+		respondError(w, http.StatusPreconditionFailed, err.Error(), "conditionNotMet")
 		return
 	}
 
@@ -107,17 +190,163 @@ func (h *Storage) UpdateBucket(w http.ResponseWriter, r *http.Request) {
 			respondError(w, http.StatusNotFound, err.Error(), "notFound")
 			return
 		}
+		if strings.Contains(err.Error(), "precondition failed") {
+			respondError(w, http.StatusPreconditionFailed, err.Error(), "conditionNotMet")
+			return
+		}
+		if strings.Contains(err.Error(), "locked retention policy") {
+			respondError(w, http.StatusForbidden, err.Error(), "forbidden")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error(), "internalError")
+		return
+	}
+
+	w.Header().Set("ETag", bucket.Etag)
+	respondStorageJSON(w, r, http.StatusOK, bucket)
+}
+
+// PatchBucket handles PATCH /storage/v1/b/{bucket} - Patch bucket metadata.
+// Labels are merged and fields set to null are cleared. Etags are checked like by UpdateBucket.
+// Reference: https://cloud.google.com/storage/docs/json_api/v1/buckets/patch
+func (h *Storage) PatchBucket(w http.ResponseWriter, r *http.Request) {
+	bucketName := r.PathValue("bucket")
+
+	if bucketName == "" {
+		respondError(w, http.StatusBadRequest, "Bucket name is required", "required")
+		return
+	}
+
+	var req storage.BucketPatchRequest
+	nullFields, err := decodePatchRequest(r, &req, h.store.StrictValidation(), &storage.Bucket{})
+	if err != nil {
+		respondError(w, http.StatusBadRequest, invalidJSONMessage(err), "invalid")
+		return
+	}
+	req.NullFields = nullFields
+
+	if err := validateEncryption(req.Encryption); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+		return
+	}
+	if err := validateAutoclass(req.Autoclass); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+		return
+	}
+	if err := storage.ValidateLifecycle(req.Lifecycle); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+		return
+	}
+	if err := storage.ValidateIamConfiguration(req.IamConfiguration); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+		return
+	}
+	if err := storage.ValidateRetentionPolicy(req.RetentionPolicy); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+		return
+	}
+	if req.Etag, err = ifMatchEtag(r, req.Etag); err != nil {
+		respondError(w, http.StatusPreconditionFailed, err.Error(), "conditionNotMet")
+		return
+	}
+
+	bucket, err := h.store.PatchBucket(bucketName, &req)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondError(w, http.StatusNotFound, err.Error(), "notFound")
+			return
+		}
+		if strings.Contains(err.Error(), "precondition failed") {
+			respondError(w, http.StatusPreconditionFailed, err.Error(), "conditionNotMet")
+			return
+		}
+		if strings.Contains(err.Error(), "locked retention policy") {
+			respondError(w, http.StatusForbidden, err.Error(), "forbidden")
+			return
+		}
 		respondError(w, http.StatusInternalServerError, err.Error(), "internalError")
 		return
 	}
 
-	respondJSON(w, http.StatusOK, bucket)
+	w.Header().Set("ETag", bucket.Etag)
+	respondStorageJSON(w, r, http.StatusOK, bucket)
+}
+
+// LockRetentionPolicy handles POST /storage/v1/b/{bucket}/lockRetentionPolicy?ifMetagenerationMatch={metageneration} -
+// Lock the retention policy of a bucket. The metageneration is required, so the policy that is locked is the one
+// the caller saw.
+// Reference: https://cloud.google.com/storage/docs/json_api/v1/buckets/lockRetentionPolicy
+func (h *Storage) LockRetentionPolicy(w http.ResponseWriter, r *http.Request) {
+	bucketName := r.PathValue("bucket")
+
+	metageneration, err := parsePrecondition(r, "ifMetagenerationMatch", "")
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+		return
+	}
+	if metageneration == nil {
+		respondError(w, http.StatusBadRequest, "Required parameter: ifMetagenerationMatch", "required")
+		return
+	}
+
+	bucket, err := h.store.LockRetentionPolicy(bucketName, *metageneration)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			respondError(w, http.StatusNotFound, "Bucket not found", "notFound")
+		case strings.Contains(err.Error(), "precondition failed"):
+			respondError(w, http.StatusPreconditionFailed, err.Error(), "conditionNotMet")
+		case strings.Contains(err.Error(), "invalid"):
+			respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+		default:
+			respondError(w, http.StatusInternalServerError, err.Error(), "internalError")
+		}
+		return
+	}
+
+	w.Header().Set("ETag", bucket.Etag)
+	respondStorageJSON(w, r, http.StatusOK, bucket)
+}
+
+// checkProject checks that a request that creates or lists buckets names a project. The real API requires
+// the project query parameter, but the mock only does with strict validation, since buckets aren't per project.
+// Returns false after writing the error response.
+func (h *Storage) checkProject(w http.ResponseWriter, r *http.Request) bool {
+	if h.store.StrictValidation() && r.URL.Query().Get("project") == "" {
+		respondError(w, http.StatusBadRequest, "Required parameter: project", "required")
+		return false
+	}
+	return true
+}
+
+// decodePatchRequest decodes the JSON body of a PATCH request into v like decodeJSON and returns
+// the names of the top-level fields set to null, which the API uses to clear fields.
+func decodePatchRequest(r *http.Request, v any, strict bool, resources ...any) ([]string, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err := decodeJSON(bytes.NewReader(body), v, strict, resources...); err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, err
+	}
+	var nullFields []string
+	for name, value := range fields {
+		if string(value) == "null" {
+			nullFields = append(nullFields, name)
+		}
+	}
+	return nullFields, nil
 }
 
 // DeleteBucket handles DELETE /storage/v1/b/{bucket} - Delete a bucket.
 // Reference: https://cloud.google.com/storage/docs/json_api/v1/buckets/delete
 func (h *Storage) DeleteBucket(w http.ResponseWriter, r *http.Request) {
-	bucketName := extractBucketName(r.URL.Path, "/storage/v1/b/")
+	bucketName := r.PathValue("bucket")
 
 	if bucketName == "" {
 		respondError(w, http.StatusBadRequest, "Bucket name is required", "required")
@@ -144,9 +373,7 @@ func (h *Storage) DeleteBucket(w http.ResponseWriter, r *http.Request) {
 // ListObjects handles GET /storage/v1/b/{bucket}/o - List objects in a bucket.
 // Reference: https://cloud.google.com/storage/docs/json_api/v1/objects/list
 func (h *Storage) ListObjects(w http.ResponseWriter, r *http.Request) {
-	// Extract bucket name from path like /storage/v1/b/{bucket}/o
-	path := r.URL.Path
-	bucketName := extractBucketFromObjectsPath(path)
+	bucketName := r.PathValue("bucket")
 
 	if bucketName == "" {
 		respondError(w, http.StatusBadRequest, "Bucket name is required", "required")
@@ -162,25 +389,114 @@ func (h *Storage) ListObjects(w http.ResponseWriter, r *http.Request) {
 	// Get query parameters
 	prefix := r.URL.Query().Get("prefix")
 	delimiter := r.URL.Query().Get("delimiter")
+	pageToken := r.URL.Query().Get("pageToken")
+
+	maxResults, err := parseMaxResults(r.URL.Query().Get("maxResults"), storageDefaultMaxResults, storageDefaultMaxResults)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+		return
+	}
+
+	// Soft-deleted objects are listed separately and never grouped into prefixes
+	if r.URL.Query().Get("softDeleted") == "true" {
+		objects, nextPageToken, err := paginate(h.store.ListSoftDeletedObjects(bucketName, prefix), pageToken, maxResults, func(obj *storage.Object) string {
+			return fmt.Sprintf("%s\x00%020d", obj.Name, obj.Generation)
+		})
+		if err != nil {
+			respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+			return
+		}
+
+		response := &storage.ObjectList{
+			Kind:          "storage#objects",
+			Items:         objects,
+			NextPageToken: nextPageToken,
+		}
+		respondStorageJSON(w, r, http.StatusOK, response)
+		return
+	}
+
+	// Managed folders aren't emulated, so there are no folders to add as prefixes, but the parameter is validated like in the real API
+	if r.URL.Query().Get("includeFoldersAsPrefixes") == "true" && delimiter != "/" {
+		respondError(w, http.StatusBadRequest, "includeFoldersAsPrefixes is only supported with delimiter '/'", "invalid")
+		return
+	}
 
-	objects, prefixes := h.store.ListObjects(bucketName, prefix, delimiter)
+	opts := store.ListOptions{
+		Prefix:                   prefix,
+		Delimiter:                delimiter,
+		StartOffset:              r.URL.Query().Get("startOffset"),
+		EndOffset:                r.URL.Query().Get("endOffset"),
+		MatchGlob:                r.URL.Query().Get("matchGlob"),
+		IncludeTrailingDelimiter: r.URL.Query().Get("includeTrailingDelimiter") == "true",
+	}
+	objects, prefixes, err := h.store.ListObjectsWithOptions(bucketName, opts)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+		return
+	}
+
+	// Objects and prefixes both count towards maxResults, like in the real API
+	entries, nextPageToken, err := paginate(mergeObjectListEntries(objects, prefixes), pageToken, maxResults, objectListEntry.key)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+		return
+	}
 
 	response := &storage.ObjectList{
-		Kind:     "storage#objects",
-		Items:    objects,
-		Prefixes: prefixes,
+		Kind:          "storage#objects",
+		Items:         []*storage.Object{},
+		NextPageToken: nextPageToken,
+	}
+	for _, entry := range entries {
+		if entry.object != nil {
+			response.Items = append(response.Items, entry.object)
+		} else {
+			response.Prefixes = append(response.Prefixes, entry.name)
+		}
+	}
+
+	respondStorageJSON(w, r, http.StatusOK, response)
+}
+
+// objectListEntry is a single entry in an object listing, either an object or a prefix.
+type objectListEntry struct {
+	name   string
+	object *storage.Object
+}
+
+// key returns the page key of an entry. With includeTrailingDelimiter, an object like "a/" is listed after
+// the prefix with the same name, so its key sorts after the prefix's.
+func (e objectListEntry) key() string {
+	if e.object != nil {
+		return e.name + "\x00"
+	}
+	return e.name
+}
+
+// mergeObjectListEntries merges sorted objects and prefixes into a single list sorted by name.
+func mergeObjectListEntries(objects []*storage.Object, prefixes []string) []objectListEntry {
+	entries := make([]objectListEntry, 0, len(objects)+len(prefixes))
+
+	i, j := 0, 0
+	for i < len(objects) || j < len(prefixes) {
+		if j >= len(prefixes) || (i < len(objects) && objects[i].Name < prefixes[j]) {
+			entries = append(entries, objectListEntry{name: objects[i].Name, object: objects[i]})
+			i++
+		} else {
+			entries = append(entries, objectListEntry{name: prefixes[j]})
+			j++
+		}
 	}
 
-	respondJSON(w, http.StatusOK, response)
+	return entries
 }
 
 // InsertObject handles POST /upload/storage/v1/b/{bucket}/o - Upload an object.
 // Reference: https://cloud.google.com/storage/docs/json_api/v1/objects/insert
-// Supports both simple uploads and multipart/related uploads (used by Terraform).
+// Supports simple uploads, multipart/related uploads (used by Terraform) and starting resumable uploads.
 func (h *Storage) InsertObject(w http.ResponseWriter, r *http.Request) {
-	// Extract bucket name from path like /upload/storage/v1/b/{bucket}/o
-	path := r.URL.Path
-	bucketName := extractBucketFromUploadPath(path)
+	bucketName := r.PathValue("bucket")
 
 	if bucketName == "" {
 		respondError(w, http.StatusBadRequest, "Bucket name is required", "required")
@@ -193,238 +509,896 @@ func (h *Storage) InsertObject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get object name from query parameter
+	// Get object name from query parameter; multipart and resumable uploads may name the object in the body instead
 	objectName := r.URL.Query().Get("name")
-	if objectName == "" {
-		respondError(w, http.StatusBadRequest, "Object name is required", "required")
+
+	pre, err := parsePreconditions(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid")
 		return
 	}
 
-	// URL decode the object name
-	decodedName, err := url.QueryUnescape(objectName)
-	if err == nil {
-		objectName = decodedName
+	if r.URL.Query().Get("uploadType") == "resumable" {
+		h.startResumableUpload(w, r, bucketName, objectName, pre)
+		return
 	}
 
-	var content []byte
-	var contentType string
-	var metadata map[string]string
+	var content io.Reader
+	var req *storage.ObjectInsertRequest
 
 	// Check if this is a multipart/related upload (used by Terraform and other clients)
 	reqContentType := r.Header.Get("Content-Type")
-	if strings.HasPrefix(reqContentType, "multipart/related") {
-		// Parse multipart/related request
-		content, contentType, metadata, err = parseMultipartRelatedUpload(r)
+	isMultipart := strings.HasPrefix(reqContentType, "multipart/related")
+
+	// Cut off uploads beyond the maximum object size while they are streamed
+	maxObjectSize := h.store.MaxObjectSize()
+	overhead := int64(0)
+	if isMultipart {
+		overhead = multipartUploadOverhead
+	}
+	if !limitUploadBody(w, r, maxObjectSize, overhead) {
+		respondObjectTooLarge(w, maxObjectSize)
+		return
+	}
+
+	if isMultipart {
+		// Parse multipart/related request; the content part is streamed rather than buffered
+		content, req, err = parseMultipartRelatedUpload(r, h.store.StrictValidation())
 		if err != nil {
+			if isTooLarge(err) {
+				respondObjectTooLarge(w, maxObjectSize)
+				return
+			}
+			var unknownErr *unknownFieldError
+			if errors.As(err, &unknownErr) {
+				respondError(w, http.StatusBadRequest, unknownErr.Error(), "invalid")
+				return
+			}
 			respondError(w, http.StatusBadRequest, "Failed to parse multipart request: "+err.Error(), "invalid")
 			return
 		}
-	} else {
-		// Simple upload - read content directly
-		content, err = io.ReadAll(r.Body)
-		if err != nil {
-			respondError(w, http.StatusBadRequest, "Failed to read request body", "invalid")
-			return
+		if objectName == "" {
+			objectName = req.Name
 		}
+	} else {
+		// Simple upload - the request body is the content
+		content = r.Body
 
 		// Get content type from header
-		contentType = reqContentType
-		if contentType == "" {
-			contentType = "application/octet-stream"
+		req = &storage.ObjectInsertRequest{ContentType: reqContentType}
+		if req.ContentType == "" {
+			req.ContentType = "application/octet-stream"
 		}
 
 		// Get metadata from query parameters (x-goog-meta-*)
 		for key, values := range r.URL.Query() {
 			if strings.HasPrefix(key, "x-goog-meta-") && len(values) > 0 {
-				if metadata == nil {
-					metadata = make(map[string]string)
+				if req.Metadata == nil {
+					req.Metadata = make(map[string]string)
 				}
 				metaKey := strings.TrimPrefix(key, "x-goog-meta-")
-				metadata[metaKey] = values[0]
+				req.Metadata[metaKey] = values[0]
 			}
 		}
+
+		// Object attributes may also be sent as headers
+		if err := applyUploadHeaders(r, req); err != nil {
+			respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+			return
+		}
+	}
+
+	if objectName == "" {
+		respondError(w, http.StatusBadRequest, "Object name is required", "required")
+		return
 	}
 
-	obj, err := h.store.CreateObject(bucketName, objectName, contentType, content, metadata)
+	if !applyInsertKmsKeyName(w, r, req) {
+		return
+	}
+	applyUploadHashes(r, req)
+	req.PredefinedACL = r.URL.Query().Get("predefinedAcl")
+
+	// The content is streamed into the store rather than buffered in memory
+	obj, err := h.store.CreateObjectWithOptions(bucketName, objectName, req.ContentType, content, req.Metadata, store.InsertOptions(req, pre))
 	if err != nil {
+		if isTooLarge(err) {
+			respondObjectTooLarge(w, maxObjectSize)
+			return
+		}
+		if strings.Contains(err.Error(), "not found") {
+			respondError(w, http.StatusNotFound, "Bucket not found", "notFound")
+			return
+		}
+		if strings.Contains(err.Error(), "precondition failed") {
+			respondError(w, http.StatusPreconditionFailed, err.Error(), "conditionNotMet")
+			return
+		}
+		if strings.Contains(err.Error(), "invalid checksum") || strings.Contains(err.Error(), "invalid object name") ||
+			strings.Contains(err.Error(), "invalid storage class") || strings.Contains(err.Error(), "ACL") {
+			respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+			return
+		}
+		if strings.Contains(err.Error(), "quota exceeded") {
+			respondError(w, http.StatusForbidden, err.Error(), "quotaExceeded")
+			return
+		}
+		if isRetained(err) {
+			respondError(w, http.StatusForbidden, err.Error(), "retentionPolicyNotMet")
+			return
+		}
 		respondError(w, http.StatusInternalServerError, err.Error(), "internalError")
 		return
 	}
 
-	respondJSON(w, http.StatusOK, obj)
+	respondStorageJSON(w, r, http.StatusOK, obj)
 }
 
-// GetObject handles GET /storage/v1/b/{bucket}/o/{object} - Get object metadata.
-// Reference: https://cloud.google.com/storage/docs/json_api/v1/objects/get
-func (h *Storage) GetObject(w http.ResponseWriter, r *http.Request) {
-	bucketName, objectName := extractBucketAndObjectNames(r.URL.Path)
-
-	if bucketName == "" || objectName == "" {
-		respondError(w, http.StatusBadRequest, "Bucket and object names are required", "required")
+// startResumableUpload starts a resumable upload for InsertObject.
+// The request body optionally contains the object resource; the upload URL is returned in the Location header.
+// The preconditions are checked when the upload completes.
+// Reference: https://cloud.google.com/storage/docs/performing-resumable-uploads
+func (h *Storage) startResumableUpload(w http.ResponseWriter, r *http.Request, bucketName, objectName string, pre store.Preconditions) {
+	req := &storage.ObjectInsertRequest{}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Failed to read request body", "invalid")
 		return
 	}
-
-	// URL decode the object name
-	decodedName, err := url.QueryUnescape(objectName)
-	if err == nil {
-		objectName = decodedName
+	if len(bytes.TrimSpace(body)) > 0 {
+		if err := decodeJSON(bytes.NewReader(body), req, h.store.StrictValidation(), &storage.Object{}); err != nil {
+			respondError(w, http.StatusBadRequest, invalidJSONMessage(err), "invalid")
+			return
+		}
 	}
 
-	// Check if bucket exists first
-	if h.store.GetBucket(bucketName) == nil {
-		respondError(w, http.StatusNotFound, fmt.Sprintf("Bucket %s not found", bucketName), "notFound")
-		return
+	if objectName == "" {
+		objectName = req.Name
 	}
-
-	// Check if this is a media download request
-	if r.URL.Query().Get("alt") == "media" {
-		h.downloadObject(w, r, bucketName, objectName)
+	if objectName == "" {
+		respondError(w, http.StatusBadRequest, "Object name is required", "required")
 		return
 	}
 
-	obj := h.store.GetObject(bucketName, objectName)
-	if obj == nil {
-		// Return 404 with GCS-compatible error message format
-		respondError(w, http.StatusNotFound, fmt.Sprintf("No such object: %s/%s", bucketName, objectName), "notFound")
-		return
+	// The content type of the upload is announced up front, like the object size
+	if req.ContentType == "" {
+		req.ContentType = r.Header.Get("X-Upload-Content-Type")
+	}
+	if req.ContentType == "" {
+		req.ContentType = "application/octet-stream"
 	}
 
-	respondJSON(w, http.StatusOK, obj)
-}
-
-// downloadObject handles media downloads for objects.
-func (h *Storage) downloadObject(w http.ResponseWriter, r *http.Request, bucketName, objectName string) {
-	obj := h.store.GetObject(bucketName, objectName)
-	if obj == nil {
-		// Return 404 with GCS-compatible error message format
-		respondError(w, http.StatusNotFound, fmt.Sprintf("No such object: %s/%s", bucketName, objectName), "notFound")
-		return
+	// The object size may be announced up front, so oversized uploads are rejected before any content is sent
+	if maxObjectSize := h.store.MaxObjectSize(); maxObjectSize > 0 {
+		if length, err := strconv.ParseInt(r.Header.Get("X-Upload-Content-Length"), 10, 64); err == nil && length > maxObjectSize {
+			respondObjectTooLarge(w, maxObjectSize)
+			return
+		}
 	}
 
-	content := h.store.GetObjectContent(bucketName, objectName)
-	if content == nil {
-		respondError(w, http.StatusNotFound, fmt.Sprintf("No such object: %s/%s", bucketName, objectName), "notFound")
+	if !applyInsertKmsKeyName(w, r, req) {
 		return
 	}
+	applyUploadHashes(r, req)
+	req.PredefinedACL = r.URL.Query().Get("predefinedAcl")
 
-	w.Header().Set("Content-Type", obj.ContentType)
-	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(content)))
-	w.Header().Set("ETag", obj.Etag)
-	w.WriteHeader(http.StatusOK)
-	w.Write(content)
-}
-
-// DownloadObject handles GET /download/storage/v1/b/{bucket}/o/{object} - Download object content.
-// This is an alternative download endpoint.
-func (h *Storage) DownloadObject(w http.ResponseWriter, r *http.Request) {
-	// Extract bucket and object names from path like /download/storage/v1/b/{bucket}/o/{object}
-	path := strings.TrimPrefix(r.URL.Path, "/download/storage/v1/b/")
-	parts := strings.SplitN(path, "/o/", 2)
-
-	if len(parts) != 2 {
-		respondError(w, http.StatusBadRequest, "Invalid path", "invalid")
+	id, err := h.store.StartObjectUpload(bucketName, objectName, req, pre)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondError(w, http.StatusNotFound, "Bucket not found", "notFound")
+			return
+		}
+		if strings.Contains(err.Error(), "invalid object name") || strings.Contains(err.Error(), "ACL") {
+			respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+			return
+		}
+		if strings.Contains(err.Error(), "precondition failed") {
+			respondError(w, http.StatusPreconditionFailed, err.Error(), "conditionNotMet")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error(), "internalError")
 		return
 	}
 
-	bucketName := parts[0]
-	objectName := parts[1]
-
-	// URL decode the object name
-	decodedName, err := url.QueryUnescape(objectName)
-	if err == nil {
-		objectName = decodedName
+	// Clients follow the upload URL as is, so it points back at the host the client used
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	location := url.URL{
+		Scheme:   scheme,
+		Host:     r.Host,
+		Path:     "/upload/storage/v1/b/" + bucketName + "/o",
+		RawQuery: url.Values{"uploadType": {"resumable"}, "upload_id": {id}}.Encode(),
 	}
 
-	h.downloadObject(w, r, bucketName, objectName)
+	w.Header().Set("Location", location.String())
+	w.Header().Set("X-GUploader-UploadID", id)
+	w.WriteHeader(http.StatusOK)
 }
 
-// PathStyleGetObject handles GET /{bucket}/{object} - Path-style object access.
-// This is used by the Google Cloud Storage client library (e.g., in Go, Terraform, OpenTofu)
-// for downloading object content. The path format is simply /{bucket}/{object}.
-// Reference: https://cloud.google.com/storage/docs/request-endpoints#path-style
-func (h *Storage) PathStyleGetObject(w http.ResponseWriter, r *http.Request) {
-	// Extract bucket and object from path like /{bucket}/{object}
-	path := strings.TrimPrefix(r.URL.Path, "/")
-	parts := strings.SplitN(path, "/", 2)
+// ResumeUpload handles PUT /upload/storage/v1/b/{bucket}/o?upload_id={id} - Upload (part of) the content of a resumable upload.
+// A Content-Range like "bytes 0-99/*" uploads a chunk, "bytes 100-199/200" the final chunk and "bytes */200" queries the
+// upload status. Incomplete uploads are answered with 308 and the persisted range.
+// Reference: https://cloud.google.com/storage/docs/performing-resumable-uploads
+func (h *Storage) ResumeUpload(w http.ResponseWriter, r *http.Request) {
+	bucketName := r.PathValue("bucket")
+	id := r.URL.Query().Get("upload_id")
 
-	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
-		respondError(w, http.StatusBadRequest, "Invalid path: expected /{bucket}/{object}", "invalid")
+	size, err := h.store.GetObjectUploadSize(bucketName, id)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "No such upload: "+id, "notFound")
 		return
 	}
 
-	bucketName := parts[0]
-	objectName := parts[1]
-
-	// URL decode the object name
-	decodedName, err := url.QueryUnescape(objectName)
-	if err == nil {
-		objectName = decodedName
-	}
-
-	// Check if bucket exists first
-	if h.store.GetBucket(bucketName) == nil {
-		respondError(w, http.StatusNotFound, fmt.Sprintf("Bucket %s not found", bucketName), "notFound")
+	contentRange := r.Header.Get("Content-Range")
+	start, end, total, ok := parseUploadContentRange(contentRange)
+	if !ok {
+		respondError(w, http.StatusBadRequest, "Invalid Content-Range: "+contentRange, "invalid")
 		return
 	}
 
-	h.downloadObject(w, r, bucketName, objectName)
-}
-
+	// Upload the chunk unless this is a status query
+	if start >= 0 {
+		if contentRange != "" && start != size {
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid Content-Range: expected upload to resume at byte %d", size), "invalid")
+			return
+		}
+
+		maxObjectSize := h.store.MaxObjectSize()
+		if !limitUploadBody(w, r, maxObjectSize, 0) {
+			respondObjectTooLarge(w, maxObjectSize)
+			return
+		}
+		// A chunk that doesn't match its Content-Range fails while it is read, before it's appended
+		var body io.Reader = r.Body
+		if end >= 0 {
+			body = &chunkReader{r: r.Body, remaining: end - start + 1}
+		}
+		size, err = h.store.AppendObjectUpload(bucketName, id, body)
+		if err != nil {
+			if isTooLarge(err) {
+				respondObjectTooLarge(w, maxObjectSize)
+				return
+			}
+			if errors.Is(err, errChunkSize) {
+				respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid Content-Range: expected a chunk of %d bytes", end-start+1), "invalid")
+				return
+			}
+			respondError(w, http.StatusNotFound, "No such upload: "+id, "notFound")
+			return
+		}
+	}
+
+	// Without a Content-Range, the body was the rest of the content
+	if contentRange == "" {
+		total = size
+	}
+
+	if total < 0 || size < total {
+		if size > 0 {
+			w.Header().Set("Range", fmt.Sprintf("bytes=0-%d", size-1))
+		}
+		w.WriteHeader(http.StatusPermanentRedirect)
+		return
+	}
+
+	obj, err := h.store.CompleteObjectUpload(bucketName, id)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondError(w, http.StatusNotFound, err.Error(), "notFound")
+			return
+		}
+		if strings.Contains(err.Error(), "precondition failed") {
+			respondError(w, http.StatusPreconditionFailed, err.Error(), "conditionNotMet")
+			return
+		}
+		if strings.Contains(err.Error(), "invalid checksum") || strings.Contains(err.Error(), "invalid storage class") ||
+			strings.Contains(err.Error(), "ACL") {
+			respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+			return
+		}
+		if strings.Contains(err.Error(), "quota exceeded") {
+			respondError(w, http.StatusForbidden, err.Error(), "quotaExceeded")
+			return
+		}
+		if isRetained(err) {
+			respondError(w, http.StatusForbidden, err.Error(), "retentionPolicyNotMet")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error(), "internalError")
+		return
+	}
+
+	respondStorageJSON(w, r, http.StatusOK, obj)
+}
+
+// CancelUpload handles DELETE /upload/storage/v1/b/{bucket}/o?upload_id={id} - Cancel a resumable upload.
+// Like the real API, a cancelled upload is answered with 499.
+func (h *Storage) CancelUpload(w http.ResponseWriter, r *http.Request) {
+	bucketName := r.PathValue("bucket")
+	id := r.URL.Query().Get("upload_id")
+
+	if err := h.store.CancelObjectUpload(bucketName, id); err != nil {
+		respondError(w, http.StatusNotFound, "No such upload: "+id, "notFound")
+		return
+	}
+
+	w.WriteHeader(499)
+}
+
+// GetObject handles GET /storage/v1/b/{bucket}/o/{object} - Get object metadata.
+// Reference: https://cloud.google.com/storage/docs/json_api/v1/objects/get
+func (h *Storage) GetObject(w http.ResponseWriter, r *http.Request) {
+	if h.serveObjectACL(w, r) {
+		return
+	}
+
+	bucketName, objectName := r.PathValue("bucket"), r.PathValue("object")
+
+	if bucketName == "" || objectName == "" {
+		respondError(w, http.StatusBadRequest, "Bucket and object names are required", "required")
+		return
+	}
+
+	// Check if bucket exists first
+	if h.store.GetBucket(bucketName) == nil {
+		respondError(w, http.StatusNotFound, fmt.Sprintf("Bucket %s not found", bucketName), "notFound")
+		return
+	}
+
+	// Soft-deleted objects can only be retrieved by generation
+	if r.URL.Query().Get("softDeleted") == "true" {
+		generation, err := strconv.ParseInt(r.URL.Query().Get("generation"), 10, 64)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "A valid generation is required when softDeleted is true", "invalid")
+			return
+		}
+
+		obj := h.store.GetSoftDeletedObject(bucketName, objectName, generation)
+		if obj == nil {
+			respondError(w, http.StatusNotFound, fmt.Sprintf("No such object: %s/%s", bucketName, objectName), "notFound")
+			return
+		}
+
+		respondStorageJSON(w, r, http.StatusOK, obj)
+		return
+	}
+
+	// Check if this is a media download request
+	if r.URL.Query().Get("alt") == "media" {
+		h.downloadObject(w, r, bucketName, objectName)
+		return
+	}
+
+	obj := h.store.GetObject(bucketName, objectName)
+	if obj == nil {
+		// Return 404 with GCS-compatible error message format
+		respondError(w, http.StatusNotFound, fmt.Sprintf("No such object: %s/%s", bucketName, objectName), "notFound")
+		return
+	}
+
+	if !checkReadPreconditions(w, r, obj) {
+		return
+	}
+
+	if notModified(w, r, obj.Etag, obj.Updated) {
+		return
+	}
+
+	respondStorageJSON(w, r, http.StatusOK, obj)
+}
+
+// downloadObject handles media downloads for objects.
+func (h *Storage) downloadObject(w http.ResponseWriter, r *http.Request, bucketName, objectName string) {
+	obj, content, err := h.store.OpenObjectContent(bucketName, objectName)
+	if err != nil && strings.Contains(err.Error(), "evicted") {
+		respondError(w, http.StatusGone, err.Error(), "gone")
+		return
+	}
+	if err != nil {
+		// Return 404 with GCS-compatible error message format
+		respondError(w, http.StatusNotFound, fmt.Sprintf("No such object: %s/%s", bucketName, objectName), "notFound")
+		return
+	}
+	defer content.Close()
+
+	if !checkReadPreconditions(w, r, obj) {
+		return
+	}
+
+	// Like GCS, revalidations are answered with the headers caches need to refresh the stored response
+	setXGoogHeaders(w, obj)
+	setObjectContentHeaders(w, obj)
+	if notModified(w, r, obj.Etag, obj.Updated) {
+		return
+	}
+
+	// Stream the content instead of loading it into memory. ServeContent answers Range requests
+	// with 206 Partial Content or 416 Range Not Satisfiable, like the XML API
+	http.ServeContent(w, r, "", obj.Updated, content)
+}
+
+// setObjectContentHeaders sets the standard headers describing an object's content, like
+// Content-Type and the Cache-Control, Content-Disposition and Content-Language of the object.
+// Expires is derived from the max-age of the Cache-Control and the Date header, so it follows the
+// virtual clock; without a max-age the content expires right away.
+func setObjectContentHeaders(w http.ResponseWriter, obj *storage.Object) {
+	w.Header().Set("Content-Type", obj.ContentType)
+	if obj.CacheControl != "" {
+		w.Header().Set("Cache-Control", obj.CacheControl)
+	}
+	date, err := http.ParseTime(w.Header().Get("Date"))
+	if err != nil {
+		date = time.Now()
+	}
+	w.Header().Set("Expires", date.Add(maxAge(obj.CacheControl)).UTC().Format(http.TimeFormat))
+	if obj.ContentDisposition != "" {
+		w.Header().Set("Content-Disposition", obj.ContentDisposition)
+	}
+	if obj.ContentLanguage != "" {
+		w.Header().Set("Content-Language", obj.ContentLanguage)
+	}
+}
+
+// maxAge returns the max-age directive of a Cache-Control header, or 0 if there is none
+// or the content must not be cached.
+func maxAge(cacheControl string) time.Duration {
+	var age time.Duration
+	for _, directive := range strings.Split(cacheControl, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(name) {
+		case "no-cache", "no-store":
+			return 0
+		case "max-age":
+			seconds, err := strconv.Atoi(value)
+			if err == nil && seconds > 0 {
+				age = time.Duration(seconds) * time.Second
+			}
+		}
+	}
+	return age
+}
+
+// notModified sets the ETag and Last-Modified headers and evaluates the
+// If-None-Match and If-Modified-Since request headers against them.
+// If the client's cached copy is still current, it writes a 304 Not Modified
+// response and returns true.
+// Reference: https://www.rfc-editor.org/rfc/rfc9110#section-13.1
+func notModified(w http.ResponseWriter, r *http.Request, etag string, lastModified time.Time) bool {
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+
+	// If-None-Match takes precedence over If-Modified-Since
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		if !etagMatches(ifNoneMatch, etag) {
+			return false
+		}
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+
+	if ifModifiedSince := r.Header.Get("If-Modified-Since"); ifModifiedSince != "" {
+		since, err := http.ParseTime(ifModifiedSince)
+		if err != nil {
+			return false
+		}
+		// HTTP dates have second precision
+		if lastModified.Truncate(time.Second).After(since) {
+			return false
+		}
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+
+	return false
+}
+
+// checkReadPreconditions evaluates the generation preconditions of a read request against obj.
+// Like the real API, a failed ifGenerationNotMatch or ifMetagenerationNotMatch is answered with
+// 304 Not Modified and any other failed precondition with 412. Returns false if a response was written.
+func checkReadPreconditions(w http.ResponseWriter, r *http.Request, obj *storage.Object) bool {
+	pre, err := parsePreconditions(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+		return false
+	}
+
+	if err := pre.Check(obj); err != nil {
+		if strings.Contains(err.Error(), "NotMatch") {
+			w.WriteHeader(http.StatusNotModified)
+			return false
+		}
+		respondError(w, http.StatusPreconditionFailed, err.Error(), "conditionNotMet")
+		return false
+	}
+	return true
+}
+
+// ifMatchEtag returns the etag an update must match: the etag of the If-Match header or, without one,
+// the etag of the request body. "*" matches any existing resource, like a missing header.
+// Returns an error if the header and the body ask for different etags, since both can't match.
+func ifMatchEtag(r *http.Request, bodyEtag string) (string, error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return bodyEtag, nil
+	}
+
+	etag := strings.Trim(strings.TrimPrefix(header, "W/"), `"`)
+	if bodyEtag != "" && bodyEtag != etag {
+		return "", fmt.Errorf("precondition failed: If-Match etag %s doesn't match the etag %s of the request", etag, bodyEtag)
+	}
+	return etag, nil
+}
+
+// etagMatches checks if an If-None-Match header value matches the given etag.
+// The header may contain a list of (optionally weak and quoted) etags or "*".
+func etagMatches(headerValue, etag string) bool {
+	for _, candidate := range strings.Split(headerValue, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		candidate = strings.TrimPrefix(candidate, "W/")
+		candidate = strings.Trim(candidate, `"`)
+		if candidate == strings.Trim(etag, `"`) {
+			return true
+		}
+	}
+	return false
+}
+
+// DownloadObject handles GET /download/storage/v1/b/{bucket}/o/{object} - Download object content.
+// This is an alternative download endpoint.
+func (h *Storage) DownloadObject(w http.ResponseWriter, r *http.Request) {
+	h.downloadObject(w, r, r.PathValue("bucket"), r.PathValue("object"))
+}
+
 // UpdateObject handles PUT /storage/v1/b/{bucket}/o/{object} - Update object metadata.
 // Reference: https://cloud.google.com/storage/docs/json_api/v1/objects/update
 func (h *Storage) UpdateObject(w http.ResponseWriter, r *http.Request) {
-	bucketName, objectName := extractBucketAndObjectNames(r.URL.Path)
+	if h.serveObjectACL(w, r) {
+		return
+	}
+
+	bucketName, objectName := r.PathValue("bucket"), r.PathValue("object")
+
+	if bucketName == "" || objectName == "" {
+		respondError(w, http.StatusBadRequest, "Bucket and object names are required", "required")
+		return
+	}
+
+	var req storage.ObjectUpdateRequest
+	if err := decodeJSON(r.Body, &req, h.store.StrictValidation(), &storage.Object{}); err != nil {
+		respondError(w, http.StatusBadRequest, invalidJSONMessage(err), "invalid")
+		return
+	}
+
+	pre, err := parsePreconditions(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+		return
+	}
+
+	obj, err := h.store.UpdateObjectWithPreconditions(bucketName, objectName, &req, pre)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondError(w, http.StatusNotFound, err.Error(), "notFound")
+			return
+		}
+		if strings.Contains(err.Error(), "precondition failed") {
+			respondError(w, http.StatusPreconditionFailed, err.Error(), "conditionNotMet")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error(), "internalError")
+		return
+	}
+
+	respondStorageJSON(w, r, http.StatusOK, obj)
+}
+
+// PatchObject handles PATCH /storage/v1/b/{bucket}/o/{object} - Patch object metadata.
+// Custom metadata is merged and fields set to null are cleared.
+// Reference: https://cloud.google.com/storage/docs/json_api/v1/objects/patch
+func (h *Storage) PatchObject(w http.ResponseWriter, r *http.Request) {
+	if h.serveObjectACL(w, r) {
+		return
+	}
+
+	bucketName, objectName := r.PathValue("bucket"), r.PathValue("object")
+
+	if bucketName == "" || objectName == "" {
+		respondError(w, http.StatusBadRequest, "Bucket and object names are required", "required")
+		return
+	}
+
+	var req storage.ObjectPatchRequest
+	nullFields, err := decodePatchRequest(r, &req, h.store.StrictValidation(), &storage.Object{})
+	if err != nil {
+		respondError(w, http.StatusBadRequest, invalidJSONMessage(err), "invalid")
+		return
+	}
+	req.NullFields = nullFields
+
+	pre, err := parsePreconditions(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+		return
+	}
+
+	obj, err := h.store.PatchObject(bucketName, objectName, &req, pre)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondError(w, http.StatusNotFound, err.Error(), "notFound")
+			return
+		}
+		if strings.Contains(err.Error(), "precondition failed") {
+			respondError(w, http.StatusPreconditionFailed, err.Error(), "conditionNotMet")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error(), "internalError")
+		return
+	}
+
+	respondStorageJSON(w, r, http.StatusOK, obj)
+}
+
+// ObjectAction handles POST /storage/v1/b/{bucket}/o/{object}/{action} - Object actions.
+// The object wildcard also matches the action, since the object name itself may contain slashes.
+func (h *Storage) ObjectAction(w http.ResponseWriter, r *http.Request) {
+	if h.serveObjectACL(w, r) {
+		return
+	}
+
+	object := r.PathValue("object")
+	if strings.HasSuffix(object, "/restore") {
+		h.RestoreObject(w, r)
+		return
+	}
+	if strings.Contains(object, "/rewriteTo/b/") {
+		h.RewriteObject(w, r)
+		return
+	}
+
+	respondError(w, http.StatusNotFound, "Unsupported object action", "notFound")
+}
+
+// RestoreObject handles POST /storage/v1/b/{bucket}/o/{object}/restore - Restore a soft-deleted object.
+// Reference: https://cloud.google.com/storage/docs/json_api/v1/objects/restore
+func (h *Storage) RestoreObject(w http.ResponseWriter, r *http.Request) {
+	bucketName := r.PathValue("bucket")
+	objectName := strings.TrimSuffix(r.PathValue("object"), "/restore")
 
 	if bucketName == "" || objectName == "" {
 		respondError(w, http.StatusBadRequest, "Bucket and object names are required", "required")
 		return
 	}
 
-	// URL decode the object name
-	decodedName, err := url.QueryUnescape(objectName)
-	if err == nil {
-		objectName = decodedName
+	generation, err := strconv.ParseInt(r.URL.Query().Get("generation"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "A valid generation is required", "required")
+		return
 	}
 
-	var reqBody struct {
-		Metadata map[string]string `json:"metadata"`
+	obj, err := h.store.RestoreObject(bucketName, objectName, generation)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondError(w, http.StatusNotFound, err.Error(), "notFound")
+			return
+		}
+		if strings.Contains(err.Error(), "already exists") {
+			respondError(w, http.StatusPreconditionFailed, err.Error(), "conditionNotMet")
+			return
+		}
+		if strings.Contains(err.Error(), "quota exceeded") {
+			respondError(w, http.StatusForbidden, err.Error(), "quotaExceeded")
+			return
+		}
+		if isRetained(err) {
+			respondError(w, http.StatusForbidden, err.Error(), "retentionPolicyNotMet")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error(), "internalError")
+		return
 	}
-	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
-		respondError(w, http.StatusBadRequest, "Invalid JSON body", "invalid")
+
+	respondStorageJSON(w, r, http.StatusOK, obj)
+}
+
+// RewriteObject handles POST /storage/v1/b/{bucket}/o/{object}/rewriteTo/b/{destinationBucket}/o/{destinationObject}
+// - Copy an object, e.g. onto itself to change its storage class. The request body is an optional object resource
+// whose fields replace those of the source object. The mock always completes the rewrite in a single call.
+// Reference: https://cloud.google.com/storage/docs/json_api/v1/objects/rewrite
+func (h *Storage) RewriteObject(w http.ResponseWriter, r *http.Request) {
+	srcBucket := r.PathValue("bucket")
+	srcObject, destination, _ := strings.Cut(r.PathValue("object"), "/rewriteTo/b/")
+	dstBucket, dstObject, _ := strings.Cut(destination, "/o/")
+
+	if srcBucket == "" || srcObject == "" || dstBucket == "" || dstObject == "" {
+		respondError(w, http.StatusBadRequest, "Source and destination bucket and object names are required", "required")
+		return
+	}
+
+	// The object resource is optional
+	var req storage.ObjectInsertRequest
+	if r.ContentLength > 0 {
+		if err := decodeJSON(r.Body, &req, h.store.StrictValidation(), &storage.Object{}); err != nil {
+			respondError(w, http.StatusBadRequest, invalidJSONMessage(err), "invalid")
+			return
+		}
+	}
+
+	// The destination gets the predefined ACL or the ACL of the object resource, not the ACL of the source
+	req.PredefinedACL = r.URL.Query().Get("destinationPredefinedAcl")
+
+	pre, err := parsePreconditions(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid")
 		return
 	}
 
-	obj, err := h.store.UpdateObject(bucketName, objectName, reqBody.Metadata)
+	obj, err := h.store.RewriteObject(srcBucket, srcObject, dstBucket, dstObject, &req, pre)
 	if err != nil {
+		if strings.Contains(err.Error(), "evicted") {
+			respondError(w, http.StatusGone, err.Error(), "gone")
+			return
+		}
 		if strings.Contains(err.Error(), "not found") {
 			respondError(w, http.StatusNotFound, err.Error(), "notFound")
 			return
 		}
+		if strings.Contains(err.Error(), "precondition failed") {
+			respondError(w, http.StatusPreconditionFailed, err.Error(), "conditionNotMet")
+			return
+		}
+		if strings.Contains(err.Error(), "invalid") {
+			respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+			return
+		}
+		if strings.Contains(err.Error(), "quota exceeded") {
+			respondError(w, http.StatusForbidden, err.Error(), "quotaExceeded")
+			return
+		}
+		if isRetained(err) {
+			respondError(w, http.StatusForbidden, err.Error(), "retentionPolicyNotMet")
+			return
+		}
 		respondError(w, http.StatusInternalServerError, err.Error(), "internalError")
 		return
 	}
 
-	respondJSON(w, http.StatusOK, obj)
+	respondStorageJSON(w, r, http.StatusOK, &storage.RewriteResponse{
+		Kind:                "storage#rewriteResponse",
+		TotalBytesRewritten: obj.Size,
+		ObjectSize:          obj.Size,
+		Done:                true,
+		Resource:            obj,
+	})
 }
 
 // DeleteObject handles DELETE /storage/v1/b/{bucket}/o/{object} - Delete an object.
 // Reference: https://cloud.google.com/storage/docs/json_api/v1/objects/delete
 func (h *Storage) DeleteObject(w http.ResponseWriter, r *http.Request) {
-	bucketName, objectName := extractBucketAndObjectNames(r.URL.Path)
+	if h.serveObjectACL(w, r) {
+		return
+	}
+
+	bucketName, objectName := r.PathValue("bucket"), r.PathValue("object")
 
 	if bucketName == "" || objectName == "" {
 		respondError(w, http.StatusBadRequest, "Bucket and object names are required", "required")
 		return
 	}
 
-	// URL decode the object name
-	decodedName, err := url.QueryUnescape(objectName)
-	if err == nil {
-		objectName = decodedName
+	pre, err := parsePreconditions(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+		return
+	}
+
+	err = h.store.DeleteObjectWithPreconditions(bucketName, objectName, pre)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondError(w, http.StatusNotFound, err.Error(), "notFound")
+			return
+		}
+		if strings.Contains(err.Error(), "precondition failed") {
+			respondError(w, http.StatusPreconditionFailed, err.Error(), "conditionNotMet")
+			return
+		}
+		if isRetained(err) {
+			respondError(w, http.StatusForbidden, err.Error(), "retentionPolicyNotMet")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error(), "internalError")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListNotifications handles GET /storage/v1/b/{bucket}/notificationConfigs - List notification configurations.
+// Reference: https://cloud.google.com/storage/docs/json_api/v1/notifications/list
+func (h *Storage) ListNotifications(w http.ResponseWriter, r *http.Request) {
+	bucketName := r.PathValue("bucket")
+
+	notifications, err := h.store.ListNotifications(bucketName)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "Bucket not found", "notFound")
+		return
+	}
+
+	response := &storage.NotificationList{
+		Kind:  "storage#notifications",
+		Items: notifications,
+	}
+
+	respondStorageJSON(w, r, http.StatusOK, response)
+}
+
+// CreateNotification handles POST /storage/v1/b/{bucket}/notificationConfigs - Create a notification configuration.
+// Reference: https://cloud.google.com/storage/docs/json_api/v1/notifications/insert
+func (h *Storage) CreateNotification(w http.ResponseWriter, r *http.Request) {
+	bucketName := r.PathValue("bucket")
+
+	var req storage.NotificationInsertRequest
+	if err := decodeJSON(r.Body, &req, h.store.StrictValidation(), &storage.Notification{}); err != nil {
+		respondError(w, http.StatusBadRequest, invalidJSONMessage(err), "invalid")
+		return
+	}
+
+	if req.Topic == "" {
+		respondError(w, http.StatusBadRequest, "Topic is required", "required")
+		return
+	}
+
+	if req.PayloadFormat != "" && req.PayloadFormat != storage.PayloadFormatJSONAPIV1 && req.PayloadFormat != storage.PayloadFormatNone {
+		respondError(w, http.StatusBadRequest, "Invalid payload_format: "+req.PayloadFormat, "invalid")
+		return
 	}
 
-	err = h.store.DeleteObject(bucketName, objectName)
+	for _, eventType := range req.EventTypes {
+		switch eventType {
+		case storage.EventObjectFinalize, storage.EventObjectMetadataUpdate, storage.EventObjectDelete, storage.EventObjectArchive:
+		default:
+			respondError(w, http.StatusBadRequest, "Invalid event type: "+eventType, "invalid")
+			return
+		}
+	}
+
+	notification, err := h.store.CreateNotification(bucketName, &req)
 	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondError(w, http.StatusNotFound, "Bucket not found", "notFound")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error(), "internalError")
+		return
+	}
+
+	respondStorageJSON(w, r, http.StatusOK, notification)
+}
+
+// GetNotification handles GET /storage/v1/b/{bucket}/notificationConfigs/{notification} - Get a notification configuration.
+// Reference: https://cloud.google.com/storage/docs/json_api/v1/notifications/get
+func (h *Storage) GetNotification(w http.ResponseWriter, r *http.Request) {
+	bucketName, id := r.PathValue("bucket"), r.PathValue("notification")
+
+	notification := h.store.GetNotification(bucketName, id)
+	if notification == nil {
+		respondError(w, http.StatusNotFound, "Notification not found", "notFound")
+		return
+	}
+
+	respondStorageJSON(w, r, http.StatusOK, notification)
+}
+
+// DeleteNotification handles DELETE /storage/v1/b/{bucket}/notificationConfigs/{notification} - Delete a notification configuration.
+// Reference: https://cloud.google.com/storage/docs/json_api/v1/notifications/delete
+func (h *Storage) DeleteNotification(w http.ResponseWriter, r *http.Request) {
+	bucketName, id := r.PathValue("bucket"), r.PathValue("notification")
+
+	if err := h.store.DeleteNotification(bucketName, id); err != nil {
 		if strings.Contains(err.Error(), "not found") {
 			respondError(w, http.StatusNotFound, err.Error(), "notFound")
 			return
@@ -436,84 +1410,304 @@ func (h *Storage) DeleteObject(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// respondError writes a JSON error response matching the GCS API format.
+// respondError writes a JSON error response in the v1 format of Cloud Storage and Compute Engine,
+// which has error reasons like notFound.
 func respondError(w http.ResponseWriter, statusCode int, message, reason string) {
-	errResp := storage.APIError{
-		Error: storage.ErrorDetails{
-			Code:    statusCode,
-			Message: message,
-			Errors: []storage.ErrorReason{
-				{
-					Domain:  "global",
-					Reason:  reason,
-					Message: message,
-				},
-			},
-		},
+	gcperror.New(statusCode, message, reason).Write(w)
+}
+
+// respondStorageJSON writes a JSON response of the Cloud Storage JSON API, which returns partial responses
+// for the fields query parameter and honors prettyPrint.
+// Reference: https://cloud.google.com/storage/docs/json_api#partial-response
+func respondStorageJSON(w http.ResponseWriter, r *http.Request, statusCode int, data interface{}) {
+	if err := writePartialJSON(w, r, statusCode, data); err != nil {
+		gcperror.New(http.StatusBadRequest, err.Error(), "invalidParameter").
+			WithLocation("parameter", "fields").
+			Write(w)
+	}
+}
+
+// isValidKmsKeyName reports whether name is a Cloud KMS key name like
+// projects/{project}/locations/{location}/keyRings/{keyRing}/cryptoKeys/{cryptoKey},
+// optionally followed by /cryptoKeyVersions/{version}.
+func isValidKmsKeyName(name string) bool {
+	parts := strings.Split(name, "/")
+	if len(parts) != 8 && len(parts) != 10 {
+		return false
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(errResp)
+	collections := []string{"projects", "locations", "keyRings", "cryptoKeys", "cryptoKeyVersions"}
+	for i := 0; i < len(parts); i += 2 {
+		if parts[i] != collections[i/2] || parts[i+1] == "" {
+			return false
+		}
+	}
+	return true
 }
 
-// extractBucketName extracts the bucket name from a path like /storage/v1/b/{bucket}.
-func extractBucketName(path, prefix string) string {
-	path = strings.TrimPrefix(path, prefix)
-	// Remove any trailing path segments
-	if idx := strings.Index(path, "/"); idx >= 0 {
-		path = path[:idx]
+// validateEncryption checks the default Cloud KMS key of a bucket encryption configuration.
+func validateEncryption(encryption *storage.Encryption) error {
+	if encryption == nil || encryption.DefaultKmsKeyName == "" || isValidKmsKeyName(encryption.DefaultKmsKeyName) {
+		return nil
 	}
-	return path
+	return fmt.Errorf("invalid Cloud KMS key name: %s", encryption.DefaultKmsKeyName)
 }
 
-// extractBucketFromObjectsPath extracts the bucket name from a path like /storage/v1/b/{bucket}/o.
-func extractBucketFromObjectsPath(path string) string {
-	path = strings.TrimPrefix(path, "/storage/v1/b/")
-	parts := strings.SplitN(path, "/", 2)
-	if len(parts) > 0 {
-		return parts[0]
+// validateAutoclass checks the terminal storage class of a bucket Autoclass configuration.
+func validateAutoclass(autoclass *storage.Autoclass) error {
+	if autoclass == nil || !autoclass.Enabled {
+		return nil
 	}
-	return ""
+	switch autoclass.TerminalStorageClass {
+	case "", storage.StorageClassNearline, storage.StorageClassArchive:
+		return nil
+	}
+	return fmt.Errorf("invalid Autoclass terminal storage class %q: must be NEARLINE or ARCHIVE", autoclass.TerminalStorageClass)
 }
 
-// extractBucketFromUploadPath extracts the bucket name from a path like /upload/storage/v1/b/{bucket}/o.
-func extractBucketFromUploadPath(path string) string {
-	path = strings.TrimPrefix(path, "/upload/storage/v1/b/")
-	parts := strings.SplitN(path, "/", 2)
-	if len(parts) > 0 {
-		return parts[0]
+// applyInsertKmsKeyName applies the kmsKeyName query parameter to an upload and validates the key,
+// writing an error response if it is invalid.
+// The query parameter takes precedence over the key in the object resource.
+func applyInsertKmsKeyName(w http.ResponseWriter, r *http.Request, req *storage.ObjectInsertRequest) bool {
+	if kmsKeyName := r.URL.Query().Get("kmsKeyName"); kmsKeyName != "" {
+		req.KmsKeyName = kmsKeyName
+	}
+	if req.KmsKeyName != "" && !isValidKmsKeyName(req.KmsKeyName) {
+		respondError(w, http.StatusBadRequest, "Invalid Cloud KMS key name: "+req.KmsKeyName, "invalid")
+		return false
 	}
-	return ""
+	return true
 }
 
-// extractBucketAndObjectNames extracts bucket and object names from a path like /storage/v1/b/{bucket}/o/{object}.
-func extractBucketAndObjectNames(path string) (string, string) {
-	path = strings.TrimPrefix(path, "/storage/v1/b/")
-	parts := strings.SplitN(path, "/o/", 2)
-	if len(parts) != 2 {
-		return "", ""
+// applyUploadHeaders applies the object attributes sent as request headers to an upload:
+// x-goog-meta-* custom metadata, Cache-Control, Content-Disposition, Content-Language and
+// x-goog-custom-time. Attributes already set in the request take precedence.
+// Reference: https://cloud.google.com/storage/docs/xml-api/reference-headers
+func applyUploadHeaders(r *http.Request, req *storage.ObjectInsertRequest) error {
+	for key, values := range r.Header {
+		metaKey, found := strings.CutPrefix(strings.ToLower(key), "x-goog-meta-")
+		if !found || len(values) == 0 {
+			continue
+		}
+		if req.Metadata == nil {
+			req.Metadata = make(map[string]string)
+		}
+		if _, exists := req.Metadata[metaKey]; !exists {
+			req.Metadata[metaKey] = values[0]
+		}
 	}
-	return parts[0], parts[1]
+
+	if req.CacheControl == "" {
+		req.CacheControl = r.Header.Get("Cache-Control")
+	}
+	if req.ContentDisposition == "" {
+		req.ContentDisposition = r.Header.Get("Content-Disposition")
+	}
+	if req.ContentLanguage == "" {
+		req.ContentLanguage = r.Header.Get("Content-Language")
+	}
+	if customTime := r.Header.Get("X-Goog-Custom-Time"); customTime != "" && req.CustomTime == nil {
+		t, err := time.Parse(time.RFC3339, customTime)
+		if err != nil {
+			return fmt.Errorf("invalid x-goog-custom-time %q: expected an RFC 3339 timestamp", customTime)
+		}
+		req.CustomTime = &t
+	}
+	return nil
+}
+
+// parseUploadHashes reads the checksums a client sent for an upload from the X-Goog-Hash headers,
+// e.g. "crc32c=n03x6A==,md5=Ojk9c3dhfxgoKVVHYwFbHQ==", or from the Content-MD5 header.
+// Reference: https://cloud.google.com/storage/docs/xml-api/reference-headers#xgooghash
+func parseUploadHashes(r *http.Request) (md5Hash, crc32c string) {
+	md5Hash = r.Header.Get("Content-MD5")
+	for _, header := range r.Header.Values("X-Goog-Hash") {
+		for _, hash := range strings.Split(header, ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(hash), "=")
+			switch strings.ToLower(name) {
+			case "md5":
+				md5Hash = value
+			case "crc32c":
+				crc32c = value
+			}
+		}
+	}
+	return md5Hash, crc32c
+}
+
+// multipartUploadOverhead is the room left for the metadata part and the boundaries of multipart uploads
+// when their request body is limited to the maximum object size.
+const multipartUploadOverhead = 1 << 20
+
+// limitUploadBody limits the request body of an upload to maxObjectSize plus overhead bytes, so oversized
+// uploads are cut off while they are streamed. Returns false if the Content-Length already announces a larger
+// body; the caller then responds with 413. A maxObjectSize of 0 means no limit.
+func limitUploadBody(w http.ResponseWriter, r *http.Request, maxObjectSize, overhead int64) bool {
+	if maxObjectSize <= 0 {
+		return true
+	}
+	if r.ContentLength > maxObjectSize+overhead {
+		return false
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxObjectSize+overhead)
+	return true
+}
+
+// errChunkSize is returned by a chunkReader whose chunk is shorter or longer than announced.
+var errChunkSize = errors.New("chunk size doesn't match the Content-Range")
+
+// chunkReader reads a chunk of a resumable upload, failing with errChunkSize unless it has
+// exactly the remaining number of bytes.
+type chunkReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+// Read reads from the underlying reader, failing once the chunk is too long or ends too early.
+func (c *chunkReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.remaining -= int64(n)
+	if c.remaining < 0 || (err == io.EOF && c.remaining > 0) {
+		return n, errChunkSize
+	}
+	return n, err
+}
+
+// isTooLarge reports whether an error is caused by content beyond the maximum object size,
+// either from the store or from the request body limit.
+func isTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr) || strings.Contains(err.Error(), "object too large")
+}
+
+// isRetained reports whether an error is caused by a hold or the retention policy of a bucket
+// keeping an object from being deleted or overwritten.
+func isRetained(err error) bool {
+	return strings.Contains(err.Error(), "retention policy") || strings.Contains(err.Error(), "hold and can't be")
+}
+
+// respondObjectTooLarge writes the 413 entityTooLarge error for uploads beyond the maximum object size.
+func respondObjectTooLarge(w http.ResponseWriter, maxObjectSize int64) {
+	respondError(w, http.StatusRequestEntityTooLarge,
+		fmt.Sprintf("The object exceeds the maximum allowed size of %d bytes.", maxObjectSize), "entityTooLarge")
+}
+
+// applyUploadHashes applies the checksums from the upload headers to an upload.
+// Checksums in the object resource take precedence.
+func applyUploadHashes(r *http.Request, req *storage.ObjectInsertRequest) {
+	md5Hash, crc32c := parseUploadHashes(r)
+	if req.Md5Hash == "" {
+		req.Md5Hash = md5Hash
+	}
+	if req.Crc32c == "" {
+		req.Crc32c = crc32c
+	}
+}
+
+// parsePreconditions reads the generation preconditions of an object request from the
+// query parameters, or from the x-goog-if-*-match headers of the XML API.
+// Reference: https://cloud.google.com/storage/docs/request-preconditions
+func parsePreconditions(r *http.Request) (store.Preconditions, error) {
+	var pre store.Preconditions
+	var err error
+
+	if pre.IfGenerationMatch, err = parsePrecondition(r, "ifGenerationMatch", "X-Goog-If-Generation-Match"); err != nil {
+		return store.Preconditions{}, err
+	}
+	if pre.IfGenerationNotMatch, err = parsePrecondition(r, "ifGenerationNotMatch", ""); err != nil {
+		return store.Preconditions{}, err
+	}
+	if pre.IfMetagenerationMatch, err = parsePrecondition(r, "ifMetagenerationMatch", "X-Goog-If-Metageneration-Match"); err != nil {
+		return store.Preconditions{}, err
+	}
+	if pre.IfMetagenerationNotMatch, err = parsePrecondition(r, "ifMetagenerationNotMatch", ""); err != nil {
+		return store.Preconditions{}, err
+	}
+
+	return pre, nil
+}
+
+// parsePrecondition reads a single precondition from the query parameter or, if empty, the header.
+// Returns nil if neither is set.
+func parsePrecondition(r *http.Request, param, header string) (*int64, error) {
+	value := r.URL.Query().Get(param)
+	if value == "" && header != "" {
+		value = r.Header.Get(header)
+	}
+	if value == "" {
+		return nil, nil
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %s", param, value)
+	}
+	return &n, nil
+}
+
+// parseUploadContentRange parses the Content-Range header of a resumable upload request.
+// start and end are -1 for a status query ("bytes */total"), total is -1 if it is not known yet ("bytes 0-99/*").
+// A missing header is reported as a chunk of unknown range and size.
+func parseUploadContentRange(header string) (start, end, total int64, ok bool) {
+	if header == "" {
+		return 0, -1, -1, true
+	}
+
+	rangeSpec, found := strings.CutPrefix(header, "bytes ")
+	if !found {
+		return 0, 0, 0, false
+	}
+	byteRange, totalSpec, found := strings.Cut(rangeSpec, "/")
+	if !found {
+		return 0, 0, 0, false
+	}
+
+	total = -1
+	if totalSpec != "*" {
+		var err error
+		if total, err = strconv.ParseInt(totalSpec, 10, 64); err != nil || total < 0 {
+			return 0, 0, 0, false
+		}
+	}
+
+	if byteRange == "*" {
+		return -1, -1, total, true
+	}
+
+	first, last, found := strings.Cut(byteRange, "-")
+	if !found {
+		return 0, 0, 0, false
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return 0, 0, 0, false
+	}
+	end, err = strconv.ParseInt(last, 10, 64)
+	if err != nil || end < start {
+		return 0, 0, 0, false
+	}
+	return start, end, total, true
 }
 
 // parseMultipartRelatedUpload parses a multipart/related upload request.
 // This format is used by Terraform and other GCS clients.
 // The first part contains JSON metadata, the second part contains the actual content.
-func parseMultipartRelatedUpload(r *http.Request) (content []byte, contentType string, metadata map[string]string, err error) {
+// The returned content reader streams the second part and is only valid while the request body is open.
+// With strict validation, unknown fields in the metadata are rejected like in decodeJSON.
+func parseMultipartRelatedUpload(r *http.Request, strict bool) (io.Reader, *storage.ObjectInsertRequest, error) {
 	// Parse the Content-Type header to get the boundary
 	mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
 	if err != nil {
-		return nil, "", nil, fmt.Errorf("failed to parse Content-Type: %w", err)
+		return nil, nil, fmt.Errorf("failed to parse Content-Type: %w", err)
 	}
 
 	if !strings.HasPrefix(mediaType, "multipart/") {
-		return nil, "", nil, fmt.Errorf("expected multipart content type, got %s", mediaType)
+		return nil, nil, fmt.Errorf("expected multipart content type, got %s", mediaType)
 	}
 
 	boundary := params["boundary"]
 	if boundary == "" {
-		return nil, "", nil, fmt.Errorf("no boundary found in Content-Type")
+		return nil, nil, fmt.Errorf("no boundary found in Content-Type")
 	}
 
 	// Create multipart reader
@@ -522,47 +1716,36 @@ func parseMultipartRelatedUpload(r *http.Request) (content []byte, contentType s
 	// First part should be JSON metadata
 	metadataPart, err := mr.NextPart()
 	if err != nil {
-		return nil, "", nil, fmt.Errorf("failed to read metadata part: %w", err)
+		return nil, nil, fmt.Errorf("failed to read metadata part: %w", err)
 	}
 
 	// Parse JSON metadata
-	var objMetadata struct {
-		ContentType string            `json:"contentType"`
-		Metadata    map[string]string `json:"metadata"`
-	}
+	var req storage.ObjectInsertRequest
 
 	metadataBytes, err := io.ReadAll(metadataPart)
 	if err != nil {
-		return nil, "", nil, fmt.Errorf("failed to read metadata: %w", err)
+		return nil, nil, fmt.Errorf("failed to read metadata: %w", err)
 	}
 
-	if err := json.Unmarshal(metadataBytes, &objMetadata); err != nil {
-		return nil, "", nil, fmt.Errorf("failed to parse metadata JSON: %w", err)
+	if err := decodeJSON(bytes.NewReader(metadataBytes), &req, strict, &storage.Object{}); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse metadata JSON: %w", err)
 	}
 
-	contentType = objMetadata.ContentType
-	metadata = objMetadata.Metadata
-
 	// Second part should be the actual content
 	contentPart, err := mr.NextPart()
 	if err != nil {
-		return nil, "", nil, fmt.Errorf("failed to read content part: %w", err)
+		return nil, nil, fmt.Errorf("failed to read content part: %w", err)
 	}
 
 	// If content type wasn't in metadata, try to get it from the part header
-	if contentType == "" {
-		contentType = contentPart.Header.Get("Content-Type")
+	if req.ContentType == "" {
+		req.ContentType = contentPart.Header.Get("Content-Type")
 	}
 
 	// Default content type
-	if contentType == "" {
-		contentType = "application/octet-stream"
-	}
-
-	content, err = io.ReadAll(contentPart)
-	if err != nil {
-		return nil, "", nil, fmt.Errorf("failed to read content: %w", err)
+	if req.ContentType == "" {
+		req.ContentType = "application/octet-stream"
 	}
 
-	return content, contentType, metadata, nil
+	return contentPart, &req, nil
 }
@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+)
+
+// cutObjectACLPath splits the escaped object part of an object ACL request path, like "dir%2Ffile.txt/acl" or
+// "dir%2Ffile.txt/acl/allUsers", into the object name and the entity. The object wildcard of the routes also
+// matches the ACL part, since the object name itself may contain slashes. Only paths ending in an unescaped
+// /acl segment, optionally followed by something that looks like an entity, are ACL requests, so objects
+// like "dir/acl/file.txt" and "logs/acl" (sent as logs%2Facl) are still served.
+func cutObjectACLPath(escapedObject string) (objectName, entity string, ok bool) {
+	name, found := strings.CutSuffix(escapedObject, "/acl")
+	if !found {
+		idx := strings.LastIndex(escapedObject, "/acl/")
+		if idx < 0 {
+			return "", "", false
+		}
+		var err error
+		if entity, err = url.PathUnescape(escapedObject[idx+len("/acl/"):]); err != nil {
+			return "", "", false
+		}
+		kind, _, _ := strings.Cut(entity, "-")
+		switch {
+		case strings.Contains(escapedObject[idx+len("/acl/"):], "/"):
+			return "", "", false
+		case entity == storage.EntityAllUsers || entity == storage.EntityAllAuthenticatedUsers,
+			kind == "user" || kind == "group" || kind == "domain" || kind == "project":
+			name = escapedObject[:idx]
+		default:
+			return "", "", false
+		}
+	}
+
+	objectName, err := url.PathUnescape(name)
+	if err != nil || objectName == "" {
+		return "", "", false
+	}
+	return objectName, entity, true
+}
+
+// escapedObjectPath returns the escaped object part of the path of a request to an object route, like
+// "dir%2Ffile.txt/acl" for /storage/v1/b/bucket/o/dir%2Ffile.txt/acl. Bucket names can't contain slashes,
+// so the object part starts after the segment following /b/.
+func escapedObjectPath(r *http.Request) string {
+	_, rest, _ := strings.Cut(r.URL.EscapedPath(), "/b/")
+	_, rest, _ = strings.Cut(rest, "/")
+	return strings.TrimPrefix(rest, "o/")
+}
+
+// serveObjectACL serves the object ACL requests, ending in /acl or /acl/{entity}, that the object routes
+// receive. It returns false for requests that aren't object ACL requests, which the object handlers serve
+// themselves. The decision is made on the escaped path, so an /acl at the end of an object name, escaped
+// as %2Facl, is part of the name.
+// Reference: https://cloud.google.com/storage/docs/json_api/v1/objectAccessControls
+func (h *Storage) serveObjectACL(w http.ResponseWriter, r *http.Request) bool {
+	objectName, entity, ok := cutObjectACLPath(escapedObjectPath(r))
+	if !ok {
+		return false
+	}
+	bucketName := r.PathValue("bucket")
+
+	switch {
+	case entity == "" && r.Method == http.MethodGet:
+		h.listObjectACL(w, r, bucketName, objectName)
+	case entity == "" && r.Method == http.MethodPost:
+		h.setObjectACLEntry(w, r, bucketName, objectName, "")
+	case entity != "" && r.Method == http.MethodGet:
+		h.getObjectACLEntry(w, r, bucketName, objectName, entity)
+	case entity != "" && (r.Method == http.MethodPut || r.Method == http.MethodPatch):
+		h.setObjectACLEntry(w, r, bucketName, objectName, entity)
+	case entity != "" && r.Method == http.MethodDelete:
+		h.deleteObjectACLEntry(w, bucketName, objectName, entity)
+	default:
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed for object ACLs", "methodNotAllowed")
+	}
+	return true
+}
+
+// listObjectACL serves GET /storage/v1/b/{bucket}/o/{object}/acl - List the ACL entries of an object.
+func (h *Storage) listObjectACL(w http.ResponseWriter, r *http.Request, bucketName, objectName string) {
+	acl, err := h.store.ListObjectACL(bucketName, objectName)
+	if err != nil {
+		respondObjectACLError(w, err)
+		return
+	}
+
+	respondStorageJSON(w, r, http.StatusOK, &storage.ObjectAccessControls{
+		Kind:  "storage#objectAccessControls",
+		Items: acl,
+	})
+}
+
+// getObjectACLEntry serves GET /storage/v1/b/{bucket}/o/{object}/acl/{entity} - Get the ACL entry of an entity.
+func (h *Storage) getObjectACLEntry(w http.ResponseWriter, r *http.Request, bucketName, objectName, entity string) {
+	entry, err := h.store.GetObjectACLEntry(bucketName, objectName, entity)
+	if err != nil {
+		respondObjectACLError(w, err)
+		return
+	}
+
+	respondStorageJSON(w, r, http.StatusOK, entry)
+}
+
+// setObjectACLEntry serves POST /storage/v1/b/{bucket}/o/{object}/acl - Add an ACL entry, and
+// PUT and PATCH /storage/v1/b/{bucket}/o/{object}/acl/{entity} - Change the role of an entity.
+// For PUT and PATCH, the entity of the path takes precedence over the one in the body.
+func (h *Storage) setObjectACLEntry(w http.ResponseWriter, r *http.Request, bucketName, objectName, entity string) {
+	var entry storage.ObjectAccessControl
+	if err := decodeJSON(r.Body, &entry, h.store.StrictValidation(), &storage.ObjectAccessControl{}); err != nil {
+		respondError(w, http.StatusBadRequest, invalidJSONMessage(err), "invalid")
+		return
+	}
+	if entity != "" {
+		entry.Entity = entity
+	}
+	if entry.Entity == "" {
+		respondError(w, http.StatusBadRequest, "Entity is required", "required")
+		return
+	}
+
+	// Changing an entry of an entity that has none is an error, unlike adding one
+	if entity != "" {
+		if _, err := h.store.GetObjectACLEntry(bucketName, objectName, entity); err != nil {
+			respondObjectACLError(w, err)
+			return
+		}
+	}
+
+	updated, err := h.store.SetObjectACLEntry(bucketName, objectName, entry)
+	if err != nil {
+		respondObjectACLError(w, err)
+		return
+	}
+
+	respondStorageJSON(w, r, http.StatusOK, updated)
+}
+
+// deleteObjectACLEntry serves DELETE /storage/v1/b/{bucket}/o/{object}/acl/{entity} - Remove the ACL entry of an entity.
+func (h *Storage) deleteObjectACLEntry(w http.ResponseWriter, bucketName, objectName, entity string) {
+	if err := h.store.DeleteObjectACLEntry(bucketName, objectName, entity); err != nil {
+		respondObjectACLError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// respondObjectACLError writes the error response for a failed object ACL request.
+func respondObjectACLError(w http.ResponseWriter, err error) {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		respondError(w, http.StatusNotFound, err.Error(), "notFound")
+	case strings.Contains(err.Error(), "precondition failed"):
+		respondError(w, http.StatusPreconditionFailed, err.Error(), "conditionNotMet")
+	case strings.Contains(err.Error(), "invalid"):
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+	default:
+		respondError(w, http.StatusInternalServerError, err.Error(), "internalError")
+	}
+}
@@ -0,0 +1,197 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+)
+
+func TestStorage_PredefinedACL(t *testing.T) {
+	h, s := setupTestStorage()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+
+	tests := []struct {
+		name           string
+		path           string
+		handler        http.HandlerFunc
+		pattern        string
+		expectedStatus int
+		expectedPublic bool
+	}{
+		{"upload publicRead", "/upload/storage/v1/b/test-bucket/o?uploadType=media&name=public.txt&predefinedAcl=publicRead",
+			h.InsertObject, "POST /upload/storage/v1/b/{bucket}/o", http.StatusOK, true},
+		{"upload without ACL", "/upload/storage/v1/b/test-bucket/o?uploadType=media&name=private.txt",
+			h.InsertObject, "POST /upload/storage/v1/b/{bucket}/o", http.StatusOK, false},
+		{"upload invalid ACL", "/upload/storage/v1/b/test-bucket/o?uploadType=media&name=x.txt&predefinedAcl=public-read",
+			h.InsertObject, "POST /upload/storage/v1/b/{bucket}/o", http.StatusBadRequest, false},
+		{"copy authenticatedRead", "/storage/v1/b/test-bucket/o/private.txt/rewriteTo/b/test-bucket/o/copy.txt?destinationPredefinedAcl=authenticatedRead",
+			h.ObjectAction, "POST /storage/v1/b/{bucket}/o/{object...}", http.StatusOK, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body io.Reader
+			if tt.pattern != "POST /storage/v1/b/{bucket}/o/{object...}" {
+				body = strings.NewReader("content")
+			}
+			req := httptest.NewRequest(http.MethodPost, tt.path, body)
+			rr := httptest.NewRecorder()
+			serveRoute(tt.pattern, tt.handler, rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if rr.Code != http.StatusOK {
+				return
+			}
+
+			var obj storage.Object
+			if strings.Contains(tt.path, "rewriteTo") {
+				var resp storage.RewriteResponse
+				_ = json.NewDecoder(rr.Body).Decode(&resp)
+				obj = *resp.Resource
+			} else {
+				_ = json.NewDecoder(rr.Body).Decode(&obj)
+			}
+			if len(obj.Acl) == 0 || storage.IsPublicACL(obj.Acl) != tt.expectedPublic {
+				t.Errorf("expected public ACL %v, got %+v", tt.expectedPublic, obj.Acl)
+			}
+		})
+	}
+}
+
+func TestStorage_ObjectACL(t *testing.T) {
+	h, s := setupTestStorage()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+	_, _ = s.CreateObject("test-bucket", "dir/file.txt", "text/plain", []byte("hello"), nil)
+
+	const pattern = "/storage/v1/b/{bucket}/o/{object...}"
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+pattern, h.GetObject)
+	mux.HandleFunc("POST "+pattern, h.ObjectAction)
+	mux.HandleFunc("PATCH "+pattern, h.PatchObject)
+	mux.HandleFunc("DELETE "+pattern, h.DeleteObject)
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rr
+	}
+
+	rr := serve(http.MethodPost, "/storage/v1/b/test-bucket/o/dir%2Ffile.txt/acl", `{"entity":"allUsers","role":"READER"}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	rr = serve(http.MethodGet, "/storage/v1/b/test-bucket/o/dir%2Ffile.txt/acl", "")
+	var acl storage.ObjectAccessControls
+	if err := json.NewDecoder(rr.Body).Decode(&acl); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(acl.Items) != 4 || !storage.IsPublicACL(acl.Items) {
+		t.Errorf("expected 4 entries including allUsers, got %+v", acl.Items)
+	}
+
+	rr = serve(http.MethodPatch, "/storage/v1/b/test-bucket/o/dir%2Ffile.txt/acl/allUsers", `{"role":"OWNER"}`)
+	var entry storage.ObjectAccessControl
+	_ = json.NewDecoder(rr.Body).Decode(&entry)
+	if rr.Code != http.StatusOK || entry.Role != storage.RoleOwner {
+		t.Errorf("expected allUsers to be an owner, got %d %+v", rr.Code, entry)
+	}
+
+	if rr := serve(http.MethodPatch, "/storage/v1/b/test-bucket/o/dir%2Ffile.txt/acl/user-jane@example.com", `{"role":"READER"}`); rr.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for an entity without an entry, got %d", rr.Code)
+	}
+	if rr := serve(http.MethodPost, "/storage/v1/b/test-bucket/o/dir%2Ffile.txt/acl", `{"entity":"allUsers","role":"WRITER"}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for an invalid role, got %d", rr.Code)
+	}
+
+	if rr := serve(http.MethodDelete, "/storage/v1/b/test-bucket/o/dir%2Ffile.txt/acl/allUsers", ""); rr.Code != http.StatusNoContent {
+		t.Errorf("expected status 204, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := serve(http.MethodGet, "/storage/v1/b/test-bucket/o/dir%2Ffile.txt/acl/allUsers", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 after delete, got %d", rr.Code)
+	}
+
+	// The object itself is still served by the object routes
+	if rr := serve(http.MethodGet, "/storage/v1/b/test-bucket/o/dir%2Ffile.txt", ""); rr.Code != http.StatusOK {
+		t.Errorf("expected status 200 for the object, got %d", rr.Code)
+	}
+}
+
+func TestCutObjectACLPath(t *testing.T) {
+	tests := []struct {
+		object string
+		name   string
+		entity string
+		ok     bool
+	}{
+		{"file.txt/acl", "file.txt", "", true},
+		{"dir/file.txt/acl/allUsers", "dir/file.txt", "allUsers", true},
+		{"acl/file.txt/acl/user-jane@example.com", "acl/file.txt", "user-jane@example.com", true},
+		{"dir/file.txt", "", "", false},
+		{"dir/acl/file.txt", "", "", false},
+		{"dir/acl/file-1.txt", "", "", false},
+		{"dir%2Ffile.txt/acl/user-jane%40example.com", "dir/file.txt", "user-jane@example.com", true},
+		{"logs%2Facl", "", "", false},
+		{"logs%2Facl%2FallUsers", "", "", false},
+		{"/acl", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.object, func(t *testing.T) {
+			name, entity, ok := cutObjectACLPath(tt.object)
+			if name != tt.name || entity != tt.entity || ok != tt.ok {
+				t.Errorf("cutObjectACLPath() = %q, %q, %v", name, entity, ok)
+			}
+		})
+	}
+}
+
+func TestStorage_ObjectNamedACL(t *testing.T) {
+	h, s := setupTestStorage()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+	_, _ = s.CreateObject("test-bucket", "x", "text/plain", []byte("parent"), nil)
+	_, _ = s.CreateObject("test-bucket", "x/acl", "text/plain", []byte("hello"), nil)
+
+	const pattern = "/storage/v1/b/{bucket}/o/{object...}"
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+pattern, h.GetObject)
+	mux.HandleFunc("DELETE "+pattern, h.DeleteObject)
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(method, path, nil))
+		return rr
+	}
+
+	rr := serve(http.MethodGet, "/storage/v1/b/test-bucket/o/x%2Facl")
+	var obj storage.Object
+	_ = json.NewDecoder(rr.Body).Decode(&obj)
+	if rr.Code != http.StatusOK || obj.Name != "x/acl" {
+		t.Errorf("expected the metadata of x/acl, got %d %+v", rr.Code, obj)
+	}
+	if rr := serve(http.MethodGet, "/storage/v1/b/test-bucket/o/x%2Facl?alt=media"); rr.Code != http.StatusOK || rr.Body.String() != "hello" {
+		t.Errorf("expected the content of x/acl, got %d %q", rr.Code, rr.Body.String())
+	}
+
+	// An unescaped /acl segment is still the ACL of x
+	rr = serve(http.MethodGet, "/storage/v1/b/test-bucket/o/x/acl")
+	var acl storage.ObjectAccessControls
+	_ = json.NewDecoder(rr.Body).Decode(&acl)
+	if rr.Code != http.StatusOK || acl.Kind != "storage#objectAccessControls" {
+		t.Errorf("expected the ACL of x, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	if rr := serve(http.MethodDelete, "/storage/v1/b/test-bucket/o/x%2Facl"); rr.Code != http.StatusNoContent {
+		t.Errorf("expected status 204 for deleting x/acl, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if s.GetObject("test-bucket", "x/acl") != nil || s.GetObject("test-bucket", "x") == nil {
+		t.Errorf("expected only x/acl to be deleted")
+	}
+}
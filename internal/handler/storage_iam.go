@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+)
+
+// GetBucketIamPolicy handles GET /storage/v1/b/{bucket}/iam - Get the IAM policy of a bucket.
+// Reference: https://cloud.google.com/storage/docs/json_api/v1/buckets/getIamPolicy
+func (h *Storage) GetBucketIamPolicy(w http.ResponseWriter, r *http.Request) {
+	policy, err := h.store.GetBucketIamPolicy(r.PathValue("bucket"))
+	if err != nil {
+		respondError(w, http.StatusNotFound, err.Error(), "notFound")
+		return
+	}
+
+	respondStorageJSON(w, r, http.StatusOK, policy)
+}
+
+// SetBucketIamPolicy handles PUT /storage/v1/b/{bucket}/iam - Replace the IAM policy of a bucket.
+// Policies granting access to allUsers or allAuthenticatedUsers are rejected if the bucket enforces
+// public access prevention.
+// Reference: https://cloud.google.com/storage/docs/json_api/v1/buckets/setIamPolicy
+func (h *Storage) SetBucketIamPolicy(w http.ResponseWriter, r *http.Request) {
+	var policy storage.Policy
+	if err := decodeJSON(r.Body, &policy, h.store.StrictValidation(), &storage.Policy{}); err != nil {
+		respondError(w, http.StatusBadRequest, invalidJSONMessage(err), "invalid")
+		return
+	}
+
+	for _, binding := range policy.Bindings {
+		if binding.Role == "" || len(binding.Members) == 0 {
+			respondError(w, http.StatusBadRequest, "Each binding requires a role and at least one member", "invalid")
+			return
+		}
+	}
+
+	updated, err := h.store.SetBucketIamPolicy(r.PathValue("bucket"), &policy)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondError(w, http.StatusNotFound, err.Error(), "notFound")
+			return
+		}
+		if strings.Contains(err.Error(), "precondition failed") {
+			respondError(w, http.StatusPreconditionFailed, err.Error(), "conditionNotMet")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error(), "internalError")
+		return
+	}
+
+	respondStorageJSON(w, r, http.StatusOK, updated)
+}
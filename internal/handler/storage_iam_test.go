@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+)
+
+func TestStorage_BucketIamPolicy(t *testing.T) {
+	h, s := setupTestStorage()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{
+		Name:             "private-bucket",
+		IamConfiguration: &storage.IamConfiguration{PublicAccessPrevention: storage.PublicAccessPreventionEnforced},
+	})
+
+	tests := []struct {
+		name           string
+		bucket         string
+		body           string
+		expectedStatus int
+	}{
+		{"grant a user", "test-bucket", `{"bindings":[{"role":"roles/storage.objectViewer","members":["user:jane@example.com"]}]}`, http.StatusOK},
+		{"grant allUsers", "test-bucket", `{"bindings":[{"role":"roles/storage.objectViewer","members":["allUsers"]}]}`, http.StatusOK},
+		{"grant allUsers with enforced prevention", "private-bucket", `{"bindings":[{"role":"roles/storage.objectViewer","members":["allUsers"]}]}`, http.StatusPreconditionFailed},
+		{"stale etag", "test-bucket", `{"bindings":[],"etag":"CAE="}`, http.StatusPreconditionFailed},
+		{"binding without members", "test-bucket", `{"bindings":[{"role":"roles/storage.objectViewer","members":[]}]}`, http.StatusBadRequest},
+		{"missing bucket", "missing-bucket", `{"bindings":[]}`, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/storage/v1/b/"+tt.bucket+"/iam", strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+			serveRoute("PUT /storage/v1/b/{bucket}/iam", h.SetBucketIamPolicy, rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/storage/v1/b/test-bucket/iam", nil)
+	rr := httptest.NewRecorder()
+	serveRoute("GET /storage/v1/b/{bucket}/iam", h.GetBucketIamPolicy, rr, req)
+
+	var policy storage.Policy
+	if err := json.NewDecoder(rr.Body).Decode(&policy); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if policy.ResourceID != "projects/_/buckets/test-bucket" || !storage.IsPublicPolicy(&policy) {
+		t.Errorf("expected the public policy set last, got %+v", policy)
+	}
+}
+
+func TestStorage_PublicAccessPreventionSetting(t *testing.T) {
+	h, s := setupTestStorage()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+	_, _ = s.CreateObject("test-bucket", "file.txt", "text/plain", []byte("x"), nil)
+
+	patch := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, "/storage/v1/b/test-bucket", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		serveRoute("PATCH /storage/v1/b/{bucket}", h.PatchBucket, rr, req)
+		return rr
+	}
+
+	if rr := patch(`{"iamConfiguration":{"publicAccessPrevention":"always"}}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for an invalid setting, got %d", rr.Code)
+	}
+
+	rr := patch(`{"iamConfiguration":{"publicAccessPrevention":"enforced"}}`)
+	var bucket storage.Bucket
+	_ = json.NewDecoder(rr.Body).Decode(&bucket)
+	if rr.Code != http.StatusOK || !bucket.PublicAccessPreventionEnforced() {
+		t.Fatalf("expected enforced public access prevention, got %d %+v", rr.Code, bucket.IamConfiguration)
+	}
+
+	// Uploads and ACL changes that make objects public fail
+	req := httptest.NewRequest(http.MethodPost, "/upload/storage/v1/b/test-bucket/o?name=public.txt&predefinedAcl=publicRead", strings.NewReader("x"))
+	rr = httptest.NewRecorder()
+	serveRoute("POST /upload/storage/v1/b/{bucket}/o", h.InsertObject, rr, req)
+	if rr.Code != http.StatusPreconditionFailed {
+		t.Errorf("expected status 412 for a public upload, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/storage/v1/b/test-bucket/o/file.txt/acl", strings.NewReader(`{"entity":"allUsers","role":"READER"}`))
+	rr = httptest.NewRecorder()
+	serveRoute("POST /storage/v1/b/{bucket}/o/{object...}", h.ObjectAction, rr, req)
+	if rr.Code != http.StatusPreconditionFailed {
+		t.Errorf("expected status 412 for a public ACL entry, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
@@ -3,15 +3,27 @@ package handler
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/katharinasick/gcp-api-mock/internal/storage"
 	"github.com/katharinasick/gcp-api-mock/internal/store"
 )
 
+// serveRoute serves a request with a handler registered for a route pattern, like the server's router does,
+// so the handler gets the path values of the request path.
+func serveRoute(pattern string, handler http.HandlerFunc, w http.ResponseWriter, r *http.Request) {
+	mux := http.NewServeMux()
+	mux.HandleFunc(pattern, handler)
+	mux.ServeHTTP(w, r)
+}
+
 func setupTestStorage() (*Storage, *store.Store) {
 	s := store.New()
 	return NewStorage(s), s
@@ -119,11 +131,65 @@ func TestStorage_CreateBucket_Duplicate(t *testing.T) {
 	}
 }
 
+func TestStorage_CreateBucket_Autoclass(t *testing.T) {
+	h, _ := setupTestStorage()
+
+	tests := []struct {
+		name             string
+		body             string
+		expectedStatus   int
+		expectedTerminal string
+	}{
+		{"default terminal storage class", `{"name":"bucket-a","autoclass":{"enabled":true}}`, http.StatusOK, "NEARLINE"},
+		{"archive terminal storage class", `{"name":"bucket-b","autoclass":{"enabled":true,"terminalStorageClass":"ARCHIVE"}}`, http.StatusOK, "ARCHIVE"},
+		{"invalid terminal storage class", `{"name":"bucket-c","autoclass":{"enabled":true,"terminalStorageClass":"COLDLINE"}}`, http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/storage/v1/b", strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+
+			h.CreateBucket(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var bucket storage.Bucket
+			if err := json.NewDecoder(rr.Body).Decode(&bucket); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if bucket.Autoclass == nil || bucket.Autoclass.TerminalStorageClass != tt.expectedTerminal || bucket.Autoclass.ToggleTime == nil {
+				t.Errorf("expected Autoclass with terminal storage class %s, got %+v", tt.expectedTerminal, bucket.Autoclass)
+			}
+		})
+	}
+}
+
+func TestStorage_CreateBucket_InvalidLifecycle(t *testing.T) {
+	h, _ := setupTestStorage()
+
+	body := `{"name":"test-bucket","lifecycle":{"rule":[{"action":{"type":"Delete"},"condition":{"customTimeBefore":"01/31/2024"}}]}}`
+	req := httptest.NewRequest(http.MethodPost, "/storage/v1/b", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+
+	h.CreateBucket(rr, req)
+
+	if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), "customTimeBefore") {
+		t.Errorf("expected status %d naming customTimeBefore, got %d: %s", http.StatusBadRequest, rr.Code, rr.Body.String())
+	}
+}
+
 func TestStorage_GetBucket(t *testing.T) {
 	h, s := setupTestStorage()
 	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
 
 	req := httptest.NewRequest(http.MethodGet, "/storage/v1/b/test-bucket", nil)
+	req.SetPathValue("bucket", "test-bucket")
 	rr := httptest.NewRecorder()
 
 	h.GetBucket(rr, req)
@@ -146,6 +212,7 @@ func TestStorage_GetBucket_NotFound(t *testing.T) {
 	h, _ := setupTestStorage()
 
 	req := httptest.NewRequest(http.MethodGet, "/storage/v1/b/non-existent", nil)
+	req.SetPathValue("bucket", "non-existent")
 	rr := httptest.NewRecorder()
 
 	h.GetBucket(rr, req)
@@ -161,6 +228,7 @@ func TestStorage_UpdateBucket(t *testing.T) {
 
 	body := `{"storageClass": "NEARLINE", "labels": {"env": "test"}}`
 	req := httptest.NewRequest(http.MethodPut, "/storage/v1/b/test-bucket", strings.NewReader(body))
+	req.SetPathValue("bucket", "test-bucket")
 	req.Header.Set("Content-Type", "application/json")
 	rr := httptest.NewRecorder()
 
@@ -185,6 +253,7 @@ func TestStorage_UpdateBucket_NotFound(t *testing.T) {
 
 	body := `{"storageClass": "NEARLINE"}`
 	req := httptest.NewRequest(http.MethodPut, "/storage/v1/b/non-existent", strings.NewReader(body))
+	req.SetPathValue("bucket", "non-existent")
 	req.Header.Set("Content-Type", "application/json")
 	rr := httptest.NewRecorder()
 
@@ -195,11 +264,144 @@ func TestStorage_UpdateBucket_NotFound(t *testing.T) {
 	}
 }
 
+func TestStorage_UpdateBucket_Etag(t *testing.T) {
+	h, s := setupTestStorage()
+	original, _ := s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+
+	// The first read-modify-write cycle wins, the second one is based on a stale etag
+	tests := []struct {
+		name           string
+		handler        http.HandlerFunc
+		method         string
+		body           string
+		ifMatch        string
+		expectedStatus int
+	}{
+		{"update with current etag", h.UpdateBucket, http.MethodPut, `{"storageClass": "NEARLINE", "etag": "` + original.Etag + `"}`, "", http.StatusOK},
+		{"update with stale etag", h.UpdateBucket, http.MethodPut, `{"storageClass": "COLDLINE", "etag": "` + original.Etag + `"}`, "", http.StatusPreconditionFailed},
+		{"patch with stale If-Match", h.PatchBucket, http.MethodPatch, `{"labels": {"env": "test"}}`, `"` + original.Etag + `"`, http.StatusPreconditionFailed},
+		{"patch with If-Match and other body etag", h.PatchBucket, http.MethodPatch, `{"etag": "other"}`, original.Etag, http.StatusPreconditionFailed},
+		{"patch with any etag", h.PatchBucket, http.MethodPatch, `{"labels": {"env": "test"}}`, "*", http.StatusOK},
+	}
+
+	etag := original.Etag
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/storage/v1/b/test-bucket", strings.NewReader(tt.body))
+			req.SetPathValue("bucket", "test-bucket")
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			rr := httptest.NewRecorder()
+
+			tt.handler(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if rr.Code != http.StatusOK {
+				return
+			}
+			var bucket storage.Bucket
+			if err := json.NewDecoder(rr.Body).Decode(&bucket); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if bucket.Etag == etag || rr.Header().Get("ETag") != bucket.Etag {
+				t.Errorf("expected a fresh etag in the body and header, got %q and %q after %q", bucket.Etag, rr.Header().Get("ETag"), etag)
+			}
+			etag = bucket.Etag
+		})
+	}
+
+	if bucket := s.GetBucket("test-bucket"); bucket.StorageClass != "NEARLINE" {
+		t.Errorf("expected the stale update to be rejected, got storage class %s", bucket.StorageClass)
+	}
+}
+
+func TestStorage_LockRetentionPolicy(t *testing.T) {
+	h, s := setupTestStorage()
+	bucket, _ := s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket", RetentionPolicy: &storage.RetentionPolicy{RetentionPeriod: 3600}})
+	_, _ = s.CreateObject("test-bucket", "record.json", "application/json", []byte("{}"), nil)
+
+	lock := func(query string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		serveRoute("POST /storage/v1/b/{bucket}/lockRetentionPolicy", h.LockRetentionPolicy, rr,
+			httptest.NewRequest(http.MethodPost, "/storage/v1/b/test-bucket/lockRetentionPolicy"+query, nil))
+		return rr
+	}
+
+	if rr := lock(""); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d without a metageneration, got %d", http.StatusBadRequest, rr.Code)
+	}
+	if rr := lock("?ifMetagenerationMatch=42"); rr.Code != http.StatusPreconditionFailed {
+		t.Errorf("expected status %d for a stale metageneration, got %d", http.StatusPreconditionFailed, rr.Code)
+	}
+	rr := lock(fmt.Sprintf("?ifMetagenerationMatch=%d", bucket.Metageneration))
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"isLocked":true`) {
+		t.Fatalf("expected a locked policy, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	serveRoute("PATCH /storage/v1/b/{bucket}", h.PatchBucket, rr,
+		httptest.NewRequest(http.MethodPatch, "/storage/v1/b/test-bucket", strings.NewReader(`{"retentionPolicy": {"retentionPeriod": "60"}}`)))
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("expected status %d reducing a locked policy, got %d: %s", http.StatusForbidden, rr.Code, rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	serveRoute("DELETE /storage/v1/b/{bucket}/o/{object...}", h.DeleteObject, rr,
+		httptest.NewRequest(http.MethodDelete, "/storage/v1/b/test-bucket/o/record.json", nil))
+	if rr.Code != http.StatusForbidden || !strings.Contains(rr.Body.String(), "retentionPolicyNotMet") {
+		t.Errorf("expected status %d deleting a retained object, got %d: %s", http.StatusForbidden, rr.Code, rr.Body.String())
+	}
+}
+
+func TestStorage_PatchBucket(t *testing.T) {
+	h, s := setupTestStorage()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+	_, _ = s.UpdateBucket("test-bucket", &storage.BucketUpdateRequest{
+		Labels:     map[string]string{"env": "test", "team": "a"},
+		Versioning: &storage.Versioning{Enabled: true},
+	})
+
+	body := `{"labels": {"team": null, "owner": "b"}, "versioning": null}`
+	req := httptest.NewRequest(http.MethodPatch, "/storage/v1/b/test-bucket", strings.NewReader(body))
+	req.SetPathValue("bucket", "test-bucket")
+	rr := httptest.NewRecorder()
+
+	h.PatchBucket(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	var bucket storage.Bucket
+	if err := json.NewDecoder(rr.Body).Decode(&bucket); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	expectedLabels := map[string]string{"env": "test", "owner": "b"}
+	if !reflect.DeepEqual(bucket.Labels, expectedLabels) {
+		t.Errorf("expected labels %v, got %v", expectedLabels, bucket.Labels)
+	}
+	if bucket.Versioning != nil {
+		t.Errorf("expected versioning to be cleared, got %+v", bucket.Versioning)
+	}
+
+	req = httptest.NewRequest(http.MethodPatch, "/storage/v1/b/non-existent", strings.NewReader(`{}`))
+	req.SetPathValue("bucket", "non-existent")
+	rr = httptest.NewRecorder()
+	h.PatchBucket(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d", http.StatusNotFound, rr.Code)
+	}
+}
+
 func TestStorage_DeleteBucket(t *testing.T) {
 	h, s := setupTestStorage()
 	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
 
 	req := httptest.NewRequest(http.MethodDelete, "/storage/v1/b/test-bucket", nil)
+	req.SetPathValue("bucket", "test-bucket")
 	rr := httptest.NewRecorder()
 
 	h.DeleteBucket(rr, req)
@@ -218,6 +420,7 @@ func TestStorage_DeleteBucket_NotFound(t *testing.T) {
 	h, _ := setupTestStorage()
 
 	req := httptest.NewRequest(http.MethodDelete, "/storage/v1/b/non-existent", nil)
+	req.SetPathValue("bucket", "non-existent")
 	rr := httptest.NewRecorder()
 
 	h.DeleteBucket(rr, req)
@@ -233,6 +436,7 @@ func TestStorage_DeleteBucket_NotEmpty(t *testing.T) {
 	_, _ = s.CreateObject("test-bucket", "test-object", "text/plain", []byte("hello"), nil)
 
 	req := httptest.NewRequest(http.MethodDelete, "/storage/v1/b/test-bucket", nil)
+	req.SetPathValue("bucket", "test-bucket")
 	rr := httptest.NewRecorder()
 
 	h.DeleteBucket(rr, req)
@@ -271,6 +475,7 @@ func TestStorage_ListObjects_Empty(t *testing.T) {
 	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
 
 	req := httptest.NewRequest(http.MethodGet, "/storage/v1/b/test-bucket/o", nil)
+	req.SetPathValue("bucket", "test-bucket")
 	rr := httptest.NewRecorder()
 
 	h.ListObjects(rr, req)
@@ -293,6 +498,7 @@ func TestStorage_ListObjects_BucketNotFound(t *testing.T) {
 	h, _ := setupTestStorage()
 
 	req := httptest.NewRequest(http.MethodGet, "/storage/v1/b/non-existent/o", nil)
+	req.SetPathValue("bucket", "non-existent")
 	rr := httptest.NewRecorder()
 
 	h.ListObjects(rr, req)
@@ -308,6 +514,7 @@ func TestStorage_InsertObject(t *testing.T) {
 
 	content := []byte("Hello, World!")
 	req := httptest.NewRequest(http.MethodPost, "/upload/storage/v1/b/test-bucket/o?name=test.txt", bytes.NewReader(content))
+	req.SetPathValue("bucket", "test-bucket")
 	req.Header.Set("Content-Type", "text/plain")
 	rr := httptest.NewRecorder()
 
@@ -339,6 +546,7 @@ func TestStorage_InsertObject_BucketNotFound(t *testing.T) {
 	h, _ := setupTestStorage()
 
 	req := httptest.NewRequest(http.MethodPost, "/upload/storage/v1/b/non-existent/o?name=test.txt", bytes.NewReader([]byte("data")))
+	req.SetPathValue("bucket", "non-existent")
 	rr := httptest.NewRecorder()
 
 	h.InsertObject(rr, req)
@@ -353,6 +561,7 @@ func TestStorage_InsertObject_MissingName(t *testing.T) {
 	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
 
 	req := httptest.NewRequest(http.MethodPost, "/upload/storage/v1/b/test-bucket/o", bytes.NewReader([]byte("data")))
+	req.SetPathValue("bucket", "test-bucket")
 	rr := httptest.NewRecorder()
 
 	h.InsertObject(rr, req)
@@ -377,6 +586,7 @@ func TestStorage_InsertObject_MultipartRelated(t *testing.T) {
 		"--" + boundary + "--\r\n"
 
 	req := httptest.NewRequest(http.MethodPost, "/upload/storage/v1/b/test-bucket/o?name=state.tfstate", strings.NewReader(body))
+	req.SetPathValue("bucket", "test-bucket")
 	req.Header.Set("Content-Type", "multipart/related; boundary="+boundary)
 	rr := httptest.NewRecorder()
 
@@ -413,208 +623,864 @@ func TestStorage_InsertObject_MultipartRelated(t *testing.T) {
 	}
 }
 
-func TestStorage_GetObject(t *testing.T) {
+func TestStorage_InsertObject_MultipartNameInMetadata(t *testing.T) {
 	h, s := setupTestStorage()
 	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
-	_, _ = s.CreateObject("test-bucket", "test.txt", "text/plain", []byte("Hello"), nil)
 
-	req := httptest.NewRequest(http.MethodGet, "/storage/v1/b/test-bucket/o/test.txt", nil)
+	// Client libraries name the object in the metadata part instead of the query
+	boundary := "boundary123"
+	body := "--" + boundary + "\r\n" +
+		"Content-Type: application/json; charset=UTF-8\r\n\r\n" +
+		`{"name":"dir/file.txt"}` + "\r\n" +
+		"--" + boundary + "\r\n" +
+		"Content-Type: text/plain\r\n\r\n" +
+		"hello\r\n" +
+		"--" + boundary + "--\r\n"
+
+	req := httptest.NewRequest(http.MethodPost, "/upload/storage/v1/b/test-bucket/o?uploadType=multipart", strings.NewReader(body))
+	req.SetPathValue("bucket", "test-bucket")
+	req.Header.Set("Content-Type", "multipart/related; boundary="+boundary)
 	rr := httptest.NewRecorder()
 
-	h.GetObject(rr, req)
+	h.InsertObject(rr, req)
 
 	if rr.Code != http.StatusOK {
-		t.Errorf("expected status %d, got %d", http.StatusOK, rr.Code)
-	}
-
-	var obj storage.Object
-	if err := json.NewDecoder(rr.Body).Decode(&obj); err != nil {
-		t.Fatalf("failed to decode response: %v", err)
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
 	}
-
-	if obj.Name != "test.txt" {
-		t.Errorf("expected name 'test.txt', got '%s'", obj.Name)
+	if content := s.GetObjectContent("test-bucket", "dir/file.txt"); string(content) != "hello" {
+		t.Errorf("expected content 'hello', got '%s'", string(content))
 	}
 }
 
-func TestStorage_GetObject_NotFound(t *testing.T) {
+func TestStorage_InsertObject_Checksums(t *testing.T) {
 	h, s := setupTestStorage()
 	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
 
-	req := httptest.NewRequest(http.MethodGet, "/storage/v1/b/test-bucket/o/non-existent", nil)
-	rr := httptest.NewRecorder()
-
-	h.GetObject(rr, req)
+	// Checksums of "hello"
+	const md5Hash, crc32c = "XUFAKrxLKna5cZ2REBfFkg==", "mnG7TA=="
+	const wrongHash = "AAAAAAAAAAAAAAAAAAAAAA=="
 
-	if rr.Code != http.StatusNotFound {
-		t.Errorf("expected status %d, got %d", http.StatusNotFound, rr.Code)
+	multipart := func(metadata string) string {
+		return "--b\r\nContent-Type: application/json\r\n\r\n" + metadata + "\r\n" +
+			"--b\r\nContent-Type: text/plain\r\n\r\nhello\r\n--b--\r\n"
 	}
-}
 
-// TestStorage_GetObject_NotFound_Consistent verifies that both metadata and content
-// endpoints return consistent 404 errors when an object doesn't exist.
-// This is critical for OpenTofu/Terraform's GCS backend which makes two separate
-// API calls: NewReader() with alt=media and Attrs() without alt=media.
-func TestStorage_GetObject_NotFound_Consistent(t *testing.T) {
-	h, s := setupTestStorage()
-	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "cloudhaven-tfstate"})
+	tests := []struct {
+		name           string
+		query          string
+		body           string
+		multipart      bool
+		hashHeader     string
+		expectedStatus int
+	}{
+		{"matching metadata checksums", "uploadType=multipart", multipart(`{"name":"a.txt","md5Hash":"` + md5Hash + `","crc32c":"` + crc32c + `"}`), true, "", http.StatusOK},
+		{"wrong md5 in metadata", "uploadType=multipart", multipart(`{"name":"b.txt","md5Hash":"` + wrongHash + `"}`), true, "", http.StatusBadRequest},
+		{"wrong crc32c in metadata", "uploadType=multipart", multipart(`{"name":"c.txt","crc32c":"AAAAAA=="}`), true, "", http.StatusBadRequest},
+		{"matching x-goog-hash", "uploadType=media&name=d.txt", "hello", false, "crc32c=" + crc32c + ",md5=" + md5Hash, http.StatusOK},
+		{"wrong x-goog-hash", "uploadType=media&name=e.txt", "hello", false, "md5=" + wrongHash, http.StatusBadRequest},
+	}
 
-	// Test 1: Metadata endpoint (without alt=media) should return 404
-	metaReq := httptest.NewRequest(http.MethodGet, "/storage/v1/b/cloudhaven-tfstate/o/default.tfstate", nil)
-	metaRR := httptest.NewRecorder()
-	h.GetObject(metaRR, metaReq)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/upload/storage/v1/b/test-bucket/o?"+tt.query, strings.NewReader(tt.body))
+			req.SetPathValue("bucket", "test-bucket")
+			if tt.multipart {
+				req.Header.Set("Content-Type", "multipart/related; boundary=b")
+			}
+			if tt.hashHeader != "" {
+				req.Header.Set("X-Goog-Hash", tt.hashHeader)
+			}
+			rr := httptest.NewRecorder()
 
-	if metaRR.Code != http.StatusNotFound {
-		t.Errorf("metadata endpoint: expected status %d, got %d", http.StatusNotFound, metaRR.Code)
-	}
+			h.InsertObject(rr, req)
 
-	var metaErr storage.APIError
-	if err := json.NewDecoder(metaRR.Body).Decode(&metaErr); err != nil {
-		t.Fatalf("metadata endpoint: failed to decode error response: %v", err)
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+		})
 	}
 
-	if metaErr.Error.Code != 404 {
-		t.Errorf("metadata endpoint: expected error code 404, got %d", metaErr.Error.Code)
+	// Rejected uploads don't create objects
+	if obj := s.GetObject("test-bucket", "b.txt"); obj != nil {
+		t.Error("expected upload with wrong checksum not to create an object")
 	}
+}
 
-	// Test 2: Media download endpoint (with alt=media) should return same 404
-	mediaReq := httptest.NewRequest(http.MethodGet, "/storage/v1/b/cloudhaven-tfstate/o/default.tfstate?alt=media", nil)
-	mediaRR := httptest.NewRecorder()
-	h.GetObject(mediaRR, mediaReq)
+func TestStorage_InsertObject_MaxObjectSize(t *testing.T) {
+	h, s := setupTestStorage()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+	s.SetMaxObjectSize(5)
 
-	if mediaRR.Code != http.StatusNotFound {
-		t.Errorf("media endpoint: expected status %d, got %d", http.StatusNotFound, mediaRR.Code)
+	multipart := func(content string) string {
+		return "--b\r\nContent-Type: application/json\r\n\r\n{\"name\":\"m.txt\"}\r\n" +
+			"--b\r\nContent-Type: text/plain\r\n\r\n" + content + "\r\n--b--\r\n"
 	}
 
-	var mediaErr storage.APIError
-	if err := json.NewDecoder(mediaRR.Body).Decode(&mediaErr); err != nil {
-		t.Fatalf("media endpoint: failed to decode error response: %v", err)
+	tests := []struct {
+		name           string
+		query          string
+		body           string
+		multipart      bool
+		unknownLength  bool
+		expectedStatus int
+	}{
+		{"at the limit", "uploadType=media&name=a.txt", "hello", false, false, http.StatusOK},
+		{"over the limit", "uploadType=media&name=b.txt", "hello!", false, false, http.StatusRequestEntityTooLarge},
+		{"over the limit without content length", "uploadType=media&name=c.txt", "hello!", false, true, http.StatusRequestEntityTooLarge},
+		{"multipart at the limit", "uploadType=multipart", multipart("hello"), true, false, http.StatusOK},
+		{"multipart over the limit", "uploadType=multipart", multipart("hello!"), true, false, http.StatusRequestEntityTooLarge},
 	}
 
-	if mediaErr.Error.Code != 404 {
-		t.Errorf("media endpoint: expected error code 404, got %d", mediaErr.Error.Code)
-	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/upload/storage/v1/b/test-bucket/o?"+tt.query, strings.NewReader(tt.body))
+			req.SetPathValue("bucket", "test-bucket")
+			if tt.multipart {
+				req.Header.Set("Content-Type", "multipart/related; boundary=b")
+			}
+			if tt.unknownLength {
+				req.ContentLength = -1
+			}
+			rr := httptest.NewRecorder()
 
-	// Both should have the same error message format
-	expectedMsg := "No such object: cloudhaven-tfstate/default.tfstate"
-	if metaErr.Error.Message != expectedMsg {
-		t.Errorf("metadata endpoint: expected message '%s', got '%s'", expectedMsg, metaErr.Error.Message)
+			h.InsertObject(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if tt.expectedStatus == http.StatusRequestEntityTooLarge && !strings.Contains(rr.Body.String(), "entityTooLarge") {
+				t.Errorf("expected reason entityTooLarge, got %s", rr.Body.String())
+			}
+		})
 	}
-	if mediaErr.Error.Message != expectedMsg {
-		t.Errorf("media endpoint: expected message '%s', got '%s'", expectedMsg, mediaErr.Error.Message)
+
+	if obj := s.GetObject("test-bucket", "c.txt"); obj != nil {
+		t.Error("expected oversized upload not to create an object")
 	}
 }
 
-// TestStorage_GetObject_BucketNotFound verifies 404 when bucket doesn't exist
-func TestStorage_GetObject_BucketNotFound(t *testing.T) {
-	h, _ := setupTestStorage()
+func TestStorage_ResumableUpload_MaxObjectSize(t *testing.T) {
+	h, s := setupTestStorage()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+	s.SetMaxObjectSize(5)
 
-	req := httptest.NewRequest(http.MethodGet, "/storage/v1/b/non-existent-bucket/o/test.txt", nil)
+	// A declared length over the limit is rejected when the upload starts
+	req := httptest.NewRequest(http.MethodPost, "/upload/storage/v1/b/test-bucket/o?uploadType=resumable",
+		strings.NewReader(`{"name":"big.bin"}`))
+	req.Header.Set("X-Upload-Content-Length", "10")
 	rr := httptest.NewRecorder()
+	req.SetPathValue("bucket", "test-bucket")
+	h.InsertObject(rr, req)
+	if rr.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusRequestEntityTooLarge, rr.Code, rr.Body.String())
+	}
 
-	h.GetObject(rr, req)
-
-	if rr.Code != http.StatusNotFound {
-		t.Errorf("expected status %d, got %d", http.StatusNotFound, rr.Code)
+	// Without a declared length, the chunk that exceeds the limit is rejected
+	req = httptest.NewRequest(http.MethodPost, "/upload/storage/v1/b/test-bucket/o?uploadType=resumable",
+		strings.NewReader(`{"name":"big.bin"}`))
+	rr = httptest.NewRecorder()
+	req.SetPathValue("bucket", "test-bucket")
+	h.InsertObject(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
 	}
+	uploadPath := strings.TrimPrefix(rr.Header().Get("Location"), "http://example.com")
 
-	var errResp storage.APIError
-	if err := json.NewDecoder(rr.Body).Decode(&errResp); err != nil {
-		t.Fatalf("failed to decode error response: %v", err)
+	steps := []struct {
+		name           string
+		contentRange   string
+		body           string
+		expectedStatus int
+	}{
+		{"first chunk", "bytes 0-2/*", "012", http.StatusPermanentRedirect},
+		{"chunk over the limit", "bytes 3-5/*", "345", http.StatusRequestEntityTooLarge},
+		{"upload still resumable", "bytes 3-4/5", "34", http.StatusOK},
 	}
 
-	if errResp.Error.Code != 404 {
-		t.Errorf("expected error code 404, got %d", errResp.Error.Code)
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, uploadPath, strings.NewReader(step.body))
+			req.SetPathValue("bucket", "test-bucket")
+			req.Header.Set("Content-Range", step.contentRange)
+			rr := httptest.NewRecorder()
+			h.ResumeUpload(rr, req)
+
+			if rr.Code != step.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", step.expectedStatus, rr.Code, rr.Body.String())
+			}
+		})
 	}
 
-	// Should mention bucket not found
-	if !strings.Contains(errResp.Error.Message, "non-existent-bucket") {
-		t.Errorf("expected message to contain bucket name, got '%s'", errResp.Error.Message)
+	if content := s.GetObjectContent("test-bucket", "big.bin"); string(content) != "01234" {
+		t.Errorf("expected content '01234', got '%s'", string(content))
 	}
 }
 
-func TestStorage_GetObject_MediaDownload(t *testing.T) {
+func TestStorage_InsertObject_HeaderMetadata(t *testing.T) {
 	h, s := setupTestStorage()
 	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
-	content := []byte("Hello, World!")
-	_, _ = s.CreateObject("test-bucket", "test.txt", "text/plain", content, nil)
 
-	req := httptest.NewRequest(http.MethodGet, "/storage/v1/b/test-bucket/o/test.txt?alt=media", nil)
+	req := httptest.NewRequest(http.MethodPost, "/upload/storage/v1/b/test-bucket/o?uploadType=media&name=report.csv", strings.NewReader("a,b"))
+	req.SetPathValue("bucket", "test-bucket")
+	req.Header.Set("Content-Type", "text/csv")
+	req.Header.Set("X-Goog-Meta-Owner", "team-a")
+	req.Header.Set("Cache-Control", "no-store")
+	req.Header.Set("Content-Disposition", `attachment; filename="report.csv"`)
+	req.Header.Set("Content-Language", "en")
+	req.Header.Set("X-Goog-Custom-Time", "2024-03-01T12:00:00Z")
 	rr := httptest.NewRecorder()
 
-	h.GetObject(rr, req)
+	h.InsertObject(rr, req)
 
 	if rr.Code != http.StatusOK {
-		t.Errorf("expected status %d, got %d", http.StatusOK, rr.Code)
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
 	}
-
-	if rr.Header().Get("Content-Type") != "text/plain" {
-		t.Errorf("expected Content-Type 'text/plain', got '%s'", rr.Header().Get("Content-Type"))
+	var obj storage.Object
+	if err := json.NewDecoder(rr.Body).Decode(&obj); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if obj.Metadata["owner"] != "team-a" {
+		t.Errorf("expected metadata owner 'team-a', got %v", obj.Metadata)
+	}
+	if obj.CacheControl != "no-store" || obj.ContentDisposition != `attachment; filename="report.csv"` || obj.ContentLanguage != "en" {
+		t.Errorf("expected content headers in object metadata, got %+v", obj)
+	}
+	if obj.CustomTime == nil || !obj.CustomTime.Equal(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected customTime 2024-03-01T12:00:00Z, got %v", obj.CustomTime)
 	}
 
-	// Verify Content-Length is set correctly as a numeric string
-	expectedLen := "13" // len("Hello, World!") = 13
-	if rr.Header().Get("Content-Length") != expectedLen {
-		t.Errorf("expected Content-Length '%s', got '%s'", expectedLen, rr.Header().Get("Content-Length"))
+	// The attributes are served with the content
+	req = httptest.NewRequest(http.MethodGet, "/storage/v1/b/test-bucket/o/report.csv?alt=media", nil)
+	req.SetPathValue("bucket", "test-bucket")
+	req.SetPathValue("object", "report.csv")
+	rr = httptest.NewRecorder()
+	h.GetObject(rr, req)
+
+	expectedHeaders := map[string]string{
+		"Cache-Control":       "no-store",
+		"Content-Disposition": `attachment; filename="report.csv"`,
+		"Content-Language":    "en",
+		"X-Goog-Custom-Time":  "2024-03-01T12:00:00Z",
+		"X-Goog-Meta-Owner":   "team-a",
+	}
+	for header, expected := range expectedHeaders {
+		if got := rr.Header().Get(header); got != expected {
+			t.Errorf("expected %s %q, got %q", header, expected, got)
+		}
 	}
 
-	if rr.Body.String() != string(content) {
-		t.Errorf("expected body '%s', got '%s'", string(content), rr.Body.String())
+	// Invalid custom times are rejected
+	req = httptest.NewRequest(http.MethodPost, "/upload/storage/v1/b/test-bucket/o?uploadType=media&name=bad.csv", strings.NewReader("a,b"))
+	req.SetPathValue("bucket", "test-bucket")
+	req.Header.Set("X-Goog-Custom-Time", "yesterday")
+	rr = httptest.NewRecorder()
+	h.InsertObject(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d: %s", http.StatusBadRequest, rr.Code, rr.Body.String())
 	}
 }
 
-func TestStorage_DownloadObject(t *testing.T) {
+func TestStorage_ResumableUpload_Checksum(t *testing.T) {
 	h, s := setupTestStorage()
 	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
-	content := []byte("Hello, World!")
-	_, _ = s.CreateObject("test-bucket", "test.txt", "text/plain", content, nil)
 
-	req := httptest.NewRequest(http.MethodGet, "/download/storage/v1/b/test-bucket/o/test.txt", nil)
+	req := httptest.NewRequest(http.MethodPost, "/upload/storage/v1/b/test-bucket/o?uploadType=resumable",
+		strings.NewReader(`{"name":"big.bin","md5Hash":"AAAAAAAAAAAAAAAAAAAAAA=="}`))
 	rr := httptest.NewRecorder()
+	req.SetPathValue("bucket", "test-bucket")
+	h.InsertObject(rr, req)
+	uploadPath := strings.TrimPrefix(rr.Header().Get("Location"), "http://example.com")
 
-	h.DownloadObject(rr, req)
+	req = httptest.NewRequest(http.MethodPut, uploadPath, strings.NewReader("hello"))
+	req.SetPathValue("bucket", "test-bucket")
+	rr = httptest.NewRecorder()
+	h.ResumeUpload(rr, req)
 
-	if rr.Code != http.StatusOK {
-		t.Errorf("expected status %d, got %d", http.StatusOK, rr.Code)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusBadRequest, rr.Code, rr.Body.String())
 	}
-
-	if rr.Body.String() != string(content) {
-		t.Errorf("expected body '%s', got '%s'", string(content), rr.Body.String())
+	if obj := s.GetObject("test-bucket", "big.bin"); obj != nil {
+		t.Error("expected upload with wrong checksum not to create an object")
 	}
 }
 
-func TestStorage_UpdateObject(t *testing.T) {
+func TestStorage_ResumableUpload(t *testing.T) {
 	h, s := setupTestStorage()
 	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
-	_, _ = s.CreateObject("test-bucket", "test.txt", "text/plain", []byte("Hello"), nil)
 
-	body := `{"metadata": {"key": "value"}}`
-	req := httptest.NewRequest(http.MethodPut, "/storage/v1/b/test-bucket/o/test.txt", strings.NewReader(body))
-	req.Header.Set("Content-Type", "application/json")
+	// Start the upload
+	req := httptest.NewRequest(http.MethodPost, "/upload/storage/v1/b/test-bucket/o?uploadType=resumable",
+		strings.NewReader(`{"name":"big.bin","metadata":{"key":"value"}}`))
+	req.Header.Set("X-Upload-Content-Type", "application/x-test")
 	rr := httptest.NewRecorder()
-
-	h.UpdateObject(rr, req)
+	req.SetPathValue("bucket", "test-bucket")
+	h.InsertObject(rr, req)
 
 	if rr.Code != http.StatusOK {
-		t.Errorf("expected status %d, got %d", http.StatusOK, rr.Code)
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	location := rr.Header().Get("Location")
+	if !strings.HasPrefix(location, "http://example.com/upload/storage/v1/b/test-bucket/o?") || !strings.Contains(location, "upload_id=") {
+		t.Fatalf("unexpected upload URL: %s", location)
 	}
+	uploadPath := strings.TrimPrefix(location, "http://example.com")
 
-	var obj storage.Object
-	if err := json.NewDecoder(rr.Body).Decode(&obj); err != nil {
-		t.Fatalf("failed to decode response: %v", err)
+	steps := []struct {
+		name           string
+		contentRange   string
+		body           string
+		expectedStatus int
+		expectedRange  string
+	}{
+		{"first chunk", "bytes 0-4/*", "01234", http.StatusPermanentRedirect, "bytes=0-4"},
+		{"status query", "bytes */*", "", http.StatusPermanentRedirect, "bytes=0-4"},
+		{"chunk at wrong offset", "bytes 0-4/*", "01234", http.StatusBadRequest, ""},
+		{"invalid range", "bytes 9-5/*", "", http.StatusBadRequest, ""},
+		{"short chunk", "bytes 5-9/10", "567", http.StatusBadRequest, ""},
+		{"long chunk", "bytes 5-9/10", "567890", http.StatusBadRequest, ""},
+		{"status after rejected chunks", "bytes */10", "", http.StatusPermanentRedirect, "bytes=0-4"},
+		{"final chunk", "bytes 5-9/10", "56789", http.StatusOK, ""},
+		{"completed upload", "bytes */10", "", http.StatusNotFound, ""},
+	}
+
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, uploadPath, strings.NewReader(step.body))
+			req.SetPathValue("bucket", "test-bucket")
+			req.Header.Set("Content-Range", step.contentRange)
+			rr := httptest.NewRecorder()
+			h.ResumeUpload(rr, req)
+
+			if rr.Code != step.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", step.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if got := rr.Header().Get("Range"); got != step.expectedRange {
+				t.Errorf("expected Range %q, got %q", step.expectedRange, got)
+			}
+		})
 	}
 
-	if obj.Metadata["key"] != "value" {
-		t.Error("metadata not updated correctly")
+	obj := s.GetObject("test-bucket", "big.bin")
+	if obj == nil {
+		t.Fatal("expected object to be created")
+	}
+	if obj.ContentType != "application/x-test" || obj.Metadata["key"] != "value" || obj.Size != 10 {
+		t.Errorf("unexpected object: %+v", obj)
+	}
+	if content := s.GetObjectContent("test-bucket", "big.bin"); string(content) != "0123456789" {
+		t.Errorf("expected content '0123456789', got '%s'", string(content))
 	}
 }
 
-func TestStorage_UpdateObject_NotFound(t *testing.T) {
+func TestStorage_ResumableUpload_SingleRequestAndCancel(t *testing.T) {
+	h, s := setupTestStorage()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+
+	start := func(name string) string {
+		req := httptest.NewRequest(http.MethodPost, "/upload/storage/v1/b/test-bucket/o?uploadType=resumable&name="+name, nil)
+		req.SetPathValue("bucket", "test-bucket")
+		rr := httptest.NewRecorder()
+		h.InsertObject(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+		}
+		return strings.TrimPrefix(rr.Header().Get("Location"), "http://example.com")
+	}
+
+	// Without a Content-Range, the body is the whole content
+	req := httptest.NewRequest(http.MethodPut, start("small.txt"), strings.NewReader("small"))
+	req.SetPathValue("bucket", "test-bucket")
+	rr := httptest.NewRecorder()
+	h.ResumeUpload(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	if content := s.GetObjectContent("test-bucket", "small.txt"); string(content) != "small" {
+		t.Errorf("expected content 'small', got '%s'", string(content))
+	}
+
+	// Cancelled uploads are answered with 499 and are gone afterwards
+	uploadPath := start("cancelled.txt")
+	req = httptest.NewRequest(http.MethodDelete, uploadPath, nil)
+	req.SetPathValue("bucket", "test-bucket")
+	rr = httptest.NewRecorder()
+	h.CancelUpload(rr, req)
+	if rr.Code != 499 {
+		t.Errorf("expected status 499, got %d", rr.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPut, uploadPath, strings.NewReader("data"))
+	req.SetPathValue("bucket", "test-bucket")
+	rr = httptest.NewRecorder()
+	h.ResumeUpload(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d", http.StatusNotFound, rr.Code)
+	}
+}
+
+func TestParseUploadContentRange(t *testing.T) {
+	tests := []struct {
+		header                    string
+		wantStart, wantEnd, total int64
+		wantOK                    bool
+	}{
+		{"", 0, -1, -1, true},
+		{"bytes 0-99/*", 0, 99, -1, true},
+		{"bytes 100-199/200", 100, 199, 200, true},
+		{"bytes */200", -1, -1, 200, true},
+		{"bytes */*", -1, -1, -1, true},
+		{"bytes 5-1/*", 0, 0, 0, false},
+		{"items 0-1/2", 0, 0, 0, false},
+		{"bytes 0-1", 0, 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			start, end, total, ok := parseUploadContentRange(tt.header)
+			if ok != tt.wantOK {
+				t.Fatalf("parseUploadContentRange(%q) ok = %v, want %v", tt.header, ok, tt.wantOK)
+			}
+			if ok && (start != tt.wantStart || end != tt.wantEnd || total != tt.total) {
+				t.Errorf("parseUploadContentRange(%q) = %d, %d, %d, want %d, %d, %d", tt.header, start, end, total, tt.wantStart, tt.wantEnd, tt.total)
+			}
+		})
+	}
+}
+
+func TestStorage_InsertObject_KmsKeyName(t *testing.T) {
+	const bucketKey = "projects/p/locations/us/keyRings/ring/cryptoKeys/bucket-key"
+	const objectKey = "projects/p/locations/us/keyRings/ring/cryptoKeys/object-key"
+
+	tests := []struct {
+		name           string
+		query          string
+		resource       string
+		expectedStatus int
+		expectedKey    string
+	}{
+		{"bucket default", "", `{}`, http.StatusOK, bucketKey + "/cryptoKeyVersions/1"},
+		{"object resource", "", `{"kmsKeyName":"` + objectKey + `"}`, http.StatusOK, objectKey + "/cryptoKeyVersions/1"},
+		{"query parameter", "&kmsKeyName=" + objectKey + "/cryptoKeyVersions/3", `{}`, http.StatusOK, objectKey + "/cryptoKeyVersions/3"},
+		{"invalid key", "&kmsKeyName=my-key", `{}`, http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, s := setupTestStorage()
+			_, _ = s.CreateBucket(&storage.BucketInsertRequest{
+				Name:       "test-bucket",
+				Encryption: &storage.Encryption{DefaultKmsKeyName: bucketKey},
+			})
+
+			boundary := "boundary123"
+			body := "--" + boundary + "\r\n" +
+				"Content-Type: application/json\r\n\r\n" + tt.resource + "\r\n" +
+				"--" + boundary + "\r\n" +
+				"Content-Type: text/plain\r\n\r\n" + "secret" + "\r\n" +
+				"--" + boundary + "--\r\n"
+
+			req := httptest.NewRequest(http.MethodPost, "/upload/storage/v1/b/test-bucket/o?name=file.txt"+tt.query, strings.NewReader(body))
+			req.SetPathValue("bucket", "test-bucket")
+			req.Header.Set("Content-Type", "multipart/related; boundary="+boundary)
+			rr := httptest.NewRecorder()
+			h.InsertObject(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var obj storage.Object
+			if err := json.NewDecoder(rr.Body).Decode(&obj); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if obj.KmsKeyName != tt.expectedKey {
+				t.Errorf("expected kmsKeyName %s, got %s", tt.expectedKey, obj.KmsKeyName)
+			}
+		})
+	}
+}
+
+func TestStorage_CreateBucket_InvalidKmsKeyName(t *testing.T) {
+	h, _ := setupTestStorage()
+
+	body := `{"name":"test-bucket","encryption":{"defaultKmsKeyName":"projects/p/keyRings/ring"}}`
+	req := httptest.NewRequest(http.MethodPost, "/storage/v1/b", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	h.CreateBucket(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, rr.Code)
+	}
+}
+
+func TestIsValidKmsKeyName(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected bool
+	}{
+		{"projects/p/locations/us/keyRings/r/cryptoKeys/k", true},
+		{"projects/p/locations/us/keyRings/r/cryptoKeys/k/cryptoKeyVersions/1", true},
+		{"projects/p/locations/us/keyRings/r", false},
+		{"projects/p/locations/us/keyRings/r/cryptoKeys/", false},
+		{"projects/p/regions/us/keyRings/r/cryptoKeys/k", false},
+		{"my-key", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isValidKmsKeyName(tt.name); got != tt.expected {
+				t.Errorf("isValidKmsKeyName(%q) = %v, want %v", tt.name, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestStorage_GetObject(t *testing.T) {
+	h, s := setupTestStorage()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+	_, _ = s.CreateObject("test-bucket", "test.txt", "text/plain", []byte("Hello"), nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/storage/v1/b/test-bucket/o/test.txt", nil)
+	req.SetPathValue("bucket", "test-bucket")
+	req.SetPathValue("object", "test.txt")
+	rr := httptest.NewRecorder()
+
+	h.GetObject(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	var obj storage.Object
+	if err := json.NewDecoder(rr.Body).Decode(&obj); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if obj.Name != "test.txt" {
+		t.Errorf("expected name 'test.txt', got '%s'", obj.Name)
+	}
+}
+
+func TestStorage_GetObject_NotFound(t *testing.T) {
+	h, s := setupTestStorage()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+
+	req := httptest.NewRequest(http.MethodGet, "/storage/v1/b/test-bucket/o/non-existent", nil)
+	req.SetPathValue("bucket", "test-bucket")
+	req.SetPathValue("object", "non-existent")
+	rr := httptest.NewRecorder()
+
+	h.GetObject(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d", http.StatusNotFound, rr.Code)
+	}
+}
+
+// TestStorage_GetObject_NotFound_Consistent verifies that both metadata and content
+// endpoints return consistent 404 errors when an object doesn't exist.
+// This is critical for OpenTofu/Terraform's GCS backend which makes two separate
+// API calls: NewReader() with alt=media and Attrs() without alt=media.
+func TestStorage_GetObject_NotFound_Consistent(t *testing.T) {
+	h, s := setupTestStorage()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "cloudhaven-tfstate"})
+
+	// Test 1: Metadata endpoint (without alt=media) should return 404
+	metaReq := httptest.NewRequest(http.MethodGet, "/storage/v1/b/cloudhaven-tfstate/o/default.tfstate", nil)
+	metaReq.SetPathValue("bucket", "cloudhaven-tfstate")
+	metaReq.SetPathValue("object", "default.tfstate")
+	metaRR := httptest.NewRecorder()
+	h.GetObject(metaRR, metaReq)
+
+	if metaRR.Code != http.StatusNotFound {
+		t.Errorf("metadata endpoint: expected status %d, got %d", http.StatusNotFound, metaRR.Code)
+	}
+
+	var metaErr storage.APIError
+	if err := json.NewDecoder(metaRR.Body).Decode(&metaErr); err != nil {
+		t.Fatalf("metadata endpoint: failed to decode error response: %v", err)
+	}
+
+	if metaErr.Error.Code != 404 {
+		t.Errorf("metadata endpoint: expected error code 404, got %d", metaErr.Error.Code)
+	}
+
+	// Test 2: Media download endpoint (with alt=media) should return same 404
+	mediaReq := httptest.NewRequest(http.MethodGet, "/storage/v1/b/cloudhaven-tfstate/o/default.tfstate?alt=media", nil)
+	mediaReq.SetPathValue("bucket", "cloudhaven-tfstate")
+	mediaReq.SetPathValue("object", "default.tfstate")
+	mediaRR := httptest.NewRecorder()
+	h.GetObject(mediaRR, mediaReq)
+
+	if mediaRR.Code != http.StatusNotFound {
+		t.Errorf("media endpoint: expected status %d, got %d", http.StatusNotFound, mediaRR.Code)
+	}
+
+	var mediaErr storage.APIError
+	if err := json.NewDecoder(mediaRR.Body).Decode(&mediaErr); err != nil {
+		t.Fatalf("media endpoint: failed to decode error response: %v", err)
+	}
+
+	if mediaErr.Error.Code != 404 {
+		t.Errorf("media endpoint: expected error code 404, got %d", mediaErr.Error.Code)
+	}
+
+	// Both should have the same error message format
+	expectedMsg := "No such object: cloudhaven-tfstate/default.tfstate"
+	if metaErr.Error.Message != expectedMsg {
+		t.Errorf("metadata endpoint: expected message '%s', got '%s'", expectedMsg, metaErr.Error.Message)
+	}
+	if mediaErr.Error.Message != expectedMsg {
+		t.Errorf("media endpoint: expected message '%s', got '%s'", expectedMsg, mediaErr.Error.Message)
+	}
+}
+
+// TestStorage_GetObject_BucketNotFound verifies 404 when bucket doesn't exist
+func TestStorage_GetObject_BucketNotFound(t *testing.T) {
+	h, _ := setupTestStorage()
+
+	req := httptest.NewRequest(http.MethodGet, "/storage/v1/b/non-existent-bucket/o/test.txt", nil)
+	req.SetPathValue("bucket", "non-existent-bucket")
+	req.SetPathValue("object", "test.txt")
+	rr := httptest.NewRecorder()
+
+	h.GetObject(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d", http.StatusNotFound, rr.Code)
+	}
+
+	var errResp storage.APIError
+	if err := json.NewDecoder(rr.Body).Decode(&errResp); err != nil {
+		t.Fatalf("failed to decode error response: %v", err)
+	}
+
+	if errResp.Error.Code != 404 {
+		t.Errorf("expected error code 404, got %d", errResp.Error.Code)
+	}
+
+	// Should mention bucket not found
+	if !strings.Contains(errResp.Error.Message, "non-existent-bucket") {
+		t.Errorf("expected message to contain bucket name, got '%s'", errResp.Error.Message)
+	}
+}
+
+func TestStorage_GetObject_MediaDownload(t *testing.T) {
+	h, s := setupTestStorage()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+	content := []byte("Hello, World!")
+	_, _ = s.CreateObject("test-bucket", "test.txt", "text/plain", content, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/storage/v1/b/test-bucket/o/test.txt?alt=media", nil)
+	req.SetPathValue("bucket", "test-bucket")
+	req.SetPathValue("object", "test.txt")
+	rr := httptest.NewRecorder()
+
+	h.GetObject(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	if rr.Header().Get("Content-Type") != "text/plain" {
+		t.Errorf("expected Content-Type 'text/plain', got '%s'", rr.Header().Get("Content-Type"))
+	}
+
+	// Verify Content-Length is set correctly as a numeric string
+	expectedLen := "13" // len("Hello, World!") = 13
+	if rr.Header().Get("Content-Length") != expectedLen {
+		t.Errorf("expected Content-Length '%s', got '%s'", expectedLen, rr.Header().Get("Content-Length"))
+	}
+
+	if rr.Body.String() != string(content) {
+		t.Errorf("expected body '%s', got '%s'", string(content), rr.Body.String())
+	}
+}
+
+func TestStorage_GetObject_MediaDownloadRange(t *testing.T) {
+	h, s := setupTestStorage()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+	_, _ = s.CreateObject("test-bucket", "test.txt", "text/plain", []byte("Hello, World!"), nil)
+
+	tests := []struct {
+		name         string
+		rangeHeader  string
+		status       int
+		body         string
+		contentRange string
+	}{
+		{"partial", "bytes=0-4", http.StatusPartialContent, "Hello", "bytes 0-4/13"},
+		{"open ended", "bytes=7-", http.StatusPartialContent, "World!", "bytes 7-12/13"},
+		{"suffix", "bytes=-6", http.StatusPartialContent, "World!", "bytes 7-12/13"},
+		{"unsatisfiable", "bytes=20-30", http.StatusRequestedRangeNotSatisfiable, "", "bytes */13"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/storage/v1/b/test-bucket/o/test.txt?alt=media", nil)
+			req.SetPathValue("bucket", "test-bucket")
+			req.SetPathValue("object", "test.txt")
+			req.Header.Set("Range", tt.rangeHeader)
+			rr := httptest.NewRecorder()
+
+			h.GetObject(rr, req)
+
+			if rr.Code != tt.status {
+				t.Fatalf("expected status %d, got %d: %s", tt.status, rr.Code, rr.Body.String())
+			}
+			if got := rr.Header().Get("Content-Range"); got != tt.contentRange {
+				t.Errorf("expected Content-Range %q, got %q", tt.contentRange, got)
+			}
+			if tt.status == http.StatusPartialContent && rr.Body.String() != tt.body {
+				t.Errorf("expected body %q, got %q", tt.body, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestStorage_GetObject_ConditionalRequests(t *testing.T) {
+	h, s := setupTestStorage()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+	obj, _ := s.CreateObject("test-bucket", "test.txt", "text/plain", []byte("Hello"), nil)
+
+	tests := []struct {
+		name       string
+		path       string
+		header     string
+		value      string
+		wantStatus int
+	}{
+		{"metadata matching etag", "/storage/v1/b/test-bucket/o/test.txt", "If-None-Match", `"` + obj.Etag + `"`, http.StatusNotModified},
+		{"metadata other etag", "/storage/v1/b/test-bucket/o/test.txt", "If-None-Match", `"other"`, http.StatusOK},
+		{"media matching etag", "/storage/v1/b/test-bucket/o/test.txt?alt=media", "If-None-Match", obj.Etag, http.StatusNotModified},
+		{"media wildcard", "/storage/v1/b/test-bucket/o/test.txt?alt=media", "If-None-Match", "*", http.StatusNotModified},
+		{"media not modified since", "/storage/v1/b/test-bucket/o/test.txt?alt=media", "If-Modified-Since", obj.Updated.Add(time.Hour).Format(http.TimeFormat), http.StatusNotModified},
+		{"media modified since", "/storage/v1/b/test-bucket/o/test.txt?alt=media", "If-Modified-Since", obj.Updated.Add(-time.Hour).Format(http.TimeFormat), http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set(tt.header, tt.value)
+			rr := httptest.NewRecorder()
+
+			serveRoute("GET /storage/v1/b/{bucket}/o/{object...}", h.GetObject, rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, rr.Code)
+			}
+			if rr.Header().Get("ETag") != obj.Etag {
+				t.Errorf("expected ETag header %s, got %s", obj.Etag, rr.Header().Get("ETag"))
+			}
+			if rr.Header().Get("Last-Modified") == "" {
+				t.Error("expected Last-Modified header")
+			}
+			if tt.wantStatus == http.StatusNotModified && rr.Body.Len() != 0 {
+				t.Error("expected empty body for 304 response")
+			}
+		})
+	}
+}
+
+func TestStorage_GetObject_MetadataHeaders(t *testing.T) {
+	h, s := setupTestStorage()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+	obj, _ := s.CreateObjectWithOptions("test-bucket", "logo.svg", "image/svg+xml", strings.NewReader("<svg/>"),
+		map[string]string{"owner": "team-a", "not a header": "x"},
+		store.ObjectOptions{CacheControl: "public, max-age=3600", ContentDisposition: "inline", ContentLanguage: "de"})
+
+	expectedHeaders := map[string]string{
+		"Cache-Control":       "public, max-age=3600",
+		"Content-Disposition": "inline",
+		"Content-Language":    "de",
+		"X-Goog-Meta-Owner":   "team-a",
+	}
+	for _, ifNoneMatch := range []string{"", obj.Etag} {
+		req := httptest.NewRequest(http.MethodGet, "/storage/v1/b/test-bucket/o/logo.svg?alt=media", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rr := httptest.NewRecorder()
+		serveRoute("GET /storage/v1/b/{bucket}/o/{object...}", h.GetObject, rr, req)
+
+		// Revalidations carry the headers too, so caches in front of the bucket can refresh their copy
+		for header, expected := range expectedHeaders {
+			if got := rr.Header().Get(header); got != expected {
+				t.Errorf("If-None-Match %q: expected %s %q, got %q", ifNoneMatch, header, expected, got)
+			}
+		}
+		if rr.Header().Get("Expires") == "" {
+			t.Errorf("If-None-Match %q: expected Expires header", ifNoneMatch)
+		}
+		for header := range rr.Header() {
+			if strings.Contains(header, " ") {
+				t.Errorf("If-None-Match %q: unexpected header %q for a key that isn't a header name", ifNoneMatch, header)
+			}
+		}
+	}
+}
+
+func TestStorage_DownloadObject(t *testing.T) {
+	h, s := setupTestStorage()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+	content := []byte("Hello, World!")
+	_, _ = s.CreateObject("test-bucket", "test.txt", "text/plain", content, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/download/storage/v1/b/test-bucket/o/test.txt", nil)
+	req.SetPathValue("bucket", "test-bucket")
+	req.SetPathValue("object", "test.txt")
+	rr := httptest.NewRecorder()
+
+	h.DownloadObject(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	if rr.Body.String() != string(content) {
+		t.Errorf("expected body '%s', got '%s'", string(content), rr.Body.String())
+	}
+}
+
+func TestStorage_UpdateObject(t *testing.T) {
+	h, s := setupTestStorage()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+	_, _ = s.CreateObject("test-bucket", "test.txt", "text/plain", []byte("Hello"), nil)
+
+	body := `{"metadata": {"key": "value"}}`
+	req := httptest.NewRequest(http.MethodPut, "/storage/v1/b/test-bucket/o/test.txt", strings.NewReader(body))
+	req.SetPathValue("bucket", "test-bucket")
+	req.SetPathValue("object", "test.txt")
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	h.UpdateObject(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	var obj storage.Object
+	if err := json.NewDecoder(rr.Body).Decode(&obj); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if obj.Metadata["key"] != "value" {
+		t.Error("metadata not updated correctly")
+	}
+}
+
+func TestStorage_UpdateObject_NotFound(t *testing.T) {
 	h, s := setupTestStorage()
 	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
 
 	body := `{"metadata": {}}`
 	req := httptest.NewRequest(http.MethodPut, "/storage/v1/b/test-bucket/o/non-existent", strings.NewReader(body))
+	req.SetPathValue("bucket", "test-bucket")
+	req.SetPathValue("object", "non-existent")
 	req.Header.Set("Content-Type", "application/json")
 	rr := httptest.NewRecorder()
 
@@ -625,12 +1491,125 @@ func TestStorage_UpdateObject_NotFound(t *testing.T) {
 	}
 }
 
+func TestStorage_PatchObject(t *testing.T) {
+	h, s := setupTestStorage()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+	_, _ = s.CreateObjectWithOptions("test-bucket", "test.txt", "text/plain", strings.NewReader("Hello"),
+		map[string]string{"owner": "team-a", "stage": "draft"}, store.ObjectOptions{CacheControl: "no-store", ContentLanguage: "en"})
+
+	tests := []struct {
+		name           string
+		object         string
+		body           string
+		expectedStatus int
+		check          func(t *testing.T, obj *storage.Object)
+	}{
+		{
+			name:           "merges metadata",
+			object:         "test.txt",
+			body:           `{"metadata": {"stage": null, "reviewer": "b"}}`,
+			expectedStatus: http.StatusOK,
+			check: func(t *testing.T, obj *storage.Object) {
+				expected := map[string]string{"owner": "team-a", "reviewer": "b"}
+				if !reflect.DeepEqual(obj.Metadata, expected) {
+					t.Errorf("expected metadata %v, got %v", expected, obj.Metadata)
+				}
+				if obj.ContentType != "text/plain" || obj.CacheControl != "no-store" {
+					t.Errorf("expected other fields to be unchanged, got %+v", obj)
+				}
+			},
+		},
+		{
+			name:           "clears null fields",
+			object:         "test.txt",
+			body:           `{"cacheControl": null, "contentLanguage": "de", "metadata": null}`,
+			expectedStatus: http.StatusOK,
+			check: func(t *testing.T, obj *storage.Object) {
+				if obj.CacheControl != "" || obj.Metadata != nil {
+					t.Errorf("expected cacheControl and metadata to be cleared, got %+v", obj)
+				}
+				if obj.ContentLanguage != "de" {
+					t.Errorf("expected contentLanguage 'de', got '%s'", obj.ContentLanguage)
+				}
+			},
+		},
+		{
+			name:           "missing object",
+			object:         "non-existent",
+			body:           `{}`,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "invalid body",
+			object:         "test.txt",
+			body:           `[]`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPatch, "/storage/v1/b/test-bucket/o/"+tt.object, strings.NewReader(tt.body))
+			req.SetPathValue("bucket", "test-bucket")
+			req.SetPathValue("object", tt.object)
+			rr := httptest.NewRecorder()
+
+			h.PatchObject(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if tt.check != nil {
+				var obj storage.Object
+				if err := json.NewDecoder(rr.Body).Decode(&obj); err != nil {
+					t.Fatalf("failed to decode response: %v", err)
+				}
+				tt.check(t, &obj)
+			}
+		})
+	}
+}
+
+func TestStorage_DeleteObject_Hold(t *testing.T) {
+	h, s := setupTestStorage()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+	_, _ = s.CreateObject("test-bucket", "record.json", "application/json", []byte("{}"), nil)
+
+	patch := func(body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		serveRoute("PATCH /storage/v1/b/{bucket}/o/{object...}", h.PatchObject, rr,
+			httptest.NewRequest(http.MethodPatch, "/storage/v1/b/test-bucket/o/record.json", strings.NewReader(body)))
+		return rr
+	}
+	deleteObject := func() *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		serveRoute("DELETE /storage/v1/b/{bucket}/o/{object...}", h.DeleteObject, rr,
+			httptest.NewRequest(http.MethodDelete, "/storage/v1/b/test-bucket/o/record.json", nil))
+		return rr
+	}
+
+	if rr := patch(`{"temporaryHold": true}`); rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"temporaryHold":true`) {
+		t.Fatalf("expected the temporary hold to be set, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := deleteObject(); rr.Code != http.StatusForbidden || !strings.Contains(rr.Body.String(), "retentionPolicyNotMet") {
+		t.Errorf("expected status %d deleting a held object, got %d: %s", http.StatusForbidden, rr.Code, rr.Body.String())
+	}
+	if rr := patch(`{"temporaryHold": false}`); rr.Code != http.StatusOK {
+		t.Fatalf("expected the temporary hold to be released, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := deleteObject(); rr.Code != http.StatusNoContent {
+		t.Errorf("expected status %d once the hold is released, got %d: %s", http.StatusNoContent, rr.Code, rr.Body.String())
+	}
+}
+
 func TestStorage_DeleteObject(t *testing.T) {
 	h, s := setupTestStorage()
 	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
 	_, _ = s.CreateObject("test-bucket", "test.txt", "text/plain", []byte("Hello"), nil)
 
 	req := httptest.NewRequest(http.MethodDelete, "/storage/v1/b/test-bucket/o/test.txt", nil)
+	req.SetPathValue("bucket", "test-bucket")
+	req.SetPathValue("object", "test.txt")
 	rr := httptest.NewRecorder()
 
 	h.DeleteObject(rr, req)
@@ -650,6 +1629,8 @@ func TestStorage_DeleteObject_NotFound(t *testing.T) {
 	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
 
 	req := httptest.NewRequest(http.MethodDelete, "/storage/v1/b/test-bucket/o/non-existent", nil)
+	req.SetPathValue("bucket", "test-bucket")
+	req.SetPathValue("object", "non-existent")
 	rr := httptest.NewRecorder()
 
 	h.DeleteObject(rr, req)
@@ -659,6 +1640,119 @@ func TestStorage_DeleteObject_NotFound(t *testing.T) {
 	}
 }
 
+func TestStorage_RewriteObject(t *testing.T) {
+	h, s := setupTestStorage()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+	_, _ = s.CreateObject("test-bucket", "dir/file.txt", "text/plain", []byte("hello"), nil)
+
+	tests := []struct {
+		name           string
+		path           string
+		body           string
+		expectedStatus int
+		expectedClass  string
+	}{
+		{"change storage class", "/storage/v1/b/test-bucket/o/dir%2Ffile.txt/rewriteTo/b/test-bucket/o/dir%2Ffile.txt", `{"storageClass":"ARCHIVE"}`, http.StatusOK, "ARCHIVE"},
+		{"copy without body", "/storage/v1/b/test-bucket/o/dir%2Ffile.txt/rewriteTo/b/test-bucket/o/copy.txt", "", http.StatusOK, "STANDARD"},
+		{"invalid storage class", "/storage/v1/b/test-bucket/o/dir%2Ffile.txt/rewriteTo/b/test-bucket/o/copy.txt", `{"storageClass":"FROZEN"}`, http.StatusBadRequest, ""},
+		{"missing source", "/storage/v1/b/test-bucket/o/missing.txt/rewriteTo/b/test-bucket/o/copy.txt", "", http.StatusNotFound, ""},
+		{"failed precondition", "/storage/v1/b/test-bucket/o/dir%2Ffile.txt/rewriteTo/b/test-bucket/o/copy.txt?ifGenerationMatch=0", "", http.StatusPreconditionFailed, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+
+			serveRoute("POST /storage/v1/b/{bucket}/o/{object...}", h.ObjectAction, rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var resp storage.RewriteResponse
+			if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Kind != "storage#rewriteResponse" || !resp.Done || resp.TotalBytesRewritten != 5 || resp.Resource == nil ||
+				resp.Resource.StorageClass != tt.expectedClass {
+				t.Errorf("expected completed rewrite to %s, got %+v", tt.expectedClass, resp)
+			}
+		})
+	}
+}
+
+func TestStorage_SoftDeleteAndRestore(t *testing.T) {
+	h, s := setupTestStorage()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{
+		Name:             "test-bucket",
+		SoftDeletePolicy: &storage.SoftDeletePolicy{RetentionDurationSeconds: 3600},
+	})
+	obj, _ := s.CreateObject("test-bucket", "test.txt", "text/plain", []byte("Hello"), nil)
+	_ = s.DeleteObject("test-bucket", "test.txt")
+
+	// List soft-deleted objects
+	req := httptest.NewRequest(http.MethodGet, "/storage/v1/b/test-bucket/o?softDeleted=true", nil)
+	req.SetPathValue("bucket", "test-bucket")
+	rr := httptest.NewRecorder()
+	h.ListObjects(rr, req)
+
+	var list storage.ObjectList
+	if err := json.NewDecoder(rr.Body).Decode(&list); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(list.Items) != 1 {
+		t.Fatalf("expected 1 soft-deleted item, got %d", len(list.Items))
+	}
+
+	// Get soft-deleted object without generation
+	req = httptest.NewRequest(http.MethodGet, "/storage/v1/b/test-bucket/o/test.txt?softDeleted=true", nil)
+	req.SetPathValue("bucket", "test-bucket")
+	req.SetPathValue("object", "test.txt")
+	rr = httptest.NewRecorder()
+	h.GetObject(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, rr.Code)
+	}
+
+	// Get soft-deleted object with generation
+	generation := fmt.Sprintf("%d", obj.Generation)
+	req = httptest.NewRequest(http.MethodGet, "/storage/v1/b/test-bucket/o/test.txt?softDeleted=true&generation="+generation, nil)
+	req.SetPathValue("bucket", "test-bucket")
+	req.SetPathValue("object", "test.txt")
+	rr = httptest.NewRecorder()
+	h.GetObject(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	// Restore
+	req = httptest.NewRequest(http.MethodPost, "/storage/v1/b/test-bucket/o/test.txt/restore?generation="+generation, nil)
+	req.SetPathValue("bucket", "test-bucket")
+	req.SetPathValue("object", "test.txt/restore")
+	rr = httptest.NewRecorder()
+	h.ObjectAction(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	if s.GetObject("test-bucket", "test.txt") == nil {
+		t.Error("object should be live after restore")
+	}
+
+	// Restoring again returns 404
+	rr = httptest.NewRecorder()
+	req.SetPathValue("bucket", "test-bucket")
+	req.SetPathValue("object", "test.txt/restore")
+	h.ObjectAction(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d", http.StatusNotFound, rr.Code)
+	}
+}
+
 func TestStorage_ListObjects_WithPrefix(t *testing.T) {
 	h, s := setupTestStorage()
 	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
@@ -667,6 +1761,7 @@ func TestStorage_ListObjects_WithPrefix(t *testing.T) {
 	_, _ = s.CreateObject("test-bucket", "other/file3.txt", "text/plain", []byte("3"), nil)
 
 	req := httptest.NewRequest(http.MethodGet, "/storage/v1/b/test-bucket/o?prefix=folder/", nil)
+	req.SetPathValue("bucket", "test-bucket")
 	rr := httptest.NewRecorder()
 
 	h.ListObjects(rr, req)
@@ -685,6 +1780,42 @@ func TestStorage_ListObjects_WithPrefix(t *testing.T) {
 	}
 }
 
+func TestStorage_ListObjects_PartialResponse(t *testing.T) {
+	h, s := setupTestStorage()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+	_, _ = s.CreateObject("test-bucket", "a.txt", "text/plain", []byte("a"), nil)
+	_, _ = s.CreateObject("test-bucket", "b.txt", "text/plain", []byte("bb"), nil)
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"fields of items", "?fields=items(name,size),nextPageToken", http.StatusOK, `{"items":[{"name":"a.txt","size":"1"},{"name":"b.txt","size":"2"}]}` + "\n"},
+		{"pretty print", "?fields=kind&prettyPrint=true", http.StatusOK, "{\n  \"kind\": \"storage#objects\"\n}\n"},
+		{"compact print", "?fields=kind&prettyPrint=false", http.StatusOK, `{"kind":"storage#objects"}` + "\n"},
+		{"invalid fields", "?fields=items(name", http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/storage/v1/b/test-bucket/o"+tt.query, nil)
+			req.SetPathValue("bucket", "test-bucket")
+			rr := httptest.NewRecorder()
+
+			h.ListObjects(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if tt.expectedBody != "" && rr.Body.String() != tt.expectedBody {
+				t.Errorf("expected body %q, got %q", tt.expectedBody, rr.Body.String())
+			}
+		})
+	}
+}
+
 func TestStorage_ListObjects_WithDelimiter(t *testing.T) {
 	h, s := setupTestStorage()
 	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
@@ -693,6 +1824,7 @@ func TestStorage_ListObjects_WithDelimiter(t *testing.T) {
 	_, _ = s.CreateObject("test-bucket", "folder/file2.txt", "text/plain", []byte("2"), nil)
 
 	req := httptest.NewRequest(http.MethodGet, "/storage/v1/b/test-bucket/o?delimiter=/", nil)
+	req.SetPathValue("bucket", "test-bucket")
 	rr := httptest.NewRecorder()
 
 	h.ListObjects(rr, req)
@@ -717,45 +1849,297 @@ func TestStorage_ListObjects_WithDelimiter(t *testing.T) {
 	}
 }
 
-func TestExtractBucketName(t *testing.T) {
+func TestStorage_ListObjects_Filters(t *testing.T) {
+	h, s := setupTestStorage()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+	for _, name := range []string{"a.txt", "b.log", "c.txt", "logs/", "logs/1.log", "logs/2.txt"} {
+		_, _ = s.CreateObject("test-bucket", name, "text/plain", []byte(name), nil)
+	}
+
 	tests := []struct {
-		path   string
-		prefix string
-		want   string
+		name             string
+		query            string
+		expectedStatus   int
+		expectedItems    []string
+		expectedPrefixes []string
 	}{
-		{"/storage/v1/b/test-bucket", "/storage/v1/b/", "test-bucket"},
-		{"/storage/v1/b/my-bucket/o", "/storage/v1/b/", "my-bucket"},
-		{"/storage/v1/b/", "/storage/v1/b/", ""},
+		{"start offset", "startOffset=b", http.StatusOK, []string{"b.log", "c.txt", "logs/", "logs/1.log", "logs/2.txt"}, nil},
+		{"end offset", "endOffset=c", http.StatusOK, []string{"a.txt", "b.log"}, nil},
+		{"offset range", "startOffset=b&endOffset=logs/2", http.StatusOK, []string{"b.log", "c.txt", "logs/", "logs/1.log"}, nil},
+		{"match glob", "matchGlob=**.txt", http.StatusOK, []string{"a.txt", "c.txt", "logs/2.txt"}, nil},
+		{"match glob single level", "matchGlob=*.txt", http.StatusOK, []string{"a.txt", "c.txt"}, nil},
+		{"match glob with delimiter", "matchGlob=**.log&delimiter=/", http.StatusOK, []string{"b.log"}, []string{"logs/"}},
+		{"without trailing delimiter", "delimiter=/", http.StatusOK, []string{"a.txt", "b.log", "c.txt"}, []string{"logs/"}},
+		{"include trailing delimiter", "delimiter=/&includeTrailingDelimiter=true", http.StatusOK, []string{"a.txt", "b.log", "c.txt", "logs/"}, []string{"logs/"}},
+		{"include folders as prefixes", "delimiter=/&includeFoldersAsPrefixes=true", http.StatusOK, []string{"a.txt", "b.log", "c.txt"}, []string{"logs/"}},
+		{"include folders without slash delimiter", "includeFoldersAsPrefixes=true", http.StatusBadRequest, nil, nil},
+		{"invalid glob", "matchGlob=[abc", http.StatusBadRequest, nil, nil},
 	}
 
 	for _, tt := range tests {
-		t.Run(tt.path, func(t *testing.T) {
-			got := extractBucketName(tt.path, tt.prefix)
-			if got != tt.want {
-				t.Errorf("extractBucketName(%s, %s) = %s, want %s", tt.path, tt.prefix, got, tt.want)
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/storage/v1/b/test-bucket/o?"+tt.query, nil)
+			req.SetPathValue("bucket", "test-bucket")
+			rr := httptest.NewRecorder()
+			h.ListObjects(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if rr.Code != http.StatusOK {
+				return
+			}
+
+			var resp storage.ObjectList
+			if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			var names []string
+			for _, obj := range resp.Items {
+				names = append(names, obj.Name)
+			}
+			if strings.Join(names, ",") != strings.Join(tt.expectedItems, ",") {
+				t.Errorf("expected items %v, got %v", tt.expectedItems, names)
+			}
+			if strings.Join(resp.Prefixes, ",") != strings.Join(tt.expectedPrefixes, ",") {
+				t.Errorf("expected prefixes %v, got %v", tt.expectedPrefixes, resp.Prefixes)
 			}
 		})
 	}
 }
 
-func TestExtractBucketAndObjectNames(t *testing.T) {
+func TestStorage_ListObjects_Pagination(t *testing.T) {
+	h, s := setupTestStorage()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+	_, _ = s.CreateObject("test-bucket", "a.txt", "text/plain", []byte("a"), nil)
+	_, _ = s.CreateObject("test-bucket", "b/file.txt", "text/plain", []byte("b"), nil)
+	_, _ = s.CreateObject("test-bucket", "c.txt", "text/plain", []byte("c"), nil)
+
+	var names []string
+	pageToken := ""
+	for {
+		req := httptest.NewRequest(http.MethodGet, "/storage/v1/b/test-bucket/o?delimiter=/&maxResults=2&pageToken="+pageToken, nil)
+		req.SetPathValue("bucket", "test-bucket")
+		rr := httptest.NewRecorder()
+		h.ListObjects(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+		}
+
+		var resp storage.ObjectList
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+
+		if len(resp.Items)+len(resp.Prefixes) > 2 {
+			t.Errorf("page has %d entries, want at most 2", len(resp.Items)+len(resp.Prefixes))
+		}
+		for _, obj := range resp.Items {
+			names = append(names, obj.Name)
+		}
+		names = append(names, resp.Prefixes...)
+
+		if resp.NextPageToken == "" {
+			break
+		}
+		pageToken = resp.NextPageToken
+	}
+
+	if len(names) != 3 {
+		t.Errorf("expected 3 entries across pages, got %v", names)
+	}
+}
+
+func TestStorage_ListObjects_DeleteBetweenPages(t *testing.T) {
+	h, s := setupTestStorage()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		_, _ = s.CreateObject("test-bucket", name, "text/plain", []byte(name), nil)
+	}
+
+	// Cleanup code deletes the objects of each page before it asks for the next one
+	var deleted []string
+	pageToken := ""
+	for {
+		req := httptest.NewRequest(http.MethodGet, "/storage/v1/b/test-bucket/o?maxResults=2&pageToken="+url.QueryEscape(pageToken), nil)
+		req.SetPathValue("bucket", "test-bucket")
+		rr := httptest.NewRecorder()
+		h.ListObjects(rr, req)
+
+		var resp storage.ObjectList
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		for _, obj := range resp.Items {
+			if err := s.DeleteObject("test-bucket", obj.Name); err != nil {
+				t.Fatalf("failed to delete %s: %v", obj.Name, err)
+			}
+			deleted = append(deleted, obj.Name)
+		}
+
+		if resp.NextPageToken == "" {
+			break
+		}
+		pageToken = resp.NextPageToken
+	}
+
+	if strings.Join(deleted, ",") != "a,b,c,d,e" {
+		t.Errorf("expected to delete a to e, deleted %v", deleted)
+	}
+}
+
+func TestStorage_ListBuckets_InvalidPageToken(t *testing.T) {
+	h, _ := setupTestStorage()
+
+	req := httptest.NewRequest(http.MethodGet, "/storage/v1/b?pageToken=invalid!", nil)
+	rr := httptest.NewRecorder()
+
+	h.ListBuckets(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, rr.Code)
+	}
+}
+
+func TestStorage_NotificationCRUD(t *testing.T) {
+	h, s := setupTestStorage()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+
+	body := `{"topic": "//pubsub.googleapis.com/projects/p/topics/t", "event_types": ["OBJECT_FINALIZE"]}`
+	req := httptest.NewRequest(http.MethodPost, "/storage/v1/b/test-bucket/notificationConfigs", strings.NewReader(body))
+	req.SetPathValue("bucket", "test-bucket")
+	rr := httptest.NewRecorder()
+	h.CreateNotification(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	var notification storage.Notification
+	if err := json.NewDecoder(rr.Body).Decode(&notification); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if notification.Kind != "storage#notification" {
+		t.Errorf("expected kind 'storage#notification', got '%s'", notification.Kind)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/storage/v1/b/test-bucket/notificationConfigs/"+notification.ID, nil)
+	req.SetPathValue("bucket", "test-bucket")
+	req.SetPathValue("notification", notification.ID)
+	rr = httptest.NewRecorder()
+	h.GetNotification(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/storage/v1/b/test-bucket/notificationConfigs", nil)
+	req.SetPathValue("bucket", "test-bucket")
+	rr = httptest.NewRecorder()
+	h.ListNotifications(rr, req)
+	var list storage.NotificationList
+	if err := json.NewDecoder(rr.Body).Decode(&list); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(list.Items) != 1 {
+		t.Errorf("expected 1 notification, got %d", len(list.Items))
+	}
+
+	req = httptest.NewRequest(http.MethodDelete, "/storage/v1/b/test-bucket/notificationConfigs/"+notification.ID, nil)
+	req.SetPathValue("bucket", "test-bucket")
+	req.SetPathValue("notification", notification.ID)
+	rr = httptest.NewRecorder()
+	h.DeleteNotification(rr, req)
+	if rr.Code != http.StatusNoContent {
+		t.Errorf("expected status %d, got %d", http.StatusNoContent, rr.Code)
+	}
+}
+
+func TestStorage_CreateNotification_InvalidEventType(t *testing.T) {
+	h, s := setupTestStorage()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+
+	body := `{"topic": "t", "event_types": ["OBJECT_EXPLODE"]}`
+	req := httptest.NewRequest(http.MethodPost, "/storage/v1/b/test-bucket/notificationConfigs", strings.NewReader(body))
+	req.SetPathValue("bucket", "test-bucket")
+	rr := httptest.NewRecorder()
+	h.CreateNotification(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, rr.Code)
+	}
+}
+
+func TestStorage_StrictValidation(t *testing.T) {
+	h, s := setupTestStorage()
+	s.SetStrictValidation(true)
+	if _, err := s.CreateBucket(&storage.BucketInsertRequest{Name: "assets"}); err != nil {
+		t.Fatal(err)
+	}
+
 	tests := []struct {
-		path       string
-		wantBucket string
-		wantObject string
+		name            string
+		handler         http.HandlerFunc
+		method          string
+		path            string
+		contentType     string
+		body            string
+		expectedStatus  int
+		expectedMessage string
 	}{
-		{"/storage/v1/b/test-bucket/o/test.txt", "test-bucket", "test.txt"},
-		{"/storage/v1/b/my-bucket/o/folder/file.txt", "my-bucket", "folder/file.txt"},
-		{"/storage/v1/b/bucket/o/", "bucket", ""},
+		{"list buckets without project", h.ListBuckets, http.MethodGet, "/storage/v1/b", "", "", http.StatusBadRequest, "Required parameter: project"},
+		{"list buckets", h.ListBuckets, http.MethodGet, "/storage/v1/b?project=p", "", "", http.StatusOK, ""},
+		{"create bucket without project", h.CreateBucket, http.MethodPost, "/storage/v1/b", "application/json", `{"name": "logs"}`, http.StatusBadRequest, "Required parameter: project"},
+		{"create bucket with unknown field", h.CreateBucket, http.MethodPost, "/storage/v1/b?project=p", "application/json", `{"name": "logs", "storageClas": "NEARLINE"}`, http.StatusBadRequest, `Unknown name \"storageClas\"`},
+		{"create bucket with output-only field", h.CreateBucket, http.MethodPost, "/storage/v1/b?project=p", "application/json", `{"name": "logs", "id": "logs"}`, http.StatusOK, ""},
+		{"create bucket with invalid name", h.CreateBucket, http.MethodPost, "/storage/v1/b?project=p", "application/json", `{"name": "My_Bucket"}`, http.StatusBadRequest, "Invalid bucket name: 'My_Bucket'"},
+		{"create bucket with misspelled location", h.CreateBucket, http.MethodPost, "/storage/v1/b?project=p", "application/json", `{"name": "eu-logs", "location": "europe-west-1"}`, http.StatusBadRequest, "location constraint is not valid"},
+		{"create bucket in dual-region", h.CreateBucket, http.MethodPost, "/storage/v1/b?project=p", "application/json", `{"name": "eu-logs", "location": "eur4"}`, http.StatusOK, `"locationType":"dual-region"`},
+		{"patch bucket with unknown nested field", h.PatchBucket, http.MethodPatch, "/storage/v1/b/assets", "application/json", `{"versioning": {"enable": true}}`, http.StatusBadRequest, `Unknown name \"enable\"`},
+		{"upload object with invalid name", h.InsertObject, http.MethodPost, "/upload/storage/v1/b/assets/o?uploadType=media&name=..", "text/plain", "hello", http.StatusBadRequest, "invalid object name"},
+		{"start resumable upload with unknown field", h.InsertObject, http.MethodPost, "/upload/storage/v1/b/assets/o?uploadType=resumable", "application/json", `{"name": "a.txt", "contentTyp": "text/plain"}`, http.StatusBadRequest, `Unknown name \"contentTyp\"`},
+		{"multipart upload with unknown field", h.InsertObject, http.MethodPost, "/upload/storage/v1/b/assets/o?uploadType=multipart", "multipart/related; boundary=b",
+			"--b\r\nContent-Type: application/json\r\n\r\n{\"name\": \"a.txt\", \"metdata\": {}}\r\n--b\r\nContent-Type: text/plain\r\n\r\nhello\r\n--b--", http.StatusBadRequest, `Unknown name \"metdata\"`},
 	}
 
 	for _, tt := range tests {
-		t.Run(tt.path, func(t *testing.T) {
-			bucket, object := extractBucketAndObjectNames(tt.path)
-			if bucket != tt.wantBucket || object != tt.wantObject {
-				t.Errorf("extractBucketAndObjectNames(%s) = (%s, %s), want (%s, %s)",
-					tt.path, bucket, object, tt.wantBucket, tt.wantObject)
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			// The bucket routes are all for the assets bucket
+			req.SetPathValue("bucket", "assets")
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rr := httptest.NewRecorder()
+
+			tt.handler(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if !strings.Contains(rr.Body.String(), tt.expectedMessage) {
+				t.Errorf("expected body to contain %q, got %s", tt.expectedMessage, rr.Body.String())
 			}
 		})
 	}
 }
+
+func TestMaxAge(t *testing.T) {
+	tests := []struct {
+		cacheControl string
+		expected     time.Duration
+	}{
+		{"", 0},
+		{"public, max-age=3600", time.Hour},
+		{"private, max-age=0", 0},
+		{"no-cache, max-age=60", 0},
+		{"max-age=invalid", 0},
+	}
+
+	for _, tt := range tests {
+		if got := maxAge(tt.cacheControl); got != tt.expected {
+			t.Errorf("maxAge(%q) = %v, want %v", tt.cacheControl, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,395 @@
+package handler
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+	"github.com/katharinasick/gcp-api-mock/internal/store"
+)
+
+// This file implements the Cloud Storage XML API, which is used by boto-based tools and older SDKs.
+// Reference: https://cloud.google.com/storage/docs/xml-api/overview
+
+// xmlDefaultMaxKeys is the default and maximum number of entries in an XML API listing.
+const xmlDefaultMaxKeys = 1000
+
+// XMLListObjects handles GET /{bucket} - List objects in a bucket.
+// Reference: https://cloud.google.com/storage/docs/xml-api/get-bucket-list
+func (h *Storage) XMLListObjects(w http.ResponseWriter, r *http.Request) {
+	bucketName := r.PathValue("bucket")
+
+	if h.store.GetBucket(bucketName) == nil {
+		respondXMLError(w, http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist.")
+		return
+	}
+
+	query := r.URL.Query()
+	prefix := query.Get("prefix")
+	delimiter := query.Get("delimiter")
+	marker := query.Get("marker")
+
+	maxKeys := xmlDefaultMaxKeys
+	if value := query.Get("max-keys"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			respondXMLError(w, http.StatusBadRequest, "InvalidArgument", "Invalid max-keys: "+value)
+			return
+		}
+		if parsed < maxKeys {
+			maxKeys = parsed
+		}
+	}
+
+	objects, prefixes := h.store.ListObjects(bucketName, prefix, delimiter)
+
+	result := &storage.ListBucketResult{
+		Xmlns:     storage.XMLNamespace,
+		Name:      bucketName,
+		Prefix:    prefix,
+		Marker:    marker,
+		MaxKeys:   maxKeys,
+		Delimiter: delimiter,
+	}
+
+	// Objects and prefixes both count towards max-keys, and the listing resumes after the marker
+	count := 0
+	for _, entry := range mergeObjectListEntries(objects, prefixes) {
+		if entry.name <= marker {
+			continue
+		}
+		if count == maxKeys {
+			result.IsTruncated = true
+			break
+		}
+
+		if entry.object != nil {
+			result.Contents = append(result.Contents, storage.XMLObject{
+				Key:            entry.object.Name,
+				Generation:     entry.object.Generation,
+				MetaGeneration: entry.object.Metageneration,
+				LastModified:   entry.object.Updated.UTC().Format(time.RFC3339Nano),
+				ETag:           `"` + md5Hex(entry.object.Md5Hash) + `"`,
+				Size:           entry.object.Size,
+				StorageClass:   entry.object.StorageClass,
+			})
+		} else {
+			result.CommonPrefixes = append(result.CommonPrefixes, storage.XMLCommonPrefix{Prefix: entry.name})
+		}
+		result.NextMarker = entry.name
+		count++
+	}
+
+	if !result.IsTruncated {
+		result.NextMarker = ""
+	}
+
+	respondXML(w, http.StatusOK, result)
+}
+
+// XMLCreateBucket handles PUT /{bucket} - Create a new bucket.
+// Reference: https://cloud.google.com/storage/docs/xml-api/put-bucket-create
+func (h *Storage) XMLCreateBucket(w http.ResponseWriter, r *http.Request) {
+	bucketName := r.PathValue("bucket")
+
+	req := &storage.BucketInsertRequest{Name: bucketName}
+
+	// The request body is optional
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		respondXMLError(w, http.StatusBadRequest, "InvalidArgument", "Failed to read request body")
+		return
+	}
+	if len(body) > 0 {
+		var cfg storage.CreateBucketConfiguration
+		if err := xml.Unmarshal(body, &cfg); err != nil {
+			respondXMLError(w, http.StatusBadRequest, "MalformedBucketConfiguration", "Invalid XML body")
+			return
+		}
+		req.Location = cfg.LocationConstraint
+		req.StorageClass = cfg.StorageClass
+	}
+
+	if storageClass := r.Header.Get("X-Goog-Storage-Class"); storageClass != "" {
+		req.StorageClass = storageClass
+	}
+
+	if _, err := h.store.CreateBucket(req); err != nil {
+		if strings.Contains(err.Error(), "already exists") {
+			respondXMLError(w, http.StatusConflict, "BucketAlreadyOwnedByYou", "Your previous request to create the named bucket succeeded and you already own it.")
+			return
+		}
+		if strings.Contains(err.Error(), "invalid bucket name") {
+			respondXMLError(w, http.StatusBadRequest, "InvalidBucketName", "The specified bucket is not valid.")
+			return
+		}
+		if strings.Contains(err.Error(), "invalid location") {
+			respondXMLError(w, http.StatusBadRequest, "InvalidLocationConstraint", "The specified location constraint is not valid.")
+			return
+		}
+		respondXMLError(w, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// XMLDeleteBucket handles DELETE /{bucket} - Delete a bucket.
+// Reference: https://cloud.google.com/storage/docs/xml-api/delete-bucket
+func (h *Storage) XMLDeleteBucket(w http.ResponseWriter, r *http.Request) {
+	bucketName := r.PathValue("bucket")
+
+	if err := h.store.DeleteBucket(bucketName); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondXMLError(w, http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist.")
+			return
+		}
+		if strings.Contains(err.Error(), "not empty") {
+			respondXMLError(w, http.StatusConflict, "BucketNotEmpty", "The bucket you tried to delete is not empty.")
+			return
+		}
+		respondXMLError(w, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// XMLPutObject handles PUT /{bucket}/{object} - Upload an object.
+// Custom metadata is read from x-goog-meta-* headers and a canned ACL from the x-goog-acl header.
+// Reference: https://cloud.google.com/storage/docs/xml-api/put-object-upload
+func (h *Storage) XMLPutObject(w http.ResponseWriter, r *http.Request) {
+	bucketName, objectName := r.PathValue("bucket"), r.PathValue("object")
+
+	if bucketName == "" || objectName == "" {
+		respondXMLError(w, http.StatusBadRequest, "InvalidArgument", "Invalid path: expected /{bucket}/{object}")
+		return
+	}
+
+	req := &storage.ObjectInsertRequest{
+		ContentType: r.Header.Get("Content-Type"),
+		KmsKeyName:  r.Header.Get("X-Goog-Encryption-Kms-Key-Name"),
+	}
+	if req.ContentType == "" {
+		req.ContentType = "application/octet-stream"
+	}
+	if req.KmsKeyName != "" && !isValidKmsKeyName(req.KmsKeyName) {
+		respondXMLError(w, http.StatusBadRequest, "InvalidArgument", "Invalid Cloud KMS key name: "+req.KmsKeyName)
+		return
+	}
+	if err := applyUploadHeaders(r, req); err != nil {
+		respondXMLError(w, http.StatusBadRequest, "InvalidArgument", err.Error())
+		return
+	}
+	applyUploadHashes(r, req)
+	if cannedACL := r.Header.Get("X-Goog-Acl"); cannedACL != "" {
+		req.PredefinedACL = storage.PredefinedObjectACLFromXML(cannedACL)
+	}
+
+	pre, err := parsePreconditions(r)
+	if err != nil {
+		respondXMLError(w, http.StatusBadRequest, "InvalidArgument", err.Error())
+		return
+	}
+
+	if !limitUploadBody(w, r, h.store.MaxObjectSize(), 0) {
+		respondXMLError(w, http.StatusRequestEntityTooLarge, "EntityTooLarge", "Your proposed upload exceeds the maximum allowed object size.")
+		return
+	}
+
+	obj, err := h.store.CreateObjectWithOptions(bucketName, objectName, req.ContentType, r.Body, req.Metadata, store.InsertOptions(req, pre))
+	if err != nil {
+		if isTooLarge(err) {
+			respondXMLError(w, http.StatusRequestEntityTooLarge, "EntityTooLarge", "Your proposed upload exceeds the maximum allowed object size.")
+			return
+		}
+		if strings.Contains(err.Error(), "not found") {
+			respondXMLError(w, http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist.")
+			return
+		}
+		if strings.Contains(err.Error(), "precondition failed") {
+			respondXMLError(w, http.StatusPreconditionFailed, "PreconditionFailed", "At least one of the pre-conditions you specified did not hold.")
+			return
+		}
+		if strings.Contains(err.Error(), "invalid checksum") {
+			respondXMLError(w, http.StatusBadRequest, "BadDigest", "The Content-MD5 or x-goog-hash you specified did not match what was received.")
+			return
+		}
+		if strings.Contains(err.Error(), "invalid object name") || strings.Contains(err.Error(), "ACL") {
+			respondXMLError(w, http.StatusBadRequest, "InvalidArgument", err.Error())
+			return
+		}
+		if strings.Contains(err.Error(), "quota exceeded") {
+			respondXMLError(w, http.StatusForbidden, "QuotaExceeded", err.Error())
+			return
+		}
+		if isRetained(err) {
+			respondXMLError(w, http.StatusForbidden, "RetentionPolicyNotMet", err.Error())
+			return
+		}
+		respondXMLError(w, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+
+	setXGoogHeaders(w, obj)
+	w.Header().Set("ETag", `"`+md5Hex(obj.Md5Hash)+`"`)
+	w.WriteHeader(http.StatusOK)
+}
+
+// XMLGetObject handles GET and HEAD /{bucket}/{object} - Download an object. This is also the path-style access
+// the Google Cloud Storage client libraries (e.g., in Go, Terraform, OpenTofu) download object content with.
+// Like the XML API, it answers with XML errors and an MD5 ETag, and serves Range requests with 206 Partial Content.
+// Reference: https://cloud.google.com/storage/docs/xml-api/get-object-download
+func (h *Storage) XMLGetObject(w http.ResponseWriter, r *http.Request) {
+	bucketName, objectName := r.PathValue("bucket"), r.PathValue("object")
+
+	if bucketName == "" || objectName == "" {
+		respondXMLError(w, http.StatusBadRequest, "InvalidArgument", "Invalid path: expected /{bucket}/{object}")
+		return
+	}
+
+	if h.store.GetBucket(bucketName) == nil {
+		respondXMLError(w, http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist.")
+		return
+	}
+
+	obj, content, err := h.store.OpenObjectContent(bucketName, objectName)
+	if err != nil && strings.Contains(err.Error(), "evicted") {
+		respondXMLError(w, http.StatusGone, "NoSuchKey", err.Error())
+		return
+	}
+	if err != nil {
+		respondXMLError(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+		return
+	}
+	defer content.Close()
+
+	pre, err := parsePreconditions(r)
+	if err != nil {
+		respondXMLError(w, http.StatusBadRequest, "InvalidArgument", err.Error())
+		return
+	}
+	if err := pre.Check(obj); err != nil {
+		if strings.Contains(err.Error(), "NotMatch") {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		respondXMLError(w, http.StatusPreconditionFailed, "PreconditionFailed", "At least one of the pre-conditions you specified did not hold.")
+		return
+	}
+
+	setXGoogHeaders(w, obj)
+	setObjectContentHeaders(w, obj)
+	w.Header().Set("ETag", `"`+md5Hex(obj.Md5Hash)+`"`)
+
+	// ServeContent evaluates If-None-Match and If-Modified-Since against the ETag and the update time,
+	// and answers Range requests with 206 Partial Content or 416 Range Not Satisfiable
+	http.ServeContent(w, r, "", obj.Updated, content)
+}
+
+// XMLDeleteObject handles DELETE /{bucket}/{object} - Delete an object.
+// Reference: https://cloud.google.com/storage/docs/xml-api/delete-object
+func (h *Storage) XMLDeleteObject(w http.ResponseWriter, r *http.Request) {
+	bucketName, objectName := r.PathValue("bucket"), r.PathValue("object")
+
+	if bucketName == "" || objectName == "" {
+		respondXMLError(w, http.StatusBadRequest, "InvalidArgument", "Invalid path: expected /{bucket}/{object}")
+		return
+	}
+
+	if h.store.GetBucket(bucketName) == nil {
+		respondXMLError(w, http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist.")
+		return
+	}
+
+	pre, err := parsePreconditions(r)
+	if err != nil {
+		respondXMLError(w, http.StatusBadRequest, "InvalidArgument", err.Error())
+		return
+	}
+
+	if err := h.store.DeleteObjectWithPreconditions(bucketName, objectName, pre); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondXMLError(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+			return
+		}
+		if strings.Contains(err.Error(), "precondition failed") {
+			respondXMLError(w, http.StatusPreconditionFailed, "PreconditionFailed", "At least one of the pre-conditions you specified did not hold.")
+			return
+		}
+		if isRetained(err) {
+			respondXMLError(w, http.StatusForbidden, "RetentionPolicyNotMet", err.Error())
+			return
+		}
+		respondXMLError(w, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setXGoogHeaders sets the x-goog-* response headers describing an object.
+// Reference: https://cloud.google.com/storage/docs/xml-api/reference-headers
+func setXGoogHeaders(w http.ResponseWriter, obj *storage.Object) {
+	w.Header().Set("X-Goog-Generation", strconv.FormatInt(obj.Generation, 10))
+	w.Header().Set("X-Goog-Metageneration", strconv.FormatInt(obj.Metageneration, 10))
+	w.Header().Set("X-Goog-Stored-Content-Length", strconv.FormatUint(obj.Size, 10))
+	w.Header().Set("X-Goog-Stored-Content-Encoding", "identity")
+	w.Header().Set("X-Goog-Storage-Class", obj.StorageClass)
+	w.Header().Add("X-Goog-Hash", "crc32c="+obj.Crc32c)
+	w.Header().Add("X-Goog-Hash", "md5="+obj.Md5Hash)
+	for key, value := range obj.Metadata {
+		if isHeaderToken(key) {
+			w.Header().Set("X-Goog-Meta-"+key, value)
+		}
+	}
+	if obj.CustomTime != nil {
+		w.Header().Set("X-Goog-Custom-Time", obj.CustomTime.Format(time.RFC3339Nano))
+	}
+	if obj.KmsKeyName != "" {
+		w.Header().Set("X-Goog-Encryption-Kms-Key-Name", obj.KmsKeyName)
+	}
+}
+
+// isHeaderToken reports whether a metadata key can be sent as the suffix of a header name.
+// Keys set via the JSON API may contain characters like spaces that no header can carry,
+// so they are left out of the response headers instead of corrupting them.
+func isHeaderToken(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, c := range key {
+		if c >= 0x7f || c <= ' ' || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, c) {
+			return false
+		}
+	}
+	return true
+}
+
+// md5Hex converts a base64-encoded MD5 hash to hex, which the XML API uses for ETags.
+func md5Hex(md5Hash string) string {
+	decoded, err := base64.StdEncoding.DecodeString(md5Hash)
+	if err != nil {
+		return md5Hash
+	}
+	return fmt.Sprintf("%x", decoded)
+}
+
+// respondXML writes an XML response with the given status code.
+func respondXML(w http.ResponseWriter, statusCode int, data interface{}) {
+	w.Header().Set("Content-Type", "application/xml; charset=UTF-8")
+	w.WriteHeader(statusCode)
+	io.WriteString(w, xml.Header)
+	xml.NewEncoder(w).Encode(data)
+}
+
+// respondXMLError writes an error response matching the XML API format.
+func respondXMLError(w http.ResponseWriter, statusCode int, code, message string) {
+	respondXML(w, statusCode, storage.XMLError{Code: code, Message: message})
+}
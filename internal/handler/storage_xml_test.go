@@ -0,0 +1,347 @@
+package handler
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+)
+
+func TestStorage_XMLBucketLifecycle(t *testing.T) {
+	h, s := setupTestStorage()
+
+	// Create bucket with a location
+	body := `<CreateBucketConfiguration><LocationConstraint>EU</LocationConstraint></CreateBucketConfiguration>`
+	req := httptest.NewRequest(http.MethodPut, "/xml-bucket", strings.NewReader(body))
+	req.SetPathValue("bucket", "xml-bucket")
+	rr := httptest.NewRecorder()
+	h.XMLCreateBucket(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	bucket := s.GetBucket("xml-bucket")
+	if bucket == nil || bucket.Location != "EU" {
+		t.Fatalf("expected bucket in EU, got %+v", bucket)
+	}
+
+	// Creating it again conflicts
+	req = httptest.NewRequest(http.MethodPut, "/xml-bucket", nil)
+	req.SetPathValue("bucket", "xml-bucket")
+	rr = httptest.NewRecorder()
+	h.XMLCreateBucket(rr, req)
+	if rr.Code != http.StatusConflict {
+		t.Errorf("expected status %d, got %d", http.StatusConflict, rr.Code)
+	}
+
+	// Delete it
+	req = httptest.NewRequest(http.MethodDelete, "/xml-bucket", nil)
+	req.SetPathValue("bucket", "xml-bucket")
+	rr = httptest.NewRecorder()
+	h.XMLDeleteBucket(rr, req)
+	if rr.Code != http.StatusNoContent {
+		t.Errorf("expected status %d, got %d", http.StatusNoContent, rr.Code)
+	}
+
+	// Deleting a missing bucket returns an XML error
+	req = httptest.NewRequest(http.MethodDelete, "/xml-bucket", nil)
+	req.SetPathValue("bucket", "xml-bucket")
+	rr = httptest.NewRecorder()
+	h.XMLDeleteBucket(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, rr.Code)
+	}
+
+	var xmlErr storage.XMLError
+	if err := xml.NewDecoder(rr.Body).Decode(&xmlErr); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if xmlErr.Code != "NoSuchBucket" {
+		t.Errorf("expected code 'NoSuchBucket', got '%s'", xmlErr.Code)
+	}
+}
+
+func TestStorage_XMLPutObject_Checksums(t *testing.T) {
+	h, s := setupTestStorage()
+	s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+
+	tests := []struct {
+		name           string
+		header         string
+		value          string
+		expectedStatus int
+	}{
+		{"matching content-md5", "Content-MD5", "DHa+A3udVTBCI2r9w9TX3Q==", http.StatusOK},
+		{"wrong content-md5", "Content-MD5", "AAAAAAAAAAAAAAAAAAAAAA==", http.StatusBadRequest},
+		{"matching x-goog-hash", "X-Goog-Hash", "crc32c=Jnq+Lw==", http.StatusOK},
+		{"wrong x-goog-hash", "X-Goog-Hash", "crc32c=AAAAAA==", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/test-bucket/file.txt", strings.NewReader("hello xml"))
+			req.SetPathValue("bucket", "test-bucket")
+			req.SetPathValue("object", "file.txt")
+			req.Header.Set(tt.header, tt.value)
+			rr := httptest.NewRecorder()
+
+			h.XMLPutObject(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if tt.expectedStatus == http.StatusBadRequest && !strings.Contains(rr.Body.String(), "<Code>BadDigest</Code>") {
+				t.Errorf("expected BadDigest error, got %s", rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestStorage_XMLPutObject_ContentHeaders(t *testing.T) {
+	h, s := setupTestStorage()
+	s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+
+	req := httptest.NewRequest(http.MethodPut, "/test-bucket/page.html", strings.NewReader("<p>hallo</p>"))
+	req.SetPathValue("bucket", "test-bucket")
+	req.SetPathValue("object", "page.html")
+	req.Header.Set("Content-Type", "text/html")
+	req.Header.Set("Cache-Control", "public, max-age=60")
+	req.Header.Set("Content-Language", "de")
+	req.Header.Set("X-Goog-Custom-Time", "2024-03-01T12:00:00Z")
+	rr := httptest.NewRecorder()
+	h.XMLPutObject(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	obj := s.GetObject("test-bucket", "page.html")
+	if obj == nil || obj.CacheControl != "public, max-age=60" || obj.ContentLanguage != "de" || obj.CustomTime == nil {
+		t.Fatalf("expected object with content headers, got %+v", obj)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/test-bucket/page.html", nil)
+	req.SetPathValue("bucket", "test-bucket")
+	req.SetPathValue("object", "page.html")
+	rr = httptest.NewRecorder()
+	h.XMLGetObject(rr, req)
+	if rr.Header().Get("Cache-Control") != "public, max-age=60" {
+		t.Errorf("expected Cache-Control 'public, max-age=60', got '%s'", rr.Header().Get("Cache-Control"))
+	}
+	if rr.Header().Get("Content-Language") != "de" {
+		t.Errorf("expected Content-Language 'de', got '%s'", rr.Header().Get("Content-Language"))
+	}
+
+	req = httptest.NewRequest(http.MethodPut, "/test-bucket/bad.html", strings.NewReader("x"))
+	req.SetPathValue("bucket", "test-bucket")
+	req.SetPathValue("object", "bad.html")
+	req.Header.Set("X-Goog-Custom-Time", "not-a-time")
+	rr = httptest.NewRecorder()
+	h.XMLPutObject(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d: %s", http.StatusBadRequest, rr.Code, rr.Body.String())
+	}
+}
+
+func TestStorage_XMLPutGetDeleteObject(t *testing.T) {
+	h, s := setupTestStorage()
+	s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+
+	req := httptest.NewRequest(http.MethodPut, "/test-bucket/dir/file.txt", strings.NewReader("hello xml"))
+	req.SetPathValue("bucket", "test-bucket")
+	req.SetPathValue("object", "dir/file.txt")
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set("x-goog-meta-owner", "team-a")
+	rr := httptest.NewRecorder()
+	h.XMLPutObject(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	if rr.Header().Get("X-Goog-Generation") == "" {
+		t.Error("expected x-goog-generation header")
+	}
+
+	obj := s.GetObject("test-bucket", "dir/file.txt")
+	if obj == nil || obj.Metadata["owner"] != "team-a" {
+		t.Fatalf("expected object with metadata, got %+v", obj)
+	}
+
+	// Read it back via the XML API
+	req = httptest.NewRequest(http.MethodGet, "/test-bucket/dir/file.txt", nil)
+	req.SetPathValue("bucket", "test-bucket")
+	req.SetPathValue("object", "dir/file.txt")
+	rr = httptest.NewRecorder()
+	h.XMLGetObject(rr, req)
+	if rr.Body.String() != "hello xml" {
+		t.Errorf("expected content 'hello xml', got '%s'", rr.Body.String())
+	}
+	if rr.Header().Get("X-Goog-Meta-Owner") != "team-a" {
+		t.Errorf("expected x-goog-meta-owner 'team-a', got '%s'", rr.Header().Get("X-Goog-Meta-Owner"))
+	}
+	if len(rr.Header().Values("X-Goog-Hash")) != 2 {
+		t.Errorf("expected crc32c and md5 x-goog-hash headers, got %v", rr.Header().Values("X-Goog-Hash"))
+	}
+
+	req = httptest.NewRequest(http.MethodDelete, "/test-bucket/dir/file.txt", nil)
+	req.SetPathValue("bucket", "test-bucket")
+	req.SetPathValue("object", "dir/file.txt")
+	rr = httptest.NewRecorder()
+	h.XMLDeleteObject(rr, req)
+	if rr.Code != http.StatusNoContent {
+		t.Errorf("expected status %d, got %d", http.StatusNoContent, rr.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodDelete, "/test-bucket/dir/file.txt", nil)
+	req.SetPathValue("bucket", "test-bucket")
+	req.SetPathValue("object", "dir/file.txt")
+	rr = httptest.NewRecorder()
+	h.XMLDeleteObject(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d", http.StatusNotFound, rr.Code)
+	}
+}
+
+func TestStorage_XMLGetObject(t *testing.T) {
+	h, s := setupTestStorage()
+	s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+
+	req := httptest.NewRequest(http.MethodPut, "/test-bucket/hello.txt", strings.NewReader("hello"))
+	req.SetPathValue("bucket", "test-bucket")
+	req.SetPathValue("object", "hello.txt")
+	rr := httptest.NewRecorder()
+	h.XMLPutObject(rr, req)
+	putETag := rr.Header().Get("ETag")
+	if putETag != `"5d41402abc4b2a76b9719d911017c592"` {
+		t.Fatalf("expected the MD5 ETag, got %q", putETag)
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		object         string
+		header         map[string]string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"get", http.MethodGet, "hello.txt", nil, http.StatusOK, "hello"},
+		{"head", http.MethodHead, "hello.txt", nil, http.StatusOK, ""},
+		{"range", http.MethodGet, "hello.txt", map[string]string{"Range": "bytes=1-2"}, http.StatusPartialContent, "el"},
+		{"unsatisfiable range", http.MethodGet, "hello.txt", map[string]string{"Range": "bytes=10-20"}, http.StatusRequestedRangeNotSatisfiable, ""},
+		{"etag matches", http.MethodGet, "hello.txt", map[string]string{"If-None-Match": putETag}, http.StatusNotModified, ""},
+		{"generation mismatch", http.MethodGet, "hello.txt", map[string]string{"X-Goog-If-Generation-Match": "1"}, http.StatusPreconditionFailed, "<Code>PreconditionFailed</Code>"},
+		{"missing key", http.MethodGet, "missing.txt", nil, http.StatusNotFound, "<Code>NoSuchKey</Code>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/test-bucket/"+tt.object, nil)
+			req.SetPathValue("bucket", "test-bucket")
+			req.SetPathValue("object", tt.object)
+			for name, value := range tt.header {
+				req.Header.Set(name, value)
+			}
+			rr := httptest.NewRecorder()
+			h.XMLGetObject(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if tt.expectedBody != "" && !strings.Contains(rr.Body.String(), tt.expectedBody) {
+				t.Errorf("expected body to contain %q, got %q", tt.expectedBody, rr.Body.String())
+			}
+			if rr.Code == http.StatusOK && rr.Header().Get("ETag") != putETag {
+				t.Errorf("expected the ETag of the upload %s, got %s", putETag, rr.Header().Get("ETag"))
+			}
+		})
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/other-bucket/hello.txt", nil)
+	req.SetPathValue("bucket", "other-bucket")
+	req.SetPathValue("object", "hello.txt")
+	rr = httptest.NewRecorder()
+	h.XMLGetObject(rr, req)
+	if rr.Code != http.StatusNotFound || !strings.Contains(rr.Body.String(), "<Code>NoSuchBucket</Code>") {
+		t.Errorf("expected a NoSuchBucket error, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestStorage_XMLListObjects(t *testing.T) {
+	h, s := setupTestStorage()
+	s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+	for _, name := range []string{"a.txt", "b.txt", "dir/c.txt", "dir/d.txt", "e.txt"} {
+		s.CreateObject("test-bucket", name, "text/plain", []byte("x"), nil)
+	}
+
+	tests := []struct {
+		name             string
+		query            string
+		expectedKeys     []string
+		expectedPrefixes []string
+		truncated        bool
+		nextMarker       string
+	}{
+		{
+			name:         "all objects",
+			query:        "",
+			expectedKeys: []string{"a.txt", "b.txt", "dir/c.txt", "dir/d.txt", "e.txt"},
+		},
+		{
+			name:             "with delimiter",
+			query:            "?delimiter=/",
+			expectedKeys:     []string{"a.txt", "b.txt", "e.txt"},
+			expectedPrefixes: []string{"dir/"},
+		},
+		{
+			name:         "truncated",
+			query:        "?max-keys=2",
+			expectedKeys: []string{"a.txt", "b.txt"},
+			truncated:    true,
+			nextMarker:   "b.txt",
+		},
+		{
+			name:             "after marker",
+			query:            "?delimiter=/&marker=b.txt",
+			expectedKeys:     []string{"e.txt"},
+			expectedPrefixes: []string{"dir/"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/test-bucket"+tt.query, nil)
+			req.SetPathValue("bucket", "test-bucket")
+			rr := httptest.NewRecorder()
+			h.XMLListObjects(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+			}
+
+			var result storage.ListBucketResult
+			if err := xml.NewDecoder(rr.Body).Decode(&result); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			var keys []string
+			for _, obj := range result.Contents {
+				keys = append(keys, obj.Key)
+			}
+			var prefixes []string
+			for _, p := range result.CommonPrefixes {
+				prefixes = append(prefixes, p.Prefix)
+			}
+
+			if strings.Join(keys, ",") != strings.Join(tt.expectedKeys, ",") {
+				t.Errorf("expected keys %v, got %v", tt.expectedKeys, keys)
+			}
+			if strings.Join(prefixes, ",") != strings.Join(tt.expectedPrefixes, ",") {
+				t.Errorf("expected prefixes %v, got %v", tt.expectedPrefixes, prefixes)
+			}
+			if result.IsTruncated != tt.truncated || result.NextMarker != tt.nextMarker {
+				t.Errorf("expected truncated=%v nextMarker=%q, got %v %q", tt.truncated, tt.nextMarker, result.IsTruncated, result.NextMarker)
+			}
+		})
+	}
+}
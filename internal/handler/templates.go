@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/katharinasick/gcp-api-mock/internal/templating"
+)
+
+// Templates handles the admin API of the response templates.
+type Templates struct {
+	templates *templating.Templates
+}
+
+// NewTemplates creates a new Templates handler.
+func NewTemplates(templates *templating.Templates) *Templates {
+	return &Templates{templates: templates}
+}
+
+// TemplateList is the response body listing the active response templates.
+type TemplateList struct {
+	Items []templating.Rule `json:"items"`
+}
+
+// List handles GET /admin/templates - List the active response templates in the order they match,
+// with how many requests each matched.
+func (h *Templates) List(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, TemplateList{Items: h.templates.List()})
+}
+
+// Create handles POST /admin/templates - Rewrite the JSON responses of matching requests with a Go template, e.g.
+// {"method": "GET", "path": "/storage/v1/b/*", "template": "{{ json (set . \"satisfiesPZS\" false) }}"}.
+func (h *Templates) Create(w http.ResponseWriter, r *http.Request) {
+	var rule templating.Rule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid JSON body", "invalid")
+		return
+	}
+
+	created, err := h.templates.Add(rule)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, created)
+}
+
+// Delete handles DELETE /admin/templates/{id} - Remove a response template.
+func (h *Templates) Delete(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if !h.templates.Delete(id) {
+		respondError(w, http.StatusNotFound, "Template "+id+" not found", "notFound")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Clear handles DELETE /admin/templates - Remove all response templates.
+func (h *Templates) Clear(w http.ResponseWriter, r *http.Request) {
+	h.templates.Clear()
+
+	w.WriteHeader(http.StatusNoContent)
+}
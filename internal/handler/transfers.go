@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/transfer"
+)
+
+// Transfers handles the admin API for the uploads and downloads in flight.
+type Transfers struct {
+	tracker *transfer.Tracker
+}
+
+// NewTransfers creates a new Transfers handler.
+func NewTransfers(tracker *transfer.Tracker) *Transfers {
+	return &Transfers{tracker: tracker}
+}
+
+// Stats handles GET /admin/transfers - List the uploads and downloads in flight and count the completed
+// and aborted ones, e.g. to check how many transfers a shutdown cut off.
+func (h *Transfers) Stats(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, h.tracker.Stats())
+}
+
+// liftWriteDeadline lifts the server's write timeout for a request whose response can take longer to write,
+// like an archive of a large bucket. If it can't be lifted, the response is still written until the timeout.
+func liftWriteDeadline(w http.ResponseWriter, r *http.Request) {
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("Failed to lift the write timeout of %s %s: %v", r.Method, r.URL.Path, err)
+	}
+}
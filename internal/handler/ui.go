@@ -1,28 +1,65 @@
-// Package handler provides HTTP handlers for the GCP API Mock.
 package handler
 
 import (
+	"bytes"
+	"encoding/json"
 	"html/template"
+	"io"
 	"net/http"
-	"path/filepath"
+	"net/http/httptest"
+	"net/url"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/katharinasick/gcp-api-mock/internal/config"
+	"github.com/katharinasick/gcp-api-mock/internal/logging"
+	"github.com/katharinasick/gcp-api-mock/internal/namespace"
+	"github.com/katharinasick/gcp-api-mock/internal/recorder"
 	"github.com/katharinasick/gcp-api-mock/internal/sqladmin"
 	"github.com/katharinasick/gcp-api-mock/internal/storage"
 	"github.com/katharinasick/gcp-api-mock/internal/store"
+	"github.com/katharinasick/gcp-api-mock/internal/usage"
+	"github.com/katharinasick/gcp-api-mock/web"
 )
 
+// MaxLoggedBodySize is the number of bytes of request and response bodies kept in the request log.
+const MaxLoggedBodySize = 64 << 10
+
 // RequestLogEntry represents a single API request log entry.
 type RequestLogEntry struct {
-	Timestamp   string
-	Method      string
-	MethodLower string
-	Path        string
-	Status      int
-	Success     bool
+	// ID increases with every logged request, so clients can poll for newer entries
+	ID          int64     `json:"id"`
+	Time        time.Time `json:"time"`
+	Timestamp   string    `json:"-"`
+	Method      string    `json:"method"`
+	MethodLower string    `json:"-"`
+	Path        string    `json:"path"`
+	Status      int       `json:"status"`
+	Success     bool      `json:"-"`
+	// Namespace is the namespace the request was sent to, empty for the default namespace
+	Namespace string `json:"namespace,omitempty"`
+
+	// URL, RequestHeader and the bodies are captured for API requests, so they can be inspected and
+	// replayed. URL includes the query. Bodies are cut off at a size limit; the Truncated fields report it.
+	URL                   string      `json:"url,omitempty"`
+	RequestHeader         http.Header `json:"requestHeader,omitempty"`
+	RequestBody           string      `json:"requestBody,omitempty"`
+	RequestBodyTruncated  bool        `json:"requestBodyTruncated,omitempty"`
+	ResponseHeader        http.Header `json:"responseHeader,omitempty"`
+	ResponseBody          string      `json:"responseBody,omitempty"`
+	ResponseBodyTruncated bool        `json:"responseBodyTruncated,omitempty"`
+	// ReplayOf is the ID of the logged request this request replayed
+	ReplayOf int64 `json:"replayOf,omitempty"`
+
+	// requestBody is the request body as received, since RequestBody may not be valid UTF-8
+	requestBody []byte
+}
+
+// Replayable reports whether the request can be replayed: it was captured by the API logger and
+// its whole body was kept.
+func (e RequestLogEntry) Replayable() bool {
+	return e.URL != "" && !e.RequestBodyTruncated
 }
 
 // RequestLogger stores API request logs for the UI.
@@ -30,6 +67,10 @@ type RequestLogger struct {
 	mu      sync.RWMutex
 	entries []RequestLogEntry
 	maxSize int
+	// lastID is the ID of the last logged request
+	lastID int64
+	// redact removes secrets from exchanges before they are logged, if set
+	redact func(*recorder.Exchange)
 }
 
 // NewRequestLogger creates a new request logger.
@@ -40,27 +81,121 @@ func NewRequestLogger(maxSize int) *RequestLogger {
 	}
 }
 
+// SetRedaction sets a function that removes secrets from requests and responses in place before they are
+// logged. It must be set before requests are logged.
+func (rl *RequestLogger) SetRedaction(redact func(*recorder.Exchange)) {
+	rl.redact = redact
+}
+
 // Add adds a new log entry.
 func (rl *RequestLogger) Add(method, path string, status int) {
+	rl.add(&RequestLogEntry{Method: method, Path: path, Status: status})
+}
+
+// AddExchange adds a log entry for a request and its response, with bodies already cut off at the
+// size limit. requestTruncated and responseTruncated report whether they were cut off.
+func (rl *RequestLogger) AddExchange(ex *recorder.Exchange, requestTruncated, responseTruncated bool) {
+	if rl.redact != nil {
+		rl.redact(ex)
+	}
+	rl.add(newExchangeLogEntry(ex, requestTruncated, responseTruncated))
+}
+
+// newExchangeLogEntry creates a log entry for a request and its response.
+func newExchangeLogEntry(ex *recorder.Exchange, requestTruncated, responseTruncated bool) *RequestLogEntry {
+	path, _, _ := strings.Cut(ex.Request.URL, "?")
+	return &RequestLogEntry{
+		Method:                ex.Request.Method,
+		Path:                  path,
+		Status:                ex.Response.Status,
+		Namespace:             exchangeNamespace(path, ex.Request.Header),
+		URL:                   ex.Request.URL,
+		RequestHeader:         ex.Request.Header,
+		RequestBody:           string(ex.Request.Body),
+		RequestBodyTruncated:  requestTruncated,
+		ResponseHeader:        ex.Response.Header,
+		ResponseBody:          string(ex.Response.Body),
+		ResponseBodyTruncated: responseTruncated,
+		requestBody:           ex.Request.Body,
+	}
+}
+
+// exchangeNamespace returns the namespace a logged request was sent to, selected by its path prefix
+// or header, or "" for the default namespace.
+func exchangeNamespace(path string, header http.Header) string {
+	if rest, ok := strings.CutPrefix(path, namespace.PathPrefix); ok {
+		name, _, _ := strings.Cut(rest, "/")
+		return name
+	}
+	return header.Get(namespace.Header)
+}
+
+// add assigns the next ID and the time to an entry and logs it.
+func (rl *RequestLogger) add(entry *RequestLogEntry) *RequestLogEntry {
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
 
-	entry := RequestLogEntry{
-		Timestamp:   time.Now().Format("15:04:05"),
-		Method:      method,
-		MethodLower: strings.ToLower(method),
-		Path:        path,
-		Status:      status,
-		Success:     status >= 200 && status < 400,
-	}
+	now := time.Now()
+	rl.lastID++
+	entry.ID = rl.lastID
+	entry.Time = now
+	entry.Timestamp = now.Format("15:04:05")
+	entry.MethodLower = strings.ToLower(entry.Method)
+	entry.Success = entry.Status >= 200 && entry.Status < 400
 
 	// Prepend new entry (newest first)
-	rl.entries = append([]RequestLogEntry{entry}, rl.entries...)
+	rl.entries = append([]RequestLogEntry{*entry}, rl.entries...)
 
 	// Trim to max size
 	if len(rl.entries) > rl.maxSize {
 		rl.entries = rl.entries[:rl.maxSize]
 	}
+
+	return entry
+}
+
+// Get returns the log entry with an ID, and false if it doesn't exist (anymore).
+func (rl *RequestLogger) Get(id int64) (RequestLogEntry, bool) {
+	rl.mu.RLock()
+	defer rl.mu.RUnlock()
+
+	for _, entry := range rl.entries {
+		if entry.ID == id {
+			return entry, true
+		}
+	}
+	return RequestLogEntry{}, false
+}
+
+// Replay sends a logged request to h again and logs it as a replay of the entry.
+// Returns the log entry of the replay.
+func (rl *RequestLogger) Replay(entry RequestLogEntry, h http.Handler) RequestLogEntry {
+	req := httptest.NewRequest(entry.Method, entry.URL, bytes.NewReader(entry.requestBody))
+	for key, values := range entry.RequestHeader {
+		req.Header[key] = values
+	}
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+
+	body := rr.Body.Bytes()
+	truncated := len(body) > MaxLoggedBodySize
+	if truncated {
+		body = body[:MaxLoggedBodySize]
+	}
+
+	// The request was redacted when it was logged, but the response is new
+	ex := &recorder.Exchange{
+		Request:  recorder.RecordedRequest{Method: entry.Method, URL: entry.URL, Header: entry.RequestHeader.Clone(), Body: entry.requestBody},
+		Response: recorder.RecordedResponse{Status: rr.Code, Header: rr.Header(), Body: body},
+	}
+	if rl.redact != nil {
+		rl.redact(ex)
+	}
+	replay := newExchangeLogEntry(ex, false, truncated)
+	replay.ReplayOf = entry.ID
+
+	return *rl.add(replay)
 }
 
 // GetAll returns all log entries.
@@ -73,6 +208,34 @@ func (rl *RequestLogger) GetAll() []RequestLogEntry {
 	return result
 }
 
+// Since returns the log entries with an ID greater than id, oldest first.
+func (rl *RequestLogger) Since(id int64) []RequestLogEntry {
+	rl.mu.RLock()
+	defer rl.mu.RUnlock()
+
+	result := make([]RequestLogEntry, 0)
+	for i := len(rl.entries) - 1; i >= 0; i-- {
+		if rl.entries[i].ID > id {
+			result = append(result, rl.entries[i])
+		}
+	}
+	return result
+}
+
+// InNamespace returns the log entries of the requests sent to a namespace, "" for the default namespace.
+func (rl *RequestLogger) InNamespace(name string) []RequestLogEntry {
+	rl.mu.RLock()
+	defer rl.mu.RUnlock()
+
+	result := make([]RequestLogEntry, 0)
+	for _, entry := range rl.entries {
+		if entry.Namespace == name {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
+
 // Clear removes all log entries.
 func (rl *RequestLogger) Clear() {
 	rl.mu.Lock()
@@ -80,24 +243,40 @@ func (rl *RequestLogger) Clear() {
 	rl.entries = make([]RequestLogEntry, 0)
 }
 
+// ClearNamespace removes the log entries of the requests sent to a namespace, "" for the default namespace.
+func (rl *RequestLogger) ClearNamespace(name string) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	entries := make([]RequestLogEntry, 0, len(rl.entries))
+	for _, entry := range rl.entries {
+		if entry.Namespace != name {
+			entries = append(entries, entry)
+		}
+	}
+	rl.entries = entries
+}
+
 // UI handles web UI endpoints with HTMX templates.
 type UI struct {
 	cfg       *config.Config
 	templates *template.Template
 	store     *store.Store
 	logger    *RequestLogger
+	usage     *usage.Tracker
 }
 
 // NewUI creates a new UI handler.
-func NewUI(cfg *config.Config, dataStore *store.Store, logger *RequestLogger) *UI {
-	// Parse all templates from the templates directory
-	tmpl := template.Must(template.ParseGlob(filepath.Join("web", "templates", "*.html")))
+func NewUI(cfg *config.Config, dataStore *store.Store, logger *RequestLogger, tracker *usage.Tracker) *UI {
+	// Parse all templates embedded from the templates directory
+	tmpl := template.Must(template.ParseFS(web.Templates, "templates/*.html"))
 
 	return &UI{
 		cfg:       cfg,
 		templates: tmpl,
 		store:     dataStore,
 		logger:    logger,
+		usage:     tracker,
 	}
 }
 
@@ -124,6 +303,21 @@ func (u *UI) Index(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// logRequest logs a change made through the UI as the API request it corresponds to, in the namespace
+// the dashboard shows.
+func (u *UI) logRequest(r *http.Request, method, path string, status int) {
+	u.logger.add(&RequestLogEntry{Method: method, Path: path, Status: status, Namespace: r.Header.Get(namespace.Header)})
+}
+
+// basePath returns the path prefix of the namespace the dashboard shows, for links into it,
+// or "" for the default namespace.
+func basePath(r *http.Request) string {
+	if name := r.Header.Get(namespace.Header); name != "" {
+		return namespace.PathPrefix + name
+	}
+	return ""
+}
+
 // ListBucketsUI renders the bucket list partial for HTMX.
 func (u *UI) ListBucketsUI(w http.ResponseWriter, r *http.Request) {
 	buckets := u.store.ListBuckets()
@@ -162,7 +356,7 @@ func (u *UI) CreateBucketUI(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Log the request
-	u.logger.Add("POST", "/storage/v1/b", http.StatusOK)
+	u.logRequest(r, "POST", "/storage/v1/b", http.StatusOK)
 
 	// Return updated bucket list
 	u.ListBucketsUI(w, r)
@@ -170,9 +364,7 @@ func (u *UI) CreateBucketUI(w http.ResponseWriter, r *http.Request) {
 
 // DeleteBucketUI handles bucket deletion from the UI.
 func (u *UI) DeleteBucketUI(w http.ResponseWriter, r *http.Request) {
-	// Extract bucket name from path: /ui/buckets/{bucket}
-	path := r.URL.Path
-	bucketName := strings.TrimPrefix(path, "/ui/buckets/")
+	bucketName := r.PathValue("bucket")
 
 	if bucketName == "" {
 		http.Error(w, "bucket name is required", http.StatusBadRequest)
@@ -190,7 +382,7 @@ func (u *UI) DeleteBucketUI(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Log the request
-	u.logger.Add("DELETE", "/storage/v1/b/"+bucketName, http.StatusNoContent)
+	u.logRequest(r, "DELETE", "/storage/v1/b/"+bucketName, http.StatusNoContent)
 
 	// Return updated bucket list
 	u.ListBucketsUI(w, r)
@@ -238,7 +430,7 @@ func (u *UI) CreateSQLInstanceUI(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Log the request
-	u.logger.Add("POST", "/sql/v1beta4/projects/mock-project/instances", http.StatusOK)
+	u.logRequest(r, "POST", "/sql/v1beta4/projects/mock-project/instances", http.StatusOK)
 
 	// Return updated instance list
 	u.ListSQLInstancesUI(w, r)
@@ -246,9 +438,7 @@ func (u *UI) CreateSQLInstanceUI(w http.ResponseWriter, r *http.Request) {
 
 // DeleteSQLInstanceUI handles SQL instance deletion from the UI.
 func (u *UI) DeleteSQLInstanceUI(w http.ResponseWriter, r *http.Request) {
-	// Extract instance name from path: /ui/sql/instances/{instance}
-	path := r.URL.Path
-	instanceName := strings.TrimPrefix(path, "/ui/sql/instances/")
+	instanceName := r.PathValue("instance")
 
 	if instanceName == "" {
 		http.Error(w, "instance name is required", http.StatusBadRequest)
@@ -266,39 +456,154 @@ func (u *UI) DeleteSQLInstanceUI(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Log the request
-	u.logger.Add("DELETE", "/sql/v1beta4/projects/mock-project/instances/"+instanceName, http.StatusOK)
+	u.logRequest(r, "DELETE", "/sql/v1beta4/projects/mock-project/instances/"+instanceName, http.StatusOK)
 
 	// Return updated instance list
 	u.ListSQLInstancesUI(w, r)
 }
 
 // GetLogsUI renders the request log partial for HTMX.
+// The dashboard shows the requests of the namespace it shows, or those of all namespaces with ?namespaces=all.
 func (u *UI) GetLogsUI(w http.ResponseWriter, r *http.Request) {
-	entries := u.logger.GetAll()
+	entries := u.logger.InNamespace(r.Header.Get(namespace.Header))
+	if r.URL.Query().Get("namespaces") == "all" {
+		entries = u.logger.GetAll()
+	}
 
 	if err := u.templates.ExecuteTemplate(w, "logs.html", entries); err != nil {
 		http.Error(w, "failed to render template", http.StatusInternalServerError)
 	}
 }
 
-// ClearLogsUI clears all request logs.
+// ClearLogsUI clears the request logs shown by GetLogsUI.
 func (u *UI) ClearLogsUI(w http.ResponseWriter, r *http.Request) {
-	u.logger.Clear()
+	if r.URL.Query().Get("namespaces") == "all" {
+		u.logger.Clear()
+	} else {
+		u.logger.ClearNamespace(r.Header.Get(namespace.Header))
+	}
 	u.GetLogsUI(w, r)
 }
 
+// uiMaxLogEntries is the number of log entries the Cloud Logging tab shows.
+const uiMaxLogEntries = 200
+
+// UsageUI returns the API calls per method and client as an HTML partial.
+func (u *UI) UsageUI(w http.ResponseWriter, r *http.Request) {
+	if err := u.templates.ExecuteTemplate(w, "usage.html", u.usage.Report()); err != nil {
+		http.Error(w, "failed to render template", http.StatusInternalServerError)
+	}
+}
+
+// LogEntryListData holds the data for the log entries template.
+type LogEntryListData struct {
+	// Error is the error of an invalid filter
+	Error   string
+	Entries []LogEntryRow
+}
+
+// LogEntryRow is a log entry as shown in the Cloud Logging tab.
+type LogEntryRow struct {
+	Time      string
+	Timestamp string
+	Severity  string
+	// Level is the numeric severity, used to color it
+	Level        int
+	LogName      string
+	LogID        string
+	ResourceType string
+	Payload      string
+}
+
+// ListLogEntriesUI renders the newest Cloud Logging entries matching the filter query parameter for HTMX.
+func (u *UI) ListLogEntriesUI(w http.ResponseWriter, r *http.Request) {
+	var data LogEntryListData
+
+	filter, err := logging.ParseFilter(r.URL.Query().Get("filter"))
+	if err != nil {
+		data.Error = err.Error()
+	} else {
+		entries := u.store.ListLogEntries(nil, filter, true)
+		if len(entries) > uiMaxLogEntries {
+			entries = entries[:uiMaxLogEntries]
+		}
+		for _, entry := range entries {
+			data.Entries = append(data.Entries, newLogEntryRow(entry))
+		}
+	}
+
+	if err := u.templates.ExecuteTemplate(w, "log_entries.html", data); err != nil {
+		http.Error(w, "failed to render template", http.StatusInternalServerError)
+	}
+}
+
+// newLogEntryRow summarizes a log entry for the Cloud Logging tab.
+func newLogEntryRow(entry *logging.LogEntry) LogEntryRow {
+	row := LogEntryRow{
+		Time:      entry.Timestamp.Format("15:04:05.000"),
+		Timestamp: entry.Timestamp.Format(time.RFC3339Nano),
+		Severity:  entry.Severity,
+		Level:     logging.SeverityLevel(entry.Severity),
+		LogName:   entry.LogName,
+		Payload:   logEntryPayload(entry),
+	}
+	if row.Severity == "" {
+		row.Severity = "DEFAULT"
+	}
+	if _, logID, found := strings.Cut(entry.LogName, "/logs/"); found {
+		row.LogID = logID
+		if unescaped, err := url.PathUnescape(logID); err == nil {
+			row.LogID = unescaped
+		}
+	}
+	if entry.Resource != nil {
+		row.ResourceType = entry.Resource.Type
+	}
+	return row
+}
+
+// logEntryPayload returns a one-line summary of the payload of a log entry: the text payload,
+// the message of a JSON payload, or the JSON itself.
+func logEntryPayload(entry *logging.LogEntry) string {
+	if entry.TextPayload != "" {
+		return entry.TextPayload
+	}
+
+	payload := entry.JSONPayload
+	if len(payload) == 0 {
+		payload = entry.ProtoPayload
+	}
+	var fields struct {
+		Message    string `json:"message"`
+		MethodName string `json:"methodName"`
+	}
+	if err := json.Unmarshal(payload, &fields); err == nil {
+		if fields.Message != "" {
+			return fields.Message
+		}
+		if fields.MethodName != "" {
+			return fields.MethodName
+		}
+	}
+
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, payload); err != nil {
+		return string(payload)
+	}
+	return compact.String()
+}
+
 // ObjectListData holds the data for the objects template.
 type ObjectListData struct {
 	BucketName string
 	Objects    []*storage.Object
+	// BasePath is the path prefix of the namespace of the bucket, for download links
+	BasePath string
 }
 
 // ListObjectsUI renders the object list partial for HTMX.
 func (u *UI) ListObjectsUI(w http.ResponseWriter, r *http.Request) {
-	// Extract bucket name from path: /ui/buckets/{bucket}/objects
-	path := r.URL.Path
-	path = strings.TrimPrefix(path, "/ui/buckets/")
-	bucketName := strings.TrimSuffix(path, "/objects")
+	bucketName := r.PathValue("bucket")
 
 	if bucketName == "" {
 		http.Error(w, "bucket name is required", http.StatusBadRequest)
@@ -316,6 +621,7 @@ func (u *UI) ListObjectsUI(w http.ResponseWriter, r *http.Request) {
 	data := ObjectListData{
 		BucketName: bucketName,
 		Objects:    objects,
+		BasePath:   basePath(r),
 	}
 
 	if err := u.templates.ExecuteTemplate(w, "objects.html", data); err != nil {
@@ -323,20 +629,150 @@ func (u *UI) ListObjectsUI(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// DeleteObjectUI handles object deletion from the UI.
-func (u *UI) DeleteObjectUI(w http.ResponseWriter, r *http.Request) {
-	// Extract bucket and object names from path: /ui/buckets/{bucket}/objects/{object...}
-	path := r.URL.Path
-	path = strings.TrimPrefix(path, "/ui/buckets/")
-	parts := strings.SplitN(path, "/objects/", 2)
+// objectPreviewLimit is the maximum number of bytes shown in a text preview.
+const objectPreviewLimit = 64 * 1024
+
+// ObjectDetailsData holds the data for the object details template.
+type ObjectDetailsData struct {
+	Object *storage.Object
+	// PreviewKind is "image", "text" or empty if the content type can't be previewed.
+	PreviewKind string
+	// Preview is the text content for text previews, pretty-printed if it is JSON.
+	Preview string
+	// PreviewTruncated is set if the text preview was cut off at objectPreviewLimit.
+	PreviewTruncated bool
+	// Evicted is set if the content was evicted to stay below the content size limit.
+	Evicted bool
+	// BasePath is the path prefix of the namespace of the object, for download links
+	BasePath string
+}
+
+// ObjectDetailsUI renders the object details partial for HTMX, with full metadata and a content preview.
+func (u *UI) ObjectDetailsUI(w http.ResponseWriter, r *http.Request) {
+	bucketName, objectName := r.PathValue("bucket"), r.PathValue("object")
+	if bucketName == "" || objectName == "" {
+		http.Error(w, "bucket and object names are required", http.StatusBadRequest)
+		return
+	}
+
+	u.renderObjectDetails(w, r, bucketName, objectName)
+}
 
-	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+// UpdateObjectUI handles object metadata edits from the UI form.
+// Custom metadata is sent as parallel metadataKey/metadataValue fields; entries with an empty key are removed.
+func (u *UI) UpdateObjectUI(w http.ResponseWriter, r *http.Request) {
+	bucketName, objectName := r.PathValue("bucket"), r.PathValue("object")
+	if bucketName == "" || objectName == "" {
 		http.Error(w, "bucket and object names are required", http.StatusBadRequest)
 		return
 	}
 
-	bucketName := parts[0]
-	objectName := parts[1]
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	keys := r.Form["metadataKey"]
+	values := r.Form["metadataValue"]
+	metadata := make(map[string]string)
+	for i, key := range keys {
+		key = strings.TrimSpace(key)
+		if key == "" || i >= len(values) {
+			continue
+		}
+		metadata[key] = values[i]
+	}
+
+	req := &storage.ObjectUpdateRequest{
+		ContentType: strings.TrimSpace(r.FormValue("contentType")),
+		Metadata:    metadata,
+	}
+	if _, err := u.store.UpdateObject(bucketName, objectName, req); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Log the request
+	u.logRequest(r, "PUT", "/storage/v1/b/"+bucketName+"/o/"+objectName, http.StatusOK)
+
+	u.renderObjectDetails(w, r, bucketName, objectName)
+}
+
+// renderObjectDetails renders the object details template for an object.
+func (u *UI) renderObjectDetails(w http.ResponseWriter, r *http.Request, bucketName, objectName string) {
+	obj, content, err := u.store.OpenObjectContent(bucketName, objectName)
+	if err != nil && strings.Contains(err.Error(), "evicted") {
+		// The metadata is still there, only the content can't be previewed
+		if obj := u.store.GetObject(bucketName, objectName); obj != nil {
+			if err := u.templates.ExecuteTemplate(w, "object_details.html", ObjectDetailsData{Object: obj, Evicted: true, BasePath: basePath(r)}); err != nil {
+				http.Error(w, "failed to render template", http.StatusInternalServerError)
+			}
+			return
+		}
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	defer content.Close()
+
+	data := ObjectDetailsData{Object: obj, BasePath: basePath(r)}
+	switch {
+	case strings.HasPrefix(obj.ContentType, "image/"):
+		data.PreviewKind = "image"
+	case isTextContentType(obj.ContentType):
+		preview, err := io.ReadAll(io.LimitReader(content, objectPreviewLimit+1))
+		if err != nil {
+			http.Error(w, "failed to read object content", http.StatusInternalServerError)
+			return
+		}
+		data.PreviewKind = "text"
+		data.PreviewTruncated = len(preview) > objectPreviewLimit
+		if data.PreviewTruncated {
+			preview = preview[:objectPreviewLimit]
+		}
+
+		var indented bytes.Buffer
+		if !data.PreviewTruncated && json.Indent(&indented, preview, "", "  ") == nil {
+			preview = indented.Bytes()
+		}
+		data.Preview = string(preview)
+	}
+
+	if err := u.templates.ExecuteTemplate(w, "object_details.html", data); err != nil {
+		http.Error(w, "failed to render template", http.StatusInternalServerError)
+	}
+}
+
+// isTextContentType reports whether content of the given type can be shown as text.
+func isTextContentType(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.TrimSpace(mediaType)
+
+	switch {
+	case strings.HasPrefix(mediaType, "text/"):
+		return true
+	case mediaType == "application/json", strings.HasSuffix(mediaType, "+json"):
+		return true
+	case mediaType == "application/xml", strings.HasSuffix(mediaType, "+xml"):
+		return true
+	case mediaType == "application/javascript", mediaType == "application/x-yaml", mediaType == "application/yaml":
+		return true
+	}
+	return false
+}
+
+// DeleteObjectUI handles object deletion from the UI.
+func (u *UI) DeleteObjectUI(w http.ResponseWriter, r *http.Request) {
+	bucketName, objectName := r.PathValue("bucket"), r.PathValue("object")
+	if bucketName == "" || objectName == "" {
+		http.Error(w, "bucket and object names are required", http.StatusBadRequest)
+		return
+	}
 
 	err := u.store.DeleteObject(bucketName, objectName)
 	if err != nil {
@@ -344,14 +780,17 @@ func (u *UI) DeleteObjectUI(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusNotFound)
 			return
 		}
+		if isRetained(err) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	// Log the request
-	u.logger.Add("DELETE", "/storage/v1/b/"+bucketName+"/o/"+objectName, http.StatusNoContent)
+	u.logRequest(r, "DELETE", "/storage/v1/b/"+bucketName+"/o/"+objectName, http.StatusNoContent)
 
 	// Return updated object list
-	r.URL.Path = "/ui/buckets/" + bucketName + "/objects"
 	u.ListObjectsUI(w, r)
 }
@@ -1,11 +1,19 @@
 package handler
 
 import (
+	"encoding/json"
+	"html/template"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/katharinasick/gcp-api-mock/internal/config"
+	"github.com/katharinasick/gcp-api-mock/internal/logging"
+	"github.com/katharinasick/gcp-api-mock/internal/recorder"
+	"github.com/katharinasick/gcp-api-mock/internal/redact"
 	"github.com/katharinasick/gcp-api-mock/internal/storage"
 	"github.com/katharinasick/gcp-api-mock/internal/store"
 )
@@ -75,6 +83,7 @@ func TestUI_ListObjectsUI_BucketNotFound(t *testing.T) {
 	ui, _ := setupTestUI()
 
 	req := httptest.NewRequest(http.MethodGet, "/ui/buckets/non-existent/objects", nil)
+	req.SetPathValue("bucket", "non-existent")
 	rr := httptest.NewRecorder()
 
 	ui.ListObjectsUI(rr, req)
@@ -97,6 +106,8 @@ func TestUI_DeleteObjectUI(t *testing.T) {
 	}
 
 	req := httptest.NewRequest(http.MethodDelete, "/ui/buckets/test-bucket/objects/test-file.txt", nil)
+	req.SetPathValue("bucket", "test-bucket")
+	req.SetPathValue("object", "test-file.txt")
 	rr := httptest.NewRecorder()
 
 	// The handler will try to render the template which will fail, but deletion should still happen
@@ -121,6 +132,8 @@ func TestUI_DeleteObjectUI_ObjectNotFound(t *testing.T) {
 	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
 
 	req := httptest.NewRequest(http.MethodDelete, "/ui/buckets/test-bucket/objects/non-existent.txt", nil)
+	req.SetPathValue("bucket", "test-bucket")
+	req.SetPathValue("object", "non-existent.txt")
 	rr := httptest.NewRecorder()
 
 	ui.DeleteObjectUI(rr, req)
@@ -130,10 +143,35 @@ func TestUI_DeleteObjectUI_ObjectNotFound(t *testing.T) {
 	}
 }
 
+func TestUI_DeleteBucketUI_ReportsReason(t *testing.T) {
+	ui, s := setupTestUI()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+	_, _ = s.CreateObject("test-bucket", "test-file.txt", "text/plain", []byte("content"), nil)
+
+	req := httptest.NewRequest(http.MethodDelete, "/ui/buckets/test-bucket", nil)
+	req.SetPathValue("bucket", "test-bucket")
+	rr := httptest.NewRecorder()
+
+	ui.DeleteBucketUI(rr, req)
+
+	// The dashboard shows the body of failed deletes as the reason
+	if rr.Code != http.StatusConflict {
+		t.Errorf("expected status %d, got %d", http.StatusConflict, rr.Code)
+	}
+	if !strings.Contains(rr.Body.String(), "not empty") {
+		t.Errorf("expected the reason in the body, got %q", rr.Body.String())
+	}
+	if s.GetBucket("test-bucket") == nil {
+		t.Error("bucket should still exist")
+	}
+}
+
 func TestUI_DeleteObjectUI_MissingBucketName(t *testing.T) {
 	ui, _ := setupTestUI()
 
 	req := httptest.NewRequest(http.MethodDelete, "/ui/buckets//objects/test.txt", nil)
+	req.SetPathValue("bucket", "")
+	req.SetPathValue("object", "test.txt")
 	rr := httptest.NewRecorder()
 
 	ui.DeleteObjectUI(rr, req)
@@ -147,6 +185,8 @@ func TestUI_DeleteObjectUI_MissingObjectName(t *testing.T) {
 	ui, _ := setupTestUI()
 
 	req := httptest.NewRequest(http.MethodDelete, "/ui/buckets/test-bucket/objects/", nil)
+	req.SetPathValue("bucket", "test-bucket")
+	req.SetPathValue("object", "")
 	rr := httptest.NewRecorder()
 
 	ui.DeleteObjectUI(rr, req)
@@ -180,3 +220,193 @@ func TestUI_ObjectListData(t *testing.T) {
 		t.Errorf("expected object name 'doc.pdf', got '%s'", data.Objects[0].Name)
 	}
 }
+
+// setupTestUIWithTemplates creates a UI handler with the real templates loaded.
+func setupTestUIWithTemplates(t *testing.T) (*UI, *store.Store) {
+	t.Helper()
+	ui, s := setupTestUI()
+	ui.templates = template.Must(template.ParseGlob(filepath.Join("..", "..", "web", "templates", "*.html")))
+	return ui, s
+}
+
+func TestUI_ObjectDetailsUI(t *testing.T) {
+	ui, s := setupTestUIWithTemplates(t)
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+	_, _ = s.CreateObject("test-bucket", "dir/config.json", "application/json", []byte(`{"key":"value"}`), map[string]string{"owner": "alice"})
+	_, _ = s.CreateObject("test-bucket", "logo.png", "image/png", []byte("png"), nil)
+	_, _ = s.CreateObject("test-bucket", "data.bin", "application/octet-stream", []byte{0, 1, 2}, nil)
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+		expectedBody   []string
+	}{
+		{"json preview", "/ui/buckets/test-bucket/objects/dir/config.json", http.StatusOK,
+			[]string{"application/json", "owner", "alice", "&#34;key&#34;: &#34;value&#34;"}},
+		{"image preview", "/ui/buckets/test-bucket/objects/logo.png", http.StatusOK,
+			[]string{`<img src="/download/storage/v1/b/test-bucket/o/logo.png?alt=media"`}},
+		{"no preview", "/ui/buckets/test-bucket/objects/data.bin", http.StatusOK,
+			[]string{"No preview available"}},
+		{"missing object", "/ui/buckets/test-bucket/objects/missing.txt", http.StatusNotFound, nil},
+		{"missing object name", "/ui/buckets/test-bucket/objects/", http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			rr := httptest.NewRecorder()
+			serveRoute("GET /ui/buckets/{bucket}/objects/{object...}", ui.ObjectDetailsUI, rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			for _, expected := range tt.expectedBody {
+				if !strings.Contains(rr.Body.String(), expected) {
+					t.Errorf("expected body to contain %q", expected)
+				}
+			}
+		})
+	}
+}
+
+func TestUI_UpdateObjectUI(t *testing.T) {
+	ui, s := setupTestUIWithTemplates(t)
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+	_, _ = s.CreateObject("test-bucket", "file.txt", "text/plain", []byte("hello"), map[string]string{"old": "1", "keep": "2"})
+
+	form := url.Values{
+		"contentType":   {"text/csv"},
+		"metadataKey":   {"", "keep", "new"},
+		"metadataValue": {"1", "updated", "3"},
+	}
+	req := httptest.NewRequest(http.MethodPut, "/ui/buckets/test-bucket/objects/file.txt", strings.NewReader(form.Encode()))
+	req.SetPathValue("bucket", "test-bucket")
+	req.SetPathValue("object", "file.txt")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+	ui.UpdateObjectUI(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	obj := s.GetObject("test-bucket", "file.txt")
+	if obj.ContentType != "text/csv" {
+		t.Errorf("expected content type text/csv, got %s", obj.ContentType)
+	}
+	expected := map[string]string{"keep": "updated", "new": "3"}
+	if len(obj.Metadata) != len(expected) || obj.Metadata["keep"] != "updated" || obj.Metadata["new"] != "3" {
+		t.Errorf("expected metadata %v, got %v", expected, obj.Metadata)
+	}
+
+	if entries := ui.logger.GetAll(); len(entries) != 1 || entries[0].Method != "PUT" {
+		t.Errorf("expected the update to be logged, got %+v", entries)
+	}
+}
+
+func TestIsTextContentType(t *testing.T) {
+	tests := []struct {
+		contentType string
+		expected    bool
+	}{
+		{"text/plain", true},
+		{"text/html; charset=utf-8", true},
+		{"application/json", true},
+		{"application/ld+json", true},
+		{"application/xml", true},
+		{"image/png", false},
+		{"application/octet-stream", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.contentType, func(t *testing.T) {
+			if got := isTextContentType(tt.contentType); got != tt.expected {
+				t.Errorf("isTextContentType(%q) = %v, want %v", tt.contentType, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestUI_ListLogEntriesUI(t *testing.T) {
+	ui, s := setupTestUIWithTemplates(t)
+	if err := s.WriteLogEntries(&logging.WriteLogEntriesRequest{
+		LogName:  "projects/test-project/logs/cloudaudit.googleapis.com%2Factivity",
+		Resource: &logging.MonitoredResource{Type: "gcs_bucket"},
+		Entries: []*logging.LogEntry{
+			{Severity: "ERROR", JSONPayload: json.RawMessage(`{"message":"upload failed"}`)},
+			{Severity: "INFO", TextPayload: "bucket created"},
+		},
+	}); err != nil {
+		t.Fatalf("WriteLogEntries() error: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		filter       string
+		expectedBody []string
+		excludedBody []string
+	}{
+		{"all", "", []string{"upload failed", "bucket created", "cloudaudit.googleapis.com/activity", "gcs_bucket"}, nil},
+		{"filtered", "severity>=ERROR", []string{"upload failed"}, []string{"bucket created"}},
+		{"invalid filter", "severity >=", []string{"Invalid filter"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/ui/logging/entries?filter="+url.QueryEscape(tt.filter), nil)
+			rr := httptest.NewRecorder()
+			ui.ListLogEntriesUI(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+			}
+			for _, expected := range tt.expectedBody {
+				if !strings.Contains(rr.Body.String(), expected) {
+					t.Errorf("expected body to contain %q", expected)
+				}
+			}
+			for _, excluded := range tt.excludedBody {
+				if strings.Contains(rr.Body.String(), excluded) {
+					t.Errorf("expected body not to contain %q", excluded)
+				}
+			}
+		})
+	}
+}
+
+func TestRequestLogger_Redaction(t *testing.T) {
+	rules, err := redact.New([]string{"Authorization"}, []string{"$.password"})
+	if err != nil {
+		t.Fatalf("redact.New() error: %v", err)
+	}
+	logger := NewRequestLogger(10)
+	logger.SetRedaction(rules.Exchange)
+
+	logger.AddExchange(&recorder.Exchange{
+		Request: recorder.RecordedRequest{
+			Method: http.MethodPut,
+			URL:    "/sql/v1beta4/projects/p/instances/db/users?name=root",
+			Header: http.Header{"Authorization": {"Bearer ya29.secret"}},
+			Body:   []byte(`{"password":"hunter2"}`),
+		},
+		Response: recorder.RecordedResponse{Status: http.StatusOK, Body: []byte(`{"password":"hunter2"}`)},
+	}, false, false)
+
+	// The password is echoed, so the response of the replay has to be redacted too
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"password":"hunter2"}`))
+	})
+	entry := logger.GetAll()[0]
+	logger.Replay(entry, echo)
+
+	for _, entry := range logger.GetAll() {
+		if got := entry.RequestHeader.Get("Authorization"); got != redact.Placeholder {
+			t.Errorf("entry %d: expected the Authorization header to be redacted, got %q", entry.ID, got)
+		}
+		if strings.Contains(entry.RequestBody+entry.ResponseBody, "hunter2") {
+			t.Errorf("entry %d: expected the password to be redacted, got %s and %s", entry.ID, entry.RequestBody, entry.ResponseBody)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/katharinasick/gcp-api-mock/internal/usage"
+)
+
+// Usage handles the admin API for the API calls per method and client.
+type Usage struct {
+	tracker *usage.Tracker
+}
+
+// NewUsage creates a new Usage handler.
+func NewUsage(tracker *usage.Tracker) *Usage {
+	return &Usage{tracker: tracker}
+}
+
+// Get handles GET /admin/usage - Count the API calls per method and per client, with the errors among them,
+// e.g. to find the test suite that calls a shared mock the most.
+func (h *Usage) Get(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, h.tracker.Report())
+}
+
+// Reset handles DELETE /admin/usage - Forget the counted API calls.
+func (h *Usage) Reset(w http.ResponseWriter, r *http.Request) {
+	h.tracker.Reset()
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// unknownFieldError is returned by decodeJSON for a field the API doesn't know.
+type unknownFieldError struct {
+	name string
+}
+
+// Error returns the message GCP APIs answer unknown fields with.
+func (e *unknownFieldError) Error() string {
+	return fmt.Sprintf("Invalid JSON payload received. Unknown name %q: Cannot find field.", e.name)
+}
+
+// decodeJSON decodes a JSON request body into v. With strict validation, fields that neither v nor
+// any of the resources have are rejected with an *unknownFieldError, like GCP rejects them. Output-only
+// fields of the resources, like a bucket's id or timeCreated, are ignored as GCP ignores them.
+func decodeJSON(r io.Reader, v any, strict bool, resources ...any) error {
+	if !strict {
+		return json.NewDecoder(r).Decode(v)
+	}
+
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	err = decodeStrict(body, v)
+	name, unknown := unknownField(err)
+	if !unknown {
+		return err
+	}
+
+	for _, resource := range resources {
+		resourceName, unknown := unknownField(decodeStrict(body, resource))
+		if !unknown {
+			// The field belongs to the resource; decode the body again without it being rejected
+			return json.Unmarshal(body, v)
+		}
+		name = resourceName
+	}
+	return &unknownFieldError{name: name}
+}
+
+// decodeStrict decodes body into v, rejecting unknown fields.
+func decodeStrict(body []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// unknownField returns the name of the unknown field err reports, if it reports one.
+func unknownField(err error) (string, bool) {
+	if err == nil {
+		return "", false
+	}
+	quoted, found := strings.CutPrefix(err.Error(), "json: unknown field ")
+	if !found {
+		return "", false
+	}
+	return strings.Trim(quoted, `"`), true
+}
+
+// invalidJSONMessage returns the error message for a request body decodeJSON failed to decode.
+func invalidJSONMessage(err error) string {
+	if unknownErr, ok := err.(*unknownFieldError); ok {
+		return unknownErr.Error()
+	}
+	return "Invalid JSON body"
+}
@@ -0,0 +1,72 @@
+package idtoken
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSigner_MintAndVerify(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	signer := NewSigner(func() time.Time { return now })
+
+	token, err := signer.Mint("sa@test-project.iam.gserviceaccount.com", "https://service.example.com", &ComputeEngine{ProjectID: "test-project"})
+	if err != nil {
+		t.Fatalf("Mint() error: %v", err)
+	}
+
+	claims, err := signer.Verify(token)
+	if err != nil {
+		t.Fatalf("Verify() error: %v", err)
+	}
+	if claims.Issuer != Issuer || claims.Audience != "https://service.example.com" || claims.Email != "sa@test-project.iam.gserviceaccount.com" ||
+		!claims.EmailVerified || claims.Subject != Subject(claims.Email) || claims.AuthorizedParty != claims.Subject {
+		t.Errorf("unexpected claims: %+v", claims)
+	}
+	if claims.IssuedAt != now.Unix() || claims.ExpiresAt != now.Add(Lifetime).Unix() {
+		t.Errorf("expected the token to be valid for an hour from now, got iat %d, exp %d", claims.IssuedAt, claims.ExpiresAt)
+	}
+	if claims.Google == nil || claims.Google.ComputeEngine.ProjectID != "test-project" {
+		t.Errorf("expected the instance claims, got %+v", claims.Google)
+	}
+
+	// Tampered and expired tokens are rejected
+	parts := strings.Split(token, ".")
+	tampered := parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"aud":"other"}`)) + "." + parts[2]
+	if _, err := signer.Verify(tampered); err == nil {
+		t.Error("expected an error for a tampered token")
+	}
+	if _, err := NewSigner(time.Now).Verify(token); err == nil {
+		t.Error("expected an error for a token of another key")
+	}
+	now = now.Add(Lifetime)
+	if _, err := signer.Verify(token); err == nil || !strings.Contains(err.Error(), "expired") {
+		t.Errorf("expected the token to be expired, got %v", err)
+	}
+}
+
+func TestSigner_JWKS(t *testing.T) {
+	signer := NewSigner(time.Now)
+	token, _ := signer.Mint("sa@test-project.iam.gserviceaccount.com", "aud", nil)
+
+	jwks, err := signer.JWKS()
+	if err != nil {
+		t.Fatalf("JWKS() error: %v", err)
+	}
+	if len(jwks.Keys) != 1 || jwks.Keys[0].KeyType != "RSA" || jwks.Keys[0].Algorithm != "RS256" || jwks.Keys[0].Exponent != "AQAB" {
+		t.Fatalf("unexpected key set: %+v", jwks)
+	}
+
+	header, _ := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[0])
+	if !strings.Contains(string(header), `"kid":"`+jwks.Keys[0].KeyID+`"`) {
+		t.Errorf("expected the token header to name key %s, got %s", jwks.Keys[0].KeyID, header)
+	}
+}
+
+func TestSubject(t *testing.T) {
+	subject := Subject("sa@test-project.iam.gserviceaccount.com")
+	if len(subject) != 21 || subject != Subject("sa@test-project.iam.gserviceaccount.com") || subject == Subject("other@test-project.iam.gserviceaccount.com") {
+		t.Errorf("expected a stable 21-digit ID per account, got %s", subject)
+	}
+}
@@ -0,0 +1,250 @@
+// Package jobs runs the background work of the mock, like applying the clock to time-dependent state,
+// on a schedule, so features don't start goroutines of their own and the admin API can list the jobs
+// and run them right away.
+package jobs
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Job is work that runs every interval.
+type Job struct {
+	// Name identifies the job, like "store.tick".
+	Name string
+	// Description tells what the job does.
+	Description string
+	// Interval is the time between the end of a run and the start of the next one.
+	Interval time.Duration
+	// Run does the work. A returned error is logged and reported by the job's status.
+	Run func() error
+}
+
+// Status is the state of a job.
+type Status struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Interval    string `json:"interval"`
+	// Running is true while the job runs.
+	Running bool `json:"running"`
+	// Runs is the number of finished runs.
+	Runs int64 `json:"runs"`
+	// Failures is the number of runs that returned an error.
+	Failures     int64     `json:"failures"`
+	LastRun      time.Time `json:"lastRun,omitzero"`
+	LastDuration string    `json:"lastDuration,omitempty"`
+	// LastError is the error of the last run, if it failed.
+	LastError string `json:"lastError,omitempty"`
+	// NextRun is when the job runs next. It is empty while the scheduler isn't started.
+	NextRun time.Time `json:"nextRun,omitzero"`
+}
+
+// job is a registered job with its state.
+type job struct {
+	Job
+	// runMu serializes the runs of the job, so a run requested via the admin API doesn't overlap a scheduled one
+	runMu sync.Mutex
+
+	// The fields below are guarded by the scheduler's mu
+	running      bool
+	runs         int64
+	failures     int64
+	lastRun      time.Time
+	lastDuration time.Duration
+	lastError    error
+	nextRun      time.Time
+}
+
+// Scheduler runs jobs every interval once it is started.
+// It is safe for concurrent access.
+type Scheduler struct {
+	mu   sync.Mutex
+	jobs map[string]*job
+	stop chan struct{}
+	wg   sync.WaitGroup
+	// started is true between Start and Stop
+	started bool
+	stopped bool
+}
+
+// New creates a Scheduler without jobs.
+func New() *Scheduler {
+	return &Scheduler{jobs: make(map[string]*job), stop: make(chan struct{})}
+}
+
+// Register adds a job. Jobs registered after Start are scheduled right away.
+// Returns an error if a job with the name exists or the interval isn't positive.
+func (s *Scheduler) Register(j Job) error {
+	if j.Interval <= 0 {
+		return fmt.Errorf("invalid interval %s of job %s: must be positive", j.Interval, j.Name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.jobs[j.Name]; exists {
+		return fmt.Errorf("job %s already registered", j.Name)
+	}
+	registered := &job{Job: j}
+	s.jobs[j.Name] = registered
+	if s.started {
+		s.schedule(registered)
+	}
+	return nil
+}
+
+// Start runs every job once its interval has passed, and again every interval after its last run,
+// until Stop is called. Starting a started scheduler does nothing.
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.started || s.stopped {
+		return
+	}
+	s.started = true
+	for _, j := range s.jobs {
+		s.schedule(j)
+	}
+}
+
+// Stop stops scheduling jobs and waits for the runs in progress to finish. The scheduler can't be
+// started again, but jobs can still be run with Run.
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	if !s.stopped {
+		s.stopped = true
+		s.started = false
+		close(s.stop)
+		for _, j := range s.jobs {
+			j.nextRun = time.Time{}
+		}
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
+
+// schedule starts the goroutine running a job every interval. The caller must hold mu.
+func (s *Scheduler) schedule(j *job) {
+	j.nextRun = time.Now().Add(j.Interval)
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		timer := time.NewTimer(j.Interval)
+		defer timer.Stop()
+		for {
+			select {
+			case <-timer.C:
+			case <-s.stop:
+				return
+			}
+			s.run(j)
+
+			s.mu.Lock()
+			if s.started {
+				j.nextRun = time.Now().Add(j.Interval)
+			}
+			s.mu.Unlock()
+			timer.Reset(j.Interval)
+		}
+	}()
+}
+
+// Run runs a job right away and returns its status afterwards. The run waits for a run in progress
+// to finish and doesn't move the next scheduled run. Returns an error if there is no job with the name; the error of the run is in the status.
+func (s *Scheduler) Run(name string) (Status, error) {
+	s.mu.Lock()
+	j, exists := s.jobs[name]
+	s.mu.Unlock()
+	if !exists {
+		return Status{}, fmt.Errorf("job %s not found", name)
+	}
+
+	s.run(j)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return j.status(), nil
+}
+
+// run runs a job and records the result.
+func (s *Scheduler) run(j *job) {
+	j.runMu.Lock()
+	defer j.runMu.Unlock()
+
+	s.mu.Lock()
+	j.running = true
+	s.mu.Unlock()
+
+	start := time.Now()
+	err := runJob(j.Job)
+	duration := time.Since(start)
+	if err != nil {
+		log.Printf("Job %s failed: %v", j.Name, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j.running = false
+	j.runs++
+	if err != nil {
+		j.failures++
+	}
+	j.lastRun = start
+	j.lastDuration = duration
+	j.lastError = err
+}
+
+// runJob runs a job, turning a panic into an error, so a failing job doesn't take down the mock.
+func runJob(j Job) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	if j.Run == nil {
+		return errors.New("job has nothing to run")
+	}
+	return j.Run()
+}
+
+// List returns the status of all jobs, sorted by name.
+func (s *Scheduler) List() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]Status, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		statuses = append(statuses, j.status())
+	}
+	slices.SortFunc(statuses, func(a, b Status) int { return strings.Compare(a.Name, b.Name) })
+	return statuses
+}
+
+// status returns the status of the job. The caller must hold the scheduler's mu.
+func (j *job) status() Status {
+	status := Status{
+		Name:        j.Name,
+		Description: j.Description,
+		Interval:    j.Interval.String(),
+		Running:     j.running,
+		Runs:        j.runs,
+		Failures:    j.failures,
+		LastRun:     j.lastRun,
+		NextRun:     j.nextRun,
+	}
+	if !j.lastRun.IsZero() {
+		status.LastDuration = j.lastDuration.String()
+	}
+	if j.lastError != nil {
+		status.LastError = j.lastError.Error()
+	}
+	return status
+}
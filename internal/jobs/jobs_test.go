@@ -0,0 +1,96 @@
+package jobs
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestScheduler_Run(t *testing.T) {
+	s := New()
+	var runs atomic.Int32
+	fail := false
+	jobs := []Job{
+		{Name: "count", Description: "Counts its runs", Interval: time.Hour, Run: func() error {
+			runs.Add(1)
+			if fail {
+				return errors.New("boom")
+			}
+			return nil
+		}},
+		{Name: "panic", Interval: time.Hour, Run: func() error { panic("oops") }},
+	}
+	for _, j := range jobs {
+		if err := s.Register(j); err != nil {
+			t.Fatalf("Register() error: %v", err)
+		}
+	}
+	if err := s.Register(Job{Name: "count", Interval: time.Hour}); err == nil {
+		t.Error("expected an error for a duplicate job")
+	}
+	if err := s.Register(Job{Name: "never", Run: func() error { return nil }}); err == nil {
+		t.Error("expected an error for a job without interval")
+	}
+
+	status, err := s.Run("count")
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if runs.Load() != 1 || status.Runs != 1 || status.Failures != 0 || status.LastRun.IsZero() || status.LastDuration == "" {
+		t.Errorf("expected one successful run, got %+v", status)
+	}
+	if !status.NextRun.IsZero() {
+		t.Errorf("expected no next run before the scheduler is started, got %v", status.NextRun)
+	}
+
+	fail = true
+	if status, _ = s.Run("count"); status.Failures != 1 || status.LastError != "boom" {
+		t.Errorf("expected a failed run, got %+v", status)
+	}
+
+	// A panicking job fails instead of taking down the mock
+	if status, _ = s.Run("panic"); status.LastError != "panic: oops" {
+		t.Errorf("expected the panic as error, got %+v", status)
+	}
+
+	if _, err := s.Run("missing"); err == nil {
+		t.Error("expected an error for a missing job")
+	}
+
+	statuses := s.List()
+	if len(statuses) != 2 || statuses[0].Name != "count" || statuses[0].Description != "Counts its runs" || statuses[1].Name != "panic" {
+		t.Errorf("expected the jobs sorted by name, got %+v", statuses)
+	}
+}
+
+func TestScheduler_StartAndStop(t *testing.T) {
+	s := New()
+	ran := make(chan struct{}, 10)
+	_ = s.Register(Job{Name: "tick", Interval: 10 * time.Millisecond, Run: func() error {
+		ran <- struct{}{}
+		return nil
+	}})
+
+	s.Start()
+	if status := s.List()[0]; status.NextRun.IsZero() {
+		t.Error("expected a next run once the scheduler is started")
+	}
+	for range 2 {
+		select {
+		case <-ran:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for a scheduled run")
+		}
+	}
+
+	s.Stop()
+	if status := s.List()[0]; !status.NextRun.IsZero() || status.Runs < 2 {
+		t.Errorf("expected no next run after Stop and at least 2 runs, got %+v", status)
+	}
+
+	// Jobs can still be run after Stop
+	if status, _ := s.Run("tick"); status.Runs < 3 {
+		t.Errorf("expected another run, got %+v", status)
+	}
+}
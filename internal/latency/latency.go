@@ -0,0 +1,273 @@
+// Package latency injects configurable response latency into API requests,
+// so clients can be tested against realistic timings instead of an instant mock.
+package latency
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Range is a latency range; delays are drawn uniformly between Min and Max.
+type Range struct {
+	Min time.Duration
+	Max time.Duration
+}
+
+// ParseRange parses a range like "20ms-80ms", or a fixed latency like "2s".
+func ParseRange(s string) (Range, error) {
+	minValue, maxValue, isRange := strings.Cut(strings.TrimSpace(s), "-")
+	if !isRange {
+		maxValue = minValue
+	}
+
+	lower, err := time.ParseDuration(strings.TrimSpace(minValue))
+	if err != nil {
+		return Range{}, fmt.Errorf("invalid latency %q: %w", s, err)
+	}
+	upper, err := time.ParseDuration(strings.TrimSpace(maxValue))
+	if err != nil {
+		return Range{}, fmt.Errorf("invalid latency %q: %w", s, err)
+	}
+	if lower < 0 || upper < lower {
+		return Range{}, fmt.Errorf("invalid latency %q: bounds must be non-negative and ascending", s)
+	}
+
+	return Range{Min: lower, Max: upper}, nil
+}
+
+// String formats the range the way ParseRange reads it.
+func (r Range) String() string {
+	if r.Min == r.Max {
+		return r.Min.String()
+	}
+	return r.Min.String() + "-" + r.Max.String()
+}
+
+// MarshalJSON encodes the range as a string like "20ms-80ms".
+func (r Range) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.String())
+}
+
+// UnmarshalJSON decodes a range from a string like "20ms-80ms".
+func (r *Range) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("invalid latency: %w", err)
+	}
+	parsed, err := ParseRange(s)
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+// Profile maps operations to latency ranges.
+// Operations are named "{service}.{verb}", e.g. "storage.get" or "sql.insert".
+// "{service}.*" matches every operation of a service and "*" matches every operation.
+type Profile map[string]Range
+
+// ParseProfile parses a comma-separated profile like "storage.get=20ms-80ms,sql.insert=2s-5s".
+func ParseProfile(s string) (Profile, error) {
+	profile := make(Profile)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		op, value, found := strings.Cut(entry, "=")
+		op = strings.TrimSpace(op)
+		if !found || op == "" {
+			return nil, fmt.Errorf("invalid latency profile entry %q: expected operation=range", entry)
+		}
+
+		r, err := ParseRange(value)
+		if err != nil {
+			return nil, err
+		}
+		profile[op] = r
+	}
+	return profile, nil
+}
+
+// LoadProfile reads a profile from a JSON file mapping operations to ranges,
+// e.g. {"storage.get": "20ms-80ms", "sql.insert": "2s-5s"}.
+func LoadProfile(path string) (Profile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read latency profile: %w", err)
+	}
+
+	var profile Profile
+	if err := json.Unmarshal(data, &profile); err != nil {
+		return nil, fmt.Errorf("failed to parse latency profile: %w", err)
+	}
+	return profile, nil
+}
+
+// Injector holds the active latency profile.
+// It is safe for concurrent access, so the profile can be changed while requests are served.
+type Injector struct {
+	mu      sync.RWMutex
+	profile Profile
+}
+
+// New creates a new Injector with an empty profile, which adds no latency.
+func New() *Injector {
+	return &Injector{profile: make(Profile)}
+}
+
+// Profile returns a copy of the active profile.
+func (i *Injector) Profile() Profile {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	profile := make(Profile, len(i.profile))
+	for op, r := range i.profile {
+		profile[op] = r
+	}
+	return profile
+}
+
+// SetProfile replaces the active profile.
+func (i *Injector) SetProfile(profile Profile) {
+	copied := make(Profile, len(profile))
+	for op, r := range profile {
+		copied[op] = r
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.profile = copied
+}
+
+// Delay returns a random delay for the operation, or 0 if no range matches it.
+// An exact match wins over "{service}.*", which wins over "*".
+func (i *Injector) Delay(op string) time.Duration {
+	i.mu.RLock()
+	r, ok := i.profile[op]
+	if !ok {
+		service, _, _ := strings.Cut(op, ".")
+		r, ok = i.profile[service+".*"]
+	}
+	if !ok {
+		r, ok = i.profile["*"]
+	}
+	i.mu.RUnlock()
+
+	if !ok || r.Max <= 0 {
+		return 0
+	}
+	if r.Max == r.Min {
+		return r.Min
+	}
+	return r.Min + rand.N(r.Max-r.Min+1)
+}
+
+// listCollections are the last path segments of list requests.
+var listCollections = map[string]bool{
+	"b":                   true,
+	"o":                   true,
+	"notificationConfigs": true,
+	"hmacKeys":            true,
+	"instances":           true,
+	"databases":           true,
+	"users":               true,
+	"operations":          true,
+	"services":            true,
+	"revisions":           true,
+	"_catalog":            true,
+	"timeSeries":          true,
+	"metricDescriptors":   true,
+	"logs":                true,
+	"jobs":                true,
+	"triggers":            true,
+	"clusters":            true,
+	"networks":            true,
+	"subnetworks":         true,
+	"budgets":             true,
+	"policies":            true,
+}
+
+// Operation classifies an API request as "{service}.{verb}", e.g. "storage.get".
+// Services are storage, sql, compute, billingbudgets, firestore, scheduler, eventarc, redis, container, orgpolicy, run, registry, monitoring and logging; verbs are get, list, insert, update and delete.
+// Returns "" for requests that are not API requests.
+func Operation(r *http.Request) string {
+	path := r.URL.Path
+
+	var service string
+	switch {
+	case strings.HasPrefix(path, "/storage/") || strings.HasPrefix(path, "/upload/storage/") || strings.HasPrefix(path, "/download/storage/"),
+		path == "/b" || strings.HasPrefix(path, "/b/"):
+		service = "storage"
+	case strings.HasPrefix(path, "/sql/"):
+		service = "sql"
+	case strings.HasPrefix(path, "/compute/"):
+		service = "compute"
+	case strings.HasPrefix(path, "/v1/billingAccounts/"):
+		service = "billingbudgets"
+	case strings.HasPrefix(path, "/v1/projects/") && strings.Contains(path, "/documents"):
+		service = "firestore"
+	case strings.HasPrefix(path, "/v1/projects/") && strings.Contains(path, "/jobs"):
+		service = "scheduler"
+	case strings.HasPrefix(path, "/v1/projects/") && strings.Contains(path, "/triggers"):
+		service = "eventarc"
+	case strings.HasPrefix(path, "/v1/projects/") && strings.Contains(path, "/locations/") && strings.Contains(path, "/instances"):
+		service = "redis"
+	case strings.HasPrefix(path, "/v1/projects/") && strings.Contains(path, "/clusters"):
+		service = "container"
+	case strings.HasPrefix(path, "/v2/entries:") || strings.HasPrefix(path, "/v2/projects/") && strings.HasSuffix(path, "/logs"):
+		service = "logging"
+	case strings.HasPrefix(path, "/v2/projects/") && strings.Contains(path, "/policies"):
+		service = "orgpolicy"
+	case strings.HasPrefix(path, "/v2/projects/") && strings.Contains(path, "/locations/"):
+		service = "run"
+	case strings.HasPrefix(path, "/v2/"):
+		service = "registry"
+	case strings.HasPrefix(path, "/v3/projects/"):
+		service = "monitoring"
+	default:
+		return ""
+	}
+
+	var verb string
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		verb = "get"
+		segments := strings.Split(strings.TrimSuffix(path, "/"), "/")
+		if listCollections[segments[len(segments)-1]] || isFirestoreCollection(path) {
+			verb = "list"
+		}
+	case http.MethodPost:
+		verb = "insert"
+		// Custom list methods like entries:list are POSTs
+		if strings.HasSuffix(path, ":list") {
+			verb = "list"
+		}
+	case http.MethodPut, http.MethodPatch:
+		verb = "update"
+	case http.MethodDelete:
+		verb = "delete"
+	default:
+		verb = strings.ToLower(r.Method)
+	}
+
+	return service + "." + verb
+}
+
+// isFirestoreCollection reports whether path names a Firestore collection rather than a document.
+// Collection paths have an odd number of segments after /documents/.
+func isFirestoreCollection(path string) bool {
+	_, rest, found := strings.Cut(path, "/documents/")
+	if !found || !strings.HasPrefix(path, "/v1/projects/") {
+		return false
+	}
+	return len(strings.Split(strings.Trim(rest, "/"), "/"))%2 == 1
+}
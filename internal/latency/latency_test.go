@@ -0,0 +1,152 @@
+package latency
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected Range
+		wantErr  bool
+	}{
+		{"range", "20ms-80ms", Range{Min: 20 * time.Millisecond, Max: 80 * time.Millisecond}, false},
+		{"fixed", "2s", Range{Min: 2 * time.Second, Max: 2 * time.Second}, false},
+		{"spaces", " 2s - 5s ", Range{Min: 2 * time.Second, Max: 5 * time.Second}, false},
+		{"descending", "80ms-20ms", Range{}, true},
+		{"not a duration", "fast", Range{}, true},
+		{"empty", "", Range{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRange(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRange(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.expected {
+				t.Errorf("ParseRange(%q) = %+v, want %+v", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseProfile(t *testing.T) {
+	profile, err := ParseProfile("storage.get=20ms-80ms, sql.insert=2s-5s,")
+	if err != nil {
+		t.Fatalf("ParseProfile() error: %v", err)
+	}
+	if len(profile) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(profile))
+	}
+	if r := profile["sql.insert"]; r.Min != 2*time.Second || r.Max != 5*time.Second {
+		t.Errorf("unexpected sql.insert range: %+v", r)
+	}
+
+	if _, err := ParseProfile("storage.get"); err == nil {
+		t.Error("expected error for entry without range")
+	}
+}
+
+func TestLoadProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "latency.json")
+	if err := os.WriteFile(path, []byte(`{"storage.*": "10ms-20ms"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	profile, err := LoadProfile(path)
+	if err != nil {
+		t.Fatalf("LoadProfile() error: %v", err)
+	}
+	if r := profile["storage.*"]; r.Min != 10*time.Millisecond || r.Max != 20*time.Millisecond {
+		t.Errorf("unexpected storage.* range: %+v", r)
+	}
+}
+
+func TestInjector_Delay(t *testing.T) {
+	injector := New()
+	if delay := injector.Delay("storage.get"); delay != 0 {
+		t.Errorf("expected no delay with empty profile, got %v", delay)
+	}
+
+	injector.SetProfile(Profile{
+		"storage.get": {Min: 20 * time.Millisecond, Max: 80 * time.Millisecond},
+		"sql.*":       {Min: time.Second, Max: time.Second},
+		"*":           {Min: time.Millisecond, Max: time.Millisecond},
+	})
+
+	for range 100 {
+		if delay := injector.Delay("storage.get"); delay < 20*time.Millisecond || delay > 80*time.Millisecond {
+			t.Fatalf("storage.get delay %v out of range", delay)
+		}
+	}
+
+	tests := []struct {
+		op       string
+		expected time.Duration
+	}{
+		{"sql.insert", time.Second},
+		{"storage.list", time.Millisecond},
+		{"run.delete", time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		if delay := injector.Delay(tt.op); delay != tt.expected {
+			t.Errorf("Delay(%q) = %v, want %v", tt.op, delay, tt.expected)
+		}
+	}
+}
+
+func TestOperation(t *testing.T) {
+	tests := []struct {
+		method   string
+		path     string
+		expected string
+	}{
+		{http.MethodGet, "/storage/v1/b", "storage.list"},
+		{http.MethodGet, "/storage/v1/b/bucket/o/file.txt", "storage.get"},
+		{http.MethodPost, "/upload/storage/v1/b/bucket/o", "storage.insert"},
+		{http.MethodGet, "/download/storage/v1/b/bucket/o/file.txt", "storage.get"},
+		{http.MethodGet, "/storage/v1/projects/p/hmacKeys", "storage.list"},
+		{http.MethodPost, "/sql/v1beta4/projects/p/instances", "sql.insert"},
+		{http.MethodPatch, "/sql/v1beta4/projects/p/instances/i", "sql.update"},
+		{http.MethodGet, "/v1/projects/p/databases/(default)/documents/users", "firestore.list"},
+		{http.MethodGet, "/v1/projects/p/databases/(default)/documents/users/alice", "firestore.get"},
+		{http.MethodGet, "/v1/projects/p/locations/l/jobs", "scheduler.list"},
+		{http.MethodPost, "/v1/projects/p/locations/l/jobs/j:run", "scheduler.insert"},
+		{http.MethodGet, "/v1/projects/p/locations/l/triggers", "eventarc.list"},
+		{http.MethodPatch, "/v1/projects/p/locations/l/instances/cache", "redis.update"},
+		{http.MethodGet, "/v1/projects/p/locations/l/clusters", "container.list"},
+		{http.MethodGet, "/compute/v1/projects/p/regions/r/subnetworks", "compute.list"},
+		{http.MethodPost, "/compute/v1/projects/p/global/networks", "compute.insert"},
+		{http.MethodGet, "/v1/billingAccounts/012345-6789AB-CDEF01/budgets", "billingbudgets.list"},
+		{http.MethodPatch, "/v2/projects/p/policies/sql.restrictPublicIp", "orgpolicy.update"},
+		{http.MethodGet, "/v2/projects/p/policies", "orgpolicy.list"},
+		{http.MethodDelete, "/v2/projects/p/locations/l/services/s", "run.delete"},
+		{http.MethodPut, "/v2/myimage/manifests/latest", "registry.update"},
+		{http.MethodGet, "/v2/_catalog", "registry.list"},
+		{http.MethodPost, "/v3/projects/p/timeSeries", "monitoring.insert"},
+		{http.MethodGet, "/v3/projects/p/timeSeries", "monitoring.list"},
+		{http.MethodGet, "/v3/projects/p/metricDescriptors/custom.googleapis.com/requests", "monitoring.get"},
+		{http.MethodPost, "/v2/entries:write", "logging.insert"},
+		{http.MethodPost, "/v2/entries:list", "logging.list"},
+		{http.MethodGet, "/v2/projects/p/logs", "logging.list"},
+		{http.MethodGet, "/ui/buckets", ""},
+		{http.MethodGet, "/health", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if got := Operation(req); got != tt.expected {
+				t.Errorf("Operation() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
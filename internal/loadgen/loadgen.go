@@ -0,0 +1,322 @@
+// Package loadgen fills the store with generated buckets, objects and Cloud SQL instances for performance
+// tests, like those of listing or cleanup code, so they don't need a bespoke seeding script. Objects are
+// created in parallel, with sizes drawn from a configurable distribution and random or compressible content.
+// Generation is reproducible: the same options and seed create the same names, sizes and content.
+package loadgen
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/config"
+	"github.com/katharinasick/gcp-api-mock/internal/sqladmin"
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+	"github.com/katharinasick/gcp-api-mock/internal/store"
+)
+
+// Content kinds.
+const (
+	// ContentRandom is incompressible random content.
+	ContentRandom = "random"
+	// ContentCompressible is text made of repeated lines, which compresses well.
+	ContentCompressible = "compressible"
+)
+
+// DefaultPrefix is the prefix of the names of generated buckets and instances.
+const DefaultPrefix = "loadtest"
+
+// maxParallelism is the maximum number of objects created at the same time.
+const maxParallelism = 64
+
+// Options describe what to generate.
+type Options struct {
+	// Buckets is the number of buckets to generate.
+	Buckets int `json:"buckets"`
+	// Objects is the number of objects to generate in each bucket.
+	Objects int `json:"objects"`
+	// Folders spreads the objects of each bucket over that many folders, like folder-0001/, instead of
+	// creating them at the top level.
+	Folders int `json:"folders,omitempty"`
+	// Sizes is the size distribution of the objects, like "4KiB", "1KiB-64KiB" or "90%=1KiB-16KiB,10%=1MiB-8MiB".
+	// Sizes are drawn uniformly from the ranges. If empty, objects are 1KiB.
+	Sizes string `json:"sizes,omitempty"`
+	// Content is the kind of content of the objects, ContentRandom (the default) or ContentCompressible.
+	Content string `json:"content,omitempty"`
+	// SQLInstances is the number of Cloud SQL instances to generate.
+	SQLInstances int `json:"sqlInstances"`
+	// DatabaseVersion is the database version of the instances. If empty, the store's default is used.
+	DatabaseVersion string `json:"databaseVersion,omitempty"`
+	// Prefix is the prefix of the names of the buckets and instances, like loadtest-0001. If empty, DefaultPrefix is used.
+	Prefix string `json:"prefix,omitempty"`
+	// Seed makes the generation reproducible. If zero, a random seed is used and returned in the result.
+	Seed uint64 `json:"seed,omitempty"`
+	// Parallelism is the number of objects created at the same time. If zero, it is the number of CPUs.
+	Parallelism int `json:"parallelism,omitempty"`
+}
+
+// Result summarizes a generation.
+type Result struct {
+	// Buckets is the number of created buckets. Buckets that already existed are reused and not counted.
+	Buckets int `json:"buckets"`
+	// Objects is the number of created objects.
+	Objects int64 `json:"objects"`
+	// Bytes is the size of the content of the created objects.
+	Bytes int64 `json:"bytes"`
+	// SQLInstances is the number of created instances. Instances that already existed are kept and not counted.
+	SQLInstances int `json:"sqlInstances"`
+	// Seed is the seed the generation can be repeated with.
+	Seed uint64 `json:"seed"`
+	// Duration is how long the generation took.
+	Duration string `json:"duration"`
+}
+
+// sizeRange is an entry of a size distribution.
+type sizeRange struct {
+	// weight is the fraction of the objects whose size is in the range.
+	weight   float64
+	min, max int64
+}
+
+// SizeDistribution is a distribution of object sizes.
+type SizeDistribution []sizeRange
+
+// ParseSizeDistribution parses a size distribution like "4KiB", "1KiB-64KiB" or "90%=1KiB-16KiB,10%=1MiB-8MiB".
+// The percentages of the entries must add up to 100%; a single entry may leave it out.
+func ParseSizeDistribution(s string) (SizeDistribution, error) {
+	var distribution SizeDistribution
+	total := 0.0
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		r := sizeRange{weight: 100}
+		sizes := entry
+		if percent, rest, found := strings.Cut(entry, "="); found {
+			value, isPercent := strings.CutSuffix(strings.TrimSpace(percent), "%")
+			weight, err := strconv.ParseFloat(value, 64)
+			if !isPercent || err != nil || weight <= 0 || weight > 100 {
+				return nil, fmt.Errorf("invalid size distribution entry %q: expected a percentage like 90%%=1KiB-16KiB", entry)
+			}
+			r.weight, sizes = weight, rest
+		}
+
+		low, high, isRange := strings.Cut(sizes, "-")
+		var err error
+		if r.min, err = config.ParseByteSize(low); err != nil {
+			return nil, fmt.Errorf("invalid size distribution entry %q: %w", entry, err)
+		}
+		r.max = r.min
+		if isRange {
+			if r.max, err = config.ParseByteSize(high); err != nil {
+				return nil, fmt.Errorf("invalid size distribution entry %q: %w", entry, err)
+			}
+			if r.max < r.min {
+				return nil, fmt.Errorf("invalid size distribution entry %q: the maximum is below the minimum", entry)
+			}
+		}
+
+		total += r.weight
+		distribution = append(distribution, r)
+	}
+
+	if len(distribution) == 0 {
+		return nil, fmt.Errorf("invalid size distribution %q: no sizes", s)
+	}
+	if math.Abs(total-100) > 1e-9 {
+		return nil, fmt.Errorf("invalid size distribution %q: the percentages add up to %v%%, not 100%%", s, total)
+	}
+	return distribution, nil
+}
+
+// draw returns a size drawn from the distribution.
+func (d SizeDistribution) draw(rng *rand.Rand) int64 {
+	choice := rng.Float64() * 100
+	r := d[len(d)-1]
+	for _, candidate := range d {
+		if choice < candidate.weight {
+			r = candidate
+			break
+		}
+		choice -= candidate.weight
+	}
+	return r.min + rng.Int64N(r.max-r.min+1)
+}
+
+// Validate checks the options and returns an "invalid" error naming the problem.
+func (o *Options) Validate() error {
+	for _, count := range []struct {
+		name  string
+		value int
+	}{{"buckets", o.Buckets}, {"objects", o.Objects}, {"folders", o.Folders}, {"sqlInstances", o.SQLInstances}, {"parallelism", o.Parallelism}} {
+		if count.value < 0 {
+			return fmt.Errorf("invalid %s %d: must not be negative", count.name, count.value)
+		}
+	}
+	if o.Objects > 0 && o.Buckets == 0 {
+		return fmt.Errorf("invalid objects %d: objects need buckets", o.Objects)
+	}
+	if o.Content != "" && o.Content != ContentRandom && o.Content != ContentCompressible {
+		return fmt.Errorf("invalid content %q: expected %s or %s", o.Content, ContentRandom, ContentCompressible)
+	}
+	if o.Sizes != "" {
+		if _, err := ParseSizeDistribution(o.Sizes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Generate creates the buckets, objects and instances described by opts in s. Existing buckets are reused
+// and objects with the same names overwritten, so generating into a filled store is safe. Generation stops
+// at the first error or when ctx is done, keeping what was created until then.
+func Generate(ctx context.Context, s *store.Store, opts Options) (*Result, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	sizes := SizeDistribution{{weight: 100, min: 1024, max: 1024}}
+	if opts.Sizes != "" {
+		sizes, _ = ParseSizeDistribution(opts.Sizes)
+	}
+	if opts.Prefix == "" {
+		opts.Prefix = DefaultPrefix
+	}
+	if opts.Seed == 0 {
+		opts.Seed = rand.Uint64()
+	}
+	if opts.Parallelism == 0 {
+		opts.Parallelism = runtime.GOMAXPROCS(0)
+	}
+	opts.Parallelism = min(opts.Parallelism, maxParallelism)
+
+	start := time.Now()
+	result := &Result{Seed: opts.Seed}
+	defer func() { result.Duration = time.Since(start).String() }()
+
+	buckets := make([]string, opts.Buckets)
+	for i := range buckets {
+		buckets[i] = name(opts.Prefix, i, opts.Buckets)
+		if _, err := s.CreateBucket(&storage.BucketInsertRequest{Name: buckets[i]}); err != nil {
+			if !strings.Contains(err.Error(), "already exists") {
+				return result, fmt.Errorf("failed to create bucket %s: %w", buckets[i], err)
+			}
+			continue
+		}
+		result.Buckets++
+	}
+
+	for i := range opts.SQLInstances {
+		instance := name(opts.Prefix, i, opts.SQLInstances)
+		_, _, err := s.CreateSQLInstance(&sqladmin.InstanceInsertRequest{Name: instance, DatabaseVersion: opts.DatabaseVersion})
+		if err != nil {
+			if !strings.Contains(err.Error(), "already exists") {
+				return result, fmt.Errorf("failed to create instance %s: %w", instance, err)
+			}
+			continue
+		}
+		result.SQLInstances++
+	}
+
+	err := createObjects(ctx, s, opts, buckets, sizes, result)
+	return result, err
+}
+
+// createObjects creates the objects of all buckets with opts.Parallelism workers.
+func createObjects(ctx context.Context, s *store.Store, opts Options, buckets []string, sizes SizeDistribution, result *Result) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	total := int64(len(buckets)) * int64(opts.Objects)
+	var next, objects, bytes atomic.Int64
+	var wg sync.WaitGroup
+	for range opts.Parallelism {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				index := next.Add(1) - 1
+				if index >= total {
+					return
+				}
+				size, err := createObject(s, opts, buckets[index/int64(opts.Objects)], int(index%int64(opts.Objects)), index, sizes)
+				if err != nil {
+					cancel(err)
+					return
+				}
+				objects.Add(1)
+				bytes.Add(size)
+			}
+		}()
+	}
+	wg.Wait()
+
+	result.Objects, result.Bytes = objects.Load(), bytes.Load()
+	return context.Cause(ctx)
+}
+
+// createObject creates the object with the given index in a bucket and returns its size. index is the
+// index of the object among all generated objects, which its size and content are derived from.
+func createObject(s *store.Store, opts Options, bucket string, i int, index int64, sizes SizeDistribution) (int64, error) {
+	objectName := name("object", i, opts.Objects)
+	if opts.Folders > 0 {
+		objectName = name("folder", i%opts.Folders, opts.Folders) + "/" + objectName
+	}
+
+	rng := rand.New(rand.NewPCG(opts.Seed, uint64(index)))
+	size := sizes.draw(rng)
+
+	var content io.Reader
+	contentType := "application/octet-stream"
+	if opts.Content == ContentCompressible {
+		contentType = "text/plain"
+		content = &compressibleReader{line: fmt.Sprintf("%s/%s generated by the GCP API Mock for load tests\n", bucket, objectName)}
+	} else {
+		var seed [32]byte
+		binary.LittleEndian.PutUint64(seed[:], opts.Seed)
+		binary.LittleEndian.PutUint64(seed[8:], uint64(index))
+		content = rand.NewChaCha8(seed)
+	}
+
+	if _, err := s.CreateObjectFromReader(bucket, objectName, contentType, io.LimitReader(content, size), nil); err != nil {
+		return 0, fmt.Errorf("failed to create object %s/%s: %w", bucket, objectName, err)
+	}
+	return size, nil
+}
+
+// name returns the i-th of count names with a prefix, like loadtest-0001, padded so the names sort by index.
+func name(prefix string, i, count int) string {
+	width := max(4, len(strconv.Itoa(count-1)))
+	return fmt.Sprintf("%s-%0*d", prefix, width, i)
+}
+
+// compressibleReader endlessly repeats a numbered line.
+type compressibleReader struct {
+	line    string
+	number  int
+	pending []byte
+}
+
+func (r *compressibleReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(r.pending) == 0 {
+			r.number++
+			r.pending = fmt.Appendf(nil, "%08d %s", r.number, r.line)
+		}
+		copied := copy(p[n:], r.pending)
+		r.pending = r.pending[copied:]
+		n += copied
+	}
+	return n, nil
+}
@@ -0,0 +1,173 @@
+package loadgen
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/katharinasick/gcp-api-mock/internal/store"
+)
+
+// readObject returns the content of an object.
+func readObject(t *testing.T, s *store.Store, bucket, name string) []byte {
+	t.Helper()
+	_, content, err := s.OpenObjectContent(bucket, name)
+	if err != nil {
+		t.Fatalf("OpenObjectContent(%s, %s) error: %v", bucket, name, err)
+	}
+	defer content.Close()
+	data, err := io.ReadAll(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// gzipSize returns the size of data compressed with gzip.
+func gzipSize(data []byte) int {
+	var compressed bytes.Buffer
+	w := gzip.NewWriter(&compressed)
+	_, _ = w.Write(data)
+	_ = w.Close()
+	return compressed.Len()
+}
+
+func TestParseSizeDistribution(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected SizeDistribution
+		err      string
+	}{
+		{"4KiB", SizeDistribution{{100, 4096, 4096}}, ""},
+		{"1KiB-64KiB", SizeDistribution{{100, 1024, 65536}}, ""},
+		{"90%=1KiB-16KiB, 10%=1MB", SizeDistribution{{90, 1024, 16384}, {10, 1e6, 1e6}}, ""},
+		{"", nil, "no sizes"},
+		{"64KiB-1KiB", nil, "below the minimum"},
+		{"90%=1KiB,5%=1MiB", nil, "add up to 95%"},
+		{"90=1KiB", nil, "expected a percentage"},
+		{"big", nil, "invalid size"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			distribution, err := ParseSizeDistribution(tt.input)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("expected error containing %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseSizeDistribution() error: %v", err)
+			}
+			if len(distribution) != len(tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, distribution)
+			}
+			for i := range distribution {
+				if distribution[i] != tt.expected[i] {
+					t.Errorf("expected %v, got %v", tt.expected, distribution)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	s := store.New()
+	opts := Options{Buckets: 3, Objects: 50, Folders: 5, Sizes: "50%=10-100,50%=1KiB-2KiB", SQLInstances: 2, Prefix: "perf", Seed: 42, Parallelism: 8}
+
+	result, err := Generate(context.Background(), s, opts)
+	if err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+	if result.Buckets != 3 || result.Objects != 150 || result.SQLInstances != 2 || result.Seed != 42 {
+		t.Errorf("expected 3 buckets, 150 objects, 2 instances and seed 42, got %+v", result)
+	}
+
+	var size int64
+	for _, bucket := range []string{"perf-0000", "perf-0001", "perf-0002"} {
+		objects, _ := s.ListObjects(bucket, "", "")
+		if len(objects) != 50 {
+			t.Fatalf("expected 50 objects in %s, got %d", bucket, len(objects))
+		}
+		for _, obj := range objects {
+			if obj.Size < 10 || (obj.Size > 100 && obj.Size < 1024) || obj.Size > 2048 {
+				t.Errorf("expected the size of %s to be drawn from the distribution, got %d", obj.Name, obj.Size)
+			}
+			size += int64(obj.Size)
+		}
+		if folder, _ := s.ListObjects(bucket, "folder-0003/", ""); len(folder) != 10 {
+			t.Errorf("expected 10 objects in folder-0003/ of %s, got %d", bucket, len(folder))
+		}
+	}
+	if result.Bytes != size {
+		t.Errorf("expected %d bytes, got %d", size, result.Bytes)
+	}
+	for _, instance := range []string{"perf-0000", "perf-0001"} {
+		if s.GetSQLInstance(instance) == nil {
+			t.Errorf("expected instance %s to be created", instance)
+		}
+	}
+
+	// The same seed generates the same objects, reusing the buckets and instances
+	again := store.New()
+	if _, err := Generate(context.Background(), again, opts); err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+	name := "folder-0002/object-0007"
+	if !bytes.Equal(readObject(t, s, "perf-0001", name), readObject(t, again, "perf-0001", name)) {
+		t.Errorf("expected the same seed to generate the same content")
+	}
+	result, err = Generate(context.Background(), again, opts)
+	if err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+	if result.Buckets != 0 || result.SQLInstances != 0 || result.Objects != 150 {
+		t.Errorf("expected the existing buckets and instances to be reused, got %+v", result)
+	}
+}
+
+func TestGenerate_Content(t *testing.T) {
+	s := store.New()
+	for _, content := range []string{ContentRandom, ContentCompressible} {
+		if _, err := Generate(context.Background(), s, Options{Buckets: 1, Objects: 1, Sizes: "64KiB", Content: content, Prefix: content}); err != nil {
+			t.Fatalf("Generate() error: %v", err)
+		}
+	}
+
+	random := readObject(t, s, "random-0000", "object-0000")
+	compressible := readObject(t, s, "compressible-0000", "object-0000")
+	if len(random) != 65536 || len(compressible) != 65536 {
+		t.Fatalf("expected 64KiB objects, got %d and %d bytes", len(random), len(compressible))
+	}
+	if size := gzipSize(random); size < len(random) {
+		t.Errorf("expected random content not to compress, got %d bytes", size)
+	}
+	if size := gzipSize(compressible); size > len(compressible)/4 {
+		t.Errorf("expected compressible content to compress, got %d bytes", size)
+	}
+}
+
+func TestGenerate_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{"negative count", Options{Buckets: -1}},
+		{"objects without buckets", Options{Objects: 10}},
+		{"unknown content", Options{Buckets: 1, Content: "zeros"}},
+		{"invalid sizes", Options{Buckets: 1, Sizes: "1KiB-"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Generate(context.Background(), store.New(), tt.opts)
+			if err == nil || !strings.Contains(err.Error(), "invalid") {
+				t.Errorf("expected an invalid error, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,393 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Filter is a parsed query in the Logging query language, like
+// logName = "projects/p/logs/app" AND severity >= WARNING AND jsonPayload.user : "alice".
+// Comparisons can be combined with AND (or just spaces), OR, NOT and parentheses; like in Cloud Logging,
+// OR binds more tightly than AND. Free-text search without a field is not supported.
+// Reference: https://cloud.google.com/logging/docs/view/logging-query-language
+type Filter struct {
+	root filterNode
+}
+
+// filterNode is a node of a parsed filter.
+type filterNode interface {
+	matches(fields map[string]any) bool
+}
+
+type (
+	andNode  []filterNode
+	orNode   []filterNode
+	notNode  struct{ node filterNode }
+	compNode struct {
+		path  []string
+		op    string
+		value string
+		regex *regexp.Regexp
+	}
+)
+
+// filterOperators are the comparison operators, longest first so prefixes don't shadow them.
+var filterOperators = []string{"!=", ">=", "<=", "=~", "!~", "=", ">", "<", ":"}
+
+// ParseFilter parses a filter. An empty filter matches everything.
+func ParseFilter(s string) (*Filter, error) {
+	tokens, err := tokenizeFilter(s)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &filterParser{tokens: tokens}
+	root, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("invalid filter: unexpected %q", p.tokens[p.pos])
+	}
+	return &Filter{root: root}, nil
+}
+
+// Matches reports whether a log entry matches the filter.
+func (f *Filter) Matches(entry *LogEntry) bool {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return false
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return false
+	}
+	return f.root.matches(fields)
+}
+
+// filterParser is a recursive descent parser over filter tokens.
+type filterParser struct {
+	tokens []string
+	pos    int
+}
+
+// peek returns the next token, or "" at the end.
+func (p *filterParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+// parseAnd parses terms joined by AND or juxtaposition.
+func (p *filterParser) parseAnd() (filterNode, error) {
+	var nodes andNode
+	for p.pos < len(p.tokens) && p.peek() != ")" {
+		if len(nodes) > 0 && p.peek() == "AND" {
+			p.pos++
+		}
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+// parseOr parses factors joined by OR.
+func (p *filterParser) parseOr() (filterNode, error) {
+	node, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := orNode{node}
+	for p.peek() == "OR" {
+		p.pos++
+		node, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	if len(nodes) == 1 {
+		return node, nil
+	}
+	return nodes, nil
+}
+
+// parseNot parses an optionally negated comparison or parenthesized expression.
+func (p *filterParser) parseNot() (filterNode, error) {
+	if p.peek() == "NOT" {
+		p.pos++
+		node, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{node}, nil
+	}
+
+	if p.peek() == "(" {
+		p.pos++
+		node, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("invalid filter: missing )")
+		}
+		p.pos++
+		return node, nil
+	}
+
+	return p.parseComparison()
+}
+
+// parseComparison parses a comparison like severity >= ERROR.
+func (p *filterParser) parseComparison() (filterNode, error) {
+	if p.pos+3 > len(p.tokens) {
+		return nil, fmt.Errorf("invalid filter: incomplete comparison at %q", p.peek())
+	}
+	field, op, value := p.tokens[p.pos], p.tokens[p.pos+1], p.tokens[p.pos+2]
+	if isQuoted(field) || !isOperator(op) {
+		return nil, fmt.Errorf("invalid filter: expected a comparison like field = value at %q", field)
+	}
+	if isOperator(value) || value == "(" || value == ")" {
+		return nil, fmt.Errorf("invalid filter: missing value after %s %s", field, op)
+	}
+	p.pos += 3
+
+	node := compNode{path: strings.Split(field, "."), op: op, value: unquote(value)}
+	if op == "=~" || op == "!~" {
+		regex, err := regexp.Compile(node.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter: invalid regular expression %q: %w", node.value, err)
+		}
+		node.regex = regex
+	}
+	return node, nil
+}
+
+// tokenizeFilter splits a filter into words, operators, parentheses and quoted strings.
+func tokenizeFilter(s string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case unicode.IsSpace(rune(c)):
+			i++
+			continue
+		case c == '(' || c == ')':
+			tokens = append(tokens, string(c))
+			i++
+			continue
+		case c == '"':
+			end := i + 1
+			for end < len(s) && s[end] != '"' {
+				if s[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(s) {
+				return nil, fmt.Errorf("invalid filter: unterminated string")
+			}
+			tokens = append(tokens, s[i:end+1])
+			i = end + 1
+			continue
+		}
+
+		if op := operatorAt(s[i:]); op != "" {
+			tokens = append(tokens, op)
+			i += len(op)
+			continue
+		}
+
+		end := i
+		for end < len(s) && !unicode.IsSpace(rune(s[end])) && !strings.ContainsRune(`()"`, rune(s[end])) && operatorAt(s[end:]) == "" {
+			end++
+		}
+		tokens = append(tokens, s[i:end])
+		i = end
+	}
+	return tokens, nil
+}
+
+// operatorAt returns the operator s starts with, or "".
+func operatorAt(s string) string {
+	for _, op := range filterOperators {
+		if strings.HasPrefix(s, op) {
+			return op
+		}
+	}
+	return ""
+}
+
+// isOperator reports whether a token is a comparison operator.
+func isOperator(token string) bool {
+	return operatorAt(token) == token && token != ""
+}
+
+// isQuoted reports whether a token is a quoted string.
+func isQuoted(token string) bool {
+	return strings.HasPrefix(token, `"`)
+}
+
+// unquote returns the content of a quoted string token, or the token itself if it isn't quoted.
+func unquote(token string) string {
+	if !isQuoted(token) {
+		return token
+	}
+	if value, err := strconv.Unquote(token); err == nil {
+		return value
+	}
+	return strings.Trim(token, `"`)
+}
+
+func (n andNode) matches(fields map[string]any) bool {
+	for _, node := range n {
+		if !node.matches(fields) {
+			return false
+		}
+	}
+	return true
+}
+
+func (n orNode) matches(fields map[string]any) bool {
+	for _, node := range n {
+		if node.matches(fields) {
+			return true
+		}
+	}
+	return false
+}
+
+func (n notNode) matches(fields map[string]any) bool {
+	return !n.node.matches(fields)
+}
+
+// matches compares the field of the entry with the value. Missing fields only match != and !~.
+func (n compNode) matches(fields map[string]any) bool {
+	value, found := lookupField(fields, n.path)
+	if !found {
+		return n.op == "!=" || n.op == "!~"
+	}
+
+	switch n.op {
+	case ":":
+		// field:* only tests for presence
+		return n.value == "*" || hasValue(value, n.value)
+	case "=~":
+		return n.regex.MatchString(fieldString(value))
+	case "!~":
+		return !n.regex.MatchString(fieldString(value))
+	}
+
+	cmp := compareField(n.path, value, n.value)
+	switch n.op {
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	}
+	return false
+}
+
+// lookupField returns the value at a dotted path of the encoded entry.
+func lookupField(fields map[string]any, path []string) (any, bool) {
+	var value any = fields
+	for _, key := range path {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if value, ok = object[key]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// hasValue reports whether a field contains value, case-insensitively. For objects and arrays,
+// any nested value can contain it.
+func hasValue(field any, value string) bool {
+	switch field := field.(type) {
+	case map[string]any:
+		for _, nested := range field {
+			if hasValue(nested, value) {
+				return true
+			}
+		}
+		return false
+	case []any:
+		for _, nested := range field {
+			if hasValue(nested, value) {
+				return true
+			}
+		}
+		return false
+	default:
+		return strings.Contains(strings.ToLower(fieldString(field)), strings.ToLower(value))
+	}
+}
+
+// compareField compares a field with a value: severities by level, timestamps as times, numbers as numbers
+// and everything else as strings.
+func compareField(path []string, field any, value string) int {
+	s := fieldString(field)
+
+	if len(path) == 1 && path[0] == "severity" {
+		return severities[s] - severities[strings.ToUpper(value)]
+	}
+	if len(path) == 1 && (path[0] == "timestamp" || path[0] == "receiveTimestamp") {
+		fieldTime, err1 := time.Parse(time.RFC3339Nano, s)
+		valueTime, err2 := time.Parse(time.RFC3339Nano, value)
+		if err1 == nil && err2 == nil {
+			return fieldTime.Compare(valueTime)
+		}
+	}
+	if number, ok := field.(float64); ok {
+		if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+			switch {
+			case number < parsed:
+				return -1
+			case number > parsed:
+				return 1
+			default:
+				return 0
+			}
+		}
+	}
+	return strings.Compare(s, value)
+}
+
+// fieldString returns the string form of a decoded JSON value.
+func fieldString(field any) string {
+	switch field := field.(type) {
+	case string:
+		return field
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(field, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(field)
+	default:
+		data, _ := json.Marshal(field)
+		return string(data)
+	}
+}
@@ -0,0 +1,84 @@
+package logging
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestFilter_Matches(t *testing.T) {
+	entry := &LogEntry{
+		LogName:     "projects/p/logs/app",
+		Resource:    &MonitoredResource{Type: "cloud_run_revision", Labels: map[string]string{"service_name": "api"}},
+		Timestamp:   time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		Severity:    "WARNING",
+		Labels:      map[string]string{"env": "dev"},
+		JSONPayload: json.RawMessage(`{"message": "Payment failed", "user": {"id": "alice"}, "attempt": 3}`),
+	}
+
+	tests := []struct {
+		filter string
+		want   bool
+	}{
+		{``, true},
+		{`logName = "projects/p/logs/app"`, true},
+		{`logName="projects/p/logs/other"`, false},
+		{`severity >= WARNING`, true},
+		{`severity>=error`, false},
+		{`severity < ERROR AND severity > INFO`, true},
+		{`resource.type = "cloud_run_revision" resource.labels.service_name = "api"`, true},
+		{`labels.env = "prod"`, false},
+		{`labels.env != "prod"`, true},
+		{`labels.missing != "x"`, true},
+		{`labels.missing = ""`, false},
+		{`jsonPayload.message : "payment"`, true},
+		{`jsonPayload.user.id = "alice"`, true},
+		{`jsonPayload : "alice"`, true},
+		{`jsonPayload.attempt > 2`, true},
+		{`jsonPayload.attempt > 10`, false},
+		{`jsonPayload.user : *`, true},
+		{`textPayload : *`, false},
+		{`jsonPayload.message =~ "^Payment (failed|declined)$"`, true},
+		{`jsonPayload.message !~ "failed"`, false},
+		{`timestamp >= "2024-01-01T00:00:00Z" AND timestamp < "2024-01-02T00:00:00Z"`, true},
+		{`timestamp > "2024-01-01T12:00:00Z"`, false},
+		{`severity = ERROR OR severity = WARNING`, true},
+		{`NOT severity = WARNING`, false},
+		{`labels.env = "prod" OR (severity = WARNING AND resource.labels.service_name = "api")`, true},
+		// OR binds more tightly than AND
+		{`severity = ERROR OR labels.env = "dev" AND logName = "projects/p/logs/other"`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			f, err := ParseFilter(tt.filter)
+			if err != nil {
+				t.Fatalf("ParseFilter() error = %v", err)
+			}
+			if got := f.Matches(entry); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFilter_Invalid(t *testing.T) {
+	filters := []string{
+		`severity`,
+		`severity >=`,
+		`"free text"`,
+		`logName = "unterminated`,
+		`(severity = ERROR`,
+		`severity = ERROR)`,
+		`jsonPayload.message =~ "("`,
+		`severity = ERROR AND`,
+	}
+
+	for _, filter := range filters {
+		t.Run(filter, func(t *testing.T) {
+			if _, err := ParseFilter(filter); err == nil {
+				t.Errorf("ParseFilter(%q) expected an error", filter)
+			}
+		})
+	}
+}
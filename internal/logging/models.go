@@ -0,0 +1,110 @@
+// Package logging provides data models for the Cloud Logging API (v2) mock.
+package logging
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// LogEntry is a log entry.
+// Reference: https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry
+type LogEntry struct {
+	// LogName is the log the entry belongs to, e.g. projects/{project}/logs/{logId}.
+	LogName string `json:"logName,omitempty"`
+	// Resource is the monitored resource that produced the entry.
+	Resource *MonitoredResource `json:"resource,omitempty"`
+	// Timestamp is the time the event described by the entry occurred.
+	Timestamp time.Time `json:"timestamp"`
+	// ReceiveTimestamp is the time the entry was received.
+	ReceiveTimestamp time.Time `json:"receiveTimestamp"`
+	// Severity is the severity of the entry, e.g. INFO or ERROR.
+	Severity string `json:"severity,omitempty"`
+	// InsertID is a unique identifier for the entry; generated if empty.
+	InsertID string `json:"insertId,omitempty"`
+	// Labels are user-defined key/value labels.
+	Labels map[string]string `json:"labels,omitempty"`
+	// TextPayload, JSONPayload and ProtoPayload are the alternative payloads of the entry.
+	TextPayload  string          `json:"textPayload,omitempty"`
+	JSONPayload  json.RawMessage `json:"jsonPayload,omitempty"`
+	ProtoPayload json.RawMessage `json:"protoPayload,omitempty"`
+	// HTTPRequest describes the HTTP request the entry is about.
+	HTTPRequest json.RawMessage `json:"httpRequest,omitempty"`
+	// Trace is the trace the entry belongs to, e.g. projects/{project}/traces/{traceId}.
+	Trace string `json:"trace,omitempty"`
+	// SpanID is the span within the trace.
+	SpanID string `json:"spanId,omitempty"`
+	// TraceSampled is true if the trace was sampled.
+	TraceSampled bool `json:"traceSampled,omitempty"`
+	// Operation identifies the operation the entry belongs to.
+	Operation json.RawMessage `json:"operation,omitempty"`
+	// SourceLocation is the source code location of the entry.
+	SourceLocation json.RawMessage `json:"sourceLocation,omitempty"`
+}
+
+// MonitoredResource is the resource that produced a log entry, e.g. a cloud_run_revision or k8s_container.
+type MonitoredResource struct {
+	Type   string            `json:"type"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// WriteLogEntriesRequest is the request body of entries.write. LogName, Resource and Labels
+// are defaults for entries that don't set them.
+// Reference: https://cloud.google.com/logging/docs/reference/v2/rest/v2/entries/write
+type WriteLogEntriesRequest struct {
+	LogName        string             `json:"logName,omitempty"`
+	Resource       *MonitoredResource `json:"resource,omitempty"`
+	Labels         map[string]string  `json:"labels,omitempty"`
+	Entries        []*LogEntry        `json:"entries"`
+	PartialSuccess bool               `json:"partialSuccess,omitempty"`
+	DryRun         bool               `json:"dryRun,omitempty"`
+}
+
+// ListLogEntriesRequest is the request body of entries.list.
+// Reference: https://cloud.google.com/logging/docs/reference/v2/rest/v2/entries/list
+type ListLogEntriesRequest struct {
+	// ResourceNames are the projects to list entries from, e.g. projects/{project}.
+	ResourceNames []string `json:"resourceNames"`
+	// Filter selects the entries, see ParseFilter.
+	Filter string `json:"filter,omitempty"`
+	// OrderBy is "timestamp asc" (the default) or "timestamp desc".
+	OrderBy   string `json:"orderBy,omitempty"`
+	PageSize  int    `json:"pageSize,omitempty"`
+	PageToken string `json:"pageToken,omitempty"`
+}
+
+// ListLogEntriesResponse is the response of entries.list.
+type ListLogEntriesResponse struct {
+	Entries       []*LogEntry `json:"entries"`
+	NextPageToken string      `json:"nextPageToken,omitempty"`
+}
+
+// ListLogsResponse is the response of logs.list.
+type ListLogsResponse struct {
+	LogNames      []string `json:"logNames"`
+	NextPageToken string   `json:"nextPageToken,omitempty"`
+}
+
+// severities orders the severities of log entries; DEFAULT is the lowest.
+var severities = map[string]int{
+	"DEFAULT":   0,
+	"DEBUG":     100,
+	"INFO":      200,
+	"NOTICE":    300,
+	"WARNING":   400,
+	"ERROR":     500,
+	"CRITICAL":  600,
+	"ALERT":     700,
+	"EMERGENCY": 800,
+}
+
+// IsValidSeverity reports whether severity is a known severity. An empty severity is DEFAULT.
+func IsValidSeverity(severity string) bool {
+	_, ok := severities[severity]
+	return ok || severity == ""
+}
+
+// SeverityLevel returns the numeric level of a severity, e.g. 500 for ERROR, for ordering severities.
+// Unknown severities are DEFAULT (0).
+func SeverityLevel(severity string) int {
+	return severities[severity]
+}
@@ -0,0 +1,40 @@
+// Package longrunning provides the google.longrunning.Operation the Cloud Run, Eventarc and Memorystore for Redis
+// APIs return for their changes. The mock completes every operation immediately, so Done is always true.
+package longrunning
+
+import (
+	"encoding/json"
+
+	"github.com/katharinasick/gcp-api-mock/internal/gcperror"
+)
+
+// Operation is a google.longrunning.Operation.
+// Reference: https://cloud.google.com/run/docs/reference/rest/v2/projects.locations.operations
+type Operation struct {
+	// Name is the resource name, e.g. projects/{project}/locations/{location}/operations/{operation}.
+	Name string `json:"name"`
+	// Metadata is the operation metadata, a google.protobuf.Any.
+	Metadata json.RawMessage `json:"metadata,omitempty"`
+	// Done is true once the operation has completed.
+	Done bool `json:"done"`
+	// Response is the result of the operation, a google.protobuf.Any.
+	Response json.RawMessage `json:"response,omitempty"`
+	// Error is the error of a failed operation.
+	Error *gcperror.Details `json:"error,omitempty"`
+}
+
+// NewAny encodes v as a google.protobuf.Any JSON object with the given type URL.
+func NewAny(typeURL string, v any) (json.RawMessage, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	fields["@type"], _ = json.Marshal(typeURL)
+
+	return json.Marshal(fields)
+}
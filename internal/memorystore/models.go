@@ -0,0 +1,82 @@
+// Package memorystore provides data models for the Memorystore for Redis API (v1) mock.
+package memorystore
+
+import (
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/longrunning"
+)
+
+// Type URLs of the resources in operation responses and metadata.
+const (
+	InstanceTypeURL          = "type.googleapis.com/google.cloud.redis.v1.Instance"
+	OperationMetadataTypeURL = "type.googleapis.com/google.cloud.redis.v1.OperationMetadata"
+	EmptyTypeURL             = "type.googleapis.com/google.protobuf.Empty"
+)
+
+// Instance states.
+const (
+	StateReady = "READY"
+)
+
+// Service tiers.
+const (
+	TierBasic      = "BASIC"
+	TierStandardHA = "STANDARD_HA"
+)
+
+// Instance is a Memorystore for Redis instance.
+// Reference: https://cloud.google.com/memorystore/docs/redis/reference/rest/v1/projects.locations.instances
+type Instance struct {
+	// Name is the resource name, e.g. projects/{project}/locations/{location}/instances/{instance}.
+	Name string `json:"name"`
+	// DisplayName is a user-provided name for the instance.
+	DisplayName string `json:"displayName,omitempty"`
+	// Labels are user-defined key/value labels.
+	Labels map[string]string `json:"labels,omitempty"`
+	// LocationId is the zone the instance is in, e.g. us-central1-a.
+	LocationId string `json:"locationId,omitempty"`
+	// AlternativeLocationId is the zone of the replica of STANDARD_HA instances.
+	AlternativeLocationId string `json:"alternativeLocationId,omitempty"`
+	// RedisVersion is the version of Redis, e.g. REDIS_7_0.
+	RedisVersion string `json:"redisVersion,omitempty"`
+	// ReservedIpRange is the CIDR range the instance's IP address is taken from, e.g. 10.0.0.0/29.
+	ReservedIpRange string `json:"reservedIpRange,omitempty"`
+	// Host and Port are the endpoint clients connect to.
+	Host string `json:"host,omitempty"`
+	Port int    `json:"port,omitempty"`
+	// CurrentLocationId is the zone the primary currently runs in.
+	CurrentLocationId string `json:"currentLocationId,omitempty"`
+	// CreateTime is the time the instance was created.
+	CreateTime time.Time `json:"createTime,omitzero"`
+	// State is the state of the instance, e.g. READY.
+	State string `json:"state,omitempty"`
+	// StatusMessage describes the state.
+	StatusMessage string `json:"statusMessage,omitempty"`
+	// RedisConfigs are Redis configuration parameters, e.g. "maxmemory-policy".
+	RedisConfigs map[string]string `json:"redisConfigs,omitempty"`
+	// Tier is BASIC or STANDARD_HA.
+	Tier string `json:"tier,omitempty"`
+	// MemorySizeGb is the memory size of the instance in GiB.
+	MemorySizeGb int `json:"memorySizeGb,omitempty"`
+	// AuthorizedNetwork is the VPC network the instance is reachable from.
+	AuthorizedNetwork string `json:"authorizedNetwork,omitempty"`
+	// ConnectMode is DIRECT_PEERING or PRIVATE_SERVICE_ACCESS.
+	ConnectMode string `json:"connectMode,omitempty"`
+	// AuthEnabled requires clients to authenticate with an AUTH string.
+	AuthEnabled bool `json:"authEnabled,omitempty"`
+	// TransitEncryptionMode is DISABLED or SERVER_AUTHENTICATION.
+	TransitEncryptionMode string `json:"transitEncryptionMode,omitempty"`
+	// ReplicaCount is the number of read replicas of STANDARD_HA instances.
+	ReplicaCount int `json:"replicaCount,omitempty"`
+}
+
+// Operation is a google.longrunning.Operation.
+// Reference: https://cloud.google.com/memorystore/docs/redis/reference/rest/v1/projects.locations.operations
+type Operation = longrunning.Operation
+
+// ListInstancesResponse is the response of instances.list.
+type ListInstancesResponse struct {
+	Instances     []*Instance `json:"instances,omitempty"`
+	NextPageToken string      `json:"nextPageToken,omitempty"`
+}
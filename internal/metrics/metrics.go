@@ -0,0 +1,209 @@
+// Package metrics counts the requests the mock serves and exports them with the size of the stored resources
+// in the Prometheus text format, so a shared mock can be scraped to find out which buckets, and so which test
+// suites, fill it up.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/katharinasick/gcp-api-mock/internal/store"
+)
+
+// ContentType is the content type of the Prometheus text format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// requestKey identifies a request counter.
+type requestKey struct {
+	service string
+	method  string
+	code    int
+}
+
+// bucketRequestKey identifies a bucket request counter.
+type bucketRequestKey struct {
+	bucket string
+	method string
+}
+
+// Metrics counts the served API requests.
+// It is safe for concurrent access.
+type Metrics struct {
+	mu             sync.Mutex
+	requests       map[requestKey]int64
+	bucketRequests map[bucketRequestKey]int64
+}
+
+// New creates Metrics without counted requests.
+func New() *Metrics {
+	return &Metrics{
+		requests:       make(map[requestKey]int64),
+		bucketRequests: make(map[bucketRequestKey]int64),
+	}
+}
+
+// ObserveRequest counts a request to a service, like "storage.googleapis.com", answered with the status code.
+func (m *Metrics) ObserveRequest(service, method string, code int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests[requestKey{service: service, method: method, code: code}]++
+}
+
+// ObserveBucketRequest counts a Cloud Storage request for a bucket or its objects.
+func (m *Metrics) ObserveBucketRequest(bucket, method string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.bucketRequests[bucketRequestKey{bucket: bucket, method: method}]++
+}
+
+// BucketRequests returns the number of requests for each bucket and its objects, keyed by bucket name.
+func (m *Metrics) BucketRequests() map[string]int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	requests := make(map[string]int64)
+	for key, count := range m.bucketRequests {
+		requests[key.bucket] += count
+	}
+	return requests
+}
+
+// BucketStats is the size of a bucket and how many requests it got.
+type BucketStats struct {
+	// Bucket is the name of the bucket.
+	Bucket string `json:"bucket"`
+	// ObjectCount is the number of live objects in the bucket.
+	ObjectCount int `json:"objectCount"`
+	// UsedBytes is the total size of the live objects in the bucket.
+	UsedBytes int64 `json:"usedBytes"`
+	// Requests is the number of requests for the bucket and its objects.
+	Requests int64 `json:"requests"`
+}
+
+// TopBuckets returns the n largest buckets of a store, by used bytes, object count or requests, as given by
+// orderBy ("bytes", "objects" or "requests"). Ties are ordered by name. n <= 0 returns all buckets.
+// Returns an error if orderBy is invalid.
+func (m *Metrics) TopBuckets(s *store.Store, n int, orderBy string) ([]BucketStats, error) {
+	var value func(b BucketStats) int64
+	switch orderBy {
+	case "", "bytes":
+		value = func(b BucketStats) int64 { return b.UsedBytes }
+	case "objects":
+		value = func(b BucketStats) int64 { return int64(b.ObjectCount) }
+	case "requests":
+		value = func(b BucketStats) int64 { return b.Requests }
+	default:
+		return nil, fmt.Errorf("invalid orderBy %q: must be bytes, objects or requests", orderBy)
+	}
+
+	requests := m.BucketRequests()
+	var stats []BucketStats
+	for bucket, usage := range s.ListBucketUsage() {
+		stats = append(stats, BucketStats{
+			Bucket:      bucket,
+			ObjectCount: usage.ObjectCount,
+			UsedBytes:   usage.UsedBytes,
+			Requests:    requests[bucket],
+		})
+	}
+	slices.SortFunc(stats, func(a, b BucketStats) int {
+		if va, vb := value(a), value(b); va != vb {
+			if va > vb {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.Bucket, b.Bucket)
+	})
+	if n > 0 && len(stats) > n {
+		stats = stats[:n]
+	}
+	return stats, nil
+}
+
+// Write writes the request counters and the size of the resources of a store in the Prometheus text format.
+// Series are sorted, so equal states produce equal output.
+func (m *Metrics) Write(w io.Writer, s *store.Store) error {
+	e := &encoder{w: w}
+
+	m.mu.Lock()
+	var requests, bucketRequests []sample
+	for key, count := range m.requests {
+		requests = append(requests, sample{
+			labels: []string{"service", key.service, "method", key.method, "code", strconv.Itoa(key.code)},
+			value:  count,
+		})
+	}
+	for key, count := range m.bucketRequests {
+		bucketRequests = append(bucketRequests, sample{labels: []string{"bucket", key.bucket, "method", key.method}, value: count})
+	}
+	m.mu.Unlock()
+
+	usage := s.ListBucketUsage()
+	var objects, bytes int64
+	var bucketObjects, bucketBytes []sample
+	for bucket, u := range usage {
+		objects += int64(u.ObjectCount)
+		bytes += u.UsedBytes
+		bucketObjects = append(bucketObjects, sample{labels: []string{"bucket", bucket}, value: int64(u.ObjectCount)})
+		bucketBytes = append(bucketBytes, sample{labels: []string{"bucket", bucket}, value: u.UsedBytes})
+	}
+
+	e.family("gcp_mock_requests_total", "counter", "API requests served, by service, method and status code.", requests)
+	e.family("gcp_mock_storage_buckets", "gauge", "Number of Cloud Storage buckets.", []sample{{value: int64(len(usage))}})
+	e.family("gcp_mock_storage_objects", "gauge", "Number of live Cloud Storage objects.", []sample{{value: objects}})
+	e.family("gcp_mock_storage_bytes", "gauge", "Total size of the live Cloud Storage objects in bytes.", []sample{{value: bytes}})
+	e.family("gcp_mock_storage_bucket_objects", "gauge", "Number of live objects in a bucket.", bucketObjects)
+	e.family("gcp_mock_storage_bucket_bytes", "gauge", "Total size of the live objects in a bucket in bytes.", bucketBytes)
+	e.family("gcp_mock_storage_bucket_requests_total", "counter", "Cloud Storage requests for a bucket and its objects, by method.", bucketRequests)
+	e.family("gcp_mock_sql_instances", "gauge", "Number of Cloud SQL instances.", []sample{{value: int64(len(s.ListSQLInstances()))}})
+	return e.err
+}
+
+// sample is a value of a metric with its labels as name, value pairs.
+type sample struct {
+	labels []string
+	value  int64
+}
+
+// encoder writes metric families in the Prometheus text format, keeping the first error.
+type encoder struct {
+	w   io.Writer
+	err error
+}
+
+// family writes a metric family with its samples, sorted by their labels.
+func (e *encoder) family(name, metricType, help string, samples []sample) {
+	if e.err != nil {
+		return
+	}
+
+	lines := make([]string, 0, len(samples))
+	for _, s := range samples {
+		lines = append(lines, name+formatLabels(s.labels)+" "+strconv.FormatInt(s.value, 10)+"\n")
+	}
+	slices.Sort(lines)
+
+	_, e.err = fmt.Fprintf(e.w, "# HELP %s %s\n# TYPE %s %s\n%s", name, help, name, metricType, strings.Join(lines, ""))
+}
+
+// formatLabels formats label name, value pairs like {bucket="a",method="GET"}, or "" without labels.
+func formatLabels(labels []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	pairs := make([]string, 0, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, labels[i]+`="`+labelEscaper.Replace(labels[i+1])+`"`)
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// labelEscaper escapes label values as the Prometheus text format requires.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+	"github.com/katharinasick/gcp-api-mock/internal/store"
+)
+
+// newTestStore returns a store with a large bucket, a small one and an empty one.
+func newTestStore(t *testing.T) *store.Store {
+	t.Helper()
+	s := store.New()
+	for _, name := range []string{"large", "small", "empty"} {
+		if _, err := s.CreateBucket(&storage.BucketInsertRequest{Name: name}); err != nil {
+			t.Fatalf("CreateBucket() error: %v", err)
+		}
+	}
+	s.CreateObject("large", "a.bin", "application/octet-stream", make([]byte, 300), nil)
+	s.CreateObject("small", "a.txt", "text/plain", []byte("hello"), nil)
+	s.CreateObject("small", "b.txt", "text/plain", []byte("world"), nil)
+	return s
+}
+
+func TestMetrics_Write(t *testing.T) {
+	s := newTestStore(t)
+	m := New()
+	m.ObserveRequest("storage.googleapis.com", "GET", 200)
+	m.ObserveRequest("storage.googleapis.com", "GET", 200)
+	m.ObserveRequest("storage.googleapis.com", "GET", 404)
+	m.ObserveBucketRequest("small", "GET")
+	m.ObserveBucketRequest(`we"ird`, "PUT")
+
+	var buf bytes.Buffer
+	if err := m.Write(&buf, s); err != nil {
+		t.Fatalf("Write() error: %v", err)
+	}
+	output := buf.String()
+
+	for _, want := range []string{
+		"# TYPE gcp_mock_requests_total counter\n",
+		`gcp_mock_requests_total{service="storage.googleapis.com",method="GET",code="200"} 2` + "\n",
+		`gcp_mock_requests_total{service="storage.googleapis.com",method="GET",code="404"} 1` + "\n",
+		"gcp_mock_storage_buckets 3\n",
+		"gcp_mock_storage_objects 3\n",
+		"gcp_mock_storage_bytes 310\n",
+		`gcp_mock_storage_bucket_objects{bucket="small"} 2` + "\n",
+		`gcp_mock_storage_bucket_bytes{bucket="large"} 300` + "\n",
+		`gcp_mock_storage_bucket_bytes{bucket="empty"} 0` + "\n",
+		`gcp_mock_storage_bucket_requests_total{bucket="small",method="GET"} 1` + "\n",
+		`gcp_mock_storage_bucket_requests_total{bucket="we\"ird",method="PUT"} 1` + "\n",
+		"gcp_mock_sql_instances 0\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+}
+
+func TestMetrics_TopBuckets(t *testing.T) {
+	s := newTestStore(t)
+	m := New()
+	for range 3 {
+		m.ObserveBucketRequest("empty", "GET")
+	}
+	m.ObserveBucketRequest("small", "GET")
+
+	tests := []struct {
+		name    string
+		n       int
+		orderBy string
+		want    []string
+		wantErr bool
+	}{
+		{"by bytes", 0, "", []string{"large", "small", "empty"}, false},
+		{"top 2 by bytes", 2, "bytes", []string{"large", "small"}, false},
+		{"by objects", 0, "objects", []string{"small", "large", "empty"}, false},
+		{"by requests", 1, "requests", []string{"empty"}, false},
+		{"invalid order", 0, "age", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats, err := m.TopBuckets(s, tt.n, tt.orderBy)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TopBuckets() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var got []string
+			for _, b := range stats {
+				got = append(got, b.Bucket)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("TopBuckets() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	stats, _ := m.TopBuckets(s, 1, "objects")
+	if stats[0].ObjectCount != 2 || stats[0].UsedBytes != 10 || stats[0].Requests != 1 {
+		t.Errorf("unexpected stats of bucket small: %+v", stats[0])
+	}
+}
@@ -1,42 +1,103 @@
-// Package middleware provides HTTP middleware for the GCP API Mock.
 package middleware
 
 import (
+	"bytes"
+	"io"
 	"net/http"
-	"strings"
+
+	"github.com/katharinasick/gcp-api-mock/internal/recorder"
 )
 
-// RequestLoggerFunc is a function type for logging requests to the UI.
-type RequestLoggerFunc func(method, path string, status int)
+// RequestLoggerFunc is a function type for logging requests to the UI. The bodies of the exchange are cut off
+// at the size limit of the logger; requestTruncated and responseTruncated report whether they were.
+type RequestLoggerFunc func(ex *recorder.Exchange, requestTruncated, responseTruncated bool)
+
+// cappedResponseWriter wraps http.ResponseWriter to capture the status code and the start of the body.
+type cappedResponseWriter struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+	maxSize    int
+	truncated  bool
+}
+
+// WriteHeader captures the status code before writing it.
+func (rw *cappedResponseWriter) WriteHeader(code int) {
+	rw.statusCode = code
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+// Write captures the body up to the size limit before writing it.
+func (rw *cappedResponseWriter) Write(b []byte) (int, error) {
+	if remaining := rw.maxSize - rw.body.Len(); len(b) > remaining {
+		rw.body.Write(b[:remaining])
+		rw.truncated = true
+	} else {
+		rw.body.Write(b)
+	}
+	return rw.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the underlying ResponseWriter, for http.ResponseController.
+func (rw *cappedResponseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
 
-// APILogger creates middleware that logs API requests (non-UI, non-static) to the request logger.
-func APILogger(logFn RequestLoggerFunc) func(http.Handler) http.Handler {
+// APILogger creates middleware that logs API requests (non-UI, non-static) to the request logger,
+// along with their headers and the first maxBodySize bytes of the request and response bodies.
+func APILogger(logFn RequestLoggerFunc, maxBodySize int) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Wrap response writer to capture status code
-			wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			// Only log API requests (storage, sql), not UI or static files
+			if !shouldLogRequest(r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
 
-			next.ServeHTTP(wrapped, r)
+			// Read the start of the request body; the handler still gets all of it
+			var reqBody []byte
+			var reqTruncated bool
+			if r.Body != nil {
+				var err error
+				reqBody, err = io.ReadAll(io.LimitReader(r.Body, int64(maxBodySize)+1))
+				if err != nil {
+					http.Error(w, "failed to read request body", http.StatusBadRequest)
+					return
+				}
+				r.Body = struct {
+					io.Reader
+					io.Closer
+				}{io.MultiReader(bytes.NewReader(reqBody), r.Body), r.Body}
 
-			// Only log API requests (storage, sql), not UI or static files
-			path := r.URL.Path
-			if shouldLogRequest(path) {
-				logFn(r.Method, path, wrapped.statusCode)
+				if len(reqBody) > maxBodySize {
+					reqBody, reqTruncated = reqBody[:maxBodySize], true
+				}
 			}
+
+			// Wrap response writer to capture status code and body
+			wrapped := &cappedResponseWriter{ResponseWriter: w, statusCode: http.StatusOK, maxSize: maxBodySize}
+
+			next.ServeHTTP(wrapped, r)
+
+			logFn(&recorder.Exchange{
+				Request: recorder.RecordedRequest{
+					Method: r.Method,
+					URL:    r.URL.RequestURI(),
+					Header: r.Header.Clone(),
+					Body:   reqBody,
+				},
+				Response: recorder.RecordedResponse{
+					Status: wrapped.statusCode,
+					Header: w.Header().Clone(),
+					Body:   wrapped.body.Bytes(),
+				},
+			}, reqTruncated, wrapped.truncated)
 		})
 	}
 }
 
 // shouldLogRequest determines if a request should be logged to the UI.
-// It logs storage and SQL API requests, but not UI or static file requests.
+// It logs storage, SQL, Firestore, registry and Cloud Run API requests, but not UI or static file requests.
 func shouldLogRequest(path string) bool {
-	// Log Cloud Storage API requests
-	if strings.HasPrefix(path, "/storage/") || strings.HasPrefix(path, "/upload/storage/") || strings.HasPrefix(path, "/download/storage/") {
-		return true
-	}
-	// Log Cloud SQL API requests
-	if strings.HasPrefix(path, "/sql/") {
-		return true
-	}
-	return false
+	return apiService(path) != ""
 }
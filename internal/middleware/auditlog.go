@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/katharinasick/gcp-api-mock/internal/auditlog"
+	"github.com/katharinasick/gcp-api-mock/internal/gcperror"
+)
+
+// AuditLog creates middleware that writes a Cloud Audit Logs entry for each admin action, like creating a bucket
+// or a Cloud SQL user, including failed ones. The principal is taken from the email claim of JWT Bearer tokens.
+func AuditLog(logger *auditlog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			action, ok := auditlog.Classify(r)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			wrapped := &recordingResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+
+			next.ServeHTTP(wrapped, r)
+
+			var principal string
+			if token, ok := bearerToken(r); ok {
+				principal = principalFromToken(token)
+			}
+			logger.Log(action, r, principal, wrapped.statusCode, errorMessage(wrapped))
+		})
+	}
+}
+
+// errorMessage returns the message of the JSON error a request was answered with, if it failed.
+func errorMessage(rw *recordingResponseWriter) string {
+	if rw.statusCode < 400 {
+		return ""
+	}
+
+	var resp gcperror.Response
+	if err := json.Unmarshal(rw.body.Bytes(), &resp); err != nil {
+		return ""
+	}
+	return resp.Error.Message
+}
@@ -0,0 +1,202 @@
+package middleware
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/katharinasick/gcp-api-mock/internal/gcperror"
+	"github.com/katharinasick/gcp-api-mock/internal/registry"
+	"github.com/katharinasick/gcp-api-mock/internal/s3"
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+)
+
+// Auth creates middleware that requires API requests, including path-style requests of the Cloud Storage
+// XML API, to carry a Bearer token. Requests without a token get 401. Tokens are not verified, but if a
+// token is a JWT that names a project (a project_id claim or a service account email), requests for a
+// different project get 403. Errors are written in the format of the API being called.
+// If S3 is enabled, AWS-signed requests are left to the S3 layer, which verifies their signatures.
+func Auth(s3Enabled bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			service := apiService(r.URL.Path)
+			_, xmlAPI := storageBucket(r.URL.Path)
+			if (service == "" && !xmlAPI) || (s3Enabled && s3.IsSigned(r)) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			token, ok := bearerToken(r)
+			if !ok {
+				// Docker only sends the credentials of its credential helper after a Basic challenge
+				if service == ServiceArtifactRegistry {
+					w.Header().Set("WWW-Authenticate", `Basic realm="Artifact Registry"`)
+				} else {
+					w.Header().Set("WWW-Authenticate", `Bearer realm="https://accounts.google.com/"`)
+				}
+				respondAuthError(w, service, http.StatusUnauthorized,
+					"Anonymous caller does not have access to this resource. Request is missing a Bearer token.")
+				return
+			}
+
+			tokenProject := projectFromToken(token)
+			requestProject := projectFromRequest(r)
+			if tokenProject != "" && requestProject != "" && tokenProject != requestProject {
+				respondAuthError(w, service, http.StatusForbidden,
+					"The caller does not have permission: token belongs to project "+tokenProject+", not "+requestProject+".")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// bearerToken returns the Bearer token from the Authorization header.
+// Docker clients send the token as the password of Basic auth (e.g. user "oauth2accesstoken"),
+// so that is accepted for registry requests too.
+func bearerToken(r *http.Request) (string, bool) {
+	if strings.HasPrefix(r.URL.Path, "/v2/") {
+		if _, password, ok := r.BasicAuth(); ok && password != "" {
+			return password, true
+		}
+	}
+
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// tokenClaims are the claims of a JWT the mock looks at.
+type tokenClaims struct {
+	ProjectID string `json:"project_id"`
+	Email     string `json:"email"`
+	Subject   string `json:"sub"`
+}
+
+// parseTokenClaims returns the claims of a token if it is a JWT.
+// Tokens are not verified, and opaque access tokens have no claims.
+func parseTokenClaims(token string) (tokenClaims, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return tokenClaims{}, false
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return tokenClaims{}, false
+	}
+
+	var claims tokenClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return tokenClaims{}, false
+	}
+	return claims, true
+}
+
+// projectFromToken returns the project a token belongs to, if it can be determined.
+// Only JWTs carry a project; opaque access tokens return an empty string.
+func projectFromToken(token string) string {
+	claims, ok := parseTokenClaims(token)
+	if !ok {
+		return ""
+	}
+
+	if claims.ProjectID != "" {
+		return claims.ProjectID
+	}
+	for _, email := range []string{claims.Email, claims.Subject} {
+		if project := projectFromServiceAccount(email); project != "" {
+			return project
+		}
+	}
+
+	return ""
+}
+
+// principalFromToken returns the email of the account a token was issued to, if it can be determined.
+func principalFromToken(token string) string {
+	claims, ok := parseTokenClaims(token)
+	if !ok {
+		return ""
+	}
+
+	for _, email := range []string{claims.Email, claims.Subject} {
+		if strings.Contains(email, "@") {
+			return email
+		}
+	}
+	return ""
+}
+
+// projectFromServiceAccount returns the project of a service account email
+// like name@project.iam.gserviceaccount.com.
+func projectFromServiceAccount(email string) string {
+	_, domain, found := strings.Cut(email, "@")
+	if !found {
+		return ""
+	}
+
+	project, found := strings.CutSuffix(domain, ".iam.gserviceaccount.com")
+	if !found {
+		return ""
+	}
+	return project
+}
+
+// projectFromRequest returns the project a request targets, if it names one.
+// Cloud SQL, Compute Engine, Firestore, Cloud Scheduler, Eventarc, Memorystore, GKE, Organization Policy, Cloud Run, Cloud Monitoring and Cloud Logging logs requests carry it in the path, Cloud Storage requests in the project query parameter
+// or the x-goog-user-project header.
+func projectFromRequest(r *http.Request) string {
+	for _, prefix := range []string{"/sql/v1beta4/projects/", "/compute/v1/projects/", "/v1/projects/", "/v2/projects/", "/v3/projects/"} {
+		if rest, found := strings.CutPrefix(r.URL.Path, prefix); found {
+			project, _, _ := strings.Cut(rest, "/")
+			return project
+		}
+	}
+
+	if project := r.URL.Query().Get("project"); project != "" {
+		return project
+	}
+	return r.Header.Get("X-Goog-User-Project")
+}
+
+// respondAuthError writes an authentication error in the format of the API being called, service, which
+// is "" for the XML API. The v1 APIs report missing credentials against the Authorization header, newer
+// APIs only have the status, and Docker clients only understand registry errors.
+func respondAuthError(w http.ResponseWriter, service string, statusCode int, message string) {
+	switch service {
+	case "":
+		code := "AuthenticationRequired"
+		if statusCode == http.StatusForbidden {
+			code = "AccessDenied"
+		}
+		w.Header().Set("Content-Type", "application/xml; charset=UTF-8")
+		w.WriteHeader(statusCode)
+		io.WriteString(w, xml.Header)
+		xml.NewEncoder(w).Encode(storage.XMLError{Code: code, Message: message})
+	case ServiceArtifactRegistry:
+		code := "UNAUTHORIZED"
+		if statusCode == http.StatusForbidden {
+			code = "DENIED"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(statusCode)
+		json.NewEncoder(w).Encode(registry.ErrorResponse{Errors: []registry.Error{{Code: code, Message: message}}})
+	case ServiceStorage, ServiceSQLAdmin, ServiceCompute:
+		if statusCode == http.StatusForbidden {
+			gcperror.New(statusCode, message, "forbidden").Write(w)
+			return
+		}
+		gcperror.New(statusCode, message, "required").WithLocation("header", "Authorization").Write(w)
+	default:
+		gcperror.New(statusCode, message, "").Write(w)
+	}
+}
@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+)
+
+// CorsLookup returns the CORS configuration of a bucket, and false if the bucket doesn't exist.
+type CorsLookup func(bucket string) ([]storage.Cors, bool)
+
+// CORS creates middleware that handles cross-origin requests for objects according to the
+// CORS configuration of their bucket, like Cloud Storage does for browser uploads and downloads.
+// Preflight requests are answered directly: with the allowed method and headers if a rule matches,
+// or 403 otherwise. Other cross-origin requests get the CORS response headers of the matching rule.
+// Requests that aren't for objects of an existing bucket are passed through unchanged.
+// Reference: https://cloud.google.com/storage/docs/cross-origin
+func CORS(lookup CorsLookup) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			bucket, _ := storageBucket(r.URL.Path)
+			if bucket == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			rules, exists := lookup(bucket)
+			if !exists {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			requestMethod := r.Header.Get("Access-Control-Request-Method")
+			isPreflight := r.Method == http.MethodOptions && requestMethod != ""
+			if !isPreflight {
+				requestMethod = r.Method
+			}
+
+			rule := matchCorsRule(rules, origin, requestMethod)
+			w.Header().Add("Vary", "Origin")
+
+			if isPreflight {
+				if rule == nil {
+					http.Error(w, "CORS request not allowed by the bucket's CORS configuration", http.StatusForbidden)
+					return
+				}
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				w.Header().Set("Access-Control-Allow-Methods", requestMethod)
+				if headers := r.Header.Get("Access-Control-Request-Headers"); headers != "" {
+					w.Header().Set("Access-Control-Allow-Headers", headers)
+				}
+				if rule.MaxAgeSeconds > 0 {
+					w.Header().Set("Access-Control-Max-Age", strconv.Itoa(rule.MaxAgeSeconds))
+				}
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+
+			if rule != nil {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				if len(rule.ResponseHeader) > 0 {
+					w.Header().Set("Access-Control-Expose-Headers", strings.Join(rule.ResponseHeader, ", "))
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// matchCorsRule returns the first rule that allows the origin and method, or nil if none does.
+func matchCorsRule(rules []storage.Cors, origin, method string) *storage.Cors {
+	for i, rule := range rules {
+		originAllowed := false
+		for _, allowed := range rule.Origin {
+			if allowed == "*" || strings.EqualFold(allowed, origin) {
+				originAllowed = true
+				break
+			}
+		}
+		if !originAllowed {
+			continue
+		}
+
+		for _, allowed := range rule.Method {
+			if allowed == "*" || strings.EqualFold(allowed, method) {
+				return &rules[i]
+			}
+		}
+	}
+	return nil
+}
+
+// storageBucket returns the bucket of an object request, e.g. "my-bucket" for /storage/v1/b/my-bucket/o/file.txt,
+// /upload/storage/v1/b/my-bucket/o or the path-style /my-bucket/file.txt, and whether it is an XML API (path-style) request.
+// Path-style bucket requests like /my-bucket are included. Returns "" for requests that aren't for objects.
+func storageBucket(path string) (string, bool) {
+	for _, prefix := range []string{"/storage/v1/b/", "/upload/storage/v1/b/", "/download/storage/v1/b/", "/b/"} {
+		if rest, found := strings.CutPrefix(path, prefix); found {
+			bucket, objectPath, _ := strings.Cut(rest, "/")
+			if objectPath != "o" && !strings.HasPrefix(objectPath, "o/") {
+				return "", false
+			}
+			return bucket, false
+		}
+	}
+
+	// Other API paths and the routes of the mock itself are never path-style requests
+	if apiService(path) != "" {
+		return "", false
+	}
+	bucket, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	if slices.Contains(mockRouteSegments, bucket) {
+		return "", false
+	}
+
+	// Path-style requests; the caller checks whether the bucket exists
+	return bucket, bucket != ""
+}
+
+// mockRouteSegments are the first path segments of the routes of the mock itself and of its metadata server.
+var mockRouteSegments = []string{"admin", "ui", "static", "discovery", "health", "ready", "version", "capabilities", "metrics", "computeMetadata", "oauth2"}
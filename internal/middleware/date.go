@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/clock"
+	"github.com/katharinasick/gcp-api-mock/internal/gcperror"
+)
+
+// ClockSkewHeader is the request header that overrides the clock skew for a single request, e.g. "-10m".
+const ClockSkewHeader = "X-Mock-Clock-Skew"
+
+// Date creates middleware that sets the Date response header to the virtual time shifted by the clock's skew,
+// or by the skew of the X-Mock-Clock-Skew request header, so clients validating the server time against their
+// own see the mock's clock instead of the host's. The header is set before the handler runs, so handlers can
+// derive headers like Expires from it; the server keeps a Date header that is already set.
+func Date(clk *clock.Clock) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			skew := clk.Skew()
+			if value := r.Header.Get(ClockSkewHeader); value != "" {
+				parsed, err := time.ParseDuration(value)
+				if err != nil {
+					gcperror.New(http.StatusBadRequest, "Invalid "+ClockSkewHeader+" header: "+err.Error(), "invalid").Write(w)
+					return
+				}
+				skew = parsed
+			}
+
+			w.Header().Set("Date", clk.Now().Add(skew).UTC().Format(http.TimeFormat))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
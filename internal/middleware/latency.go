@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/latency"
+)
+
+// Latency creates middleware that delays API requests according to the injector's profile.
+// The delay is cut short if the client goes away.
+func Latency(injector *latency.Injector) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			op := latency.Operation(r)
+			if op == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if delay := injector.Delay(op); delay > 0 {
+				timer := time.NewTimer(delay)
+				select {
+				case <-timer.C:
+				case <-r.Context().Done():
+					timer.Stop()
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/katharinasick/gcp-api-mock/internal/metrics"
+)
+
+// Metrics counts the served API requests, and the Cloud Storage requests for each bucket.
+// Only requests for buckets that exist when the request arrives are counted per bucket, so requests
+// for mistyped or random names don't add series; path-style requests for other names aren't API requests.
+func Metrics(m *metrics.Metrics, bucketExists func(bucket string) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			service := apiService(r.URL.Path)
+			bucket, pathStyle := storageBucket(r.URL.Path)
+			if bucket == "" && service == ServiceStorage {
+				bucket = bucketOfBucketRequest(r.URL.Path)
+			}
+			if bucket != "" && !bucketExists(bucket) {
+				bucket = ""
+			}
+			if pathStyle && bucket != "" {
+				service = ServiceStorage
+			}
+			if service == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			next.ServeHTTP(wrapped, r)
+
+			m.ObserveRequest(service, r.Method, wrapped.statusCode)
+			if bucket != "" {
+				m.ObserveBucketRequest(bucket, r.Method)
+			}
+		})
+	}
+}
+
+// bucketOfBucketRequest returns the bucket of a JSON API request for a bucket rather than its objects,
+// e.g. "my-bucket" for /storage/v1/b/my-bucket or /storage/v1/b/my-bucket/iam. Returns "" for other requests.
+func bucketOfBucketRequest(path string) string {
+	for _, prefix := range []string{"/storage/v1/b/", "/b/"} {
+		if rest, found := strings.CutPrefix(path, prefix); found {
+			bucket, _, _ := strings.Cut(rest, "/")
+			return bucket
+		}
+	}
+	return ""
+}
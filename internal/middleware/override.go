@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/katharinasick/gcp-api-mock/internal/override"
+)
+
+// OverrideHeader is set to the ID of the rule that answered a request, so stubbed responses stand out
+// in the request log.
+const OverrideHeader = "X-Mock-Override"
+
+// Override creates middleware that answers requests matching a rule with the rule's response
+// instead of passing them on.
+func Override(overrides *override.Overrides) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rule, ok := overrides.Match(r)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set(OverrideHeader, rule.ID)
+			rule.Write(w)
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/katharinasick/gcp-api-mock/internal/gcperror"
+	"github.com/katharinasick/gcp-api-mock/internal/readonly"
+)
+
+// ReadOnlyHeader is set on the responses of mutating requests that read-only mode answered,
+// so they stand out in the request log.
+const ReadOnlyHeader = "X-Mock-Read-Only"
+
+// readOnlyMessage is the message mutating requests are rejected with in reject mode.
+const readOnlyMessage = "The mock is in read-only mode; the request was not applied."
+
+// ReadOnly creates middleware that keeps mutating requests from changing state while the switch is on.
+// In success mode they get a synthetic success: DELETEs get 204, other requests get 200 with their JSON
+// object body echoed, or an empty object. In reject mode they get 403.
+func ReadOnly(sw *readonly.Switch) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mode := sw.Mode()
+			if mode == readonly.ModeOff || !readonly.IsMutating(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set(ReadOnlyHeader, string(mode))
+			if mode == readonly.ModeReject {
+				gcperror.New(http.StatusForbidden, readOnlyMessage, "forbidden").Write(w)
+				return
+			}
+
+			if r.Method == http.MethodDelete {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+			w.WriteHeader(http.StatusOK)
+			w.Write(syntheticBody(r))
+		})
+	}
+}
+
+// syntheticBody returns the body of a synthetic success: the request body if it is a JSON object,
+// which resembles the resource the request would have created or changed, or an empty object.
+func syntheticBody(r *http.Request) []byte {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return []byte("{}")
+	}
+	var object map[string]json.RawMessage
+	if json.Unmarshal(body, &object) != nil {
+		return []byte("{}")
+	}
+	return body
+}
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/recorder"
+)
+
+// recordingResponseWriter wraps http.ResponseWriter to capture the status code and body.
+type recordingResponseWriter struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+// WriteHeader captures the status code before writing it.
+func (rw *recordingResponseWriter) WriteHeader(code int) {
+	rw.statusCode = code
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+// Write captures the body before writing it.
+func (rw *recordingResponseWriter) Write(b []byte) (int, error) {
+	rw.body.Write(b)
+	return rw.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the underlying ResponseWriter, for http.ResponseController.
+func (rw *recordingResponseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// Record creates middleware that records API requests (non-UI, non-static) and their responses
+// while the recorder is active.
+func Record(rec *recorder.Recorder) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !rec.Active() || !shouldLogRequest(r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Buffer the request body so it can be both recorded and handled
+			var reqBody []byte
+			if r.Body != nil {
+				var err error
+				reqBody, err = io.ReadAll(r.Body)
+				if err != nil {
+					http.Error(w, "failed to read request body", http.StatusBadRequest)
+					return
+				}
+				r.Body = io.NopCloser(bytes.NewReader(reqBody))
+			}
+
+			started := time.Now().UTC()
+			wrapped := &recordingResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+
+			next.ServeHTTP(wrapped, r)
+
+			err := rec.Record(&recorder.Exchange{
+				Time: started,
+				Request: recorder.RecordedRequest{
+					Method: r.Method,
+					URL:    r.URL.RequestURI(),
+					Header: r.Header.Clone(),
+					Body:   reqBody,
+				},
+				Response: recorder.RecordedResponse{
+					Status: wrapped.statusCode,
+					Header: w.Header().Clone(),
+					Body:   wrapped.body.Bytes(),
+				},
+			})
+			if err != nil {
+				log.Printf("Failed to record request: %v", err)
+			}
+		})
+	}
+}
@@ -4,14 +4,21 @@ import (
 	"log"
 	"net/http"
 	"runtime/debug"
+
+	"github.com/katharinasick/gcp-api-mock/internal/gcperror"
 )
 
 // Recovery middleware recovers from panics and returns a 500 error.
+// API requests get the JSON error envelope client libraries expect, everything else a plain text error.
 func Recovery(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if err := recover(); err != nil {
 				log.Printf("panic recovered: %v\n%s", err, debug.Stack())
+				if shouldLogRequest(r.URL.Path) {
+					gcperror.New(http.StatusInternalServerError, "Internal Server Error", "internalError").Write(w)
+					return
+				}
 				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 			}
 		}()
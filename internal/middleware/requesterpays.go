@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+
+	"github.com/katharinasick/gcp-api-mock/internal/gcperror"
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+)
+
+// requesterPaysMessage is the message Cloud Storage answers requests without a billing project with.
+const requesterPaysMessage = "Bucket is a requester pays bucket but no user project provided."
+
+// RequesterPays creates middleware that rejects object requests for Requester Pays buckets that don't name
+// a project to bill, with the userProject query parameter or the x-goog-user-project header, with 400.
+// Bucket metadata requests are allowed without a project, so the bucket owner can still change the billing configuration.
+// Reference: https://cloud.google.com/storage/docs/requester-pays
+func RequesterPays(requesterPays func(bucket string) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			bucket, xmlAPI := storageBucket(r.URL.Path)
+			if bucket == "" || !requesterPays(bucket) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if r.URL.Query().Get("userProject") != "" || r.Header.Get("X-Goog-User-Project") != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if xmlAPI {
+				w.Header().Set("Content-Type", "application/xml; charset=UTF-8")
+				w.WriteHeader(http.StatusBadRequest)
+				io.WriteString(w, xml.Header)
+				xml.NewEncoder(w).Encode(storage.XMLError{Code: "UserProjectMissing", Message: requesterPaysMessage})
+				return
+			}
+			gcperror.New(http.StatusBadRequest, requesterPaysMessage, "userProjectMissing").
+				WithLocation("parameter", "userProject").
+				Write(w)
+		})
+	}
+}
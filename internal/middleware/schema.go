@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/katharinasick/gcp-api-mock/internal/gcperror"
+	"github.com/katharinasick/gcp-api-mock/internal/schema"
+)
+
+// SchemaValidation creates middleware that checks the successful JSON responses of the methods known to
+// the validator against their schemas and logs divergences. If fail is true, diverging responses are
+// replaced with a 500 error listing the divergences, so tests notice drift right away.
+func SchemaValidation(validator *schema.Validator, fail bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			method := validator.Method(r)
+			if method == nil || method.Response == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			wrapped := &bufferingResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			next.ServeHTTP(wrapped, r)
+
+			body := wrapped.body.Bytes()
+			mediaType, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type"))
+			if mediaType == "application/json" && wrapped.statusCode < 300 && len(body) > 0 {
+				divergences, err := validator.Validate(r, body)
+				if err != nil {
+					divergences = []string{err.Error()}
+				}
+				if len(divergences) > 0 {
+					message := "response of " + method.ID + " diverges from the discovery document: " + strings.Join(divergences, "; ")
+					log.Printf("%s %s: %s", r.Method, r.URL.Path, message)
+					if fail {
+						w.Header().Del("Content-Length")
+						gcperror.New(http.StatusInternalServerError, message, "internalError").Write(w)
+						return
+					}
+				}
+			}
+
+			w.WriteHeader(wrapped.statusCode)
+			w.Write(body)
+		})
+	}
+}
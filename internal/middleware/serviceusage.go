@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/katharinasick/gcp-api-mock/internal/gcperror"
+	"github.com/katharinasick/gcp-api-mock/internal/registry"
+)
+
+// Service names of the emulated APIs, as used by the Service Usage API.
+const (
+	ServiceStorage          = "storage.googleapis.com"
+	ServiceSQLAdmin         = "sqladmin.googleapis.com"
+	ServiceFirestore        = "firestore.googleapis.com"
+	ServiceArtifactRegistry = "artifactregistry.googleapis.com"
+	ServiceRun              = "run.googleapis.com"
+	ServiceMonitoring       = "monitoring.googleapis.com"
+	ServiceLogging          = "logging.googleapis.com"
+	ServiceScheduler        = "cloudscheduler.googleapis.com"
+	ServiceEventarc         = "eventarc.googleapis.com"
+	ServiceRedis            = "redis.googleapis.com"
+	ServiceContainer        = "container.googleapis.com"
+	ServiceCompute          = "compute.googleapis.com"
+	ServiceBillingBudgets   = "billingbudgets.googleapis.com"
+	ServiceOrgPolicy        = "orgpolicy.googleapis.com"
+)
+
+// serviceTitles are the display names used in SERVICE_DISABLED errors.
+var serviceTitles = map[string]string{
+	ServiceStorage:          "Cloud Storage JSON API",
+	ServiceSQLAdmin:         "Cloud SQL Admin API",
+	ServiceFirestore:        "Cloud Firestore API",
+	ServiceArtifactRegistry: "Artifact Registry API",
+	ServiceRun:              "Cloud Run Admin API",
+	ServiceMonitoring:       "Cloud Monitoring API",
+	ServiceLogging:          "Cloud Logging API",
+	ServiceScheduler:        "Cloud Scheduler API",
+	ServiceEventarc:         "Eventarc API",
+	ServiceRedis:            "Google Cloud Memorystore for Redis API",
+	ServiceContainer:        "Kubernetes Engine API",
+	ServiceCompute:          "Compute Engine API",
+	ServiceBillingBudgets:   "Cloud Billing Budget API",
+	ServiceOrgPolicy:        "Organization Policy API",
+}
+
+// ServiceUsage creates middleware that rejects requests to disabled services with 403 SERVICE_DISABLED,
+// like a real project in which the API has not been enabled. This catches code that calls APIs it shouldn't.
+func ServiceUsage(disabled []string) func(http.Handler) http.Handler {
+	isDisabled := make(map[string]bool, len(disabled))
+	for _, service := range disabled {
+		isDisabled[service] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			service := apiService(r.URL.Path)
+			if service == "" || !isDisabled[service] {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			respondServiceDisabled(w, service, projectFromRequest(r))
+		})
+	}
+}
+
+// locationServices are the services of the v1 APIs with resources named projects/{project}/locations/{location}/{collection},
+// by collection. Operations are shared by several of them, so they aren't attributed to a service.
+var locationServices = map[string]string{
+	"jobs":      ServiceScheduler,
+	"triggers":  ServiceEventarc,
+	"instances": ServiceRedis,
+	"clusters":  ServiceContainer,
+}
+
+// apiService returns the service an API request is for, or "" if it is not an API request.
+// /b/... is the Cloud Storage JSON API without its /storage/v1 prefix.
+// The APIs under /v1/projects/ and /v2/projects/ are told apart by the collection segment after the project, or after
+// the location for projects/{project}/locations/{location}/... paths, never by words in resource IDs: a Memorystore
+// instance named jobs-cache is still a Memorystore instance. Cloud Logging, Organization Policy, Cloud Run and the
+// Docker registry share the /v2/ prefix; Cloud Logging paths are entries methods or a project's logs, Organization
+// Policy paths a project's policies, Cloud Run paths a project's locations and everything else is the registry.
+func apiService(path string) string {
+	switch {
+	case strings.HasPrefix(path, "/storage/") || strings.HasPrefix(path, "/upload/storage/") || strings.HasPrefix(path, "/download/storage/"):
+		return ServiceStorage
+	case path == "/b" || strings.HasPrefix(path, "/b/"):
+		return ServiceStorage
+	case strings.HasPrefix(path, "/sql/"):
+		return ServiceSQLAdmin
+	case strings.HasPrefix(path, "/compute/"):
+		return ServiceCompute
+	case strings.HasPrefix(path, "/v1/billingAccounts/"):
+		return ServiceBillingBudgets
+	case strings.HasPrefix(path, "/v2/entries:"):
+		return ServiceLogging
+	}
+
+	// segments are like ["v1", "projects", "{project}", "locations", "{location}", "instances", ...]
+	segments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	isProject := len(segments) >= 4 && segments[1] == "projects" && segments[2] != ""
+	switch segments[0] {
+	case "v1":
+		switch {
+		case isProject && segments[3] == "databases":
+			return ServiceFirestore
+		case isProject && segments[3] == "locations" && len(segments) >= 6:
+			return locationServices[segments[5]]
+		}
+	case "v2":
+		switch {
+		case isProject && segments[3] == "logs":
+			return ServiceLogging
+		case isProject && segments[3] == "policies":
+			return ServiceOrgPolicy
+		case isProject && segments[3] == "locations":
+			return ServiceRun
+		default:
+			return ServiceArtifactRegistry
+		}
+	case "v3":
+		if len(segments) >= 3 && segments[1] == "projects" {
+			return ServiceMonitoring
+		}
+	}
+	return ""
+}
+
+// respondServiceDisabled writes a SERVICE_DISABLED error in the format of the disabled API.
+// Docker clients only understand registry errors, so registry requests get a DENIED registry error.
+func respondServiceDisabled(w http.ResponseWriter, service, project string) {
+	message := serviceTitles[service] + " has not been used before or it is disabled."
+	if project != "" {
+		message = serviceTitles[service] + " has not been used in project " + project + " before or it is disabled. " +
+			"Enable it by visiting https://console.developers.google.com/apis/api/" + service + "/overview?project=" + project + " then retry."
+	}
+
+	if service == ServiceArtifactRegistry {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(registry.ErrorResponse{
+			Errors: []registry.Error{{Code: "DENIED", Message: message}},
+		})
+		return
+	}
+
+	metadata := map[string]string{"service": service}
+	if project != "" {
+		metadata["consumer"] = "projects/" + project
+	}
+	gcperror.New(http.StatusForbidden, message, "accessNotConfigured").
+		WithErrorInfo("SERVICE_DISABLED", "googleapis.com", metadata).
+		Write(w)
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/gcperror"
+	"github.com/katharinasick/gcp-api-mock/internal/latency"
+	"github.com/katharinasick/gcp-api-mock/internal/slo"
+)
+
+// SLOHeader marks the API requests the simulated objective failed, so they stand out in the request log.
+const SLOHeader = "X-Mock-SLO"
+
+// SLO creates middleware that delays API requests and fails some of them according to the simulator's
+// objective. Failed requests are answered without being served, so they don't change any state.
+// The delay is cut short if the client goes away.
+func SLO(simulator *slo.Simulator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if latency.Operation(r) == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			decision, ok := simulator.Next()
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if decision.Delay > 0 {
+				timer := time.NewTimer(decision.Delay)
+				select {
+				case <-timer.C:
+				case <-r.Context().Done():
+					timer.Stop()
+					return
+				}
+			}
+
+			if !decision.Fail {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set(SLOHeader, "failed")
+			if decision.Status == http.StatusTooManyRequests {
+				w.Header().Set("Retry-After", "1")
+				gcperror.New(decision.Status, "The rate of requests exceeds the simulated quota. Please try again later.", "rateLimitExceeded").Write(w)
+				return
+			}
+			gcperror.New(decision.Status, "We encountered an internal error. Please try again.", "backendError").Write(w)
+		})
+	}
+}
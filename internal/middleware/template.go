@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"bytes"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/katharinasick/gcp-api-mock/internal/gcperror"
+	"github.com/katharinasick/gcp-api-mock/internal/templating"
+)
+
+// TemplateHeader is set to the ID of the rule that rewrote a response, so templated responses stand out
+// in the request log.
+const TemplateHeader = "X-Mock-Template"
+
+// bufferingResponseWriter wraps http.ResponseWriter to hold back the status code and body until the
+// handler is done, so middleware can change or replace the response.
+type bufferingResponseWriter struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+// WriteHeader captures the status code without writing it.
+func (rw *bufferingResponseWriter) WriteHeader(code int) {
+	rw.statusCode = code
+}
+
+// Write captures the body without writing it.
+func (rw *bufferingResponseWriter) Write(b []byte) (int, error) {
+	return rw.body.Write(b)
+}
+
+// Unwrap returns the underlying ResponseWriter, for http.ResponseController.
+func (rw *bufferingResponseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// Template creates middleware that rewrites the JSON responses of requests matching a rule with the
+// rule's template. Other responses, like media downloads, are passed on unchanged.
+// Templates that fail are answered with 500, so a broken template doesn't go unnoticed.
+func Template(templates *templating.Templates) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rule, ok := templates.Match(r)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			wrapped := &bufferingResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			next.ServeHTTP(wrapped, r)
+
+			body := wrapped.body.Bytes()
+			mediaType, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type"))
+			if mediaType == "application/json" && len(body) > 0 {
+				templated, err := rule.Apply(body)
+				if err != nil {
+					log.Printf("Failed to apply response template to %s %s: %v", r.Method, r.URL.Path, err)
+					w.Header().Del("Content-Length")
+					w.Header().Set(TemplateHeader, rule.ID)
+					gcperror.New(http.StatusInternalServerError, err.Error(), "internalError").Write(w)
+					return
+				}
+				body = templated
+				w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+				w.Header().Set(TemplateHeader, rule.ID)
+			}
+
+			w.WriteHeader(wrapped.statusCode)
+			w.Write(body)
+		})
+	}
+}
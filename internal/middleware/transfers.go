@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/transfer"
+)
+
+// transferResponseWriter wraps http.ResponseWriter to capture the status code and whether writing failed.
+type transferResponseWriter struct {
+	http.ResponseWriter
+	statusCode int
+	writeErr   error
+}
+
+// WriteHeader captures the status code before writing it.
+func (rw *transferResponseWriter) WriteHeader(code int) {
+	rw.statusCode = code
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+// Write captures the first error, e.g. because the client went away mid download.
+func (rw *transferResponseWriter) Write(b []byte) (int, error) {
+	n, err := rw.ResponseWriter.Write(b)
+	if err != nil && rw.writeErr == nil {
+		rw.writeErr = err
+	}
+	return n, err
+}
+
+// Unwrap returns the underlying ResponseWriter, for http.ResponseController.
+func (rw *transferResponseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// transferBody wraps a request body to capture whether reading it failed, e.g. because the client went away mid upload.
+type transferBody struct {
+	io.ReadCloser
+	readErr error
+}
+
+// Read captures the first error other than the end of the body or an exceeded size limit.
+func (b *transferBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	var maxBytesErr *http.MaxBytesError
+	if err != nil && err != io.EOF && !errors.As(err, &maxBytesErr) && b.readErr == nil {
+		b.readErr = err
+	}
+	return n, err
+}
+
+// Transfers creates middleware that tracks uploads and downloads in flight and the sessions of resumable uploads,
+// so the server can let them finish before it shuts down. Transfers of large objects take longer than the server's
+// read and write timeouts, so those are lifted for them.
+func Transfers(tracker *transfer.Tracker) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !isTransfer(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			rc := http.NewResponseController(w)
+			if err := errors.Join(rc.SetReadDeadline(time.Time{}), rc.SetWriteDeadline(time.Time{})); err != nil {
+				log.Printf("Failed to lift the timeouts of %s %s: %v", r.Method, r.URL.Path, err)
+			}
+
+			uploadID := r.URL.Query().Get("upload_id")
+			if uploadID != "" {
+				tracker.TouchSession(uploadID)
+			}
+
+			done := tracker.Begin(r.Method, r.URL.Path)
+			wrapped := &transferResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			var body *transferBody
+			if r.Body != nil {
+				body = &transferBody{ReadCloser: r.Body}
+				r.Body = body
+			}
+
+			next.ServeHTTP(wrapped, r)
+
+			done(r.Context().Err() != nil || wrapped.writeErr != nil || (body != nil && body.readErr != nil))
+
+			switch {
+			case uploadID == "":
+				// A resumable upload starts a session the client uploads the content in
+				if id := wrapped.Header().Get("X-GUploader-UploadID"); id != "" && wrapped.statusCode == http.StatusOK {
+					tracker.TouchSession(id)
+				}
+			case endsUploadSession(wrapped.statusCode):
+				tracker.EndSession(uploadID)
+			}
+		})
+	}
+}
+
+// endsUploadSession reports whether a response to a resumable upload request ends the session:
+// it was completed, cancelled (499) or doesn't exist. Incomplete uploads are answered with 308,
+// and the client can retry a chunk after other errors.
+func endsUploadSession(status int) bool {
+	return (status >= 200 && status < 300) || status == http.StatusNotFound || status == http.StatusGone || status == 499
+}
+
+// isTransfer reports whether a request uploads or downloads content: media uploads and downloads of the
+// JSON API, object requests of the XML and S3 APIs and registry blobs.
+func isTransfer(r *http.Request) bool {
+	path := r.URL.Path
+	switch {
+	case strings.HasPrefix(path, "/upload/") || strings.HasPrefix(path, "/download/"):
+		return true
+	case r.URL.Query().Get("alt") == "media":
+		return true
+	case strings.HasPrefix(path, "/v2/") && strings.Contains(path, "/blobs/"):
+		return true
+	case apiService(path) != "" || (r.Method != http.MethodGet && r.Method != http.MethodPut):
+		return false
+	}
+
+	for _, prefix := range []string{"/admin/", "/ui/", "/static/", "/discovery/"} {
+		if strings.HasPrefix(path, prefix) {
+			return false
+		}
+	}
+
+	// Path-style object requests like GET /bucket/object
+	bucket, object, ok := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	return ok && bucket != "" && object != ""
+}
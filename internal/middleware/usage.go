@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/katharinasick/gcp-api-mock/internal/discovery"
+	"github.com/katharinasick/gcp-api-mock/internal/usage"
+)
+
+// internalRoutePrefixes are the path prefixes of the routes of the mock itself rather than of an emulated API.
+var internalRoutePrefixes = []string{"/ui/", "/admin/", "/static/", "/discovery/"}
+
+// internalRoutes are the paths of the routes of the mock itself rather than of an emulated API.
+var internalRoutes = []string{"/{$}", "/health", "/ready", "/version", "/capabilities", "/metrics"}
+
+// Usage counts the API calls per method and client. It must wrap the ServeMux directly, as the method is
+// found by the route pattern the mux matched: calls of emulated methods are counted by their discovery
+// method ID, like "storage.objects.get", others, like XML API calls, by the pattern.
+// Calls to the routes of the mock itself, like the dashboard, and unmatched requests aren't counted.
+func Usage(tracker *usage.Tracker) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			next.ServeHTTP(wrapped, r)
+
+			if r.Pattern == "" || isInternalRoute(r.Pattern) {
+				return
+			}
+			method := discovery.MethodID(r.Pattern)
+			if method == "" {
+				method = r.Pattern
+			}
+			tracker.Observe(method, usage.Client(r), wrapped.statusCode)
+		})
+	}
+}
+
+// isInternalRoute reports whether a route pattern is one of the mock itself.
+func isInternalRoute(pattern string) bool {
+	_, path, found := strings.Cut(pattern, " ")
+	if !found {
+		path = pattern
+	}
+	for _, prefix := range internalRoutePrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	for _, route := range internalRoutes {
+		if path == route {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// VirtualHost creates middleware that turns virtual-hosted-style requests like
+// GET https://my-bucket.storage.googleapis.com/file.txt into path-style requests (GET /my-bucket/file.txt),
+// so the XML API routes serve them. Requests for any other host are passed through unchanged.
+func VirtualHost(domains []string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			bucket := bucketFromHost(r.Host, domains)
+			if bucket == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			r2 := r.Clone(r.Context())
+			r2.URL.Path = "/" + bucket + r.URL.Path
+			if r.URL.RawPath != "" {
+				r2.URL.RawPath = "/" + bucket + r.URL.RawPath
+			}
+			next.ServeHTTP(w, r2)
+		})
+	}
+}
+
+// bucketFromHost returns the bucket of a virtual-hosted-style host like my-bucket.storage.googleapis.com,
+// or "" if host is not a subdomain of one of the domains.
+func bucketFromHost(host string, domains []string) string {
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	host = strings.ToLower(host)
+
+	for _, domain := range domains {
+		if bucket, found := strings.CutSuffix(host, "."+strings.ToLower(domain)); found && bucket != "" {
+			return bucket
+		}
+	}
+	return ""
+}
@@ -0,0 +1,372 @@
+// Package mirror keeps the buckets of a store in sync with a directory, so fixture payloads can be edited
+// like files: each bucket is a directory and each object a file at the path of its name, like
+// assets/img/logo.svg for the object img/logo.svg of the bucket assets. Objects written via the API are written
+// to their files, and files created, edited or deleted locally are uploaded to or deleted from the store.
+//
+// Changes are found by comparing both sides with the state of the last sync: objects by their generation
+// and files by their size and modification time. If both changed since, the object wins. Object names that
+// aren't valid file paths, like "dir/" or "a//b", are left out of the directory.
+package mirror
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+	"github.com/katharinasick/gcp-api-mock/internal/store"
+)
+
+// tempPrefix is the name prefix of the files objects are written to before they're renamed,
+// so editors and the mirror itself never see half-written files.
+const tempPrefix = ".gcp-mock-"
+
+// Mirror syncs the buckets of a store with the directories of a directory.
+// It is safe for concurrent access.
+type Mirror struct {
+	store *store.Store
+	dir   string
+
+	mu sync.Mutex
+	// buckets are the buckets that existed on both sides after the last sync
+	buckets map[string]bool
+	// files is a map of bucket name to object name to the state both sides had after the last sync
+	files map[string]map[string]fileState
+	// skipped holds the "bucket/object" keys of objects that can't be mirrored, so they're logged only once
+	skipped map[string]bool
+}
+
+// fileState is the state of an object and its file after a sync.
+type fileState struct {
+	generation int64
+	size       int64
+	modTime    time.Time
+}
+
+// New creates a Mirror of the buckets of a store in dir, creating the directory if it doesn't exist.
+// Call Sync to mirror the existing buckets and directories.
+func New(s *store.Store, dir string) (*Mirror, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Mirror{
+		store:   s,
+		dir:     dir,
+		buckets: make(map[string]bool),
+		files:   make(map[string]map[string]fileState),
+		skipped: make(map[string]bool),
+	}, nil
+}
+
+// Dir returns the directory the buckets are mirrored to.
+func (m *Mirror) Dir() string {
+	return m.dir
+}
+
+// Sync applies the changes of each side since the last sync to the other side. On the first sync, files win
+// over objects with the same name, so the directory can hold the fixtures the mock starts with.
+// Changes that can't be applied are logged and tried again on the next sync.
+func (m *Mirror) Sync() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	dirs, err := m.readBucketDirs()
+	if err != nil {
+		log.Printf("Failed to read the mirror directory: %v", err)
+		return
+	}
+	bucketNames := make(map[string]bool)
+	for _, bucket := range m.store.ListBuckets() {
+		bucketNames[bucket.Name] = true
+	}
+
+	// New directories become buckets and new buckets directories, before their content is synced
+	for name := range dirs {
+		if !bucketNames[name] && !m.buckets[name] {
+			if _, err := m.store.CreateBucket(&storage.BucketInsertRequest{Name: name}); err != nil {
+				m.logSkipped(name, "Failed to create bucket for directory %s: %v", name, err)
+				continue
+			}
+			bucketNames[name] = true
+		}
+	}
+	for name := range bucketNames {
+		if !dirs[name] && !m.buckets[name] {
+			if err := os.MkdirAll(filepath.Join(m.dir, name), 0o755); err != nil {
+				log.Printf("Failed to create directory for bucket %s: %v", name, err)
+				continue
+			}
+			dirs[name] = true
+		}
+	}
+
+	for name := range bucketNames {
+		if dirs[name] {
+			m.syncBucket(name)
+		}
+	}
+
+	// Buckets deleted on one side are deleted on the other, after their objects
+	for name := range m.buckets {
+		switch {
+		case bucketNames[name] && !dirs[name]:
+			m.syncBucket(name)
+			if err := m.store.DeleteBucket(name); err != nil {
+				log.Printf("Failed to delete bucket %s of a deleted directory: %v", name, err)
+				continue
+			}
+		case dirs[name] && !bucketNames[name]:
+			if err := os.RemoveAll(filepath.Join(m.dir, name)); err != nil {
+				log.Printf("Failed to remove the directory of deleted bucket %s: %v", name, err)
+				continue
+			}
+		default:
+			continue
+		}
+		delete(m.buckets, name)
+		delete(m.files, name)
+	}
+	for name := range bucketNames {
+		if dirs[name] {
+			m.buckets[name] = true
+		}
+	}
+}
+
+// syncBucket syncs the objects of a bucket with the files of its directory. The caller must hold m.mu.
+func (m *Mirror) syncBucket(bucketName string) {
+	objects := make(map[string]*storage.Object)
+	storeObjects, _ := m.store.ListObjects(bucketName, "", "")
+	for _, obj := range storeObjects {
+		if !fs.ValidPath(obj.Name) || isTempFile(obj.Name) {
+			m.logSkipped(bucketName+"/"+obj.Name, "Not mirroring object %s of bucket %s: its name isn't a valid file path", obj.Name, bucketName)
+			continue
+		}
+		objects[obj.Name] = obj
+	}
+	files, err := m.readFiles(bucketName)
+	if err != nil {
+		log.Printf("Failed to read the directory of bucket %s: %v", bucketName, err)
+		return
+	}
+
+	states := m.files[bucketName]
+	if states == nil {
+		states = make(map[string]fileState)
+		m.files[bucketName] = states
+	}
+
+	// Forget objects deleted on both sides
+	for name := range states {
+		if objects[name] == nil && files[name] == nil {
+			delete(states, name)
+		}
+	}
+
+	names := make(map[string]bool)
+	for name := range objects {
+		names[name] = true
+	}
+	for name := range files {
+		names[name] = true
+	}
+	for name := range names {
+		obj, hasObject := objects[name]
+		file, hasFile := files[name]
+		state, known := states[name]
+		objectChanged := hasObject && (!known || obj.Generation != state.generation)
+		fileChanged := hasFile && (!known || file.Size() != state.size || !file.ModTime().Equal(state.modTime))
+
+		var err error
+		switch {
+		case hasObject && hasFile && known && objectChanged:
+			err = m.writeFile(bucketName, obj, states)
+		case hasObject && hasFile && fileChanged:
+			err = m.uploadFile(bucketName, name, obj, states)
+		case hasObject && hasFile:
+			// Neither side changed
+		case hasObject && known && !objectChanged:
+			// The file was deleted locally
+			err = m.store.DeleteObject(bucketName, name)
+			delete(states, name)
+		case hasObject:
+			err = m.writeFile(bucketName, obj, states)
+		case hasFile && known && !fileChanged:
+			// The object was deleted via the API
+			err = m.removeFile(bucketName, name)
+			delete(states, name)
+		case hasFile:
+			err = m.uploadFile(bucketName, name, nil, states)
+		}
+		if err != nil {
+			log.Printf("Failed to mirror object %s of bucket %s: %v", name, bucketName, err)
+		}
+	}
+}
+
+// writeFile writes the content of an object to its file and records the state of both.
+func (m *Mirror) writeFile(bucketName string, obj *storage.Object, states map[string]fileState) error {
+	current, content, err := m.store.OpenObjectContent(bucketName, obj.Name)
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	path := m.filePath(bucketName, obj.Name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	temp, err := os.CreateTemp(filepath.Dir(path), tempPrefix+"*")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(temp, content); err != nil {
+		temp.Close()
+		os.Remove(temp.Name())
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		os.Remove(temp.Name())
+		return err
+	}
+	if err := os.Rename(temp.Name(), path); err != nil {
+		os.Remove(temp.Name())
+		return err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	states[obj.Name] = fileState{generation: current.Generation, size: info.Size(), modTime: info.ModTime()}
+	return nil
+}
+
+// uploadFile uploads a file as the content of its object and records the state of both. An existing object
+// keeps its content type and custom metadata; new objects get the content type of the file extension.
+func (m *Mirror) uploadFile(bucketName, objectName string, existing *storage.Object, states map[string]fileState) error {
+	path := m.filePath(bucketName, objectName)
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	contentType := mime.TypeByExtension(filepath.Ext(path))
+	var metadata map[string]string
+	if existing != nil {
+		contentType, metadata = existing.ContentType, existing.Metadata
+	}
+	obj, err := m.store.CreateObjectFromReader(bucketName, objectName, contentType, file, metadata)
+	if err != nil {
+		return err
+	}
+	states[objectName] = fileState{generation: obj.Generation, size: info.Size(), modTime: info.ModTime()}
+	return nil
+}
+
+// removeFile removes the file of an object along with the directories it leaves empty.
+func (m *Mirror) removeFile(bucketName, objectName string) error {
+	path := m.filePath(bucketName, objectName)
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	bucketDir := filepath.Join(m.dir, bucketName)
+	for dir := filepath.Dir(path); dir != bucketDir; dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+	return nil
+}
+
+// readBucketDirs returns the names of the directories in the mirror directory.
+func (m *Mirror) readBucketDirs() (map[string]bool, error) {
+	entries, err := os.ReadDir(m.dir)
+	if err != nil {
+		return nil, err
+	}
+	dirs := make(map[string]bool)
+	for _, entry := range entries {
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			dirs[entry.Name()] = true
+		}
+	}
+	return dirs, nil
+}
+
+// readFiles returns the files in the directory of a bucket by object name. A missing directory has no files.
+func (m *Mirror) readFiles(bucketName string) (map[string]fs.FileInfo, error) {
+	files := make(map[string]fs.FileInfo)
+	bucketDir := filepath.Join(m.dir, bucketName)
+	err := filepath.WalkDir(bucketDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !entry.Type().IsRegular() || isTempFile(entry.Name()) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			// The file was deleted in the meantime
+			return nil
+		}
+		rel, err := filepath.Rel(bucketDir, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = info
+		return nil
+	})
+	return files, err
+}
+
+// filePath returns the path of the file of an object.
+func (m *Mirror) filePath(bucketName, objectName string) string {
+	return filepath.Join(m.dir, bucketName, filepath.FromSlash(objectName))
+}
+
+// isTempFile reports whether the last element of a path is a file the mirror writes objects to.
+func isTempFile(name string) bool {
+	return strings.HasPrefix(path.Base(name), tempPrefix)
+}
+
+// logSkipped logs that something can't be mirrored, once per key. The caller must hold m.mu.
+func (m *Mirror) logSkipped(key, format string, args ...any) {
+	if m.skipped[key] {
+		return
+	}
+	m.skipped[key] = true
+	log.Printf(format, args...)
+}
+
+// Middleware syncs after requests that may change objects, so their files are up to date when the response
+// is sent. Reads don't change objects, so they are served without syncing.
+func (m *Mirror) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+		if r.Method != http.MethodGet && r.Method != http.MethodHead && r.Method != http.MethodOptions {
+			m.Sync()
+		}
+	})
+}
@@ -0,0 +1,112 @@
+package mirror
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+	"github.com/katharinasick/gcp-api-mock/internal/store"
+)
+
+// writeFile writes a file in the mirror directory, moving its modification time forward,
+// so the change is seen even on file systems with coarse timestamps.
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, future, future); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMirror_FilesToObjects(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "fixtures", "config.json"), "{}")
+	writeFile(t, filepath.Join(dir, "fixtures", "img", "logo.svg"), "<svg/>")
+
+	s := store.New()
+	m, err := New(s, dir)
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	m.Sync()
+
+	if s.GetBucket("fixtures") == nil {
+		t.Fatal("expected a bucket for the directory")
+	}
+	obj := s.GetObject("fixtures", "img/logo.svg")
+	if obj == nil || obj.ContentType != "image/svg+xml" {
+		t.Fatalf("expected an object for the file with the content type of its extension, got %+v", obj)
+	}
+
+	// Local edits are uploaded
+	writeFile(t, filepath.Join(dir, "fixtures", "config.json"), `{"debug": true}`)
+	m.Sync()
+	if content := s.GetObjectContent("fixtures", "config.json"); string(content) != `{"debug": true}` {
+		t.Errorf("expected the edited content, got %q", content)
+	}
+
+	// Local deletes delete the object, and removing the directory deletes the bucket
+	os.Remove(filepath.Join(dir, "fixtures", "config.json"))
+	m.Sync()
+	if s.GetObject("fixtures", "config.json") != nil {
+		t.Error("expected the object of the deleted file to be deleted")
+	}
+	os.RemoveAll(filepath.Join(dir, "fixtures"))
+	m.Sync()
+	if s.GetBucket("fixtures") != nil {
+		t.Error("expected the bucket of the deleted directory to be deleted")
+	}
+}
+
+func TestMirror_ObjectsToFiles(t *testing.T) {
+	dir := t.TempDir()
+	s := store.New()
+	m, err := New(s, dir)
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	s.CreateBucket(&storage.BucketInsertRequest{Name: "assets"})
+	s.CreateObject("assets", "reports/2024.csv", "text/csv", []byte("a,b"), nil)
+	s.CreateObject("assets", "folder/", "", nil, nil)
+	m.Sync()
+
+	path := filepath.Join(dir, "assets", "reports", "2024.csv")
+	if content, err := os.ReadFile(path); err != nil || string(content) != "a,b" {
+		t.Fatalf("expected the object to be written to its file, got %q, %v", content, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "assets", "folder")); !os.IsNotExist(err) {
+		t.Errorf("expected no file for an object name that isn't a file path, got %v", err)
+	}
+
+	// API writes overwrite the file, and win over local edits
+	writeFile(t, path, "local")
+	s.CreateObject("assets", "reports/2024.csv", "text/csv", []byte("a,b,c"), nil)
+	m.Sync()
+	if content, _ := os.ReadFile(path); string(content) != "a,b,c" {
+		t.Errorf("expected the file to have the content of the API write, got %q", content)
+	}
+
+	// API deletes remove the file with the directories it leaves empty
+	s.DeleteObject("assets", "reports/2024.csv")
+	m.Sync()
+	if _, err := os.Stat(filepath.Join(dir, "assets", "reports")); !os.IsNotExist(err) {
+		t.Errorf("expected the directory to be removed, got %v", err)
+	}
+
+	// Deleting the bucket removes its directory
+	s.DeleteObject("assets", "folder/")
+	s.DeleteBucket("assets")
+	m.Sync()
+	if _, err := os.Stat(filepath.Join(dir, "assets")); !os.IsNotExist(err) {
+		t.Errorf("expected the bucket directory to be removed, got %v", err)
+	}
+}
@@ -0,0 +1,240 @@
+package monitoring
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Filter is a parsed monitoring filter, like
+// metric.type = "custom.googleapis.com/requests" AND resource.labels.zone = starts_with("us-").
+// Only clauses joined with AND are supported.
+// Reference: https://cloud.google.com/monitoring/api/v3/filters
+type Filter struct {
+	clauses []clause
+}
+
+// clause is a single comparison of a filter.
+type clause struct {
+	// selector is the compared field, e.g. "metric.type" or "resource.labels.zone".
+	selector string
+	negate   bool
+	// function is "" for an exact comparison, otherwise starts_with, ends_with or has_substring.
+	function string
+	value    string
+}
+
+// matchFunctions are the string functions a clause can compare with.
+var matchFunctions = map[string]func(s, value string) bool{
+	"":              func(s, value string) bool { return s == value },
+	"starts_with":   strings.HasPrefix,
+	"ends_with":     strings.HasSuffix,
+	"has_substring": strings.Contains,
+}
+
+// ParseFilter parses a filter. An empty filter matches everything.
+func ParseFilter(s string) (*Filter, error) {
+	tokens, err := tokenizeFilter(s)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &Filter{}
+	for len(tokens) > 0 {
+		if len(f.clauses) > 0 {
+			if tokens[0] != "AND" {
+				return nil, fmt.Errorf("invalid filter: expected AND, got %q", tokens[0])
+			}
+			tokens = tokens[1:]
+		}
+
+		c, rest, err := parseClause(tokens)
+		if err != nil {
+			return nil, err
+		}
+		f.clauses = append(f.clauses, c)
+		tokens = rest
+	}
+
+	return f, nil
+}
+
+// parseClause parses the clause at the start of tokens and returns the remaining tokens.
+func parseClause(tokens []string) (clause, []string, error) {
+	if len(tokens) < 3 {
+		return clause{}, nil, fmt.Errorf("invalid filter: incomplete comparison")
+	}
+
+	c := clause{selector: tokens[0]}
+	switch tokens[1] {
+	case "=":
+	case "!=":
+		c.negate = true
+	default:
+		return clause{}, nil, fmt.Errorf("invalid filter: unsupported operator %q", tokens[1])
+	}
+	if !isSelector(c.selector) {
+		return clause{}, nil, fmt.Errorf("invalid filter: unsupported selector %q", c.selector)
+	}
+
+	// A value is a string, or a string function like starts_with("prefix")
+	if value, ok := unquote(tokens[2]); ok {
+		c.value = value
+		return c, tokens[3:], nil
+	}
+
+	if len(tokens) < 6 || tokens[3] != "(" || tokens[5] != ")" {
+		return clause{}, nil, fmt.Errorf("invalid filter: expected a string after %s %s", tokens[0], tokens[1])
+	}
+	if _, ok := matchFunctions[tokens[2]]; !ok {
+		return clause{}, nil, fmt.Errorf("invalid filter: unsupported function %q", tokens[2])
+	}
+	value, ok := unquote(tokens[4])
+	if !ok {
+		return clause{}, nil, fmt.Errorf("invalid filter: %s expects a string", tokens[2])
+	}
+	c.function = tokens[2]
+	c.value = value
+	return c, tokens[6:], nil
+}
+
+// tokenizeFilter splits a filter into selectors, operators, parentheses, and quoted strings.
+func tokenizeFilter(s string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(s); {
+		switch c := s[i]; {
+		case unicode.IsSpace(rune(c)):
+			i++
+		case c == '(' || c == ')' || c == '=':
+			tokens = append(tokens, string(c))
+			i++
+		case c == '!':
+			if i+1 >= len(s) || s[i+1] != '=' {
+				return nil, fmt.Errorf("invalid filter: unexpected %q", c)
+			}
+			tokens = append(tokens, "!=")
+			i += 2
+		case c == '"':
+			end := i + 1
+			for end < len(s) && s[end] != '"' {
+				if s[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(s) {
+				return nil, fmt.Errorf("invalid filter: unterminated string")
+			}
+			tokens = append(tokens, s[i:end+1])
+			i = end + 1
+		default:
+			end := i
+			for end < len(s) && !unicode.IsSpace(rune(s[end])) && !strings.ContainsRune(`()="!`, rune(s[end])) {
+				end++
+			}
+			tokens = append(tokens, s[i:end])
+			i = end
+		}
+	}
+	return tokens, nil
+}
+
+// unquote returns the content of a quoted string token.
+func unquote(token string) (string, bool) {
+	if !strings.HasPrefix(token, `"`) {
+		return "", false
+	}
+	value, err := strconv.Unquote(token)
+	return value, err == nil
+}
+
+// isSelector reports whether a filter can compare a field.
+func isSelector(selector string) bool {
+	switch selector {
+	case "project", "metric.type", "resource.type":
+		return true
+	}
+	for _, prefix := range []string{"metric.labels.", "metric.label.", "resource.labels.", "resource.label."} {
+		if strings.HasPrefix(selector, prefix) && len(selector) > len(prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches reports whether a time series matches the filter.
+// The project selector always matches, since time series are only listed within a project.
+func (f *Filter) Matches(ts *TimeSeries) bool {
+	for _, c := range f.clauses {
+		if c.selector == "project" {
+			continue
+		}
+		if !c.matches(seriesField(ts, c.selector)) {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchesDescriptor reports whether a metric descriptor matches the filter.
+// Only metric.type clauses apply to descriptors.
+func (f *Filter) MatchesDescriptor(d *MetricDescriptor) bool {
+	for _, c := range f.clauses {
+		if c.selector == "metric.type" && !c.matches(d.Type) {
+			return false
+		}
+	}
+	return true
+}
+
+// MetricType returns the metric type an exact metric.type clause selects, if the filter has one.
+func (f *Filter) MetricType() string {
+	for _, c := range f.clauses {
+		if c.selector == "metric.type" && c.function == "" && !c.negate {
+			return c.value
+		}
+	}
+	return ""
+}
+
+// matches compares a field value with the clause.
+func (c clause) matches(value string) bool {
+	return matchFunctions[c.function](value, c.value) != c.negate
+}
+
+// seriesField returns the value of the field a selector names.
+func seriesField(ts *TimeSeries, selector string) string {
+	if key, found := cutLabelSelector(selector, "metric"); found {
+		if ts.Metric == nil {
+			return ""
+		}
+		return ts.Metric.Labels[key]
+	}
+	if key, found := cutLabelSelector(selector, "resource"); found {
+		if ts.Resource == nil {
+			return ""
+		}
+		return ts.Resource.Labels[key]
+	}
+
+	switch selector {
+	case "metric.type":
+		if ts.Metric != nil {
+			return ts.Metric.Type
+		}
+	case "resource.type":
+		if ts.Resource != nil {
+			return ts.Resource.Type
+		}
+	}
+	return ""
+}
+
+// cutLabelSelector returns the label key of a selector like metric.labels.{key} or its alias metric.label.{key}.
+func cutLabelSelector(selector, kind string) (string, bool) {
+	if key, found := strings.CutPrefix(selector, kind+".labels."); found {
+		return key, true
+	}
+	return strings.CutPrefix(selector, kind+".label.")
+}
@@ -0,0 +1,86 @@
+package monitoring
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFilter_Matches(t *testing.T) {
+	ts := &TimeSeries{
+		Metric:   &Metric{Type: "custom.googleapis.com/requests", Labels: map[string]string{"status": "200"}},
+		Resource: &MonitoredResource{Type: "generic_task", Labels: map[string]string{"location": "us-central1", "job": "api"}},
+	}
+
+	tests := []struct {
+		filter string
+		want   bool
+	}{
+		{``, true},
+		{`metric.type = "custom.googleapis.com/requests"`, true},
+		{`metric.type="custom.googleapis.com/errors"`, false},
+		{`metric.type = starts_with("custom.googleapis.com/")`, true},
+		{`metric.type = ends_with("/errors")`, false},
+		{`metric.type = has_substring("request")`, true},
+		{`project = "p" AND resource.type = "generic_task"`, true},
+		{`metric.type = "custom.googleapis.com/requests" AND metric.labels.status = "500"`, false},
+		{`metric.label.status = "200" AND resource.labels.location = starts_with("us-")`, true},
+		{`resource.labels.job != "api"`, false},
+		{`resource.labels.missing = ""`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			f, err := ParseFilter(tt.filter)
+			if err != nil {
+				t.Fatalf("ParseFilter() error = %v", err)
+			}
+			if got := f.Matches(ts); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFilter_Invalid(t *testing.T) {
+	filters := []string{
+		`metric.type`,
+		`metric.type > "x"`,
+		`metric.type = "x" OR resource.type = "y"`,
+		`metric.type = "unterminated`,
+		`metric.type = matches("x")`,
+		`metadata.user_labels.team = "a"`,
+		`metric.type = custom`,
+	}
+
+	for _, filter := range filters {
+		t.Run(filter, func(t *testing.T) {
+			if _, err := ParseFilter(filter); err == nil {
+				t.Errorf("ParseFilter(%q) expected an error", filter)
+			}
+		})
+	}
+}
+
+func TestFilter_MetricType(t *testing.T) {
+	f, err := ParseFilter(`resource.type = "global" AND metric.type = "custom.googleapis.com/x"`)
+	if err != nil {
+		t.Fatalf("ParseFilter() error = %v", err)
+	}
+	if got := f.MetricType(); got != "custom.googleapis.com/x" {
+		t.Errorf("MetricType() = %q", got)
+	}
+}
+
+func TestInt64_JSON(t *testing.T) {
+	for _, data := range []string{`"42"`, `42`} {
+		var v Int64
+		if err := json.Unmarshal([]byte(data), &v); err != nil || v != 42 {
+			t.Errorf("Unmarshal(%s) = %d, %v", data, v, err)
+		}
+	}
+
+	data, err := json.Marshal(Int64(42))
+	if err != nil || string(data) != `"42"` {
+		t.Errorf("Marshal() = %s, %v", data, err)
+	}
+}
@@ -0,0 +1,166 @@
+// Package monitoring provides data models for the Cloud Monitoring API (v3) mock.
+package monitoring
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Metric kinds.
+const (
+	MetricKindGauge      = "GAUGE"
+	MetricKindDelta      = "DELTA"
+	MetricKindCumulative = "CUMULATIVE"
+)
+
+// Value types.
+const (
+	ValueTypeBool         = "BOOL"
+	ValueTypeInt64        = "INT64"
+	ValueTypeDouble       = "DOUBLE"
+	ValueTypeString       = "STRING"
+	ValueTypeDistribution = "DISTRIBUTION"
+)
+
+// MetricDescriptor defines a metric type and its schema.
+// Reference: https://cloud.google.com/monitoring/api/ref_v3/rest/v3/projects.metricDescriptors
+type MetricDescriptor struct {
+	// Name is the resource name, e.g. projects/{project}/metricDescriptors/custom.googleapis.com/requests.
+	Name string `json:"name"`
+	// Type is the metric type, e.g. custom.googleapis.com/requests.
+	Type string `json:"type"`
+	// Labels describe the labels of the metric.
+	Labels []*LabelDescriptor `json:"labels,omitempty"`
+	// MetricKind is GAUGE, DELTA or CUMULATIVE.
+	MetricKind string `json:"metricKind"`
+	// ValueType is the type of the metric's values, e.g. INT64.
+	ValueType string `json:"valueType"`
+	// Unit is the unit of the values, e.g. "By" or "ms".
+	Unit string `json:"unit,omitempty"`
+	// Description is a detailed description of the metric.
+	Description string `json:"description,omitempty"`
+	// DisplayName is a concise name for the metric.
+	DisplayName string `json:"displayName,omitempty"`
+	// MonitoredResourceTypes are the resource types the metric can be written for.
+	MonitoredResourceTypes []string `json:"monitoredResourceTypes,omitempty"`
+}
+
+// LabelDescriptor describes a label of a metric.
+type LabelDescriptor struct {
+	Key         string `json:"key"`
+	ValueType   string `json:"valueType,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// TimeSeries is a collection of data points for a metric written for a monitored resource.
+// Reference: https://cloud.google.com/monitoring/api/ref_v3/rest/v3/TimeSeries
+type TimeSeries struct {
+	// Metric identifies the metric type and its label values.
+	Metric *Metric `json:"metric"`
+	// Resource identifies the monitored resource the data was written for.
+	Resource *MonitoredResource `json:"resource"`
+	// MetricKind is the kind of the metric; defaults to the kind of its descriptor.
+	MetricKind string `json:"metricKind,omitempty"`
+	// ValueType is the type of the values; defaults to the type of its descriptor.
+	ValueType string `json:"valueType,omitempty"`
+	// Points are the data points. Writes carry exactly one point; lists return the newest point first.
+	Points []*Point `json:"points,omitempty"`
+	// Unit is the unit of the values.
+	Unit string `json:"unit,omitempty"`
+}
+
+// Metric is a metric type together with label values.
+type Metric struct {
+	Type   string            `json:"type"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// MonitoredResource is the resource data was written for, e.g. a generic_task or gce_instance.
+type MonitoredResource struct {
+	Type   string            `json:"type"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// Point is a single data point of a time series.
+type Point struct {
+	Interval *TimeInterval `json:"interval"`
+	Value    *TypedValue   `json:"value"`
+}
+
+// TimeInterval is the time span of a point. Gauge points only have an end time.
+type TimeInterval struct {
+	EndTime   time.Time  `json:"endTime"`
+	StartTime *time.Time `json:"startTime,omitempty"`
+}
+
+// TypedValue is the value of a point; exactly one of its fields is set.
+type TypedValue struct {
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	Int64Value  *Int64   `json:"int64Value,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+	StringValue *string  `json:"stringValue,omitempty"`
+	// DistributionValue is kept as written, since the mock doesn't aggregate points.
+	DistributionValue json.RawMessage `json:"distributionValue,omitempty"`
+}
+
+// ValueType returns the value type of the set field, or "" if no field is set.
+func (v *TypedValue) ValueType() string {
+	switch {
+	case v == nil:
+		return ""
+	case v.BoolValue != nil:
+		return ValueTypeBool
+	case v.Int64Value != nil:
+		return ValueTypeInt64
+	case v.DoubleValue != nil:
+		return ValueTypeDouble
+	case v.StringValue != nil:
+		return ValueTypeString
+	case v.DistributionValue != nil:
+		return ValueTypeDistribution
+	default:
+		return ""
+	}
+}
+
+// Int64 is an int64 that is encoded as a JSON string, like all 64-bit integers in Google APIs.
+// Numbers are accepted too, since some clients write them.
+type Int64 int64
+
+// MarshalJSON encodes the value as a string.
+func (i Int64) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strconv.FormatInt(int64(i), 10))
+}
+
+// UnmarshalJSON decodes the value from a string or a number.
+func (i *Int64) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		s = string(data)
+	}
+	value, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid int64 value %s", data)
+	}
+	*i = Int64(value)
+	return nil
+}
+
+// CreateTimeSeriesRequest is the request body of timeSeries.create.
+type CreateTimeSeriesRequest struct {
+	TimeSeries []*TimeSeries `json:"timeSeries"`
+}
+
+// ListTimeSeriesResponse is the response of timeSeries.list.
+type ListTimeSeriesResponse struct {
+	TimeSeries    []*TimeSeries `json:"timeSeries"`
+	NextPageToken string        `json:"nextPageToken,omitempty"`
+}
+
+// ListMetricDescriptorsResponse is the response of metricDescriptors.list.
+type ListMetricDescriptorsResponse struct {
+	MetricDescriptors []*MetricDescriptor `json:"metricDescriptors"`
+	NextPageToken     string              `json:"nextPageToken,omitempty"`
+}
@@ -0,0 +1,255 @@
+// Package namespace isolates test runs that share a mock deployment. Each namespace is served by its own copy
+// of the mock's state, selected with the X-Mock-Namespace header or a /_ns/{namespace} path prefix,
+// and removed after it hasn't been used for a while.
+package namespace
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/gcperror"
+)
+
+// Header is the request header that selects a namespace.
+const Header = "X-Mock-Namespace"
+
+// PathPrefix is the path prefix that selects a namespace, as in /_ns/ci-1234/storage/v1/b.
+// Bucket names start with a letter or number, so the prefix never clashes with path-style storage requests.
+const PathPrefix = "/_ns/"
+
+// validName matches namespace names: 1-63 letters, numbers, dashes and underscores, like CI job IDs.
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]{0,62}$`)
+
+// IsValidName checks if a namespace name is valid.
+func IsValidName(name string) bool {
+	return validName.MatchString(name)
+}
+
+// Factory creates the handler serving a new namespace, with a state of its own, and a function applying
+// the clock to that state, which Tick calls. The prefix is the path prefix of the namespace, for links back into it.
+type Factory func(name, prefix string) (handler http.Handler, tick func())
+
+// Info describes a namespace.
+type Info struct {
+	Name       string    `json:"name"`
+	CreateTime time.Time `json:"createTime"`
+	LastUsed   time.Time `json:"lastUsed"`
+	ExpireTime time.Time `json:"expireTime,omitzero"`
+}
+
+// Namespaces creates namespaces on their first request and removes them when they expire.
+type Namespaces struct {
+	mu         sync.Mutex
+	namespaces map[string]*namespace
+	factory    Factory
+	ttl        time.Duration
+	now        func() time.Time
+}
+
+// namespace is a namespace and the handler serving it.
+type namespace struct {
+	handler    http.Handler
+	tick       func()
+	createTime time.Time
+	lastUsed   time.Time
+}
+
+// New creates the namespaces, served by handlers from factory.
+// Namespaces are removed once they haven't been used for ttl; a ttl of 0 keeps them until they're deleted.
+func New(factory Factory, ttl time.Duration) *Namespaces {
+	return &Namespaces{
+		namespaces: make(map[string]*namespace),
+		factory:    factory,
+		ttl:        ttl,
+		now:        time.Now,
+	}
+}
+
+// SetClock replaces the function used to read the current time. Tests use this to expire namespaces.
+// Expiry uses the real time rather than the mock's virtual clock, so time travel in one namespace
+// doesn't expire the others.
+func (n *Namespaces) SetClock(now func() time.Time) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.now = now
+}
+
+// Handler sends requests for a namespace to the handler of that namespace, creating it on the first request,
+// and all other requests to next. Requests with an invalid namespace name are rejected with 400.
+func (n *Namespaces) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, prefix := r.Header.Get(Header), ""
+		if rest, ok := strings.CutPrefix(r.URL.Path, PathPrefix); ok {
+			name, _, _ = strings.Cut(rest, "/")
+			prefix = PathPrefix + name
+		}
+		if name == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !IsValidName(name) {
+			gcperror.New(http.StatusBadRequest, "Invalid namespace "+name+": must be 1-63 letters, numbers, dashes and underscores", "invalid").
+				WithLocation("header", Header).
+				Write(w)
+			return
+		}
+
+		h := n.get(name)
+		if prefix == "" {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		// The prefix becomes the header, so recorded requests are replayed into the namespace too.
+		// Links the namespace sends back, like resumable upload URLs, have to keep the prefix.
+		r2 := r.Clone(r.Context())
+		r2.Header.Set(Header, name)
+		r2.URL.Path = strings.TrimPrefix(r.URL.Path, prefix)
+		r2.URL.RawPath = strings.TrimPrefix(r.URL.RawPath, prefix)
+		if r2.URL.Path == "" {
+			r2.URL.Path = "/"
+		}
+		h.ServeHTTP(&prefixedLocationWriter{ResponseWriter: w, host: r.Host, prefix: prefix}, r2)
+	})
+}
+
+// get returns the handler of a namespace, creating the namespace if it doesn't exist.
+// Expired namespaces are removed along the way.
+func (n *Namespaces) get(name string) http.Handler {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	now := n.now()
+	n.removeExpired(now)
+
+	ns, ok := n.namespaces[name]
+	if !ok {
+		handler, tick := n.factory(name, PathPrefix+name)
+		ns = &namespace{handler: handler, tick: tick, createTime: now}
+		n.namespaces[name] = ns
+	}
+	ns.lastUsed = now
+	return ns.handler
+}
+
+// RemoveExpired removes the namespaces that haven't been used for the TTL and returns how many were removed.
+// Expired namespaces are also removed whenever a namespace is used, so this only frees the state of
+// namespaces sooner if no namespace is used anymore.
+func (n *Namespaces) RemoveExpired() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	count := len(n.namespaces)
+	n.removeExpired(n.now())
+	return count - len(n.namespaces)
+}
+
+// removeExpired removes the namespaces that haven't been used for the TTL. Must be called with mu held.
+func (n *Namespaces) removeExpired(now time.Time) {
+	if n.ttl <= 0 {
+		return
+	}
+	for name, ns := range n.namespaces {
+		if !now.Before(ns.lastUsed.Add(n.ttl)) {
+			delete(n.namespaces, name)
+		}
+	}
+}
+
+// Tick applies the clock to the state of every namespace, like the background job of the default state does.
+func (n *Namespaces) Tick() {
+	n.mu.Lock()
+	ticks := make([]func(), 0, len(n.namespaces))
+	for _, ns := range n.namespaces {
+		if ns.tick != nil {
+			ticks = append(ticks, ns.tick)
+		}
+	}
+	n.mu.Unlock()
+
+	// Namespaces are ticked without the lock, so they can still be used meanwhile
+	for _, tick := range ticks {
+		tick()
+	}
+}
+
+// List returns the namespaces sorted by name.
+func (n *Namespaces) List() []Info {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.removeExpired(n.now())
+
+	infos := make([]Info, 0, len(n.namespaces))
+	for name, ns := range n.namespaces {
+		info := Info{Name: name, CreateTime: ns.createTime, LastUsed: ns.lastUsed}
+		if n.ttl > 0 {
+			info.ExpireTime = ns.lastUsed.Add(n.ttl)
+		}
+		infos = append(infos, info)
+	}
+	slices.SortFunc(infos, func(a, b Info) int { return strings.Compare(a.Name, b.Name) })
+	return infos
+}
+
+// Delete removes a namespace and its state. Returns false if the namespace doesn't exist.
+func (n *Namespaces) Delete(name string) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if _, ok := n.namespaces[name]; !ok {
+		return false
+	}
+	delete(n.namespaces, name)
+	return true
+}
+
+// prefixedLocationWriter adds the namespace prefix to Location headers pointing back at the mock.
+type prefixedLocationWriter struct {
+	http.ResponseWriter
+	host        string
+	prefix      string
+	wroteHeader bool
+}
+
+// WriteHeader rewrites the Location header before writing the header.
+func (w *prefixedLocationWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if location := w.Header().Get("Location"); location != "" {
+			w.Header().Set("Location", w.prefixLocation(location))
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write writes the header first, so the Location header is rewritten.
+func (w *prefixedLocationWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the underlying ResponseWriter, for http.ResponseController.
+func (w *prefixedLocationWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// prefixLocation adds the prefix to the path of a relative URL or a URL on the mock's host.
+func (w *prefixedLocationWriter) prefixLocation(location string) string {
+	u, err := url.Parse(location)
+	if err != nil || (u.Host != "" && u.Host != w.host) || !strings.HasPrefix(u.Path, "/") || strings.HasPrefix(u.Path, w.prefix+"/") {
+		return location
+	}
+	u.Path = w.prefix + u.Path
+	if u.RawPath != "" {
+		u.RawPath = w.prefix + u.RawPath
+	}
+	return u.String()
+}
@@ -0,0 +1,157 @@
+package namespace
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// echoFactory creates handlers that answer with the namespace name, the path and the namespace header.
+func echoFactory(name, prefix string) (http.Handler, func()) {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(name + " " + r.URL.Path + " " + r.Header.Get(Header)))
+	}), nil
+}
+
+func TestNamespaces_Handler(t *testing.T) {
+	n := New(echoFactory, 0)
+	h := n.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("default " + r.URL.Path))
+	}))
+
+	tests := []struct {
+		name           string
+		path           string
+		header         string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"default namespace", "/storage/v1/b", "", http.StatusOK, "default /storage/v1/b"},
+		{"header", "/storage/v1/b", "ci-1", http.StatusOK, "ci-1 /storage/v1/b ci-1"},
+		{"path prefix", "/_ns/ci-2/storage/v1/b", "", http.StatusOK, "ci-2 /storage/v1/b ci-2"},
+		{"path prefix takes precedence", "/_ns/ci-2/storage/v1/b", "ci-1", http.StatusOK, "ci-2 /storage/v1/b ci-2"},
+		{"path prefix only", "/_ns/ci-2", "", http.StatusOK, "ci-2 / ci-2"},
+		{"invalid name", "/storage/v1/b", "a/b", http.StatusBadRequest, ""},
+		{"empty name in path", "/_ns//storage/v1/b", "", http.StatusOK, "default /_ns//storage/v1/b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set(Header, tt.header)
+			}
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if tt.expectedBody != "" && rr.Body.String() != tt.expectedBody {
+				t.Errorf("expected body %q, got %q", tt.expectedBody, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestNamespaces_TTL(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	created := 0
+	n := New(func(name, prefix string) (http.Handler, func()) {
+		created++
+		return http.NotFoundHandler(), nil
+	}, time.Hour)
+	n.SetClock(func() time.Time { return now })
+	h := n.Handler(http.NotFoundHandler())
+
+	request := func(name string) {
+		req := httptest.NewRequest(http.MethodGet, "/storage/v1/b", nil)
+		req.Header.Set(Header, name)
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	request("ci-1")
+	request("ci-2")
+	now = now.Add(45 * time.Minute)
+	request("ci-1")
+	if created != 2 {
+		t.Fatalf("expected 2 namespaces to be created, got %d", created)
+	}
+
+	// ci-2 expires an hour after its last request, ci-1 stays since it was used in the meantime
+	now = now.Add(15 * time.Minute)
+	infos := n.List()
+	if len(infos) != 1 || infos[0].Name != "ci-1" {
+		t.Fatalf("expected only ci-1 to be left, got %+v", infos)
+	}
+	if expected := now.Add(45 * time.Minute); !infos[0].ExpireTime.Equal(expected) {
+		t.Errorf("expected expire time %v, got %v", expected, infos[0].ExpireTime)
+	}
+
+	// An expired namespace starts over with a new state
+	request("ci-2")
+	if created != 3 {
+		t.Errorf("expected ci-2 to be created again, got %d creations", created)
+	}
+
+	if !n.Delete("ci-2") {
+		t.Error("expected ci-2 to be deleted")
+	}
+	if n.Delete("ci-2") {
+		t.Error("expected deleting ci-2 twice to fail")
+	}
+
+	// Expired namespaces are also removed without requests
+	now = now.Add(2 * time.Hour)
+	if removed := n.RemoveExpired(); removed != 1 {
+		t.Errorf("expected ci-1 to be removed, got %d removed", removed)
+	}
+}
+
+func TestNamespaces_Tick(t *testing.T) {
+	ticks := make(map[string]int)
+	n := New(func(name, prefix string) (http.Handler, func()) {
+		return http.NotFoundHandler(), func() { ticks[name]++ }
+	}, 0)
+	h := n.Handler(http.NotFoundHandler())
+
+	for _, name := range []string{"ci-1", "ci-2"} {
+		req := httptest.NewRequest(http.MethodGet, "/storage/v1/b", nil)
+		req.Header.Set(Header, name)
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	n.Tick()
+	n.Delete("ci-2")
+	n.Tick()
+
+	if ticks["ci-1"] != 2 || ticks["ci-2"] != 1 {
+		t.Errorf("expected ci-1 to be ticked twice and ci-2 once, got %v", ticks)
+	}
+}
+
+func TestPrefixedLocationWriter(t *testing.T) {
+	tests := []struct {
+		name     string
+		location string
+		expected string
+	}{
+		{"same host", "http://localhost:8080/upload/storage/v1/b/bucket/o?upload_id=1", "http://localhost:8080/_ns/ci/upload/storage/v1/b/bucket/o?upload_id=1"},
+		{"relative", "/storage/v1/b/bucket", "/_ns/ci/storage/v1/b/bucket"},
+		{"already prefixed", "/_ns/ci/storage/v1/b/bucket", "/_ns/ci/storage/v1/b/bucket"},
+		{"other host", "https://example.com/callback", "https://example.com/callback"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			w := &prefixedLocationWriter{ResponseWriter: rr, host: "localhost:8080", prefix: "/_ns/ci"}
+			w.Header().Set("Location", tt.location)
+			w.Write([]byte("ok"))
+
+			if got := rr.Header().Get("Location"); got != tt.expected {
+				t.Errorf("expected Location %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+// Package notification delivers Cloud Storage bucket notifications for the GCP API Mock.
+package notification
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+)
+
+// PushRequest is the body of a Pub/Sub push request.
+// Webhook topics receive the same payload a push subscription would.
+// Reference: https://cloud.google.com/pubsub/docs/push#receive_push
+type PushRequest struct {
+	Message      PushMessage `json:"message"`
+	Subscription string      `json:"subscription"`
+}
+
+// PushMessage is the Pub/Sub message contained in a push request.
+type PushMessage struct {
+	Attributes  map[string]string `json:"attributes"`
+	Data        string            `json:"data,omitempty"`
+	MessageID   string            `json:"messageId"`
+	PublishTime time.Time         `json:"publishTime"`
+}
+
+// Dispatcher delivers object events to the topics of notification configurations.
+// Topics that are http(s) URLs receive a Pub/Sub push-style POST request,
+// all other topics are logged since there is no Pub/Sub mock to publish to.
+type Dispatcher struct {
+	client    *http.Client
+	messageID atomic.Int64
+}
+
+// NewDispatcher creates a new Dispatcher.
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Deliver builds the message for an object event and sends it asynchronously.
+// It matches the store.NotificationHandler signature.
+func (d *Dispatcher) Deliver(notification *storage.Notification, eventType string, obj storage.Object) {
+	msg, err := d.buildMessage(notification, eventType, &obj)
+	if err != nil {
+		log.Printf("notification %s: failed to build message: %v", notification.ID, err)
+		return
+	}
+
+	if !isWebhook(notification.Topic) {
+		log.Printf("notification %s: %s for gs://%s/%s (topic %s)", notification.ID, eventType, obj.Bucket, obj.Name, notification.Topic)
+		return
+	}
+
+	go d.push(notification, msg)
+}
+
+// buildMessage builds the Pub/Sub message for an object event.
+// Reference: https://cloud.google.com/storage/docs/pubsub-notifications#attributes
+func (d *Dispatcher) buildMessage(notification *storage.Notification, eventType string, obj *storage.Object) (PushMessage, error) {
+	now := time.Now().UTC()
+
+	attributes := make(map[string]string, len(notification.CustomAttributes)+7)
+	for k, v := range notification.CustomAttributes {
+		attributes[k] = v
+	}
+	attributes["notificationConfig"] = fmt.Sprintf("projects/_/buckets/%s/notificationConfigs/%s", obj.Bucket, notification.ID)
+	attributes["eventType"] = eventType
+	attributes["payloadFormat"] = notification.PayloadFormat
+	attributes["bucketId"] = obj.Bucket
+	attributes["objectId"] = obj.Name
+	attributes["objectGeneration"] = fmt.Sprintf("%d", obj.Generation)
+	attributes["eventTime"] = now.Format(time.RFC3339Nano)
+
+	msg := PushMessage{
+		Attributes:  attributes,
+		MessageID:   fmt.Sprintf("%d", d.messageID.Add(1)),
+		PublishTime: now,
+	}
+
+	if notification.PayloadFormat == storage.PayloadFormatJSONAPIV1 {
+		payload, err := json.Marshal(obj)
+		if err != nil {
+			return PushMessage{}, err
+		}
+		msg.Data = base64.StdEncoding.EncodeToString(payload)
+	}
+
+	return msg, nil
+}
+
+// push sends a message to a webhook topic.
+func (d *Dispatcher) push(notification *storage.Notification, msg PushMessage) {
+	body, err := json.Marshal(PushRequest{
+		Message:      msg,
+		Subscription: "projects/mock-project/subscriptions/gcp-api-mock-notification-" + notification.ID,
+	})
+	if err != nil {
+		log.Printf("notification %s: failed to encode push request: %v", notification.ID, err)
+		return
+	}
+
+	resp, err := d.client.Post(notification.Topic, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Printf("notification %s: failed to deliver to %s: %v", notification.ID, notification.Topic, err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		log.Printf("notification %s: webhook %s returned status %d", notification.ID, notification.Topic, resp.StatusCode)
+	}
+}
+
+// isWebhook returns true if a topic is an http(s) URL rather than a Pub/Sub topic.
+func isWebhook(topic string) bool {
+	return strings.HasPrefix(topic, "http://") || strings.HasPrefix(topic, "https://")
+}
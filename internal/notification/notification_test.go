@@ -0,0 +1,76 @@
+package notification
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+)
+
+func TestDispatcher_Deliver_Webhook(t *testing.T) {
+	received := make(chan PushRequest, 1)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req PushRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode push request: %v", err)
+		}
+		received <- req
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer webhook.Close()
+
+	notification := &storage.Notification{
+		ID:               "1",
+		Topic:            webhook.URL,
+		PayloadFormat:    storage.PayloadFormatJSONAPIV1,
+		CustomAttributes: map[string]string{"team": "data"},
+	}
+	obj := storage.Object{Name: "file.txt", Bucket: "test-bucket", Generation: 42}
+
+	NewDispatcher().Deliver(notification, storage.EventObjectFinalize, obj)
+
+	select {
+	case req := <-received:
+		attrs := req.Message.Attributes
+		if attrs["eventType"] != storage.EventObjectFinalize {
+			t.Errorf("eventType = %s, want %s", attrs["eventType"], storage.EventObjectFinalize)
+		}
+		if attrs["bucketId"] != "test-bucket" || attrs["objectId"] != "file.txt" || attrs["objectGeneration"] != "42" {
+			t.Errorf("unexpected object attributes: %v", attrs)
+		}
+		if attrs["team"] != "data" {
+			t.Error("custom attributes not included")
+		}
+
+		data, err := base64.StdEncoding.DecodeString(req.Message.Data)
+		if err != nil {
+			t.Fatalf("failed to decode data: %v", err)
+		}
+		var payload storage.Object
+		if err := json.Unmarshal(data, &payload); err != nil {
+			t.Fatalf("failed to decode payload: %v", err)
+		}
+		if payload.Name != "file.txt" {
+			t.Errorf("payload name = %s, want file.txt", payload.Name)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not called")
+	}
+}
+
+func TestDispatcher_BuildMessage_NoPayload(t *testing.T) {
+	notification := &storage.Notification{ID: "1", PayloadFormat: storage.PayloadFormatNone}
+
+	msg, err := NewDispatcher().buildMessage(notification, storage.EventObjectDelete, &storage.Object{Name: "file.txt"})
+	if err != nil {
+		t.Fatalf("buildMessage() error: %v", err)
+	}
+
+	if msg.Data != "" {
+		t.Error("expected no data for payload format NONE")
+	}
+}
@@ -0,0 +1,102 @@
+// Package orgpolicy provides data models for the Organization Policy API (v2) mock.
+package orgpolicy
+
+import "time"
+
+// Constraints the mock enforces. Policies for other constraints are stored but have no effect.
+const (
+	// ConstraintPublicAccessPrevention rejects IAM policies and ACLs granting access to allUsers or
+	// allAuthenticatedUsers on any bucket.
+	ConstraintPublicAccessPrevention = "storage.publicAccessPrevention"
+	// ConstraintUniformBucketLevelAccess requires uniform bucket-level access on new buckets and keeps
+	// existing buckets from turning it off.
+	ConstraintUniformBucketLevelAccess = "storage.uniformBucketLevelAccess"
+	// ConstraintRestrictPublicIp rejects Cloud SQL instances with a public IP address.
+	ConstraintRestrictPublicIp = "sql.restrictPublicIp"
+	// ConstraintRestrictAuthorizedNetworks rejects Cloud SQL instances with authorized networks.
+	ConstraintRestrictAuthorizedNetworks = "sql.restrictAuthorizedNetworks"
+)
+
+// BooleanConstraints are the constraints the mock enforces. They are boolean constraints, so their rules
+// set enforce rather than values.
+var BooleanConstraints = []string{
+	ConstraintPublicAccessPrevention,
+	ConstraintUniformBucketLevelAccess,
+	ConstraintRestrictPublicIp,
+	ConstraintRestrictAuthorizedNetworks,
+}
+
+// Policy is the organization policy of a resource for a constraint.
+// Reference: https://cloud.google.com/resource-manager/docs/reference/orgpolicy/rest/v2/organizations.policies
+type Policy struct {
+	// Name is the resource name, e.g. projects/{project}/policies/storage.publicAccessPrevention.
+	Name string `json:"name"`
+	// Spec is the policy that is enforced.
+	Spec *PolicySpec `json:"spec,omitempty"`
+	// DryRunSpec is a policy that is evaluated but not enforced. The mock stores it only.
+	DryRunSpec *PolicySpec `json:"dryRunSpec,omitempty"`
+	// Etag changes whenever the policy changes.
+	Etag string `json:"etag,omitempty"`
+}
+
+// PolicySpec defines how a policy is enforced.
+type PolicySpec struct {
+	// Etag changes whenever the spec changes.
+	Etag string `json:"etag,omitempty"`
+	// UpdateTime is the time the spec was last changed.
+	UpdateTime time.Time `json:"updateTime,omitzero"`
+	// Rules are the rules of the policy. Rules with a condition are stored but not evaluated.
+	Rules []*PolicyRule `json:"rules,omitempty"`
+	// InheritFromParent merges the policy with the one of the parent. There is no resource hierarchy in the mock.
+	InheritFromParent bool `json:"inheritFromParent,omitempty"`
+	// Reset restores the default behavior of the constraint, ignoring the rules.
+	Reset bool `json:"reset,omitempty"`
+}
+
+// PolicyRule is a rule of a policy. Exactly one of Values, AllowAll, DenyAll and Enforce is set.
+type PolicyRule struct {
+	// Values are the allowed and denied values of list constraints.
+	Values *StringValues `json:"values,omitempty"`
+	// AllowAll allows all values of list constraints.
+	AllowAll bool `json:"allowAll,omitempty"`
+	// DenyAll denies all values of list constraints.
+	DenyAll bool `json:"denyAll,omitempty"`
+	// Enforce enforces boolean constraints.
+	Enforce *bool `json:"enforce,omitempty"`
+	// Condition limits the rule to resources matching a CEL expression.
+	Condition *Expr `json:"condition,omitempty"`
+}
+
+// StringValues are the allowed and denied values of a rule.
+type StringValues struct {
+	AllowedValues []string `json:"allowedValues,omitempty"`
+	DeniedValues  []string `json:"deniedValues,omitempty"`
+}
+
+// Expr is a CEL expression.
+type Expr struct {
+	Expression  string `json:"expression,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Location    string `json:"location,omitempty"`
+}
+
+// ListPoliciesResponse is the response of policies.list.
+type ListPoliciesResponse struct {
+	Policies      []*Policy `json:"policies,omitempty"`
+	NextPageToken string    `json:"nextPageToken,omitempty"`
+}
+
+// Enforced reports whether the policy enforces its boolean constraint: its spec has a rule enforcing it
+// without a condition, and isn't reset.
+func (p *Policy) Enforced() bool {
+	if p == nil || p.Spec == nil || p.Spec.Reset {
+		return false
+	}
+	for _, rule := range p.Spec.Rules {
+		if rule.Condition == nil && rule.Enforce != nil && *rule.Enforce {
+			return true
+		}
+	}
+	return false
+}
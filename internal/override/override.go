@@ -0,0 +1,177 @@
+// Package override answers matching API requests with stubbed responses instead of the mock's state,
+// to force edge-case payloads the store can't produce, like a malformed resource or a rare error.
+package override
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Rule answers matching requests with a fixed response.
+type Rule struct {
+	// ID identifies the rule; it is assigned when the rule is added.
+	ID string `json:"id"`
+	// Method is the HTTP method of matching requests; empty matches any method.
+	Method string `json:"method,omitempty"`
+	// Path is the path of matching requests, like /storage/v1/b/foo. "*" matches any characters
+	// of a path segment, like /storage/v1/b/*/o.
+	Path string `json:"path"`
+	// Status is the status code of the response; 0 means 200.
+	Status int `json:"status,omitempty"`
+	// Headers are the headers of the response. Without a Content-Type, JSON bodies are sent as
+	// application/json and text bodies as text/plain.
+	Headers map[string]string `json:"headers,omitempty"`
+	// Body is a JSON body, sent exactly as written.
+	Body json.RawMessage `json:"body,omitempty"`
+	// BodyText is a body of any other format, like XML.
+	BodyText string `json:"bodyText,omitempty"`
+	// Times is how many requests the rule answers before it is removed; 0 answers requests until it is deleted.
+	Times int `json:"times,omitempty"`
+	// Hits is how many requests the rule answered.
+	Hits int `json:"hits"`
+}
+
+// Validate checks that a rule can be added.
+// Returns an "invalid override" error naming the problem.
+func (rule *Rule) Validate() error {
+	switch {
+	case !strings.HasPrefix(rule.Path, "/"):
+		return fmt.Errorf("invalid override: path must start with a slash")
+	case rule.Path == "/admin" || strings.HasPrefix(rule.Path, "/admin/"):
+		return fmt.Errorf("invalid override: the admin API can't be overridden")
+	case rule.Status != 0 && (rule.Status < 100 || rule.Status > 599):
+		return fmt.Errorf("invalid override: status %d is not an HTTP status code", rule.Status)
+	case rule.Times < 0:
+		return fmt.Errorf("invalid override: times must not be negative")
+	case len(rule.Body) > 0 && rule.BodyText != "":
+		return fmt.Errorf("invalid override: set either body or bodyText")
+	case len(rule.Body) > 0 && !json.Valid(rule.Body):
+		return fmt.Errorf("invalid override: body is not valid JSON")
+	}
+	if _, err := path.Match(rule.Path, ""); err != nil {
+		return fmt.Errorf("invalid override: invalid path pattern %q", rule.Path)
+	}
+	return nil
+}
+
+// matches reports whether a request matches the rule.
+func (rule *Rule) matches(r *http.Request) bool {
+	if rule.Method != "" && !strings.EqualFold(rule.Method, r.Method) {
+		return false
+	}
+	matched, _ := path.Match(rule.Path, r.URL.Path)
+	return matched
+}
+
+// Write writes the rule's response.
+func (rule *Rule) Write(w http.ResponseWriter) {
+	for name, value := range rule.Headers {
+		w.Header().Set(name, value)
+	}
+	body := []byte(rule.BodyText)
+	if len(rule.Body) > 0 {
+		body = rule.Body
+	}
+	if w.Header().Get("Content-Type") == "" && len(body) > 0 {
+		if len(rule.Body) > 0 {
+			w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		} else {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		}
+	}
+
+	status := rule.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// Overrides holds the active rules.
+// It is safe for concurrent access, so rules can be changed while requests are served.
+type Overrides struct {
+	mu     sync.Mutex
+	rules  []*Rule
+	nextID int
+}
+
+// New creates a new Overrides without rules.
+func New() *Overrides {
+	return &Overrides{}
+}
+
+// Add validates a rule and adds it after the existing ones. Returns the rule with its ID.
+func (o *Overrides) Add(rule Rule) (Rule, error) {
+	if err := rule.Validate(); err != nil {
+		return Rule{}, err
+	}
+	rule.Method = strings.ToUpper(rule.Method)
+	rule.Hits = 0
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.nextID++
+	rule.ID = strconv.Itoa(o.nextID)
+	o.rules = append(o.rules, &rule)
+	return rule, nil
+}
+
+// List returns copies of the active rules in the order they match.
+func (o *Overrides) List() []Rule {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	rules := make([]Rule, len(o.rules))
+	for i, rule := range o.rules {
+		rules[i] = *rule
+	}
+	return rules
+}
+
+// Delete removes a rule. Returns false if there is no rule with the ID.
+func (o *Overrides) Delete(id string) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for i, rule := range o.rules {
+		if rule.ID == id {
+			o.rules = append(o.rules[:i], o.rules[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Clear removes all rules.
+func (o *Overrides) Clear() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.rules = nil
+}
+
+// Match returns a copy of the first rule matching the request and counts the request as answered by it,
+// removing the rule once it answered as many requests as it should. Returns false if no rule matches.
+func (o *Overrides) Match(r *http.Request) (Rule, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for i, rule := range o.rules {
+		if !rule.matches(r) {
+			continue
+		}
+		rule.Hits++
+		if rule.Times > 0 && rule.Hits >= rule.Times {
+			o.rules = append(o.rules[:i], o.rules[i+1:]...)
+		}
+		return *rule, true
+	}
+	return Rule{}, false
+}
@@ -0,0 +1,116 @@
+package override
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRule_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    Rule
+		wantErr bool
+	}{
+		{name: "exact path", rule: Rule{Path: "/storage/v1/b/foo"}},
+		{name: "pattern", rule: Rule{Method: "get", Path: "/storage/v1/b/*/o", Status: 503, Times: 2}},
+		{name: "relative path", rule: Rule{Path: "storage/v1/b"}, wantErr: true},
+		{name: "admin API", rule: Rule{Path: "/admin/overrides"}, wantErr: true},
+		{name: "invalid status", rule: Rule{Path: "/b", Status: 1000}, wantErr: true},
+		{name: "negative times", rule: Rule{Path: "/b", Times: -1}, wantErr: true},
+		{name: "both bodies", rule: Rule{Path: "/b", Body: []byte(`{}`), BodyText: "text"}, wantErr: true},
+		{name: "invalid JSON", rule: Rule{Path: "/b", Body: []byte(`{`)}, wantErr: true},
+		{name: "invalid pattern", rule: Rule{Path: "/b/[a"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rule.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "invalid override") {
+				t.Errorf("expected an invalid override error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestOverrides_Match(t *testing.T) {
+	o := New()
+	limited, _ := o.Add(Rule{Method: "get", Path: "/storage/v1/b/foo", Status: 500, Times: 2})
+	unlimited, _ := o.Add(Rule{Path: "/storage/v1/b/*", Status: 200})
+
+	tests := []struct {
+		method string
+		path   string
+		wantID string
+	}{
+		{http.MethodGet, "/storage/v1/b/foo", limited.ID},
+		{http.MethodPatch, "/storage/v1/b/foo", unlimited.ID},
+		{http.MethodGet, "/storage/v1/b/foo", limited.ID},
+		// The limited rule answered twice and is gone
+		{http.MethodGet, "/storage/v1/b/foo", unlimited.ID},
+		{http.MethodGet, "/storage/v1/b/foo/o", ""},
+	}
+	for _, tt := range tests {
+		rule, ok := o.Match(httptest.NewRequest(tt.method, tt.path, nil))
+		if rule.ID != tt.wantID || ok != (tt.wantID != "") {
+			t.Errorf("%s %s: expected rule %q, got %q (%v)", tt.method, tt.path, tt.wantID, rule.ID, ok)
+		}
+	}
+
+	rules := o.List()
+	if len(rules) != 1 || rules[0].ID != unlimited.ID || rules[0].Hits != 2 {
+		t.Errorf("expected only the unlimited rule with 2 hits to be left, got %+v", rules)
+	}
+
+	if !o.Delete(unlimited.ID) || o.Delete(unlimited.ID) {
+		t.Error("expected Delete() to remove the rule once")
+	}
+	if _, ok := o.Match(httptest.NewRequest(http.MethodGet, "/storage/v1/b/foo", nil)); ok {
+		t.Error("expected no rule to match after deleting all rules")
+	}
+}
+
+func TestRule_Write(t *testing.T) {
+	tests := []struct {
+		name            string
+		rule            Rule
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			name:            "JSON body",
+			rule:            Rule{Body: []byte(`{"name": "foo", "size": "-1"}`)},
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/json; charset=UTF-8",
+			wantBody:        `{"name": "foo", "size": "-1"}`,
+		},
+		{
+			name:            "text body with headers",
+			rule:            Rule{Status: 503, BodyText: "<Error/>", Headers: map[string]string{"Content-Type": "application/xml"}},
+			wantStatus:      http.StatusServiceUnavailable,
+			wantContentType: "application/xml",
+			wantBody:        "<Error/>",
+		},
+		{
+			name:       "no body",
+			rule:       Rule{Status: 204},
+			wantStatus: http.StatusNoContent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			tt.rule.Write(rr)
+			if rr.Code != tt.wantStatus || rr.Header().Get("Content-Type") != tt.wantContentType || rr.Body.String() != tt.wantBody {
+				t.Errorf("expected %d %q %q, got %d %q %q", tt.wantStatus, tt.wantContentType, tt.wantBody,
+					rr.Code, rr.Header().Get("Content-Type"), rr.Body.String())
+			}
+		})
+	}
+}
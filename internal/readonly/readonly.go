@@ -0,0 +1,84 @@
+// Package readonly provides the read-only mode of the mock, in which mutating API requests don't change
+// any state. It lets tools like terraform plan run against the mock with a production configuration,
+// and the request log shows what they would have changed.
+package readonly
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Mode is how mutating API requests are answered.
+type Mode string
+
+const (
+	// ModeOff serves mutating requests as usual.
+	ModeOff Mode = "off"
+	// ModeSuccess answers mutating requests with a synthetic success without changing any state.
+	ModeSuccess Mode = "success"
+	// ModeReject answers mutating requests with 403 Forbidden.
+	ModeReject Mode = "reject"
+)
+
+// ParseMode parses a mode. An empty string and "false" are off, "true" is success.
+func ParseMode(s string) (Mode, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "false", string(ModeOff):
+		return ModeOff, nil
+	case "true", string(ModeSuccess):
+		return ModeSuccess, nil
+	case string(ModeReject):
+		return ModeReject, nil
+	default:
+		return ModeOff, fmt.Errorf("invalid read-only mode %q: must be off, success or reject", s)
+	}
+}
+
+// Switch holds the read-only mode, which can be changed at runtime.
+// It is safe for concurrent use.
+type Switch struct {
+	mu   sync.RWMutex
+	mode Mode
+}
+
+// New creates a switch in the given mode.
+func New(mode Mode) *Switch {
+	return &Switch{mode: mode}
+}
+
+// Mode returns the current mode.
+func (s *Switch) Mode() Mode {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.mode
+}
+
+// SetMode changes the mode.
+func (s *Switch) SetMode(mode Mode) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mode = mode
+}
+
+// readMethodSuffixes are the custom methods that are POSTs but only read, like Firestore's runQuery.
+var readMethodSuffixes = []string{":runQuery", ":runAggregationQuery", ":batchGet", ":list"}
+
+// IsMutating reports whether a request may change state. The mock's own admin API is never mutating,
+// so read-only mode can always be turned off again.
+func IsMutating(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	if r.URL.Path == "/admin" || strings.HasPrefix(r.URL.Path, "/admin/") {
+		return false
+	}
+	for _, suffix := range readMethodSuffixes {
+		if strings.HasSuffix(r.URL.Path, suffix) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,74 @@
+package readonly
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseMode(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Mode
+		wantErr bool
+	}{
+		{input: "", want: ModeOff},
+		{input: "off", want: ModeOff},
+		{input: "false", want: ModeOff},
+		{input: "true", want: ModeSuccess},
+		{input: "Success", want: ModeSuccess},
+		{input: "reject", want: ModeReject},
+		{input: "sometimes", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseMode(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseMode(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ParseMode(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsMutating(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		want   bool
+	}{
+		{http.MethodGet, "/storage/v1/b/my-bucket", false},
+		{http.MethodHead, "/my-bucket/file.txt", false},
+		{http.MethodPost, "/storage/v1/b", true},
+		{http.MethodPut, "/my-bucket/file.txt", true},
+		{http.MethodPatch, "/sql/v1beta4/projects/p/instances/i", true},
+		{http.MethodDelete, "/ui/buckets/my-bucket", true},
+		{http.MethodPost, "/v1/projects/p/databases/(default)/documents:runQuery", false},
+		{http.MethodPost, "/v2/entries:list", false},
+		{http.MethodPost, "/v2/entries:write", true},
+		{http.MethodPut, "/admin/readonly", false},
+		{http.MethodPost, "/admin/reset", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			if got := IsMutating(httptest.NewRequest(tt.method, tt.path, nil)); got != tt.want {
+				t.Errorf("IsMutating() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSwitch(t *testing.T) {
+	sw := New(ModeOff)
+	if sw.Mode() != ModeOff {
+		t.Errorf("expected mode off, got %q", sw.Mode())
+	}
+	sw.SetMode(ModeReject)
+	if sw.Mode() != ModeReject {
+		t.Errorf("expected mode reject, got %q", sw.Mode())
+	}
+}
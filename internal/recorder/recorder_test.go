@@ -0,0 +1,79 @@
+package recorder
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+func TestRecorder_StartStop(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recording.jsonl")
+	r := New()
+
+	if r.Active() {
+		t.Error("new recorder should not be active")
+	}
+
+	if err := r.Start(path); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	if err := r.Start(path); err == nil {
+		t.Error("expected error when starting twice")
+	}
+
+	r.SetRedaction(func(ex *Exchange) { ex.Request.Header.Set("Authorization", "[REDACTED]") })
+	_ = r.Record(&Exchange{
+		Request:  RecordedRequest{Method: http.MethodGet, URL: "/storage/v1/b", Header: http.Header{"Authorization": {"Bearer secret"}}},
+		Response: RecordedResponse{Status: http.StatusOK},
+	})
+
+	status, err := r.Stop()
+	if err != nil {
+		t.Fatalf("Stop() error: %v", err)
+	}
+	if status.Count != 1 || status.Path != path {
+		t.Errorf("unexpected status: %+v", status)
+	}
+
+	if _, err := r.Stop(); err == nil {
+		t.Error("expected error when stopping without recording")
+	}
+
+	exchanges, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if len(exchanges) != 1 || exchanges[0].Request.URL != "/storage/v1/b" {
+		t.Errorf("unexpected exchanges: %+v", exchanges)
+	}
+	if got := exchanges[0].Request.Header.Get("Authorization"); got != "[REDACTED]" {
+		t.Errorf("expected the exchange to be redacted before it was recorded, got %q", got)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	exchanges := []Exchange{
+		{Request: RecordedRequest{Method: http.MethodGet, URL: "/ok"}, Response: RecordedResponse{Status: http.StatusOK}},
+		{Request: RecordedRequest{Method: http.MethodGet, URL: "/missing"}, Response: RecordedResponse{Status: http.StatusOK}},
+	}
+
+	results := Replay(h, exchanges)
+
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	if !results[0].Match {
+		t.Error("expected first exchange to match")
+	}
+	if results[1].Match || results[1].ReplayedStatus != http.StatusNotFound {
+		t.Errorf("expected second exchange to mismatch with 404, got %+v", results[1])
+	}
+}
@@ -0,0 +1,228 @@
+// Package redact removes secrets like tokens and Cloud SQL user passwords from logged requests,
+// so request logs and recordings of the mock can be shared in bug reports.
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/katharinasick/gcp-api-mock/internal/recorder"
+)
+
+// Placeholder replaces redacted values.
+const Placeholder = "[REDACTED]"
+
+// Rules redact the values of headers and the JSON values at paths in bodies.
+type Rules struct {
+	// headers are the canonical names of the redacted headers
+	headers map[string]bool
+	paths   []Path
+}
+
+// New creates rules redacting the headers with the given names, in any case, and the JSON values at the given paths.
+// Returns an error if a path is invalid.
+func New(headers, paths []string) (*Rules, error) {
+	rules := &Rules{headers: make(map[string]bool)}
+	for _, name := range headers {
+		rules.headers[http.CanonicalHeaderKey(strings.TrimSpace(name))] = true
+	}
+	for _, expr := range paths {
+		path, err := ParsePath(expr)
+		if err != nil {
+			return nil, err
+		}
+		rules.paths = append(rules.paths, path)
+	}
+	return rules, nil
+}
+
+// Empty reports whether the rules redact nothing.
+func (r *Rules) Empty() bool {
+	return len(r.headers) == 0 && len(r.paths) == 0
+}
+
+// Exchange redacts the headers and bodies of a request and its response in place.
+// Bodies are replaced, not modified, so they may still be shared with the handler that served the request.
+func (r *Rules) Exchange(ex *recorder.Exchange) {
+	r.Header(ex.Request.Header)
+	r.Header(ex.Response.Header)
+	ex.Request.Body = r.Body(ex.Request.Body)
+	ex.Response.Body = r.Body(ex.Response.Body)
+}
+
+// Header redacts the values of the redacted headers in place.
+func (r *Rules) Header(header http.Header) {
+	for name, values := range header {
+		if !r.headers[http.CanonicalHeaderKey(name)] {
+			continue
+		}
+		for i := range values {
+			values[i] = Placeholder
+		}
+	}
+}
+
+// Body returns a JSON body with the values at the paths redacted. Bodies that aren't JSON objects or arrays,
+// like object content, are returned as is, except for those that look like JSON but can't be parsed, like
+// bodies cut off at the size limit of the request log: they might contain a value that should be redacted,
+// so they are replaced by the placeholder as a whole. Redacted bodies are compacted.
+func (r *Rules) Body(body []byte) []byte {
+	if len(r.paths) == 0 {
+		return body
+	}
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 || (trimmed[0] != '{' && trimmed[0] != '[') {
+		return body
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(trimmed))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil || decoder.More() {
+		return []byte(Placeholder)
+	}
+
+	redacted := false
+	for _, path := range r.paths {
+		var changed bool
+		value, changed = path.redact(value)
+		redacted = redacted || changed
+	}
+	if !redacted {
+		return body
+	}
+
+	result, err := json.Marshal(value)
+	if err != nil {
+		return []byte(Placeholder)
+	}
+	return result
+}
+
+// step is a step of a path.
+type step struct {
+	// name is the member a step selects, or "" for all members and elements.
+	name string
+	// index is the array element a step selects, or -1.
+	index int
+	// descendant selects the member at any depth below the current value, for "..".
+	descendant bool
+}
+
+// Path is a JSONPath like $.password, $.settings.userLabels.token, $.items[*].secret, $.items[0].secret
+// or $..password, which matches password members at any depth.
+type Path struct {
+	expr  string
+	steps []step
+}
+
+// String returns the expression the path was parsed from.
+func (p Path) String() string {
+	return p.expr
+}
+
+// ParsePath parses a JSONPath with member (.name), wildcard (.* and [*]), index ([0]) and
+// descendant (..name) steps.
+func ParsePath(expr string) (Path, error) {
+	path := Path{expr: expr}
+	rest, found := strings.CutPrefix(strings.TrimSpace(expr), "$")
+	if !found {
+		return Path{}, fmt.Errorf("invalid JSON path %q: must start with $", expr)
+	}
+
+	for rest != "" {
+		var s step
+		switch {
+		case strings.HasPrefix(rest, "["):
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return Path{}, fmt.Errorf("invalid JSON path %q: missing ]", expr)
+			}
+			s.index = -1
+			if inner := rest[1:end]; inner != "*" {
+				index, err := strconv.Atoi(inner)
+				if err != nil || index < 0 {
+					return Path{}, fmt.Errorf("invalid JSON path %q: %q must be [*] or an index like [0]", expr, rest[:end+1])
+				}
+				s.index = index
+			}
+			rest = rest[end+1:]
+		case strings.HasPrefix(rest, "."):
+			rest = rest[1:]
+			if s.descendant = strings.HasPrefix(rest, "."); s.descendant {
+				rest = rest[1:]
+			}
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			s.name, s.index = rest[:end], -1
+			if s.name == "" {
+				return Path{}, fmt.Errorf("invalid JSON path %q: missing member name", expr)
+			}
+			if s.name == "*" {
+				if s.descendant {
+					return Path{}, fmt.Errorf("invalid JSON path %q: ..* would redact everything", expr)
+				}
+				s.name = ""
+			}
+			rest = rest[end:]
+		default:
+			return Path{}, fmt.Errorf("invalid JSON path %q: expected . or [ at %q", expr, rest)
+		}
+		path.steps = append(path.steps, s)
+	}
+
+	if len(path.steps) == 0 {
+		return Path{}, fmt.Errorf("invalid JSON path %q: would redact the whole body", expr)
+	}
+	return path, nil
+}
+
+// redact replaces the values at the path in a decoded JSON value and reports whether any were replaced.
+func (p Path) redact(value any) (any, bool) {
+	return redactSteps(value, p.steps)
+}
+
+// redactSteps replaces the values the steps select below value.
+func redactSteps(value any, steps []step) (any, bool) {
+	if len(steps) == 0 {
+		return Placeholder, true
+	}
+	s, rest := steps[0], steps[1:]
+
+	changed := false
+	apply := func(child any, steps []step) any {
+		child, childChanged := redactSteps(child, steps)
+		changed = changed || childChanged
+		return child
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		for key, child := range v {
+			switch {
+			case s.descendant && key == s.name:
+				v[key] = apply(child, rest)
+			case s.descendant:
+				v[key] = apply(child, steps)
+			case s.index < 0 && (s.name == "" || s.name == key):
+				v[key] = apply(child, rest)
+			}
+		}
+	case []any:
+		for i, child := range v {
+			switch {
+			case s.descendant:
+				v[i] = apply(child, steps)
+			case s.name == "" && (s.index < 0 || s.index == i):
+				v[i] = apply(child, rest)
+			}
+		}
+	}
+	return value, changed
+}
@@ -0,0 +1,95 @@
+package redact
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/katharinasick/gcp-api-mock/internal/recorder"
+)
+
+func TestParsePath(t *testing.T) {
+	for _, expr := range []string{"$.password", "$.settings.userLabels.token", "$.items[*].secret", "$.items[0].secret", "$..password", "$.*.token"} {
+		if _, err := ParsePath(expr); err != nil {
+			t.Errorf("ParsePath(%q) error: %v", expr, err)
+		}
+	}
+	for _, expr := range []string{"password", "$", "$.", "$.items[x]", "$.items[0", "$..*", "$password"} {
+		if _, err := ParsePath(expr); err == nil {
+			t.Errorf("expected ParsePath(%q) to fail", expr)
+		}
+	}
+}
+
+func TestRules_Body(t *testing.T) {
+	tests := []struct {
+		name     string
+		paths    []string
+		body     string
+		expected string
+	}{
+		{"member", []string{"$.password"}, `{"name": "root", "password": "hunter2"}`, `{"name":"root","password":"[REDACTED]"}`},
+		{"nested member", []string{"$.settings.token"}, `{"settings":{"token":"abc","tier":"db-f1-micro"}}`, `{"settings":{"tier":"db-f1-micro","token":"[REDACTED]"}}`},
+		{"array elements", []string{"$.items[*].secret"}, `{"items":[{"secret":"a"},{"secret":"b"}]}`, `{"items":[{"secret":"[REDACTED]"},{"secret":"[REDACTED]"}]}`},
+		{"array index", []string{"$.items[1].secret"}, `{"items":[{"secret":"a"},{"secret":"b"}]}`, `{"items":[{"secret":"a"},{"secret":"[REDACTED]"}]}`},
+		{"descendants", []string{"$..password"}, `[{"password":"a","user":{"password":{"old":"b"}}}]`, `[{"password":"[REDACTED]","user":{"password":"[REDACTED]"}}]`},
+		{"wildcard", []string{"$.*.token"}, `{"a":{"token":"x"},"b":{"token":"y"}}`, `{"a":{"token":"[REDACTED]"},"b":{"token":"[REDACTED]"}}`},
+		{"nothing to redact", []string{"$.password"}, `{"name": "root", "count": 10}`, `{"name": "root", "count": 10}`},
+		{"not JSON", []string{"$.password"}, `password=hunter2`, `password=hunter2`},
+		{"truncated JSON", []string{"$.password"}, `{"name": "root", "passw`, `[REDACTED]`},
+		{"numbers are kept", []string{"$.password"}, `{"size":"12","n":12345678901234567890,"password":"x"}`, `{"n":12345678901234567890,"password":"[REDACTED]","size":"12"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := New(nil, tt.paths)
+			if err != nil {
+				t.Fatalf("New() error: %v", err)
+			}
+			if got := string(rules.Body([]byte(tt.body))); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRules_Exchange(t *testing.T) {
+	rules, err := New([]string{"authorization", "X-Goog-Api-Key"}, []string{"$.password"})
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	body := []byte(`{"name":"root","password":"hunter2"}`)
+	ex := &recorder.Exchange{
+		Request: recorder.RecordedRequest{
+			Method: http.MethodPost,
+			URL:    "/sql/v1beta4/projects/p/instances/db/users",
+			Header: http.Header{"Authorization": {"Bearer ya29.secret"}, "Content-Type": {"application/json"}},
+			Body:   body,
+		},
+		Response: recorder.RecordedResponse{
+			Status: http.StatusOK,
+			Header: http.Header{"X-Goog-Api-Key": {"key"}},
+			Body:   []byte(`{"kind":"sql#operation"}`),
+		},
+	}
+	rules.Exchange(ex)
+
+	if got := ex.Request.Header.Get("Authorization"); got != Placeholder {
+		t.Errorf("expected the Authorization header to be redacted, got %q", got)
+	}
+	if got := ex.Request.Header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("expected other headers to be kept, got %q", got)
+	}
+	if got := ex.Response.Header.Get("X-Goog-Api-Key"); got != Placeholder {
+		t.Errorf("expected response headers to be redacted, got %q", got)
+	}
+	if got := string(ex.Request.Body); got != `{"name":"root","password":"[REDACTED]"}` {
+		t.Errorf("expected the password to be redacted, got %s", got)
+	}
+	if string(body) != `{"name":"root","password":"hunter2"}` {
+		t.Errorf("expected the original body to be left alone, got %s", body)
+	}
+	if got := string(ex.Response.Body); got != `{"kind":"sql#operation"}` {
+		t.Errorf("expected the response body to be kept, got %s", got)
+	}
+}
@@ -0,0 +1,68 @@
+// Package registry provides data models for the Docker Registry v2 API mock,
+// which stands in for Artifact Registry and Container Registry.
+package registry
+
+import "time"
+
+// Repository represents an image repository, e.g. "my-project/my-repo/my-image".
+type Repository struct {
+	// Name is the name of the repository.
+	Name string `json:"name"`
+	// Manifests is a map of manifest digest to manifest.
+	Manifests map[string]*Manifest `json:"-"`
+	// Tags is a map of tag to manifest digest.
+	Tags map[string]string `json:"-"`
+	// Blobs is the set of blob digests linked to the repository.
+	Blobs map[string]bool `json:"-"`
+}
+
+// Manifest represents an image manifest or index.
+type Manifest struct {
+	// Digest is the content digest of the manifest, e.g. "sha256:...".
+	Digest string `json:"digest"`
+	// MediaType is the media type the manifest was pushed with.
+	MediaType string `json:"mediaType"`
+	// Content is the raw manifest.
+	Content []byte `json:"-"`
+	// Created is the time the manifest was pushed.
+	Created time.Time `json:"created"`
+}
+
+// TagList represents the response for listing the tags of a repository.
+// Reference: https://distribution.github.io/distribution/spec/api/#listing-image-tags
+type TagList struct {
+	Name string   `json:"name"`
+	Tags []string `json:"tags"`
+}
+
+// Catalog represents the response for listing repositories.
+// Reference: https://distribution.github.io/distribution/spec/api/#listing-repositories
+type Catalog struct {
+	Repositories []string `json:"repositories"`
+}
+
+// Error codes defined by the registry API.
+// Reference: https://distribution.github.io/distribution/spec/api/#errors-2
+const (
+	ErrorCodeBlobUnknown       = "BLOB_UNKNOWN"
+	ErrorCodeBlobUploadUnknown = "BLOB_UPLOAD_UNKNOWN"
+	ErrorCodeDigestInvalid     = "DIGEST_INVALID"
+	ErrorCodeManifestUnknown   = "MANIFEST_UNKNOWN"
+	ErrorCodeManifestInvalid   = "MANIFEST_INVALID"
+	ErrorCodeNameUnknown       = "NAME_UNKNOWN"
+	ErrorCodeNameInvalid       = "NAME_INVALID"
+	ErrorCodeUnsupported       = "UNSUPPORTED"
+	ErrorCodeBlobUploadInvalid = "BLOB_UPLOAD_INVALID"
+)
+
+// ErrorResponse represents an error response from the registry API.
+type ErrorResponse struct {
+	Errors []Error `json:"errors"`
+}
+
+// Error is a single registry API error.
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Detail  string `json:"detail,omitempty"`
+}
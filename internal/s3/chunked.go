@@ -0,0 +1,108 @@
+package s3
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// IsChunked returns true if a request body uses the aws-chunked encoding of streaming uploads.
+// Reference: https://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-streaming.html
+func IsChunked(r *http.Request) bool {
+	if strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		return true
+	}
+	return strings.Contains(r.Header.Get("Content-Encoding"), "aws-chunked")
+}
+
+// chunkedReader decodes an aws-chunked body. Each chunk is framed as
+// hex-size[;chunk-signature=...]\r\n data \r\n, and a zero-sized chunk (optionally
+// followed by trailing headers) ends the body. Chunk signatures are not verified.
+type chunkedReader struct {
+	r         *bufio.Reader
+	remaining int64
+	done      bool
+}
+
+// NewChunkedReader returns a reader that decodes an aws-chunked body.
+func NewChunkedReader(r io.Reader) io.Reader {
+	return &chunkedReader{r: bufio.NewReader(r)}
+}
+
+// Read reads decoded content.
+func (c *chunkedReader) Read(p []byte) (int, error) {
+	if c.done {
+		return 0, io.EOF
+	}
+
+	if c.remaining == 0 {
+		if err := c.nextChunk(); err != nil {
+			return 0, err
+		}
+		if c.done {
+			return 0, io.EOF
+		}
+	}
+
+	if int64(len(p)) > c.remaining {
+		p = p[:c.remaining]
+	}
+	n, err := c.r.Read(p)
+	c.remaining -= int64(n)
+
+	// Consume the CRLF that ends the chunk
+	if c.remaining == 0 && err == nil {
+		if _, err := c.readLine(); err != nil {
+			return n, err
+		}
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+
+	return n, err
+}
+
+// nextChunk reads the next chunk header.
+func (c *chunkedReader) nextChunk() error {
+	line, err := c.readLine()
+	if err != nil {
+		return err
+	}
+
+	sizeField, _, _ := strings.Cut(line, ";")
+	size, err := strconv.ParseInt(strings.TrimSpace(sizeField), 16, 64)
+	if err != nil || size < 0 {
+		return fmt.Errorf("invalid chunk size: %s", line)
+	}
+
+	if size == 0 {
+		// Skip trailing headers up to the final empty line
+		for {
+			line, err := c.readLine()
+			if err != nil || line == "" {
+				break
+			}
+		}
+		c.done = true
+		return nil
+	}
+
+	c.remaining = size
+	return nil
+}
+
+// readLine reads a CRLF-terminated line without the line ending.
+func (c *chunkedReader) readLine() (string, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		if err == io.EOF {
+			return "", io.ErrUnexpectedEOF
+		}
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
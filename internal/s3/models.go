@@ -0,0 +1,165 @@
+// Package s3 provides data models and protocol helpers for the S3 compatibility layer of the GCP API Mock.
+package s3
+
+import "encoding/xml"
+
+// Namespace is the XML namespace used by S3 responses.
+const Namespace = "http://s3.amazonaws.com/doc/2006-03-01/"
+
+// ListAllMyBucketsResult represents the response for listing buckets.
+// Reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_ListBuckets.html
+type ListAllMyBucketsResult struct {
+	XMLName xml.Name `xml:"ListAllMyBucketsResult"`
+	// Xmlns is the XML namespace of the response.
+	Xmlns string `xml:"xmlns,attr"`
+	// Owner is the owner of the buckets.
+	Owner Owner `xml:"Owner"`
+	// Buckets are the buckets.
+	Buckets []Bucket `xml:"Buckets>Bucket"`
+}
+
+// Owner represents the owner of a bucket or object.
+type Owner struct {
+	// ID is the ID of the owner.
+	ID string `xml:"ID"`
+	// DisplayName is the display name of the owner.
+	DisplayName string `xml:"DisplayName"`
+}
+
+// Bucket represents a bucket in a bucket listing.
+type Bucket struct {
+	// Name is the name of the bucket.
+	Name string `xml:"Name"`
+	// CreationDate is the creation time of the bucket in RFC 3339 format.
+	CreationDate string `xml:"CreationDate"`
+}
+
+// ListBucketResult represents the response for listing objects, both for
+// ListObjects (marker based) and ListObjectsV2 (continuation token based).
+// Reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_ListObjectsV2.html
+type ListBucketResult struct {
+	XMLName xml.Name `xml:"ListBucketResult"`
+	// Xmlns is the XML namespace of the response.
+	Xmlns string `xml:"xmlns,attr"`
+	// Name is the name of the bucket.
+	Name string `xml:"Name"`
+	// Prefix is the prefix the listing was filtered by.
+	Prefix string `xml:"Prefix"`
+	// Marker is the key the listing started after (ListObjects only).
+	Marker *string `xml:"Marker,omitempty"`
+	// NextMarker is the marker to use for the next page (ListObjects only).
+	NextMarker string `xml:"NextMarker,omitempty"`
+	// StartAfter is the key the listing started after (ListObjectsV2 only).
+	StartAfter string `xml:"StartAfter,omitempty"`
+	// ContinuationToken is the token the listing continued from (ListObjectsV2 only).
+	ContinuationToken string `xml:"ContinuationToken,omitempty"`
+	// NextContinuationToken is the token to use for the next page (ListObjectsV2 only).
+	NextContinuationToken string `xml:"NextContinuationToken,omitempty"`
+	// KeyCount is the number of keys and prefixes returned (ListObjectsV2 only).
+	KeyCount *int `xml:"KeyCount,omitempty"`
+	// MaxKeys is the maximum number of entries returned.
+	MaxKeys int `xml:"MaxKeys"`
+	// Delimiter is the delimiter used to group keys into common prefixes.
+	Delimiter string `xml:"Delimiter,omitempty"`
+	// IsTruncated is true if there are more entries to list.
+	IsTruncated bool `xml:"IsTruncated"`
+	// Contents are the objects in the listing.
+	Contents []Object `xml:"Contents"`
+	// CommonPrefixes are the prefixes keys were grouped into.
+	CommonPrefixes []CommonPrefix `xml:"CommonPrefixes"`
+}
+
+// Object represents an object in an object listing.
+type Object struct {
+	// Key is the name of the object.
+	Key string `xml:"Key"`
+	// LastModified is the modification time of the object in RFC 3339 format.
+	LastModified string `xml:"LastModified"`
+	// ETag is the quoted hex MD5 of the object content.
+	ETag string `xml:"ETag"`
+	// Size is the size of the object in bytes.
+	Size uint64 `xml:"Size"`
+	// StorageClass is the storage class of the object.
+	StorageClass string `xml:"StorageClass"`
+}
+
+// CommonPrefix represents a common prefix in an object listing.
+type CommonPrefix struct {
+	// Prefix is the common prefix.
+	Prefix string `xml:"Prefix"`
+}
+
+// LocationConstraint represents the response for getting a bucket's location.
+// Reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketLocation.html
+type LocationConstraint struct {
+	XMLName xml.Name `xml:"LocationConstraint"`
+	// Xmlns is the XML namespace of the response.
+	Xmlns string `xml:"xmlns,attr"`
+	// Location is the location of the bucket.
+	Location string `xml:",chardata"`
+}
+
+// CopyObjectResult represents the response for copying an object.
+// Reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_CopyObject.html
+type CopyObjectResult struct {
+	XMLName xml.Name `xml:"CopyObjectResult"`
+	// LastModified is the modification time of the copy in RFC 3339 format.
+	LastModified string `xml:"LastModified"`
+	// ETag is the quoted hex MD5 of the copy's content.
+	ETag string `xml:"ETag"`
+}
+
+// Delete is the request body for deleting multiple objects.
+// Reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_DeleteObjects.html
+type Delete struct {
+	XMLName xml.Name `xml:"Delete"`
+	// Quiet suppresses successful deletions from the response.
+	Quiet bool `xml:"Quiet"`
+	// Objects are the objects to delete.
+	Objects []ObjectIdentifier `xml:"Object"`
+}
+
+// ObjectIdentifier identifies an object to delete.
+type ObjectIdentifier struct {
+	// Key is the name of the object.
+	Key string `xml:"Key"`
+}
+
+// DeleteResult represents the response for deleting multiple objects.
+type DeleteResult struct {
+	XMLName xml.Name `xml:"DeleteResult"`
+	// Xmlns is the XML namespace of the response.
+	Xmlns string `xml:"xmlns,attr"`
+	// Deleted are the objects that were deleted.
+	Deleted []DeletedObject `xml:"Deleted"`
+	// Errors are the objects that could not be deleted.
+	Errors []DeleteError `xml:"Error"`
+}
+
+// DeletedObject is an object that was deleted.
+type DeletedObject struct {
+	// Key is the name of the object.
+	Key string `xml:"Key"`
+}
+
+// DeleteError is an object that could not be deleted.
+type DeleteError struct {
+	// Key is the name of the object.
+	Key string `xml:"Key"`
+	// Code is the error code.
+	Code string `xml:"Code"`
+	// Message is a human-readable description of the error.
+	Message string `xml:"Message"`
+}
+
+// Error represents an S3 error response.
+// Reference: https://docs.aws.amazon.com/AmazonS3/latest/API/ErrorResponses.html
+type Error struct {
+	XMLName xml.Name `xml:"Error"`
+	// Code is the error code, e.g. "NoSuchKey".
+	Code string `xml:"Code"`
+	// Message is a human-readable description of the error.
+	Message string `xml:"Message"`
+	// Resource is the bucket or object the error relates to.
+	Resource string `xml:"Resource,omitempty"`
+}
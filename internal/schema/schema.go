@@ -0,0 +1,300 @@
+// Package schema checks the responses of the mock against the schemas of Google's discovery documents,
+// so fields the mock invented, renamed or encodes differently than GCP are caught as the APIs evolve.
+// Reference: https://developers.google.com/discovery/v1/reference/apis
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/discovery"
+)
+
+// APIs are the APIs whose responses are checked, as {name, version}.
+var APIs = [][2]string{
+	{"storage", "v1"},
+	{"sqladmin", "v1beta4"},
+}
+
+// DocumentURL returns the URL of Google's discovery document of an API.
+func DocumentURL(name, version string) string {
+	return "https://www.googleapis.com/discovery/v1/apis/" + name + "/" + version + "/rest"
+}
+
+// LoadDocument reads a discovery document from a file, or downloads it if path is an http(s) URL.
+func LoadDocument(path string) (*discovery.RestDescription, error) {
+	var data []byte
+	var err error
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		data, err = download(path)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read discovery document %s: %w", path, err)
+	}
+
+	var doc discovery.RestDescription
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse discovery document %s: %w", path, err)
+	}
+	return &doc, nil
+}
+
+// download fetches a discovery document.
+func download(url string) ([]byte, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// route is a method of a discovery document with the pattern of the paths it's served at.
+type route struct {
+	httpMethod string
+	pattern    *regexp.Regexp
+	// literal is the length of the path without parameters; the most specific route matches.
+	literal int
+	method  *discovery.Method
+	doc     *discovery.RestDescription
+}
+
+// Validator checks responses against the methods and schemas of discovery documents.
+// It is read-only once created, so it is safe for concurrent use.
+type Validator struct {
+	routes []*route
+}
+
+// pathParamPattern matches the parameters of a method path, {name} or {+name} if it may contain slashes.
+var pathParamPattern = regexp.MustCompile(`\{(\+?)[A-Za-z0-9_.]+\}`)
+
+// New creates a Validator for the methods of discovery documents.
+func New(docs ...*discovery.RestDescription) *Validator {
+	v := &Validator{}
+	for _, doc := range docs {
+		var collect func(resources map[string]*discovery.Resource)
+		collect = func(resources map[string]*discovery.Resource) {
+			for _, resource := range resources {
+				for _, m := range resource.Methods {
+					v.addRoute(doc, m, "/"+doc.ServicePath+m.Path)
+					if m.MediaUpload != nil {
+						for _, protocol := range m.MediaUpload.Protocols {
+							v.addRoute(doc, m, protocol.Path)
+						}
+					}
+				}
+				collect(resource.Resources)
+			}
+		}
+		collect(doc.Resources)
+	}
+	return v
+}
+
+// addRoute adds a route for a method served at path.
+func (v *Validator) addRoute(doc *discovery.RestDescription, m *discovery.Method, path string) {
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	last := 0
+	for _, match := range pathParamPattern.FindAllStringSubmatchIndex(path, -1) {
+		pattern.WriteString(regexp.QuoteMeta(path[last:match[0]]))
+		if match[3] > match[2] {
+			pattern.WriteString(".+")
+		} else {
+			pattern.WriteString("[^/]+")
+		}
+		last = match[1]
+	}
+	pattern.WriteString(regexp.QuoteMeta(path[last:]))
+	pattern.WriteString("$")
+
+	v.routes = append(v.routes, &route{
+		httpMethod: m.HTTPMethod,
+		pattern:    regexp.MustCompile(pattern.String()),
+		literal:    len(pathParamPattern.ReplaceAllString(path, "")),
+		method:     m,
+		doc:        doc,
+	})
+}
+
+// Method returns the method of a request, matching its escaped path, or nil if no document has one.
+func (v *Validator) Method(r *http.Request) *discovery.Method {
+	if route := v.route(r); route != nil {
+		return route.method
+	}
+	return nil
+}
+
+// route returns the most specific route matching a request.
+func (v *Validator) route(r *http.Request) *route {
+	var best *route
+	path := r.URL.EscapedPath()
+	for _, route := range v.routes {
+		if route.httpMethod == r.Method && route.pattern.MatchString(path) && (best == nil || route.literal > best.literal) {
+			best = route
+		}
+	}
+	return best
+}
+
+// Validate checks a JSON response body of a request against the response schema of its method.
+// Returns the divergences, like "Bucket.foo: unknown field", or nil if the body matches the schema
+// or there is no schema to check it against.
+func (v *Validator) Validate(r *http.Request, body []byte) ([]string, error) {
+	route := v.route(r)
+	if route == nil || route.method.Response == nil {
+		return nil, nil
+	}
+
+	var value any
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("response of %s is not JSON: %w", route.method.ID, err)
+	}
+
+	c := &checker{schemas: route.doc.Schemas}
+	c.check(route.method.Response.Ref, value, route.method.Response)
+	return c.divergences, nil
+}
+
+// checker collects the divergences of a value from a schema.
+type checker struct {
+	schemas     map[string]*discovery.Schema
+	divergences []string
+}
+
+// diverge adds a divergence at path.
+func (c *checker) diverge(path, format string, args ...any) {
+	c.divergences = append(c.divergences, path+": "+fmt.Sprintf(format, args...))
+}
+
+// check compares the value at path with a schema, resolving references to the schemas of the document.
+func (c *checker) check(path string, value any, schema *discovery.Schema) {
+	for schema != nil && schema.Ref != "" {
+		resolved, ok := c.schemas[schema.Ref]
+		if !ok {
+			c.diverge(path, "schema %s is missing from the discovery document", schema.Ref)
+			return
+		}
+		schema = resolved
+	}
+	if schema == nil || value == nil {
+		return
+	}
+
+	switch schema.Type {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			c.diverge(path, "expected an object, got %s", jsonType(value))
+			return
+		}
+		if len(schema.Properties) == 0 && schema.AdditionalProperties == nil {
+			return // Free-form object
+		}
+		keys := make([]string, 0, len(object))
+		for key := range object {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			property, ok := schema.Properties[key]
+			if !ok {
+				property = schema.AdditionalProperties
+			}
+			if property == nil {
+				c.diverge(path+"."+key, "unknown field")
+				continue
+			}
+			c.check(path+"."+key, object[key], property)
+		}
+	case "array":
+		array, ok := value.([]any)
+		if !ok {
+			c.diverge(path, "expected an array, got %s", jsonType(value))
+			return
+		}
+		for i, item := range array {
+			c.check(path+"["+strconv.Itoa(i)+"]", item, schema.Items)
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			c.diverge(path, "expected a string, got %s", jsonType(value))
+			return
+		}
+		c.checkFormat(path, s, schema.Format)
+		if len(schema.Enum) > 0 && !slices.Contains(schema.Enum, s) {
+			c.diverge(path, "%q is not one of %s", s, strings.Join(schema.Enum, ", "))
+		}
+	case "integer":
+		n, ok := value.(json.Number)
+		if _, err := n.Int64(); !ok || err != nil {
+			c.diverge(path, "expected an integer, got %s", jsonType(value))
+		}
+	case "number":
+		if _, ok := value.(json.Number); !ok {
+			c.diverge(path, "expected a number, got %s", jsonType(value))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			c.diverge(path, "expected a boolean, got %s", jsonType(value))
+		}
+	}
+}
+
+// checkFormat checks the format of a string, like int64 numbers encoded as strings or timestamps.
+func (c *checker) checkFormat(path, s, format string) {
+	var err error
+	switch format {
+	case "int64":
+		_, err = strconv.ParseInt(s, 10, 64)
+	case "uint64":
+		_, err = strconv.ParseUint(s, 10, 64)
+	case "date-time":
+		_, err = time.Parse(time.RFC3339Nano, s)
+	default:
+		return
+	}
+	if err != nil {
+		c.diverge(path, "%q is not a valid %s", s, format)
+	}
+}
+
+// jsonType names the JSON type of a decoded value.
+func jsonType(value any) string {
+	switch value := value.(type) {
+	case map[string]any:
+		return "an object"
+	case []any:
+		return "an array"
+	case string:
+		return "a string"
+	case json.Number:
+		if strings.ContainsAny(value.String(), ".eE") {
+			return "a number"
+		}
+		return "an integer"
+	case bool:
+		return "a boolean"
+	default:
+		return "null"
+	}
+}
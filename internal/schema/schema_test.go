@@ -0,0 +1,156 @@
+package schema
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/katharinasick/gcp-api-mock/internal/discovery"
+)
+
+// testDocument is a discovery document in the format of Google's, trimmed to a few methods.
+const testDocument = `{
+  "name": "storage",
+  "version": "v1",
+  "servicePath": "storage/v1/",
+  "resources": {
+    "buckets": {"methods": {
+      "get": {"id": "storage.buckets.get", "path": "b/{bucket}", "httpMethod": "GET", "response": {"$ref": "Bucket"}},
+      "delete": {"id": "storage.buckets.delete", "path": "b/{bucket}", "httpMethod": "DELETE"}
+    }},
+    "objects": {"methods": {
+      "get": {"id": "storage.objects.get", "path": "b/{bucket}/o/{object}", "httpMethod": "GET", "response": {"$ref": "Object"}},
+      "insert": {"id": "storage.objects.insert", "path": "b/{bucket}/o", "httpMethod": "POST", "response": {"$ref": "Object"},
+        "mediaUpload": {"protocols": {"simple": {"multipart": true, "path": "/upload/storage/v1/b/{bucket}/o"}}}}
+    }}
+  },
+  "schemas": {
+    "Bucket": {"id": "Bucket", "type": "object", "properties": {
+      "name": {"type": "string"},
+      "metageneration": {"type": "string", "format": "int64"},
+      "timeCreated": {"type": "string", "format": "date-time"},
+      "rpo": {"type": "string", "enum": ["DEFAULT", "ASYNC_TURBO"]},
+      "labels": {"type": "object", "additionalProperties": {"type": "string"}},
+      "versioning": {"type": "object", "properties": {"enabled": {"type": "boolean"}}},
+      "acl": {"type": "array", "items": {"$ref": "BucketAccessControl"}},
+      "retentionPolicy": {"type": "object", "properties": {"retentionPeriod": {"type": "string", "format": "int64"}}}
+    }},
+    "BucketAccessControl": {"id": "BucketAccessControl", "type": "object", "properties": {"entity": {"type": "string"}}},
+    "Object": {"id": "Object", "type": "object", "properties": {"name": {"type": "string"}, "componentCount": {"type": "integer", "format": "int32"}}}
+  }
+}`
+
+func newTestValidator(t *testing.T) *Validator {
+	t.Helper()
+
+	var doc discovery.RestDescription
+	if err := json.Unmarshal([]byte(testDocument), &doc); err != nil {
+		t.Fatalf("failed to parse test document: %v", err)
+	}
+	return New(&doc)
+}
+
+func TestValidator_Method(t *testing.T) {
+	v := newTestValidator(t)
+
+	tests := []struct {
+		method string
+		target string
+		wantID string
+	}{
+		{http.MethodGet, "/storage/v1/b/foo", "storage.buckets.get"},
+		{http.MethodDelete, "/storage/v1/b/foo", "storage.buckets.delete"},
+		{http.MethodGet, "/storage/v1/b/foo/o/dir%2Ffile.txt", "storage.objects.get"},
+		{http.MethodPost, "/upload/storage/v1/b/foo/o?uploadType=media&name=a", "storage.objects.insert"},
+		{http.MethodGet, "/storage/v1/b/foo/o/dir/file.txt", ""},
+		{http.MethodGet, "/sql/v1beta4/projects/p/instances", ""},
+	}
+	for _, tt := range tests {
+		method := v.Method(httptest.NewRequest(tt.method, tt.target, nil))
+		if (method == nil && tt.wantID != "") || (method != nil && method.ID != tt.wantID) {
+			t.Errorf("%s %s: expected method %q, got %+v", tt.method, tt.target, tt.wantID, method)
+		}
+	}
+}
+
+func TestValidator_Validate(t *testing.T) {
+	v := newTestValidator(t)
+
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{"valid", `{"name": "foo", "metageneration": "1", "timeCreated": "2024-01-02T03:04:05.678Z", "rpo": "DEFAULT",
+			"labels": {"env": "test"}, "versioning": {"enabled": true}, "acl": [{"entity": "allUsers"}]}`, nil},
+		{"unknown field", `{"name": "foo", "softDeletePolicy": {}}`, []string{"Bucket.softDeletePolicy: unknown field"}},
+		{"nested unknown field", `{"acl": [{"entity": "allUsers", "role": "READER"}]}`, []string{"Bucket.acl[0].role: unknown field"}},
+		{"int64 as number", `{"metageneration": 1, "retentionPolicy": {"retentionPeriod": "ten"}}`, []string{
+			"Bucket.metageneration: expected a string, got an integer",
+			`Bucket.retentionPolicy.retentionPeriod: "ten" is not a valid int64`,
+		}},
+		{"wrong types", `{"versioning": {"enabled": "true"}, "labels": {"env": 1}, "acl": {}}`, []string{
+			"Bucket.acl: expected an array, got an object",
+			"Bucket.labels.env: expected a string, got an integer",
+			"Bucket.versioning.enabled: expected a boolean, got a string",
+		}},
+		{"invalid timestamp and enum", `{"timeCreated": "yesterday", "rpo": "TURBO"}`, []string{
+			`Bucket.rpo: "TURBO" is not one of DEFAULT, ASYNC_TURBO`,
+			`Bucket.timeCreated: "yesterday" is not a valid date-time`,
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := v.Validate(httptest.NewRequest(http.MethodGet, "/storage/v1/b/foo", nil), []byte(tt.body))
+			if err != nil {
+				t.Fatalf("Validate() error: %v", err)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("expected divergences %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	// Methods without a response schema have nothing to check
+	if got, err := v.Validate(httptest.NewRequest(http.MethodDelete, "/storage/v1/b/foo", nil), []byte(`{"any": 1}`)); err != nil || got != nil {
+		t.Errorf("expected no divergences for a method without a response, got %q (%v)", got, err)
+	}
+	if _, err := v.Validate(httptest.NewRequest(http.MethodGet, "/storage/v1/b/foo", nil), []byte(`<xml/>`)); err == nil {
+		t.Error("expected an error for a response that isn't JSON")
+	}
+}
+
+func TestLoadDocument(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.v1.json")
+	if err := os.WriteFile(path, []byte(testDocument), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := LoadDocument(path)
+	if err != nil {
+		t.Fatalf("LoadDocument() error: %v", err)
+	}
+	if doc.Schemas["Bucket"] == nil || len(doc.Schemas["Bucket"].Properties["rpo"].Enum) != 2 {
+		t.Errorf("expected the schemas with their enums, got %+v", doc.Schemas)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/discovery/v1/apis/storage/v1/rest" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(testDocument))
+	}))
+	defer server.Close()
+
+	if _, err := LoadDocument(server.URL + "/discovery/v1/apis/storage/v1/rest"); err != nil {
+		t.Errorf("LoadDocument() error for a URL: %v", err)
+	}
+	if _, err := LoadDocument(server.URL + "/missing"); err == nil {
+		t.Error("expected an error for a missing document")
+	}
+}
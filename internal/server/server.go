@@ -2,63 +2,731 @@
 package server
 
 import (
+	"context"
+	"crypto/tls"
+	"io/fs"
+	"log"
 	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
 	"time"
 
+	"github.com/katharinasick/gcp-api-mock/internal/auditlog"
+	"github.com/katharinasick/gcp-api-mock/internal/blob"
+	"github.com/katharinasick/gcp-api-mock/internal/capabilities"
+	"github.com/katharinasick/gcp-api-mock/internal/certs"
+	"github.com/katharinasick/gcp-api-mock/internal/clock"
+	"github.com/katharinasick/gcp-api-mock/internal/cloudscheduler"
 	"github.com/katharinasick/gcp-api-mock/internal/config"
+	"github.com/katharinasick/gcp-api-mock/internal/discovery"
+	"github.com/katharinasick/gcp-api-mock/internal/eventarc"
+	"github.com/katharinasick/gcp-api-mock/internal/gke"
 	"github.com/katharinasick/gcp-api-mock/internal/handler"
+	"github.com/katharinasick/gcp-api-mock/internal/idtoken"
+	"github.com/katharinasick/gcp-api-mock/internal/jobs"
+	"github.com/katharinasick/gcp-api-mock/internal/latency"
+	"github.com/katharinasick/gcp-api-mock/internal/metrics"
 	"github.com/katharinasick/gcp-api-mock/internal/middleware"
+	"github.com/katharinasick/gcp-api-mock/internal/mirror"
+	"github.com/katharinasick/gcp-api-mock/internal/namespace"
+	"github.com/katharinasick/gcp-api-mock/internal/notification"
+	"github.com/katharinasick/gcp-api-mock/internal/override"
+	"github.com/katharinasick/gcp-api-mock/internal/readonly"
+	"github.com/katharinasick/gcp-api-mock/internal/recorder"
+	"github.com/katharinasick/gcp-api-mock/internal/redact"
+	"github.com/katharinasick/gcp-api-mock/internal/s3"
+	"github.com/katharinasick/gcp-api-mock/internal/schema"
+	"github.com/katharinasick/gcp-api-mock/internal/sharedstate"
+	"github.com/katharinasick/gcp-api-mock/internal/slo"
+	"github.com/katharinasick/gcp-api-mock/internal/sqldata"
+	"github.com/katharinasick/gcp-api-mock/internal/sqlproxy"
 	"github.com/katharinasick/gcp-api-mock/internal/store"
+	"github.com/katharinasick/gcp-api-mock/internal/templating"
+	"github.com/katharinasick/gcp-api-mock/internal/transfer"
+	"github.com/katharinasick/gcp-api-mock/internal/usage"
+	"github.com/katharinasick/gcp-api-mock/web"
 )
 
+// Server is the HTTP server of the mock. It lets uploads and downloads in flight finish when it shuts down.
+type Server struct {
+	*http.Server
+	transfers *transfer.Tracker
+	sqlProxy  *sqlproxy.Proxy
+	jobs      *jobs.Scheduler
+}
+
+// tickInterval is how often the time-dependent state of the default namespace is updated in the background.
+const tickInterval = time.Second
+
+// namespaceExpiryInterval is how often namespaces are checked for expiry in the background.
+const namespaceExpiryInterval = time.Minute
+
 // New creates and configures a new HTTP server with all routes and middleware.
-func New(cfg *config.Config) *http.Server {
-	// Initialize in-memory store
-	dataStore := store.New()
+func New(cfg *config.Config) *Server {
+	return NewWithStore(cfg, store.New())
+}
+
+// NewWithStore creates a server like New that serves the resources of dataStore,
+// so callers can seed and inspect the state directly.
+func NewWithStore(cfg *config.Config, dataStore *store.Store) *Server {
+	// Read the time from a virtual clock, so tests can freeze and advance it via the admin API
+	clk := clock.New()
+	clk.SetSkew(parseClockSkew(cfg))
+	configureStore := newStoreConfigurer(cfg, clk)
+	configureStore(dataStore, cfg.ExternalURL())
 
-	// Create router with all routes and get the request logger
-	mux, requestLogger := newRouter(cfg, dataStore)
+	// Store object content on disk if configured
+	var backend blob.Backend = blob.NewMemoryBackend()
+	if cfg.BlobDir != "" {
+		diskBackend, err := blob.NewDiskBackend(cfg.BlobDir)
+		if err != nil {
+			log.Printf("Failed to use blob directory, keeping object content in memory: %v", err)
+		} else {
+			backend = diskBackend
+		}
+	}
+	// Store equal content only once if configured
+	if cfg.BlobDedup {
+		backend = blob.NewDedupBackend(backend)
+	}
+	// Evict the least recently used content beyond the size limit if configured
+	maxContentSize := parseMaxContentSize(cfg)
+	if maxContentSize > 0 {
+		backend = blob.NewLRUBackend(backend, maxContentSize)
+	}
+	dataStore.SetBlobBackend(backend)
+
+	// Restore a snapshot at startup if configured
+	if cfg.SnapshotFile != "" {
+		if err := restoreSnapshot(dataStore, cfg.SnapshotFile); err != nil {
+			log.Printf("Failed to restore snapshot, starting empty: %v", err)
+		}
+	}
+
+	// Start recording right away if configured, otherwise recording is started via the admin API
+	rec := recorder.New()
+	if cfg.RecordFile != "" {
+		if err := rec.Start(cfg.RecordFile); err != nil {
+			log.Printf("Failed to start recording: %v", err)
+		}
+	}
+
+	// Redact secrets from the request log and recordings if configured
+	requestLogger := handler.NewRequestLogger(100)
+	if redaction := newRedaction(cfg); redaction != nil {
+		requestLogger.SetRedaction(redaction.Exchange)
+		rec.SetRedaction(redaction.Exchange)
+	}
+
+	// Emit audit log entries for admin actions if configured
+	var auditLogger *auditlog.Logger
+	if cfg.AuditLogFile != "" || cfg.AuditLogURL != "" {
+		var err error
+		auditLogger, err = auditlog.New(cfg.AuditLogFile, cfg.AuditLogURL)
+		if err != nil {
+			log.Printf("Failed to set up audit logging: %v", err)
+		}
+	}
+
+	injector := newLatencyInjector(cfg)
+
+	// Serve HTTPS if configured, generating a self-signed certificate if none is provided
+	var tlsConfig *tls.Config
+	var caCert []byte
+	if cfg.IsTLS() {
+		var err error
+		tlsConfig, caCert, err = newTLSConfig(cfg)
+		if err != nil {
+			log.Printf("Failed to set up TLS, serving plain HTTP: %v", err)
+		}
+	}
+
+	// All namespaces share the request log, recording, latency profile, simulated objective, clock, audit log, read-only mode and ID token key
+	env := &environment{
+		cfg:           cfg,
+		rec:           rec,
+		injector:      injector,
+		slo:           newSLOSimulator(cfg),
+		clk:           clk,
+		requestLogger: requestLogger,
+		auditLogger:   auditLogger,
+		caCert:        caCert,
+		transfers:     transfer.New(),
+		readOnly:      newReadOnlySwitch(cfg),
+		templates:     newResponseTemplates(cfg),
+		schemas:       newSchemaValidator(cfg),
+		signer:        idtoken.NewSigner(clk.Now),
+		metrics:       metrics.New(),
+		jobs:          jobs.New(),
+	}
+
+	// Share the state of the default namespace with other replicas if configured
+	if cfg.RedisURL != "" {
+		backend, err := sharedstate.NewRedis(cfg.RedisURL, cfg.RedisKey)
+		if err != nil {
+			log.Printf("Failed to set up shared state, keeping state per replica: %v", err)
+		} else {
+			env.sharedState = sharedstate.New(dataStore, backend)
+		}
+	}
+
+	// Give the Cloud SQL instances of the default namespace ports if configured
+	env.sqlProxy = newSQLProxy(cfg, dataStore)
+
+	// Back the Cloud SQL databases of the default namespace with SQLite files if configured
+	if cfg.SQLDataDir != "" {
+		files, err := sqldata.New(dataStore, cfg.SQLDataDir)
+		if err != nil {
+			log.Printf("Failed to set up SQLite databases, keeping databases as metadata only: %v", err)
+		} else {
+			files.Sync()
+			env.sqlData = files
+		}
+	}
+
+	// Mirror the buckets of the default namespace to a directory if configured
+	if cfg.MirrorDir != "" {
+		env.mirror = newMirror(cfg, dataStore, env.jobs)
+	}
+
+	// Each namespace gets an empty store of its own, keeping object content in memory
+	namespaceTTL := parseNamespaceTTL(cfg)
+	namespaces := namespace.New(func(name, prefix string) (http.Handler, func()) {
+		namespaceStore := store.New()
+		configureStore(namespaceStore, cfg.ExternalURL()+prefix)
+		var backend blob.Backend = blob.NewMemoryBackend()
+		if cfg.BlobDedup {
+			backend = blob.NewDedupBackend(backend)
+		}
+		if maxContentSize > 0 {
+			backend = blob.NewLRUBackend(backend, maxContentSize)
+		}
+		namespaceStore.SetBlobBackend(backend)
+		return env.newHandler(namespaceStore, nil), namespaceStore.Tick
+	}, namespaceTTL)
+
+	// Apply the clock to the stores in the background, so time-dependent changes, like a Cloud SQL instance
+	// becoming RUNNABLE or a lifecycle rule deleting an object, happen and are published without a request.
+	// Replicas sharing their state apply it while holding the shared locks, like requests that change it.
+	tickDefault := func() error {
+		dataStore.Tick()
+		return nil
+	}
+	if env.sharedState != nil {
+		tickDefault = func() error {
+			return env.sharedState.Run(context.Background(), store.TickFamilies, dataStore.Tick)
+		}
+	}
+	registerJob(env.jobs, jobs.Job{
+		Name:        "store.tick",
+		Description: "Purge expired soft-deleted objects, apply lifecycle rules, complete Cloud SQL instance creations, start and end maintenance and run due Cloud Scheduler jobs, in all namespaces",
+		Interval:    tickInterval,
+		Run: func() error {
+			namespaces.Tick()
+			return tickDefault()
+		},
+	})
+	if namespaceTTL > 0 {
+		registerJob(env.jobs, jobs.Job{
+			Name:        "namespaces.expire",
+			Description: "Remove the namespaces that haven't been used for GCP_MOCK_NAMESPACE_TTL",
+			Interval:    namespaceExpiryInterval,
+			Run: func() error {
+				if removed := namespaces.RemoveExpired(); removed > 0 {
+					log.Printf("Removed %d expired namespaces", removed)
+				}
+				return nil
+			},
+		})
+	}
 
 	// Apply middleware stack
-	var h http.Handler = mux
-	h = middleware.APILogger(requestLogger.Add)(h) // Log API requests to UI
+	defaultHandler := env.newHandler(dataStore, namespaces)
+	defaultHandler = middleware.Metrics(env.metrics, func(bucket string) bool {
+		return dataStore.GetBucket(bucket) != nil
+	})(defaultHandler)
+	if env.sqlProxy != nil {
+		defaultHandler = env.sqlProxy.Middleware(defaultHandler)
+	}
+	if env.sqlData != nil {
+		defaultHandler = env.sqlData.Middleware(defaultHandler)
+	}
+	if env.mirror != nil {
+		defaultHandler = env.mirror.Middleware(defaultHandler)
+	}
+	if env.sharedState != nil {
+		defaultHandler = env.sharedState.Middleware(defaultHandler)
+	}
+	h := namespaces.Handler(defaultHandler)
+	h = middleware.APILogger(env.requestLogger.AddExchange, handler.MaxLoggedBodySize)(h) // Log API requests to UI
 	h = middleware.Logger(h)
 	h = middleware.Recovery(h)
 	h = middleware.RequestID(h)
 
-	return &http.Server{
-		Addr:         cfg.Address(),
-		Handler:      h,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+	env.jobs.Start()
+	return &Server{
+		Server: &http.Server{
+			Addr:         cfg.Address(),
+			Handler:      h,
+			TLSConfig:    tlsConfig,
+			ReadTimeout:  15 * time.Second,
+			WriteTimeout: 15 * time.Second,
+			IdleTimeout:  60 * time.Second,
+		},
+		transfers: env.transfers,
+		sqlProxy:  env.sqlProxy,
+		jobs:      env.jobs,
+	}
+}
+
+// Shutdown drains the server before shutting it down: /ready fails, so load balancers stop sending new clients,
+// while uploads and downloads in flight and resumable uploads waiting for their next chunk are still served.
+// Once they're finished, the HTTP server is shut down gracefully. If ctx is done first, the remaining transfers
+// are aborted and the context's error is returned.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.transfers.Drain()
+	if stats := s.transfers.Stats(); len(stats.Active) > 0 || stats.Sessions > 0 {
+		log.Printf("Draining %d transfers and %d resumable upload sessions", len(stats.Active), stats.Sessions)
+	}
+
+	if err := s.transfers.Wait(ctx); err != nil {
+		stats := s.transfers.Stats()
+		log.Printf("Aborting %d transfers and %d resumable upload sessions", len(stats.Active), stats.Sessions)
+		s.closeBackgroundServices()
+		s.Server.Close()
+		return err
+	}
+	s.closeBackgroundServices()
+	return s.Server.Shutdown(ctx)
+}
+
+// Close stops the background jobs and closes the ports of the Cloud SQL instances, like Shutdown,
+// and closes the HTTP server right away. Servers whose handler is served elsewhere, like by an
+// httptest.Server, are closed with it too, so their jobs don't outlive them.
+func (s *Server) Close() error {
+	s.closeBackgroundServices()
+	return s.Server.Close()
+}
+
+// closeBackgroundServices closes the ports of the Cloud SQL instances, if they have any,
+// and stops the background jobs, like watching the mirror directory.
+func (s *Server) closeBackgroundServices() {
+	if s.sqlProxy != nil {
+		s.sqlProxy.Close()
 	}
+	s.jobs.Stop()
+}
+
+// environment holds what the handlers of all namespaces share.
+type environment struct {
+	cfg           *config.Config
+	rec           *recorder.Recorder
+	injector      *latency.Injector
+	slo           *slo.Simulator
+	clk           *clock.Clock
+	requestLogger *handler.RequestLogger
+	auditLogger   *auditlog.Logger
+	caCert        []byte
+	transfers     *transfer.Tracker
+	sharedState   *sharedstate.Syncer
+	readOnly      *readonly.Switch
+	templates     *templating.Templates
+	schemas       *schema.Validator
+	signer        *idtoken.Signer
+	sqlProxy      *sqlproxy.Proxy
+	sqlData       *sqldata.Files
+	mirror        *mirror.Mirror
+	metrics       *metrics.Metrics
+	jobs          *jobs.Scheduler
+}
+
+// newHandler creates the routes and the middleware that depend on the state of dataStore.
+// The namespaces are only passed for the default namespace, which serves their admin API.
+func (env *environment) newHandler(dataStore *store.Store, namespaces *namespace.Namespaces) http.Handler {
+	cfg := env.cfg
+
+	// Create router with all routes, counting the API calls of the namespace and answering them with its overrides
+	tracker := usage.New()
+	overrides := override.New()
+	var h http.Handler = middleware.Usage(tracker)(env.newRouter(dataStore, namespaces, tracker, overrides))
+
+	if cfg.S3Enabled {
+		h = routeS3Requests(newS3Router(cfg, dataStore), h)
+	}
+	if len(cfg.DisabledServices) > 0 {
+		h = middleware.ServiceUsage(cfg.DisabledServices)(h)
+	}
+	h = middleware.RequesterPays(dataStore.IsRequesterPays)(h)
+	if env.auditLogger != nil {
+		h = middleware.AuditLog(env.auditLogger)(h)
+	}
+	h = middleware.ReadOnly(env.readOnly)(h)
+	if cfg.IsStrictAuth() {
+		h = middleware.Auth(cfg.S3Enabled)(h)
+	}
+	if env.schemas != nil {
+		h = middleware.SchemaValidation(env.schemas, cfg.SchemaValidation == "fail")(h)
+	}
+	h = middleware.Template(env.templates)(h)
+	h = middleware.Override(overrides)(h)
+	h = middleware.Date(env.clk)(h)
+	h = middleware.Latency(env.injector)(h)
+	h = middleware.SLO(env.slo)(h)
+	h = middleware.Record(env.rec)(h)
+	h = middleware.CORS(dataStore.GetBucketCors)(h)
+	h = middleware.Transfers(env.transfers)(h)
+	if len(cfg.VirtualHostDomains) > 0 {
+		h = middleware.VirtualHost(cfg.VirtualHostDomains)(h)
+	}
+	return h
+}
+
+// newStoreConfigurer returns a function applying the configuration to a store, with the base URL
+// of its self links. Invalid settings are logged once and left out.
+func newStoreConfigurer(cfg *config.Config, clk *clock.Clock) func(dataStore *store.Store, baseURL string) {
+	var maxObjectSize int64
+	if cfg.MaxObjectSize != "" {
+		size, err := config.ParseByteSize(cfg.MaxObjectSize)
+		if err != nil {
+			log.Printf("Invalid maximum object size, not limiting object size: %v", err)
+		} else {
+			maxObjectSize = size
+		}
+	}
+
+	// Make new Cloud SQL instances take a while to become RUNNABLE if configured
+	var sqlCreateDelay time.Duration
+	if cfg.SQLCreateDelay != "" {
+		delay, err := time.ParseDuration(cfg.SQLCreateDelay)
+		if err != nil {
+			log.Printf("Invalid Cloud SQL create delay, creating instances right away: %v", err)
+		} else {
+			sqlCreateDelay = delay
+		}
+	}
+
+	// Put Cloud SQL instances into MAINTENANCE within their maintenance window if configured
+	var sqlMaintenanceDuration time.Duration
+	if cfg.SQLMaintenanceDuration != "" {
+		duration, err := time.ParseDuration(cfg.SQLMaintenanceDuration)
+		if err != nil || duration <= 0 {
+			log.Printf("Invalid Cloud SQL maintenance duration, not simulating scheduled maintenance: %q", cfg.SQLMaintenanceDuration)
+		} else {
+			sqlMaintenanceDuration = duration
+		}
+	}
+
+	dispatcher := notification.NewDispatcher()
+	schedulerDispatcher := cloudscheduler.NewDispatcher()
+	eventarcDispatcher := eventarc.NewDispatcher()
+
+	// Point GKE clusters at a real cluster, like a local kind cluster, if configured
+	var gkeKubeconfig *gke.Kubeconfig
+	if cfg.GKEKubeconfig != "" {
+		kubeconfig, err := gke.LoadKubeconfig(cfg.GKEKubeconfig)
+		if err != nil {
+			log.Printf("Invalid GKE kubeconfig, giving clusters made-up endpoints: %v", err)
+		} else {
+			gkeKubeconfig = kubeconfig
+		}
+	}
+
+	return func(dataStore *store.Store, baseURL string) {
+		dataStore.SetBaseURL(baseURL)
+		dataStore.SetNotificationHandler(dispatcher.Deliver)
+		dataStore.SetTriggerHandler(eventarcDispatcher.Deliver)
+		dataStore.SetRedisEndpoint(cfg.MemorystoreEndpoint)
+		dataStore.SetGKEKubeconfig(gkeKubeconfig)
+		dataStore.SetStrictValidation(cfg.StrictValidation)
+		dataStore.SetStrictObjectPaths(cfg.StrictObjectPaths)
+		dataStore.SetClock(clk.Now)
+		if maxObjectSize > 0 {
+			dataStore.SetMaxObjectSize(maxObjectSize)
+		}
+		if sqlCreateDelay > 0 {
+			dataStore.SetSQLCreateDelay(sqlCreateDelay)
+		}
+		if sqlMaintenanceDuration > 0 {
+			dataStore.SetSQLMaintenanceDuration(sqlMaintenanceDuration)
+		}
+		// Run Cloud Scheduler jobs when they are due if configured
+		if cfg.SchedulerCron {
+			dataStore.SetSchedulerHandler(func(job *cloudscheduler.Job, scheduleTime time.Time) {
+				schedulerDispatcher.Deliver(job, scheduleTime, func(status *cloudscheduler.Status) {
+					dataStore.RecordSchedulerAttempt(job.Name, job.LastAttemptTime, status)
+				})
+			})
+		}
+	}
+}
+
+// newReadOnlySwitch returns the switch of the read-only mode in the configured mode.
+// An invalid mode is logged and the mock starts in read-only mode, rejecting mutating requests,
+// since whoever configured it didn't want them applied.
+func newReadOnlySwitch(cfg *config.Config) *readonly.Switch {
+	mode, err := readonly.ParseMode(cfg.ReadOnly)
+	if err != nil {
+		log.Printf("Invalid read-only mode, rejecting mutating requests: %v", err)
+		mode = readonly.ModeReject
+	}
+	return readonly.New(mode)
+}
+
+// newSQLProxy returns the proxy giving the Cloud SQL instances of dataStore ports, with the ports of the
+// existing instances open, or nil if it isn't configured. Invalid settings are logged and the instances get no ports.
+func newSQLProxy(cfg *config.Config, dataStore *store.Store) *sqlproxy.Proxy {
+	if cfg.SQLProxyPorts == "" {
+		return nil
+	}
+	ports, err := sqlproxy.ParsePortRange(cfg.SQLProxyPorts)
+	if err != nil {
+		log.Printf("Invalid Cloud SQL proxy ports, not opening ports for instances: %v", err)
+		return nil
+	}
+	targets, err := sqlproxy.ParseTargets(cfg.SQLProxyTargets)
+	if err != nil {
+		log.Printf("Invalid Cloud SQL proxy targets, not opening ports for instances: %v", err)
+		return nil
+	}
+
+	proxy := sqlproxy.New(dataStore, cfg.Host, ports, targets)
+	proxy.Sync()
+	return proxy
+}
+
+// newMirror mirrors the buckets of dataStore to the configured directory and registers a job watching it
+// for local edits. Returns nil if the directory can't be used; an invalid interval is logged
+// and the default of one second is used.
+func newMirror(cfg *config.Config, dataStore *store.Store, scheduler *jobs.Scheduler) *mirror.Mirror {
+	m, err := mirror.New(dataStore, cfg.MirrorDir)
+	if err != nil {
+		log.Printf("Failed to use mirror directory, not mirroring buckets: %v", err)
+		return nil
+	}
+
+	interval := time.Second
+	if cfg.MirrorInterval != "" {
+		parsed, err := time.ParseDuration(cfg.MirrorInterval)
+		if err != nil || parsed <= 0 {
+			log.Printf("Invalid mirror interval, checking the mirror directory every second: %q", cfg.MirrorInterval)
+		} else {
+			interval = parsed
+		}
+	}
+
+	m.Sync()
+	registerJob(scheduler, jobs.Job{
+		Name:        "mirror.sync",
+		Description: "Pick up the files edited in GCP_MOCK_MIRROR_DIR",
+		Interval:    interval,
+		Run: func() error {
+			m.Sync()
+			return nil
+		},
+	})
+	return m
+}
+
+// registerJob registers a background job, logging if it can't be.
+func registerJob(scheduler *jobs.Scheduler, job jobs.Job) {
+	if err := scheduler.Register(job); err != nil {
+		log.Printf("Failed to register background job: %v", err)
+	}
+}
+
+// parseMaxContentSize returns the size limit of the stored content in bytes, or 0 if it isn't limited.
+// An invalid limit is logged and the content isn't limited.
+func parseMaxContentSize(cfg *config.Config) int64 {
+	if cfg.MaxContentSize == "" {
+		return 0
+	}
+	size, err := config.ParseByteSize(cfg.MaxContentSize)
+	if err != nil {
+		log.Printf("Invalid maximum content size, not limiting stored content: %v", err)
+		return 0
+	}
+	return size
+}
+
+// parseClockSkew returns the skew of the time reported in HTTP headers. An invalid skew is logged
+// and the headers report the virtual time as it is.
+func parseClockSkew(cfg *config.Config) time.Duration {
+	if cfg.ClockSkew == "" {
+		return 0
+	}
+	skew, err := time.ParseDuration(cfg.ClockSkew)
+	if err != nil {
+		log.Printf("Invalid clock skew, reporting the time without skew: %v", err)
+		return 0
+	}
+	return skew
+}
+
+// parseNamespaceTTL returns how long unused namespaces are kept. Invalid TTLs are logged and
+// namespaces are kept until they're deleted.
+func parseNamespaceTTL(cfg *config.Config) time.Duration {
+	if cfg.NamespaceTTL == "" {
+		return 0
+	}
+	ttl, err := time.ParseDuration(cfg.NamespaceTTL)
+	if err != nil {
+		log.Printf("Invalid namespace TTL, keeping namespaces until they're deleted: %v", err)
+		return 0
+	}
+	return ttl
 }
 
 // newRouter creates and configures the HTTP router with all application routes.
-// Returns the mux and request logger for middleware integration.
-func newRouter(cfg *config.Config, dataStore *store.Store) (*http.ServeMux, *handler.RequestLogger) {
+// With namespaces, the router serves their admin API and replays requests into the namespace they were sent to.
+// The usage report of the router shows the calls the tracker counted.
+func (env *environment) newRouter(dataStore *store.Store, namespaces *namespace.Namespaces, tracker *usage.Tracker, overrides *override.Overrides) *http.ServeMux {
 	mux := http.NewServeMux()
 
-	// Create request logger for UI
-	requestLogger := handler.NewRequestLogger(100)
+	var replay http.Handler = mux
+	if namespaces != nil {
+		replay = namespaces.Handler(mux)
+	}
 
 	// Create handlers
-	healthHandler := handler.NewHealth()
+	readinessChecks := []handler.ReadinessCheck{
+		{Name: "blobStorage", Check: dataStore.CheckBlobBackend},
+		{Name: "shutdown", Check: env.transfers.CheckReady},
+	}
+	if env.sharedState != nil {
+		readinessChecks = append(readinessChecks, handler.ReadinessCheck{Name: "sharedState", Check: env.sharedState.Check})
+	}
+	healthHandler := handler.NewHealth(readinessChecks...)
 	storageHandler := handler.NewStorage(dataStore)
 	sqlAdminHandler := handler.NewSQLAdmin(dataStore)
+	firestoreHandler := handler.NewFirestore(dataStore)
+	registryHandler := handler.NewRegistry(dataStore)
+	cloudRunHandler := handler.NewCloudRun(dataStore)
+	monitoringHandler := handler.NewMonitoring(dataStore)
+	loggingHandler := handler.NewLogging(dataStore)
+	schedulerHandler := handler.NewCloudScheduler(dataStore)
+	eventarcHandler := handler.NewEventarc(dataStore)
+	memorystoreHandler := handler.NewMemorystore(dataStore)
+	gkeHandler := handler.NewGKE(dataStore)
+	computeHandler := handler.NewCompute(dataStore)
+	billingHandler := handler.NewBilling(dataStore)
+	orgPolicyHandler := handler.NewOrgPolicy(dataStore)
+	locationOperationsHandler := handler.NewLocationOperations(dataStore)
+	discoveryHandler := handler.NewDiscovery()
+	adminHandler := handler.NewAdmin(dataStore, env.rec, replay, env.injector, env.clk, env.requestLogger)
 
 	// Health check routes
 	mux.HandleFunc("GET /health", healthHandler.Check)
 	mux.HandleFunc("GET /ready", healthHandler.Ready)
+	mux.HandleFunc("GET /version", healthHandler.Version)
+	mux.HandleFunc("GET /capabilities", handler.NewCapabilities(capabilities.Get(env.cfg)).Get)
+
+	// API discovery routes
+	mux.HandleFunc("GET /discovery/v1/apis", discoveryHandler.ListAPIs)
+	mux.HandleFunc("GET /discovery/v1/apis/{api}/{version}/rest", discoveryHandler.GetRest)
+
+	// GCE metadata server routes (GCE_METADATA_HOST) and the keys its ID tokens are signed with
+	metadataHandler := handler.NewMetadata(dataStore, env.signer)
+	mux.HandleFunc("GET /computeMetadata/v1/project/project-id", metadataHandler.GetProjectID)
+	mux.HandleFunc("GET /computeMetadata/v1/project/numeric-project-id", metadataHandler.GetNumericProjectID)
+	mux.HandleFunc("GET /computeMetadata/v1/instance/service-accounts/{account}/email", metadataHandler.GetServiceAccountEmail)
+	mux.HandleFunc("GET /computeMetadata/v1/instance/service-accounts/{account}/token", metadataHandler.GetServiceAccountToken)
+	mux.HandleFunc("GET /computeMetadata/v1/instance/service-accounts/{account}/identity", metadataHandler.GetServiceAccountIdentity)
+	mux.HandleFunc("GET /oauth2/v3/certs", metadataHandler.GetCerts)
+
+	// Admin routes (control the mock itself)
+	mux.HandleFunc("GET /admin/recording", adminHandler.GetRecording)
+	mux.HandleFunc("POST /admin/recording/start", adminHandler.StartRecording)
+	mux.HandleFunc("POST /admin/recording/stop", adminHandler.StopRecording)
+	mux.HandleFunc("POST /admin/recording/replay", adminHandler.ReplayRecording)
+	mux.HandleFunc("GET /admin/latency", adminHandler.GetLatency)
+	mux.HandleFunc("PUT /admin/latency", adminHandler.SetLatency)
+	mux.HandleFunc("GET /admin/clock", adminHandler.GetClock)
+	mux.HandleFunc("PUT /admin/clock", adminHandler.SetClock)
+	mux.HandleFunc("POST /admin/clock/advance", adminHandler.AdvanceClock)
+	mux.HandleFunc("DELETE /admin/clock", adminHandler.ResetClock)
+	mux.HandleFunc("PUT /admin/sql/instances/{instance}/state", adminHandler.SetSQLInstanceState)
+	mux.HandleFunc("POST /admin/sql/instances/{instance}/maintenance", adminHandler.StartSQLMaintenance)
+	mux.HandleFunc("GET /admin/storage/buckets/{bucket}/quota", adminHandler.GetBucketQuota)
+	mux.HandleFunc("PUT /admin/storage/buckets/{bucket}/quota", adminHandler.SetBucketQuota)
+	mux.HandleFunc("DELETE /admin/storage/buckets/{bucket}/quota", adminHandler.DeleteBucketQuota)
+	mux.HandleFunc("DELETE /admin/storage/buckets/{bucket}/objects", adminHandler.DeleteObjects)
+	mux.HandleFunc("GET /admin/storage/buckets/{bucket}/archive", adminHandler.DownloadArchive)
+	mux.HandleFunc("GET /admin/storage/objects", adminHandler.SearchObjects)
+	mux.HandleFunc("GET /admin/storage/dedup", adminHandler.GetDedupStats)
+	mux.HandleFunc("GET /admin/storage/content", adminHandler.GetContentLimitStats)
+	mux.HandleFunc("POST /admin/storage/import", adminHandler.ImportBucket)
+	mux.HandleFunc("POST /admin/generate", adminHandler.GenerateData)
+	mux.HandleFunc("POST /admin/reset", adminHandler.Reset)
+	mux.HandleFunc("POST /admin/tick", adminHandler.Tick)
+	mux.HandleFunc("POST /admin/scheduler/projects/{project}/locations/{location}/jobs/{job}/run", schedulerHandler.RunJobNow)
+	mux.HandleFunc("GET /admin/gke/projects/{project}/locations/{location}/clusters/{cluster}/kubeconfig", gkeHandler.GetKubeconfig)
+	mux.HandleFunc("POST /admin/billing/billingAccounts/{billingAccount}/budgets/{budget}/alert", billingHandler.SendAlert)
+	mux.HandleFunc("GET /admin/requests", adminHandler.ListRequests)
+	mux.HandleFunc("GET /admin/requests/export", adminHandler.ExportRequests)
+	mux.HandleFunc("POST /admin/requests/{id}/replay", adminHandler.ReplayRequest)
+	mux.HandleFunc("GET /admin/snapshot", adminHandler.Snapshot)
+	mux.HandleFunc("POST /admin/restore", adminHandler.Restore)
+	mux.HandleFunc("GET /admin/terraform", adminHandler.ExportTerraform)
+	mux.HandleFunc("GET /admin/transfers", handler.NewTransfers(env.transfers).Stats)
+	usageHandler := handler.NewUsage(tracker)
+	mux.HandleFunc("GET /admin/usage", usageHandler.Get)
+	mux.HandleFunc("DELETE /admin/usage", usageHandler.Reset)
+	readOnlyHandler := handler.NewReadOnly(env.readOnly)
+	mux.HandleFunc("GET /admin/readonly", readOnlyHandler.Get)
+	mux.HandleFunc("PUT /admin/readonly", readOnlyHandler.Set)
+	overridesHandler := handler.NewOverrides(overrides)
+	mux.HandleFunc("GET /admin/overrides", overridesHandler.List)
+	mux.HandleFunc("POST /admin/overrides", overridesHandler.Create)
+	mux.HandleFunc("DELETE /admin/overrides", overridesHandler.Clear)
+	mux.HandleFunc("DELETE /admin/overrides/{id}", overridesHandler.Delete)
+	sloHandler := handler.NewSLO(env.slo)
+	mux.HandleFunc("GET /admin/slo", sloHandler.Get)
+	mux.HandleFunc("PUT /admin/slo", sloHandler.Set)
+	mux.HandleFunc("DELETE /admin/slo", sloHandler.Delete)
+	templatesHandler := handler.NewTemplates(env.templates)
+	mux.HandleFunc("GET /admin/templates", templatesHandler.List)
+	mux.HandleFunc("POST /admin/templates", templatesHandler.Create)
+	mux.HandleFunc("DELETE /admin/templates", templatesHandler.Clear)
+	mux.HandleFunc("DELETE /admin/templates/{id}", templatesHandler.Delete)
+	if namespaces != nil {
+		namespacesHandler := handler.NewNamespaces(namespaces)
+		mux.HandleFunc("GET /admin/namespaces", namespacesHandler.List)
+		mux.HandleFunc("DELETE /admin/namespaces/{namespace}", namespacesHandler.Delete)
+	}
+	if namespaces != nil {
+		jobsHandler := handler.NewJobs(env.jobs)
+		mux.HandleFunc("GET /admin/jobs", jobsHandler.List)
+		mux.HandleFunc("POST /admin/jobs/{name}/run", jobsHandler.Run)
+	}
+	if namespaces != nil {
+		metricsHandler := handler.NewMetrics(env.metrics, dataStore)
+		mux.HandleFunc("GET /metrics", metricsHandler.Serve)
+		mux.HandleFunc("GET /admin/storage/usage", metricsHandler.TopBuckets)
+	}
+	if namespaces != nil && env.sqlProxy != nil {
+		sqlProxyHandler := handler.NewSQLProxy(env.sqlProxy)
+		mux.HandleFunc("GET /admin/sql/proxy", sqlProxyHandler.List)
+		mux.HandleFunc("PUT /admin/sql/proxy/{connectionName}", sqlProxyHandler.SetTarget)
+		mux.HandleFunc("DELETE /admin/sql/proxy/{connectionName}", sqlProxyHandler.DeleteTarget)
+	}
+	if namespaces != nil && env.sqlData != nil {
+		sqlDataHandler := handler.NewSQLData(env.sqlData)
+		mux.HandleFunc("GET /admin/sql/databases", sqlDataHandler.List)
+		mux.HandleFunc("GET /admin/sql/instances/{instance}/databases/{database}/dsn", sqlDataHandler.Get)
+	}
+	if env.caCert != nil {
+		mux.HandleFunc("GET /admin/tls/ca.pem", serveCACertificate(env.caCert))
+	}
 
 	// Static files
-	mux.Handle("GET /static/", http.StripPrefix("/static/", http.FileServer(http.Dir("web/static"))))
+	static, _ := fs.Sub(web.Static, "static")
+	mux.Handle("GET /static/", http.StripPrefix("/static/", http.FileServerFS(static)))
 
 	// UI routes (HTMX templates)
 	// Note: Using {$} to match ONLY the exact root path, not as a catch-all.
 	// This allows GET /{bucket}/{object...} to work for path-style storage requests.
-	uiHandler := handler.NewUI(cfg, dataStore, requestLogger)
+	uiHandler := handler.NewUI(env.cfg, dataStore, env.requestLogger, tracker)
 	mux.HandleFunc("GET /{$}", uiHandler.Index)
 
 	// UI API routes for HTMX partials
@@ -66,12 +734,17 @@ func newRouter(cfg *config.Config, dataStore *store.Store) (*http.ServeMux, *han
 	mux.HandleFunc("POST /ui/buckets", uiHandler.CreateBucketUI)
 	mux.HandleFunc("DELETE /ui/buckets/{bucket}", uiHandler.DeleteBucketUI)
 	mux.HandleFunc("GET /ui/buckets/{bucket}/objects", uiHandler.ListObjectsUI)
+	mux.HandleFunc("GET /ui/buckets/{bucket}/objects/{object...}", uiHandler.ObjectDetailsUI)
+	mux.HandleFunc("PUT /ui/buckets/{bucket}/objects/{object...}", uiHandler.UpdateObjectUI)
 	mux.HandleFunc("DELETE /ui/buckets/{bucket}/objects/{object...}", uiHandler.DeleteObjectUI)
+	mux.HandleFunc("GET /ui/storage/search", uiHandler.SearchObjectsUI)
 	mux.HandleFunc("GET /ui/sql/instances", uiHandler.ListSQLInstancesUI)
 	mux.HandleFunc("POST /ui/sql/instances", uiHandler.CreateSQLInstanceUI)
 	mux.HandleFunc("DELETE /ui/sql/instances/{instance}", uiHandler.DeleteSQLInstanceUI)
 	mux.HandleFunc("GET /ui/logs", uiHandler.GetLogsUI)
 	mux.HandleFunc("DELETE /ui/logs", uiHandler.ClearLogsUI)
+	mux.HandleFunc("GET /ui/logging/entries", uiHandler.ListLogEntriesUI)
+	mux.HandleFunc("GET /ui/usage", uiHandler.UsageUI)
 
 	// Cloud Storage API routes
 	// Bucket operations
@@ -79,26 +752,60 @@ func newRouter(cfg *config.Config, dataStore *store.Store) (*http.ServeMux, *han
 	mux.HandleFunc("POST /storage/v1/b", storageHandler.CreateBucket)
 	mux.HandleFunc("GET /storage/v1/b/{bucket}", storageHandler.GetBucket)
 	mux.HandleFunc("PUT /storage/v1/b/{bucket}", storageHandler.UpdateBucket)
-	mux.HandleFunc("PATCH /storage/v1/b/{bucket}", storageHandler.UpdateBucket)
+	mux.HandleFunc("PATCH /storage/v1/b/{bucket}", storageHandler.PatchBucket)
 	mux.HandleFunc("DELETE /storage/v1/b/{bucket}", storageHandler.DeleteBucket)
+	mux.HandleFunc("POST /storage/v1/b/{bucket}/lockRetentionPolicy", storageHandler.LockRetentionPolicy)
+
+	// Bucket IAM operations
+	mux.HandleFunc("GET /storage/v1/b/{bucket}/iam", storageHandler.GetBucketIamPolicy)
+	mux.HandleFunc("PUT /storage/v1/b/{bucket}/iam", storageHandler.SetBucketIamPolicy)
+
+	// Notification operations
+	mux.HandleFunc("GET /storage/v1/b/{bucket}/notificationConfigs", storageHandler.ListNotifications)
+	mux.HandleFunc("POST /storage/v1/b/{bucket}/notificationConfigs", storageHandler.CreateNotification)
+	mux.HandleFunc("GET /storage/v1/b/{bucket}/notificationConfigs/{notification}", storageHandler.GetNotification)
+	mux.HandleFunc("DELETE /storage/v1/b/{bucket}/notificationConfigs/{notification}", storageHandler.DeleteNotification)
+
+	// HMAC key operations
+	mux.HandleFunc("GET /storage/v1/projects/{project}/hmacKeys", storageHandler.ListHmacKeys)
+	mux.HandleFunc("POST /storage/v1/projects/{project}/hmacKeys", storageHandler.CreateHmacKey)
+	mux.HandleFunc("GET /storage/v1/projects/{project}/hmacKeys/{accessId}", storageHandler.GetHmacKey)
+	mux.HandleFunc("PUT /storage/v1/projects/{project}/hmacKeys/{accessId}", storageHandler.UpdateHmacKey)
+	mux.HandleFunc("DELETE /storage/v1/projects/{project}/hmacKeys/{accessId}", storageHandler.DeleteHmacKey)
 
 	// Object operations
 	mux.HandleFunc("GET /storage/v1/b/{bucket}/o", storageHandler.ListObjects)
 	mux.HandleFunc("GET /storage/v1/b/{bucket}/o/{object...}", storageHandler.GetObject)
 	mux.HandleFunc("PUT /storage/v1/b/{bucket}/o/{object...}", storageHandler.UpdateObject)
+	mux.HandleFunc("PATCH /storage/v1/b/{bucket}/o/{object...}", storageHandler.PatchObject)
 	mux.HandleFunc("DELETE /storage/v1/b/{bucket}/o/{object...}", storageHandler.DeleteObject)
+	mux.HandleFunc("POST /storage/v1/b/{bucket}/o/{object...}", storageHandler.ObjectAction)
 
 	// Object upload (uses different path prefix)
 	mux.HandleFunc("POST /upload/storage/v1/b/{bucket}/o", storageHandler.InsertObject)
+	mux.HandleFunc("PUT /upload/storage/v1/b/{bucket}/o", storageHandler.ResumeUpload)
+	mux.HandleFunc("DELETE /upload/storage/v1/b/{bucket}/o", storageHandler.CancelUpload)
 
 	// Object download (alternative download endpoint)
 	mux.HandleFunc("GET /download/storage/v1/b/{bucket}/o/{object...}", storageHandler.DownloadObject)
 
-	// Path-style object access (used by GCS client library for downloads)
-	// Format: GET /{bucket}/{object}
-	// This must be registered to handle requests like GET /mybucket/myobject
-	// The GCS Go client library uses this format for NewReader() calls
-	mux.HandleFunc("GET /{bucket}/{object...}", storageHandler.PathStyleGetObject)
+	// JSON API shortcuts without the /storage/v1 prefix, used by some clients when STORAGE_EMULATOR_HOST is set.
+	// Bucket names are at least 3 characters long, so /b/... never clashes with XML API paths.
+	jsonAPIAlias := storageJSONAPIAlias(mux)
+	for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+		mux.Handle(method+" /b", jsonAPIAlias)
+		mux.Handle(method+" /b/{path...}", jsonAPIAlias)
+	}
+
+	// Cloud Storage XML API routes (used by boto-based tools and older SDKs)
+	// GET /{bucket}/{object} is also the path-style access the GCS client libraries download objects with,
+	// e.g. the Go client's NewReader.
+	mux.HandleFunc("GET /{bucket}/{object...}", storageHandler.XMLGetObject)
+	mux.HandleFunc("GET /{bucket}", storageHandler.XMLListObjects)
+	mux.HandleFunc("PUT /{bucket}", storageHandler.XMLCreateBucket)
+	mux.HandleFunc("DELETE /{bucket}", storageHandler.XMLDeleteBucket)
+	mux.HandleFunc("PUT /{bucket}/{object...}", storageHandler.XMLPutObject)
+	mux.HandleFunc("DELETE /{bucket}/{object...}", storageHandler.XMLDeleteObject)
 
 	// Cloud SQL Admin API routes
 	// Note: Cloud SQL uses v1beta4 API (unlike Storage which uses v1)
@@ -108,6 +815,8 @@ func newRouter(cfg *config.Config, dataStore *store.Store) (*http.ServeMux, *han
 	mux.HandleFunc("GET /sql/v1beta4/projects/{project}/instances/{instance}", sqlAdminHandler.GetInstance)
 	mux.HandleFunc("PATCH /sql/v1beta4/projects/{project}/instances/{instance}", sqlAdminHandler.UpdateInstance)
 	mux.HandleFunc("DELETE /sql/v1beta4/projects/{project}/instances/{instance}", sqlAdminHandler.DeleteInstance)
+	mux.HandleFunc("POST /sql/v1beta4/projects/{project}/instances/{instance}/restart", sqlAdminHandler.RestartInstance)
+	mux.HandleFunc("POST /sql/v1beta4/projects/{project}/instances/{instance}/promoteReplica", sqlAdminHandler.PromoteReplica)
 
 	// Database operations
 	mux.HandleFunc("GET /sql/v1beta4/projects/{project}/instances/{instance}/databases", sqlAdminHandler.ListDatabases)
@@ -126,5 +835,331 @@ func newRouter(cfg *config.Config, dataStore *store.Store) (*http.ServeMux, *han
 	mux.HandleFunc("GET /sql/v1beta4/projects/{project}/operations", sqlAdminHandler.ListOperations)
 	mux.HandleFunc("GET /sql/v1beta4/projects/{project}/operations/{operation}", sqlAdminHandler.GetOperation)
 
-	return mux, requestLogger
+	// Flags
+	mux.HandleFunc("GET /sql/v1beta4/flags", sqlAdminHandler.ListFlags)
+
+	// Firestore API routes
+	// Document paths alternate between collection and document IDs, so a single wildcard covers both.
+	mux.HandleFunc("GET /v1/projects/{project}/databases/{database}/documents/{path...}", firestoreHandler.GetDocument)
+	mux.HandleFunc("POST /v1/projects/{project}/databases/{database}/documents/{path...}", firestoreHandler.DocumentAction)
+	mux.HandleFunc("PATCH /v1/projects/{project}/databases/{database}/documents/{path...}", firestoreHandler.UpdateDocument)
+	mux.HandleFunc("DELETE /v1/projects/{project}/databases/{database}/documents/{path...}", firestoreHandler.DeleteDocument)
+	mux.HandleFunc("POST /v1/projects/{project}/databases/{database}/documents:runQuery", firestoreHandler.RunQuery)
+
+	// Cloud Run Admin API v2 routes
+	// These are more specific than the registry's /v2/{path...} patterns, so they take precedence.
+	mux.HandleFunc("GET /v2/projects/{project}/locations/{location}/services", cloudRunHandler.ListServices)
+	mux.HandleFunc("POST /v2/projects/{project}/locations/{location}/services", cloudRunHandler.CreateService)
+	mux.HandleFunc("GET /v2/projects/{project}/locations/{location}/services/{service}", cloudRunHandler.GetService)
+	mux.HandleFunc("PATCH /v2/projects/{project}/locations/{location}/services/{service}", cloudRunHandler.UpdateService)
+	mux.HandleFunc("DELETE /v2/projects/{project}/locations/{location}/services/{service}", cloudRunHandler.DeleteService)
+	mux.HandleFunc("GET /v2/projects/{project}/locations/{location}/services/{service}/revisions", cloudRunHandler.ListRevisions)
+	mux.HandleFunc("GET /v2/projects/{project}/locations/{location}/services/{service}/revisions/{revision}", cloudRunHandler.GetRevision)
+	mux.HandleFunc("GET /v2/projects/{project}/locations/{location}/operations", cloudRunHandler.ListOperations)
+	mux.HandleFunc("GET /v2/projects/{project}/locations/{location}/operations/{operation}", cloudRunHandler.GetOperation)
+
+	// Cloud Monitoring API v3 routes
+	// Metric types contain slashes, e.g. custom.googleapis.com/requests, so descriptor names end in a wildcard.
+	mux.HandleFunc("POST /v3/projects/{project}/timeSeries", monitoringHandler.CreateTimeSeries)
+	mux.HandleFunc("POST /v3/projects/{project}/timeSeries:createService", monitoringHandler.CreateTimeSeries)
+	mux.HandleFunc("GET /v3/projects/{project}/timeSeries", monitoringHandler.ListTimeSeries)
+	mux.HandleFunc("GET /v3/projects/{project}/metricDescriptors", monitoringHandler.ListMetricDescriptors)
+	mux.HandleFunc("POST /v3/projects/{project}/metricDescriptors", monitoringHandler.CreateMetricDescriptor)
+	mux.HandleFunc("GET /v3/projects/{project}/metricDescriptors/{type...}", monitoringHandler.GetMetricDescriptor)
+	mux.HandleFunc("DELETE /v3/projects/{project}/metricDescriptors/{type...}", monitoringHandler.DeleteMetricDescriptor)
+
+	// Cloud Logging API v2 routes
+	// These are more specific than the registry's /v2/{path...} patterns, so they take precedence.
+	mux.HandleFunc("POST /v2/entries:write", loggingHandler.WriteEntries)
+	mux.HandleFunc("POST /v2/entries:list", loggingHandler.ListEntries)
+	mux.HandleFunc("GET /v2/projects/{project}/logs", loggingHandler.ListLogs)
+
+	// Organization Policy API v2 routes (project policies)
+	// Like the Cloud Logging routes, these take precedence over the registry's /v2/{path...} patterns.
+	mux.HandleFunc("GET /v2/projects/{project}/policies", orgPolicyHandler.ListPolicies)
+	mux.HandleFunc("POST /v2/projects/{project}/policies", orgPolicyHandler.CreatePolicy)
+	mux.HandleFunc("GET /v2/projects/{project}/policies/{policy}", orgPolicyHandler.GetPolicy)
+	mux.HandleFunc("PATCH /v2/projects/{project}/policies/{policy}", orgPolicyHandler.UpdatePolicy)
+	mux.HandleFunc("DELETE /v2/projects/{project}/policies/{policy}", orgPolicyHandler.DeletePolicy)
+
+	// Cloud Scheduler API v1 routes
+	// Custom methods like jobs/{job}:run are POSTs to the job, so JobAction cuts the verb off the job ID.
+	mux.HandleFunc("GET /v1/projects/{project}/locations/{location}/jobs", schedulerHandler.ListJobs)
+	mux.HandleFunc("POST /v1/projects/{project}/locations/{location}/jobs", schedulerHandler.CreateJob)
+	mux.HandleFunc("GET /v1/projects/{project}/locations/{location}/jobs/{job}", schedulerHandler.GetJob)
+	mux.HandleFunc("PATCH /v1/projects/{project}/locations/{location}/jobs/{job}", schedulerHandler.UpdateJob)
+	mux.HandleFunc("DELETE /v1/projects/{project}/locations/{location}/jobs/{job}", schedulerHandler.DeleteJob)
+	mux.HandleFunc("POST /v1/projects/{project}/locations/{location}/jobs/{job}", schedulerHandler.JobAction)
+
+	// Eventarc API v1 routes
+	mux.HandleFunc("GET /v1/projects/{project}/locations/{location}/triggers", eventarcHandler.ListTriggers)
+	mux.HandleFunc("POST /v1/projects/{project}/locations/{location}/triggers", eventarcHandler.CreateTrigger)
+	mux.HandleFunc("GET /v1/projects/{project}/locations/{location}/triggers/{trigger}", eventarcHandler.GetTrigger)
+	mux.HandleFunc("PATCH /v1/projects/{project}/locations/{location}/triggers/{trigger}", eventarcHandler.UpdateTrigger)
+	mux.HandleFunc("DELETE /v1/projects/{project}/locations/{location}/triggers/{trigger}", eventarcHandler.DeleteTrigger)
+
+	// Memorystore for Redis API v1 routes
+	mux.HandleFunc("GET /v1/projects/{project}/locations/{location}/instances", memorystoreHandler.ListInstances)
+	mux.HandleFunc("POST /v1/projects/{project}/locations/{location}/instances", memorystoreHandler.CreateInstance)
+	mux.HandleFunc("GET /v1/projects/{project}/locations/{location}/instances/{instance}", memorystoreHandler.GetInstance)
+	mux.HandleFunc("PATCH /v1/projects/{project}/locations/{location}/instances/{instance}", memorystoreHandler.UpdateInstance)
+	mux.HandleFunc("DELETE /v1/projects/{project}/locations/{location}/instances/{instance}", memorystoreHandler.DeleteInstance)
+
+	// Compute Engine API v1 routes (VPC networks)
+	mux.HandleFunc("GET /compute/v1/projects/{project}/global/networks", computeHandler.ListNetworks)
+	mux.HandleFunc("POST /compute/v1/projects/{project}/global/networks", computeHandler.InsertNetwork)
+	mux.HandleFunc("GET /compute/v1/projects/{project}/global/networks/{network}", computeHandler.GetNetwork)
+	mux.HandleFunc("PATCH /compute/v1/projects/{project}/global/networks/{network}", computeHandler.PatchNetwork)
+	mux.HandleFunc("DELETE /compute/v1/projects/{project}/global/networks/{network}", computeHandler.DeleteNetwork)
+	mux.HandleFunc("GET /compute/v1/projects/{project}/regions/{region}/subnetworks", computeHandler.ListSubnetworks)
+	mux.HandleFunc("POST /compute/v1/projects/{project}/regions/{region}/subnetworks", computeHandler.InsertSubnetwork)
+	mux.HandleFunc("GET /compute/v1/projects/{project}/regions/{region}/subnetworks/{subnetwork}", computeHandler.GetSubnetwork)
+	mux.HandleFunc("PATCH /compute/v1/projects/{project}/regions/{region}/subnetworks/{subnetwork}", computeHandler.PatchSubnetwork)
+	mux.HandleFunc("DELETE /compute/v1/projects/{project}/regions/{region}/subnetworks/{subnetwork}", computeHandler.DeleteSubnetwork)
+	mux.HandleFunc("GET /compute/v1/projects/{project}/global/operations/{operation}", computeHandler.GetGlobalOperation)
+	mux.HandleFunc("POST /compute/v1/projects/{project}/global/operations/{operation}/wait", computeHandler.GetGlobalOperation)
+	mux.HandleFunc("GET /compute/v1/projects/{project}/regions/{region}/operations/{operation}", computeHandler.GetRegionOperation)
+	mux.HandleFunc("POST /compute/v1/projects/{project}/regions/{region}/operations/{operation}/wait", computeHandler.GetRegionOperation)
+
+	// Cloud Billing Budget API v1 routes
+	mux.HandleFunc("GET /v1/billingAccounts/{billingAccount}/budgets", billingHandler.ListBudgets)
+	mux.HandleFunc("POST /v1/billingAccounts/{billingAccount}/budgets", billingHandler.CreateBudget)
+	mux.HandleFunc("GET /v1/billingAccounts/{billingAccount}/budgets/{budget}", billingHandler.GetBudget)
+
+	// GKE API v1 routes
+	mux.HandleFunc("GET /v1/projects/{project}/locations/{location}/clusters", gkeHandler.ListClusters)
+	mux.HandleFunc("POST /v1/projects/{project}/locations/{location}/clusters", gkeHandler.CreateCluster)
+	mux.HandleFunc("GET /v1/projects/{project}/locations/{location}/clusters/{cluster}", gkeHandler.GetCluster)
+	mux.HandleFunc("DELETE /v1/projects/{project}/locations/{location}/clusters/{cluster}", gkeHandler.DeleteCluster)
+
+	// Operations of the Eventarc, Memorystore and GKE APIs, which share their path
+	mux.HandleFunc("GET /v1/projects/{project}/locations/{location}/operations/{operation}", locationOperationsHandler.GetOperation)
+
+	// Docker Registry v2 API routes (Artifact Registry / Container Registry)
+	// Repository names contain slashes, so the registry handler parses the rest of the path itself.
+	mux.HandleFunc("GET /v2/{$}", registryHandler.Base)
+	mux.HandleFunc("GET /v2/_catalog", registryHandler.Catalog)
+	mux.HandleFunc("GET /v2/{path...}", registryHandler.Dispatch)
+	mux.HandleFunc("POST /v2/{path...}", registryHandler.Dispatch)
+	mux.HandleFunc("PUT /v2/{path...}", registryHandler.Dispatch)
+	mux.HandleFunc("PATCH /v2/{path...}", registryHandler.Dispatch)
+	mux.HandleFunc("DELETE /v2/{path...}", registryHandler.Dispatch)
+
+	return mux
+}
+
+// storageJSONAPIAlias serves a JSON API shortcut like /b/{bucket}/o by sending it to the /storage/v1 route.
+func storageJSONAPIAlias(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r2 := r.Clone(r.Context())
+		r2.URL.Path = "/storage/v1" + r.URL.Path
+		if r.URL.RawPath != "" {
+			r2.URL.RawPath = "/storage/v1" + r.URL.RawPath
+		}
+		mux.ServeHTTP(w, r2)
+	})
+}
+
+// restoreSnapshot restores the store from a snapshot file downloaded from /admin/snapshot.
+func restoreSnapshot(dataStore *store.Store, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	summary, err := dataStore.Restore(file)
+	if err != nil {
+		return err
+	}
+	log.Printf("Restored snapshot %s: %d buckets, %d objects, %d SQL instances", path, summary.Buckets, summary.Objects, summary.SQLInstances)
+	return nil
+}
+
+// newResponseTemplates creates the response templates, adding the ones of the configured file.
+// Invalid templates are logged and left out, so the mock still starts.
+func newResponseTemplates(cfg *config.Config) *templating.Templates {
+	templates := templating.New()
+	if cfg.TemplatesFile == "" {
+		return templates
+	}
+
+	rules, err := templating.LoadRules(cfg.TemplatesFile)
+	if err != nil {
+		log.Printf("Failed to load response templates file: %v", err)
+	}
+	for _, rule := range rules {
+		if _, err := templates.Add(rule); err != nil {
+			log.Printf("Skipping response template for %s: %v", rule.Path, err)
+		}
+	}
+	return templates
+}
+
+// newRedaction creates the rules redacting secrets from logged requests, or returns nil if none are configured.
+// Invalid JSON paths are logged and left out.
+func newRedaction(cfg *config.Config) *redact.Rules {
+	var paths []string
+	for _, path := range cfg.RedactJSONPaths {
+		if _, err := redact.ParsePath(path); err != nil {
+			log.Printf("Skipping redaction: %v", err)
+			continue
+		}
+		paths = append(paths, path)
+	}
+
+	rules, err := redact.New(cfg.RedactHeaders, paths)
+	if err != nil || rules.Empty() {
+		return nil
+	}
+	return rules
+}
+
+// newSchemaValidator creates the validator of the responses if schema validation is configured.
+// Documents that can't be loaded are logged and left out, so their APIs' responses aren't checked.
+func newSchemaValidator(cfg *config.Config) *schema.Validator {
+	switch cfg.SchemaValidation {
+	case "":
+		return nil
+	case "log", "fail":
+	default:
+		log.Printf("Invalid schema validation mode %q, not checking responses: must be log or fail", cfg.SchemaValidation)
+		return nil
+	}
+
+	var docs []*discovery.RestDescription
+	for _, api := range schema.APIs {
+		path := schema.DocumentURL(api[0], api[1])
+		if cfg.SchemaDir != "" {
+			path = filepath.Join(cfg.SchemaDir, api[0]+"."+api[1]+".json")
+		}
+		doc, err := schema.LoadDocument(path)
+		if err != nil {
+			log.Printf("Not checking %s %s responses: %v", api[0], api[1], err)
+			continue
+		}
+		docs = append(docs, doc)
+	}
+	return schema.New(docs...)
+}
+
+// newSLOSimulator creates the simulator of the configured objective.
+// An invalid objective is logged and ignored, so the mock still starts without simulating one.
+func newSLOSimulator(cfg *config.Config) *slo.Simulator {
+	simulator := slo.New()
+	if cfg.SLO == "" {
+		return simulator
+	}
+
+	objective, err := slo.ParseObjective(cfg.SLO)
+	if err != nil {
+		log.Printf("Failed to parse service level objective: %v", err)
+		return simulator
+	}
+	_ = simulator.SetObjective(&objective)
+	return simulator
+}
+
+// newLatencyInjector creates the latency injector from the configured profile file and profile.
+// Invalid profiles are logged and ignored, so the mock still starts without injected latency.
+func newLatencyInjector(cfg *config.Config) *latency.Injector {
+	injector := latency.New()
+	profile := make(latency.Profile)
+
+	if cfg.LatencyFile != "" {
+		fileProfile, err := latency.LoadProfile(cfg.LatencyFile)
+		if err != nil {
+			log.Printf("Failed to load latency profile file: %v", err)
+		}
+		for op, r := range fileProfile {
+			profile[op] = r
+		}
+	}
+
+	if cfg.Latency != "" {
+		envProfile, err := latency.ParseProfile(cfg.Latency)
+		if err != nil {
+			log.Printf("Failed to parse latency profile: %v", err)
+		}
+		for op, r := range envProfile {
+			profile[op] = r
+		}
+	}
+
+	injector.SetProfile(profile)
+	return injector
+}
+
+// newTLSConfig creates the TLS configuration from the configured certificate files,
+// or from a generated self-signed certificate valid for localhost, the configured host and base URL
+// and the virtual host domains. For a generated certificate, the PEM-encoded CA certificate is returned too.
+func newTLSConfig(cfg *config.Config) (*tls.Config, []byte, error) {
+	if cfg.TLSCertFile != "" && cfg.TLSKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &tls.Config{Certificates: []tls.Certificate{cert}}, nil, nil
+	}
+
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+	if cfg.Host != "" && cfg.Host != "0.0.0.0" {
+		hosts = append(hosts, cfg.Host)
+	}
+	if u, err := url.Parse(cfg.ExternalURL()); err == nil && u.Hostname() != "" {
+		hosts = append(hosts, u.Hostname())
+	}
+	for _, domain := range cfg.VirtualHostDomains {
+		hosts = append(hosts, domain, "*."+domain)
+	}
+
+	bundle, err := certs.GenerateSelfSigned(hosts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &tls.Config{Certificates: []tls.Certificate{bundle.Certificate}}, bundle.CACertPEM, nil
+}
+
+// serveCACertificate serves the CA certificate of the generated TLS certificate,
+// so clients can add it to their trust store.
+func serveCACertificate(caCert []byte) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-pem-file")
+		w.Header().Set("Content-Disposition", `attachment; filename="gcp-api-mock-ca.pem"`)
+		w.Write(caCert)
+	}
+}
+
+// newS3Router creates the router for the S3 compatibility layer.
+// S3 uses path-style requests like the Cloud Storage XML API, so it needs its own router.
+func newS3Router(cfg *config.Config, dataStore *store.Store) http.Handler {
+	mux := http.NewServeMux()
+
+	s3Handler := handler.NewS3(dataStore, cfg.S3AccessKey, cfg.S3SecretKey, cfg.IsStrictAuth())
+
+	// Bucket operations
+	mux.HandleFunc("GET /{$}", s3Handler.ListBuckets)
+	mux.HandleFunc("GET /{bucket}", s3Handler.GetBucket)
+	mux.HandleFunc("PUT /{bucket}", s3Handler.CreateBucket)
+	mux.HandleFunc("DELETE /{bucket}", s3Handler.DeleteBucket)
+	mux.HandleFunc("POST /{bucket}", s3Handler.PostBucket)
+
+	// Object operations
+	mux.HandleFunc("GET /{bucket}/{key...}", s3Handler.GetObject)
+	mux.HandleFunc("PUT /{bucket}/{key...}", s3Handler.PutObject)
+	mux.HandleFunc("DELETE /{bucket}/{key...}", s3Handler.DeleteObject)
+	mux.HandleFunc("POST /{bucket}/{key...}", s3Handler.PostObject)
+
+	return s3Handler.Authenticate(mux)
+}
+
+// routeS3Requests sends AWS-signed requests to the S3 router and all other requests to next.
+func routeS3Requests(s3Router, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s3.IsSigned(r) {
+			s3Router.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
 }
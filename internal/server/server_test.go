@@ -1,17 +1,31 @@
 package server
 
 import (
+	"archive/tar"
+	"context"
+	"encoding/base64"
 	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/katharinasick/gcp-api-mock/internal/blob"
 	"github.com/katharinasick/gcp-api-mock/internal/config"
+	"github.com/katharinasick/gcp-api-mock/internal/discovery"
+	"github.com/katharinasick/gcp-api-mock/internal/handler"
+	"github.com/katharinasick/gcp-api-mock/internal/registry"
+	"github.com/katharinasick/gcp-api-mock/internal/schema"
 	"github.com/katharinasick/gcp-api-mock/internal/sqladmin"
+	"github.com/katharinasick/gcp-api-mock/internal/sqldata"
 	"github.com/katharinasick/gcp-api-mock/internal/storage"
+	"github.com/katharinasick/gcp-api-mock/internal/usage"
 )
 
 // changeToProjectRoot changes to the project root directory for tests.
@@ -234,6 +248,16 @@ func TestServer_ObjectCRUD(t *testing.T) {
 		t.Errorf("object content = %s, want %s", rr.Body.String(), content)
 	}
 
+	// Patch object metadata
+	patchReq := httptest.NewRequest(http.MethodPatch, "/storage/v1/b/object-test-bucket/o/test.txt",
+		strings.NewReader(`{"metadata": {"owner": "team-a"}}`))
+	rr = httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rr, patchReq)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("patch object failed: %d - %s", rr.Code, rr.Body.String())
+	}
+
 	// List objects
 	listReq := httptest.NewRequest(http.MethodGet, "/storage/v1/b/object-test-bucket/o", nil)
 	rr = httptest.NewRecorder()
@@ -262,6 +286,199 @@ func TestServer_ObjectCRUD(t *testing.T) {
 	}
 }
 
+func TestServer_EncodedObjectNames(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	srv := New(&config.Config{})
+
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return rr
+	}
+
+	if rr := serve(http.MethodPost, "/storage/v1/b", `{"name": "encoded-bucket"}`); rr.Code != http.StatusOK {
+		t.Fatalf("create bucket failed: %d - %s", rr.Code, rr.Body.String())
+	}
+	if rr := serve(http.MethodPost, "/upload/storage/v1/b/encoded-bucket/o?name=dir%2Fa%2Bb.txt", "content"); rr.Code != http.StatusOK {
+		t.Fatalf("upload object failed: %d - %s", rr.Code, rr.Body.String())
+	}
+
+	// Encoded slashes and plus signs in the path are part of the object name
+	rr := serve(http.MethodGet, "/storage/v1/b/encoded-bucket/o/dir%2Fa%2Bb.txt", "")
+	var obj storage.Object
+	if err := json.NewDecoder(rr.Body).Decode(&obj); err != nil {
+		t.Fatalf("failed to decode object: %v", err)
+	}
+	if rr.Code != http.StatusOK || obj.Name != "dir/a+b.txt" {
+		t.Errorf("expected object dir/a+b.txt, got %d %q", rr.Code, obj.Name)
+	}
+
+	if rr := serve(http.MethodPost, "/storage/v1/b/encoded-bucket/o/dir%2Fa%2Bb.txt/rewriteTo/b/encoded-bucket/o/copy%2Bb.txt", ""); rr.Code != http.StatusOK {
+		t.Errorf("rewrite object failed: %d - %s", rr.Code, rr.Body.String())
+	}
+	if rr := serve(http.MethodGet, "/storage/v1/b/encoded-bucket/o/copy%2Bb.txt?alt=media", ""); rr.Body.String() != "content" {
+		t.Errorf("expected rewritten content, got %d %q", rr.Code, rr.Body.String())
+	}
+
+	// An encoded slash doesn't split a Cloud SQL instance name
+	if rr := serve(http.MethodGet, "/sql/v1beta4/projects/test-project/instances/foo%2Fbar", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for an unknown instance, got %d", rr.Code)
+	}
+}
+
+func TestServer_Metrics(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	srv := New(&config.Config{})
+
+	requests := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{http.MethodPost, "/storage/v1/b?project=test-project", `{"name": "suite-a"}`},
+		{http.MethodPost, "/storage/v1/b?project=test-project", `{"name": "suite-b"}`},
+		{http.MethodPost, "/upload/storage/v1/b/suite-a/o?uploadType=media&name=big.bin", strings.Repeat("x", 100)},
+		{http.MethodPost, "/upload/storage/v1/b/suite-b/o?uploadType=media&name=small.txt", "hi"},
+		{http.MethodGet, "/storage/v1/b/suite-b", ""},
+		{http.MethodGet, "/suite-b/small.txt", ""},
+		{http.MethodGet, "/storage/v1/b/missing/o", ""},
+		{http.MethodGet, "/no-such-path", ""},
+	}
+	for _, r := range requests {
+		srv.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(r.method, r.path, strings.NewReader(r.body)))
+	}
+
+	rr := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rr.Code != http.StatusOK || !strings.HasPrefix(rr.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Fatalf("expected Prometheus metrics, got %d %s", rr.Code, rr.Header().Get("Content-Type"))
+	}
+	body := rr.Body.String()
+	for _, want := range []string{
+		`gcp_mock_requests_total{service="storage.googleapis.com",method="POST",code="200"} 4`,
+		`gcp_mock_requests_total{service="storage.googleapis.com",method="GET",code="404"} 1`,
+		`gcp_mock_storage_bucket_bytes{bucket="suite-a"} 100`,
+		`gcp_mock_storage_bucket_objects{bucket="suite-b"} 1`,
+		`gcp_mock_storage_bucket_requests_total{bucket="suite-b",method="GET"} 2`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected metrics to contain %q, got:\n%s", want, body)
+		}
+	}
+	// Requests for buckets that don't exist don't add series
+	if strings.Contains(body, `bucket="missing"`) || strings.Contains(body, `bucket="no-such-path"`) {
+		t.Errorf("expected no series for unknown buckets, got:\n%s", body)
+	}
+
+	rr = httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/storage/usage?top=1", nil))
+	var usage handler.BucketStatsList
+	if err := json.NewDecoder(rr.Body).Decode(&usage); err != nil {
+		t.Fatalf("failed to decode usage: %v", err)
+	}
+	if len(usage.Items) != 1 || usage.Items[0].Bucket != "suite-a" || usage.Items[0].UsedBytes != 100 {
+		t.Errorf("expected suite-a as the largest bucket, got %+v", usage.Items)
+	}
+
+	rr = httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/storage/usage?orderBy=age", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for an invalid order, got %d", rr.Code)
+	}
+}
+
+func TestServer_APIUsage(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	srv := New(&config.Config{})
+
+	requests := []struct {
+		method    string
+		path      string
+		body      string
+		client    string
+		namespace string
+	}{
+		{http.MethodPost, "/storage/v1/b?project=test-project", `{"name": "suite-a"}`, "gl-go/1.23.0 gccl/1.43.0", ""},
+		{http.MethodPost, "/upload/storage/v1/b/suite-a/o?uploadType=media&name=a.txt", "a", "gl-go/1.23.0 gccl/1.43.0", ""},
+		{http.MethodGet, "/storage/v1/b/suite-a/o/a.txt", "", "gl-go/1.23.0 gccl/1.43.0", ""},
+		{http.MethodGet, "/storage/v1/b/suite-a/o/missing.txt", "", "gl-python/3.12 gccl/2.18.0", ""},
+		{http.MethodGet, "/suite-a/a.txt", "", "", ""},
+		{http.MethodGet, "/storage/v1/b", "", "gl-python/3.12 gccl/2.18.0", "ci-1"},
+		// Calls to the mock itself aren't API calls
+		{http.MethodGet, "/ui/buckets", "", "", ""},
+		{http.MethodGet, "/health", "", "", ""},
+	}
+	for _, r := range requests {
+		req := httptest.NewRequest(r.method, r.path, strings.NewReader(r.body))
+		if r.client != "" {
+			req.Header.Set("X-Goog-Api-Client", r.client)
+		}
+		if r.namespace != "" {
+			req.Header.Set("X-Mock-Namespace", r.namespace)
+		}
+		srv.Handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	rr := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/usage", nil))
+	var report usage.Report
+	if err := json.NewDecoder(rr.Body).Decode(&report); err != nil {
+		t.Fatalf("failed to decode usage: %v", err)
+	}
+	if report.Calls != 5 || report.Errors != 1 {
+		t.Errorf("expected 5 calls and 1 error in the default namespace, got %d and %d", report.Calls, report.Errors)
+	}
+	calls := make(map[string]int64)
+	for _, m := range report.Methods {
+		calls[m.Method] = m.Calls
+	}
+	for method, want := range map[string]int64{
+		"storage.buckets.insert":    1,
+		"storage.objects.insert":    1,
+		"storage.objects.get":       2,
+		"GET /{bucket}/{object...}": 1,
+	} {
+		if calls[method] != want {
+			t.Errorf("expected %d calls of %s, got %+v", want, method, report.Methods)
+		}
+	}
+	if len(report.Clients) != 3 || report.Clients[0].Client != "gl-go/1.23.0 gccl/1.43.0" || report.Clients[0].Calls != 3 {
+		t.Errorf("expected the Go client first with 3 calls, got %+v", report.Clients)
+	}
+
+	// Each namespace counts its own calls
+	req := httptest.NewRequest(http.MethodGet, "/admin/usage", nil)
+	req.Header.Set("X-Mock-Namespace", "ci-1")
+	rr = httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rr, req)
+	if !strings.Contains(rr.Body.String(), `"method":"storage.buckets.list"`) || strings.Contains(rr.Body.String(), "storage.objects.get") {
+		t.Errorf("expected only the calls of the namespace, got %s", rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/ui/usage", nil))
+	if !strings.Contains(rr.Body.String(), "storage.objects.get") || !strings.Contains(rr.Body.String(), "gl-python/3.12 gccl/2.18.0") {
+		t.Errorf("expected the usage partial to list methods and clients, got %s", rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/admin/usage", nil))
+	if rr.Code != http.StatusNoContent {
+		t.Errorf("expected status 204 for reset, got %d", rr.Code)
+	}
+	rr = httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/usage", nil))
+	if !strings.Contains(rr.Body.String(), `"calls":0`) {
+		t.Errorf("expected no calls after reset, got %s", rr.Body.String())
+	}
+}
+
 func TestServer_HealthEndpoints(t *testing.T) {
 	cleanup := changeToProjectRoot(t)
 	defer cleanup()
@@ -284,6 +501,16 @@ func TestServer_HealthEndpoints(t *testing.T) {
 			path:       "/ready",
 			wantStatus: http.StatusOK,
 		},
+		{
+			name:       "version",
+			path:       "/version",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "capabilities",
+			path:       "/capabilities",
+			wantStatus: http.StatusOK,
+		},
 	}
 
 	for _, tt := range tests {
@@ -299,6 +526,65 @@ func TestServer_HealthEndpoints(t *testing.T) {
 	}
 }
 
+func TestServer_ReadyWithUnwritableBlobDir(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	blobDir := filepath.Join(t.TempDir(), "blobs")
+	cfg := &config.Config{BlobDir: blobDir}
+	srv := New(cfg)
+
+	// The directory disappears, e.g. because a volume was unmounted
+	if err := os.RemoveAll(blobDir); err != nil {
+		t.Fatalf("failed to remove blob directory: %v", err)
+	}
+
+	for path, expectedStatus := range map[string]int{"/ready": http.StatusServiceUnavailable, "/health": http.StatusOK} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, req)
+
+		if rr.Code != expectedStatus {
+			t.Errorf("%s: expected status %d, got %d: %s", path, expectedStatus, rr.Code, rr.Body.String())
+		}
+	}
+}
+
+func TestServer_DiscoveryRoutes(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	srv := New(&config.Config{AuthMode: "strict"})
+
+	tests := []struct {
+		name             string
+		path             string
+		wantStatus       int
+		expectedContains string
+	}{
+		{"list APIs", "/discovery/v1/apis", http.StatusOK, `"discoveryRestUrl":"http://mock.test/discovery/v1/apis/storage/v1/rest"`},
+		{"storage document", "/discovery/v1/apis/storage/v1/rest", http.StatusOK, `"rootUrl":"http://mock.test/"`},
+		{"sqladmin document", "/discovery/v1/apis/sqladmin/v1beta4/rest", http.StatusOK, `"id":"sqladmin.instances.insert"`},
+		{"unknown API", "/discovery/v1/apis/pubsub/v1/rest", http.StatusNotFound, "notFound"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Discovery documents are public, so no token is needed even in strict auth mode
+			req := httptest.NewRequest(http.MethodGet, "http://mock.test"+tt.path, nil)
+			rr := httptest.NewRecorder()
+			srv.Handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, rr.Code, rr.Body.String())
+			}
+			if !strings.Contains(rr.Body.String(), tt.expectedContains) {
+				t.Errorf("expected body to contain %q, got %s", tt.expectedContains, rr.Body.String())
+			}
+		})
+	}
+}
+
 func TestServer_SQLInstanceCRUD(t *testing.T) {
 	cleanup := changeToProjectRoot(t)
 	defer cleanup()
@@ -594,3 +880,2367 @@ func TestServer_SQLOperations(t *testing.T) {
 		t.Errorf("operation name = %s, want %s", op.Name, createOp.Name)
 	}
 }
+
+func TestServer_SQLInstanceStates(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	cfg := &config.Config{}
+	srv := New(cfg)
+
+	const instancePath = "/sql/v1beta4/projects/test-project/instances/state-instance"
+	steps := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+		expectedState  string
+	}{
+		{"create", http.MethodPost, "/sql/v1beta4/projects/test-project/instances", `{"name":"state-instance"}`, http.StatusOK, "RUNNABLE"},
+		{"restart", http.MethodPost, instancePath + "/restart", "", http.StatusOK, "RUNNABLE"},
+		{"stop", http.MethodPatch, instancePath, `{"settings":{"activationPolicy":"NEVER"}}`, http.StatusOK, "STOPPED"},
+		{"restart stopped", http.MethodPost, instancePath + "/restart", "", http.StatusConflict, "STOPPED"},
+		{"start", http.MethodPatch, instancePath, `{"settings":{"activationPolicy":"ALWAYS"}}`, http.StatusOK, "RUNNABLE"},
+		{"suspend", http.MethodPut, "/admin/sql/instances/state-instance/state", `{"state":"SUSPENDED"}`, http.StatusOK, "SUSPENDED"},
+		{"patch suspended", http.MethodPatch, instancePath, `{"settings":{"tier":"db-n1-standard-2"}}`, http.StatusConflict, "SUSPENDED"},
+		{"restart suspended", http.MethodPost, instancePath + "/restart", "", http.StatusConflict, "SUSPENDED"},
+		{"maintenance", http.MethodPut, "/admin/sql/instances/state-instance/state", `{"state":"MAINTENANCE"}`, http.StatusOK, "MAINTENANCE"},
+		{"invalid state", http.MethodPut, "/admin/sql/instances/state-instance/state", `{"state":"EXPLODED"}`, http.StatusBadRequest, "MAINTENANCE"},
+		{"unknown instance", http.MethodPut, "/admin/sql/instances/missing/state", `{"state":"RUNNABLE"}`, http.StatusNotFound, "MAINTENANCE"},
+		{"back to runnable", http.MethodPut, "/admin/sql/instances/state-instance/state", `{"state":"RUNNABLE"}`, http.StatusOK, "RUNNABLE"},
+	}
+
+	for _, step := range steps {
+		req := httptest.NewRequest(step.method, step.path, strings.NewReader(step.body))
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, req)
+
+		if rr.Code != step.expectedStatus {
+			t.Errorf("%s: expected status %d, got %d: %s", step.name, step.expectedStatus, rr.Code, rr.Body.String())
+		}
+
+		rr = httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, instancePath, nil))
+		var instance sqladmin.DatabaseInstance
+		if err := json.NewDecoder(rr.Body).Decode(&instance); err != nil {
+			t.Fatalf("%s: failed to decode instance: %v", step.name, err)
+		}
+		if instance.State != step.expectedState {
+			t.Errorf("%s: expected state %s, got %s", step.name, step.expectedState, instance.State)
+		}
+		if instance.State == "SUSPENDED" && (len(instance.SuspensionReason) != 1 || instance.SuspensionReason[0] != "BILLING_ISSUE") {
+			t.Errorf("%s: expected suspension reason BILLING_ISSUE, got %v", step.name, instance.SuspensionReason)
+		}
+	}
+}
+
+func TestServer_SQLMaintenance(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	srv := New(&config.Config{SQLMaintenanceDuration: "10m"})
+
+	const instancePath = "/sql/v1beta4/projects/test-project/instances/maintained"
+	steps := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+		expectedState  string
+	}{
+		// 2025-01-15 is a Wednesday, the window is on Wednesdays at 10:00 UTC
+		{"freeze clock", http.MethodPut, "/admin/clock", `{"time": "2025-01-15T09:00:00Z", "frozen": true}`, http.StatusOK, ""},
+		{"create", http.MethodPost, "/sql/v1beta4/projects/test-project/instances",
+			`{"name": "maintained", "settings": {"maintenanceWindow": {"day": 3, "hour": 10}}}`, http.StatusOK, "RUNNABLE"},
+		{"enter window", http.MethodPost, "/admin/clock/advance", `{"duration": "1h5m"}`, http.StatusOK, "MAINTENANCE"},
+		{"restart in maintenance", http.MethodPost, instancePath + "/restart", "", http.StatusConflict, "MAINTENANCE"},
+		{"maintenance done", http.MethodPost, "/admin/clock/advance", `{"duration": "5m"}`, http.StatusOK, "RUNNABLE"},
+		{"trigger", http.MethodPost, "/admin/sql/instances/maintained/maintenance", `{"duration": "1m"}`, http.StatusOK, "MAINTENANCE"},
+		{"trigger in maintenance", http.MethodPost, "/admin/sql/instances/maintained/maintenance", "", http.StatusConflict, "MAINTENANCE"},
+		{"triggered maintenance done", http.MethodPost, "/admin/clock/advance", `{"duration": "1m"}`, http.StatusOK, "RUNNABLE"},
+		{"invalid duration", http.MethodPost, "/admin/sql/instances/maintained/maintenance", `{"duration": "soon"}`, http.StatusBadRequest, "RUNNABLE"},
+		{"unknown instance", http.MethodPost, "/admin/sql/instances/missing/maintenance", "", http.StatusNotFound, "RUNNABLE"},
+	}
+
+	for _, step := range steps {
+		req := httptest.NewRequest(step.method, step.path, strings.NewReader(step.body))
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, req)
+
+		if rr.Code != step.expectedStatus {
+			t.Errorf("%s: expected status %d, got %d: %s", step.name, step.expectedStatus, rr.Code, rr.Body.String())
+		}
+		if step.expectedState == "" {
+			continue
+		}
+
+		rr = httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, instancePath, nil))
+		var instance sqladmin.DatabaseInstance
+		if err := json.NewDecoder(rr.Body).Decode(&instance); err != nil {
+			t.Fatalf("%s: failed to decode instance: %v", step.name, err)
+		}
+		if instance.State != step.expectedState {
+			t.Errorf("%s: expected state %s, got %s", step.name, step.expectedState, instance.State)
+		}
+	}
+
+	// The scheduled and the triggered maintenance are both DONE operations
+	rr := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/sql/v1beta4/projects/test-project/operations?instance=maintained", nil))
+	var ops sqladmin.OperationsListResponse
+	if err := json.NewDecoder(rr.Body).Decode(&ops); err != nil {
+		t.Fatalf("failed to decode operations: %v", err)
+	}
+	var maintenance int
+	for _, op := range ops.Items {
+		if op.OperationType != "MAINTENANCE" {
+			continue
+		}
+		maintenance++
+		if op.Status != "DONE" {
+			t.Errorf("expected maintenance operation %s DONE, got %s", op.Name, op.Status)
+		}
+	}
+	if maintenance != 2 {
+		t.Errorf("expected 2 maintenance operations, got %d", maintenance)
+	}
+}
+
+func TestServer_SQLProxy(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	srv := New(&config.Config{Host: "127.0.0.1", SQLProxyPorts: "0"})
+	defer srv.Shutdown(context.Background())
+
+	listProxy := func() handler.SQLProxyList {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/sql/proxy", nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("list proxy failed: %d - %s", rr.Code, rr.Body.String())
+		}
+		var list handler.SQLProxyList
+		if err := json.NewDecoder(rr.Body).Decode(&list); err != nil {
+			t.Fatalf("failed to decode proxy list: %v", err)
+		}
+		return list
+	}
+
+	rr := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/sql/v1beta4/projects/test-project/instances",
+		strings.NewReader(`{"name": "proxied", "databaseVersion": "POSTGRES_15", "region": "us-central1"}`)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("create instance failed: %d - %s", rr.Code, rr.Body.String())
+	}
+
+	list := listProxy()
+	if len(list.Items) != 1 || list.Items[0].Instance != "proxied" || list.Items[0].Port == 0 {
+		t.Fatalf("expected a port for the instance, got %+v", list.Items)
+	}
+	connectionName := list.Items[0].ConnectionName
+
+	steps := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+	}{
+		{"set target", http.MethodPut, "/admin/sql/proxy/" + connectionName, `{"target": "localhost:5432"}`, http.StatusNoContent},
+		{"invalid target", http.MethodPut, "/admin/sql/proxy/" + connectionName, `{"target": "localhost"}`, http.StatusBadRequest},
+		{"missing target", http.MethodPut, "/admin/sql/proxy/" + connectionName, `{}`, http.StatusBadRequest},
+	}
+	for _, step := range steps {
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, httptest.NewRequest(step.method, step.path, strings.NewReader(step.body)))
+		if rr.Code != step.expectedStatus {
+			t.Errorf("%s: expected status %d, got %d: %s", step.name, step.expectedStatus, rr.Code, rr.Body.String())
+		}
+	}
+	if target := listProxy().Items[0].Target; target != "localhost:5432" {
+		t.Errorf("expected target localhost:5432, got %q", target)
+	}
+
+	// Deleting the instance closes its port
+	rr = httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/sql/v1beta4/projects/test-project/instances/proxied", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("delete instance failed: %d - %s", rr.Code, rr.Body.String())
+	}
+	if list := listProxy(); len(list.Items) != 0 {
+		t.Errorf("expected no ports after deleting the instance, got %+v", list.Items)
+	}
+}
+
+func TestServer_SQLData(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	srv := New(&config.Config{SQLDataDir: t.TempDir()})
+
+	steps := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+	}{
+		{"create instance", http.MethodPost, "/sql/v1beta4/projects/test-project/instances", `{"name": "queried", "databaseVersion": "POSTGRES_15"}`, http.StatusOK},
+		{"create database", http.MethodPost, "/sql/v1beta4/projects/test-project/instances/queried/databases", `{"name": "app"}`, http.StatusOK},
+		{"get dsn", http.MethodGet, "/admin/sql/instances/queried/databases/app/dsn", "", http.StatusOK},
+		{"unknown database", http.MethodGet, "/admin/sql/instances/queried/databases/missing/dsn", "", http.StatusNotFound},
+		{"delete database", http.MethodDelete, "/sql/v1beta4/projects/test-project/instances/queried/databases/app", "", http.StatusOK},
+		{"get deleted dsn", http.MethodGet, "/admin/sql/instances/queried/databases/app/dsn", "", http.StatusNotFound},
+	}
+
+	for _, step := range steps {
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, httptest.NewRequest(step.method, step.path, strings.NewReader(step.body)))
+		if rr.Code != step.expectedStatus {
+			t.Fatalf("%s: expected status %d, got %d: %s", step.name, step.expectedStatus, rr.Code, rr.Body.String())
+		}
+		if step.name != "get dsn" {
+			continue
+		}
+
+		var db sqldata.Database
+		if err := json.NewDecoder(rr.Body).Decode(&db); err != nil {
+			t.Fatalf("failed to decode database: %v", err)
+		}
+		if db.Driver != "sqlite3" || !strings.HasPrefix(db.DSN, "file:") {
+			t.Errorf("unexpected database file: %+v", db)
+		}
+		if _, err := os.Stat(db.Path); err != nil {
+			t.Errorf("expected the SQLite file to exist: %v", err)
+		}
+	}
+
+	// The default database of the instance is backed too
+	rr := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/sql/databases", nil))
+	var list handler.SQLDataList
+	if err := json.NewDecoder(rr.Body).Decode(&list); err != nil {
+		t.Fatalf("failed to decode databases: %v", err)
+	}
+	if len(list.Items) != 1 || list.Items[0].Instance != "queried" {
+		t.Errorf("expected the default database of the instance, got %+v", list.Items)
+	}
+}
+
+func TestServer_Mirror(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "fixtures"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "fixtures", "config.json"), []byte("{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := New(&config.Config{MirrorDir: dir, MirrorInterval: "1h"})
+	defer srv.Shutdown(context.Background())
+
+	// Files present at startup are objects
+	rr := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/storage/v1/b/fixtures/o/config.json?alt=media", nil))
+	if rr.Code != http.StatusOK || rr.Body.String() != "{}" {
+		t.Fatalf("expected the file to be served as an object, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	// Uploads are written to files before the response is sent
+	rr = httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/upload/storage/v1/b/fixtures/o?uploadType=media&name=data/users.json", strings.NewReader(`[]`)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("upload failed: %d - %s", rr.Code, rr.Body.String())
+	}
+	if content, err := os.ReadFile(filepath.Join(dir, "fixtures", "data", "users.json")); err != nil || string(content) != "[]" {
+		t.Errorf("expected the uploaded object in its file, got %q, %v", content, err)
+	}
+}
+
+func TestServer_DateHeaders(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	srv := New(&config.Config{ClockSkew: "-10m"})
+
+	send := func(method, path, body string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, req)
+		return rr
+	}
+	send(http.MethodPut, "/admin/clock", `{"time": "2030-01-01T12:00:00Z", "frozen": true}`, nil)
+
+	// The configured skew shifts the Date header away from the virtual time
+	rr := send(http.MethodGet, "/storage/v1/b?project=test-project", "", nil)
+	if date := rr.Header().Get("Date"); date != "Tue, 01 Jan 2030 11:50:00 GMT" {
+		t.Errorf("expected the skewed virtual time, got Date %q", date)
+	}
+
+	// A request can bring its own skew
+	rr = send(http.MethodGet, "/storage/v1/b?project=test-project", "", http.Header{"X-Mock-Clock-Skew": {"1h"}})
+	if date := rr.Header().Get("Date"); date != "Tue, 01 Jan 2030 13:00:00 GMT" {
+		t.Errorf("expected the skew of the request, got Date %q", date)
+	}
+	if rr = send(http.MethodGet, "/storage/v1/b?project=test-project", "", http.Header{"X-Mock-Clock-Skew": {"soon"}}); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid skew, got %d", rr.Code)
+	}
+
+	// Downloads expire after the max-age of the object
+	send(http.MethodPut, "/admin/clock", `{"skew": "0s"}`, nil)
+	send(http.MethodPost, "/storage/v1/b?project=test-project", `{"name": "dated"}`, nil)
+	send(http.MethodPost, "/upload/storage/v1/b/dated/o?uploadType=media&name=a.txt", "a", http.Header{"Cache-Control": {"public, max-age=3600"}})
+	rr = send(http.MethodGet, "/storage/v1/b/dated/o/a.txt?alt=media", "", nil)
+	if rr.Code != http.StatusOK || rr.Header().Get("Expires") != "Tue, 01 Jan 2030 13:00:00 GMT" {
+		t.Errorf("expected the download to expire in an hour, got %d with Expires %q", rr.Code, rr.Header().Get("Expires"))
+	}
+}
+
+func TestServer_SQLReplicas(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	cfg := &config.Config{}
+	srv := New(cfg)
+
+	const instancesPath = "/sql/v1beta4/projects/test-project/instances"
+	steps := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+	}{
+		{"create primary", http.MethodPost, instancesPath, `{"name":"primary","region":"europe-west1"}`, http.StatusOK},
+		{"create replica", http.MethodPost, instancesPath, `{"name":"replica","masterInstanceName":"primary"}`, http.StatusOK},
+		{"create replica of missing primary", http.MethodPost, instancesPath, `{"name":"orphan","masterInstanceName":"missing"}`, http.StatusNotFound},
+		{"delete primary with replicas", http.MethodDelete, instancesPath + "/primary", "", http.StatusBadRequest},
+		{"promote primary", http.MethodPost, instancesPath + "/primary/promoteReplica", "", http.StatusBadRequest},
+		{"promote replica", http.MethodPost, instancesPath + "/replica/promoteReplica", "", http.StatusOK},
+		{"promote missing instance", http.MethodPost, instancesPath + "/missing/promoteReplica", "", http.StatusNotFound},
+		{"delete primary", http.MethodDelete, instancesPath + "/primary", "", http.StatusOK},
+	}
+
+	for _, step := range steps {
+		req := httptest.NewRequest(step.method, step.path, strings.NewReader(step.body))
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, req)
+
+		if rr.Code != step.expectedStatus {
+			t.Errorf("%s: expected status %d, got %d: %s", step.name, step.expectedStatus, rr.Code, rr.Body.String())
+		}
+
+		if step.name == "create replica" {
+			rr = httptest.NewRecorder()
+			srv.Handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, instancesPath+"/primary", nil))
+			var primary sqladmin.DatabaseInstance
+			if err := json.NewDecoder(rr.Body).Decode(&primary); err != nil {
+				t.Fatalf("failed to decode primary: %v", err)
+			}
+			if len(primary.ReplicaNames) != 1 || primary.ReplicaNames[0] != "replica" {
+				t.Errorf("expected replica names [replica], got %v", primary.ReplicaNames)
+			}
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, instancesPath+"/replica", nil))
+	var replica sqladmin.DatabaseInstance
+	if err := json.NewDecoder(rr.Body).Decode(&replica); err != nil {
+		t.Fatalf("failed to decode replica: %v", err)
+	}
+	if replica.Region != "europe-west1" || replica.MasterInstanceName != "" {
+		t.Errorf("expected promoted replica in europe-west1 without primary, got %s and %q", replica.Region, replica.MasterInstanceName)
+	}
+}
+
+func TestServer_BucketQuota(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	cfg := &config.Config{}
+	srv := New(cfg)
+
+	const quotaPath = "/admin/storage/buckets/quota-bucket/quota"
+	const uploadPath = "/upload/storage/v1/b/quota-bucket/o?uploadType=media&name="
+	steps := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+	}{
+		{"create bucket", http.MethodPost, "/storage/v1/b", `{"name":"quota-bucket"}`, http.StatusOK},
+		{"set quota", http.MethodPut, quotaPath, `{"maxBytes":8}`, http.StatusOK},
+		{"set quota of missing bucket", http.MethodPut, "/admin/storage/buckets/missing/quota", `{"maxBytes":8}`, http.StatusNotFound},
+		{"upload within quota", http.MethodPost, uploadPath + "a.txt", "12345", http.StatusOK},
+		{"upload beyond quota", http.MethodPost, uploadPath + "b.txt", "12345", http.StatusForbidden},
+		{"get usage", http.MethodGet, quotaPath, "", http.StatusOK},
+		{"delete object", http.MethodDelete, "/storage/v1/b/quota-bucket/o/a.txt", "", http.StatusNoContent},
+		{"upload after cleanup", http.MethodPost, uploadPath + "b.txt", "12345", http.StatusOK},
+		{"remove quota", http.MethodDelete, quotaPath, "", http.StatusNoContent},
+		{"upload without quota", http.MethodPost, uploadPath + "c.txt", "12345", http.StatusOK},
+	}
+
+	for _, step := range steps {
+		req := httptest.NewRequest(step.method, step.path, strings.NewReader(step.body))
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, req)
+
+		if rr.Code != step.expectedStatus {
+			t.Errorf("%s: expected status %d, got %d: %s", step.name, step.expectedStatus, rr.Code, rr.Body.String())
+		}
+		if step.name == "upload beyond quota" && !strings.Contains(rr.Body.String(), "quotaExceeded") {
+			t.Errorf("%s: expected quotaExceeded reason, got %s", step.name, rr.Body.String())
+		}
+		if step.name == "get usage" && !strings.Contains(rr.Body.String(), `"usedBytes":5`) {
+			t.Errorf("%s: expected 5 used bytes, got %s", step.name, rr.Body.String())
+		}
+	}
+}
+
+func TestServer_DeleteObjectsByPrefix(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	srv := New(&config.Config{})
+
+	const uploadPath = "/upload/storage/v1/b/suites/o?uploadType=media&name="
+	steps := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"create bucket", http.MethodPost, "/storage/v1/b", `{"name":"suites"}`, http.StatusOK, ""},
+		{"upload a/1", http.MethodPost, uploadPath + "a/1.txt", "1", http.StatusOK, ""},
+		{"upload a/2", http.MethodPost, uploadPath + "a/2.txt", "2", http.StatusOK, ""},
+		{"upload b/1", http.MethodPost, uploadPath + "b/1.txt", "1", http.StatusOK, ""},
+		{"delete prefix", http.MethodDelete, "/admin/storage/buckets/suites/objects?prefix=a/", "", http.StatusOK, `{"deleted":2}`},
+		{"deleted object", http.MethodGet, "/storage/v1/b/suites/o/a%2F1.txt", "", http.StatusNotFound, ""},
+		{"kept object", http.MethodGet, "/storage/v1/b/suites/o/b%2F1.txt", "", http.StatusOK, ""},
+		{"delete all", http.MethodDelete, "/admin/storage/buckets/suites/objects", "", http.StatusOK, `{"deleted":1}`},
+		{"delete bucket", http.MethodDelete, "/storage/v1/b/suites", "", http.StatusNoContent, ""},
+		{"missing bucket", http.MethodDelete, "/admin/storage/buckets/suites/objects", "", http.StatusNotFound, ""},
+	}
+
+	for _, step := range steps {
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, httptest.NewRequest(step.method, step.path, strings.NewReader(step.body)))
+
+		if rr.Code != step.expectedStatus {
+			t.Errorf("%s: expected status %d, got %d: %s", step.name, step.expectedStatus, rr.Code, rr.Body.String())
+		}
+		if step.expectedBody != "" && !strings.Contains(rr.Body.String(), step.expectedBody) {
+			t.Errorf("%s: expected body to contain %s, got %s", step.name, step.expectedBody, rr.Body.String())
+		}
+	}
+}
+
+func TestServer_Jobs(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	srv := New(&config.Config{NamespaceTTL: "1h"})
+	defer srv.Close()
+
+	rr := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/jobs", nil))
+	var list handler.JobList
+	if err := json.NewDecoder(rr.Body).Decode(&list); err != nil {
+		t.Fatalf("failed to decode jobs: %v", err)
+	}
+	if len(list.Items) != 2 || list.Items[0].Name != "namespaces.expire" || list.Items[1].Name != "store.tick" {
+		t.Fatalf("expected the namespace expiry and store tick jobs, got %+v", list.Items)
+	}
+	if list.Items[1].NextRun.IsZero() {
+		t.Errorf("expected the jobs to be scheduled, got %+v", list.Items[1])
+	}
+
+	rr = httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/admin/jobs/namespaces.expire/run", nil))
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"runs":1`) {
+		t.Errorf("expected the job to run once, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/admin/jobs/missing/run", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for a missing job, got %d", rr.Code)
+	}
+}
+
+func TestServer_TickAppliesClockToNamespaces(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	srv := New(&config.Config{})
+	defer srv.Close()
+
+	serve := func(method, path, namespace, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if namespace != "" {
+			req.Header.Set("X-Mock-Namespace", namespace)
+		}
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	steps := []struct {
+		method, path, namespace, body string
+	}{
+		{http.MethodPost, "/storage/v1/b", "ci-1", `{"name":"scratch","lifecycle":{"rule":[{"action":{"type":"Delete"},"condition":{"age":1}}]}}`},
+		{http.MethodPost, "/upload/storage/v1/b/scratch/o?uploadType=media&name=old.txt", "ci-1", "old"},
+		{http.MethodPut, "/admin/clock", "", `{"frozen": true}`},
+		// Advancing the clock of the default namespace doesn't tick ci-1
+		{http.MethodPost, "/admin/clock/advance", "", `{"duration": "48h"}`},
+		{http.MethodPost, "/admin/jobs/store.tick/run", "", ""},
+	}
+	for _, step := range steps {
+		if rr := serve(step.method, step.path, step.namespace, step.body); rr.Code != http.StatusOK {
+			t.Fatalf("%s %s: expected status %d, got %d: %s", step.method, step.path, http.StatusOK, rr.Code, rr.Body.String())
+		}
+	}
+
+	if rr := serve(http.MethodGet, "/storage/v1/b/scratch/o/old.txt", "ci-1", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("expected the lifecycle rule to delete old.txt, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestServer_BlobDedup(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	srv := New(&config.Config{BlobDedup: true})
+
+	const fixture = "the same fixture content"
+	steps := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{http.MethodPost, "/storage/v1/b", `{"name":"suite-a"}`},
+		{http.MethodPost, "/storage/v1/b", `{"name":"suite-b"}`},
+		{http.MethodPost, "/upload/storage/v1/b/suite-a/o?uploadType=media&name=fixture.txt", fixture},
+		{http.MethodPost, "/upload/storage/v1/b/suite-b/o?uploadType=media&name=fixture.txt", fixture},
+		{http.MethodPost, "/upload/storage/v1/b/suite-b/o?uploadType=media&name=copy.txt", fixture},
+		{http.MethodDelete, "/storage/v1/b/suite-b/o/copy.txt", ""},
+	}
+	for _, step := range steps {
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, httptest.NewRequest(step.method, step.path, strings.NewReader(step.body)))
+		if rr.Code >= 300 {
+			t.Fatalf("%s %s: expected success, got %d: %s", step.method, step.path, rr.Code, rr.Body.String())
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/storage/dedup", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var stats blob.DedupStats
+	if err := json.NewDecoder(rr.Body).Decode(&stats); err != nil {
+		t.Fatalf("failed to decode stats: %v", err)
+	}
+	if stats.Blobs != 1 || stats.References != 2 || stats.StoredBytes != int64(len(fixture)) {
+		t.Errorf("expected both objects to share one blob, got %+v", stats)
+	}
+
+	// The content is still served for both objects
+	for _, bucket := range []string{"suite-a", "suite-b"} {
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/download/storage/v1/b/"+bucket+"/o/fixture.txt?alt=media", nil))
+		if rr.Body.String() != fixture {
+			t.Errorf("%s: expected the fixture content, got %q", bucket, rr.Body.String())
+		}
+	}
+
+	// Without deduplication the stats are not available
+	rr = httptest.NewRecorder()
+	New(&config.Config{}).Handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/storage/dedup", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 without deduplication, got %d", rr.Code)
+	}
+}
+
+func TestServer_MaxContentSize(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	// Dedup is enabled to check that the limit is found behind it
+	srv := New(&config.Config{MaxContentSize: "10", BlobDedup: true})
+
+	steps := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{http.MethodPost, "/storage/v1/b", `{"name":"cache"}`},
+		{http.MethodPost, "/upload/storage/v1/b/cache/o?uploadType=media&name=old.txt", "aaaaaa"},
+		{http.MethodPost, "/upload/storage/v1/b/cache/o?uploadType=media&name=new.txt", "bbbbbb"},
+	}
+	for _, step := range steps {
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, httptest.NewRequest(step.method, step.path, strings.NewReader(step.body)))
+		if rr.Code >= 300 {
+			t.Fatalf("%s %s: expected success, got %d: %s", step.method, step.path, rr.Code, rr.Body.String())
+		}
+	}
+
+	// The evicted object keeps its metadata, but its content is gone
+	rr := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/storage/v1/b/cache/o/old.txt", nil))
+	var obj storage.Object
+	if err := json.NewDecoder(rr.Body).Decode(&obj); err != nil || obj.Size != 6 {
+		t.Errorf("expected the metadata of the evicted object, got %d: %+v, %v", rr.Code, obj, err)
+	}
+	for _, path := range []string{"/download/storage/v1/b/cache/o/old.txt?alt=media", "/cache/old.txt"} {
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		if rr.Code != http.StatusGone || !strings.Contains(rr.Body.String(), "evicted") {
+			t.Errorf("%s: expected status 410 explaining the eviction, got %d: %s", path, rr.Code, rr.Body.String())
+		}
+	}
+	rr = httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/download/storage/v1/b/cache/o/new.txt?alt=media", nil))
+	if rr.Body.String() != "bbbbbb" {
+		t.Errorf("expected the content of the recent object, got %d: %q", rr.Code, rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/storage/content", nil))
+	var stats blob.LRUStats
+	if err := json.NewDecoder(rr.Body).Decode(&stats); err != nil {
+		t.Fatalf("failed to decode stats: %v", err)
+	}
+	want := blob.LRUStats{MaxBytes: 10, StoredBytes: 6, Blobs: 1, Evictions: 1, EvictedBytes: 6}
+	if stats != want {
+		t.Errorf("expected stats %+v, got %+v", want, stats)
+	}
+
+	// Snapshots keep the content evicted
+	rr = httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/snapshot", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 for the snapshot, got %d: %s", rr.Code, rr.Body.String())
+	}
+	snapshot := rr.Body
+	restored := New(&config.Config{})
+	rr = httptest.NewRecorder()
+	restored.Handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/admin/restore", snapshot))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 for the restore, got %d: %s", rr.Code, rr.Body.String())
+	}
+	rr = httptest.NewRecorder()
+	restored.Handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/download/storage/v1/b/cache/o/old.txt?alt=media", nil))
+	if rr.Code != http.StatusGone {
+		t.Errorf("expected status 410 for the restored evicted object, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	// Without a limit the stats are not available
+	rr = httptest.NewRecorder()
+	New(&config.Config{}).Handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/storage/content", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 without a limit, got %d", rr.Code)
+	}
+}
+
+func TestServer_StrictAuth(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	cfg := &config.Config{AuthMode: "strict"}
+	srv := New(cfg)
+
+	// JWT with a service account for project "other-project" (header and signature are not checked)
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"email":"ci@other-project.iam.gserviceaccount.com"}`))
+	otherProjectToken := "e30." + payload + ".sig"
+
+	tests := []struct {
+		name           string
+		path           string
+		token          string
+		expectedStatus int
+		format         string
+	}{
+		{"storage without token", "/storage/v1/b?project=test-project", "", http.StatusUnauthorized, "storage"},
+		{"storage with opaque token", "/storage/v1/b?project=test-project", "ya29.mock", http.StatusOK, "storage"},
+		{"storage with token for other project", "/storage/v1/b?project=test-project", otherProjectToken, http.StatusForbidden, "storage"},
+		{"sql without token", "/sql/v1beta4/projects/test-project/instances", "", http.StatusUnauthorized, "sql"},
+		{"sql with token for other project", "/sql/v1beta4/projects/test-project/instances", otherProjectToken, http.StatusForbidden, "sql"},
+		{"sql with token for same project", "/sql/v1beta4/projects/other-project/instances", otherProjectToken, http.StatusOK, "sql"},
+		{"xml without token", "/some-bucket/file.txt", "", http.StatusUnauthorized, "xml"},
+		{"registry without token", "/v2/", "", http.StatusUnauthorized, "registry"},
+		{"registry with token", "/v2/", "ya29.mock", http.StatusOK, "registry"},
+		{"cloud run without token", "/v2/projects/test-project/locations/us-central1/services", "", http.StatusUnauthorized, "status"},
+		{"health without token", "/health", "", http.StatusOK, ""},
+		{"admin without token", "/admin/clock", "", http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rr := httptest.NewRecorder()
+
+			srv.Handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+
+			if tt.expectedStatus < 400 {
+				return
+			}
+
+			switch tt.format {
+			case "sql", "status":
+				var errResp sqladmin.APIError
+				if err := json.NewDecoder(rr.Body).Decode(&errResp); err != nil {
+					t.Fatalf("failed to decode response: %v", err)
+				}
+				if errResp.Error.Status == "" {
+					t.Error("expected error status to be set")
+				}
+			case "xml":
+				var errResp storage.XMLError
+				if err := xml.NewDecoder(rr.Body).Decode(&errResp); err != nil {
+					t.Fatalf("failed to decode response: %v", err)
+				}
+				if errResp.Code != "AuthenticationRequired" {
+					t.Errorf("expected error code AuthenticationRequired, got %s", errResp.Code)
+				}
+			case "registry":
+				// Docker only sends the token of its credential helper after a Basic challenge
+				if got := rr.Header().Get("WWW-Authenticate"); !strings.HasPrefix(got, "Basic ") {
+					t.Errorf("expected a Basic challenge, got %q", got)
+				}
+				var errResp registry.ErrorResponse
+				if err := json.NewDecoder(rr.Body).Decode(&errResp); err != nil {
+					t.Fatalf("failed to decode response: %v", err)
+				}
+				if len(errResp.Errors) != 1 || errResp.Errors[0].Code != "UNAUTHORIZED" {
+					t.Errorf("expected an UNAUTHORIZED error, got %+v", errResp.Errors)
+				}
+			default:
+				var errResp storage.APIError
+				if err := json.NewDecoder(rr.Body).Decode(&errResp); err != nil {
+					t.Fatalf("failed to decode response: %v", err)
+				}
+				if errResp.Error.Code != tt.expectedStatus {
+					t.Errorf("expected error code %d, got %d", tt.expectedStatus, errResp.Error.Code)
+				}
+			}
+		})
+	}
+
+	// Docker sends the token as the password of Basic auth
+	req := httptest.NewRequest(http.MethodGet, "/v2/", nil)
+	req.SetBasicAuth("oauth2accesstoken", "ya29.mock")
+	rr := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected status %d for Basic auth, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+}
+
+func TestServer_StrictAuthS3(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	cfg := &config.Config{AuthMode: "strict", S3Enabled: true}
+	srv := New(cfg)
+
+	// Without configured credentials or a matching HMAC key, the signature can't be verified
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential=AKID/20240101/us-east-1/s3/aws4_request,SignedHeaders=host,Signature=abc")
+	rr := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusForbidden, rr.Code, rr.Body.String())
+	}
+	if !strings.Contains(rr.Body.String(), "<Code>InvalidAccessKeyId</Code>") {
+		t.Errorf("expected an InvalidAccessKeyId error, got %s", rr.Body.String())
+	}
+}
+
+func TestServer_DisabledServices(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	cfg := &config.Config{DisabledServices: []string{"sqladmin.googleapis.com", "artifactregistry.googleapis.com"}}
+	srv := New(cfg)
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+	}{
+		{"enabled storage", "/storage/v1/b?project=test-project", http.StatusOK},
+		{"disabled sql", "/sql/v1beta4/projects/test-project/instances", http.StatusForbidden},
+		{"enabled cloud run", "/v2/projects/test-project/locations/us-central1/services", http.StatusOK},
+		{"disabled registry", "/v2/_catalog", http.StatusForbidden},
+		{"health", "/health", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			rr := httptest.NewRecorder()
+
+			srv.Handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+
+	// Client libraries detect disabled APIs by the SERVICE_DISABLED error info
+	req := httptest.NewRequest(http.MethodGet, "/sql/v1beta4/projects/test-project/instances", nil)
+	rr := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rr, req)
+
+	var errResp sqladmin.APIError
+	if err := json.NewDecoder(rr.Body).Decode(&errResp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if errResp.Error.Status != "PERMISSION_DENIED" {
+		t.Errorf("expected status PERMISSION_DENIED, got %s", errResp.Error.Status)
+	}
+	if len(errResp.Error.Details) != 1 || errResp.Error.Details[0].Reason != "SERVICE_DISABLED" {
+		t.Fatalf("expected SERVICE_DISABLED error info, got %+v", errResp.Error.Details)
+	}
+	if consumer := errResp.Error.Details[0].Metadata["consumer"]; consumer != "projects/test-project" {
+		t.Errorf("expected consumer projects/test-project, got %s", consumer)
+	}
+}
+
+func TestServer_DisabledServices_ResourceIDs(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	// Services are told apart by the collection of the path, not by words in resource IDs
+	cfg := &config.Config{DisabledServices: []string{
+		"cloudscheduler.googleapis.com", "eventarc.googleapis.com", "orgpolicy.googleapis.com",
+		"container.googleapis.com", "firestore.googleapis.com",
+	}}
+	srv := New(cfg)
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+	}{
+		{"memorystore instance named like jobs", "/v1/projects/p/locations/us-central1/instances/jobs-cache", http.StatusNotFound},
+		{"memorystore instance named like other collections", "/v1/projects/p/locations/us-central1/instances/triggers-clusters-documents", http.StatusNotFound},
+		{"memorystore instance named clusters", "/v1/projects/p/locations/us-central1/instances/clusters", http.StatusNotFound},
+		{"cloud run service named like policies", "/v2/projects/p/locations/us-central1/services/policies-api", http.StatusNotFound},
+		{"cloud run service named like jobs and triggers", "/v2/projects/p/locations/us-central1/services/jobs-triggers", http.StatusNotFound},
+		{"scheduler job named like instances", "/v1/projects/p/locations/us-central1/jobs/instances-sync", http.StatusForbidden},
+		{"eventarc trigger named like clusters", "/v1/projects/p/locations/us-central1/triggers/clusters-events", http.StatusForbidden},
+		{"gke cluster named like instances", "/v1/projects/p/locations/us-central1/clusters/instances", http.StatusForbidden},
+		{"org policy", "/v2/projects/p/policies/storage.uniformBucketLevelAccess", http.StatusForbidden},
+		{"firestore document", "/v1/projects/p/databases/(default)/documents/jobs/1", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			rr := httptest.NewRecorder()
+
+			srv.Handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestServer_XMLAPIRoutes(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	cfg := &config.Config{}
+	srv := New(cfg)
+
+	steps := []struct {
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+	}{
+		{http.MethodPut, "/xml-bucket", "", http.StatusOK},
+		{http.MethodPut, "/xml-bucket/file.txt", "content", http.StatusOK},
+		{http.MethodGet, "/xml-bucket", "", http.StatusOK},
+		{http.MethodGet, "/xml-bucket/file.txt", "", http.StatusOK},
+		{http.MethodHead, "/xml-bucket/file.txt", "", http.StatusOK},
+		{http.MethodGet, "/storage/v1/b/xml-bucket/o/file.txt", "", http.StatusOK},
+		{http.MethodDelete, "/xml-bucket/file.txt", "", http.StatusNoContent},
+		{http.MethodDelete, "/xml-bucket", "", http.StatusNoContent},
+		{http.MethodGet, "/health", "", http.StatusOK},
+	}
+
+	for _, step := range steps {
+		req := httptest.NewRequest(step.method, step.path, strings.NewReader(step.body))
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, req)
+
+		if rr.Code != step.expectedStatus {
+			t.Errorf("%s %s: expected status %d, got %d: %s", step.method, step.path, step.expectedStatus, rr.Code, rr.Body.String())
+		}
+	}
+}
+
+func TestServer_BucketCORS(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	cfg := &config.Config{}
+	srv := New(cfg)
+
+	cors := `{"name":"cors-bucket","cors":[{"origin":["https://app.example.com"],"method":["GET","PUT"],"responseHeader":["Content-Type","x-goog-generation"],"maxAgeSeconds":3600}]}`
+	for _, body := range []string{cors, `{"name":"plain-bucket"}`} {
+		req := httptest.NewRequest(http.MethodPost, "/storage/v1/b?project=test-project", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("create bucket: expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+		}
+	}
+
+	steps := []struct {
+		name                string
+		method              string
+		path                string
+		origin              string
+		requestMethod       string
+		expectedStatus      int
+		expectedAllowOrigin string
+	}{
+		{"preflight upload", http.MethodOptions, "/cors-bucket/photo.jpg", "https://app.example.com", "PUT", http.StatusOK, "https://app.example.com"},
+		{"preflight JSON API", http.MethodOptions, "/upload/storage/v1/b/cors-bucket/o", "https://app.example.com", "PUT", http.StatusOK, "https://app.example.com"},
+		{"preflight disallowed method", http.MethodOptions, "/cors-bucket/photo.jpg", "https://app.example.com", "DELETE", http.StatusForbidden, ""},
+		{"preflight disallowed origin", http.MethodOptions, "/cors-bucket/photo.jpg", "https://evil.example.com", "PUT", http.StatusForbidden, ""},
+		{"preflight bucket without CORS", http.MethodOptions, "/plain-bucket/photo.jpg", "https://app.example.com", "PUT", http.StatusForbidden, ""},
+		{"upload from allowed origin", http.MethodPut, "/cors-bucket/photo.jpg", "https://app.example.com", "", http.StatusOK, "https://app.example.com"},
+		{"download from other origin", http.MethodGet, "/cors-bucket/photo.jpg", "https://evil.example.com", "", http.StatusOK, ""},
+		{"same-origin download", http.MethodGet, "/cors-bucket/photo.jpg", "", "", http.StatusOK, ""},
+	}
+
+	for _, step := range steps {
+		req := httptest.NewRequest(step.method, step.path, strings.NewReader("image"))
+		if step.origin != "" {
+			req.Header.Set("Origin", step.origin)
+		}
+		if step.requestMethod != "" {
+			req.Header.Set("Access-Control-Request-Method", step.requestMethod)
+			req.Header.Set("Access-Control-Request-Headers", "content-type")
+		}
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, req)
+
+		if rr.Code != step.expectedStatus {
+			t.Errorf("%s: expected status %d, got %d: %s", step.name, step.expectedStatus, rr.Code, rr.Body.String())
+		}
+		if got := rr.Header().Get("Access-Control-Allow-Origin"); got != step.expectedAllowOrigin {
+			t.Errorf("%s: expected Access-Control-Allow-Origin %q, got %q", step.name, step.expectedAllowOrigin, got)
+		}
+		if step.method == http.MethodOptions && rr.Code == http.StatusOK && rr.Header().Get("Access-Control-Max-Age") != "3600" {
+			t.Errorf("%s: expected Access-Control-Max-Age 3600, got %q", step.name, rr.Header().Get("Access-Control-Max-Age"))
+		}
+		if step.method == http.MethodPut && rr.Header().Get("Access-Control-Expose-Headers") != "Content-Type, x-goog-generation" {
+			t.Errorf("%s: unexpected Access-Control-Expose-Headers %q", step.name, rr.Header().Get("Access-Control-Expose-Headers"))
+		}
+	}
+}
+
+func TestServer_RequesterPays(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	cfg := &config.Config{}
+	srv := New(cfg)
+
+	steps := []struct {
+		name           string
+		method         string
+		path           string
+		userProject    string
+		body           string
+		expectedStatus int
+		expectedReason string
+	}{
+		{"create bucket", http.MethodPost, "/storage/v1/b?project=test-project", "", `{"name":"rp-bucket","billing":{"requesterPays":true}}`, http.StatusOK, ""},
+		{"get bucket metadata", http.MethodGet, "/storage/v1/b/rp-bucket", "", "", http.StatusOK, ""},
+		{"upload without user project", http.MethodPost, "/upload/storage/v1/b/rp-bucket/o?name=file.txt", "", "data", http.StatusBadRequest, "userProjectMissing"},
+		{"upload with user project", http.MethodPost, "/upload/storage/v1/b/rp-bucket/o?name=file.txt&userProject=billing-project", "", "data", http.StatusOK, ""},
+		{"list without user project", http.MethodGet, "/storage/v1/b/rp-bucket/o", "", "", http.StatusBadRequest, "userProjectMissing"},
+		{"list with user project header", http.MethodGet, "/storage/v1/b/rp-bucket/o", "billing-project", "", http.StatusOK, ""},
+		{"XML read without user project", http.MethodGet, "/rp-bucket/file.txt", "", "", http.StatusBadRequest, ""},
+		{"XML read with user project", http.MethodGet, "/rp-bucket/file.txt", "billing-project", "", http.StatusOK, ""},
+		{"disable requester pays", http.MethodPatch, "/storage/v1/b/rp-bucket", "", `{"billing":{"requesterPays":false}}`, http.StatusOK, ""},
+		{"read after disabling", http.MethodGet, "/storage/v1/b/rp-bucket/o/file.txt", "", "", http.StatusOK, ""},
+	}
+
+	for _, step := range steps {
+		req := httptest.NewRequest(step.method, step.path, strings.NewReader(step.body))
+		if step.userProject != "" {
+			req.Header.Set("X-Goog-User-Project", step.userProject)
+		}
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, req)
+
+		if rr.Code != step.expectedStatus {
+			t.Errorf("%s: expected status %d, got %d: %s", step.name, step.expectedStatus, rr.Code, rr.Body.String())
+		}
+		if step.expectedReason != "" && !strings.Contains(rr.Body.String(), `"reason":"`+step.expectedReason+`"`) {
+			t.Errorf("%s: expected reason %s, got %s", step.name, step.expectedReason, rr.Body.String())
+		}
+	}
+}
+
+func TestServer_StorageEmulatorHostClients(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	cfg := &config.Config{}
+	srv := New(cfg)
+
+	multipartBody := func(name, content string) string {
+		return "--b\r\nContent-Type: application/json; charset=UTF-8\r\n\r\n" +
+			`{"name":"` + name + `"}` + "\r\n--b\r\nContent-Type: text/plain\r\n\r\n" +
+			content + "\r\n--b--\r\n"
+	}
+
+	// Requests as the Go, Python and Node clients send them when STORAGE_EMULATOR_HOST is set
+	steps := []struct {
+		name           string
+		method         string
+		path           string
+		contentType    string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"create bucket", http.MethodPost, "/storage/v1/b?alt=json&prettyPrint=false&project=test-project", "application/json", `{"name":"emulator-bucket"}`, http.StatusOK, ""},
+		{"go multipart upload", http.MethodPost, "/upload/storage/v1/b/emulator-bucket/o?alt=json&name=go.txt&prettyPrint=false&projection=full&uploadType=multipart", "multipart/related; boundary=b", multipartBody("go.txt", "from go"), http.StatusOK, ""},
+		{"go XML read", http.MethodGet, "/emulator-bucket/go.txt", "", "", http.StatusOK, "from go"},
+		{"python multipart upload", http.MethodPost, "/upload/storage/v1/b/emulator-bucket/o?uploadType=multipart", "multipart/related; boundary=b", multipartBody("python.txt", "from python"), http.StatusOK, ""},
+		{"python download", http.MethodGet, "/download/storage/v1/b/emulator-bucket/o/python.txt?alt=media", "", "", http.StatusOK, "from python"},
+		{"node media read", http.MethodGet, "/storage/v1/b/emulator-bucket/o/go.txt?alt=media", "", "", http.StatusOK, "from go"},
+		{"shortcut list buckets", http.MethodGet, "/b?project=test-project", "", "", http.StatusOK, ""},
+		{"shortcut get bucket", http.MethodGet, "/b/emulator-bucket", "", "", http.StatusOK, ""},
+		{"shortcut list objects", http.MethodGet, "/b/emulator-bucket/o", "", "", http.StatusOK, ""},
+		{"shortcut media read", http.MethodGet, "/b/emulator-bucket/o/python.txt?alt=media", "", "", http.StatusOK, "from python"},
+		{"shortcut delete object", http.MethodDelete, "/b/emulator-bucket/o/python.txt", "", "", http.StatusNoContent, ""},
+	}
+
+	for _, step := range steps {
+		req := httptest.NewRequest(step.method, step.path, strings.NewReader(step.body))
+		if step.contentType != "" {
+			req.Header.Set("Content-Type", step.contentType)
+		}
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, req)
+
+		if rr.Code != step.expectedStatus {
+			t.Errorf("%s: expected status %d, got %d: %s", step.name, step.expectedStatus, rr.Code, rr.Body.String())
+		}
+		if step.expectedBody != "" && rr.Body.String() != step.expectedBody {
+			t.Errorf("%s: expected body %q, got %q", step.name, step.expectedBody, rr.Body.String())
+		}
+	}
+
+	// Node uploads resumably by default: start the upload, then PUT the content to the returned URL
+	req := httptest.NewRequest(http.MethodPost, "/upload/storage/v1/b/emulator-bucket/o?name=node.txt&uploadType=resumable",
+		strings.NewReader(`{"contentType":"text/plain"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPut, rr.Header().Get("Location"), strings.NewReader("from node"))
+	req.Header.Set("Content-Range", "bytes 0-8/9")
+	rr = httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/emulator-bucket/node.txt", nil)
+	rr = httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rr, req)
+	if rr.Body.String() != "from node" {
+		t.Errorf("expected body 'from node', got %q", rr.Body.String())
+	}
+}
+
+func TestServer_TerraformGCSBackendLocking(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	cfg := &config.Config{}
+	srv := New(cfg)
+
+	do := func(method, path, contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, req)
+		return rr
+	}
+	upload := func(name, content, query string) *httptest.ResponseRecorder {
+		body := "--b\r\nContent-Type: application/json; charset=UTF-8\r\n\r\n" +
+			`{"name":"` + name + `"}` + "\r\n--b\r\nContent-Type: application/json\r\n\r\n" +
+			content + "\r\n--b--\r\n"
+		return do(http.MethodPost, "/upload/storage/v1/b/tf-state/o?alt=json&uploadType=multipart"+query, "multipart/related; boundary=b", body)
+	}
+
+	if rr := do(http.MethodPost, "/storage/v1/b?project=test-project", "application/json", `{"name":"tf-state"}`); rr.Code != http.StatusOK {
+		t.Fatalf("create bucket: expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	// terraform init/plan/apply: the lock file is created only if it doesn't exist, and its generation is the lock ID
+	rr := upload("env/default.tflock", `{"ID":"first"}`, "&ifGenerationMatch=0")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("lock: expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	var lock storage.Object
+	if err := json.NewDecoder(rr.Body).Decode(&lock); err != nil || lock.Generation == 0 {
+		t.Fatalf("lock: expected the generation to be returned, got %+v (%v)", lock, err)
+	}
+
+	// A concurrent run can't take the lock, even with the same lock info
+	if rr := upload("env/default.tflock", `{"ID":"first"}`, "&ifGenerationMatch=0"); rr.Code != http.StatusPreconditionFailed {
+		t.Errorf("second lock: expected status %d, got %d", http.StatusPreconditionFailed, rr.Code)
+	}
+	// It reads the lock info to report who holds the lock
+	if rr := do(http.MethodGet, "/tf-state/env/default.tflock", "", ""); rr.Body.String() != `{"ID":"first"}` {
+		t.Errorf("lock info: unexpected body %q", rr.Body.String())
+	}
+
+	if rr := upload("env/default.tfstate", `{"version":4}`, ""); rr.Code != http.StatusOK {
+		t.Errorf("write state: expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	// Unlocking with a stale lock ID fails, unlocking with the lock's generation succeeds
+	stale := fmt.Sprintf("/storage/v1/b/tf-state/o/env%%2Fdefault.tflock?ifGenerationMatch=%d", lock.Generation-1)
+	if rr := do(http.MethodDelete, stale, "", ""); rr.Code != http.StatusPreconditionFailed {
+		t.Errorf("unlock with stale ID: expected status %d, got %d", http.StatusPreconditionFailed, rr.Code)
+	}
+	unlock := fmt.Sprintf("/storage/v1/b/tf-state/o/env%%2Fdefault.tflock?ifGenerationMatch=%d", lock.Generation)
+	if rr := do(http.MethodDelete, unlock, "", ""); rr.Code != http.StatusNoContent {
+		t.Errorf("unlock: expected status %d, got %d: %s", http.StatusNoContent, rr.Code, rr.Body.String())
+	}
+	if rr := do(http.MethodDelete, unlock, "", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("second unlock: expected status %d, got %d", http.StatusNotFound, rr.Code)
+	}
+
+	// Preconditions of resumable uploads are checked when they complete, so a run that loses the race doesn't overwrite the lock
+	rr = do(http.MethodPost, "/upload/storage/v1/b/tf-state/o?name=env/default.tflock&uploadType=resumable&ifGenerationMatch=0", "application/json", "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("start resumable lock: expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+	location := rr.Header().Get("Location")
+	if rr := upload("env/default.tflock", `{"ID":"other"}`, "&ifGenerationMatch=0"); rr.Code != http.StatusOK {
+		t.Fatalf("lock: expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+	if rr := do(http.MethodPut, location, "", `{"ID":"second"}`); rr.Code != http.StatusPreconditionFailed {
+		t.Errorf("complete resumable lock: expected status %d, got %d", http.StatusPreconditionFailed, rr.Code)
+	}
+}
+
+func TestServer_VirtualHostedStyle(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	cfg := &config.Config{VirtualHostDomains: []string{"storage.googleapis.com"}}
+	srv := New(cfg)
+
+	steps := []struct {
+		method         string
+		host           string
+		path           string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{http.MethodPut, "localhost:8080", "/vhost-bucket", "", http.StatusOK, ""},
+		{http.MethodPut, "vhost-bucket.storage.googleapis.com", "/dir/file.txt", "content", http.StatusOK, ""},
+		{http.MethodGet, "vhost-bucket.storage.googleapis.com:443", "/dir/file.txt", "", http.StatusOK, "content"},
+		{http.MethodGet, "localhost:8080", "/vhost-bucket/dir/file.txt", "", http.StatusOK, "content"},
+		{http.MethodGet, "storage.googleapis.com", "/vhost-bucket/dir/file.txt", "", http.StatusOK, "content"},
+		{http.MethodGet, "other-bucket.storage.googleapis.com", "/dir/file.txt", "", http.StatusNotFound, ""},
+	}
+
+	for _, step := range steps {
+		req := httptest.NewRequest(step.method, step.path, strings.NewReader(step.body))
+		req.Host = step.host
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, req)
+
+		if rr.Code != step.expectedStatus {
+			t.Errorf("%s %s%s: expected status %d, got %d: %s", step.method, step.host, step.path, step.expectedStatus, rr.Code, rr.Body.String())
+		}
+		if step.expectedBody != "" && rr.Body.String() != step.expectedBody {
+			t.Errorf("%s %s%s: expected body %q, got %q", step.method, step.host, step.path, step.expectedBody, rr.Body.String())
+		}
+	}
+}
+
+func TestServer_TLS(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	cfg := &config.Config{Port: "8443", TLSEnabled: true, VirtualHostDomains: []string{"storage.googleapis.com"}}
+	srv := New(cfg)
+
+	if srv.TLSConfig == nil || len(srv.TLSConfig.Certificates) != 1 {
+		t.Fatal("expected a generated TLS certificate")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/tls/ca.pem", nil)
+	rr := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+	if !strings.HasPrefix(rr.Body.String(), "-----BEGIN CERTIFICATE-----") {
+		t.Errorf("expected PEM CA certificate, got %q", rr.Body.String())
+	}
+
+	// selfLinks use the HTTPS base URL
+	req = httptest.NewRequest(http.MethodPost, "/storage/v1/b?project=test-project", strings.NewReader(`{"name":"tls-bucket"}`))
+	rr = httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rr, req)
+
+	var bucket storage.Bucket
+	if err := json.NewDecoder(rr.Body).Decode(&bucket); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if bucket.SelfLink != "https://localhost:8443/storage/v1/b/tls-bucket" {
+		t.Errorf("unexpected selfLink: %s", bucket.SelfLink)
+	}
+}
+
+func TestServer_S3Routing(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	cfg := &config.Config{S3Enabled: true}
+	srv := New(cfg)
+
+	sign := func(req *http.Request) *http.Request {
+		req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential=AKID/20240101/us-east-1/s3/aws4_request,SignedHeaders=host,Signature=abc")
+		return req
+	}
+
+	// Create a bucket via S3 and see it via the JSON API
+	rr := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rr, sign(httptest.NewRequest(http.MethodPut, "/shared-bucket", nil)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/storage/v1/b/shared-bucket", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	// Signed requests to the root list buckets instead of serving the dashboard
+	rr = httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rr, sign(httptest.NewRequest(http.MethodGet, "/", nil)))
+	if !strings.Contains(rr.Body.String(), "<ListAllMyBucketsResult") {
+		t.Errorf("expected S3 bucket listing, got %s", rr.Body.String())
+	}
+}
+
+func TestServer_FirestoreRoutes(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	cfg := &config.Config{}
+	srv := New(cfg)
+
+	const documents = "/v1/projects/test-project/databases/(default)/documents"
+	steps := []struct {
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+	}{
+		{http.MethodPost, documents + "/users?documentId=alice", `{"fields":{"name":{"stringValue":"Alice"}}}`, http.StatusOK},
+		{http.MethodPost, documents + "/users/alice/orders?documentId=o1", `{}`, http.StatusOK},
+		{http.MethodGet, documents + "/users/alice", "", http.StatusOK},
+		{http.MethodGet, documents + "/users/alice/orders", "", http.StatusOK},
+		{http.MethodPatch, documents + "/users/bob", `{"fields":{}}`, http.StatusOK},
+		{http.MethodPost, documents + ":runQuery", `{"structuredQuery":{"from":[{"collectionId":"users"}]}}`, http.StatusOK},
+		{http.MethodPost, documents + "/users/alice:runQuery", `{"structuredQuery":{"from":[{"collectionId":"orders"}]}}`, http.StatusOK},
+		{http.MethodDelete, documents + "/users/alice", "", http.StatusOK},
+		{http.MethodGet, documents + "/users/alice", "", http.StatusNotFound},
+	}
+
+	for _, step := range steps {
+		req := httptest.NewRequest(step.method, step.path, strings.NewReader(step.body))
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, req)
+
+		if rr.Code != step.expectedStatus {
+			t.Errorf("%s %s: expected status %d, got %d: %s", step.method, step.path, step.expectedStatus, rr.Code, rr.Body.String())
+		}
+	}
+}
+
+func TestServer_CloudRunRoutes(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	cfg := &config.Config{}
+	srv := New(cfg)
+
+	const location = "/v2/projects/test-project/locations/us-central1"
+	steps := []struct {
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+	}{
+		{http.MethodPost, location + "/services?serviceId=api", `{"template":{"containers":[{"image":"nginx"}]}}`, http.StatusOK},
+		{http.MethodGet, location + "/services", "", http.StatusOK},
+		{http.MethodGet, location + "/services/api", "", http.StatusOK},
+		{http.MethodPatch, location + "/services/api", `{"template":{"containers":[{"image":"nginx:2"}]}}`, http.StatusOK},
+		{http.MethodGet, location + "/services/api/revisions", "", http.StatusOK},
+		{http.MethodGet, location + "/operations", "", http.StatusOK},
+		{http.MethodDelete, location + "/services/api", "", http.StatusOK},
+		{http.MethodGet, location + "/services/api", "", http.StatusNotFound},
+		// Other /v2/ paths are still served by the Docker registry
+		{http.MethodGet, "/v2/", "", http.StatusOK},
+	}
+
+	for _, step := range steps {
+		req := httptest.NewRequest(step.method, step.path, strings.NewReader(step.body))
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, req)
+
+		if rr.Code != step.expectedStatus {
+			t.Errorf("%s %s: expected status %d, got %d: %s", step.method, step.path, step.expectedStatus, rr.Code, rr.Body.String())
+		}
+	}
+}
+
+func TestServer_MonitoringRoutes(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	srv := New(&config.Config{})
+
+	const project = "/v3/projects/test-project"
+	const point = `{"timeSeries":[{"metric":{"type":"custom.googleapis.com/requests"},"resource":{"type":"global"},` +
+		`"points":[{"interval":{"endTime":"2024-01-01T12:00:00Z"},"value":{"doubleValue":1.5}}]}]}`
+	steps := []struct {
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+	}{
+		{http.MethodPost, project + "/timeSeries", point, http.StatusOK},
+		{http.MethodGet, project + "/timeSeries?filter=metric.type%3D%22custom.googleapis.com%2Frequests%22&interval.endTime=2024-01-01T12:00:00Z", "", http.StatusOK},
+		{http.MethodGet, project + "/metricDescriptors", "", http.StatusOK},
+		{http.MethodGet, project + "/metricDescriptors/custom.googleapis.com/requests", "", http.StatusOK},
+		{http.MethodDelete, project + "/metricDescriptors/custom.googleapis.com/requests", "", http.StatusOK},
+		{http.MethodGet, project + "/metricDescriptors/custom.googleapis.com/requests", "", http.StatusNotFound},
+	}
+
+	for _, step := range steps {
+		req := httptest.NewRequest(step.method, step.path, strings.NewReader(step.body))
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, req)
+
+		if rr.Code != step.expectedStatus {
+			t.Errorf("%s %s: expected status %d, got %d: %s", step.method, step.path, step.expectedStatus, rr.Code, rr.Body.String())
+		}
+	}
+}
+
+func TestServer_LoggingRoutes(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	srv := New(&config.Config{})
+
+	const entries = `{"logName":"projects/test-project/logs/app","resource":{"type":"global"},"entries":[{"textPayload":"hello"}]}`
+	steps := []struct {
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+	}{
+		{http.MethodPost, "/v2/entries:write", entries, http.StatusOK},
+		{http.MethodPost, "/v2/entries:list", `{"resourceNames":["projects/test-project"],"filter":"textPayload:hello"}`, http.StatusOK},
+		{http.MethodGet, "/v2/projects/test-project/logs", "", http.StatusOK},
+		{http.MethodPost, "/v2/entries:list", `{}`, http.StatusBadRequest},
+	}
+
+	for _, step := range steps {
+		req := httptest.NewRequest(step.method, step.path, strings.NewReader(step.body))
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, req)
+
+		if rr.Code != step.expectedStatus {
+			t.Errorf("%s %s: expected status %d, got %d: %s", step.method, step.path, step.expectedStatus, rr.Code, rr.Body.String())
+		}
+	}
+}
+
+func TestServer_SchedulerRoutes(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	srv := New(&config.Config{})
+
+	const location = "/v1/projects/test-project/locations/europe-west1"
+	const job = `{"name":"projects/test-project/locations/europe-west1/jobs/cleanup","schedule":"0 * * * *","pubsubTarget":{"topicName":"projects/test-project/topics/cleanup"}}`
+	steps := []struct {
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+	}{
+		{http.MethodPost, location + "/jobs", job, http.StatusOK},
+		{http.MethodPost, location + "/jobs", job, http.StatusConflict},
+		{http.MethodGet, location + "/jobs", "", http.StatusOK},
+		{http.MethodPatch, location + "/jobs/cleanup?updateMask=description", `{"description":"hourly"}`, http.StatusOK},
+		{http.MethodPost, location + "/jobs/cleanup:pause", "", http.StatusOK},
+		{http.MethodPost, "/admin/scheduler/projects/test-project/locations/europe-west1/jobs/cleanup/run", "", http.StatusOK},
+		{http.MethodDelete, location + "/jobs/cleanup", "", http.StatusOK},
+		{http.MethodGet, location + "/jobs/cleanup", "", http.StatusNotFound},
+	}
+
+	for _, step := range steps {
+		req := httptest.NewRequest(step.method, step.path, strings.NewReader(step.body))
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, req)
+
+		if rr.Code != step.expectedStatus {
+			t.Errorf("%s %s: expected status %d, got %d: %s", step.method, step.path, step.expectedStatus, rr.Code, rr.Body.String())
+		}
+	}
+}
+
+func TestServer_EventarcTriggers(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	received := make(chan http.Header, 1)
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header
+	}))
+	defer endpoint.Close()
+
+	srv := New(&config.Config{})
+
+	trigger := `{"eventFilters":[{"attribute":"type","value":"google.cloud.storage.object.v1.finalized"},{"attribute":"bucket","value":"uploads"}],` +
+		`"destination":{"httpEndpoint":{"uri":"` + endpoint.URL + `"}}}`
+	steps := []struct {
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+	}{
+		{http.MethodPost, "/v1/projects/test-project/locations/europe-west1/triggers?triggerId=uploads", trigger, http.StatusOK},
+		{http.MethodGet, "/v1/projects/test-project/locations/europe-west1/triggers", "", http.StatusOK},
+		{http.MethodPost, "/storage/v1/b?project=test-project", `{"name":"uploads"}`, http.StatusOK},
+		{http.MethodPost, "/upload/storage/v1/b/uploads/o?uploadType=media&name=a.txt", "hello", http.StatusOK},
+	}
+	for _, step := range steps {
+		req := httptest.NewRequest(step.method, step.path, strings.NewReader(step.body))
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, req)
+
+		if rr.Code != step.expectedStatus {
+			t.Fatalf("%s %s: expected status %d, got %d: %s", step.method, step.path, step.expectedStatus, rr.Code, rr.Body.String())
+		}
+	}
+
+	select {
+	case header := <-received:
+		if header.Get("Ce-Type") != "google.cloud.storage.object.v1.finalized" || header.Get("Ce-Subject") != "objects/a.txt" {
+			t.Errorf("unexpected event headers: %v", header)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the event to be delivered")
+	}
+}
+
+func TestServer_MemorystoreInstances(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	srv := New(&config.Config{MemorystoreEndpoint: "localhost:6379"})
+
+	steps := []struct {
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+	}{
+		{http.MethodPost, "/v1/projects/test-project/locations/europe-west1/instances?instanceId=cache", `{"tier":"BASIC","memorySizeGb":1}`, http.StatusOK},
+		{http.MethodGet, "/v1/projects/test-project/locations/-/instances", "", http.StatusOK},
+		{http.MethodPatch, "/v1/projects/test-project/locations/europe-west1/instances/cache?updateMask=memorySizeGb", `{"memorySizeGb":2}`, http.StatusOK},
+		{http.MethodGet, "/v1/projects/test-project/locations/europe-west1/instances/cache", "", http.StatusOK},
+		{http.MethodDelete, "/v1/projects/test-project/locations/europe-west1/instances/cache", "", http.StatusOK},
+		{http.MethodGet, "/v1/projects/test-project/locations/europe-west1/instances/cache", "", http.StatusNotFound},
+	}
+	var body string
+	for _, step := range steps {
+		req := httptest.NewRequest(step.method, step.path, strings.NewReader(step.body))
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, req)
+
+		if rr.Code != step.expectedStatus {
+			t.Fatalf("%s %s: expected status %d, got %d: %s", step.method, step.path, step.expectedStatus, rr.Code, rr.Body.String())
+		}
+		if step.method == http.MethodGet && step.expectedStatus == http.StatusOK && strings.HasSuffix(step.path, "/cache") {
+			body = rr.Body.String()
+		}
+	}
+
+	if !strings.Contains(body, `"host":"localhost"`) || !strings.Contains(body, `"port":6379`) || !strings.Contains(body, `"memorySizeGb":2`) {
+		t.Errorf("expected the instance to report the configured endpoint, got %s", body)
+	}
+}
+
+func TestServer_GKEClusters(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	kubeconfig := filepath.Join(t.TempDir(), "kubeconfig")
+	if err := os.WriteFile(kubeconfig, []byte("clusters:\n- cluster:\n    server: https://127.0.0.1:44321\n  name: kind-kind\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	srv := New(&config.Config{GKEKubeconfig: kubeconfig})
+
+	steps := []struct {
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+	}{
+		{http.MethodPost, "/v1/projects/test-project/locations/europe-west1/clusters", `{"cluster":{"name":"platform","initialNodeCount":1}}`, http.StatusOK},
+		{http.MethodGet, "/v1/projects/test-project/locations/-/clusters", "", http.StatusOK},
+		{http.MethodGet, "/v1/projects/test-project/locations/europe-west1/clusters/platform", "", http.StatusOK},
+		{http.MethodGet, "/admin/gke/projects/test-project/locations/europe-west1/clusters/platform/kubeconfig", "", http.StatusOK},
+		{http.MethodDelete, "/v1/projects/test-project/locations/europe-west1/clusters/platform", "", http.StatusOK},
+		{http.MethodGet, "/v1/projects/test-project/locations/europe-west1/clusters/platform", "", http.StatusNotFound},
+	}
+	var kubeconfigBody string
+	for _, step := range steps {
+		req := httptest.NewRequest(step.method, step.path, strings.NewReader(step.body))
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, req)
+
+		if rr.Code != step.expectedStatus {
+			t.Fatalf("%s %s: expected status %d, got %d: %s", step.method, step.path, step.expectedStatus, rr.Code, rr.Body.String())
+		}
+		if strings.HasSuffix(step.path, "/kubeconfig") {
+			kubeconfigBody = rr.Body.String()
+		}
+	}
+
+	if !strings.Contains(kubeconfigBody, "server: https://127.0.0.1:44321\n") {
+		t.Errorf("expected the kubeconfig to point at the kind cluster, got:\n%s", kubeconfigBody)
+	}
+}
+
+func TestServer_ComputeNetworks(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	srv := New(&config.Config{})
+
+	steps := []struct {
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+	}{
+		{http.MethodPost, "/compute/v1/projects/test-project/global/networks", `{"name":"vpc","autoCreateSubnetworks":false}`, http.StatusOK},
+		{http.MethodPost, "/compute/v1/projects/test-project/regions/europe-west1/subnetworks", `{"name":"apps","network":"projects/test-project/global/networks/vpc","ipCidrRange":"10.0.0.0/24"}`, http.StatusOK},
+		{http.MethodGet, "/compute/v1/projects/test-project/regions/europe-west1/subnetworks/apps", "", http.StatusOK},
+		{http.MethodGet, "/compute/v1/projects/test-project/global/networks", "", http.StatusOK},
+		// Cloud SQL private IP must reference an existing network
+		{http.MethodPost, "/sql/v1beta4/projects/test-project/instances", `{"name":"private-db","databaseVersion":"POSTGRES_16","settings":{"tier":"db-f1-micro","ipConfiguration":{"privateNetwork":"projects/test-project/global/networks/missing"}}}`, http.StatusBadRequest},
+		{http.MethodPost, "/sql/v1beta4/projects/test-project/instances", `{"name":"private-db","databaseVersion":"POSTGRES_16","settings":{"tier":"db-f1-micro","ipConfiguration":{"privateNetwork":"projects/test-project/global/networks/vpc"}}}`, http.StatusOK},
+		{http.MethodDelete, "/compute/v1/projects/test-project/global/networks/vpc", "", http.StatusBadRequest},
+		{http.MethodDelete, "/compute/v1/projects/test-project/regions/europe-west1/subnetworks/apps", "", http.StatusOK},
+		{http.MethodDelete, "/compute/v1/projects/test-project/global/networks/vpc", "", http.StatusOK},
+		{http.MethodGet, "/compute/v1/projects/test-project/global/networks/vpc", "", http.StatusNotFound},
+	}
+	for _, step := range steps {
+		req := httptest.NewRequest(step.method, step.path, strings.NewReader(step.body))
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, req)
+
+		if rr.Code != step.expectedStatus {
+			t.Fatalf("%s %s: expected status %d, got %d: %s", step.method, step.path, step.expectedStatus, rr.Code, rr.Body.String())
+		}
+	}
+}
+
+func TestServer_BillingBudgets(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	srv := New(&config.Config{})
+	account := "/v1/billingAccounts/012345-6789AB-CDEF01"
+
+	req := httptest.NewRequest(http.MethodPost, account+"/budgets", strings.NewReader(`{"displayName":"monthly","amount":{"specifiedAmount":{"units":"100"}},"thresholdRules":[{"thresholdPercent":0.9}],"notificationsRule":{"pubsubTopic":"projects/test-project/topics/budgets"}}`))
+	rr := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var budget struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&budget); err != nil {
+		t.Fatalf("failed to decode budget: %v", err)
+	}
+
+	steps := []struct {
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+	}{
+		{http.MethodGet, account + "/budgets", "", http.StatusOK},
+		{http.MethodGet, "/v1/" + budget.Name, "", http.StatusOK},
+		{http.MethodPost, "/admin/billing/" + budget.Name + "/alert", `{"costAmount":95}`, http.StatusOK},
+		{http.MethodPost, "/admin/billing/" + account[len("/v1/"):] + "/budgets/missing/alert", `{"costAmount":95}`, http.StatusNotFound},
+	}
+	for _, step := range steps {
+		req := httptest.NewRequest(step.method, step.path, strings.NewReader(step.body))
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, req)
+
+		if rr.Code != step.expectedStatus {
+			t.Fatalf("%s %s: expected status %d, got %d: %s", step.method, step.path, step.expectedStatus, rr.Code, rr.Body.String())
+		}
+	}
+}
+
+func TestServer_OrgPolicies(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	srv := New(&config.Config{})
+	policies := "/v2/projects/mock-project/policies"
+
+	steps := []struct {
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+	}{
+		{http.MethodPost, "/storage/v1/b?project=test-project", `{"name":"org-bucket"}`, http.StatusOK},
+		{http.MethodPost, policies, `{"name":"projects/mock-project/policies/sql.restrictPublicIp","spec":{"rules":[{"enforce":true}]}}`, http.StatusOK},
+		{http.MethodPost, policies, `{"name":"projects/mock-project/policies/storage.publicAccessPrevention","spec":{"rules":[{"enforce":true}]}}`, http.StatusOK},
+		{http.MethodGet, policies, "", http.StatusOK},
+		{http.MethodGet, policies + "/sql.restrictPublicIp", "", http.StatusOK},
+		// Requests violating the policies fail like in a project with these constraints
+		{http.MethodPost, "/sql/v1beta4/projects/test-project/instances", `{"name":"org-db"}`, http.StatusBadRequest},
+		{http.MethodPut, "/storage/v1/b/org-bucket/iam", `{"bindings":[{"role":"roles/storage.objectViewer","members":["allUsers"]}]}`, http.StatusPreconditionFailed},
+		{http.MethodPatch, policies + "/sql.restrictPublicIp?updateMask=spec", `{"spec":{"reset":true}}`, http.StatusOK},
+		{http.MethodPost, "/sql/v1beta4/projects/test-project/instances", `{"name":"org-db"}`, http.StatusOK},
+		{http.MethodDelete, policies + "/storage.publicAccessPrevention", "", http.StatusOK},
+		{http.MethodPut, "/storage/v1/b/org-bucket/iam", `{"bindings":[{"role":"roles/storage.objectViewer","members":["allUsers"]}]}`, http.StatusOK},
+		{http.MethodGet, policies + "/storage.publicAccessPrevention", "", http.StatusNotFound},
+	}
+	for _, step := range steps {
+		req := httptest.NewRequest(step.method, step.path, strings.NewReader(step.body))
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, req)
+
+		if rr.Code != step.expectedStatus {
+			t.Fatalf("%s %s: expected status %d, got %d: %s", step.method, step.path, step.expectedStatus, rr.Code, rr.Body.String())
+		}
+	}
+}
+
+func TestServer_AuditLog(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	srv := New(&config.Config{AuditLogFile: path})
+
+	steps := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{http.MethodPost, "/storage/v1/b?project=test-project", `{"name":"audited"}`},
+		{http.MethodGet, "/storage/v1/b/audited", ""}, // reads are not audited
+		{http.MethodPost, "/storage/v1/b?project=test-project", `{"name":"audited"}`},
+		{http.MethodDelete, "/storage/v1/b/audited", ""},
+	}
+	for _, step := range steps {
+		req := httptest.NewRequest(step.method, step.path, strings.NewReader(step.body))
+		srv.Handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read audit log: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+
+	expected := []struct {
+		methodName string
+		severity   string
+	}{
+		{"storage.buckets.create", "NOTICE"},
+		{"storage.buckets.create", "ERROR"},
+		{"storage.buckets.delete", "NOTICE"},
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("expected %d audit log entries, got %d: %s", len(expected), len(lines), data)
+	}
+	for i, want := range expected {
+		var entry struct {
+			Severity     string `json:"severity"`
+			ProtoPayload struct {
+				MethodName   string `json:"methodName"`
+				ResourceName string `json:"resourceName"`
+				Status       struct {
+					Message string `json:"message"`
+				} `json:"status"`
+			} `json:"protoPayload"`
+		}
+		if err := json.Unmarshal([]byte(lines[i]), &entry); err != nil {
+			t.Fatalf("failed to parse entry %d: %v", i, err)
+		}
+		if entry.ProtoPayload.MethodName != want.methodName || entry.Severity != want.severity {
+			t.Errorf("entry %d: got %s (%s), want %s (%s)", i, entry.ProtoPayload.MethodName, entry.Severity, want.methodName, want.severity)
+		}
+		if entry.ProtoPayload.ResourceName != "projects/_/buckets/audited" {
+			t.Errorf("entry %d: resourceName = %q", i, entry.ProtoPayload.ResourceName)
+		}
+		if want.severity == "ERROR" && entry.ProtoPayload.Status.Message == "" {
+			t.Errorf("entry %d: expected the error message in the status", i)
+		}
+	}
+}
+
+func TestServer_Namespaces(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	cfg := &config.Config{}
+	srv := New(cfg)
+
+	steps := []struct {
+		name           string
+		method         string
+		path           string
+		namespace      string
+		body           string
+		expectedStatus int
+		expectedBody   string
+		unexpectedBody string
+	}{
+		{"create bucket in namespace via header", http.MethodPost, "/storage/v1/b", "ci-1", `{"name":"shared-name"}`, http.StatusOK, "", ""},
+		{"create bucket in namespace via prefix", http.MethodPost, "/_ns/ci-2/storage/v1/b", "", `{"name":"shared-name"}`, http.StatusOK, "", ""},
+		{"create bucket twice in namespace", http.MethodPost, "/_ns/ci-1/storage/v1/b", "", `{"name":"shared-name"}`, http.StatusConflict, "", ""},
+		{"default namespace is untouched", http.MethodGet, "/storage/v1/b", "", "", http.StatusOK, "", "shared-name"},
+		{"list buckets of namespace", http.MethodGet, "/storage/v1/b", "ci-2", "", http.StatusOK, "shared-name", ""},
+		{"invalid namespace", http.MethodGet, "/storage/v1/b", "../etc", "", http.StatusBadRequest, "Invalid namespace", ""},
+		{"list namespaces", http.MethodGet, "/admin/namespaces", "", "", http.StatusOK, `"name":"ci-2"`, ""},
+		{"delete namespace", http.MethodDelete, "/admin/namespaces/ci-1", "", "", http.StatusNoContent, "", ""},
+		{"delete missing namespace", http.MethodDelete, "/admin/namespaces/ci-1", "", "", http.StatusNotFound, "", ""},
+		{"deleted namespace starts empty", http.MethodGet, "/_ns/ci-1/storage/v1/b", "", "", http.StatusOK, "", "shared-name"},
+	}
+
+	for _, step := range steps {
+		req := httptest.NewRequest(step.method, step.path, strings.NewReader(step.body))
+		if step.namespace != "" {
+			req.Header.Set("X-Mock-Namespace", step.namespace)
+		}
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, req)
+
+		if rr.Code != step.expectedStatus {
+			t.Errorf("%s: expected status %d, got %d: %s", step.name, step.expectedStatus, rr.Code, rr.Body.String())
+		}
+		if step.expectedBody != "" && !strings.Contains(rr.Body.String(), step.expectedBody) {
+			t.Errorf("%s: expected body to contain %q, got %s", step.name, step.expectedBody, rr.Body.String())
+		}
+		if step.unexpectedBody != "" && strings.Contains(rr.Body.String(), step.unexpectedBody) {
+			t.Errorf("%s: expected body not to contain %q, got %s", step.name, step.unexpectedBody, rr.Body.String())
+		}
+	}
+}
+
+func TestServer_NamespaceDashboard(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	srv := New(&config.Config{})
+
+	// The dashboard sends its requests with the header of the namespace it shows
+	steps := []struct {
+		name           string
+		method         string
+		path           string
+		namespace      string
+		body           string
+		expectedStatus int
+		expectedBody   string
+		unexpectedBody string
+	}{
+		{"create bucket in namespace", http.MethodPost, "/storage/v1/b", "ci-1", `{"name":"ci-bucket"}`, http.StatusOK, "", ""},
+		{"upload object in namespace", http.MethodPost, "/upload/storage/v1/b/ci-bucket/o?uploadType=media&name=a.txt", "ci-1", "a", http.StatusOK, "", ""},
+		{"create bucket in default namespace", http.MethodPost, "/storage/v1/b", "", `{"name":"default-bucket"}`, http.StatusOK, "", ""},
+		{"buckets of namespace", http.MethodGet, "/ui/buckets", "ci-1", "", http.StatusOK, "ci-bucket", "default-bucket"},
+		{"buckets of default namespace", http.MethodGet, "/ui/buckets", "", "", http.StatusOK, "default-bucket", "ci-bucket"},
+		{"download links keep the namespace", http.MethodGet, "/ui/buckets/ci-bucket/objects", "ci-1", "", http.StatusOK, `href="/_ns/ci-1/download/storage/v1/b/ci-bucket/o/a.txt?alt=media"`, ""},
+		{"requests of namespace", http.MethodGet, "/ui/logs", "ci-1", "", http.StatusOK, "/upload/storage/v1/b/ci-bucket/o", "default-bucket"},
+		{"requests of default namespace", http.MethodGet, "/ui/logs", "", "", http.StatusOK, "/storage/v1/b", "ci-bucket"},
+		{"requests of all namespaces", http.MethodGet, "/ui/logs?namespaces=all", "", "", http.StatusOK, "ci-1", ""},
+		{"clear requests of namespace", http.MethodDelete, "/ui/logs", "ci-1", "", http.StatusOK, "", "ci-bucket"},
+		{"other requests are kept", http.MethodGet, "/ui/logs?namespaces=all", "", "", http.StatusOK, "/storage/v1/b", "ci-bucket"},
+	}
+
+	for _, step := range steps {
+		req := httptest.NewRequest(step.method, step.path, strings.NewReader(step.body))
+		if step.namespace != "" {
+			req.Header.Set("X-Mock-Namespace", step.namespace)
+		}
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, req)
+
+		if rr.Code != step.expectedStatus {
+			t.Errorf("%s: expected status %d, got %d: %s", step.name, step.expectedStatus, rr.Code, rr.Body.String())
+		}
+		if step.expectedBody != "" && !strings.Contains(rr.Body.String(), step.expectedBody) {
+			t.Errorf("%s: expected body to contain %q, got %s", step.name, step.expectedBody, rr.Body.String())
+		}
+		if step.unexpectedBody != "" && strings.Contains(rr.Body.String(), step.unexpectedBody) {
+			t.Errorf("%s: expected body not to contain %q, got %s", step.name, step.unexpectedBody, rr.Body.String())
+		}
+	}
+}
+
+func TestServer_NamespaceResumableUpload(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	cfg := &config.Config{}
+	srv := New(cfg)
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := serve(http.MethodPost, "/_ns/ci/storage/v1/b", `{"name":"uploads"}`); rr.Code != http.StatusOK {
+		t.Fatalf("failed to create bucket: %d %s", rr.Code, rr.Body.String())
+	}
+
+	rr := serve(http.MethodPost, "/_ns/ci/upload/storage/v1/b/uploads/o?uploadType=resumable&name=file.txt", "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("failed to start resumable upload: %d %s", rr.Code, rr.Body.String())
+	}
+
+	// The upload URL stays in the namespace
+	location := rr.Header().Get("Location")
+	if !strings.Contains(location, "/_ns/ci/upload/storage/v1/b/uploads/o?") {
+		t.Fatalf("expected upload URL in the namespace, got %q", location)
+	}
+
+	if rr := serve(http.MethodPut, location, "hello"); rr.Code != http.StatusOK {
+		t.Fatalf("failed to upload content: %d %s", rr.Code, rr.Body.String())
+	}
+	if rr := serve(http.MethodGet, "/_ns/ci/storage/v1/b/uploads/o/file.txt", ""); rr.Code != http.StatusOK {
+		t.Errorf("expected object in the namespace, got %d %s", rr.Code, rr.Body.String())
+	}
+	if rr := serve(http.MethodGet, "/storage/v1/b/uploads/o/file.txt", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("expected object not to exist in the default namespace, got %d", rr.Code)
+	}
+}
+
+func TestServer_ShutdownDrainsResumableUploads(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	cfg := &config.Config{}
+	srv := New(cfg)
+
+	serve := func(method, path, contentRange, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if contentRange != "" {
+			req.Header.Set("Content-Range", contentRange)
+		}
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := serve(http.MethodPost, "/storage/v1/b", "", `{"name":"uploads"}`); rr.Code != http.StatusOK {
+		t.Fatalf("failed to create bucket: %d %s", rr.Code, rr.Body.String())
+	}
+	rr := serve(http.MethodPost, "/upload/storage/v1/b/uploads/o?uploadType=resumable&name=big.bin", "", "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("failed to start resumable upload: %d %s", rr.Code, rr.Body.String())
+	}
+	location := rr.Header().Get("Location")
+	if rr := serve(http.MethodPut, location, "bytes 0-4/*", "hello"); rr.Code != http.StatusPermanentRedirect {
+		t.Fatalf("failed to upload first chunk: %d %s", rr.Code, rr.Body.String())
+	}
+
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- srv.Shutdown(context.Background()) }()
+
+	// The server keeps serving the upload, but isn't ready for new clients
+	time.Sleep(100 * time.Millisecond)
+	select {
+	case err := <-shutdown:
+		t.Fatalf("expected shutdown to wait for the upload, got %v", err)
+	default:
+	}
+	if rr := serve(http.MethodGet, "/ready", "", ""); rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected /ready to fail while draining, got %d", rr.Code)
+	}
+
+	if rr := serve(http.MethodPut, location, "bytes 5-10/11", " world"); rr.Code != http.StatusOK {
+		t.Fatalf("failed to upload final chunk: %d %s", rr.Code, rr.Body.String())
+	}
+
+	select {
+	case err := <-shutdown:
+		if err != nil {
+			t.Errorf("Shutdown() error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected shutdown to finish after the upload")
+	}
+
+	rr = serve(http.MethodGet, "/admin/transfers", "", "")
+	if !strings.Contains(rr.Body.String(), `"completed":3`) || !strings.Contains(rr.Body.String(), `"aborted":0`) {
+		t.Errorf("expected 3 completed transfers, got %s", rr.Body.String())
+	}
+}
+
+func TestServer_ShutdownTimeoutAbortsTransfers(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	cfg := &config.Config{}
+	srv := New(cfg)
+
+	// A resumable upload is waiting for its next chunk
+	srv.transfers.TouchSession("upload-1")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err == nil {
+		t.Error("expected Shutdown() to report the aborted session")
+	}
+}
+
+func TestServer_ArchiveOutlastsWriteTimeout(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	srv := New(&config.Config{})
+	defer srv.Close()
+
+	// The archive is bigger than the socket buffers, so the server writes it while the client reads
+	content := strings.Repeat("x", 32<<20)
+	for _, step := range []struct{ path, body string }{
+		{"/storage/v1/b", `{"name":"fixtures"}`},
+		{"/upload/storage/v1/b/fixtures/o?uploadType=media&name=big.bin", content},
+	} {
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, step.path, strings.NewReader(step.body)))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("POST %s: expected status %d, got %d: %s", step.path, http.StatusOK, rr.Code, rr.Body.String())
+		}
+	}
+
+	ts := httptest.NewUnstartedServer(srv.Handler)
+	ts.Config.WriteTimeout = 200 * time.Millisecond
+	ts.Start()
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/admin/storage/buckets/fixtures/archive?format=tar")
+	if err != nil {
+		t.Fatalf("failed to download the archive: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// Read the archive only after the write timeout has passed
+	time.Sleep(500 * time.Millisecond)
+	header, err := tar.NewReader(resp.Body).Next()
+	if err != nil {
+		t.Fatalf("failed to read the archive: %v", err)
+	}
+	if n, err := io.Copy(io.Discard, resp.Body); err != nil || n < int64(len(content)) {
+		t.Errorf("expected the whole archive with %s, got %d bytes: %v", header.Name, n, err)
+	}
+}
+
+func TestServer_MediaTransfersOutlastTimeouts(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	srv := New(&config.Config{})
+	defer srv.Close()
+
+	rr := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/storage/v1/b", strings.NewReader(`{"name":"media"}`)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("failed to create bucket: %d %s", rr.Code, rr.Body.String())
+	}
+
+	ts := httptest.NewUnstartedServer(srv.Handler)
+	ts.Config.ReadTimeout = 200 * time.Millisecond
+	ts.Config.WriteTimeout = 200 * time.Millisecond
+	ts.Start()
+	defer ts.Close()
+
+	// The client pauses mid upload for longer than the read timeout
+	content := strings.Repeat("x", 32<<20)
+	body, pw := io.Pipe()
+	go func() {
+		io.WriteString(pw, content[:len(content)/2])
+		time.Sleep(500 * time.Millisecond)
+		io.WriteString(pw, content[len(content)/2:])
+		pw.Close()
+	}()
+	resp, err := http.Post(ts.URL+"/upload/storage/v1/b/media/o?uploadType=media&name=big.bin", "application/octet-stream", body)
+	if err != nil {
+		t.Fatalf("failed to upload: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected upload status %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	// And reads the download only after the write timeout has passed
+	resp, err = http.Get(ts.URL + "/storage/v1/b/media/o/big.bin?alt=media")
+	if err != nil {
+		t.Fatalf("failed to download: %v", err)
+	}
+	defer resp.Body.Close()
+	time.Sleep(500 * time.Millisecond)
+	if n, err := io.Copy(io.Discard, resp.Body); err != nil || n != int64(len(content)) {
+		t.Errorf("expected %d bytes, got %d: %v", len(content), n, err)
+	}
+}
+
+func TestServer_ReadOnly(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	srv := New(&config.Config{ReadOnly: "success"})
+
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return rr
+	}
+
+	// Mutating requests get a synthetic success echoing their body, without creating anything
+	rr := serve(http.MethodPost, "/storage/v1/b?project=test-project", `{"name": "planned-bucket"}`)
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "planned-bucket") {
+		t.Errorf("expected synthetic success echoing the body, got %d - %s", rr.Code, rr.Body.String())
+	}
+	if rr.Header().Get("X-Mock-Read-Only") != "success" {
+		t.Errorf("expected X-Mock-Read-Only header, got %q", rr.Header().Get("X-Mock-Read-Only"))
+	}
+	if rr := serve(http.MethodGet, "/storage/v1/b/planned-bucket", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("expected the bucket not to be created, got %d", rr.Code)
+	}
+	if rr := serve(http.MethodDelete, "/storage/v1/b/planned-bucket", ""); rr.Code != http.StatusNoContent {
+		t.Errorf("expected synthetic delete to return 204, got %d", rr.Code)
+	}
+
+	// Read-only POSTs are still served
+	if rr := serve(http.MethodPost, "/v2/entries:list", `{}`); rr.Header().Get("X-Mock-Read-Only") != "" {
+		t.Errorf("expected entries:list to be served, got %d - %s", rr.Code, rr.Body.String())
+	}
+
+	// Reject mode answers mutating requests with 403
+	if rr := serve(http.MethodPut, "/admin/readonly", `{"mode": "reject"}`); rr.Code != http.StatusOK {
+		t.Fatalf("set read-only mode failed: %d - %s", rr.Code, rr.Body.String())
+	}
+	if rr := serve(http.MethodPost, "/storage/v1/b?project=test-project", `{"name": "planned-bucket"}`); rr.Code != http.StatusForbidden {
+		t.Errorf("expected status 403 in reject mode, got %d", rr.Code)
+	}
+
+	// Turning read-only mode off applies mutating requests again
+	if rr := serve(http.MethodPut, "/admin/readonly", `{"mode": "off"}`); rr.Code != http.StatusOK {
+		t.Fatalf("turn off read-only mode failed: %d - %s", rr.Code, rr.Body.String())
+	}
+	if rr := serve(http.MethodPost, "/storage/v1/b?project=test-project", `{"name": "planned-bucket"}`); rr.Code != http.StatusOK {
+		t.Fatalf("create bucket failed: %d - %s", rr.Code, rr.Body.String())
+	}
+	if rr := serve(http.MethodGet, "/storage/v1/b/planned-bucket", ""); rr.Code != http.StatusOK {
+		t.Errorf("expected the bucket to be created, got %d", rr.Code)
+	}
+	if rr := serve(http.MethodPut, "/admin/readonly", `{"mode": "sometimes"}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for an invalid mode, got %d", rr.Code)
+	}
+}
+
+func TestServer_SchemaValidation(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	// The mock's own discovery documents stand in for Google's, which aren't available offline
+	dir := t.TempDir()
+	for _, api := range schema.APIs {
+		data, err := json.Marshal(discovery.Document(api[0], api[1], "http://localhost:8080/"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, api[0]+"."+api[1]+".json"), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	srv := New(&config.Config{SchemaValidation: "fail", SchemaDir: dir})
+
+	steps := []struct {
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+	}{
+		{http.MethodPost, "/storage/v1/b?project=test-project", `{"name":"checked","labels":{"env":"test"},"versioning":{"enabled":true}}`, http.StatusOK},
+		{http.MethodGet, "/storage/v1/b/checked", "", http.StatusOK},
+		{http.MethodGet, "/storage/v1/b?project=test-project", "", http.StatusOK},
+		{http.MethodPost, "/upload/storage/v1/b/checked/o?uploadType=media&name=dir/file.txt", "hello", http.StatusOK},
+		{http.MethodGet, "/storage/v1/b/checked/o/dir%2Ffile.txt", "", http.StatusOK},
+		{http.MethodGet, "/storage/v1/b/checked/o?prefix=dir/", "", http.StatusOK},
+		{http.MethodPost, "/sql/v1beta4/projects/test-project/instances", `{"name":"checked-db","databaseVersion":"POSTGRES_15"}`, http.StatusOK},
+		{http.MethodGet, "/sql/v1beta4/projects/test-project/instances/checked-db", "", http.StatusOK},
+		{http.MethodGet, "/sql/v1beta4/projects/test-project/instances", "", http.StatusOK},
+		// Error responses aren't checked
+		{http.MethodGet, "/storage/v1/b/missing", "", http.StatusNotFound},
+	}
+	for _, step := range steps {
+		req := httptest.NewRequest(step.method, step.path, strings.NewReader(step.body))
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, req)
+
+		if rr.Code != step.expectedStatus {
+			t.Fatalf("%s %s: expected status %d, got %d: %s", step.method, step.path, step.expectedStatus, rr.Code, rr.Body.String())
+		}
+	}
+
+	// A field the document doesn't know fails the request
+	doc := discovery.Document("storage", "v1", "http://localhost:8080/")
+	delete(doc.Schemas["Bucket"].Properties, "labels")
+	data, _ := json.Marshal(doc)
+	if err := os.WriteFile(filepath.Join(dir, "storage.v1.json"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	srv = New(&config.Config{SchemaValidation: "fail", SchemaDir: dir})
+	for _, step := range steps[:2] {
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, httptest.NewRequest(step.method, step.path, strings.NewReader(step.body)))
+		if rr.Code != http.StatusInternalServerError || !strings.Contains(rr.Body.String(), "Bucket.labels: unknown field") {
+			t.Errorf("%s %s: expected the divergence, got %d: %s", step.method, step.path, rr.Code, rr.Body.String())
+		}
+	}
+}
+
+func TestServer_ResponseTemplates(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	path := filepath.Join(t.TempDir(), "templates.json")
+	if err := os.WriteFile(path, []byte(`[{"method": "GET", "path": "/storage/v1/b/*", "template": "{{ json (set . \"satisfiesPZS\" false) }}"}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	srv := New(&config.Config{TemplatesFile: path})
+
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return rr
+	}
+
+	if rr := serve(http.MethodPost, "/storage/v1/b?project=test-project", `{"name": "foo"}`); rr.Code != http.StatusOK {
+		t.Fatalf("create bucket failed: %d - %s", rr.Code, rr.Body.String())
+	}
+
+	// The template of the file adds a field to the real response
+	rr := serve(http.MethodGet, "/storage/v1/b/foo", "")
+	var bucket map[string]any
+	if err := json.NewDecoder(rr.Body).Decode(&bucket); err != nil {
+		t.Fatalf("failed to decode bucket: %v", err)
+	}
+	if bucket["satisfiesPZS"] != false || bucket["name"] != "foo" {
+		t.Errorf("expected the templated bucket, got %v", bucket)
+	}
+	if rr.Header().Get("X-Mock-Template") != "1" {
+		t.Errorf("expected X-Mock-Template 1, got %q", rr.Header().Get("X-Mock-Template"))
+	}
+
+	// Error responses are JSON too, so templates see them
+	if rr := serve(http.MethodGet, "/storage/v1/b/missing", ""); rr.Code != http.StatusNotFound || !strings.Contains(rr.Body.String(), "satisfiesPZS") {
+		t.Errorf("expected the templated 404, got %d - %s", rr.Code, rr.Body.String())
+	}
+
+	// Templates that don't produce JSON fail loudly
+	if rr := serve(http.MethodPost, "/admin/templates", `{"path": "/storage/v1/b/foo/o", "template": "{{ .kind }}"}`); rr.Code != http.StatusOK {
+		t.Fatalf("create template failed: %d - %s", rr.Code, rr.Body.String())
+	}
+	if rr := serve(http.MethodGet, "/storage/v1/b/foo/o", ""); rr.Code != http.StatusInternalServerError {
+		t.Errorf("expected status 500 for a broken template, got %d - %s", rr.Code, rr.Body.String())
+	}
+	if rr := serve(http.MethodPost, "/admin/templates", `{"path": "/admin/requests", "template": "{{ json . }}"}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected templating the admin API to be rejected, got %d", rr.Code)
+	}
+	if rr := serve(http.MethodGet, "/admin/templates", ""); !strings.Contains(rr.Body.String(), `"hits":2`) {
+		t.Errorf("expected the templates to list their hits, got %s", rr.Body.String())
+	}
+
+	if rr := serve(http.MethodDelete, "/admin/templates/2", ""); rr.Code != http.StatusNoContent {
+		t.Fatalf("delete template failed: %d", rr.Code)
+	}
+	if rr := serve(http.MethodGet, "/storage/v1/b/foo/o", ""); rr.Code != http.StatusOK {
+		t.Errorf("expected the real response after deleting the template, got %d", rr.Code)
+	}
+	if rr := serve(http.MethodDelete, "/admin/templates", ""); rr.Code != http.StatusNoContent {
+		t.Fatalf("clear templates failed: %d", rr.Code)
+	}
+	if rr := serve(http.MethodGet, "/storage/v1/b/foo", ""); strings.Contains(rr.Body.String(), "satisfiesPZS") {
+		t.Errorf("expected the real bucket after clearing the templates, got %s", rr.Body.String())
+	}
+}
+
+func TestServer_ResponseOverrides(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	srv := New(&config.Config{})
+
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return rr
+	}
+
+	if rr := serve(http.MethodPost, "/storage/v1/b?project=test-project", `{"name": "foo"}`); rr.Code != http.StatusOK {
+		t.Fatalf("create bucket failed: %d - %s", rr.Code, rr.Body.String())
+	}
+
+	// Force a payload the store can't produce for the next request only
+	rr := serve(http.MethodPost, "/admin/overrides", `{"method": "GET", "path": "/storage/v1/b/foo", "body": {"kind": "storage#bucket", "name": "foo", "location": ""}, "times": 1}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("create override failed: %d - %s", rr.Code, rr.Body.String())
+	}
+	var rule struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&rule); err != nil || rule.ID == "" {
+		t.Fatalf("expected the override with its ID, got %v", err)
+	}
+
+	rr = serve(http.MethodGet, "/storage/v1/b/foo", "")
+	if rr.Body.String() != `{"kind": "storage#bucket", "name": "foo", "location": ""}` {
+		t.Errorf("expected the overridden body, got %d - %s", rr.Code, rr.Body.String())
+	}
+	if rr.Header().Get("X-Mock-Override") != rule.ID {
+		t.Errorf("expected X-Mock-Override %q, got %q", rule.ID, rr.Header().Get("X-Mock-Override"))
+	}
+	if rr := serve(http.MethodGet, "/storage/v1/b/foo", ""); rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "US") {
+		t.Errorf("expected the real bucket once the override is used up, got %d - %s", rr.Code, rr.Body.String())
+	}
+
+	// Overrides without a limit answer until they're deleted, and never touch the admin API
+	if rr := serve(http.MethodPost, "/admin/overrides", `{"path": "/storage/v1/b/*/o", "status": 503, "body": {"error": {"code": 503}}}`); rr.Code != http.StatusOK {
+		t.Fatalf("create override failed: %d - %s", rr.Code, rr.Body.String())
+	}
+	for range 2 {
+		if rr := serve(http.MethodGet, "/storage/v1/b/foo/o", ""); rr.Code != http.StatusServiceUnavailable {
+			t.Errorf("expected the overridden status 503, got %d", rr.Code)
+		}
+	}
+	if rr := serve(http.MethodPost, "/admin/overrides", `{"path": "/admin/reset", "status": 500}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected overriding the admin API to be rejected, got %d", rr.Code)
+	}
+	if rr := serve(http.MethodGet, "/admin/overrides", ""); !strings.Contains(rr.Body.String(), `"hits":2`) {
+		t.Errorf("expected the override to list its hits, got %s", rr.Body.String())
+	}
+
+	if rr := serve(http.MethodDelete, "/admin/overrides", ""); rr.Code != http.StatusNoContent {
+		t.Fatalf("clear overrides failed: %d", rr.Code)
+	}
+	if rr := serve(http.MethodGet, "/storage/v1/b/foo/o", ""); rr.Code != http.StatusOK {
+		t.Errorf("expected the real response after clearing the overrides, got %d", rr.Code)
+	}
+	if rr := serve(http.MethodDelete, "/admin/overrides/"+rule.ID, ""); rr.Code != http.StatusNotFound {
+		t.Errorf("expected deleting a used up override to return 404, got %d", rr.Code)
+	}
+}
+
+func TestServer_ResponseOverridesPerNamespace(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	srv := New(&config.Config{})
+	defer srv.Close()
+
+	serve := func(method, target, namespace, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("X-Mock-Namespace", namespace)
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// An override created in one namespace leaves the requests of other namespaces alone
+	if rr := serve(http.MethodPost, "/admin/overrides", "ci-1", `{"path": "/storage/v1/b", "status": 503, "body": {"error": {"code": 503}}}`); rr.Code != http.StatusOK {
+		t.Fatalf("create override failed: %d - %s", rr.Code, rr.Body.String())
+	}
+	if rr := serve(http.MethodGet, "/storage/v1/b?project=test-project", "ci-1", ""); rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected the overridden status 503 in ci-1, got %d", rr.Code)
+	}
+	if rr := serve(http.MethodGet, "/storage/v1/b?project=test-project", "ci-2", ""); rr.Code != http.StatusOK {
+		t.Errorf("expected the real response in ci-2, got %d - %s", rr.Code, rr.Body.String())
+	}
+	if rr := serve(http.MethodGet, "/admin/overrides", "ci-2", ""); strings.Contains(rr.Body.String(), "/storage/v1/b") {
+		t.Errorf("expected ci-2 to list no overrides, got %s", rr.Body.String())
+	}
+}
@@ -0,0 +1,300 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/katharinasick/gcp-api-mock/internal/config"
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+)
+
+// stressOperation is a request a stress test worker sends, with the status codes it may be answered with
+// while other workers change the same resources.
+type stressOperation struct {
+	name string
+	// send sends the request and returns the status code and body of the response.
+	send    func(c *stressClient, rng *rand.Rand) (int, string)
+	allowed []int
+}
+
+// stressClient sends requests to a server under test.
+type stressClient struct {
+	client *http.Client
+	url    string
+}
+
+// do sends a request and returns the status code and body of the response, or 0 and the error.
+func (c *stressClient) do(method, path, contentType, body string) (int, string) {
+	status, respBody, _ := c.doWithHeader(method, path, contentType, body, nil)
+	return status, respBody
+}
+
+// doWithHeader sends a request with additional headers and returns the status code, body and headers
+// of the response, or 0 and the error.
+func (c *stressClient) doWithHeader(method, path, contentType, body string, header http.Header) (int, string, http.Header) {
+	req, err := http.NewRequest(method, c.url+path, strings.NewReader(body))
+	if err != nil {
+		return 0, err.Error(), nil
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err.Error(), nil
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err.Error(), nil
+	}
+	return resp.StatusCode, string(respBody), resp.Header
+}
+
+const (
+	stressBuckets   = 4
+	stressObjects   = 16
+	stressInstances = 4
+)
+
+// stressOperations are the requests of the stress test. They work on a few shared buckets, objects and
+// instances, so workers contend for the same resources.
+var stressOperations = []stressOperation{
+	{
+		name: "upload object",
+		send: func(c *stressClient, rng *rand.Rand) (int, string) {
+			content := strings.Repeat("x", rng.IntN(4096))
+			return c.do(http.MethodPost, fmt.Sprintf("/upload/storage/v1/b/stress-%d/o?uploadType=media&name=object-%d",
+				rng.IntN(stressBuckets), rng.IntN(stressObjects)), "text/plain", content)
+		},
+		allowed: []int{http.StatusOK},
+	},
+	{
+		name: "resumable upload",
+		send: func(c *stressClient, rng *rand.Rand) (int, string) {
+			status, body, header := c.doWithHeader(http.MethodPost, fmt.Sprintf("/upload/storage/v1/b/stress-%d/o?uploadType=resumable&name=object-%d",
+				rng.IntN(stressBuckets), rng.IntN(stressObjects)), "application/json", `{"contentType": "text/plain"}`, nil)
+			if status != http.StatusOK {
+				return status, body
+			}
+			location, err := url.Parse(header.Get("Location"))
+			if err != nil {
+				return 0, err.Error()
+			}
+			content := strings.Repeat("y", 1+rng.IntN(4096))
+			status, body, _ = c.doWithHeader(http.MethodPut, location.RequestURI(), "", content,
+				http.Header{"Content-Range": {fmt.Sprintf("bytes 0-%d/%d", len(content)-1, len(content))}})
+			return status, body
+		},
+		allowed: []int{http.StatusOK},
+	},
+	{
+		name: "get object",
+		send: func(c *stressClient, rng *rand.Rand) (int, string) {
+			return c.do(http.MethodGet, fmt.Sprintf("/storage/v1/b/stress-%d/o/object-%d",
+				rng.IntN(stressBuckets), rng.IntN(stressObjects)), "", "")
+		},
+		allowed: []int{http.StatusOK, http.StatusNotFound},
+	},
+	{
+		name: "download object",
+		send: func(c *stressClient, rng *rand.Rand) (int, string) {
+			return c.do(http.MethodGet, fmt.Sprintf("/storage/v1/b/stress-%d/o/object-%d?alt=media",
+				rng.IntN(stressBuckets), rng.IntN(stressObjects)), "", "")
+		},
+		allowed: []int{http.StatusOK, http.StatusNotFound},
+	},
+	{
+		name: "patch object",
+		send: func(c *stressClient, rng *rand.Rand) (int, string) {
+			return c.do(http.MethodPatch, fmt.Sprintf("/storage/v1/b/stress-%d/o/object-%d",
+				rng.IntN(stressBuckets), rng.IntN(stressObjects)), "application/json",
+				fmt.Sprintf(`{"metadata": {"worker": "%d"}}`, rng.IntN(1000)))
+		},
+		allowed: []int{http.StatusOK, http.StatusNotFound},
+	},
+	{
+		name: "delete object",
+		send: func(c *stressClient, rng *rand.Rand) (int, string) {
+			return c.do(http.MethodDelete, fmt.Sprintf("/storage/v1/b/stress-%d/o/object-%d",
+				rng.IntN(stressBuckets), rng.IntN(stressObjects)), "", "")
+		},
+		allowed: []int{http.StatusNoContent, http.StatusNotFound},
+	},
+	{
+		name: "list objects",
+		send: func(c *stressClient, rng *rand.Rand) (int, string) {
+			return c.do(http.MethodGet, fmt.Sprintf("/storage/v1/b/stress-%d/o?maxResults=5", rng.IntN(stressBuckets)), "", "")
+		},
+		allowed: []int{http.StatusOK},
+	},
+	{
+		name: "create and delete bucket",
+		send: func(c *stressClient, rng *rand.Rand) (int, string) {
+			name := fmt.Sprintf("stress-temp-%d", rng.IntN(8))
+			status, body := c.do(http.MethodPost, "/storage/v1/b?project=test-project", "application/json",
+				fmt.Sprintf(`{"name": %q}`, name))
+			if status != http.StatusOK {
+				return status, body
+			}
+			return c.do(http.MethodDelete, "/storage/v1/b/"+name, "", "")
+		},
+		allowed: []int{http.StatusNoContent, http.StatusNotFound, http.StatusConflict},
+	},
+	{
+		name: "list buckets",
+		send: func(c *stressClient, rng *rand.Rand) (int, string) {
+			return c.do(http.MethodGet, "/storage/v1/b?project=test-project", "", "")
+		},
+		allowed: []int{http.StatusOK},
+	},
+	{
+		name: "get instance",
+		send: func(c *stressClient, rng *rand.Rand) (int, string) {
+			return c.do(http.MethodGet, fmt.Sprintf("/sql/v1beta4/projects/test-project/instances/stress-%d",
+				rng.IntN(stressInstances)), "", "")
+		},
+		allowed: []int{http.StatusOK},
+	},
+	{
+		name: "patch instance",
+		send: func(c *stressClient, rng *rand.Rand) (int, string) {
+			return c.do(http.MethodPatch, fmt.Sprintf("/sql/v1beta4/projects/test-project/instances/stress-%d",
+				rng.IntN(stressInstances)), "application/json",
+				fmt.Sprintf(`{"settings": {"userLabels": {"worker": "w%d"}}}`, rng.IntN(1000)))
+		},
+		allowed: []int{http.StatusOK},
+	},
+	{
+		name: "list instances",
+		send: func(c *stressClient, rng *rand.Rand) (int, string) {
+			return c.do(http.MethodGet, "/sql/v1beta4/projects/test-project/instances", "", "")
+		},
+		allowed: []int{http.StatusOK},
+	},
+	{
+		name: "create and delete database",
+		send: func(c *stressClient, rng *rand.Rand) (int, string) {
+			instance := fmt.Sprintf("/sql/v1beta4/projects/test-project/instances/stress-%d", rng.IntN(stressInstances))
+			name := fmt.Sprintf("db-%d", rng.IntN(8))
+			status, body := c.do(http.MethodPost, instance+"/databases", "application/json", fmt.Sprintf(`{"name": %q}`, name))
+			if status != http.StatusOK {
+				return status, body
+			}
+			return c.do(http.MethodDelete, instance+"/databases/"+name, "", "")
+		},
+		allowed: []int{http.StatusOK, http.StatusNotFound, http.StatusConflict},
+	},
+	{
+		name: "dashboard",
+		send: func(c *stressClient, rng *rand.Rand) (int, string) {
+			return c.do(http.MethodGet, fmt.Sprintf("/ui/buckets/stress-%d/objects", rng.IntN(stressBuckets)), "", "")
+		},
+		allowed: []int{http.StatusOK},
+	},
+	{
+		name: "list requests",
+		send: func(c *stressClient, rng *rand.Rand) (int, string) {
+			return c.do(http.MethodGet, "/admin/requests", "", "")
+		},
+		allowed: []int{http.StatusOK},
+	},
+	{
+		name: "tick",
+		send: func(c *stressClient, rng *rand.Rand) (int, string) {
+			return c.do(http.MethodPost, "/admin/tick", "", "")
+		},
+		allowed: []int{http.StatusNoContent},
+	},
+	{
+		name: "snapshot",
+		send: func(c *stressClient, rng *rand.Rand) (int, string) {
+			return c.do(http.MethodGet, "/admin/snapshot", "", "")
+		},
+		allowed: []int{http.StatusOK},
+	},
+}
+
+// TestServer_ConcurrentMixedRequests sends bucket, object, Cloud SQL and admin requests from hundreds of
+// goroutines at once. Run it with -race: besides unexpected responses, it catches data races anywhere
+// between the HTTP layer and the store.
+func TestServer_ConcurrentMixedRequests(t *testing.T) {
+	cleanup := changeToProjectRoot(t)
+	defer cleanup()
+
+	workers, requestsPerWorker := 200, 10
+	if testing.Short() {
+		workers, requestsPerWorker = 50, 10
+	}
+
+	srv := New(&config.Config{})
+	ts := httptest.NewServer(srv.Handler)
+	defer ts.Close()
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = workers
+	c := &stressClient{client: &http.Client{Transport: transport}, url: ts.URL}
+	defer transport.CloseIdleConnections()
+
+	for i := range stressBuckets {
+		if status, body := c.do(http.MethodPost, "/storage/v1/b?project=test-project", "application/json",
+			fmt.Sprintf(`{"name": "stress-%d"}`, i)); status != http.StatusOK {
+			t.Fatalf("create bucket failed: %d - %s", status, body)
+		}
+	}
+	for i := range stressInstances {
+		if status, body := c.do(http.MethodPost, "/sql/v1beta4/projects/test-project/instances", "application/json",
+			fmt.Sprintf(`{"name": "stress-%d", "databaseVersion": "POSTGRES_15", "region": "us-central1"}`, i)); status != http.StatusOK {
+			t.Fatalf("create instance failed: %d - %s", status, body)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for worker := range workers {
+		wg.Go(func() {
+			rng := rand.New(rand.NewPCG(uint64(worker), 859))
+			for range requestsPerWorker {
+				op := stressOperations[rng.IntN(len(stressOperations))]
+				status, body := op.send(c, rng)
+				if !slices.Contains(op.allowed, status) {
+					t.Errorf("%s: unexpected status %d - %s", op.name, status, body)
+				}
+			}
+		})
+	}
+	wg.Wait()
+
+	// Every listed object must still be readable, with the content its metadata describes
+	for i := range stressBuckets {
+		status, body := c.do(http.MethodGet, fmt.Sprintf("/storage/v1/b/stress-%d/o", i), "", "")
+		if status != http.StatusOK {
+			t.Fatalf("list objects failed: %d - %s", status, body)
+		}
+		var list storage.ObjectList
+		if err := json.Unmarshal([]byte(body), &list); err != nil {
+			t.Fatalf("failed to decode objects: %v", err)
+		}
+		for _, obj := range list.Items {
+			status, content := c.do(http.MethodGet, fmt.Sprintf("/storage/v1/b/stress-%d/o/%s?alt=media", i, obj.Name), "", "")
+			if status != http.StatusOK {
+				t.Errorf("download %s failed: %d - %s", obj.Name, status, content)
+				continue
+			}
+			if uint64(len(content)) != obj.Size {
+				t.Errorf("object %s has %d bytes, metadata says %d", obj.Name, len(content), obj.Size)
+			}
+		}
+	}
+}
@@ -0,0 +1,439 @@
+package sharedstate
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// lockTTL is how long the lock is held if the replica holding it goes away. While a request is served,
+// the lock is extended every third of it, so long uploads keep it.
+const lockTTL = 30 * time.Second
+
+// entriesBatchSize is the maximum number of entries read with one command.
+const entriesBatchSize = 500
+
+// lockRetryInterval is how often a replica tries to acquire a lock held by another replica.
+const lockRetryInterval = 20 * time.Millisecond
+
+// unlockScript deletes the lock only if it's still held with the given token.
+const unlockScript = `if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("del", KEYS[1]) else return 0 end`
+
+// extendScript extends the lock only if it's still held with the given token.
+const extendScript = `if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("pexpire", KEYS[1], ARGV[2]) else return 0 end`
+
+// redisError is an error reply of Redis.
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// Redis keeps the shared state in Redis, with the keys of each resource family under {key}:{family}:
+// the entries in the hash {key}:{family}:entries, the version each entry last changed in as its score in
+// the sorted set {key}:{family}:changes, the version of the family in {key}:{family}:version and its lock
+// in {key}:{family}:lock. Deleted entries keep their score, so other replicas learn about the deletion.
+type Redis struct {
+	addr     string
+	username string
+	password string
+	db       int
+	key      string
+
+	// mu serializes the commands on the connection
+	mu   sync.Mutex
+	conn net.Conn
+	rd   *bufio.Reader
+}
+
+// NewRedis creates a Redis backend for a URL like redis://:password@localhost:6379/0.
+// Returns an "invalid Redis URL" error if the URL can't be used; the connection is opened on the first command.
+func NewRedis(rawURL, key string) (*Redis, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Redis URL: %w", err)
+	}
+	if u.Scheme != "redis" {
+		return nil, fmt.Errorf("invalid Redis URL %q: the scheme must be redis", rawURL)
+	}
+
+	r := &Redis{addr: u.Host, key: key}
+	if u.Port() == "" {
+		r.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		r.username = u.User.Username()
+		r.password, _ = u.User.Password()
+	}
+	if db := strings.TrimPrefix(u.Path, "/"); db != "" {
+		if r.db, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("invalid Redis URL %q: the path must be a database number", rawURL)
+		}
+	}
+	return r, nil
+}
+
+// Lock acquires the lock of a family, waiting until ctx is done. The lock is extended until it's released.
+func (r *Redis) Lock(ctx context.Context, family string) (func(), error) {
+	token, err := newLockToken()
+	if err != nil {
+		return nil, err
+	}
+	lockKey := r.familyKey(family, "lock")
+	ttl := strconv.FormatInt(lockTTL.Milliseconds(), 10)
+
+	for {
+		reply, err := r.do(ctx, "SET", lockKey, token, "NX", "PX", ttl)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		if err != nil {
+			return nil, err
+		}
+		if reply != nil {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(lockRetryInterval):
+		}
+	}
+
+	stop := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(lockTTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				r.do(context.Background(), "EVAL", extendScript, "1", lockKey, token, ttl)
+			}
+		}
+	}()
+
+	return func() {
+		close(stop)
+		r.do(context.Background(), "EVAL", unlockScript, "1", lockKey, token)
+	}, nil
+}
+
+// Versions returns the versions of families, read with one command.
+func (r *Redis) Versions(ctx context.Context, families []string) ([]int64, error) {
+	args := []string{"MGET"}
+	for _, family := range families {
+		args = append(args, r.familyKey(family, "version"))
+	}
+	reply, err := r.do(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	values, ok := reply.([]any)
+	if !ok || len(values) != len(families) {
+		return nil, fmt.Errorf("redis: unexpected MGET reply %v", reply)
+	}
+
+	versions := make([]int64, len(families))
+	for i, value := range values {
+		if versions[i], err = parseVersion(value); err != nil {
+			return nil, err
+		}
+	}
+	return versions, nil
+}
+
+// Changes returns the entries of a family changed after a version and its current version. The changed keys
+// and the version are read in one transaction and the entries afterwards in batches, so an entry may already
+// have a later change, which is loaded again with the next version.
+func (r *Redis) Changes(ctx context.Context, family string, since int64) (map[string][]byte, int64, error) {
+	replies, err := r.transaction(ctx,
+		[]string{"GET", r.familyKey(family, "version")},
+		[]string{"ZRANGEBYSCORE", r.familyKey(family, "changes"), "(" + strconv.FormatInt(since, 10), "+inf"},
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	version, err := parseVersion(replies[0])
+	if err != nil {
+		return nil, 0, err
+	}
+	members, _ := replies[1].([]any)
+
+	changes := make(map[string][]byte, len(members))
+	for start := 0; start < len(members); start += entriesBatchSize {
+		batch := members[start:min(start+entriesBatchSize, len(members))]
+		args := []string{"HMGET", r.familyKey(family, "entries")}
+		for _, member := range batch {
+			key, _ := member.([]byte)
+			args = append(args, string(key))
+		}
+		reply, err := r.do(ctx, args...)
+		if err != nil {
+			return nil, 0, err
+		}
+		values, ok := reply.([]any)
+		if !ok || len(values) != len(batch) {
+			return nil, 0, fmt.Errorf("redis: unexpected HMGET reply %v", reply)
+		}
+		// Missing entries were deleted
+		for i, value := range values {
+			data, _ := value.([]byte)
+			changes[args[i+2]] = data
+		}
+	}
+	return changes, version, nil
+}
+
+// Save sets and deletes the changed entries of a family and sets its version in one transaction.
+func (r *Redis) Save(ctx context.Context, family string, version int64, changes map[string][]byte) (int64, error) {
+	version++
+	score := strconv.FormatInt(version, 10)
+
+	set := []string{"HSET", r.familyKey(family, "entries")}
+	del := []string{"HDEL", r.familyKey(family, "entries")}
+	scores := []string{"ZADD", r.familyKey(family, "changes")}
+	for key, data := range changes {
+		if data == nil {
+			del = append(del, key)
+		} else {
+			set = append(set, key, string(data))
+		}
+		scores = append(scores, score, key)
+	}
+
+	var commands [][]string
+	if len(set) > 2 {
+		commands = append(commands, set)
+	}
+	if len(del) > 2 {
+		commands = append(commands, del)
+	}
+	if len(scores) > 2 {
+		commands = append(commands, scores)
+	}
+	commands = append(commands, []string{"SET", r.familyKey(family, "version"), score})
+	if _, err := r.transaction(ctx, commands...); err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// familyKey returns the Redis key of a family's part, like {key}:storage:entries.
+func (r *Redis) familyKey(family, part string) string {
+	return r.key + ":" + family + ":" + part
+}
+
+// transaction runs commands in a MULTI/EXEC transaction and returns their replies.
+func (r *Redis) transaction(ctx context.Context, commands ...[]string) ([]any, error) {
+	pipeline := append([][]string{{"MULTI"}}, commands...)
+	pipeline = append(pipeline, []string{"EXEC"})
+
+	replies, err := r.pipeline(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	results, ok := replies[len(replies)-1].([]any)
+	if !ok || len(results) != len(commands) {
+		return nil, errors.New("redis: transaction aborted")
+	}
+	for _, result := range results {
+		if err, ok := result.(redisError); ok {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// do runs a command and returns its reply.
+func (r *Redis) do(ctx context.Context, args ...string) (any, error) {
+	replies, err := r.pipeline(ctx, [][]string{args})
+	if err != nil {
+		return nil, err
+	}
+	return replies[0], nil
+}
+
+// pipeline sends commands at once and reads their replies. Error replies are returned as the error.
+// After a network error, the connection is closed and opened again for the next command.
+func (r *Redis) pipeline(ctx context.Context, commands [][]string) ([]any, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.connect(ctx); err != nil {
+		return nil, err
+	}
+	deadline, hasDeadline := ctx.Deadline()
+	r.conn.SetDeadline(deadline)
+
+	replies, err := r.roundTrip(commands)
+	if err != nil {
+		var replyErr redisError
+		if !errors.As(err, &replyErr) {
+			r.conn.Close()
+			r.conn = nil
+		}
+		// The connection's deadline may pass a moment before the context reports it
+		if hasDeadline && errors.Is(err, os.ErrDeadlineExceeded) {
+			return nil, context.DeadlineExceeded
+		}
+		return nil, err
+	}
+	return replies, nil
+}
+
+// roundTrip writes the commands and reads one reply per command.
+func (r *Redis) roundTrip(commands [][]string) ([]any, error) {
+	w := bufio.NewWriter(r.conn)
+	for _, args := range commands {
+		writeCommand(w, args)
+	}
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+
+	replies := make([]any, len(commands))
+	var firstErr error
+	for i := range commands {
+		reply, err := readReply(r.rd)
+		if err != nil {
+			if _, ok := err.(redisError); !ok {
+				return nil, err
+			}
+			// Read the remaining replies, so the connection stays usable
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+		replies[i] = reply
+	}
+	return replies, firstErr
+}
+
+// connect opens the connection if it isn't open, authenticating and selecting the database.
+func (r *Redis) connect(ctx context.Context) error {
+	if r.conn != nil {
+		return nil
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", r.addr)
+	if err != nil {
+		return err
+	}
+	r.conn, r.rd = conn, bufio.NewReader(conn)
+
+	var setup [][]string
+	switch {
+	case r.username != "" && r.password != "":
+		setup = append(setup, []string{"AUTH", r.username, r.password})
+	case r.password != "":
+		setup = append(setup, []string{"AUTH", r.password})
+	}
+	if r.db != 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(r.db)})
+	}
+	if len(setup) == 0 {
+		return nil
+	}
+
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+	if _, err := r.roundTrip(setup); err != nil {
+		conn.Close()
+		r.conn = nil
+		return err
+	}
+	return nil
+}
+
+// writeCommand writes a command as an array of bulk strings.
+func writeCommand(w *bufio.Writer, args []string) {
+	fmt.Fprintf(w, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+}
+
+// readReply reads a reply: a string for simple strings, int64 for integers, []byte for bulk strings,
+// []any for arrays and nil for null replies. Error replies are returned as a redisError.
+func readReply(rd *bufio.Reader) (any, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("redis: empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(rd, buf); err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		items := make([]any, n)
+		for i := range items {
+			item, err := readReply(rd)
+			if err != nil {
+				if replyErr, ok := err.(redisError); ok {
+					items[i] = replyErr
+					continue
+				}
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: invalid reply %q", line)
+	}
+}
+
+// parseVersion parses the reply of GET on the version key; a missing key is version 0.
+func parseVersion(reply any) (int64, error) {
+	value, ok := reply.([]byte)
+	if !ok {
+		return 0, nil
+	}
+	return strconv.ParseInt(string(value), 10, 64)
+}
+
+// newLockToken returns a random token identifying the holder of a lock.
+func newLockToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
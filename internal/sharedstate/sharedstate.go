@@ -0,0 +1,462 @@
+// Package sharedstate keeps the state of several mock replicas in sync, so clients can spread their requests
+// across replicas behind a load balancer. The replicas share the resources of their stores through a Backend
+// like Redis, one entry per resource (see store.Entries): every request first loads the entries other replicas
+// changed, and requests that change the state hold the lock of the resource families they change, like
+// Cloud Storage or Cloud SQL, and save the entries that changed afterwards.
+package sharedstate
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/katharinasick/gcp-api-mock/internal/gcperror"
+	"github.com/katharinasick/gcp-api-mock/internal/store"
+)
+
+// Backend stores the entries of the resource families shared by the replicas.
+type Backend interface {
+	// Lock acquires the lock that serializes the changes of a family across replicas, waiting until ctx is done.
+	Lock(ctx context.Context, family string) (unlock func(), err error)
+	// Versions returns the versions of families, 0 for a family no replica saved yet.
+	Versions(ctx context.Context, families []string) ([]int64, error)
+	// Changes returns the entries of a family that changed after the given version, with nil values for
+	// deleted entries, and the current version of the family.
+	Changes(ctx context.Context, family string, since int64) (map[string][]byte, int64, error)
+	// Save sets the changed entries of a family, deleting those with nil values, and returns the new version,
+	// which follows version. The caller must hold the lock of the family and have loaded its version.
+	Save(ctx context.Context, family string, version int64, changes map[string][]byte) (int64, error)
+}
+
+// Syncer keeps the state of a store in sync with the shared state in a backend.
+type Syncer struct {
+	store    *store.Store
+	backend  Backend
+	families map[string]*family
+}
+
+// family is the state of a resource family on this replica.
+type family struct {
+	// mu serializes loading and saving the family on this replica
+	mu sync.Mutex
+	// version is the version of the shared family the store has
+	version int64
+	// hashes are the hashes of the entries the store had when the family was last loaded or saved, so only
+	// changed entries are saved. Content entries never change, so their hashes are left zero.
+	hashes map[string][sha256.Size]byte
+}
+
+// New creates a Syncer for a store.
+func New(s *store.Store, backend Backend) *Syncer {
+	families := make(map[string]*family, len(store.Families))
+	for _, name := range store.Families {
+		families[name] = &family{hashes: make(map[string][sha256.Size]byte)}
+	}
+	return &Syncer{store: s, backend: backend, families: families}
+}
+
+// Check verifies that the backend is reachable.
+func (s *Syncer) Check() error {
+	_, err := s.backend.Versions(context.Background(), store.Families)
+	return err
+}
+
+// Middleware syncs the store around each request. Requests that change the state (all but GET, HEAD
+// and OPTIONS) hold the locks of the families they may change while they're served, so the replicas'
+// changes don't overwrite each other, and are answered only once their changes are saved. If the backend
+// isn't reachable, or saving fails, requests fail with 503.
+func (s *Syncer) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !touchesState(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ctx := r.Context()
+
+		if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+			if err := s.load(ctx); err != nil {
+				respondUnavailable(w, err)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		families := requestFamilies(r.URL.Path)
+		unlock, err := s.lock(ctx, families)
+		if err != nil {
+			respondUnavailable(w, err)
+			return
+		}
+		defer unlock()
+
+		if err := s.load(ctx); err != nil {
+			respondUnavailable(w, err)
+			return
+		}
+		// The response is held back until the changes are saved, so clients don't see a change other
+		// replicas don't know about and retry the request if saving fails
+		buffered := &bufferedResponseWriter{ResponseWriter: w, header: make(http.Header), statusCode: http.StatusOK}
+		next.ServeHTTP(buffered, r)
+
+		for _, name := range families {
+			if err := s.save(context.WithoutCancel(ctx), name); err != nil {
+				log.Printf("Failed to save shared state of %s: %v", name, err)
+				respondUnavailable(w, err)
+				return
+			}
+		}
+		buffered.send()
+	})
+}
+
+// bufferedResponseWriter holds back the headers, status code and body of a response until send is called.
+type bufferedResponseWriter struct {
+	http.ResponseWriter
+	header     http.Header
+	statusCode int
+	body       bytes.Buffer
+}
+
+// Header returns the held back headers.
+func (rw *bufferedResponseWriter) Header() http.Header {
+	return rw.header
+}
+
+// WriteHeader captures the status code without writing it.
+func (rw *bufferedResponseWriter) WriteHeader(code int) {
+	rw.statusCode = code
+}
+
+// Write captures the body without writing it.
+func (rw *bufferedResponseWriter) Write(b []byte) (int, error) {
+	return rw.body.Write(b)
+}
+
+// Unwrap returns the underlying ResponseWriter, for http.ResponseController.
+func (rw *bufferedResponseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// send writes the held back response.
+func (rw *bufferedResponseWriter) send() {
+	for key, values := range rw.header {
+		rw.ResponseWriter.Header()[key] = values
+	}
+	rw.ResponseWriter.WriteHeader(rw.statusCode)
+	rw.ResponseWriter.Write(rw.body.Bytes())
+}
+
+// Run runs fn like a request that changes families: while holding their locks, after loading the entries
+// other replicas changed, and saving the entries of the families that changed afterwards. Background jobs,
+// like applying the clock to the store, use it to change the shared state without a request.
+func (s *Syncer) Run(ctx context.Context, families []string, fn func()) error {
+	unlock, err := s.lock(ctx, families)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := s.load(ctx); err != nil {
+		return err
+	}
+	fn()
+
+	var errs []error
+	for _, name := range families {
+		if err := s.save(ctx, name); err != nil {
+			errs = append(errs, fmt.Errorf("failed to save shared state of %s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// lock acquires the locks of families, which must be in the order of store.Families so replicas
+// taking several locks don't deadlock, and returns a function releasing them.
+func (s *Syncer) lock(ctx context.Context, families []string) (func(), error) {
+	var unlocks []func()
+	unlock := func() {
+		for i := len(unlocks) - 1; i >= 0; i-- {
+			unlocks[i]()
+		}
+	}
+	for _, name := range families {
+		unlockFamily, err := s.backend.Lock(ctx, name)
+		if err != nil {
+			unlock()
+			return nil, err
+		}
+		unlocks = append(unlocks, unlockFamily)
+	}
+	return unlock, nil
+}
+
+// load applies the entries other replicas changed to the store. Requests may read resources of any
+// family, like Eventarc triggers when an object is written, so all families are loaded.
+func (s *Syncer) load(ctx context.Context) error {
+	versions, err := s.backend.Versions(ctx, store.Families)
+	if err != nil {
+		return err
+	}
+	for i, name := range store.Families {
+		if err := s.loadFamily(ctx, name, versions[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadFamily applies the entries of a family changed since the store last loaded or saved it, if its
+// shared version isn't the store's anymore.
+func (s *Syncer) loadFamily(ctx context.Context, name string, version int64) error {
+	f := s.families[name]
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if version == f.version {
+		return nil
+	}
+	changes, version, err := s.backend.Changes(ctx, name, f.version)
+	if err != nil {
+		return err
+	}
+	if err := s.store.ApplyEntries(name, changes); err != nil {
+		return fmt.Errorf("failed to apply the shared state of %s: %w", name, err)
+	}
+
+	for key, data := range changes {
+		switch {
+		case data == nil:
+			delete(f.hashes, key)
+		case store.IsContentEntry(key):
+			f.hashes[key] = [sha256.Size]byte{}
+		default:
+			f.hashes[key] = sha256.Sum256(data)
+		}
+	}
+	f.version = version
+	return nil
+}
+
+// save saves the entries of a family that changed since the store last loaded or saved it.
+func (s *Syncer) save(ctx context.Context, name string) error {
+	f := s.families[name]
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	entries, err := s.store.Entries(name, func(key string) bool {
+		_, known := f.hashes[key]
+		return !known
+	})
+	if err != nil {
+		return err
+	}
+
+	changes := make(map[string][]byte)
+	hashes := make(map[string][sha256.Size]byte, len(entries))
+	for key, data := range entries {
+		if store.IsContentEntry(key) {
+			hashes[key] = [sha256.Size]byte{}
+			if _, known := f.hashes[key]; !known {
+				changes[key] = data
+			}
+			continue
+		}
+		hashes[key] = sha256.Sum256(data)
+		if hash, known := f.hashes[key]; !known || hash != hashes[key] {
+			changes[key] = data
+		}
+	}
+	for key := range f.hashes {
+		if _, exists := entries[key]; !exists {
+			changes[key] = nil
+		}
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	version, err := s.backend.Save(ctx, name, f.version, changes)
+	if err != nil {
+		return err
+	}
+	f.version, f.hashes = version, hashes
+	return nil
+}
+
+// requestFamilies returns the resource families a request may change, in the order of store.Families.
+// Requests of other paths, like most of the admin API, may change resources of all families.
+func requestFamilies(path string) []string {
+	switch {
+	case hasAnyPrefix(path, "/storage/", "/upload/storage/", "/download/storage/", "/admin/storage/", "/ui/buckets", "/ui/storage/"):
+		return []string{store.FamilyStorage}
+	case hasAnyPrefix(path, "/sql/", "/admin/sql/", "/ui/sql/"):
+		return []string{store.FamilySQL}
+	case hasAnyPrefix(path, "/v2/entries:", "/ui/logging/", "/ui/logs"):
+		return []string{store.FamilyLogging}
+	case hasAnyPrefix(path, "/v1/billingAccounts/", "/admin/billing/"):
+		return []string{store.FamilyBilling}
+	case strings.HasPrefix(path, "/admin/scheduler/"):
+		return []string{store.FamilyScheduler}
+	case strings.HasPrefix(path, "/admin/gke/"):
+		return []string{store.FamilyGKE}
+	case strings.HasPrefix(path, "/compute/"):
+		return []string{store.FamilyCompute}
+	case strings.HasPrefix(path, "/v3/"):
+		return []string{store.FamilyMonitoring}
+	}
+
+	// /v1/projects/{project}/databases/... and /v1/projects/{project}/locations/{location}/{collection}/...,
+	// /v2/projects/{project}/{collection}/...
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) >= 4 && segments[1] == "projects" {
+		switch {
+		case segments[0] == "v1" && segments[3] == "databases":
+			return []string{store.FamilyFirestore}
+		case segments[0] == "v1" && segments[3] == "locations" && len(segments) >= 6:
+			switch segments[5] {
+			case "jobs":
+				return []string{store.FamilyScheduler}
+			case "triggers":
+				return []string{store.FamilyEventarc}
+			case "instances":
+				return []string{store.FamilyRedis}
+			case "clusters":
+				return []string{store.FamilyGKE}
+			}
+		case segments[0] == "v2" && segments[3] == "logs":
+			return []string{store.FamilyLogging}
+		case segments[0] == "v2" && segments[3] == "policies":
+			return []string{store.FamilyOrgPolicy}
+		case segments[0] == "v2" && segments[3] == "locations":
+			return []string{store.FamilyRun}
+		}
+	}
+	if segments[0] == "v2" {
+		return []string{store.FamilyRegistry}
+	}
+	return store.Families
+}
+
+// hasAnyPrefix reports whether s starts with any of the prefixes.
+func hasAnyPrefix(s string, prefixes ...string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// touchesState reports whether a request may read or change the state of the store.
+// Probes and static files are served without the backend, so they work while it's unreachable.
+func touchesState(path string) bool {
+	switch path {
+	case "/health", "/ready", "/version":
+		return false
+	}
+	return !strings.HasPrefix(path, "/static/")
+}
+
+// respondUnavailable writes a 503 error for an unreachable backend.
+func respondUnavailable(w http.ResponseWriter, err error) {
+	gcperror.New(http.StatusServiceUnavailable, "Shared state unavailable: "+err.Error(), "backendError").Write(w)
+}
+
+// MemoryBackend keeps the shared state in memory, for replicas in the same process like in tests.
+type MemoryBackend struct {
+	mu       sync.Mutex
+	families map[string]*memoryFamily
+}
+
+// memoryFamily is a resource family in a MemoryBackend.
+type memoryFamily struct {
+	lock    chan struct{}
+	version int64
+	entries map[string][]byte
+	// changed is the version each entry was last changed in, including deleted entries
+	changed map[string]int64
+}
+
+// NewMemoryBackend creates a new MemoryBackend.
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{families: make(map[string]*memoryFamily)}
+}
+
+// family returns a family, creating it if it doesn't exist. The caller must hold b.mu.
+func (b *MemoryBackend) family(name string) *memoryFamily {
+	f, exists := b.families[name]
+	if !exists {
+		f = &memoryFamily{lock: make(chan struct{}, 1), entries: make(map[string][]byte), changed: make(map[string]int64)}
+		b.families[name] = f
+	}
+	return f
+}
+
+// Lock acquires the lock of a family, waiting until ctx is done.
+func (b *MemoryBackend) Lock(ctx context.Context, family string) (func(), error) {
+	b.mu.Lock()
+	lock := b.family(family).lock
+	b.mu.Unlock()
+
+	select {
+	case lock <- struct{}{}:
+		return func() { <-lock }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Versions returns the versions of families.
+func (b *MemoryBackend) Versions(ctx context.Context, families []string) ([]int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	versions := make([]int64, len(families))
+	for i, name := range families {
+		versions[i] = b.family(name).version
+	}
+	return versions, nil
+}
+
+// Changes returns the entries of a family changed after a version and its current version.
+func (b *MemoryBackend) Changes(ctx context.Context, family string, since int64) (map[string][]byte, int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	f := b.family(family)
+	changes := make(map[string][]byte)
+	for key, version := range f.changed {
+		if version > since {
+			changes[key] = f.entries[key]
+		}
+	}
+	return changes, f.version, nil
+}
+
+// Save sets the changed entries of a family and returns its new version.
+func (b *MemoryBackend) Save(ctx context.Context, family string, version int64, changes map[string][]byte) (int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	f := b.family(family)
+	if version != f.version {
+		return 0, fmt.Errorf("version %d of %s is outdated, the current version is %d", version, family, f.version)
+	}
+	f.version++
+	for key, data := range changes {
+		if data == nil {
+			delete(f.entries, key)
+		} else {
+			f.entries[key] = data
+		}
+		f.changed[key] = f.version
+	}
+	return f.version, nil
+}
@@ -0,0 +1,459 @@
+package sharedstate
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+	"github.com/katharinasick/gcp-api-mock/internal/store"
+)
+
+// bucketHandler creates, deletes and lists buckets of a store, standing in for the API handlers.
+func bucketHandler(s *store.Store) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			if _, err := s.CreateBucket(&storage.BucketInsertRequest{Name: r.URL.Query().Get("name")}); err != nil {
+				http.Error(w, err.Error(), http.StatusConflict)
+			}
+			return
+		case http.MethodDelete:
+			if err := s.DeleteBucket(r.URL.Query().Get("name")); err != nil {
+				http.Error(w, err.Error(), http.StatusNotFound)
+			}
+			return
+		}
+		var names []string
+		for _, bucket := range s.ListBuckets() {
+			names = append(names, bucket.Name)
+		}
+		fmt.Fprint(w, strings.Join(names, ","))
+	})
+}
+
+func TestSyncer_SharesStateBetweenReplicas(t *testing.T) {
+	backends := map[string]func(t *testing.T) Backend{
+		"memory": func(t *testing.T) Backend { return NewMemoryBackend() },
+		"redis": func(t *testing.T) Backend {
+			addr := startFakeRedis(t, "secret")
+			backend, err := NewRedis("redis://:secret@"+addr+"/2", "test")
+			if err != nil {
+				t.Fatalf("NewRedis() error: %v", err)
+			}
+			return backend
+		},
+	}
+
+	for name, newBackend := range backends {
+		t.Run(name, func(t *testing.T) {
+			backend := newBackend(t)
+			storeA, storeB := store.New(), store.New()
+			replicaA := New(storeA, backend).Middleware(bucketHandler(storeA))
+			replicaB := New(storeB, backend).Middleware(bucketHandler(storeB))
+
+			serve := func(h http.Handler, method, target string) string {
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+				if w.Code != http.StatusOK {
+					t.Fatalf("%s %s: expected status 200, got %d: %s", method, target, w.Code, w.Body.String())
+				}
+				return w.Body.String()
+			}
+
+			serve(replicaA, http.MethodPost, "/storage/v1/b?name=bucket-a")
+			if got := serve(replicaB, http.MethodGet, "/storage/v1/b"); got != "bucket-a" {
+				t.Errorf("expected replica B to see bucket-a, got %q", got)
+			}
+
+			serve(replicaB, http.MethodPost, "/storage/v1/b?name=bucket-b")
+			if got := serve(replicaA, http.MethodGet, "/storage/v1/b"); got != "bucket-a,bucket-b" {
+				t.Errorf("expected replica A to see both buckets, got %q", got)
+			}
+
+			serve(replicaA, http.MethodDelete, "/storage/v1/b?name=bucket-a")
+			if got := serve(replicaB, http.MethodGet, "/storage/v1/b"); got != "bucket-b" {
+				t.Errorf("expected replica B to see the deletion of bucket-a, got %q", got)
+			}
+		})
+	}
+}
+
+func TestSyncer_ConcurrentWrites(t *testing.T) {
+	backend := NewMemoryBackend()
+	var replicas []http.Handler
+	for range 3 {
+		s := store.New()
+		replicas = append(replicas, New(s, backend).Middleware(bucketHandler(s)))
+	}
+
+	// Writes on different replicas are serialized by the lock, so none of them is lost
+	var wg sync.WaitGroup
+	for i := range 12 {
+		wg.Go(func() {
+			target := fmt.Sprintf("/storage/v1/b?name=bucket-%02d", i)
+			replicas[i%len(replicas)].ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, target, nil))
+		})
+	}
+	wg.Wait()
+
+	w := httptest.NewRecorder()
+	replicas[0].ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/storage/v1/b", nil))
+	if got := len(strings.Split(w.Body.String(), ",")); got != 12 {
+		t.Errorf("expected 12 buckets, got %d: %s", got, w.Body.String())
+	}
+}
+
+func TestSyncer_Run(t *testing.T) {
+	backend := NewMemoryBackend()
+	storeA, storeB := store.New(), store.New()
+	syncerA := New(storeA, backend)
+	replicaB := New(storeB, backend).Middleware(bucketHandler(storeB))
+
+	// A job changes the state of replica A without a request, and replica B sees the change
+	err := syncerA.Run(context.Background(), []string{store.FamilyStorage}, func() {
+		storeA.CreateBucket(&storage.BucketInsertRequest{Name: "from-job"})
+	})
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	replicaB.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/storage/v1/b", nil))
+	if w.Body.String() != "from-job" {
+		t.Errorf("expected replica B to see the bucket of the job, got %q", w.Body.String())
+	}
+}
+
+func TestRequestFamilies(t *testing.T) {
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"/storage/v1/b/my-bucket/o", []string{store.FamilyStorage}},
+		{"/upload/storage/v1/b/my-bucket/o", []string{store.FamilyStorage}},
+		{"/admin/storage/buckets/my-bucket/objects", []string{store.FamilyStorage}},
+		{"/sql/v1beta4/projects/p/instances", []string{store.FamilySQL}},
+		{"/v1/projects/p/databases/(default)/documents/users", []string{store.FamilyFirestore}},
+		{"/v1/projects/p/locations/us-central1/jobs/nightly:run", []string{store.FamilyScheduler}},
+		{"/v1/projects/p/locations/us-central1/instances", []string{store.FamilyRedis}},
+		{"/v2/projects/p/locations/us-central1/services", []string{store.FamilyRun}},
+		{"/v2/projects/p/policies/compute.skipDefaultNetworkCreation", []string{store.FamilyOrgPolicy}},
+		{"/v2/entries:write", []string{store.FamilyLogging}},
+		{"/v2/my-repo/blobs/uploads/", []string{store.FamilyRegistry}},
+		{"/v3/projects/p/timeSeries", []string{store.FamilyMonitoring}},
+		{"/admin/reset", store.Families},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := requestFamilies(tt.path); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("expected families %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSyncer_BackendUnavailable(t *testing.T) {
+	s := store.New()
+	backend, err := NewRedis("redis://127.0.0.1:1", "test")
+	if err != nil {
+		t.Fatalf("NewRedis() error: %v", err)
+	}
+	syncer := New(s, backend)
+	h := syncer.Middleware(bucketHandler(s))
+
+	if err := syncer.Check(); err == nil {
+		t.Error("expected Check() to fail without a reachable backend")
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/storage/v1/b", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", w.Code)
+	}
+
+	// Probes don't depend on the backend
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected /health to be served, got status %d", w.Code)
+	}
+}
+
+// failingSaveBackend is a MemoryBackend whose saves fail.
+type failingSaveBackend struct {
+	*MemoryBackend
+}
+
+// Save fails without saving the changes.
+func (b failingSaveBackend) Save(context.Context, string, int64, map[string][]byte) (int64, error) {
+	return 0, errors.New("connection reset")
+}
+
+func TestSyncer_SaveFails(t *testing.T) {
+	s := store.New()
+	h := New(s, failingSaveBackend{NewMemoryBackend()}).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "unsaved"})
+		w.Header().Set("Location", "/storage/v1/b/unsaved")
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	// The response of a change that wasn't saved is replaced by a 503, so the client retries it
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/storage/v1/b", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", w.Code)
+	}
+	if location := w.Header().Get("Location"); location != "" {
+		t.Errorf("expected the headers of the handler to be dropped, got Location %q", location)
+	}
+}
+
+func TestNewRedis(t *testing.T) {
+	tests := []struct {
+		name     string
+		url      string
+		wantAddr string
+		wantDB   int
+		wantErr  bool
+	}{
+		{name: "host and port", url: "redis://redis:6380", wantAddr: "redis:6380"},
+		{name: "default port", url: "redis://redis", wantAddr: "redis:6379"},
+		{name: "database", url: "redis://:secret@redis:6379/3", wantAddr: "redis:6379", wantDB: 3},
+		{name: "wrong scheme", url: "http://redis:6379", wantErr: true},
+		{name: "invalid database", url: "redis://redis:6379/cache", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewRedis(tt.url, "test")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewRedis() error: %v", err)
+			}
+			if r.addr != tt.wantAddr || r.db != tt.wantDB {
+				t.Errorf("expected %s db %d, got %s db %d", tt.wantAddr, tt.wantDB, r.addr, r.db)
+			}
+		})
+	}
+}
+
+func TestRedis_Lock(t *testing.T) {
+	backend, err := NewRedis("redis://"+startFakeRedis(t, ""), "test")
+	if err != nil {
+		t.Fatalf("NewRedis() error: %v", err)
+	}
+
+	unlock, err := backend.Lock(context.Background(), store.FamilyStorage)
+	if err != nil {
+		t.Fatalf("Lock() error: %v", err)
+	}
+
+	// The lock is held, so a second Lock waits until the context is done
+	ctx, cancel := context.WithTimeout(context.Background(), 5*lockRetryInterval)
+	defer cancel()
+	if _, err := backend.Lock(ctx, store.FamilyStorage); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected Lock() to wait for the held lock, got %v", err)
+	}
+
+	// Families have their own locks
+	unlockSQL, err := backend.Lock(context.Background(), store.FamilySQL)
+	if err != nil {
+		t.Fatalf("Lock() of another family error: %v", err)
+	}
+	unlockSQL()
+
+	unlock()
+	unlock, err = backend.Lock(context.Background(), store.FamilyStorage)
+	if err != nil {
+		t.Fatalf("Lock() after unlock error: %v", err)
+	}
+	unlock()
+}
+
+// startFakeRedis starts a server speaking the subset of the Redis protocol the backend uses
+// and returns its address. If password isn't empty, clients must authenticate.
+func startFakeRedis(t *testing.T, password string) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	var mu sync.Mutex
+	data := newFakeRedisData()
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				rd := bufio.NewReader(conn)
+				authenticated := password == ""
+				var queued [][]string
+				inMulti := false
+
+				for {
+					reply, err := readReply(rd)
+					if err != nil {
+						return
+					}
+					var args []string
+					for _, arg := range reply.([]any) {
+						args = append(args, string(arg.([]byte)))
+					}
+
+					command := strings.ToUpper(args[0])
+					switch {
+					case command == "AUTH":
+						authenticated = args[len(args)-1] == password
+						if !authenticated {
+							fmt.Fprint(conn, "-WRONGPASS invalid password\r\n")
+							continue
+						}
+						fmt.Fprint(conn, "+OK\r\n")
+					case !authenticated:
+						fmt.Fprint(conn, "-NOAUTH Authentication required.\r\n")
+					case command == "MULTI":
+						inMulti = true
+						fmt.Fprint(conn, "+OK\r\n")
+					case command == "EXEC":
+						mu.Lock()
+						fmt.Fprintf(conn, "*%d\r\n", len(queued))
+						for _, queuedArgs := range queued {
+							fmt.Fprint(conn, fakeRedisCommand(data, queuedArgs))
+						}
+						mu.Unlock()
+						queued, inMulti = nil, false
+					case inMulti:
+						queued = append(queued, args)
+						fmt.Fprint(conn, "+QUEUED\r\n")
+					default:
+						mu.Lock()
+						fmt.Fprint(conn, fakeRedisCommand(data, args))
+						mu.Unlock()
+					}
+				}
+			}()
+		}
+	}()
+
+	return ln.Addr().String()
+}
+
+// fakeRedisData is the data of a fake Redis server.
+type fakeRedisData struct {
+	strings map[string]string
+	hashes  map[string]map[string]string
+	zsets   map[string]map[string]int64
+}
+
+func newFakeRedisData() *fakeRedisData {
+	return &fakeRedisData{strings: make(map[string]string), hashes: make(map[string]map[string]string), zsets: make(map[string]map[string]int64)}
+}
+
+// fakeRedisCommand runs a command against data and returns the encoded reply. Keys don't expire.
+func fakeRedisCommand(data *fakeRedisData, args []string) string {
+	bulk := func(value string, ok bool) string {
+		if !ok {
+			return "$-1\r\n"
+		}
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+	}
+
+	switch strings.ToUpper(args[0]) {
+	case "SELECT":
+		return "+OK\r\n"
+	case "GET":
+		value, ok := data.strings[args[1]]
+		return bulk(value, ok)
+	case "MGET":
+		reply := fmt.Sprintf("*%d\r\n", len(args)-1)
+		for _, key := range args[1:] {
+			value, ok := data.strings[key]
+			reply += bulk(value, ok)
+		}
+		return reply
+	case "SET":
+		if len(args) > 3 && strings.ToUpper(args[3]) == "NX" {
+			if _, ok := data.strings[args[1]]; ok {
+				return "$-1\r\n"
+			}
+		}
+		data.strings[args[1]] = args[2]
+		return "+OK\r\n"
+	case "HSET":
+		if data.hashes[args[1]] == nil {
+			data.hashes[args[1]] = make(map[string]string)
+		}
+		for i := 2; i+1 < len(args); i += 2 {
+			data.hashes[args[1]][args[i]] = args[i+1]
+		}
+		return fmt.Sprintf(":%d\r\n", (len(args)-2)/2)
+	case "HDEL":
+		for _, field := range args[2:] {
+			delete(data.hashes[args[1]], field)
+		}
+		return fmt.Sprintf(":%d\r\n", len(args)-2)
+	case "HMGET":
+		reply := fmt.Sprintf("*%d\r\n", len(args)-2)
+		for _, field := range args[2:] {
+			value, ok := data.hashes[args[1]][field]
+			reply += bulk(value, ok)
+		}
+		return reply
+	case "ZADD":
+		if data.zsets[args[1]] == nil {
+			data.zsets[args[1]] = make(map[string]int64)
+		}
+		for i := 2; i+1 < len(args); i += 2 {
+			score, _ := strconv.ParseInt(args[i], 10, 64)
+			data.zsets[args[1]][args[i+1]] = score
+		}
+		return fmt.Sprintf(":%d\r\n", (len(args)-2)/2)
+	case "ZRANGEBYSCORE":
+		// The backend only asks for the members with scores above an exclusive minimum, like (3 +inf
+		since, _ := strconv.ParseInt(strings.TrimPrefix(args[2], "("), 10, 64)
+		var members []string
+		for member, score := range data.zsets[args[1]] {
+			if score > since {
+				members = append(members, member)
+			}
+		}
+		reply := fmt.Sprintf("*%d\r\n", len(members))
+		for _, member := range members {
+			reply += bulk(member, true)
+		}
+		return reply
+	case "EVAL":
+		// The lock scripts compare KEYS[1] with ARGV[1] before deleting or extending it
+		key, token := args[3], args[4]
+		if data.strings[key] != token {
+			return ":0\r\n"
+		}
+		if args[1] == unlockScript {
+			delete(data.strings, key)
+		}
+		return ":1\r\n"
+	default:
+		return fmt.Sprintf("-ERR unknown command '%s'\r\n", args[0])
+	}
+}
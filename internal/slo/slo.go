@@ -0,0 +1,359 @@
+// Package slo simulates a service level objective: API requests fail at a target rate and take
+// latencies following a target distribution, like "99.9% success, p99 300ms". Unlike per-request
+// randomness, the targets are met over every stretch of requests, so long-running load tests
+// against the mock see realistic aggregate behavior instead of lucky or unlucky streaks.
+package slo
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Duration is a duration encoded in JSON as a string like "300ms".
+type Duration time.Duration
+
+// MarshalJSON encodes the duration as a string like "300ms".
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON decodes a duration from a string like "300ms".
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("invalid duration: %w", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q: %w", s, err)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// Latency is a latency distribution given by its percentiles. Latencies between the percentiles are
+// interpolated linearly; unset percentiles are skipped. Latencies above the highest percentile go up
+// to Max, twice the highest percentile by default.
+type Latency struct {
+	Min Duration `json:"min,omitempty"`
+	P50 Duration `json:"p50,omitempty"`
+	P90 Duration `json:"p90,omitempty"`
+	P95 Duration `json:"p95,omitempty"`
+	P99 Duration `json:"p99,omitempty"`
+	Max Duration `json:"max,omitempty"`
+}
+
+// quantile is a point of the latency distribution.
+type quantile struct {
+	level float64
+	value time.Duration
+}
+
+// quantiles returns the points of the distribution, or nil if no percentile is set.
+func (l Latency) quantiles() []quantile {
+	points := []quantile{{0, time.Duration(l.Min)}}
+	for _, p := range []quantile{{0.5, time.Duration(l.P50)}, {0.9, time.Duration(l.P90)}, {0.95, time.Duration(l.P95)}, {0.99, time.Duration(l.P99)}} {
+		if p.value > 0 {
+			points = append(points, p)
+		}
+	}
+	if len(points) == 1 && l.Max == 0 {
+		return nil
+	}
+
+	upper := time.Duration(l.Max)
+	if upper == 0 {
+		upper = 2 * points[len(points)-1].value
+	}
+	return append(points, quantile{1, upper})
+}
+
+// at returns the latency at a quantile level between 0 and 1.
+func at(points []quantile, level float64) time.Duration {
+	for i := 1; i < len(points); i++ {
+		if level <= points[i].level {
+			lower, upper := points[i-1], points[i]
+			fraction := (level - lower.level) / (upper.level - lower.level)
+			return lower.value + time.Duration(fraction*float64(upper.value-lower.value))
+		}
+	}
+	return points[len(points)-1].value
+}
+
+// Objective is a service level objective the mock simulates.
+type Objective struct {
+	// SuccessRate is the fraction of API requests that succeed, like 0.999.
+	SuccessRate float64 `json:"successRate"`
+	// Latency is the latency distribution of the requests, failed ones included.
+	Latency Latency `json:"latency"`
+	// ErrorStatus is the status of the failed requests: 500, 503 (the default) or 429.
+	ErrorStatus int `json:"errorStatus,omitempty"`
+}
+
+// NewObjective returns an objective without failures or latency, to decode one into.
+func NewObjective() Objective {
+	return Objective{SuccessRate: 1, ErrorStatus: http.StatusServiceUnavailable}
+}
+
+// Validate checks an objective. Returns an "invalid objective" error naming the problem.
+func (o *Objective) Validate() error {
+	if o.SuccessRate < 0 || o.SuccessRate > 1 || math.IsNaN(o.SuccessRate) {
+		return fmt.Errorf("invalid objective: successRate %v must be between 0 and 1", o.SuccessRate)
+	}
+	switch o.ErrorStatus {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusServiceUnavailable:
+	default:
+		return fmt.Errorf("invalid objective: errorStatus %d must be 429, 500 or 503", o.ErrorStatus)
+	}
+
+	l := o.Latency
+	var previous Duration
+	for _, p := range []struct {
+		name  string
+		value Duration
+	}{{"min", l.Min}, {"p50", l.P50}, {"p90", l.P90}, {"p95", l.P95}, {"p99", l.P99}, {"max", l.Max}} {
+		if p.value < 0 {
+			return fmt.Errorf("invalid objective: latency %s must not be negative", p.name)
+		}
+		if p.value == 0 {
+			continue
+		}
+		if p.value < previous {
+			return fmt.Errorf("invalid objective: latency %s %s is below a lower percentile", p.name, time.Duration(p.value))
+		}
+		previous = p.value
+	}
+	return nil
+}
+
+// ParseObjective parses an objective like "success=99.9%,p50=20ms,p99=300ms,max=2s,status=503".
+// The success rate may also be a fraction like 0.999.
+func ParseObjective(s string) (Objective, error) {
+	objective := NewObjective()
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, value, found := strings.Cut(entry, "=")
+		if !found {
+			return Objective{}, fmt.Errorf("invalid objective entry %q: expected key=value", entry)
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+
+		var err error
+		switch key {
+		case "success":
+			// Percentages are scaled in the exponent, so 99.9% is exactly as close to 0.999 as 0.999 is
+			if percent, isPercent := strings.CutSuffix(value, "%"); isPercent {
+				value = percent + "e-2"
+			}
+			objective.SuccessRate, err = strconv.ParseFloat(value, 64)
+		case "status":
+			objective.ErrorStatus, err = strconv.Atoi(value)
+		case "min", "p50", "p90", "p95", "p99", "max":
+			var d time.Duration
+			d, err = time.ParseDuration(value)
+			field := map[string]*Duration{
+				"min": &objective.Latency.Min, "p50": &objective.Latency.P50, "p90": &objective.Latency.P90,
+				"p95": &objective.Latency.P95, "p99": &objective.Latency.P99, "max": &objective.Latency.Max,
+			}[key]
+			*field = Duration(d)
+		default:
+			return Objective{}, fmt.Errorf("invalid objective entry %q: unknown key %s", entry, key)
+		}
+		if err != nil {
+			return Objective{}, fmt.Errorf("invalid objective entry %q: %w", entry, err)
+		}
+	}
+
+	if err := objective.Validate(); err != nil {
+		return Objective{}, err
+	}
+	return objective, nil
+}
+
+// Decision is what happens to a request.
+type Decision struct {
+	// Delay is how long the request is delayed.
+	Delay time.Duration
+	// Fail is whether the request fails instead of being served.
+	Fail bool
+	// Status is the status of a failed request.
+	Status int
+}
+
+// Stats are the requests the objective was applied to since it was set, so load tests can check
+// what they saw against it.
+type Stats struct {
+	Requests    int     `json:"requests"`
+	Failures    int     `json:"failures"`
+	SuccessRate float64 `json:"successRate"`
+	// The percentiles are those of the delays of the last 10,000 requests.
+	P50 Duration `json:"p50"`
+	P90 Duration `json:"p90"`
+	P99 Duration `json:"p99"`
+}
+
+// Status is the active objective with its stats.
+type Status struct {
+	// Objective is nil if no objective is set.
+	Objective *Objective `json:"objective"`
+	Stats     Stats      `json:"stats"`
+}
+
+const (
+	// latencySlots is the number of requests whose latencies are spread over the distribution together,
+	// so each percentile is met by every 100 requests.
+	latencySlots = 100
+	// minFailureBlock and maxFailureBlock bound the number of requests whose failures are placed together.
+	minFailureBlock = 100
+	maxFailureBlock = 100000
+	// keptDelays is the number of recent delays the percentiles of the stats are computed from.
+	keptDelays = 10000
+)
+
+// Simulator decides the fate of requests according to the active objective.
+// It is safe for concurrent access.
+type Simulator struct {
+	mu        sync.Mutex
+	objective *Objective
+	points    []quantile
+
+	// failures marks the requests of the current block that fail; the block is refilled when it is used up.
+	failures []bool
+	// failureCarry is the fraction of a failure owed to the next block, so rates like 99.95% are met exactly.
+	failureCarry float64
+	failureNext  int
+	// slots are the latency quantile slots of the current requests in random order.
+	slots    []int
+	slotNext int
+
+	requests int
+	failed   int
+	delays   []time.Duration
+}
+
+// New creates a simulator without an objective, which leaves requests alone.
+func New() *Simulator {
+	return &Simulator{}
+}
+
+// SetObjective replaces the active objective and resets the stats, or removes it if objective is nil.
+func (s *Simulator) SetObjective(objective *Objective) error {
+	if objective != nil {
+		if err := objective.Validate(); err != nil {
+			return err
+		}
+		copied := *objective
+		objective = &copied
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objective = objective
+	s.points = nil
+	if objective != nil {
+		s.points = objective.Latency.quantiles()
+	}
+	s.failures, s.failureCarry, s.failureNext = nil, 0, 0
+	s.slots, s.slotNext = nil, 0
+	s.requests, s.failed, s.delays = 0, 0, nil
+	return nil
+}
+
+// Status returns the active objective and its stats.
+func (s *Simulator) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := Status{Stats: Stats{Requests: s.requests, Failures: s.failed, SuccessRate: 1}}
+	if s.objective != nil {
+		objective := *s.objective
+		status.Objective = &objective
+	}
+	if s.requests > 0 {
+		status.Stats.SuccessRate = 1 - float64(s.failed)/float64(s.requests)
+	}
+	if len(s.delays) > 0 {
+		sorted := slices.Clone(s.delays)
+		slices.Sort(sorted)
+		percentile := func(p float64) Duration {
+			return Duration(sorted[int(math.Ceil(p*float64(len(sorted))))-1])
+		}
+		status.Stats.P50, status.Stats.P90, status.Stats.P99 = percentile(0.5), percentile(0.9), percentile(0.99)
+	}
+	return status
+}
+
+// Next decides the fate of the next request. Returns false if no objective is set.
+func (s *Simulator) Next() (Decision, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.objective == nil {
+		return Decision{}, false
+	}
+
+	decision := Decision{Delay: s.nextDelay(), Fail: s.nextFailure(), Status: s.objective.ErrorStatus}
+	s.requests++
+	if decision.Fail {
+		s.failed++
+	}
+	if len(s.delays) == keptDelays {
+		s.delays = s.delays[1:]
+	}
+	s.delays = append(s.delays, decision.Delay)
+	return decision, true
+}
+
+// nextFailure reports whether the next request fails. Each block of requests has as many failures as
+// the objective allows, at random positions. Callers must hold the lock.
+func (s *Simulator) nextFailure() bool {
+	failureRate := 1 - s.objective.SuccessRate
+	if failureRate <= 0 {
+		return false
+	}
+
+	if s.failureNext == len(s.failures) {
+		size := min(maxFailureBlock, max(minFailureBlock, int(math.Ceil(1/failureRate))))
+		owed := s.failureCarry + failureRate*float64(size)
+		count := min(size, int(math.Floor(owed+1e-9)))
+		s.failureCarry = max(0, owed-float64(count))
+
+		s.failures = make([]bool, size)
+		for _, i := range rand.Perm(size)[:count] {
+			s.failures[i] = true
+		}
+		s.failureNext = 0
+	}
+
+	fail := s.failures[s.failureNext]
+	s.failureNext++
+	return fail
+}
+
+// nextDelay returns the delay of the next request. Every latencySlots requests take one latency from
+// each slice of the distribution, in random order. Callers must hold the lock.
+func (s *Simulator) nextDelay() time.Duration {
+	if s.points == nil {
+		return 0
+	}
+
+	if s.slotNext == len(s.slots) {
+		s.slots = rand.Perm(latencySlots)
+		s.slotNext = 0
+	}
+	slot := s.slots[s.slotNext]
+	s.slotNext++
+
+	return at(s.points, (float64(slot)+rand.Float64())/latencySlots)
+}
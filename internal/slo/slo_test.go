@@ -0,0 +1,136 @@
+package slo
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseObjective(t *testing.T) {
+	objective, err := ParseObjective("success=99.9%, p50=20ms, p99=300ms, max=2s, status=500")
+	if err != nil {
+		t.Fatalf("ParseObjective() error: %v", err)
+	}
+	if objective.SuccessRate != 0.999 || objective.ErrorStatus != http.StatusInternalServerError {
+		t.Errorf("unexpected objective %+v", objective)
+	}
+	if objective.Latency.P50 != Duration(20*time.Millisecond) || objective.Latency.P99 != Duration(300*time.Millisecond) || objective.Latency.Max != Duration(2*time.Second) {
+		t.Errorf("unexpected latency %+v", objective.Latency)
+	}
+
+	if objective, err := ParseObjective("success=0.95"); err != nil || objective.SuccessRate != 0.95 || objective.ErrorStatus != http.StatusServiceUnavailable {
+		t.Errorf("expected a fraction and the default status, got %+v, %v", objective, err)
+	}
+
+	for _, s := range []string{"success=101%", "success=high", "p50=fast", "p50=300ms,p99=20ms", "status=404", "p75=20ms", "success"} {
+		if _, err := ParseObjective(s); err == nil {
+			t.Errorf("expected ParseObjective(%q) to fail", s)
+		}
+	}
+}
+
+func TestSimulator_Failures(t *testing.T) {
+	s := New()
+	if _, ok := s.Next(); ok {
+		t.Fatal("expected no decision without an objective")
+	}
+
+	objective := NewObjective()
+	objective.SuccessRate = 0.999
+	if err := s.SetObjective(&objective); err != nil {
+		t.Fatalf("SetObjective() error: %v", err)
+	}
+
+	// Every block of 1000 requests has exactly one failure
+	for block := range 5 {
+		failures := 0
+		for range 1000 {
+			decision, ok := s.Next()
+			if !ok {
+				t.Fatal("expected a decision")
+			}
+			if decision.Fail {
+				failures++
+				if decision.Status != http.StatusServiceUnavailable {
+					t.Errorf("expected status 503, got %d", decision.Status)
+				}
+			}
+		}
+		if failures != 1 {
+			t.Errorf("block %d: expected 1 failure, got %d", block, failures)
+		}
+	}
+
+	stats := s.Status().Stats
+	if stats.Requests != 5000 || stats.Failures != 5 || stats.SuccessRate != 0.999 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	// Rates that don't divide blocks evenly carry the remainder over
+	objective.SuccessRate = 0.9995
+	_ = s.SetObjective(&objective)
+	for range 10000 {
+		s.Next()
+	}
+	if failures := s.Status().Stats.Failures; failures != 5 {
+		t.Errorf("expected 5 failures in 10000 requests, got %d", failures)
+	}
+
+	_ = s.SetObjective(nil)
+	if _, ok := s.Next(); ok {
+		t.Error("expected no decision after the objective was removed")
+	}
+}
+
+func TestSimulator_Latency(t *testing.T) {
+	s := New()
+	objective := NewObjective()
+	objective.Latency = Latency{P50: Duration(20 * time.Millisecond), P99: Duration(300 * time.Millisecond), Max: Duration(time.Second)}
+	if err := s.SetObjective(&objective); err != nil {
+		t.Fatalf("SetObjective() error: %v", err)
+	}
+
+	for range 10000 {
+		decision, _ := s.Next()
+		if decision.Fail {
+			t.Fatal("expected no failures at a success rate of 100%")
+		}
+		if decision.Delay < 0 || decision.Delay > time.Second {
+			t.Fatalf("delay %s is out of range", decision.Delay)
+		}
+	}
+
+	// The stratified delays hit the percentiles within the width of a slot
+	stats := s.Status().Stats
+	within := func(got Duration, want, tolerance time.Duration) bool {
+		return time.Duration(got) >= want-tolerance && time.Duration(got) <= want+tolerance
+	}
+	if !within(stats.P50, 20*time.Millisecond, time.Millisecond) {
+		t.Errorf("expected p50 around 20ms, got %s", time.Duration(stats.P50))
+	}
+	if !within(stats.P99, 300*time.Millisecond, 70*time.Millisecond) {
+		t.Errorf("expected p99 around 300ms, got %s", time.Duration(stats.P99))
+	}
+}
+
+func TestLatency_Quantiles(t *testing.T) {
+	points := Latency{P50: Duration(100 * time.Millisecond)}.quantiles()
+	for _, tt := range []struct {
+		level    float64
+		expected time.Duration
+	}{
+		{0, 0},
+		{0.25, 50 * time.Millisecond},
+		{0.5, 100 * time.Millisecond},
+		{0.75, 150 * time.Millisecond},
+		{1, 200 * time.Millisecond},
+	} {
+		if got := at(points, tt.level); got != tt.expected {
+			t.Errorf("at(%v): expected %s, got %s", tt.level, tt.expected, got)
+		}
+	}
+
+	if points := (Latency{}).quantiles(); points != nil {
+		t.Errorf("expected no distribution without percentiles, got %v", points)
+	}
+}
@@ -0,0 +1,330 @@
+package sqladmin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// InstanceFilter is a parsed filter of instances.list, like
+// "state:RUNNABLE settings.userLabels.env:prod". Expressions are compared with the field of the instance's
+// JSON representation they name. Expressions separated by spaces must all match, AND and OR join them
+// explicitly, with OR binding tighter, and NOT or a leading "-" negates one. Parentheses group expressions.
+// Reference: https://cloud.google.com/sql/docs/mysql/admin-api/rest/v1beta4/instances/list
+type InstanceFilter struct {
+	root filterNode
+}
+
+// filterNode is an expression of a filter.
+type filterNode interface {
+	matches(instance map[string]any) bool
+}
+
+// andNode matches if all of its expressions match.
+type andNode []filterNode
+
+func (n andNode) matches(instance map[string]any) bool {
+	for _, child := range n {
+		if !child.matches(instance) {
+			return false
+		}
+	}
+	return true
+}
+
+// orNode matches if any of its expressions matches.
+type orNode []filterNode
+
+func (n orNode) matches(instance map[string]any) bool {
+	for _, child := range n {
+		if child.matches(instance) {
+			return true
+		}
+	}
+	return false
+}
+
+// notNode matches if its expression doesn't.
+type notNode struct{ child filterNode }
+
+func (n notNode) matches(instance map[string]any) bool {
+	return !n.child.matches(instance)
+}
+
+// comparison compares a field with a value. The ":" operator matches fields equal to the value, or starting with
+// it if it ends with "*"; a value of "*" matches fields that are set. "=" and "!=" compare exactly.
+// Repeated fields match if any element does.
+type comparison struct {
+	// field is the dotted path of the field, like "settings.userLabels.env"
+	field    string
+	operator string
+	value    string
+}
+
+func (c comparison) matches(instance map[string]any) bool {
+	values := lookupField(instance, strings.Split(c.field, "."))
+	if c.operator == "!=" {
+		for _, v := range values {
+			if v == c.value {
+				return false
+			}
+		}
+		return true
+	}
+
+	for _, v := range values {
+		switch {
+		case c.operator == "=":
+			if v == c.value {
+				return true
+			}
+		case c.value == "*":
+			return true
+		case strings.HasSuffix(c.value, "*"):
+			if strings.HasPrefix(v, strings.TrimSuffix(c.value, "*")) {
+				return true
+			}
+		case v == c.value:
+			return true
+		}
+	}
+	return false
+}
+
+// lookupField returns the values of a field of a decoded JSON object as strings. Repeated fields have a value
+// per element; objects and missing fields have none.
+func lookupField(value any, path []string) []string {
+	switch v := value.(type) {
+	case map[string]any:
+		if len(path) == 0 {
+			return nil
+		}
+		return lookupField(v[path[0]], path[1:])
+	case []any:
+		var values []string
+		for _, item := range v {
+			values = append(values, lookupField(item, path)...)
+		}
+		return values
+	case nil:
+		return nil
+	default:
+		if len(path) > 0 {
+			return nil
+		}
+		return []string{fmt.Sprint(v)}
+	}
+}
+
+// ParseInstanceFilter parses a filter of instances.list. An empty filter matches every instance.
+// Returns an "invalid filter" error for malformed filters.
+func ParseInstanceFilter(s string) (*InstanceFilter, error) {
+	tokens, err := tokenizeInstanceFilter(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return &InstanceFilter{root: andNode{}}, nil
+	}
+
+	p := &filterParser{tokens: tokens}
+	root, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("invalid filter: unexpected %q", p.tokens[p.pos])
+	}
+	return &InstanceFilter{root: root}, nil
+}
+
+// Matches reports whether an instance matches the filter.
+func (f *InstanceFilter) Matches(instance *DatabaseInstance) bool {
+	encoded, err := json.Marshal(instance)
+	if err != nil {
+		return false
+	}
+	dec := json.NewDecoder(bytes.NewReader(encoded))
+	dec.UseNumber()
+	var fields map[string]any
+	if err := dec.Decode(&fields); err != nil {
+		return false
+	}
+	return f.root.matches(fields)
+}
+
+// filterParser is a recursive descent parser for instance filters.
+type filterParser struct {
+	tokens []string
+	pos    int
+}
+
+// peek returns the next token, or "" at the end.
+func (p *filterParser) peek() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+// parseAnd parses expressions joined with AND or spaces, up to the end or a closing parenthesis.
+func (p *filterParser) parseAnd() (filterNode, error) {
+	var node andNode
+	for {
+		child, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		node = append(node, child)
+
+		switch p.peek() {
+		case "", ")":
+			return node, nil
+		case "AND":
+			p.pos++
+		}
+	}
+}
+
+// parseOr parses expressions joined with OR.
+func (p *filterParser) parseOr() (filterNode, error) {
+	var node orNode
+	for {
+		child, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		node = append(node, child)
+
+		if p.peek() != "OR" {
+			if len(node) == 1 {
+				return node[0], nil
+			}
+			return node, nil
+		}
+		p.pos++
+	}
+}
+
+// parseUnary parses a negated or parenthesized expression, or a comparison.
+func (p *filterParser) parseUnary() (filterNode, error) {
+	token := p.peek()
+	switch {
+	case token == "":
+		return nil, fmt.Errorf("invalid filter: incomplete expression")
+	case token == "NOT":
+		p.pos++
+		child, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{child}, nil
+	case token == "(":
+		p.pos++
+		child, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("invalid filter: missing closing parenthesis")
+		}
+		p.pos++
+		return child, nil
+	case strings.HasPrefix(token, "-") && len(token) > 1:
+		p.tokens[p.pos] = token[1:]
+		child, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{child}, nil
+	}
+	return p.parseComparison()
+}
+
+// parseComparison parses a comparison like settings.userLabels.env:prod.
+func (p *filterParser) parseComparison() (filterNode, error) {
+	if p.pos+3 > len(p.tokens) {
+		return nil, fmt.Errorf("invalid filter: incomplete comparison %q", strings.Join(p.tokens[p.pos:], " "))
+	}
+	field, operator, value := p.tokens[p.pos], p.tokens[p.pos+1], p.tokens[p.pos+2]
+	if !isFieldPath(field) {
+		return nil, fmt.Errorf("invalid filter: invalid field %q", field)
+	}
+	if operator != ":" && operator != "=" && operator != "!=" {
+		return nil, fmt.Errorf("invalid filter: unsupported operator %q after %s", operator, field)
+	}
+	if isOperatorToken(value) {
+		return nil, fmt.Errorf("invalid filter: expected a value after %s%s", field, operator)
+	}
+	if unquoted, err := strconv.Unquote(value); err == nil && strings.HasPrefix(value, `"`) {
+		value = unquoted
+	}
+	p.pos += 3
+	return comparison{field: field, operator: operator, value: value}, nil
+}
+
+// isFieldPath reports whether a token is a dotted field path like settings.tier.
+func isFieldPath(token string) bool {
+	if token == "" || isOperatorToken(token) || strings.HasPrefix(token, `"`) {
+		return false
+	}
+	for _, part := range strings.Split(token, ".") {
+		if part == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// isOperatorToken reports whether a token is an operator or a parenthesis.
+func isOperatorToken(token string) bool {
+	switch token {
+	case ":", "=", "!=", "(", ")":
+		return true
+	}
+	return false
+}
+
+// tokenizeInstanceFilter splits a filter into fields, operators, parentheses, values and quoted strings.
+func tokenizeInstanceFilter(s string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(s); {
+		switch c := s[i]; {
+		case unicode.IsSpace(rune(c)):
+			i++
+		case c == '(' || c == ')' || c == ':' || c == '=':
+			tokens = append(tokens, string(c))
+			i++
+		case c == '!':
+			if i+1 >= len(s) || s[i+1] != '=' {
+				return nil, fmt.Errorf("invalid filter: unexpected %q", c)
+			}
+			tokens = append(tokens, "!=")
+			i += 2
+		case c == '"':
+			end := i + 1
+			for end < len(s) && s[end] != '"' {
+				if s[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(s) {
+				return nil, fmt.Errorf("invalid filter: unterminated string")
+			}
+			tokens = append(tokens, s[i:end+1])
+			i = end + 1
+		default:
+			end := i
+			for end < len(s) && !unicode.IsSpace(rune(s[end])) && !strings.ContainsRune(`():="!`, rune(s[end])) {
+				end++
+			}
+			tokens = append(tokens, s[i:end])
+			i = end
+		}
+	}
+	return tokens, nil
+}
@@ -0,0 +1,62 @@
+package sqladmin
+
+import "testing"
+
+func TestParseInstanceFilter(t *testing.T) {
+	prod := &DatabaseInstance{
+		Name:            "prod-db",
+		State:           "RUNNABLE",
+		DatabaseVersion: "POSTGRES_15",
+		Settings:        &Settings{Tier: "db-custom-2-7680", UserLabels: map[string]string{"env": "prod", "team": "payments"}},
+		IPAddresses:     []*IPMapping{{Type: "PRIMARY", IPAddress: "10.0.0.3"}},
+	}
+	dev := &DatabaseInstance{
+		Name:            "dev-db",
+		State:           "SUSPENDED",
+		DatabaseVersion: "MYSQL_8_0",
+		Settings:        &Settings{Tier: "db-f1-micro", UserLabels: map[string]string{"env": "dev"}},
+	}
+
+	tests := []struct {
+		filter    string
+		wantProd  bool
+		wantDev   bool
+		wantError bool
+	}{
+		{filter: "", wantProd: true, wantDev: true},
+		{filter: "settings.userLabels.env:prod", wantProd: true},
+		{filter: "state:RUNNABLE databaseVersion:POSTGRES_15", wantProd: true},
+		{filter: "state:RUNNABLE AND databaseVersion:MYSQL_8_0"},
+		{filter: "settings.userLabels.env:prod OR settings.userLabels.env:dev", wantProd: true, wantDev: true},
+		{filter: "NOT state:RUNNABLE", wantDev: true},
+		{filter: "-settings.userLabels.env:prod", wantDev: true},
+		{filter: "name:prod-*", wantProd: true},
+		{filter: "settings.userLabels.team:*", wantProd: true},
+		{filter: `settings.tier="db-f1-micro"`, wantDev: true},
+		{filter: "state!=RUNNABLE", wantDev: true},
+		{filter: "ipAddresses.ipAddress:10.0.0.3", wantProd: true},
+		{filter: "(state:SUSPENDED OR state:RUNNABLE) settings.userLabels.env:dev", wantDev: true},
+		{filter: "state:", wantError: true},
+		{filter: "state RUNNABLE", wantError: true},
+		{filter: "(state:RUNNABLE", wantError: true},
+		{filter: `name:"unterminated`, wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			f, err := ParseInstanceFilter(tt.filter)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("ParseInstanceFilter(%q) error = %v, wantError %v", tt.filter, err, tt.wantError)
+			}
+			if tt.wantError {
+				return
+			}
+			if got := f.Matches(prod); got != tt.wantProd {
+				t.Errorf("Matches(prod-db) = %v, want %v", got, tt.wantProd)
+			}
+			if got := f.Matches(dev); got != tt.wantDev {
+				t.Errorf("Matches(dev-db) = %v, want %v", got, tt.wantDev)
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package sqladmin
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Flag is a database flag that can be set on instances of some database versions.
+// Reference: https://cloud.google.com/sql/docs/mysql/admin-api/rest/v1beta4/flags
+type Flag struct {
+	// Kind is the kind of resource. This is always "sql#flag".
+	Kind string `json:"kind"`
+	// Name is the name of the flag, e.g. "max_connections".
+	Name string `json:"name"`
+	// Type is the type of the flag's value: BOOLEAN, STRING, INTEGER, FLOAT, NONE or MYSQL_TIMEZONE.
+	Type string `json:"type"`
+	// AppliesTo lists the database versions the flag can be set on.
+	AppliesTo []string `json:"appliesTo"`
+	// AllowedStringValues lists the values of a STRING flag; any value is allowed if empty.
+	AllowedStringValues []string `json:"allowedStringValues,omitempty"`
+	// MinValue is the minimum value of an INTEGER or FLOAT flag.
+	MinValue *int64 `json:"minValue,omitempty,string"`
+	// MaxValue is the maximum value of an INTEGER or FLOAT flag.
+	MaxValue *int64 `json:"maxValue,omitempty,string"`
+	// RequiresRestart specifies if setting the flag restarts the instance.
+	RequiresRestart bool `json:"requiresRestart"`
+}
+
+// FlagsListResponse represents a response from listing flags.
+// Reference: https://cloud.google.com/sql/docs/mysql/admin-api/rest/v1beta4/flags/list
+type FlagsListResponse struct {
+	// Kind is the kind of resource. This is always "sql#flagsList".
+	Kind string `json:"kind"`
+	// Items contains the list of flags.
+	Items []*Flag `json:"items"`
+}
+
+// databaseVersions lists the database versions per engine, keyed by their prefix.
+var databaseVersions = map[string][]string{
+	"MYSQL": {"MYSQL_5_6", "MYSQL_5_7", "MYSQL_8_0", "MYSQL_8_4"},
+	"POSTGRES": {
+		"POSTGRES_9_6", "POSTGRES_10", "POSTGRES_11", "POSTGRES_12", "POSTGRES_13",
+		"POSTGRES_14", "POSTGRES_15", "POSTGRES_16", "POSTGRES_17",
+	},
+	"SQLSERVER": {
+		"SQLSERVER_2017_STANDARD", "SQLSERVER_2017_ENTERPRISE", "SQLSERVER_2017_EXPRESS", "SQLSERVER_2017_WEB",
+		"SQLSERVER_2019_STANDARD", "SQLSERVER_2019_ENTERPRISE", "SQLSERVER_2019_EXPRESS", "SQLSERVER_2019_WEB",
+		"SQLSERVER_2022_STANDARD", "SQLSERVER_2022_ENTERPRISE", "SQLSERVER_2022_EXPRESS", "SQLSERVER_2022_WEB",
+	},
+}
+
+// flagSpec describes a supported flag of one database engine.
+type flagSpec struct {
+	engine          string
+	name            string
+	flagType        string
+	allowed         []string
+	min, max        *int64
+	requiresRestart bool
+}
+
+// supportedFlags is the catalog of flags the mock accepts, a subset of the
+// flags Cloud SQL supports, covering the commonly configured ones.
+var supportedFlags = func() []flagSpec {
+	spec := func(engine, name, flagType string, requiresRestart bool) flagSpec {
+		return flagSpec{engine: engine, name: name, flagType: flagType, requiresRestart: requiresRestart}
+	}
+	ranged := func(engine, name, flagType string, lo, hi int64, requiresRestart bool) flagSpec {
+		f := spec(engine, name, flagType, requiresRestart)
+		f.min, f.max = &lo, &hi
+		return f
+	}
+	enum := func(engine, name string, allowed ...string) flagSpec {
+		f := spec(engine, name, "STRING", false)
+		f.allowed = allowed
+		return f
+	}
+
+	return []flagSpec{
+		// MySQL
+		spec("MYSQL", "cloudsql_iam_authentication", "BOOLEAN", false),
+		enum("MYSQL", "character_set_server", "utf8", "utf8mb3", "utf8mb4", "latin1", "ascii", "binary"),
+		spec("MYSQL", "default_time_zone", "MYSQL_TIMEZONE", true),
+		spec("MYSQL", "event_scheduler", "BOOLEAN", false),
+		spec("MYSQL", "explicit_defaults_for_timestamp", "BOOLEAN", false),
+		spec("MYSQL", "general_log", "BOOLEAN", false),
+		ranged("MYSQL", "innodb_buffer_pool_size", "INTEGER", 5242880, 1099511627776, false),
+		ranged("MYSQL", "innodb_lock_wait_timeout", "INTEGER", 1, 1073741824, false),
+		spec("MYSQL", "local_infile", "BOOLEAN", false),
+		spec("MYSQL", "log_bin_trust_function_creators", "BOOLEAN", false),
+		enum("MYSQL", "log_output", "FILE", "TABLE", "NONE"),
+		ranged("MYSQL", "long_query_time", "FLOAT", 0, 30000000, false),
+		ranged("MYSQL", "max_allowed_packet", "INTEGER", 16384, 1073741824, false),
+		ranged("MYSQL", "max_connections", "INTEGER", 10, 100000, false),
+		spec("MYSQL", "performance_schema", "BOOLEAN", true),
+		spec("MYSQL", "require_secure_transport", "BOOLEAN", false),
+		spec("MYSQL", "slow_query_log", "BOOLEAN", false),
+		spec("MYSQL", "sql_mode", "STRING", false),
+		enum("MYSQL", "transaction_isolation", "READ-UNCOMMITTED", "READ-COMMITTED", "REPEATABLE-READ", "SERIALIZABLE"),
+		ranged("MYSQL", "wait_timeout", "INTEGER", 1, 31536000, false),
+
+		// PostgreSQL
+		spec("POSTGRES", "autovacuum", "BOOLEAN", false),
+		spec("POSTGRES", "cloudsql.enable_pgaudit", "BOOLEAN", true),
+		spec("POSTGRES", "cloudsql.iam_authentication", "BOOLEAN", false),
+		spec("POSTGRES", "cloudsql.logical_decoding", "BOOLEAN", true),
+		ranged("POSTGRES", "idle_in_transaction_session_timeout", "INTEGER", 0, 2147483647, false),
+		spec("POSTGRES", "log_checkpoints", "BOOLEAN", false),
+		spec("POSTGRES", "log_connections", "BOOLEAN", false),
+		spec("POSTGRES", "log_disconnections", "BOOLEAN", false),
+		spec("POSTGRES", "log_lock_waits", "BOOLEAN", false),
+		ranged("POSTGRES", "log_min_duration_statement", "INTEGER", -1, 2147483647, false),
+		enum("POSTGRES", "log_statement", "none", "ddl", "mod", "all"),
+		ranged("POSTGRES", "log_temp_files", "INTEGER", -1, 2147483647, false),
+		ranged("POSTGRES", "maintenance_work_mem", "INTEGER", 1024, 2147483647, false),
+		ranged("POSTGRES", "max_connections", "INTEGER", 14, 262143, true),
+		spec("POSTGRES", "pgaudit.log", "STRING", false),
+		ranged("POSTGRES", "random_page_cost", "FLOAT", 0, 2147483647, false),
+		ranged("POSTGRES", "statement_timeout", "INTEGER", 0, 2147483647, false),
+		ranged("POSTGRES", "temp_file_limit", "INTEGER", 1048576, 2147483647, false),
+		spec("POSTGRES", "timezone", "STRING", false),
+		ranged("POSTGRES", "track_activity_query_size", "INTEGER", 100, 1048576, true),
+		ranged("POSTGRES", "work_mem", "INTEGER", 64, 2147483647, false),
+
+		// SQL Server
+		spec("SQLSERVER", "contained database authentication", "BOOLEAN", false),
+		ranged("SQLSERVER", "cost threshold for parallelism", "INTEGER", 0, 32767, false),
+		spec("SQLSERVER", "cross db ownership chaining", "BOOLEAN", false),
+		ranged("SQLSERVER", "max degree of parallelism", "INTEGER", 0, 32767, false),
+		ranged("SQLSERVER", "max server memory (mb)", "INTEGER", 1000, 2147483647, false),
+		spec("SQLSERVER", "remote access", "BOOLEAN", true),
+		ranged("SQLSERVER", "user connections", "INTEGER", 0, 32767, true),
+	}
+}()
+
+// databaseEngine returns the engine of a database version, e.g. "POSTGRES" for "POSTGRES_15".
+// Returns "" for unknown engines.
+func databaseEngine(databaseVersion string) string {
+	for engine := range databaseVersions {
+		if strings.HasPrefix(databaseVersion, engine+"_") {
+			return engine
+		}
+	}
+	return ""
+}
+
+// ListFlags returns the supported flags, sorted by name. If databaseVersion
+// is set, only the flags that apply to it are returned.
+func ListFlags(databaseVersion string) []*Flag {
+	engine := databaseEngine(databaseVersion)
+	flags := make([]*Flag, 0, len(supportedFlags))
+	for _, spec := range supportedFlags {
+		if databaseVersion != "" && spec.engine != engine {
+			continue
+		}
+		flags = append(flags, &Flag{
+			Kind:                "sql#flag",
+			Name:                spec.name,
+			Type:                spec.flagType,
+			AppliesTo:           databaseVersions[spec.engine],
+			AllowedStringValues: spec.allowed,
+			MinValue:            spec.min,
+			MaxValue:            spec.max,
+			RequiresRestart:     spec.requiresRestart,
+		})
+	}
+
+	slices.SortStableFunc(flags, func(a, b *Flag) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return flags
+}
+
+// ValidateFlags checks database flags against the flags supported for a database version.
+// Returns an "invalid flag name" error for unknown flags and an "invalid flag value"
+// error for values that don't match the flag's type, range or allowed values.
+func ValidateFlags(databaseVersion string, flags []*DatabaseFlags) error {
+	engine := databaseEngine(databaseVersion)
+	for _, flag := range flags {
+		if flag == nil {
+			continue
+		}
+
+		i := slices.IndexFunc(supportedFlags, func(spec flagSpec) bool {
+			return spec.engine == engine && spec.name == flag.Name
+		})
+		if i < 0 {
+			return fmt.Errorf("invalid flag name %q for database version %s", flag.Name, databaseVersion)
+		}
+
+		if err := supportedFlags[i].validate(flag.Value); err != nil {
+			return fmt.Errorf("invalid flag value %q for flag %s: %v", flag.Value, flag.Name, err)
+		}
+	}
+	return nil
+}
+
+// validate checks a flag value against the spec.
+func (f flagSpec) validate(value string) error {
+	switch f.flagType {
+	case "BOOLEAN":
+		switch strings.ToLower(value) {
+		case "on", "off", "true", "false":
+			return nil
+		}
+		return fmt.Errorf("expected on or off")
+	case "INTEGER":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("expected an integer")
+		}
+		return f.checkRange(float64(n))
+	case "FLOAT":
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("expected a number")
+		}
+		return f.checkRange(n)
+	case "STRING":
+		if len(f.allowed) > 0 && !slices.ContainsFunc(f.allowed, func(allowed string) bool {
+			return strings.EqualFold(allowed, value)
+		}) {
+			return fmt.Errorf("expected one of %s", strings.Join(f.allowed, ", "))
+		}
+	}
+	return nil
+}
+
+// checkRange checks a numeric flag value against the spec's bounds.
+func (f flagSpec) checkRange(n float64) error {
+	if (f.min != nil && n < float64(*f.min)) || (f.max != nil && n > float64(*f.max)) {
+		return fmt.Errorf("expected a value between %d and %d", *f.min, *f.max)
+	}
+	return nil
+}
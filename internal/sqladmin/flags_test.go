@@ -0,0 +1,84 @@
+package sqladmin
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestListFlags(t *testing.T) {
+	flags := ListFlags("POSTGRES_15")
+	if len(flags) == 0 {
+		t.Fatal("expected flags for POSTGRES_15")
+	}
+
+	for i, flag := range flags {
+		if i > 0 && flags[i-1].Name > flag.Name {
+			t.Errorf("flags are not sorted by name: %s before %s", flags[i-1].Name, flag.Name)
+		}
+		if !strings.HasPrefix(flag.AppliesTo[0], "POSTGRES_") {
+			t.Errorf("flag %s applies to %v", flag.Name, flag.AppliesTo)
+		}
+	}
+
+	if all := ListFlags(""); len(all) <= len(flags) {
+		t.Errorf("expected more flags without a database version, got %d", len(all))
+	}
+	if unknown := ListFlags("ORACLE_19"); len(unknown) != 0 {
+		t.Errorf("expected no flags for an unknown database version, got %d", len(unknown))
+	}
+}
+
+func TestFlag_JSONBounds(t *testing.T) {
+	for _, flag := range ListFlags("MYSQL_8_0") {
+		if flag.Name != "max_connections" {
+			continue
+		}
+
+		data, err := json.Marshal(flag)
+		if err != nil {
+			t.Fatalf("failed to marshal flag: %v", err)
+		}
+		// int64 values are encoded as strings like in the real API
+		if !strings.Contains(string(data), `"minValue":"10"`) || !strings.Contains(string(data), `"maxValue":"100000"`) {
+			t.Errorf("unexpected bounds in %s", data)
+		}
+		return
+	}
+	t.Fatal("max_connections not found")
+}
+
+func TestValidateFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		flags   []*DatabaseFlags
+		wantErr string
+	}{
+		{"no flags", "MYSQL_8_0", nil, ""},
+		{"valid mysql flags", "MYSQL_8_0", []*DatabaseFlags{{Name: "slow_query_log", Value: "on"}, {Name: "long_query_time", Value: "0.5"}}, ""},
+		{"valid postgres flags", "POSTGRES_15", []*DatabaseFlags{{Name: "log_min_duration_statement", Value: "-1"}, {Name: "log_statement", Value: "ddl"}}, ""},
+		{"valid sql server flag", "SQLSERVER_2019_STANDARD", []*DatabaseFlags{{Name: "user connections", Value: "100"}}, ""},
+		{"typo", "POSTGRES_15", []*DatabaseFlags{{Name: "log_min_duration_statment", Value: "100"}}, "invalid flag name"},
+		{"flag of another engine", "POSTGRES_15", []*DatabaseFlags{{Name: "slow_query_log", Value: "on"}}, "invalid flag name"},
+		{"not a boolean", "MYSQL_8_0", []*DatabaseFlags{{Name: "general_log", Value: "yes"}}, "invalid flag value"},
+		{"not an integer", "MYSQL_8_0", []*DatabaseFlags{{Name: "max_connections", Value: "many"}}, "invalid flag value"},
+		{"out of range", "MYSQL_8_0", []*DatabaseFlags{{Name: "max_connections", Value: "5"}}, "invalid flag value"},
+		{"not allowed", "MYSQL_8_0", []*DatabaseFlags{{Name: "log_output", Value: "SYSLOG"}}, "invalid flag value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateFlags(tt.version, tt.flags)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateFlags() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateFlags() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,7 +1,11 @@
 // Package sqladmin provides data models for the Google Cloud SQL Admin API mock.
 package sqladmin
 
-import "time"
+import (
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/gcperror"
+)
 
 // DatabaseInstance represents a Cloud SQL database instance.
 // Based on the official Cloud SQL Admin API v1 specification.
@@ -9,8 +13,10 @@ import "time"
 type DatabaseInstance struct {
 	// Kind is the kind of resource. For instances, this is always "sql#instance".
 	Kind string `json:"kind"`
-	// State is the current serving state of the instance.
+	// State is the current serving state of the instance, e.g. RUNNABLE, STOPPED, SUSPENDED or MAINTENANCE.
 	State string `json:"state"`
+	// SuspensionReason lists why a SUSPENDED instance is suspended, e.g. BILLING_ISSUE.
+	SuspensionReason []string `json:"suspensionReason,omitempty"`
 	// DatabaseVersion is the database engine type and version (e.g., "MYSQL_8_0").
 	DatabaseVersion string `json:"databaseVersion"`
 	// Settings contains user settings for the instance.
@@ -549,6 +555,9 @@ type InstanceInsertRequest struct {
 type InstancePatchRequest struct {
 	// Settings contains the settings for the instance.
 	Settings *Settings `json:"settings,omitempty"`
+	// Etag is the etag of the instance the patch is based on. If set, the patch fails unless it is
+	// still the etag of the instance.
+	Etag string `json:"etag,omitempty"`
 }
 
 // DatabaseInsertRequest represents the request body for creating a database.
@@ -591,21 +600,10 @@ type UserUpdateRequest struct {
 
 // APIError represents an error response from the Cloud SQL Admin API.
 // Reference: https://cloud.google.com/sql/docs/mysql/admin-api/rest/v1/ErrorResponse
-type APIError struct {
-	Error ErrorDetails `json:"error"`
-}
+type APIError = gcperror.Response
 
 // ErrorDetails contains the details of an API error.
-type ErrorDetails struct {
-	Code    int           `json:"code"`
-	Message string        `json:"message"`
-	Status  string        `json:"status,omitempty"`
-	Errors  []ErrorReason `json:"errors,omitempty"`
-}
+type ErrorDetails = gcperror.Details
 
 // ErrorReason contains the reason for an error.
-type ErrorReason struct {
-	Domain  string `json:"domain"`
-	Reason  string `json:"reason"`
-	Message string `json:"message"`
-}
+type ErrorReason = gcperror.Reason
@@ -0,0 +1,113 @@
+package sqladmin
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Editions of Cloud SQL instances.
+const (
+	EditionEnterprise     = "ENTERPRISE"
+	EditionEnterprisePlus = "ENTERPRISE_PLUS"
+)
+
+// regions lists the regions Cloud SQL instances can be created in.
+// Reference: https://cloud.google.com/sql/docs/mysql/locations
+var regions = []string{
+	"africa-south1",
+	"asia-east1", "asia-east2", "asia-northeast1", "asia-northeast2", "asia-northeast3",
+	"asia-south1", "asia-south2", "asia-southeast1", "asia-southeast2",
+	"australia-southeast1", "australia-southeast2",
+	"europe-central2", "europe-north1", "europe-north2", "europe-southwest1",
+	"europe-west1", "europe-west2", "europe-west3", "europe-west4", "europe-west6",
+	"europe-west8", "europe-west9", "europe-west10", "europe-west12",
+	"me-central1", "me-central2", "me-west1",
+	"northamerica-northeast1", "northamerica-northeast2", "northamerica-south1",
+	"southamerica-east1", "southamerica-west1",
+	"us-central1", "us-east1", "us-east4", "us-east5", "us-south1",
+	"us-west1", "us-west2", "us-west3", "us-west4",
+}
+
+// Machine sizes of the predefined tiers.
+var (
+	n1StandardCPUs    = []int{1, 2, 4, 8, 16, 32, 64, 96}
+	n1HighmemCPUs     = []int{2, 4, 8, 16, 32, 64, 96}
+	perfOptimizedCPUs = []int{2, 4, 8, 16, 32, 48, 64, 80, 96, 128}
+)
+
+// ValidateRegion checks that Cloud SQL instances can be created in a region.
+// Returns an "invalid region" error otherwise.
+func ValidateRegion(region string) error {
+	if !slices.Contains(regions, region) {
+		return fmt.Errorf("invalid region %q: not a Cloud SQL region", region)
+	}
+	return nil
+}
+
+// ValidateTier checks that a tier (machine type) is available for a database version and edition.
+// An empty edition is ENTERPRISE. Returns an "invalid tier" error otherwise.
+// Reference: https://cloud.google.com/sql/docs/mysql/instance-settings#machine-type-2ndgen
+func ValidateTier(databaseVersion, edition, tier string) error {
+	if edition == "" {
+		edition = EditionEnterprise
+	}
+	if !isTierAvailable(databaseEngine(databaseVersion), edition, tier) {
+		return fmt.Errorf("invalid tier %q for database version %s and edition %s", tier, databaseVersion, edition)
+	}
+	return nil
+}
+
+// isTierAvailable reports whether a tier is available for a database engine and edition.
+func isTierAvailable(engine, edition, tier string) bool {
+	if edition == EditionEnterprisePlus {
+		cpus, ok := tierCPUs(tier, "db-perf-optimized-N-")
+		return ok && engine != "SQLSERVER" && slices.Contains(perfOptimizedCPUs, cpus)
+	}
+	if edition != EditionEnterprise {
+		return false
+	}
+
+	switch {
+	case tier == "db-f1-micro" || tier == "db-g1-small":
+		return engine != "SQLSERVER"
+	case strings.HasPrefix(tier, "db-n1-standard-"):
+		cpus, ok := tierCPUs(tier, "db-n1-standard-")
+		return ok && engine == "MYSQL" && slices.Contains(n1StandardCPUs, cpus)
+	case strings.HasPrefix(tier, "db-n1-highmem-"):
+		cpus, ok := tierCPUs(tier, "db-n1-highmem-")
+		return ok && engine == "MYSQL" && slices.Contains(n1HighmemCPUs, cpus)
+	case strings.HasPrefix(tier, "db-custom-"):
+		return isValidCustomTier(strings.TrimPrefix(tier, "db-custom-"))
+	}
+	return false
+}
+
+// tierCPUs returns the number of vCPUs of a predefined tier like db-n1-standard-4.
+func tierCPUs(tier, prefix string) (int, bool) {
+	value, found := strings.CutPrefix(tier, prefix)
+	if !found {
+		return 0, false
+	}
+	cpus, err := strconv.Atoi(value)
+	return cpus, err == nil
+}
+
+// isValidCustomTier checks the "{vCPUs}-{memoryMB}" suffix of a custom tier. Custom machines have 1 or
+// an even number of up to 96 vCPUs and 0.9 to 6.5 GB of memory per vCPU, in multiples of 256 MB.
+func isValidCustomTier(size string) bool {
+	cpuValue, memoryValue, found := strings.Cut(size, "-")
+	if !found {
+		return false
+	}
+	cpus, err := strconv.Atoi(cpuValue)
+	if err != nil || cpus < 1 || cpus > 96 || (cpus > 1 && cpus%2 != 0) {
+		return false
+	}
+	memoryMB, err := strconv.Atoi(memoryValue)
+	if err != nil || memoryMB%256 != 0 {
+		return false
+	}
+	return memoryMB >= max(3840, cpus*922) && memoryMB <= cpus*6656
+}
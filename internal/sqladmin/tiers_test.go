@@ -0,0 +1,55 @@
+package sqladmin
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateTier(t *testing.T) {
+	tests := []struct {
+		databaseVersion string
+		edition         string
+		tier            string
+		valid           bool
+	}{
+		{"MYSQL_8_0", "", "db-n1-standard-1", true},
+		{"MYSQL_8_0", "", "db-n1-highmem-16", true},
+		{"MYSQL_8_0", "", "db-n1-highmem-1", false},
+		{"POSTGRES_15", "", "db-n1-standard-1", false},
+		{"POSTGRES_15", "ENTERPRISE", "db-f1-micro", true},
+		{"SQLSERVER_2019_STANDARD", "", "db-g1-small", false},
+		{"POSTGRES_15", "", "db-custom-2-7680", true},
+		{"POSTGRES_15", "", "db-custom-1-3840", true},
+		{"POSTGRES_15", "", "db-custom-3-7680", false},
+		{"POSTGRES_15", "", "db-custom-2-1024", false},
+		{"POSTGRES_15", "", "db-custom-2-7000", false},
+		{"SQLSERVER_2019_STANDARD", "", "db-custom-4-16384", true},
+		{"POSTGRES_16", "ENTERPRISE_PLUS", "db-perf-optimized-N-8", true},
+		{"POSTGRES_16", "ENTERPRISE_PLUS", "db-custom-2-7680", false},
+		{"POSTGRES_16", "", "db-perf-optimized-N-8", false},
+		{"MYSQL_8_0", "", "db-standard-1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.databaseVersion+"/"+tt.edition+"/"+tt.tier, func(t *testing.T) {
+			err := ValidateTier(tt.databaseVersion, tt.edition, tt.tier)
+			if tt.valid && err != nil {
+				t.Errorf("expected tier to be valid, got %v", err)
+			}
+			if !tt.valid && (err == nil || !strings.Contains(err.Error(), "invalid tier")) {
+				t.Errorf("expected invalid tier error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateRegion(t *testing.T) {
+	if err := ValidateRegion("europe-west1"); err != nil {
+		t.Errorf("expected europe-west1 to be valid, got %v", err)
+	}
+	for _, region := range []string{"europe-west", "EU", "us-central1-a", ""} {
+		if err := ValidateRegion(region); err == nil || !strings.Contains(err.Error(), "invalid region") {
+			t.Errorf("expected invalid region error for %q, got %v", region, err)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+// Package sqldata backs the Cloud SQL databases of a store with SQLite database files, so integration tests can
+// create an instance and a database via the Admin API and then run queries against it. The mock only manages
+// the files: it creates an empty one for every database (SQLite treats an empty file as an empty database)
+// and removes it when the database or its instance is deleted. Tests open the files with a SQLite driver of
+// their own, so queries use the SQLite dialect, whatever the database version of the instance.
+package sqldata
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/katharinasick/gcp-api-mock/internal/store"
+)
+
+// Database is the SQLite file backing a Cloud SQL database.
+type Database struct {
+	// Instance is the name of the instance.
+	Instance string `json:"instance"`
+	// Database is the name of the database.
+	Database string `json:"database"`
+	// Driver is the database/sql driver name of SQLite drivers like github.com/mattn/go-sqlite3.
+	Driver string `json:"driver"`
+	// Path is the path of the SQLite file.
+	Path string `json:"path"`
+	// DSN is the data source name to open the file with, like "file:/data/main/app.sqlite".
+	DSN string `json:"dsn"`
+}
+
+// Files keeps a SQLite file for each Cloud SQL database of a store in a directory.
+// It is safe for concurrent access.
+type Files struct {
+	store *store.Store
+	dir   string
+
+	mu sync.Mutex
+	// databases is a map of "instance/database" to the file of that database
+	databases map[string]*Database
+}
+
+// New creates Files for the databases of a store in dir, creating the directory if it doesn't exist.
+// Files already in the directory are kept for the databases they belong to.
+// Call Sync to create the files of the existing databases.
+func New(s *store.Store, dir string) (*Files, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create database directory: %w", err)
+	}
+	return &Files{store: s, dir: dir, databases: make(map[string]*Database)}, nil
+}
+
+// Sync creates files for new databases and removes the files of deleted databases and instances.
+// Files that can't be created or removed are logged and tried again on the next sync. The store is read
+// while f.mu is held, so a sync that read an older state can't undo the changes of a concurrent one.
+func (f *Files) Sync() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	existing := make(map[string]bool)
+	for _, instance := range f.store.ListSQLInstances() {
+		databases, err := f.store.ListSQLDatabases(instance.Name)
+		if err != nil {
+			// The instance was deleted in the meantime
+			continue
+		}
+		for _, db := range databases {
+			key := instance.Name + "/" + db.Name
+			existing[key] = true
+			if _, ok := f.databases[key]; ok {
+				continue
+			}
+			file := f.database(instance.Name, db.Name)
+			if err := createFile(file.Path); err != nil {
+				log.Printf("Failed to create the SQLite file of database %s: %v", key, err)
+				continue
+			}
+			f.databases[key] = file
+		}
+	}
+
+	for key, db := range f.databases {
+		if existing[key] {
+			continue
+		}
+		if err := os.Remove(db.Path); err != nil && !os.IsNotExist(err) {
+			log.Printf("Failed to remove the SQLite file of database %s: %v", key, err)
+			continue
+		}
+		// The instance directory is removed along with its last database
+		os.Remove(filepath.Dir(db.Path))
+		delete(f.databases, key)
+	}
+}
+
+// database returns the file of a database. Names are escaped, so any name maps to a file in the directory.
+func (f *Files) database(instance, database string) *Database {
+	path := filepath.Join(f.dir, url.PathEscape(instance), url.PathEscape(database)+".sqlite")
+	return &Database{
+		Instance: instance,
+		Database: database,
+		Driver:   "sqlite3",
+		Path:     path,
+		DSN:      "file:" + filepath.ToSlash(path),
+	}
+}
+
+// createFile creates an empty file at path, keeping the content of an existing one.
+func createFile(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// Get returns the file of a database. Returns false if the database has none.
+func (f *Files) Get(instance, database string) (Database, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	db, ok := f.databases[instance+"/"+database]
+	if !ok {
+		return Database{}, false
+	}
+	return *db, true
+}
+
+// List returns the files of all databases, sorted by instance and database.
+func (f *Files) List() []Database {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	result := make([]Database, 0, len(f.databases))
+	for _, db := range f.databases {
+		result = append(result, *db)
+	}
+	slices.SortFunc(result, func(a, b Database) int {
+		if c := strings.Compare(a.Instance, b.Instance); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Database, b.Database)
+	})
+	return result
+}
+
+// Middleware syncs the files after requests that may create or delete databases: Cloud SQL Admin API and
+// admin API requests, since e.g. resets and snapshot restores replace the databases.
+func (f *Files) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+		if strings.HasPrefix(r.URL.Path, "/sql/") || strings.HasPrefix(r.URL.Path, "/admin/") {
+			f.Sync()
+		}
+	})
+}
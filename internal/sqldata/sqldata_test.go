@@ -0,0 +1,84 @@
+package sqldata
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/katharinasick/gcp-api-mock/internal/sqladmin"
+	"github.com/katharinasick/gcp-api-mock/internal/store"
+)
+
+func TestFiles(t *testing.T) {
+	dir := t.TempDir()
+	s := store.New()
+	s.CreateSQLInstance(&sqladmin.InstanceInsertRequest{Name: "main", DatabaseVersion: "POSTGRES_15"})
+	s.CreateSQLDatabase("main", &sqladmin.DatabaseInsertRequest{Name: "app"})
+
+	f, err := New(s, dir)
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	f.Sync()
+
+	db, ok := f.Get("main", "app")
+	if !ok {
+		t.Fatalf("expected a file for database app, got %+v", f.List())
+	}
+	if db.Path != filepath.Join(dir, "main", "app.sqlite") || db.DSN != "file:"+filepath.ToSlash(db.Path) || db.Driver != "sqlite3" {
+		t.Errorf("unexpected database file: %+v", db)
+	}
+	if info, err := os.Stat(db.Path); err != nil || info.Size() != 0 {
+		t.Errorf("expected an empty file at %s, got %v", db.Path, err)
+	}
+
+	// Content written by tests is kept across syncs
+	if err := os.WriteFile(db.Path, []byte("data"), 0o644); err != nil {
+		t.Fatalf("failed to write the file: %v", err)
+	}
+	f.Sync()
+	if content, _ := os.ReadFile(db.Path); string(content) != "data" {
+		t.Errorf("expected the content to be kept, got %q", content)
+	}
+
+	// The files of deleted databases are removed
+	s.DeleteSQLDatabase("main", "app")
+	f.Sync()
+	if _, ok := f.Get("main", "app"); ok {
+		t.Error("expected no file for a deleted database")
+	}
+	if _, err := os.Stat(db.Path); !os.IsNotExist(err) {
+		t.Errorf("expected the file to be removed, got %v", err)
+	}
+
+	// And so are the files of deleted instances, with their directory
+	s.DeleteSQLInstance("main")
+	f.Sync()
+	if databases := f.List(); len(databases) != 0 {
+		t.Errorf("expected no files, got %+v", databases)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "main")); !os.IsNotExist(err) {
+		t.Errorf("expected the instance directory to be removed, got %v", err)
+	}
+}
+
+func TestFiles_EscapesNames(t *testing.T) {
+	dir := t.TempDir()
+	s := store.New()
+	s.CreateSQLInstance(&sqladmin.InstanceInsertRequest{Name: "main"})
+	s.CreateSQLDatabase("main", &sqladmin.DatabaseInsertRequest{Name: "../escape"})
+
+	f, err := New(s, dir)
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	f.Sync()
+
+	db, ok := f.Get("main", "../escape")
+	if !ok {
+		t.Fatalf("expected a file for database ../escape, got %+v", f.List())
+	}
+	if filepath.Dir(db.Path) != filepath.Join(dir, "main") {
+		t.Errorf("expected the file in the instance directory, got %s", db.Path)
+	}
+}
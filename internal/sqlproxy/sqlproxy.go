@@ -0,0 +1,318 @@
+// Package sqlproxy gives every Cloud SQL instance of a store a TCP port, so code that builds connection strings
+// from the Admin API can open sockets to the instances. Connections are forwarded to a database configured for
+// the instance's connection name, like a local Postgres container, or closed right away if there is none.
+// Connections to instances that aren't RUNNABLE are closed right away too, like the real instance would refuse them.
+package sqlproxy
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/katharinasick/gcp-api-mock/internal/store"
+)
+
+// PortRange is the range of ports the instances get.
+type PortRange struct {
+	// First is the first port of the range; 0 lets the system pick a free port for each instance.
+	First int
+	// Last is the last port of the range.
+	Last int
+}
+
+// ParsePortRange parses a port range like "13306-13399", a single port like "13306", or "0" to let the system
+// pick the ports.
+func ParsePortRange(value string) (PortRange, error) {
+	first, last, isRange := strings.Cut(value, "-")
+	firstPort, err := strconv.Atoi(strings.TrimSpace(first))
+	if err != nil || firstPort < 0 || firstPort > 65535 {
+		return PortRange{}, fmt.Errorf("invalid port range %q: ports must be between 0 and 65535", value)
+	}
+	lastPort := firstPort
+	if isRange {
+		lastPort, err = strconv.Atoi(strings.TrimSpace(last))
+		if err != nil || lastPort < firstPort || lastPort > 65535 || firstPort == 0 {
+			return PortRange{}, fmt.Errorf("invalid port range %q: expected a range like 13306-13399", value)
+		}
+	}
+	return PortRange{First: firstPort, Last: lastPort}, nil
+}
+
+// ParseTargets parses the databases connections are forwarded to, like
+// "my-project:us-central1:main=localhost:5432,my-project:us-central1:other=db:3306".
+func ParseTargets(value string) (map[string]string, error) {
+	targets := make(map[string]string)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		connectionName, target, ok := strings.Cut(entry, "=")
+		if !ok || connectionName == "" || target == "" {
+			return nil, fmt.Errorf("invalid proxy target %q: expected connectionName=host:port", entry)
+		}
+		if _, _, err := net.SplitHostPort(target); err != nil {
+			return nil, fmt.Errorf("invalid proxy target %q: %v", entry, err)
+		}
+		targets[connectionName] = target
+	}
+	return targets, nil
+}
+
+// Listener is the port of an instance.
+type Listener struct {
+	// ConnectionName is the connection name of the instance, like "my-project:us-central1:main".
+	ConnectionName string `json:"connectionName"`
+	// Instance is the name of the instance.
+	Instance string `json:"instance"`
+	// State is the state of the instance; only connections to RUNNABLE instances are forwarded.
+	State string `json:"state"`
+	// Address is the address the port listens on.
+	Address string `json:"address"`
+	// Port is the port of the instance.
+	Port int `json:"port"`
+	// Target is the database connections are forwarded to, or empty if they're closed right away.
+	Target string `json:"target,omitempty"`
+	// Connections is the number of open connections.
+	Connections int `json:"connections"`
+}
+
+// listener is the port of an instance and its open connections.
+type listener struct {
+	net.Listener
+	connectionName string
+	instance       string
+	state          string
+	conns          map[net.Conn]bool
+}
+
+// Proxy listens on a port for each Cloud SQL instance of a store.
+// It is safe for concurrent access.
+type Proxy struct {
+	store *store.Store
+	host  string
+	ports PortRange
+
+	mu sync.Mutex
+	// listeners is a map of connection name to the port of that instance
+	listeners map[string]*listener
+	// targets is a map of connection name to the database its connections are forwarded to
+	targets map[string]string
+	closed  bool
+}
+
+// New creates a Proxy for the instances of a store, listening on host with ports from the range.
+// Connections to the instances with the given connection names are forwarded to the target databases.
+// Call Sync to open the ports of the existing instances.
+func New(s *store.Store, host string, ports PortRange, targets map[string]string) *Proxy {
+	p := &Proxy{
+		store:     s,
+		host:      host,
+		ports:     ports,
+		listeners: make(map[string]*listener),
+		targets:   make(map[string]string),
+	}
+	for connectionName, target := range targets {
+		p.targets[connectionName] = target
+	}
+	return p
+}
+
+// Sync opens ports for new instances and closes the ports of deleted ones, with their connections.
+// Ports that can't be opened are logged and tried again on the next sync.
+func (p *Proxy) Sync() {
+	instances := p.store.ListSQLInstances()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return
+	}
+
+	existing := make(map[string]bool)
+	for _, instance := range instances {
+		existing[instance.ConnectionName] = true
+		if l, ok := p.listeners[instance.ConnectionName]; ok {
+			l.state = instance.State
+			continue
+		}
+
+		netListener, err := p.listen()
+		if err != nil {
+			log.Printf("Failed to open a Cloud SQL proxy port for %s: %v", instance.ConnectionName, err)
+			continue
+		}
+		l := &listener{
+			Listener:       netListener,
+			connectionName: instance.ConnectionName,
+			instance:       instance.Name,
+			state:          instance.State,
+			conns:          make(map[net.Conn]bool),
+		}
+		p.listeners[instance.ConnectionName] = l
+		log.Printf("Cloud SQL proxy for %s listening on %s", instance.ConnectionName, netListener.Addr())
+		go p.serve(l)
+	}
+
+	for connectionName, l := range p.listeners {
+		if !existing[connectionName] {
+			p.closeListener(l)
+		}
+	}
+}
+
+// listen opens the first free port of the range.
+// Must be called with the lock held.
+func (p *Proxy) listen() (net.Listener, error) {
+	if p.ports.First == 0 {
+		return net.Listen("tcp", net.JoinHostPort(p.host, "0"))
+	}
+
+	used := make(map[int]bool)
+	for _, l := range p.listeners {
+		used[l.Addr().(*net.TCPAddr).Port] = true
+	}
+	for port := p.ports.First; port <= p.ports.Last; port++ {
+		if used[port] {
+			continue
+		}
+		if l, err := net.Listen("tcp", net.JoinHostPort(p.host, strconv.Itoa(port))); err == nil {
+			return l, nil
+		}
+	}
+	return nil, fmt.Errorf("no free port between %d and %d", p.ports.First, p.ports.Last)
+}
+
+// closeListener closes a port and its connections and forgets it.
+// Must be called with the lock held.
+func (p *Proxy) closeListener(l *listener) {
+	l.Close()
+	for conn := range l.conns {
+		conn.Close()
+	}
+	delete(p.listeners, l.connectionName)
+}
+
+// serve accepts connections on a port until it is closed.
+func (p *Proxy) serve(l *listener) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go p.handle(l, conn)
+	}
+}
+
+// handle forwards a connection to the target of its instance, or closes it if the instance has none
+// or isn't RUNNABLE.
+func (p *Proxy) handle(l *listener, conn net.Conn) {
+	p.mu.Lock()
+	target := p.targets[l.connectionName]
+	if target == "" || l.state != "RUNNABLE" || p.listeners[l.connectionName] != l {
+		p.mu.Unlock()
+		conn.Close()
+		return
+	}
+	l.conns[conn] = true
+	p.mu.Unlock()
+
+	defer func() {
+		p.mu.Lock()
+		delete(l.conns, conn)
+		p.mu.Unlock()
+		conn.Close()
+	}()
+
+	upstream, err := net.Dial("tcp", target)
+	if err != nil {
+		log.Printf("Cloud SQL proxy for %s failed to connect to %s: %v", l.connectionName, target, err)
+		return
+	}
+	defer upstream.Close()
+
+	// Forward both directions until either side closes the connection
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(upstream, conn)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(conn, upstream)
+		done <- struct{}{}
+	}()
+	<-done
+}
+
+// List returns the ports of the instances, sorted by connection name.
+func (p *Proxy) List() []Listener {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	result := make([]Listener, 0, len(p.listeners))
+	for _, l := range p.listeners {
+		addr := l.Addr().(*net.TCPAddr)
+		result = append(result, Listener{
+			ConnectionName: l.connectionName,
+			Instance:       l.instance,
+			State:          l.state,
+			Address:        addr.String(),
+			Port:           addr.Port,
+			Target:         p.targets[l.connectionName],
+			Connections:    len(l.conns),
+		})
+	}
+	slices.SortFunc(result, func(a, b Listener) int {
+		return strings.Compare(a.ConnectionName, b.ConnectionName)
+	})
+	return result
+}
+
+// SetTarget forwards new connections to the instance with the connection name to a database, like "localhost:5432".
+// The instance doesn't have to exist yet. An empty target closes new connections right away.
+func (p *Proxy) SetTarget(connectionName, target string) error {
+	if target != "" {
+		if _, _, err := net.SplitHostPort(target); err != nil {
+			return fmt.Errorf("invalid target %q: %v", target, err)
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if target == "" {
+		delete(p.targets, connectionName)
+	} else {
+		p.targets[connectionName] = target
+	}
+	return nil
+}
+
+// Middleware syncs the ports after requests that may create or delete instances or change their state:
+// Cloud SQL Admin API and admin API requests, since e.g. resets and snapshot restores replace the instances.
+func (p *Proxy) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+		if strings.HasPrefix(r.URL.Path, "/sql/") || strings.HasPrefix(r.URL.Path, "/admin/") {
+			p.Sync()
+		}
+	})
+}
+
+// Close closes all ports and their connections. Later syncs don't open new ones.
+func (p *Proxy) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.closed = true
+	for _, l := range p.listeners {
+		p.closeListener(l)
+	}
+}
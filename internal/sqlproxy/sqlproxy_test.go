@@ -0,0 +1,185 @@
+package sqlproxy
+
+import (
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/sqladmin"
+	"github.com/katharinasick/gcp-api-mock/internal/store"
+)
+
+func TestParsePortRange(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    PortRange
+		wantErr bool
+	}{
+		{"13306-13399", PortRange{First: 13306, Last: 13399}, false},
+		{"13306", PortRange{First: 13306, Last: 13306}, false},
+		{"0", PortRange{}, false},
+		{"13399-13306", PortRange{}, true},
+		{"0-100", PortRange{}, true},
+		{"70000", PortRange{}, true},
+		{"mysql", PortRange{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParsePortRange(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePortRange() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParsePortRange() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTargets(t *testing.T) {
+	targets, err := ParseTargets("p:us-central1:main=localhost:5432, p:europe-west1:other=db:3306")
+	if err != nil {
+		t.Fatalf("ParseTargets() error: %v", err)
+	}
+	if len(targets) != 2 || targets["p:us-central1:main"] != "localhost:5432" || targets["p:europe-west1:other"] != "db:3306" {
+		t.Errorf("unexpected targets: %v", targets)
+	}
+
+	for _, value := range []string{"p:us-central1:main", "p:us-central1:main=localhost", "=localhost:5432"} {
+		if _, err := ParseTargets(value); err == nil {
+			t.Errorf("ParseTargets(%q): expected an error", value)
+		}
+	}
+}
+
+// startEchoServer starts a TCP server that sends back what it receives, standing in for a database.
+func startEchoServer(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return l.Addr().String()
+}
+
+// expectClosed checks that the proxy closes a connection to the port right away.
+func expectClosed(t *testing.T, port int) {
+	t.Helper()
+	conn, err := net.Dial("tcp", "127.0.0.1:"+strconv.Itoa(port))
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("expected the connection to be closed, got %v", err)
+	}
+}
+
+func TestProxy(t *testing.T) {
+	s := store.New()
+	main, _, _ := s.CreateSQLInstance(&sqladmin.InstanceInsertRequest{Name: "main", DatabaseVersion: "POSTGRES_15"})
+	other, _, _ := s.CreateSQLInstance(&sqladmin.InstanceInsertRequest{Name: "other", DatabaseVersion: "MYSQL_8_0"})
+
+	p := New(s, "127.0.0.1", PortRange{}, map[string]string{main.ConnectionName: startEchoServer(t)})
+	defer p.Close()
+	p.Sync()
+
+	listeners := p.List()
+	if len(listeners) != 2 {
+		t.Fatalf("expected 2 ports, got %+v", listeners)
+	}
+	ports := make(map[string]int)
+	for _, l := range listeners {
+		ports[l.Instance] = l.Port
+	}
+
+	// Connections to an instance with a target are forwarded
+	conn, err := net.Dial("tcp", "127.0.0.1:"+strconv.Itoa(ports["main"]))
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Write([]byte("ping")); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != "ping" {
+		t.Errorf("expected the target to answer ping, got %q, %v", buf, err)
+	}
+	conn.Close()
+
+	// Connections to an instance without a target are closed right away
+	expectClosed(t, ports["other"])
+
+	// So are connections to instances that aren't RUNNABLE
+	s.SetSQLInstanceState("main", "MAINTENANCE", nil)
+	p.Sync()
+	expectClosed(t, ports["main"])
+
+	// The ports of deleted instances are closed
+	s.DeleteSQLInstance(other.Name)
+	p.Sync()
+	if listeners := p.List(); len(listeners) != 1 || listeners[0].Instance != "main" {
+		t.Errorf("expected only the port of main, got %+v", listeners)
+	}
+	if conn, err := net.Dial("tcp", "127.0.0.1:"+strconv.Itoa(ports["other"])); err == nil {
+		conn.Close()
+		t.Error("expected the port of a deleted instance to be closed")
+	}
+}
+
+func TestProxy_PortRange(t *testing.T) {
+	// Find a free port
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	s := store.New()
+	s.CreateSQLInstance(&sqladmin.InstanceInsertRequest{Name: "first"})
+	s.CreateSQLInstance(&sqladmin.InstanceInsertRequest{Name: "second"})
+
+	p := New(s, "127.0.0.1", PortRange{First: port, Last: port}, nil)
+	defer p.Close()
+	p.Sync()
+
+	// Only one instance fits into the range
+	listeners := p.List()
+	if len(listeners) != 1 || listeners[0].Port != port {
+		t.Errorf("expected one instance on port %d, got %+v", port, listeners)
+	}
+}
+
+func TestProxy_SetTarget(t *testing.T) {
+	p := New(store.New(), "127.0.0.1", PortRange{}, nil)
+	defer p.Close()
+
+	if err := p.SetTarget("p:us-central1:main", "localhost"); err == nil {
+		t.Error("expected a target without port to be rejected")
+	}
+	if err := p.SetTarget("p:us-central1:main", "localhost:5432"); err != nil {
+		t.Errorf("SetTarget() error: %v", err)
+	}
+	if err := p.SetTarget("p:us-central1:main", ""); err != nil {
+		t.Errorf("SetTarget() error: %v", err)
+	}
+}
@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ObjectAccessControl is an entry of an object's access control list.
+// Reference: https://cloud.google.com/storage/docs/json_api/v1/objectAccessControls
+type ObjectAccessControl struct {
+	// Kind is the kind of item this is. For object ACL entries, this is always "storage#objectAccessControl".
+	Kind string `json:"kind"`
+	// ID is the ID of the entry, including the bucket name, object name, generation and entity.
+	ID string `json:"id,omitempty"`
+	// SelfLink is the link to this entry.
+	SelfLink string `json:"selfLink,omitempty"`
+	// Bucket is the name of the bucket containing the object.
+	Bucket string `json:"bucket,omitempty"`
+	// Object is the name of the object.
+	Object string `json:"object,omitempty"`
+	// Generation is the content generation of the object.
+	Generation int64 `json:"generation,omitempty,string"`
+	// Entity is who the entry grants access to, like "user-jane@example.com" or "allUsers".
+	Entity string `json:"entity"`
+	// Role is the access granted to the entity: OWNER or READER.
+	Role string `json:"role"`
+	// Email is the email address of a user or group entity.
+	Email string `json:"email,omitempty"`
+	// Domain is the domain of a domain entity.
+	Domain string `json:"domain,omitempty"`
+	// ProjectTeam is the project team of a project entity.
+	ProjectTeam *ProjectTeam `json:"projectTeam,omitempty"`
+}
+
+// ProjectTeam is the project team an ACL entity like "project-owners-123456789012" stands for.
+type ProjectTeam struct {
+	// ProjectNumber is the project number.
+	ProjectNumber string `json:"projectNumber"`
+	// Team is the team: owners, editors or viewers.
+	Team string `json:"team"`
+}
+
+// ObjectAccessControls represents the access control list of an object.
+// Reference: https://cloud.google.com/storage/docs/json_api/v1/objectAccessControls/list
+type ObjectAccessControls struct {
+	// Kind is the kind of item this is. For object ACLs, this is always "storage#objectAccessControls".
+	Kind string `json:"kind"`
+	// Items is the list of ACL entries.
+	Items []ObjectAccessControl `json:"items"`
+}
+
+// Object ACL roles.
+const (
+	RoleOwner  = "OWNER"
+	RoleReader = "READER"
+)
+
+// Special ACL entities, which stand for everyone and for everyone signed in with a Google account.
+const (
+	EntityAllUsers              = "allUsers"
+	EntityAllAuthenticatedUsers = "allAuthenticatedUsers"
+)
+
+// PredefinedObjectACL returns the ACL entries of a predefined object ACL, like publicRead, for a
+// bucket of the given project. The mock doesn't know who uploads an object, so the owners of the
+// project own it; the ACLs that grant the bucket owner access are the same as private.
+// Returns an "invalid predefined ACL" error for unknown names.
+// Reference: https://cloud.google.com/storage/docs/access-control/lists#predefined-acl
+func PredefinedObjectACL(name string, projectNumber uint64) ([]ObjectAccessControl, error) {
+	team := func(team, role string) ObjectAccessControl {
+		return ObjectAccessControl{Entity: fmt.Sprintf("project-%s-%d", team, projectNumber), Role: role}
+	}
+	owner := team("owners", RoleOwner)
+
+	switch name {
+	case "private", "bucketOwnerRead", "bucketOwnerFullControl":
+		return []ObjectAccessControl{owner}, nil
+	case "projectPrivate":
+		return []ObjectAccessControl{owner, team("editors", RoleOwner), team("viewers", RoleReader)}, nil
+	case "publicRead":
+		return []ObjectAccessControl{owner, {Entity: EntityAllUsers, Role: RoleReader}}, nil
+	case "authenticatedRead":
+		return []ObjectAccessControl{owner, {Entity: EntityAllAuthenticatedUsers, Role: RoleReader}}, nil
+	default:
+		return nil, fmt.Errorf("invalid predefined ACL %q", name)
+	}
+}
+
+// xmlCannedACLs maps the canned ACLs of the XML API's x-goog-acl header to the predefined ACLs of the JSON API.
+var xmlCannedACLs = map[string]string{
+	"private":                   "private",
+	"project-private":           "projectPrivate",
+	"public-read":               "publicRead",
+	"authenticated-read":        "authenticatedRead",
+	"bucket-owner-read":         "bucketOwnerRead",
+	"bucket-owner-full-control": "bucketOwnerFullControl",
+}
+
+// PredefinedObjectACLFromXML returns the predefined ACL name for a canned ACL of the XML API, like "public-read".
+// Unknown canned ACLs are returned as is, so they are rejected as invalid predefined ACLs.
+func PredefinedObjectACLFromXML(cannedACL string) string {
+	if name, ok := xmlCannedACLs[cannedACL]; ok {
+		return name
+	}
+	return cannedACL
+}
+
+// ParseObjectACLEntry validates the entity and role of an ACL entry and returns the entry with the
+// fields derived from the entity, like the email address of a user.
+// Returns an "invalid ACL" error naming the invalid field.
+func ParseObjectACLEntry(entry ObjectAccessControl) (ObjectAccessControl, error) {
+	parsed := ObjectAccessControl{Entity: entry.Entity, Role: entry.Role}
+
+	if entry.Role != RoleOwner && entry.Role != RoleReader {
+		return parsed, fmt.Errorf("invalid ACL role %q: must be OWNER or READER", entry.Role)
+	}
+
+	kind, value, _ := strings.Cut(entry.Entity, "-")
+	switch {
+	case entry.Entity == EntityAllUsers || entry.Entity == EntityAllAuthenticatedUsers:
+	case (kind == "user" || kind == "group") && value != "":
+		if strings.Contains(value, "@") {
+			parsed.Email = value
+		}
+	case kind == "domain" && value != "":
+		parsed.Domain = value
+	case kind == "project":
+		team, projectNumber, _ := strings.Cut(value, "-")
+		if (team != "owners" && team != "editors" && team != "viewers") || projectNumber == "" {
+			return parsed, fmt.Errorf("invalid ACL entity %q: project entities must look like project-owners-{project}", entry.Entity)
+		}
+		parsed.ProjectTeam = &ProjectTeam{ProjectNumber: projectNumber, Team: team}
+	default:
+		return parsed, fmt.Errorf("invalid ACL entity %q", entry.Entity)
+	}
+	return parsed, nil
+}
+
+// IsPublicACL reports whether an ACL grants access to allUsers or allAuthenticatedUsers.
+func IsPublicACL(acl []ObjectAccessControl) bool {
+	for _, entry := range acl {
+		if entry.Entity == EntityAllUsers || entry.Entity == EntityAllAuthenticatedUsers {
+			return true
+		}
+	}
+	return false
+}
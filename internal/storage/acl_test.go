@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPredefinedObjectACL(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"private", "project-owners-123:OWNER"},
+		{"bucketOwnerFullControl", "project-owners-123:OWNER"},
+		{"projectPrivate", "project-owners-123:OWNER,project-editors-123:OWNER,project-viewers-123:READER"},
+		{"publicRead", "project-owners-123:OWNER,allUsers:READER"},
+		{"authenticatedRead", "project-owners-123:OWNER,allAuthenticatedUsers:READER"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			acl, err := PredefinedObjectACL(tt.name, 123)
+			if err != nil {
+				t.Fatalf("PredefinedObjectACL() error: %v", err)
+			}
+			var entries []string
+			for _, entry := range acl {
+				entries = append(entries, entry.Entity+":"+entry.Role)
+			}
+			if got := strings.Join(entries, ","); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+
+	if _, err := PredefinedObjectACL("public-read", 123); err == nil || !strings.Contains(err.Error(), "invalid predefined ACL") {
+		t.Errorf("expected invalid predefined ACL error, got %v", err)
+	}
+	if name := PredefinedObjectACLFromXML("public-read"); name != "publicRead" {
+		t.Errorf("expected canned ACL public-read to be publicRead, got %s", name)
+	}
+}
+
+func TestParseObjectACLEntry(t *testing.T) {
+	tests := []struct {
+		entity      string
+		role        string
+		expectError bool
+		check       func(ObjectAccessControl) bool
+	}{
+		{entity: "allUsers", role: RoleReader},
+		{entity: "allAuthenticatedUsers", role: RoleReader},
+		{entity: "user-jane@example.com", role: RoleOwner, check: func(e ObjectAccessControl) bool { return e.Email == "jane@example.com" }},
+		{entity: "group-devs@example.com", role: RoleReader, check: func(e ObjectAccessControl) bool { return e.Email == "devs@example.com" }},
+		{entity: "domain-example.com", role: RoleReader, check: func(e ObjectAccessControl) bool { return e.Domain == "example.com" }},
+		{entity: "project-viewers-123", role: RoleReader, check: func(e ObjectAccessControl) bool {
+			return e.ProjectTeam != nil && e.ProjectTeam.Team == "viewers" && e.ProjectTeam.ProjectNumber == "123"
+		}},
+		{entity: "allUsers", role: "WRITER", expectError: true},
+		{entity: "project-admins-123", role: RoleReader, expectError: true},
+		{entity: "everyone", role: RoleReader, expectError: true},
+		{entity: "user-", role: RoleReader, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.entity+":"+tt.role, func(t *testing.T) {
+			entry, err := ParseObjectACLEntry(ObjectAccessControl{Entity: tt.entity, Role: tt.role})
+			if tt.expectError {
+				if err == nil || !strings.Contains(err.Error(), "invalid ACL") {
+					t.Errorf("expected invalid ACL error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseObjectACLEntry() error: %v", err)
+			}
+			if tt.check != nil && !tt.check(entry) {
+				t.Errorf("unexpected entry %+v", entry)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package storage
+
+import "fmt"
+
+// Public access prevention settings of a bucket's IAM configuration. Buckets without an explicit
+// setting inherit it from the organization policy, which the mock doesn't have, so only enforced
+// buckets reject public access.
+// Reference: https://cloud.google.com/storage/docs/public-access-prevention
+const (
+	PublicAccessPreventionInherited = "inherited"
+	PublicAccessPreventionEnforced  = "enforced"
+)
+
+// Special IAM members, which stand for everyone and for everyone signed in with a Google account.
+const (
+	MemberAllUsers              = "allUsers"
+	MemberAllAuthenticatedUsers = "allAuthenticatedUsers"
+)
+
+// Policy is the IAM policy of a bucket.
+// Reference: https://cloud.google.com/storage/docs/json_api/v1/buckets/getIamPolicy
+type Policy struct {
+	// Kind is the kind of item this is. For policies, this is always "storage#policy".
+	Kind string `json:"kind"`
+	// ResourceID is the resource the policy applies to, like "projects/_/buckets/my-bucket".
+	ResourceID string `json:"resourceId"`
+	// Version is the IAM policy format version; version 3 is required for conditions.
+	Version int `json:"version,omitempty"`
+	// Bindings associate roles with members.
+	Bindings []PolicyBinding `json:"bindings"`
+	// Etag is the HTTP 1.1 Entity tag for the policy. If set when a policy is replaced,
+	// it must match the etag of the current policy.
+	Etag string `json:"etag,omitempty"`
+}
+
+// PolicyBinding grants a role to members, optionally under a condition.
+type PolicyBinding struct {
+	// Role is the role, like "roles/storage.objectViewer".
+	Role string `json:"role"`
+	// Members are who the role is granted to, like "user:jane@example.com" or "allUsers".
+	Members []string `json:"members"`
+	// Condition restricts when the binding applies.
+	Condition *Expr `json:"condition,omitempty"`
+}
+
+// Expr is a condition of a policy binding in the Common Expression Language.
+type Expr struct {
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Expression  string `json:"expression"`
+}
+
+// PublicAccessPreventionEnforced reports whether the bucket rejects IAM policies and ACLs that grant
+// access to allUsers or allAuthenticatedUsers.
+func (b *Bucket) PublicAccessPreventionEnforced() bool {
+	return b.IamConfiguration != nil && b.IamConfiguration.PublicAccessPrevention == PublicAccessPreventionEnforced
+}
+
+// ValidateIamConfiguration checks the public access prevention setting of a bucket IAM configuration.
+// "unspecified", the former name of "inherited", is accepted as well.
+func ValidateIamConfiguration(iam *IamConfiguration) error {
+	if iam == nil {
+		return nil
+	}
+	switch iam.PublicAccessPrevention {
+	case "", PublicAccessPreventionInherited, PublicAccessPreventionEnforced, "unspecified":
+		return nil
+	}
+	return fmt.Errorf("invalid public access prevention %q: must be inherited or enforced", iam.PublicAccessPrevention)
+}
+
+// IsPublicPolicy reports whether a policy grants a role to allUsers or allAuthenticatedUsers.
+func IsPublicPolicy(policy *Policy) bool {
+	for _, binding := range policy.Bindings {
+		for _, member := range binding.Members {
+			if member == MemberAllUsers || member == MemberAllAuthenticatedUsers {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// DefaultBucketPolicy returns the IAM policy of a new bucket in a project, which grants the project's
+// owners and editors the legacy bucket owner role and its viewers the legacy bucket reader role.
+func DefaultBucketPolicy(bucketName, projectID string) *Policy {
+	return &Policy{
+		Kind:       "storage#policy",
+		ResourceID: "projects/_/buckets/" + bucketName,
+		Version:    1,
+		Bindings: []PolicyBinding{
+			{Role: "roles/storage.legacyBucketOwner", Members: []string{"projectEditor:" + projectID, "projectOwner:" + projectID}},
+			{Role: "roles/storage.legacyBucketReader", Members: []string{"projectViewer:" + projectID}},
+		},
+	}
+}
@@ -0,0 +1,38 @@
+package storage
+
+import "testing"
+
+func TestValidateIamConfiguration(t *testing.T) {
+	tests := []struct {
+		prevention string
+		valid      bool
+	}{
+		{"", true},
+		{PublicAccessPreventionInherited, true},
+		{PublicAccessPreventionEnforced, true},
+		{"unspecified", true},
+		{"ENFORCED", false},
+		{"on", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.prevention, func(t *testing.T) {
+			err := ValidateIamConfiguration(&IamConfiguration{PublicAccessPrevention: tt.prevention})
+			if tt.valid != (err == nil) {
+				t.Errorf("expected valid %v, got %v", tt.valid, err)
+			}
+		})
+	}
+}
+
+func TestIsPublicPolicy(t *testing.T) {
+	policy := DefaultBucketPolicy("my-bucket", "my-project")
+	if IsPublicPolicy(policy) {
+		t.Error("expected the default policy not to be public")
+	}
+
+	policy.Bindings = append(policy.Bindings, PolicyBinding{Role: "roles/storage.objectViewer", Members: []string{MemberAllUsers}})
+	if !IsPublicPolicy(policy) {
+		t.Error("expected a policy granting allUsers to be public")
+	}
+}
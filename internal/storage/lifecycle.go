@@ -0,0 +1,188 @@
+package storage
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Lifecycle action types.
+// Reference: https://cloud.google.com/storage/docs/lifecycle#actions
+const (
+	LifecycleActionDelete          = "Delete"
+	LifecycleActionSetStorageClass = "SetStorageClass"
+)
+
+// lifecycleDateFormat is the format of the date conditions, like createdBefore.
+const lifecycleDateFormat = "2006-01-02"
+
+// LifecycleObjectState is the state of an object version that lifecycle conditions depend on,
+// besides its metadata.
+type LifecycleObjectState struct {
+	// Live is true for the live version of an object and false for noncurrent versions.
+	Live bool
+	// NoncurrentTime is the time a noncurrent version was replaced or deleted.
+	NoncurrentTime time.Time
+	// NewerVersions is the number of versions of the object that are newer than this one.
+	NewerVersions int
+	// Held is true if the object is under an event-based or temporary hold.
+	Held bool
+}
+
+// Applies reports whether a lifecycle rule acts on an object version at the given time.
+// Objects under a hold are never deleted, but still change their storage class.
+func (r *LifecycleRule) Applies(obj *Object, state LifecycleObjectState, now time.Time) bool {
+	if r.Action == nil {
+		return false
+	}
+	if r.Action.Type == LifecycleActionDelete && state.Held {
+		return false
+	}
+	return r.Condition.Matches(obj, state, now)
+}
+
+// Action returns the action the rules of a lifecycle configuration take on an object version at the given
+// time, or nil if none applies. Like in Cloud Storage, Delete takes precedence over SetStorageClass, and of
+// several SetStorageClass actions the one to the coldest storage class is taken. SetStorageClass actions to
+// the storage class the object already has are ignored.
+// Reference: https://cloud.google.com/storage/docs/lifecycle#lifecycle-actions
+func (l *Lifecycle) Action(obj *Object, state LifecycleObjectState, now time.Time) *LifecycleAction {
+	var action *LifecycleAction
+	for _, rule := range l.Rule {
+		if !rule.Applies(obj, state, now) {
+			continue
+		}
+		switch rule.Action.Type {
+		case LifecycleActionDelete:
+			return rule.Action
+		case LifecycleActionSetStorageClass:
+			if rule.Action.StorageClass == obj.StorageClass {
+				continue
+			}
+			if action == nil || storageClassColdness(rule.Action.StorageClass) > storageClassColdness(action.StorageClass) {
+				action = rule.Action
+			}
+		}
+	}
+	return action
+}
+
+// storageClassColdness orders storage classes by how rarely they are meant to be accessed.
+func storageClassColdness(storageClass string) int {
+	switch storageClass {
+	case StorageClassArchive:
+		return 3
+	case StorageClassColdline:
+		return 2
+	case StorageClassNearline:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Matches reports whether an object version meets all conditions at the given time.
+// Conditions on the custom time are only met by objects that have one, and conditions on the
+// noncurrent time or newer versions only by noncurrent versions, like in Cloud Storage.
+// A condition without any criteria matches every object.
+// Reference: https://cloud.google.com/storage/docs/lifecycle#conditions
+func (c *LifecycleCondition) Matches(obj *Object, state LifecycleObjectState, now time.Time) bool {
+	if c == nil {
+		return true
+	}
+
+	if c.Age != nil && daysSince(obj.TimeCreated, now) < *c.Age {
+		return false
+	}
+	if c.CreatedBefore != "" && !before(obj.TimeCreated, c.CreatedBefore) {
+		return false
+	}
+
+	if c.CustomTimeBefore != "" && (obj.CustomTime == nil || !before(*obj.CustomTime, c.CustomTimeBefore)) {
+		return false
+	}
+	if c.DaysSinceCustomTime != nil && (obj.CustomTime == nil || daysSince(*obj.CustomTime, now) < *c.DaysSinceCustomTime) {
+		return false
+	}
+
+	if c.IsLive != nil && *c.IsLive != state.Live {
+		return false
+	}
+	switch strings.ToUpper(c.WithState) {
+	case "LIVE":
+		if !state.Live {
+			return false
+		}
+	case "ARCHIVED":
+		if state.Live {
+			return false
+		}
+	}
+
+	if c.NoncurrentTimeBefore != "" && (state.Live || !before(state.NoncurrentTime, c.NoncurrentTimeBefore)) {
+		return false
+	}
+	if c.DaysSinceNoncurrentTime != nil && (state.Live || daysSince(state.NoncurrentTime, now) < *c.DaysSinceNoncurrentTime) {
+		return false
+	}
+	if c.NumNewerVersions != nil && (state.Live || state.NewerVersions < *c.NumNewerVersions) {
+		return false
+	}
+
+	if len(c.MatchesStorageClass) > 0 && !slices.Contains(c.MatchesStorageClass, obj.StorageClass) {
+		return false
+	}
+	if len(c.MatchesPrefix) > 0 && !slices.ContainsFunc(c.MatchesPrefix, func(prefix string) bool { return strings.HasPrefix(obj.Name, prefix) }) {
+		return false
+	}
+	if len(c.MatchesSuffix) > 0 && !slices.ContainsFunc(c.MatchesSuffix, func(suffix string) bool { return strings.HasSuffix(obj.Name, suffix) }) {
+		return false
+	}
+
+	return true
+}
+
+// ValidateLifecycle checks the actions and the date conditions of a lifecycle configuration.
+// Returns an "invalid lifecycle" error naming the invalid rule.
+func ValidateLifecycle(lifecycle *Lifecycle) error {
+	if lifecycle == nil {
+		return nil
+	}
+
+	for i, rule := range lifecycle.Rule {
+		switch {
+		case rule.Action == nil:
+			return fmt.Errorf("invalid lifecycle rule %d: action is required", i)
+		case rule.Action.Type == LifecycleActionSetStorageClass && !IsValidStorageClass(rule.Action.StorageClass):
+			return fmt.Errorf("invalid lifecycle rule %d: invalid storage class %q", i, rule.Action.StorageClass)
+		case rule.Action.Type != LifecycleActionDelete && rule.Action.Type != LifecycleActionSetStorageClass && rule.Action.Type != "AbortIncompleteMultipartUpload":
+			return fmt.Errorf("invalid lifecycle rule %d: unknown action type %q", i, rule.Action.Type)
+		}
+
+		if rule.Condition == nil {
+			continue
+		}
+		for name, date := range map[string]string{
+			"createdBefore":        rule.Condition.CreatedBefore,
+			"customTimeBefore":     rule.Condition.CustomTimeBefore,
+			"noncurrentTimeBefore": rule.Condition.NoncurrentTimeBefore,
+		} {
+			if _, err := time.Parse(lifecycleDateFormat, date); date != "" && err != nil {
+				return fmt.Errorf("invalid lifecycle rule %d: %s must be a date like 2024-01-31, got %q", i, name, date)
+			}
+		}
+	}
+	return nil
+}
+
+// daysSince returns the number of full days from t to now.
+func daysSince(t, now time.Time) int {
+	return int(now.Sub(t) / (24 * time.Hour))
+}
+
+// before reports whether t is before midnight UTC of a date condition. Invalid dates match nothing.
+func before(t time.Time, date string) bool {
+	d, err := time.Parse(lifecycleDateFormat, date)
+	return err == nil && t.Before(d)
+}
@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLifecycleCondition_Matches(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	customTime := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	intPtr := func(n int) *int { return &n }
+	boolPtr := func(b bool) *bool { return &b }
+
+	obj := &Object{
+		Name:         "logs/app.log",
+		TimeCreated:  now.AddDate(0, 0, -10),
+		StorageClass: "STANDARD",
+		CustomTime:   &customTime,
+	}
+	withoutCustomTime := &Object{Name: "logs/app.log", TimeCreated: now.AddDate(0, 0, -10), StorageClass: "STANDARD"}
+
+	live := LifecycleObjectState{Live: true}
+	noncurrent := LifecycleObjectState{NoncurrentTime: now.AddDate(0, 0, -5), NewerVersions: 2}
+
+	tests := []struct {
+		name      string
+		condition *LifecycleCondition
+		obj       *Object
+		state     LifecycleObjectState
+		expected  bool
+	}{
+		{"no condition", nil, obj, live, true},
+		{"age reached", &LifecycleCondition{Age: intPtr(10)}, obj, live, true},
+		{"age not reached", &LifecycleCondition{Age: intPtr(11)}, obj, live, false},
+		{"created before", &LifecycleCondition{CreatedBefore: "2024-05-23"}, obj, live, true},
+		{"not created before", &LifecycleCondition{CreatedBefore: "2024-05-22"}, obj, live, false},
+		{"custom time before", &LifecycleCondition{CustomTimeBefore: "2024-05-02"}, obj, live, true},
+		{"custom time not before", &LifecycleCondition{CustomTimeBefore: "2024-05-01"}, obj, live, false},
+		{"custom time before without custom time", &LifecycleCondition{CustomTimeBefore: "2099-01-01"}, withoutCustomTime, live, false},
+		{"days since custom time reached", &LifecycleCondition{DaysSinceCustomTime: intPtr(31)}, obj, live, true},
+		{"days since custom time not reached", &LifecycleCondition{DaysSinceCustomTime: intPtr(32)}, obj, live, false},
+		{"days since custom time without custom time", &LifecycleCondition{DaysSinceCustomTime: intPtr(0)}, withoutCustomTime, live, false},
+		{"noncurrent time before", &LifecycleCondition{NoncurrentTimeBefore: "2024-05-28"}, obj, noncurrent, true},
+		{"noncurrent time not before", &LifecycleCondition{NoncurrentTimeBefore: "2024-05-27"}, obj, noncurrent, false},
+		{"noncurrent time before on live object", &LifecycleCondition{NoncurrentTimeBefore: "2099-01-01"}, obj, live, false},
+		{"days since noncurrent time", &LifecycleCondition{DaysSinceNoncurrentTime: intPtr(5)}, obj, noncurrent, true},
+		{"days since noncurrent time not reached", &LifecycleCondition{DaysSinceNoncurrentTime: intPtr(6)}, obj, noncurrent, false},
+		{"num newer versions", &LifecycleCondition{NumNewerVersions: intPtr(2)}, obj, noncurrent, true},
+		{"too few newer versions", &LifecycleCondition{NumNewerVersions: intPtr(3)}, obj, noncurrent, false},
+		{"is live", &LifecycleCondition{IsLive: boolPtr(true)}, obj, live, true},
+		{"is not live", &LifecycleCondition{IsLive: boolPtr(false)}, obj, live, false},
+		{"with state archived", &LifecycleCondition{WithState: "ARCHIVED"}, obj, noncurrent, true},
+		{"with state live on noncurrent version", &LifecycleCondition{WithState: "LIVE"}, obj, noncurrent, false},
+		{"matches storage class", &LifecycleCondition{MatchesStorageClass: []string{"NEARLINE", "STANDARD"}}, obj, live, true},
+		{"other storage class", &LifecycleCondition{MatchesStorageClass: []string{"NEARLINE"}}, obj, live, false},
+		{"matches prefix", &LifecycleCondition{MatchesPrefix: []string{"tmp/", "logs/"}}, obj, live, true},
+		{"other prefix", &LifecycleCondition{MatchesPrefix: []string{"tmp/"}}, obj, live, false},
+		{"matches suffix", &LifecycleCondition{MatchesSuffix: []string{".log"}}, obj, live, true},
+		{"other suffix", &LifecycleCondition{MatchesSuffix: []string{".txt"}}, obj, live, false},
+		{"all conditions met", &LifecycleCondition{Age: intPtr(7), DaysSinceCustomTime: intPtr(30), MatchesPrefix: []string{"logs/"}}, obj, live, true},
+		{"one condition not met", &LifecycleCondition{Age: intPtr(7), DaysSinceCustomTime: intPtr(30), MatchesSuffix: []string{".txt"}}, obj, live, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.condition.Matches(tt.obj, tt.state, now); got != tt.expected {
+				t.Errorf("Matches() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLifecycleRule_Applies(t *testing.T) {
+	now := time.Now()
+	obj := &Object{Name: "file.txt", TimeCreated: now.AddDate(0, 0, -1)}
+	held := LifecycleObjectState{Live: true, Held: true}
+
+	deleteRule := &LifecycleRule{Action: &LifecycleAction{Type: LifecycleActionDelete}}
+	if deleteRule.Applies(obj, held, now) {
+		t.Error("expected Delete not to apply to an object under a hold")
+	}
+	if !deleteRule.Applies(obj, LifecycleObjectState{Live: true}, now) {
+		t.Error("expected Delete to apply to an object without a hold")
+	}
+
+	setStorageClassRule := &LifecycleRule{Action: &LifecycleAction{Type: LifecycleActionSetStorageClass, StorageClass: "NEARLINE"}}
+	if !setStorageClassRule.Applies(obj, held, now) {
+		t.Error("expected SetStorageClass to apply to an object under a hold")
+	}
+}
+
+func TestLifecycle_Action(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	intPtr := func(n int) *int { return &n }
+	obj := &Object{Name: "logs/app.log", TimeCreated: now.AddDate(0, 0, -100), StorageClass: "NEARLINE"}
+	live := LifecycleObjectState{Live: true}
+
+	deleteOld := LifecycleRule{Action: &LifecycleAction{Type: LifecycleActionDelete}, Condition: &LifecycleCondition{Age: intPtr(90)}}
+	deleteLater := LifecycleRule{Action: &LifecycleAction{Type: LifecycleActionDelete}, Condition: &LifecycleCondition{Age: intPtr(365)}}
+	toColdline := LifecycleRule{Action: &LifecycleAction{Type: LifecycleActionSetStorageClass, StorageClass: "COLDLINE"}, Condition: &LifecycleCondition{Age: intPtr(30)}}
+	toArchive := LifecycleRule{Action: &LifecycleAction{Type: LifecycleActionSetStorageClass, StorageClass: "ARCHIVE"}, Condition: &LifecycleCondition{Age: intPtr(60)}}
+	toNearline := LifecycleRule{Action: &LifecycleAction{Type: LifecycleActionSetStorageClass, StorageClass: "NEARLINE"}}
+
+	tests := []struct {
+		name     string
+		rules    []LifecycleRule
+		state    LifecycleObjectState
+		expected *LifecycleAction
+	}{
+		{"no rules", nil, live, nil},
+		{"no matching rule", []LifecycleRule{deleteLater}, live, nil},
+		{"delete takes precedence", []LifecycleRule{toArchive, deleteOld}, live, deleteOld.Action},
+		{"coldest storage class", []LifecycleRule{toColdline, toArchive}, live, toArchive.Action},
+		{"same storage class", []LifecycleRule{toNearline}, live, nil},
+		{"held object changes its storage class", []LifecycleRule{deleteOld, toColdline}, LifecycleObjectState{Live: true, Held: true}, toColdline.Action},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lifecycle := &Lifecycle{Rule: tt.rules}
+			if action := lifecycle.Action(obj, tt.state, now); action != tt.expected {
+				t.Errorf("expected %+v, got %+v", tt.expected, action)
+			}
+		})
+	}
+}
+
+func TestValidateLifecycle(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  LifecycleRule
+		valid bool
+	}{
+		{"delete", LifecycleRule{Action: &LifecycleAction{Type: "Delete"}, Condition: &LifecycleCondition{CreatedBefore: "2024-01-31"}}, true},
+		{"set storage class", LifecycleRule{Action: &LifecycleAction{Type: "SetStorageClass", StorageClass: "COLDLINE"}}, true},
+		{"missing action", LifecycleRule{Condition: &LifecycleCondition{}}, false},
+		{"unknown action", LifecycleRule{Action: &LifecycleAction{Type: "Archive"}}, false},
+		{"invalid storage class", LifecycleRule{Action: &LifecycleAction{Type: "SetStorageClass", StorageClass: "FROZEN"}}, false},
+		{"invalid created before", LifecycleRule{Action: &LifecycleAction{Type: "Delete"}, Condition: &LifecycleCondition{CreatedBefore: "31.01.2024"}}, false},
+		{"invalid custom time before", LifecycleRule{Action: &LifecycleAction{Type: "Delete"}, Condition: &LifecycleCondition{CustomTimeBefore: "2024-01-31T00:00:00Z"}}, false},
+		{"invalid noncurrent time before", LifecycleRule{Action: &LifecycleAction{Type: "Delete"}, Condition: &LifecycleCondition{NoncurrentTimeBefore: "yesterday"}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateLifecycle(&Lifecycle{Rule: []LifecycleRule{tt.rule}})
+			if tt.valid && err != nil {
+				t.Errorf("expected valid lifecycle, got %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("expected invalid lifecycle")
+			}
+		})
+	}
+}
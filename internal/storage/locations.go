@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Location types of buckets.
+const (
+	LocationTypeRegion      = "region"
+	LocationTypeDualRegion  = "dual-region"
+	LocationTypeMultiRegion = "multi-region"
+)
+
+// locations maps the Cloud Storage bucket locations, in upper case as the API returns them, to their location type.
+// Reference: https://cloud.google.com/storage/docs/locations#available-locations
+var locations = map[string]string{
+	// Multi-regions
+	"ASIA": LocationTypeMultiRegion,
+	"EU":   LocationTypeMultiRegion,
+	"US":   LocationTypeMultiRegion,
+
+	// Predefined dual-regions
+	"ASIA1": LocationTypeDualRegion,
+	"EUR4":  LocationTypeDualRegion,
+	"EUR5":  LocationTypeDualRegion,
+	"EUR7":  LocationTypeDualRegion,
+	"EUR8":  LocationTypeDualRegion,
+	"NAM4":  LocationTypeDualRegion,
+
+	// Regions
+	"AFRICA-SOUTH1":           LocationTypeRegion,
+	"ASIA-EAST1":              LocationTypeRegion,
+	"ASIA-EAST2":              LocationTypeRegion,
+	"ASIA-NORTHEAST1":         LocationTypeRegion,
+	"ASIA-NORTHEAST2":         LocationTypeRegion,
+	"ASIA-NORTHEAST3":         LocationTypeRegion,
+	"ASIA-SOUTH1":             LocationTypeRegion,
+	"ASIA-SOUTH2":             LocationTypeRegion,
+	"ASIA-SOUTHEAST1":         LocationTypeRegion,
+	"ASIA-SOUTHEAST2":         LocationTypeRegion,
+	"AUSTRALIA-SOUTHEAST1":    LocationTypeRegion,
+	"AUSTRALIA-SOUTHEAST2":    LocationTypeRegion,
+	"EUROPE-CENTRAL2":         LocationTypeRegion,
+	"EUROPE-NORTH1":           LocationTypeRegion,
+	"EUROPE-NORTH2":           LocationTypeRegion,
+	"EUROPE-SOUTHWEST1":       LocationTypeRegion,
+	"EUROPE-WEST1":            LocationTypeRegion,
+	"EUROPE-WEST2":            LocationTypeRegion,
+	"EUROPE-WEST3":            LocationTypeRegion,
+	"EUROPE-WEST4":            LocationTypeRegion,
+	"EUROPE-WEST6":            LocationTypeRegion,
+	"EUROPE-WEST8":            LocationTypeRegion,
+	"EUROPE-WEST9":            LocationTypeRegion,
+	"EUROPE-WEST10":           LocationTypeRegion,
+	"EUROPE-WEST12":           LocationTypeRegion,
+	"ME-CENTRAL1":             LocationTypeRegion,
+	"ME-CENTRAL2":             LocationTypeRegion,
+	"ME-WEST1":                LocationTypeRegion,
+	"NORTHAMERICA-NORTHEAST1": LocationTypeRegion,
+	"NORTHAMERICA-NORTHEAST2": LocationTypeRegion,
+	"NORTHAMERICA-SOUTH1":     LocationTypeRegion,
+	"SOUTHAMERICA-EAST1":      LocationTypeRegion,
+	"SOUTHAMERICA-WEST1":      LocationTypeRegion,
+	"US-CENTRAL1":             LocationTypeRegion,
+	"US-EAST1":                LocationTypeRegion,
+	"US-EAST4":                LocationTypeRegion,
+	"US-EAST5":                LocationTypeRegion,
+	"US-SOUTH1":               LocationTypeRegion,
+	"US-WEST1":                LocationTypeRegion,
+	"US-WEST2":                LocationTypeRegion,
+	"US-WEST3":                LocationTypeRegion,
+	"US-WEST4":                LocationTypeRegion,
+}
+
+// LocationType returns the location type of a bucket location, which is case-insensitive.
+// Returns false if the location doesn't exist.
+func LocationType(location string) (string, bool) {
+	locationType, ok := locations[strings.ToUpper(location)]
+	return locationType, ok
+}
+
+// ValidateLocation checks that buckets can be created in a location.
+// Returns an "invalid location" error otherwise.
+func ValidateLocation(location string) error {
+	if _, ok := LocationType(location); !ok {
+		return fmt.Errorf("invalid location %q: not a Cloud Storage location", location)
+	}
+	return nil
+}
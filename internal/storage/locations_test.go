@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLocationType(t *testing.T) {
+	tests := []struct {
+		location     string
+		locationType string
+		valid        bool
+	}{
+		{"US", LocationTypeMultiRegion, true},
+		{"eu", LocationTypeMultiRegion, true},
+		{"EUR4", LocationTypeDualRegion, true},
+		{"nam4", LocationTypeDualRegion, true},
+		{"us-central1", LocationTypeRegion, true},
+		{"EUROPE-WEST3", LocationTypeRegion, true},
+		{"europe-west-1", "", false},
+		{"us-central", "", false},
+		{"us-east-1", "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.location, func(t *testing.T) {
+			locationType, ok := LocationType(tt.location)
+			if ok != tt.valid || locationType != tt.locationType {
+				t.Errorf("LocationType(%q) = %q, %v, expected %q, %v", tt.location, locationType, ok, tt.locationType, tt.valid)
+			}
+
+			err := ValidateLocation(tt.location)
+			if tt.valid && err != nil {
+				t.Errorf("expected valid location, got %v", err)
+			}
+			if !tt.valid && (err == nil || !strings.Contains(err.Error(), "invalid location")) {
+				t.Errorf("expected invalid location error, got %v", err)
+			}
+		})
+	}
+}
@@ -1,7 +1,11 @@
 // Package storage provides data models for the Google Cloud Storage API mock.
 package storage
 
-import "time"
+import (
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/gcperror"
+)
 
 // Bucket represents a Cloud Storage bucket.
 // Based on the official GCS JSON API v1 specification.
@@ -41,6 +45,16 @@ type Bucket struct {
 	Lifecycle *Lifecycle `json:"lifecycle,omitempty"`
 	// SoftDeletePolicy is the bucket's soft delete policy.
 	SoftDeletePolicy *SoftDeletePolicy `json:"softDeletePolicy,omitempty"`
+	// RetentionPolicy is the bucket's retention policy.
+	RetentionPolicy *RetentionPolicy `json:"retentionPolicy,omitempty"`
+	// Encryption is the bucket's encryption configuration.
+	Encryption *Encryption `json:"encryption,omitempty"`
+	// Cors is the bucket's Cross-Origin Resource Sharing (CORS) configuration.
+	Cors []Cors `json:"cors,omitempty"`
+	// Billing is the bucket's billing configuration.
+	Billing *Billing `json:"billing,omitempty"`
+	// Autoclass is the bucket's Autoclass configuration.
+	Autoclass *Autoclass `json:"autoclass,omitempty"`
 }
 
 // IamConfiguration represents the bucket's IAM configuration.
@@ -123,6 +137,84 @@ type SoftDeletePolicy struct {
 	EffectiveTime *time.Time `json:"effectiveTime,omitempty"`
 }
 
+// RetentionPolicy represents the bucket's retention policy. Objects can't be deleted before they
+// reach the retention period. Once locked, the policy can't be removed or its period reduced.
+// Reference: https://cloud.google.com/storage/docs/bucket-lock
+type RetentionPolicy struct {
+	// RetentionPeriod is the minimum age of objects before they can be deleted, in seconds.
+	RetentionPeriod int64 `json:"retentionPeriod,string"`
+	// EffectiveTime is the time at which the policy became effective.
+	EffectiveTime *time.Time `json:"effectiveTime,omitempty"`
+	// IsLocked is true once the policy is locked with buckets.lockRetentionPolicy.
+	IsLocked bool `json:"isLocked,omitempty"`
+}
+
+// Encryption represents the bucket's encryption configuration.
+type Encryption struct {
+	// DefaultKmsKeyName is the Cloud KMS key used to encrypt objects that don't specify a key,
+	// in the format projects/{project}/locations/{location}/keyRings/{keyRing}/cryptoKeys/{cryptoKey}.
+	DefaultKmsKeyName string `json:"defaultKmsKeyName,omitempty"`
+}
+
+// Billing represents the bucket's billing configuration.
+type Billing struct {
+	// RequesterPays makes requesters pay for requests, so they must name a project to bill.
+	// Reference: https://cloud.google.com/storage/docs/requester-pays
+	RequesterPays bool `json:"requesterPays"`
+}
+
+// Autoclass represents the bucket's Autoclass configuration. With Autoclass, objects start in STANDARD
+// and move to colder storage classes when they aren't accessed, up to the terminal storage class.
+// Reference: https://cloud.google.com/storage/docs/autoclass
+type Autoclass struct {
+	// Enabled specifies whether Autoclass is enabled.
+	Enabled bool `json:"enabled"`
+	// ToggleTime is the time at which Autoclass was last enabled or disabled.
+	ToggleTime *time.Time `json:"toggleTime,omitempty"`
+	// TerminalStorageClass is the coldest storage class objects move to, "NEARLINE" (default) or "ARCHIVE".
+	TerminalStorageClass string `json:"terminalStorageClass,omitempty"`
+	// TerminalStorageClassUpdateTime is the time at which the terminal storage class was last set.
+	TerminalStorageClassUpdateTime *time.Time `json:"terminalStorageClassUpdateTime,omitempty"`
+}
+
+// Storage classes.
+// Reference: https://cloud.google.com/storage/docs/storage-classes
+const (
+	StorageClassStandard = "STANDARD"
+	StorageClassNearline = "NEARLINE"
+	StorageClassColdline = "COLDLINE"
+	StorageClassArchive  = "ARCHIVE"
+)
+
+// storageClasses are the valid storage classes, including legacy ones.
+var storageClasses = map[string]bool{
+	StorageClassStandard:           true,
+	StorageClassNearline:           true,
+	StorageClassColdline:           true,
+	StorageClassArchive:            true,
+	"MULTI_REGIONAL":               true,
+	"REGIONAL":                     true,
+	"DURABLE_REDUCED_AVAILABILITY": true,
+}
+
+// IsValidStorageClass reports whether a storage class exists.
+func IsValidStorageClass(storageClass string) bool {
+	return storageClasses[storageClass]
+}
+
+// Cors is a CORS rule of a bucket, which allows cross-origin requests from browsers.
+// Reference: https://cloud.google.com/storage/docs/cross-origin
+type Cors struct {
+	// Origin lists the origins allowed to make requests; "*" allows any origin.
+	Origin []string `json:"origin,omitempty"`
+	// Method lists the HTTP methods allowed, e.g. "GET" or "PUT".
+	Method []string `json:"method,omitempty"`
+	// ResponseHeader lists the response headers browsers may expose to the requesting script.
+	ResponseHeader []string `json:"responseHeader,omitempty"`
+	// MaxAgeSeconds is how long browsers may cache preflight responses.
+	MaxAgeSeconds int `json:"maxAgeSeconds,omitempty"`
+}
+
 // BucketList represents a list of buckets.
 // Reference: https://cloud.google.com/storage/docs/json_api/v1/buckets/list
 type BucketList struct {
@@ -156,12 +248,22 @@ type Object struct {
 	Metageneration int64 `json:"metageneration,string"`
 	// ContentType is the Content-Type of the object data.
 	ContentType string `json:"contentType"`
+	// CacheControl is the Cache-Control directive for the object data.
+	CacheControl string `json:"cacheControl,omitempty"`
+	// ContentDisposition is the Content-Disposition of the object data.
+	ContentDisposition string `json:"contentDisposition,omitempty"`
+	// ContentLanguage is the Content-Language of the object data.
+	ContentLanguage string `json:"contentLanguage,omitempty"`
+	// CustomTime is a user-specified timestamp for the object in RFC 3339 format.
+	CustomTime *time.Time `json:"customTime,omitempty"`
 	// TimeCreated is the creation time of the object in RFC 3339 format.
 	TimeCreated time.Time `json:"timeCreated"`
 	// Updated is the modification time of the object's metadata in RFC 3339 format.
 	Updated time.Time `json:"updated"`
 	// StorageClass is the storage class of the object.
 	StorageClass string `json:"storageClass"`
+	// TimeStorageClassUpdated is the time at which the object's storage class was last changed.
+	TimeStorageClassUpdated time.Time `json:"timeStorageClassUpdated"`
 	// Size is the Content-Length of the data in bytes.
 	Size uint64 `json:"size,string"`
 	// Md5Hash is the MD5 hash of the data; encoded using base64.
@@ -172,6 +274,17 @@ type Object struct {
 	Etag string `json:"etag"`
 	// Metadata are user-provided metadata, in key/value pairs.
 	Metadata map[string]string `json:"metadata,omitempty"`
+	// KmsKeyName is the Cloud KMS key version used to encrypt the object, if any.
+	KmsKeyName string `json:"kmsKeyName,omitempty"`
+	// Acl is the access control list of the object. It's empty if the bucket has uniform bucket-level access.
+	Acl []ObjectAccessControl `json:"acl,omitempty"`
+	// EventBasedHold and TemporaryHold keep the object from being deleted or overwritten while they are set.
+	EventBasedHold bool `json:"eventBasedHold,omitempty"`
+	TemporaryHold  bool `json:"temporaryHold,omitempty"`
+	// SoftDeleteTime is the time at which the object became soft-deleted in RFC 3339 format.
+	SoftDeleteTime *time.Time `json:"softDeleteTime,omitempty"`
+	// HardDeleteTime is the time at which a soft-deleted object will be permanently deleted in RFC 3339 format.
+	HardDeleteTime *time.Time `json:"hardDeleteTime,omitempty"`
 }
 
 // ObjectList represents a list of objects.
@@ -187,6 +300,136 @@ type ObjectList struct {
 	NextPageToken string `json:"nextPageToken,omitempty"`
 }
 
+// RewriteResponse represents the response of an object rewrite.
+// The mock always rewrites objects in a single call, so Done is always true.
+// Reference: https://cloud.google.com/storage/docs/json_api/v1/objects/rewrite
+type RewriteResponse struct {
+	// Kind is the kind of item this is. For rewrite responses, this is always "storage#rewriteResponse".
+	Kind string `json:"kind"`
+	// TotalBytesRewritten is the number of bytes rewritten so far.
+	TotalBytesRewritten uint64 `json:"totalBytesRewritten,string"`
+	// ObjectSize is the size of the source object.
+	ObjectSize uint64 `json:"objectSize,string"`
+	// Done is true when the rewrite is complete.
+	Done bool `json:"done"`
+	// RewriteToken is the token to continue an incomplete rewrite with.
+	RewriteToken string `json:"rewriteToken,omitempty"`
+	// Resource is the destination object once the rewrite is done.
+	Resource *Object `json:"resource,omitempty"`
+}
+
+// Notification represents a bucket notification configuration.
+// Reference: https://cloud.google.com/storage/docs/json_api/v1/notifications
+type Notification struct {
+	// Kind is the kind of item this is. For notifications, this is always "storage#notification".
+	Kind string `json:"kind"`
+	// ID is the ID of the notification.
+	ID string `json:"id"`
+	// SelfLink is the canonical URL of this notification.
+	SelfLink string `json:"selfLink"`
+	// Topic is the Pub/Sub topic to which this subscription publishes.
+	// The mock also accepts an http(s) URL, which receives Pub/Sub push-style requests.
+	Topic string `json:"topic"`
+	// EventTypes limits notifications to the given event types. If empty, all events are sent.
+	EventTypes []string `json:"event_types,omitempty"`
+	// CustomAttributes are attributes to be included in every message.
+	CustomAttributes map[string]string `json:"custom_attributes,omitempty"`
+	// PayloadFormat is the desired content of the payload ("JSON_API_V1" or "NONE").
+	PayloadFormat string `json:"payload_format"`
+	// ObjectNamePrefix limits notifications to objects with this name prefix.
+	ObjectNamePrefix string `json:"object_name_prefix,omitempty"`
+	// Etag is the HTTP 1.1 Entity tag for this notification.
+	Etag string `json:"etag"`
+}
+
+// NotificationList represents a list of notification configurations.
+// Reference: https://cloud.google.com/storage/docs/json_api/v1/notifications/list
+type NotificationList struct {
+	// Kind is the kind of item this is. For notification lists, this is always "storage#notifications".
+	Kind string `json:"kind"`
+	// Items is the list of notifications.
+	Items []*Notification `json:"items"`
+}
+
+// NotificationInsertRequest represents the request body for creating a notification configuration.
+type NotificationInsertRequest struct {
+	Topic            string            `json:"topic"`
+	EventTypes       []string          `json:"event_types,omitempty"`
+	CustomAttributes map[string]string `json:"custom_attributes,omitempty"`
+	PayloadFormat    string            `json:"payload_format,omitempty"`
+	ObjectNamePrefix string            `json:"object_name_prefix,omitempty"`
+}
+
+// Notification event types.
+// Reference: https://cloud.google.com/storage/docs/pubsub-notifications#events
+const (
+	EventObjectFinalize       = "OBJECT_FINALIZE"
+	EventObjectMetadataUpdate = "OBJECT_METADATA_UPDATE"
+	EventObjectDelete         = "OBJECT_DELETE"
+	EventObjectArchive        = "OBJECT_ARCHIVE"
+)
+
+// Notification payload formats.
+const (
+	PayloadFormatJSONAPIV1 = "JSON_API_V1"
+	PayloadFormatNone      = "NONE"
+)
+
+// HmacKeyMetadata represents the metadata of an HMAC key, which authenticates requests to the XML API
+// and its S3-compatible surface on behalf of a service account.
+// Reference: https://cloud.google.com/storage/docs/json_api/v1/projects/hmacKeys
+type HmacKeyMetadata struct {
+	// Kind is the kind of item this is. For HMAC key metadata, this is always "storage#hmacKeyMetadata".
+	Kind string `json:"kind"`
+	// ID is the ID of the key, {project}/{accessId}.
+	ID string `json:"id"`
+	// SelfLink is the canonical URL of this key.
+	SelfLink string `json:"selfLink"`
+	// AccessID is the ID of the key, which signed requests carry as their access key.
+	AccessID string `json:"accessId"`
+	// ProjectID is the project the key belongs to.
+	ProjectID string `json:"projectId"`
+	// ServiceAccountEmail is the email of the service account the key authenticates as.
+	ServiceAccountEmail string `json:"serviceAccountEmail"`
+	// State is the state of the key: ACTIVE, INACTIVE or DELETED.
+	State string `json:"state"`
+	// TimeCreated is the creation time of the key in RFC 3339 format.
+	TimeCreated time.Time `json:"timeCreated"`
+	// Updated is the last modification time of the key in RFC 3339 format.
+	Updated time.Time `json:"updated"`
+	// Etag is the HTTP 1.1 Entity tag for this key.
+	Etag string `json:"etag"`
+}
+
+// HmacKey represents a newly created HMAC key. The secret is only returned on creation.
+// Reference: https://cloud.google.com/storage/docs/json_api/v1/projects/hmacKeys/create
+type HmacKey struct {
+	// Kind is the kind of item this is. For HMAC keys, this is always "storage#hmacKey".
+	Kind string `json:"kind"`
+	// Metadata is the metadata of the key.
+	Metadata *HmacKeyMetadata `json:"metadata"`
+	// Secret is the secret of the key, which requests are signed with.
+	Secret string `json:"secret"`
+}
+
+// HmacKeyList represents a list of HMAC key metadata.
+// Reference: https://cloud.google.com/storage/docs/json_api/v1/projects/hmacKeys/list
+type HmacKeyList struct {
+	// Kind is the kind of item this is. For HMAC key lists, this is always "storage#hmacKeysMetadata".
+	Kind string `json:"kind"`
+	// Items is the list of key metadata.
+	Items []*HmacKeyMetadata `json:"items"`
+	// NextPageToken is the continuation token for the next page of results.
+	NextPageToken string `json:"nextPageToken,omitempty"`
+}
+
+// HMAC key states.
+const (
+	HmacKeyActive   = "ACTIVE"
+	HmacKeyInactive = "INACTIVE"
+	HmacKeyDeleted  = "DELETED"
+)
+
 // BucketInsertRequest represents the request body for creating a bucket.
 type BucketInsertRequest struct {
 	Name             string            `json:"name"`
@@ -197,6 +440,11 @@ type BucketInsertRequest struct {
 	Versioning       *Versioning       `json:"versioning,omitempty"`
 	Lifecycle        *Lifecycle        `json:"lifecycle,omitempty"`
 	SoftDeletePolicy *SoftDeletePolicy `json:"softDeletePolicy,omitempty"`
+	RetentionPolicy  *RetentionPolicy  `json:"retentionPolicy,omitempty"`
+	Encryption       *Encryption       `json:"encryption,omitempty"`
+	Cors             []Cors            `json:"cors,omitempty"`
+	Billing          *Billing          `json:"billing,omitempty"`
+	Autoclass        *Autoclass        `json:"autoclass,omitempty"`
 }
 
 // BucketUpdateRequest represents the request body for updating a bucket.
@@ -207,24 +455,84 @@ type BucketUpdateRequest struct {
 	Versioning       *Versioning       `json:"versioning,omitempty"`
 	Lifecycle        *Lifecycle        `json:"lifecycle,omitempty"`
 	SoftDeletePolicy *SoftDeletePolicy `json:"softDeletePolicy,omitempty"`
+	RetentionPolicy  *RetentionPolicy  `json:"retentionPolicy,omitempty"`
+	Encryption       *Encryption       `json:"encryption,omitempty"`
+	Cors             []Cors            `json:"cors,omitempty"`
+	Billing          *Billing          `json:"billing,omitempty"`
+	Autoclass        *Autoclass        `json:"autoclass,omitempty"`
+	// Etag is the etag of the bucket the update is based on. If set, the update fails unless it is
+	// still the etag of the bucket, so concurrent read-modify-write cycles don't overwrite each other.
+	Etag string `json:"etag,omitempty"`
+}
+
+// BucketPatchRequest represents the request body for patching a bucket.
+// Fields that are missing are left unchanged, fields set to null are cleared and labels are merged.
+// Reference: https://cloud.google.com/storage/docs/json_api/v1/buckets/patch
+type BucketPatchRequest struct {
+	BucketUpdateRequest
+	// Labels are merged into the bucket labels; labels set to null are removed.
+	Labels map[string]*string `json:"labels,omitempty"`
+	// NullFields lists the JSON names of the fields set to null in the request.
+	NullFields []string `json:"-"`
+}
+
+// ObjectInsertRequest represents the object resource sent with multipart and resumable uploads.
+type ObjectInsertRequest struct {
+	Name               string            `json:"name,omitempty"`
+	ContentType        string            `json:"contentType,omitempty"`
+	CacheControl       string            `json:"cacheControl,omitempty"`
+	ContentDisposition string            `json:"contentDisposition,omitempty"`
+	ContentLanguage    string            `json:"contentLanguage,omitempty"`
+	CustomTime         *time.Time        `json:"customTime,omitempty"`
+	Metadata           map[string]string `json:"metadata,omitempty"`
+	KmsKeyName         string            `json:"kmsKeyName,omitempty"`
+	StorageClass       string            `json:"storageClass,omitempty"`
+	// Md5Hash and Crc32c are the base64-encoded checksums the uploaded content must have, if set.
+	Md5Hash string `json:"md5Hash,omitempty"`
+	Crc32c  string `json:"crc32c,omitempty"`
+	// Acl is the access control list of the object. If empty, the object gets the projectPrivate ACL.
+	Acl []ObjectAccessControl `json:"acl,omitempty"`
+	// PredefinedACL is the predefined ACL of the object, like publicRead, from the predefinedAcl
+	// query parameter. It takes precedence over Acl.
+	PredefinedACL string `json:"-"`
+}
+
+// ObjectUpdateRequest represents the request body for updating an object's metadata.
+// The writable fields are replaced, except for the content type, which is only changed if set.
+type ObjectUpdateRequest struct {
+	ContentType        string            `json:"contentType,omitempty"`
+	CacheControl       string            `json:"cacheControl,omitempty"`
+	ContentDisposition string            `json:"contentDisposition,omitempty"`
+	ContentLanguage    string            `json:"contentLanguage,omitempty"`
+	CustomTime         *time.Time        `json:"customTime,omitempty"`
+	Metadata           map[string]string `json:"metadata"`
+	EventBasedHold     bool              `json:"eventBasedHold,omitempty"`
+	TemporaryHold      bool              `json:"temporaryHold,omitempty"`
+}
+
+// ObjectPatchRequest represents the request body for patching an object's metadata.
+// Fields that are missing are left unchanged and fields set to null are cleared.
+// Reference: https://cloud.google.com/storage/docs/json_api/v1/objects/patch
+type ObjectPatchRequest struct {
+	ContentType        *string    `json:"contentType,omitempty"`
+	CacheControl       *string    `json:"cacheControl,omitempty"`
+	ContentDisposition *string    `json:"contentDisposition,omitempty"`
+	ContentLanguage    *string    `json:"contentLanguage,omitempty"`
+	CustomTime         *time.Time `json:"customTime,omitempty"`
+	EventBasedHold     *bool      `json:"eventBasedHold,omitempty"`
+	TemporaryHold      *bool      `json:"temporaryHold,omitempty"`
+	// Metadata is merged into the custom metadata; keys set to null are removed.
+	Metadata map[string]*string `json:"metadata,omitempty"`
+	// NullFields lists the JSON names of the fields set to null in the request.
+	NullFields []string `json:"-"`
 }
 
 // APIError represents an error response from the GCS API.
 // Reference: https://cloud.google.com/storage/docs/json_api/v1/status-codes
-type APIError struct {
-	Error ErrorDetails `json:"error"`
-}
+type APIError = gcperror.Response
 
 // ErrorDetails contains the details of an API error.
-type ErrorDetails struct {
-	Code    int           `json:"code"`
-	Message string        `json:"message"`
-	Errors  []ErrorReason `json:"errors,omitempty"`
-}
+type ErrorDetails = gcperror.Details
 
 // ErrorReason contains the reason for an error.
-type ErrorReason struct {
-	Domain  string `json:"domain"`
-	Reason  string `json:"reason"`
-	Message string `json:"message"`
-}
+type ErrorReason = gcperror.Reason
@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+	"unicode/utf8"
+)
+
+// ValidateBucketName checks a bucket name against the Cloud Storage naming requirements.
+// Returns an "invalid bucket name" error naming the violated requirement.
+// Reference: https://cloud.google.com/storage/docs/buckets#naming
+func ValidateBucketName(name string) error {
+	invalid := func(reason string) error {
+		return fmt.Errorf("invalid bucket name %q: %s", name, reason)
+	}
+
+	maxLength := 63
+	if strings.Contains(name, ".") {
+		maxLength = 222
+	}
+	if len(name) < 3 || len(name) > maxLength {
+		return invalid("must contain 3-63 characters, or up to 222 if it contains dots")
+	}
+
+	for _, c := range name {
+		if !isLowerAlphanumeric(c) && c != '-' && c != '_' && c != '.' {
+			return invalid("may only contain lowercase letters, numbers, dashes, underscores and dots")
+		}
+	}
+	if !isLowerAlphanumeric(rune(name[0])) || !isLowerAlphanumeric(rune(name[len(name)-1])) {
+		return invalid("must start and end with a number or letter")
+	}
+
+	for _, component := range strings.Split(name, ".") {
+		if component == "" || len(component) > 63 {
+			return invalid("each dot-separated component must contain 1-63 characters")
+		}
+	}
+	if _, err := netip.ParseAddr(name); err == nil {
+		return invalid("must not be an IP address")
+	}
+	if strings.HasPrefix(name, "goog") {
+		return invalid(`must not start with "goog"`)
+	}
+	if strings.Contains(name, "google") || strings.Contains(name, "g00gle") {
+		return invalid(`must not contain "google" or close misspellings`)
+	}
+	return nil
+}
+
+// ValidateObjectName checks an object name against the Cloud Storage naming requirements.
+// Returns an "invalid object name" error naming the violated requirement.
+// Reference: https://cloud.google.com/storage/docs/objects#naming
+func ValidateObjectName(name string) error {
+	invalid := func(reason string) error {
+		return fmt.Errorf("invalid object name %q: %s", name, reason)
+	}
+
+	switch {
+	case name == "":
+		return invalid("must not be empty")
+	case len(name) > 1024:
+		return invalid(fmt.Sprintf("the maximum object length is 1024 bytes, but got a name with %d bytes", len(name)))
+	case !utf8.ValidString(name):
+		return invalid("must be valid UTF-8")
+	case strings.ContainsAny(name, "\r\n"):
+		return invalid("must not contain carriage return or line feed characters")
+	case name == "." || name == "..":
+		return invalid(`must not be "." or ".."`)
+	case strings.HasPrefix(name, ".well-known/acme-challenge/"):
+		return invalid(`must not start with ".well-known/acme-challenge/"`)
+	}
+	return nil
+}
+
+// ValidateObjectPath checks that an object name is a clean path: no leading slash, empty, "." or ".." segments,
+// or backslashes. Cloud Storage accepts such names, but they usually come from joining paths wrongly, and they
+// break tools that map objects to files, like gcloud storage cp -r or gcsfuse. Trailing slashes are allowed,
+// since they name folder placeholders.
+// Returns an "invalid object name" error naming the violated requirement.
+func ValidateObjectPath(name string) error {
+	invalid := func(reason string) error {
+		return fmt.Errorf("invalid object name %q: %s", name, reason)
+	}
+
+	if strings.HasPrefix(name, "/") {
+		return invalid("must not start with a slash")
+	}
+	if strings.Contains(name, "\\") {
+		return invalid("must not contain backslashes; use slashes to separate folders")
+	}
+	for _, segment := range strings.Split(strings.TrimSuffix(name, "/"), "/") {
+		switch segment {
+		case "":
+			return invalid("must not contain empty path segments")
+		case ".", "..":
+			return invalid(`must not contain "." or ".." path segments`)
+		}
+	}
+	return nil
+}
+
+// isLowerAlphanumeric reports whether c is a lowercase ASCII letter or a digit.
+func isLowerAlphanumeric(c rune) bool {
+	return (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9')
+}
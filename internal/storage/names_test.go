@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateBucketName(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{"my-bucket", true},
+		{"my_bucket.example.com", true},
+		{"b01", true},
+		{"ab", false},
+		{strings.Repeat("a", 64), false},
+		{strings.Repeat("a", 63) + "." + strings.Repeat("b", 63), true},
+		{strings.Repeat("a", 64) + ".com", false},
+		{"My-Bucket", false},
+		{"-bucket", false},
+		{"bucket_", false},
+		{"my..bucket", false},
+		{"192.168.5.4", false},
+		{"goog-bucket", false},
+		{"my-google-bucket", false},
+		{"bucket with spaces", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateBucketName(tt.name)
+			if tt.valid && err != nil {
+				t.Errorf("expected name to be valid, got %v", err)
+			}
+			if !tt.valid && (err == nil || !strings.Contains(err.Error(), "invalid bucket name")) {
+				t.Errorf("expected invalid bucket name error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateObjectName(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{"file.txt", true},
+		{"dir/sub dir/file (1).txt", true},
+		{"ünïcödé", true},
+		{".well-known/security.txt", true},
+		{"", false},
+		{strings.Repeat("a", 1025), false},
+		{"line\nbreak", false},
+		{"\xff", false},
+		{".", false},
+		{"..", false},
+		{".well-known/acme-challenge/token", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateObjectName(tt.name)
+			if tt.valid && err != nil {
+				t.Errorf("expected name to be valid, got %v", err)
+			}
+			if !tt.valid && (err == nil || !strings.Contains(err.Error(), "invalid object name")) {
+				t.Errorf("expected invalid object name error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateObjectPath(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{"file.txt", true},
+		{"dir/sub/file.txt", true},
+		{"dir/", true},
+		{"..file", true},
+		{"/file.txt", false},
+		{"dir\\file.txt", false},
+		{"dir//file.txt", false},
+		{"dir//", false},
+		{"./file.txt", false},
+		{"dir/../file.txt", false},
+		{"dir/..", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateObjectPath(tt.name)
+			if tt.valid && err != nil {
+				t.Errorf("expected name to be valid, got %v", err)
+			}
+			if !tt.valid && (err == nil || !strings.Contains(err.Error(), "invalid object name")) {
+				t.Errorf("expected invalid object name error, got %v", err)
+			}
+		})
+	}
+}
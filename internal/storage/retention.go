@@ -0,0 +1,26 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// MaxRetentionPeriod is the longest retention period of a retention policy, 100 years in seconds.
+const MaxRetentionPeriod = 3_155_760_000
+
+// ValidateRetentionPolicy checks the retention period of a retention policy.
+// Returns an "invalid retention policy" error.
+func ValidateRetentionPolicy(policy *RetentionPolicy) error {
+	if policy == nil {
+		return nil
+	}
+	if policy.RetentionPeriod <= 0 || policy.RetentionPeriod > MaxRetentionPeriod {
+		return fmt.Errorf("invalid retention policy: retentionPeriod must be between 1 and %d seconds", MaxRetentionPeriod)
+	}
+	return nil
+}
+
+// RetentionExpirationTime returns the time until which a retention policy keeps an object from being deleted.
+func (p *RetentionPolicy) RetentionExpirationTime(obj *Object) time.Time {
+	return obj.TimeCreated.Add(time.Duration(p.RetentionPeriod) * time.Second)
+}
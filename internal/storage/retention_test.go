@@ -0,0 +1,43 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateRetentionPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  *RetentionPolicy
+		wantErr bool
+	}{
+		{name: "no policy", policy: nil},
+		{name: "one day", policy: &RetentionPolicy{RetentionPeriod: 86400}},
+		{name: "100 years", policy: &RetentionPolicy{RetentionPeriod: MaxRetentionPeriod}},
+		{name: "zero", policy: &RetentionPolicy{}, wantErr: true},
+		{name: "negative", policy: &RetentionPolicy{RetentionPeriod: -1}, wantErr: true},
+		{name: "too long", policy: &RetentionPolicy{RetentionPeriod: MaxRetentionPeriod + 1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRetentionPolicy(tt.policy)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateRetentionPolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "invalid retention policy") {
+				t.Errorf("expected an invalid retention policy error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestRetentionPolicy_RetentionExpirationTime(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	policy := &RetentionPolicy{RetentionPeriod: 3600}
+
+	if got := policy.RetentionExpirationTime(&Object{TimeCreated: created}); !got.Equal(created.Add(time.Hour)) {
+		t.Errorf("expected %v, got %v", created.Add(time.Hour), got)
+	}
+}
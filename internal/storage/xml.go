@@ -0,0 +1,76 @@
+package storage
+
+import "encoding/xml"
+
+// XMLNamespace is the namespace used by the Cloud Storage XML API, which is S3-compatible.
+const XMLNamespace = "http://doc.s3.amazonaws.com/2006-03-01"
+
+// ListBucketResult represents an object listing in the XML API.
+// Reference: https://cloud.google.com/storage/docs/xml-api/get-bucket-list
+type ListBucketResult struct {
+	XMLName xml.Name `xml:"ListBucketResult"`
+	// Xmlns is the XML namespace of the response.
+	Xmlns string `xml:"xmlns,attr"`
+	// Name is the name of the bucket.
+	Name string `xml:"Name"`
+	// Prefix is the prefix the listing was filtered by.
+	Prefix string `xml:"Prefix"`
+	// Marker is the key the listing started after.
+	Marker string `xml:"Marker"`
+	// NextMarker is the marker to use for the next page, if the listing is truncated.
+	NextMarker string `xml:"NextMarker,omitempty"`
+	// MaxKeys is the maximum number of entries returned.
+	MaxKeys int `xml:"MaxKeys"`
+	// Delimiter is the delimiter used to group keys into common prefixes.
+	Delimiter string `xml:"Delimiter,omitempty"`
+	// IsTruncated is true if there are more entries to list.
+	IsTruncated bool `xml:"IsTruncated"`
+	// Contents are the objects in the listing.
+	Contents []XMLObject `xml:"Contents"`
+	// CommonPrefixes are the prefixes keys were grouped into.
+	CommonPrefixes []XMLCommonPrefix `xml:"CommonPrefixes"`
+}
+
+// XMLObject represents an object in an XML API listing.
+type XMLObject struct {
+	// Key is the name of the object.
+	Key string `xml:"Key"`
+	// Generation is the content generation of the object.
+	Generation int64 `xml:"Generation"`
+	// MetaGeneration is the metadata generation of the object.
+	MetaGeneration int64 `xml:"MetaGeneration"`
+	// LastModified is the modification time of the object in RFC 3339 format.
+	LastModified string `xml:"LastModified"`
+	// ETag is the HTTP 1.1 Entity tag for the object.
+	ETag string `xml:"ETag"`
+	// Size is the size of the object in bytes.
+	Size uint64 `xml:"Size"`
+	// StorageClass is the storage class of the object.
+	StorageClass string `xml:"StorageClass"`
+}
+
+// XMLCommonPrefix represents a common prefix in an XML API listing.
+type XMLCommonPrefix struct {
+	// Prefix is the common prefix.
+	Prefix string `xml:"Prefix"`
+}
+
+// CreateBucketConfiguration is the optional request body for creating a bucket in the XML API.
+// Reference: https://cloud.google.com/storage/docs/xml-api/put-bucket-create
+type CreateBucketConfiguration struct {
+	XMLName xml.Name `xml:"CreateBucketConfiguration"`
+	// LocationConstraint is the location of the bucket.
+	LocationConstraint string `xml:"LocationConstraint"`
+	// StorageClass is the default storage class of the bucket.
+	StorageClass string `xml:"StorageClass"`
+}
+
+// XMLError represents an error response from the XML API.
+// Reference: https://cloud.google.com/storage/docs/xml-api/reference-status
+type XMLError struct {
+	XMLName xml.Name `xml:"Error"`
+	// Code is the error code, e.g. "NoSuchKey".
+	Code string `xml:"Code"`
+	// Message is a human-readable description of the error.
+	Message string `xml:"Message"`
+}
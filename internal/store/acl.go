@@ -0,0 +1,207 @@
+package store
+
+import (
+	"fmt"
+
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+)
+
+// =============================================================================
+// Cloud Storage Object ACL Operations
+// =============================================================================
+
+// uniformBucketLevelAccess reports whether a bucket has uniform bucket-level access, which disables object ACLs.
+func uniformBucketLevelAccess(bucket *storage.Bucket) bool {
+	iam := bucket.IamConfiguration
+	return iam != nil && iam.UniformBucketLevelAccess != nil && iam.UniformBucketLevelAccess.Enabled
+}
+
+// newObjectACL returns the ACL of a new object in a bucket: the predefined ACL or the ACL of the options,
+// or projectPrivate, the default object ACL of new buckets. Objects in buckets with uniform bucket-level
+// access don't have an ACL.
+// Returns an "invalid" error if the ACL is invalid or set although the bucket has uniform bucket-level access,
+// and a "precondition failed" error if it's public although the bucket or the organization policy enforces
+// public access prevention.
+func (s *Store) newObjectACL(bucket *storage.Bucket, opts ObjectOptions) ([]storage.ObjectAccessControl, error) {
+	if uniformBucketLevelAccess(bucket) {
+		if opts.PredefinedACL != "" || len(opts.ACL) > 0 {
+			return nil, fmt.Errorf("invalid ACL: bucket %s has uniform bucket-level access, so objects can't have ACLs", bucket.Name)
+		}
+		return nil, nil
+	}
+
+	var acl []storage.ObjectAccessControl
+	switch {
+	case opts.PredefinedACL != "":
+		predefined, err := storage.PredefinedObjectACL(opts.PredefinedACL, bucket.ProjectNumber)
+		if err != nil {
+			return nil, err
+		}
+		acl = predefined
+	case len(opts.ACL) == 0:
+		return storage.PredefinedObjectACL("projectPrivate", bucket.ProjectNumber)
+	default:
+		for _, entry := range opts.ACL {
+			parsed, err := storage.ParseObjectACLEntry(entry)
+			if err != nil {
+				return nil, err
+			}
+			acl = setACLEntry(acl, parsed)
+		}
+	}
+
+	if err := s.checkPublicAccessPrevention(bucket, storage.IsPublicACL(acl)); err != nil {
+		return nil, err
+	}
+	return acl, nil
+}
+
+// setACLEntry replaces the entry for the entity of entry in acl, or appends entry if the entity has none.
+func setACLEntry(acl []storage.ObjectAccessControl, entry storage.ObjectAccessControl) []storage.ObjectAccessControl {
+	for i := range acl {
+		if acl[i].Entity == entry.Entity {
+			acl[i] = entry
+			return acl
+		}
+	}
+	return append(acl, entry)
+}
+
+// findACLEntry returns a copy of the entry for an entity in acl, or nil if the entity has none.
+func findACLEntry(acl []storage.ObjectAccessControl, entity string) *storage.ObjectAccessControl {
+	for _, entry := range acl {
+		if entry.Entity == entity {
+			entry = clone(entry)
+			return &entry
+		}
+	}
+	return nil
+}
+
+// aclEqual reports whether two ACLs grant the same roles to the same entities.
+func aclEqual(a, b []storage.ObjectAccessControl) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	roles := make(map[string]string, len(a))
+	for _, entry := range a {
+		roles[entry.Entity] = entry.Role
+	}
+	for _, entry := range b {
+		if role, ok := roles[entry.Entity]; !ok || role != entry.Role {
+			return false
+		}
+	}
+	return true
+}
+
+// completeObjectACL fills in the fields of an object's ACL entries that refer to the object.
+// It must be called whenever the ACL or the generation of the object changes.
+func completeObjectACL(baseURL string, obj *storage.Object) {
+	for i := range obj.Acl {
+		entry := &obj.Acl[i]
+		entry.Kind = "storage#objectAccessControl"
+		entry.ID = fmt.Sprintf("%s/%s/%d/%s", obj.Bucket, obj.Name, obj.Generation, entry.Entity)
+		entry.SelfLink = fmt.Sprintf("%s/storage/v1/b/%s/o/%s/acl/%s", baseURL, obj.Bucket, obj.Name, entry.Entity)
+		entry.Bucket = obj.Bucket
+		entry.Object = obj.Name
+		entry.Generation = obj.Generation
+	}
+}
+
+// lookupObjectForACL returns the live object whose ACL is read or changed.
+// Returns an error if the bucket or the object doesn't exist, or the bucket has uniform bucket-level access.
+// Callers must hold the storage lock.
+func (s *Store) lookupObjectForACL(bucketName, objectName string) (*storage.Object, error) {
+	bucket, exists := s.buckets[bucketName]
+	if !exists {
+		return nil, fmt.Errorf("bucket %s not found", bucketName)
+	}
+	objData, exists := s.objects[bucketName][objectName]
+	if !exists {
+		return nil, fmt.Errorf("object %s not found in bucket %s", objectName, bucketName)
+	}
+	if uniformBucketLevelAccess(bucket) {
+		return nil, fmt.Errorf("invalid ACL request: bucket %s has uniform bucket-level access, so objects don't have ACLs", bucketName)
+	}
+	return objData.Metadata, nil
+}
+
+// ListObjectACL returns the ACL entries of an object.
+// Returns an error if the object doesn't exist or the bucket has uniform bucket-level access.
+func (s *Store) ListObjectACL(bucketName, objectName string) ([]storage.ObjectAccessControl, error) {
+	s.storageMu.RLock()
+	defer s.storageMu.RUnlock()
+
+	obj, err := s.lookupObjectForACL(bucketName, objectName)
+	if err != nil {
+		return nil, err
+	}
+	return clone(obj.Acl), nil
+}
+
+// GetObjectACLEntry returns the ACL entry of an object for an entity.
+// Returns an error if the object or the entry doesn't exist, or the bucket has uniform bucket-level access.
+func (s *Store) GetObjectACLEntry(bucketName, objectName, entity string) (*storage.ObjectAccessControl, error) {
+	s.storageMu.RLock()
+	defer s.storageMu.RUnlock()
+
+	obj, err := s.lookupObjectForACL(bucketName, objectName)
+	if err != nil {
+		return nil, err
+	}
+	entry := findACLEntry(obj.Acl, entity)
+	if entry == nil {
+		return nil, fmt.Errorf("ACL entry for %s not found on object %s", entity, objectName)
+	}
+	return entry, nil
+}
+
+// SetObjectACLEntry adds an ACL entry to an object, replacing the entry for the same entity if there is one.
+// Like any metadata change, it bumps the metageneration of the object.
+// Returns an error if the object doesn't exist, the entry is invalid, the bucket has uniform bucket-level access
+// or the entry is public although the bucket enforces public access prevention.
+func (s *Store) SetObjectACLEntry(bucketName, objectName string, entry storage.ObjectAccessControl) (*storage.ObjectAccessControl, error) {
+	parsed, err := storage.ParseObjectACLEntry(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	s.storageMu.Lock()
+	defer s.storageMu.Unlock()
+
+	obj, err := s.lookupObjectForACL(bucketName, objectName)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkPublicAccessPrevention(s.buckets[bucketName], storage.IsPublicACL([]storage.ObjectAccessControl{parsed})); err != nil {
+		return nil, err
+	}
+
+	obj.Acl = setACLEntry(obj.Acl, parsed)
+	completeObjectACL(s.config().baseURL, obj)
+	s.finishObjectMetadataUpdate(obj)
+
+	return findACLEntry(obj.Acl, parsed.Entity), nil
+}
+
+// DeleteObjectACLEntry removes the ACL entry of an object for an entity.
+// Returns an error if the object or the entry doesn't exist, or the bucket has uniform bucket-level access.
+func (s *Store) DeleteObjectACLEntry(bucketName, objectName, entity string) error {
+	s.storageMu.Lock()
+	defer s.storageMu.Unlock()
+
+	obj, err := s.lookupObjectForACL(bucketName, objectName)
+	if err != nil {
+		return err
+	}
+
+	for i, entry := range obj.Acl {
+		if entry.Entity == entity {
+			obj.Acl = append(obj.Acl[:i], obj.Acl[i+1:]...)
+			s.finishObjectMetadataUpdate(obj)
+			return nil
+		}
+	}
+	return fmt.Errorf("ACL entry for %s not found on object %s", entity, objectName)
+}
@@ -0,0 +1,105 @@
+package store
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+)
+
+func TestStore_ObjectACL(t *testing.T) {
+	s := New()
+	bucket, _ := s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+	owners := fmt.Sprintf("project-owners-%d", bucket.ProjectNumber)
+
+	// New objects get the projectPrivate ACL
+	obj, err := s.CreateObject("test-bucket", "private.txt", "text/plain", []byte("x"), nil)
+	if err != nil {
+		t.Fatalf("CreateObject() error: %v", err)
+	}
+	if len(obj.Acl) != 3 || obj.Acl[0].Entity != owners || obj.Acl[0].Object != "private.txt" {
+		t.Errorf("expected the projectPrivate ACL, got %+v", obj.Acl)
+	}
+
+	obj, err = s.CreateObjectWithOptions("test-bucket", "public.txt", "text/plain", bytes.NewReader([]byte("x")), nil,
+		ObjectOptions{PredefinedACL: "publicRead"})
+	if err != nil {
+		t.Fatalf("CreateObjectWithOptions() error: %v", err)
+	}
+	if !storage.IsPublicACL(obj.Acl) {
+		t.Errorf("expected a public ACL, got %+v", obj.Acl)
+	}
+
+	// Uploading the same content with another ACL replaces the object
+	replaced, _ := s.CreateObjectWithOptions("test-bucket", "public.txt", "text/plain", bytes.NewReader([]byte("x")), nil,
+		ObjectOptions{PredefinedACL: "private"})
+	if replaced.Generation == obj.Generation || storage.IsPublicACL(replaced.Acl) {
+		t.Errorf("expected a new private generation, got %+v", replaced)
+	}
+
+	// Entries are added, replaced and removed, bumping the metageneration
+	entry, err := s.SetObjectACLEntry("test-bucket", "private.txt", storage.ObjectAccessControl{Entity: "user-jane@example.com", Role: storage.RoleReader})
+	if err != nil {
+		t.Fatalf("SetObjectACLEntry() error: %v", err)
+	}
+	if entry.Email != "jane@example.com" || entry.Kind != "storage#objectAccessControl" {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+	_, _ = s.SetObjectACLEntry("test-bucket", "private.txt", storage.ObjectAccessControl{Entity: "user-jane@example.com", Role: storage.RoleOwner})
+	if entry, _ := s.GetObjectACLEntry("test-bucket", "private.txt", "user-jane@example.com"); entry == nil || entry.Role != storage.RoleOwner {
+		t.Errorf("expected jane to be an owner, got %+v", entry)
+	}
+	if err := s.DeleteObjectACLEntry("test-bucket", "private.txt", "user-jane@example.com"); err != nil {
+		t.Fatalf("DeleteObjectACLEntry() error: %v", err)
+	}
+	if obj := s.GetObject("test-bucket", "private.txt"); obj.Metageneration != 4 || len(obj.Acl) != 3 {
+		t.Errorf("expected metageneration 4 with the original ACL, got %d %+v", obj.Metageneration, obj.Acl)
+	}
+	if _, err := s.GetObjectACLEntry("test-bucket", "private.txt", "user-jane@example.com"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected not found error, got %v", err)
+	}
+
+	// The destination of a rewrite gets its own ACL rather than the source's
+	copied, err := s.RewriteObject("test-bucket", "public.txt", "test-bucket", "copy.txt", &storage.ObjectInsertRequest{PredefinedACL: "authenticatedRead"}, Preconditions{})
+	if err != nil {
+		t.Fatalf("RewriteObject() error: %v", err)
+	}
+	if entry := findACLEntry(copied.Acl, storage.EntityAllAuthenticatedUsers); entry == nil {
+		t.Errorf("expected allAuthenticatedUsers in the ACL, got %+v", copied.Acl)
+	}
+
+	if _, err := s.CreateObjectWithOptions("test-bucket", "x.txt", "text/plain", bytes.NewReader(nil), nil, ObjectOptions{PredefinedACL: "everyone"}); err == nil ||
+		!strings.Contains(err.Error(), "invalid predefined ACL") {
+		t.Errorf("expected invalid predefined ACL error, got %v", err)
+	}
+}
+
+func TestStore_ObjectACLWithUniformBucketLevelAccess(t *testing.T) {
+	s := New()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+	_, _ = s.CreateObject("test-bucket", "before.txt", "text/plain", []byte("x"), nil)
+
+	_, err := s.PatchBucket("test-bucket", &storage.BucketPatchRequest{BucketUpdateRequest: storage.BucketUpdateRequest{
+		IamConfiguration: &storage.IamConfiguration{UniformBucketLevelAccess: &storage.UniformBucketLevelAccess{Enabled: true}},
+	}})
+	if err != nil {
+		t.Fatalf("PatchBucket() error: %v", err)
+	}
+
+	// ACLs can't be set or read, and existing ACLs are hidden
+	if _, err := s.CreateObjectWithOptions("test-bucket", "public.txt", "text/plain", bytes.NewReader(nil), nil, ObjectOptions{PredefinedACL: "publicRead"}); err == nil ||
+		!strings.Contains(err.Error(), "uniform bucket-level access") {
+		t.Errorf("expected uniform bucket-level access error, got %v", err)
+	}
+	if obj, _ := s.CreateObject("test-bucket", "after.txt", "text/plain", []byte("x"), nil); len(obj.Acl) != 0 {
+		t.Errorf("expected no ACL, got %+v", obj.Acl)
+	}
+	if obj := s.GetObject("test-bucket", "before.txt"); len(obj.Acl) != 0 {
+		t.Errorf("expected the ACL to be hidden, got %+v", obj.Acl)
+	}
+	if _, err := s.ListObjectACL("test-bucket", "before.txt"); err == nil || !strings.Contains(err.Error(), "invalid ACL request") {
+		t.Errorf("expected invalid ACL request error, got %v", err)
+	}
+}
@@ -0,0 +1,112 @@
+package store
+
+import (
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+)
+
+// =============================================================================
+// Cloud Storage Autoclass Operations
+// =============================================================================
+
+// autoclassTransitions are the storage classes Autoclass moves objects to after they weren't
+// accessed for a while, coldest first.
+// Reference: https://cloud.google.com/storage/docs/autoclass#transitions
+var autoclassTransitions = []struct {
+	idle         time.Duration
+	storageClass string
+}{
+	{365 * 24 * time.Hour, storage.StorageClassArchive},
+	{90 * 24 * time.Hour, storage.StorageClassColdline},
+	{30 * 24 * time.Hour, storage.StorageClassNearline},
+}
+
+// updateAutoclass returns the Autoclass configuration of a bucket after setting it to requested,
+// keeping the toggle and update times of the current configuration for settings that didn't change.
+func updateAutoclass(current, requested *storage.Autoclass, now time.Time) *storage.Autoclass {
+	autoclass := &storage.Autoclass{Enabled: requested.Enabled, TerminalStorageClass: requested.TerminalStorageClass}
+	if current != nil && current.Enabled == autoclass.Enabled {
+		autoclass.ToggleTime = current.ToggleTime
+	} else {
+		autoclass.ToggleTime = &now
+	}
+
+	if !autoclass.Enabled {
+		autoclass.TerminalStorageClass = ""
+		return autoclass
+	}
+
+	if autoclass.TerminalStorageClass == "" {
+		autoclass.TerminalStorageClass = storage.StorageClassNearline
+	}
+	if current != nil && current.Enabled && current.TerminalStorageClass == autoclass.TerminalStorageClass {
+		autoclass.TerminalStorageClassUpdateTime = current.TerminalStorageClassUpdateTime
+	} else {
+		autoclass.TerminalStorageClassUpdateTime = &now
+	}
+	return autoclass
+}
+
+// autoclassStorageClass returns the storage class Autoclass moved an object to and when it did.
+// Objects start in STANDARD when they are created, read or Autoclass is enabled, and move to colder
+// storage classes while they aren't read, up to the terminal storage class.
+func autoclassStorageClass(autoclass *storage.Autoclass, objData *ObjectData, now time.Time) (string, time.Time) {
+	since := objData.Metadata.TimeCreated
+	if accessed := objData.accessed.Load(); accessed != 0 && time.Unix(0, accessed).After(since) {
+		since = time.Unix(0, accessed)
+	}
+	if autoclass.ToggleTime != nil && autoclass.ToggleTime.After(since) {
+		since = *autoclass.ToggleTime
+	}
+
+	for _, transition := range autoclassTransitions {
+		if transition.storageClass != storage.StorageClassNearline && autoclass.TerminalStorageClass != storage.StorageClassArchive {
+			continue
+		}
+		if now.Sub(since) >= transition.idle {
+			return transition.storageClass, since.Add(transition.idle)
+		}
+	}
+	return storage.StorageClassStandard, since
+}
+
+// objectMetadata returns a copy of the metadata of an object, with the storage class Autoclass
+// moved it to if the bucket has Autoclass enabled. Objects in buckets with uniform bucket-level
+// access are returned without their ACL, which only takes effect again once it's disabled.
+// Callers must hold the storage lock.
+func (s *Store) objectMetadata(bucketName string, objData *ObjectData) *storage.Object {
+	obj := clone(objData.Metadata)
+	bucket := s.buckets[bucketName]
+	if bucket != nil && bucket.Autoclass != nil && bucket.Autoclass.Enabled {
+		obj.StorageClass, obj.TimeStorageClassUpdated = autoclassStorageClass(bucket.Autoclass, objData, s.now())
+	}
+	if bucket != nil && uniformBucketLevelAccess(bucket) {
+		obj.Acl = nil
+	}
+	return obj
+}
+
+// setAutoclass sets the Autoclass configuration of a bucket. Enabling Autoclass makes STANDARD the
+// default storage class; disabling it keeps objects in the storage classes Autoclass moved them to.
+// Callers must hold the storage write lock.
+func (s *Store) setAutoclass(bucketName string, bucket *storage.Bucket, requested *storage.Autoclass) {
+	if bucket.Autoclass != nil && bucket.Autoclass.Enabled && !requested.Enabled {
+		s.settleAutoclass(bucketName, bucket.Autoclass)
+	}
+
+	bucket.Autoclass = updateAutoclass(bucket.Autoclass, requested, s.now())
+	if bucket.Autoclass.Enabled {
+		bucket.StorageClass = storage.StorageClassStandard
+	}
+}
+
+// settleAutoclass stores the storage classes Autoclass moved the objects of a bucket to,
+// so they keep them once Autoclass is disabled.
+// Callers must hold the storage write lock.
+func (s *Store) settleAutoclass(bucketName string, autoclass *storage.Autoclass) {
+	now := s.now()
+	for _, objData := range s.objects[bucketName] {
+		objData.Metadata.StorageClass, objData.Metadata.TimeStorageClassUpdated = autoclassStorageClass(autoclass, objData, now)
+	}
+}
@@ -0,0 +1,143 @@
+package store
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+)
+
+func TestStore_Autoclass(t *testing.T) {
+	s := New()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.SetClock(func() time.Time { return now })
+
+	bucket, err := s.CreateBucket(&storage.BucketInsertRequest{
+		Name:         "test-bucket",
+		StorageClass: "NEARLINE",
+		Autoclass:    &storage.Autoclass{Enabled: true, TerminalStorageClass: "ARCHIVE"},
+	})
+	if err != nil {
+		t.Fatalf("CreateBucket() error = %v", err)
+	}
+	if bucket.StorageClass != "STANDARD" || bucket.Autoclass.ToggleTime == nil || !bucket.Autoclass.ToggleTime.Equal(now) {
+		t.Errorf("expected STANDARD bucket with Autoclass toggled now, got %+v, %+v", bucket, bucket.Autoclass)
+	}
+
+	_, _ = s.CreateObject("test-bucket", "cold.txt", "text/plain", []byte("cold"), nil)
+	_, _ = s.CreateObject("test-bucket", "read.txt", "text/plain", []byte("read"), nil)
+
+	if _, err := s.CreateObjectWithOptions("test-bucket", "set.txt", "", strings.NewReader("x"), nil, ObjectOptions{StorageClass: "COLDLINE"}); err == nil || !strings.Contains(err.Error(), "invalid storage class") {
+		t.Errorf("expected invalid storage class error, got %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		days         int
+		read         bool
+		coldClass    string
+		readClass    string
+		updatedAfter int
+	}{
+		{"new objects", 0, false, "STANDARD", "STANDARD", 0},
+		{"after 30 days", 30, true, "NEARLINE", "NEARLINE", 30},
+		{"after 90 days", 90, false, "COLDLINE", "NEARLINE", 90},
+		{"after 365 days", 365, false, "ARCHIVE", "COLDLINE", 365},
+	}
+
+	start := now
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = start.AddDate(0, 0, tt.days)
+
+			cold := s.GetObject("test-bucket", "cold.txt")
+			if cold.StorageClass != tt.coldClass || !cold.TimeStorageClassUpdated.Equal(start.AddDate(0, 0, tt.updatedAfter)) {
+				t.Errorf("expected %s since day %d, got %s since %v", tt.coldClass, tt.updatedAfter, cold.StorageClass, cold.TimeStorageClassUpdated)
+			}
+			if read := s.GetObject("test-bucket", "read.txt"); read.StorageClass != tt.readClass {
+				t.Errorf("expected read object in %s, got %s", tt.readClass, read.StorageClass)
+			}
+
+			if tt.read {
+				s.GetObjectContent("test-bucket", "read.txt")
+			}
+		})
+	}
+
+	// A NEARLINE terminal storage class stops transitions at NEARLINE
+	if _, err := s.PatchBucket("test-bucket", &storage.BucketPatchRequest{BucketUpdateRequest: storage.BucketUpdateRequest{
+		Autoclass: &storage.Autoclass{Enabled: true},
+	}}); err != nil {
+		t.Fatalf("PatchBucket() error = %v", err)
+	}
+	if obj := s.GetObject("test-bucket", "cold.txt"); obj.StorageClass != "NEARLINE" {
+		t.Errorf("expected NEARLINE with NEARLINE terminal storage class, got %s", obj.StorageClass)
+	}
+	objects, _ := s.ListObjects("test-bucket", "", "")
+	if len(objects) != 2 || objects[0].StorageClass != "NEARLINE" {
+		t.Errorf("expected listed objects with Autoclass storage classes, got %+v", objects)
+	}
+
+	// Disabling Autoclass keeps the storage classes objects were moved to
+	bucket, err = s.UpdateBucket("test-bucket", &storage.BucketUpdateRequest{Autoclass: &storage.Autoclass{}})
+	if err != nil {
+		t.Fatalf("UpdateBucket() error = %v", err)
+	}
+	if bucket.Autoclass.Enabled || !bucket.Autoclass.ToggleTime.Equal(now) {
+		t.Errorf("expected disabled Autoclass toggled now, got %+v", bucket.Autoclass)
+	}
+	now = now.AddDate(1, 0, 0)
+	if obj := s.GetObject("test-bucket", "cold.txt"); obj.StorageClass != "NEARLINE" {
+		t.Errorf("expected NEARLINE after disabling Autoclass, got %s", obj.StorageClass)
+	}
+}
+
+func TestStore_RewriteObject(t *testing.T) {
+	s := New()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "src-bucket"})
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "dst-bucket", StorageClass: "NEARLINE"})
+	_, _ = s.CreateObject("src-bucket", "file.txt", "text/plain", []byte("hello"), map[string]string{"key": "value"})
+
+	// Rewriting an object onto itself changes its storage class
+	obj, err := s.RewriteObject("src-bucket", "file.txt", "src-bucket", "file.txt", &storage.ObjectInsertRequest{StorageClass: "COLDLINE"}, Preconditions{})
+	if err != nil {
+		t.Fatalf("RewriteObject() error = %v", err)
+	}
+	if obj.StorageClass != "COLDLINE" || obj.ContentType != "text/plain" || obj.Metadata["key"] != "value" {
+		t.Errorf("expected COLDLINE object with the source attributes, got %+v", obj)
+	}
+	if content := s.GetObjectContent("src-bucket", "file.txt"); string(content) != "hello" {
+		t.Errorf("expected content 'hello', got '%s'", string(content))
+	}
+
+	// Without a storage class, the destination bucket's default is used
+	obj, err = s.RewriteObject("src-bucket", "file.txt", "dst-bucket", "copy.txt", &storage.ObjectInsertRequest{ContentType: "text/csv"}, Preconditions{})
+	if err != nil {
+		t.Fatalf("RewriteObject() error = %v", err)
+	}
+	if obj.StorageClass != "NEARLINE" || obj.ContentType != "text/csv" || obj.Size != 5 {
+		t.Errorf("expected NEARLINE text/csv copy, got %+v", obj)
+	}
+
+	tests := []struct {
+		name          string
+		srcObject     string
+		dstBucket     string
+		req           *storage.ObjectInsertRequest
+		expectedError string
+	}{
+		{"missing source", "missing.txt", "dst-bucket", &storage.ObjectInsertRequest{}, "not found"},
+		{"missing destination bucket", "file.txt", "missing-bucket", &storage.ObjectInsertRequest{}, "not found"},
+		{"invalid storage class", "file.txt", "dst-bucket", &storage.ObjectInsertRequest{StorageClass: "FROZEN"}, "invalid storage class"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.RewriteObject("src-bucket", tt.srcObject, tt.dstBucket, "other.txt", tt.req, Preconditions{})
+			if err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+				t.Errorf("expected %q error, got %v", tt.expectedError, err)
+			}
+		})
+	}
+}
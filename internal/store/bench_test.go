@@ -0,0 +1,81 @@
+package store
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/sqladmin"
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+)
+
+func TestStore_FamilyLocksAreIndependent(t *testing.T) {
+	s := New()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+
+	// A long-running storage write must not block Cloud SQL requests
+	s.storageMu.Lock()
+	defer s.storageMu.Unlock()
+
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := s.CreateSQLInstance(&sqladmin.InstanceInsertRequest{Name: "test-instance"})
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("CreateSQLInstance() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("CreateSQLInstance() blocked on the storage lock")
+	}
+}
+
+func BenchmarkStore_ParallelObjectWrites(b *testing.B) {
+	s := New()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "bench-bucket"})
+	content := make([]byte, 1024)
+
+	var seq atomic.Int64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			name := fmt.Sprintf("object-%d", seq.Add(1)%1000)
+			if _, err := s.CreateObject("bench-bucket", name, "text/plain", content, nil); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkStore_ParallelMixedFamilies(b *testing.B) {
+	s := New()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "bench-bucket"})
+	_, _, _ = s.CreateSQLInstance(&sqladmin.InstanceInsertRequest{Name: "bench-instance"})
+	content := make([]byte, 1024)
+
+	var seq atomic.Int64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			n := seq.Add(1)
+			// Object writes interleaved with Cloud SQL and Firestore requests, like a parallel test suite
+			switch n % 3 {
+			case 0:
+				if _, err := s.CreateObject("bench-bucket", fmt.Sprintf("object-%d", n%1000), "text/plain", content, nil); err != nil {
+					b.Fatal(err)
+				}
+			case 1:
+				if _, err := s.ListSQLDatabases("bench-instance"); err != nil {
+					b.Fatal(err)
+				}
+				_ = s.ListSQLOperations("bench-instance")
+			case 2:
+				_ = s.GetDocument(fmt.Sprintf("projects/p/databases/(default)/documents/c/doc-%d", n%100))
+			}
+		}
+	})
+}
@@ -0,0 +1,212 @@
+package store
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/billing"
+)
+
+// =============================================================================
+// Cloud Billing Budget Operations
+// =============================================================================
+
+// billingAccountPattern matches billing account parents like billingAccounts/012345-6789AB-CDEF01.
+var billingAccountPattern = regexp.MustCompile(`^billingAccounts/[0-9A-F]{6}-[0-9A-F]{6}-[0-9A-F]{6}$`)
+
+// currencyCodePattern matches three-letter ISO 4217 currency codes.
+var currencyCodePattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// CreateBillingBudget creates a budget below parent (billingAccounts/{billingAccount}) with a generated ID.
+func (s *Store) CreateBillingBudget(parent string, req *billing.Budget) (*billing.Budget, error) {
+	s.billingMu.Lock()
+	defer s.billingMu.Unlock()
+
+	if !billingAccountPattern.MatchString(parent) {
+		return nil, fmt.Errorf("invalid parent %q: must be like billingAccounts/012345-6789AB-CDEF01", parent)
+	}
+	if req == nil {
+		return nil, fmt.Errorf("invalid request: budget is required")
+	}
+
+	budget := clone(req)
+	if err := applyBillingBudgetDefaults(budget); err != nil {
+		return nil, err
+	}
+	budget.Name = parent + "/budgets/" + newUUID()
+	budget.Etag = generateEtag()
+	s.billingBudgets[budget.Name] = budget
+
+	return clone(budget), nil
+}
+
+// GetBillingBudget retrieves a budget by name (billingAccounts/{billingAccount}/budgets/{budget}).
+// Returns nil if the budget doesn't exist.
+func (s *Store) GetBillingBudget(name string) *billing.Budget {
+	s.billingMu.RLock()
+	defer s.billingMu.RUnlock()
+
+	return clone(s.billingBudgets[name])
+}
+
+// ListBillingBudgets returns all budgets below parent, sorted by name.
+func (s *Store) ListBillingBudgets(parent string) []*billing.Budget {
+	s.billingMu.RLock()
+	defer s.billingMu.RUnlock()
+
+	budgets := make([]*billing.Budget, 0)
+	for name, budget := range s.billingBudgets {
+		if strings.HasPrefix(name, parent+"/budgets/") {
+			budgets = append(budgets, budget)
+		}
+	}
+
+	sort.Slice(budgets, func(i, j int) bool {
+		return budgets[i].Name < budgets[j].Name
+	})
+
+	return clone(budgets)
+}
+
+// BillingBudgetNotification builds the notification a budget sends to its Pub/Sub topic for a spend.
+// The thresholds exceeded are the highest ones of each spend basis the cost (or forecast) has reached,
+// and the cost interval starts at the beginning of the budget's calendar period.
+func (s *Store) BillingBudgetNotification(name string, req *billing.AlertRequest) (*billing.Budget, *billing.Notification, error) {
+	s.billingMu.RLock()
+	defer s.billingMu.RUnlock()
+
+	budget, exists := s.billingBudgets[name]
+	if !exists {
+		return nil, nil, fmt.Errorf("budget %s not found", name)
+	}
+	if budget.NotificationsRule == nil || budget.NotificationsRule.PubsubTopic == "" {
+		return nil, nil, fmt.Errorf("precondition failed: budget %s has no notificationsRule.pubsubTopic to send notifications to", name)
+	}
+	if req.CostAmount < 0 || req.ForecastedAmount < 0 {
+		return nil, nil, fmt.Errorf("invalid alert: costAmount and forecastedAmount must not be negative")
+	}
+
+	n := &billing.Notification{
+		BudgetDisplayName: budget.DisplayName,
+		CostAmount:        req.CostAmount,
+		CostIntervalStart: billingPeriodStart(s.now(), budget.BudgetFilter.CalendarPeriod),
+	}
+	if amount := budget.Amount.SpecifiedAmount; amount != nil {
+		units, _ := strconv.ParseInt(amount.Units, 10, 64)
+		n.BudgetAmount = float64(units) + float64(amount.Nanos)/1e9
+		n.BudgetAmountType = billing.AmountTypeSpecified
+		n.CurrencyCode = amount.CurrencyCode
+	} else {
+		if req.BudgetAmount <= 0 {
+			return nil, nil, fmt.Errorf("invalid alert: budgetAmount, the spend of the last period, is required for budgets with lastPeriodAmount")
+		}
+		n.BudgetAmount = req.BudgetAmount
+		n.BudgetAmountType = billing.AmountTypeLastPeriod
+		n.CurrencyCode = "USD"
+	}
+
+	for _, rule := range budget.ThresholdRules {
+		threshold := rule.ThresholdPercent * n.BudgetAmount
+		switch {
+		case rule.SpendBasis == billing.SpendBasisForecasted:
+			if req.ForecastedAmount >= threshold {
+				n.ForecastThresholdExceeded = max(n.ForecastThresholdExceeded, rule.ThresholdPercent)
+			}
+		case req.CostAmount >= threshold:
+			n.AlertThresholdExceeded = max(n.AlertThresholdExceeded, rule.ThresholdPercent)
+		}
+	}
+
+	return clone(budget), n, nil
+}
+
+// applyBillingBudgetDefaults validates a new budget and fills in the defaults of the Budget API.
+func applyBillingBudgetDefaults(budget *billing.Budget) error {
+	if budget.Amount == nil || (budget.Amount.SpecifiedAmount == nil) == (budget.Amount.LastPeriodAmount == nil) {
+		return fmt.Errorf("invalid budget: amount must set exactly one of specifiedAmount and lastPeriodAmount")
+	}
+	if amount := budget.Amount.SpecifiedAmount; amount != nil {
+		if amount.Units == "" {
+			amount.Units = "0"
+		}
+		if units, err := strconv.ParseInt(amount.Units, 10, 64); err != nil || units < 0 {
+			return fmt.Errorf("invalid amount.specifiedAmount.units %q: must be a non-negative integer", amount.Units)
+		}
+		if amount.Nanos < 0 || amount.Nanos >= 1e9 {
+			return fmt.Errorf("invalid amount.specifiedAmount.nanos %d: must be between 0 and 999999999", amount.Nanos)
+		}
+		if amount.CurrencyCode == "" {
+			amount.CurrencyCode = "USD"
+		}
+		if !currencyCodePattern.MatchString(amount.CurrencyCode) {
+			return fmt.Errorf("invalid amount.specifiedAmount.currencyCode %q: must be a three-letter ISO 4217 code", amount.CurrencyCode)
+		}
+	}
+
+	if budget.BudgetFilter == nil {
+		budget.BudgetFilter = &billing.Filter{}
+	}
+	filter := budget.BudgetFilter
+	if filter.CreditTypesTreatment == "" {
+		filter.CreditTypesTreatment = "INCLUDE_ALL_CREDITS"
+	}
+	if !slices.Contains([]string{"INCLUDE_ALL_CREDITS", "EXCLUDE_ALL_CREDITS", "INCLUDE_SPECIFIED_CREDITS"}, filter.CreditTypesTreatment) {
+		return fmt.Errorf("invalid budgetFilter.creditTypesTreatment %q", filter.CreditTypesTreatment)
+	}
+	if filter.CalendarPeriod == "" {
+		filter.CalendarPeriod = "MONTH"
+	}
+	if !slices.Contains([]string{"MONTH", "QUARTER", "YEAR"}, filter.CalendarPeriod) {
+		return fmt.Errorf("invalid budgetFilter.calendarPeriod %q: must be MONTH, QUARTER or YEAR", filter.CalendarPeriod)
+	}
+	for _, project := range filter.Projects {
+		if !strings.HasPrefix(project, "projects/") {
+			return fmt.Errorf("invalid budgetFilter.projects entry %q: must be like projects/{project}", project)
+		}
+	}
+
+	for _, rule := range budget.ThresholdRules {
+		if rule == nil || rule.ThresholdPercent <= 0 {
+			return fmt.Errorf("invalid thresholdRules: thresholdPercent must be greater than 0")
+		}
+		if rule.SpendBasis == "" {
+			rule.SpendBasis = billing.SpendBasisCurrent
+		}
+		if rule.SpendBasis != billing.SpendBasisCurrent && rule.SpendBasis != billing.SpendBasisForecasted {
+			return fmt.Errorf("invalid thresholdRules.spendBasis %q: must be CURRENT_SPEND or FORECASTED_SPEND", rule.SpendBasis)
+		}
+	}
+
+	if rule := budget.NotificationsRule; rule != nil && rule.PubsubTopic != "" {
+		isWebhook := strings.HasPrefix(rule.PubsubTopic, "http://") || strings.HasPrefix(rule.PubsubTopic, "https://")
+		if !isWebhook && !pubsubTopicPattern.MatchString(rule.PubsubTopic) {
+			return fmt.Errorf("invalid notificationsRule.pubsubTopic %q: must be like projects/{project}/topics/{topic}", rule.PubsubTopic)
+		}
+		if rule.SchemaVersion == "" {
+			rule.SchemaVersion = billing.SchemaVersion
+		}
+		if rule.SchemaVersion != billing.SchemaVersion {
+			return fmt.Errorf("invalid notificationsRule.schemaVersion %q: must be %s", rule.SchemaVersion, billing.SchemaVersion)
+		}
+	}
+
+	return nil
+}
+
+// billingPeriodStart returns the start of the calendar period (MONTH, QUARTER or YEAR) t is in, in UTC.
+func billingPeriodStart(t time.Time, period string) time.Time {
+	t = t.UTC()
+	month := t.Month()
+	switch period {
+	case "QUARTER":
+		month = (month-1)/3*3 + 1
+	case "YEAR":
+		month = time.January
+	}
+	return time.Date(t.Year(), month, 1, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,111 @@
+package store
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/billing"
+)
+
+const testBillingAccount = "billingAccounts/012345-6789AB-CDEF01"
+
+func TestStore_CreateBillingBudget(t *testing.T) {
+	s := New()
+
+	budget, err := s.CreateBillingBudget(testBillingAccount, &billing.Budget{
+		DisplayName:    "monthly",
+		Amount:         &billing.BudgetAmount{SpecifiedAmount: &billing.Money{Units: "100"}},
+		ThresholdRules: []*billing.ThresholdRule{{ThresholdPercent: 0.5}},
+	})
+	if err != nil {
+		t.Fatalf("CreateBillingBudget() error: %v", err)
+	}
+	if !strings.HasPrefix(budget.Name, testBillingAccount+"/budgets/") || budget.Etag == "" {
+		t.Errorf("unexpected budget: %+v", budget)
+	}
+	if budget.Amount.SpecifiedAmount.CurrencyCode != "USD" || budget.BudgetFilter.CalendarPeriod != "MONTH" ||
+		budget.ThresholdRules[0].SpendBasis != billing.SpendBasisCurrent {
+		t.Errorf("expected defaults to be filled in, got %+v", budget)
+	}
+	if s.GetBillingBudget(budget.Name) == nil || len(s.ListBillingBudgets(testBillingAccount)) != 1 {
+		t.Error("expected the budget to be stored")
+	}
+	if len(s.ListBillingBudgets("billingAccounts/FEDCBA-987654-321000")) != 0 {
+		t.Error("expected no budgets for another billing account")
+	}
+
+	invalid := []struct {
+		name   string
+		parent string
+		budget *billing.Budget
+	}{
+		{"invalid billing account", "billingAccounts/mine", &billing.Budget{Amount: &billing.BudgetAmount{LastPeriodAmount: &billing.LastPeriodAmount{}}}},
+		{"missing amount", testBillingAccount, &billing.Budget{}},
+		{"both amounts", testBillingAccount, &billing.Budget{Amount: &billing.BudgetAmount{SpecifiedAmount: &billing.Money{Units: "1"}, LastPeriodAmount: &billing.LastPeriodAmount{}}}},
+		{"invalid units", testBillingAccount, &billing.Budget{Amount: &billing.BudgetAmount{SpecifiedAmount: &billing.Money{Units: "ten"}}}},
+		{"invalid threshold", testBillingAccount, &billing.Budget{
+			Amount:         &billing.BudgetAmount{LastPeriodAmount: &billing.LastPeriodAmount{}},
+			ThresholdRules: []*billing.ThresholdRule{{ThresholdPercent: 0}},
+		}},
+		{"invalid topic", testBillingAccount, &billing.Budget{
+			Amount:            &billing.BudgetAmount{LastPeriodAmount: &billing.LastPeriodAmount{}},
+			NotificationsRule: &billing.NotificationsRule{PubsubTopic: "budget-alerts"},
+		}},
+	}
+	for _, tt := range invalid {
+		if _, err := s.CreateBillingBudget(tt.parent, tt.budget); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
+
+func TestStore_BillingBudgetNotification(t *testing.T) {
+	s := New()
+	s.SetClock(func() time.Time { return time.Date(2024, time.May, 17, 12, 0, 0, 0, time.UTC) })
+
+	budget, err := s.CreateBillingBudget(testBillingAccount, &billing.Budget{
+		DisplayName:  "quarterly",
+		BudgetFilter: &billing.Filter{CalendarPeriod: "QUARTER"},
+		Amount:       &billing.BudgetAmount{SpecifiedAmount: &billing.Money{CurrencyCode: "EUR", Units: "200", Nanos: 500000000}},
+		ThresholdRules: []*billing.ThresholdRule{
+			{ThresholdPercent: 0.5},
+			{ThresholdPercent: 0.9},
+			{ThresholdPercent: 1.0, SpendBasis: billing.SpendBasisForecasted},
+		},
+		NotificationsRule: &billing.NotificationsRule{PubsubTopic: "projects/test-project/topics/budgets"},
+	})
+	if err != nil {
+		t.Fatalf("CreateBillingBudget() error: %v", err)
+	}
+
+	_, n, err := s.BillingBudgetNotification(budget.Name, &billing.AlertRequest{CostAmount: 150, ForecastedAmount: 250})
+	if err != nil {
+		t.Fatalf("BillingBudgetNotification() error: %v", err)
+	}
+	expected := billing.Notification{
+		BudgetDisplayName:         "quarterly",
+		AlertThresholdExceeded:    0.5,
+		ForecastThresholdExceeded: 1.0,
+		CostAmount:                150,
+		CostIntervalStart:         time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC),
+		BudgetAmount:              200.5,
+		BudgetAmountType:          billing.AmountTypeSpecified,
+		CurrencyCode:              "EUR",
+	}
+	if *n != expected {
+		t.Errorf("expected notification %+v, got %+v", expected, *n)
+	}
+
+	// Budgets without a topic don't send notifications
+	silent, err := s.CreateBillingBudget(testBillingAccount, &billing.Budget{Amount: &billing.BudgetAmount{LastPeriodAmount: &billing.LastPeriodAmount{}}})
+	if err != nil {
+		t.Fatalf("CreateBillingBudget() error: %v", err)
+	}
+	if _, _, err := s.BillingBudgetNotification(silent.Name, &billing.AlertRequest{CostAmount: 1}); err == nil || !strings.Contains(err.Error(), "precondition failed") {
+		t.Errorf("expected a precondition error, got %v", err)
+	}
+	if _, _, err := s.BillingBudgetNotification(testBillingAccount+"/budgets/missing", &billing.AlertRequest{}); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/sqladmin"
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+)
+
+func TestClone(t *testing.T) {
+	created := time.Now().UTC()
+	original := &sqladmin.DatabaseInstance{
+		Name:         "test-instance",
+		ReplicaNames: []string{"replica"},
+		CreateTime:   created,
+		Settings: &sqladmin.Settings{
+			UserLabels:    map[string]string{"env": "test"},
+			DatabaseFlags: []*sqladmin.DatabaseFlags{{Name: "max_connections", Value: "100"}},
+		},
+	}
+
+	c := clone(original)
+	if c == original || c.Settings == original.Settings {
+		t.Fatal("expected clone to copy pointers")
+	}
+	if !c.CreateTime.Equal(created) || c.Settings.DatabaseFlags[0].Value != "100" {
+		t.Errorf("expected clone to keep values, got %+v", c)
+	}
+
+	c.ReplicaNames[0] = "changed"
+	c.Settings.UserLabels["env"] = "changed"
+	c.Settings.DatabaseFlags[0].Value = "200"
+	if original.ReplicaNames[0] != "replica" || original.Settings.UserLabels["env"] != "test" || original.Settings.DatabaseFlags[0].Value != "100" {
+		t.Errorf("modifying the clone changed the original: %+v", original.Settings)
+	}
+
+	var nilInstance *sqladmin.DatabaseInstance
+	if clone(nilInstance) != nil {
+		t.Error("expected clone of nil to be nil")
+	}
+}
+
+func TestStore_ReturnsCopies(t *testing.T) {
+	s := New()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket", Labels: map[string]string{"env": "test"}})
+	_, _, _ = s.CreateSQLInstance(&sqladmin.InstanceInsertRequest{Name: "test-instance"})
+
+	bucket := s.GetBucket("test-bucket")
+	bucket.Labels["env"] = "changed"
+	bucket.StorageClass = "ARCHIVE"
+	if got := s.GetBucket("test-bucket"); got.Labels["env"] != "test" || got.StorageClass == "ARCHIVE" {
+		t.Errorf("modifying a returned bucket changed the store: %+v", got)
+	}
+
+	instances := s.ListSQLInstances()
+	instances[0].State = "SUSPENDED"
+	if got := s.GetSQLInstance("test-instance"); got.State == "SUSPENDED" {
+		t.Error("modifying a returned instance changed the store")
+	}
+}
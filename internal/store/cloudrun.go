@@ -0,0 +1,392 @@
+package store
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/cloudrun"
+	"github.com/katharinasick/gcp-api-mock/internal/longrunning"
+)
+
+// =============================================================================
+// Cloud Run Service Operations
+// =============================================================================
+
+// Traffic allocation types of Cloud Run traffic targets.
+const (
+	runTrafficLatest   = "TRAFFIC_TARGET_ALLOCATION_TYPE_LATEST"
+	runTrafficRevision = "TRAFFIC_TARGET_ALLOCATION_TYPE_REVISION"
+)
+
+// runRevisionSuffixAlphabet is the alphabet for generated revision name suffixes.
+const runRevisionSuffixAlphabet = "abcdefghijklmnopqrstuvwxyz"
+
+// newRunRevisionSuffix generates the random three letter suffix Cloud Run appends to revision names.
+func newRunRevisionSuffix() string {
+	b := make([]byte, 3)
+	rand.Read(b)
+	for i := range b {
+		b[i] = runRevisionSuffixAlphabet[int(b[i])%len(runRevisionSuffixAlphabet)]
+	}
+	return string(b)
+}
+
+// splitRunServiceName splits a service name like projects/{project}/locations/{location}/services/{service}
+// into its parent (projects/{project}/locations/{location}) and service ID.
+func splitRunServiceName(name string) (string, string) {
+	parent, serviceID, found := strings.Cut(name, "/services/")
+	if !found {
+		return "", ""
+	}
+	return parent, serviceID
+}
+
+// CreateRunService creates a service below parent (projects/{project}/locations/{location}) and deploys
+// its first revision. The returned operation is already done.
+func (s *Store) CreateRunService(parent, serviceID string, req *cloudrun.Service) (*cloudrun.Operation, error) {
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+
+	name := parent + "/services/" + serviceID
+	if _, exists := s.runServices[name]; exists {
+		return nil, fmt.Errorf("service %s already exists", name)
+	}
+
+	op, err := s.deployRunService(name, req, nil)
+	return clone(op), err
+}
+
+// GetRunService retrieves a service by name.
+// Returns nil if the service doesn't exist.
+func (s *Store) GetRunService(name string) *cloudrun.Service {
+	s.runMu.RLock()
+	defer s.runMu.RUnlock()
+
+	return clone(s.runServices[name])
+}
+
+// ListRunServices returns all services below parent, sorted by name.
+func (s *Store) ListRunServices(parent string) []*cloudrun.Service {
+	s.runMu.RLock()
+	defer s.runMu.RUnlock()
+
+	services := make([]*cloudrun.Service, 0)
+	for name, service := range s.runServices {
+		if servicesParent, _ := splitRunServiceName(name); servicesParent == parent {
+			services = append(services, service)
+		}
+	}
+
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].Name < services[j].Name
+	})
+
+	return clone(services)
+}
+
+// UpdateRunService replaces the spec of a service. A new revision is deployed if the template changed.
+// If the service doesn't exist, it is created if allowMissing is set.
+func (s *Store) UpdateRunService(name string, req *cloudrun.Service, allowMissing bool) (*cloudrun.Operation, error) {
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+
+	existing, exists := s.runServices[name]
+	if !exists && !allowMissing {
+		return nil, fmt.Errorf("service %s not found", name)
+	}
+
+	op, err := s.deployRunService(name, req, existing)
+	return clone(op), err
+}
+
+// DeleteRunService deletes a service along with its revisions.
+func (s *Store) DeleteRunService(name string) (*cloudrun.Operation, error) {
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+
+	service, exists := s.runServices[name]
+	if !exists {
+		return nil, fmt.Errorf("service %s not found", name)
+	}
+
+	for revisionName, revision := range s.runRevisions {
+		if revision.Service == name {
+			delete(s.runRevisions, revisionName)
+		}
+	}
+	delete(s.runServices, name)
+
+	parent, _ := splitRunServiceName(name)
+	op, err := s.createRunOperation(parent, service)
+	return clone(op), err
+}
+
+// deployRunService stores the service built from req, replacing existing (nil when creating).
+// Must be called with the lock held.
+func (s *Store) deployRunService(name string, req *cloudrun.Service, existing *cloudrun.Service) (*cloudrun.Operation, error) {
+	parent, serviceID := splitRunServiceName(name)
+	if parent == "" || serviceID == "" {
+		return nil, fmt.Errorf("invalid service name %s", name)
+	}
+
+	now := s.now()
+	service := &cloudrun.Service{
+		Name:        name,
+		Description: req.Description,
+		Uid:         newUUID(),
+		Generation:  1,
+		Labels:      req.Labels,
+		Annotations: req.Annotations,
+		CreateTime:  now,
+		UpdateTime:  now,
+		Ingress:     req.Ingress,
+		LaunchStage: req.LaunchStage,
+		Template:    req.Template,
+		Traffic:     req.Traffic,
+		Uri:         fmt.Sprintf("https://%s-%d.%s.run.app", serviceID, s.config().projectNumber, runLocation(parent)),
+		Etag:        generateEtag(),
+	}
+	if service.Ingress == "" {
+		service.Ingress = "INGRESS_TRAFFIC_ALL"
+	}
+	if service.LaunchStage == "" {
+		service.LaunchStage = "GA"
+	}
+	if service.Template == nil {
+		service.Template = &cloudrun.RevisionTemplate{}
+	}
+	if len(service.Traffic) == 0 {
+		service.Traffic = []*cloudrun.TrafficTarget{{Type: runTrafficLatest, Percent: 100}}
+	}
+
+	// Revisions are immutable, so a new one is only needed if the template changed
+	var revision *cloudrun.Revision
+	if existing != nil {
+		service.Uid = existing.Uid
+		service.Generation = existing.Generation + 1
+		service.CreateTime = existing.CreateTime
+		service.LatestCreatedRevision = existing.LatestCreatedRevision
+	}
+	if existing == nil || !runTemplatesEqual(existing.Template, service.Template) {
+		var err error
+		if revision, err = s.newRunRevision(service, now); err != nil {
+			return nil, err
+		}
+		service.LatestCreatedRevision = revision.Name
+	}
+	service.LatestReadyRevision = service.LatestCreatedRevision
+	service.ObservedGeneration = service.Generation
+
+	trafficStatuses, err := s.runTrafficStatuses(service, revision)
+	if err != nil {
+		return nil, err
+	}
+	service.TrafficStatuses = trafficStatuses
+
+	ready := &cloudrun.Condition{Type: "Ready", State: "CONDITION_SUCCEEDED", LastTransitionTime: now}
+	service.TerminalCondition = ready
+	service.Conditions = []*cloudrun.Condition{
+		{Type: "RoutesReady", State: "CONDITION_SUCCEEDED", LastTransitionTime: now},
+		{Type: "ConfigurationsReady", State: "CONDITION_SUCCEEDED", LastTransitionTime: now},
+	}
+
+	if revision != nil {
+		s.runRevisions[revision.Name] = revision
+	}
+	s.runServices[name] = service
+
+	return s.createRunOperation(parent, service)
+}
+
+// newRunRevision builds the next revision of a service from its template.
+// Must be called with the lock held.
+func (s *Store) newRunRevision(service *cloudrun.Service, now time.Time) (*cloudrun.Revision, error) {
+	_, serviceID := splitRunServiceName(service.Name)
+	template := service.Template
+
+	revisionID := template.Revision
+	if revisionID == "" {
+		count := 0
+		for _, revision := range s.runRevisions {
+			if revision.Service == service.Name {
+				count++
+			}
+		}
+		revisionID = fmt.Sprintf("%s-%05d-%s", serviceID, count+1, newRunRevisionSuffix())
+	}
+
+	name := service.Name + "/revisions/" + revisionID
+	if _, exists := s.runRevisions[name]; exists {
+		return nil, fmt.Errorf("revision %s already exists", name)
+	}
+
+	return &cloudrun.Revision{
+		Name:                          name,
+		Uid:                           newUUID(),
+		Generation:                    1,
+		Labels:                        template.Labels,
+		Annotations:                   template.Annotations,
+		CreateTime:                    now,
+		UpdateTime:                    now,
+		LaunchStage:                   service.LaunchStage,
+		Service:                       service.Name,
+		Scaling:                       template.Scaling,
+		Timeout:                       template.Timeout,
+		ServiceAccount:                template.ServiceAccount,
+		Containers:                    template.Containers,
+		ExecutionEnvironment:          template.ExecutionEnvironment,
+		MaxInstanceRequestConcurrency: template.MaxInstanceRequestConcurrency,
+		Conditions: []*cloudrun.Condition{
+			{Type: "Ready", State: "CONDITION_SUCCEEDED", LastTransitionTime: now},
+		},
+		ObservedGeneration: 1,
+		Etag:               generateEtag(),
+	}, nil
+}
+
+// runTrafficStatuses validates the traffic targets of a service and resolves them to revisions.
+// The pending revision counts as existing, since it is stored together with the service.
+// Must be called with the lock held.
+func (s *Store) runTrafficStatuses(service *cloudrun.Service, pending *cloudrun.Revision) ([]*cloudrun.TrafficTargetStatus, error) {
+	statuses := make([]*cloudrun.TrafficTargetStatus, 0, len(service.Traffic))
+	total := 0
+	for _, target := range service.Traffic {
+		status := &cloudrun.TrafficTargetStatus{
+			Type:     target.Type,
+			Revision: target.Revision,
+			Percent:  target.Percent,
+			Tag:      target.Tag,
+		}
+
+		switch target.Type {
+		case runTrafficLatest:
+			_, status.Revision, _ = strings.Cut(service.LatestReadyRevision, "/revisions/")
+		case runTrafficRevision:
+			name := service.Name + "/revisions/" + target.Revision
+			if _, exists := s.runRevisions[name]; !exists && (pending == nil || pending.Name != name) {
+				return nil, fmt.Errorf("invalid traffic target: revision %s does not exist", target.Revision)
+			}
+		default:
+			return nil, fmt.Errorf("invalid traffic target type: %s", target.Type)
+		}
+
+		if target.Tag != "" {
+			status.Uri = strings.Replace(service.Uri, "https://", "https://"+target.Tag+"---", 1)
+		}
+		total += target.Percent
+		statuses = append(statuses, status)
+	}
+
+	if total != 100 {
+		return nil, fmt.Errorf("invalid traffic targets: percentages add up to %d, not 100", total)
+	}
+
+	return statuses, nil
+}
+
+// runTemplatesEqual reports whether two revision templates describe the same revision.
+func runTemplatesEqual(a, b *cloudrun.RevisionTemplate) bool {
+	aJSON, _ := json.Marshal(a)
+	bJSON, _ := json.Marshal(b)
+	return string(aJSON) == string(bJSON)
+}
+
+// runLocation returns the location of a parent like projects/{project}/locations/{location}.
+func runLocation(parent string) string {
+	_, location, _ := strings.Cut(parent, "/locations/")
+	return location
+}
+
+// =============================================================================
+// Cloud Run Revision Operations
+// =============================================================================
+
+// GetRunRevision retrieves a revision by name.
+// Returns nil if the revision doesn't exist.
+func (s *Store) GetRunRevision(name string) *cloudrun.Revision {
+	s.runMu.RLock()
+	defer s.runMu.RUnlock()
+
+	return clone(s.runRevisions[name])
+}
+
+// ListRunRevisions returns the revisions of a service, newest first.
+func (s *Store) ListRunRevisions(serviceName string) ([]*cloudrun.Revision, error) {
+	s.runMu.RLock()
+	defer s.runMu.RUnlock()
+
+	if _, exists := s.runServices[serviceName]; !exists {
+		return nil, fmt.Errorf("service %s not found", serviceName)
+	}
+
+	revisions := make([]*cloudrun.Revision, 0)
+	for _, revision := range s.runRevisions {
+		if revision.Service == serviceName {
+			revisions = append(revisions, revision)
+		}
+	}
+
+	sort.Slice(revisions, func(i, j int) bool {
+		if !revisions[i].CreateTime.Equal(revisions[j].CreateTime) {
+			return revisions[i].CreateTime.After(revisions[j].CreateTime)
+		}
+		return revisions[i].Name > revisions[j].Name
+	})
+
+	return clone(revisions), nil
+}
+
+// =============================================================================
+// Cloud Run Operation Operations
+// =============================================================================
+
+// createRunOperation creates and stores a completed operation whose response is the service.
+// Must be called with the lock held.
+func (s *Store) createRunOperation(parent string, service *cloudrun.Service) (*cloudrun.Operation, error) {
+	response, err := longrunning.NewAny(cloudrun.ServiceTypeURL, service)
+	if err != nil {
+		return nil, err
+	}
+
+	op := &cloudrun.Operation{
+		Name:     parent + "/operations/" + newUUID(),
+		Metadata: response,
+		Done:     true,
+		Response: response,
+	}
+	s.runOperations[op.Name] = op
+
+	return op, nil
+}
+
+// GetRunOperation retrieves an operation by name.
+// Returns nil if the operation doesn't exist.
+func (s *Store) GetRunOperation(name string) *cloudrun.Operation {
+	s.runMu.RLock()
+	defer s.runMu.RUnlock()
+
+	return clone(s.runOperations[name])
+}
+
+// ListRunOperations returns all operations below parent, sorted by name.
+func (s *Store) ListRunOperations(parent string) []*cloudrun.Operation {
+	s.runMu.RLock()
+	defer s.runMu.RUnlock()
+
+	operations := make([]*cloudrun.Operation, 0)
+	for name, op := range s.runOperations {
+		if strings.HasPrefix(name, parent+"/operations/") {
+			operations = append(operations, op)
+		}
+	}
+
+	sort.Slice(operations, func(i, j int) bool {
+		return operations[i].Name < operations[j].Name
+	})
+
+	return clone(operations)
+}
@@ -0,0 +1,123 @@
+package store
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/katharinasick/gcp-api-mock/internal/cloudrun"
+)
+
+const testRunParent = "projects/test-project/locations/us-central1"
+
+func TestStore_CreateRunService(t *testing.T) {
+	s := New()
+
+	op, err := s.CreateRunService(testRunParent, "api", &cloudrun.Service{
+		Template: &cloudrun.RevisionTemplate{Containers: []*cloudrun.Container{{Image: "nginx"}}},
+	})
+	if err != nil {
+		t.Fatalf("CreateRunService() error: %v", err)
+	}
+	if !op.Done || !strings.Contains(string(op.Response), cloudrun.ServiceTypeURL) {
+		t.Errorf("expected a done operation with a Service response, got %+v", op)
+	}
+
+	service := s.GetRunService(testRunParent + "/services/api")
+	if service == nil {
+		t.Fatal("expected service to exist")
+	}
+	if service.Generation != 1 || service.Ingress != "INGRESS_TRAFFIC_ALL" {
+		t.Errorf("unexpected defaults: generation %d, ingress %s", service.Generation, service.Ingress)
+	}
+	if service.Uri != "https://api-123456789012.us-central1.run.app" {
+		t.Errorf("unexpected uri: %s", service.Uri)
+	}
+	if len(service.TrafficStatuses) != 1 || service.TrafficStatuses[0].Percent != 100 {
+		t.Errorf("expected all traffic on the latest revision, got %+v", service.TrafficStatuses)
+	}
+
+	revision := s.GetRunRevision(service.LatestCreatedRevision)
+	if revision == nil || revision.Containers[0].Image != "nginx" {
+		t.Errorf("expected first revision with the template's containers, got %+v", revision)
+	}
+
+	if _, err := s.CreateRunService(testRunParent, "api", &cloudrun.Service{}); err == nil {
+		t.Error("expected error for duplicate service")
+	}
+}
+
+func TestStore_UpdateRunService(t *testing.T) {
+	s := New()
+	name := testRunParent + "/services/api"
+	template := func(image string) *cloudrun.RevisionTemplate {
+		return &cloudrun.RevisionTemplate{Containers: []*cloudrun.Container{{Image: image}}}
+	}
+
+	if _, err := s.UpdateRunService(name, &cloudrun.Service{Template: template("nginx")}, false); err == nil {
+		t.Error("expected error when updating a missing service")
+	}
+	if _, err := s.UpdateRunService(name, &cloudrun.Service{Template: template("nginx")}, true); err != nil {
+		t.Fatalf("UpdateRunService() with allowMissing error: %v", err)
+	}
+	first := s.GetRunService(name).LatestCreatedRevision
+
+	// Changing only labels doesn't create a revision
+	if _, err := s.UpdateRunService(name, &cloudrun.Service{Template: template("nginx"), Labels: map[string]string{"env": "test"}}, false); err != nil {
+		t.Fatalf("UpdateRunService() error: %v", err)
+	}
+	service := s.GetRunService(name)
+	if service.Generation != 2 || service.LatestCreatedRevision != first {
+		t.Errorf("expected generation 2 on revision %s, got %d on %s", first, service.Generation, service.LatestCreatedRevision)
+	}
+
+	// Changing the template does
+	if _, err := s.UpdateRunService(name, &cloudrun.Service{Template: template("nginx:2")}, false); err != nil {
+		t.Fatalf("UpdateRunService() error: %v", err)
+	}
+	revisions, _ := s.ListRunRevisions(name)
+	if len(revisions) != 2 {
+		t.Errorf("expected 2 revisions, got %d", len(revisions))
+	}
+
+	// Traffic must target existing revisions and add up to 100
+	_, firstID, _ := strings.Cut(first, "/revisions/")
+	invalid := []*cloudrun.TrafficTarget{
+		{Type: "TRAFFIC_TARGET_ALLOCATION_TYPE_REVISION", Revision: firstID, Percent: 50},
+	}
+	if _, err := s.UpdateRunService(name, &cloudrun.Service{Template: template("nginx:2"), Traffic: invalid}, false); err == nil {
+		t.Error("expected error for traffic not adding up to 100")
+	}
+	split := []*cloudrun.TrafficTarget{
+		{Type: "TRAFFIC_TARGET_ALLOCATION_TYPE_REVISION", Revision: firstID, Percent: 50},
+		{Type: "TRAFFIC_TARGET_ALLOCATION_TYPE_LATEST", Percent: 50, Tag: "canary"},
+	}
+	if _, err := s.UpdateRunService(name, &cloudrun.Service{Template: template("nginx:2"), Traffic: split}, false); err != nil {
+		t.Fatalf("UpdateRunService() with traffic split error: %v", err)
+	}
+	if uri := s.GetRunService(name).TrafficStatuses[1].Uri; uri != "https://canary---api-123456789012.us-central1.run.app" {
+		t.Errorf("unexpected tag uri: %s", uri)
+	}
+}
+
+func TestStore_DeleteRunService(t *testing.T) {
+	s := New()
+	name := testRunParent + "/services/api"
+
+	if _, err := s.DeleteRunService(name); err == nil {
+		t.Error("expected error when deleting a missing service")
+	}
+
+	s.CreateRunService(testRunParent, "api", &cloudrun.Service{})
+	revision := s.GetRunService(name).LatestCreatedRevision
+
+	op, err := s.DeleteRunService(name)
+	if err != nil {
+		t.Fatalf("DeleteRunService() error: %v", err)
+	}
+	if s.GetRunService(name) != nil || s.GetRunRevision(revision) != nil {
+		t.Error("expected service and revisions to be deleted")
+	}
+	if s.GetRunOperation(op.Name) == nil || len(s.ListRunOperations(testRunParent)) != 2 {
+		t.Error("expected create and delete operations to be listed")
+	}
+}
@@ -0,0 +1,445 @@
+package store
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/compute"
+)
+
+// =============================================================================
+// Compute Engine Network Operations
+// =============================================================================
+
+// computeNamePattern matches valid names of Compute Engine resources (RFC 1035): lowercase letters, digits and
+// hyphens, starting with a letter and not ending with a hyphen, at most 63 characters.
+var computeNamePattern = regexp.MustCompile(`^[a-z]([-a-z0-9]{0,61}[a-z0-9])?$`)
+
+// computeNetworkPattern matches references to networks: URLs, relative names like
+// projects/{project}/global/networks/{network} and partial names like global/networks/{network}.
+var computeNetworkPattern = regexp.MustCompile(`^(?:(?:.*/)?projects/([^/]+)/)?global/networks/([^/]+)$`)
+
+// computeDefaultNetwork is the network every project has. References to it are always valid,
+// whether or not the network was created in the mock.
+const computeDefaultNetwork = "default"
+
+// InsertComputeNetwork creates a network in a project. The returned operation is already done.
+func (s *Store) InsertComputeNetwork(project string, req *compute.Network) (*compute.Operation, error) {
+	s.computeMu.Lock()
+	defer s.computeMu.Unlock()
+
+	if !computeNamePattern.MatchString(req.Name) {
+		return nil, fmt.Errorf("invalid network name %q: must be 1-63 lowercase letters, digits and hyphens, starting with a letter", req.Name)
+	}
+	key := computeNetworkKey(project, req.Name)
+	if _, exists := s.computeNetworks[key]; exists {
+		return nil, fmt.Errorf("network %s already exists", key)
+	}
+
+	network := clone(req)
+	if network.AutoCreateSubnetworks == nil {
+		autoCreate := true
+		network.AutoCreateSubnetworks = &autoCreate
+	}
+	if network.RoutingConfig == nil || network.RoutingConfig.RoutingMode == "" {
+		network.RoutingConfig = &compute.RoutingConfig{RoutingMode: "REGIONAL"}
+	}
+	if network.Mtu == 0 {
+		network.Mtu = 1460
+	}
+	if network.NetworkFirewallPolicyEnforcementOrder == "" {
+		network.NetworkFirewallPolicyEnforcementOrder = "AFTER_CLASSIC_FIREWALL"
+	}
+	if err := validateComputeNetwork(network); err != nil {
+		return nil, err
+	}
+	network.Kind = "compute#network"
+	network.Id = newComputeID()
+	network.CreationTimestamp = s.now()
+	network.SelfLink = s.computeURL(key)
+	network.Subnetworks = nil
+	s.computeNetworks[key] = network
+
+	return clone(s.createComputeOperation("projects/"+project+"/global", "insert", network.SelfLink, network.Id)), nil
+}
+
+// GetComputeNetwork retrieves a network by project and name.
+// Returns nil if the network doesn't exist.
+func (s *Store) GetComputeNetwork(project, name string) *compute.Network {
+	s.computeMu.RLock()
+	defer s.computeMu.RUnlock()
+
+	return clone(s.computeNetworks[computeNetworkKey(project, name)])
+}
+
+// ListComputeNetworks returns the networks of a project, sorted by name.
+func (s *Store) ListComputeNetworks(project string) []*compute.Network {
+	s.computeMu.RLock()
+	defer s.computeMu.RUnlock()
+
+	prefix := "projects/" + project + "/global/networks/"
+	networks := make([]*compute.Network, 0)
+	for key, network := range s.computeNetworks {
+		if strings.HasPrefix(key, prefix) {
+			networks = append(networks, network)
+		}
+	}
+
+	sort.Slice(networks, func(i, j int) bool {
+		return networks[i].Name < networks[j].Name
+	})
+
+	return clone(networks)
+}
+
+// PatchComputeNetwork changes the routing mode and MTU of a network to their values in req, if set.
+// The returned operation is already done.
+func (s *Store) PatchComputeNetwork(project, name string, req *compute.Network) (*compute.Operation, error) {
+	s.computeMu.Lock()
+	defer s.computeMu.Unlock()
+
+	key := computeNetworkKey(project, name)
+	existing, exists := s.computeNetworks[key]
+	if !exists {
+		return nil, fmt.Errorf("network %s not found", key)
+	}
+
+	network := clone(existing)
+	if req.RoutingConfig != nil && req.RoutingConfig.RoutingMode != "" {
+		network.RoutingConfig = clone(req.RoutingConfig)
+	}
+	if req.Mtu != 0 {
+		network.Mtu = req.Mtu
+	}
+	if err := validateComputeNetwork(network); err != nil {
+		return nil, err
+	}
+	s.computeNetworks[key] = network
+
+	return clone(s.createComputeOperation("projects/"+project+"/global", "patch", network.SelfLink, network.Id)), nil
+}
+
+// DeleteComputeNetwork deletes a network. Networks with subnetworks can't be deleted.
+// The returned operation is already done.
+func (s *Store) DeleteComputeNetwork(project, name string) (*compute.Operation, error) {
+	s.computeMu.Lock()
+	defer s.computeMu.Unlock()
+
+	key := computeNetworkKey(project, name)
+	network, exists := s.computeNetworks[key]
+	if !exists {
+		return nil, fmt.Errorf("network %s not found", key)
+	}
+	if len(network.Subnetworks) > 0 {
+		return nil, fmt.Errorf("network %s is in use by subnetwork %s", key, network.Subnetworks[0])
+	}
+	delete(s.computeNetworks, key)
+
+	return clone(s.createComputeOperation("projects/"+project+"/global", "delete", network.SelfLink, network.Id)), nil
+}
+
+// validateComputeNetwork validates the settings of a network.
+func validateComputeNetwork(network *compute.Network) error {
+	if mode := network.RoutingConfig.RoutingMode; mode != "REGIONAL" && mode != "GLOBAL" {
+		return fmt.Errorf("invalid routingMode %q: must be REGIONAL or GLOBAL", mode)
+	}
+	if network.Mtu < 1300 || network.Mtu > 8896 {
+		return fmt.Errorf("invalid mtu %d: must be between 1300 and 8896", network.Mtu)
+	}
+	return nil
+}
+
+// checkComputeNetwork checks that a reference to a network, like the privateNetwork of a Cloud SQL instance,
+// names a network that exists in the mock or the default network. project is the project of partial references.
+func (s *Store) checkComputeNetwork(field, ref, project string) error {
+	match := computeNetworkPattern.FindStringSubmatch(ref)
+	if match == nil {
+		return fmt.Errorf("invalid %s %q: must be a network like projects/{project}/global/networks/{network}", field, ref)
+	}
+	if match[1] != "" {
+		project = match[1]
+	}
+	if match[2] == computeDefaultNetwork {
+		return nil
+	}
+
+	s.computeMu.RLock()
+	defer s.computeMu.RUnlock()
+
+	if _, exists := s.computeNetworks[computeNetworkKey(project, match[2])]; !exists {
+		return fmt.Errorf("invalid %s %q: the network doesn't exist, create it with networks.insert first", field, ref)
+	}
+	return nil
+}
+
+// computeNetworkKey returns the key of a network: projects/{project}/global/networks/{network}.
+func computeNetworkKey(project, name string) string {
+	return "projects/" + project + "/global/networks/" + name
+}
+
+// =============================================================================
+// Compute Engine Subnetwork Operations
+// =============================================================================
+
+// InsertComputeSubnetwork creates a subnetwork in a project and region. Its network must exist and its range
+// must not overlap the ranges of the other subnetworks of the network. The returned operation is already done.
+func (s *Store) InsertComputeSubnetwork(project, region string, req *compute.Subnetwork) (*compute.Operation, error) {
+	s.computeMu.Lock()
+	defer s.computeMu.Unlock()
+
+	if !computeNamePattern.MatchString(req.Name) {
+		return nil, fmt.Errorf("invalid subnetwork name %q: must be 1-63 lowercase letters, digits and hyphens, starting with a letter", req.Name)
+	}
+	key := computeSubnetworkKey(project, region, req.Name)
+	if _, exists := s.computeSubnetworks[key]; exists {
+		return nil, fmt.Errorf("subnetwork %s already exists", key)
+	}
+
+	match := computeNetworkPattern.FindStringSubmatch(req.Network)
+	if match == nil {
+		return nil, fmt.Errorf("invalid network %q: must be a network like projects/{project}/global/networks/{network}", req.Network)
+	}
+	networkProject := project
+	if match[1] != "" {
+		networkProject = match[1]
+	}
+	networkKey := computeNetworkKey(networkProject, match[2])
+	network, exists := s.computeNetworks[networkKey]
+	if !exists {
+		return nil, fmt.Errorf("network %s not found", networkKey)
+	}
+
+	subnetwork := clone(req)
+	gateway, err := s.validateComputeSubnetwork(networkKey, key, subnetwork)
+	if err != nil {
+		return nil, err
+	}
+	if subnetwork.Purpose == "" {
+		subnetwork.Purpose = "PRIVATE"
+	}
+	if subnetwork.StackType == "" {
+		subnetwork.StackType = "IPV4_ONLY"
+	}
+	subnetwork.Kind = "compute#subnetwork"
+	subnetwork.Id = newComputeID()
+	subnetwork.CreationTimestamp = s.now()
+	subnetwork.Network = network.SelfLink
+	subnetwork.GatewayAddress = gateway
+	subnetwork.Region = s.computeURL("projects/" + project + "/regions/" + region)
+	subnetwork.SelfLink = s.computeURL(key)
+	subnetwork.Fingerprint = generateEtag()
+	s.computeSubnetworks[key] = subnetwork
+
+	network.Subnetworks = append(network.Subnetworks, subnetwork.SelfLink)
+	slices.Sort(network.Subnetworks)
+
+	return clone(s.createComputeOperation("projects/"+project+"/regions/"+region, "insert", subnetwork.SelfLink, subnetwork.Id)), nil
+}
+
+// GetComputeSubnetwork retrieves a subnetwork by project, region and name.
+// Returns nil if the subnetwork doesn't exist.
+func (s *Store) GetComputeSubnetwork(project, region, name string) *compute.Subnetwork {
+	s.computeMu.RLock()
+	defer s.computeMu.RUnlock()
+
+	return clone(s.computeSubnetworks[computeSubnetworkKey(project, region, name)])
+}
+
+// ListComputeSubnetworks returns the subnetworks of a project in a region, sorted by name.
+func (s *Store) ListComputeSubnetworks(project, region string) []*compute.Subnetwork {
+	s.computeMu.RLock()
+	defer s.computeMu.RUnlock()
+
+	prefix := "projects/" + project + "/regions/" + region + "/subnetworks/"
+	subnetworks := make([]*compute.Subnetwork, 0)
+	for key, subnetwork := range s.computeSubnetworks {
+		if strings.HasPrefix(key, prefix) {
+			subnetworks = append(subnetworks, subnetwork)
+		}
+	}
+
+	sort.Slice(subnetworks, func(i, j int) bool {
+		return subnetworks[i].Name < subnetworks[j].Name
+	})
+
+	return clone(subnetworks)
+}
+
+// PatchComputeSubnetwork changes the description, private Google access and secondary ranges of a subnetwork.
+// The fingerprint of req must match the current one, like the real API requires.
+// The returned operation is already done.
+func (s *Store) PatchComputeSubnetwork(project, region, name string, req *compute.Subnetwork) (*compute.Operation, error) {
+	s.computeMu.Lock()
+	defer s.computeMu.Unlock()
+
+	key := computeSubnetworkKey(project, region, name)
+	existing, exists := s.computeSubnetworks[key]
+	if !exists {
+		return nil, fmt.Errorf("subnetwork %s not found", key)
+	}
+	if req.Fingerprint == "" {
+		return nil, fmt.Errorf("invalid fingerprint: the current fingerprint of the subnetwork is required")
+	}
+	if req.Fingerprint != existing.Fingerprint {
+		return nil, fmt.Errorf("precondition failed: fingerprint %s doesn't match the current fingerprint %s", req.Fingerprint, existing.Fingerprint)
+	}
+
+	subnetwork := clone(existing)
+	subnetwork.Description = req.Description
+	subnetwork.PrivateIpGoogleAccess = req.PrivateIpGoogleAccess
+	subnetwork.SecondaryIpRanges = clone(req.SecondaryIpRanges)
+	networkKey := strings.TrimPrefix(subnetwork.Network, s.computeURL(""))
+	if _, err := s.validateComputeSubnetwork(networkKey, key, subnetwork); err != nil {
+		return nil, err
+	}
+	subnetwork.Fingerprint = generateEtag()
+	s.computeSubnetworks[key] = subnetwork
+
+	return clone(s.createComputeOperation("projects/"+project+"/regions/"+region, "patch", subnetwork.SelfLink, subnetwork.Id)), nil
+}
+
+// DeleteComputeSubnetwork deletes a subnetwork. The returned operation is already done.
+func (s *Store) DeleteComputeSubnetwork(project, region, name string) (*compute.Operation, error) {
+	s.computeMu.Lock()
+	defer s.computeMu.Unlock()
+
+	key := computeSubnetworkKey(project, region, name)
+	subnetwork, exists := s.computeSubnetworks[key]
+	if !exists {
+		return nil, fmt.Errorf("subnetwork %s not found", key)
+	}
+	delete(s.computeSubnetworks, key)
+
+	if network := s.computeNetworks[strings.TrimPrefix(subnetwork.Network, s.computeURL(""))]; network != nil {
+		network.Subnetworks = slices.DeleteFunc(network.Subnetworks, func(link string) bool {
+			return link == subnetwork.SelfLink
+		})
+	}
+
+	return clone(s.createComputeOperation("projects/"+project+"/regions/"+region, "delete", subnetwork.SelfLink, subnetwork.Id)), nil
+}
+
+// validateComputeSubnetwork validates the IP ranges of a subnetwork with a key in a network and returns its
+// gateway address. The primary and secondary ranges must be valid IPv4 ranges of /8 to /29 that don't overlap
+// each other or the ranges of the other subnetworks of the network.
+// Must be called with the lock held.
+func (s *Store) validateComputeSubnetwork(networkKey, key string, subnetwork *compute.Subnetwork) (string, error) {
+	ranges := []string{subnetwork.IpCidrRange}
+	for _, secondary := range subnetwork.SecondaryIpRanges {
+		if secondary == nil || !computeNamePattern.MatchString(secondary.RangeName) {
+			return "", fmt.Errorf("invalid secondaryIpRanges: every range needs a rangeName of lowercase letters, digits and hyphens")
+		}
+		ranges = append(ranges, secondary.IpCidrRange)
+	}
+
+	var networks []*net.IPNet
+	for _, cidr := range ranges {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil || ipNet.IP.To4() == nil {
+			return "", fmt.Errorf("invalid ipCidrRange %q: must be an IPv4 range like 10.0.0.0/24", cidr)
+		}
+		if ones, _ := ipNet.Mask.Size(); ones < 8 || ones > 29 {
+			return "", fmt.Errorf("invalid ipCidrRange %q: the prefix length must be between 8 and 29", cidr)
+		}
+		networks = append(networks, ipNet)
+	}
+	for other, otherSubnetwork := range s.computeSubnetworks {
+		if other == key || strings.TrimPrefix(otherSubnetwork.Network, s.computeURL("")) != networkKey {
+			continue
+		}
+		if _, ipNet, err := net.ParseCIDR(otherSubnetwork.IpCidrRange); err == nil {
+			networks = append(networks, ipNet)
+		}
+		for _, secondary := range otherSubnetwork.SecondaryIpRanges {
+			if _, ipNet, err := net.ParseCIDR(secondary.IpCidrRange); err == nil {
+				networks = append(networks, ipNet)
+			}
+		}
+	}
+	for i := range len(ranges) {
+		for j := range networks {
+			if i != j && (networks[i].Contains(networks[j].IP) || networks[j].Contains(networks[i].IP)) {
+				return "", fmt.Errorf("invalid ipCidrRange %q: it overlaps %s", ranges[i], networks[j])
+			}
+		}
+	}
+
+	gateway := slices.Clone(networks[0].IP.To4())
+	gateway[3]++
+	return gateway.String(), nil
+}
+
+// computeSubnetworkKey returns the key of a subnetwork: projects/{project}/regions/{region}/subnetworks/{subnetwork}.
+func computeSubnetworkKey(project, region, name string) string {
+	return "projects/" + project + "/regions/" + region + "/subnetworks/" + name
+}
+
+// computeURL returns the URL of a Compute Engine resource with a relative name like projects/{project}/global/networks/{network}.
+func (s *Store) computeURL(name string) string {
+	return s.config().baseURL + "/compute/v1/" + name
+}
+
+// newComputeID returns a random numeric ID like the ones Compute Engine gives resources.
+func newComputeID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return strconv.FormatUint(binary.BigEndian.Uint64(b)>>1, 10)
+}
+
+// newOperationID returns the ID of a Compute Engine or GKE operation started at now,
+// like operation-1700000000000-1a2b3c4d.
+func newOperationID(now time.Time) string {
+	return fmt.Sprintf("operation-%d-%s", now.UnixMilli(), newUUID()[:8])
+}
+
+// =============================================================================
+// Compute Engine Operation Operations
+// =============================================================================
+
+// createComputeOperation creates and stores a completed operation below scope (projects/{project}/global or
+// projects/{project}/regions/{region}) for a change of the resource at targetLink.
+// Must be called with the lock held.
+func (s *Store) createComputeOperation(scope, operationType, targetLink, targetID string) *compute.Operation {
+	now := s.now()
+	name := newOperationID(now)
+	key := scope + "/operations/" + name
+
+	op := &compute.Operation{
+		Kind:          "compute#operation",
+		Id:            newComputeID(),
+		Name:          name,
+		OperationType: operationType,
+		TargetLink:    targetLink,
+		TargetId:      targetID,
+		Status:        compute.OperationDone,
+		Progress:      100,
+		InsertTime:    now,
+		StartTime:     now,
+		EndTime:       now,
+		SelfLink:      s.computeURL(key),
+	}
+	if strings.Contains(scope, "/regions/") {
+		op.Region = s.computeURL(scope)
+	}
+	s.computeOperations[key] = op
+
+	return op
+}
+
+// GetComputeOperation retrieves an operation by its key: projects/{project}/global/operations/{operation}
+// or projects/{project}/regions/{region}/operations/{operation}.
+// Returns nil if the operation doesn't exist.
+func (s *Store) GetComputeOperation(key string) *compute.Operation {
+	s.computeMu.RLock()
+	defer s.computeMu.RUnlock()
+
+	return clone(s.computeOperations[key])
+}
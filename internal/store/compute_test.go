@@ -0,0 +1,175 @@
+package store
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/katharinasick/gcp-api-mock/internal/compute"
+	"github.com/katharinasick/gcp-api-mock/internal/sqladmin"
+)
+
+func newTestComputeNetwork(t *testing.T, s *Store, name string) {
+	t.Helper()
+	autoCreate := false
+	if _, err := s.InsertComputeNetwork("test-project", &compute.Network{Name: name, AutoCreateSubnetworks: &autoCreate}); err != nil {
+		t.Fatalf("InsertComputeNetwork() error: %v", err)
+	}
+}
+
+func TestStore_InsertComputeNetwork(t *testing.T) {
+	s := New()
+
+	op, err := s.InsertComputeNetwork("test-project", &compute.Network{Name: "vpc"})
+	if err != nil {
+		t.Fatalf("InsertComputeNetwork() error: %v", err)
+	}
+	if op.Status != compute.OperationDone || op.OperationType != "insert" || !strings.HasSuffix(op.TargetLink, "/compute/v1/projects/test-project/global/networks/vpc") {
+		t.Errorf("unexpected operation: %+v", op)
+	}
+	if s.GetComputeOperation("projects/test-project/global/operations/"+op.Name) == nil {
+		t.Error("expected the operation to be stored")
+	}
+
+	network := s.GetComputeNetwork("test-project", "vpc")
+	if network == nil || network.Id != op.TargetId || !*network.AutoCreateSubnetworks || network.RoutingConfig.RoutingMode != "REGIONAL" || network.Mtu != 1460 {
+		t.Errorf("unexpected network: %+v", network)
+	}
+
+	invalid := []*compute.Network{
+		{Name: "vpc"},
+		{Name: "Invalid_Name"},
+		{Name: "jumbo", Mtu: 9000},
+		{Name: "routing", RoutingConfig: &compute.RoutingConfig{RoutingMode: "LOCAL"}},
+	}
+	for _, network := range invalid {
+		if _, err := s.InsertComputeNetwork("test-project", network); err == nil {
+			t.Errorf("expected an error for network %+v", network)
+		}
+	}
+}
+
+func TestStore_InsertComputeSubnetwork(t *testing.T) {
+	s := New()
+	newTestComputeNetwork(t, s, "vpc")
+	newTestComputeNetwork(t, s, "other")
+
+	op, err := s.InsertComputeSubnetwork("test-project", "europe-west1", &compute.Subnetwork{
+		Name:              "apps",
+		Network:           "https://www.googleapis.com/compute/v1/projects/test-project/global/networks/vpc",
+		IpCidrRange:       "10.0.0.0/24",
+		SecondaryIpRanges: []*compute.SecondaryIpRange{{RangeName: "pods", IpCidrRange: "10.4.0.0/14"}},
+	})
+	if err != nil {
+		t.Fatalf("InsertComputeSubnetwork() error: %v", err)
+	}
+	if s.GetComputeOperation("projects/test-project/regions/europe-west1/operations/"+op.Name) == nil || op.Region == "" {
+		t.Errorf("expected a regional operation, got %+v", op)
+	}
+
+	subnetwork := s.GetComputeSubnetwork("test-project", "europe-west1", "apps")
+	if subnetwork == nil || subnetwork.GatewayAddress != "10.0.0.1" || subnetwork.Fingerprint == "" ||
+		!strings.HasSuffix(subnetwork.Network, "/projects/test-project/global/networks/vpc") {
+		t.Errorf("unexpected subnetwork: %+v", subnetwork)
+	}
+	if network := s.GetComputeNetwork("test-project", "vpc"); len(network.Subnetworks) != 1 || network.Subnetworks[0] != subnetwork.SelfLink {
+		t.Errorf("expected the network to list the subnetwork, got %v", network.Subnetworks)
+	}
+
+	// Other networks may reuse the range
+	if _, err := s.InsertComputeSubnetwork("test-project", "europe-west1", &compute.Subnetwork{
+		Name: "other-apps", Network: "global/networks/other", IpCidrRange: "10.0.0.0/24",
+	}); err != nil {
+		t.Errorf("InsertComputeSubnetwork() error: %v", err)
+	}
+
+	invalid := []struct {
+		name       string
+		subnetwork *compute.Subnetwork
+	}{
+		{"duplicate", &compute.Subnetwork{Name: "apps", Network: "global/networks/vpc", IpCidrRange: "10.1.0.0/24"}},
+		{"missing network", &compute.Subnetwork{Name: "a", Network: "global/networks/missing", IpCidrRange: "10.1.0.0/24"}},
+		{"bad network", &compute.Subnetwork{Name: "b", Network: "vpc", IpCidrRange: "10.1.0.0/24"}},
+		{"bad range", &compute.Subnetwork{Name: "c", Network: "global/networks/vpc", IpCidrRange: "10.1.0.0"}},
+		{"too small", &compute.Subnetwork{Name: "d", Network: "global/networks/vpc", IpCidrRange: "10.1.0.0/30"}},
+		{"overlapping", &compute.Subnetwork{Name: "e", Network: "global/networks/vpc", IpCidrRange: "10.0.0.128/25"}},
+		{"overlapping secondary", &compute.Subnetwork{Name: "f", Network: "global/networks/vpc", IpCidrRange: "10.1.0.0/24",
+			SecondaryIpRanges: []*compute.SecondaryIpRange{{RangeName: "pods", IpCidrRange: "10.5.0.0/16"}}}},
+	}
+	for _, tt := range invalid {
+		if _, err := s.InsertComputeSubnetwork("test-project", "europe-west1", tt.subnetwork); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
+
+func TestStore_PatchComputeSubnetwork(t *testing.T) {
+	s := New()
+	newTestComputeNetwork(t, s, "vpc")
+	if _, err := s.InsertComputeSubnetwork("test-project", "europe-west1", &compute.Subnetwork{
+		Name: "apps", Network: "global/networks/vpc", IpCidrRange: "10.0.0.0/24",
+	}); err != nil {
+		t.Fatalf("InsertComputeSubnetwork() error: %v", err)
+	}
+	fingerprint := s.GetComputeSubnetwork("test-project", "europe-west1", "apps").Fingerprint
+
+	if _, err := s.PatchComputeSubnetwork("test-project", "europe-west1", "apps", &compute.Subnetwork{Description: "stale", Fingerprint: "stale"}); err == nil || !strings.Contains(err.Error(), "precondition failed") {
+		t.Errorf("expected a precondition error for a stale fingerprint, got %v", err)
+	}
+	if _, err := s.PatchComputeSubnetwork("test-project", "europe-west1", "apps", &compute.Subnetwork{Description: "Apps", Fingerprint: fingerprint}); err != nil {
+		t.Fatalf("PatchComputeSubnetwork() error: %v", err)
+	}
+	if subnetwork := s.GetComputeSubnetwork("test-project", "europe-west1", "apps"); subnetwork.Description != "Apps" || subnetwork.Fingerprint == fingerprint {
+		t.Errorf("expected the description and a new fingerprint, got %+v", subnetwork)
+	}
+}
+
+func TestStore_DeleteComputeNetwork(t *testing.T) {
+	s := New()
+	newTestComputeNetwork(t, s, "vpc")
+	if _, err := s.InsertComputeSubnetwork("test-project", "europe-west1", &compute.Subnetwork{
+		Name: "apps", Network: "global/networks/vpc", IpCidrRange: "10.0.0.0/24",
+	}); err != nil {
+		t.Fatalf("InsertComputeSubnetwork() error: %v", err)
+	}
+
+	if _, err := s.DeleteComputeNetwork("test-project", "vpc"); err == nil || !strings.Contains(err.Error(), "in use") {
+		t.Errorf("expected an in use error while the network has subnetworks, got %v", err)
+	}
+	if _, err := s.DeleteComputeSubnetwork("test-project", "europe-west1", "apps"); err != nil {
+		t.Fatalf("DeleteComputeSubnetwork() error: %v", err)
+	}
+	if _, err := s.DeleteComputeNetwork("test-project", "vpc"); err != nil {
+		t.Fatalf("DeleteComputeNetwork() error: %v", err)
+	}
+	if s.GetComputeNetwork("test-project", "vpc") != nil {
+		t.Error("expected the network to be deleted")
+	}
+}
+
+func TestStore_SQLPrivateNetwork(t *testing.T) {
+	s := New()
+	autoCreate := false
+	if _, err := s.InsertComputeNetwork("mock-project", &compute.Network{Name: "vpc", AutoCreateSubnetworks: &autoCreate}); err != nil {
+		t.Fatalf("InsertComputeNetwork() error: %v", err)
+	}
+
+	tests := []struct {
+		network string
+		valid   bool
+	}{
+		{"projects/mock-project/global/networks/vpc", true},
+		{"https://www.googleapis.com/compute/v1/projects/mock-project/global/networks/vpc", true},
+		{"projects/mock-project/global/networks/default", true},
+		{"projects/mock-project/global/networks/missing", false},
+		{"vpc", false},
+	}
+	for i, tt := range tests {
+		_, _, err := s.CreateSQLInstance(&sqladmin.InstanceInsertRequest{
+			Name:     "db-" + string(rune('a'+i)),
+			Settings: &sqladmin.Settings{IPConfiguration: &sqladmin.IPConfiguration{PrivateNetwork: tt.network}},
+		})
+		if (err == nil) != tt.valid {
+			t.Errorf("privateNetwork %q: expected valid %v, got error %v", tt.network, tt.valid, err)
+		}
+	}
+}
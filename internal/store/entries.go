@@ -0,0 +1,640 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/blob"
+	"github.com/katharinasick/gcp-api-mock/internal/logging"
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+)
+
+// =============================================================================
+// State Entry Operations
+// =============================================================================
+
+// Resource families. The resources of a family share a lock and change independently of other families.
+const (
+	FamilyStorage    = "storage"
+	FamilySQL        = "sql"
+	FamilyFirestore  = "firestore"
+	FamilyRegistry   = "registry"
+	FamilyRun        = "run"
+	FamilyMonitoring = "monitoring"
+	FamilyLogging    = "logging"
+	FamilyScheduler  = "scheduler"
+	FamilyEventarc   = "eventarc"
+	FamilyRedis      = "redis"
+	FamilyGKE        = "gke"
+	FamilyCompute    = "compute"
+	FamilyBilling    = "billing"
+	FamilyOrgPolicy  = "orgPolicy"
+)
+
+// Families are the names of all resource families.
+var Families = []string{
+	FamilyStorage, FamilySQL, FamilyFirestore, FamilyRegistry, FamilyRun, FamilyMonitoring, FamilyLogging,
+	FamilyScheduler, FamilyEventarc, FamilyRedis, FamilyGKE, FamilyCompute, FamilyBilling, FamilyOrgPolicy,
+}
+
+// TickFamilies are the families Tick changes, in the order of Families.
+var TickFamilies = []string{FamilyStorage, FamilySQL, FamilyScheduler}
+
+// Keys of content entries start with these prefixes. The content under a key never changes.
+const (
+	objectContentPrefix   = "content/"
+	registryContentPrefix = "blobs/"
+)
+
+// IsContentEntry reports whether an entry key is the key of object or registry blob content.
+func IsContentEntry(key string) bool {
+	return strings.HasPrefix(key, objectContentPrefix) || strings.HasPrefix(key, registryContentPrefix)
+}
+
+// entryFamily encodes the resources of a family as entries and applies changed entries.
+type entryFamily struct {
+	mu      func(s *Store) *sync.RWMutex
+	entries func(s *Store, w *entryWriter)
+	// apply applies the changed entries, with nil values for deleted entries
+	apply func(s *Store, changes map[string][]byte) error
+}
+
+// entryFamilies are the resource families by name.
+var entryFamilies = map[string]entryFamily{
+	FamilyStorage: {
+		mu:      func(s *Store) *sync.RWMutex { return &s.storageMu },
+		entries: storageEntries,
+		apply:   applyStorageEntries,
+	},
+	FamilySQL: {
+		mu: func(s *Store) *sync.RWMutex { return &s.sqlMu },
+		entries: func(s *Store, w *entryWriter) {
+			addEntries(w, "instances", s.sqlInstances)
+			addNestedEntries(w, "databases", s.sqlDatabases)
+			addNestedEntries(w, "users", s.sqlUsers)
+			addEntries(w, "operations", s.sqlOperations)
+			addEntries(w, "pendingCreates", s.sqlPendingCreates)
+			addEntries(w, "maintenance", s.sqlMaintenance)
+		},
+		apply: func(s *Store, changes map[string][]byte) error {
+			return applyEntries(changes, map[string]func(id string, data []byte) error{
+				"instances":      func(id string, data []byte) error { return applyEntry(s.sqlInstances, id, data) },
+				"databases":      func(id string, data []byte) error { return applyNestedEntry(s.sqlDatabases, id, data) },
+				"users":          func(id string, data []byte) error { return applyNestedEntry(s.sqlUsers, id, data) },
+				"operations":     func(id string, data []byte) error { return applyEntry(s.sqlOperations, id, data) },
+				"pendingCreates": func(id string, data []byte) error { return applyEntry(s.sqlPendingCreates, id, data) },
+				"maintenance":    func(id string, data []byte) error { return applyEntry(s.sqlMaintenance, id, data) },
+			})
+		},
+	},
+	FamilyFirestore: {
+		mu: func(s *Store) *sync.RWMutex { return &s.firestoreMu },
+		entries: func(s *Store, w *entryWriter) {
+			addEntries(w, "documents", s.documents)
+		},
+		apply: func(s *Store, changes map[string][]byte) error {
+			return applyEntries(changes, map[string]func(id string, data []byte) error{
+				"documents": func(id string, data []byte) error { return applyEntry(s.documents, id, data) },
+			})
+		},
+	},
+	FamilyRegistry: {
+		mu:      func(s *Store) *sync.RWMutex { return &s.registryMu },
+		entries: registryEntries,
+		apply:   applyRegistryEntries,
+	},
+	FamilyRun: {
+		mu: func(s *Store) *sync.RWMutex { return &s.runMu },
+		entries: func(s *Store, w *entryWriter) {
+			addEntries(w, "services", s.runServices)
+			addEntries(w, "revisions", s.runRevisions)
+			addEntries(w, "operations", s.runOperations)
+		},
+		apply: func(s *Store, changes map[string][]byte) error {
+			return applyEntries(changes, map[string]func(id string, data []byte) error{
+				"services":   func(id string, data []byte) error { return applyEntry(s.runServices, id, data) },
+				"revisions":  func(id string, data []byte) error { return applyEntry(s.runRevisions, id, data) },
+				"operations": func(id string, data []byte) error { return applyEntry(s.runOperations, id, data) },
+			})
+		},
+	},
+	FamilyMonitoring: {
+		mu: func(s *Store) *sync.RWMutex { return &s.monitoringMu },
+		entries: func(s *Store, w *entryWriter) {
+			addEntries(w, "metricDescriptors", s.metricDescriptors)
+			addNestedEntries(w, "timeSeries", s.timeSeries)
+		},
+		apply: func(s *Store, changes map[string][]byte) error {
+			return applyEntries(changes, map[string]func(id string, data []byte) error{
+				"metricDescriptors": func(id string, data []byte) error { return applyEntry(s.metricDescriptors, id, data) },
+				"timeSeries":        func(id string, data []byte) error { return applyNestedEntry(s.timeSeries, id, data) },
+			})
+		},
+	},
+	FamilyLogging: {
+		mu:      func(s *Store) *sync.RWMutex { return &s.loggingMu },
+		entries: loggingEntries,
+		apply:   applyLoggingEntries,
+	},
+	FamilyScheduler: {
+		mu: func(s *Store) *sync.RWMutex { return &s.schedulerMu },
+		entries: func(s *Store, w *entryWriter) {
+			addEntries(w, "jobs", s.schedulerJobs)
+		},
+		apply: func(s *Store, changes map[string][]byte) error {
+			return applyEntries(changes, map[string]func(id string, data []byte) error{
+				"jobs": func(id string, data []byte) error { return applyEntry(s.schedulerJobs, id, data) },
+			})
+		},
+	},
+	FamilyEventarc: {
+		mu: func(s *Store) *sync.RWMutex { return &s.eventarcMu },
+		entries: func(s *Store, w *entryWriter) {
+			addEntries(w, "triggers", s.eventarcTriggers)
+			addEntries(w, "operations", s.eventarcOperations)
+		},
+		apply: func(s *Store, changes map[string][]byte) error {
+			return applyEntries(changes, map[string]func(id string, data []byte) error{
+				"triggers":   func(id string, data []byte) error { return applyEntry(s.eventarcTriggers, id, data) },
+				"operations": func(id string, data []byte) error { return applyEntry(s.eventarcOperations, id, data) },
+			})
+		},
+	},
+	FamilyRedis: {
+		mu: func(s *Store) *sync.RWMutex { return &s.redisMu },
+		entries: func(s *Store, w *entryWriter) {
+			addEntries(w, "instances", s.redisInstances)
+			addEntries(w, "operations", s.redisOperations)
+			w.add("seq/ranges", s.redisRangeSeq)
+		},
+		apply: func(s *Store, changes map[string][]byte) error {
+			return applyEntries(changes, map[string]func(id string, data []byte) error{
+				"instances":  func(id string, data []byte) error { return applyEntry(s.redisInstances, id, data) },
+				"operations": func(id string, data []byte) error { return applyEntry(s.redisOperations, id, data) },
+				"seq":        func(id string, data []byte) error { return applySeq(&s.redisRangeSeq, data) },
+			})
+		},
+	},
+	FamilyGKE: {
+		mu: func(s *Store) *sync.RWMutex { return &s.gkeMu },
+		entries: func(s *Store, w *entryWriter) {
+			addEntries(w, "clusters", s.gkeClusters)
+			addEntries(w, "operations", s.gkeOperations)
+			w.add("seq/ranges", s.gkeRangeSeq)
+		},
+		apply: func(s *Store, changes map[string][]byte) error {
+			return applyEntries(changes, map[string]func(id string, data []byte) error{
+				"clusters":   func(id string, data []byte) error { return applyEntry(s.gkeClusters, id, data) },
+				"operations": func(id string, data []byte) error { return applyEntry(s.gkeOperations, id, data) },
+				"seq":        func(id string, data []byte) error { return applySeq(&s.gkeRangeSeq, data) },
+			})
+		},
+	},
+	FamilyCompute: {
+		mu: func(s *Store) *sync.RWMutex { return &s.computeMu },
+		entries: func(s *Store, w *entryWriter) {
+			addEntries(w, "networks", s.computeNetworks)
+			addEntries(w, "subnetworks", s.computeSubnetworks)
+			addEntries(w, "operations", s.computeOperations)
+		},
+		apply: func(s *Store, changes map[string][]byte) error {
+			return applyEntries(changes, map[string]func(id string, data []byte) error{
+				"networks":    func(id string, data []byte) error { return applyEntry(s.computeNetworks, id, data) },
+				"subnetworks": func(id string, data []byte) error { return applyEntry(s.computeSubnetworks, id, data) },
+				"operations":  func(id string, data []byte) error { return applyEntry(s.computeOperations, id, data) },
+			})
+		},
+	},
+	FamilyBilling: {
+		mu: func(s *Store) *sync.RWMutex { return &s.billingMu },
+		entries: func(s *Store, w *entryWriter) {
+			addEntries(w, "budgets", s.billingBudgets)
+		},
+		apply: func(s *Store, changes map[string][]byte) error {
+			return applyEntries(changes, map[string]func(id string, data []byte) error{
+				"budgets": func(id string, data []byte) error { return applyEntry(s.billingBudgets, id, data) },
+			})
+		},
+	},
+	FamilyOrgPolicy: {
+		mu: func(s *Store) *sync.RWMutex { return &s.orgPolicyMu },
+		entries: func(s *Store, w *entryWriter) {
+			addEntries(w, "policies", s.orgPolicies)
+		},
+		apply: func(s *Store, changes map[string][]byte) error {
+			return applyEntries(changes, map[string]func(id string, data []byte) error{
+				"policies": func(id string, data []byte) error { return applyEntry(s.orgPolicies, id, data) },
+			})
+		},
+	},
+}
+
+// Entries returns the resources of a family, each encoded as its own entry and keyed like "buckets/my-bucket",
+// so they can be stored and compared one by one. Object and registry blob content has entries of its own,
+// keyed like "content/my-bucket/file.txt#1"; since the content under a key never changes, it is only read
+// if readContent returns true for the key, and the entry is nil otherwise. Evicted content has no entry.
+// In-progress uploads are not included.
+func (s *Store) Entries(family string, readContent func(key string) bool) (map[string][]byte, error) {
+	f, exists := entryFamilies[family]
+	if !exists {
+		return nil, fmt.Errorf("unknown resource family %q", family)
+	}
+
+	mu := f.mu(s)
+	mu.RLock()
+	defer mu.RUnlock()
+
+	w := &entryWriter{entries: make(map[string][]byte), readContent: readContent}
+	f.entries(s, w)
+	if w.err != nil {
+		return nil, w.err
+	}
+	return w.entries, nil
+}
+
+// ApplyEntries changes the resources of a family to the given entries, as returned by Entries, and deletes
+// those whose entries are nil. Resources without a changed entry are kept. The changes are applied without
+// publishing events, like restoring a snapshot; if an entry is invalid, those applied before it are kept.
+func (s *Store) ApplyEntries(family string, changes map[string][]byte) error {
+	f, exists := entryFamilies[family]
+	if !exists {
+		return fmt.Errorf("unknown resource family %q", family)
+	}
+
+	mu := f.mu(s)
+	mu.Lock()
+	defer mu.Unlock()
+
+	return f.apply(s, changes)
+}
+
+// entryWriter collects the entries of a family.
+type entryWriter struct {
+	entries     map[string][]byte
+	readContent func(key string) bool
+	err         error
+}
+
+// add adds the entry of a resource.
+func (w *entryWriter) add(key string, resource any) {
+	data, err := json.Marshal(resource)
+	if err != nil && w.err == nil {
+		w.err = fmt.Errorf("failed to encode %s: %w", key, err)
+	}
+	w.entries[key] = data
+}
+
+// addContent adds the entry of content and returns its key, or an empty key if the content was evicted.
+func (w *entryWriter) addContent(key string, content blob.Blob) string {
+	if blob.IsEvicted(content) {
+		return ""
+	}
+	w.entries[key] = nil
+	if !w.readContent(key) {
+		return key
+	}
+
+	reader, err := content.Open()
+	if err != nil {
+		if w.err == nil {
+			w.err = fmt.Errorf("failed to read %s: %w", key, err)
+		}
+		return key
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil && w.err == nil {
+		w.err = fmt.Errorf("failed to read %s: %w", key, err)
+	}
+	w.entries[key] = data
+	return key
+}
+
+// addEntries adds the entries of the resources in a map, keyed by kind and map key.
+func addEntries[T any](w *entryWriter, kind string, resources map[string]T) {
+	for id, resource := range resources {
+		w.add(kind+"/"+id, resource)
+	}
+}
+
+// addNestedEntries adds the entries of the resources in a map of maps, like the databases of each
+// Cloud SQL instance, keyed by kind and both map keys.
+func addNestedEntries[T any](w *entryWriter, kind string, resources map[string]map[string]T) {
+	for parent, children := range resources {
+		for id, resource := range children {
+			w.add(kind+"/"+parent+"/"+id, resource)
+		}
+	}
+}
+
+// applyEntries applies changed entries with the function for their kind.
+func applyEntries(changes map[string][]byte, kinds map[string]func(id string, data []byte) error) error {
+	for key, data := range changes {
+		kind, id, _ := strings.Cut(key, "/")
+		apply, exists := kinds[kind]
+		if !exists {
+			return fmt.Errorf("invalid entry %s: unknown kind", key)
+		}
+		if err := apply(id, data); err != nil {
+			return fmt.Errorf("invalid entry %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// applyEntry sets or, for a nil entry, deletes a resource in a map.
+func applyEntry[T any](resources map[string]T, id string, data []byte) error {
+	if data == nil {
+		delete(resources, id)
+		return nil
+	}
+	var resource T
+	if err := json.Unmarshal(data, &resource); err != nil {
+		return err
+	}
+	resources[id] = resource
+	return nil
+}
+
+// applyNestedEntry sets or deletes a resource in a map of maps, see addNestedEntries.
+func applyNestedEntry[T any](resources map[string]map[string]T, id string, data []byte) error {
+	parent, child, _ := strings.Cut(id, "/")
+	if resources[parent] == nil {
+		if data == nil {
+			return nil
+		}
+		resources[parent] = make(map[string]T)
+	}
+	return applyEntry(resources[parent], child, data)
+}
+
+// applySeq sets a sequence number, resetting it for a nil entry.
+func applySeq(seq *int, data []byte) error {
+	if data == nil {
+		*seq = 0
+		return nil
+	}
+	return json.Unmarshal(data, seq)
+}
+
+// objectContentKey returns the key of the content entry of an object generation.
+func objectContentKey(bucketName string, obj *storage.Object) string {
+	return fmt.Sprintf("%s%s/%s#%d", objectContentPrefix, bucketName, obj.Name, obj.Generation)
+}
+
+// storageEntries adds the entries of the Cloud Storage resources.
+func storageEntries(s *Store, w *entryWriter) {
+	addEntries(w, "buckets", s.buckets)
+	for bucketName, bucketObjects := range s.objects {
+		for objectName, objData := range bucketObjects {
+			w.add("objects/"+bucketName+"/"+objectName, &snapshotObject{
+				Metadata: objData.Metadata,
+				Content:  w.addContent(objectContentKey(bucketName, objData.Metadata), objData.Content),
+			})
+		}
+	}
+	for bucketName, softDeleted := range s.softDeletedObjects {
+		for _, objData := range softDeleted {
+			w.add(fmt.Sprintf("softDeleted/%s/%s#%d", bucketName, objData.Metadata.Name, objData.Metadata.Generation), &snapshotObject{
+				Metadata: objData.Metadata,
+				Content:  w.addContent(objectContentKey(bucketName, objData.Metadata), objData.Content),
+			})
+		}
+	}
+	addNestedEntries(w, "notifications", s.notifications)
+	w.add("seq/notifications", s.notificationSeq)
+	addEntries(w, "quotas", s.bucketQuotas)
+	addEntries(w, "policies", s.bucketPolicies)
+	addEntries(w, "hmacKeys", s.hmacKeys)
+}
+
+// applyStorageEntries applies changed entries of the Cloud Storage resources. Objects whose content
+// entry didn't change keep their content, and content no object refers to anymore is released.
+func applyStorageEntries(s *Store, changes map[string][]byte) error {
+	contents := make(map[string]blob.Blob)
+	for bucketName, bucketObjects := range s.objects {
+		for _, objData := range bucketObjects {
+			contents[objectContentKey(bucketName, objData.Metadata)] = objData.Content
+		}
+	}
+	for bucketName, softDeleted := range s.softDeletedObjects {
+		for _, objData := range softDeleted {
+			contents[objectContentKey(bucketName, objData.Metadata)] = objData.Content
+		}
+	}
+
+	// Write the new content first, so the objects can refer to it
+	backend := s.config().blobs
+	resources := make(map[string][]byte)
+	for key, data := range changes {
+		if !strings.HasPrefix(key, objectContentPrefix) {
+			resources[key] = data
+			continue
+		}
+		if _, exists := contents[key]; exists || data == nil {
+			continue
+		}
+		content, err := backend.Write(bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %w", key, err)
+		}
+		contents[key] = content
+	}
+
+	objectData := func(data []byte) (string, *ObjectData, error) {
+		var obj snapshotObject
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return "", nil, err
+		}
+		if obj.Content == "" {
+			return "", &ObjectData{Metadata: obj.Metadata, Content: blob.Evicted(int64(obj.Metadata.Size))}, nil
+		}
+		content, exists := contents[obj.Content]
+		if !exists {
+			return "", nil, fmt.Errorf("content %s is missing", obj.Content)
+		}
+		return obj.Content, &ObjectData{Metadata: obj.Metadata, Content: content}, nil
+	}
+
+	err := applyEntries(resources, map[string]func(id string, data []byte) error{
+		"buckets": func(id string, data []byte) error { return applyEntry(s.buckets, id, data) },
+		"objects": func(id string, data []byte) error {
+			bucketName, objectName, _ := strings.Cut(id, "/")
+			if data == nil {
+				delete(s.objects[bucketName], objectName)
+				return nil
+			}
+			_, objData, err := objectData(data)
+			if err != nil {
+				return err
+			}
+			if s.objects[bucketName] == nil {
+				s.objects[bucketName] = make(map[string]*ObjectData)
+			}
+			s.objects[bucketName][objectName] = objData
+			return nil
+		},
+		"softDeleted": func(id string, data []byte) error {
+			bucketName, generation, _ := strings.Cut(id, "/")
+			softDeleted := s.softDeletedObjects[bucketName]
+			for i, objData := range softDeleted {
+				if fmt.Sprintf("%s#%d", objData.Metadata.Name, objData.Metadata.Generation) == generation {
+					softDeleted = append(softDeleted[:i:i], softDeleted[i+1:]...)
+					break
+				}
+			}
+			if data != nil {
+				_, objData, err := objectData(data)
+				if err != nil {
+					return err
+				}
+				softDeleted = append(softDeleted, objData)
+			}
+			s.softDeletedObjects[bucketName] = softDeleted
+			return nil
+		},
+		"notifications": func(id string, data []byte) error { return applyNestedEntry(s.notifications, id, data) },
+		"seq":           func(id string, data []byte) error { return applySeq(&s.notificationSeq, data) },
+		"quotas":        func(id string, data []byte) error { return applyEntry(s.bucketQuotas, id, data) },
+		"policies":      func(id string, data []byte) error { return applyEntry(s.bucketPolicies, id, data) },
+		"hmacKeys":      func(id string, data []byte) error { return applyEntry(s.hmacKeys, id, data) },
+	})
+
+	// Every bucket has a map of objects, even if it's empty, and only buckets do
+	for bucketName := range s.buckets {
+		if s.objects[bucketName] == nil {
+			s.objects[bucketName] = make(map[string]*ObjectData)
+		}
+	}
+	for bucketName := range s.objects {
+		if _, exists := s.buckets[bucketName]; !exists {
+			delete(s.objects, bucketName)
+		}
+	}
+	s.rebuildMetadataIndex()
+
+	used := make(map[string]bool)
+	for bucketName, bucketObjects := range s.objects {
+		for _, objData := range bucketObjects {
+			used[objectContentKey(bucketName, objData.Metadata)] = true
+		}
+	}
+	for bucketName, softDeleted := range s.softDeletedObjects {
+		for _, objData := range softDeleted {
+			used[objectContentKey(bucketName, objData.Metadata)] = true
+		}
+	}
+	for key, content := range contents {
+		if !used[key] {
+			content.Release()
+		}
+	}
+	return err
+}
+
+// registryEntries adds the entries of the Artifact Registry resources.
+func registryEntries(s *Store, w *entryWriter) {
+	for name, repo := range s.repositories {
+		w.add("repositories/"+name, newSnapshotRepository(repo))
+	}
+	for digest, content := range s.registryBlobs {
+		w.addContent(registryContentPrefix+digest, content)
+	}
+}
+
+// applyRegistryEntries applies changed entries of the Artifact Registry resources.
+func applyRegistryEntries(s *Store, changes map[string][]byte) error {
+	backend := s.config().blobs
+	return applyEntries(changes, map[string]func(id string, data []byte) error{
+		"repositories": func(id string, data []byte) error {
+			if data == nil {
+				delete(s.repositories, id)
+				return nil
+			}
+			var repo snapshotRepository
+			if err := json.Unmarshal(data, &repo); err != nil {
+				return err
+			}
+			s.repositories[id] = repo.restore(id)
+			return nil
+		},
+		"blobs": func(digest string, data []byte) error {
+			if content, exists := s.registryBlobs[digest]; exists {
+				if data == nil {
+					content.Release()
+					delete(s.registryBlobs, digest)
+				}
+				return nil
+			}
+			if data == nil {
+				return nil
+			}
+			content, err := backend.Write(bytes.NewReader(data))
+			if err != nil {
+				return err
+			}
+			s.registryBlobs[digest] = content
+			return nil
+		},
+	})
+}
+
+// logEntryKey returns the key of the entry of a log entry. Entries are ordered by their keys, which start
+// with the receive time, so several entries received at once are numbered in the order they were written.
+func logEntryKey(entry *logging.LogEntry, n int) string {
+	return fmt.Sprintf("entries/%s/%06d", entry.ReceiveTimestamp.UTC().Format("20060102T150405.000000000"), n)
+}
+
+// logEntryKeys returns the keys of the log entries in the order they were received.
+func logEntryKeys(entries []*logging.LogEntry) []string {
+	keys := make([]string, len(entries))
+	var last time.Time
+	n := 0
+	for i, entry := range entries {
+		if !entry.ReceiveTimestamp.Equal(last) {
+			last, n = entry.ReceiveTimestamp, 0
+		}
+		keys[i] = logEntryKey(entry, n)
+		n++
+	}
+	return keys
+}
+
+// loggingEntries adds the entries of the Cloud Logging resources.
+func loggingEntries(s *Store, w *entryWriter) {
+	for i, key := range logEntryKeys(s.logEntries) {
+		w.add(key, s.logEntries[i])
+	}
+	w.add("seq/entries", s.logEntrySeq)
+}
+
+// applyLoggingEntries applies changed entries of the Cloud Logging resources, keeping the log entries in
+// the order they were received.
+func applyLoggingEntries(s *Store, changes map[string][]byte) error {
+	entries := make(map[string]*logging.LogEntry)
+	for i, key := range logEntryKeys(s.logEntries) {
+		entries[strings.TrimPrefix(key, "entries/")] = s.logEntries[i]
+	}
+
+	err := applyEntries(changes, map[string]func(id string, data []byte) error{
+		"entries": func(id string, data []byte) error { return applyEntry(entries, id, data) },
+		"seq":     func(id string, data []byte) error { return applySeq(&s.logEntrySeq, data) },
+	})
+
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	s.logEntries = make([]*logging.LogEntry, len(keys))
+	for i, key := range keys {
+		s.logEntries[i] = entries[key]
+	}
+	return err
+}
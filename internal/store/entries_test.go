@@ -0,0 +1,112 @@
+package store
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/katharinasick/gcp-api-mock/internal/sqladmin"
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+)
+
+// copyEntries applies the entries of all families of one store that differ from known to another store
+// and returns the entries, like a replica saving its changes and another loading them.
+func copyEntries(t *testing.T, from, to *Store, known map[string]map[string][]byte) map[string]map[string][]byte {
+	t.Helper()
+	all := make(map[string]map[string][]byte)
+	for _, family := range Families {
+		entries, err := from.Entries(family, func(key string) bool {
+			_, exists := known[family][key]
+			return !exists
+		})
+		if err != nil {
+			t.Fatalf("Entries(%s) error: %v", family, err)
+		}
+
+		changes := make(map[string][]byte)
+		for key, data := range entries {
+			if previous, exists := known[family][key]; !exists || (!IsContentEntry(key) && string(previous) != string(data)) {
+				changes[key] = data
+			}
+		}
+		for key := range known[family] {
+			if _, exists := entries[key]; !exists {
+				changes[key] = nil
+			}
+		}
+		if err := to.ApplyEntries(family, changes); err != nil {
+			t.Fatalf("ApplyEntries(%s) error: %v", family, err)
+		}
+		all[family] = entries
+	}
+	return all
+}
+
+func TestStore_Entries(t *testing.T) {
+	s := New()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+	obj, _ := s.CreateObject("test-bucket", "file.txt", "text/plain", []byte("hello"), nil)
+	contentKey := objectContentKey("test-bucket", obj)
+
+	entries, err := s.Entries(FamilyStorage, func(string) bool { return true })
+	if err != nil {
+		t.Fatalf("Entries() error: %v", err)
+	}
+	for _, key := range []string{"buckets/test-bucket", "objects/test-bucket/file.txt", contentKey} {
+		if entries[key] == nil {
+			t.Errorf("expected an entry %s", key)
+		}
+	}
+	if string(entries[contentKey]) != "hello" {
+		t.Errorf("expected the content entry to hold the content, got %q", entries[contentKey])
+	}
+
+	// Content is only read when asked for
+	entries, _ = s.Entries(FamilyStorage, func(string) bool { return false })
+	if data, exists := entries[contentKey]; !exists || data != nil {
+		t.Errorf("expected a content entry without content, got %q", data)
+	}
+
+	if _, err := s.Entries("unknown", nil); err == nil || !strings.Contains(err.Error(), "unknown resource family") {
+		t.Errorf("expected an unknown family error, got %v", err)
+	}
+}
+
+func TestStore_ApplyEntries(t *testing.T) {
+	s, replica := New(), New()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+	_, _ = s.CreateObject("test-bucket", "file.txt", "text/plain", []byte("hello"), nil)
+	_, _ = s.CreateObject("test-bucket", "other.txt", "text/plain", []byte("other"), nil)
+	_, _, _ = s.CreateSQLInstance(&sqladmin.InstanceInsertRequest{Name: "test-instance"})
+
+	known := copyEntries(t, s, replica, nil)
+	if content := replica.GetObjectContent("test-bucket", "file.txt"); string(content) != "hello" {
+		t.Errorf("expected object content 'hello', got '%s'", content)
+	}
+	if replica.GetSQLInstance("test-instance") == nil || replica.GetSQLDatabase("test-instance", "mysql") == nil {
+		t.Error("expected SQL instance and its databases to be applied")
+	}
+
+	// Only the changed entries are applied; deleted resources are deleted
+	_, _ = s.CreateObject("test-bucket", "file.txt", "text/plain", []byte("changed"), nil)
+	_ = s.DeleteObject("test-bucket", "other.txt")
+	_, _ = s.DeleteSQLInstance("test-instance")
+	copyEntries(t, s, replica, known)
+
+	if content := replica.GetObjectContent("test-bucket", "file.txt"); string(content) != "changed" {
+		t.Errorf("expected object content 'changed', got '%s'", content)
+	}
+	if replica.GetObject("test-bucket", "other.txt") != nil {
+		t.Error("expected the deleted object to be deleted")
+	}
+	if replica.GetSQLInstance("test-instance") != nil {
+		t.Error("expected the deleted instance to be deleted")
+	}
+
+	// The replica keeps working
+	if _, err := replica.CreateObject("test-bucket", "new.txt", "text/plain", []byte("new"), nil); err != nil {
+		t.Errorf("CreateObject() after applying entries error: %v", err)
+	}
+	if objects, _ := replica.ListObjects("test-bucket", "", ""); len(objects) != 2 {
+		t.Errorf("expected 2 objects, got %d", len(objects))
+	}
+}
@@ -0,0 +1,405 @@
+package store
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/katharinasick/gcp-api-mock/internal/eventarc"
+	"github.com/katharinasick/gcp-api-mock/internal/logging"
+	"github.com/katharinasick/gcp-api-mock/internal/longrunning"
+)
+
+// =============================================================================
+// Eventarc Trigger Operations
+// =============================================================================
+
+// TriggerHandler is called for every Eventarc trigger matching a change in the store, with the event to deliver.
+// It is called while the lock of the changed resource family is held, so it must not block or call back into the store.
+type TriggerHandler func(trigger *eventarc.Trigger, event *eventarc.CloudEvent)
+
+// eventarcTriggerIDPattern matches valid trigger IDs: lowercase letters, digits and hyphens, starting with a letter.
+var eventarcTriggerIDPattern = regexp.MustCompile(`^[a-z][a-z0-9-]{0,63}$`)
+
+// eventarcOperationMetadataTypeURL is the type URL of the metadata of Eventarc operations.
+const eventarcOperationMetadataTypeURL = "type.googleapis.com/google.cloud.eventarc.v1.OperationMetadata"
+
+// eventarcTriggerFields are the fields of a trigger that can be changed with triggers.patch.
+var eventarcTriggerFields = []string{
+	"eventFilters", "serviceAccount", "destination", "transport", "labels", "eventDataContentType",
+}
+
+// splitEventarcTriggerName splits a trigger name like projects/{project}/locations/{location}/triggers/{trigger}
+// into its parent (projects/{project}/locations/{location}) and trigger ID.
+func splitEventarcTriggerName(name string) (string, string) {
+	parent, triggerID, found := strings.Cut(name, "/triggers/")
+	if !found {
+		return "", ""
+	}
+	return parent, triggerID
+}
+
+// SetTriggerHandler sets the handler that delivers the events of Eventarc triggers.
+// Without a handler, triggers are stored but never fire.
+func (s *Store) SetTriggerHandler(handler TriggerHandler) {
+	s.configure(func(cfg *storeConfig) {
+		cfg.triggerHandler = handler
+	})
+}
+
+// CreateEventarcTrigger creates a trigger below parent (projects/{project}/locations/{location}).
+// The returned operation is already done.
+func (s *Store) CreateEventarcTrigger(parent, triggerID string, req *eventarc.Trigger) (*eventarc.Operation, error) {
+	s.eventarcMu.Lock()
+	defer s.eventarcMu.Unlock()
+
+	if !eventarcTriggerIDPattern.MatchString(triggerID) {
+		return nil, fmt.Errorf("invalid trigger ID %q: must start with a lowercase letter and contain only lowercase letters, digits and hyphens", triggerID)
+	}
+	name := parent + "/triggers/" + triggerID
+	if _, exists := s.eventarcTriggers[name]; exists {
+		return nil, fmt.Errorf("trigger %s already exists", name)
+	}
+
+	trigger := clone(req)
+	trigger.Name = name
+	if err := prepareEventarcTrigger(trigger); err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	trigger.Uid = newUUID()
+	trigger.CreateTime = now
+	trigger.UpdateTime = now
+	trigger.Etag = generateEtag()
+	s.eventarcTriggers[name] = trigger
+
+	op, err := s.createEventarcOperation(trigger, "create")
+	return clone(op), err
+}
+
+// GetEventarcTrigger retrieves a trigger by name.
+// Returns nil if the trigger doesn't exist.
+func (s *Store) GetEventarcTrigger(name string) *eventarc.Trigger {
+	s.eventarcMu.RLock()
+	defer s.eventarcMu.RUnlock()
+
+	return clone(s.eventarcTriggers[name])
+}
+
+// ListEventarcTriggers returns all triggers below parent, sorted by name.
+func (s *Store) ListEventarcTriggers(parent string) []*eventarc.Trigger {
+	s.eventarcMu.RLock()
+	defer s.eventarcMu.RUnlock()
+
+	triggers := make([]*eventarc.Trigger, 0)
+	for name, trigger := range s.eventarcTriggers {
+		if triggerParent, _ := splitEventarcTriggerName(name); triggerParent == parent {
+			triggers = append(triggers, trigger)
+		}
+	}
+
+	sort.Slice(triggers, func(i, j int) bool {
+		return triggers[i].Name < triggers[j].Name
+	})
+
+	return clone(triggers)
+}
+
+// UpdateEventarcTrigger changes the fields of a trigger named by updateMask to their values in req.
+// Without an update mask, the fields set in req are changed. The returned operation is already done.
+func (s *Store) UpdateEventarcTrigger(name string, req *eventarc.Trigger, updateMask []string) (*eventarc.Operation, error) {
+	s.eventarcMu.Lock()
+	defer s.eventarcMu.Unlock()
+
+	existing, exists := s.eventarcTriggers[name]
+	if !exists {
+		return nil, fmt.Errorf("trigger %s not found", name)
+	}
+
+	if len(updateMask) == 0 {
+		updateMask = setEventarcTriggerFields(req)
+	}
+	trigger := clone(existing)
+	for _, field := range updateMask {
+		switch field {
+		case "eventFilters", "event_filters":
+			trigger.EventFilters = clone(req.EventFilters)
+		case "serviceAccount", "service_account":
+			trigger.ServiceAccount = req.ServiceAccount
+		case "destination":
+			trigger.Destination = clone(req.Destination)
+		case "transport":
+			trigger.Transport = clone(req.Transport)
+		case "labels":
+			trigger.Labels = clone(req.Labels)
+		case "eventDataContentType", "event_data_content_type":
+			trigger.EventDataContentType = req.EventDataContentType
+		default:
+			return nil, fmt.Errorf("invalid update mask field %q: must be one of %s", field, strings.Join(eventarcTriggerFields, ", "))
+		}
+	}
+	if err := prepareEventarcTrigger(trigger); err != nil {
+		return nil, err
+	}
+
+	trigger.UpdateTime = s.now()
+	trigger.Etag = generateEtag()
+	s.eventarcTriggers[name] = trigger
+
+	op, err := s.createEventarcOperation(trigger, "update")
+	return clone(op), err
+}
+
+// DeleteEventarcTrigger deletes a trigger. The returned operation is already done.
+func (s *Store) DeleteEventarcTrigger(name string) (*eventarc.Operation, error) {
+	s.eventarcMu.Lock()
+	defer s.eventarcMu.Unlock()
+
+	trigger, exists := s.eventarcTriggers[name]
+	if !exists {
+		return nil, fmt.Errorf("trigger %s not found", name)
+	}
+	delete(s.eventarcTriggers, name)
+
+	op, err := s.createEventarcOperation(trigger, "delete")
+	return clone(op), err
+}
+
+// prepareEventarcTrigger validates the event filters and destination of a trigger and fills in the defaults.
+// Triggers need exactly one filter on the event type, which must be one the mock emits, and the filters
+// its events require, like the bucket of Cloud Storage events.
+func prepareEventarcTrigger(trigger *eventarc.Trigger) error {
+	var eventType string
+	attributes := make(map[string]bool, len(trigger.EventFilters))
+	for _, filter := range trigger.EventFilters {
+		if filter.Attribute == "type" {
+			if eventType != "" {
+				return fmt.Errorf("invalid trigger %s: exactly one event filter on type is required", trigger.Name)
+			}
+			if filter.Operator != "" {
+				return fmt.Errorf("invalid trigger %s: the event filter on type must not have an operator", trigger.Name)
+			}
+			eventType = filter.Value
+			continue
+		}
+		if filter.Operator != "" && filter.Operator != eventarc.OperatorMatchPathPattern {
+			return fmt.Errorf("invalid operator %q in event filter on %s: must be empty or %s", filter.Operator, filter.Attribute, eventarc.OperatorMatchPathPattern)
+		}
+		attributes[filter.Attribute] = true
+	}
+	if eventType == "" {
+		return fmt.Errorf("invalid trigger %s: exactly one event filter on type is required", trigger.Name)
+	}
+
+	allowed, supported := eventarc.EventAttributes[eventType]
+	if !supported {
+		return fmt.Errorf("invalid event type %q: must be one of %s", eventType, strings.Join(slices.Sorted(maps.Keys(eventarc.EventAttributes)), ", "))
+	}
+	for attribute := range attributes {
+		if !slices.Contains(allowed, attribute) {
+			return fmt.Errorf("invalid event filter on %s: events of type %s have the attributes %s", attribute, eventType, strings.Join(allowed, ", "))
+		}
+	}
+	for _, attribute := range eventarc.RequiredAttributes[eventType] {
+		if !attributes[attribute] {
+			return fmt.Errorf("invalid trigger %s: events of type %s require an event filter on %s", trigger.Name, eventType, attribute)
+		}
+	}
+
+	destination := trigger.Destination
+	if destination == nil || countEventarcDestinations(destination) != 1 {
+		return fmt.Errorf("invalid trigger %s: exactly one of destination.cloudRun, destination.httpEndpoint and destination.workflow is required", trigger.Name)
+	}
+	if destination.HttpEndpoint != nil {
+		uri := destination.HttpEndpoint.Uri
+		if !strings.HasPrefix(uri, "http://") && !strings.HasPrefix(uri, "https://") {
+			return fmt.Errorf("invalid HTTP endpoint URI %q: must start with http:// or https://", uri)
+		}
+	}
+	if destination.CloudRun != nil && destination.CloudRun.Service == "" {
+		return fmt.Errorf("invalid trigger %s: destination.cloudRun.service is required", trigger.Name)
+	}
+
+	if trigger.EventDataContentType == "" {
+		trigger.EventDataContentType = "application/json"
+	}
+	return nil
+}
+
+// countEventarcDestinations returns the number of destinations set in a trigger destination.
+func countEventarcDestinations(destination *eventarc.Destination) int {
+	destinations := 0
+	for _, set := range []bool{destination.CloudRun != nil, destination.HttpEndpoint != nil, destination.Workflow != ""} {
+		if set {
+			destinations++
+		}
+	}
+	return destinations
+}
+
+// setEventarcTriggerFields returns the changeable fields that are set in req.
+func setEventarcTriggerFields(req *eventarc.Trigger) []string {
+	var fields []string
+	for _, field := range eventarcTriggerFields {
+		var set bool
+		switch field {
+		case "eventFilters":
+			set = req.EventFilters != nil
+		case "serviceAccount":
+			set = req.ServiceAccount != ""
+		case "destination":
+			set = req.Destination != nil
+		case "transport":
+			set = req.Transport != nil
+		case "labels":
+			set = req.Labels != nil
+		case "eventDataContentType":
+			set = req.EventDataContentType != ""
+		}
+		if set {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// =============================================================================
+// Eventarc Operation Operations
+// =============================================================================
+
+// createEventarcOperation creates and stores a completed operation for a change of a trigger,
+// whose response is the trigger. verb is create, update or delete.
+// Must be called with the lock held.
+func (s *Store) createEventarcOperation(trigger *eventarc.Trigger, verb string) (*eventarc.Operation, error) {
+	now := s.now()
+	metadata, err := longrunning.NewAny(eventarcOperationMetadataTypeURL, map[string]any{
+		"createTime": now,
+		"endTime":    now,
+		"target":     trigger.Name,
+		"verb":       verb,
+		"apiVersion": "v1",
+	})
+	if err != nil {
+		return nil, err
+	}
+	response, err := longrunning.NewAny(eventarc.TriggerTypeURL, trigger)
+	if err != nil {
+		return nil, err
+	}
+
+	parent, _ := splitEventarcTriggerName(trigger.Name)
+	op := &eventarc.Operation{
+		Name:     parent + "/operations/" + newUUID(),
+		Metadata: metadata,
+		Done:     true,
+		Response: response,
+	}
+	s.eventarcOperations[op.Name] = op
+
+	return op, nil
+}
+
+// GetEventarcOperation retrieves an operation by name.
+// Returns nil if the operation doesn't exist.
+func (s *Store) GetEventarcOperation(name string) *eventarc.Operation {
+	s.eventarcMu.RLock()
+	defer s.eventarcMu.RUnlock()
+
+	return clone(s.eventarcOperations[name])
+}
+
+// =============================================================================
+// Eventarc Event Delivery
+// =============================================================================
+
+// fireTriggers passes the CloudEvent of a change to the trigger handler for every trigger it matches.
+// Triggers of every project and location match, like all resources of the mock belong to every project.
+// Must be called with the lock of the changed resource family held, like publish.
+func (s *Store) fireTriggers(e Event) {
+	handler := s.config().triggerHandler
+	if handler == nil {
+		return
+	}
+
+	s.eventarcMu.RLock()
+	defer s.eventarcMu.RUnlock()
+
+	if len(s.eventarcTriggers) == 0 {
+		return
+	}
+	event, err := s.cloudEvent(e)
+	if err != nil || event == nil {
+		return
+	}
+	for _, name := range slices.Sorted(maps.Keys(s.eventarcTriggers)) {
+		if trigger := s.eventarcTriggers[name]; trigger.Matches(event) {
+			handler(clone(trigger), event)
+		}
+	}
+}
+
+// cloudEvent returns the CloudEvent Eventarc delivers for a change: a Cloud Storage event for object changes,
+// and a Cloud Audit Logs event for the changes of buckets and Cloud SQL instances and databases.
+func (s *Store) cloudEvent(e Event) (*eventarc.CloudEvent, error) {
+	now := s.now()
+	projectID := s.config().projectID
+
+	switch e.Type {
+	case EventObjectFinalized:
+		return eventarc.NewStorageEvent(eventarc.EventTypeObjectFinalized, e.Object.Bucket, e.Object.Name, e.Object, now)
+	case EventObjectMetadataUpdated:
+		return eventarc.NewStorageEvent(eventarc.EventTypeObjectMetadataUpdated, e.Object.Bucket, e.Object.Name, e.Object, now)
+	case EventObjectDeleted:
+		return eventarc.NewStorageEvent(eventarc.EventTypeObjectDeleted, e.Object.Bucket, e.Object.Name, e.Object, now)
+	case EventBucketCreated, EventBucketDeleted:
+		method := "storage.buckets.create"
+		if e.Type == EventBucketDeleted {
+			method = "storage.buckets.delete"
+		}
+		resource := &logging.MonitoredResource{Type: "gcs_bucket", Labels: map[string]string{
+			"bucket_name": e.Bucket.Name,
+			"location":    strings.ToLower(e.Bucket.Location),
+			"project_id":  projectID,
+		}}
+		return eventarc.NewAuditLogEvent(projectID, "storage.googleapis.com", method, "projects/_/buckets/"+e.Bucket.Name, resource, now)
+	case EventSQLInstanceCreated, EventSQLInstanceUpdated, EventSQLInstanceDeleted:
+		method := map[EventType]string{
+			EventSQLInstanceCreated: "cloudsql.instances.create",
+			EventSQLInstanceUpdated: "cloudsql.instances.update",
+			EventSQLInstanceDeleted: "cloudsql.instances.delete",
+		}[e.Type]
+		project := e.Instance.Project
+		if project == "" {
+			project = projectID
+		}
+		return eventarc.NewAuditLogEvent(project, "cloudsql.googleapis.com", method, "projects/"+project+"/instances/"+e.Instance.Name,
+			sqlMonitoredResource(project, e.Instance.Name, e.Instance.Region), now)
+	case EventSQLDatabaseCreated, EventSQLDatabaseDeleted:
+		method := "cloudsql.databases.create"
+		if e.Type == EventSQLDatabaseDeleted {
+			method = "cloudsql.databases.delete"
+		}
+		project := e.Database.Project
+		if project == "" {
+			project = projectID
+		}
+		return eventarc.NewAuditLogEvent(project, "cloudsql.googleapis.com", method,
+			"projects/"+project+"/instances/"+e.Database.Instance+"/databases/"+e.Database.Name,
+			sqlMonitoredResource(project, e.Database.Instance, ""), now)
+	default:
+		return nil, nil
+	}
+}
+
+// sqlMonitoredResource returns the monitored resource of a Cloud SQL instance in audit log entries.
+func sqlMonitoredResource(project, instance, region string) *logging.MonitoredResource {
+	return &logging.MonitoredResource{Type: "cloudsql_database", Labels: map[string]string{
+		"database_id": project + ":" + instance,
+		"project_id":  project,
+		"region":      region,
+	}}
+}
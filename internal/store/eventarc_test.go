@@ -0,0 +1,155 @@
+package store
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/katharinasick/gcp-api-mock/internal/eventarc"
+	"github.com/katharinasick/gcp-api-mock/internal/sqladmin"
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+)
+
+const testEventarcParent = "projects/test-project/locations/europe-west1"
+
+func newTestEventarcTrigger(filters ...*eventarc.EventFilter) *eventarc.Trigger {
+	return &eventarc.Trigger{
+		EventFilters: filters,
+		Destination:  &eventarc.Destination{HttpEndpoint: &eventarc.HttpEndpoint{Uri: "http://localhost:9090/events"}},
+	}
+}
+
+func TestStore_CreateEventarcTrigger(t *testing.T) {
+	s := New()
+
+	op, err := s.CreateEventarcTrigger(testEventarcParent, "uploads", newTestEventarcTrigger(
+		&eventarc.EventFilter{Attribute: "type", Value: eventarc.EventTypeObjectFinalized},
+		&eventarc.EventFilter{Attribute: "bucket", Value: "uploads"},
+	))
+	if err != nil {
+		t.Fatalf("CreateEventarcTrigger() error: %v", err)
+	}
+	var response eventarc.Trigger
+	if err := json.Unmarshal(op.Response, &response); err != nil || !op.Done || response.Name != testEventarcParent+"/triggers/uploads" {
+		t.Errorf("expected a done operation with the trigger, got %+v", op)
+	}
+	if s.GetEventarcOperation(op.Name) == nil {
+		t.Error("expected the operation to be stored")
+	}
+
+	trigger := s.GetEventarcTrigger(testEventarcParent + "/triggers/uploads")
+	if trigger == nil || trigger.Uid == "" || trigger.Etag == "" || trigger.EventDataContentType != "application/json" {
+		t.Errorf("unexpected trigger: %+v", trigger)
+	}
+
+	typeFilter := &eventarc.EventFilter{Attribute: "type", Value: eventarc.EventTypeAuditLogWritten}
+	invalid := []struct {
+		id      string
+		trigger *eventarc.Trigger
+	}{
+		{"uploads", newTestEventarcTrigger(typeFilter, &eventarc.EventFilter{Attribute: "serviceName", Value: "x"}, &eventarc.EventFilter{Attribute: "methodName", Value: "y"})},
+		{"Invalid_ID", newTestEventarcTrigger(typeFilter)},
+		{"no-type", newTestEventarcTrigger(&eventarc.EventFilter{Attribute: "bucket", Value: "uploads"})},
+		{"unknown-type", newTestEventarcTrigger(&eventarc.EventFilter{Attribute: "type", Value: "google.cloud.pubsub.topic.v1.messagePublished"})},
+		{"missing-method", newTestEventarcTrigger(typeFilter, &eventarc.EventFilter{Attribute: "serviceName", Value: "cloudsql.googleapis.com"})},
+		{"unknown-attribute", newTestEventarcTrigger(
+			&eventarc.EventFilter{Attribute: "type", Value: eventarc.EventTypeObjectFinalized},
+			&eventarc.EventFilter{Attribute: "bucket", Value: "uploads"},
+			&eventarc.EventFilter{Attribute: "color", Value: "blue"},
+		)},
+		{"no-destination", &eventarc.Trigger{EventFilters: []*eventarc.EventFilter{
+			{Attribute: "type", Value: eventarc.EventTypeObjectFinalized},
+			{Attribute: "bucket", Value: "uploads"},
+		}}},
+	}
+	for _, tt := range invalid {
+		if _, err := s.CreateEventarcTrigger(testEventarcParent, tt.id, tt.trigger); err == nil {
+			t.Errorf("expected an error for trigger %s", tt.id)
+		}
+	}
+}
+
+func TestStore_UpdateEventarcTrigger(t *testing.T) {
+	s := New()
+	name := testEventarcParent + "/triggers/uploads"
+	if _, err := s.CreateEventarcTrigger(testEventarcParent, "uploads", newTestEventarcTrigger(
+		&eventarc.EventFilter{Attribute: "type", Value: eventarc.EventTypeObjectFinalized},
+		&eventarc.EventFilter{Attribute: "bucket", Value: "uploads"},
+	)); err != nil {
+		t.Fatalf("CreateEventarcTrigger() error: %v", err)
+	}
+
+	if _, err := s.UpdateEventarcTrigger(name, &eventarc.Trigger{Labels: map[string]string{"env": "dev"}}, nil); err != nil {
+		t.Fatalf("UpdateEventarcTrigger() error: %v", err)
+	}
+	if trigger := s.GetEventarcTrigger(name); trigger.Labels["env"] != "dev" || trigger.Destination == nil {
+		t.Errorf("unexpected trigger after update: %+v", trigger)
+	}
+
+	if _, err := s.UpdateEventarcTrigger(name, &eventarc.Trigger{}, []string{"destination"}); err == nil {
+		t.Error("expected an error for removing the destination")
+	}
+	if _, err := s.UpdateEventarcTrigger(name, &eventarc.Trigger{}, []string{"uid"}); err == nil {
+		t.Error("expected an error for an unknown update mask field")
+	}
+	if _, err := s.DeleteEventarcTrigger(name); err != nil {
+		t.Fatalf("DeleteEventarcTrigger() error: %v", err)
+	}
+	if _, err := s.DeleteEventarcTrigger(name); err == nil {
+		t.Error("expected an error for a deleted trigger")
+	}
+}
+
+func TestStore_EventarcTriggers_Fire(t *testing.T) {
+	s := New()
+
+	type delivery struct {
+		trigger string
+		event   *eventarc.CloudEvent
+	}
+	var deliveries []delivery
+	s.SetTriggerHandler(func(trigger *eventarc.Trigger, event *eventarc.CloudEvent) {
+		deliveries = append(deliveries, delivery{trigger.Name, event})
+	})
+
+	if _, err := s.CreateEventarcTrigger(testEventarcParent, "uploads", newTestEventarcTrigger(
+		&eventarc.EventFilter{Attribute: "type", Value: eventarc.EventTypeObjectFinalized},
+		&eventarc.EventFilter{Attribute: "bucket", Value: "uploads"},
+	)); err != nil {
+		t.Fatalf("CreateEventarcTrigger() error: %v", err)
+	}
+	if _, err := s.CreateEventarcTrigger(testEventarcParent, "sql", newTestEventarcTrigger(
+		&eventarc.EventFilter{Attribute: "type", Value: eventarc.EventTypeAuditLogWritten},
+		&eventarc.EventFilter{Attribute: "serviceName", Value: "cloudsql.googleapis.com"},
+		&eventarc.EventFilter{Attribute: "methodName", Value: "cloudsql.instances.create"},
+	)); err != nil {
+		t.Fatalf("CreateEventarcTrigger() error: %v", err)
+	}
+
+	// Only objects in the filtered bucket fire the object trigger
+	for _, bucket := range []string{"uploads", "other"} {
+		if _, err := s.CreateBucket(&storage.BucketInsertRequest{Name: bucket}); err != nil {
+			t.Fatalf("CreateBucket() error: %v", err)
+		}
+		if _, err := s.CreateObject(bucket, "a.txt", "text/plain", []byte("a"), nil); err != nil {
+			t.Fatalf("CreateObject() error: %v", err)
+		}
+	}
+	if len(deliveries) != 1 || deliveries[0].trigger != testEventarcParent+"/triggers/uploads" {
+		t.Fatalf("expected one delivery for the uploads trigger, got %+v", deliveries)
+	}
+	event := deliveries[0].event
+	var obj storage.Object
+	if err := json.Unmarshal(event.Data, &obj); err != nil || obj.Name != "a.txt" || event.Subject != "objects/a.txt" {
+		t.Errorf("unexpected event %+v with data %s", event, event.Data)
+	}
+
+	if _, _, err := s.CreateSQLInstance(&sqladmin.InstanceInsertRequest{Name: "db"}); err != nil {
+		t.Fatalf("CreateSQLInstance() error: %v", err)
+	}
+	if len(deliveries) != 2 || deliveries[1].trigger != testEventarcParent+"/triggers/sql" {
+		t.Fatalf("expected a delivery for the sql trigger, got %+v", deliveries)
+	}
+	if got := deliveries[1].event.Attribute("resourcename"); got != "projects/mock-project/instances/db" {
+		t.Errorf("unexpected resource name %q", got)
+	}
+}
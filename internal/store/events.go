@@ -0,0 +1,157 @@
+package store
+
+import (
+	"sync"
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/sqladmin"
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+)
+
+// EventType is the kind of change an Event describes.
+type EventType string
+
+// Event types of the changes published to subscribers.
+const (
+	EventBucketCreated         EventType = "bucket.created"
+	EventBucketDeleted         EventType = "bucket.deleted"
+	EventObjectFinalized       EventType = "object.finalized"
+	EventObjectMetadataUpdated EventType = "object.metadataUpdated"
+	EventObjectDeleted         EventType = "object.deleted"
+	EventSQLInstanceCreated    EventType = "sqlInstance.created"
+	EventSQLInstanceUpdated    EventType = "sqlInstance.updated"
+	EventSQLInstanceDeleted    EventType = "sqlInstance.deleted"
+	EventSQLDatabaseCreated    EventType = "sqlDatabase.created"
+	EventSQLDatabaseDeleted    EventType = "sqlDatabase.deleted"
+)
+
+// Event is a change of a resource in the store. Only the field of the changed resource is set, like Object
+// for object events; deleted resources are reported as they were before the deletion.
+type Event struct {
+	Type EventType
+	// Time is the time of the change on the store's clock.
+	Time     time.Time
+	Bucket   *storage.Bucket
+	Object   *storage.Object
+	Instance *sqladmin.DatabaseInstance
+	Database *sqladmin.Database
+}
+
+// Subscription receives the events of a store on C, in the order the changes were made.
+// Events are queued until they're received, so a slow receiver never blocks the store.
+type Subscription struct {
+	// C delivers the events. It is closed by Close.
+	C <-chan Event
+
+	store *Store
+	out   chan Event
+	done  chan struct{}
+	// wake has room for one signal, sent when events are queued
+	wake chan struct{}
+
+	mu     sync.Mutex
+	queue  []Event
+	closed bool
+}
+
+// Subscribe returns a subscription to the changes of the store, so tests can wait for an object to be
+// written instead of polling for it. Changes that depend on the clock, like a Cloud SQL instance becoming
+// RUNNABLE after its create delay, are published when the store applies them, e.g. on Tick.
+// Reset and restoring a snapshot replace resources without publishing events. Call Close when done.
+func (s *Store) Subscribe() *Subscription {
+	out := make(chan Event)
+	sub := &Subscription{
+		C:     out,
+		store: s,
+		out:   out,
+		done:  make(chan struct{}),
+		wake:  make(chan struct{}, 1),
+	}
+
+	s.subscribersMu.Lock()
+	s.subscribers[sub] = true
+	s.subscriberCount.Add(1)
+	s.subscribersMu.Unlock()
+
+	go sub.deliver()
+	return sub
+}
+
+// Close ends the subscription and closes C. Queued events that weren't received are dropped.
+func (sub *Subscription) Close() {
+	sub.store.subscribersMu.Lock()
+	if sub.store.subscribers[sub] {
+		delete(sub.store.subscribers, sub)
+		sub.store.subscriberCount.Add(-1)
+	}
+	sub.store.subscribersMu.Unlock()
+
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	if !sub.closed {
+		sub.closed = true
+		close(sub.done)
+	}
+}
+
+// enqueue queues an event for delivery.
+func (sub *Subscription) enqueue(e Event) {
+	sub.mu.Lock()
+	sub.queue = append(sub.queue, e)
+	sub.mu.Unlock()
+
+	select {
+	case sub.wake <- struct{}{}:
+	default:
+		// A wake-up is already pending
+	}
+}
+
+// deliver sends the queued events to C until the subscription is closed.
+func (sub *Subscription) deliver() {
+	defer close(sub.out)
+	for {
+		sub.mu.Lock()
+		queue := sub.queue
+		sub.queue = nil
+		sub.mu.Unlock()
+
+		for _, e := range queue {
+			select {
+			case sub.out <- e:
+			case <-sub.done:
+				return
+			}
+		}
+
+		select {
+		case <-sub.wake:
+		case <-sub.done:
+			return
+		}
+	}
+}
+
+// publish passes a copy of an event to every subscription and fires the Eventarc triggers it matches.
+// The resource of the event may be one the store keeps; it is only copied if there are subscribers.
+// It is called while a resource lock is held, so the events of a resource family are published in the
+// order of the changes.
+func (s *Store) publish(e Event) {
+	s.fireTriggers(e)
+
+	if s.subscriberCount.Load() == 0 {
+		return
+	}
+
+	e.Time = s.now()
+	e.Bucket = clone(e.Bucket)
+	e.Object = clone(e.Object)
+	e.Instance = clone(e.Instance)
+	e.Database = clone(e.Database)
+
+	s.subscribersMu.Lock()
+	defer s.subscribersMu.Unlock()
+	for sub := range s.subscribers {
+		sub.enqueue(e)
+	}
+}
@@ -0,0 +1,114 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/sqladmin"
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+)
+
+// nextEvent returns the next event of a subscription, failing the test if none arrives in time.
+func nextEvent(t *testing.T, sub *Subscription) Event {
+	t.Helper()
+
+	select {
+	case e, ok := <-sub.C:
+		if !ok {
+			t.Fatal("subscription closed")
+		}
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for an event")
+		return Event{}
+	}
+}
+
+func TestStore_Subscribe(t *testing.T) {
+	s := New()
+	sub := s.Subscribe()
+	defer sub.Close()
+
+	// Events are queued until they're received, so the store doesn't wait for the subscriber
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+	_, _ = s.CreateObject("test-bucket", "a.txt", "text/plain", []byte("a"), nil)
+	_, _ = s.PatchObject("test-bucket", "a.txt", &storage.ObjectPatchRequest{Metadata: map[string]*string{}}, Preconditions{})
+	_ = s.DeleteObject("test-bucket", "a.txt")
+	_ = s.DeleteBucket("test-bucket")
+	_, _, _ = s.CreateSQLInstance(&sqladmin.InstanceInsertRequest{Name: "db"})
+	_, _, _ = s.CreateSQLDatabase("db", &sqladmin.DatabaseInsertRequest{Name: "app"})
+	_, _ = s.SetSQLInstanceState("db", "STOPPED", nil)
+	_, _ = s.DeleteSQLDatabase("db", "app")
+	_, _ = s.DeleteSQLInstance("db")
+
+	expected := []EventType{
+		EventBucketCreated,
+		EventObjectFinalized,
+		EventObjectMetadataUpdated,
+		EventObjectDeleted,
+		EventBucketDeleted,
+		EventSQLInstanceCreated,
+		EventSQLDatabaseCreated,
+		EventSQLInstanceUpdated,
+		EventSQLDatabaseDeleted,
+		EventSQLInstanceDeleted,
+	}
+	for i, want := range expected {
+		e := nextEvent(t, sub)
+		if e.Type != want {
+			t.Fatalf("event %d: expected %s, got %s", i, want, e.Type)
+		}
+		if e.Time.IsZero() {
+			t.Errorf("event %d: expected a time", i)
+		}
+		switch want {
+		case EventObjectFinalized:
+			if e.Object == nil || e.Object.Name != "a.txt" || e.Object.Generation == 0 {
+				t.Errorf("expected the written object, got %+v", e.Object)
+			}
+		case EventSQLInstanceUpdated:
+			if e.Instance == nil || e.Instance.State != "STOPPED" {
+				t.Errorf("expected the stopped instance, got %+v", e.Instance)
+			}
+		case EventSQLDatabaseDeleted:
+			if e.Database == nil || e.Database.Name != "app" {
+				t.Errorf("expected the deleted database, got %+v", e.Database)
+			}
+		}
+	}
+
+	// Events are copies, so changing them doesn't change the store
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "other-bucket"})
+	e := nextEvent(t, sub)
+	e.Bucket.Name = "changed"
+	if s.GetBucket("other-bucket") == nil {
+		t.Error("expected the bucket to be unaffected by changes to the event")
+	}
+
+	// Closing a subscription closes its channel
+	sub.Close()
+	for range sub.C {
+	}
+}
+
+func TestStore_Subscribe_ClockDependentChanges(t *testing.T) {
+	s := New()
+	now := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	s.SetClock(func() time.Time { return now })
+	s.SetSQLCreateDelay(time.Minute)
+
+	_, _, _ = s.CreateSQLInstance(&sqladmin.InstanceInsertRequest{Name: "db"})
+	sub := s.Subscribe()
+	defer sub.Close()
+
+	now = now.Add(2 * time.Minute)
+	s.Tick()
+
+	e := nextEvent(t, sub)
+	if e.Type != EventSQLInstanceUpdated || e.Instance.State != "RUNNABLE" {
+		t.Errorf("expected the instance to become RUNNABLE, got %s %+v", e.Type, e.Instance)
+	}
+	if !e.Time.Equal(now) {
+		t.Errorf("expected the event at %v, got %v", now, e.Time)
+	}
+}
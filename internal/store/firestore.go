@@ -0,0 +1,189 @@
+package store
+
+import (
+	"crypto/rand"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/katharinasick/gcp-api-mock/internal/firestore"
+)
+
+// =============================================================================
+// Firestore Document Operations
+// =============================================================================
+
+// documentIDAlphabet is the alphabet for generated document IDs.
+const documentIDAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+// newDocumentID generates a random 20 character document ID, like the Firestore client libraries do.
+func newDocumentID() string {
+	b := make([]byte, 20)
+	rand.Read(b)
+	for i := range b {
+		b[i] = documentIDAlphabet[int(b[i])%len(documentIDAlphabet)]
+	}
+	return string(b)
+}
+
+// CreateDocument creates a new document in the collection collectionID below parent.
+// Parent is a database documents root (projects/{project}/databases/{database}/documents) or a document name.
+// If documentID is empty, an ID is generated.
+func (s *Store) CreateDocument(parent, collectionID, documentID string, fields map[string]*firestore.Value) (*firestore.Document, error) {
+	if documentID == "" {
+		documentID = newDocumentID()
+	}
+
+	s.firestoreMu.Lock()
+	defer s.firestoreMu.Unlock()
+
+	name := parent + "/" + collectionID + "/" + documentID
+	if _, exists := s.documents[name]; exists {
+		return nil, fmt.Errorf("document %s already exists", name)
+	}
+
+	now := s.now()
+	doc := &firestore.Document{
+		Name:       name,
+		Fields:     fields,
+		CreateTime: now,
+		UpdateTime: now,
+	}
+	s.documents[name] = doc
+
+	return clone(doc), nil
+}
+
+// GetDocument retrieves a document by name.
+// Returns nil if the document doesn't exist.
+func (s *Store) GetDocument(name string) *firestore.Document {
+	s.firestoreMu.RLock()
+	defer s.firestoreMu.RUnlock()
+
+	return clone(s.documents[name])
+}
+
+// UpdateDocument updates or creates a document.
+// If updateMask is empty, all fields are replaced; otherwise only the masked field paths are
+// set from fields, or removed if they are not in fields.
+// If exists is set, the update fails unless the document's existence matches it.
+func (s *Store) UpdateDocument(name string, fields map[string]*firestore.Value, updateMask []string, exists *bool) (*firestore.Document, error) {
+	s.firestoreMu.Lock()
+	defer s.firestoreMu.Unlock()
+
+	existing, found := s.documents[name]
+	if exists != nil && *exists && !found {
+		return nil, fmt.Errorf("document %s not found", name)
+	}
+	if exists != nil && !*exists && found {
+		return nil, fmt.Errorf("document %s already exists", name)
+	}
+
+	now := s.now()
+	doc := &firestore.Document{
+		Name:       name,
+		Fields:     fields,
+		CreateTime: now,
+		UpdateTime: now,
+	}
+
+	if found {
+		doc.CreateTime = existing.CreateTime
+		if len(updateMask) > 0 {
+			doc.Fields = existing.Fields
+			for _, path := range updateMask {
+				if value := firestore.GetField(fields, path); value != nil {
+					doc.Fields = firestore.SetField(doc.Fields, path, value)
+				} else {
+					doc.Fields = firestore.DeleteField(doc.Fields, path)
+				}
+			}
+		}
+	}
+
+	// Documents are replaced rather than modified, so previously returned documents stay unchanged
+	s.documents[name] = doc
+
+	return clone(doc), nil
+}
+
+// DeleteDocument deletes a document by name.
+func (s *Store) DeleteDocument(name string) error {
+	s.firestoreMu.Lock()
+	defer s.firestoreMu.Unlock()
+
+	if _, exists := s.documents[name]; !exists {
+		return fmt.Errorf("document %s not found", name)
+	}
+
+	delete(s.documents, name)
+	return nil
+}
+
+// ListDocuments returns the documents in the collection collectionID directly below parent, sorted by name.
+func (s *Store) ListDocuments(parent, collectionID string) []*firestore.Document {
+	s.firestoreMu.RLock()
+	defer s.firestoreMu.RUnlock()
+
+	return clone(s.collectDocuments(parent, collectionID, false))
+}
+
+// RunQuery runs a structured query below parent and returns the matching documents.
+func (s *Store) RunQuery(parent string, query *firestore.StructuredQuery) ([]*firestore.Document, error) {
+	if len(query.From) != 1 {
+		return nil, fmt.Errorf("query must select exactly one collection")
+	}
+
+	s.firestoreMu.RLock()
+	docs := s.collectDocuments(parent, query.From[0].CollectionID, query.From[0].AllDescendants)
+	s.firestoreMu.RUnlock()
+
+	var matching []*firestore.Document
+	for _, doc := range docs {
+		if firestore.Matches(doc.Fields, query.Where) {
+			matching = append(matching, doc)
+		}
+	}
+
+	matching = firestore.SortDocuments(matching, query.OrderBy)
+
+	if query.Offset > 0 {
+		if query.Offset >= len(matching) {
+			return nil, nil
+		}
+		matching = matching[query.Offset:]
+	}
+	if query.Limit != nil && *query.Limit >= 0 && *query.Limit < len(matching) {
+		matching = matching[:*query.Limit]
+	}
+
+	return clone(matching), nil
+}
+
+// collectDocuments returns the documents in collections with the given ID below parent, sorted by name.
+// If allDescendants is false, only the direct child collection is included.
+// Callers must hold the Firestore lock.
+func (s *Store) collectDocuments(parent, collectionID string, allDescendants bool) []*firestore.Document {
+	var docs []*firestore.Document
+	for name, doc := range s.documents {
+		if !strings.HasPrefix(name, parent+"/") {
+			continue
+		}
+
+		// The collection of a document is the second to last segment of its name
+		collectionPath := name[:strings.LastIndex(name, "/")]
+		if allDescendants {
+			if collectionPath[strings.LastIndex(collectionPath, "/")+1:] == collectionID {
+				docs = append(docs, doc)
+			}
+		} else if collectionPath == parent+"/"+collectionID {
+			docs = append(docs, doc)
+		}
+	}
+
+	sort.Slice(docs, func(i, j int) bool {
+		return docs[i].Name < docs[j].Name
+	})
+
+	return docs
+}
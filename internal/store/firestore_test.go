@@ -0,0 +1,106 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/katharinasick/gcp-api-mock/internal/firestore"
+)
+
+const testDocumentsRoot = "projects/test-project/databases/(default)/documents"
+
+func testStringValue(s string) *firestore.Value {
+	return &firestore.Value{StringValue: &s}
+}
+
+func TestStore_DocumentCRUD(t *testing.T) {
+	s := New()
+
+	doc, err := s.CreateDocument(testDocumentsRoot, "users", "alice", map[string]*firestore.Value{
+		"name": testStringValue("Alice"),
+		"city": testStringValue("Vienna"),
+	})
+	if err != nil {
+		t.Fatalf("CreateDocument() error: %v", err)
+	}
+	if doc.Name != testDocumentsRoot+"/users/alice" {
+		t.Errorf("unexpected name: %s", doc.Name)
+	}
+
+	if _, err := s.CreateDocument(testDocumentsRoot, "users", "alice", nil); err == nil {
+		t.Error("expected error when creating a duplicate document")
+	}
+
+	generated, err := s.CreateDocument(testDocumentsRoot, "users", "", nil)
+	if err != nil {
+		t.Fatalf("CreateDocument() error: %v", err)
+	}
+	if len(generated.Name) != len(testDocumentsRoot+"/users/")+20 {
+		t.Errorf("expected a generated 20 character ID, got %s", generated.Name)
+	}
+
+	// Update only the masked fields: city is removed, name is kept
+	updated, err := s.UpdateDocument(doc.Name, map[string]*firestore.Value{
+		"age": {IntegerValue: new(string)},
+	}, []string{"age", "city"}, nil)
+	if err != nil {
+		t.Fatalf("UpdateDocument() error: %v", err)
+	}
+	if updated.Fields["name"] == nil || updated.Fields["city"] != nil || updated.Fields["age"] == nil {
+		t.Errorf("unexpected fields after masked update: %+v", updated.Fields)
+	}
+	if !updated.CreateTime.Equal(doc.CreateTime) {
+		t.Error("expected create time to be kept")
+	}
+
+	exists := true
+	if _, err := s.UpdateDocument(testDocumentsRoot+"/users/missing", nil, nil, &exists); err == nil {
+		t.Error("expected error when updating a missing document with exists=true")
+	}
+
+	if err := s.DeleteDocument(doc.Name); err != nil {
+		t.Fatalf("DeleteDocument() error: %v", err)
+	}
+	if s.GetDocument(doc.Name) != nil {
+		t.Error("expected document to be deleted")
+	}
+}
+
+func TestStore_RunQuery(t *testing.T) {
+	s := New()
+
+	s.CreateDocument(testDocumentsRoot, "users", "alice", map[string]*firestore.Value{"city": testStringValue("Vienna")})
+	s.CreateDocument(testDocumentsRoot, "users", "bob", map[string]*firestore.Value{"city": testStringValue("Berlin")})
+	s.CreateDocument(testDocumentsRoot+"/users/alice", "orders", "o1", map[string]*firestore.Value{"city": testStringValue("Vienna")})
+
+	query := &firestore.StructuredQuery{
+		From: []firestore.CollectionSelector{{CollectionID: "users"}},
+		Where: &firestore.Filter{FieldFilter: &firestore.FieldFilter{
+			Field: firestore.FieldReference{FieldPath: "city"},
+			Op:    "EQUAL",
+			Value: testStringValue("Vienna"),
+		}},
+	}
+
+	docs, err := s.RunQuery(testDocumentsRoot, query)
+	if err != nil {
+		t.Fatalf("RunQuery() error: %v", err)
+	}
+	if len(docs) != 1 || docs[0].Name != testDocumentsRoot+"/users/alice" {
+		t.Errorf("unexpected results: %+v", docs)
+	}
+
+	// Collection group query over all orders collections
+	query.From = []firestore.CollectionSelector{{CollectionID: "orders", AllDescendants: true}}
+	docs, err = s.RunQuery(testDocumentsRoot, query)
+	if err != nil {
+		t.Fatalf("RunQuery() error: %v", err)
+	}
+	if len(docs) != 1 {
+		t.Errorf("expected 1 result from collection group query, got %d", len(docs))
+	}
+
+	// Only direct children are listed
+	if users := s.ListDocuments(testDocumentsRoot, "users"); len(users) != 2 {
+		t.Errorf("expected 2 users, got %d", len(users))
+	}
+}
@@ -0,0 +1,295 @@
+package store
+
+import (
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/katharinasick/gcp-api-mock/internal/certs"
+	"github.com/katharinasick/gcp-api-mock/internal/gke"
+)
+
+// =============================================================================
+// GKE Cluster Operations
+// =============================================================================
+
+// gkeClusterNamePattern matches valid cluster names: lowercase letters, digits and hyphens, starting with a letter
+// and not ending with a hyphen, at most 40 characters.
+var gkeClusterNamePattern = regexp.MustCompile(`^[a-z]([a-z0-9-]{0,38}[a-z0-9])?$`)
+
+// gkeDefaultVersion is the Kubernetes version of clusters that don't ask for a specific one.
+const gkeDefaultVersion = "1.30.5-gke.1014001"
+
+// gkeServicesIpv4Cidr is the range GKE takes the IP addresses of services from by default.
+const gkeServicesIpv4Cidr = "34.118.224.0/20"
+
+// SetGKEKubeconfig sets the API server and credentials of a real cluster, like a local kind cluster, that all
+// GKE clusters report as their endpoint and CA, and that their kubeconfig connects to. Without it, clusters get
+// made-up endpoints and no kubeconfig.
+func (s *Store) SetGKEKubeconfig(kubeconfig *gke.Kubeconfig) {
+	s.configure(func(cfg *storeConfig) {
+		cfg.gkeKubeconfig = kubeconfig
+	})
+}
+
+// CreateGKECluster creates a cluster below parent (projects/{project}/locations/{location}).
+// The cluster is RUNNING right away and the returned operation is already done.
+func (s *Store) CreateGKECluster(parent string, req *gke.Cluster) (*gke.Operation, error) {
+	s.gkeMu.Lock()
+	defer s.gkeMu.Unlock()
+
+	if req == nil {
+		return nil, fmt.Errorf("invalid request: cluster is required")
+	}
+	if !gkeClusterNamePattern.MatchString(req.Name) {
+		return nil, fmt.Errorf("invalid cluster name %q: must start with a lowercase letter, contain only lowercase letters, digits and hyphens and be at most 40 characters long", req.Name)
+	}
+	name := parent + "/clusters/" + req.Name
+	if _, exists := s.gkeClusters[name]; exists {
+		return nil, fmt.Errorf("cluster %s already exists", name)
+	}
+
+	cluster := clone(req)
+	autopilot := cluster.Autopilot != nil && cluster.Autopilot.Enabled
+	switch {
+	case cluster.InitialNodeCount < 0:
+		return nil, fmt.Errorf("invalid initialNodeCount %d: must not be negative", cluster.InitialNodeCount)
+	case cluster.InitialNodeCount > 0 && len(cluster.NodePools) > 0:
+		return nil, fmt.Errorf("invalid cluster: initialNodeCount and nodePools can't both be set")
+	case !autopilot && cluster.InitialNodeCount == 0 && len(cluster.NodePools) == 0:
+		return nil, fmt.Errorf("invalid cluster: initialNodeCount or nodePools is required for clusters without Autopilot")
+	}
+	for _, pool := range cluster.NodePools {
+		if pool == nil || !gkeClusterNamePattern.MatchString(pool.Name) {
+			return nil, fmt.Errorf("invalid node pool: must have a name of lowercase letters, digits and hyphens")
+		}
+		if pool.InitialNodeCount < 0 {
+			return nil, fmt.Errorf("invalid initialNodeCount %d of node pool %s: must not be negative", pool.InitialNodeCount, pool.Name)
+		}
+	}
+
+	_, location := parseGKEParent(parent)
+	cfg := s.config()
+	version := cluster.InitialClusterVersion
+	if version == "" || version == "latest" || version == "-" {
+		version = gkeDefaultVersion
+	}
+	if len(cluster.Locations) == 0 {
+		cluster.Locations = gkeZones(location)
+	}
+
+	// Standard clusters created with initialNodeCount get a default pool, like with gcloud
+	if cluster.InitialNodeCount > 0 {
+		cluster.NodePools = []*gke.NodePool{{
+			Name:             "default-pool",
+			Config:           cluster.NodeConfig,
+			InitialNodeCount: cluster.InitialNodeCount,
+		}}
+	}
+	for _, pool := range cluster.NodePools {
+		if pool.Config == nil {
+			pool.Config = &gke.NodeConfig{}
+		}
+		applyGKENodeConfigDefaults(pool.Config)
+		if len(pool.Locations) == 0 {
+			pool.Locations = cluster.Locations
+		}
+		pool.SelfLink = fmt.Sprintf("%s/v1/%s/nodePools/%s", cfg.baseURL, name, pool.Name)
+		pool.Version = version
+		pool.Status = gke.StatusRunning
+	}
+	if len(cluster.NodePools) > 0 {
+		cluster.NodeConfig = clone(cluster.NodePools[0].Config)
+	}
+
+	if cluster.LoggingService == "" {
+		cluster.LoggingService = "logging.googleapis.com/kubernetes"
+	}
+	if cluster.MonitoringService == "" {
+		cluster.MonitoringService = "monitoring.googleapis.com/kubernetes"
+	}
+	if cluster.Network == "" {
+		cluster.Network = "default"
+	}
+	if cluster.Subnetwork == "" {
+		cluster.Subnetwork = "default"
+	}
+	if cluster.ClusterIpv4Cidr == "" {
+		// Hand out consecutive /14 ranges, like GKE picks in an empty network
+		cluster.ClusterIpv4Cidr = fmt.Sprintf("10.%d.0.0/14", s.gkeRangeSeq*4%256)
+	}
+	if cluster.ServicesIpv4Cidr == "" {
+		cluster.ServicesIpv4Cidr = gkeServicesIpv4Cidr
+	}
+	if cluster.MasterAuth == nil {
+		cluster.MasterAuth = &gke.MasterAuth{}
+	}
+	if kubeconfig := cfg.gkeKubeconfig; kubeconfig != nil {
+		cluster.Endpoint = strings.TrimPrefix(kubeconfig.Server, "https://")
+		cluster.MasterAuth.ClusterCaCertificate = kubeconfig.CertificateAuthorityData
+	} else {
+		// A documentation address (TEST-NET-3), so nothing real is reached
+		cluster.Endpoint = fmt.Sprintf("203.0.113.%d", s.gkeRangeSeq%254+1)
+		caPEM, err := certs.GenerateCA(cluster.Name)
+		if err != nil {
+			return nil, err
+		}
+		cluster.MasterAuth.ClusterCaCertificate = base64.StdEncoding.EncodeToString(caPEM)
+	}
+	s.gkeRangeSeq++
+
+	cluster.SelfLink = fmt.Sprintf("%s/v1/%s", cfg.baseURL, name)
+	cluster.Zone = location
+	cluster.Location = location
+	cluster.CurrentMasterVersion = version
+	cluster.CurrentNodeVersion = version
+	cluster.CreateTime = s.now()
+	cluster.Status = gke.StatusRunning
+	cluster.Id = strings.ReplaceAll(newUUID(), "-", "")
+	s.gkeClusters[name] = cluster
+
+	return clone(s.createGKEOperation(name, gke.OperationCreateCluster)), nil
+}
+
+// GetGKECluster retrieves a cluster by name (projects/{project}/locations/{location}/clusters/{cluster}).
+// Returns nil if the cluster doesn't exist.
+func (s *Store) GetGKECluster(name string) *gke.Cluster {
+	s.gkeMu.RLock()
+	defer s.gkeMu.RUnlock()
+
+	return clone(s.gkeClusters[name])
+}
+
+// ListGKEClusters returns all clusters below parent, sorted by name.
+// The location "-" lists the clusters of all locations of the project.
+func (s *Store) ListGKEClusters(parent string) []*gke.Cluster {
+	s.gkeMu.RLock()
+	defer s.gkeMu.RUnlock()
+
+	project, location := parseGKEParent(parent)
+	clusters := make([]*gke.Cluster, 0)
+	for name, cluster := range s.gkeClusters {
+		clusterParent, _, _ := strings.Cut(name, "/clusters/")
+		clusterProject, clusterLocation := parseGKEParent(clusterParent)
+		if clusterProject == project && (location == "-" || clusterLocation == location) {
+			clusters = append(clusters, cluster)
+		}
+	}
+
+	sort.Slice(clusters, func(i, j int) bool {
+		return clusters[i].Name < clusters[j].Name
+	})
+
+	return clone(clusters)
+}
+
+// DeleteGKECluster deletes a cluster. The returned operation is already done.
+func (s *Store) DeleteGKECluster(name string) (*gke.Operation, error) {
+	s.gkeMu.Lock()
+	defer s.gkeMu.Unlock()
+
+	if _, exists := s.gkeClusters[name]; !exists {
+		return nil, fmt.Errorf("cluster %s not found", name)
+	}
+	delete(s.gkeClusters, name)
+
+	return clone(s.createGKEOperation(name, gke.OperationDeleteCluster)), nil
+}
+
+// GKEKubeconfig returns a kubeconfig file for a cluster that connects to the configured real cluster.
+func (s *Store) GKEKubeconfig(name string) ([]byte, error) {
+	s.gkeMu.RLock()
+	defer s.gkeMu.RUnlock()
+
+	cluster, exists := s.gkeClusters[name]
+	if !exists {
+		return nil, fmt.Errorf("cluster %s not found", name)
+	}
+	kubeconfig := s.config().gkeKubeconfig
+	if kubeconfig == nil {
+		return nil, fmt.Errorf("no kubeconfig configured: set GCP_MOCK_GKE_KUBECONFIG to the kubeconfig of a kind cluster")
+	}
+
+	parent, _, _ := strings.Cut(name, "/clusters/")
+	project, location := parseGKEParent(parent)
+	contextName := "gke_" + project + "_" + location + "_" + cluster.Name
+	return kubeconfig.Render(contextName, "", ""), nil
+}
+
+// applyGKENodeConfigDefaults fills in the node settings GKE defaults to.
+func applyGKENodeConfigDefaults(config *gke.NodeConfig) {
+	if config.MachineType == "" {
+		config.MachineType = "e2-medium"
+	}
+	if config.DiskSizeGb == 0 {
+		config.DiskSizeGb = 100
+	}
+	if config.DiskType == "" {
+		config.DiskType = "pd-balanced"
+	}
+	if config.ImageType == "" {
+		config.ImageType = "COS_CONTAINERD"
+	}
+	if config.ServiceAccount == "" {
+		config.ServiceAccount = "default"
+	}
+	if len(config.OauthScopes) == 0 {
+		config.OauthScopes = []string{"https://www.googleapis.com/auth/cloud-platform"}
+	}
+}
+
+// gkeZones returns the zones of the nodes of a cluster in a location: the location itself if it is a zone
+// like us-central1-a, otherwise three zones of the region.
+func gkeZones(location string) []string {
+	if strings.Count(location, "-") >= 2 {
+		return []string{location}
+	}
+	return []string{location + "-a", location + "-b", location + "-c"}
+}
+
+// parseGKEParent returns the project and location of a parent like projects/{project}/locations/{location}.
+func parseGKEParent(parent string) (string, string) {
+	rest, _ := strings.CutPrefix(parent, "projects/")
+	project, location, _ := strings.Cut(rest, "/locations/")
+	return project, location
+}
+
+// =============================================================================
+// GKE Operation Operations
+// =============================================================================
+
+// createGKEOperation creates and stores a completed operation of a type for a cluster.
+// Must be called with the lock held.
+func (s *Store) createGKEOperation(target, operationType string) *gke.Operation {
+	now := s.now()
+	parent, _, _ := strings.Cut(target, "/clusters/")
+	_, location := parseGKEParent(parent)
+	id := newOperationID(now)
+	baseURL := s.config().baseURL
+
+	op := &gke.Operation{
+		Name:          id,
+		Zone:          location,
+		OperationType: operationType,
+		Status:        gke.OperationDone,
+		SelfLink:      fmt.Sprintf("%s/v1/%s/operations/%s", baseURL, parent, id),
+		TargetLink:    fmt.Sprintf("%s/v1/%s", baseURL, target),
+		Location:      location,
+		StartTime:     now,
+		EndTime:       now,
+	}
+	s.gkeOperations[parent+"/operations/"+id] = op
+
+	return op
+}
+
+// GetGKEOperation retrieves an operation by its full name (projects/{project}/locations/{location}/operations/{operation}).
+// Returns nil if the operation doesn't exist.
+func (s *Store) GetGKEOperation(name string) *gke.Operation {
+	s.gkeMu.RLock()
+	defer s.gkeMu.RUnlock()
+
+	return clone(s.gkeOperations[name])
+}
@@ -0,0 +1,133 @@
+package store
+
+import (
+	"encoding/base64"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/katharinasick/gcp-api-mock/internal/gke"
+)
+
+const testGKEParent = "projects/test-project/locations/europe-west1"
+
+func TestStore_CreateGKECluster(t *testing.T) {
+	s := New()
+
+	op, err := s.CreateGKECluster(testGKEParent, &gke.Cluster{Name: "platform", InitialNodeCount: 3})
+	if err != nil {
+		t.Fatalf("CreateGKECluster() error: %v", err)
+	}
+	if op.Status != gke.OperationDone || op.OperationType != gke.OperationCreateCluster || !strings.HasSuffix(op.TargetLink, "/v1/"+testGKEParent+"/clusters/platform") {
+		t.Errorf("unexpected operation: %+v", op)
+	}
+	if s.GetGKEOperation(testGKEParent+"/operations/"+op.Name) == nil {
+		t.Error("expected the operation to be stored")
+	}
+
+	cluster := s.GetGKECluster(testGKEParent + "/clusters/platform")
+	if cluster == nil {
+		t.Fatal("expected the cluster to exist")
+	}
+	if cluster.Status != gke.StatusRunning || cluster.Location != "europe-west1" || cluster.Endpoint == "" || len(cluster.Id) != 32 ||
+		!slices.Equal(cluster.Locations, []string{"europe-west1-a", "europe-west1-b", "europe-west1-c"}) {
+		t.Errorf("unexpected cluster: %+v", cluster)
+	}
+	if len(cluster.NodePools) != 1 || cluster.NodePools[0].Name != "default-pool" || cluster.NodePools[0].InitialNodeCount != 3 ||
+		cluster.NodePools[0].Config.MachineType != "e2-medium" {
+		t.Errorf("expected a default pool, got %+v", cluster.NodePools)
+	}
+	if ca, err := base64.StdEncoding.DecodeString(cluster.MasterAuth.ClusterCaCertificate); err != nil || !strings.HasPrefix(string(ca), "-----BEGIN CERTIFICATE-----") {
+		t.Errorf("expected a base64-encoded CA certificate, got %q", cluster.MasterAuth.ClusterCaCertificate)
+	}
+
+	// Zonal clusters have their nodes in their zone
+	if _, err := s.CreateGKECluster("projects/test-project/locations/europe-west1-b", &gke.Cluster{
+		Name: "zonal", NodePools: []*gke.NodePool{{Name: "pool", InitialNodeCount: 1}},
+	}); err != nil {
+		t.Fatalf("CreateGKECluster() error: %v", err)
+	}
+	if cluster := s.GetGKECluster("projects/test-project/locations/europe-west1-b/clusters/zonal"); !slices.Equal(cluster.Locations, []string{"europe-west1-b"}) {
+		t.Errorf("unexpected locations: %v", cluster.Locations)
+	}
+
+	invalid := []*gke.Cluster{
+		nil,
+		{Name: "platform", InitialNodeCount: 1},
+		{Name: "Invalid_Name", InitialNodeCount: 1},
+		{Name: "no-nodes"},
+		{Name: "both", InitialNodeCount: 1, NodePools: []*gke.NodePool{{Name: "pool"}}},
+	}
+	for _, cluster := range invalid {
+		if _, err := s.CreateGKECluster(testGKEParent, cluster); err == nil {
+			t.Errorf("expected an error for cluster %+v", cluster)
+		}
+	}
+
+	// Autopilot clusters don't need nodes
+	if _, err := s.CreateGKECluster(testGKEParent, &gke.Cluster{Name: "autopilot", Autopilot: &gke.Autopilot{Enabled: true}}); err != nil {
+		t.Errorf("CreateGKECluster() error: %v", err)
+	}
+}
+
+func TestStore_GKEKubeconfig(t *testing.T) {
+	s := New()
+	name := testGKEParent + "/clusters/platform"
+
+	if _, err := s.CreateGKECluster(testGKEParent, &gke.Cluster{Name: "platform", InitialNodeCount: 1}); err != nil {
+		t.Fatalf("CreateGKECluster() error: %v", err)
+	}
+	if _, err := s.GKEKubeconfig(name); err == nil {
+		t.Error("expected an error without a configured kubeconfig")
+	}
+
+	s.SetGKEKubeconfig(&gke.Kubeconfig{Server: "https://127.0.0.1:44321", CertificateAuthorityData: "Q0E=", Token: "secret"})
+	if _, err := s.CreateGKECluster(testGKEParent, &gke.Cluster{Name: "kind", InitialNodeCount: 1}); err != nil {
+		t.Fatalf("CreateGKECluster() error: %v", err)
+	}
+	cluster := s.GetGKECluster(testGKEParent + "/clusters/kind")
+	if cluster.Endpoint != "127.0.0.1:44321" || cluster.MasterAuth.ClusterCaCertificate != "Q0E=" {
+		t.Errorf("expected the kind cluster's endpoint and CA, got %s, %s", cluster.Endpoint, cluster.MasterAuth.ClusterCaCertificate)
+	}
+
+	kubeconfig, err := s.GKEKubeconfig(testGKEParent + "/clusters/kind")
+	if err != nil {
+		t.Fatalf("GKEKubeconfig() error: %v", err)
+	}
+	if !strings.Contains(string(kubeconfig), "current-context: gke_test-project_europe-west1_kind\n") ||
+		!strings.Contains(string(kubeconfig), "server: https://127.0.0.1:44321\n") {
+		t.Errorf("unexpected kubeconfig:\n%s", kubeconfig)
+	}
+
+	if _, err := s.GKEKubeconfig(testGKEParent + "/clusters/missing"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+}
+
+func TestStore_ListAndDeleteGKEClusters(t *testing.T) {
+	s := New()
+
+	for _, parent := range []string{testGKEParent, "projects/test-project/locations/us-central1", "projects/other-project/locations/europe-west1"} {
+		if _, err := s.CreateGKECluster(parent, &gke.Cluster{Name: "platform", InitialNodeCount: 1}); err != nil {
+			t.Fatalf("CreateGKECluster() error: %v", err)
+		}
+	}
+
+	if clusters := s.ListGKEClusters(testGKEParent); len(clusters) != 1 {
+		t.Errorf("expected 1 cluster in the location, got %d", len(clusters))
+	}
+	if clusters := s.ListGKEClusters("projects/test-project/locations/-"); len(clusters) != 2 {
+		t.Errorf("expected 2 clusters in all locations, got %d", len(clusters))
+	}
+
+	op, err := s.DeleteGKECluster(testGKEParent + "/clusters/platform")
+	if err != nil {
+		t.Fatalf("DeleteGKECluster() error: %v", err)
+	}
+	if op.OperationType != gke.OperationDeleteCluster || s.GetGKECluster(testGKEParent+"/clusters/platform") != nil {
+		t.Errorf("expected the cluster to be deleted, got %+v", op)
+	}
+	if _, err := s.DeleteGKECluster(testGKEParent + "/clusters/platform"); err == nil {
+		t.Error("expected an error deleting a missing cluster")
+	}
+}
@@ -0,0 +1,86 @@
+package store
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// compileGlob compiles a Cloud Storage matchGlob pattern into a regular expression.
+// "*" matches any characters except "/", "**" matches any characters including "/",
+// "?" matches a single character except "/", "[...]" matches a character class and
+// "{a,b}" matches any of the comma-separated alternatives.
+// Reference: https://cloud.google.com/storage/docs/json_api/v1/objects/list#list-objects-and-prefixes-using-glob
+func compileGlob(glob string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+
+	inAlternation := false
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				i++
+				// "**/" also matches no directory at all, e.g. "a/**/b" matches "a/b"
+				if i+1 < len(glob) && glob[i+1] == '/' {
+					i++
+					b.WriteString("(?:.*/)?")
+				} else {
+					b.WriteString(".*")
+				}
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid matchGlob %q: unterminated character class", glob)
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		case '{':
+			if inAlternation {
+				return nil, fmt.Errorf("invalid matchGlob %q: nested alternatives", glob)
+			}
+			inAlternation = true
+			b.WriteString("(?:")
+		case '}':
+			if !inAlternation {
+				return nil, fmt.Errorf("invalid matchGlob %q: unmatched }", glob)
+			}
+			inAlternation = false
+			b.WriteString(")")
+		case ',':
+			if inAlternation {
+				b.WriteString("|")
+			} else {
+				b.WriteString(",")
+			}
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				c = glob[i]
+			}
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	if inAlternation {
+		return nil, fmt.Errorf("invalid matchGlob %q: unterminated alternatives", glob)
+	}
+
+	b.WriteString("$")
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid matchGlob %q: %w", glob, err)
+	}
+	return re, nil
+}
@@ -0,0 +1,45 @@
+package store
+
+import "testing"
+
+func TestCompileGlob(t *testing.T) {
+	tests := []struct {
+		glob  string
+		name  string
+		match bool
+	}{
+		{"*.txt", "file.txt", true},
+		{"*.txt", "dir/file.txt", false},
+		{"**.txt", "dir/file.txt", true},
+		{"a/**/b", "a/b", true},
+		{"a/**/b", "a/x/y/b", true},
+		{"a/**/b", "ab", false},
+		{"file?.log", "file1.log", true},
+		{"file?.log", "file10.log", false},
+		{"[ab]*", "apple", true},
+		{"[!ab]*", "apple", false},
+		{"*.{jpg,png}", "photo.png", true},
+		{"*.{jpg,png}", "photo.gif", false},
+		{"a+b(c)", "a+b(c)", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.glob+" "+tt.name, func(t *testing.T) {
+			re, err := compileGlob(tt.glob)
+			if err != nil {
+				t.Fatalf("compileGlob() error: %v", err)
+			}
+			if got := re.MatchString(tt.name); got != tt.match {
+				t.Errorf("match = %v, want %v", got, tt.match)
+			}
+		})
+	}
+}
+
+func TestCompileGlob_Invalid(t *testing.T) {
+	for _, glob := range []string{"[abc", "{a,b", "a}", "{a,{b}}"} {
+		if _, err := compileGlob(glob); err == nil {
+			t.Errorf("expected error for %q", glob)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package store
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+)
+
+// =============================================================================
+// Cloud Storage HMAC Key Operations
+// =============================================================================
+
+// hmacAccessIDAlphabet is the alphabet of generated access IDs.
+const hmacAccessIDAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// newHmacAccessID generates an access ID like the ones of service account keys, GOOG1E followed by
+// 55 uppercase letters and digits.
+func newHmacAccessID() string {
+	b := make([]byte, 55)
+	rand.Read(b)
+	for i := range b {
+		b[i] = hmacAccessIDAlphabet[int(b[i])%len(hmacAccessIDAlphabet)]
+	}
+	return "GOOG1E" + string(b)
+}
+
+// newHmacSecret generates a 40 character base64 secret, like the real API.
+func newHmacSecret() string {
+	b := make([]byte, 30)
+	rand.Read(b)
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+// CreateHmacKey creates an active HMAC key for a service account of a project.
+// Returns the key with its secret, which isn't returned again.
+func (s *Store) CreateHmacKey(project, serviceAccountEmail string) (*storage.HmacKey, error) {
+	if !strings.Contains(serviceAccountEmail, "@") {
+		return nil, fmt.Errorf("invalid service account email %q", serviceAccountEmail)
+	}
+
+	s.storageMu.Lock()
+	defer s.storageMu.Unlock()
+
+	now := s.now()
+	accessID := newHmacAccessID()
+	key := &storage.HmacKey{
+		Kind: "storage#hmacKey",
+		Metadata: &storage.HmacKeyMetadata{
+			Kind:                "storage#hmacKeyMetadata",
+			ID:                  project + "/" + accessID,
+			SelfLink:            fmt.Sprintf("%s/storage/v1/projects/%s/hmacKeys/%s", s.config().baseURL, project, accessID),
+			AccessID:            accessID,
+			ProjectID:           project,
+			ServiceAccountEmail: serviceAccountEmail,
+			State:               storage.HmacKeyActive,
+			TimeCreated:         now,
+			Updated:             now,
+			Etag:                generateEtag(),
+		},
+		Secret: newHmacSecret(),
+	}
+	s.hmacKeys[accessID] = key
+
+	return clone(key), nil
+}
+
+// GetHmacKey retrieves the metadata of an HMAC key of a project, including deleted keys.
+// Returns nil if the key doesn't exist.
+func (s *Store) GetHmacKey(project, accessID string) *storage.HmacKeyMetadata {
+	s.storageMu.RLock()
+	defer s.storageMu.RUnlock()
+
+	key, exists := s.hmacKeys[accessID]
+	if !exists || key.Metadata.ProjectID != project {
+		return nil
+	}
+	return clone(key.Metadata)
+}
+
+// ListHmacKeys returns the metadata of the HMAC keys of a project in creation order.
+// If serviceAccountEmail is set, only the keys of that service account are returned.
+// Deleted keys are only returned if showDeleted is set.
+func (s *Store) ListHmacKeys(project, serviceAccountEmail string, showDeleted bool) []*storage.HmacKeyMetadata {
+	s.storageMu.RLock()
+	defer s.storageMu.RUnlock()
+
+	keys := make([]*storage.HmacKeyMetadata, 0)
+	for _, key := range s.hmacKeys {
+		metadata := key.Metadata
+		if metadata.ProjectID != project ||
+			(serviceAccountEmail != "" && metadata.ServiceAccountEmail != serviceAccountEmail) ||
+			(!showDeleted && metadata.State == storage.HmacKeyDeleted) {
+			continue
+		}
+		keys = append(keys, metadata)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].TimeCreated.Equal(keys[j].TimeCreated) {
+			return keys[i].TimeCreated.Before(keys[j].TimeCreated)
+		}
+		return keys[i].AccessID < keys[j].AccessID
+	})
+
+	return clone(keys)
+}
+
+// UpdateHmacKey changes the state of an HMAC key to ACTIVE or INACTIVE. If req has an etag,
+// it must match the current one. Deleted keys can't be updated.
+func (s *Store) UpdateHmacKey(project, accessID string, req *storage.HmacKeyMetadata) (*storage.HmacKeyMetadata, error) {
+	s.storageMu.Lock()
+	defer s.storageMu.Unlock()
+
+	key, exists := s.hmacKeys[accessID]
+	if !exists || key.Metadata.ProjectID != project {
+		return nil, fmt.Errorf("hmac key %s not found", accessID)
+	}
+	metadata := key.Metadata
+
+	if req.State != storage.HmacKeyActive && req.State != storage.HmacKeyInactive {
+		return nil, fmt.Errorf("invalid state %q: must be %s or %s", req.State, storage.HmacKeyActive, storage.HmacKeyInactive)
+	}
+	if metadata.State == storage.HmacKeyDeleted {
+		return nil, fmt.Errorf("invalid request: deleted keys can't be updated")
+	}
+	if err := checkEtag(req.Etag, metadata.Etag); err != nil {
+		return nil, err
+	}
+
+	if metadata.State != req.State {
+		metadata.State = req.State
+		metadata.Updated = s.now()
+		metadata.Etag = generateEtag()
+	}
+
+	return clone(metadata), nil
+}
+
+// DeleteHmacKey deletes an inactive HMAC key. Like in the real API, deleted keys are kept in the DELETED state,
+// and active keys must be deactivated before they can be deleted.
+func (s *Store) DeleteHmacKey(project, accessID string) error {
+	s.storageMu.Lock()
+	defer s.storageMu.Unlock()
+
+	key, exists := s.hmacKeys[accessID]
+	if !exists || key.Metadata.ProjectID != project || key.Metadata.State == storage.HmacKeyDeleted {
+		return fmt.Errorf("hmac key %s not found", accessID)
+	}
+	metadata := key.Metadata
+
+	if metadata.State != storage.HmacKeyInactive {
+		return fmt.Errorf("invalid state: cannot delete keys in '%s' state", metadata.State)
+	}
+
+	metadata.State = storage.HmacKeyDeleted
+	metadata.Updated = s.now()
+	metadata.Etag = generateEtag()
+
+	return nil
+}
+
+// HmacKeySecret returns the secret of an HMAC key, so requests signed with it can be verified, and whether
+// the key is active. Returns false if no key has the access ID.
+func (s *Store) HmacKeySecret(accessID string) (secret string, active bool, ok bool) {
+	s.storageMu.RLock()
+	defer s.storageMu.RUnlock()
+
+	key, exists := s.hmacKeys[accessID]
+	if !exists {
+		return "", false, false
+	}
+	return key.Secret, key.Metadata.State == storage.HmacKeyActive, true
+}
@@ -0,0 +1,79 @@
+package store
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+)
+
+func TestStore_HmacKeys(t *testing.T) {
+	s := New()
+	const email = "sa@test-project.iam.gserviceaccount.com"
+
+	key, err := s.CreateHmacKey("test-project", email)
+	if err != nil {
+		t.Fatalf("CreateHmacKey() error: %v", err)
+	}
+	metadata := key.Metadata
+	if !strings.HasPrefix(metadata.AccessID, "GOOG1E") || len(metadata.AccessID) != 61 || len(key.Secret) != 40 {
+		t.Errorf("expected a plausible access ID and secret, got %q and %q", metadata.AccessID, key.Secret)
+	}
+	if metadata.ID != "test-project/"+metadata.AccessID || metadata.State != storage.HmacKeyActive || metadata.Etag == "" {
+		t.Errorf("unexpected metadata: %+v", metadata)
+	}
+	if _, err := s.CreateHmacKey("test-project", "not-an-email"); err == nil || !strings.Contains(err.Error(), "invalid") {
+		t.Errorf("expected invalid error, got %v", err)
+	}
+
+	other, _ := s.CreateHmacKey("test-project", "other@test-project.iam.gserviceaccount.com")
+	if keys := s.ListHmacKeys("test-project", "", false); len(keys) != 2 || keys[0].AccessID != metadata.AccessID {
+		t.Errorf("expected both keys in creation order, got %v", keys)
+	}
+	if keys := s.ListHmacKeys("test-project", email, false); len(keys) != 1 || keys[0].AccessID != metadata.AccessID {
+		t.Errorf("expected the key of the service account, got %v", keys)
+	}
+	if s.GetHmacKey("other-project", metadata.AccessID) != nil || len(s.ListHmacKeys("other-project", "", false)) != 0 {
+		t.Error("expected the keys to belong to their project only")
+	}
+
+	// Active keys can't be deleted, and stale etags are rejected
+	if err := s.DeleteHmacKey("test-project", metadata.AccessID); err == nil || !strings.Contains(err.Error(), "cannot delete keys in 'ACTIVE' state") {
+		t.Errorf("expected active keys to be kept, got %v", err)
+	}
+	if _, err := s.UpdateHmacKey("test-project", metadata.AccessID, &storage.HmacKeyMetadata{State: storage.HmacKeyInactive, Etag: "stale"}); err == nil ||
+		!strings.Contains(err.Error(), "precondition failed") {
+		t.Errorf("expected precondition failed error, got %v", err)
+	}
+	if _, err := s.UpdateHmacKey("test-project", metadata.AccessID, &storage.HmacKeyMetadata{State: storage.HmacKeyDeleted}); err == nil ||
+		!strings.Contains(err.Error(), "invalid") {
+		t.Errorf("expected invalid state error, got %v", err)
+	}
+	updated, err := s.UpdateHmacKey("test-project", metadata.AccessID, &storage.HmacKeyMetadata{State: storage.HmacKeyInactive, Etag: metadata.Etag})
+	if err != nil {
+		t.Fatalf("UpdateHmacKey() error: %v", err)
+	}
+	if updated.State != storage.HmacKeyInactive || updated.Etag == metadata.Etag {
+		t.Errorf("expected an inactive key with a new etag, got %+v", updated)
+	}
+	if _, active, ok := s.HmacKeySecret(metadata.AccessID); !ok || active {
+		t.Errorf("expected an inactive key, got active=%v ok=%v", active, ok)
+	}
+
+	// Deleted keys are kept in the DELETED state
+	if err := s.DeleteHmacKey("test-project", metadata.AccessID); err != nil {
+		t.Fatalf("DeleteHmacKey() error: %v", err)
+	}
+	if err := s.DeleteHmacKey("test-project", metadata.AccessID); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected not found error, got %v", err)
+	}
+	if keys := s.ListHmacKeys("test-project", "", false); len(keys) != 1 || keys[0].AccessID != other.Metadata.AccessID {
+		t.Errorf("expected deleted keys to be hidden, got %v", keys)
+	}
+	if keys := s.ListHmacKeys("test-project", "", true); len(keys) != 2 || keys[0].State != storage.HmacKeyDeleted {
+		t.Errorf("expected deleted keys with showDeleted, got %v", keys)
+	}
+	if _, err := s.UpdateHmacKey("test-project", metadata.AccessID, &storage.HmacKeyMetadata{State: storage.HmacKeyActive}); err == nil {
+		t.Error("expected deleted keys not to be updatable")
+	}
+}
@@ -0,0 +1,134 @@
+package store
+
+import (
+	"fmt"
+
+	"github.com/katharinasick/gcp-api-mock/internal/orgpolicy"
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+)
+
+// =============================================================================
+// Cloud Storage Bucket IAM Operations
+// =============================================================================
+
+// defaultPolicyEtag is the etag of the default IAM policy of a bucket, like in the real API.
+const defaultPolicyEtag = "CAE="
+
+// updateIamConfiguration returns the IAM configuration of a bucket after an update. The fields set in
+// requested replace those of current; like in the real API, the configuration always includes the
+// uniform bucket-level access and public access prevention settings.
+func updateIamConfiguration(current, requested *storage.IamConfiguration) *storage.IamConfiguration {
+	iam := &storage.IamConfiguration{
+		UniformBucketLevelAccess: &storage.UniformBucketLevelAccess{},
+		PublicAccessPrevention:   storage.PublicAccessPreventionInherited,
+	}
+	for _, update := range []*storage.IamConfiguration{current, requested} {
+		if update == nil {
+			continue
+		}
+		if update.UniformBucketLevelAccess != nil {
+			iam.UniformBucketLevelAccess = update.UniformBucketLevelAccess
+		}
+		switch update.PublicAccessPrevention {
+		case "":
+		case "unspecified":
+			iam.PublicAccessPrevention = storage.PublicAccessPreventionInherited
+		default:
+			iam.PublicAccessPrevention = update.PublicAccessPrevention
+		}
+	}
+	return iam
+}
+
+// publicAccessPreventionError returns the error for making a resource of a bucket with enforced
+// public access prevention public. Handlers answer it like a failed precondition, like the real API.
+func publicAccessPreventionError(bucketName string) error {
+	return fmt.Errorf("precondition failed: public access prevention is enforced on bucket %s, so it can't grant access to allUsers or allAuthenticatedUsers", bucketName)
+}
+
+// checkPublicAccessPrevention returns an error if public is true and the bucket, or the organization policy
+// constraint storage.publicAccessPrevention, enforces public access prevention.
+// Callers must hold the storage lock.
+func (s *Store) checkPublicAccessPrevention(bucket *storage.Bucket, public bool) error {
+	switch {
+	case !public:
+		return nil
+	case bucket.PublicAccessPreventionEnforced():
+		return publicAccessPreventionError(bucket.Name)
+	case s.orgPolicyEnforced(orgpolicy.ConstraintPublicAccessPrevention):
+		return fmt.Errorf("precondition failed: %w", s.orgPolicyError(orgpolicy.ConstraintPublicAccessPrevention,
+			"bucket "+bucket.Name+" can't grant access to allUsers or allAuthenticatedUsers"))
+	}
+	return nil
+}
+
+// checkUniformBucketLevelAccess returns an error if the IAM configuration of a bucket turns uniform bucket-level
+// access off while the organization policy constraint storage.uniformBucketLevelAccess requires it.
+func (s *Store) checkUniformBucketLevelAccess(bucketName string, iam *storage.IamConfiguration) error {
+	if iam.UniformBucketLevelAccess.Enabled || !s.orgPolicyEnforced(orgpolicy.ConstraintUniformBucketLevelAccess) {
+		return nil
+	}
+	return fmt.Errorf("precondition failed: %w", s.orgPolicyError(orgpolicy.ConstraintUniformBucketLevelAccess,
+		"bucket "+bucketName+" must have uniform bucket-level access enabled"))
+}
+
+// GetBucketIamPolicy returns the IAM policy of a bucket. Buckets whose policy was never set have the
+// default policy of new buckets.
+// Returns an error if the bucket doesn't exist.
+func (s *Store) GetBucketIamPolicy(bucketName string) (*storage.Policy, error) {
+	s.storageMu.RLock()
+	defer s.storageMu.RUnlock()
+
+	if _, exists := s.buckets[bucketName]; !exists {
+		return nil, fmt.Errorf("bucket %s not found", bucketName)
+	}
+
+	return s.bucketIamPolicy(bucketName), nil
+}
+
+// bucketIamPolicy returns a copy of the IAM policy of a bucket.
+// Callers must hold the storage lock.
+func (s *Store) bucketIamPolicy(bucketName string) *storage.Policy {
+	if policy, exists := s.bucketPolicies[bucketName]; exists {
+		return clone(policy)
+	}
+	policy := storage.DefaultBucketPolicy(bucketName, s.config().projectID)
+	policy.Etag = defaultPolicyEtag
+	return policy
+}
+
+// SetBucketIamPolicy replaces the IAM policy of a bucket. If the policy has an etag, it must match the
+// etag of the current policy, so concurrent read-modify-write cycles don't overwrite each other.
+// Returns an error if the bucket doesn't exist, the etag doesn't match or the policy grants public
+// access to a bucket with enforced public access prevention.
+func (s *Store) SetBucketIamPolicy(bucketName string, policy *storage.Policy) (*storage.Policy, error) {
+	s.storageMu.Lock()
+	defer s.storageMu.Unlock()
+
+	bucket, exists := s.buckets[bucketName]
+	if !exists {
+		return nil, fmt.Errorf("bucket %s not found", bucketName)
+	}
+
+	current := s.bucketIamPolicy(bucketName)
+	if policy.Etag != "" && policy.Etag != current.Etag {
+		return nil, fmt.Errorf("precondition failed: etag %s doesn't match the etag %s of the current policy", policy.Etag, current.Etag)
+	}
+	if err := s.checkPublicAccessPrevention(bucket, storage.IsPublicPolicy(policy)); err != nil {
+		return nil, err
+	}
+
+	updated := &storage.Policy{
+		Kind:       "storage#policy",
+		ResourceID: "projects/_/buckets/" + bucketName,
+		Version:    max(policy.Version, 1),
+		Bindings:   clone(policy.Bindings),
+		Etag:       generateEtag(),
+	}
+	if updated.Bindings == nil {
+		updated.Bindings = []storage.PolicyBinding{}
+	}
+	s.bucketPolicies[bucketName] = updated
+
+	return clone(updated), nil
+}
@@ -0,0 +1,90 @@
+package store
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+)
+
+func TestStore_BucketIamConfiguration(t *testing.T) {
+	s := New()
+	bucket, _ := s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+
+	// New buckets report their settings, like in the real API
+	iam := bucket.IamConfiguration
+	if iam == nil || iam.PublicAccessPrevention != storage.PublicAccessPreventionInherited || iam.UniformBucketLevelAccess == nil {
+		t.Fatalf("expected default IAM configuration, got %+v", iam)
+	}
+
+	// Patching one setting keeps the other
+	_, _ = s.PatchBucket("test-bucket", &storage.BucketPatchRequest{BucketUpdateRequest: storage.BucketUpdateRequest{
+		IamConfiguration: &storage.IamConfiguration{PublicAccessPrevention: storage.PublicAccessPreventionEnforced},
+	}})
+	bucket, _ = s.PatchBucket("test-bucket", &storage.BucketPatchRequest{BucketUpdateRequest: storage.BucketUpdateRequest{
+		IamConfiguration: &storage.IamConfiguration{UniformBucketLevelAccess: &storage.UniformBucketLevelAccess{Enabled: false}},
+	}})
+	if !bucket.PublicAccessPreventionEnforced() {
+		t.Errorf("expected public access prevention to stay enforced, got %+v", bucket.IamConfiguration)
+	}
+}
+
+func TestStore_BucketIamPolicy(t *testing.T) {
+	s := New()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+
+	policy, err := s.GetBucketIamPolicy("test-bucket")
+	if err != nil {
+		t.Fatalf("GetBucketIamPolicy() error: %v", err)
+	}
+	if len(policy.Bindings) != 2 || policy.Etag != defaultPolicyEtag {
+		t.Errorf("expected the default policy, got %+v", policy)
+	}
+
+	policy.Bindings = append(policy.Bindings, storage.PolicyBinding{Role: "roles/storage.objectViewer", Members: []string{"allUsers"}})
+	updated, err := s.SetBucketIamPolicy("test-bucket", policy)
+	if err != nil {
+		t.Fatalf("SetBucketIamPolicy() error: %v", err)
+	}
+	if len(updated.Bindings) != 3 || updated.Etag == policy.Etag {
+		t.Errorf("expected 3 bindings with a new etag, got %+v", updated)
+	}
+
+	// A policy read before the last change is rejected
+	if _, err := s.SetBucketIamPolicy("test-bucket", policy); err == nil || !strings.Contains(err.Error(), "precondition failed") {
+		t.Errorf("expected precondition failed error for a stale etag, got %v", err)
+	}
+	if _, err := s.GetBucketIamPolicy("missing-bucket"); err == nil {
+		t.Error("expected an error for a missing bucket")
+	}
+}
+
+func TestStore_PublicAccessPrevention(t *testing.T) {
+	s := New()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{
+		Name:             "test-bucket",
+		IamConfiguration: &storage.IamConfiguration{PublicAccessPrevention: storage.PublicAccessPreventionEnforced},
+	})
+	_, _ = s.CreateObject("test-bucket", "file.txt", "text/plain", []byte("x"), nil)
+
+	policy := &storage.Policy{Bindings: []storage.PolicyBinding{{Role: "roles/storage.objectViewer", Members: []string{"allAuthenticatedUsers"}}}}
+	if _, err := s.SetBucketIamPolicy("test-bucket", policy); err == nil || !strings.Contains(err.Error(), "public access prevention") {
+		t.Errorf("expected public access prevention error for the policy, got %v", err)
+	}
+
+	if _, err := s.CreateObjectWithOptions("test-bucket", "public.txt", "text/plain", bytes.NewReader(nil), nil, ObjectOptions{PredefinedACL: "publicRead"}); err == nil ||
+		!strings.Contains(err.Error(), "public access prevention") {
+		t.Errorf("expected public access prevention error for the upload, got %v", err)
+	}
+
+	if _, err := s.SetObjectACLEntry("test-bucket", "file.txt", storage.ObjectAccessControl{Entity: "allUsers", Role: storage.RoleReader}); err == nil ||
+		!strings.Contains(err.Error(), "public access prevention") {
+		t.Errorf("expected public access prevention error for the ACL entry, got %v", err)
+	}
+
+	// Access for specific users is still allowed
+	if _, err := s.SetObjectACLEntry("test-bucket", "file.txt", storage.ObjectAccessControl{Entity: "user-jane@example.com", Role: storage.RoleReader}); err != nil {
+		t.Errorf("SetObjectACLEntry() error: %v", err)
+	}
+}
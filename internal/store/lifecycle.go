@@ -0,0 +1,54 @@
+package store
+
+import (
+	"sort"
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+)
+
+// =============================================================================
+// Cloud Storage Object Lifecycle Management
+// =============================================================================
+
+// applyLifecycle takes the actions of the lifecycle rules of all buckets on their live objects at the given
+// time, like the lifecycle runs of Cloud Storage. Delete actions delete objects like objects.delete, so soft
+// delete policies and notifications apply, but skip objects under a hold or the retention policy of their
+// bucket. SetStorageClass actions are skipped in buckets with Autoclass, which manages the storage classes.
+// Noncurrent versions aren't kept, so conditions on them never match.
+// Callers must hold the storage write lock.
+func (s *Store) applyLifecycle(now time.Time) {
+	for bucketName, bucket := range s.buckets {
+		if bucket.Lifecycle == nil || len(bucket.Lifecycle.Rule) == 0 {
+			continue
+		}
+		autoclass := bucket.Autoclass != nil && bucket.Autoclass.Enabled
+
+		// Act in name order, so notifications and events are in a predictable order
+		bucketObjects := s.objects[bucketName]
+		names := make([]string, 0, len(bucketObjects))
+		for name := range bucketObjects {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			objData := bucketObjects[name]
+			obj := objData.Metadata
+			state := storage.LifecycleObjectState{Live: true, Held: obj.EventBasedHold || obj.TemporaryHold}
+			action := bucket.Lifecycle.Action(obj, state, now)
+			switch {
+			case action == nil:
+			case action.Type == storage.LifecycleActionDelete:
+				if s.checkObjectRetention(bucketName, obj, now, "deleted") == nil {
+					s.deleteObject(bucketName, objData, now)
+				}
+			case action.Type == storage.LifecycleActionSetStorageClass && !autoclass:
+				s.objectMetadataIndex.remove(objData)
+				obj.StorageClass, obj.TimeStorageClassUpdated = action.StorageClass, now
+				s.finishObjectMetadataUpdate(obj)
+				s.objectMetadataIndex.add(objData)
+			}
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+)
+
+func TestStore_Tick_Lifecycle(t *testing.T) {
+	s := New()
+	now := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	s.SetClock(func() time.Time { return now })
+	intPtr := func(n int) *int { return &n }
+
+	lifecycle := &storage.Lifecycle{Rule: []storage.LifecycleRule{
+		{Action: &storage.LifecycleAction{Type: storage.LifecycleActionDelete}, Condition: &storage.LifecycleCondition{Age: intPtr(30), MatchesPrefix: []string{"tmp/"}}},
+		{Action: &storage.LifecycleAction{Type: storage.LifecycleActionSetStorageClass, StorageClass: storage.StorageClassColdline}, Condition: &storage.LifecycleCondition{Age: intPtr(7)}},
+	}}
+	if _, err := s.CreateBucket(&storage.BucketInsertRequest{Name: "managed", Lifecycle: lifecycle}); err != nil {
+		t.Fatalf("CreateBucket() error: %v", err)
+	}
+	for _, name := range []string{"tmp/a.txt", "tmp/held.txt", "data/b.txt"} {
+		if _, err := s.CreateObject("managed", name, "text/plain", []byte(name), nil); err != nil {
+			t.Fatalf("CreateObject() error: %v", err)
+		}
+	}
+	hold := true
+	if _, err := s.PatchObject("managed", "tmp/held.txt", &storage.ObjectPatchRequest{TemporaryHold: &hold}, Preconditions{}); err != nil {
+		t.Fatalf("PatchObject() error: %v", err)
+	}
+
+	s.Tick()
+	if objects, _ := s.ListObjects("managed", "", ""); len(objects) != 3 || objects[0].StorageClass != storage.StorageClassStandard {
+		t.Fatalf("expected no rule to apply yet, got %+v", objects)
+	}
+
+	now = now.AddDate(0, 0, 7)
+	s.Tick()
+	obj := s.GetObject("managed", "data/b.txt")
+	if obj.StorageClass != storage.StorageClassColdline || !obj.TimeStorageClassUpdated.Equal(now) || obj.Metageneration != 2 {
+		t.Errorf("expected the storage class to change to COLDLINE now, got %s at %v (metageneration %d)", obj.StorageClass, obj.TimeStorageClassUpdated, obj.Metageneration)
+	}
+
+	now = now.AddDate(0, 0, 30)
+	s.Tick()
+	if s.GetObject("managed", "tmp/a.txt") != nil {
+		t.Error("expected tmp/a.txt to be deleted")
+	}
+	if obj := s.GetObject("managed", "tmp/held.txt"); obj == nil || obj.StorageClass != storage.StorageClassColdline {
+		t.Errorf("expected the held object to be kept and change its storage class, got %+v", obj)
+	}
+
+	// Once the hold is released, the next run deletes the object
+	hold = false
+	if _, err := s.PatchObject("managed", "tmp/held.txt", &storage.ObjectPatchRequest{TemporaryHold: &hold}, Preconditions{}); err != nil {
+		t.Fatalf("PatchObject() error: %v", err)
+	}
+	s.Tick()
+	if s.GetObject("managed", "tmp/held.txt") != nil {
+		t.Error("expected tmp/held.txt to be deleted once its hold is released")
+	}
+}
+
+func TestStore_Tick_LifecycleRetentionPolicy(t *testing.T) {
+	s := New()
+	now := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	s.SetClock(func() time.Time { return now })
+	age := 1
+
+	if _, err := s.CreateBucket(&storage.BucketInsertRequest{
+		Name:            "retained",
+		RetentionPolicy: &storage.RetentionPolicy{RetentionPeriod: 7 * 24 * 3600},
+		Lifecycle:       &storage.Lifecycle{Rule: []storage.LifecycleRule{{Action: &storage.LifecycleAction{Type: storage.LifecycleActionDelete}, Condition: &storage.LifecycleCondition{Age: &age}}}},
+	}); err != nil {
+		t.Fatalf("CreateBucket() error: %v", err)
+	}
+	if _, err := s.CreateObject("retained", "record.json", "application/json", []byte("{}"), nil); err != nil {
+		t.Fatalf("CreateObject() error: %v", err)
+	}
+
+	now = now.AddDate(0, 0, 1)
+	s.Tick()
+	if s.GetObject("retained", "record.json") == nil {
+		t.Error("expected the retention policy to keep the object from being deleted")
+	}
+
+	now = now.AddDate(0, 0, 6)
+	s.Tick()
+	if s.GetObject("retained", "record.json") != nil {
+		t.Error("expected the object to be deleted after the retention period")
+	}
+}
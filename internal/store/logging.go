@@ -0,0 +1,168 @@
+package store
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/katharinasick/gcp-api-mock/internal/logging"
+)
+
+// =============================================================================
+// Cloud Logging Operations
+// =============================================================================
+
+// maxLogEntries is the number of log entries kept; older entries are dropped when more are written.
+const maxLogEntries = 10000
+
+// logNamePattern matches log names like projects/{project}/logs/{logId}.
+// Log IDs are URL-encoded, e.g. cloudaudit.googleapis.com%2Factivity.
+var logNamePattern = regexp.MustCompile(`^(projects|organizations|folders|billingAccounts)/[^/]+/logs/[^/]+$`)
+
+// WriteLogEntries writes log entries. Entries without a log name, resource or labels take those of the request.
+// Invalid entries fail the whole request, unless PartialSuccess is set; then the valid entries are still
+// written and an error describing the invalid ones is returned. DryRun only validates the entries.
+func (s *Store) WriteLogEntries(req *logging.WriteLogEntriesRequest) error {
+	if len(req.Entries) == 0 {
+		return fmt.Errorf("invalid request: at least one log entry is required")
+	}
+
+	now := s.now()
+	entries := make([]*logging.LogEntry, 0, len(req.Entries))
+	var errs []string
+	for i, e := range req.Entries {
+		if e == nil {
+			errs = append(errs, fmt.Sprintf("entries[%d]: log entry is empty", i))
+			continue
+		}
+
+		entry := clone(e)
+		if entry.LogName == "" {
+			entry.LogName = req.LogName
+		}
+		if entry.Resource == nil {
+			entry.Resource = clone(req.Resource)
+		}
+		for key, value := range req.Labels {
+			if entry.Labels == nil {
+				entry.Labels = make(map[string]string)
+			}
+			if _, exists := entry.Labels[key]; !exists {
+				entry.Labels[key] = value
+			}
+		}
+
+		if err := validateLogEntry(entry); err != nil {
+			errs = append(errs, fmt.Sprintf("entries[%d]: %v", i, err))
+			continue
+		}
+		if entry.Timestamp.IsZero() {
+			entry.Timestamp = now
+		}
+		entry.ReceiveTimestamp = now
+		entries = append(entries, entry)
+	}
+
+	var err error
+	if len(errs) > 0 {
+		err = fmt.Errorf("invalid log entries: %s", strings.Join(errs, "; "))
+		if !req.PartialSuccess {
+			return err
+		}
+	}
+	if req.DryRun {
+		return err
+	}
+
+	s.loggingMu.Lock()
+	defer s.loggingMu.Unlock()
+
+	for _, entry := range entries {
+		if entry.InsertID == "" {
+			s.logEntrySeq++
+			entry.InsertID = fmt.Sprintf("%012d", s.logEntrySeq)
+		}
+	}
+	s.logEntries = append(s.logEntries, entries...)
+	if overflow := len(s.logEntries) - maxLogEntries; overflow > 0 {
+		s.logEntries = append([]*logging.LogEntry(nil), s.logEntries[overflow:]...)
+	}
+
+	return err
+}
+
+// ListLogEntries returns the log entries of the given resources (e.g. projects/{project}) matching filter,
+// sorted by timestamp, newest first if descending is set. Entries with equal timestamps are sorted by insert
+// ID, in the same direction; generated insert IDs follow the order the entries were received in, so the order
+// is stable across pages. Without resource names, the entries of all resources are returned.
+func (s *Store) ListLogEntries(resourceNames []string, filter *logging.Filter, descending bool) []*logging.LogEntry {
+	s.loggingMu.RLock()
+	defer s.loggingMu.RUnlock()
+
+	entries := make([]*logging.LogEntry, 0)
+	for _, entry := range s.logEntries {
+		if (len(resourceNames) == 0 || inLogResources(entry.LogName, resourceNames)) && filter.Matches(entry) {
+			entries = append(entries, entry)
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if descending {
+			a, b = b, a
+		}
+		switch {
+		case !a.Timestamp.Equal(b.Timestamp):
+			return a.Timestamp.Before(b.Timestamp)
+		case a.InsertID != b.InsertID:
+			return a.InsertID < b.InsertID
+		}
+		return a.LogName < b.LogName
+	})
+
+	return clone(entries)
+}
+
+// ListLogs returns the names of the logs of a resource (e.g. projects/{project}) that have entries, sorted.
+func (s *Store) ListLogs(resourceName string) []string {
+	s.loggingMu.RLock()
+	defer s.loggingMu.RUnlock()
+
+	seen := make(map[string]bool)
+	names := make([]string, 0)
+	for _, entry := range s.logEntries {
+		if inLogResources(entry.LogName, []string{resourceName}) && !seen[entry.LogName] {
+			seen[entry.LogName] = true
+			names = append(names, entry.LogName)
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// inLogResources reports whether a log belongs to one of the given resources.
+func inLogResources(logName string, resourceNames []string) bool {
+	for _, resourceName := range resourceNames {
+		if strings.HasPrefix(logName, strings.TrimSuffix(resourceName, "/")+"/logs/") {
+			return true
+		}
+	}
+	return false
+}
+
+// validateLogEntry checks that a log entry, after applying the defaults of the request, is complete.
+func validateLogEntry(entry *logging.LogEntry) error {
+	switch {
+	case entry.LogName == "":
+		return fmt.Errorf("logName is required")
+	case !logNamePattern.MatchString(entry.LogName):
+		return fmt.Errorf("invalid logName %q: must be like projects/{project}/logs/{logId}", entry.LogName)
+	case entry.Resource == nil || entry.Resource.Type == "":
+		return fmt.Errorf("resource.type is required")
+	case !logging.IsValidSeverity(entry.Severity):
+		return fmt.Errorf("invalid severity %q", entry.Severity)
+	}
+	return nil
+}
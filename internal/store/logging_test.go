@@ -0,0 +1,141 @@
+package store
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/logging"
+)
+
+func TestStore_WriteLogEntries(t *testing.T) {
+	s := New()
+	t0 := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	err := s.WriteLogEntries(&logging.WriteLogEntriesRequest{
+		LogName:  "projects/p/logs/app",
+		Resource: &logging.MonitoredResource{Type: "global"},
+		Labels:   map[string]string{"env": "dev"},
+		Entries: []*logging.LogEntry{
+			{TextPayload: "second", Timestamp: t0.Add(time.Second), Severity: "ERROR"},
+			{TextPayload: "first", Timestamp: t0, Labels: map[string]string{"env": "prod"}},
+			{TextPayload: "other log", LogName: "projects/p/logs/worker"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("WriteLogEntries() error: %v", err)
+	}
+	if err := s.WriteLogEntries(&logging.WriteLogEntriesRequest{
+		LogName:  "projects/other/logs/app",
+		Resource: &logging.MonitoredResource{Type: "global"},
+		Entries:  []*logging.LogEntry{{TextPayload: "other project"}},
+	}); err != nil {
+		t.Fatalf("WriteLogEntries() error: %v", err)
+	}
+
+	all, _ := logging.ParseFilter("")
+	entries := s.ListLogEntries([]string{"projects/p"}, all, false)
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries in project p, got %d", len(entries))
+	}
+	if entries[0].TextPayload != "first" || entries[1].TextPayload != "second" {
+		t.Errorf("expected entries sorted by timestamp, got %q, %q", entries[0].TextPayload, entries[1].TextPayload)
+	}
+	if entries[0].Labels["env"] != "prod" || entries[1].Labels["env"] != "dev" {
+		t.Errorf("expected request labels as defaults, got %v and %v", entries[0].Labels, entries[1].Labels)
+	}
+	if entries[2].Timestamp.IsZero() || entries[2].ReceiveTimestamp.IsZero() || entries[2].InsertID == "" {
+		t.Errorf("expected a default timestamp, receive timestamp and insert ID, got %+v", entries[2])
+	}
+
+	errors, _ := logging.ParseFilter(`severity >= ERROR`)
+	if entries := s.ListLogEntries([]string{"projects/p", "projects/other"}, errors, true); len(entries) != 1 || entries[0].TextPayload != "second" {
+		t.Errorf("expected only the ERROR entry, got %+v", entries)
+	}
+	if entries := s.ListLogEntries([]string{"projects/p", "projects/other"}, all, true); len(entries) != 4 || entries[len(entries)-1].TextPayload != "first" {
+		t.Errorf("expected 4 entries, oldest last, got %d", len(entries))
+	}
+
+	logs := s.ListLogs("projects/p")
+	if strings.Join(logs, ",") != "projects/p/logs/app,projects/p/logs/worker" {
+		t.Errorf("ListLogs() = %v", logs)
+	}
+}
+
+func TestStore_WriteLogEntries_Invalid(t *testing.T) {
+	resource := &logging.MonitoredResource{Type: "global"}
+
+	tests := []struct {
+		name string
+		req  *logging.WriteLogEntriesRequest
+		want string
+	}{
+		{"no entries", &logging.WriteLogEntriesRequest{LogName: "projects/p/logs/app", Resource: resource}, "at least one"},
+		{"no log name", &logging.WriteLogEntriesRequest{Resource: resource, Entries: []*logging.LogEntry{{}}}, "logName is required"},
+		{"invalid log name", &logging.WriteLogEntriesRequest{LogName: "app", Resource: resource, Entries: []*logging.LogEntry{{}}}, "invalid logName"},
+		{"no resource", &logging.WriteLogEntriesRequest{LogName: "projects/p/logs/app", Entries: []*logging.LogEntry{{}}}, "resource.type is required"},
+		{"invalid severity", &logging.WriteLogEntriesRequest{LogName: "projects/p/logs/app", Resource: resource, Entries: []*logging.LogEntry{{Severity: "FATAL"}}}, "invalid severity"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+			err := s.WriteLogEntries(tt.req)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("WriteLogEntries() error = %v, want it to contain %q", err, tt.want)
+			}
+			if logs := s.ListLogs("projects/p"); len(logs) != 0 {
+				t.Errorf("expected nothing written, got %v", logs)
+			}
+		})
+	}
+}
+
+func TestStore_WriteLogEntries_PartialSuccessAndDryRun(t *testing.T) {
+	s := New()
+	all, _ := logging.ParseFilter("")
+	req := &logging.WriteLogEntriesRequest{
+		LogName:  "projects/p/logs/app",
+		Resource: &logging.MonitoredResource{Type: "global"},
+		Entries:  []*logging.LogEntry{{TextPayload: "ok"}, {Severity: "FATAL"}},
+	}
+
+	req.DryRun, req.PartialSuccess = true, true
+	if err := s.WriteLogEntries(req); err == nil {
+		t.Error("expected an error for the invalid entry")
+	}
+	if entries := s.ListLogEntries([]string{"projects/p"}, all, false); len(entries) != 0 {
+		t.Fatalf("expected a dry run to write nothing, got %d entries", len(entries))
+	}
+
+	req.DryRun = false
+	if err := s.WriteLogEntries(req); err == nil || !strings.Contains(err.Error(), "entries[1]") {
+		t.Errorf("expected an error for entries[1], got %v", err)
+	}
+	if entries := s.ListLogEntries([]string{"projects/p"}, all, false); len(entries) != 1 || entries[0].TextPayload != "ok" {
+		t.Errorf("expected the valid entry to be written, got %+v", entries)
+	}
+}
+
+func TestStore_WriteLogEntries_Capped(t *testing.T) {
+	s := New()
+	entries := make([]*logging.LogEntry, maxLogEntries+5)
+	for i := range entries {
+		entries[i] = &logging.LogEntry{}
+	}
+	entries[5].TextPayload = "oldest kept"
+
+	if err := s.WriteLogEntries(&logging.WriteLogEntriesRequest{
+		LogName:  "projects/p/logs/app",
+		Resource: &logging.MonitoredResource{Type: "global"},
+		Entries:  entries,
+	}); err != nil {
+		t.Fatalf("WriteLogEntries() error: %v", err)
+	}
+
+	all, _ := logging.ParseFilter("")
+	listed := s.ListLogEntries([]string{"projects/p"}, all, false)
+	if len(listed) != maxLogEntries || listed[0].TextPayload != "oldest kept" {
+		t.Errorf("expected the %d newest entries, got %d starting with %q", maxLogEntries, len(listed), listed[0].TextPayload)
+	}
+}
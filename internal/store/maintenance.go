@@ -0,0 +1,219 @@
+package store
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/sqladmin"
+)
+
+// defaultSQLMaintenanceDuration is how long maintenance started with StartSQLMaintenance takes
+// if neither the request nor the configuration set a duration.
+const defaultSQLMaintenanceDuration = 10 * time.Minute
+
+// sqlMaintenance is the maintenance state of a Cloud SQL instance.
+type sqlMaintenance struct {
+	// Last is the start of the last maintenance, so a maintenance window is used only once.
+	Last time.Time `json:"last"`
+	// Operation is the name of the running MAINTENANCE operation, or empty if no maintenance is running.
+	Operation string `json:"operation,omitempty"`
+	// EndsAt is the time the running maintenance completes.
+	EndsAt time.Time `json:"endsAt,omitzero"`
+}
+
+// SetSQLMaintenanceDuration enables scheduled maintenance: when the clock reaches the maintenance window
+// of a RUNNABLE instance, and the window isn't in one of its deny maintenance periods, the instance is in
+// MAINTENANCE for the given duration, with a RUNNING operation of type MAINTENANCE. 0 disables it.
+func (s *Store) SetSQLMaintenanceDuration(duration time.Duration) {
+	s.configure(func(cfg *storeConfig) {
+		cfg.sqlMaintenanceDuration = duration
+	})
+}
+
+// updateSQLState applies the current time to Cloud SQL state: delayed creates complete and maintenance
+// starts and ends.
+// Must be called with the write lock held.
+func (s *Store) updateSQLState() {
+	s.completeSQLCreates()
+	s.updateSQLMaintenance()
+}
+
+// updateSQLMaintenance completes maintenance that has ended and, if scheduled maintenance is enabled,
+// starts it for instances whose maintenance window has been reached. If the clock skipped a whole
+// maintenance, e.g. because it was advanced by days, the maintenance is recorded as done.
+// Must be called with the write lock held.
+func (s *Store) updateSQLMaintenance() {
+	now := s.now()
+	for name, m := range s.sqlMaintenance {
+		if m.Operation != "" && !now.Before(m.EndsAt) {
+			s.finishSQLMaintenance(name, m)
+		}
+	}
+
+	duration := s.config().sqlMaintenanceDuration
+	if duration <= 0 {
+		return
+	}
+	for name, instance := range s.sqlInstances {
+		if instance.State != "RUNNABLE" || instance.Settings == nil || instance.Settings.MaintenanceWindow == nil {
+			continue
+		}
+		start, ok := lastMaintenanceWindow(instance.Settings.MaintenanceWindow, now)
+		if !ok || start.Before(instance.CreateTime) {
+			continue
+		}
+		if m := s.sqlMaintenance[name]; m != nil && !start.After(m.Last) {
+			continue
+		}
+		if inDenyMaintenancePeriod(instance.Settings.DenyMaintenancePeriods, start) {
+			continue
+		}
+
+		m := s.startSQLMaintenance(instance, start, duration)
+		if !now.Before(m.EndsAt) {
+			s.finishSQLMaintenance(name, m)
+		}
+	}
+}
+
+// startSQLMaintenance puts an instance into MAINTENANCE from start on and creates a RUNNING MAINTENANCE
+// operation for it.
+// Must be called with the write lock held.
+func (s *Store) startSQLMaintenance(instance *sqladmin.DatabaseInstance, start time.Time, duration time.Duration) *sqlMaintenance {
+	op := s.createOperation("MAINTENANCE", instance.Name, start)
+	op.Status = "RUNNING"
+	op.EndTime = time.Time{}
+
+	instance.State = "MAINTENANCE"
+	instance.Etag = generateEtag()
+	s.publish(Event{Type: EventSQLInstanceUpdated, Instance: instance})
+
+	m := &sqlMaintenance{Last: start, Operation: op.Name, EndsAt: start.Add(duration)}
+	s.sqlMaintenance[instance.Name] = m
+	return m
+}
+
+// finishSQLMaintenance completes the running maintenance of an instance: its operation is DONE and
+// the instance is RUNNABLE again, unless its state was changed in the meantime.
+// Must be called with the write lock held.
+func (s *Store) finishSQLMaintenance(name string, m *sqlMaintenance) {
+	if op, ok := s.sqlOperations[m.Operation]; ok {
+		op.Status = "DONE"
+		op.EndTime = m.EndsAt
+	}
+	if instance, ok := s.sqlInstances[name]; ok && instance.State == "MAINTENANCE" {
+		instance.State = "RUNNABLE"
+		instance.Etag = generateEtag()
+		s.publish(Event{Type: EventSQLInstanceUpdated, Instance: instance})
+	}
+	m.Operation = ""
+	m.EndsAt = time.Time{}
+}
+
+// StartSQLMaintenance puts a RUNNABLE Cloud SQL instance into MAINTENANCE right away, regardless of its
+// maintenance window, and returns the RUNNING MAINTENANCE operation. The maintenance takes the given duration,
+// or the configured one if it is 0. Unless force is set, instances in a deny maintenance period are rejected.
+// Returns an error if the instance doesn't exist, isn't RUNNABLE or is in a deny maintenance period.
+func (s *Store) StartSQLMaintenance(name string, duration time.Duration, force bool) (*sqladmin.Operation, error) {
+	s.sqlMu.Lock()
+	defer s.sqlMu.Unlock()
+
+	s.updateSQLState()
+
+	instance, exists := s.sqlInstances[name]
+	if !exists {
+		return nil, fmt.Errorf("instance %s not found", name)
+	}
+
+	if err := checkSQLInstanceState(instance, "RUNNABLE"); err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	if !force && instance.Settings != nil && inDenyMaintenancePeriod(instance.Settings.DenyMaintenancePeriods, now) {
+		return nil, fmt.Errorf("invalid request: instance %s is in a deny maintenance period", name)
+	}
+
+	if duration <= 0 {
+		duration = s.config().sqlMaintenanceDuration
+	}
+	if duration <= 0 {
+		duration = defaultSQLMaintenanceDuration
+	}
+
+	m := s.startSQLMaintenance(instance, now, duration)
+	return clone(s.sqlOperations[m.Operation]), nil
+}
+
+// lastMaintenanceWindow returns the start of the latest maintenance window at or before now.
+// Windows start at the full hour in UTC, on the day of the week from 1 (Monday) to 7 (Sunday),
+// or every day if the day is 0. Returns false if the window is invalid.
+func lastMaintenanceWindow(window *sqladmin.MaintenanceWindow, now time.Time) (time.Time, bool) {
+	if window.Day < 0 || window.Day > 7 || window.Hour < 0 || window.Hour > 23 {
+		return time.Time{}, false
+	}
+
+	now = now.UTC()
+	start := time.Date(now.Year(), now.Month(), now.Day(), int(window.Hour), 0, 0, 0, time.UTC)
+	if start.After(now) {
+		start = start.AddDate(0, 0, -1)
+	}
+	if window.Day == 0 {
+		return start, true
+	}
+	// time.Weekday starts with Sunday as 0, Cloud SQL with Monday as 1
+	for int(start.Weekday()+6)%7+1 != int(window.Day) {
+		start = start.AddDate(0, 0, -1)
+	}
+	return start, true
+}
+
+// inDenyMaintenancePeriod reports whether t is in one of the deny maintenance periods.
+// Periods start on their start date and end on their end date, both at their time in UTC.
+// Dates without a year, like "12-24", recur every year.
+func inDenyMaintenancePeriod(periods []*sqladmin.DenyMaintenancePeriod, t time.Time) bool {
+	t = t.UTC()
+	for _, period := range periods {
+		// A recurring period that started last year may not have ended yet
+		for _, year := range []int{t.Year() - 1, t.Year()} {
+			start, ok := denyMaintenanceTime(period.StartDate, period.Time, year)
+			if !ok {
+				break
+			}
+			end, ok := denyMaintenanceTime(period.EndDate, period.Time, year)
+			if !ok {
+				break
+			}
+			if end.Before(start) {
+				end = end.AddDate(1, 0, 0)
+			}
+			if !t.Before(start) && !t.After(end) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// denyMaintenanceTime returns the time of a deny maintenance period date, like "2025-12-24" or "12-24",
+// and time of day, like "00:00:00". Dates without a year are in the given year.
+// Returns false if the date or time is invalid.
+func denyMaintenanceTime(date, timeOfDay string, year int) (time.Time, bool) {
+	day, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		recurring, err := time.Parse("01-02", date)
+		if err != nil {
+			return time.Time{}, false
+		}
+		day = time.Date(year, recurring.Month(), recurring.Day(), 0, 0, 0, 0, time.UTC)
+	}
+
+	if timeOfDay == "" {
+		return day, true
+	}
+	clock, err := time.Parse("15:04:05", timeOfDay)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return day.Add(clock.Sub(time.Date(0, 1, 1, 0, 0, 0, 0, time.UTC))), true
+}
@@ -0,0 +1,220 @@
+package store
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/sqladmin"
+)
+
+func TestLastMaintenanceWindow(t *testing.T) {
+	// A Wednesday
+	now := time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		window sqladmin.MaintenanceWindow
+		want   time.Time
+		wantOK bool
+	}{
+		{"this hour", sqladmin.MaintenanceWindow{Day: 3, Hour: 10}, time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC), true},
+		{"later today", sqladmin.MaintenanceWindow{Day: 3, Hour: 11}, time.Date(2025, 1, 8, 11, 0, 0, 0, time.UTC), true},
+		{"monday", sqladmin.MaintenanceWindow{Day: 1, Hour: 2}, time.Date(2025, 1, 13, 2, 0, 0, 0, time.UTC), true},
+		{"sunday", sqladmin.MaintenanceWindow{Day: 7, Hour: 0}, time.Date(2025, 1, 12, 0, 0, 0, 0, time.UTC), true},
+		{"any day", sqladmin.MaintenanceWindow{Hour: 12}, time.Date(2025, 1, 14, 12, 0, 0, 0, time.UTC), true},
+		{"invalid day", sqladmin.MaintenanceWindow{Day: 8}, time.Time{}, false},
+		{"invalid hour", sqladmin.MaintenanceWindow{Day: 1, Hour: 24}, time.Time{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := lastMaintenanceWindow(&tt.window, now)
+			if ok != tt.wantOK || !got.Equal(tt.want) {
+				t.Errorf("lastMaintenanceWindow() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestInDenyMaintenancePeriod(t *testing.T) {
+	periods := []*sqladmin.DenyMaintenancePeriod{
+		{StartDate: "2025-03-01", EndDate: "2025-03-10", Time: "12:00:00"},
+		{StartDate: "12-20", EndDate: "01-05", Time: "00:00:00"},
+	}
+
+	tests := []struct {
+		name string
+		t    time.Time
+		want bool
+	}{
+		{"before the period", time.Date(2025, 3, 1, 11, 59, 0, 0, time.UTC), false},
+		{"start of the period", time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC), true},
+		{"in the period", time.Date(2025, 3, 5, 0, 0, 0, 0, time.UTC), true},
+		{"after the period", time.Date(2025, 3, 10, 12, 1, 0, 0, time.UTC), false},
+		{"recurring period before new year", time.Date(2026, 12, 24, 0, 0, 0, 0, time.UTC), true},
+		{"recurring period after new year", time.Date(2027, 1, 2, 0, 0, 0, 0, time.UTC), true},
+		{"outside the recurring period", time.Date(2026, 1, 6, 0, 0, 0, 0, time.UTC), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := inDenyMaintenancePeriod(periods, tt.t); got != tt.want {
+				t.Errorf("inDenyMaintenancePeriod() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// maintenanceOperations returns the MAINTENANCE operations of an instance, newest first.
+func maintenanceOperations(s *Store, instance string) []*sqladmin.Operation {
+	var ops []*sqladmin.Operation
+	for _, op := range s.ListSQLOperations(instance) {
+		if op.OperationType == "MAINTENANCE" {
+			ops = append(ops, op)
+		}
+	}
+	return ops
+}
+
+func TestStore_SQLMaintenance_Scheduled(t *testing.T) {
+	s := New()
+	// A Wednesday, an hour before the maintenance window
+	now := time.Date(2025, 1, 15, 9, 0, 0, 0, time.UTC)
+	s.SetClock(func() time.Time { return now })
+	s.SetSQLMaintenanceDuration(10 * time.Minute)
+
+	_, _, err := s.CreateSQLInstance(&sqladmin.InstanceInsertRequest{
+		Name:     "maintained",
+		Settings: &sqladmin.Settings{MaintenanceWindow: &sqladmin.MaintenanceWindow{Day: 3, Hour: 10}},
+	})
+	if err != nil {
+		t.Fatalf("CreateSQLInstance() error: %v", err)
+	}
+	if got := s.GetSQLInstance("maintained").State; got != "RUNNABLE" {
+		t.Errorf("expected state RUNNABLE before the window, got %s", got)
+	}
+
+	// In MAINTENANCE with a running operation within the window
+	now = now.Add(65 * time.Minute)
+	if got := s.GetSQLInstance("maintained").State; got != "MAINTENANCE" {
+		t.Errorf("expected state MAINTENANCE in the window, got %s", got)
+	}
+	ops := maintenanceOperations(s, "maintained")
+	if len(ops) != 1 || ops[0].Status != "RUNNING" || !ops[0].StartTime.Equal(time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected a RUNNING maintenance operation started at 10:00, got %+v", ops)
+	}
+
+	// Runnable again and done once the maintenance duration has passed
+	now = now.Add(5 * time.Minute)
+	s.Tick()
+	if got := s.GetSQLInstance("maintained").State; got != "RUNNABLE" {
+		t.Errorf("expected state RUNNABLE after the maintenance, got %s", got)
+	}
+	if got := s.GetSQLOperation(ops[0].Name); got.Status != "DONE" || !got.EndTime.Equal(time.Date(2025, 1, 15, 10, 10, 0, 0, time.UTC)) {
+		t.Errorf("expected the operation DONE at 10:10, got %s at %v", got.Status, got.EndTime)
+	}
+
+	// A window is used only once
+	now = now.Add(time.Minute)
+	if got := len(maintenanceOperations(s, "maintained")); got != 1 {
+		t.Errorf("expected 1 maintenance operation, got %d", got)
+	}
+
+	// Skipped windows are recorded as done maintenance
+	now = now.Add(7 * 24 * time.Hour)
+	if got := s.GetSQLInstance("maintained").State; got != "RUNNABLE" {
+		t.Errorf("expected state RUNNABLE after a skipped window, got %s", got)
+	}
+	ops = maintenanceOperations(s, "maintained")
+	if len(ops) != 2 || ops[0].Status != "DONE" {
+		t.Errorf("expected a second, DONE maintenance operation, got %+v", ops)
+	}
+}
+
+func TestStore_SQLMaintenance_DenyPeriod(t *testing.T) {
+	s := New()
+	now := time.Date(2025, 1, 15, 9, 0, 0, 0, time.UTC)
+	s.SetClock(func() time.Time { return now })
+	s.SetSQLMaintenanceDuration(10 * time.Minute)
+
+	s.CreateSQLInstance(&sqladmin.InstanceInsertRequest{
+		Name: "frozen",
+		Settings: &sqladmin.Settings{
+			MaintenanceWindow:      &sqladmin.MaintenanceWindow{Day: 3, Hour: 10},
+			DenyMaintenancePeriods: []*sqladmin.DenyMaintenancePeriod{{StartDate: "2025-01-01", EndDate: "2025-01-31"}},
+		},
+	})
+
+	now = now.Add(65 * time.Minute)
+	if got := s.GetSQLInstance("frozen").State; got != "RUNNABLE" {
+		t.Errorf("expected state RUNNABLE in a deny maintenance period, got %s", got)
+	}
+	if ops := maintenanceOperations(s, "frozen"); len(ops) != 0 {
+		t.Errorf("expected no maintenance operations, got %+v", ops)
+	}
+
+	_, err := s.StartSQLMaintenance("frozen", 0, false)
+	if err == nil || !strings.Contains(err.Error(), "invalid") {
+		t.Errorf("expected an invalid request error, got %v", err)
+	}
+	if _, err := s.StartSQLMaintenance("frozen", 0, true); err != nil {
+		t.Errorf("expected forced maintenance to start, got %v", err)
+	}
+}
+
+func TestStore_SQLMaintenance_Disabled(t *testing.T) {
+	s := New()
+	now := time.Date(2025, 1, 15, 9, 0, 0, 0, time.UTC)
+	s.SetClock(func() time.Time { return now })
+
+	s.CreateSQLInstance(&sqladmin.InstanceInsertRequest{
+		Name:     "unmaintained",
+		Settings: &sqladmin.Settings{MaintenanceWindow: &sqladmin.MaintenanceWindow{Day: 3, Hour: 10}},
+	})
+
+	now = now.Add(65 * time.Minute)
+	if got := s.GetSQLInstance("unmaintained").State; got != "RUNNABLE" {
+		t.Errorf("expected state RUNNABLE without scheduled maintenance, got %s", got)
+	}
+}
+
+func TestStore_StartSQLMaintenance(t *testing.T) {
+	s := New()
+	now := time.Date(2025, 1, 15, 9, 0, 0, 0, time.UTC)
+	s.SetClock(func() time.Time { return now })
+
+	if _, err := s.StartSQLMaintenance("missing", 0, false); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+
+	s.CreateSQLInstance(&sqladmin.InstanceInsertRequest{Name: "main"})
+
+	op, err := s.StartSQLMaintenance("main", 0, false)
+	if err != nil {
+		t.Fatalf("StartSQLMaintenance() error: %v", err)
+	}
+	if op.OperationType != "MAINTENANCE" || op.Status != "RUNNING" {
+		t.Errorf("expected a RUNNING MAINTENANCE operation, got %s %s", op.OperationType, op.Status)
+	}
+	if got := s.GetSQLInstance("main").State; got != "MAINTENANCE" {
+		t.Errorf("expected state MAINTENANCE, got %s", got)
+	}
+
+	// Instances in maintenance can't be maintained or restarted
+	if _, err := s.StartSQLMaintenance("main", 0, false); err == nil || !strings.Contains(err.Error(), "not in an appropriate state") {
+		t.Errorf("expected an invalid state error, got %v", err)
+	}
+	if _, err := s.RestartSQLInstance("main"); err == nil {
+		t.Error("expected restarting an instance in maintenance to fail")
+	}
+
+	// The default duration applies without a configured one
+	now = now.Add(defaultSQLMaintenanceDuration)
+	if got := s.GetSQLInstance("main").State; got != "RUNNABLE" {
+		t.Errorf("expected state RUNNABLE after the maintenance, got %s", got)
+	}
+	if got := s.GetSQLOperation(op.Name).Status; got != "DONE" {
+		t.Errorf("expected the operation DONE, got %s", got)
+	}
+}
@@ -0,0 +1,295 @@
+package store
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/katharinasick/gcp-api-mock/internal/longrunning"
+	"github.com/katharinasick/gcp-api-mock/internal/memorystore"
+)
+
+// =============================================================================
+// Memorystore for Redis Instance Operations
+// =============================================================================
+
+// redisInstanceIDPattern matches valid instance IDs: lowercase letters, digits and hyphens, starting with a letter
+// and not ending with a hyphen, at most 40 characters.
+var redisInstanceIDPattern = regexp.MustCompile(`^[a-z]([a-z0-9-]{0,38}[a-z0-9])?$`)
+
+// redisVersions are the supported Redis versions.
+var redisVersions = []string{"REDIS_3_2", "REDIS_4_0", "REDIS_5_0", "REDIS_6_X", "REDIS_7_0", "REDIS_7_2"}
+
+// redisDefaultVersion is the Redis version of instances that don't set one.
+const redisDefaultVersion = "REDIS_7_0"
+
+// redisInstanceFields are the fields of an instance that can be changed with instances.patch.
+var redisInstanceFields = []string{"displayName", "labels", "memorySizeGb", "redisConfigs", "replicaCount"}
+
+// Default ports of Redis instances, without and with in-transit encryption.
+const (
+	redisPort    = 6379
+	redisTLSPort = 6378
+)
+
+// splitRedisInstanceName splits an instance name like projects/{project}/locations/{location}/instances/{instance}
+// into its parent (projects/{project}/locations/{location}) and instance ID.
+func splitRedisInstanceName(name string) (string, string) {
+	parent, instanceID, found := strings.Cut(name, "/instances/")
+	if !found {
+		return "", ""
+	}
+	return parent, instanceID
+}
+
+// SetRedisEndpoint sets the address (host:port) of a Redis server that all Memorystore instances report as
+// their host and port, so clients can connect to it. Without it, instances get addresses from their IP range.
+func (s *Store) SetRedisEndpoint(addr string) {
+	s.configure(func(cfg *storeConfig) {
+		cfg.redisEndpoint = addr
+	})
+}
+
+// CreateRedisInstance creates an instance below parent (projects/{project}/locations/{location}).
+// The instance is READY right away and the returned operation is already done.
+func (s *Store) CreateRedisInstance(parent, instanceID string, req *memorystore.Instance) (*memorystore.Operation, error) {
+	s.redisMu.Lock()
+	defer s.redisMu.Unlock()
+
+	if !redisInstanceIDPattern.MatchString(instanceID) {
+		return nil, fmt.Errorf("invalid instance ID %q: must start with a lowercase letter, contain only lowercase letters, digits and hyphens and be at most 40 characters long", instanceID)
+	}
+	name := parent + "/instances/" + instanceID
+	if _, exists := s.redisInstances[name]; exists {
+		return nil, fmt.Errorf("instance %s already exists", name)
+	}
+
+	instance := clone(req)
+	instance.Name = name
+	if err := validateRedisInstance(instance); err != nil {
+		return nil, err
+	}
+
+	project, location := parseRedisParent(parent)
+	if instance.RedisVersion == "" {
+		instance.RedisVersion = redisDefaultVersion
+	}
+	if instance.LocationId == "" {
+		instance.LocationId = location + "-a"
+	}
+	if instance.Tier == memorystore.TierStandardHA && instance.AlternativeLocationId == "" {
+		instance.AlternativeLocationId = location + "-b"
+	}
+	if instance.AuthorizedNetwork == "" {
+		instance.AuthorizedNetwork = "projects/" + project + "/global/networks/default"
+	}
+	if instance.ConnectMode == "" {
+		instance.ConnectMode = "DIRECT_PEERING"
+	}
+	if instance.TransitEncryptionMode == "" {
+		instance.TransitEncryptionMode = "DISABLED"
+	}
+	if instance.ReservedIpRange == "" {
+		// Hand out consecutive /29 ranges, like the ranges Memorystore picks in an empty network
+		offset := s.redisRangeSeq * 8
+		instance.ReservedIpRange = fmt.Sprintf("10.%d.%d.%d/29", offset>>16&255, offset>>8&255, offset&255)
+		s.redisRangeSeq++
+	}
+	instance.CurrentLocationId = instance.LocationId
+	instance.CreateTime = s.now()
+	instance.State = memorystore.StateReady
+	instance.Host, instance.Port = s.redisAddress(instance)
+	s.redisInstances[name] = instance
+
+	op, err := s.createRedisOperation(name, "create", memorystore.InstanceTypeURL, instance)
+	return clone(op), err
+}
+
+// GetRedisInstance retrieves an instance by name.
+// Returns nil if the instance doesn't exist.
+func (s *Store) GetRedisInstance(name string) *memorystore.Instance {
+	s.redisMu.RLock()
+	defer s.redisMu.RUnlock()
+
+	return clone(s.redisInstances[name])
+}
+
+// ListRedisInstances returns all instances below parent, sorted by name.
+// The location "-" lists the instances of all locations of the project.
+func (s *Store) ListRedisInstances(parent string) []*memorystore.Instance {
+	s.redisMu.RLock()
+	defer s.redisMu.RUnlock()
+
+	project, location := parseRedisParent(parent)
+	instances := make([]*memorystore.Instance, 0)
+	for name, instance := range s.redisInstances {
+		instanceParent, _ := splitRedisInstanceName(name)
+		instanceProject, instanceLocation := parseRedisParent(instanceParent)
+		if instanceProject == project && (location == "-" || instanceLocation == location) {
+			instances = append(instances, instance)
+		}
+	}
+
+	sort.Slice(instances, func(i, j int) bool {
+		return instances[i].Name < instances[j].Name
+	})
+
+	return clone(instances)
+}
+
+// UpdateRedisInstance changes the fields of an instance named by updateMask to their values in req.
+// The returned operation is already done.
+func (s *Store) UpdateRedisInstance(name string, req *memorystore.Instance, updateMask []string) (*memorystore.Operation, error) {
+	s.redisMu.Lock()
+	defer s.redisMu.Unlock()
+
+	existing, exists := s.redisInstances[name]
+	if !exists {
+		return nil, fmt.Errorf("instance %s not found", name)
+	}
+	if len(updateMask) == 0 {
+		return nil, fmt.Errorf("invalid update mask: at least one of %s is required", strings.Join(redisInstanceFields, ", "))
+	}
+
+	instance := clone(existing)
+	for _, field := range updateMask {
+		switch field {
+		case "displayName", "display_name":
+			instance.DisplayName = req.DisplayName
+		case "labels":
+			instance.Labels = clone(req.Labels)
+		case "memorySizeGb", "memory_size_gb":
+			instance.MemorySizeGb = req.MemorySizeGb
+		case "redisConfigs", "redis_configs":
+			instance.RedisConfigs = clone(req.RedisConfigs)
+		case "replicaCount", "replica_count":
+			instance.ReplicaCount = req.ReplicaCount
+		default:
+			return nil, fmt.Errorf("invalid update mask field %q: must be one of %s", field, strings.Join(redisInstanceFields, ", "))
+		}
+	}
+	if err := validateRedisInstance(instance); err != nil {
+		return nil, err
+	}
+	s.redisInstances[name] = instance
+
+	op, err := s.createRedisOperation(name, "update", memorystore.InstanceTypeURL, instance)
+	return clone(op), err
+}
+
+// DeleteRedisInstance deletes an instance. The returned operation is already done.
+func (s *Store) DeleteRedisInstance(name string) (*memorystore.Operation, error) {
+	s.redisMu.Lock()
+	defer s.redisMu.Unlock()
+
+	if _, exists := s.redisInstances[name]; !exists {
+		return nil, fmt.Errorf("instance %s not found", name)
+	}
+	delete(s.redisInstances, name)
+
+	op, err := s.createRedisOperation(name, "delete", memorystore.EmptyTypeURL, struct{}{})
+	return clone(op), err
+}
+
+// validateRedisInstance validates the settings of an instance.
+func validateRedisInstance(instance *memorystore.Instance) error {
+	if instance.Tier != memorystore.TierBasic && instance.Tier != memorystore.TierStandardHA {
+		return fmt.Errorf("invalid tier %q: must be %s or %s", instance.Tier, memorystore.TierBasic, memorystore.TierStandardHA)
+	}
+	if instance.MemorySizeGb < 1 || instance.MemorySizeGb > 300 {
+		return fmt.Errorf("invalid memorySizeGb %d: must be between 1 and 300", instance.MemorySizeGb)
+	}
+	if instance.RedisVersion != "" && !slices.Contains(redisVersions, instance.RedisVersion) {
+		return fmt.Errorf("invalid redisVersion %q: must be one of %s", instance.RedisVersion, strings.Join(redisVersions, ", "))
+	}
+	if instance.ReplicaCount < 0 || instance.ReplicaCount > 5 || instance.ReplicaCount > 0 && instance.Tier != memorystore.TierStandardHA {
+		return fmt.Errorf("invalid replicaCount %d: must be between 0 and 5, and 0 for %s instances", instance.ReplicaCount, memorystore.TierBasic)
+	}
+	if instance.ReservedIpRange != "" {
+		if _, _, err := net.ParseCIDR(instance.ReservedIpRange); err != nil {
+			return fmt.Errorf("invalid reservedIpRange %q: must be a CIDR range like 10.0.0.0/29", instance.ReservedIpRange)
+		}
+	}
+	return nil
+}
+
+// redisAddress returns the host and port of an instance: the configured Redis endpoint if there is one,
+// otherwise the third address of its IP range and the Redis port.
+// Must be called with the lock held.
+func (s *Store) redisAddress(instance *memorystore.Instance) (string, int) {
+	if endpoint := s.config().redisEndpoint; endpoint != "" {
+		host, port, err := net.SplitHostPort(endpoint)
+		if err == nil {
+			if portNumber, err := strconv.Atoi(port); err == nil {
+				return host, portNumber
+			}
+		}
+	}
+
+	port := redisPort
+	if instance.TransitEncryptionMode == "SERVER_AUTHENTICATION" {
+		port = redisTLSPort
+	}
+	ip, _, err := net.ParseCIDR(instance.ReservedIpRange)
+	if ip4 := ip.To4(); err == nil && ip4 != nil {
+		ip4[3] += 3
+		return ip4.String(), port
+	}
+	return "10.0.0.3", port
+}
+
+// parseRedisParent returns the project and location of a parent like projects/{project}/locations/{location}.
+func parseRedisParent(parent string) (string, string) {
+	rest, _ := strings.CutPrefix(parent, "projects/")
+	project, location, _ := strings.Cut(rest, "/locations/")
+	return project, location
+}
+
+// =============================================================================
+// Memorystore for Redis Operation Operations
+// =============================================================================
+
+// createRedisOperation creates and stores a completed operation for a change of an instance.
+// verb is create, update or delete, and response the result of the operation with its type URL.
+// Must be called with the lock held.
+func (s *Store) createRedisOperation(target, verb, typeURL string, response any) (*memorystore.Operation, error) {
+	now := s.now()
+	metadata, err := longrunning.NewAny(memorystore.OperationMetadataTypeURL, map[string]any{
+		"createTime": now,
+		"endTime":    now,
+		"target":     target,
+		"verb":       verb,
+		"apiVersion": "v1",
+	})
+	if err != nil {
+		return nil, err
+	}
+	responseAny, err := longrunning.NewAny(typeURL, response)
+	if err != nil {
+		return nil, err
+	}
+
+	parent, _ := splitRedisInstanceName(target)
+	op := &memorystore.Operation{
+		Name:     parent + "/operations/operation-" + newUUID(),
+		Metadata: metadata,
+		Done:     true,
+		Response: responseAny,
+	}
+	s.redisOperations[op.Name] = op
+
+	return op, nil
+}
+
+// GetRedisOperation retrieves an operation by name.
+// Returns nil if the operation doesn't exist.
+func (s *Store) GetRedisOperation(name string) *memorystore.Operation {
+	s.redisMu.RLock()
+	defer s.redisMu.RUnlock()
+
+	return clone(s.redisOperations[name])
+}
@@ -0,0 +1,150 @@
+package store
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/katharinasick/gcp-api-mock/internal/memorystore"
+)
+
+const testRedisParent = "projects/test-project/locations/europe-west1"
+
+func TestStore_CreateRedisInstance(t *testing.T) {
+	s := New()
+
+	op, err := s.CreateRedisInstance(testRedisParent, "cache", &memorystore.Instance{Tier: memorystore.TierStandardHA, MemorySizeGb: 1})
+	if err != nil {
+		t.Fatalf("CreateRedisInstance() error: %v", err)
+	}
+	var response memorystore.Instance
+	if err := json.Unmarshal(op.Response, &response); err != nil || !op.Done || response.Name != testRedisParent+"/instances/cache" {
+		t.Errorf("expected a done operation with the instance, got %+v", op)
+	}
+	if s.GetRedisOperation(op.Name) == nil {
+		t.Error("expected the operation to be stored")
+	}
+
+	instance := s.GetRedisInstance(testRedisParent + "/instances/cache")
+	if instance == nil {
+		t.Fatal("expected the instance to exist")
+	}
+	if instance.State != memorystore.StateReady || instance.RedisVersion != "REDIS_7_0" ||
+		instance.LocationId != "europe-west1-a" || instance.AlternativeLocationId != "europe-west1-b" ||
+		instance.AuthorizedNetwork != "projects/test-project/global/networks/default" {
+		t.Errorf("unexpected defaults: %+v", instance)
+	}
+	if instance.ReservedIpRange != "10.0.0.0/29" || instance.Host != "10.0.0.3" || instance.Port != 6379 {
+		t.Errorf("unexpected address: %s, %s:%d", instance.ReservedIpRange, instance.Host, instance.Port)
+	}
+
+	// Each instance gets its own range
+	if _, err := s.CreateRedisInstance(testRedisParent, "sessions", &memorystore.Instance{
+		Tier: memorystore.TierBasic, MemorySizeGb: 1, TransitEncryptionMode: "SERVER_AUTHENTICATION",
+	}); err != nil {
+		t.Fatalf("CreateRedisInstance() error: %v", err)
+	}
+	if instance := s.GetRedisInstance(testRedisParent + "/instances/sessions"); instance.Host != "10.0.0.11" || instance.Port != 6378 {
+		t.Errorf("unexpected address: %s:%d", instance.Host, instance.Port)
+	}
+
+	invalid := []struct {
+		id       string
+		instance *memorystore.Instance
+	}{
+		{"cache", &memorystore.Instance{Tier: memorystore.TierBasic, MemorySizeGb: 1}},
+		{"Invalid_ID", &memorystore.Instance{Tier: memorystore.TierBasic, MemorySizeGb: 1}},
+		{"no-tier", &memorystore.Instance{MemorySizeGb: 1}},
+		{"too-large", &memorystore.Instance{Tier: memorystore.TierBasic, MemorySizeGb: 301}},
+		{"unknown-version", &memorystore.Instance{Tier: memorystore.TierBasic, MemorySizeGb: 1, RedisVersion: "REDIS_8_0"}},
+		{"basic-replicas", &memorystore.Instance{Tier: memorystore.TierBasic, MemorySizeGb: 1, ReplicaCount: 1}},
+		{"bad-range", &memorystore.Instance{Tier: memorystore.TierBasic, MemorySizeGb: 1, ReservedIpRange: "10.0.0.0"}},
+	}
+	for _, tt := range invalid {
+		if _, err := s.CreateRedisInstance(testRedisParent, tt.id, tt.instance); err == nil {
+			t.Errorf("expected an error for instance %q", tt.id)
+		}
+	}
+}
+
+func TestStore_CreateRedisInstance_Endpoint(t *testing.T) {
+	s := New()
+	s.SetRedisEndpoint("localhost:16379")
+
+	if _, err := s.CreateRedisInstance(testRedisParent, "cache", &memorystore.Instance{Tier: memorystore.TierBasic, MemorySizeGb: 1}); err != nil {
+		t.Fatalf("CreateRedisInstance() error: %v", err)
+	}
+	if instance := s.GetRedisInstance(testRedisParent + "/instances/cache"); instance.Host != "localhost" || instance.Port != 16379 {
+		t.Errorf("expected the configured endpoint, got %s:%d", instance.Host, instance.Port)
+	}
+}
+
+func TestStore_ListRedisInstances(t *testing.T) {
+	s := New()
+
+	for _, parent := range []string{testRedisParent, "projects/test-project/locations/us-central1", "projects/other-project/locations/europe-west1"} {
+		if _, err := s.CreateRedisInstance(parent, "cache", &memorystore.Instance{Tier: memorystore.TierBasic, MemorySizeGb: 1}); err != nil {
+			t.Fatalf("CreateRedisInstance() error: %v", err)
+		}
+	}
+
+	if instances := s.ListRedisInstances(testRedisParent); len(instances) != 1 {
+		t.Errorf("expected 1 instance in the location, got %d", len(instances))
+	}
+	if instances := s.ListRedisInstances("projects/test-project/locations/-"); len(instances) != 2 {
+		t.Errorf("expected 2 instances in all locations, got %d", len(instances))
+	}
+}
+
+func TestStore_UpdateRedisInstance(t *testing.T) {
+	s := New()
+	name := testRedisParent + "/instances/cache"
+
+	if _, err := s.CreateRedisInstance(testRedisParent, "cache", &memorystore.Instance{Tier: memorystore.TierBasic, MemorySizeGb: 1, DisplayName: "Cache"}); err != nil {
+		t.Fatalf("CreateRedisInstance() error: %v", err)
+	}
+
+	if _, err := s.UpdateRedisInstance(name, &memorystore.Instance{MemorySizeGb: 5, DisplayName: "ignored"}, []string{"memorySizeGb"}); err != nil {
+		t.Fatalf("UpdateRedisInstance() error: %v", err)
+	}
+	if instance := s.GetRedisInstance(name); instance.MemorySizeGb != 5 || instance.DisplayName != "Cache" {
+		t.Errorf("expected only the masked field to change, got %+v", instance)
+	}
+
+	invalid := []struct {
+		name       string
+		updateMask []string
+		instance   *memorystore.Instance
+	}{
+		{"empty mask", nil, &memorystore.Instance{}},
+		{"unknown field", []string{"tier"}, &memorystore.Instance{Tier: memorystore.TierStandardHA}},
+		{"invalid value", []string{"memorySizeGb"}, &memorystore.Instance{}},
+	}
+	for _, tt := range invalid {
+		if _, err := s.UpdateRedisInstance(name, tt.instance, tt.updateMask); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+	if _, err := s.UpdateRedisInstance(testRedisParent+"/instances/missing", &memorystore.Instance{}, []string{"displayName"}); err == nil {
+		t.Error("expected an error for a missing instance")
+	}
+}
+
+func TestStore_DeleteRedisInstance(t *testing.T) {
+	s := New()
+	name := testRedisParent + "/instances/cache"
+
+	if _, err := s.CreateRedisInstance(testRedisParent, "cache", &memorystore.Instance{Tier: memorystore.TierBasic, MemorySizeGb: 1}); err != nil {
+		t.Fatalf("CreateRedisInstance() error: %v", err)
+	}
+
+	op, err := s.DeleteRedisInstance(name)
+	if err != nil {
+		t.Fatalf("DeleteRedisInstance() error: %v", err)
+	}
+	if !op.Done || s.GetRedisInstance(name) != nil {
+		t.Errorf("expected the instance to be deleted, got %+v", op)
+	}
+	if _, err := s.DeleteRedisInstance(name); err == nil {
+		t.Error("expected an error deleting a missing instance")
+	}
+}
@@ -0,0 +1,322 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/monitoring"
+)
+
+// =============================================================================
+// Cloud Monitoring Operations
+// =============================================================================
+
+// maxTimeSeriesPerRequest is the number of time series timeSeries.create accepts per request.
+const maxTimeSeriesPerRequest = 200
+
+// userMetricPrefixes are the prefixes of the metric types that can be written to.
+// Descriptors of these types are created on the first write if they don't exist.
+var userMetricPrefixes = []string{
+	"custom.googleapis.com/",
+	"external.googleapis.com/",
+	"prometheus.googleapis.com/",
+	"workload.googleapis.com/",
+}
+
+// metricDescriptorName returns the resource name of the descriptor of a metric type in a project.
+func metricDescriptorName(project, metricType string) string {
+	return "projects/" + project + "/metricDescriptors/" + metricType
+}
+
+// TimeSeriesKey identifies a time series by its metric and resource; ListTimeSeries sorts by it.
+// Maps are encoded with sorted keys, so equal label sets produce equal keys.
+func TimeSeriesKey(ts *monitoring.TimeSeries) string {
+	key, _ := json.Marshal([]any{ts.Metric, ts.Resource})
+	return string(key)
+}
+
+// CreateMetricDescriptor creates or replaces the descriptor of a metric type in a project.
+func (s *Store) CreateMetricDescriptor(project string, descriptor *monitoring.MetricDescriptor) (*monitoring.MetricDescriptor, error) {
+	if descriptor.Type == "" {
+		return nil, fmt.Errorf("invalid metric descriptor: type is required")
+	}
+	if !isUserMetricType(descriptor.Type) {
+		return nil, fmt.Errorf("invalid metric type %q: must start with one of %s", descriptor.Type, strings.Join(userMetricPrefixes, ", "))
+	}
+	switch descriptor.MetricKind {
+	case monitoring.MetricKindGauge, monitoring.MetricKindDelta, monitoring.MetricKindCumulative:
+	default:
+		return nil, fmt.Errorf("invalid metric kind %q", descriptor.MetricKind)
+	}
+	switch descriptor.ValueType {
+	case monitoring.ValueTypeBool, monitoring.ValueTypeInt64, monitoring.ValueTypeDouble, monitoring.ValueTypeString, monitoring.ValueTypeDistribution:
+	default:
+		return nil, fmt.Errorf("invalid value type %q", descriptor.ValueType)
+	}
+
+	s.monitoringMu.Lock()
+	defer s.monitoringMu.Unlock()
+
+	d := clone(descriptor)
+	d.Name = metricDescriptorName(project, d.Type)
+	s.metricDescriptors[d.Name] = d
+
+	return clone(d), nil
+}
+
+// GetMetricDescriptor retrieves a metric descriptor by name.
+// Returns nil if the descriptor doesn't exist.
+func (s *Store) GetMetricDescriptor(name string) *monitoring.MetricDescriptor {
+	s.monitoringMu.RLock()
+	defer s.monitoringMu.RUnlock()
+
+	return clone(s.metricDescriptors[name])
+}
+
+// ListMetricDescriptors returns the metric descriptors of a project matching filter, sorted by type.
+func (s *Store) ListMetricDescriptors(project string, filter *monitoring.Filter) []*monitoring.MetricDescriptor {
+	s.monitoringMu.RLock()
+	defer s.monitoringMu.RUnlock()
+
+	prefix := metricDescriptorName(project, "")
+	descriptors := make([]*monitoring.MetricDescriptor, 0)
+	for name, descriptor := range s.metricDescriptors {
+		if strings.HasPrefix(name, prefix) && filter.MatchesDescriptor(descriptor) {
+			descriptors = append(descriptors, descriptor)
+		}
+	}
+
+	sort.Slice(descriptors, func(i, j int) bool {
+		return descriptors[i].Type < descriptors[j].Type
+	})
+
+	return clone(descriptors)
+}
+
+// DeleteMetricDescriptor deletes a metric descriptor along with the time series written for it.
+func (s *Store) DeleteMetricDescriptor(name string) error {
+	s.monitoringMu.Lock()
+	defer s.monitoringMu.Unlock()
+
+	descriptor, exists := s.metricDescriptors[name]
+	if !exists {
+		return fmt.Errorf("metric descriptor %s not found", name)
+	}
+
+	project, _, _ := strings.Cut(strings.TrimPrefix(name, "projects/"), "/")
+	for key, ts := range s.timeSeries[project] {
+		if ts.Metric.Type == descriptor.Type {
+			delete(s.timeSeries[project], key)
+		}
+	}
+	delete(s.metricDescriptors, name)
+
+	return nil
+}
+
+// CreateTimeSeries writes one point to each of the given time series of a project.
+// Descriptors of metric types without one are created from the first point written.
+// Like Cloud Monitoring, points of a time series must be written in order; the request is
+// validated as a whole, so either all points are written or none.
+func (s *Store) CreateTimeSeries(project string, series []*monitoring.TimeSeries) error {
+	if len(series) == 0 {
+		return fmt.Errorf("invalid request: at least one TimeSeries is required")
+	}
+	if len(series) > maxTimeSeriesPerRequest {
+		return fmt.Errorf("invalid request: at most %d TimeSeries can be written per request, got %d", maxTimeSeriesPerRequest, len(series))
+	}
+
+	s.monitoringMu.Lock()
+	defer s.monitoringMu.Unlock()
+
+	keys := make([]string, len(series))
+	seen := make(map[string]bool, len(series))
+	descriptors := make(map[string]*monitoring.MetricDescriptor)
+	for i, ts := range series {
+		if err := validateTimeSeries(ts); err != nil {
+			return fmt.Errorf("invalid timeSeries[%d]: %w", i, err)
+		}
+
+		keys[i] = TimeSeriesKey(ts)
+		if seen[keys[i]] {
+			return fmt.Errorf("invalid timeSeries[%d]: duplicate TimeSeries encountered; only one point can be written per TimeSeries per request", i)
+		}
+		seen[keys[i]] = true
+
+		name := metricDescriptorName(project, ts.Metric.Type)
+		descriptor := descriptors[name]
+		if descriptor == nil {
+			descriptor = s.metricDescriptors[name]
+		}
+		if descriptor == nil {
+			if !isUserMetricType(ts.Metric.Type) {
+				return fmt.Errorf("invalid timeSeries[%d]: metric type %q is not a user-defined metric type", i, ts.Metric.Type)
+			}
+			descriptor = newMetricDescriptor(name, ts)
+			descriptors[name] = descriptor
+		}
+		if err := checkPointType(ts, descriptor); err != nil {
+			return fmt.Errorf("invalid timeSeries[%d]: %w", i, err)
+		}
+
+		if existing := s.timeSeries[project][keys[i]]; existing != nil {
+			last := existing.Points[0].Interval.EndTime
+			if !ts.Points[0].Interval.EndTime.After(last) {
+				return fmt.Errorf("invalid timeSeries[%d]: points must be written in order; "+
+					"one or more of the points specified had an older end time than the most recent point", i)
+			}
+		}
+	}
+
+	for name, descriptor := range descriptors {
+		s.metricDescriptors[name] = descriptor
+	}
+	if s.timeSeries[project] == nil {
+		s.timeSeries[project] = make(map[string]*monitoring.TimeSeries)
+	}
+	for i, ts := range series {
+		descriptor := s.metricDescriptors[metricDescriptorName(project, ts.Metric.Type)]
+		point := clone(ts.Points[0])
+
+		existing := s.timeSeries[project][keys[i]]
+		if existing == nil {
+			existing = &monitoring.TimeSeries{
+				Metric:     clone(ts.Metric),
+				Resource:   clone(ts.Resource),
+				MetricKind: descriptor.MetricKind,
+				ValueType:  descriptor.ValueType,
+				Unit:       descriptor.Unit,
+			}
+			s.timeSeries[project][keys[i]] = existing
+		}
+		// Points are kept newest first, the order timeSeries.list returns them in
+		existing.Points = append([]*monitoring.Point{point}, existing.Points...)
+	}
+
+	return nil
+}
+
+// ListTimeSeries returns the time series of a project matching filter with the points whose end time
+// lies in (start, end], newest first. Time series without points in the interval are omitted.
+// If headersOnly is set, the points are left out.
+func (s *Store) ListTimeSeries(project string, filter *monitoring.Filter, start, end time.Time, headersOnly bool) []*monitoring.TimeSeries {
+	s.monitoringMu.RLock()
+	defer s.monitoringMu.RUnlock()
+
+	keys := make([]string, 0)
+	for key, ts := range s.timeSeries[project] {
+		if filter.Matches(ts) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	series := make([]*monitoring.TimeSeries, 0)
+	for _, key := range keys {
+		ts := s.timeSeries[project][key]
+
+		var points []*monitoring.Point
+		for _, point := range ts.Points {
+			endTime := point.Interval.EndTime
+			if endTime.After(start) && !endTime.After(end) {
+				points = append(points, point)
+			}
+		}
+		if len(points) == 0 {
+			continue
+		}
+
+		listed := *ts
+		listed.Points = points
+		if headersOnly {
+			listed.Points = nil
+		}
+		series = append(series, &listed)
+	}
+
+	return clone(series)
+}
+
+// isUserMetricType reports whether a metric type is user-defined and can be written to.
+func isUserMetricType(metricType string) bool {
+	for _, prefix := range userMetricPrefixes {
+		if strings.HasPrefix(metricType, prefix) && len(metricType) > len(prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateTimeSeries checks that a time series written by a client is complete.
+func validateTimeSeries(ts *monitoring.TimeSeries) error {
+	switch {
+	case ts == nil:
+		return fmt.Errorf("time series is empty")
+	case ts.Metric == nil || ts.Metric.Type == "":
+		return fmt.Errorf("metric.type is required")
+	case ts.Resource == nil || ts.Resource.Type == "":
+		return fmt.Errorf("resource.type is required")
+	case len(ts.Points) != 1:
+		return fmt.Errorf("exactly one point must be written per time series, got %d", len(ts.Points))
+	}
+
+	point := ts.Points[0]
+	switch {
+	case point == nil || point.Interval == nil || point.Interval.EndTime.IsZero():
+		return fmt.Errorf("point interval.endTime is required")
+	case point.Value.ValueType() == "":
+		return fmt.Errorf("point value is required")
+	}
+	return nil
+}
+
+// checkPointType checks the kind and value type of a time series against the descriptor of its metric.
+func checkPointType(ts *monitoring.TimeSeries, descriptor *monitoring.MetricDescriptor) error {
+	if ts.MetricKind != "" && ts.MetricKind != descriptor.MetricKind {
+		return fmt.Errorf("metric kind %s does not match the kind %s of metric %s", ts.MetricKind, descriptor.MetricKind, descriptor.Type)
+	}
+	if valueType := ts.Points[0].Value.ValueType(); valueType != descriptor.ValueType {
+		return fmt.Errorf("value type %s does not match the type %s of metric %s", valueType, descriptor.ValueType, descriptor.Type)
+	}
+
+	interval := ts.Points[0].Interval
+	if descriptor.MetricKind == monitoring.MetricKindGauge {
+		if interval.StartTime != nil && !interval.StartTime.Equal(interval.EndTime) {
+			return fmt.Errorf("the start time of GAUGE points must equal their end time")
+		}
+		return nil
+	}
+	if interval.StartTime == nil || !interval.StartTime.Before(interval.EndTime) {
+		return fmt.Errorf("%s points need a start time before their end time", descriptor.MetricKind)
+	}
+	return nil
+}
+
+// newMetricDescriptor creates the descriptor of a metric from the first time series written for it.
+// The kind defaults to GAUGE and the value type is taken from the point.
+func newMetricDescriptor(name string, ts *monitoring.TimeSeries) *monitoring.MetricDescriptor {
+	descriptor := &monitoring.MetricDescriptor{
+		Name:       name,
+		Type:       ts.Metric.Type,
+		MetricKind: ts.MetricKind,
+		ValueType:  ts.Points[0].Value.ValueType(),
+		Unit:       ts.Unit,
+	}
+	if descriptor.MetricKind == "" {
+		descriptor.MetricKind = monitoring.MetricKindGauge
+	}
+
+	keys := make([]string, 0, len(ts.Metric.Labels))
+	for key := range ts.Metric.Labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		descriptor.Labels = append(descriptor.Labels, &monitoring.LabelDescriptor{Key: key, ValueType: monitoring.ValueTypeString})
+	}
+
+	return descriptor
+}
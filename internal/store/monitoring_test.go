@@ -0,0 +1,136 @@
+package store
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/monitoring"
+)
+
+// testGaugePoint creates a time series with a single INT64 gauge point.
+func testGaugePoint(metricType, job string, value int64, endTime time.Time) *monitoring.TimeSeries {
+	v := monitoring.Int64(value)
+	return &monitoring.TimeSeries{
+		Metric:   &monitoring.Metric{Type: metricType, Labels: map[string]string{"status": "200"}},
+		Resource: &monitoring.MonitoredResource{Type: "generic_task", Labels: map[string]string{"job": job}},
+		Points: []*monitoring.Point{{
+			Interval: &monitoring.TimeInterval{EndTime: endTime},
+			Value:    &monitoring.TypedValue{Int64Value: &v},
+		}},
+	}
+}
+
+func TestStore_CreateTimeSeries(t *testing.T) {
+	s := New()
+	const metricType = "custom.googleapis.com/requests"
+	t0 := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	if err := s.CreateTimeSeries("p", []*monitoring.TimeSeries{
+		testGaugePoint(metricType, "api", 1, t0),
+		testGaugePoint(metricType, "worker", 5, t0),
+	}); err != nil {
+		t.Fatalf("CreateTimeSeries() error: %v", err)
+	}
+	if err := s.CreateTimeSeries("p", []*monitoring.TimeSeries{testGaugePoint(metricType, "api", 2, t0.Add(time.Minute))}); err != nil {
+		t.Fatalf("CreateTimeSeries() error: %v", err)
+	}
+
+	// The descriptor is created from the first write
+	descriptor := s.GetMetricDescriptor("projects/p/metricDescriptors/" + metricType)
+	if descriptor == nil || descriptor.MetricKind != monitoring.MetricKindGauge || descriptor.ValueType != monitoring.ValueTypeInt64 {
+		t.Fatalf("expected an INT64 GAUGE descriptor, got %+v", descriptor)
+	}
+
+	filter, _ := monitoring.ParseFilter(`metric.type = "` + metricType + `" AND resource.labels.job = "api"`)
+	series := s.ListTimeSeries("p", filter, t0.Add(-time.Hour), t0.Add(time.Hour), false)
+	if len(series) != 1 || len(series[0].Points) != 2 {
+		t.Fatalf("expected 1 series with 2 points, got %+v", series)
+	}
+	if *series[0].Points[0].Value.Int64Value != 2 {
+		t.Errorf("expected the newest point first, got %d", *series[0].Points[0].Value.Int64Value)
+	}
+
+	// Only points in the interval are returned, and series without any are left out
+	all, _ := monitoring.ParseFilter(`metric.type = "` + metricType + `"`)
+	series = s.ListTimeSeries("p", all, t0, t0.Add(time.Hour), false)
+	if len(series) != 1 || len(series[0].Points) != 1 {
+		t.Errorf("expected only the api series' second point, got %+v", series)
+	}
+
+	// Series are kept per project
+	if series := s.ListTimeSeries("other", all, t0.Add(-time.Hour), t0.Add(time.Hour), false); len(series) != 0 {
+		t.Errorf("expected no series in another project, got %d", len(series))
+	}
+}
+
+func TestStore_CreateTimeSeries_Invalid(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	const metricType = "custom.googleapis.com/requests"
+
+	double := 1.5
+	wrongType := testGaugePoint(metricType, "api", 1, t0.Add(time.Hour))
+	wrongType.Points[0].Value = &monitoring.TypedValue{DoubleValue: &double}
+
+	tests := []struct {
+		name   string
+		series []*monitoring.TimeSeries
+		want   string
+	}{
+		{"no series", nil, "at least one"},
+		{"built-in metric", []*monitoring.TimeSeries{testGaugePoint("compute.googleapis.com/instance/uptime", "api", 1, t0)}, "not a user-defined"},
+		{"duplicate series", []*monitoring.TimeSeries{testGaugePoint(metricType, "api", 1, t0.Add(time.Hour)), testGaugePoint(metricType, "api", 2, t0.Add(time.Hour))}, "duplicate"},
+		{"out of order", []*monitoring.TimeSeries{testGaugePoint(metricType, "api", 1, t0)}, "in order"},
+		{"value type mismatch", []*monitoring.TimeSeries{wrongType}, "does not match"},
+		{"missing resource", []*monitoring.TimeSeries{{Metric: &monitoring.Metric{Type: metricType}}}, "resource.type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+			if err := s.CreateTimeSeries("p", []*monitoring.TimeSeries{testGaugePoint(metricType, "api", 1, t0)}); err != nil {
+				t.Fatalf("CreateTimeSeries() error: %v", err)
+			}
+
+			err := s.CreateTimeSeries("p", tt.series)
+			if err == nil || !strings.Contains(err.Error(), "invalid") || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an invalid error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestStore_MetricDescriptors(t *testing.T) {
+	s := New()
+	const metricType = "custom.googleapis.com/queue_depth"
+
+	if _, err := s.CreateMetricDescriptor("p", &monitoring.MetricDescriptor{Type: metricType, MetricKind: "GAUGE", ValueType: "DOUBLE"}); err != nil {
+		t.Fatalf("CreateMetricDescriptor() error: %v", err)
+	}
+	if _, err := s.CreateMetricDescriptor("p", &monitoring.MetricDescriptor{Type: "custom.googleapis.com/x", MetricKind: "SUM", ValueType: "DOUBLE"}); err == nil {
+		t.Error("expected error for an invalid metric kind")
+	}
+
+	double := 3.0
+	ts := testGaugePoint(metricType, "api", 0, time.Now())
+	ts.Points[0].Value = &monitoring.TypedValue{DoubleValue: &double}
+	if err := s.CreateTimeSeries("p", []*monitoring.TimeSeries{ts}); err != nil {
+		t.Fatalf("CreateTimeSeries() error: %v", err)
+	}
+
+	all, _ := monitoring.ParseFilter("")
+	if descriptors := s.ListMetricDescriptors("p", all); len(descriptors) != 1 || descriptors[0].Type != metricType {
+		t.Errorf("expected the created descriptor, got %+v", descriptors)
+	}
+
+	// Deleting the descriptor deletes its data
+	if err := s.DeleteMetricDescriptor("projects/p/metricDescriptors/" + metricType); err != nil {
+		t.Fatalf("DeleteMetricDescriptor() error: %v", err)
+	}
+	if series := s.ListTimeSeries("p", all, time.Time{}, time.Now().Add(time.Hour), false); len(series) != 0 {
+		t.Errorf("expected the series to be deleted, got %d", len(series))
+	}
+	if err := s.DeleteMetricDescriptor("projects/p/metricDescriptors/" + metricType); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected not found error, got %v", err)
+	}
+}
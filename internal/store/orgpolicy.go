@@ -0,0 +1,183 @@
+package store
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/katharinasick/gcp-api-mock/internal/orgpolicy"
+)
+
+// =============================================================================
+// Organization Policy Operations
+// =============================================================================
+
+// orgPolicyNamePattern matches policy names like projects/my-project/policies/storage.publicAccessPrevention.
+var orgPolicyNamePattern = regexp.MustCompile(`^projects/[^/]+/policies/[A-Za-z0-9._]+$`)
+
+// CreateOrgPolicy creates a policy below parent (projects/{project}).
+// Policies for any constraint are accepted; the mock enforces the constraints in orgpolicy.BooleanConstraints.
+func (s *Store) CreateOrgPolicy(parent string, req *orgpolicy.Policy) (*orgpolicy.Policy, error) {
+	s.orgPolicyMu.Lock()
+	defer s.orgPolicyMu.Unlock()
+
+	if req == nil {
+		return nil, fmt.Errorf("invalid request: policy is required")
+	}
+	if !strings.HasPrefix(req.Name, parent+"/policies/") || !orgPolicyNamePattern.MatchString(req.Name) {
+		return nil, fmt.Errorf("invalid policy name %q: must be like %s/policies/{constraint}", req.Name, parent)
+	}
+	if _, exists := s.orgPolicies[req.Name]; exists {
+		return nil, fmt.Errorf("policy %s already exists", req.Name)
+	}
+	if err := validateOrgPolicy(req); err != nil {
+		return nil, err
+	}
+
+	policy := &orgpolicy.Policy{Name: req.Name}
+	s.setOrgPolicySpecs(policy, req, nil)
+	s.orgPolicies[policy.Name] = policy
+
+	return clone(policy), nil
+}
+
+// GetOrgPolicy retrieves a policy by name (projects/{project}/policies/{constraint}).
+// Returns nil if the policy doesn't exist.
+func (s *Store) GetOrgPolicy(name string) *orgpolicy.Policy {
+	s.orgPolicyMu.RLock()
+	defer s.orgPolicyMu.RUnlock()
+
+	return clone(s.orgPolicies[name])
+}
+
+// ListOrgPolicies returns all policies below parent, sorted by name.
+func (s *Store) ListOrgPolicies(parent string) []*orgpolicy.Policy {
+	s.orgPolicyMu.RLock()
+	defer s.orgPolicyMu.RUnlock()
+
+	policies := make([]*orgpolicy.Policy, 0)
+	for name, policy := range s.orgPolicies {
+		if strings.HasPrefix(name, parent+"/policies/") {
+			policies = append(policies, policy)
+		}
+	}
+
+	sort.Slice(policies, func(i, j int) bool {
+		return policies[i].Name < policies[j].Name
+	})
+
+	return clone(policies)
+}
+
+// UpdateOrgPolicy replaces the specs of a policy named in updateMask (spec and dryRunSpec), or both if it is empty.
+// If the request has an etag, it must match the etag of the policy.
+func (s *Store) UpdateOrgPolicy(name string, req *orgpolicy.Policy, updateMask []string) (*orgpolicy.Policy, error) {
+	s.orgPolicyMu.Lock()
+	defer s.orgPolicyMu.Unlock()
+
+	policy, exists := s.orgPolicies[name]
+	if !exists {
+		return nil, fmt.Errorf("policy %s not found", name)
+	}
+	if err := checkEtag(req.Etag, policy.Etag); err != nil {
+		return nil, err
+	}
+	for _, field := range updateMask {
+		if !slices.Contains([]string{"spec", "dryRunSpec", "dry_run_spec"}, field) {
+			return nil, fmt.Errorf("invalid updateMask field %q: only spec and dryRunSpec can be updated", field)
+		}
+	}
+	if err := validateOrgPolicy(req); err != nil {
+		return nil, err
+	}
+
+	s.setOrgPolicySpecs(policy, req, updateMask)
+
+	return clone(policy), nil
+}
+
+// DeleteOrgPolicy deletes a policy, restoring the default behavior of its constraint.
+func (s *Store) DeleteOrgPolicy(name string) error {
+	s.orgPolicyMu.Lock()
+	defer s.orgPolicyMu.Unlock()
+
+	if _, exists := s.orgPolicies[name]; !exists {
+		return fmt.Errorf("policy %s not found", name)
+	}
+	delete(s.orgPolicies, name)
+
+	return nil
+}
+
+// setOrgPolicySpecs copies the specs of req named in updateMask, or all of them if it is empty, to policy
+// and gives the changed specs and the policy new etags. Must be called with the lock held.
+func (s *Store) setOrgPolicySpecs(policy, req *orgpolicy.Policy, updateMask []string) {
+	now := s.now()
+	update := func(current **orgpolicy.PolicySpec, requested *orgpolicy.PolicySpec, fields ...string) {
+		if len(updateMask) > 0 && !slices.ContainsFunc(fields, func(field string) bool { return slices.Contains(updateMask, field) }) {
+			return
+		}
+		*current = clone(requested)
+		if *current != nil {
+			(*current).Etag = generateEtag()
+			(*current).UpdateTime = now
+		}
+	}
+	update(&policy.Spec, req.Spec, "spec")
+	update(&policy.DryRunSpec, req.DryRunSpec, "dryRunSpec", "dry_run_spec")
+	policy.Etag = generateEtag()
+}
+
+// validateOrgPolicy checks that every rule of a policy sets exactly one of values, allowAll, denyAll and
+// enforce, and that the rules of the boolean constraints the mock enforces set enforce.
+func validateOrgPolicy(policy *orgpolicy.Policy) error {
+	constraint := policy.Name[strings.LastIndex(policy.Name, "/")+1:]
+	boolean := slices.Contains(orgpolicy.BooleanConstraints, constraint)
+
+	for _, spec := range []*orgpolicy.PolicySpec{policy.Spec, policy.DryRunSpec} {
+		if spec == nil {
+			continue
+		}
+		for _, rule := range spec.Rules {
+			if rule == nil {
+				return fmt.Errorf("invalid policy %s: rules must not be empty", policy.Name)
+			}
+			set := 0
+			for _, isSet := range []bool{rule.Values != nil, rule.AllowAll, rule.DenyAll, rule.Enforce != nil} {
+				if isSet {
+					set++
+				}
+			}
+			if set != 1 {
+				return fmt.Errorf("invalid policy %s: each rule must set exactly one of values, allowAll, denyAll and enforce", policy.Name)
+			}
+			if boolean && rule.Enforce == nil {
+				return fmt.Errorf("invalid policy %s: constraints/%s is a boolean constraint, so its rules must set enforce", policy.Name, constraint)
+			}
+		}
+	}
+	return nil
+}
+
+// orgPolicyEnforced reports whether the policy of the mock's project, named by its ID or number, enforces
+// a boolean constraint. Cloud Storage and Cloud SQL resources belong to that project.
+func (s *Store) orgPolicyEnforced(constraint string) bool {
+	s.orgPolicyMu.RLock()
+	defer s.orgPolicyMu.RUnlock()
+
+	cfg := s.config()
+	for _, project := range []string{cfg.projectID, strconv.FormatUint(cfg.projectNumber, 10)} {
+		if s.orgPolicies["projects/"+project+"/policies/"+constraint].Enforced() {
+			return true
+		}
+	}
+	return false
+}
+
+// orgPolicyError returns the error for a request that violates a constraint enforced by an organization policy.
+func (s *Store) orgPolicyError(constraint, reason string) error {
+	return fmt.Errorf("request violates constraint constraints/%s enforced by the organization policy of project %s: %s", constraint, s.config().projectID, reason)
+}
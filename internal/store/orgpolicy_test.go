@@ -0,0 +1,174 @@
+package store
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/katharinasick/gcp-api-mock/internal/orgpolicy"
+	"github.com/katharinasick/gcp-api-mock/internal/sqladmin"
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+)
+
+// enforcePolicy returns a policy enforcing a boolean constraint for a project.
+func enforcePolicy(project, constraint string) *orgpolicy.Policy {
+	enforce := true
+	return &orgpolicy.Policy{
+		Name: "projects/" + project + "/policies/" + constraint,
+		Spec: &orgpolicy.PolicySpec{Rules: []*orgpolicy.PolicyRule{{Enforce: &enforce}}},
+	}
+}
+
+func TestStore_OrgPolicies(t *testing.T) {
+	s := New()
+
+	policy, err := s.CreateOrgPolicy("projects/test-project", enforcePolicy("test-project", orgpolicy.ConstraintRestrictPublicIp))
+	if err != nil {
+		t.Fatalf("CreateOrgPolicy() error: %v", err)
+	}
+	if policy.Etag == "" || policy.Spec.Etag == "" || policy.Spec.UpdateTime.IsZero() {
+		t.Errorf("expected etags and an update time, got %+v", policy)
+	}
+	if _, err := s.CreateOrgPolicy("projects/test-project", enforcePolicy("test-project", orgpolicy.ConstraintRestrictPublicIp)); err == nil ||
+		!strings.Contains(err.Error(), "already exists") {
+		t.Errorf("expected already exists error, got %v", err)
+	}
+	if s.GetOrgPolicy(policy.Name) == nil || len(s.ListOrgPolicies("projects/test-project")) != 1 || len(s.ListOrgPolicies("projects/other")) != 0 {
+		t.Error("expected the policy to be stored for its project only")
+	}
+
+	// Only the named specs are updated, and stale etags are rejected
+	dryRun := &orgpolicy.Policy{DryRunSpec: &orgpolicy.PolicySpec{Reset: true}}
+	updated, err := s.UpdateOrgPolicy(policy.Name, dryRun, []string{"dryRunSpec"})
+	if err != nil {
+		t.Fatalf("UpdateOrgPolicy() error: %v", err)
+	}
+	if updated.Spec == nil || updated.DryRunSpec == nil || !updated.DryRunSpec.Reset || updated.Etag == policy.Etag {
+		t.Errorf("expected the spec to be kept and the dry-run spec to be set, got %+v", updated)
+	}
+	dryRun.Etag = policy.Etag
+	if _, err := s.UpdateOrgPolicy(policy.Name, dryRun, nil); err == nil || !strings.Contains(err.Error(), "precondition failed") {
+		t.Errorf("expected precondition failed error, got %v", err)
+	}
+	if _, err := s.UpdateOrgPolicy(policy.Name, &orgpolicy.Policy{}, []string{"name"}); err == nil || !strings.Contains(err.Error(), "invalid") {
+		t.Errorf("expected invalid updateMask error, got %v", err)
+	}
+
+	if err := s.DeleteOrgPolicy(policy.Name); err != nil {
+		t.Fatalf("DeleteOrgPolicy() error: %v", err)
+	}
+	if err := s.DeleteOrgPolicy(policy.Name); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected not found error, got %v", err)
+	}
+}
+
+func TestStore_CreateOrgPolicyInvalid(t *testing.T) {
+	s := New()
+	enforce := true
+
+	invalid := []struct {
+		name   string
+		policy *orgpolicy.Policy
+	}{
+		{"other project", enforcePolicy("other", orgpolicy.ConstraintRestrictPublicIp)},
+		{"invalid constraint", enforcePolicy("test-project", "constraints/sql.restrictPublicIp")},
+		{"empty rule", &orgpolicy.Policy{
+			Name: "projects/test-project/policies/gcp.resourceLocations",
+			Spec: &orgpolicy.PolicySpec{Rules: []*orgpolicy.PolicyRule{{}}},
+		}},
+		{"two kinds of rule", &orgpolicy.Policy{
+			Name: "projects/test-project/policies/gcp.resourceLocations",
+			Spec: &orgpolicy.PolicySpec{Rules: []*orgpolicy.PolicyRule{{AllowAll: true, Enforce: &enforce}}},
+		}},
+		{"values for a boolean constraint", &orgpolicy.Policy{
+			Name: "projects/test-project/policies/" + orgpolicy.ConstraintPublicAccessPrevention,
+			Spec: &orgpolicy.PolicySpec{Rules: []*orgpolicy.PolicyRule{{AllowAll: true}}},
+		}},
+	}
+	for _, tt := range invalid {
+		if _, err := s.CreateOrgPolicy("projects/test-project", tt.policy); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+
+	// List constraints are stored, even though they aren't enforced
+	if _, err := s.CreateOrgPolicy("projects/test-project", &orgpolicy.Policy{
+		Name: "projects/test-project/policies/gcp.resourceLocations",
+		Spec: &orgpolicy.PolicySpec{Rules: []*orgpolicy.PolicyRule{{Values: &orgpolicy.StringValues{AllowedValues: []string{"in:eu-locations"}}}}},
+	}); err != nil {
+		t.Errorf("CreateOrgPolicy() error: %v", err)
+	}
+}
+
+func TestStore_OrgPolicyStorage(t *testing.T) {
+	s := New()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+
+	// Policies of other projects have no effect
+	_, _ = s.CreateOrgPolicy("projects/other", enforcePolicy("other", orgpolicy.ConstraintPublicAccessPrevention))
+	public := &storage.Policy{Bindings: []storage.PolicyBinding{{Role: "roles/storage.objectViewer", Members: []string{"allUsers"}}}}
+	if _, err := s.SetBucketIamPolicy("test-bucket", public); err != nil {
+		t.Fatalf("SetBucketIamPolicy() error: %v", err)
+	}
+
+	// The mock's project can be named by its number, too
+	_, _ = s.CreateOrgPolicy("projects/123456789012", enforcePolicy("123456789012", orgpolicy.ConstraintPublicAccessPrevention))
+	if _, err := s.SetBucketIamPolicy("test-bucket", public); err == nil ||
+		!strings.Contains(err.Error(), "precondition failed") || !strings.Contains(err.Error(), "constraints/storage.publicAccessPrevention") {
+		t.Errorf("expected org policy error for the IAM policy, got %v", err)
+	}
+	_, _ = s.CreateObject("test-bucket", "file.txt", "text/plain", []byte("x"), nil)
+	if _, err := s.SetObjectACLEntry("test-bucket", "file.txt", storage.ObjectAccessControl{Entity: "allUsers", Role: storage.RoleReader}); err == nil ||
+		!strings.Contains(err.Error(), "constraints/storage.publicAccessPrevention") {
+		t.Errorf("expected org policy error for the ACL entry, got %v", err)
+	}
+
+	_, _ = s.CreateOrgPolicy("projects/mock-project", enforcePolicy("mock-project", orgpolicy.ConstraintUniformBucketLevelAccess))
+	if _, err := s.CreateBucket(&storage.BucketInsertRequest{
+		Name:             "fine-grained",
+		IamConfiguration: &storage.IamConfiguration{UniformBucketLevelAccess: &storage.UniformBucketLevelAccess{Enabled: false}},
+	}); err == nil || !strings.Contains(err.Error(), "constraints/storage.uniformBucketLevelAccess") {
+		t.Errorf("expected org policy error for the new bucket, got %v", err)
+	}
+	if _, err := s.CreateBucket(&storage.BucketInsertRequest{
+		Name:             "uniform",
+		IamConfiguration: &storage.IamConfiguration{UniformBucketLevelAccess: &storage.UniformBucketLevelAccess{Enabled: true}},
+	}); err != nil {
+		t.Errorf("CreateBucket() error: %v", err)
+	}
+	if _, err := s.PatchBucket("uniform", &storage.BucketPatchRequest{BucketUpdateRequest: storage.BucketUpdateRequest{
+		IamConfiguration: &storage.IamConfiguration{UniformBucketLevelAccess: &storage.UniformBucketLevelAccess{Enabled: false}},
+	}}); err == nil || !strings.Contains(err.Error(), "constraints/storage.uniformBucketLevelAccess") {
+		t.Errorf("expected org policy error for the patch, got %v", err)
+	}
+}
+
+func TestStore_OrgPolicySQL(t *testing.T) {
+	s := New()
+	_, _ = s.CreateOrgPolicy("projects/mock-project", enforcePolicy("mock-project", orgpolicy.ConstraintRestrictPublicIp))
+
+	// Instances get a public IP by default
+	if _, _, err := s.CreateSQLInstance(&sqladmin.InstanceInsertRequest{Name: "public"}); err == nil ||
+		!strings.Contains(err.Error(), "invalid request") || !strings.Contains(err.Error(), "constraints/sql.restrictPublicIp") {
+		t.Errorf("expected org policy error, got %v", err)
+	}
+	private := &sqladmin.InstanceInsertRequest{Name: "private", Settings: &sqladmin.Settings{
+		IPConfiguration: &sqladmin.IPConfiguration{PrivateNetwork: "projects/mock-project/global/networks/default"},
+	}}
+	if _, _, err := s.CreateSQLInstance(private); err != nil {
+		t.Fatalf("CreateSQLInstance() error: %v", err)
+	}
+	if _, _, err := s.UpdateSQLInstance("private", &sqladmin.InstancePatchRequest{Settings: &sqladmin.Settings{
+		IPConfiguration: &sqladmin.IPConfiguration{IPv4Enabled: true},
+	}}); err == nil || !strings.Contains(err.Error(), "constraints/sql.restrictPublicIp") {
+		t.Errorf("expected org policy error for the update, got %v", err)
+	}
+
+	// Policies that are reset restore the default behavior
+	enforce := true
+	_, _ = s.UpdateOrgPolicy("projects/mock-project/policies/"+orgpolicy.ConstraintRestrictPublicIp, &orgpolicy.Policy{
+		Spec: &orgpolicy.PolicySpec{Reset: true, Rules: []*orgpolicy.PolicyRule{{Enforce: &enforce}}},
+	}, nil)
+	if _, _, err := s.CreateSQLInstance(&sqladmin.InstanceInsertRequest{Name: "public"}); err != nil {
+		t.Errorf("CreateSQLInstance() error: %v", err)
+	}
+}
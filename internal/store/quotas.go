@@ -0,0 +1,106 @@
+package store
+
+import "fmt"
+
+// =============================================================================
+// Cloud Storage Bucket Quota Operations
+// =============================================================================
+
+// BucketQuota limits the live objects in a bucket, to test how clients handle quota errors.
+// A zero limit means no limit. Writes that would exceed a limit fail with a "quota exceeded" error.
+type BucketQuota struct {
+	// MaxBytes is the maximum total size of the live objects in the bucket
+	MaxBytes int64 `json:"maxBytes,omitempty"`
+	// MaxObjects is the maximum number of live objects in the bucket
+	MaxObjects int `json:"maxObjects,omitempty"`
+}
+
+// BucketUsage is the quota of a bucket and how much of it is used.
+type BucketUsage struct {
+	BucketQuota
+	// UsedBytes is the total size of the live objects in the bucket
+	UsedBytes int64 `json:"usedBytes"`
+	// ObjectCount is the number of live objects in the bucket
+	ObjectCount int `json:"objectCount"`
+}
+
+// SetBucketQuota sets the quota of a bucket, replacing the previous one.
+// A quota without limits removes the quota. Objects already in the bucket are kept,
+// even if they exceed the new quota.
+// Returns an error if the bucket doesn't exist or a limit is negative.
+func (s *Store) SetBucketQuota(bucketName string, quota BucketQuota) (*BucketUsage, error) {
+	if quota.MaxBytes < 0 || quota.MaxObjects < 0 {
+		return nil, fmt.Errorf("invalid quota: limits must not be negative")
+	}
+
+	s.storageMu.Lock()
+	defer s.storageMu.Unlock()
+
+	if _, exists := s.buckets[bucketName]; !exists {
+		return nil, fmt.Errorf("bucket %s not found", bucketName)
+	}
+
+	if quota == (BucketQuota{}) {
+		delete(s.bucketQuotas, bucketName)
+	} else {
+		s.bucketQuotas[bucketName] = quota
+	}
+
+	return s.bucketUsage(bucketName), nil
+}
+
+// GetBucketUsage returns the quota of a bucket and how much of it is used.
+// Returns an error if the bucket doesn't exist.
+func (s *Store) GetBucketUsage(bucketName string) (*BucketUsage, error) {
+	s.storageMu.RLock()
+	defer s.storageMu.RUnlock()
+
+	if _, exists := s.buckets[bucketName]; !exists {
+		return nil, fmt.Errorf("bucket %s not found", bucketName)
+	}
+
+	return s.bucketUsage(bucketName), nil
+}
+
+// ListBucketUsage returns the quota and usage of every bucket, keyed by bucket name.
+func (s *Store) ListBucketUsage() map[string]*BucketUsage {
+	s.storageMu.RLock()
+	defer s.storageMu.RUnlock()
+
+	usage := make(map[string]*BucketUsage, len(s.buckets))
+	for bucketName := range s.buckets {
+		usage[bucketName] = s.bucketUsage(bucketName)
+	}
+	return usage
+}
+
+// bucketUsage returns the quota and usage of a bucket.
+// Callers must hold the storage lock.
+func (s *Store) bucketUsage(bucketName string) *BucketUsage {
+	usage := &BucketUsage{BucketQuota: s.bucketQuotas[bucketName]}
+	for _, objData := range s.objects[bucketName] {
+		usage.UsedBytes += objData.Content.Size()
+		usage.ObjectCount++
+	}
+	return usage
+}
+
+// checkBucketQuota returns an error if adding addedBytes, and a new object if newObject is set,
+// would exceed the quota of a bucket. Writes that don't grow the bucket are always allowed,
+// so clients can clean up a bucket that is over its quota.
+// Callers must hold the storage lock.
+func (s *Store) checkBucketQuota(bucketName string, addedBytes int64, newObject bool) error {
+	quota, exists := s.bucketQuotas[bucketName]
+	if !exists {
+		return nil
+	}
+
+	usage := s.bucketUsage(bucketName)
+	if quota.MaxObjects > 0 && newObject && usage.ObjectCount+1 > quota.MaxObjects {
+		return fmt.Errorf("quota exceeded: bucket %s is limited to %d objects", bucketName, quota.MaxObjects)
+	}
+	if quota.MaxBytes > 0 && addedBytes > 0 && usage.UsedBytes+addedBytes > quota.MaxBytes {
+		return fmt.Errorf("quota exceeded: bucket %s is limited to %d bytes, %d are used", bucketName, quota.MaxBytes, usage.UsedBytes)
+	}
+	return nil
+}
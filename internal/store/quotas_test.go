@@ -0,0 +1,67 @@
+package store
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+)
+
+func TestStore_BucketQuota(t *testing.T) {
+	s := New()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+
+	if _, err := s.SetBucketQuota("test-bucket", BucketQuota{MaxBytes: 10, MaxObjects: 2}); err != nil {
+		t.Fatalf("SetBucketQuota() error = %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		object      string
+		content     string
+		expectError bool
+	}{
+		{"within quota", "a.txt", "12345", false},
+		{"second object", "b.txt", "123", false},
+		{"too many objects", "c.txt", "1", true},
+		{"overwrite within quota", "a.txt", "1234567", false},
+		{"overwrite beyond byte quota", "b.txt", "12345", true},
+		{"shrinking overwrite", "a.txt", "1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.CreateObject("test-bucket", tt.object, "text/plain", []byte(tt.content), nil)
+			if tt.expectError {
+				if err == nil || !strings.Contains(err.Error(), "quota exceeded") {
+					t.Errorf("expected quota exceeded error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("CreateObject() error = %v", err)
+			}
+		})
+	}
+
+	usage, err := s.GetBucketUsage("test-bucket")
+	if err != nil {
+		t.Fatalf("GetBucketUsage() error = %v", err)
+	}
+	if usage.UsedBytes != 4 || usage.ObjectCount != 2 || usage.MaxBytes != 10 {
+		t.Errorf("expected 4 bytes in 2 objects with a 10 byte quota, got %+v", usage)
+	}
+
+	// Removing the quota allows writes again
+	_, _ = s.SetBucketQuota("test-bucket", BucketQuota{})
+	if _, err := s.CreateObject("test-bucket", "c.txt", "text/plain", []byte("12345678901"), nil); err != nil {
+		t.Errorf("expected write without quota to succeed, got %v", err)
+	}
+
+	if _, err := s.SetBucketQuota("test-bucket", BucketQuota{MaxBytes: -1}); err == nil {
+		t.Error("expected error for negative quota")
+	}
+	if _, err := s.SetBucketQuota("non-existent", BucketQuota{MaxObjects: 1}); err == nil {
+		t.Error("expected error for non-existent bucket")
+	}
+}
@@ -0,0 +1,327 @@
+package store
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/katharinasick/gcp-api-mock/internal/blob"
+	"github.com/katharinasick/gcp-api-mock/internal/registry"
+)
+
+// =============================================================================
+// Artifact Registry Operations
+// =============================================================================
+
+// registryUpload is an in-progress blob upload.
+type registryUpload struct {
+	// name is the repository the blob is uploaded to
+	name string
+	// content is the data uploaded so far
+	content blob.Blob
+}
+
+// newUUID generates a random UUID, used for upload IDs and resource UIDs.
+func newUUID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// sha256Digest returns the digest of content in the "sha256:<hex>" format.
+func sha256Digest(r io.Reader) (string, error) {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, r); err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// repository returns the repository with the given name, creating it if needed.
+// Callers must hold the registry write lock.
+func (s *Store) repository(name string) *registry.Repository {
+	repo, exists := s.repositories[name]
+	if !exists {
+		repo = &registry.Repository{
+			Name:      name,
+			Manifests: make(map[string]*registry.Manifest),
+			Tags:      make(map[string]string),
+			Blobs:     make(map[string]bool),
+		}
+		s.repositories[name] = repo
+	}
+	return repo
+}
+
+// StartRegistryUpload starts a blob upload to a repository and returns the upload ID.
+func (s *Store) StartRegistryUpload(name string) (string, error) {
+	content, err := s.config().blobs.Write(bytes.NewReader(nil))
+	if err != nil {
+		return "", fmt.Errorf("failed to start upload: %w", err)
+	}
+
+	s.registryMu.Lock()
+	defer s.registryMu.Unlock()
+
+	id := newUUID()
+	s.registryUploads[id] = &registryUpload{name: name, content: content}
+
+	return id, nil
+}
+
+// GetRegistryUploadSize returns the number of bytes uploaded so far.
+func (s *Store) GetRegistryUploadSize(name, id string) (int64, error) {
+	s.registryMu.RLock()
+	defer s.registryMu.RUnlock()
+
+	upload, exists := s.registryUploads[id]
+	if !exists || upload.name != name {
+		return 0, fmt.Errorf("upload %s not found", id)
+	}
+
+	return upload.content.Size(), nil
+}
+
+// AppendRegistryUpload appends a chunk to a blob upload and returns the new upload size.
+func (s *Store) AppendRegistryUpload(name, id string, r io.Reader) (int64, error) {
+	s.registryMu.RLock()
+	upload, exists := s.registryUploads[id]
+	s.registryMu.RUnlock()
+
+	if !exists || upload.name != name {
+		return 0, fmt.Errorf("upload %s not found", id)
+	}
+
+	// Write the combined content outside the lock, so large chunks don't block other requests
+	existing, err := upload.content.Open()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read upload: %w", err)
+	}
+	content, err := s.config().blobs.Write(io.MultiReader(existing, r))
+	existing.Close()
+	if err != nil {
+		return 0, fmt.Errorf("failed to write upload: %w", err)
+	}
+
+	s.registryMu.Lock()
+	defer s.registryMu.Unlock()
+
+	if s.registryUploads[id] != upload {
+		content.Release()
+		return 0, fmt.Errorf("upload %s not found", id)
+	}
+
+	previous := upload.content
+	s.registryUploads[id] = &registryUpload{name: name, content: content}
+	previous.Release()
+
+	return content.Size(), nil
+}
+
+// CompleteRegistryUpload appends the final chunk to a blob upload, verifies the digest of the
+// uploaded content and links the blob to the repository.
+func (s *Store) CompleteRegistryUpload(name, id, digest string, r io.Reader) error {
+	if !strings.HasPrefix(digest, "sha256:") {
+		return fmt.Errorf("invalid digest %s: only sha256 is supported", digest)
+	}
+
+	if _, err := s.AppendRegistryUpload(name, id, r); err != nil {
+		return err
+	}
+
+	s.registryMu.Lock()
+	upload, exists := s.registryUploads[id]
+	if exists {
+		delete(s.registryUploads, id)
+	}
+	s.registryMu.Unlock()
+
+	if !exists {
+		return fmt.Errorf("upload %s not found", id)
+	}
+
+	content, err := upload.content.Open()
+	if err != nil {
+		upload.content.Release()
+		return fmt.Errorf("failed to read upload: %w", err)
+	}
+	actual, err := sha256Digest(content)
+	content.Close()
+	if err != nil {
+		upload.content.Release()
+		return fmt.Errorf("failed to read upload: %w", err)
+	}
+	if actual != digest {
+		upload.content.Release()
+		return fmt.Errorf("invalid digest %s: content has digest %s", digest, actual)
+	}
+
+	s.registryMu.Lock()
+	defer s.registryMu.Unlock()
+
+	// Blobs are content-addressed, so an existing blob with the same digest is kept
+	if _, exists := s.registryBlobs[digest]; exists {
+		upload.content.Release()
+	} else {
+		s.registryBlobs[digest] = upload.content
+	}
+	s.repository(name).Blobs[digest] = true
+
+	return nil
+}
+
+// CancelRegistryUpload cancels a blob upload.
+func (s *Store) CancelRegistryUpload(name, id string) error {
+	s.registryMu.Lock()
+	defer s.registryMu.Unlock()
+
+	upload, exists := s.registryUploads[id]
+	if !exists || upload.name != name {
+		return fmt.Errorf("upload %s not found", id)
+	}
+
+	delete(s.registryUploads, id)
+	upload.content.Release()
+
+	return nil
+}
+
+// MountRegistryBlob links an existing blob to a repository without uploading it again.
+// Returns false if the blob doesn't exist.
+func (s *Store) MountRegistryBlob(name, digest string) bool {
+	s.registryMu.Lock()
+	defer s.registryMu.Unlock()
+
+	if _, exists := s.registryBlobs[digest]; !exists {
+		return false
+	}
+
+	s.repository(name).Blobs[digest] = true
+	return true
+}
+
+// OpenRegistryBlob opens a blob linked to a repository for reading.
+// The caller must close the returned reader.
+func (s *Store) OpenRegistryBlob(name, digest string) (int64, io.ReadSeekCloser, error) {
+	s.registryMu.RLock()
+	defer s.registryMu.RUnlock()
+
+	repo, exists := s.repositories[name]
+	if !exists || !repo.Blobs[digest] {
+		return 0, nil, fmt.Errorf("blob %s not found", digest)
+	}
+
+	content := s.registryBlobs[digest]
+	reader, err := content.Open()
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to read blob: %w", err)
+	}
+
+	return content.Size(), reader, nil
+}
+
+// PutManifest stores a manifest in a repository under a tag or digest reference.
+// If the reference is a digest, it must match the manifest content.
+func (s *Store) PutManifest(name, reference, mediaType string, content []byte) (*registry.Manifest, error) {
+	digest, _ := sha256Digest(bytes.NewReader(content))
+	if strings.HasPrefix(reference, "sha256:") && reference != digest {
+		return nil, fmt.Errorf("invalid digest %s: manifest has digest %s", reference, digest)
+	}
+
+	s.registryMu.Lock()
+	defer s.registryMu.Unlock()
+
+	manifest := &registry.Manifest{
+		Digest:    digest,
+		MediaType: mediaType,
+		Content:   content,
+		Created:   s.now(),
+	}
+
+	repo := s.repository(name)
+	repo.Manifests[digest] = manifest
+	if !strings.HasPrefix(reference, "sha256:") {
+		repo.Tags[reference] = digest
+	}
+
+	return clone(manifest), nil
+}
+
+// GetManifest retrieves a manifest by tag or digest.
+// Returns nil if the manifest doesn't exist.
+func (s *Store) GetManifest(name, reference string) *registry.Manifest {
+	s.registryMu.RLock()
+	defer s.registryMu.RUnlock()
+
+	repo, exists := s.repositories[name]
+	if !exists {
+		return nil
+	}
+
+	if digest, isTag := repo.Tags[reference]; isTag {
+		reference = digest
+	}
+
+	return clone(repo.Manifests[reference])
+}
+
+// DeleteManifest deletes a manifest by digest, along with all tags pointing to it.
+func (s *Store) DeleteManifest(name, digest string) error {
+	s.registryMu.Lock()
+	defer s.registryMu.Unlock()
+
+	repo, exists := s.repositories[name]
+	if !exists {
+		return fmt.Errorf("repository %s not found", name)
+	}
+	if _, exists := repo.Manifests[digest]; !exists {
+		return fmt.Errorf("manifest %s not found", digest)
+	}
+
+	delete(repo.Manifests, digest)
+	for tag, tagDigest := range repo.Tags {
+		if tagDigest == digest {
+			delete(repo.Tags, tag)
+		}
+	}
+
+	return nil
+}
+
+// ListTags returns the sorted tags of a repository.
+func (s *Store) ListTags(name string) ([]string, error) {
+	s.registryMu.RLock()
+	defer s.registryMu.RUnlock()
+
+	repo, exists := s.repositories[name]
+	if !exists {
+		return nil, fmt.Errorf("repository %s not found", name)
+	}
+
+	tags := make([]string, 0, len(repo.Tags))
+	for tag := range repo.Tags {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	return tags, nil
+}
+
+// ListRepositories returns the sorted names of all repositories.
+func (s *Store) ListRepositories() []string {
+	s.registryMu.RLock()
+	defer s.registryMu.RUnlock()
+
+	names := make([]string, 0, len(s.repositories))
+	for name := range s.repositories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,97 @@
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"strings"
+	"testing"
+)
+
+func testDigest(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func TestStore_RegistryUpload(t *testing.T) {
+	s := New()
+
+	id, err := s.StartRegistryUpload("project/repo/image")
+	if err != nil {
+		t.Fatalf("StartRegistryUpload() error: %v", err)
+	}
+
+	size, err := s.AppendRegistryUpload("project/repo/image", id, strings.NewReader("layer "))
+	if err != nil || size != 6 {
+		t.Fatalf("AppendRegistryUpload() = %d, %v", size, err)
+	}
+
+	if err := s.CompleteRegistryUpload("project/repo/image", id, testDigest("layer data"), strings.NewReader("data")); err != nil {
+		t.Fatalf("CompleteRegistryUpload() error: %v", err)
+	}
+
+	_, content, err := s.OpenRegistryBlob("project/repo/image", testDigest("layer data"))
+	if err != nil {
+		t.Fatalf("OpenRegistryBlob() error: %v", err)
+	}
+	data, _ := io.ReadAll(content)
+	content.Close()
+	if string(data) != "layer data" {
+		t.Errorf("expected 'layer data', got '%s'", string(data))
+	}
+
+	// The upload is gone once completed
+	if _, err := s.GetRegistryUploadSize("project/repo/image", id); err == nil {
+		t.Error("expected completed upload to be removed")
+	}
+
+	// Blobs are only visible in repositories they are linked to, until mounted
+	if _, _, err := s.OpenRegistryBlob("project/repo/other", testDigest("layer data")); err == nil {
+		t.Error("expected blob not to be linked to another repository")
+	}
+	if !s.MountRegistryBlob("project/repo/other", testDigest("layer data")) {
+		t.Error("expected mount to succeed")
+	}
+	if _, _, err := s.OpenRegistryBlob("project/repo/other", testDigest("layer data")); err != nil {
+		t.Errorf("expected mounted blob to be readable: %v", err)
+	}
+}
+
+func TestStore_RegistryUpload_DigestMismatch(t *testing.T) {
+	s := New()
+
+	id, _ := s.StartRegistryUpload("repo")
+	err := s.CompleteRegistryUpload("repo", id, testDigest("expected"), strings.NewReader("actual"))
+	if err == nil || !strings.Contains(err.Error(), "invalid digest") {
+		t.Errorf("expected invalid digest error, got %v", err)
+	}
+}
+
+func TestStore_Manifests(t *testing.T) {
+	s := New()
+	content := `{"schemaVersion":2}`
+
+	manifest, err := s.PutManifest("repo", "latest", "application/vnd.oci.image.manifest.v1+json", []byte(content))
+	if err != nil {
+		t.Fatalf("PutManifest() error: %v", err)
+	}
+	if manifest.Digest != testDigest(content) {
+		t.Errorf("unexpected digest: %s", manifest.Digest)
+	}
+
+	if s.GetManifest("repo", "latest") == nil || s.GetManifest("repo", manifest.Digest) == nil {
+		t.Error("expected manifest to be found by tag and digest")
+	}
+
+	if _, err := s.PutManifest("repo", testDigest("other"), "", []byte(content)); err == nil {
+		t.Error("expected error when the digest reference doesn't match")
+	}
+
+	if err := s.DeleteManifest("repo", manifest.Digest); err != nil {
+		t.Fatalf("DeleteManifest() error: %v", err)
+	}
+	tags, _ := s.ListTags("repo")
+	if len(tags) != 0 {
+		t.Errorf("expected tags to be removed with the manifest, got %v", tags)
+	}
+}
@@ -0,0 +1,153 @@
+package store
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+)
+
+func TestStore_LockRetentionPolicy(t *testing.T) {
+	s := New()
+	now := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	s.SetClock(func() time.Time { return now })
+
+	bucket, err := s.CreateBucket(&storage.BucketInsertRequest{Name: "locked", RetentionPolicy: &storage.RetentionPolicy{RetentionPeriod: 3600, IsLocked: true}})
+	if err != nil {
+		t.Fatalf("CreateBucket() error: %v", err)
+	}
+	if bucket.RetentionPolicy.IsLocked || bucket.RetentionPolicy.EffectiveTime == nil || !bucket.RetentionPolicy.EffectiveTime.Equal(now) {
+		t.Errorf("expected an unlocked policy effective now, got %+v", bucket.RetentionPolicy)
+	}
+
+	// Unlocked policies can be reduced
+	bucket, err = s.PatchBucket("locked", &storage.BucketPatchRequest{BucketUpdateRequest: storage.BucketUpdateRequest{RetentionPolicy: &storage.RetentionPolicy{RetentionPeriod: 60}}})
+	if err != nil || bucket.RetentionPolicy.RetentionPeriod != 60 {
+		t.Fatalf("expected the period to be reduced, got %+v, %v", bucket.RetentionPolicy, err)
+	}
+
+	if _, err := s.LockRetentionPolicy("locked", bucket.Metageneration-1); err == nil || !strings.Contains(err.Error(), "precondition failed") {
+		t.Errorf("expected precondition failed error, got %v", err)
+	}
+	locked, err := s.LockRetentionPolicy("locked", bucket.Metageneration)
+	if err != nil {
+		t.Fatalf("LockRetentionPolicy() error: %v", err)
+	}
+	if !locked.RetentionPolicy.IsLocked || locked.Metageneration != bucket.Metageneration+1 {
+		t.Errorf("expected a locked policy and a new metageneration, got %+v", locked)
+	}
+
+	// Locked policies can be extended, but not reduced or removed
+	reduce := &storage.BucketUpdateRequest{RetentionPolicy: &storage.RetentionPolicy{RetentionPeriod: 30}}
+	if _, err := s.UpdateBucket("locked", reduce); err == nil || !strings.Contains(err.Error(), "locked retention policy") {
+		t.Errorf("expected reductions to fail, got %v", err)
+	}
+	if _, err := s.PatchBucket("locked", &storage.BucketPatchRequest{NullFields: []string{"retentionPolicy"}}); err == nil ||
+		!strings.Contains(err.Error(), "locked retention policy") {
+		t.Errorf("expected removals to fail, got %v", err)
+	}
+	extended, err := s.UpdateBucket("locked", &storage.BucketUpdateRequest{RetentionPolicy: &storage.RetentionPolicy{RetentionPeriod: 7200}})
+	if err != nil || extended.RetentionPolicy.RetentionPeriod != 7200 || !extended.RetentionPolicy.IsLocked {
+		t.Errorf("expected the locked period to be extended, got %+v, %v", extended.RetentionPolicy, err)
+	}
+
+	if _, err := s.CreateBucket(&storage.BucketInsertRequest{Name: "unprotected"}); err != nil {
+		t.Fatalf("CreateBucket() error: %v", err)
+	}
+	if _, err := s.LockRetentionPolicy("unprotected", 1); err == nil || !strings.Contains(err.Error(), "invalid") {
+		t.Errorf("expected buckets without a policy to be rejected, got %v", err)
+	}
+}
+
+func TestStore_DeleteObject_RetentionPolicy(t *testing.T) {
+	s := New()
+	now := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	s.SetClock(func() time.Time { return now })
+
+	if _, err := s.CreateBucket(&storage.BucketInsertRequest{Name: "retained", RetentionPolicy: &storage.RetentionPolicy{RetentionPeriod: 3600}}); err != nil {
+		t.Fatalf("CreateBucket() error: %v", err)
+	}
+	if _, err := s.CreateObject("retained", "record.json", "application/json", []byte("{}"), nil); err != nil {
+		t.Fatalf("CreateObject() error: %v", err)
+	}
+
+	if err := s.DeleteObject("retained", "record.json"); err == nil || !strings.Contains(err.Error(), "retention policy") {
+		t.Errorf("expected the object to be retained, got %v", err)
+	}
+
+	now = now.Add(time.Hour)
+	if err := s.DeleteObject("retained", "record.json"); err != nil {
+		t.Errorf("expected the object to be deletable after the retention period, got %v", err)
+	}
+}
+
+func TestStore_ReplaceObject_RetentionPolicy(t *testing.T) {
+	s := New()
+	now := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	s.SetClock(func() time.Time { return now })
+
+	if _, err := s.CreateBucket(&storage.BucketInsertRequest{Name: "retained", RetentionPolicy: &storage.RetentionPolicy{RetentionPeriod: 3600}}); err != nil {
+		t.Fatalf("CreateBucket() error: %v", err)
+	}
+	for _, name := range []string{"logs/a.json", "logs/b.json"} {
+		if _, err := s.CreateObject("retained", name, "application/json", []byte(`{"name":"`+name+`"}`), nil); err != nil {
+			t.Fatalf("CreateObject() error: %v", err)
+		}
+	}
+
+	if _, err := s.CreateObject("retained", "logs/a.json", "application/json", []byte(`{"changed":true}`), nil); err == nil || !strings.Contains(err.Error(), "retention policy") {
+		t.Errorf("expected overwriting a retained object to fail, got %v", err)
+	}
+	if _, err := s.RewriteObject("retained", "logs/b.json", "retained", "logs/a.json", &storage.ObjectInsertRequest{}, Preconditions{}); err == nil || !strings.Contains(err.Error(), "retention policy") {
+		t.Errorf("expected rewriting onto a retained object to fail, got %v", err)
+	}
+	if _, err := s.DeleteObjects("retained", "logs/"); err == nil || !strings.Contains(err.Error(), "retention policy") {
+		t.Errorf("expected deleting retained objects by prefix to fail, got %v", err)
+	}
+	if objects, _ := s.ListObjects("retained", "", ""); len(objects) != 2 {
+		t.Errorf("expected no object to be deleted, got %d objects", len(objects))
+	}
+	if obj := s.GetObject("retained", "logs/a.json"); obj == nil || obj.Size != uint64(len(`{"name":"logs/a.json"}`)) {
+		t.Errorf("expected the retained object to be unchanged, got %+v", obj)
+	}
+
+	now = now.Add(time.Hour)
+	if _, err := s.CreateObject("retained", "logs/a.json", "application/json", []byte(`{"changed":true}`), nil); err != nil {
+		t.Errorf("expected overwriting after the retention period to succeed, got %v", err)
+	}
+	// The new generation is retained from its own creation
+	now = now.Add(time.Hour)
+	if deleted, err := s.DeleteObjects("retained", "logs/"); err != nil || deleted != 2 {
+		t.Errorf("expected both objects to be deleted after the retention period, got %d, %v", deleted, err)
+	}
+}
+
+func TestStore_DeleteObject_Hold(t *testing.T) {
+	s := New()
+	if _, err := s.CreateBucket(&storage.BucketInsertRequest{Name: "held"}); err != nil {
+		t.Fatalf("CreateBucket() error: %v", err)
+	}
+	if _, err := s.CreateObject("held", "record.json", "application/json", []byte("{}"), nil); err != nil {
+		t.Fatalf("CreateObject() error: %v", err)
+	}
+
+	obj, err := s.UpdateObject("held", "record.json", &storage.ObjectUpdateRequest{EventBasedHold: true})
+	if err != nil || !obj.EventBasedHold {
+		t.Fatalf("expected the event-based hold to be set, got %+v, %v", obj, err)
+	}
+	if err := s.DeleteObject("held", "record.json"); err == nil || !strings.Contains(err.Error(), "event-based hold") {
+		t.Errorf("expected the hold to keep the object from being deleted, got %v", err)
+	}
+	if _, err := s.CreateObject("held", "record.json", "application/json", []byte(`{"changed":true}`), nil); err == nil || !strings.Contains(err.Error(), "event-based hold") {
+		t.Errorf("expected the hold to keep the object from being overwritten, got %v", err)
+	}
+
+	obj, err = s.PatchObject("held", "record.json", &storage.ObjectPatchRequest{NullFields: []string{"eventBasedHold"}}, Preconditions{})
+	if err != nil || obj.EventBasedHold {
+		t.Fatalf("expected the event-based hold to be released, got %+v, %v", obj, err)
+	}
+	if err := s.DeleteObject("held", "record.json"); err != nil {
+		t.Errorf("expected the object to be deletable once the hold is released, got %v", err)
+	}
+}
@@ -0,0 +1,356 @@
+package store
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/cloudscheduler"
+)
+
+// =============================================================================
+// Cloud Scheduler Job Operations
+// =============================================================================
+
+// SchedulerHandler is called for every Cloud Scheduler job that is due, with the time it was scheduled for.
+// It is called while the Cloud Scheduler lock is held, so it must not block or call back into the store.
+type SchedulerHandler func(job *cloudscheduler.Job, scheduleTime time.Time)
+
+// schedulerJobIDPattern matches valid job IDs: letters, digits, hyphens and underscores, at most 500 characters.
+var schedulerJobIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,500}$`)
+
+// pubsubTopicPattern matches Pub/Sub topic names like projects/my-project/topics/my-topic.
+var pubsubTopicPattern = regexp.MustCompile(`^projects/[^/]+/topics/[^/]+$`)
+
+// schedulerJobFields are the fields of a job that can be changed with jobs.patch.
+var schedulerJobFields = []string{
+	"description", "schedule", "timeZone", "retryConfig", "attemptDeadline",
+	"httpTarget", "pubsubTarget", "appEngineHttpTarget",
+}
+
+// splitSchedulerJobName splits a job name like projects/{project}/locations/{location}/jobs/{job}
+// into its parent (projects/{project}/locations/{location}) and job ID.
+func splitSchedulerJobName(name string) (string, string) {
+	parent, jobID, found := strings.Cut(name, "/jobs/")
+	if !found {
+		return "", ""
+	}
+	return parent, jobID
+}
+
+// SetSchedulerHandler sets the handler that runs Cloud Scheduler jobs on their schedule.
+// Without a handler, jobs only run when they're run explicitly.
+func (s *Store) SetSchedulerHandler(handler SchedulerHandler) {
+	s.configure(func(cfg *storeConfig) {
+		cfg.schedulerHandler = handler
+	})
+}
+
+// CreateSchedulerJob creates a job below parent (projects/{project}/locations/{location}).
+// If the job has no name, a job ID is generated. The job is ENABLED and scheduled for its next run.
+func (s *Store) CreateSchedulerJob(parent string, req *cloudscheduler.Job) (*cloudscheduler.Job, error) {
+	s.schedulerMu.Lock()
+	defer s.schedulerMu.Unlock()
+
+	name := req.Name
+	if name == "" {
+		name = parent + "/jobs/" + newUUID()
+	}
+	jobParent, jobID := splitSchedulerJobName(name)
+	if jobParent != parent {
+		return nil, fmt.Errorf("invalid job name %s: must be below %s", name, parent)
+	}
+	if !schedulerJobIDPattern.MatchString(jobID) {
+		return nil, fmt.Errorf("invalid job name %s: job IDs may only contain letters, digits, hyphens and underscores", name)
+	}
+	if _, exists := s.schedulerJobs[name]; exists {
+		return nil, fmt.Errorf("job %s already exists", name)
+	}
+
+	job := clone(req)
+	job.Name = name
+	job.State = cloudscheduler.StateEnabled
+	job.Status = nil
+	job.LastAttemptTime = time.Time{}
+	if err := prepareSchedulerJob(job); err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	job.UserUpdateTime = now
+	job.ScheduleTime = nextSchedulerRun(job, now)
+	s.schedulerJobs[name] = job
+
+	return clone(job), nil
+}
+
+// GetSchedulerJob retrieves a job by name.
+// Returns nil if the job doesn't exist.
+func (s *Store) GetSchedulerJob(name string) *cloudscheduler.Job {
+	s.schedulerMu.RLock()
+	defer s.schedulerMu.RUnlock()
+
+	return clone(s.schedulerJobs[name])
+}
+
+// ListSchedulerJobs returns all jobs below parent, sorted by name.
+func (s *Store) ListSchedulerJobs(parent string) []*cloudscheduler.Job {
+	s.schedulerMu.RLock()
+	defer s.schedulerMu.RUnlock()
+
+	jobs := make([]*cloudscheduler.Job, 0)
+	for name, job := range s.schedulerJobs {
+		if jobParent, _ := splitSchedulerJobName(name); jobParent == parent {
+			jobs = append(jobs, job)
+		}
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].Name < jobs[j].Name
+	})
+
+	return clone(jobs)
+}
+
+// UpdateSchedulerJob changes the fields of a job named by updateMask to their values in req.
+// Without an update mask, the fields set in req are changed. Setting a target replaces the previous one.
+func (s *Store) UpdateSchedulerJob(name string, req *cloudscheduler.Job, updateMask []string) (*cloudscheduler.Job, error) {
+	s.schedulerMu.Lock()
+	defer s.schedulerMu.Unlock()
+
+	existing, exists := s.schedulerJobs[name]
+	if !exists {
+		return nil, fmt.Errorf("job %s not found", name)
+	}
+
+	if countSchedulerTargets(req) > 1 {
+		return nil, fmt.Errorf("invalid job %s: at most one of httpTarget, pubsubTarget and appEngineHttpTarget may be set", name)
+	}
+	if len(updateMask) == 0 {
+		updateMask = setSchedulerJobFields(req)
+	}
+	job := clone(existing)
+	for _, field := range updateMask {
+		switch field {
+		case "description":
+			job.Description = req.Description
+		case "schedule":
+			job.Schedule = req.Schedule
+		case "timeZone", "time_zone":
+			job.TimeZone = req.TimeZone
+		case "retryConfig", "retry_config":
+			job.RetryConfig = clone(req.RetryConfig)
+		case "attemptDeadline", "attempt_deadline":
+			job.AttemptDeadline = req.AttemptDeadline
+		case "httpTarget", "http_target":
+			job.HttpTarget, job.PubsubTarget, job.AppEngineHttpTarget = clone(req.HttpTarget), nil, nil
+		case "pubsubTarget", "pubsub_target":
+			job.HttpTarget, job.PubsubTarget, job.AppEngineHttpTarget = nil, clone(req.PubsubTarget), nil
+		case "appEngineHttpTarget", "app_engine_http_target":
+			job.HttpTarget, job.PubsubTarget, job.AppEngineHttpTarget = nil, nil, clone(req.AppEngineHttpTarget)
+		default:
+			return nil, fmt.Errorf("invalid update mask field %q: must be one of %s", field, strings.Join(schedulerJobFields, ", "))
+		}
+	}
+	if err := prepareSchedulerJob(job); err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	job.UserUpdateTime = now
+	if job.State == cloudscheduler.StateEnabled {
+		job.ScheduleTime = nextSchedulerRun(job, now)
+	}
+	s.schedulerJobs[name] = job
+
+	return clone(job), nil
+}
+
+// DeleteSchedulerJob deletes a job.
+func (s *Store) DeleteSchedulerJob(name string) error {
+	s.schedulerMu.Lock()
+	defer s.schedulerMu.Unlock()
+
+	if _, exists := s.schedulerJobs[name]; !exists {
+		return fmt.Errorf("job %s not found", name)
+	}
+	delete(s.schedulerJobs, name)
+	return nil
+}
+
+// PauseSchedulerJob pauses a job, so it doesn't run on its schedule until it is resumed.
+func (s *Store) PauseSchedulerJob(name string) (*cloudscheduler.Job, error) {
+	s.schedulerMu.Lock()
+	defer s.schedulerMu.Unlock()
+
+	job, exists := s.schedulerJobs[name]
+	if !exists {
+		return nil, fmt.Errorf("job %s not found", name)
+	}
+	job.State = cloudscheduler.StatePaused
+	job.ScheduleTime = time.Time{}
+	job.UserUpdateTime = s.now()
+
+	return clone(job), nil
+}
+
+// ResumeSchedulerJob resumes a paused job and schedules its next run.
+func (s *Store) ResumeSchedulerJob(name string) (*cloudscheduler.Job, error) {
+	s.schedulerMu.Lock()
+	defer s.schedulerMu.Unlock()
+
+	job, exists := s.schedulerJobs[name]
+	if !exists {
+		return nil, fmt.Errorf("job %s not found", name)
+	}
+	now := s.now()
+	job.State = cloudscheduler.StateEnabled
+	job.ScheduleTime = nextSchedulerRun(job, now)
+	job.UserUpdateTime = now
+
+	return clone(job), nil
+}
+
+// StartSchedulerAttempt records an attempt to run a job now, whatever its schedule and state, and returns
+// the job to deliver. The result of the delivery is recorded with RecordSchedulerAttempt.
+func (s *Store) StartSchedulerAttempt(name string) (*cloudscheduler.Job, error) {
+	s.schedulerMu.Lock()
+	defer s.schedulerMu.Unlock()
+
+	job, exists := s.schedulerJobs[name]
+	if !exists {
+		return nil, fmt.Errorf("job %s not found", name)
+	}
+	job.LastAttemptTime = s.now()
+
+	return clone(job), nil
+}
+
+// RecordSchedulerAttempt records the status of the attempt started at attemptTime as the job's status,
+// unless the job was deleted or attempted again since.
+func (s *Store) RecordSchedulerAttempt(name string, attemptTime time.Time, status *cloudscheduler.Status) {
+	s.schedulerMu.Lock()
+	defer s.schedulerMu.Unlock()
+
+	if job, exists := s.schedulerJobs[name]; exists && job.LastAttemptTime.Equal(attemptTime) {
+		job.Status = clone(status)
+	}
+}
+
+// runDueSchedulerJobs passes the enabled jobs whose schedule time has been reached to the scheduler handler
+// and schedules their next run. If the clock skipped several runs, e.g. because it was advanced by days,
+// the job runs once, like Cloud Scheduler doesn't catch up on missed runs. Without a handler, nothing happens.
+// Must be called with the write lock held.
+func (s *Store) runDueSchedulerJobs() {
+	handler := s.config().schedulerHandler
+	if handler == nil {
+		return
+	}
+
+	now := s.now()
+	for _, job := range s.schedulerJobs {
+		if job.State != cloudscheduler.StateEnabled || job.ScheduleTime.IsZero() || now.Before(job.ScheduleTime) {
+			continue
+		}
+		scheduleTime := job.ScheduleTime
+		job.LastAttemptTime = now
+		job.ScheduleTime = nextSchedulerRun(job, now)
+		handler(clone(job), scheduleTime)
+	}
+}
+
+// prepareSchedulerJob validates the schedule, time zone and target of a job and fills in the defaults
+// of its target.
+func prepareSchedulerJob(job *cloudscheduler.Job) error {
+	if job.Schedule == "" {
+		return fmt.Errorf("invalid job %s: schedule is required", job.Name)
+	}
+	if _, err := cloudscheduler.ParseSchedule(job.Schedule); err != nil {
+		return err
+	}
+	if _, err := time.LoadLocation(job.TimeZone); err != nil {
+		return fmt.Errorf("invalid time zone %q", job.TimeZone)
+	}
+	if job.AttemptDeadline != "" {
+		if d, err := time.ParseDuration(job.AttemptDeadline); err != nil || d <= 0 {
+			return fmt.Errorf("invalid attempt deadline %q", job.AttemptDeadline)
+		}
+	}
+
+	if countSchedulerTargets(job) != 1 {
+		return fmt.Errorf("invalid job %s: exactly one of httpTarget, pubsubTarget and appEngineHttpTarget is required", job.Name)
+	}
+	if job.HttpTarget != nil {
+		if !strings.HasPrefix(job.HttpTarget.Uri, "http://") && !strings.HasPrefix(job.HttpTarget.Uri, "https://") {
+			return fmt.Errorf("invalid HTTP target URI %q: must start with http:// or https://", job.HttpTarget.Uri)
+		}
+		if job.HttpTarget.HttpMethod == "" {
+			job.HttpTarget.HttpMethod = "POST"
+		}
+	}
+	if job.PubsubTarget != nil {
+		if !pubsubTopicPattern.MatchString(job.PubsubTarget.TopicName) {
+			return fmt.Errorf("invalid Pub/Sub topic %q: must be like projects/{project}/topics/{topic}", job.PubsubTarget.TopicName)
+		}
+	}
+	if job.AppEngineHttpTarget != nil && job.AppEngineHttpTarget.HttpMethod == "" {
+		job.AppEngineHttpTarget.HttpMethod = "POST"
+	}
+	return nil
+}
+
+// countSchedulerTargets returns the number of targets set in a job.
+func countSchedulerTargets(job *cloudscheduler.Job) int {
+	targets := 0
+	for _, set := range []bool{job.HttpTarget != nil, job.PubsubTarget != nil, job.AppEngineHttpTarget != nil} {
+		if set {
+			targets++
+		}
+	}
+	return targets
+}
+
+// nextSchedulerRun returns the first time after now the schedule of a valid job matches, in UTC.
+func nextSchedulerRun(job *cloudscheduler.Job, now time.Time) time.Time {
+	schedule, err := cloudscheduler.ParseSchedule(job.Schedule)
+	if err != nil {
+		return time.Time{}
+	}
+	location, err := time.LoadLocation(job.TimeZone)
+	if err != nil {
+		location = time.UTC
+	}
+	return schedule.Next(now.In(location)).UTC()
+}
+
+// setSchedulerJobFields returns the changeable fields that are set in req.
+func setSchedulerJobFields(req *cloudscheduler.Job) []string {
+	var fields []string
+	for _, field := range schedulerJobFields {
+		var set bool
+		switch field {
+		case "description":
+			set = req.Description != ""
+		case "schedule":
+			set = req.Schedule != ""
+		case "timeZone":
+			set = req.TimeZone != ""
+		case "retryConfig":
+			set = req.RetryConfig != nil
+		case "attemptDeadline":
+			set = req.AttemptDeadline != ""
+		case "httpTarget":
+			set = req.HttpTarget != nil
+		case "pubsubTarget":
+			set = req.PubsubTarget != nil
+		case "appEngineHttpTarget":
+			set = req.AppEngineHttpTarget != nil
+		}
+		if set {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
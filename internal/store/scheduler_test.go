@@ -0,0 +1,187 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/cloudscheduler"
+)
+
+const testSchedulerParent = "projects/test-project/locations/europe-west1"
+
+func newTestSchedulerJob(id, schedule string) *cloudscheduler.Job {
+	return &cloudscheduler.Job{
+		Name:       testSchedulerParent + "/jobs/" + id,
+		Schedule:   schedule,
+		HttpTarget: &cloudscheduler.HttpTarget{Uri: "http://localhost:9090/tasks/" + id},
+	}
+}
+
+func TestStore_CreateSchedulerJob(t *testing.T) {
+	s := New()
+	now := time.Date(2025, 1, 15, 10, 7, 0, 0, time.UTC)
+	s.SetClock(func() time.Time { return now })
+
+	job, err := s.CreateSchedulerJob(testSchedulerParent, newTestSchedulerJob("cleanup", "*/15 * * * *"))
+	if err != nil {
+		t.Fatalf("CreateSchedulerJob() error: %v", err)
+	}
+	if job.State != cloudscheduler.StateEnabled || job.HttpTarget.HttpMethod != "POST" {
+		t.Errorf("unexpected defaults: state %s, method %s", job.State, job.HttpTarget.HttpMethod)
+	}
+	if expected := time.Date(2025, 1, 15, 10, 15, 0, 0, time.UTC); !job.ScheduleTime.Equal(expected) {
+		t.Errorf("expected schedule time %v, got %v", expected, job.ScheduleTime)
+	}
+
+	if _, err := s.CreateSchedulerJob(testSchedulerParent, newTestSchedulerJob("cleanup", "* * * * *")); err == nil {
+		t.Error("expected an error for an existing job")
+	}
+
+	invalid := []*cloudscheduler.Job{
+		newTestSchedulerJob("bad.id", "* * * * *"),
+		newTestSchedulerJob("bad-schedule", "every minute"),
+		{Name: testSchedulerParent + "/jobs/no-target", Schedule: "* * * * *"},
+		{Name: testSchedulerParent + "/jobs/bad-topic", Schedule: "* * * * *", PubsubTarget: &cloudscheduler.PubsubTarget{TopicName: "topic"}},
+		{Name: "projects/other/locations/europe-west1/jobs/elsewhere", Schedule: "* * * * *", HttpTarget: &cloudscheduler.HttpTarget{Uri: "http://localhost"}},
+	}
+	for _, req := range invalid {
+		if _, err := s.CreateSchedulerJob(testSchedulerParent, req); err == nil {
+			t.Errorf("expected an error for job %s", req.Name)
+		}
+	}
+
+	// Without a name, a job ID is generated
+	req := newTestSchedulerJob("", "* * * * *")
+	req.Name = ""
+	if job, err := s.CreateSchedulerJob(testSchedulerParent, req); err != nil || len(s.ListSchedulerJobs(testSchedulerParent)) != 2 {
+		t.Errorf("expected a job with a generated name, got %+v, %v", job, err)
+	}
+}
+
+func TestStore_UpdateSchedulerJob(t *testing.T) {
+	s := New()
+	name := testSchedulerParent + "/jobs/cleanup"
+	if _, err := s.CreateSchedulerJob(testSchedulerParent, newTestSchedulerJob("cleanup", "0 * * * *")); err != nil {
+		t.Fatalf("CreateSchedulerJob() error: %v", err)
+	}
+
+	// Only the fields in the update mask change
+	job, err := s.UpdateSchedulerJob(name, &cloudscheduler.Job{Description: "ignored", Schedule: "0 9 * * *"}, []string{"schedule"})
+	if err != nil {
+		t.Fatalf("UpdateSchedulerJob() error: %v", err)
+	}
+	if job.Schedule != "0 9 * * *" || job.Description != "" || job.HttpTarget == nil {
+		t.Errorf("unexpected job after masked update: %+v", job)
+	}
+
+	// Without an update mask, the fields set in the request change, and a new target replaces the old one
+	job, err = s.UpdateSchedulerJob(name, &cloudscheduler.Job{
+		PubsubTarget: &cloudscheduler.PubsubTarget{TopicName: "projects/test-project/topics/cleanup"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("UpdateSchedulerJob() error: %v", err)
+	}
+	if job.HttpTarget != nil || job.PubsubTarget == nil || job.Schedule != "0 9 * * *" {
+		t.Errorf("unexpected job after update: %+v", job)
+	}
+
+	if _, err := s.UpdateSchedulerJob(name, &cloudscheduler.Job{}, []string{"state"}); err == nil {
+		t.Error("expected an error for an unknown update mask field")
+	}
+	if _, err := s.UpdateSchedulerJob(name, &cloudscheduler.Job{Schedule: "60 * * * *"}, nil); err == nil {
+		t.Error("expected an error for an invalid schedule")
+	}
+	if _, err := s.UpdateSchedulerJob(testSchedulerParent+"/jobs/missing", &cloudscheduler.Job{}, nil); err == nil {
+		t.Error("expected an error for a missing job")
+	}
+}
+
+func TestStore_SchedulerJobs_Tick(t *testing.T) {
+	s := New()
+	now := time.Date(2025, 1, 15, 10, 7, 0, 0, time.UTC)
+	s.SetClock(func() time.Time { return now })
+
+	type run struct {
+		name         string
+		scheduleTime time.Time
+	}
+	var runs []run
+	s.SetSchedulerHandler(func(job *cloudscheduler.Job, scheduleTime time.Time) {
+		runs = append(runs, run{job.Name, scheduleTime})
+	})
+
+	name := testSchedulerParent + "/jobs/cleanup"
+	if _, err := s.CreateSchedulerJob(testSchedulerParent, newTestSchedulerJob("cleanup", "*/15 * * * *")); err != nil {
+		t.Fatalf("CreateSchedulerJob() error: %v", err)
+	}
+
+	// Nothing runs before the schedule time
+	s.Tick()
+	if len(runs) != 0 {
+		t.Fatalf("expected no runs, got %+v", runs)
+	}
+
+	// Runs once the clock reached the schedule time and is scheduled for the next run
+	now = time.Date(2025, 1, 15, 10, 15, 1, 0, time.UTC)
+	s.Tick()
+	s.Tick()
+	if len(runs) != 1 || runs[0].name != name || !runs[0].scheduleTime.Equal(time.Date(2025, 1, 15, 10, 15, 0, 0, time.UTC)) {
+		t.Fatalf("expected one run at 10:15, got %+v", runs)
+	}
+	job := s.GetSchedulerJob(name)
+	if !job.LastAttemptTime.Equal(now) || !job.ScheduleTime.Equal(time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC)) {
+		t.Errorf("unexpected attempt time %v or schedule time %v", job.LastAttemptTime, job.ScheduleTime)
+	}
+
+	// The status of the attempt is recorded, unless another attempt started since
+	s.RecordSchedulerAttempt(name, job.LastAttemptTime, &cloudscheduler.Status{Code: 14, Message: "unavailable"})
+	s.RecordSchedulerAttempt(name, job.LastAttemptTime.Add(-time.Hour), &cloudscheduler.Status{})
+	if status := s.GetSchedulerJob(name).Status; status == nil || status.Code != 14 {
+		t.Errorf("expected status code 14, got %+v", status)
+	}
+
+	// Skipped runs aren't caught up on
+	now = now.Add(24 * time.Hour)
+	s.Tick()
+	if len(runs) != 2 {
+		t.Errorf("expected 2 runs after a day, got %d", len(runs))
+	}
+
+	// Paused jobs don't run until they're resumed
+	if _, err := s.PauseSchedulerJob(name); err != nil {
+		t.Fatalf("PauseSchedulerJob() error: %v", err)
+	}
+	now = now.Add(time.Hour)
+	s.Tick()
+	if len(runs) != 2 {
+		t.Errorf("expected no runs while paused, got %d", len(runs))
+	}
+	job, err := s.ResumeSchedulerJob(name)
+	if err != nil {
+		t.Fatalf("ResumeSchedulerJob() error: %v", err)
+	}
+	if job.State != cloudscheduler.StateEnabled || !job.ScheduleTime.After(now) {
+		t.Errorf("expected an enabled job scheduled after now, got %s at %v", job.State, job.ScheduleTime)
+	}
+}
+
+func TestStore_SchedulerJobs_TimeZone(t *testing.T) {
+	s := New()
+	s.SetClock(func() time.Time { return time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC) })
+
+	req := newTestSchedulerJob("report", "0 9 * * *")
+	req.TimeZone = "America/New_York"
+	job, err := s.CreateSchedulerJob(testSchedulerParent, req)
+	if err != nil {
+		t.Fatalf("CreateSchedulerJob() error: %v", err)
+	}
+	if expected := time.Date(2025, 1, 15, 14, 0, 0, 0, time.UTC); !job.ScheduleTime.Equal(expected) {
+		t.Errorf("expected schedule time %v, got %v", expected, job.ScheduleTime)
+	}
+
+	req = newTestSchedulerJob("invalid-zone", "0 9 * * *")
+	req.TimeZone = "Mars/Olympus"
+	if _, err := s.CreateSchedulerJob(testSchedulerParent, req); err == nil {
+		t.Error("expected an error for an unknown time zone")
+	}
+}
@@ -0,0 +1,177 @@
+package store
+
+import (
+	"mime"
+	"sort"
+	"strings"
+
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+)
+
+// =============================================================================
+// Cloud Storage Object Search
+// =============================================================================
+
+// metadataIndex indexes the live objects of all buckets by their custom metadata, so searches for
+// a metadata entry don't scan every object. Terms are a key alone, matching objects with the key,
+// or a key and value joined by metadataTermSeparator.
+type metadataIndex map[string]map[*ObjectData]struct{}
+
+// metadataTermSeparator joins the key and value of a metadata term. Keys can't contain it.
+const metadataTermSeparator = "\x00"
+
+// metadataTerms returns the index terms of custom metadata.
+func metadataTerms(metadata map[string]string) []string {
+	terms := make([]string, 0, 2*len(metadata))
+	for key, value := range metadata {
+		terms = append(terms, key, key+metadataTermSeparator+value)
+	}
+	return terms
+}
+
+// add indexes an object.
+func (idx metadataIndex) add(objData *ObjectData) {
+	for _, term := range metadataTerms(objData.Metadata.Metadata) {
+		if idx[term] == nil {
+			idx[term] = make(map[*ObjectData]struct{})
+		}
+		idx[term][objData] = struct{}{}
+	}
+}
+
+// remove removes an object from the index. It must be called before the object's metadata changes.
+func (idx metadataIndex) remove(objData *ObjectData) {
+	for _, term := range metadataTerms(objData.Metadata.Metadata) {
+		delete(idx[term], objData)
+		if len(idx[term]) == 0 {
+			delete(idx, term)
+		}
+	}
+}
+
+// rebuildMetadataIndex indexes all live objects from scratch. Callers must hold the storage write lock.
+func (s *Store) rebuildMetadataIndex() {
+	s.objectMetadataIndex = make(metadataIndex)
+	for _, bucketObjects := range s.objects {
+		for _, objData := range bucketObjects {
+			s.objectMetadataIndex.add(objData)
+		}
+	}
+}
+
+// ObjectQuery selects objects across buckets. Empty fields match every object.
+type ObjectQuery struct {
+	// Bucket limits the search to one bucket.
+	Bucket string
+	// Prefix matches objects whose names start with it.
+	Prefix string
+	// ContentType matches objects of a media type, ignoring parameters like charset,
+	// or of all subtypes of a type with a wildcard like "image/*".
+	ContentType string
+	// Metadata matches objects with all of the custom metadata entries; an empty value matches any value of the key.
+	Metadata map[string]string
+	// MinSize and MaxSize limit the size of matching objects in bytes; negative values don't.
+	MinSize int64
+	MaxSize int64
+}
+
+// matches reports whether an object matches the query. Metadata is checked by SearchObjects.
+func (q *ObjectQuery) matches(obj *storage.Object) bool {
+	if q.Bucket != "" && obj.Bucket != q.Bucket {
+		return false
+	}
+	if q.Prefix != "" && !hasPrefix(obj.Name, q.Prefix) {
+		return false
+	}
+	if q.MinSize >= 0 && obj.Size < uint64(q.MinSize) {
+		return false
+	}
+	if q.MaxSize >= 0 && obj.Size > uint64(q.MaxSize) {
+		return false
+	}
+	if q.ContentType != "" {
+		mediaType, _, err := mime.ParseMediaType(obj.ContentType)
+		if err != nil {
+			mediaType = obj.ContentType
+		}
+		if typ, found := strings.CutSuffix(q.ContentType, "/*"); found {
+			return strings.HasPrefix(mediaType, typ+"/")
+		}
+		return strings.EqualFold(mediaType, q.ContentType)
+	}
+	return true
+}
+
+// SearchObjects returns the live objects of all buckets matching a query, ordered by bucket and name,
+// along with the total number of matches. At most limit objects are returned if limit is positive.
+// Metadata entries are looked up in an index, so searches by metadata don't scan all objects.
+func (s *Store) SearchObjects(query ObjectQuery, limit int) ([]*storage.Object, int) {
+	s.storageMu.RLock()
+	defer s.storageMu.RUnlock()
+
+	var candidates []*ObjectData
+	if len(query.Metadata) > 0 {
+		candidates = s.searchMetadataIndex(query.Metadata)
+	} else {
+		for bucketName, bucketObjects := range s.objects {
+			if query.Bucket != "" && bucketName != query.Bucket {
+				continue
+			}
+			for _, objData := range bucketObjects {
+				candidates = append(candidates, objData)
+			}
+		}
+	}
+
+	var objects []*storage.Object
+	for _, objData := range candidates {
+		if query.matches(objData.Metadata) {
+			objects = append(objects, objData.Metadata)
+		}
+	}
+
+	sort.Slice(objects, func(i, j int) bool {
+		if objects[i].Bucket != objects[j].Bucket {
+			return objects[i].Bucket < objects[j].Bucket
+		}
+		return objects[i].Name < objects[j].Name
+	})
+
+	total := len(objects)
+	if limit > 0 && len(objects) > limit {
+		objects = objects[:limit]
+	}
+	return clone(objects), total
+}
+
+// searchMetadataIndex returns the objects with all of the metadata entries, intersecting the index entries
+// of the terms starting with the one with the fewest objects. Callers must hold the storage lock.
+func (s *Store) searchMetadataIndex(metadata map[string]string) []*ObjectData {
+	var sets []map[*ObjectData]struct{}
+	for key, value := range metadata {
+		term := key
+		if value != "" {
+			term += metadataTermSeparator + value
+		}
+		set, ok := s.objectMetadataIndex[term]
+		if !ok {
+			return nil
+		}
+		sets = append(sets, set)
+	}
+	sort.Slice(sets, func(i, j int) bool { return len(sets[i]) < len(sets[j]) })
+
+	var objects []*ObjectData
+	for objData := range sets[0] {
+		found := true
+		for _, set := range sets[1:] {
+			if _, found = set[objData]; !found {
+				break
+			}
+		}
+		if found {
+			objects = append(objects, objData)
+		}
+	}
+	return objects
+}
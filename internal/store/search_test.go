@@ -0,0 +1,130 @@
+package store
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+)
+
+// searchNames returns the bucket and name of the objects matching a query.
+func searchNames(s *Store, query ObjectQuery) []string {
+	objects, _ := s.SearchObjects(query, 0)
+	names := make([]string, 0, len(objects))
+	for _, obj := range objects {
+		names = append(names, obj.Bucket+"/"+obj.Name)
+	}
+	return names
+}
+
+func TestStore_SearchObjects(t *testing.T) {
+	s := New()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "assets"})
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "logs"})
+	_, _ = s.CreateObject("assets", "images/cat.png", "image/png", make([]byte, 2048), map[string]string{"test": "checkout"})
+	_, _ = s.CreateObject("assets", "images/dog.jpg", "image/jpeg", make([]byte, 10), map[string]string{"test": "login", "owner": "ci"})
+	_, _ = s.CreateObject("assets", "data.json", "application/json; charset=utf-8", []byte("{}"), nil)
+	_, _ = s.CreateObject("logs", "run-1.txt", "text/plain", []byte("ok"), map[string]string{"test": "checkout"})
+
+	tests := []struct {
+		name     string
+		query    ObjectQuery
+		expected []string
+	}{
+		{"everything", ObjectQuery{MinSize: -1, MaxSize: -1}, []string{"assets/data.json", "assets/images/cat.png", "assets/images/dog.jpg", "logs/run-1.txt"}},
+		{"metadata value", ObjectQuery{Metadata: map[string]string{"test": "checkout"}, MinSize: -1, MaxSize: -1}, []string{"assets/images/cat.png", "logs/run-1.txt"}},
+		{"metadata key", ObjectQuery{Metadata: map[string]string{"owner": ""}, MinSize: -1, MaxSize: -1}, []string{"assets/images/dog.jpg"}},
+		{"all metadata entries", ObjectQuery{Metadata: map[string]string{"test": "login", "owner": "ci"}, MinSize: -1, MaxSize: -1}, []string{"assets/images/dog.jpg"}},
+		{"unknown metadata", ObjectQuery{Metadata: map[string]string{"test": "unknown"}, MinSize: -1, MaxSize: -1}, []string{}},
+		{"metadata and bucket", ObjectQuery{Bucket: "logs", Metadata: map[string]string{"test": "checkout"}, MinSize: -1, MaxSize: -1}, []string{"logs/run-1.txt"}},
+		{"prefix", ObjectQuery{Prefix: "images/", MinSize: -1, MaxSize: -1}, []string{"assets/images/cat.png", "assets/images/dog.jpg"}},
+		{"content type wildcard", ObjectQuery{ContentType: "image/*", MinSize: -1, MaxSize: -1}, []string{"assets/images/cat.png", "assets/images/dog.jpg"}},
+		{"content type with parameters", ObjectQuery{ContentType: "application/json", MinSize: -1, MaxSize: -1}, []string{"assets/data.json"}},
+		{"size range", ObjectQuery{MinSize: 3, MaxSize: 1024}, []string{"assets/images/dog.jpg"}},
+		{"min size", ObjectQuery{MinSize: 1024, MaxSize: -1}, []string{"assets/images/cat.png"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if names := searchNames(s, tt.query); !slices.Equal(names, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, names)
+			}
+		})
+	}
+
+	objects, total := s.SearchObjects(ObjectQuery{MinSize: -1, MaxSize: -1}, 2)
+	if len(objects) != 2 || total != 4 {
+		t.Errorf("expected 2 of 4 objects, got %d of %d", len(objects), total)
+	}
+}
+
+func TestStore_SearchObjects_Index(t *testing.T) {
+	s := New()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{
+		Name:             "assets",
+		SoftDeletePolicy: &storage.SoftDeletePolicy{RetentionDurationSeconds: 3600},
+	})
+	checkout := ObjectQuery{Metadata: map[string]string{"test": "checkout"}, MinSize: -1, MaxSize: -1}
+	login := ObjectQuery{Metadata: map[string]string{"test": "login"}, MinSize: -1, MaxSize: -1}
+
+	_, _ = s.CreateObject("assets", "a.txt", "text/plain", []byte("a"), map[string]string{"test": "checkout"})
+	if names := searchNames(s, checkout); !slices.Equal(names, []string{"assets/a.txt"}) {
+		t.Fatalf("expected the created object to be found, got %v", names)
+	}
+
+	// Overwriting replaces the indexed metadata
+	_, _ = s.CreateObject("assets", "a.txt", "text/plain", []byte("a"), map[string]string{"test": "login"})
+	if names := searchNames(s, checkout); len(names) != 0 {
+		t.Errorf("expected the overwritten metadata to be removed from the index, got %v", names)
+	}
+	if names := searchNames(s, login); len(names) != 1 {
+		t.Errorf("expected the new metadata to be indexed, got %v", names)
+	}
+
+	if _, err := s.UpdateObjectWithPreconditions("assets", "a.txt", &storage.ObjectUpdateRequest{Metadata: map[string]string{"test": "checkout"}}, Preconditions{}); err != nil {
+		t.Fatalf("UpdateObjectWithPreconditions() error: %v", err)
+	}
+	if names := searchNames(s, checkout); len(names) != 1 {
+		t.Errorf("expected the updated metadata to be indexed, got %v", names)
+	}
+
+	login2 := "login"
+	if _, err := s.PatchObject("assets", "a.txt", &storage.ObjectPatchRequest{Metadata: map[string]*string{"test": &login2}}, Preconditions{}); err != nil {
+		t.Fatalf("PatchObject() error: %v", err)
+	}
+	if names := searchNames(s, checkout); len(names) != 0 {
+		t.Errorf("expected the patched metadata to be removed from the index, got %v", names)
+	}
+	if names := searchNames(s, login); len(names) != 1 {
+		t.Errorf("expected the patched metadata to be indexed, got %v", names)
+	}
+
+	// Soft-deleted objects aren't found until they are restored
+	live := s.GetObject("assets", "a.txt")
+	if err := s.DeleteObject("assets", "a.txt"); err != nil {
+		t.Fatalf("DeleteObject() error: %v", err)
+	}
+	if names := searchNames(s, login); len(names) != 0 {
+		t.Errorf("expected the deleted object to be removed from the index, got %v", names)
+	}
+	if _, err := s.RestoreObject("assets", "a.txt", live.Generation); err != nil {
+		t.Fatalf("RestoreObject() error: %v", err)
+	}
+	if names := searchNames(s, login); len(names) != 1 {
+		t.Errorf("expected the restored object to be indexed, got %v", names)
+	}
+
+	// Restoring a snapshot rebuilds the index
+	var snapshot bytes.Buffer
+	if err := s.Snapshot(&snapshot); err != nil {
+		t.Fatalf("Snapshot() error: %v", err)
+	}
+	restored := New()
+	if _, err := restored.Restore(&snapshot); err != nil {
+		t.Fatalf("Restore() error: %v", err)
+	}
+	if names := searchNames(restored, login); !slices.Equal(names, []string{"assets/a.txt"}) {
+		t.Errorf("expected the restored snapshot to be indexed, got %v", names)
+	}
+}
@@ -0,0 +1,461 @@
+package store
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/billing"
+	"github.com/katharinasick/gcp-api-mock/internal/blob"
+	"github.com/katharinasick/gcp-api-mock/internal/cloudrun"
+	"github.com/katharinasick/gcp-api-mock/internal/cloudscheduler"
+	"github.com/katharinasick/gcp-api-mock/internal/compute"
+	"github.com/katharinasick/gcp-api-mock/internal/eventarc"
+	"github.com/katharinasick/gcp-api-mock/internal/firestore"
+	"github.com/katharinasick/gcp-api-mock/internal/gke"
+	"github.com/katharinasick/gcp-api-mock/internal/logging"
+	"github.com/katharinasick/gcp-api-mock/internal/memorystore"
+	"github.com/katharinasick/gcp-api-mock/internal/monitoring"
+	"github.com/katharinasick/gcp-api-mock/internal/orgpolicy"
+	"github.com/katharinasick/gcp-api-mock/internal/registry"
+	"github.com/katharinasick/gcp-api-mock/internal/sqladmin"
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+)
+
+// =============================================================================
+// Snapshot Operations
+// =============================================================================
+
+// snapshotVersion is the version of the snapshot format written by Snapshot.
+const snapshotVersion = 1
+
+// snapshotStateFile is the name of the archive entry that holds all resource metadata.
+const snapshotStateFile = "state.json"
+
+// snapshotState is the resource metadata of a snapshot. Content is stored in separate
+// archive entries, which the state refers to by entry name.
+type snapshotState struct {
+	Version            int                                          `json:"version"`
+	CreateTime         time.Time                                    `json:"createTime"`
+	Buckets            map[string]*storage.Bucket                   `json:"buckets"`
+	Objects            map[string]map[string]*snapshotObject        `json:"objects"`
+	SoftDeletedObjects map[string][]*snapshotObject                 `json:"softDeletedObjects"`
+	Notifications      map[string]map[string]*storage.Notification  `json:"notifications"`
+	NotificationSeq    int                                          `json:"notificationSeq"`
+	BucketQuotas       map[string]BucketQuota                       `json:"bucketQuotas,omitempty"`
+	BucketPolicies     map[string]*storage.Policy                   `json:"bucketPolicies,omitempty"`
+	HmacKeys           map[string]*storage.HmacKey                  `json:"hmacKeys,omitempty"`
+	SQLInstances       map[string]*sqladmin.DatabaseInstance        `json:"sqlInstances"`
+	SQLDatabases       map[string]map[string]*sqladmin.Database     `json:"sqlDatabases"`
+	SQLUsers           map[string]map[string]*sqladmin.User         `json:"sqlUsers"`
+	SQLOperations      map[string]*sqladmin.Operation               `json:"sqlOperations"`
+	SQLPendingCreates  map[string]time.Time                         `json:"sqlPendingCreates,omitempty"`
+	SQLMaintenance     map[string]*sqlMaintenance                   `json:"sqlMaintenance,omitempty"`
+	Documents          map[string]*firestore.Document               `json:"documents"`
+	Repositories       map[string]*snapshotRepository               `json:"repositories"`
+	RegistryBlobs      map[string]string                            `json:"registryBlobs"`
+	RunServices        map[string]*cloudrun.Service                 `json:"runServices"`
+	RunRevisions       map[string]*cloudrun.Revision                `json:"runRevisions"`
+	RunOperations      map[string]*cloudrun.Operation               `json:"runOperations"`
+	MetricDescriptors  map[string]*monitoring.MetricDescriptor      `json:"metricDescriptors,omitempty"`
+	TimeSeries         map[string]map[string]*monitoring.TimeSeries `json:"timeSeries,omitempty"`
+	LogEntries         []*logging.LogEntry                          `json:"logEntries,omitempty"`
+	LogEntrySeq        int                                          `json:"logEntrySeq,omitempty"`
+	SchedulerJobs      map[string]*cloudscheduler.Job               `json:"schedulerJobs,omitempty"`
+	EventarcTriggers   map[string]*eventarc.Trigger                 `json:"eventarcTriggers,omitempty"`
+	EventarcOperations map[string]*eventarc.Operation               `json:"eventarcOperations,omitempty"`
+	RedisInstances     map[string]*memorystore.Instance             `json:"redisInstances,omitempty"`
+	RedisOperations    map[string]*memorystore.Operation            `json:"redisOperations,omitempty"`
+	RedisRangeSeq      int                                          `json:"redisRangeSeq,omitempty"`
+	GKEClusters        map[string]*gke.Cluster                      `json:"gkeClusters,omitempty"`
+	GKEOperations      map[string]*gke.Operation                    `json:"gkeOperations,omitempty"`
+	GKERangeSeq        int                                          `json:"gkeRangeSeq,omitempty"`
+	ComputeNetworks    map[string]*compute.Network                  `json:"computeNetworks,omitempty"`
+	ComputeSubnetworks map[string]*compute.Subnetwork               `json:"computeSubnetworks,omitempty"`
+	ComputeOperations  map[string]*compute.Operation                `json:"computeOperations,omitempty"`
+	BillingBudgets     map[string]*billing.Budget                   `json:"billingBudgets,omitempty"`
+	OrgPolicies        map[string]*orgpolicy.Policy                 `json:"orgPolicies,omitempty"`
+}
+
+// snapshotObject is an object in a snapshot or a state entry.
+type snapshotObject struct {
+	Metadata *storage.Object `json:"metadata"`
+	// Content is the name of the archive entry or the key of the state entry with the object content,
+	// or empty if the content was evicted.
+	Content string `json:"content,omitempty"`
+}
+
+// snapshotRepository is a registry repository in a snapshot.
+// Unlike registry.Repository, all of its fields are serialized.
+type snapshotRepository struct {
+	Manifests map[string]*snapshotManifest `json:"manifests"`
+	Tags      map[string]string            `json:"tags"`
+	Blobs     []string                     `json:"blobs"`
+}
+
+// newSnapshotRepository returns the snapshot of a registry repository.
+func newSnapshotRepository(repo *registry.Repository) *snapshotRepository {
+	snapshotRepo := &snapshotRepository{
+		Manifests: make(map[string]*snapshotManifest),
+		Tags:      repo.Tags,
+	}
+	for digest, manifest := range repo.Manifests {
+		snapshotRepo.Manifests[digest] = &snapshotManifest{MediaType: manifest.MediaType, Content: manifest.Content, Created: manifest.Created}
+	}
+	for digest := range repo.Blobs {
+		snapshotRepo.Blobs = append(snapshotRepo.Blobs, digest)
+	}
+	sort.Strings(snapshotRepo.Blobs)
+	return snapshotRepo
+}
+
+// restore returns the registry repository with the given name a snapshot describes.
+func (repo *snapshotRepository) restore(name string) *registry.Repository {
+	restored := &registry.Repository{
+		Name:      name,
+		Manifests: make(map[string]*registry.Manifest),
+		Tags:      orEmpty(repo.Tags),
+		Blobs:     make(map[string]bool),
+	}
+	for digest, manifest := range repo.Manifests {
+		restored.Manifests[digest] = &registry.Manifest{Digest: digest, MediaType: manifest.MediaType, Content: manifest.Content, Created: manifest.Created}
+	}
+	for _, digest := range repo.Blobs {
+		restored.Blobs[digest] = true
+	}
+	return restored
+}
+
+// snapshotManifest is a registry manifest in a snapshot, including its content.
+type snapshotManifest struct {
+	MediaType string    `json:"mediaType"`
+	Content   []byte    `json:"content"`
+	Created   time.Time `json:"created"`
+}
+
+// SnapshotSummary counts the resources restored from a snapshot.
+type SnapshotSummary struct {
+	Buckets      int `json:"buckets"`
+	Objects      int `json:"objects"`
+	SQLInstances int `json:"sqlInstances"`
+	Documents    int `json:"documents"`
+	Repositories int `json:"repositories"`
+	RunServices  int `json:"runServices"`
+}
+
+// Snapshot writes the entire state of the store, including object and registry blob content,
+// to w as a tar archive. In-progress uploads are not included.
+// All resource families are read-locked while the archive is written, so the snapshot is consistent.
+func (s *Store) Snapshot(w io.Writer) error {
+	s.rLockAll()
+	defer s.rUnlockAll()
+
+	now := s.now()
+	state := &snapshotState{
+		Version:            snapshotVersion,
+		CreateTime:         now,
+		Buckets:            s.buckets,
+		Objects:            make(map[string]map[string]*snapshotObject),
+		SoftDeletedObjects: make(map[string][]*snapshotObject),
+		Notifications:      s.notifications,
+		NotificationSeq:    s.notificationSeq,
+		BucketQuotas:       s.bucketQuotas,
+		BucketPolicies:     s.bucketPolicies,
+		HmacKeys:           s.hmacKeys,
+		SQLInstances:       s.sqlInstances,
+		SQLDatabases:       s.sqlDatabases,
+		SQLUsers:           s.sqlUsers,
+		SQLOperations:      s.sqlOperations,
+		SQLPendingCreates:  s.sqlPendingCreates,
+		SQLMaintenance:     s.sqlMaintenance,
+		Documents:          s.documents,
+		Repositories:       make(map[string]*snapshotRepository),
+		RegistryBlobs:      make(map[string]string),
+		RunServices:        s.runServices,
+		RunRevisions:       s.runRevisions,
+		RunOperations:      s.runOperations,
+		MetricDescriptors:  s.metricDescriptors,
+		TimeSeries:         s.timeSeries,
+		LogEntries:         s.logEntries,
+		LogEntrySeq:        s.logEntrySeq,
+		SchedulerJobs:      s.schedulerJobs,
+		EventarcTriggers:   s.eventarcTriggers,
+		EventarcOperations: s.eventarcOperations,
+		RedisInstances:     s.redisInstances,
+		RedisOperations:    s.redisOperations,
+		RedisRangeSeq:      s.redisRangeSeq,
+		GKEClusters:        s.gkeClusters,
+		GKEOperations:      s.gkeOperations,
+		GKERangeSeq:        s.gkeRangeSeq,
+		ComputeNetworks:    s.computeNetworks,
+		ComputeSubnetworks: s.computeSubnetworks,
+		ComputeOperations:  s.computeOperations,
+		BillingBudgets:     s.billingBudgets,
+		OrgPolicies:        s.orgPolicies,
+	}
+
+	// Collect the content entries in a stable order, so equal states produce equal archives
+	var entries []string
+	contents := make(map[string]blob.Blob)
+	addContent := func(name string, content blob.Blob) string {
+		entries = append(entries, name)
+		contents[name] = content
+		return name
+	}
+	// Evicted content isn't written; restoring the snapshot keeps it evicted
+	addObjectContent := func(name string, content blob.Blob) string {
+		if blob.IsEvicted(content) {
+			return ""
+		}
+		return addContent(name, content)
+	}
+
+	for bucketName, bucketObjects := range s.objects {
+		state.Objects[bucketName] = make(map[string]*snapshotObject)
+		for objectName, objData := range bucketObjects {
+			state.Objects[bucketName][objectName] = &snapshotObject{
+				Metadata: objData.Metadata,
+				Content:  addObjectContent("objects/"+bucketName+"/"+objectName, objData.Content),
+			}
+		}
+	}
+	for bucketName, softDeleted := range s.softDeletedObjects {
+		for _, objData := range softDeleted {
+			name := fmt.Sprintf("soft-deleted/%s/%s#%d", bucketName, objData.Metadata.Name, objData.Metadata.Generation)
+			state.SoftDeletedObjects[bucketName] = append(state.SoftDeletedObjects[bucketName], &snapshotObject{
+				Metadata: objData.Metadata,
+				Content:  addObjectContent(name, objData.Content),
+			})
+		}
+	}
+	for name, repo := range s.repositories {
+		state.Repositories[name] = newSnapshotRepository(repo)
+	}
+	for digest, content := range s.registryBlobs {
+		state.RegistryBlobs[digest] = addContent("registry/blobs/"+digest, content)
+	}
+	sort.Strings(entries)
+
+	stateJSON, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot state: %w", err)
+	}
+
+	tw := tar.NewWriter(w)
+	if err := tw.WriteHeader(&tar.Header{Name: snapshotStateFile, Mode: 0o644, Size: int64(len(stateJSON)), ModTime: now}); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if _, err := tw.Write(stateJSON); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	for _, name := range entries {
+		if err := writeSnapshotContent(tw, name, contents[name], now); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return nil
+}
+
+// writeSnapshotContent writes a content blob as an archive entry.
+func writeSnapshotContent(tw *tar.Writer, name string, content blob.Blob, modTime time.Time) error {
+	reader, err := content.Open()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	defer reader.Close()
+
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: content.Size(), ModTime: modTime}); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if _, err := io.Copy(tw, reader); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return nil
+}
+
+// Restore replaces the entire state of the store with a snapshot written by Snapshot.
+// The current state is kept if the snapshot is invalid.
+func (s *Store) Restore(r io.Reader) (*SnapshotSummary, error) {
+	backend := s.config().blobs
+
+	// Read the archive before touching the store; content is streamed to the blob backend
+	var state *snapshotState
+	contents := make(map[string]blob.Blob)
+	releaseContents := func() {
+		for _, content := range contents {
+			content.Release()
+		}
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			releaseContents()
+			return nil, fmt.Errorf("invalid snapshot: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		if header.Name == snapshotStateFile {
+			state = &snapshotState{}
+			if err := json.NewDecoder(tr).Decode(state); err != nil {
+				releaseContents()
+				return nil, fmt.Errorf("invalid snapshot state: %w", err)
+			}
+			continue
+		}
+
+		content, err := backend.Write(tr)
+		if err != nil {
+			releaseContents()
+			return nil, fmt.Errorf("failed to restore %s: %w", header.Name, err)
+		}
+		contents[header.Name] = content
+	}
+
+	if state == nil {
+		releaseContents()
+		return nil, fmt.Errorf("invalid snapshot: %s is missing", snapshotStateFile)
+	}
+	if state.Version != snapshotVersion {
+		releaseContents()
+		return nil, fmt.Errorf("invalid snapshot: unsupported version %d", state.Version)
+	}
+
+	// Link the content entries to the restored resources
+	used := make(map[string]bool)
+	content := func(name string) (blob.Blob, error) {
+		c, exists := contents[name]
+		if !exists {
+			return nil, fmt.Errorf("invalid snapshot: content %s is missing", name)
+		}
+		used[name] = true
+		return c, nil
+	}
+	objectContent := func(obj *snapshotObject) (blob.Blob, error) {
+		if obj.Content == "" {
+			return blob.Evicted(int64(obj.Metadata.Size)), nil
+		}
+		return content(obj.Content)
+	}
+
+	summary := &SnapshotSummary{}
+	objects := make(map[string]map[string]*ObjectData)
+	for bucketName, bucketObjects := range state.Objects {
+		objects[bucketName] = make(map[string]*ObjectData)
+		for objectName, obj := range bucketObjects {
+			c, err := objectContent(obj)
+			if err != nil {
+				releaseContents()
+				return nil, err
+			}
+			objects[bucketName][objectName] = &ObjectData{Metadata: obj.Metadata, Content: c}
+			summary.Objects++
+		}
+	}
+	softDeleted := make(map[string][]*ObjectData)
+	for bucketName, bucketObjects := range state.SoftDeletedObjects {
+		for _, obj := range bucketObjects {
+			c, err := objectContent(obj)
+			if err != nil {
+				releaseContents()
+				return nil, err
+			}
+			softDeleted[bucketName] = append(softDeleted[bucketName], &ObjectData{Metadata: obj.Metadata, Content: c})
+		}
+	}
+	registryBlobs := make(map[string]blob.Blob)
+	for digest, name := range state.RegistryBlobs {
+		c, err := content(name)
+		if err != nil {
+			releaseContents()
+			return nil, err
+		}
+		registryBlobs[digest] = c
+	}
+	repositories := make(map[string]*registry.Repository)
+	for name, repo := range state.Repositories {
+		repositories[name] = repo.restore(name)
+	}
+
+	// Entries no resource refers to are not kept
+	for name, c := range contents {
+		if !used[name] {
+			c.Release()
+		}
+	}
+
+	s.lockAll()
+	defer s.unlockAll()
+
+	s.reset()
+	s.buckets = orEmpty(state.Buckets)
+	s.objects = objects
+	s.rebuildMetadataIndex()
+	s.softDeletedObjects = softDeleted
+	s.notifications = orEmpty(state.Notifications)
+	s.notificationSeq = state.NotificationSeq
+	s.bucketQuotas = orEmpty(state.BucketQuotas)
+	s.bucketPolicies = orEmpty(state.BucketPolicies)
+	s.hmacKeys = orEmpty(state.HmacKeys)
+	s.sqlInstances = orEmpty(state.SQLInstances)
+	s.sqlDatabases = orEmpty(state.SQLDatabases)
+	s.sqlUsers = orEmpty(state.SQLUsers)
+	s.sqlOperations = orEmpty(state.SQLOperations)
+	s.sqlPendingCreates = orEmpty(state.SQLPendingCreates)
+	s.sqlMaintenance = orEmpty(state.SQLMaintenance)
+	s.documents = orEmpty(state.Documents)
+	s.repositories = repositories
+	s.registryBlobs = registryBlobs
+	s.runServices = orEmpty(state.RunServices)
+	s.runRevisions = orEmpty(state.RunRevisions)
+	s.runOperations = orEmpty(state.RunOperations)
+	s.metricDescriptors = orEmpty(state.MetricDescriptors)
+	s.timeSeries = orEmpty(state.TimeSeries)
+	s.logEntries = state.LogEntries
+	s.logEntrySeq = state.LogEntrySeq
+	s.schedulerJobs = orEmpty(state.SchedulerJobs)
+	s.eventarcTriggers = orEmpty(state.EventarcTriggers)
+	s.eventarcOperations = orEmpty(state.EventarcOperations)
+	s.redisInstances = orEmpty(state.RedisInstances)
+	s.redisOperations = orEmpty(state.RedisOperations)
+	s.redisRangeSeq = state.RedisRangeSeq
+	s.gkeClusters = orEmpty(state.GKEClusters)
+	s.gkeOperations = orEmpty(state.GKEOperations)
+	s.gkeRangeSeq = state.GKERangeSeq
+	s.computeNetworks = orEmpty(state.ComputeNetworks)
+	s.computeSubnetworks = orEmpty(state.ComputeSubnetworks)
+	s.computeOperations = orEmpty(state.ComputeOperations)
+	s.billingBudgets = orEmpty(state.BillingBudgets)
+	s.orgPolicies = orEmpty(state.OrgPolicies)
+
+	summary.Buckets = len(s.buckets)
+	summary.SQLInstances = len(s.sqlInstances)
+	summary.Documents = len(s.documents)
+	summary.Repositories = len(s.repositories)
+	summary.RunServices = len(s.runServices)
+
+	return summary, nil
+}
+
+// orEmpty returns m, or an empty map if m is nil, e.g. because a snapshot has no entry for it.
+func orEmpty[K comparable, V any](m map[K]V) map[K]V {
+	if m == nil {
+		return make(map[K]V)
+	}
+	return m
+}
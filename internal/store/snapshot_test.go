@@ -0,0 +1,128 @@
+package store
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/katharinasick/gcp-api-mock/internal/cloudrun"
+	"github.com/katharinasick/gcp-api-mock/internal/firestore"
+	"github.com/katharinasick/gcp-api-mock/internal/sqladmin"
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+)
+
+func TestStore_SnapshotAndRestore(t *testing.T) {
+	s := New()
+
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{
+		Name:             "test-bucket",
+		SoftDeletePolicy: &storage.SoftDeletePolicy{RetentionDurationSeconds: 3600},
+	})
+	_, _ = s.CreateObject("test-bucket", "dir/file.txt", "text/plain", []byte("hello"), map[string]string{"key": "value"})
+	_, _ = s.CreateObject("test-bucket", "deleted.txt", "text/plain", []byte("bye"), nil)
+	_ = s.DeleteObject("test-bucket", "deleted.txt")
+	_, _, _ = s.CreateSQLInstance(&sqladmin.InstanceInsertRequest{Name: "test-instance"})
+	_, _ = s.CreateDocument(testDocumentsRoot, "users", "alice", map[string]*firestore.Value{"name": testStringValue("Alice")})
+	_, _ = s.CreateRunService(testRunParent, "api", &cloudrun.Service{
+		Template: &cloudrun.RevisionTemplate{Containers: []*cloudrun.Container{{Image: "nginx"}}},
+	})
+
+	id, _ := s.StartRegistryUpload("repo")
+	_ = s.CompleteRegistryUpload("repo", id, testDigest("layer"), strings.NewReader("layer"))
+	_, _ = s.PutManifest("repo", "latest", "application/vnd.oci.image.manifest.v1+json", []byte(`{"schemaVersion":2}`))
+
+	var snapshot bytes.Buffer
+	if err := s.Snapshot(&snapshot); err != nil {
+		t.Fatalf("Snapshot() error: %v", err)
+	}
+
+	restored := New()
+	_, _ = restored.CreateBucket(&storage.BucketInsertRequest{Name: "replaced-bucket"})
+
+	summary, err := restored.Restore(bytes.NewReader(snapshot.Bytes()))
+	if err != nil {
+		t.Fatalf("Restore() error: %v", err)
+	}
+	if summary.Buckets != 1 || summary.Objects != 1 || summary.SQLInstances != 1 || summary.Documents != 1 ||
+		summary.Repositories != 1 || summary.RunServices != 1 {
+		t.Errorf("unexpected summary: %+v", summary)
+	}
+
+	if restored.GetBucket("replaced-bucket") != nil {
+		t.Error("expected existing state to be replaced")
+	}
+	if content := restored.GetObjectContent("test-bucket", "dir/file.txt"); string(content) != "hello" {
+		t.Errorf("expected object content 'hello', got '%s'", string(content))
+	}
+	if obj := restored.GetObject("test-bucket", "dir/file.txt"); obj == nil || obj.Metadata["key"] != "value" {
+		t.Errorf("expected object metadata to be restored, got %+v", obj)
+	}
+	if len(restored.ListSoftDeletedObjects("test-bucket", "")) != 1 {
+		t.Error("expected soft-deleted object to be restored")
+	}
+	if restored.GetSQLInstance("test-instance") == nil || restored.GetSQLDatabase("test-instance", "mysql") == nil {
+		t.Error("expected SQL instance and its databases to be restored")
+	}
+	if restored.GetDocument(testDocumentsRoot+"/users/alice") == nil {
+		t.Error("expected document to be restored")
+	}
+	if restored.GetRunService(testRunParent+"/services/api") == nil {
+		t.Error("expected Cloud Run service to be restored")
+	}
+	if restored.GetManifest("repo", "latest") == nil {
+		t.Error("expected registry manifest to be restored")
+	}
+	_, layer, err := restored.OpenRegistryBlob("repo", testDigest("layer"))
+	if err != nil {
+		t.Fatalf("expected registry blob to be restored: %v", err)
+	}
+	data, _ := io.ReadAll(layer)
+	layer.Close()
+	if string(data) != "layer" {
+		t.Errorf("expected blob content 'layer', got '%s'", string(data))
+	}
+
+	// The restored store keeps working
+	if _, err := restored.CreateObject("test-bucket", "new.txt", "text/plain", []byte("new"), nil); err != nil {
+		t.Errorf("CreateObject() after restore error: %v", err)
+	}
+}
+
+func TestStore_Restore_Invalid(t *testing.T) {
+	s := New()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "kept-bucket"})
+
+	var empty bytes.Buffer
+	if err := New().Snapshot(&empty); err != nil {
+		t.Fatalf("Snapshot() error: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		archive []byte
+	}{
+		{"not a tar archive", []byte("garbage")},
+		{"empty archive", make([]byte, 1024)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.Restore(bytes.NewReader(tt.archive))
+			if err == nil || !strings.Contains(err.Error(), "invalid") {
+				t.Errorf("expected invalid snapshot error, got %v", err)
+			}
+			if s.GetBucket("kept-bucket") == nil {
+				t.Error("expected state to be kept after a failed restore")
+			}
+		})
+	}
+
+	// Restoring an empty snapshot clears the store
+	if _, err := s.Restore(&empty); err != nil {
+		t.Fatalf("Restore() error: %v", err)
+	}
+	if s.GetBucket("kept-bucket") != nil {
+		t.Error("expected empty snapshot to clear the store")
+	}
+}
@@ -2,27 +2,79 @@
 package store
 
 import (
+	"bytes"
 	"crypto/md5"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"hash/crc32"
+	"io"
+	"regexp"
+	"slices"
 	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
-
+	"unicode"
+
+	"github.com/katharinasick/gcp-api-mock/internal/billing"
+	"github.com/katharinasick/gcp-api-mock/internal/blob"
+	"github.com/katharinasick/gcp-api-mock/internal/cloudrun"
+	"github.com/katharinasick/gcp-api-mock/internal/cloudscheduler"
+	"github.com/katharinasick/gcp-api-mock/internal/compute"
+	"github.com/katharinasick/gcp-api-mock/internal/eventarc"
+	"github.com/katharinasick/gcp-api-mock/internal/firestore"
+	"github.com/katharinasick/gcp-api-mock/internal/gke"
+	"github.com/katharinasick/gcp-api-mock/internal/logging"
+	"github.com/katharinasick/gcp-api-mock/internal/memorystore"
+	"github.com/katharinasick/gcp-api-mock/internal/monitoring"
+	"github.com/katharinasick/gcp-api-mock/internal/orgpolicy"
+	"github.com/katharinasick/gcp-api-mock/internal/registry"
 	"github.com/katharinasick/gcp-api-mock/internal/sqladmin"
 	"github.com/katharinasick/gcp-api-mock/internal/storage"
 )
 
 // Store is the main in-memory data store for all GCP resources.
-// It is safe for concurrent access.
+// It is safe for concurrent access; resources are returned as copies, so callers may modify them.
 type Store struct {
-	mu sync.RWMutex
+	// Each resource family has its own lock, so e.g. object writes don't wait for Cloud SQL
+	// operations. Methods that span families, like Reset and Snapshot, use lockAll.
+	storageMu    sync.RWMutex
+	sqlMu        sync.RWMutex
+	firestoreMu  sync.RWMutex
+	registryMu   sync.RWMutex
+	runMu        sync.RWMutex
+	monitoringMu sync.RWMutex
+	loggingMu    sync.RWMutex
+	schedulerMu  sync.RWMutex
+	eventarcMu   sync.RWMutex
+	redisMu      sync.RWMutex
+	gkeMu        sync.RWMutex
+	computeMu    sync.RWMutex
+	billingMu    sync.RWMutex
+	orgPolicyMu  sync.RWMutex
 
 	// Cloud Storage data
 	buckets map[string]*storage.Bucket
 	// objects is a map of bucket name to a map of object name to object
 	objects map[string]map[string]*ObjectData
+	// softDeletedObjects is a map of bucket name to the soft-deleted objects in that bucket
+	softDeletedObjects map[string][]*ObjectData
+	// objectMetadataIndex indexes the live objects by their custom metadata, see SearchObjects
+	objectMetadataIndex metadataIndex
+	// objectUploads is a map of upload ID to in-progress resumable upload
+	objectUploads map[string]*objectUpload
+	// notifications is a map of bucket name to a map of notification ID to notification
+	notifications map[string]map[string]*storage.Notification
+	// notificationSeq is the last assigned notification ID
+	notificationSeq int
+	// bucketQuotas is a map of bucket name to the quota configured for that bucket
+	bucketQuotas map[string]BucketQuota
+	// bucketPolicies is a map of bucket name to the IAM policy set on that bucket
+	bucketPolicies map[string]*storage.Policy
+	// hmacKeys is a map of access ID to HMAC key, including its secret
+	hmacKeys map[string]*storage.HmacKey
 
 	// Cloud SQL data
 	// sqlInstances is a map of instance name to database instance
@@ -33,127 +85,678 @@ type Store struct {
 	sqlUsers map[string]map[string]*sqladmin.User
 	// sqlOperations is a map of operation name to operation
 	sqlOperations map[string]*sqladmin.Operation
+	// sqlPendingCreates is a map of create operation name to the time the instance becomes RUNNABLE
+	sqlPendingCreates map[string]time.Time
+	// sqlMaintenance is a map of instance name to the maintenance state of that instance
+	sqlMaintenance map[string]*sqlMaintenance
+
+	// Firestore data
+	// documents is a map of document name to document
+	documents map[string]*firestore.Document
+
+	// Artifact Registry data
+	// repositories is a map of repository name to repository
+	repositories map[string]*registry.Repository
+	// registryBlobs is a map of digest to blob content, shared by all repositories
+	registryBlobs map[string]blob.Blob
+	// registryUploads is a map of upload ID to in-progress blob upload
+	registryUploads map[string]*registryUpload
+
+	// Cloud Run data
+	// runServices is a map of service name to service
+	runServices map[string]*cloudrun.Service
+	// runRevisions is a map of revision name to revision
+	runRevisions map[string]*cloudrun.Revision
+	// runOperations is a map of operation name to operation
+	runOperations map[string]*cloudrun.Operation
+
+	// Cloud Monitoring data
+	// metricDescriptors is a map of descriptor name to metric descriptor
+	metricDescriptors map[string]*monitoring.MetricDescriptor
+	// timeSeries is a map of project to a map of series key (see TimeSeriesKey) to time series
+	timeSeries map[string]map[string]*monitoring.TimeSeries
+
+	// Cloud Logging data
+	// logEntries are the written log entries in the order they were received, at most maxLogEntries
+	logEntries []*logging.LogEntry
+	// logEntrySeq is the last generated insert ID
+	logEntrySeq int
+
+	// Cloud Scheduler data
+	// schedulerJobs is a map of job name to job
+	schedulerJobs map[string]*cloudscheduler.Job
+
+	// Eventarc data
+	// eventarcTriggers is a map of trigger name to trigger
+	eventarcTriggers map[string]*eventarc.Trigger
+	// eventarcOperations is a map of operation name to operation
+	eventarcOperations map[string]*eventarc.Operation
+
+	// Memorystore for Redis data
+	// redisInstances is a map of instance name to instance
+	redisInstances map[string]*memorystore.Instance
+	// redisOperations is a map of operation name to operation
+	redisOperations map[string]*memorystore.Operation
+	// redisRangeSeq is the number of IP ranges handed out to instances
+	redisRangeSeq int
+
+	// GKE data
+	// gkeClusters is a map of cluster name to cluster
+	gkeClusters map[string]*gke.Cluster
+	// gkeOperations is a map of operation name (projects/{project}/locations/{location}/operations/{operation}) to operation
+	gkeOperations map[string]*gke.Operation
+	// gkeRangeSeq is the number of IP ranges and endpoints handed out to clusters
+	gkeRangeSeq int
+
+	// Compute Engine data
+	// computeNetworks is a map of network key (projects/{project}/global/networks/{network}) to network
+	computeNetworks map[string]*compute.Network
+	// computeSubnetworks is a map of subnetwork key (projects/{project}/regions/{region}/subnetworks/{subnetwork}) to subnetwork
+	computeSubnetworks map[string]*compute.Subnetwork
+	// computeOperations is a map of operation key (projects/{project}/{global or regions/{region}}/operations/{operation}) to operation
+	computeOperations map[string]*compute.Operation
+
+	// Cloud Billing data
+	// billingBudgets is a map of budget name (billingAccounts/{billingAccount}/budgets/{budget}) to budget
+	billingBudgets map[string]*billing.Budget
+
+	// Organization Policy data
+	// orgPolicies is a map of policy name (projects/{project}/policies/{constraint}) to policy
+	orgPolicies map[string]*orgpolicy.Policy
+
+	// cfg holds the configuration shared by all resource families. It is replaced as a whole
+	// on changes, so it can be read without holding any of the locks above.
+	cfg atomic.Pointer[storeConfig]
+	// configMu serializes configuration changes
+	configMu sync.Mutex
+
+	// subscribers are the open subscriptions to changes, see Subscribe. They have their own lock,
+	// since changes are published while the lock of their resource family is held.
+	subscribersMu sync.Mutex
+	subscribers   map[*Subscription]bool
+	// subscriberCount is the number of subscribers, so changes aren't copied if there are none
+	subscriberCount atomic.Int32
+}
 
+// storeConfig is the configuration of a Store.
+type storeConfig struct {
 	// baseURL is the base URL for generating self links
 	baseURL string
 	// projectID is the default project ID for the mock
 	projectID string
 	// projectNumber is the default project number for the mock
 	projectNumber uint64
+	// clock returns the current time; it can be replaced to fast-forward time in tests
+	clock func() time.Time
+	// sqlCreateDelay is how long new Cloud SQL instances stay in PENDING_CREATE
+	sqlCreateDelay time.Duration
+	// sqlMaintenanceDuration is how long scheduled Cloud SQL maintenance takes; 0 disables it
+	sqlMaintenanceDuration time.Duration
+	// strictValidation rejects resource names and settings the real APIs reject
+	strictValidation bool
+	// strictObjectPaths rejects object names that aren't clean paths, which the real API accepts
+	strictObjectPaths bool
+	// maxObjectSize is the size limit of object content in bytes; 0 means no limit
+	maxObjectSize int64
+	// notificationHandler is called for object events matching a notification configuration
+	notificationHandler NotificationHandler
+	// schedulerHandler is called for Cloud Scheduler jobs that are due; without it, jobs don't run on their schedule
+	schedulerHandler SchedulerHandler
+	// triggerHandler is called for events matching an Eventarc trigger; without it, triggers don't fire
+	triggerHandler TriggerHandler
+	// redisEndpoint is the address Memorystore instances report as their host and port; empty for made-up addresses
+	redisEndpoint string
+	// gkeKubeconfig is the real cluster GKE clusters connect to; nil for made-up endpoints
+	gkeKubeconfig *gke.Kubeconfig
+	// blobs stores the object content
+	blobs blob.Backend
 }
 
-// ObjectData stores the object metadata and its binary content.
+// NotificationHandler is called for every notification configuration matching an object event.
+// It is called while the Cloud Storage lock is held, so it must not block or call back into the store.
+type NotificationHandler func(notification *storage.Notification, eventType string, obj storage.Object)
+
+// ObjectData stores the object metadata and its content.
 type ObjectData struct {
 	Metadata *storage.Object
-	Content  []byte
+	Content  blob.Blob
+	// accessed is the time the content was last read in Unix nanoseconds, or 0 if it never was.
+	// It is updated while holding the read lock, so it must be accessed atomically.
+	accessed atomic.Int64
 }
 
 // New creates a new empty Store.
 func New() *Store {
-	return &Store{
-		buckets:       make(map[string]*storage.Bucket),
-		objects:       make(map[string]map[string]*ObjectData),
-		sqlInstances:  make(map[string]*sqladmin.DatabaseInstance),
-		sqlDatabases:  make(map[string]map[string]*sqladmin.Database),
-		sqlUsers:      make(map[string]map[string]*sqladmin.User),
-		sqlOperations: make(map[string]*sqladmin.Operation),
+	s := &Store{
+		buckets:             make(map[string]*storage.Bucket),
+		objects:             make(map[string]map[string]*ObjectData),
+		softDeletedObjects:  make(map[string][]*ObjectData),
+		objectMetadataIndex: make(metadataIndex),
+		notifications:       make(map[string]map[string]*storage.Notification),
+		bucketQuotas:        make(map[string]BucketQuota),
+		bucketPolicies:      make(map[string]*storage.Policy),
+		hmacKeys:            make(map[string]*storage.HmacKey),
+		objectUploads:       make(map[string]*objectUpload),
+		sqlInstances:        make(map[string]*sqladmin.DatabaseInstance),
+		sqlDatabases:        make(map[string]map[string]*sqladmin.Database),
+		sqlUsers:            make(map[string]map[string]*sqladmin.User),
+		sqlOperations:       make(map[string]*sqladmin.Operation),
+		sqlPendingCreates:   make(map[string]time.Time),
+		sqlMaintenance:      make(map[string]*sqlMaintenance),
+		documents:           make(map[string]*firestore.Document),
+		repositories:        make(map[string]*registry.Repository),
+		registryBlobs:       make(map[string]blob.Blob),
+		registryUploads:     make(map[string]*registryUpload),
+		runServices:         make(map[string]*cloudrun.Service),
+		runRevisions:        make(map[string]*cloudrun.Revision),
+		runOperations:       make(map[string]*cloudrun.Operation),
+		metricDescriptors:   make(map[string]*monitoring.MetricDescriptor),
+		timeSeries:          make(map[string]map[string]*monitoring.TimeSeries),
+		schedulerJobs:       make(map[string]*cloudscheduler.Job),
+		eventarcTriggers:    make(map[string]*eventarc.Trigger),
+		eventarcOperations:  make(map[string]*eventarc.Operation),
+		redisInstances:      make(map[string]*memorystore.Instance),
+		redisOperations:     make(map[string]*memorystore.Operation),
+		gkeClusters:         make(map[string]*gke.Cluster),
+		gkeOperations:       make(map[string]*gke.Operation),
+		computeNetworks:     make(map[string]*compute.Network),
+		computeSubnetworks:  make(map[string]*compute.Subnetwork),
+		computeOperations:   make(map[string]*compute.Operation),
+		billingBudgets:      make(map[string]*billing.Budget),
+		orgPolicies:         make(map[string]*orgpolicy.Policy),
+		subscribers:         make(map[*Subscription]bool),
+	}
+	s.cfg.Store(&storeConfig{
 		baseURL:       "http://localhost:8080",
 		projectID:     "mock-project",
 		projectNumber: 123456789012,
-	}
+		clock:         time.Now,
+		blobs:         blob.NewMemoryBackend(),
+	})
+	return s
+}
+
+// config returns the current configuration of the store.
+func (s *Store) config() *storeConfig {
+	return s.cfg.Load()
+}
+
+// configure applies update to a copy of the configuration and makes the copy current.
+func (s *Store) configure(update func(cfg *storeConfig)) {
+	s.configMu.Lock()
+	defer s.configMu.Unlock()
+
+	cfg := *s.cfg.Load()
+	update(&cfg)
+	s.cfg.Store(&cfg)
+}
+
+// lockAll write-locks all resource families, always in the same order to avoid deadlocks.
+func (s *Store) lockAll() {
+	s.storageMu.Lock()
+	s.sqlMu.Lock()
+	s.firestoreMu.Lock()
+	s.registryMu.Lock()
+	s.runMu.Lock()
+	s.monitoringMu.Lock()
+	s.loggingMu.Lock()
+	s.schedulerMu.Lock()
+	s.eventarcMu.Lock()
+	s.redisMu.Lock()
+	s.gkeMu.Lock()
+	s.computeMu.Lock()
+	s.billingMu.Lock()
+	s.orgPolicyMu.Lock()
+}
+
+// unlockAll releases the locks taken by lockAll.
+func (s *Store) unlockAll() {
+	s.orgPolicyMu.Unlock()
+	s.billingMu.Unlock()
+	s.computeMu.Unlock()
+	s.gkeMu.Unlock()
+	s.redisMu.Unlock()
+	s.eventarcMu.Unlock()
+	s.schedulerMu.Unlock()
+	s.loggingMu.Unlock()
+	s.monitoringMu.Unlock()
+	s.runMu.Unlock()
+	s.registryMu.Unlock()
+	s.firestoreMu.Unlock()
+	s.sqlMu.Unlock()
+	s.storageMu.Unlock()
+}
+
+// rLockAll read-locks all resource families in the order of lockAll.
+func (s *Store) rLockAll() {
+	s.storageMu.RLock()
+	s.sqlMu.RLock()
+	s.firestoreMu.RLock()
+	s.registryMu.RLock()
+	s.runMu.RLock()
+	s.monitoringMu.RLock()
+	s.loggingMu.RLock()
+	s.schedulerMu.RLock()
+	s.eventarcMu.RLock()
+	s.redisMu.RLock()
+	s.gkeMu.RLock()
+	s.computeMu.RLock()
+	s.billingMu.RLock()
+	s.orgPolicyMu.RLock()
+}
+
+// rUnlockAll releases the locks taken by rLockAll.
+func (s *Store) rUnlockAll() {
+	s.orgPolicyMu.RUnlock()
+	s.billingMu.RUnlock()
+	s.computeMu.RUnlock()
+	s.gkeMu.RUnlock()
+	s.redisMu.RUnlock()
+	s.eventarcMu.RUnlock()
+	s.schedulerMu.RUnlock()
+	s.loggingMu.RUnlock()
+	s.monitoringMu.RUnlock()
+	s.runMu.RUnlock()
+	s.registryMu.RUnlock()
+	s.firestoreMu.RUnlock()
+	s.sqlMu.RUnlock()
+	s.storageMu.RUnlock()
 }
 
 // Reset clears all data from the store.
 // Useful for testing and resetting state.
 func (s *Store) Reset() {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	s.lockAll()
+	defer s.unlockAll()
+
+	s.reset()
+}
+
+// reset clears all data from the store and releases all content.
+// Callers must hold all locks, see lockAll.
+func (s *Store) reset() {
+	for bucketName, bucketObjects := range s.objects {
+		for _, objData := range bucketObjects {
+			objData.Content.Release()
+		}
+		for _, objData := range s.softDeletedObjects[bucketName] {
+			objData.Content.Release()
+		}
+	}
 
 	s.buckets = make(map[string]*storage.Bucket)
 	s.objects = make(map[string]map[string]*ObjectData)
+	s.softDeletedObjects = make(map[string][]*ObjectData)
+	s.objectMetadataIndex = make(metadataIndex)
+	for _, upload := range s.objectUploads {
+		upload.content.Release()
+	}
+	s.objectUploads = make(map[string]*objectUpload)
+	s.notifications = make(map[string]map[string]*storage.Notification)
+	s.bucketQuotas = make(map[string]BucketQuota)
+	s.bucketPolicies = make(map[string]*storage.Policy)
+	s.hmacKeys = make(map[string]*storage.HmacKey)
 	s.sqlInstances = make(map[string]*sqladmin.DatabaseInstance)
 	s.sqlDatabases = make(map[string]map[string]*sqladmin.Database)
 	s.sqlUsers = make(map[string]map[string]*sqladmin.User)
 	s.sqlOperations = make(map[string]*sqladmin.Operation)
+	s.sqlPendingCreates = make(map[string]time.Time)
+	s.sqlMaintenance = make(map[string]*sqlMaintenance)
+	s.documents = make(map[string]*firestore.Document)
+
+	for _, content := range s.registryBlobs {
+		content.Release()
+	}
+	for _, upload := range s.registryUploads {
+		upload.content.Release()
+	}
+	s.repositories = make(map[string]*registry.Repository)
+	s.registryBlobs = make(map[string]blob.Blob)
+	s.registryUploads = make(map[string]*registryUpload)
+
+	s.runServices = make(map[string]*cloudrun.Service)
+	s.runRevisions = make(map[string]*cloudrun.Revision)
+	s.runOperations = make(map[string]*cloudrun.Operation)
+
+	s.metricDescriptors = make(map[string]*monitoring.MetricDescriptor)
+	s.timeSeries = make(map[string]map[string]*monitoring.TimeSeries)
+
+	s.logEntries = nil
+	s.logEntrySeq = 0
+
+	s.schedulerJobs = make(map[string]*cloudscheduler.Job)
+
+	s.eventarcTriggers = make(map[string]*eventarc.Trigger)
+	s.eventarcOperations = make(map[string]*eventarc.Operation)
+
+	s.redisInstances = make(map[string]*memorystore.Instance)
+	s.redisOperations = make(map[string]*memorystore.Operation)
+	s.redisRangeSeq = 0
+
+	s.gkeClusters = make(map[string]*gke.Cluster)
+	s.gkeOperations = make(map[string]*gke.Operation)
+	s.gkeRangeSeq = 0
+
+	s.computeNetworks = make(map[string]*compute.Network)
+	s.computeSubnetworks = make(map[string]*compute.Subnetwork)
+	s.computeOperations = make(map[string]*compute.Operation)
+	s.billingBudgets = make(map[string]*billing.Budget)
+	s.orgPolicies = make(map[string]*orgpolicy.Policy)
+}
+
+// objectSizeReader fails reads once more than max bytes have been read.
+type objectSizeReader struct {
+	r    io.Reader
+	max  int64
+	read int64
+}
+
+// limitObjectSize limits the content read from r to max bytes; reading more fails with an
+// "object too large" error instead of silently truncating the content. A max of 0 means no limit.
+func limitObjectSize(r io.Reader, max int64) io.Reader {
+	if max <= 0 {
+		return r
+	}
+	return &objectSizeReader{r: r, max: max}
+}
+
+// Read reads from the underlying reader, failing once the limit is exceeded.
+func (r *objectSizeReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.read += int64(n)
+	if r.read > r.max {
+		return n, fmt.Errorf("object too large: the content exceeds the maximum object size of %d bytes", r.max)
+	}
+	return n, err
 }
 
 // SetBaseURL sets the base URL for generating self links.
 func (s *Store) SetBaseURL(baseURL string) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	s.baseURL = baseURL
+	s.configure(func(cfg *storeConfig) {
+		cfg.baseURL = baseURL
+	})
+}
+
+// BaseURL returns the base URL of the self links of the mock.
+func (s *Store) BaseURL() string {
+	return s.config().baseURL
 }
 
 // SetProject sets the project ID and number for the mock.
 func (s *Store) SetProject(projectID string, projectNumber uint64) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	s.projectID = projectID
-	s.projectNumber = projectNumber
+	s.configure(func(cfg *storeConfig) {
+		cfg.projectID = projectID
+		cfg.projectNumber = projectNumber
+	})
+}
+
+// ProjectID returns the project ID of the mock.
+func (s *Store) ProjectID() string {
+	return s.config().projectID
+}
+
+// ProjectNumber returns the project number of the mock.
+func (s *Store) ProjectNumber() uint64 {
+	return s.config().projectNumber
+}
+
+// SetClock replaces the function used to read the current time.
+// Tests use this to fast-forward time, e.g. to expire soft-deleted objects.
+func (s *Store) SetClock(clock func() time.Time) {
+	s.configure(func(cfg *storeConfig) {
+		cfg.clock = clock
+	})
+}
+
+// Tick applies the current time to time-dependent state: soft-deleted objects past their retention
+// are hard-deleted, the lifecycle rules of buckets delete objects and change their storage classes, delayed Cloud SQL instance creations complete, Cloud SQL maintenance starts and ends
+// and Cloud Scheduler jobs that are due run if a scheduler handler is set. It is called after the clock moves, so moving it back doesn't revive state that has already expired.
+func (s *Store) Tick() {
+	s.storageMu.Lock()
+	now := s.now()
+	for bucketName := range s.softDeletedObjects {
+		s.purgeExpiredSoftDeletedObjects(bucketName, now)
+	}
+	s.applyLifecycle(now)
+	s.storageMu.Unlock()
+
+	s.sqlMu.Lock()
+	s.updateSQLState()
+	s.sqlMu.Unlock()
+
+	s.schedulerMu.Lock()
+	s.runDueSchedulerJobs()
+	s.schedulerMu.Unlock()
+}
+
+// SetStrictValidation enables or disables strict validation. When enabled, bucket names are checked
+// against the Cloud Storage naming requirements, bucket locations against the Cloud Storage locations
+// and Cloud SQL tiers and regions against the ones Cloud SQL offers, like the real APIs do.
+// Object names are always checked, since the real API rejects invalid ones no matter how they're sent.
+func (s *Store) SetStrictValidation(strict bool) {
+	s.configure(func(cfg *storeConfig) {
+		cfg.strictValidation = strict
+	})
+}
+
+// StrictValidation reports whether strict validation is enabled.
+func (s *Store) StrictValidation() bool {
+	return s.config().strictValidation
+}
+
+// SetStrictObjectPaths enables or disables rejecting object names that aren't clean paths, like "/a.txt"
+// or "a/../b.txt". Cloud Storage accepts them, but they usually point at path handling bugs in clients.
+func (s *Store) SetStrictObjectPaths(strict bool) {
+	s.configure(func(cfg *storeConfig) {
+		cfg.strictObjectPaths = strict
+	})
+}
+
+// validateObjectName checks a new object's name against the Cloud Storage naming requirements, and whether
+// it is a clean path if strict object paths are enabled.
+// Returns an "invalid object name" error naming the violated requirement.
+func (cfg *storeConfig) validateObjectName(name string) error {
+	if err := storage.ValidateObjectName(name); err != nil {
+		return err
+	}
+	if cfg.strictObjectPaths {
+		return storage.ValidateObjectPath(name)
+	}
+	return nil
+}
+
+// SetMaxObjectSize limits the size of object content in bytes. Writes of larger content fail
+// with an "object too large" error. 0 removes the limit.
+func (s *Store) SetMaxObjectSize(size int64) {
+	s.configure(func(cfg *storeConfig) {
+		cfg.maxObjectSize = size
+	})
+}
+
+// MaxObjectSize returns the size limit of object content in bytes, or 0 if there is none.
+func (s *Store) MaxObjectSize() int64 {
+	return s.config().maxObjectSize
+}
+
+// SetSQLCreateDelay sets how long new Cloud SQL instances stay in PENDING_CREATE before they become RUNNABLE.
+// Their create operations stay RUNNING for as long, like real instance creation which takes minutes.
+func (s *Store) SetSQLCreateDelay(delay time.Duration) {
+	s.configure(func(cfg *storeConfig) {
+		cfg.sqlCreateDelay = delay
+	})
+}
+
+// SetNotificationHandler sets the handler that delivers bucket notifications.
+func (s *Store) SetNotificationHandler(handler NotificationHandler) {
+	s.configure(func(cfg *storeConfig) {
+		cfg.notificationHandler = handler
+	})
+}
+
+// SetBlobBackend sets the backend that stores object content.
+// It must be called before any objects are created.
+func (s *Store) SetBlobBackend(backend blob.Backend) {
+	s.configure(func(cfg *storeConfig) {
+		cfg.blobs = backend
+	})
+}
+
+// CheckBlobBackend verifies that object content can be stored, e.g. that the blob directory is writable.
+func (s *Store) CheckBlobBackend() error {
+	return blob.Check(s.config().blobs)
+}
+
+// BlobDedupStats returns the statistics of the content deduplication, if the blob backend deduplicates content.
+func (s *Store) BlobDedupStats() (blob.DedupStats, bool) {
+	backend, ok := blob.Find[*blob.DedupBackend](s.config().blobs)
+	if !ok {
+		return blob.DedupStats{}, false
+	}
+	return backend.Stats(), true
+}
+
+// ContentLimitStats returns how much content is stored and how much was evicted, if the blob backend
+// limits the size of the stored content.
+func (s *Store) ContentLimitStats() (blob.LRUStats, bool) {
+	backend, ok := blob.Find[*blob.LRUBackend](s.config().blobs)
+	if !ok {
+		return blob.LRUStats{}, false
+	}
+	return backend.Stats(), true
+}
+
+// Now returns the current time in UTC according to the store's clock.
+func (s *Store) Now() time.Time {
+	return s.now()
+}
+
+// now returns the current time in UTC according to the store's clock.
+func (s *Store) now() time.Time {
+	return s.config().clock().UTC()
 }
 
 // CreateBucket creates a new bucket in the store.
 // Returns an error if a bucket with the same name already exists.
 func (s *Store) CreateBucket(req *storage.BucketInsertRequest) (*storage.Bucket, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	s.storageMu.Lock()
+	defer s.storageMu.Unlock()
+
+	cfg := s.config()
+
+	if cfg.strictValidation {
+		if err := storage.ValidateBucketName(req.Name); err != nil {
+			return nil, err
+		}
+	}
 
 	if _, exists := s.buckets[req.Name]; exists {
 		return nil, fmt.Errorf("bucket %s already exists", req.Name)
 	}
 
-	now := time.Now().UTC()
+	now := s.now()
 
 	// Set defaults if not provided
-	location := req.Location
+	location := strings.ToUpper(req.Location)
 	if location == "" {
 		location = "US"
 	}
+	locationType, known := storage.LocationType(location)
+	if !known {
+		if cfg.strictValidation {
+			return nil, storage.ValidateLocation(req.Location)
+		}
+		locationType = storage.LocationTypeRegion
+	}
 
 	storageClass := req.StorageClass
 	if storageClass == "" {
 		storageClass = "STANDARD"
 	}
 
+	var autoclass *storage.Autoclass
+	if req.Autoclass != nil {
+		autoclass = updateAutoclass(nil, req.Autoclass, now)
+		if autoclass.Enabled {
+			storageClass = storage.StorageClassStandard
+		}
+	}
+
+	iamConfiguration := updateIamConfiguration(nil, req.IamConfiguration)
+	if err := s.checkUniformBucketLevelAccess(req.Name, iamConfiguration); err != nil {
+		return nil, err
+	}
+
+	var retentionPolicy *storage.RetentionPolicy
+	if req.RetentionPolicy != nil {
+		retentionPolicy = updateRetentionPolicy(nil, req.RetentionPolicy, now)
+	}
+
 	bucket := &storage.Bucket{
 		Kind:             "storage#bucket",
 		ID:               req.Name,
-		SelfLink:         fmt.Sprintf("%s/storage/v1/b/%s", s.baseURL, req.Name),
-		ProjectNumber:    s.projectNumber,
+		SelfLink:         fmt.Sprintf("%s/storage/v1/b/%s", cfg.baseURL, req.Name),
+		ProjectNumber:    cfg.projectNumber,
 		Name:             req.Name,
 		TimeCreated:      now,
 		Updated:          now,
 		Metageneration:   1,
 		Location:         location,
-		LocationType:     "region",
+		LocationType:     locationType,
 		StorageClass:     storageClass,
 		Etag:             generateEtag(),
 		Labels:           req.Labels,
-		IamConfiguration: req.IamConfiguration,
+		IamConfiguration: iamConfiguration,
 		Versioning:       req.Versioning,
 		Lifecycle:        req.Lifecycle,
 		SoftDeletePolicy: req.SoftDeletePolicy,
+		RetentionPolicy:  retentionPolicy,
+		Encryption:       req.Encryption,
+		Cors:             req.Cors,
+		Billing:          req.Billing,
+		Autoclass:        autoclass,
 	}
 
 	s.buckets[req.Name] = bucket
 	s.objects[req.Name] = make(map[string]*ObjectData)
+	s.publish(Event{Type: EventBucketCreated, Bucket: bucket})
 
-	return bucket, nil
+	return clone(bucket), nil
 }
 
 // GetBucket retrieves a bucket by name.
 // Returns nil if the bucket doesn't exist.
 func (s *Store) GetBucket(name string) *storage.Bucket {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	s.storageMu.RLock()
+	defer s.storageMu.RUnlock()
+
+	return clone(s.buckets[name])
+}
+
+// GetBucketCors returns the CORS configuration of a bucket, and false if the bucket doesn't exist.
+func (s *Store) GetBucketCors(name string) ([]storage.Cors, bool) {
+	s.storageMu.RLock()
+	defer s.storageMu.RUnlock()
+
+	bucket, exists := s.buckets[name]
+	if !exists {
+		return nil, false
+	}
+	return clone(bucket.Cors), true
+}
+
+// IsRequesterPays reports whether a bucket exists and has Requester Pays enabled.
+func (s *Store) IsRequesterPays(name string) bool {
+	s.storageMu.RLock()
+	defer s.storageMu.RUnlock()
 
-	return s.buckets[name]
+	bucket, exists := s.buckets[name]
+	return exists && bucket.Billing != nil && bucket.Billing.RequesterPays
 }
 
 // ListBuckets returns all buckets in the store.
 func (s *Store) ListBuckets() []*storage.Bucket {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	s.storageMu.RLock()
+	defer s.storageMu.RUnlock()
 
 	buckets := make([]*storage.Bucket, 0, len(s.buckets))
 	for _, bucket := range s.buckets {
@@ -165,20 +768,110 @@ func (s *Store) ListBuckets() []*storage.Bucket {
 		return buckets[i].Name < buckets[j].Name
 	})
 
-	return buckets
+	return clone(buckets)
 }
 
 // UpdateBucket updates an existing bucket.
-// Returns an error if the bucket doesn't exist.
+// Returns an error if the bucket doesn't exist or the etag of the request doesn't match.
 func (s *Store) UpdateBucket(name string, req *storage.BucketUpdateRequest) (*storage.Bucket, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	s.storageMu.Lock()
+	defer s.storageMu.Unlock()
 
 	bucket, exists := s.buckets[name]
 	if !exists {
 		return nil, fmt.Errorf("bucket %s not found", name)
 	}
+	if err := checkEtag(req.Etag, bucket.Etag); err != nil {
+		return nil, err
+	}
+	if req.IamConfiguration != nil {
+		if err := s.checkUniformBucketLevelAccess(name, updateIamConfiguration(bucket.IamConfiguration, req.IamConfiguration)); err != nil {
+			return nil, err
+		}
+	}
+	if err := checkRetentionPolicyChange(bucket, req.RetentionPolicy, false); err != nil {
+		return nil, err
+	}
+
+	s.applyBucketUpdate(name, bucket, req)
+
+	bucket.Updated = s.now()
+	bucket.Metageneration++
+	bucket.Etag = generateEtag()
+
+	return clone(bucket), nil
+}
+
+// PatchBucket patches an existing bucket. Unlike UpdateBucket, it merges labels
+// and clears the fields listed in req.NullFields.
+// Returns an error if the bucket doesn't exist or the etag of the request doesn't match.
+func (s *Store) PatchBucket(name string, req *storage.BucketPatchRequest) (*storage.Bucket, error) {
+	s.storageMu.Lock()
+	defer s.storageMu.Unlock()
+
+	bucket, exists := s.buckets[name]
+	if !exists {
+		return nil, fmt.Errorf("bucket %s not found", name)
+	}
+	if err := checkEtag(req.Etag, bucket.Etag); err != nil {
+		return nil, err
+	}
+	if req.IamConfiguration != nil {
+		if err := s.checkUniformBucketLevelAccess(name, updateIamConfiguration(bucket.IamConfiguration, req.IamConfiguration)); err != nil {
+			return nil, err
+		}
+	}
+	if err := checkRetentionPolicyChange(bucket, req.RetentionPolicy, slices.Contains(req.NullFields, "retentionPolicy")); err != nil {
+		return nil, err
+	}
+
+	s.applyBucketUpdate(name, bucket, &req.BucketUpdateRequest)
+
+	for _, field := range req.NullFields {
+		switch field {
+		case "labels":
+			bucket.Labels = nil
+		case "versioning":
+			bucket.Versioning = nil
+		case "lifecycle":
+			bucket.Lifecycle = nil
+		case "softDeletePolicy":
+			bucket.SoftDeletePolicy = nil
+		case "retentionPolicy":
+			bucket.RetentionPolicy = nil
+		case "encryption":
+			bucket.Encryption = nil
+		case "billing":
+			bucket.Billing = nil
+		case "cors":
+			bucket.Cors = nil
+		case "autoclass":
+			s.setAutoclass(name, bucket, &storage.Autoclass{})
+			bucket.Autoclass = nil
+		}
+	}
+
+	for key, value := range req.Labels {
+		if value == nil {
+			delete(bucket.Labels, key)
+			continue
+		}
+		if bucket.Labels == nil {
+			bucket.Labels = make(map[string]string)
+		}
+		bucket.Labels[key] = *value
+	}
+
+	bucket.Updated = s.now()
+	bucket.Metageneration++
+	bucket.Etag = generateEtag()
+
+	return clone(bucket), nil
+}
 
+// applyBucketUpdate applies the fields set in an update request to a bucket.
+// Callers must hold the storage write lock.
+func (s *Store) applyBucketUpdate(name string, bucket *storage.Bucket, req *storage.BucketUpdateRequest) {
 	if req.StorageClass != "" {
 		bucket.StorageClass = req.StorageClass
 	}
@@ -188,7 +881,7 @@ func (s *Store) UpdateBucket(name string, req *storage.BucketUpdateRequest) (*st
 	}
 
 	if req.IamConfiguration != nil {
-		bucket.IamConfiguration = req.IamConfiguration
+		bucket.IamConfiguration = updateIamConfiguration(bucket.IamConfiguration, req.IamConfiguration)
 	}
 
 	if req.Versioning != nil {
@@ -203,30 +896,62 @@ func (s *Store) UpdateBucket(name string, req *storage.BucketUpdateRequest) (*st
 		bucket.SoftDeletePolicy = req.SoftDeletePolicy
 	}
 
-	bucket.Updated = time.Now().UTC()
-	bucket.Metageneration++
-	bucket.Etag = generateEtag()
+	if req.RetentionPolicy != nil {
+		bucket.RetentionPolicy = updateRetentionPolicy(bucket.RetentionPolicy, req.RetentionPolicy, s.now())
+	}
 
-	return bucket, nil
+	// An encryption configuration without a default key removes the default key
+	if req.Encryption != nil {
+		bucket.Encryption = req.Encryption
+		if req.Encryption.DefaultKmsKeyName == "" {
+			bucket.Encryption = nil
+		}
+	}
+
+	if req.Billing != nil {
+		bucket.Billing = req.Billing
+	}
+
+	if req.Autoclass != nil {
+		s.setAutoclass(name, bucket, req.Autoclass)
+	}
+
+	// An empty CORS configuration removes all rules
+	if req.Cors != nil {
+		bucket.Cors = req.Cors
+		if len(req.Cors) == 0 {
+			bucket.Cors = nil
+		}
+	}
 }
 
 // DeleteBucket deletes a bucket by name.
 // Returns an error if the bucket doesn't exist or contains objects.
 func (s *Store) DeleteBucket(name string) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	s.storageMu.Lock()
+	defer s.storageMu.Unlock()
 
-	if _, exists := s.buckets[name]; !exists {
+	bucket, exists := s.buckets[name]
+	if !exists {
 		return fmt.Errorf("bucket %s not found", name)
 	}
 
-	// Check if bucket has objects
+	// Check if bucket has objects (soft-deleted objects don't count)
 	if len(s.objects[name]) > 0 {
 		return fmt.Errorf("bucket %s is not empty", name)
 	}
 
+	for _, objData := range s.softDeletedObjects[name] {
+		objData.Content.Release()
+	}
+
 	delete(s.buckets, name)
 	delete(s.objects, name)
+	delete(s.softDeletedObjects, name)
+	delete(s.notifications, name)
+	delete(s.bucketQuotas, name)
+	delete(s.bucketPolicies, name)
+	s.publish(Event{Type: EventBucketDeleted, Bucket: bucket})
 
 	return nil
 }
@@ -235,66 +960,270 @@ func (s *Store) DeleteBucket(name string) error {
 // Returns an error if the bucket doesn't exist.
 // If an object with the same name and content already exists, returns the existing object.
 func (s *Store) CreateObject(bucketName, objectName, contentType string, content []byte, metadata map[string]string) (*storage.Object, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	return s.CreateObjectFromReader(bucketName, objectName, contentType, bytes.NewReader(content), metadata)
+}
 
+// ObjectOptions holds optional settings for creating an object.
+type ObjectOptions struct {
+	// KmsKeyName is the Cloud KMS key to encrypt the object with.
+	// If empty, the bucket's default key is used, if it has one.
+	KmsKeyName string
+	// Preconditions must hold for the live object the new object replaces.
+	Preconditions Preconditions
+	// Md5Hash and Crc32c are the base64-encoded checksums provided by the client.
+	// If set, content with other checksums is rejected, like corrupted uploads in GCS.
+	Md5Hash string
+	Crc32c  string
+	// CacheControl, ContentDisposition, ContentLanguage and CustomTime are stored with the
+	// object; downloads answer with them.
+	CacheControl       string
+	ContentDisposition string
+	ContentLanguage    string
+	CustomTime         *time.Time
+	// StorageClass is the storage class of the object. If empty, the bucket's default storage class is used.
+	StorageClass string
+	// PredefinedACL and ACL set the access control list of the object; the predefined ACL, like publicRead,
+	// takes precedence. Without either, the object gets the projectPrivate ACL.
+	PredefinedACL string
+	ACL           []storage.ObjectAccessControl
+}
+
+// InsertOptions returns the options for creating an object from the object resource of an upload.
+func InsertOptions(req *storage.ObjectInsertRequest, pre Preconditions) ObjectOptions {
+	return ObjectOptions{
+		KmsKeyName:         req.KmsKeyName,
+		Preconditions:      pre,
+		Md5Hash:            req.Md5Hash,
+		Crc32c:             req.Crc32c,
+		CacheControl:       req.CacheControl,
+		ContentDisposition: req.ContentDisposition,
+		ContentLanguage:    req.ContentLanguage,
+		CustomTime:         req.CustomTime,
+		StorageClass:       req.StorageClass,
+		PredefinedACL:      req.PredefinedACL,
+		ACL:                req.Acl,
+	}
+}
+
+// Preconditions are the generation preconditions of an object request, like ifGenerationMatch.
+// Nil fields aren't checked. A generation of 0 stands for "no live object", so
+// IfGenerationMatch 0 only allows creating an object that doesn't exist yet.
+// Reference: https://cloud.google.com/storage/docs/request-preconditions
+type Preconditions struct {
+	IfGenerationMatch        *int64
+	IfGenerationNotMatch     *int64
+	IfMetagenerationMatch    *int64
+	IfMetagenerationNotMatch *int64
+}
+
+// Check returns an error if the preconditions don't hold for obj, which is nil if there is no live object.
+func (p Preconditions) Check(obj *storage.Object) error {
+	var generation, metageneration int64
+	if obj != nil {
+		generation, metageneration = obj.Generation, obj.Metageneration
+	}
+
+	if p.IfGenerationMatch != nil && *p.IfGenerationMatch != generation {
+		return fmt.Errorf("precondition failed: ifGenerationMatch %d doesn't match generation %d", *p.IfGenerationMatch, generation)
+	}
+	if p.IfGenerationNotMatch != nil && *p.IfGenerationNotMatch == generation {
+		return fmt.Errorf("precondition failed: ifGenerationNotMatch %d matches generation %d", *p.IfGenerationNotMatch, generation)
+	}
+	// Metageneration preconditions require a live object
+	if p.IfMetagenerationMatch != nil && (obj == nil || *p.IfMetagenerationMatch != metageneration) {
+		return fmt.Errorf("precondition failed: ifMetagenerationMatch %d doesn't match metageneration %d", *p.IfMetagenerationMatch, metageneration)
+	}
+	if p.IfMetagenerationNotMatch != nil && (obj == nil || *p.IfMetagenerationNotMatch == metageneration) {
+		return fmt.Errorf("precondition failed: ifMetagenerationNotMatch %d matches metageneration %d", *p.IfMetagenerationNotMatch, metageneration)
+	}
+	return nil
+}
+
+// CreateObjectFromReader creates a new object in the specified bucket with the content read from r.
+// Returns an error if the bucket doesn't exist or the content can't be read.
+// If an object with the same name and content already exists, returns the existing object.
+func (s *Store) CreateObjectFromReader(bucketName, objectName, contentType string, r io.Reader, metadata map[string]string) (*storage.Object, error) {
+	return s.CreateObjectWithOptions(bucketName, objectName, contentType, r, metadata, ObjectOptions{})
+}
+
+// CreateObjectWithOptions creates a new object like CreateObjectFromReader, with additional options.
+// The content is streamed to the blob backend before the store is locked, so large uploads
+// don't block other requests.
+func (s *Store) CreateObjectWithOptions(bucketName, objectName, contentType string, r io.Reader, metadata map[string]string, opts ObjectOptions) (*storage.Object, error) {
+	cfg := s.config()
+
+	s.storageMu.RLock()
+	_, exists := s.buckets[bucketName]
+	s.storageMu.RUnlock()
+	backend := cfg.blobs
+
+	if !exists {
+		return nil, fmt.Errorf("bucket %s not found", bucketName)
+	}
+	if err := cfg.validateObjectName(objectName); err != nil {
+		return nil, err
+	}
+	if opts.StorageClass != "" && !storage.IsValidStorageClass(opts.StorageClass) {
+		return nil, fmt.Errorf("invalid storage class %q", opts.StorageClass)
+	}
+
+	// Compute checksums while the content is streamed to the backend
+	md5Hash := md5.New()
+	crc32cHash := crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	content, err := backend.Write(io.TeeReader(limitObjectSize(r, cfg.maxObjectSize), io.MultiWriter(md5Hash, crc32cHash)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to store object content: %w", err)
+	}
+	md5Sum := base64.StdEncoding.EncodeToString(md5Hash.Sum(nil))
+	crc32cSum := encodeCRC32C(crc32cHash.Sum32())
+
+	if opts.Md5Hash != "" && opts.Md5Hash != md5Sum {
+		content.Release()
+		return nil, fmt.Errorf("invalid checksum: provided MD5 hash %q doesn't match calculated MD5 hash %q", opts.Md5Hash, md5Sum)
+	}
+	if opts.Crc32c != "" && opts.Crc32c != crc32cSum {
+		content.Release()
+		return nil, fmt.Errorf("invalid checksum: provided CRC32C %q doesn't match calculated CRC32C %q", opts.Crc32c, crc32cSum)
+	}
+
+	s.storageMu.Lock()
+	defer s.storageMu.Unlock()
+
+	// The bucket may have been deleted while the content was written
 	bucket, exists := s.buckets[bucketName]
 	if !exists {
+		content.Release()
 		return nil, fmt.Errorf("bucket %s not found", bucketName)
 	}
 
-	// Check if object already exists with the same content
-	if existingObjData, exists := s.objects[bucketName][objectName]; exists {
-		existingMD5 := existingObjData.Metadata.Md5Hash
-		newMD5 := computeMD5Hash(content)
+	kmsKeyName := opts.KmsKeyName
+	if kmsKeyName == "" && bucket.Encryption != nil {
+		kmsKeyName = bucket.Encryption.DefaultKmsKeyName
+	}
+	kmsKeyName = kmsKeyVersionName(kmsKeyName)
 
-		// If content is the same, check if metadata is also the same
-		if existingMD5 == newMD5 && metadataEqual(existingObjData.Metadata.Metadata, metadata) {
-			// Content and metadata unchanged, return existing object
-			return existingObjData.Metadata, nil
+	storageClass := opts.StorageClass
+	if bucket.Autoclass != nil && bucket.Autoclass.Enabled {
+		// Autoclass manages the storage class; new objects always start in STANDARD
+		if storageClass != "" && storageClass != storage.StorageClassStandard {
+			content.Release()
+			return nil, fmt.Errorf("invalid storage class %q: the storage class of objects in buckets with Autoclass can't be set", storageClass)
 		}
+		storageClass = storage.StorageClassStandard
+	}
+	if storageClass == "" {
+		storageClass = bucket.StorageClass
 	}
 
-	now := time.Now().UTC()
-	generation := now.UnixNano()
+	acl, err := s.newObjectACL(bucket, opts)
+	if err != nil {
+		content.Release()
+		return nil, err
+	}
+
+	existingObjData, replacesExisting := s.objects[bucketName][objectName]
+	var existing *storage.Object
+	if replacesExisting {
+		existing = existingObjData.Metadata
+	}
+	if err := opts.Preconditions.Check(existing); err != nil {
+		content.Release()
+		return nil, err
+	}
 
 	if contentType == "" {
 		contentType = "application/octet-stream"
 	}
 
+	// Check if object already exists with the same content
+	if replacesExisting {
+		// If content is the same, check if metadata is also the same
+		if existing.Md5Hash == md5Sum && existing.ContentType == contentType && metadataEqual(existing.Metadata, metadata) &&
+			existing.KmsKeyName == kmsKeyName && existing.StorageClass == storageClass && existing.CacheControl == opts.CacheControl &&
+			existing.ContentDisposition == opts.ContentDisposition && existing.ContentLanguage == opts.ContentLanguage &&
+			timesEqual(existing.CustomTime, opts.CustomTime) && aclEqual(existing.Acl, acl) {
+			// Content and metadata unchanged, return existing object
+			content.Release()
+			return clone(existingObjData.Metadata), nil
+		}
+	}
+
+	now := s.now()
+
+	// Overwriting deletes the live generation, which the retention policy of the bucket may not allow yet
+	var replacedSize int64
+	if replacesExisting {
+		if err := s.checkObjectRetention(bucketName, existing, now, "overwritten"); err != nil {
+			content.Release()
+			return nil, err
+		}
+		replacedSize = existingObjData.Content.Size()
+	}
+	if err := s.checkBucketQuota(bucketName, content.Size()-replacedSize, !replacesExisting); err != nil {
+		content.Release()
+		return nil, err
+	}
+
+	generation := now.UnixNano()
+	// Generations must increase even if the clock stands still, since clients use them for preconditions
+	if replacesExisting && generation <= existing.Generation {
+		generation = existing.Generation + 1
+	}
+
 	obj := &storage.Object{
-		Kind:           "storage#object",
-		ID:             fmt.Sprintf("%s/%s/%d", bucketName, objectName, generation),
-		SelfLink:       fmt.Sprintf("%s/storage/v1/b/%s/o/%s", s.baseURL, bucketName, objectName),
-		MediaLink:      fmt.Sprintf("%s/download/storage/v1/b/%s/o/%s?alt=media", s.baseURL, bucketName, objectName),
-		Name:           objectName,
-		Bucket:         bucketName,
-		Generation:     generation,
-		Metageneration: 1,
-		ContentType:    contentType,
-		TimeCreated:    now,
-		Updated:        now,
-		StorageClass:   bucket.StorageClass,
-		Size:           uint64(len(content)),
-		Md5Hash:        computeMD5Hash(content),
-		Crc32c:         computeCRC32C(content),
-		Etag:           generateEtag(),
-		Metadata:       metadata,
-	}
-
-	s.objects[bucketName][objectName] = &ObjectData{
+		Kind:                    "storage#object",
+		ID:                      fmt.Sprintf("%s/%s/%d", bucketName, objectName, generation),
+		SelfLink:                fmt.Sprintf("%s/storage/v1/b/%s/o/%s", cfg.baseURL, bucketName, objectName),
+		MediaLink:               fmt.Sprintf("%s/download/storage/v1/b/%s/o/%s?alt=media", cfg.baseURL, bucketName, objectName),
+		Name:                    objectName,
+		Bucket:                  bucketName,
+		Generation:              generation,
+		Metageneration:          1,
+		ContentType:             contentType,
+		CacheControl:            opts.CacheControl,
+		ContentDisposition:      opts.ContentDisposition,
+		ContentLanguage:         opts.ContentLanguage,
+		CustomTime:              opts.CustomTime,
+		TimeCreated:             now,
+		Updated:                 now,
+		StorageClass:            storageClass,
+		TimeStorageClassUpdated: now,
+		Size:                    uint64(content.Size()),
+		Md5Hash:                 md5Sum,
+		Crc32c:                  crc32cSum,
+		Etag:                    generateEtag(),
+		Metadata:                metadata,
+		KmsKeyName:              kmsKeyName,
+		Acl:                     acl,
+	}
+	completeObjectACL(cfg.baseURL, obj)
+
+	objData := &ObjectData{
 		Metadata: obj,
 		Content:  content,
 	}
+	s.objects[bucketName][objectName] = objData
+	s.objectMetadataIndex.add(objData)
 
-	return obj, nil
+	// Overwriting a live object deletes the previous generation, which is kept as soft-deleted like
+	// deleted objects are, so it can be restored
+	if replacesExisting {
+		s.objectMetadataIndex.remove(existingObjData)
+		s.purgeExpiredSoftDeletedObjects(bucketName, now)
+		s.retireObject(bucketName, existingObjData, now)
+		s.publishObjectEvent(storage.EventObjectDelete, existingObjData.Metadata)
+	}
+	s.publishObjectEvent(storage.EventObjectFinalize, obj)
+	s.publish(Event{Type: EventObjectFinalized, Object: obj})
+
+	return clone(obj), nil
 }
 
 // GetObject retrieves an object's metadata by bucket and object name.
 // Returns nil if the object doesn't exist.
 func (s *Store) GetObject(bucketName, objectName string) *storage.Object {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	s.storageMu.RLock()
+	defer s.storageMu.RUnlock()
 
 	bucketObjects, exists := s.objects[bucketName]
 	if !exists {
@@ -306,128 +1235,711 @@ func (s *Store) GetObject(bucketName, objectName string) *storage.Object {
 		return nil
 	}
 
-	return objData.Metadata
+	return s.objectMetadata(bucketName, objData)
 }
 
 // GetObjectContent retrieves an object's content by bucket and object name.
+// The whole content is read into memory; use OpenObjectContent to stream it instead.
 // Returns nil if the object doesn't exist.
 func (s *Store) GetObjectContent(bucketName, objectName string) []byte {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	_, r, err := s.OpenObjectContent(bucketName, objectName)
+	if err != nil {
+		return nil
+	}
+	defer r.Close()
+
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil
+	}
+
+	return content
+}
+
+// OpenObjectContent opens a reader for an object's content along with the metadata
+// of the object generation being read. The caller must close the reader.
+// Returns an error if the object doesn't exist, and an "evicted" error if its content was evicted
+// to stay below the content size limit.
+func (s *Store) OpenObjectContent(bucketName, objectName string) (*storage.Object, io.ReadSeekCloser, error) {
+	s.storageMu.RLock()
+	defer s.storageMu.RUnlock()
+
+	objData, exists := s.objects[bucketName][objectName]
+	if !exists {
+		return nil, nil, fmt.Errorf("object %s not found in bucket %s", objectName, bucketName)
+	}
+
+	r, err := objData.Content.Open()
+	if errors.Is(err, blob.ErrEvicted) {
+		return nil, nil, fmt.Errorf("content of object %s in bucket %s was evicted to stay below the content size limit; upload it again", objectName, bucketName)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open object content: %w", err)
+	}
+
+	obj := s.objectMetadata(bucketName, objData)
+	objData.accessed.Store(s.now().UnixNano())
+
+	return obj, r, nil
+}
+
+// ListObjects returns all objects in a bucket, optionally filtered by prefix.
+func (s *Store) ListObjects(bucketName, prefix, delimiter string) ([]*storage.Object, []string) {
+	objects, prefixes, _ := s.ListObjectsWithOptions(bucketName, ListOptions{Prefix: prefix, Delimiter: delimiter})
+	return objects, prefixes
+}
+
+// ListOptions holds the filters of an object listing.
+// Reference: https://cloud.google.com/storage/docs/json_api/v1/objects/list
+type ListOptions struct {
+	// Prefix only lists objects whose names start with it.
+	Prefix string
+	// Delimiter groups objects whose names contain it after the prefix into prefixes.
+	Delimiter string
+	// StartOffset only lists names lexicographically equal to or after it.
+	StartOffset string
+	// EndOffset only lists names lexicographically before it.
+	EndOffset string
+	// MatchGlob only lists objects whose names match the glob pattern.
+	MatchGlob string
+	// IncludeTrailingDelimiter also lists objects whose names end with the delimiter, besides their prefix.
+	IncludeTrailingDelimiter bool
+}
+
+// ListObjectsWithOptions returns the objects and prefixes in a bucket that match the options.
+// Returns an error if the glob pattern is invalid.
+func (s *Store) ListObjectsWithOptions(bucketName string, opts ListOptions) ([]*storage.Object, []string, error) {
+	var glob *regexp.Regexp
+	if opts.MatchGlob != "" {
+		var err error
+		if glob, err = compileGlob(opts.MatchGlob); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	s.storageMu.RLock()
+	defer s.storageMu.RUnlock()
 
 	bucketObjects, exists := s.objects[bucketName]
 	if !exists {
-		return nil
+		return nil, nil, nil
+	}
+
+	prefix, delimiter := opts.Prefix, opts.Delimiter
+	var objects []*storage.Object
+	prefixSet := make(map[string]struct{})
+
+	for name, objData := range bucketObjects {
+		// Check prefix filter
+		if prefix != "" && !hasPrefix(name, prefix) {
+			continue
+		}
+
+		// Check the lexicographic range and glob filters
+		if opts.StartOffset != "" && name < opts.StartOffset {
+			continue
+		}
+		if opts.EndOffset != "" && name >= opts.EndOffset {
+			continue
+		}
+		if glob != nil && !glob.MatchString(name) {
+			continue
+		}
+
+		// Handle delimiter (for hierarchical listing)
+		if delimiter != "" {
+			remainingPath := name
+			if prefix != "" {
+				remainingPath = name[len(prefix):]
+			}
+
+			delimIndex := indexOf(remainingPath, delimiter)
+			if delimIndex >= 0 {
+				// This is a "folder" - add to prefixes
+				folderPrefix := prefix + remainingPath[:delimIndex+len(delimiter)]
+				prefixSet[folderPrefix] = struct{}{}
+
+				// An object named like the folder itself is listed too if requested
+				if !opts.IncludeTrailingDelimiter || folderPrefix != name {
+					continue
+				}
+			}
+		}
+
+		objects = append(objects, s.objectMetadata(bucketName, objData))
+	}
+
+	// Sort objects by name for consistent ordering
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].Name < objects[j].Name
+	})
+
+	// Convert prefixes set to sorted slice
+	var prefixes []string
+	for p := range prefixSet {
+		prefixes = append(prefixes, p)
+	}
+	sort.Strings(prefixes)
+
+	return objects, prefixes, nil
+}
+
+// UpdateObject updates an object's metadata.
+// The writable fields and the custom metadata are replaced; the content type is only changed if set.
+// Returns an error if the object doesn't exist.
+func (s *Store) UpdateObject(bucketName, objectName string, req *storage.ObjectUpdateRequest) (*storage.Object, error) {
+	return s.UpdateObjectWithPreconditions(bucketName, objectName, req, Preconditions{})
+}
+
+// UpdateObjectWithPreconditions updates an object's metadata like UpdateObject if the preconditions hold.
+func (s *Store) UpdateObjectWithPreconditions(bucketName, objectName string, req *storage.ObjectUpdateRequest, pre Preconditions) (*storage.Object, error) {
+	s.storageMu.Lock()
+	defer s.storageMu.Unlock()
+
+	bucketObjects, exists := s.objects[bucketName]
+	if !exists {
+		return nil, fmt.Errorf("bucket %s not found", bucketName)
 	}
 
 	objData, exists := bucketObjects[objectName]
 	if !exists {
+		return nil, fmt.Errorf("object %s not found in bucket %s", objectName, bucketName)
+	}
+
+	if err := pre.Check(objData.Metadata); err != nil {
+		return nil, err
+	}
+
+	s.objectMetadataIndex.remove(objData)
+	defer s.objectMetadataIndex.add(objData)
+
+	obj := objData.Metadata
+	if req.ContentType != "" {
+		obj.ContentType = req.ContentType
+	}
+	obj.CacheControl = req.CacheControl
+	obj.ContentDisposition = req.ContentDisposition
+	obj.ContentLanguage = req.ContentLanguage
+	obj.CustomTime = req.CustomTime
+	obj.Metadata = req.Metadata
+	obj.EventBasedHold = req.EventBasedHold
+	obj.TemporaryHold = req.TemporaryHold
+
+	return s.finishObjectMetadataUpdate(obj), nil
+}
+
+// PatchObject patches an object's metadata if the preconditions hold. Unlike UpdateObject,
+// it only changes the fields set in the request, merges the custom metadata and clears
+// the fields listed in req.NullFields.
+// Returns an error if the object doesn't exist.
+func (s *Store) PatchObject(bucketName, objectName string, req *storage.ObjectPatchRequest, pre Preconditions) (*storage.Object, error) {
+	s.storageMu.Lock()
+	defer s.storageMu.Unlock()
+
+	bucketObjects, exists := s.objects[bucketName]
+	if !exists {
+		return nil, fmt.Errorf("bucket %s not found", bucketName)
+	}
+
+	objData, exists := bucketObjects[objectName]
+	if !exists {
+		return nil, fmt.Errorf("object %s not found in bucket %s", objectName, bucketName)
+	}
+
+	if err := pre.Check(objData.Metadata); err != nil {
+		return nil, err
+	}
+
+	s.objectMetadataIndex.remove(objData)
+	defer s.objectMetadataIndex.add(objData)
+
+	obj := objData.Metadata
+	if req.ContentType != nil {
+		obj.ContentType = *req.ContentType
+	}
+	if req.CacheControl != nil {
+		obj.CacheControl = *req.CacheControl
+	}
+	if req.ContentDisposition != nil {
+		obj.ContentDisposition = *req.ContentDisposition
+	}
+	if req.ContentLanguage != nil {
+		obj.ContentLanguage = *req.ContentLanguage
+	}
+	if req.CustomTime != nil {
+		obj.CustomTime = req.CustomTime
+	}
+	if req.EventBasedHold != nil {
+		obj.EventBasedHold = *req.EventBasedHold
+	}
+	if req.TemporaryHold != nil {
+		obj.TemporaryHold = *req.TemporaryHold
+	}
+
+	for _, field := range req.NullFields {
+		switch field {
+		case "contentType":
+			obj.ContentType = "application/octet-stream"
+		case "cacheControl":
+			obj.CacheControl = ""
+		case "contentDisposition":
+			obj.ContentDisposition = ""
+		case "contentLanguage":
+			obj.ContentLanguage = ""
+		case "customTime":
+			obj.CustomTime = nil
+		case "eventBasedHold":
+			obj.EventBasedHold = false
+		case "temporaryHold":
+			obj.TemporaryHold = false
+		case "metadata":
+			obj.Metadata = nil
+		}
+	}
+
+	for key, value := range req.Metadata {
+		if value == nil {
+			delete(obj.Metadata, key)
+			continue
+		}
+		if obj.Metadata == nil {
+			obj.Metadata = make(map[string]string)
+		}
+		obj.Metadata[key] = *value
+	}
+
+	return s.finishObjectMetadataUpdate(obj), nil
+}
+
+// finishObjectMetadataUpdate bumps the metageneration of an object after a metadata change,
+// publishes the change and returns a copy of the object.
+// Callers must hold the storage write lock.
+func (s *Store) finishObjectMetadataUpdate(obj *storage.Object) *storage.Object {
+	obj.Updated = s.now()
+	obj.Metageneration++
+	obj.Etag = generateEtag()
+
+	s.publishObjectEvent(storage.EventObjectMetadataUpdate, obj)
+	s.publish(Event{Type: EventObjectMetadataUpdated, Object: obj})
+
+	return clone(obj)
+}
+
+// DeleteObject deletes an object by bucket and object name.
+// If the bucket has a soft delete policy with a non-zero retention duration,
+// the object is kept as soft-deleted until the retention duration has passed.
+// Returns an error if the object doesn't exist.
+func (s *Store) DeleteObject(bucketName, objectName string) error {
+	return s.DeleteObjectWithPreconditions(bucketName, objectName, Preconditions{})
+}
+
+// DeleteObjectWithPreconditions deletes an object like DeleteObject if the preconditions hold.
+func (s *Store) DeleteObjectWithPreconditions(bucketName, objectName string, pre Preconditions) error {
+	s.storageMu.Lock()
+	defer s.storageMu.Unlock()
+
+	bucketObjects, exists := s.objects[bucketName]
+	if !exists {
+		return fmt.Errorf("bucket %s not found", bucketName)
+	}
+
+	objData, exists := bucketObjects[objectName]
+	if !exists {
+		return fmt.Errorf("object %s not found in bucket %s", objectName, bucketName)
+	}
+
+	if err := pre.Check(objData.Metadata); err != nil {
+		return err
+	}
+
+	now := s.now()
+	if err := s.checkObjectRetention(bucketName, objData.Metadata, now, "deleted"); err != nil {
+		return err
+	}
+	s.purgeExpiredSoftDeletedObjects(bucketName, now)
+	s.deleteObject(bucketName, objData, now)
+
+	return nil
+}
+
+// DeleteObjects deletes all objects of a bucket whose names start with prefix, all of them without one,
+// like single deletes would, but in one go. If a hold or the retention policy of the bucket keeps any of
+// them from being deleted, none are. Returns the number of deleted objects, or an error if the bucket doesn't exist.
+func (s *Store) DeleteObjects(bucketName, prefix string) (int, error) {
+	s.storageMu.Lock()
+	defer s.storageMu.Unlock()
+
+	bucketObjects, exists := s.objects[bucketName]
+	if !exists {
+		return 0, fmt.Errorf("bucket %s not found", bucketName)
+	}
+
+	now := s.now()
+	s.purgeExpiredSoftDeletedObjects(bucketName, now)
+
+	// Delete in name order, so notifications and events are in a predictable order
+	var names []string
+	for name := range bucketObjects {
+		if hasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := s.checkObjectRetention(bucketName, bucketObjects[name].Metadata, now, "deleted"); err != nil {
+			return 0, err
+		}
+	}
+	for _, name := range names {
+		s.deleteObject(bucketName, bucketObjects[name], now)
+	}
+
+	return len(names), nil
+}
+
+// deleteObject removes a live object from its bucket, keeping it as soft-deleted if the bucket has a
+// soft delete policy, and publishes the deletion. Callers must hold the storage write lock.
+func (s *Store) deleteObject(bucketName string, objData *ObjectData, now time.Time) {
+	delete(s.objects[bucketName], objData.Metadata.Name)
+	s.objectMetadataIndex.remove(objData)
+	s.retireObject(bucketName, objData, now)
+
+	s.publishObjectEvent(storage.EventObjectDelete, objData.Metadata)
+	s.publish(Event{Type: EventObjectDeleted, Object: objData.Metadata})
+}
+
+// retireObject disposes of a generation that is no longer live because it was deleted or overwritten:
+// it is kept as soft-deleted if the bucket has a soft delete policy, and its content is released otherwise.
+// Callers must hold the storage write lock.
+func (s *Store) retireObject(bucketName string, objData *ObjectData, now time.Time) {
+	policy := s.buckets[bucketName].SoftDeletePolicy
+	if policy == nil || policy.RetentionDurationSeconds <= 0 {
+		objData.Content.Release()
+		return
+	}
+
+	softDeleteTime := now
+	hardDeleteTime := now.Add(time.Duration(policy.RetentionDurationSeconds) * time.Second)
+	objData.Metadata.SoftDeleteTime = &softDeleteTime
+	objData.Metadata.HardDeleteTime = &hardDeleteTime
+	s.softDeletedObjects[bucketName] = append(s.softDeletedObjects[bucketName], objData)
+}
+
+// ListSoftDeletedObjects returns all soft-deleted objects in a bucket that are
+// still within their retention duration, optionally filtered by prefix.
+func (s *Store) ListSoftDeletedObjects(bucketName, prefix string) []*storage.Object {
+	s.storageMu.RLock()
+	defer s.storageMu.RUnlock()
+
+	now := s.now()
+
+	var objects []*storage.Object
+	for _, objData := range s.softDeletedObjects[bucketName] {
+		if objData.Metadata.HardDeleteTime.Before(now) {
+			continue
+		}
+		if prefix != "" && !hasPrefix(objData.Metadata.Name, prefix) {
+			continue
+		}
+		objects = append(objects, objData.Metadata)
+	}
+
+	// Sort by name, then by generation for consistent ordering
+	sort.Slice(objects, func(i, j int) bool {
+		if objects[i].Name != objects[j].Name {
+			return objects[i].Name < objects[j].Name
+		}
+		return objects[i].Generation < objects[j].Generation
+	})
+
+	return clone(objects)
+}
+
+// GetSoftDeletedObject retrieves a soft-deleted object by bucket, object name and generation.
+// Returns nil if no such soft-deleted object exists or its retention duration has passed.
+func (s *Store) GetSoftDeletedObject(bucketName, objectName string, generation int64) *storage.Object {
+	s.storageMu.RLock()
+	defer s.storageMu.RUnlock()
+
+	idx := s.findSoftDeletedObject(bucketName, objectName, generation)
+	if idx < 0 {
 		return nil
 	}
 
-	return objData.Content
+	return clone(s.softDeletedObjects[bucketName][idx].Metadata)
+}
+
+// RestoreObject restores a soft-deleted object as the live version of the object.
+// The restored object gets a new generation, like in the real API.
+// Returns an error if the soft-deleted object doesn't exist or a live object with the same name exists.
+func (s *Store) RestoreObject(bucketName, objectName string, generation int64) (*storage.Object, error) {
+	s.storageMu.Lock()
+	defer s.storageMu.Unlock()
+
+	bucketObjects, exists := s.objects[bucketName]
+	if !exists {
+		return nil, fmt.Errorf("bucket %s not found", bucketName)
+	}
+
+	now := s.now()
+	s.purgeExpiredSoftDeletedObjects(bucketName, now)
+
+	idx := s.findSoftDeletedObject(bucketName, objectName, generation)
+	if idx < 0 {
+		return nil, fmt.Errorf("soft-deleted object %s#%d not found in bucket %s", objectName, generation, bucketName)
+	}
+
+	if _, exists := bucketObjects[objectName]; exists {
+		return nil, fmt.Errorf("object %s already exists in bucket %s", objectName, bucketName)
+	}
+
+	objData := s.softDeletedObjects[bucketName][idx]
+	if err := s.checkBucketQuota(bucketName, objData.Content.Size(), true); err != nil {
+		return nil, err
+	}
+
+	// Like in CreateObject, the new generation must be newer than every earlier one even if the clock stands still
+	newGeneration := now.UnixNano()
+	for _, deleted := range s.softDeletedObjects[bucketName] {
+		if deleted.Metadata.Name == objectName && newGeneration <= deleted.Metadata.Generation {
+			newGeneration = deleted.Metadata.Generation + 1
+		}
+	}
+	s.softDeletedObjects[bucketName] = append(s.softDeletedObjects[bucketName][:idx], s.softDeletedObjects[bucketName][idx+1:]...)
+
+	obj := objData.Metadata
+	obj.ID = fmt.Sprintf("%s/%s/%d", bucketName, objectName, newGeneration)
+	obj.Generation = newGeneration
+	obj.Metageneration = 1
+	obj.Updated = now
+	obj.Etag = generateEtag()
+	obj.SoftDeleteTime = nil
+	obj.HardDeleteTime = nil
+	completeObjectACL(s.config().baseURL, obj)
+
+	bucketObjects[objectName] = objData
+	s.objectMetadataIndex.add(objData)
+
+	s.publishObjectEvent(storage.EventObjectFinalize, obj)
+	s.publish(Event{Type: EventObjectFinalized, Object: obj})
+
+	return clone(obj), nil
+}
+
+// RewriteObject copies an object to a destination object, which may be the object itself, e.g. to change
+// its storage class. The fields set in req replace those of the source object; without a storage class,
+// the destination bucket's default storage class is used. The content is copied in a single call.
+// Returns an error if the source object or the destination bucket doesn't exist.
+func (s *Store) RewriteObject(srcBucket, srcObject, dstBucket, dstObject string, req *storage.ObjectInsertRequest, pre Preconditions) (*storage.Object, error) {
+	src, r, err := s.OpenObjectContent(srcBucket, srcObject)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	contentType := src.ContentType
+	if req.ContentType != "" {
+		contentType = req.ContentType
+	}
+	metadata := src.Metadata
+	if req.Metadata != nil {
+		metadata = req.Metadata
+	}
+
+	opts := InsertOptions(req, pre)
+	if opts.CacheControl == "" {
+		opts.CacheControl = src.CacheControl
+	}
+	if opts.ContentDisposition == "" {
+		opts.ContentDisposition = src.ContentDisposition
+	}
+	if opts.ContentLanguage == "" {
+		opts.ContentLanguage = src.ContentLanguage
+	}
+	if opts.CustomTime == nil {
+		opts.CustomTime = src.CustomTime
+	}
+
+	return s.CreateObjectWithOptions(dstBucket, dstObject, contentType, r, metadata, opts)
+}
+
+// findSoftDeletedObject returns the index of a soft-deleted object that is still
+// within its retention duration, or -1 if it doesn't exist.
+// The caller must hold the lock.
+func (s *Store) findSoftDeletedObject(bucketName, objectName string, generation int64) int {
+	now := s.now()
+	for i, objData := range s.softDeletedObjects[bucketName] {
+		if objData.Metadata.Name == objectName && objData.Metadata.Generation == generation && !objData.Metadata.HardDeleteTime.Before(now) {
+			return i
+		}
+	}
+	return -1
+}
+
+// purgeExpiredSoftDeletedObjects permanently removes soft-deleted objects whose
+// retention duration has passed. The caller must hold the write lock.
+func (s *Store) purgeExpiredSoftDeletedObjects(bucketName string, now time.Time) {
+	softDeleted := s.softDeletedObjects[bucketName]
+	kept := softDeleted[:0]
+	for _, objData := range softDeleted {
+		if !objData.Metadata.HardDeleteTime.Before(now) {
+			kept = append(kept, objData)
+		} else {
+			objData.Content.Release()
+		}
+	}
+	s.softDeletedObjects[bucketName] = kept
 }
 
-// ListObjects returns all objects in a bucket, optionally filtered by prefix.
-func (s *Store) ListObjects(bucketName, prefix, delimiter string) ([]*storage.Object, []string) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// CreateNotification creates a new notification configuration for a bucket.
+// Returns an error if the bucket doesn't exist.
+func (s *Store) CreateNotification(bucketName string, req *storage.NotificationInsertRequest) (*storage.Notification, error) {
+	s.storageMu.Lock()
+	defer s.storageMu.Unlock()
 
-	bucketObjects, exists := s.objects[bucketName]
-	if !exists {
-		return nil, nil
+	if _, exists := s.buckets[bucketName]; !exists {
+		return nil, fmt.Errorf("bucket %s not found", bucketName)
 	}
 
-	var objects []*storage.Object
-	prefixSet := make(map[string]struct{})
-
-	for name, objData := range bucketObjects {
-		// Check prefix filter
-		if prefix != "" && !hasPrefix(name, prefix) {
-			continue
-		}
+	payloadFormat := req.PayloadFormat
+	if payloadFormat == "" {
+		payloadFormat = storage.PayloadFormatJSONAPIV1
+	}
 
-		// Handle delimiter (for hierarchical listing)
-		if delimiter != "" {
-			remainingPath := name
-			if prefix != "" {
-				remainingPath = name[len(prefix):]
-			}
+	s.notificationSeq++
+	id := fmt.Sprintf("%d", s.notificationSeq)
 
-			delimIndex := indexOf(remainingPath, delimiter)
-			if delimIndex >= 0 {
-				// This is a "folder" - add to prefixes
-				folderPrefix := prefix + remainingPath[:delimIndex+len(delimiter)]
-				prefixSet[folderPrefix] = struct{}{}
-				continue
-			}
-		}
+	notification := &storage.Notification{
+		Kind:             "storage#notification",
+		ID:               id,
+		SelfLink:         fmt.Sprintf("%s/storage/v1/b/%s/notificationConfigs/%s", s.config().baseURL, bucketName, id),
+		Topic:            req.Topic,
+		EventTypes:       req.EventTypes,
+		CustomAttributes: req.CustomAttributes,
+		PayloadFormat:    payloadFormat,
+		ObjectNamePrefix: req.ObjectNamePrefix,
+		Etag:             generateEtag(),
+	}
 
-		objects = append(objects, objData.Metadata)
+	if s.notifications[bucketName] == nil {
+		s.notifications[bucketName] = make(map[string]*storage.Notification)
 	}
+	s.notifications[bucketName][id] = notification
 
-	// Sort objects by name for consistent ordering
-	sort.Slice(objects, func(i, j int) bool {
-		return objects[i].Name < objects[j].Name
-	})
+	return clone(notification), nil
+}
 
-	// Convert prefixes set to sorted slice
-	var prefixes []string
-	for p := range prefixSet {
-		prefixes = append(prefixes, p)
-	}
-	sort.Strings(prefixes)
+// GetNotification retrieves a notification configuration by bucket name and ID.
+// Returns nil if the notification doesn't exist.
+func (s *Store) GetNotification(bucketName, id string) *storage.Notification {
+	s.storageMu.RLock()
+	defer s.storageMu.RUnlock()
 
-	return objects, prefixes
+	return clone(s.notifications[bucketName][id])
 }
 
-// UpdateObject updates an object's metadata.
-// Returns an error if the object doesn't exist.
-func (s *Store) UpdateObject(bucketName, objectName string, metadata map[string]string) (*storage.Object, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// ListNotifications returns all notification configurations of a bucket.
+// Returns an error if the bucket doesn't exist.
+func (s *Store) ListNotifications(bucketName string) ([]*storage.Notification, error) {
+	s.storageMu.RLock()
+	defer s.storageMu.RUnlock()
 
-	bucketObjects, exists := s.objects[bucketName]
-	if !exists {
+	if _, exists := s.buckets[bucketName]; !exists {
 		return nil, fmt.Errorf("bucket %s not found", bucketName)
 	}
 
-	objData, exists := bucketObjects[objectName]
-	if !exists {
-		return nil, fmt.Errorf("object %s not found in bucket %s", objectName, bucketName)
+	notifications := make([]*storage.Notification, 0, len(s.notifications[bucketName]))
+	for _, notification := range s.notifications[bucketName] {
+		notifications = append(notifications, notification)
 	}
 
-	objData.Metadata.Metadata = metadata
-	objData.Metadata.Updated = time.Now().UTC()
-	objData.Metadata.Metageneration++
-	objData.Metadata.Etag = generateEtag()
+	// Sort by ID (creation order) for consistent ordering
+	sort.Slice(notifications, func(i, j int) bool {
+		if len(notifications[i].ID) != len(notifications[j].ID) {
+			return len(notifications[i].ID) < len(notifications[j].ID)
+		}
+		return notifications[i].ID < notifications[j].ID
+	})
 
-	return objData.Metadata, nil
+	return clone(notifications), nil
 }
 
-// DeleteObject deletes an object by bucket and object name.
-// Returns an error if the object doesn't exist.
-func (s *Store) DeleteObject(bucketName, objectName string) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// DeleteNotification deletes a notification configuration.
+// Returns an error if the bucket or notification doesn't exist.
+func (s *Store) DeleteNotification(bucketName, id string) error {
+	s.storageMu.Lock()
+	defer s.storageMu.Unlock()
 
-	bucketObjects, exists := s.objects[bucketName]
-	if !exists {
+	if _, exists := s.buckets[bucketName]; !exists {
 		return fmt.Errorf("bucket %s not found", bucketName)
 	}
 
-	if _, exists := bucketObjects[objectName]; !exists {
-		return fmt.Errorf("object %s not found in bucket %s", objectName, bucketName)
+	if _, exists := s.notifications[bucketName][id]; !exists {
+		return fmt.Errorf("notification %s not found in bucket %s", id, bucketName)
 	}
 
-	delete(bucketObjects, objectName)
+	delete(s.notifications[bucketName], id)
 
 	return nil
 }
 
-// generateEtag generates a simple etag for a resource.
+// publishObjectEvent passes an object event to the notification handler for every
+// notification configuration of the object's bucket that matches the event.
+// The caller must hold the write lock.
+func (s *Store) publishObjectEvent(eventType string, obj *storage.Object) {
+	handler := s.config().notificationHandler
+	if handler == nil {
+		return
+	}
+
+	for _, notification := range s.notifications[obj.Bucket] {
+		if !notificationMatches(notification, eventType, obj.Name) {
+			continue
+		}
+		handler(notification, eventType, *obj)
+	}
+}
+
+// notificationMatches checks if a notification configuration applies to an event on an object.
+func notificationMatches(notification *storage.Notification, eventType, objectName string) bool {
+	if notification.ObjectNamePrefix != "" && !hasPrefix(objectName, notification.ObjectNamePrefix) {
+		return false
+	}
+
+	if len(notification.EventTypes) == 0 {
+		return true
+	}
+
+	for _, t := range notification.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// lastEtag is the number of the last generated etag.
+var lastEtag atomic.Int64
+
+// generateEtag generates a simple etag for a resource. Etags are unique, even if they are generated
+// within the same nanosecond, so a changed resource never gets the etag it had before.
 func generateEtag() string {
-	return fmt.Sprintf("CAE%d=", time.Now().UnixNano())
+	for {
+		last := lastEtag.Load()
+		next := max(time.Now().UnixNano(), last+1)
+		if lastEtag.CompareAndSwap(last, next) {
+			return fmt.Sprintf("CAE%d=", next)
+		}
+	}
+}
+
+// checkEtag checks the etag a change is based on against the current etag of the resource.
+// An empty etag matches any resource. Returns an error if the etags don't match.
+func checkEtag(etag, current string) error {
+	if etag != "" && etag != current {
+		return fmt.Errorf("precondition failed: etag %s doesn't match the current etag %s", etag, current)
+	}
+	return nil
 }
 
 // computeMD5Hash computes the base64-encoded MD5 hash of data.
@@ -440,15 +1952,27 @@ func computeMD5Hash(data []byte) string {
 func computeCRC32C(data []byte) string {
 	// Use Castagnoli polynomial for CRC32C
 	table := crc32.MakeTable(crc32.Castagnoli)
-	checksum := crc32.Checksum(data, table)
-	// Convert to 4 bytes big-endian and base64 encode
-	bytes := []byte{
+	return encodeCRC32C(crc32.Checksum(data, table))
+}
+
+// encodeCRC32C base64-encodes a CRC32C checksum as 4 big-endian bytes.
+func encodeCRC32C(checksum uint32) string {
+	buf := []byte{
 		byte(checksum >> 24),
 		byte(checksum >> 16),
 		byte(checksum >> 8),
 		byte(checksum),
 	}
-	return base64.StdEncoding.EncodeToString(bytes)
+	return base64.StdEncoding.EncodeToString(buf)
+}
+
+// kmsKeyVersionName returns the name of the key version objects report for a Cloud KMS key.
+// The mock has no key rotation, so it's always the first version.
+func kmsKeyVersionName(keyName string) string {
+	if keyName == "" || strings.Contains(keyName, "/cryptoKeyVersions/") {
+		return keyName
+	}
+	return keyName + "/cryptoKeyVersions/1"
 }
 
 // hasPrefix checks if a string has the given prefix.
@@ -466,6 +1990,14 @@ func indexOf(s, substr string) int {
 	return -1
 }
 
+// timesEqual checks if two optional times are both unset or the same instant.
+func timesEqual(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
 // metadataEqual compares two metadata maps for equality.
 func metadataEqual(a, b map[string]string) bool {
 	if len(a) != len(b) {
@@ -486,26 +2018,76 @@ func metadataEqual(a, b map[string]string) bool {
 // CreateSQLInstance creates a new Cloud SQL instance in the store.
 // Returns an error if an instance with the same name already exists.
 func (s *Store) CreateSQLInstance(req *sqladmin.InstanceInsertRequest) (*sqladmin.DatabaseInstance, *sqladmin.Operation, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	s.sqlMu.Lock()
+	defer s.sqlMu.Unlock()
+
+	cfg := s.config()
 
 	if _, exists := s.sqlInstances[req.Name]; exists {
 		return nil, nil, fmt.Errorf("instance %s already exists", req.Name)
 	}
 
-	now := time.Now().UTC()
+	// Read replicas default to the region and database version of their primary
+	region := req.Region
+	databaseVersion := req.DatabaseVersion
+	var primary *sqladmin.DatabaseInstance
+	if req.MasterInstanceName != "" {
+		var exists bool
+		primary, exists = s.sqlInstances[req.MasterInstanceName]
+		if !exists {
+			return nil, nil, fmt.Errorf("primary instance %s not found", req.MasterInstanceName)
+		}
+		if primary.MasterInstanceName != "" {
+			return nil, nil, fmt.Errorf("invalid primary instance %s: replicas can't have replicas", req.MasterInstanceName)
+		}
+		if databaseVersion != "" && databaseVersion != primary.DatabaseVersion {
+			return nil, nil, fmt.Errorf("invalid database version %s: replicas must use the version of their primary, %s", databaseVersion, primary.DatabaseVersion)
+		}
+		if region == "" {
+			region = primary.Region
+		}
+		databaseVersion = primary.DatabaseVersion
+	}
+
+	now := s.now()
 
 	// Set defaults
-	region := req.Region
 	if region == "" {
 		region = "us-central1"
 	}
 
-	databaseVersion := req.DatabaseVersion
 	if databaseVersion == "" {
 		databaseVersion = "MYSQL_8_0"
 	}
 
+	var ipConfiguration *sqladmin.IPConfiguration
+	if req.Settings != nil {
+		if err := sqladmin.ValidateFlags(databaseVersion, req.Settings.DatabaseFlags); err != nil {
+			return nil, nil, err
+		}
+		if err := s.checkSQLPrivateNetwork(req.Settings.IPConfiguration); err != nil {
+			return nil, nil, err
+		}
+		ipConfiguration = req.Settings.IPConfiguration
+	}
+	// Instances without an IP configuration get a public IP address
+	if ipConfiguration == nil {
+		ipConfiguration = &sqladmin.IPConfiguration{IPv4Enabled: true}
+	}
+	if err := s.checkSQLOrgPolicies(ipConfiguration); err != nil {
+		return nil, nil, err
+	}
+	if cfg.strictValidation {
+		if err := sqladmin.ValidateRegion(region); err != nil {
+			return nil, nil, err
+		}
+		if req.Settings != nil && req.Settings.Tier != "" {
+			if err := sqladmin.ValidateTier(databaseVersion, req.Settings.Edition, req.Settings.Tier); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
 	// Create default settings if not provided
 	settings := req.Settings
 	if settings == nil {
@@ -539,14 +2121,14 @@ func (s *Store) CreateSQLInstance(req *sqladmin.InstanceInsertRequest) (*sqladmi
 	instance := &sqladmin.DatabaseInstance{
 		Kind:            "sql#instance",
 		Name:            req.Name,
-		State:           "RUNNABLE",
+		State:           activeSQLState(settings),
 		DatabaseVersion: databaseVersion,
 		Region:          region,
-		Project:         s.projectID,
+		Project:         cfg.projectID,
 		BackendType:     "SECOND_GEN",
 		InstanceType:    "CLOUD_SQL_INSTANCE",
-		SelfLink:        fmt.Sprintf("%s/sql/v1beta4/projects/%s/instances/%s", s.baseURL, s.projectID, req.Name),
-		ConnectionName:  fmt.Sprintf("%s:%s:%s", s.projectID, region, req.Name),
+		SelfLink:        fmt.Sprintf("%s/sql/v1beta4/projects/%s/instances/%s", cfg.baseURL, cfg.projectID, req.Name),
+		ConnectionName:  fmt.Sprintf("%s:%s:%s", cfg.projectID, region, req.Name),
 		CreateTime:      now,
 		Settings:        settings,
 		Etag:            generateEtag(),
@@ -557,12 +2139,13 @@ func (s *Store) CreateSQLInstance(req *sqladmin.InstanceInsertRequest) (*sqladmi
 				IPAddress: mockIP,
 			},
 		},
-		ServiceAccountEmailAddress: fmt.Sprintf("p%d-abc123@gcp-sa-cloud-sql.iam.gserviceaccount.com", s.projectNumber),
+		ServiceAccountEmailAddress: fmt.Sprintf("p%d-abc123@gcp-sa-cloud-sql.iam.gserviceaccount.com", cfg.projectNumber),
 	}
 
-	if req.MasterInstanceName != "" {
-		instance.MasterInstanceName = req.MasterInstanceName
+	if primary != nil {
+		instance.MasterInstanceName = primary.Name
 		instance.InstanceType = "READ_REPLICA_INSTANCE"
+		primary.ReplicaNames = append(primary.ReplicaNames, req.Name)
 	}
 
 	s.sqlInstances[req.Name] = instance
@@ -576,8 +2159,8 @@ func (s *Store) CreateSQLInstance(req *sqladmin.InstanceInsertRequest) (*sqladmi
 		Charset:   "utf8",
 		Collation: "utf8_general_ci",
 		Instance:  req.Name,
-		Project:   s.projectID,
-		SelfLink:  fmt.Sprintf("%s/sql/v1beta4/projects/%s/instances/%s/databases/mysql", s.baseURL, s.projectID, req.Name),
+		Project:   cfg.projectID,
+		SelfLink:  fmt.Sprintf("%s/sql/v1beta4/projects/%s/instances/%s/databases/mysql", cfg.baseURL, cfg.projectID, req.Name),
 		Etag:      generateEtag(),
 	}
 	s.sqlDatabases[req.Name]["mysql"] = defaultDB
@@ -588,7 +2171,7 @@ func (s *Store) CreateSQLInstance(req *sqladmin.InstanceInsertRequest) (*sqladmi
 		Name:     "root",
 		Host:     "%",
 		Instance: req.Name,
-		Project:  s.projectID,
+		Project:  cfg.projectID,
 		Type:     "BUILT_IN",
 		Etag:     generateEtag(),
 	}
@@ -597,22 +2180,80 @@ func (s *Store) CreateSQLInstance(req *sqladmin.InstanceInsertRequest) (*sqladmi
 	// Create operation
 	op := s.createOperation("CREATE", req.Name, now)
 
-	return instance, op, nil
+	// Keep the instance pending until the create delay has passed
+	if cfg.sqlCreateDelay > 0 {
+		instance.State = "PENDING_CREATE"
+		op.Status = "RUNNING"
+		op.EndTime = time.Time{}
+		s.sqlPendingCreates[op.Name] = now.Add(cfg.sqlCreateDelay)
+	}
+	s.publish(Event{Type: EventSQLInstanceCreated, Instance: instance})
+
+	return clone(instance), clone(op), nil
+}
+
+// activeSQLState returns the state of a created instance with the given settings:
+// STOPPED if its activation policy is NEVER, otherwise RUNNABLE.
+func activeSQLState(settings *sqladmin.Settings) string {
+	if settings != nil && settings.ActivationPolicy == "NEVER" {
+		return "STOPPED"
+	}
+	return "RUNNABLE"
+}
+
+// checkSQLInstanceState returns an error if the instance is in none of the given states.
+// The message matches the one Cloud SQL answers requests for instances in the wrong state with.
+func checkSQLInstanceState(instance *sqladmin.DatabaseInstance, states ...string) error {
+	for _, state := range states {
+		if instance.State == state {
+			return nil
+		}
+	}
+	return fmt.Errorf("instance %s is %s: the instance or operation is not in an appropriate state to handle the request", instance.Name, instance.State)
+}
+
+// completeSQLCreates marks instances whose create delay has passed as RUNNABLE
+// and completes their create operations.
+// Must be called with the write lock held.
+func (s *Store) completeSQLCreates() {
+	if len(s.sqlPendingCreates) == 0 {
+		return
+	}
+
+	now := s.now()
+	for opName, readyAt := range s.sqlPendingCreates {
+		if now.Before(readyAt) {
+			continue
+		}
+
+		if op, ok := s.sqlOperations[opName]; ok {
+			op.Status = "DONE"
+			op.EndTime = readyAt
+			if instance, ok := s.sqlInstances[op.TargetId]; ok && instance.State == "PENDING_CREATE" {
+				instance.State = activeSQLState(instance.Settings)
+				s.publish(Event{Type: EventSQLInstanceUpdated, Instance: instance})
+			}
+		}
+		delete(s.sqlPendingCreates, opName)
+	}
 }
 
 // GetSQLInstance retrieves a Cloud SQL instance by name.
 // Returns nil if the instance doesn't exist.
 func (s *Store) GetSQLInstance(name string) *sqladmin.DatabaseInstance {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	s.sqlMu.Lock()
+	defer s.sqlMu.Unlock()
 
-	return s.sqlInstances[name]
+	s.updateSQLState()
+	return clone(s.sqlInstances[name])
 }
 
 // ListSQLInstances returns all Cloud SQL instances in the store.
 func (s *Store) ListSQLInstances() []*sqladmin.DatabaseInstance {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	s.sqlMu.Lock()
+	defer s.sqlMu.Unlock()
+
+	s.updateSQLState()
 
 	instances := make([]*sqladmin.DatabaseInstance, 0, len(s.sqlInstances))
 	for _, instance := range s.sqlInstances {
@@ -624,23 +2265,83 @@ func (s *Store) ListSQLInstances() []*sqladmin.DatabaseInstance {
 		return instances[i].Name < instances[j].Name
 	})
 
-	return instances
+	return clone(instances)
+}
+
+// checkSQLPrivateNetwork checks that the private network of an instance, if it has one, is a network of the mock.
+// Must be called with the Cloud SQL lock held.
+func (s *Store) checkSQLPrivateNetwork(ipConfiguration *sqladmin.IPConfiguration) error {
+	if ipConfiguration == nil || ipConfiguration.PrivateNetwork == "" {
+		return nil
+	}
+	return s.checkComputeNetwork("privateNetwork", ipConfiguration.PrivateNetwork, s.config().projectID)
+}
+
+// checkSQLOrgPolicies checks the IP configuration of an instance against the organization policy constraints
+// sql.restrictPublicIp and sql.restrictAuthorizedNetworks.
+func (s *Store) checkSQLOrgPolicies(ipConfiguration *sqladmin.IPConfiguration) error {
+	if ipConfiguration.IPv4Enabled && s.orgPolicyEnforced(orgpolicy.ConstraintRestrictPublicIp) {
+		return fmt.Errorf("invalid request: %w", s.orgPolicyError(orgpolicy.ConstraintRestrictPublicIp,
+			"instances can't have a public IP address; set settings.ipConfiguration.ipv4Enabled to false"))
+	}
+	if len(ipConfiguration.AuthorizedNetworks) > 0 && s.orgPolicyEnforced(orgpolicy.ConstraintRestrictAuthorizedNetworks) {
+		return fmt.Errorf("invalid request: %w", s.orgPolicyError(orgpolicy.ConstraintRestrictAuthorizedNetworks,
+			"instances can't have authorized networks"))
+	}
+	return nil
 }
 
 // UpdateSQLInstance updates an existing Cloud SQL instance.
-// Returns an error if the instance doesn't exist.
+// Returns an error if the instance doesn't exist or the etag of the request doesn't match.
 func (s *Store) UpdateSQLInstance(name string, req *sqladmin.InstancePatchRequest) (*sqladmin.DatabaseInstance, *sqladmin.Operation, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	s.sqlMu.Lock()
+	defer s.sqlMu.Unlock()
+
+	s.updateSQLState()
 
 	instance, exists := s.sqlInstances[name]
 	if !exists {
 		return nil, nil, fmt.Errorf("instance %s not found", name)
 	}
+	if err := checkEtag(req.Etag, instance.Etag); err != nil {
+		return nil, nil, err
+	}
+
+	if err := checkSQLInstanceState(instance, "RUNNABLE", "STOPPED"); err != nil {
+		return nil, nil, err
+	}
+
+	if req.Settings != nil {
+		if err := sqladmin.ValidateFlags(instance.DatabaseVersion, req.Settings.DatabaseFlags); err != nil {
+			return nil, nil, err
+		}
+		if err := s.checkSQLPrivateNetwork(req.Settings.IPConfiguration); err != nil {
+			return nil, nil, err
+		}
+		if req.Settings.IPConfiguration != nil {
+			if err := s.checkSQLOrgPolicies(req.Settings.IPConfiguration); err != nil {
+				return nil, nil, err
+			}
+		}
+		if s.config().strictValidation && req.Settings.Tier != "" {
+			edition := req.Settings.Edition
+			if edition == "" {
+				edition = instance.Settings.Edition
+			}
+			if err := sqladmin.ValidateTier(instance.DatabaseVersion, edition, req.Settings.Tier); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
 
-	now := time.Now().UTC()
+	now := s.now()
 
 	if req.Settings != nil {
+		// The activation policy starts and stops the instance
+		if req.Settings.ActivationPolicy != "" {
+			instance.Settings.ActivationPolicy = req.Settings.ActivationPolicy
+			instance.State = activeSQLState(instance.Settings)
+		}
 		if req.Settings.Tier != "" {
 			instance.Settings.Tier = req.Settings.Tier
 		}
@@ -665,23 +2366,132 @@ func (s *Store) UpdateSQLInstance(name string, req *sqladmin.InstancePatchReques
 		if req.Settings.DatabaseFlags != nil {
 			instance.Settings.DatabaseFlags = req.Settings.DatabaseFlags
 		}
+		if req.Settings.PasswordValidationPolicy != nil {
+			instance.Settings.PasswordValidationPolicy = req.Settings.PasswordValidationPolicy
+		}
 		instance.Settings.DeletionProtectionEnabled = req.Settings.DeletionProtectionEnabled
 		instance.Settings.SettingsVersion++
 	}
 
 	instance.Etag = generateEtag()
+	s.publish(Event{Type: EventSQLInstanceUpdated, Instance: instance})
 
 	// Create operation
 	op := s.createOperation("UPDATE", name, now)
 
-	return instance, op, nil
+	return clone(instance), clone(op), nil
+}
+
+// RestartSQLInstance restarts a Cloud SQL instance.
+// Returns an error if the instance doesn't exist or isn't RUNNABLE, e.g. because it is stopped.
+func (s *Store) RestartSQLInstance(name string) (*sqladmin.Operation, error) {
+	s.sqlMu.Lock()
+	defer s.sqlMu.Unlock()
+
+	s.updateSQLState()
+
+	instance, exists := s.sqlInstances[name]
+	if !exists {
+		return nil, fmt.Errorf("instance %s not found", name)
+	}
+
+	if err := checkSQLInstanceState(instance, "RUNNABLE"); err != nil {
+		return nil, err
+	}
+
+	return clone(s.createOperation("RESTART", name, s.now())), nil
+}
+
+// PromoteSQLReplica promotes a read replica to a standalone primary instance.
+// Returns an error if the instance doesn't exist or isn't a replica.
+func (s *Store) PromoteSQLReplica(name string) (*sqladmin.Operation, error) {
+	s.sqlMu.Lock()
+	defer s.sqlMu.Unlock()
+
+	instance, exists := s.sqlInstances[name]
+	if !exists {
+		return nil, fmt.Errorf("instance %s not found", name)
+	}
+
+	if instance.MasterInstanceName == "" {
+		return nil, fmt.Errorf("invalid request: instance %s is not a read replica", name)
+	}
+
+	if primary, ok := s.sqlInstances[instance.MasterInstanceName]; ok {
+		primary.ReplicaNames = removeString(primary.ReplicaNames, name)
+		primary.Etag = generateEtag()
+	}
+
+	instance.MasterInstanceName = ""
+	instance.InstanceType = "CLOUD_SQL_INSTANCE"
+	instance.Etag = generateEtag()
+	s.publish(Event{Type: EventSQLInstanceUpdated, Instance: instance})
+
+	return clone(s.createOperation("PROMOTE_REPLICA", name, s.now())), nil
+}
+
+// removeString returns values without the first occurrence of value.
+// Returns nil if no values are left.
+func removeString(values []string, value string) []string {
+	for i, v := range values {
+		if v == value {
+			values = append(values[:i:i], values[i+1:]...)
+			break
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	return values
+}
+
+// sqlInstanceStates are the states an instance can be put into with SetSQLInstanceState.
+var sqlInstanceStates = map[string]bool{
+	"RUNNABLE":    true,
+	"STOPPED":     true,
+	"SUSPENDED":   true,
+	"MAINTENANCE": true,
+	"FAILED":      true,
+}
+
+// SetSQLInstanceState puts a Cloud SQL instance into a state that can't be reached through the API,
+// like SUSPENDED or MAINTENANCE, so clients that react to the instance state can be tested.
+// Suspended instances get the suspension reasons, BILLING_ISSUE if none are given.
+// Returns an error if the instance doesn't exist or the state is invalid.
+func (s *Store) SetSQLInstanceState(name, state string, suspensionReasons []string) (*sqladmin.DatabaseInstance, error) {
+	s.sqlMu.Lock()
+	defer s.sqlMu.Unlock()
+
+	s.updateSQLState()
+
+	instance, exists := s.sqlInstances[name]
+	if !exists {
+		return nil, fmt.Errorf("instance %s not found", name)
+	}
+
+	if !sqlInstanceStates[state] {
+		return nil, fmt.Errorf("invalid instance state %q: must be RUNNABLE, STOPPED, SUSPENDED, MAINTENANCE or FAILED", state)
+	}
+
+	instance.State = state
+	instance.SuspensionReason = nil
+	if state == "SUSPENDED" {
+		instance.SuspensionReason = suspensionReasons
+		if len(instance.SuspensionReason) == 0 {
+			instance.SuspensionReason = []string{"BILLING_ISSUE"}
+		}
+	}
+	instance.Etag = generateEtag()
+	s.publish(Event{Type: EventSQLInstanceUpdated, Instance: instance})
+
+	return clone(instance), nil
 }
 
 // DeleteSQLInstance deletes a Cloud SQL instance by name.
 // Returns an error if the instance doesn't exist.
 func (s *Store) DeleteSQLInstance(name string) (*sqladmin.Operation, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	s.sqlMu.Lock()
+	defer s.sqlMu.Unlock()
 
 	instance, exists := s.sqlInstances[name]
 	if !exists {
@@ -693,16 +2503,30 @@ func (s *Store) DeleteSQLInstance(name string) (*sqladmin.Operation, error) {
 		return nil, fmt.Errorf("instance %s has deletion protection enabled", name)
 	}
 
-	now := time.Now().UTC()
+	// Replicas must be deleted or promoted before their primary
+	if len(instance.ReplicaNames) > 0 {
+		return nil, fmt.Errorf("instance %s has replicas %s: delete or promote them first", name, strings.Join(instance.ReplicaNames, ", "))
+	}
+
+	if primary, ok := s.sqlInstances[instance.MasterInstanceName]; ok {
+		primary.ReplicaNames = removeString(primary.ReplicaNames, name)
+	}
+
+	now := s.now()
 
 	delete(s.sqlInstances, name)
 	delete(s.sqlDatabases, name)
 	delete(s.sqlUsers, name)
+	if m, ok := s.sqlMaintenance[name]; ok && m.Operation != "" {
+		s.finishSQLMaintenance(name, m)
+	}
+	delete(s.sqlMaintenance, name)
+	s.publish(Event{Type: EventSQLInstanceDeleted, Instance: instance})
 
 	// Create operation
 	op := s.createOperation("DELETE", name, now)
 
-	return op, nil
+	return clone(op), nil
 }
 
 // =============================================================================
@@ -712,8 +2536,10 @@ func (s *Store) DeleteSQLInstance(name string) (*sqladmin.Operation, error) {
 // CreateSQLDatabase creates a new database in a Cloud SQL instance.
 // Returns an error if the instance doesn't exist or database already exists.
 func (s *Store) CreateSQLDatabase(instanceName string, req *sqladmin.DatabaseInsertRequest) (*sqladmin.Database, *sqladmin.Operation, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	s.sqlMu.Lock()
+	defer s.sqlMu.Unlock()
+
+	cfg := s.config()
 
 	instanceDBs, exists := s.sqlDatabases[instanceName]
 	if !exists {
@@ -724,7 +2550,7 @@ func (s *Store) CreateSQLDatabase(instanceName string, req *sqladmin.DatabaseIns
 		return nil, nil, fmt.Errorf("database %s already exists in instance %s", req.Name, instanceName)
 	}
 
-	now := time.Now().UTC()
+	now := s.now()
 
 	// Set defaults
 	charset := req.Charset
@@ -743,37 +2569,38 @@ func (s *Store) CreateSQLDatabase(instanceName string, req *sqladmin.DatabaseIns
 		Charset:   charset,
 		Collation: collation,
 		Instance:  instanceName,
-		Project:   s.projectID,
-		SelfLink:  fmt.Sprintf("%s/sql/v1beta4/projects/%s/instances/%s/databases/%s", s.baseURL, s.projectID, instanceName, req.Name),
+		Project:   cfg.projectID,
+		SelfLink:  fmt.Sprintf("%s/sql/v1beta4/projects/%s/instances/%s/databases/%s", cfg.baseURL, cfg.projectID, instanceName, req.Name),
 		Etag:      generateEtag(),
 	}
 
 	instanceDBs[req.Name] = db
+	s.publish(Event{Type: EventSQLDatabaseCreated, Database: db})
 
 	// Create operation
 	op := s.createOperation("CREATE_DATABASE", instanceName, now)
 
-	return db, op, nil
+	return clone(db), clone(op), nil
 }
 
 // GetSQLDatabase retrieves a database by instance and database name.
 // Returns nil if the database doesn't exist.
 func (s *Store) GetSQLDatabase(instanceName, dbName string) *sqladmin.Database {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	s.sqlMu.RLock()
+	defer s.sqlMu.RUnlock()
 
 	instanceDBs, exists := s.sqlDatabases[instanceName]
 	if !exists {
 		return nil
 	}
 
-	return instanceDBs[dbName]
+	return clone(instanceDBs[dbName])
 }
 
 // ListSQLDatabases returns all databases in a Cloud SQL instance.
 func (s *Store) ListSQLDatabases(instanceName string) ([]*sqladmin.Database, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	s.sqlMu.RLock()
+	defer s.sqlMu.RUnlock()
 
 	instanceDBs, exists := s.sqlDatabases[instanceName]
 	if !exists {
@@ -790,14 +2617,14 @@ func (s *Store) ListSQLDatabases(instanceName string) ([]*sqladmin.Database, err
 		return databases[i].Name < databases[j].Name
 	})
 
-	return databases, nil
+	return clone(databases), nil
 }
 
 // UpdateSQLDatabase updates an existing database in a Cloud SQL instance.
 // Returns an error if the instance or database doesn't exist.
 func (s *Store) UpdateSQLDatabase(instanceName, dbName string, req *sqladmin.DatabasePatchRequest) (*sqladmin.Database, *sqladmin.Operation, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	s.sqlMu.Lock()
+	defer s.sqlMu.Unlock()
 
 	instanceDBs, exists := s.sqlDatabases[instanceName]
 	if !exists {
@@ -809,7 +2636,7 @@ func (s *Store) UpdateSQLDatabase(instanceName, dbName string, req *sqladmin.Dat
 		return nil, nil, fmt.Errorf("database %s not found in instance %s", dbName, instanceName)
 	}
 
-	now := time.Now().UTC()
+	now := s.now()
 
 	if req.Charset != "" {
 		db.Charset = req.Charset
@@ -822,32 +2649,34 @@ func (s *Store) UpdateSQLDatabase(instanceName, dbName string, req *sqladmin.Dat
 	// Create operation
 	op := s.createOperation("UPDATE_DATABASE", instanceName, now)
 
-	return db, op, nil
+	return clone(db), clone(op), nil
 }
 
 // DeleteSQLDatabase deletes a database from a Cloud SQL instance.
 // Returns an error if the instance or database doesn't exist.
 func (s *Store) DeleteSQLDatabase(instanceName, dbName string) (*sqladmin.Operation, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	s.sqlMu.Lock()
+	defer s.sqlMu.Unlock()
 
 	instanceDBs, exists := s.sqlDatabases[instanceName]
 	if !exists {
 		return nil, fmt.Errorf("instance %s not found", instanceName)
 	}
 
-	if _, exists := instanceDBs[dbName]; !exists {
+	db, exists := instanceDBs[dbName]
+	if !exists {
 		return nil, fmt.Errorf("database %s not found in instance %s", dbName, instanceName)
 	}
 
-	now := time.Now().UTC()
+	now := s.now()
 
 	delete(instanceDBs, dbName)
+	s.publish(Event{Type: EventSQLDatabaseDeleted, Database: db})
 
 	// Create operation
 	op := s.createOperation("DELETE_DATABASE", instanceName, now)
 
-	return op, nil
+	return clone(op), nil
 }
 
 // =============================================================================
@@ -865,8 +2694,8 @@ func userKey(name, host string) string {
 // CreateSQLUser creates a new user in a Cloud SQL instance.
 // Returns an error if the instance doesn't exist or user already exists.
 func (s *Store) CreateSQLUser(instanceName string, req *sqladmin.UserInsertRequest) (*sqladmin.User, *sqladmin.Operation, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	s.sqlMu.Lock()
+	defer s.sqlMu.Unlock()
 
 	instanceUsers, exists := s.sqlUsers[instanceName]
 	if !exists {
@@ -883,19 +2712,26 @@ func (s *Store) CreateSQLUser(instanceName string, req *sqladmin.UserInsertReque
 		return nil, nil, fmt.Errorf("user %s already exists in instance %s", key, instanceName)
 	}
 
-	now := time.Now().UTC()
-
 	userType := req.Type
 	if userType == "" {
 		userType = "BUILT_IN"
 	}
+	if !sqlUserTypes[userType] {
+		return nil, nil, fmt.Errorf("invalid user type %s", userType)
+	}
+
+	if err := s.checkSQLUserPassword(instanceName, req.Name, userType, req.Password); err != nil {
+		return nil, nil, err
+	}
+
+	now := s.now()
 
 	user := &sqladmin.User{
 		Kind:     "sql#user",
 		Name:     req.Name,
 		Host:     host,
 		Instance: instanceName,
-		Project:  s.projectID,
+		Project:  s.config().projectID,
 		Type:     userType,
 		Etag:     generateEtag(),
 	}
@@ -905,27 +2741,27 @@ func (s *Store) CreateSQLUser(instanceName string, req *sqladmin.UserInsertReque
 	// Create operation
 	op := s.createOperation("CREATE_USER", instanceName, now)
 
-	return user, op, nil
+	return clone(user), clone(op), nil
 }
 
 // GetSQLUser retrieves a user by instance, user name, and host.
 // Returns nil if the user doesn't exist.
 func (s *Store) GetSQLUser(instanceName, userName, host string) *sqladmin.User {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	s.sqlMu.RLock()
+	defer s.sqlMu.RUnlock()
 
 	instanceUsers, exists := s.sqlUsers[instanceName]
 	if !exists {
 		return nil
 	}
 
-	return instanceUsers[userKey(userName, host)]
+	return clone(instanceUsers[userKey(userName, host)])
 }
 
 // ListSQLUsers returns all users in a Cloud SQL instance.
 func (s *Store) ListSQLUsers(instanceName string) ([]*sqladmin.User, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	s.sqlMu.RLock()
+	defer s.sqlMu.RUnlock()
 
 	instanceUsers, exists := s.sqlUsers[instanceName]
 	if !exists {
@@ -942,14 +2778,14 @@ func (s *Store) ListSQLUsers(instanceName string) ([]*sqladmin.User, error) {
 		return users[i].Name < users[j].Name
 	})
 
-	return users, nil
+	return clone(users), nil
 }
 
 // UpdateSQLUser updates an existing user in a Cloud SQL instance.
 // Returns an error if the instance or user doesn't exist.
 func (s *Store) UpdateSQLUser(instanceName, userName, host string, req *sqladmin.UserUpdateRequest) (*sqladmin.User, *sqladmin.Operation, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	s.sqlMu.Lock()
+	defer s.sqlMu.Unlock()
 
 	instanceUsers, exists := s.sqlUsers[instanceName]
 	if !exists {
@@ -962,7 +2798,13 @@ func (s *Store) UpdateSQLUser(instanceName, userName, host string, req *sqladmin
 		return nil, nil, fmt.Errorf("user %s not found in instance %s", key, instanceName)
 	}
 
-	now := time.Now().UTC()
+	if req.Password != "" {
+		if err := s.checkSQLUserPassword(instanceName, userName, user.Type, req.Password); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	now := s.now()
 
 	// Note: password is not stored in the response
 	if req.Host != "" && req.Host != host {
@@ -976,14 +2818,75 @@ func (s *Store) UpdateSQLUser(instanceName, userName, host string, req *sqladmin
 	// Create operation
 	op := s.createOperation("UPDATE_USER", instanceName, now)
 
-	return user, op, nil
+	return clone(user), clone(op), nil
+}
+
+// sqlUserTypes are the user types that can be created in a Cloud SQL instance.
+var sqlUserTypes = map[string]bool{
+	"BUILT_IN":                  true,
+	"CLOUD_IAM_USER":            true,
+	"CLOUD_IAM_SERVICE_ACCOUNT": true,
+	"CLOUD_IAM_GROUP":           true,
+}
+
+// checkSQLUserPassword validates a user's password against its type and the
+// password validation policy of the instance. IAM users authenticate with
+// tokens and must not have a password. The caller must hold the lock.
+// ReuseInterval and PasswordChangeInterval aren't enforced as no password
+// history is kept.
+func (s *Store) checkSQLUserPassword(instanceName, userName, userType, password string) error {
+	if userType != "BUILT_IN" {
+		if password != "" {
+			return fmt.Errorf("invalid request: users of type %s can't have a password", userType)
+		}
+		return nil
+	}
+
+	instance, exists := s.sqlInstances[instanceName]
+	if !exists || instance.Settings == nil {
+		return nil
+	}
+	policy := instance.Settings.PasswordValidationPolicy
+	if policy == nil || !policy.EnablePasswordPolicy {
+		return nil
+	}
+
+	if int(policy.MinLength) > len(password) {
+		return fmt.Errorf("invalid password: it must be at least %d characters long", policy.MinLength)
+	}
+	if policy.Complexity == "COMPLEXITY_DEFAULT" && !isComplexPassword(password) {
+		return fmt.Errorf("invalid password: it must contain lowercase and uppercase letters, numbers and symbols")
+	}
+	if policy.DisallowUsernameSubstring && strings.Contains(strings.ToLower(password), strings.ToLower(userName)) {
+		return fmt.Errorf("invalid password: it must not contain the user name")
+	}
+	return nil
+}
+
+// isComplexPassword reports whether a password contains lowercase and
+// uppercase letters, numbers and symbols, as required by COMPLEXITY_DEFAULT.
+func isComplexPassword(password string) bool {
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	return lower && upper && digit && symbol
 }
 
 // DeleteSQLUser deletes a user from a Cloud SQL instance.
 // Returns an error if the instance or user doesn't exist.
 func (s *Store) DeleteSQLUser(instanceName, userName, host string) (*sqladmin.Operation, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	s.sqlMu.Lock()
+	defer s.sqlMu.Unlock()
 
 	instanceUsers, exists := s.sqlUsers[instanceName]
 	if !exists {
@@ -995,14 +2898,14 @@ func (s *Store) DeleteSQLUser(instanceName, userName, host string) (*sqladmin.Op
 		return nil, fmt.Errorf("user %s not found in instance %s", key, instanceName)
 	}
 
-	now := time.Now().UTC()
+	now := s.now()
 
 	delete(instanceUsers, key)
 
 	// Create operation
 	op := s.createOperation("DELETE_USER", instanceName, now)
 
-	return op, nil
+	return clone(op), nil
 }
 
 // =============================================================================
@@ -1011,7 +2914,14 @@ func (s *Store) DeleteSQLUser(instanceName, userName, host string) (*sqladmin.Op
 
 // createOperation creates and stores a new operation.
 func (s *Store) createOperation(opType, targetID string, now time.Time) *sqladmin.Operation {
-	opName := fmt.Sprintf("operation-%d", now.UnixNano())
+	cfg := s.config()
+
+	// Operations may be created for the same time, e.g. maintenance of several instances in the same window
+	id := now.UnixNano()
+	for s.sqlOperations[fmt.Sprintf("operation-%d", id)] != nil {
+		id++
+	}
+	opName := fmt.Sprintf("operation-%d", id)
 
 	op := &sqladmin.Operation{
 		Kind:          "sql#operation",
@@ -1021,10 +2931,10 @@ func (s *Store) createOperation(opType, targetID string, now time.Time) *sqladmi
 		InsertTime:    now,
 		StartTime:     now,
 		EndTime:       now,
-		TargetProject: s.projectID,
+		TargetProject: cfg.projectID,
 		TargetId:      targetID,
-		SelfLink:      fmt.Sprintf("%s/sql/v1beta4/projects/%s/operations/%s", s.baseURL, s.projectID, opName),
-		TargetLink:    fmt.Sprintf("%s/sql/v1beta4/projects/%s/instances/%s", s.baseURL, s.projectID, targetID),
+		SelfLink:      fmt.Sprintf("%s/sql/v1beta4/projects/%s/operations/%s", cfg.baseURL, cfg.projectID, opName),
+		TargetLink:    fmt.Sprintf("%s/sql/v1beta4/projects/%s/instances/%s", cfg.baseURL, cfg.projectID, targetID),
 	}
 
 	s.sqlOperations[opName] = op
@@ -1035,16 +2945,19 @@ func (s *Store) createOperation(opType, targetID string, now time.Time) *sqladmi
 // GetSQLOperation retrieves an operation by name.
 // Returns nil if the operation doesn't exist.
 func (s *Store) GetSQLOperation(name string) *sqladmin.Operation {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	s.sqlMu.Lock()
+	defer s.sqlMu.Unlock()
 
-	return s.sqlOperations[name]
+	s.updateSQLState()
+	return clone(s.sqlOperations[name])
 }
 
 // ListSQLOperations returns all operations in the store, optionally filtered by instance.
 func (s *Store) ListSQLOperations(instanceName string) []*sqladmin.Operation {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	s.sqlMu.Lock()
+	defer s.sqlMu.Unlock()
+
+	s.updateSQLState()
 
 	operations := make([]*sqladmin.Operation, 0, len(s.sqlOperations))
 	for _, op := range s.sqlOperations {
@@ -1053,10 +2966,13 @@ func (s *Store) ListSQLOperations(instanceName string) []*sqladmin.Operation {
 		}
 	}
 
-	// Sort by insert time (newest first)
+	// Sort by insert time (newest first), then by name for consistent ordering
 	sort.Slice(operations, func(i, j int) bool {
-		return operations[i].InsertTime.After(operations[j].InsertTime)
+		if !operations[i].InsertTime.Equal(operations[j].InsertTime) {
+			return operations[i].InsertTime.After(operations[j].InsertTime)
+		}
+		return operations[i].Name > operations[j].Name
 	})
 
-	return operations
+	return clone(operations)
 }
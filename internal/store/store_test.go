@@ -1,8 +1,13 @@
 package store
 
 import (
+	"os"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/katharinasick/gcp-api-mock/internal/blob"
 	"github.com/katharinasick/gcp-api-mock/internal/sqladmin"
 	"github.com/katharinasick/gcp-api-mock/internal/storage"
 )
@@ -84,6 +89,44 @@ func TestStore_CreateBucket(t *testing.T) {
 	}
 }
 
+func TestStore_CreateBucket_Location(t *testing.T) {
+	tests := []struct {
+		location             string
+		strict               bool
+		expectedLocation     string
+		expectedLocationType string
+		wantErr              bool
+	}{
+		{"", false, "US", "multi-region", false},
+		{"eu", false, "EU", "multi-region", false},
+		{"NAM4", false, "NAM4", "dual-region", false},
+		{"europe-west1", true, "EUROPE-WEST1", "region", false},
+		{"europe-west-1", false, "EUROPE-WEST-1", "region", false},
+		{"europe-west-1", true, "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.location, func(t *testing.T) {
+			s := New()
+			s.SetStrictValidation(tt.strict)
+
+			bucket, err := s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket", Location: tt.location})
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "invalid location") {
+					t.Errorf("expected invalid location error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateBucket() error = %v", err)
+			}
+			if bucket.Location != tt.expectedLocation || bucket.LocationType != tt.expectedLocationType {
+				t.Errorf("expected %s %s, got %s %s", tt.expectedLocationType, tt.expectedLocation, bucket.LocationType, bucket.Location)
+			}
+		})
+	}
+}
+
 func TestStore_CreateBucket_DuplicateError(t *testing.T) {
 	s := New()
 	req := &storage.BucketInsertRequest{Name: "test-bucket"}
@@ -167,6 +210,78 @@ func TestStore_UpdateBucket(t *testing.T) {
 	}
 }
 
+func TestStore_BucketCors(t *testing.T) {
+	s := New()
+	rules := []storage.Cors{{Origin: []string{"https://app.example.com"}, Method: []string{"PUT"}}}
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket", Cors: rules})
+
+	if cors, exists := s.GetBucketCors("test-bucket"); !exists || len(cors) != 1 {
+		t.Errorf("expected 1 CORS rule, got %v (exists %v)", cors, exists)
+	}
+	if _, exists := s.GetBucketCors("non-existent"); exists {
+		t.Error("expected non-existent bucket to not exist")
+	}
+
+	// Updates without CORS keep the rules, an empty list removes them
+	_, _ = s.UpdateBucket("test-bucket", &storage.BucketUpdateRequest{Labels: map[string]string{"env": "test"}})
+	if cors, _ := s.GetBucketCors("test-bucket"); len(cors) != 1 {
+		t.Errorf("expected CORS rule to be kept, got %v", cors)
+	}
+	_, _ = s.UpdateBucket("test-bucket", &storage.BucketUpdateRequest{Cors: []storage.Cors{}})
+	if cors, _ := s.GetBucketCors("test-bucket"); cors != nil {
+		t.Errorf("expected CORS rules to be removed, got %v", cors)
+	}
+}
+
+func TestStore_IsRequesterPays(t *testing.T) {
+	s := New()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "rp-bucket", Billing: &storage.Billing{RequesterPays: true}})
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "plain-bucket"})
+
+	if !s.IsRequesterPays("rp-bucket") {
+		t.Error("expected rp-bucket to be requester pays")
+	}
+	if s.IsRequesterPays("plain-bucket") || s.IsRequesterPays("non-existent") {
+		t.Error("expected only rp-bucket to be requester pays")
+	}
+
+	_, _ = s.UpdateBucket("rp-bucket", &storage.BucketUpdateRequest{Billing: &storage.Billing{RequesterPays: false}})
+	if s.IsRequesterPays("rp-bucket") {
+		t.Error("expected requester pays to be disabled")
+	}
+}
+
+func TestStore_BucketDefaultKmsKey(t *testing.T) {
+	s := New()
+	const key = "projects/p/locations/us/keyRings/ring/cryptoKeys/key"
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+
+	// Objects created before the bucket has a default key aren't encrypted with one
+	obj, _ := s.CreateObject("test-bucket", "before.txt", "text/plain", []byte("a"), nil)
+	if obj.KmsKeyName != "" {
+		t.Errorf("expected no kmsKeyName, got %s", obj.KmsKeyName)
+	}
+
+	_, _ = s.UpdateBucket("test-bucket", &storage.BucketUpdateRequest{Encryption: &storage.Encryption{DefaultKmsKeyName: key}})
+	obj, _ = s.CreateObject("test-bucket", "after.txt", "text/plain", []byte("a"), nil)
+	if obj.KmsKeyName != key+"/cryptoKeyVersions/1" {
+		t.Errorf("expected the bucket's default key, got %s", obj.KmsKeyName)
+	}
+
+	// An explicit key takes precedence
+	obj, _ = s.CreateObjectWithOptions("test-bucket", "explicit.txt", "text/plain", strings.NewReader("a"), nil,
+		ObjectOptions{KmsKeyName: "projects/p/locations/us/keyRings/ring/cryptoKeys/other"})
+	if obj.KmsKeyName != "projects/p/locations/us/keyRings/ring/cryptoKeys/other/cryptoKeyVersions/1" {
+		t.Errorf("expected the explicit key, got %s", obj.KmsKeyName)
+	}
+
+	// An empty encryption configuration removes the default key
+	bucket, _ := s.UpdateBucket("test-bucket", &storage.BucketUpdateRequest{Encryption: &storage.Encryption{}})
+	if bucket.Encryption != nil {
+		t.Errorf("expected encryption to be removed, got %+v", bucket.Encryption)
+	}
+}
+
 func TestStore_DeleteBucket(t *testing.T) {
 	s := New()
 	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
@@ -230,6 +345,24 @@ func TestStore_CreateObject(t *testing.T) {
 	}
 }
 
+func TestStore_CreateObject_SameContentNewContentType(t *testing.T) {
+	s := New()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+
+	_, _ = s.CreateObject("test-bucket", "data", "text/plain", []byte("{}"), nil)
+	obj, err := s.CreateObject("test-bucket", "data", "application/json", []byte("{}"), nil)
+	if err != nil {
+		t.Fatalf("CreateObject() error: %v", err)
+	}
+
+	if obj.ContentType != "application/json" {
+		t.Errorf("content type = %s, want application/json", obj.ContentType)
+	}
+	if got := s.GetObject("test-bucket", "data").ContentType; got != "application/json" {
+		t.Errorf("stored content type = %s, want application/json", got)
+	}
+}
+
 func TestStore_CreateObject_BucketNotFound(t *testing.T) {
 	s := New()
 
@@ -239,6 +372,42 @@ func TestStore_CreateObject_BucketNotFound(t *testing.T) {
 	}
 }
 
+func TestStore_CreateObject_InvalidName(t *testing.T) {
+	tests := []struct {
+		name       string
+		objectName string
+		strict     bool
+		wantErr    bool
+	}{
+		{"dot dot", "..", false, true},
+		{"invalid UTF-8", "\xff", false, true},
+		{"leading slash", "/a.txt", false, false},
+		{"leading slash strict", "/a.txt", true, true},
+		{"dot dot segment", "a/../b.txt", false, false},
+		{"dot dot segment strict", "a/../b.txt", true, true},
+		{"folder placeholder strict", "a/b/", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+			s.SetStrictObjectPaths(tt.strict)
+			_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+
+			_, err := s.CreateObject("test-bucket", tt.objectName, "text/plain", []byte("data"), nil)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "invalid object name") {
+					t.Errorf("expected invalid object name error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("CreateObject() error = %v", err)
+			}
+		})
+	}
+}
+
 func TestStore_GetObject(t *testing.T) {
 	s := New()
 	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
@@ -277,6 +446,44 @@ func TestStore_GetObjectContent(t *testing.T) {
 	}
 }
 
+func TestStore_CreateObjectFromReader_DiskBackend(t *testing.T) {
+	dir := t.TempDir()
+	backend, err := blob.NewDiskBackend(dir)
+	if err != nil {
+		t.Fatalf("NewDiskBackend() error: %v", err)
+	}
+
+	s := New()
+	s.SetBlobBackend(backend)
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+
+	obj, err := s.CreateObjectFromReader("test-bucket", "test.txt", "text/plain", strings.NewReader("Hello, World!"), nil)
+	if err != nil {
+		t.Fatalf("CreateObjectFromReader() error: %v", err)
+	}
+
+	if obj.Size != 13 {
+		t.Errorf("size = %d, want 13", obj.Size)
+	}
+	if obj.Md5Hash != computeMD5Hash([]byte("Hello, World!")) {
+		t.Errorf("md5Hash = %s, want %s", obj.Md5Hash, computeMD5Hash([]byte("Hello, World!")))
+	}
+	if obj.Crc32c != computeCRC32C([]byte("Hello, World!")) {
+		t.Errorf("crc32c = %s, want %s", obj.Crc32c, computeCRC32C([]byte("Hello, World!")))
+	}
+
+	if string(s.GetObjectContent("test-bucket", "test.txt")) != "Hello, World!" {
+		t.Error("content mismatch")
+	}
+
+	// Deleting the object removes its blob file
+	_ = s.DeleteObject("test-bucket", "test.txt")
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Errorf("expected blob directory to be empty after delete, got %d entries", len(entries))
+	}
+}
+
 func TestStore_ListObjects(t *testing.T) {
 	s := New()
 	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
@@ -314,7 +521,7 @@ func TestStore_UpdateObject(t *testing.T) {
 	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
 	_, _ = s.CreateObject("test-bucket", "test-object.txt", "text/plain", []byte("data"), nil)
 
-	updated, err := s.UpdateObject("test-bucket", "test-object.txt", map[string]string{"key": "value"})
+	updated, err := s.UpdateObject("test-bucket", "test-object.txt", &storage.ObjectUpdateRequest{Metadata: map[string]string{"key": "value"}})
 	if err != nil {
 		t.Fatalf("UpdateObject() error: %v", err)
 	}
@@ -324,7 +531,7 @@ func TestStore_UpdateObject(t *testing.T) {
 	}
 
 	// Update non-existent object
-	_, err = s.UpdateObject("test-bucket", "non-existent", nil)
+	_, err = s.UpdateObject("test-bucket", "non-existent", &storage.ObjectUpdateRequest{})
 	if err == nil {
 		t.Error("expected error for non-existent object")
 	}
@@ -351,6 +558,474 @@ func TestStore_DeleteObject(t *testing.T) {
 	}
 }
 
+func TestStore_DeleteObjects(t *testing.T) {
+	s := New()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{
+		Name:             "test-bucket",
+		SoftDeletePolicy: &storage.SoftDeletePolicy{RetentionDurationSeconds: 3600},
+	})
+	for _, name := range []string{"suite-a/1.txt", "suite-a/2.txt", "suite-ab.txt", "suite-b/1.txt"} {
+		_, _ = s.CreateObject("test-bucket", name, "text/plain", []byte("data"), nil)
+	}
+
+	deleted, err := s.DeleteObjects("test-bucket", "suite-a/")
+	if err != nil {
+		t.Fatalf("DeleteObjects() error: %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("expected 2 deleted objects, got %d", deleted)
+	}
+	objects, _ := s.ListObjects("test-bucket", "", "")
+	if len(objects) != 2 || objects[0].Name != "suite-ab.txt" || objects[1].Name != "suite-b/1.txt" {
+		t.Errorf("expected the objects outside the prefix to be kept, got %+v", objects)
+	}
+	if softDeleted := s.ListSoftDeletedObjects("test-bucket", "suite-a/"); len(softDeleted) != 2 {
+		t.Errorf("expected the deleted objects to be soft-deleted, got %d", len(softDeleted))
+	}
+
+	// Without a prefix all objects are deleted
+	if deleted, _ := s.DeleteObjects("test-bucket", ""); deleted != 2 {
+		t.Errorf("expected 2 deleted objects, got %d", deleted)
+	}
+
+	if _, err := s.DeleteObjects("non-existent", ""); err == nil {
+		t.Error("expected error for non-existent bucket")
+	}
+}
+
+func TestStore_DeleteObject_SoftDelete(t *testing.T) {
+	s := New()
+	now := time.Now()
+	s.SetClock(func() time.Time { return now })
+
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{
+		Name:             "test-bucket",
+		SoftDeletePolicy: &storage.SoftDeletePolicy{RetentionDurationSeconds: 3600},
+	})
+	obj, _ := s.CreateObject("test-bucket", "test-object.txt", "text/plain", []byte("data"), nil)
+	generation := obj.Generation
+
+	if err := s.DeleteObject("test-bucket", "test-object.txt"); err != nil {
+		t.Fatalf("DeleteObject() error: %v", err)
+	}
+
+	if s.GetObject("test-bucket", "test-object.txt") != nil {
+		t.Error("soft-deleted object should not be returned as live object")
+	}
+
+	softDeleted := s.ListSoftDeletedObjects("test-bucket", "")
+	if len(softDeleted) != 1 {
+		t.Fatalf("expected 1 soft-deleted object, got %d", len(softDeleted))
+	}
+	if softDeleted[0].SoftDeleteTime == nil || softDeleted[0].HardDeleteTime == nil {
+		t.Error("soft-deleted object should have softDeleteTime and hardDeleteTime set")
+	}
+
+	if s.GetSoftDeletedObject("test-bucket", "test-object.txt", generation) == nil {
+		t.Error("GetSoftDeletedObject() returned nil for soft-deleted object")
+	}
+
+	// Soft-deleted objects don't prevent deleting the bucket
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "other-bucket"})
+	_, _ = s.CreateObject("other-bucket", "file.txt", "text/plain", []byte("data"), nil)
+	_ = s.DeleteObject("other-bucket", "file.txt")
+	if len(s.ListSoftDeletedObjects("other-bucket", "")) != 0 {
+		t.Error("objects in buckets without soft delete policy should be deleted permanently")
+	}
+
+	// Fast-forward past the retention duration
+	now = now.Add(2 * time.Hour)
+
+	if len(s.ListSoftDeletedObjects("test-bucket", "")) != 0 {
+		t.Error("soft-deleted object should be purged after the retention duration")
+	}
+	if s.GetSoftDeletedObject("test-bucket", "test-object.txt", generation) != nil {
+		t.Error("GetSoftDeletedObject() returned expired object")
+	}
+}
+
+func TestStore_CreateObject_OverwriteSoftDelete(t *testing.T) {
+	s := New()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{
+		Name:             "test-bucket",
+		SoftDeletePolicy: &storage.SoftDeletePolicy{RetentionDurationSeconds: 3600},
+	})
+	first, _ := s.CreateObject("test-bucket", "test-object.txt", "text/plain", []byte("first"), nil)
+	if _, err := s.CreateObject("test-bucket", "test-object.txt", "text/plain", []byte("second"), nil); err != nil {
+		t.Fatalf("CreateObject() error: %v", err)
+	}
+
+	// The overwritten generation is soft-deleted like a deleted object
+	softDeleted := s.GetSoftDeletedObject("test-bucket", "test-object.txt", first.Generation)
+	if softDeleted == nil || softDeleted.SoftDeleteTime == nil {
+		t.Fatalf("expected the overwritten generation to be soft-deleted, got %+v", softDeleted)
+	}
+
+	if err := s.DeleteObject("test-bucket", "test-object.txt"); err != nil {
+		t.Fatalf("DeleteObject() error: %v", err)
+	}
+	if _, err := s.RestoreObject("test-bucket", "test-object.txt", first.Generation); err != nil {
+		t.Fatalf("RestoreObject() error: %v", err)
+	}
+	if content := string(s.GetObjectContent("test-bucket", "test-object.txt")); content != "first" {
+		t.Errorf("expected the content of the restored generation, got %q", content)
+	}
+
+	// Without a soft delete policy, overwritten generations are gone
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "other-bucket"})
+	_, _ = s.CreateObject("other-bucket", "file.txt", "text/plain", []byte("first"), nil)
+	_, _ = s.CreateObject("other-bucket", "file.txt", "text/plain", []byte("second"), nil)
+	if len(s.ListSoftDeletedObjects("other-bucket", "")) != 0 {
+		t.Error("expected overwritten generations in buckets without soft delete policy to be deleted permanently")
+	}
+}
+
+func TestStore_RestoreObject(t *testing.T) {
+	s := New()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{
+		Name:             "test-bucket",
+		SoftDeletePolicy: &storage.SoftDeletePolicy{RetentionDurationSeconds: 3600},
+	})
+	obj, _ := s.CreateObject("test-bucket", "test-object.txt", "text/plain", []byte("data"), nil)
+	generation := obj.Generation
+	_ = s.DeleteObject("test-bucket", "test-object.txt")
+
+	restored, err := s.RestoreObject("test-bucket", "test-object.txt", generation)
+	if err != nil {
+		t.Fatalf("RestoreObject() error: %v", err)
+	}
+
+	if restored.SoftDeleteTime != nil || restored.HardDeleteTime != nil {
+		t.Error("restored object should not have soft delete times")
+	}
+
+	if string(s.GetObjectContent("test-bucket", "test-object.txt")) != "data" {
+		t.Error("restored object content mismatch")
+	}
+
+	if len(s.ListSoftDeletedObjects("test-bucket", "")) != 0 {
+		t.Error("restored object should no longer be soft-deleted")
+	}
+
+	// Restoring again fails
+	_, err = s.RestoreObject("test-bucket", "test-object.txt", generation)
+	if err == nil {
+		t.Error("expected error when restoring non-existent soft-deleted object")
+	}
+}
+
+func TestStore_RestoreObject_FrozenClock(t *testing.T) {
+	s := New()
+	now := time.Date(2024, time.May, 17, 12, 0, 0, 0, time.UTC)
+	s.SetClock(func() time.Time { return now })
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{
+		Name:             "test-bucket",
+		SoftDeletePolicy: &storage.SoftDeletePolicy{RetentionDurationSeconds: 3600},
+	})
+	first, _ := s.CreateObject("test-bucket", "test-object.txt", "text/plain", []byte("first"), nil)
+	second, _ := s.CreateObject("test-bucket", "test-object.txt", "text/plain", []byte("second"), nil)
+	_ = s.DeleteObject("test-bucket", "test-object.txt")
+
+	// With the clock standing still, the restored generation must still be newer than every earlier one
+	restored, err := s.RestoreObject("test-bucket", "test-object.txt", first.Generation)
+	if err != nil {
+		t.Fatalf("RestoreObject() error: %v", err)
+	}
+	if restored.Generation <= second.Generation {
+		t.Errorf("restored generation = %d, want more than %d", restored.Generation, second.Generation)
+	}
+}
+
+func TestStore_RestoreObject_LiveObjectExists(t *testing.T) {
+	s := New()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{
+		Name:             "test-bucket",
+		SoftDeletePolicy: &storage.SoftDeletePolicy{RetentionDurationSeconds: 3600},
+	})
+	obj, _ := s.CreateObject("test-bucket", "test-object.txt", "text/plain", []byte("old"), nil)
+	_ = s.DeleteObject("test-bucket", "test-object.txt")
+	_, _ = s.CreateObject("test-bucket", "test-object.txt", "text/plain", []byte("new"), nil)
+
+	_, err := s.RestoreObject("test-bucket", "test-object.txt", obj.Generation)
+	if err == nil {
+		t.Error("expected error when a live object with the same name exists")
+	}
+}
+
+func TestPreconditions_Check(t *testing.T) {
+	n := func(v int64) *int64 { return &v }
+	obj := &storage.Object{Generation: 5, Metageneration: 2}
+
+	tests := []struct {
+		name    string
+		pre     Preconditions
+		obj     *storage.Object
+		wantErr bool
+	}{
+		{"no preconditions", Preconditions{}, obj, false},
+		{"generation match", Preconditions{IfGenerationMatch: n(5)}, obj, false},
+		{"generation mismatch", Preconditions{IfGenerationMatch: n(4)}, obj, true},
+		{"does not exist", Preconditions{IfGenerationMatch: n(0)}, nil, false},
+		{"does not exist but object exists", Preconditions{IfGenerationMatch: n(0)}, obj, true},
+		{"generation not match", Preconditions{IfGenerationNotMatch: n(4)}, obj, false},
+		{"generation not match fails", Preconditions{IfGenerationNotMatch: n(5)}, obj, true},
+		{"metageneration match", Preconditions{IfMetagenerationMatch: n(2)}, obj, false},
+		{"metageneration mismatch", Preconditions{IfMetagenerationMatch: n(1)}, obj, true},
+		{"metageneration match without object", Preconditions{IfMetagenerationMatch: n(0)}, nil, true},
+		{"metageneration not match fails", Preconditions{IfMetagenerationNotMatch: n(2)}, obj, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.pre.Check(tt.obj)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "precondition failed") {
+				t.Errorf("expected precondition failed error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestStore_PatchObject(t *testing.T) {
+	s := New()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+	created, _ := s.CreateObject("test-bucket", "file.txt", "text/plain", []byte("hello"), map[string]string{"a": "1", "b": "2"})
+
+	language := "en"
+	c := "3"
+	patched, err := s.PatchObject("test-bucket", "file.txt", &storage.ObjectPatchRequest{
+		ContentLanguage: &language,
+		Metadata:        map[string]*string{"b": nil, "c": &c},
+		NullFields:      []string{"contentType"},
+	}, Preconditions{})
+	if err != nil {
+		t.Fatalf("PatchObject() error = %v", err)
+	}
+
+	if patched.ContentLanguage != "en" || patched.ContentType != "application/octet-stream" {
+		t.Errorf("expected patched content fields, got %+v", patched)
+	}
+	if !reflect.DeepEqual(patched.Metadata, map[string]string{"a": "1", "c": "3"}) {
+		t.Errorf("expected merged metadata, got %v", patched.Metadata)
+	}
+	if patched.Metageneration != created.Metageneration+1 {
+		t.Errorf("expected metageneration %d, got %d", created.Metageneration+1, patched.Metageneration)
+	}
+
+	stale := int64(1)
+	if _, err := s.PatchObject("test-bucket", "file.txt", &storage.ObjectPatchRequest{}, Preconditions{IfMetagenerationMatch: &stale}); err == nil {
+		t.Error("expected patch with stale metageneration to fail")
+	}
+}
+
+func TestStore_PatchBucket(t *testing.T) {
+	s := New()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+	_, _ = s.UpdateBucket("test-bucket", &storage.BucketUpdateRequest{
+		Labels:           map[string]string{"env": "test"},
+		SoftDeletePolicy: &storage.SoftDeletePolicy{RetentionDurationSeconds: 604800},
+	})
+
+	owner := "team-a"
+	bucket, err := s.PatchBucket("test-bucket", &storage.BucketPatchRequest{
+		BucketUpdateRequest: storage.BucketUpdateRequest{StorageClass: "NEARLINE"},
+		Labels:              map[string]*string{"owner": &owner},
+		NullFields:          []string{"softDeletePolicy"},
+	})
+	if err != nil {
+		t.Fatalf("PatchBucket() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(bucket.Labels, map[string]string{"env": "test", "owner": "team-a"}) {
+		t.Errorf("expected merged labels, got %v", bucket.Labels)
+	}
+	if bucket.StorageClass != "NEARLINE" || bucket.SoftDeletePolicy != nil {
+		t.Errorf("expected patched bucket, got %+v", bucket)
+	}
+
+	if _, err := s.PatchBucket("non-existent", &storage.BucketPatchRequest{}); err == nil {
+		t.Error("expected error for non-existent bucket")
+	}
+}
+
+func TestStore_CreateObjectWithOptions_Checksums(t *testing.T) {
+	s := New()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+
+	// Checksums of "hello"
+	opts := ObjectOptions{Md5Hash: "XUFAKrxLKna5cZ2REBfFkg==", Crc32c: "mnG7TA=="}
+	if _, err := s.CreateObjectWithOptions("test-bucket", "ok.txt", "", strings.NewReader("hello"), nil, opts); err != nil {
+		t.Fatalf("CreateObjectWithOptions() error = %v", err)
+	}
+
+	opts.Crc32c = "AAAAAA=="
+	_, err := s.CreateObjectWithOptions("test-bucket", "corrupt.txt", "", strings.NewReader("hello"), nil, opts)
+	if err == nil || !strings.Contains(err.Error(), "invalid checksum") {
+		t.Errorf("expected invalid checksum error, got %v", err)
+	}
+	if s.GetObject("test-bucket", "corrupt.txt") != nil {
+		t.Error("expected object with wrong checksum not to be created")
+	}
+}
+
+func TestStore_CreateObjectWithOptions_MaxObjectSize(t *testing.T) {
+	s := New()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+	s.SetMaxObjectSize(5)
+
+	if _, err := s.CreateObjectWithOptions("test-bucket", "ok.txt", "", strings.NewReader("hello"), nil, ObjectOptions{}); err != nil {
+		t.Fatalf("CreateObjectWithOptions() error = %v", err)
+	}
+
+	_, err := s.CreateObjectWithOptions("test-bucket", "big.txt", "", strings.NewReader("hello!"), nil, ObjectOptions{})
+	if err == nil || !strings.Contains(err.Error(), "object too large") {
+		t.Errorf("expected object too large error, got %v", err)
+	}
+	if s.GetObject("test-bucket", "big.txt") != nil {
+		t.Error("expected oversized object not to be created")
+	}
+
+	// 0 removes the limit
+	s.SetMaxObjectSize(0)
+	if _, err := s.CreateObjectWithOptions("test-bucket", "big.txt", "", strings.NewReader("hello!"), nil, ObjectOptions{}); err != nil {
+		t.Errorf("CreateObjectWithOptions() without limit error = %v", err)
+	}
+}
+
+func TestStore_CreateObjectWithOptions_ContentHeaders(t *testing.T) {
+	s := New()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+
+	customTime := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	opts := ObjectOptions{CacheControl: "no-store", ContentLanguage: "en", CustomTime: &customTime}
+	first, err := s.CreateObjectWithOptions("test-bucket", "file.txt", "text/plain", strings.NewReader("hello"), nil, opts)
+	if err != nil {
+		t.Fatalf("CreateObjectWithOptions() error = %v", err)
+	}
+	if first.CacheControl != "no-store" || first.ContentLanguage != "en" || !first.CustomTime.Equal(customTime) {
+		t.Errorf("expected content headers on object, got %+v", first)
+	}
+
+	// Identical content with different attributes creates a new generation
+	opts.CacheControl = "public, max-age=60"
+	second, err := s.CreateObjectWithOptions("test-bucket", "file.txt", "text/plain", strings.NewReader("hello"), nil, opts)
+	if err != nil {
+		t.Fatalf("CreateObjectWithOptions() error = %v", err)
+	}
+	if second.Generation == first.Generation || second.CacheControl != "public, max-age=60" {
+		t.Errorf("expected new generation with updated Cache-Control, got %+v", second)
+	}
+}
+
+func TestStore_ObjectPreconditions(t *testing.T) {
+	s := New()
+	now := time.Now()
+	s.SetClock(func() time.Time { return now })
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+
+	doesNotExist := int64(0)
+	create := func(content string) (*storage.Object, error) {
+		return s.CreateObjectWithOptions("test-bucket", "file.tflock", "text/plain", strings.NewReader(content), nil,
+			ObjectOptions{Preconditions: Preconditions{IfGenerationMatch: &doesNotExist}})
+	}
+
+	first, err := create("lock")
+	if err != nil {
+		t.Fatalf("CreateObjectWithOptions() error: %v", err)
+	}
+	// Identical content doesn't bypass the precondition
+	if _, err := create("lock"); err == nil || !strings.Contains(err.Error(), "precondition failed") {
+		t.Errorf("expected precondition failed error, got %v", err)
+	}
+
+	// Generations increase even while the clock stands still
+	second, _ := s.CreateObject("test-bucket", "file.tflock", "text/plain", []byte("other"), nil)
+	if second.Generation <= first.Generation {
+		t.Errorf("expected generation > %d, got %d", first.Generation, second.Generation)
+	}
+
+	stale := Preconditions{IfGenerationMatch: &first.Generation}
+	if _, err := s.UpdateObjectWithPreconditions("test-bucket", "file.tflock", &storage.ObjectUpdateRequest{}, stale); err == nil {
+		t.Error("expected update with stale generation to fail")
+	}
+	if err := s.DeleteObjectWithPreconditions("test-bucket", "file.tflock", stale); err == nil {
+		t.Error("expected delete with stale generation to fail")
+	}
+	if s.GetObject("test-bucket", "file.tflock") == nil {
+		t.Fatal("expected object to survive failed preconditions")
+	}
+
+	current := Preconditions{IfGenerationMatch: &second.Generation}
+	if err := s.DeleteObjectWithPreconditions("test-bucket", "file.tflock", current); err != nil {
+		t.Errorf("DeleteObjectWithPreconditions() error: %v", err)
+	}
+}
+
+func TestStore_NotificationCRUD(t *testing.T) {
+	s := New()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+
+	notification, err := s.CreateNotification("test-bucket", &storage.NotificationInsertRequest{Topic: "projects/p/topics/t"})
+	if err != nil {
+		t.Fatalf("CreateNotification() error: %v", err)
+	}
+	if notification.PayloadFormat != storage.PayloadFormatJSONAPIV1 {
+		t.Errorf("payload format = %s, want %s", notification.PayloadFormat, storage.PayloadFormatJSONAPIV1)
+	}
+
+	if s.GetNotification("test-bucket", notification.ID) == nil {
+		t.Error("GetNotification() returned nil for existing notification")
+	}
+
+	notifications, err := s.ListNotifications("test-bucket")
+	if err != nil || len(notifications) != 1 {
+		t.Errorf("ListNotifications() = %v, %v; want 1 notification", notifications, err)
+	}
+
+	if err := s.DeleteNotification("test-bucket", notification.ID); err != nil {
+		t.Fatalf("DeleteNotification() error: %v", err)
+	}
+	if s.GetNotification("test-bucket", notification.ID) != nil {
+		t.Error("notification still exists after delete")
+	}
+
+	if _, err := s.CreateNotification("non-existent", &storage.NotificationInsertRequest{Topic: "t"}); err == nil {
+		t.Error("expected error for non-existent bucket")
+	}
+}
+
+func TestStore_NotificationHandler(t *testing.T) {
+	s := New()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+	_, _ = s.CreateNotification("test-bucket", &storage.NotificationInsertRequest{
+		Topic:            "projects/p/topics/t",
+		ObjectNamePrefix: "logs/",
+		EventTypes:       []string{storage.EventObjectFinalize, storage.EventObjectDelete},
+	})
+
+	var events []string
+	s.SetNotificationHandler(func(notification *storage.Notification, eventType string, obj storage.Object) {
+		events = append(events, eventType+":"+obj.Name)
+	})
+
+	_, _ = s.CreateObject("test-bucket", "logs/a.txt", "text/plain", []byte("a"), nil)
+	_, _ = s.CreateObject("test-bucket", "other.txt", "text/plain", []byte("b"), nil)
+	_, _ = s.UpdateObject("test-bucket", "logs/a.txt", &storage.ObjectUpdateRequest{Metadata: map[string]string{"k": "v"}})
+	_ = s.DeleteObject("test-bucket", "logs/a.txt")
+
+	want := []string{"OBJECT_FINALIZE:logs/a.txt", "OBJECT_DELETE:logs/a.txt"}
+	if len(events) != len(want) {
+		t.Fatalf("events = %v, want %v", events, want)
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Errorf("event %d = %s, want %s", i, events[i], want[i])
+		}
+	}
+}
+
 func TestComputeMD5Hash(t *testing.T) {
 	data := []byte("Hello, World!")
 	hash := computeMD5Hash(data)
@@ -445,6 +1120,126 @@ func TestStore_CreateSQLInstance_DuplicateError(t *testing.T) {
 	}
 }
 
+func TestStore_CreateSQLInstance_CreateDelay(t *testing.T) {
+	s := New()
+	now := time.Now()
+	s.SetClock(func() time.Time { return now })
+	s.SetSQLCreateDelay(3 * time.Minute)
+
+	instance, op, err := s.CreateSQLInstance(&sqladmin.InstanceInsertRequest{Name: "slow-instance"})
+	if err != nil {
+		t.Fatalf("CreateSQLInstance() error: %v", err)
+	}
+	if instance.State != "PENDING_CREATE" {
+		t.Errorf("expected state PENDING_CREATE, got %s", instance.State)
+	}
+	if op.Status != "RUNNING" {
+		t.Errorf("expected operation status RUNNING, got %s", op.Status)
+	}
+
+	// Still pending before the delay has passed
+	now = now.Add(2 * time.Minute)
+	if got := s.GetSQLInstance("slow-instance"); got.State != "PENDING_CREATE" {
+		t.Errorf("expected state PENDING_CREATE after 2m, got %s", got.State)
+	}
+
+	// Runnable and done once it has passed
+	now = now.Add(time.Minute)
+	if got := s.GetSQLOperation(op.Name); got.Status != "DONE" || got.EndTime.IsZero() {
+		t.Errorf("expected operation DONE with end time after 3m, got %s at %v", got.Status, got.EndTime)
+	}
+	if got := s.ListSQLInstances()[0]; got.State != "RUNNABLE" {
+		t.Errorf("expected state RUNNABLE after 3m, got %s", got.State)
+	}
+}
+
+func TestStore_CreateSQLInstance_ActivationPolicyNever(t *testing.T) {
+	s := New()
+	now := time.Now()
+	s.SetClock(func() time.Time { return now })
+	s.SetSQLCreateDelay(time.Minute)
+
+	instance, _, _ := s.CreateSQLInstance(&sqladmin.InstanceInsertRequest{
+		Name:     "stopped-instance",
+		Settings: &sqladmin.Settings{ActivationPolicy: "NEVER"},
+	})
+	if instance.State != "PENDING_CREATE" {
+		t.Errorf("expected state PENDING_CREATE, got %s", instance.State)
+	}
+
+	// Instances that must never run are stopped once created
+	now = now.Add(time.Minute)
+	if got := s.GetSQLInstance("stopped-instance").State; got != "STOPPED" {
+		t.Errorf("expected state STOPPED, got %s", got)
+	}
+	if _, err := s.RestartSQLInstance("stopped-instance"); err == nil {
+		t.Error("expected restarting a stopped instance to fail")
+	}
+}
+
+func TestStore_SQLReplicas(t *testing.T) {
+	s := New()
+	_, _, _ = s.CreateSQLInstance(&sqladmin.InstanceInsertRequest{
+		Name:            "primary",
+		Region:          "europe-west1",
+		DatabaseVersion: "POSTGRES_15",
+	})
+
+	if _, _, err := s.CreateSQLInstance(&sqladmin.InstanceInsertRequest{Name: "orphan", MasterInstanceName: "missing"}); err == nil {
+		t.Error("expected creating a replica of a missing primary to fail")
+	}
+	if _, _, err := s.CreateSQLInstance(&sqladmin.InstanceInsertRequest{Name: "mismatch", MasterInstanceName: "primary", DatabaseVersion: "MYSQL_8_0"}); err == nil {
+		t.Error("expected creating a replica with another database version to fail")
+	}
+
+	replica, _, err := s.CreateSQLInstance(&sqladmin.InstanceInsertRequest{Name: "replica-1", MasterInstanceName: "primary"})
+	if err != nil {
+		t.Fatalf("CreateSQLInstance() error = %v", err)
+	}
+	if replica.Region != "europe-west1" || replica.DatabaseVersion != "POSTGRES_15" {
+		t.Errorf("expected region and version of the primary, got %s and %s", replica.Region, replica.DatabaseVersion)
+	}
+	if replica.InstanceType != "READ_REPLICA_INSTANCE" {
+		t.Errorf("expected instance type READ_REPLICA_INSTANCE, got %s", replica.InstanceType)
+	}
+	_, _, _ = s.CreateSQLInstance(&sqladmin.InstanceInsertRequest{Name: "replica-2", MasterInstanceName: "primary", Region: "us-east1"})
+
+	if _, _, err := s.CreateSQLInstance(&sqladmin.InstanceInsertRequest{Name: "cascade", MasterInstanceName: "replica-1"}); err == nil {
+		t.Error("expected creating a replica of a replica to fail")
+	}
+
+	if got := s.GetSQLInstance("primary").ReplicaNames; len(got) != 2 || got[0] != "replica-1" || got[1] != "replica-2" {
+		t.Errorf("expected replica names [replica-1 replica-2], got %v", got)
+	}
+	if _, err := s.DeleteSQLInstance("primary"); err == nil {
+		t.Error("expected deleting a primary with replicas to fail")
+	}
+
+	// Promote one replica and delete the other
+	if _, err := s.PromoteSQLReplica("primary"); err == nil {
+		t.Error("expected promoting a primary to fail")
+	}
+	op, err := s.PromoteSQLReplica("replica-1")
+	if err != nil {
+		t.Fatalf("PromoteSQLReplica() error = %v", err)
+	}
+	if op.OperationType != "PROMOTE_REPLICA" {
+		t.Errorf("expected operation type PROMOTE_REPLICA, got %s", op.OperationType)
+	}
+	if got := s.GetSQLInstance("replica-1"); got.MasterInstanceName != "" || got.InstanceType != "CLOUD_SQL_INSTANCE" {
+		t.Errorf("expected promoted replica to be a primary, got master %q and type %s", got.MasterInstanceName, got.InstanceType)
+	}
+	if _, err := s.DeleteSQLInstance("replica-2"); err != nil {
+		t.Fatalf("DeleteSQLInstance() error = %v", err)
+	}
+	if got := s.GetSQLInstance("primary").ReplicaNames; len(got) != 0 {
+		t.Errorf("expected no replica names, got %v", got)
+	}
+	if _, err := s.DeleteSQLInstance("primary"); err != nil {
+		t.Errorf("DeleteSQLInstance() error = %v", err)
+	}
+}
+
 func TestStore_GetSQLInstance(t *testing.T) {
 	s := New()
 	_, _, _ = s.CreateSQLInstance(&sqladmin.InstanceInsertRequest{Name: "test-instance"})
@@ -749,6 +1544,57 @@ func TestStore_CreateSQLUser_Duplicate(t *testing.T) {
 	}
 }
 
+func TestStore_CreateSQLUser_PasswordPolicy(t *testing.T) {
+	s := New()
+	_, _, _ = s.CreateSQLInstance(&sqladmin.InstanceInsertRequest{
+		Name: "test-instance",
+		Settings: &sqladmin.Settings{
+			PasswordValidationPolicy: &sqladmin.PasswordValidationPolicy{
+				EnablePasswordPolicy:      true,
+				MinLength:                 8,
+				Complexity:                "COMPLEXITY_DEFAULT",
+				DisallowUsernameSubstring: true,
+			},
+		},
+	})
+
+	tests := []struct {
+		name     string
+		req      sqladmin.UserInsertRequest
+		wantErr  bool
+		wantType string
+	}{
+		{"strong password", sqladmin.UserInsertRequest{Name: "app", Password: "S3cure-pass"}, false, "BUILT_IN"},
+		{"too short", sqladmin.UserInsertRequest{Name: "short", Password: "S3c-ure"}, true, ""},
+		{"not complex", sqladmin.UserInsertRequest{Name: "simple", Password: "simplepassword"}, true, ""},
+		{"contains user name", sqladmin.UserInsertRequest{Name: "admin", Password: "Admin-1234"}, true, ""},
+		{"iam user", sqladmin.UserInsertRequest{Name: "dev@example.com", Type: "CLOUD_IAM_USER"}, false, "CLOUD_IAM_USER"},
+		{"iam service account", sqladmin.UserInsertRequest{Name: "ci@test-project.iam", Type: "CLOUD_IAM_SERVICE_ACCOUNT"}, false, "CLOUD_IAM_SERVICE_ACCOUNT"},
+		{"iam user with password", sqladmin.UserInsertRequest{Name: "ops@example.com", Type: "CLOUD_IAM_USER", Password: "S3cure-pass"}, true, ""},
+		{"unknown type", sqladmin.UserInsertRequest{Name: "robot", Type: "ROBOT"}, true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, _, err := s.CreateSQLUser("test-instance", &tt.req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CreateSQLUser() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && user.Type != tt.wantType {
+				t.Errorf("expected type %s, got %s", tt.wantType, user.Type)
+			}
+		})
+	}
+
+	// Password changes are validated as well
+	if _, _, err := s.UpdateSQLUser("test-instance", "app", "%", &sqladmin.UserUpdateRequest{Password: "weak"}); err == nil {
+		t.Error("expected updating to a weak password to fail")
+	}
+	if _, _, err := s.UpdateSQLUser("test-instance", "dev@example.com", "%", &sqladmin.UserUpdateRequest{Password: "S3cure-pass"}); err == nil {
+		t.Error("expected setting a password for an IAM user to fail")
+	}
+}
+
 func TestStore_GetSQLUser(t *testing.T) {
 	s := New()
 	_, _, _ = s.CreateSQLInstance(&sqladmin.InstanceInsertRequest{Name: "test-instance"})
@@ -0,0 +1,161 @@
+package store
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/katharinasick/gcp-api-mock/internal/blob"
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+)
+
+// =============================================================================
+// Cloud Storage Resumable Upload Operations
+// =============================================================================
+
+// objectUpload is an in-progress resumable object upload.
+type objectUpload struct {
+	// bucket is the bucket the object is uploaded to
+	bucket string
+	// name is the name of the object
+	name string
+	// req holds the object resource sent when the upload was started
+	req *storage.ObjectInsertRequest
+	// preconditions are checked against the live object when the upload completes
+	preconditions Preconditions
+	// content is the data uploaded so far
+	content blob.Blob
+}
+
+// StartObjectUpload starts a resumable upload of an object and returns the upload ID.
+// The preconditions are checked when the upload completes, like in the real API.
+// Returns an error if the bucket doesn't exist.
+func (s *Store) StartObjectUpload(bucketName, objectName string, req *storage.ObjectInsertRequest, pre Preconditions) (string, error) {
+	cfg := s.config()
+
+	s.storageMu.RLock()
+	bucket, exists := s.buckets[bucketName]
+	var aclErr error
+	if exists {
+		_, aclErr = s.newObjectACL(bucket, InsertOptions(req, pre))
+	}
+	s.storageMu.RUnlock()
+
+	if !exists {
+		return "", fmt.Errorf("bucket %s not found", bucketName)
+	}
+	if err := cfg.validateObjectName(objectName); err != nil {
+		return "", err
+	}
+	// An invalid ACL is rejected up front rather than once all content is uploaded
+	if aclErr != nil {
+		return "", aclErr
+	}
+
+	content, err := cfg.blobs.Write(bytes.NewReader(nil))
+	if err != nil {
+		return "", fmt.Errorf("failed to start upload: %w", err)
+	}
+
+	s.storageMu.Lock()
+	defer s.storageMu.Unlock()
+
+	id := newUUID()
+	s.objectUploads[id] = &objectUpload{bucket: bucketName, name: objectName, req: req, preconditions: pre, content: content}
+
+	return id, nil
+}
+
+// GetObjectUploadSize returns the number of bytes uploaded so far.
+func (s *Store) GetObjectUploadSize(bucketName, id string) (int64, error) {
+	s.storageMu.RLock()
+	defer s.storageMu.RUnlock()
+
+	upload, exists := s.objectUploads[id]
+	if !exists || upload.bucket != bucketName {
+		return 0, fmt.Errorf("upload %s not found", id)
+	}
+
+	return upload.content.Size(), nil
+}
+
+// AppendObjectUpload appends a chunk to a resumable upload and returns the new upload size.
+// Returns an "object too large" error if the upload grows beyond the maximum object size.
+func (s *Store) AppendObjectUpload(bucketName, id string, r io.Reader) (int64, error) {
+	cfg := s.config()
+
+	s.storageMu.RLock()
+	upload, exists := s.objectUploads[id]
+	backend := cfg.blobs
+	s.storageMu.RUnlock()
+
+	if !exists || upload.bucket != bucketName {
+		return 0, fmt.Errorf("upload %s not found", id)
+	}
+
+	// Write the combined content outside the lock, so large chunks don't block other requests
+	existing, err := upload.content.Open()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read upload: %w", err)
+	}
+	content, err := backend.Write(limitObjectSize(io.MultiReader(existing, r), cfg.maxObjectSize))
+	existing.Close()
+	if err != nil {
+		return 0, fmt.Errorf("failed to write upload: %w", err)
+	}
+
+	s.storageMu.Lock()
+	defer s.storageMu.Unlock()
+
+	if s.objectUploads[id] != upload {
+		content.Release()
+		return 0, fmt.Errorf("upload %s not found", id)
+	}
+
+	appended := *upload
+	appended.content = content
+	s.objectUploads[id] = &appended
+	upload.content.Release()
+
+	return content.Size(), nil
+}
+
+// CompleteObjectUpload finishes a resumable upload and creates the object from the uploaded content.
+func (s *Store) CompleteObjectUpload(bucketName, id string) (*storage.Object, error) {
+	s.storageMu.Lock()
+	upload, exists := s.objectUploads[id]
+	if exists && upload.bucket == bucketName {
+		delete(s.objectUploads, id)
+	}
+	s.storageMu.Unlock()
+
+	if !exists || upload.bucket != bucketName {
+		return nil, fmt.Errorf("upload %s not found", id)
+	}
+	defer upload.content.Release()
+
+	content, err := upload.content.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+	defer content.Close()
+
+	opts := InsertOptions(upload.req, upload.preconditions)
+	return s.CreateObjectWithOptions(upload.bucket, upload.name, upload.req.ContentType, content, upload.req.Metadata, opts)
+}
+
+// CancelObjectUpload cancels a resumable upload.
+func (s *Store) CancelObjectUpload(bucketName, id string) error {
+	s.storageMu.Lock()
+	defer s.storageMu.Unlock()
+
+	upload, exists := s.objectUploads[id]
+	if !exists || upload.bucket != bucketName {
+		return fmt.Errorf("upload %s not found", id)
+	}
+
+	delete(s.objectUploads, id)
+	upload.content.Release()
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package store
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+)
+
+func TestStore_ObjectUpload(t *testing.T) {
+	s := New()
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+
+	id, err := s.StartObjectUpload("test-bucket", "file.txt", &storage.ObjectInsertRequest{ContentType: "text/plain"}, Preconditions{})
+	if err != nil {
+		t.Fatalf("StartObjectUpload() error: %v", err)
+	}
+
+	for _, chunk := range []string{"hello ", "world"} {
+		if _, err := s.AppendObjectUpload("test-bucket", id, strings.NewReader(chunk)); err != nil {
+			t.Fatalf("AppendObjectUpload() error: %v", err)
+		}
+	}
+	if size, err := s.GetObjectUploadSize("test-bucket", id); err != nil || size != 11 {
+		t.Errorf("GetObjectUploadSize() = %d, %v", size, err)
+	}
+
+	// Uploads belong to their bucket
+	if _, err := s.GetObjectUploadSize("other-bucket", id); err == nil {
+		t.Error("expected upload not to be found in another bucket")
+	}
+
+	obj, err := s.CompleteObjectUpload("test-bucket", id)
+	if err != nil {
+		t.Fatalf("CompleteObjectUpload() error: %v", err)
+	}
+	if obj.Name != "file.txt" || obj.ContentType != "text/plain" || obj.Size != 11 {
+		t.Errorf("unexpected object: %+v", obj)
+	}
+	if content := s.GetObjectContent("test-bucket", "file.txt"); string(content) != "hello world" {
+		t.Errorf("expected content 'hello world', got '%s'", string(content))
+	}
+
+	// The upload is gone once completed
+	if _, err := s.CompleteObjectUpload("test-bucket", id); err == nil {
+		t.Error("expected completed upload to be removed")
+	}
+}
+
+func TestStore_ObjectUpload_Errors(t *testing.T) {
+	s := New()
+
+	if _, err := s.StartObjectUpload("missing-bucket", "file.txt", &storage.ObjectInsertRequest{}, Preconditions{}); err == nil {
+		t.Error("expected error for missing bucket")
+	}
+
+	_, _ = s.CreateBucket(&storage.BucketInsertRequest{Name: "test-bucket"})
+	id, _ := s.StartObjectUpload("test-bucket", "file.txt", &storage.ObjectInsertRequest{}, Preconditions{})
+
+	if err := s.CancelObjectUpload("test-bucket", id); err != nil {
+		t.Fatalf("CancelObjectUpload() error: %v", err)
+	}
+	if _, err := s.AppendObjectUpload("test-bucket", id, strings.NewReader("data")); err == nil {
+		t.Error("expected error appending to a cancelled upload")
+	}
+	if err := s.CancelObjectUpload("test-bucket", id); err == nil {
+		t.Error("expected error cancelling twice")
+	}
+}
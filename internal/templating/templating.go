@@ -0,0 +1,200 @@
+// Package templating rewrites the JSON responses of matching API requests with Go templates,
+// e.g. to add fields GCP shipped that the mock doesn't model yet, so clients' tolerance of unknown fields
+// can be tested without changing the mock.
+package templating
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// Rule rewrites the JSON responses of matching requests with a template.
+type Rule struct {
+	// ID identifies the rule; it is assigned when the rule is added.
+	ID string `json:"id"`
+	// Method is the HTTP method of matching requests; empty matches any method.
+	Method string `json:"method,omitempty"`
+	// Path is the path of matching requests, like /storage/v1/b/foo. "*" matches any characters
+	// of a path segment, like /storage/v1/b/*/o.
+	Path string `json:"path"`
+	// Template is a text/template producing the new JSON body. Its data is the decoded response body,
+	// see Funcs for the functions it can call.
+	Template string `json:"template"`
+	// Hits is how many requests the rule matched.
+	Hits int `json:"hits"`
+
+	tmpl *template.Template
+}
+
+// Funcs are the functions templates can call in addition to the text/template builtins:
+//
+//   - json marshals a value to JSON, e.g. {{ json . }} writes the response unchanged.
+//   - set sets a key of an object and returns the object, e.g. {{ json (set . "satisfiesPZS" false) }}.
+//   - unset removes a key of an object and returns the object.
+var Funcs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"set": func(object map[string]any, key string, value any) map[string]any {
+		object[key] = value
+		return object
+	},
+	"unset": func(object map[string]any, key string) map[string]any {
+		delete(object, key)
+		return object
+	},
+}
+
+// Validate checks that a rule can be added and parses its template.
+// Returns an "invalid template" error naming the problem.
+func (rule *Rule) Validate() error {
+	switch {
+	case !strings.HasPrefix(rule.Path, "/"):
+		return fmt.Errorf("invalid template: path must start with a slash")
+	case rule.Path == "/admin" || strings.HasPrefix(rule.Path, "/admin/"):
+		return fmt.Errorf("invalid template: responses of the admin API can't be templated")
+	case strings.TrimSpace(rule.Template) == "":
+		return fmt.Errorf("invalid template: template is required")
+	}
+	if _, err := path.Match(rule.Path, ""); err != nil {
+		return fmt.Errorf("invalid template: invalid path pattern %q", rule.Path)
+	}
+
+	tmpl, err := template.New(rule.Path).Funcs(Funcs).Option("missingkey=zero").Parse(rule.Template)
+	if err != nil {
+		return fmt.Errorf("invalid template: %w", err)
+	}
+	rule.tmpl = tmpl
+	return nil
+}
+
+// matches reports whether a request matches the rule.
+func (rule *Rule) matches(r *http.Request) bool {
+	if rule.Method != "" && !strings.EqualFold(rule.Method, r.Method) {
+		return false
+	}
+	matched, _ := path.Match(rule.Path, r.URL.Path)
+	return matched
+}
+
+// Apply executes the rule's template over a JSON response body and returns the new body.
+// Returns an error if the body isn't JSON or the template doesn't produce JSON.
+func (rule *Rule) Apply(body []byte) ([]byte, error) {
+	var data any
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&data); err != nil {
+		return nil, fmt.Errorf("template %s: response is not JSON: %w", rule.ID, err)
+	}
+
+	var out bytes.Buffer
+	if err := rule.tmpl.Execute(&out, data); err != nil {
+		return nil, fmt.Errorf("template %s: %w", rule.ID, err)
+	}
+	if !json.Valid(out.Bytes()) {
+		return nil, fmt.Errorf("template %s: output is not valid JSON: %s", rule.ID, out.String())
+	}
+	return out.Bytes(), nil
+}
+
+// Templates holds the active rules.
+// It is safe for concurrent access, so rules can be changed while requests are served.
+type Templates struct {
+	mu     sync.Mutex
+	rules  []*Rule
+	nextID int
+}
+
+// New creates a new Templates without rules.
+func New() *Templates {
+	return &Templates{}
+}
+
+// LoadRules reads a JSON file with an array of rules, like [{"path": "/storage/v1/b/*", "template": "..."}].
+func LoadRules(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response templates: %w", err)
+	}
+
+	var rules []Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse response templates: %w", err)
+	}
+	return rules, nil
+}
+
+// Add validates a rule and adds it after the existing ones. Returns the rule with its ID.
+func (t *Templates) Add(rule Rule) (Rule, error) {
+	if err := rule.Validate(); err != nil {
+		return Rule{}, err
+	}
+	rule.Method = strings.ToUpper(rule.Method)
+	rule.Hits = 0
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.nextID++
+	rule.ID = strconv.Itoa(t.nextID)
+	t.rules = append(t.rules, &rule)
+	return rule, nil
+}
+
+// List returns copies of the active rules in the order they match.
+func (t *Templates) List() []Rule {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	rules := make([]Rule, len(t.rules))
+	for i, rule := range t.rules {
+		rules[i] = *rule
+	}
+	return rules
+}
+
+// Delete removes a rule. Returns false if there is no rule with the ID.
+func (t *Templates) Delete(id string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for i, rule := range t.rules {
+		if rule.ID == id {
+			t.rules = append(t.rules[:i], t.rules[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Clear removes all rules.
+func (t *Templates) Clear() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.rules = nil
+}
+
+// Match returns a copy of the first rule matching the request and counts the request as matched by it.
+// Returns false if no rule matches.
+func (t *Templates) Match(r *http.Request) (Rule, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, rule := range t.rules {
+		if rule.matches(r) {
+			rule.Hits++
+			return *rule, true
+		}
+	}
+	return Rule{}, false
+}
@@ -0,0 +1,129 @@
+package templating
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRule_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    Rule
+		wantErr bool
+	}{
+		{name: "exact path", rule: Rule{Path: "/storage/v1/b/foo", Template: "{{ json . }}"}},
+		{name: "pattern", rule: Rule{Method: "get", Path: "/storage/v1/b/*/o", Template: `{{ json (set . "x" 1) }}`}},
+		{name: "relative path", rule: Rule{Path: "storage/v1/b", Template: "{{ json . }}"}, wantErr: true},
+		{name: "admin API", rule: Rule{Path: "/admin/requests", Template: "{{ json . }}"}, wantErr: true},
+		{name: "missing template", rule: Rule{Path: "/b"}, wantErr: true},
+		{name: "unknown function", rule: Rule{Path: "/b", Template: "{{ yaml . }}"}, wantErr: true},
+		{name: "invalid pattern", rule: Rule{Path: "/b/[a", Template: "{{ json . }}"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rule.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "invalid template") {
+				t.Errorf("expected an invalid template error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestRule_Apply(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		body     string
+		want     string
+		wantErr  bool
+	}{
+		{"unchanged", "{{ json . }}", `{"name":"foo","size":"12"}`, `{"name":"foo","size":"12"}`, false},
+		{"add field", `{{ json (set . "satisfiesPZS" false) }}`, `{"name":"foo"}`, `{"name":"foo","satisfiesPZS":false}`, false},
+		{"remove field", `{{ json (unset . "name") }}`, `{"name":"foo","id":1}`, `{"id":1}`, false},
+		// Numbers keep their exact representation
+		{"large number", "{{ json . }}", `{"generation":1712345678901234567}`, `{"generation":1712345678901234567}`, false},
+		{"literal JSON", `{"items": [{{ range $i, $item := .items }}{{ if $i }},{{ end }}{{ json (set $item "extra" true) }}{{ end }}]}`,
+			`{"items":[{"a":1},{"a":2}]}`, `{"items": [{"a":1,"extra":true},{"a":2,"extra":true}]}`, false},
+		{"not JSON", "{{ .name }}", `{"name":"foo"}`, "", true},
+		{"response not JSON", "{{ json . }}", `<xml/>`, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := Rule{Path: "/b", Template: tt.template}
+			if err := rule.Validate(); err != nil {
+				t.Fatalf("Validate() error: %v", err)
+			}
+
+			got, err := rule.Apply([]byte(tt.body))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Apply() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestTemplates_Match(t *testing.T) {
+	templates := New()
+	bucket, _ := templates.Add(Rule{Method: "get", Path: "/storage/v1/b/foo", Template: "{{ json . }}"})
+	wildcard, _ := templates.Add(Rule{Path: "/storage/v1/b/*", Template: "{{ json . }}"})
+
+	tests := []struct {
+		method string
+		path   string
+		wantID string
+	}{
+		{http.MethodGet, "/storage/v1/b/foo", bucket.ID},
+		{http.MethodPatch, "/storage/v1/b/foo", wildcard.ID},
+		{http.MethodGet, "/storage/v1/b/foo", bucket.ID},
+		{http.MethodGet, "/storage/v1/b/foo/o", ""},
+	}
+	for _, tt := range tests {
+		rule, ok := templates.Match(httptest.NewRequest(tt.method, tt.path, nil))
+		if rule.ID != tt.wantID || ok != (tt.wantID != "") {
+			t.Errorf("%s %s: expected rule %q, got %q (%v)", tt.method, tt.path, tt.wantID, rule.ID, ok)
+		}
+	}
+
+	rules := templates.List()
+	if len(rules) != 2 || rules[0].Hits != 2 || rules[1].Hits != 1 {
+		t.Errorf("expected the rules with their hits, got %+v", rules)
+	}
+	if !templates.Delete(bucket.ID) || templates.Delete(bucket.ID) {
+		t.Error("expected the rule to be deleted once")
+	}
+	templates.Clear()
+	if len(templates.List()) != 0 {
+		t.Error("expected no rules after Clear()")
+	}
+}
+
+func TestLoadRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "templates.json")
+	if err := os.WriteFile(path, []byte(`[{"method": "GET", "path": "/storage/v1/b/*", "template": "{{ json . }}"}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	rules, err := LoadRules(path)
+	if err != nil {
+		t.Fatalf("LoadRules() error: %v", err)
+	}
+	if len(rules) != 1 || rules[0].Path != "/storage/v1/b/*" {
+		t.Errorf("unexpected rules: %+v", rules)
+	}
+
+	if _, err := LoadRules(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
@@ -0,0 +1,349 @@
+// Package terraform renders the resources of the GCP API Mock as Terraform configuration, so infrastructure
+// prototyped against the mock can be codified with the Google provider.
+package terraform
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/katharinasick/gcp-api-mock/internal/sqladmin"
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+)
+
+// Export formats.
+const (
+	// FormatHCL renders a resource block with the configuration of each resource, followed by its import block.
+	FormatHCL = "hcl"
+	// FormatImport renders only import blocks, for generating the configuration with
+	// terraform plan -generate-config-out.
+	FormatImport = "import"
+)
+
+// IsValidFormat checks if a format is a known export format.
+func IsValidFormat(format string) bool {
+	return format == FormatHCL || format == FormatImport
+}
+
+// Resources are the mock resources to export.
+type Resources struct {
+	// Project is the project ID the resources belong to.
+	Project string
+	// Buckets are the Cloud Storage buckets.
+	Buckets []*storage.Bucket
+	// SQLInstances are the Cloud SQL instances.
+	SQLInstances []*sqladmin.DatabaseInstance
+	// SQLDatabases are the databases of the Cloud SQL instances, by instance name.
+	SQLDatabases map[string][]*sqladmin.Database
+	// SQLUsers are the users of the Cloud SQL instances, by instance name.
+	SQLUsers map[string][]*sqladmin.User
+}
+
+// Export writes the resources as Terraform configuration in the given format.
+// The default database and root user that every Cloud SQL instance is created with are left out,
+// since they aren't managed with Terraform. Passwords aren't exported.
+func Export(w io.Writer, res *Resources, format string) error {
+	if !IsValidFormat(format) {
+		return fmt.Errorf("invalid format %q: must be %q or %q", format, FormatHCL, FormatImport)
+	}
+
+	e := &exporter{w: bufio.NewWriter(w), format: format, names: make(map[string]bool)}
+	fmt.Fprintf(e.w, "# Generated by the GCP API Mock from project %s.\n", res.Project)
+	if format == FormatImport {
+		fmt.Fprintln(e.w, "# Generate the resource configuration with: terraform plan -generate-config-out=generated.tf")
+	}
+
+	buckets := slices.Clone(res.Buckets)
+	slices.SortFunc(buckets, func(a, b *storage.Bucket) int { return strings.Compare(a.Name, b.Name) })
+	for _, bucket := range buckets {
+		e.bucket(res.Project, bucket)
+	}
+
+	instances := slices.Clone(res.SQLInstances)
+	slices.SortFunc(instances, func(a, b *sqladmin.DatabaseInstance) int { return strings.Compare(a.Name, b.Name) })
+	// Primary instances come first, so replicas can reference them
+	slices.SortStableFunc(instances, func(a, b *sqladmin.DatabaseInstance) int {
+		return strings.Compare(a.MasterInstanceName, b.MasterInstanceName)
+	})
+	instanceAddresses := make(map[string]string, len(instances))
+	for _, instance := range instances {
+		instanceAddresses[instance.Name] = e.sqlInstance(instance, instanceAddresses)
+	}
+	for _, instance := range instances {
+		databases := slices.Clone(res.SQLDatabases[instance.Name])
+		slices.SortFunc(databases, func(a, b *sqladmin.Database) int { return strings.Compare(a.Name, b.Name) })
+		for _, database := range databases {
+			e.sqlDatabase(instance, database, instanceAddresses[instance.Name])
+		}
+
+		users := slices.Clone(res.SQLUsers[instance.Name])
+		slices.SortFunc(users, func(a, b *sqladmin.User) int {
+			return strings.Compare(a.Name+"@"+a.Host, b.Name+"@"+b.Host)
+		})
+		for _, user := range users {
+			e.sqlUser(instance, user, instanceAddresses[instance.Name])
+		}
+	}
+
+	return e.w.Flush()
+}
+
+// exporter writes the blocks of one export.
+type exporter struct {
+	w      *bufio.Writer
+	format string
+	// names are the resource addresses used so far, to keep them unique
+	names map[string]bool
+}
+
+// attribute is an argument of a block. Its value is an HCL expression.
+type attribute struct {
+	name  string
+	value string
+}
+
+// block is a nested block, like the settings of a Cloud SQL instance.
+type block struct {
+	typ        string
+	attributes []attribute
+	blocks     []block
+}
+
+// address returns a unique resource address of the given type for a resource name.
+// Characters that aren't allowed in Terraform identifiers are replaced by underscores.
+func (e *exporter) address(typ, name string) string {
+	label := invalidIdentifierChars.ReplaceAllString(name, "_")
+	if label == "" || (label[0] >= '0' && label[0] <= '9') || label[0] == '-' {
+		label = "_" + label
+	}
+
+	address := typ + "." + label
+	for i := 2; e.names[address]; i++ {
+		address = fmt.Sprintf("%s.%s_%d", typ, label, i)
+	}
+	e.names[address] = true
+	return address
+}
+
+// invalidIdentifierChars matches the characters that aren't allowed in Terraform identifiers.
+var invalidIdentifierChars = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// resource writes the resource block (unless only import blocks are exported) and the import block of a resource.
+func (e *exporter) resource(address, id string, body block) {
+	typ, label, _ := strings.Cut(address, ".")
+
+	fmt.Fprintln(e.w)
+	if e.format == FormatHCL {
+		fmt.Fprintf(e.w, "resource %s %s {\n", quote(typ), quote(label))
+		e.body(body, 1)
+		fmt.Fprintln(e.w, "}")
+		fmt.Fprintln(e.w)
+	}
+	fmt.Fprintln(e.w, "import {")
+	e.body(block{attributes: []attribute{{"to", address}, {"id", quote(id)}}}, 1)
+	fmt.Fprintln(e.w, "}")
+}
+
+// body writes the attributes and nested blocks of a block, aligning the equals signs like terraform fmt.
+func (e *exporter) body(b block, depth int) {
+	indent := strings.Repeat("  ", depth)
+
+	width := 0
+	for _, attr := range b.attributes {
+		width = max(width, len(attr.name))
+	}
+	for _, attr := range b.attributes {
+		fmt.Fprintf(e.w, "%s%-*s = %s\n", indent, width, attr.name, strings.ReplaceAll(attr.value, "\n", "\n"+indent))
+	}
+
+	for _, nested := range b.blocks {
+		fmt.Fprintln(e.w)
+		fmt.Fprintf(e.w, "%s%s {\n", indent, nested.typ)
+		e.body(nested, depth+1)
+		fmt.Fprintf(e.w, "%s}\n", indent)
+	}
+}
+
+// bucket writes a google_storage_bucket resource.
+func (e *exporter) bucket(project string, bucket *storage.Bucket) {
+	body := block{attributes: []attribute{
+		{"name", quote(bucket.Name)},
+		{"project", quote(project)},
+		{"location", quote(bucket.Location)},
+	}}
+	if bucket.StorageClass != "" {
+		body.attributes = append(body.attributes, attribute{"storage_class", quote(bucket.StorageClass)})
+	}
+	if iam := bucket.IamConfiguration; iam != nil {
+		if iam.UniformBucketLevelAccess != nil && iam.UniformBucketLevelAccess.Enabled {
+			body.attributes = append(body.attributes, attribute{"uniform_bucket_level_access", "true"})
+		}
+		if iam.PublicAccessPrevention != "" {
+			body.attributes = append(body.attributes, attribute{"public_access_prevention", quote(iam.PublicAccessPrevention)})
+		}
+	}
+	if len(bucket.Labels) > 0 {
+		body.attributes = append(body.attributes, attribute{"labels", stringMap(bucket.Labels)})
+	}
+
+	if bucket.Versioning != nil && bucket.Versioning.Enabled {
+		body.blocks = append(body.blocks, block{typ: "versioning", attributes: []attribute{{"enabled", "true"}}})
+	}
+	if bucket.Autoclass != nil && bucket.Autoclass.Enabled {
+		autoclass := block{typ: "autoclass", attributes: []attribute{{"enabled", "true"}}}
+		if bucket.Autoclass.TerminalStorageClass != "" {
+			autoclass.attributes = append(autoclass.attributes, attribute{"terminal_storage_class", quote(bucket.Autoclass.TerminalStorageClass)})
+		}
+		body.blocks = append(body.blocks, autoclass)
+	}
+
+	e.resource(e.address("google_storage_bucket", bucket.Name), project+"/"+bucket.Name, body)
+}
+
+// sqlInstance writes a google_sql_database_instance resource and returns its address.
+// Replicas reference their primary instance by its address in instanceAddresses.
+func (e *exporter) sqlInstance(instance *sqladmin.DatabaseInstance, instanceAddresses map[string]string) string {
+	address := e.address("google_sql_database_instance", instance.Name)
+
+	body := block{attributes: []attribute{
+		{"name", quote(instance.Name)},
+		{"project", quote(instance.Project)},
+		{"region", quote(instance.Region)},
+		{"database_version", quote(instance.DatabaseVersion)},
+	}}
+	if instance.MasterInstanceName != "" {
+		primary := quote(instance.MasterInstanceName)
+		if primaryAddress, ok := instanceAddresses[instance.MasterInstanceName]; ok {
+			primary = primaryAddress + ".name"
+		}
+		body.attributes = append(body.attributes, attribute{"master_instance_name", primary})
+	}
+
+	if s := instance.Settings; s != nil {
+		settings := block{typ: "settings", attributes: []attribute{{"tier", quote(s.Tier)}}}
+		if s.Edition != "" {
+			settings.attributes = append(settings.attributes, attribute{"edition", quote(s.Edition)})
+		}
+		if s.AvailabilityType != "" {
+			settings.attributes = append(settings.attributes, attribute{"availability_type", quote(s.AvailabilityType)})
+		}
+		if s.DataDiskSizeGb > 0 {
+			settings.attributes = append(settings.attributes, attribute{"disk_size", strconv.FormatInt(s.DataDiskSizeGb, 10)})
+		}
+		if s.DeletionProtectionEnabled {
+			settings.attributes = append(settings.attributes, attribute{"deletion_protection_enabled", "true"})
+		}
+		if len(s.UserLabels) > 0 {
+			settings.attributes = append(settings.attributes, attribute{"user_labels", stringMap(s.UserLabels)})
+		}
+		for _, flag := range s.DatabaseFlags {
+			settings.blocks = append(settings.blocks, block{typ: "database_flags", attributes: []attribute{
+				{"name", quote(flag.Name)},
+				{"value", quote(flag.Value)},
+			}})
+		}
+		body.blocks = append(body.blocks, settings)
+	}
+
+	e.resource(address, fmt.Sprintf("projects/%s/instances/%s", instance.Project, instance.Name), body)
+	return address
+}
+
+// sqlDatabase writes a google_sql_database resource, unless it's the default database of the instance.
+func (e *exporter) sqlDatabase(instance *sqladmin.DatabaseInstance, database *sqladmin.Database, instanceAddress string) {
+	if database.Name == "mysql" || database.Name == "postgres" {
+		return
+	}
+
+	body := block{attributes: []attribute{
+		{"name", quote(database.Name)},
+		{"project", quote(instance.Project)},
+		{"instance", instanceAddress + ".name"},
+	}}
+	if database.Charset != "" {
+		body.attributes = append(body.attributes, attribute{"charset", quote(database.Charset)})
+	}
+	if database.Collation != "" {
+		body.attributes = append(body.attributes, attribute{"collation", quote(database.Collation)})
+	}
+
+	address := e.address("google_sql_database", instance.Name+"_"+database.Name)
+	e.resource(address, fmt.Sprintf("projects/%s/instances/%s/databases/%s", instance.Project, instance.Name, database.Name), body)
+}
+
+// sqlUser writes a google_sql_user resource, unless it's the default root user of the instance.
+// The import ID of MySQL users includes their host.
+func (e *exporter) sqlUser(instance *sqladmin.DatabaseInstance, user *sqladmin.User, instanceAddress string) {
+	if user.Type == "BUILT_IN" && (user.Name == "root" || user.Name == "postgres" || user.Name == "sqlserver") {
+		return
+	}
+
+	body := block{attributes: []attribute{
+		{"name", quote(user.Name)},
+		{"project", quote(instance.Project)},
+		{"instance", instanceAddress + ".name"},
+	}}
+	if user.Host != "" {
+		body.attributes = append(body.attributes, attribute{"host", quote(user.Host)})
+	}
+	if user.Type != "" && user.Type != "BUILT_IN" {
+		body.attributes = append(body.attributes, attribute{"type", quote(user.Type)})
+	}
+
+	id := fmt.Sprintf("%s/%s/%s", instance.Project, instance.Name, user.Name)
+	if user.Host != "" {
+		id = fmt.Sprintf("%s/%s/%s/%s", instance.Project, instance.Name, user.Host, user.Name)
+	}
+	e.resource(e.address("google_sql_user", instance.Name+"_"+user.Name), id, body)
+}
+
+// stringMap returns an HCL map expression with the keys in sorted order.
+func stringMap(m map[string]string) string {
+	keys := make([]string, 0, len(m))
+	width := 0
+	for key := range m {
+		keys = append(keys, key)
+		width = max(width, len(quote(key)))
+	}
+	slices.Sort(keys)
+
+	var sb strings.Builder
+	sb.WriteString("{\n")
+	for _, key := range keys {
+		fmt.Fprintf(&sb, "  %-*s = %s\n", width, quote(key), quote(m[key]))
+	}
+	sb.WriteString("}")
+	return sb.String()
+}
+
+// quote returns an HCL string literal. Template sequences are escaped, so values are taken literally.
+func quote(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for i, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		case r == '\n':
+			sb.WriteString(`\n`)
+		case r == '\r':
+			sb.WriteString(`\r`)
+		case r == '\t':
+			sb.WriteString(`\t`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&sb, `\u%04x`, r)
+		case (r == '$' || r == '%') && strings.HasPrefix(s[i+1:], "{"):
+			sb.WriteRune(r)
+			sb.WriteRune(r)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
@@ -0,0 +1,150 @@
+package terraform
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/katharinasick/gcp-api-mock/internal/sqladmin"
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+)
+
+func testResources() *Resources {
+	return &Resources{
+		Project: "my-project",
+		Buckets: []*storage.Bucket{
+			{
+				Name:         "my-assets",
+				Location:     "EU",
+				StorageClass: "STANDARD",
+				Labels:       map[string]string{"env": "dev"},
+				Versioning:   &storage.Versioning{Enabled: true},
+			},
+		},
+		SQLInstances: []*sqladmin.DatabaseInstance{
+			{Name: "main-replica", Project: "my-project", Region: "us-central1", DatabaseVersion: "MYSQL_8_0", MasterInstanceName: "main"},
+			{
+				Name:            "main",
+				Project:         "my-project",
+				Region:          "us-central1",
+				DatabaseVersion: "MYSQL_8_0",
+				Settings: &sqladmin.Settings{
+					Tier:          "db-f1-micro",
+					DatabaseFlags: []*sqladmin.DatabaseFlags{{Name: "max_connections", Value: "100"}},
+				},
+			},
+		},
+		SQLDatabases: map[string][]*sqladmin.Database{
+			"main": {{Name: "mysql"}, {Name: "app", Charset: "utf8mb4"}},
+		},
+		SQLUsers: map[string][]*sqladmin.User{
+			"main": {{Name: "root", Host: "%", Type: "BUILT_IN"}, {Name: "app", Host: "%", Type: "BUILT_IN"}},
+		},
+	}
+}
+
+func TestExport_HCL(t *testing.T) {
+	var sb strings.Builder
+	if err := Export(&sb, testResources(), FormatHCL); err != nil {
+		t.Fatalf("Export() failed: %v", err)
+	}
+	out := sb.String()
+
+	expected := []string{
+		`resource "google_storage_bucket" "my-assets" {`,
+		`  storage_class = "STANDARD"`,
+		"  labels        = {\n    \"env\" = \"dev\"\n  }",
+		"  versioning {\n    enabled = true\n  }",
+		"import {\n  to = google_storage_bucket.my-assets\n  id = \"my-project/my-assets\"\n}",
+		`resource "google_sql_database_instance" "main" {`,
+		"    database_flags {\n      name  = \"max_connections\"\n      value = \"100\"\n    }",
+		`  id = "projects/my-project/instances/main"`,
+		`  master_instance_name = google_sql_database_instance.main.name`,
+		`resource "google_sql_database" "main_app" {`,
+		`  instance = google_sql_database_instance.main.name`,
+		`  id = "projects/my-project/instances/main/databases/app"`,
+		`resource "google_sql_user" "main_app" {`,
+		`  id = "my-project/main/%/app"`,
+	}
+	for _, s := range expected {
+		if !strings.Contains(out, s) {
+			t.Errorf("expected output to contain %q, got:\n%s", s, out)
+		}
+	}
+
+	// The default database and root user aren't exported
+	for _, s := range []string{`"main_mysql"`, `"main_root"`} {
+		if strings.Contains(out, s) {
+			t.Errorf("expected output not to contain %q, got:\n%s", s, out)
+		}
+	}
+
+	// The primary instance comes before its replica
+	if strings.Index(out, `"main-replica"`) < strings.Index(out, `"main"`) {
+		t.Errorf("expected the primary instance before its replica, got:\n%s", out)
+	}
+}
+
+func TestExport_Import(t *testing.T) {
+	var sb strings.Builder
+	if err := Export(&sb, testResources(), FormatImport); err != nil {
+		t.Fatalf("Export() failed: %v", err)
+	}
+	out := sb.String()
+
+	if strings.Contains(out, "\nresource ") {
+		t.Errorf("expected only import blocks, got:\n%s", out)
+	}
+	if n := strings.Count(out, "import {"); n != 5 {
+		t.Errorf("expected 5 import blocks, got %d:\n%s", n, out)
+	}
+}
+
+func TestExport_InvalidFormat(t *testing.T) {
+	if err := Export(&strings.Builder{}, testResources(), "json"); err == nil || !strings.Contains(err.Error(), "invalid format") {
+		t.Errorf("expected invalid format error, got %v", err)
+	}
+}
+
+func TestExporter_Address(t *testing.T) {
+	e := &exporter{names: make(map[string]bool)}
+
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"my-bucket", "google_storage_bucket.my-bucket"},
+		{"my.bucket.example.com", "google_storage_bucket.my_bucket_example_com"},
+		{"my_bucket_example_com", "google_storage_bucket.my_bucket_example_com_2"},
+		{"1st-bucket", "google_storage_bucket._1st-bucket"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := e.address("google_storage_bucket", tt.name); got != tt.expected {
+				t.Errorf("address(%q) = %q, expected %q", tt.name, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"plain", `"plain"`},
+		{`say "hi"`, `"say \"hi\""`},
+		{"line\nbreak", `"line\nbreak"`},
+		{"${var.x}", `"$${var.x}"`},
+		{"%{if}", `"%%{if}"`},
+		{"100% $5", `"100% $5"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := quote(tt.input); got != tt.expected {
+				t.Errorf("quote(%q) = %s, expected %s", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+// Package transfer tracks the uploads and downloads in flight, so the server can let them finish
+// before it shuts down instead of cutting them off.
+package transfer
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+// SessionIdleTimeout is how long a resumable upload session is waited for after its last request.
+// Clients upload the next chunk right away, so sessions idle for longer have most likely been abandoned.
+const SessionIdleTimeout = time.Minute
+
+// pollInterval is how often Wait checks whether the transfers have finished.
+const pollInterval = 50 * time.Millisecond
+
+// Transfer is an upload or download in flight.
+type Transfer struct {
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	StartTime time.Time `json:"startTime"`
+}
+
+// Stats are the transfers in flight and the numbers of finished transfers.
+type Stats struct {
+	// Active are the transfer requests in flight, oldest first.
+	Active []Transfer `json:"active"`
+	// Sessions is the number of resumable upload sessions that are waiting for their next chunk.
+	Sessions int `json:"sessions"`
+	// Completed is the number of transfer requests that got a response.
+	Completed int64 `json:"completed"`
+	// Aborted is the number of transfer requests cut off, because the client went away or the server shut down.
+	Aborted int64 `json:"aborted"`
+	// Draining is true once the server is shutting down.
+	Draining bool `json:"draining"`
+}
+
+// Tracker tracks the transfer requests and resumable upload sessions in flight.
+type Tracker struct {
+	mu     sync.Mutex
+	active map[uint64]Transfer
+	nextID uint64
+	// sessions is a map of upload ID to the time of the last request of the upload session
+	sessions  map[string]time.Time
+	completed int64
+	aborted   int64
+	draining  bool
+	now       func() time.Time
+}
+
+// New creates a new Tracker.
+func New() *Tracker {
+	return &Tracker{
+		active:   make(map[uint64]Transfer),
+		sessions: make(map[string]time.Time),
+		now:      time.Now,
+	}
+}
+
+// SetClock replaces the function used to read the current time. Tests use this to let sessions go idle.
+func (t *Tracker) SetClock(now func() time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.now = now
+}
+
+// Begin tracks a transfer request until the returned function is called with whether it was aborted.
+func (t *Tracker) Begin(method, path string) func(aborted bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	id := t.nextID
+	t.nextID++
+	t.active[id] = Transfer{Method: method, Path: path, StartTime: t.now()}
+
+	return func(aborted bool) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+
+		delete(t.active, id)
+		if aborted {
+			t.aborted++
+		} else {
+			t.completed++
+		}
+	}
+}
+
+// TouchSession records a request of a resumable upload session, starting the session if it's new.
+func (t *Tracker) TouchSession(uploadID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sessions[uploadID] = t.now()
+}
+
+// EndSession stops tracking a resumable upload session, because it was completed or cancelled.
+func (t *Tracker) EndSession(uploadID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.sessions, uploadID)
+}
+
+// Drain marks the server as shutting down. The transfers in flight are still served.
+func (t *Tracker) Drain() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.draining = true
+}
+
+// CheckReady returns an error once the server is draining, so load balancers stop sending new requests.
+func (t *Tracker) CheckReady() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.draining {
+		return errors.New("shutting down, draining transfers in flight")
+	}
+	return nil
+}
+
+// Wait waits until no transfer requests are in flight and no resumable upload session is waiting for its
+// next chunk. Returns the context's error if it's done first.
+func (t *Tracker) Wait(ctx context.Context) error {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if stats := t.Stats(); len(stats.Active) == 0 && stats.Sessions == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Stats returns the transfers in flight and the numbers of finished transfers.
+// Sessions idle for longer than SessionIdleTimeout are forgotten.
+func (t *Tracker) Stats() Stats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	for id, lastRequest := range t.sessions {
+		if now.Sub(lastRequest) >= SessionIdleTimeout {
+			delete(t.sessions, id)
+		}
+	}
+
+	active := make([]Transfer, 0, len(t.active))
+	for _, transfer := range t.active {
+		active = append(active, transfer)
+	}
+	slices.SortFunc(active, func(a, b Transfer) int { return a.StartTime.Compare(b.StartTime) })
+
+	return Stats{
+		Active:    active,
+		Sessions:  len(t.sessions),
+		Completed: t.completed,
+		Aborted:   t.aborted,
+		Draining:  t.draining,
+	}
+}
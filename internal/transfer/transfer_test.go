@@ -0,0 +1,80 @@
+package transfer
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTracker_Begin(t *testing.T) {
+	tracker := New()
+
+	upload := tracker.Begin("POST", "/upload/storage/v1/b/bucket/o")
+	download := tracker.Begin("GET", "/download/storage/v1/b/bucket/o/file.txt")
+	if stats := tracker.Stats(); len(stats.Active) != 2 {
+		t.Fatalf("expected 2 active transfers, got %+v", stats)
+	}
+
+	upload(false)
+	download(true)
+
+	stats := tracker.Stats()
+	if len(stats.Active) != 0 || stats.Completed != 1 || stats.Aborted != 1 {
+		t.Errorf("expected 1 completed and 1 aborted transfer, got %+v", stats)
+	}
+}
+
+func TestTracker_Sessions(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	tracker := New()
+	tracker.SetClock(func() time.Time { return now })
+
+	tracker.TouchSession("upload-1")
+	tracker.TouchSession("upload-2")
+	tracker.EndSession("upload-1")
+	if stats := tracker.Stats(); stats.Sessions != 1 {
+		t.Fatalf("expected 1 session, got %d", stats.Sessions)
+	}
+
+	// Abandoned sessions are forgotten
+	now = now.Add(SessionIdleTimeout)
+	if stats := tracker.Stats(); stats.Sessions != 0 {
+		t.Errorf("expected the idle session to be forgotten, got %d sessions", stats.Sessions)
+	}
+}
+
+func TestTracker_Wait(t *testing.T) {
+	tracker := New()
+	done := tracker.Begin("PUT", "/bucket/file.txt")
+	tracker.TouchSession("upload-1")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := tracker.Wait(ctx); err == nil {
+		t.Fatal("expected Wait() to time out with transfers in flight")
+	}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		done(false)
+		tracker.EndSession("upload-1")
+	}()
+	if err := tracker.Wait(context.Background()); err != nil {
+		t.Errorf("Wait() error: %v", err)
+	}
+}
+
+func TestTracker_Drain(t *testing.T) {
+	tracker := New()
+	if err := tracker.CheckReady(); err != nil {
+		t.Fatalf("expected tracker to be ready, got %v", err)
+	}
+
+	tracker.Drain()
+	if err := tracker.CheckReady(); err == nil {
+		t.Error("expected draining tracker not to be ready")
+	}
+	if !tracker.Stats().Draining {
+		t.Error("expected stats to report draining")
+	}
+}
@@ -0,0 +1,180 @@
+// Package usage counts the API calls the mock serves per method and per client, so the users of a shared
+// mock can see which clients call it the most and which methods fail for them.
+package usage
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// UnknownClient is the client of calls without an x-goog-api-client or User-Agent header.
+const UnknownClient = "unknown"
+
+// Client returns the client of a request: the x-goog-api-client header the Google client libraries send,
+// like "gl-go/1.23.0 gccl/1.43.0", or the User-Agent if it's missing.
+func Client(r *http.Request) string {
+	if client := r.Header.Get("X-Goog-Api-Client"); client != "" {
+		return client
+	}
+	if client := r.UserAgent(); client != "" {
+		return client
+	}
+	return UnknownClient
+}
+
+// key identifies the calls of a client to a method.
+type key struct {
+	method string
+	client string
+}
+
+// counter counts the calls of a client to a method.
+type counter struct {
+	calls     int64
+	errors    int64
+	firstSeen time.Time
+	lastSeen  time.Time
+}
+
+// Tracker counts the calls of the clients to the API methods.
+// It is safe for concurrent access.
+type Tracker struct {
+	mu       sync.Mutex
+	counters map[key]*counter
+	now      func() time.Time
+}
+
+// New creates a Tracker without counted calls.
+func New() *Tracker {
+	return &Tracker{counters: make(map[key]*counter), now: time.Now}
+}
+
+// Observe counts a call of a client to a method, like "storage.objects.get", answered with the status code.
+// Calls answered with a status code of 400 or above are counted as errors.
+func (t *Tracker) Observe(method, client string, code int) {
+	now := t.now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c := t.counters[key{method: method, client: client}]
+	if c == nil {
+		c = &counter{firstSeen: now}
+		t.counters[key{method: method, client: client}] = c
+	}
+	c.calls++
+	if code >= 400 {
+		c.errors++
+	}
+	c.lastSeen = now
+}
+
+// Reset forgets all counted calls.
+func (t *Tracker) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	clear(t.counters)
+}
+
+// Count is the number of calls of a client to a method, or to a method by a client.
+type Count struct {
+	// Name is the method or client.
+	Name   string `json:"name"`
+	Calls  int64  `json:"calls"`
+	Errors int64  `json:"errors"`
+}
+
+// MethodStats are the calls to a method.
+type MethodStats struct {
+	Method string `json:"method"`
+	Calls  int64  `json:"calls"`
+	Errors int64  `json:"errors"`
+	// Clients are the calls to the method per client.
+	Clients []Count `json:"clients"`
+}
+
+// ClientStats are the calls of a client.
+type ClientStats struct {
+	Client    string    `json:"client"`
+	Calls     int64     `json:"calls"`
+	Errors    int64     `json:"errors"`
+	FirstSeen time.Time `json:"firstSeen"`
+	LastSeen  time.Time `json:"lastSeen"`
+	// Methods are the calls of the client per method.
+	Methods []Count `json:"methods"`
+}
+
+// Report are the counted calls per method and per client.
+type Report struct {
+	Calls   int64         `json:"calls"`
+	Errors  int64         `json:"errors"`
+	Methods []MethodStats `json:"methods"`
+	Clients []ClientStats `json:"clients"`
+}
+
+// Report returns the counted calls. Methods, clients and their breakdowns are ordered by calls, most first;
+// ties are ordered by name.
+func (t *Tracker) Report() Report {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	report := Report{Methods: []MethodStats{}, Clients: []ClientStats{}}
+	methods := make(map[string]*MethodStats)
+	clients := make(map[string]*ClientStats)
+	for k, c := range t.counters {
+		report.Calls += c.calls
+		report.Errors += c.errors
+
+		m := methods[k.method]
+		if m == nil {
+			m = &MethodStats{Method: k.method}
+			methods[k.method] = m
+		}
+		m.Calls += c.calls
+		m.Errors += c.errors
+		m.Clients = append(m.Clients, Count{Name: k.client, Calls: c.calls, Errors: c.errors})
+
+		cl := clients[k.client]
+		if cl == nil {
+			cl = &ClientStats{Client: k.client, FirstSeen: c.firstSeen, LastSeen: c.lastSeen}
+			clients[k.client] = cl
+		}
+		cl.Calls += c.calls
+		cl.Errors += c.errors
+		if c.firstSeen.Before(cl.FirstSeen) {
+			cl.FirstSeen = c.firstSeen
+		}
+		if c.lastSeen.After(cl.LastSeen) {
+			cl.LastSeen = c.lastSeen
+		}
+		cl.Methods = append(cl.Methods, Count{Name: k.method, Calls: c.calls, Errors: c.errors})
+	}
+
+	for _, m := range methods {
+		slices.SortFunc(m.Clients, compareCounts)
+		report.Methods = append(report.Methods, *m)
+	}
+	for _, cl := range clients {
+		slices.SortFunc(cl.Methods, compareCounts)
+		report.Clients = append(report.Clients, *cl)
+	}
+	slices.SortFunc(report.Methods, func(a, b MethodStats) int {
+		return compareCounts(Count{Name: a.Method, Calls: a.Calls}, Count{Name: b.Method, Calls: b.Calls})
+	})
+	slices.SortFunc(report.Clients, func(a, b ClientStats) int {
+		return compareCounts(Count{Name: a.Client, Calls: a.Calls}, Count{Name: b.Client, Calls: b.Calls})
+	})
+	return report
+}
+
+// compareCounts orders counts by calls, most first, and then by name.
+func compareCounts(a, b Count) int {
+	if a.Calls != b.Calls {
+		return cmp.Compare(b.Calls, a.Calls)
+	}
+	return cmp.Compare(a.Name, b.Name)
+}
@@ -0,0 +1,86 @@
+package usage
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClient(t *testing.T) {
+	tests := []struct {
+		name      string
+		apiClient string
+		userAgent string
+		want      string
+	}{
+		{"client library", "gl-go/1.23.0 gccl/1.43.0", "google-api-go-client/0.5", "gl-go/1.23.0 gccl/1.43.0"},
+		{"user agent", "", "curl/8.5.0", "curl/8.5.0"},
+		{"neither", "", "", UnknownClient},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/storage/v1/b", nil)
+			req.Header.Del("User-Agent")
+			if tt.apiClient != "" {
+				req.Header.Set("X-Goog-Api-Client", tt.apiClient)
+			}
+			if tt.userAgent != "" {
+				req.Header.Set("User-Agent", tt.userAgent)
+			}
+			if got := Client(req); got != tt.want {
+				t.Errorf("Client() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTracker_Report(t *testing.T) {
+	now := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	tracker := New()
+	tracker.now = func() time.Time { return now }
+
+	tracker.Observe("storage.objects.get", "go", 200)
+	now = now.Add(time.Minute)
+	tracker.Observe("storage.objects.get", "go", 404)
+	tracker.Observe("storage.objects.get", "python", 200)
+	now = now.Add(time.Minute)
+	tracker.Observe("storage.buckets.list", "go", 200)
+
+	report := tracker.Report()
+	if report.Calls != 4 || report.Errors != 1 {
+		t.Errorf("expected 4 calls and 1 error, got %d and %d", report.Calls, report.Errors)
+	}
+
+	if len(report.Methods) != 2 {
+		t.Fatalf("expected 2 methods, got %+v", report.Methods)
+	}
+	get := report.Methods[0]
+	if get.Method != "storage.objects.get" || get.Calls != 3 || get.Errors != 1 {
+		t.Errorf("expected storage.objects.get with 3 calls and 1 error first, got %+v", get)
+	}
+	if len(get.Clients) != 2 || get.Clients[0] != (Count{Name: "go", Calls: 2, Errors: 1}) ||
+		get.Clients[1] != (Count{Name: "python", Calls: 1}) {
+		t.Errorf("unexpected clients of storage.objects.get: %+v", get.Clients)
+	}
+
+	if len(report.Clients) != 2 {
+		t.Fatalf("expected 2 clients, got %+v", report.Clients)
+	}
+	goClient := report.Clients[0]
+	if goClient.Client != "go" || goClient.Calls != 3 || goClient.Errors != 1 {
+		t.Errorf("expected go with 3 calls and 1 error first, got %+v", goClient)
+	}
+	start := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	if !goClient.FirstSeen.Equal(start) || !goClient.LastSeen.Equal(start.Add(2*time.Minute)) {
+		t.Errorf("unexpected first and last seen: %v, %v", goClient.FirstSeen, goClient.LastSeen)
+	}
+	if len(goClient.Methods) != 2 || goClient.Methods[0].Name != "storage.objects.get" {
+		t.Errorf("unexpected methods of go: %+v", goClient.Methods)
+	}
+
+	tracker.Reset()
+	if report := tracker.Report(); report.Calls != 0 || len(report.Methods) != 0 || len(report.Clients) != 0 {
+		t.Errorf("expected an empty report after Reset, got %+v", report)
+	}
+}
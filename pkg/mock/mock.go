@@ -0,0 +1,337 @@
+// Package mock runs the GCP API Mock in-process, for Go tests that talk to GCP APIs over HTTP.
+//
+// A test starts a mock with New, seeds it with the helper methods and points its clients at URL:
+//
+//	func TestUpload(t *testing.T) {
+//		m := mock.New(t)
+//		m.CreateBucket("assets")
+//		m.SetStorageEmulatorHost()
+//
+//		// Run the code under test, then check the result
+//		content, ok := m.ObjectContent("assets", "report.csv")
+//		...
+//	}
+//
+// The mock is shut down when the test finishes.
+package mock
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/katharinasick/gcp-api-mock/internal/config"
+	"github.com/katharinasick/gcp-api-mock/internal/logging"
+	"github.com/katharinasick/gcp-api-mock/internal/monitoring"
+	"github.com/katharinasick/gcp-api-mock/internal/server"
+	"github.com/katharinasick/gcp-api-mock/internal/sqladmin"
+	"github.com/katharinasick/gcp-api-mock/internal/storage"
+	"github.com/katharinasick/gcp-api-mock/internal/store"
+)
+
+// Mock is a GCP API Mock running on an httptest.Server.
+type Mock struct {
+	// URL is the base URL of the mock, like http://127.0.0.1:41234.
+	URL string
+
+	tb     testing.TB
+	server *httptest.Server
+	store  *store.Store
+}
+
+// Option configures a Mock.
+type Option func(cfg *config.Config)
+
+// WithLatency injects latency into API requests, using the format of GCP_MOCK_LATENCY,
+// e.g. "storage.get=20ms-80ms,sql.*=2s".
+func WithLatency(profile string) Option {
+	return func(cfg *config.Config) {
+		cfg.Latency = profile
+	}
+}
+
+// WithSQLMaintenanceDuration puts Cloud SQL instances into MAINTENANCE for the given duration when
+// the clock reaches their maintenance window.
+func WithSQLMaintenanceDuration(duration time.Duration) Option {
+	return func(cfg *config.Config) {
+		cfg.SQLMaintenanceDuration = duration.String()
+	}
+}
+
+// WithSQLData backs every Cloud SQL database with a SQLite file in dir, e.g. t.TempDir().
+// Open it with SQLDatabaseDSN.
+func WithSQLData(dir string) Option {
+	return func(cfg *config.Config) {
+		cfg.SQLDataDir = dir
+	}
+}
+
+// WithSQLCreateDelay keeps new Cloud SQL instances in PENDING_CREATE for the given duration.
+func WithSQLCreateDelay(delay time.Duration) Option {
+	return func(cfg *config.Config) {
+		cfg.SQLCreateDelay = delay.String()
+	}
+}
+
+// WithStrictAuth makes the mock reject API requests without a Bearer token.
+func WithStrictAuth() Option {
+	return func(cfg *config.Config) {
+		cfg.AuthMode = "strict"
+	}
+}
+
+// WithStrictValidation makes the mock reject requests the real APIs reject, like unknown JSON fields,
+// invalid bucket names or unavailable Cloud SQL tiers, which it otherwise accepts.
+func WithStrictValidation() Option {
+	return func(cfg *config.Config) {
+		cfg.StrictValidation = true
+	}
+}
+
+// WithStrictObjectPaths makes the mock reject object names that aren't clean paths, like "/a.txt" or
+// "a/../b.txt", which Cloud Storage accepts but which usually point at path handling bugs.
+func WithStrictObjectPaths() Option {
+	return func(cfg *config.Config) {
+		cfg.StrictObjectPaths = true
+	}
+}
+
+// New starts a mock for a test. It is shut down when the test finishes.
+func New(tb testing.TB, opts ...Option) *Mock {
+	tb.Helper()
+
+	// Start listening first, so links in resources like an object's mediaLink point at the mock
+	ts := httptest.NewUnstartedServer(nil)
+	baseURL := "http://" + ts.Listener.Addr().String()
+
+	cfg := &config.Config{BaseURL: baseURL, AuthMode: "permissive"}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	dataStore := store.New()
+	srv := server.NewWithStore(cfg, dataStore)
+	ts.Config.Handler = srv.Handler
+	ts.Start()
+	tb.Cleanup(func() {
+		ts.Close()
+		srv.Close()
+	})
+
+	return &Mock{URL: ts.URL, tb: tb, server: ts, store: dataStore}
+}
+
+// Host returns the host and port of the mock, the format of STORAGE_EMULATOR_HOST.
+func (m *Mock) Host() string {
+	return strings.TrimPrefix(m.URL, "http://")
+}
+
+// Client returns an HTTP client for the mock.
+func (m *Mock) Client() *http.Client {
+	return m.server.Client()
+}
+
+// SetStorageEmulatorHost points Cloud Storage client libraries at the mock by setting
+// STORAGE_EMULATOR_HOST for the duration of the test.
+func (m *Mock) SetStorageEmulatorHost() {
+	m.tb.Setenv("STORAGE_EMULATOR_HOST", m.Host())
+}
+
+// Reset deletes all resources.
+func (m *Mock) Reset() {
+	m.store.Reset()
+}
+
+// CreateBucket creates a bucket and fails the test if that isn't possible.
+func (m *Mock) CreateBucket(name string) {
+	m.tb.Helper()
+
+	if _, err := m.store.CreateBucket(&storage.BucketInsertRequest{Name: name}); err != nil {
+		m.tb.Fatalf("mock: failed to create bucket %s: %v", name, err)
+	}
+}
+
+// BucketExists reports whether a bucket exists.
+func (m *Mock) BucketExists(name string) bool {
+	return m.store.GetBucket(name) != nil
+}
+
+// PutObject creates or replaces an object and fails the test if that isn't possible.
+// An empty content type is stored as application/octet-stream.
+func (m *Mock) PutObject(bucketName, objectName, contentType string, content []byte) {
+	m.tb.Helper()
+
+	if _, err := m.store.CreateObject(bucketName, objectName, contentType, content, nil); err != nil {
+		m.tb.Fatalf("mock: failed to create object gs://%s/%s: %v", bucketName, objectName, err)
+	}
+}
+
+// ObjectContent returns the content of an object, or false if the object doesn't exist.
+func (m *Mock) ObjectContent(bucketName, objectName string) ([]byte, bool) {
+	if m.store.GetObject(bucketName, objectName) == nil {
+		return nil, false
+	}
+	return m.store.GetObjectContent(bucketName, objectName), true
+}
+
+// ObjectNames returns the sorted names of the objects in a bucket that start with prefix.
+func (m *Mock) ObjectNames(bucketName, prefix string) []string {
+	objects, _ := m.store.ListObjects(bucketName, prefix, "")
+
+	names := make([]string, 0, len(objects))
+	for _, obj := range objects {
+		names = append(names, obj.Name)
+	}
+	return names
+}
+
+// Event is a change of a resource of the mock, see Events.
+type Event = store.Event
+
+// EventType is the kind of change an Event describes.
+type EventType = store.EventType
+
+// Event types of the changes Events delivers.
+const (
+	EventBucketCreated         = store.EventBucketCreated
+	EventBucketDeleted         = store.EventBucketDeleted
+	EventObjectFinalized       = store.EventObjectFinalized
+	EventObjectMetadataUpdated = store.EventObjectMetadataUpdated
+	EventObjectDeleted         = store.EventObjectDeleted
+	EventSQLInstanceCreated    = store.EventSQLInstanceCreated
+	EventSQLInstanceUpdated    = store.EventSQLInstanceUpdated
+	EventSQLInstanceDeleted    = store.EventSQLInstanceDeleted
+	EventSQLDatabaseCreated    = store.EventSQLDatabaseCreated
+	EventSQLDatabaseDeleted    = store.EventSQLDatabaseDeleted
+)
+
+// Events returns a channel that receives the changes of the mock's resources from now on, in the order
+// they were made, until the test finishes. Events are queued until they're received, so the mock never
+// waits for the test.
+func (m *Mock) Events() <-chan Event {
+	sub := m.store.Subscribe()
+	m.tb.Cleanup(sub.Close)
+	return sub.C
+}
+
+// WaitForObject waits until an object exists and returns it, so a test can synchronize on a write of the
+// code under test instead of polling. It fails the test if the object doesn't exist within timeout.
+func (m *Mock) WaitForObject(bucketName, objectName string, timeout time.Duration) *storage.Object {
+	m.tb.Helper()
+
+	// Subscribe before looking, so a write in between isn't missed
+	sub := m.store.Subscribe()
+	defer sub.Close()
+	if obj := m.store.GetObject(bucketName, objectName); obj != nil {
+		return obj
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case e := <-sub.C:
+			if e.Type == EventObjectFinalized && e.Object.Bucket == bucketName && e.Object.Name == objectName {
+				return e.Object
+			}
+		case <-timer.C:
+			m.tb.Fatalf("mock: object gs://%s/%s wasn't written within %s", bucketName, objectName, timeout)
+			return nil
+		}
+	}
+}
+
+// CreateSQLInstance creates a Cloud SQL instance, e.g. with version "POSTGRES_15",
+// and fails the test if that isn't possible.
+func (m *Mock) CreateSQLInstance(name, databaseVersion string) {
+	m.tb.Helper()
+
+	req := &sqladmin.InstanceInsertRequest{Name: name, DatabaseVersion: databaseVersion}
+	if _, _, err := m.store.CreateSQLInstance(req); err != nil {
+		m.tb.Fatalf("mock: failed to create Cloud SQL instance %s: %v", name, err)
+	}
+}
+
+// CreateSQLDatabase creates a database in a Cloud SQL instance and fails the test if that isn't possible.
+func (m *Mock) CreateSQLDatabase(instanceName, databaseName string) {
+	m.tb.Helper()
+
+	req := &sqladmin.DatabaseInsertRequest{Name: databaseName}
+	if _, _, err := m.store.CreateSQLDatabase(instanceName, req); err != nil {
+		m.tb.Fatalf("mock: failed to create database %s in %s: %v", databaseName, instanceName, err)
+	}
+}
+
+// SQLDatabaseDSN returns the DSN of the SQLite file backing a Cloud SQL database, to open it with
+// sql.Open("sqlite3", dsn). It fails the test if the mock doesn't run with WithSQLData or the database doesn't exist.
+func (m *Mock) SQLDatabaseDSN(instanceName, databaseName string) string {
+	m.tb.Helper()
+
+	resp, err := m.Client().Get(m.URL + "/admin/sql/instances/" + url.PathEscape(instanceName) +
+		"/databases/" + url.PathEscape(databaseName) + "/dsn")
+	if err != nil {
+		m.tb.Fatalf("mock: failed to get the DSN of database %s in %s: %v", databaseName, instanceName, err)
+	}
+	defer resp.Body.Close()
+
+	var db struct {
+		DSN string `json:"dsn"`
+	}
+	if resp.StatusCode != http.StatusOK || json.NewDecoder(resp.Body).Decode(&db) != nil {
+		m.tb.Fatalf("mock: database %s in %s has no SQLite file (status %d); is the mock running WithSQLData?",
+			databaseName, instanceName, resp.StatusCode)
+	}
+	return db.DSN
+}
+
+// SQLInstanceState returns the state of a Cloud SQL instance, like RUNNABLE, or "" if it doesn't exist.
+func (m *Mock) SQLInstanceState(name string) string {
+	instance := m.store.GetSQLInstance(name)
+	if instance == nil {
+		return ""
+	}
+	return instance.State
+}
+
+// MetricTypes returns the sorted types of the Cloud Monitoring metrics of a project that have a descriptor,
+// e.g. because time series were written for them.
+func (m *Mock) MetricTypes(project string) []string {
+	all, _ := monitoring.ParseFilter("")
+	descriptors := m.store.ListMetricDescriptors(project, all)
+
+	types := make([]string, 0, len(descriptors))
+	for _, descriptor := range descriptors {
+		types = append(types, descriptor.Type)
+	}
+	return types
+}
+
+// MetricPointCount returns the number of points written for a Cloud Monitoring metric type in a project,
+// across all of its time series.
+func (m *Mock) MetricPointCount(project, metricType string) int {
+	filter, err := monitoring.ParseFilter("metric.type = " + strconv.Quote(metricType))
+	if err != nil {
+		return 0
+	}
+
+	count := 0
+	for _, ts := range m.store.ListTimeSeries(project, filter, time.Time{}, time.Unix(1<<62, 0), false) {
+		count += len(ts.Points)
+	}
+	return count
+}
+
+// LogEntries returns the Cloud Logging entries of a project matching a filter in the Logging query language,
+// oldest first. An invalid filter matches nothing.
+func (m *Mock) LogEntries(project, filter string) []*logging.LogEntry {
+	f, err := logging.ParseFilter(filter)
+	if err != nil {
+		return nil
+	}
+	return m.store.ListLogEntries([]string{"projects/" + project}, f, false)
+}
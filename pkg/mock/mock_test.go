@@ -0,0 +1,222 @@
+package mock
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMock_SeedAndServe(t *testing.T) {
+	m := New(t)
+	m.CreateBucket("assets")
+	m.PutObject("assets", "config/app.json", "application/json", []byte(`{"debug":true}`))
+	m.PutObject("assets", "logo.svg", "", []byte("<svg/>"))
+	m.CreateSQLInstance("main", "POSTGRES_15")
+	m.CreateSQLDatabase("main", "app")
+
+	tests := []struct {
+		name             string
+		path             string
+		expectedStatus   int
+		expectedContains string
+	}{
+		{"get bucket", "/storage/v1/b/assets", http.StatusOK, `"name":"assets"`},
+		{"download object", "/storage/v1/b/assets/o/config%2Fapp.json?alt=media", http.StatusOK, `{"debug":true}`},
+		{"object metadata links to mock", "/storage/v1/b/assets/o/logo.svg", http.StatusOK, m.URL + "/download/storage/v1/b/assets/o/logo.svg"},
+		{"missing object", "/storage/v1/b/assets/o/missing", http.StatusNotFound, "notFound"},
+		{"get database", "/sql/v1beta4/projects/mock-project/instances/main/databases/app", http.StatusOK, `"name":"app"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := m.Client().Get(m.URL + tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+
+			if resp.StatusCode != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, resp.StatusCode, body)
+			}
+			if !strings.Contains(string(body), tt.expectedContains) {
+				t.Errorf("expected body to contain %q, got %s", tt.expectedContains, body)
+			}
+		})
+	}
+}
+
+func TestMock_Inspect(t *testing.T) {
+	m := New(t)
+	m.CreateBucket("assets")
+
+	req, _ := http.NewRequest(http.MethodPost, m.URL+"/upload/storage/v1/b/assets/o?uploadType=media&name=report.csv", strings.NewReader("a,b"))
+	req.Header.Set("Content-Type", "text/csv")
+	resp, err := m.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if !m.BucketExists("assets") || m.BucketExists("missing") {
+		t.Error("unexpected BucketExists() result")
+	}
+	if content, ok := m.ObjectContent("assets", "report.csv"); !ok || string(content) != "a,b" {
+		t.Errorf("ObjectContent() = %q, %v", content, ok)
+	}
+	if _, ok := m.ObjectContent("assets", "missing"); ok {
+		t.Error("expected missing object not to exist")
+	}
+	if names := m.ObjectNames("assets", ""); !reflect.DeepEqual(names, []string{"report.csv"}) {
+		t.Errorf("ObjectNames() = %v", names)
+	}
+
+	m.Reset()
+	if m.BucketExists("assets") {
+		t.Error("expected bucket to be deleted by Reset()")
+	}
+}
+
+func TestMock_Options(t *testing.T) {
+	m := New(t, WithSQLCreateDelay(time.Hour), WithStrictAuth())
+	m.CreateSQLInstance("main", "MYSQL_8_0")
+
+	if state := m.SQLInstanceState("main"); state != "PENDING_CREATE" {
+		t.Errorf("expected instance state PENDING_CREATE, got %q", state)
+	}
+	if state := m.SQLInstanceState("missing"); state != "" {
+		t.Errorf("expected empty state for missing instance, got %q", state)
+	}
+
+	resp, err := m.Client().Get(m.URL + "/storage/v1/b?project=mock-project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected status %d without token, got %d", http.StatusUnauthorized, resp.StatusCode)
+	}
+}
+
+func TestMock_SQLDatabaseDSN(t *testing.T) {
+	m := New(t, WithSQLData(t.TempDir()))
+	m.CreateSQLInstance("main", "POSTGRES_15")
+	m.CreateSQLDatabase("main", "app")
+
+	dsn := m.SQLDatabaseDSN("main", "app")
+	if !strings.HasPrefix(dsn, "file:") || !strings.HasSuffix(dsn, "/main/app.sqlite") {
+		t.Errorf("unexpected DSN %q", dsn)
+	}
+}
+
+func TestMock_SetStorageEmulatorHost(t *testing.T) {
+	m := New(t)
+	m.SetStorageEmulatorHost()
+
+	if host := os.Getenv("STORAGE_EMULATOR_HOST"); host != m.Host() || strings.Contains(host, "://") {
+		t.Errorf("unexpected STORAGE_EMULATOR_HOST %q", host)
+	}
+
+	var buckets struct {
+		Kind string `json:"kind"`
+	}
+	resp, err := http.Get("http://" + os.Getenv("STORAGE_EMULATOR_HOST") + "/storage/v1/b?project=mock-project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(&buckets); err != nil || buckets.Kind != "storage#buckets" {
+		t.Errorf("unexpected bucket list: %+v, %v", buckets, err)
+	}
+}
+
+func TestMock_Metrics(t *testing.T) {
+	m := New(t)
+
+	body := `{"timeSeries":[{
+		"metric":{"type":"custom.googleapis.com/jobs_processed"},
+		"resource":{"type":"global"},
+		"points":[{"interval":{"endTime":"2024-01-01T12:00:00Z"},"value":{"int64Value":"3"}}]
+	}]}`
+	resp, err := m.Client().Post(m.URL+"/v3/projects/test-project/timeSeries", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+
+	if types := m.MetricTypes("test-project"); !reflect.DeepEqual(types, []string{"custom.googleapis.com/jobs_processed"}) {
+		t.Errorf("MetricTypes() = %v", types)
+	}
+	if count := m.MetricPointCount("test-project", "custom.googleapis.com/jobs_processed"); count != 1 {
+		t.Errorf("MetricPointCount() = %d, want 1", count)
+	}
+	if count := m.MetricPointCount("other-project", "custom.googleapis.com/jobs_processed"); count != 0 {
+		t.Errorf("MetricPointCount() in another project = %d, want 0", count)
+	}
+}
+
+func TestMock_LogEntries(t *testing.T) {
+	m := New(t)
+
+	body := `{"logName":"projects/test-project/logs/app","resource":{"type":"global"},
+		"entries":[{"severity":"ERROR","textPayload":"boom"},{"severity":"INFO","textPayload":"ok"}]}`
+	resp, err := m.Client().Post(m.URL+"/v2/entries:write", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+
+	if entries := m.LogEntries("test-project", ""); len(entries) != 2 {
+		t.Errorf("expected 2 entries, got %d", len(entries))
+	}
+	if entries := m.LogEntries("test-project", "severity>=ERROR"); len(entries) != 1 || entries[0].TextPayload != "boom" {
+		t.Errorf("expected only the ERROR entry, got %+v", entries)
+	}
+	if entries := m.LogEntries("other-project", ""); len(entries) != 0 {
+		t.Errorf("expected no entries in another project, got %d", len(entries))
+	}
+}
+
+func TestMock_Events(t *testing.T) {
+	m := New(t)
+	m.CreateBucket("assets")
+	events := m.Events()
+
+	go func() {
+		req, _ := http.NewRequest(http.MethodPost, m.URL+"/upload/storage/v1/b/assets/o?uploadType=media&name=report.csv", strings.NewReader("a,b"))
+		resp, err := m.Client().Do(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
+
+	obj := m.WaitForObject("assets", "report.csv", 5*time.Second)
+	if obj.Size != 3 {
+		t.Errorf("expected an object of 3 bytes, got %d", obj.Size)
+	}
+
+	select {
+	case e := <-events:
+		if e.Type != EventObjectFinalized || e.Object.Name != "report.csv" {
+			t.Errorf("expected the object to be finalized, got %s %+v", e.Type, e.Object)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for an event")
+	}
+
+	// Existing objects are returned right away
+	if obj := m.WaitForObject("assets", "report.csv", time.Millisecond); obj.Name != "report.csv" {
+		t.Errorf("expected the existing object, got %+v", obj)
+	}
+}
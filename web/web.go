@@ -0,0 +1,15 @@
+// Package web holds the templates and static files of the dashboard.
+// They are embedded into the binary, so the mock runs from any working directory.
+package web
+
+import "embed"
+
+// Templates holds the HTML templates of the dashboard under templates/.
+//
+//go:embed templates/*.html
+var Templates embed.FS
+
+// Static holds the static files of the dashboard, like stylesheets, under static/.
+//
+//go:embed static
+var Static embed.FS